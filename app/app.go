@@ -0,0 +1,473 @@
+// Package app wires the framework's building blocks — config, logging,
+// metrics, tracing, auth, ent, the plugin runtime and the HTTP server — into
+// one application with ordered startup and graceful shutdown.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redis/v8"
+	"github.com/leeforge/framework/auth"
+	"github.com/leeforge/framework/cache"
+	"github.com/leeforge/framework/config"
+	"github.com/leeforge/framework/ent"
+	"github.com/leeforge/framework/health"
+	"github.com/leeforge/framework/http/router"
+	"github.com/leeforge/framework/http/server"
+	"github.com/leeforge/framework/logging"
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/plugin"
+	"github.com/leeforge/framework/runtime"
+	"github.com/leeforge/framework/tracing"
+	"go.uber.org/zap"
+)
+
+// Keys under which the app registers core services for plugins.
+const (
+	ServiceConfig  = "config"
+	ServiceLogger  = "logger"
+	ServiceMetrics = "metrics.collector"
+	ServiceTracer  = "tracing.tracer"
+	ServiceEnt     = "ent.client"
+	ServiceCache   = "cache"
+	ServiceAuth    = "auth.core"
+	ServiceHealth  = "health.registry"
+)
+
+// DefaultDrainTimeout bounds graceful shutdown when WithDrainTimeout is unset.
+const DefaultDrainTimeout = 30 * time.Second
+
+// Hook runs during startup (OnStart) or shutdown (OnStop).
+type Hook func(ctx context.Context) error
+
+// Component is a unit with an ordered lifecycle. Components start in the
+// order they are added and stop in reverse; Start or Stop may be nil.
+type Component struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+// App is a fully wired application. Create it with New, then call Run, or
+// Start and Stop when the caller owns signal handling.
+type App struct {
+	addr         string
+	drainTimeout time.Duration
+	signals      []os.Signal
+
+	cfg         *config.Config
+	sectionOpts []config.SectionsOption
+	sections    *config.Sections
+
+	logger      *zap.Logger
+	collector   *metrics.Collector
+	metricsPath string
+	tracer      *tracing.Tracer
+	authCfg     *auth.Config
+	authCore    *auth.AuthCore
+	entClient   *ent.Client
+	redis       *redis.Client
+	cache       *cache.MultiLevelCache
+
+	health    *health.Registry
+	router    chi.Router
+	runtime   *runtime.Runtime
+	plugins   []plugin.Plugin
+	serverCfg server.Config
+	server    *server.Server
+
+	components []Component
+	onStart    []Hook
+	onStop     []Hook
+
+	mu       sync.Mutex
+	started  []Component
+	running  bool
+	stopped  bool
+	serveErr chan error
+
+	// runtimeDown records that runtime.Shutdown ran, which closes the ent and
+	// redis clients itself.
+	runtimeDown bool
+
+	// listenAddr is kept apart from mu so hooks and components may call Addr
+	// while Start holds the lock.
+	listenAddr atomic.Value // string
+}
+
+// New builds an App from opts. Config sections are loaded and validated here,
+// so misconfiguration fails before anything starts.
+func New(opts ...Option) (*App, error) {
+	a := &App{
+		addr:         ":8080",
+		drainTimeout: DefaultDrainTimeout,
+		signals:      []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		metricsPath:  "/metrics",
+		serveErr:     make(chan error, 1),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.cfg != nil {
+		sections, err := a.cfg.LoadSections(a.sectionOpts...)
+		if err != nil {
+			return nil, err
+		}
+		a.sections = sections
+		if a.logger == nil {
+			if logCfg, ok := config.SectionValue[logging.Config](sections, logging.SectionName); ok {
+				logger := logging.NewLogger(logCfg)
+				logging.SetGlobal(logger)
+				a.logger = logger.Zap()
+			}
+		}
+	}
+	if a.logger == nil {
+		a.logger = logging.Global().Zap()
+	}
+	if a.health == nil {
+		a.health = health.NewRegistry(health.WithLogger(a.logger), health.WithMetrics(a.collector))
+	}
+	if a.router == nil {
+		a.router = router.New()
+	}
+	if a.serverCfg.Addr == "" {
+		a.serverCfg.Addr = a.addr
+	}
+	if a.serverCfg.Logger == nil {
+		a.serverCfg.Logger = a.logger
+	}
+	if a.collector != nil {
+		a.serverCfg.Metrics = a.collector
+		a.serverCfg.MetricsPath = a.metricsPath
+	}
+	if a.serverCfg.Health == nil {
+		a.serverCfg.Health = a.health
+	}
+	a.server = server.New(a.router, a.serverCfg)
+	if a.cache == nil && a.redis != nil {
+		a.cache = cache.NewMultiLevelCache(cache.NewRedisAdapter(a.redis, nil), nil)
+	}
+
+	rtCfg := runtime.Config{
+		Router: a.router,
+		Redis:  a.redis,
+		Logger: a.logger,
+		Tracer: a.tracer,
+	}
+	// Leave interface fields nil rather than holding typed nil pointers.
+	if a.entClient != nil {
+		rtCfg.DB = a.entClient
+	}
+	if a.cfg != nil {
+		rtCfg.Settings = a.cfg
+	}
+	a.runtime = runtime.NewRuntime(rtCfg)
+
+	if err := a.registerServices(); err != nil {
+		return nil, err
+	}
+	for _, p := range a.plugins {
+		if err := a.runtime.Register(p); err != nil {
+			return nil, err
+		}
+	}
+
+	a.components = a.lifecycle()
+	return a, nil
+}
+
+func (a *App) registerServices() error {
+	services := a.runtime.Services()
+	core := map[string]any{ServiceLogger: a.logger, ServiceHealth: a.health}
+	if a.cfg != nil {
+		core[ServiceConfig] = a.cfg
+	}
+	if a.collector != nil {
+		core[ServiceMetrics] = a.collector
+	}
+	if a.tracer != nil {
+		core[ServiceTracer] = a.tracer
+	}
+	if a.entClient != nil {
+		core[ServiceEnt] = a.entClient
+	}
+	if a.cache != nil {
+		core[ServiceCache] = a.cache
+	}
+	for key, svc := range core {
+		if err := services.Register(key, svc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lifecycle assembles the startup sequence: telemetry, storage, auth, user
+// components, plugins, OnStart/OnStop hooks and finally the HTTP server, so
+// the server stops accepting traffic first on shutdown.
+func (a *App) lifecycle() []Component {
+	seq := []Component{
+		{
+			Name: "telemetry",
+			Stop: func(ctx context.Context) error {
+				var err error
+				if a.tracer != nil {
+					err = a.tracer.Shutdown(ctx)
+				}
+				// Sync fails on stdout/stderr on some platforms; nothing to do about it.
+				_ = a.logger.Sync()
+				return err
+			},
+		},
+		{
+			Name: "storage",
+			Stop: a.closeStorage,
+		},
+	}
+
+	if a.authCfg != nil {
+		seq = append(seq, Component{
+			Name:  "auth",
+			Start: a.startAuth,
+			Stop: func(context.Context) error {
+				if a.authCore == nil {
+					return nil
+				}
+				return a.authCore.Close()
+			},
+		})
+	}
+
+	seq = append(seq, a.components...)
+
+	seq = append(seq,
+		Component{
+			Name:  "plugins",
+			Start: a.startPlugins,
+			Stop:  a.stopPlugins,
+		},
+		Component{
+			Name: "hooks",
+			Start: func(ctx context.Context) error {
+				for _, hook := range a.onStart {
+					if err := hook(ctx); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				var errs []error
+				for _, hook := range slices.Backward(a.onStop) {
+					errs = append(errs, hook(ctx))
+				}
+				return errors.Join(errs...)
+			},
+		},
+		Component{
+			Name:  "http",
+			Start: a.startServer,
+			Stop:  a.stopServer,
+		},
+	)
+	return seq
+}
+
+// startPlugins bootstraps the runtime and registers each HealthReporter
+// plugin as a readiness check named "plugin:<name>".
+func (a *App) startPlugins(ctx context.Context) error {
+	if err := a.runtime.Bootstrap(ctx); err != nil {
+		return err
+	}
+	for name, check := range a.runtime.HealthChecks() {
+		if err := a.health.RegisterFunc("plugin:"+name, check); err != nil {
+			a.runtimeDown = true
+			return errors.Join(err, a.runtime.Shutdown(ctx))
+		}
+	}
+	return nil
+}
+
+func (a *App) stopPlugins(ctx context.Context) error {
+	for name := range a.runtime.HealthChecks() {
+		a.health.Unregister("plugin:" + name)
+	}
+	a.runtimeDown = true
+	return a.runtime.Shutdown(ctx)
+}
+
+// closeStorage closes the cache and, unless runtime.Shutdown already did
+// (it is skipped when startup fails before plugins), the ent and redis
+// clients.
+func (a *App) closeStorage(context.Context) error {
+	if a.cache != nil {
+		a.cache.Close()
+	}
+	if a.runtimeDown {
+		return nil
+	}
+	var errs []error
+	if a.entClient != nil {
+		errs = append(errs, a.entClient.Close())
+	}
+	if a.redis != nil {
+		errs = append(errs, a.redis.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (a *App) startAuth(ctx context.Context) error {
+	cfg := *a.authCfg
+	if cfg.Logger == nil {
+		cfg.Logger = a.logger
+	}
+	core, err := auth.Setup(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	a.authCore = core
+	return a.runtime.Services().Register(ServiceAuth, core)
+}
+
+func (a *App) startServer(context.Context) error {
+	if err := a.server.Start(); err != nil {
+		return err
+	}
+	a.listenAddr.Store(a.server.Addr())
+
+	go func() {
+		<-a.server.Done()
+		if err := a.server.Err(); err != nil {
+			a.serveErr <- err
+		}
+	}()
+	return nil
+}
+
+// stopServer turns /readyz unhealthy, stops accepting connections and waits
+// for in-flight requests until ctx expires.
+func (a *App) stopServer(ctx context.Context) error {
+	return a.server.Shutdown(ctx)
+}
+
+// Start runs every component in order. If one fails, the components already
+// started are stopped in reverse and the error is returned.
+func (a *App) Start(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.running || a.stopped {
+		return fmt.Errorf("app already started")
+	}
+
+	begin := time.Now()
+	for _, c := range a.components {
+		if c.Start != nil {
+			if err := c.Start(ctx); err != nil {
+				stopErr := a.stopStarted(context.WithoutCancel(ctx))
+				a.stopped = true
+				return errors.Join(fmt.Errorf("start %s: %w", c.Name, err), stopErr)
+			}
+		}
+		a.started = append(a.started, c)
+	}
+	a.running = true
+
+	a.logger.Info("app started", zap.Duration("duration", time.Since(begin)))
+	return nil
+}
+
+// Stop shuts every started component down in reverse order within ctx and
+// returns the combined errors. Calling Stop more than once is a no-op.
+func (a *App) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.running {
+		return nil
+	}
+	a.running = false
+	a.stopped = true
+
+	a.logger.Info("app stopping")
+	return a.stopStarted(ctx)
+}
+
+func (a *App) stopStarted(ctx context.Context) error {
+	var errs []error
+	for _, c := range slices.Backward(a.started) {
+		if c.Stop == nil {
+			continue
+		}
+		if err := c.Stop(ctx); err != nil {
+			a.logger.Error("component stop failed", zap.String("component", c.Name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("stop %s: %w", c.Name, err))
+		}
+	}
+	a.started = nil
+	return errors.Join(errs...)
+}
+
+// Run starts the app, blocks until ctx is done, a shutdown signal arrives
+// (SIGINT/SIGTERM by default) or the HTTP server fails, then stops it within
+// the drain timeout.
+func (a *App) Run(ctx context.Context) error {
+	// Listen before starting so a signal during startup is not lost.
+	sigCtx, stop := signal.NotifyContext(ctx, a.signals...)
+	defer stop()
+
+	if err := a.Start(sigCtx); err != nil {
+		return err
+	}
+
+	var runErr error
+	select {
+	case <-sigCtx.Done():
+		a.logger.Info("shutdown requested", zap.Duration("drain_timeout", a.drainTimeout))
+	case runErr = <-a.serveErr:
+		a.logger.Error("http server failed", zap.Error(runErr))
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), a.drainTimeout)
+	defer cancel()
+	return errors.Join(runErr, a.Stop(stopCtx))
+}
+
+// Addr returns the address the HTTP server is listening on, or "" before Start.
+func (a *App) Addr() string {
+	addr, _ := a.listenAddr.Load().(string)
+	return addr
+}
+
+// Router returns the root router; plugins mount their routes on it too.
+func (a *App) Router() chi.Router { return a.router }
+
+// Logger returns the application logger.
+func (a *App) Logger() *zap.Logger { return a.logger }
+
+// Runtime returns the plugin runtime.
+func (a *App) Runtime() *runtime.Runtime { return a.runtime }
+
+// Services returns the service registry shared with plugins.
+func (a *App) Services() *plugin.ServiceRegistry { return a.runtime.Services() }
+
+// Sections returns the config sections decoded by New, or nil without WithConfig.
+func (a *App) Sections() *config.Sections { return a.sections }
+
+// Health returns the health registry served at /healthz and /readyz.
+func (a *App) Health() *health.Registry { return a.health }
+
+// Auth returns the auth core once started, or nil when auth is not enabled.
+func (a *App) Auth() *auth.AuthCore { return a.authCore }
@@ -0,0 +1,335 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/cache"
+	"github.com/leeforge/framework/config"
+	"github.com/leeforge/framework/ent"
+	"github.com/leeforge/framework/plugin"
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+)
+
+type pingPlugin struct{ recorder *recorder }
+
+func (p *pingPlugin) Name() string           { return "ping" }
+func (p *pingPlugin) Version() string        { return "1.0.0" }
+func (p *pingPlugin) Dependencies() []string { return nil }
+func (p *pingPlugin) Enable(context.Context, *plugin.AppContext) error {
+	p.recorder.add("plugin:enable")
+	return nil
+}
+func (p *pingPlugin) Disable(context.Context, *plugin.AppContext) error {
+	p.recorder.add("plugin:disable")
+	return nil
+}
+func (p *pingPlugin) Routes(r chi.Router) {
+	r.Get("/", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("pong")) })
+}
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(e string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+func (r *recorder) hook(e string) Hook {
+	return func(context.Context) error { r.add(e); return nil }
+}
+
+func (r *recorder) component(name string) Component {
+	return Component{
+		Name:  name,
+		Start: func(context.Context) error { r.add(name + ":start"); return nil },
+		Stop:  func(context.Context) error { r.add(name + ":stop"); return nil },
+	}
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestApp_OrderedStartAndStop(t *testing.T) {
+	rec := &recorder{}
+	a, err := New(
+		WithLogger(zap.NewNop()),
+		WithAddr("127.0.0.1:0"),
+		WithPlugins(&pingPlugin{recorder: rec}),
+		WithComponent(rec.component("cache")),
+		WithComponent(rec.component("queue")),
+		OnStart(rec.hook("start:1")),
+		OnStart(rec.hook("start:2")),
+		OnStop(rec.hook("stop:1")),
+		OnStop(rec.hook("stop:2")),
+	)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if code, body := get(t, "http://"+a.Addr()+"/ping/"); code != http.StatusOK || body != "pong" {
+		t.Errorf("GET /ping/ = %d %q", code, body)
+	}
+	if logger, err := plugin.Resolve[*zap.Logger](a.Services(), ServiceLogger); err != nil || logger == nil {
+		t.Errorf("logger service not registered: %v", err)
+	}
+
+	if err := a.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if err := a.Stop(context.Background()); err != nil {
+		t.Errorf("second Stop = %v, want no-op", err)
+	}
+
+	want := []string{
+		"cache:start", "queue:start", "plugin:enable", "start:1", "start:2",
+		"stop:2", "stop:1", "plugin:disable", "queue:stop", "cache:stop",
+	}
+	if got := rec.list(); !slices.Equal(got, want) {
+		t.Errorf("lifecycle order\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestApp_StartFailureRollsBack(t *testing.T) {
+	rec := &recorder{}
+	a, err := New(
+		WithLogger(zap.NewNop()),
+		WithAddr("127.0.0.1:0"),
+		WithComponent(rec.component("db")),
+		WithComponent(Component{
+			Name:  "broken",
+			Start: func(context.Context) error { return errors.New("boom") },
+		}),
+		OnStart(rec.hook("start")),
+	)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	err = a.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "start broken: boom") {
+		t.Fatalf("Start = %v, want broken component error", err)
+	}
+	if got := rec.list(); !slices.Equal(got, []string{"db:start", "db:stop"}) {
+		t.Errorf("events = %v, want started components stopped", got)
+	}
+	if a.Addr() != "" {
+		t.Error("HTTP server must not start after a failed component")
+	}
+	if err := a.Start(context.Background()); err == nil {
+		t.Error("restarting a failed app should be rejected")
+	}
+}
+
+func TestApp_RunDrainsInFlightRequestsOnSignal(t *testing.T) {
+	router := chi.NewRouter()
+	entered := make(chan struct{})
+	router.Get("/slow", func(w http.ResponseWriter, _ *http.Request) {
+		close(entered)
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	started := make(chan struct{})
+	a, err := New(
+		WithLogger(zap.NewNop()),
+		WithRouter(router),
+		WithAddr("127.0.0.1:0"),
+		WithSignals(syscall.SIGUSR1),
+		WithDrainTimeout(5*time.Second),
+		OnStart(func(context.Context) error { close(started); return nil }),
+	)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- a.Run(context.Background()) }()
+	<-started
+
+	type result struct {
+		code int
+		body string
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		// Addr is set right after OnStart hooks; wait for the listener.
+		for a.Addr() == "" {
+			time.Sleep(time.Millisecond)
+		}
+		resp, err := http.Get("http://" + a.Addr() + "/slow")
+		if err != nil {
+			resCh <- result{body: err.Error()}
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		resCh <- result{resp.StatusCode, string(body)}
+	}()
+
+	<-entered
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("Run = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after signal")
+	}
+	if res := <-resCh; res.code != http.StatusOK || res.body != "done" {
+		t.Errorf("in-flight request = %d %q, want drained", res.code, res.body)
+	}
+}
+
+func TestNew_RejectsInvalidConfigSections(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("lgo:\n  level: debug\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.NewConfig(config.ConfigOptions{BasePath: dir, FileName: "config", FileType: "yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = New(WithConfig(cfg), WithLogger(zap.NewNop()))
+	var serr *config.SectionsError
+	if !errors.As(err, &serr) || !slices.Contains(serr.Unknown, "lgo") {
+		t.Fatalf("New = %v, want unknown section error", err)
+	}
+}
+
+type unhealthyPlugin struct{ pingPlugin }
+
+func (p *unhealthyPlugin) Name() string                      { return "unhealthy" }
+func (p *unhealthyPlugin) HealthCheck(context.Context) error { return errors.New("upstream down") }
+
+func TestApp_PluginHealthChecksServeReadyz(t *testing.T) {
+	a, err := New(
+		WithLogger(zap.NewNop()),
+		WithAddr("127.0.0.1:0"),
+		WithPlugins(&unhealthyPlugin{pingPlugin{recorder: &recorder{}}}),
+	)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer a.Stop(context.Background())
+
+	code, body := get(t, "http://"+a.Addr()+"/readyz")
+	if code != http.StatusServiceUnavailable || !strings.Contains(body, "plugin:unhealthy") {
+		t.Errorf("GET /readyz = %d %s", code, body)
+	}
+	if code, _ := get(t, "http://"+a.Addr()+"/healthz"); code != http.StatusOK {
+		t.Errorf("GET /healthz = %d, want 200", code)
+	}
+}
+
+func newStorage(t *testing.T) (*sql.DB, *ent.Client, *redis.Client) {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:app-"+uuid.NewString()+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	client := ent.NewClient(ent.Driver(entsql.OpenDB(dialect.SQLite, db)))
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	return db, client, rdb
+}
+
+func TestApp_StopClosesStorage(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		component Component
+	}{
+		{name: "stop"},
+		{name: "failed start", component: Component{
+			Name:  "broken",
+			Start: func(context.Context) error { return errors.New("boom") },
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, client, rdb := newStorage(t)
+			opts := []Option{
+				WithLogger(zap.NewNop()),
+				WithAddr("127.0.0.1:0"),
+				WithEnt(client),
+				WithRedis(rdb),
+			}
+			if tc.component.Name != "" {
+				opts = append(opts, WithComponent(tc.component))
+			}
+			a, err := New(opts...)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+
+			mlc, err := plugin.Resolve[*cache.MultiLevelCache](a.Services(), ServiceCache)
+			if err != nil {
+				t.Fatalf("cache service not registered: %v", err)
+			}
+			if err := mlc.Set(context.Background(), "k", "v"); err != nil {
+				t.Fatalf("cache Set over redis: %v", err)
+			}
+
+			if err := a.Start(context.Background()); err == nil {
+				if err := a.Stop(context.Background()); err != nil {
+					t.Fatalf("Stop failed: %v", err)
+				}
+			} else if tc.component.Name == "" {
+				t.Fatalf("Start failed: %v", err)
+			}
+
+			if err := db.Ping(); err == nil || !strings.Contains(err.Error(), "closed") {
+				t.Errorf("ent client left open: Ping = %v", err)
+			}
+			if err := rdb.Ping(context.Background()).Err(); !errors.Is(err, redis.ErrClosed) {
+				t.Errorf("redis client left open: Ping = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redis/v8"
+	"github.com/leeforge/framework/auth"
+	"github.com/leeforge/framework/cache"
+	"github.com/leeforge/framework/config"
+	"github.com/leeforge/framework/ent"
+	"github.com/leeforge/framework/health"
+	"github.com/leeforge/framework/http/server"
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/plugin"
+	"github.com/leeforge/framework/tracing"
+	"go.uber.org/zap"
+)
+
+// Option configures an App.
+type Option func(*App)
+
+// WithConfig loads and validates every registered config section at New
+// and makes cfg available to ConfigPlugin plugins. Without WithLogger the
+// logger is built from the "log" section.
+func WithConfig(cfg *config.Config, opts ...config.SectionsOption) Option {
+	return func(a *App) {
+		a.cfg = cfg
+		a.sectionOpts = append(a.sectionOpts, opts...)
+	}
+}
+
+// WithLogger sets the application logger.
+func WithLogger(logger *zap.Logger) Option {
+	return func(a *App) { a.logger = logger }
+}
+
+// WithMetrics records HTTP metrics for every request and serves the
+// collector at path ("/metrics" when empty).
+func WithMetrics(collector *metrics.Collector, path string) Option {
+	return func(a *App) {
+		a.collector = collector
+		if path != "" {
+			a.metricsPath = path
+		}
+	}
+}
+
+// WithTracer enables event consumer spans and shuts the tracer down last.
+func WithTracer(tracer *tracing.Tracer) Option {
+	return func(a *App) { a.tracer = tracer }
+}
+
+// WithAuth runs auth.Setup during startup and registers the result as
+// ServiceAuth. cfg.Logger defaults to the app logger.
+func WithAuth(cfg auth.Config) Option {
+	return func(a *App) { a.authCfg = &cfg }
+}
+
+// WithEnt hands client to plugins as their database and registers it as
+// ServiceEnt. The app closes client on shutdown, also when startup fails.
+func WithEnt(client *ent.Client) Option {
+	return func(a *App) { a.entClient = client }
+}
+
+// WithRedis hands client to plugins for caching. Without WithCache it also
+// backs the ServiceCache cache as its L2. The app closes client on shutdown,
+// also when startup fails.
+func WithRedis(client *redis.Client) Option {
+	return func(a *App) { a.redis = client }
+}
+
+// WithCache registers c as ServiceCache in place of the cache built over the
+// WithRedis client, and closes it on shutdown.
+func WithCache(c *cache.MultiLevelCache) Option {
+	return func(a *App) { a.cache = c }
+}
+
+// WithHealth replaces the default health registry, which logs through the
+// app logger and exports gauges to the WithMetrics collector.
+func WithHealth(registry *health.Registry) Option {
+	return func(a *App) { a.health = registry }
+}
+
+// WithRouter replaces the default router (router.New), which answers
+// OPTIONS and HEAD automatically and replies 405 with an Allow header.
+func WithRouter(router chi.Router) Option {
+	return func(a *App) { a.router = router }
+}
+
+// WithAddr sets the HTTP listen address (default ":8080").
+func WithAddr(addr string) Option {
+	return func(a *App) { a.addr = addr }
+}
+
+// WithServerConfig sets HTTP timeouts, TLS, readiness and endpoint paths.
+// Addr defaults to WithAddr, Logger to the app logger, and Metrics to the
+// collector given to WithMetrics.
+func WithServerConfig(cfg server.Config) Option {
+	return func(a *App) { a.serverCfg = cfg }
+}
+
+// WithDrainTimeout bounds how long Run waits for in-flight requests and
+// component shutdown after a signal (default 30s).
+func WithDrainTimeout(d time.Duration) Option {
+	return func(a *App) {
+		if d > 0 {
+			a.drainTimeout = d
+		}
+	}
+}
+
+// WithSignals replaces the signals that trigger shutdown in Run.
+func WithSignals(signals ...os.Signal) Option {
+	return func(a *App) { a.signals = signals }
+}
+
+// WithPlugins registers plugins with the runtime.
+func WithPlugins(plugins ...plugin.Plugin) Option {
+	return func(a *App) { a.plugins = append(a.plugins, plugins...) }
+}
+
+// WithComponent adds a component started after auth and before plugins.
+func WithComponent(c Component) Option {
+	return func(a *App) { a.components = append(a.components, c) }
+}
+
+// OnStart adds a hook run after plugins are bootstrapped and before the
+// HTTP server accepts traffic. Hooks run in the order added.
+func OnStart(hook Hook) Option {
+	return func(a *App) { a.onStart = append(a.onStart, hook) }
+}
+
+// OnStop adds a hook run after the HTTP server has drained and before
+// plugins shut down. Hooks run in reverse order, like defer.
+func OnStop(hook Hook) Option {
+	return func(a *App) { a.onStop = append(a.onStop, hook) }
+}
@@ -0,0 +1,481 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/security"
+	"go.uber.org/zap"
+)
+
+// MetadataRotatedFrom 轮换生成的新 Key 在 Metadata 中记录旧 Key ID
+const MetadataRotatedFrom = "rotated_from"
+
+// APIKeyPolicy 组织级 API Key 策略
+type APIKeyPolicy struct {
+	MaxKeys         int           // 组织内有效 Key 数量上限，0 表示不限
+	MaxTTL          time.Duration // 有效期上限，0 表示不限；设置后不允许永不过期的 Key
+	RequireExpiry   bool          // 是否必须设置有效期
+	DefaultTTL      time.Duration // 请求未指定有效期时使用
+	RotationOverlap time.Duration // 轮换时旧 Key 的最长保留时间，默认 24 小时
+}
+
+// APIKeyServiceConfig API Key 自助服务配置
+type APIKeyServiceConfig struct {
+	Domain        string                  // RBAC 域，默认 "platform"
+	DefaultPolicy APIKeyPolicy            // 未单独配置的组织使用该策略
+	OrgPolicies   map[string]APIKeyPolicy // 按组织覆盖策略
+	// OrgOf 从请求解析调用方所属组织，默认取数据过滤条件中的 tenant_id
+	OrgOf func(r *http.Request) string
+}
+
+// APIKeyCaller 发起自助操作的调用方
+type APIKeyCaller struct {
+	UserID string
+	OrgID  string
+	// Key 调用方通过 API Key 认证时非空，新 Key 的权限不能超出该 Key
+	Key *APIKeyInfo
+}
+
+// CreateAPIKeyRequest 创建 API Key 请求
+type CreateAPIKeyRequest struct {
+	Name      string       `json:"name"`
+	Scopes    []Permission `json:"scopes"`
+	ExpiresIn int64        `json:"expires_in,omitempty"` // 有效期（秒），0 使用策略默认值
+}
+
+// RotateAPIKeyRequest 轮换 API Key 请求
+type RotateAPIKeyRequest struct {
+	Overlap int64 `json:"overlap,omitempty"` // 旧 Key 继续有效的时长（秒），0 使用策略值
+}
+
+// CreatedAPIKey 创建或轮换的结果，Key 明文只在此返回一次
+type CreatedAPIKey struct {
+	Key string `json:"key"`
+	*security.StoredAPIKey
+}
+
+// APIKeyService API Key 自助服务
+// 调用方只能为自己签发权限不超过自身的 Key，并受组织策略约束
+type APIKeyService struct {
+	manager *security.APIKeyManager
+	repo    security.APIKeyAdminRepository
+	rbac    RBACManager
+	config  APIKeyServiceConfig
+	logger  *zap.Logger
+
+	// mu 串行化配额检查与签发，避免并发创建或轮换突破 MaxKeys
+	mu sync.Mutex
+}
+
+// NewAPIKeyService 创建 API Key 自助服务
+func NewAPIKeyService(
+	manager *security.APIKeyManager,
+	repo security.APIKeyAdminRepository,
+	rbacManager RBACManager,
+	config APIKeyServiceConfig,
+	logger *zap.Logger,
+) *APIKeyService {
+	if config.Domain == "" {
+		config.Domain = "platform"
+	}
+	if config.OrgOf == nil {
+		config.OrgOf = orgFromRequest
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &APIKeyService{
+		manager: manager,
+		repo:    repo,
+		rbac:    rbacManager,
+		config:  config,
+		logger:  logger,
+	}
+}
+
+// Policy 返回组织生效的策略
+func (s *APIKeyService) Policy(orgID string) APIKeyPolicy {
+	policy, ok := s.config.OrgPolicies[orgID]
+	if !ok {
+		policy = s.config.DefaultPolicy
+	}
+	if policy.RotationOverlap <= 0 {
+		policy.RotationOverlap = 24 * time.Hour
+	}
+	return policy
+}
+
+// Create 为调用方签发新 Key
+func (s *APIKeyService) Create(ctx context.Context, caller APIKeyCaller, req CreateAPIKeyRequest) (*CreatedAPIKey, error) {
+	if caller.UserID == "" {
+		return nil, errors.NewUnauthorized("User not authenticated")
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.NewRequired("name")
+	}
+	if req.ExpiresIn < 0 {
+		return nil, errors.NewInvalid("expires_in", req.ExpiresIn, "must not be negative")
+	}
+	scopes, err := s.authorizeScopes(ctx, caller, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := s.Policy(caller.OrgID)
+	ttl, err := policyTTL(policy, time.Duration(req.ExpiresIn)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if policy.MaxKeys > 0 {
+		active, err := s.activeKeys(ctx, security.APIKeyFilter{OrgID: caller.OrgID})
+		if err != nil {
+			return nil, err
+		}
+		if active >= policy.MaxKeys {
+			return nil, keyLimitError(policy.MaxKeys)
+		}
+	}
+
+	plaintext, key, err := s.manager.Issue(ctx, security.APIKeyIssueOptions{
+		Name:    req.Name,
+		OwnerID: caller.UserID,
+		OrgID:   caller.OrgID,
+		Scopes:  scopes,
+		TTL:     ttl,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("API key created",
+		zap.String("key_id", key.ID),
+		zap.String("owner_id", key.OwnerID),
+		zap.String("org_id", key.OrgID),
+		zap.Strings("scopes", key.Scopes),
+	)
+	return &CreatedAPIKey{Key: plaintext, StoredAPIKey: key}, nil
+}
+
+// List 列出调用方自己的 Key（不含明文）
+func (s *APIKeyService) List(ctx context.Context, caller APIKeyCaller) ([]*security.StoredAPIKey, error) {
+	if caller.UserID == "" {
+		return nil, errors.NewUnauthorized("User not authenticated")
+	}
+	return s.repo.List(ctx, security.APIKeyFilter{OwnerID: caller.UserID, OrgID: caller.OrgID})
+}
+
+// Revoke 吊销调用方自己的 Key
+func (s *APIKeyService) Revoke(ctx context.Context, caller APIKeyCaller, id string) error {
+	key, err := s.owned(ctx, caller, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Revoke(ctx, key.Lookup); err != nil {
+		return err
+	}
+	s.logger.Info("API key revoked", zap.String("key_id", key.ID), zap.String("owner_id", key.OwnerID))
+	return nil
+}
+
+// Rotate 签发替代 Key，旧 Key 在重叠期内继续有效
+// 新 Key 沿用名称与权限范围，并按当前权限重新校验，不会保留调用方已失去的权限
+// 每个 Key 只能轮换一次；配额检查与 Create 在同一把锁下进行，旧 Key 不计入配额
+func (s *APIKeyService) Rotate(ctx context.Context, caller APIKeyCaller, id string, req RotateAPIKeyRequest) (*CreatedAPIKey, error) {
+	old, err := s.owned(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !old.Active(now) {
+		return nil, errors.NewBusiness("API key is revoked or expired").WithCode("API_KEY_INACTIVE")
+	}
+
+	policy := s.Policy(old.OrgID)
+	overlap := time.Duration(req.Overlap) * time.Second
+	switch {
+	case req.Overlap < 0:
+		return nil, errors.NewInvalid("overlap", req.Overlap, "must not be negative")
+	case overlap == 0:
+		overlap = policy.RotationOverlap
+	case overlap > policy.RotationOverlap:
+		return nil, errors.NewInvalid("overlap", req.Overlap, fmt.Sprintf("must not exceed %d seconds", int64(policy.RotationOverlap/time.Second)))
+	}
+
+	scopes, err := s.authorizeScopes(ctx, caller, parseScopes(old.Scopes))
+	if err != nil {
+		return nil, err
+	}
+	// 保持原有效期长度，永不过期的 Key 按当前策略处理
+	var ttl time.Duration
+	if !old.ExpiresAt.IsZero() {
+		ttl = old.ExpiresAt.Sub(old.CreatedAt)
+	}
+	if ttl, err = policyTTL(policy, ttl); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := s.repo.List(ctx, security.APIKeyFilter{OrgID: old.OrgID})
+	if err != nil {
+		return nil, err
+	}
+	active := 0
+	for _, k := range keys {
+		if k.Metadata[MetadataRotatedFrom] == old.ID {
+			return nil, errors.New(errors.ErrorTypeConflict, "API key has already been rotated").
+				WithCode("API_KEY_ALREADY_ROTATED").
+				WithDetail("rotated_to", k.ID).
+				WithHTTPStatus(http.StatusConflict)
+		}
+		// 旧 Key 即将被替代，不计入配额
+		if k.ID != old.ID && k.Active(now) {
+			active++
+		}
+	}
+	if policy.MaxKeys > 0 && active >= policy.MaxKeys {
+		return nil, keyLimitError(policy.MaxKeys)
+	}
+
+	plaintext, key, err := s.manager.Issue(ctx, security.APIKeyIssueOptions{
+		Name:     old.Name,
+		OwnerID:  old.OwnerID,
+		OrgID:    old.OrgID,
+		Scopes:   scopes,
+		TTL:      ttl,
+		Metadata: map[string]string{MetadataRotatedFrom: old.ID},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	oldExpiry := now.Add(overlap)
+	if old.ExpiresAt.IsZero() || oldExpiry.Before(old.ExpiresAt) {
+		if err := s.repo.SetExpiry(ctx, old.Lookup, oldExpiry); err != nil {
+			return nil, err
+		}
+	}
+	s.logger.Info("API key rotated",
+		zap.String("key_id", key.ID),
+		zap.String("rotated_from", old.ID),
+		zap.Duration("overlap", overlap),
+	)
+	return &CreatedAPIKey{Key: plaintext, StoredAPIKey: key}, nil
+}
+
+// GetByKey 实现 APIKeyStore，使自助签发的 Key 可直接用于 AuthMiddleware
+func (s *APIKeyService) GetByKey(ctx context.Context, key string) (*APIKeyInfo, error) {
+	stored, err := s.manager.Verify(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	info := &APIKeyInfo{
+		Key:         stored.ID,
+		CreatedBy:   stored.OwnerID,
+		ExpiredAt:   stored.ExpiresAt,
+		Permissions: parseScopes(stored.Scopes),
+	}
+	if stored.OrgID != "" {
+		info.DataFilters = map[string]interface{}{"tenant_id": stored.OrgID}
+	}
+	return info, nil
+}
+
+// Validate 实现 APIKeyStore
+func (s *APIKeyService) Validate(ctx context.Context, key string) error {
+	_, err := s.manager.Verify(ctx, key)
+	return err
+}
+
+// authorizeScopes 校验请求的权限范围不超出调用方：
+// 调用方必须拥有每一项权限，通过 API Key 认证时还必须在该 Key 的范围内
+func (s *APIKeyService) authorizeScopes(ctx context.Context, caller APIKeyCaller, requested []Permission) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, errors.NewRequired("scopes")
+	}
+	isSuper, _ := ctx.Value("is_super_admin").(bool)
+
+	scopes := make([]string, 0, len(requested))
+	seen := make(map[string]bool, len(requested))
+	for _, p := range requested {
+		if p.Resource == "" || p.Action == "" {
+			return nil, errors.NewInvalid("scopes", p, "resource and action are required")
+		}
+		scope := p.Resource + ":" + p.Action
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+
+		if caller.Key != nil && !keyAllows(caller.Key, p) {
+			return nil, errors.NewForbidden("Scope exceeds the calling API key: "+scope).WithDetail("scope", scope)
+		}
+		if !isSuper {
+			allowed, err := s.rbac.CheckPermission(ctx, caller.UserID, s.config.Domain, p.Resource, p.Action)
+			if err != nil {
+				return nil, errors.WrapWithType(err, errors.ErrorTypeInternal, "permission check failed")
+			}
+			if !allowed {
+				return nil, errors.NewForbidden("Scope exceeds caller permissions: "+scope).WithDetail("scope", scope)
+			}
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+// owned 获取调用方拥有的 Key，不属于调用方时与不存在一样返回 404
+func (s *APIKeyService) owned(ctx context.Context, caller APIKeyCaller, id string) (*security.StoredAPIKey, error) {
+	if caller.UserID == "" {
+		return nil, errors.NewUnauthorized("User not authenticated")
+	}
+	key, err := s.repo.FindByLookup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.OwnerID != caller.UserID || key.OrgID != caller.OrgID {
+		return nil, errors.NewNotFound("api_key", id)
+	}
+	return key, nil
+}
+
+func (s *APIKeyService) activeKeys(ctx context.Context, filter security.APIKeyFilter) (int, error) {
+	keys, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	active := 0
+	for _, key := range keys {
+		if key.Active(now) {
+			active++
+		}
+	}
+	return active, nil
+}
+
+func keyLimitError(maxKeys int) error {
+	return errors.New(errors.ErrorTypeConflict, fmt.Sprintf("organization has reached the limit of %d active API keys", maxKeys)).
+		WithCode("API_KEY_LIMIT_REACHED").
+		WithDetail("max_keys", maxKeys).
+		WithHTTPStatus(http.StatusConflict)
+}
+
+// policyTTL 按策略确定有效期，requested 为 0 时使用默认值
+func policyTTL(policy APIKeyPolicy, requested time.Duration) (time.Duration, error) {
+	ttl := requested
+	if ttl == 0 {
+		ttl = policy.DefaultTTL
+	}
+	if ttl == 0 && (policy.RequireExpiry || policy.MaxTTL > 0) {
+		return 0, errors.NewRequired("expires_in")
+	}
+	if policy.MaxTTL > 0 && ttl > policy.MaxTTL {
+		return 0, errors.NewInvalid("expires_in", int64(requested/time.Second),
+			fmt.Sprintf("must not exceed %d seconds", int64(policy.MaxTTL/time.Second)))
+	}
+	return ttl, nil
+}
+
+func keyAllows(info *APIKeyInfo, p Permission) bool {
+	for _, granted := range info.Permissions {
+		if (granted.Resource == "*" || granted.Resource == p.Resource) &&
+			(granted.Action == "*" || granted.Action == p.Action) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseScopes(scopes []string) []Permission {
+	perms := make([]Permission, 0, len(scopes))
+	for _, scope := range scopes {
+		resource, action, _ := strings.Cut(scope, ":")
+		perms = append(perms, Permission{Resource: resource, Action: action})
+	}
+	return perms
+}
+
+// orgFromRequest 默认组织解析：取数据过滤条件中的 tenant_id
+func orgFromRequest(r *http.Request) string {
+	_, keyInfo, filters := GetUserInfoFromContext(r.Context())
+	if filters == nil && keyInfo != nil {
+		filters = keyInfo.DataFilters
+	}
+	org, _ := filters["tenant_id"].(string)
+	return org
+}
+
+func (s *APIKeyService) caller(r *http.Request) APIKeyCaller {
+	userID, keyInfo, _ := GetUserInfoFromContext(r.Context())
+	return APIKeyCaller{UserID: userID, OrgID: s.config.OrgOf(r), Key: keyInfo}
+}
+
+// handleCreate POST /api-keys
+func (s *APIKeyService) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, r, errors.NewValidation("Invalid request body"))
+		return
+	}
+	created, err := s.Create(r.Context(), s.caller(r), req)
+	if err != nil {
+		response.WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	response.WriteJSON(w, r, http.StatusCreated, created)
+}
+
+// handleList GET /api-keys
+func (s *APIKeyService) handleList(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.List(r.Context(), s.caller(r))
+	if err != nil {
+		response.WriteError(w, r, err)
+		return
+	}
+	if keys == nil {
+		keys = []*security.StoredAPIKey{}
+	}
+	response.WriteJSON(w, r, http.StatusOK, keys)
+}
+
+// handleRevoke DELETE /api-keys/{id}
+func (s *APIKeyService) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := s.Revoke(r.Context(), s.caller(r), chi.URLParam(r, "id")); err != nil {
+		response.WriteError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRotate POST /api-keys/{id}/rotate
+func (s *APIKeyService) handleRotate(w http.ResponseWriter, r *http.Request) {
+	var req RotateAPIKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.WriteError(w, r, errors.NewValidation("Invalid request body"))
+			return
+		}
+	}
+	created, err := s.Rotate(r.Context(), s.caller(r), chi.URLParam(r, "id"), req)
+	if err != nil {
+		response.WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	response.WriteJSON(w, r, http.StatusCreated, created)
+}
@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/security"
+)
+
+// fakeRBAC 按 "user|resource:action" 授权
+type fakeRBAC map[string]bool
+
+func (f fakeRBAC) CheckPermission(_ context.Context, userUUID, _, resource, action string) (bool, error) {
+	return f[userUUID+"|"+resource+":"+action], nil
+}
+
+func newTestAPIKeyService(t *testing.T, rbac fakeRBAC, config APIKeyServiceConfig) (*APIKeyService, *security.MemoryAPIKeyRepository) {
+	t.Helper()
+	repo := security.NewMemoryAPIKeyRepository()
+	manager, err := security.NewAPIKeyManager(security.APIKeyManagerConfig{Prefix: "lf_test", Pepper: "pepper"}, repo)
+	if err != nil {
+		t.Fatalf("NewAPIKeyManager: %v", err)
+	}
+	return NewAPIKeyService(manager, repo, rbac, config, nil), repo
+}
+
+// statusOf 返回错误对应的 HTTP 状态码
+func statusOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	status, _ := response.FromError(err)
+	return status
+}
+
+func TestAPIKeyService_NoPrivilegeEscalation(t *testing.T) {
+	svc, _ := newTestAPIKeyService(t, fakeRBAC{"u1|article:read": true}, APIKeyServiceConfig{})
+	ctx := context.Background()
+	caller := APIKeyCaller{UserID: "u1", OrgID: "acme"}
+
+	created, err := svc.Create(ctx, caller, CreateAPIKeyRequest{
+		Name:   "ci",
+		Scopes: []Permission{{Resource: "article", Action: "read"}},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.Key == "" || strings.Join(created.Scopes, ",") != "article:read" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	_, err = svc.Create(ctx, caller, CreateAPIKeyRequest{
+		Name:   "admin",
+		Scopes: []Permission{{Resource: "article", Action: "delete"}},
+	})
+	if statusOf(err) != http.StatusForbidden {
+		t.Fatalf("scope beyond caller permissions: err = %v", err)
+	}
+
+	// 通过 Key 认证的调用方不能签发超出该 Key 的权限，即使用户本身拥有
+	svc.rbac = fakeRBAC{"u1|article:read": true, "u1|article:write": true}
+	caller.Key = &APIKeyInfo{Permissions: []Permission{{Resource: "article", Action: "read"}}}
+	_, err = svc.Create(ctx, caller, CreateAPIKeyRequest{
+		Name:   "write",
+		Scopes: []Permission{{Resource: "article", Action: "write"}},
+	})
+	if statusOf(err) != http.StatusForbidden {
+		t.Fatalf("scope beyond calling key: err = %v", err)
+	}
+}
+
+func TestAPIKeyService_Policy(t *testing.T) {
+	svc, _ := newTestAPIKeyService(t, fakeRBAC{"u1|article:read": true}, APIKeyServiceConfig{
+		OrgPolicies: map[string]APIKeyPolicy{
+			"acme": {MaxKeys: 2, MaxTTL: 30 * 24 * time.Hour, RequireExpiry: true},
+		},
+	})
+	ctx := context.Background()
+	caller := APIKeyCaller{UserID: "u1", OrgID: "acme"}
+	scopes := []Permission{{Resource: "article", Action: "read"}}
+
+	if _, err := svc.Create(ctx, caller, CreateAPIKeyRequest{Name: "k", Scopes: scopes}); statusOf(err) != http.StatusBadRequest {
+		t.Fatalf("missing expiry: err = %v", err)
+	}
+	if _, err := svc.Create(ctx, caller, CreateAPIKeyRequest{Name: "k", Scopes: scopes, ExpiresIn: 31 * 86400}); statusOf(err) != http.StatusBadRequest {
+		t.Fatalf("ttl above max: err = %v", err)
+	}
+
+	var first *CreatedAPIKey
+	for i := 0; i < 2; i++ {
+		created, err := svc.Create(ctx, caller, CreateAPIKeyRequest{Name: "k", Scopes: scopes, ExpiresIn: 86400})
+		if err != nil {
+			t.Fatalf("Create #%d: %v", i, err)
+		}
+		if first == nil {
+			first = created
+		}
+	}
+	if _, err := svc.Create(ctx, caller, CreateAPIKeyRequest{Name: "k", Scopes: scopes, ExpiresIn: 86400}); statusOf(err) != http.StatusConflict {
+		t.Fatalf("above max keys: err = %v", err)
+	}
+
+	// 吊销后释放配额
+	if err := svc.Revoke(ctx, caller, first.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := svc.Create(ctx, caller, CreateAPIKeyRequest{Name: "k", Scopes: scopes, ExpiresIn: 86400}); err != nil {
+		t.Fatalf("Create after revoke: %v", err)
+	}
+
+	// 其他组织使用默认策略
+	other := APIKeyCaller{UserID: "u1", OrgID: "other"}
+	created, err := svc.Create(ctx, other, CreateAPIKeyRequest{Name: "k", Scopes: scopes})
+	if err != nil || !created.ExpiresAt.IsZero() {
+		t.Fatalf("default policy: created = %+v, err = %v", created, err)
+	}
+}
+
+func TestAPIKeyService_RotateWithOverlap(t *testing.T) {
+	svc, _ := newTestAPIKeyService(t, fakeRBAC{"u1|article:read": true}, APIKeyServiceConfig{
+		DefaultPolicy: APIKeyPolicy{RotationOverlap: time.Hour},
+	})
+	ctx := context.Background()
+	caller := APIKeyCaller{UserID: "u1"}
+
+	old, err := svc.Create(ctx, caller, CreateAPIKeyRequest{
+		Name:   "ci",
+		Scopes: []Permission{{Resource: "article", Action: "read"}},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err := svc.Rotate(ctx, caller, old.ID, RotateAPIKeyRequest{Overlap: 7200}); statusOf(err) != http.StatusBadRequest {
+		t.Fatalf("overlap above policy: err = %v", err)
+	}
+	if _, err := svc.Rotate(ctx, APIKeyCaller{UserID: "u2"}, old.ID, RotateAPIKeyRequest{}); statusOf(err) != http.StatusNotFound {
+		t.Fatalf("rotate foreign key: err = %v", err)
+	}
+
+	rotated, err := svc.Rotate(ctx, caller, old.ID, RotateAPIKeyRequest{Overlap: 600})
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if rotated.Metadata[MetadataRotatedFrom] != old.ID || rotated.Name != "ci" {
+		t.Fatalf("rotated = %+v", rotated)
+	}
+
+	if _, err := svc.Rotate(ctx, caller, old.ID, RotateAPIKeyRequest{}); statusOf(err) != http.StatusConflict {
+		t.Fatalf("rotate an already rotated key: err = %v", err)
+	}
+
+	// 重叠期内新旧 Key 均有效
+	for _, key := range []string{old.Key, rotated.Key} {
+		if _, err := svc.GetByKey(ctx, key); err != nil {
+			t.Fatalf("GetByKey during overlap: %v", err)
+		}
+	}
+	keys, err := svc.List(ctx, caller)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("List = %v, %v", keys, err)
+	}
+	if until := time.Until(keys[0].ExpiresAt); until <= 0 || until > 10*time.Minute {
+		t.Fatalf("old key expires in %v, want within the overlap", until)
+	}
+
+	// 用户失去权限后不能再轮换
+	svc.rbac = fakeRBAC{}
+	if _, err := svc.Rotate(ctx, caller, rotated.ID, RotateAPIKeyRequest{}); statusOf(err) != http.StatusForbidden {
+		t.Fatalf("rotate after permission loss: err = %v", err)
+	}
+}
+
+func TestAPIKeyService_RotateRespectsMaxKeys(t *testing.T) {
+	svc, _ := newTestAPIKeyService(t, fakeRBAC{"u1|article:read": true}, APIKeyServiceConfig{
+		DefaultPolicy: APIKeyPolicy{MaxKeys: 2, RotationOverlap: time.Hour},
+	})
+	ctx := context.Background()
+	caller := APIKeyCaller{UserID: "u1", OrgID: "org1"}
+	req := CreateAPIKeyRequest{Name: "ci", Scopes: []Permission{{Resource: "article", Action: "read"}}}
+
+	first, err := svc.Create(ctx, caller, req)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := svc.Create(ctx, caller, req); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 被轮换的 Key 不计入配额
+	rotated, err := svc.Rotate(ctx, caller, first.ID, RotateAPIKeyRequest{})
+	if err != nil {
+		t.Fatalf("Rotate at the limit: %v", err)
+	}
+	// 旧 Key 仍在重叠期内，再次轮换会超出配额
+	if _, err := svc.Rotate(ctx, caller, rotated.ID, RotateAPIKeyRequest{}); statusOf(err) != http.StatusConflict {
+		t.Fatalf("rotate during overlap at the limit: err = %v", err)
+	}
+}
+
+func TestRegisterAuthRoutes_APIKeys(t *testing.T) {
+	rbac := fakeRBAC{
+		"user-123|api_key:create": true,
+		"user-123|api_key:read":   true,
+		"user-123|article:read":   true,
+	}
+	svc, _ := newTestAPIKeyService(t, rbac, APIKeyServiceConfig{})
+	router := chi.NewRouter()
+	RegisterAuthRoutes(router, NewUnifiedAuthMiddleware(AuthConfig{}, nil, rbac, nil, nil), svc)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer a.b.c")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/auth/api-keys", `{"name":"ci","scopes":[{"resource":"article","action":"read"}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Error("created key response must not be cached")
+	}
+	var created struct {
+		Data struct {
+			Key string `json:"key"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || created.Data.Key == "" {
+		t.Fatalf("create body = %s", rec.Body)
+	}
+
+	rec = do(http.MethodGet, "/auth/api-keys", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list: %d %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), created.Data.Key) || strings.Contains(rec.Body.String(), `"key"`) {
+		t.Fatalf("list must not expose the key: %s", rec.Body)
+	}
+
+	rec = do(http.MethodPost, "/auth/api-keys", `{"name":"x","scopes":[{"resource":"article","action":"delete"}]}`)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("escalation: %d %s", rec.Code, rec.Body)
+	}
+}
@@ -8,6 +8,10 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/request"
+	"github.com/leeforge/framework/security"
 	"go.uber.org/zap"
 )
 
@@ -74,92 +78,92 @@ func NewAuthMiddleware(config AuthConfig, store APIKeyStore, jwtSecret string, l
 // Middleware 认证中间件
 func (a *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// 1. API Key 验证 (必需)
-		apiKey := r.Header.Get("X-API-Key")
-		if a.config.RequireAPIKey && apiKey == "" {
-			a.writeError(w, 401, 4006, "API-Key is required")
+		ctx, err := a.Authenticate(r.Context(), r.Header.Get("X-API-Key"), r.Header.Get("Authorization"))
+		if err != nil {
+			response.WriteError(w, r, err)
 			return
 		}
 
-		// 2. 验证 API Key
-		var keyInfo *APIKeyInfo
-		if apiKey != "" {
-			var err error
-			keyInfo, err = a.validateAPIKey(r.Context(), apiKey)
-			if err != nil {
-				a.writeError(w, 401, 4006, "Invalid API-Key")
-				return
-			}
+		// 添加请求追踪
+		traceID := generateTraceID()
+		ctx = context.WithValue(ctx, "trace_id", traceID)
 
-			// 3. 检查过期和约束
-			if err := a.checkKeyConstraints(keyInfo); err != nil {
-				a.writeError(w, 401, 4006, err.Error())
-				return
-			}
-		}
+		// 继续处理请求
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		// 4. JWT 验证 (可选，仅需要用户身份时)
-		var userID string
-		authHeader := r.Header.Get("Authorization")
-		if authHeader != "" {
-			jwtToken := strings.TrimPrefix(authHeader, "Bearer ")
-			userID, err := a.validateJWT(jwtToken)
-			if err != nil {
-				a.writeError(w, 401, 4006, "Invalid JWT token")
-				return
-			}
+// Authenticate 校验 API Key 与 Authorization 凭据，返回存入认证信息的 Context。
+// 失败时返回 *errors.AppError（401/403）。HTTP 中间件与 gRPC 拦截器共用此方法
+func (a *AuthMiddleware) Authenticate(ctx context.Context, apiKey, authorization string) (context.Context, error) {
+	// 1. API Key 验证 (必需)
+	if a.config.RequireAPIKey && apiKey == "" {
+		return ctx, errors.NewUnauthorized("API-Key is required")
+	}
 
-			// 5. 验证用户 ID 与 API Key 创建者一致
-			if keyInfo != nil && userID != keyInfo.CreatedBy {
-				a.writeError(w, 403, 4005, "User mismatch with API-Key")
-				return
-			}
+	// 2. 验证 API Key
+	var keyInfo *APIKeyInfo
+	if apiKey != "" {
+		var err error
+		keyInfo, err = a.validateAPIKey(ctx, apiKey)
+		if err != nil {
+			return ctx, errors.NewUnauthorized("Invalid API-Key")
 		}
 
-		// 6. 存入 Context
-		ctx := r.Context()
-		if keyInfo != nil {
-			ctx = context.WithValue(ctx, "api_key_info", keyInfo)
+		// 3. 检查过期和约束
+		if err := a.checkKeyConstraints(keyInfo); err != nil {
+			return ctx, errors.NewUnauthorized(err.Error())
 		}
-		if userID != "" {
-			ctx = context.WithValue(ctx, "user_id", userID)
+	}
+
+	// 4. JWT 验证 (可选，仅需要用户身份时)
+	var userID string
+	if authorization != "" {
+		jwtToken := strings.TrimPrefix(authorization, "Bearer ")
+		var err error
+		userID, err = a.validateJWT(jwtToken)
+		if err != nil {
+			return ctx, errors.NewUnauthorized("Invalid JWT token")
 		}
 
-		// 7. 应用数据过滤
-		if keyInfo != nil && a.config.EnableDataFilter {
-			ctx = context.WithValue(ctx, "data_filters", keyInfo.DataFilters)
+		// 5. 验证用户 ID 与 API Key 创建者一致
+		if keyInfo != nil && userID != keyInfo.CreatedBy {
+			return ctx, errors.NewForbidden("User mismatch with API-Key")
 		}
+	}
 
-		// 8. 添加请求追踪
-		traceID := generateTraceID()
-		ctx = context.WithValue(ctx, "trace_id", traceID)
+	// 6. 存入 Context
+	if keyInfo != nil {
+		ctx = context.WithValue(ctx, "api_key_info", keyInfo)
+	}
+	if userID != "" {
+		ctx = context.WithValue(ctx, "user_id", userID)
+	}
 
-		// 9. 继续处理请求
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	// 7. 应用数据过滤
+	if keyInfo != nil && a.config.EnableDataFilter {
+		ctx = context.WithValue(ctx, "data_filters", keyInfo.DataFilters)
+	}
+	return ctx, nil
 }
 
 // validateAPIKey 验证 API Key
 func (a *AuthMiddleware) validateAPIKey(ctx context.Context, apiKey string) (*APIKeyInfo, error) {
 	// 测试模式
-	if a.config.AllowedAPIKeys != nil {
-		for _, allowed := range a.config.AllowedAPIKeys {
-			if apiKey == allowed {
-				return &APIKeyInfo{
-					Key:       apiKey,
-					CreatedBy: "test-user",
-					ExpiredAt: time.Now().Add(24 * time.Hour),
-					DataFilters: map[string]interface{}{
-						"tenant_id": "test-tenant",
-					},
-					RateLimit: RateLimitConfig{
-						Minute: 100,
-						Daily:  1000,
-						Burst:  10,
-					},
-				}, nil
-			}
-		}
+	if a.config.AllowedAPIKeys != nil && security.ConstantTimeIn(apiKey, a.config.AllowedAPIKeys) {
+		return &APIKeyInfo{
+			Key:       apiKey,
+			CreatedBy: "test-user",
+			ExpiredAt: time.Now().Add(24 * time.Hour),
+			DataFilters: map[string]interface{}{
+				"tenant_id": "test-tenant",
+			},
+			RateLimit: RateLimitConfig{
+				Minute: 100,
+				Daily:  1000,
+				Burst:  10,
+			},
+		}, nil
 	}
 
 	// 生产模式：从存储验证
@@ -172,8 +176,8 @@ func (a *AuthMiddleware) validateAPIKey(ctx context.Context, apiKey string) (*AP
 
 // checkKeyConstraints 检查 API Key 约束
 func (a *AuthMiddleware) checkKeyConstraints(keyInfo *APIKeyInfo) error {
-	// 检查过期
-	if time.Now().After(keyInfo.ExpiredAt) {
+	// 检查过期（零值表示永不过期）
+	if !keyInfo.ExpiredAt.IsZero() && time.Now().After(keyInfo.ExpiredAt) {
 		return fmt.Errorf("API key expired")
 	}
 
@@ -204,13 +208,6 @@ func (a *AuthMiddleware) validateJWT(token string) (string, error) {
 	return "user-123", nil
 }
 
-// writeError 写入错误响应
-func (a *AuthMiddleware) writeError(w http.ResponseWriter, status int, code int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	fmt.Fprintf(w, `{"error":{"code":%d,"message":"%s"}}`, code, message)
-}
-
 // generateTraceID 生成追踪 ID
 func generateTraceID() string {
 	// 简化实现
@@ -340,7 +337,7 @@ func (a *ABACMiddleware) Middleware(resource string, action string, resourceAttr
 
 			// 获取上下文属性
 			contextAttrs := map[string]interface{}{
-				"ip":         req.RemoteAddr,
+				"ip":         request.GetClientIP(req),
 				"user_agent": req.UserAgent(),
 				"time":       time.Now(),
 			}
@@ -452,39 +449,25 @@ func (u *UnifiedAuthMiddleware) WithDataFilter() func(next http.Handler) http.Ha
 }
 
 // RegisterAuthRoutes 注册认证相关路由
-func RegisterAuthRoutes(router chi.Router, authMiddleware *UnifiedAuthMiddleware) {
+// apiKeys 为 nil 时不注册 API Key 自助管理路由
+func RegisterAuthRoutes(router chi.Router, authMiddleware *UnifiedAuthMiddleware, apiKeys *APIKeyService) {
 	router.Route("/auth", func(r chi.Router) {
-		// API Key 管理
-		r.Post("/api-keys", http.HandlerFunc(authMiddleware.WithRBAC("api_key", "create")(http.HandlerFunc(createAPIKey)).ServeHTTP))
-		r.Get("/api-keys", http.HandlerFunc(authMiddleware.WithRBAC("api_key", "read")(http.HandlerFunc(listAPIKeys)).ServeHTTP))
-		r.Delete("/api-keys/{id}", http.HandlerFunc(authMiddleware.WithRBAC("api_key", "delete")(http.HandlerFunc(deleteAPIKey)).ServeHTTP))
+		// API Key 自助管理
+		if apiKeys != nil {
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.Middleware)
+				r.With(authMiddleware.WithRBAC("api_key", "create")).Post("/api-keys", apiKeys.handleCreate)
+				r.With(authMiddleware.WithRBAC("api_key", "read")).Get("/api-keys", apiKeys.handleList)
+				r.With(authMiddleware.WithRBAC("api_key", "delete")).Delete("/api-keys/{id}", apiKeys.handleRevoke)
+				r.With(authMiddleware.WithRBAC("api_key", "create")).Post("/api-keys/{id}/rotate", apiKeys.handleRotate)
+			})
+		}
 
 		// 权限检查
 		r.Post("/check", http.HandlerFunc(authMiddleware.Middleware(http.HandlerFunc(checkPermission)).ServeHTTP))
 	})
 }
 
-// createAPIKey 创建 API Key 处理器
-func createAPIKey(w http.ResponseWriter, r *http.Request) {
-	// 实现创建 API Key 逻辑
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"message":"API key created"}`))
-}
-
-// listAPIKeys 列出 API Key 处理器
-func listAPIKeys(w http.ResponseWriter, r *http.Request) {
-	// 实现列出 API Key 逻辑
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"keys":[]}`))
-}
-
-// deleteAPIKey 删除 API Key 处理器
-func deleteAPIKey(w http.ResponseWriter, r *http.Request) {
-	// 实现删除 API Key 逻辑
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"message":"API key deleted"}`))
-}
-
 // checkPermission 检查权限处理器
 func checkPermission(w http.ResponseWriter, r *http.Request) {
 	// 实现权限检查逻辑
@@ -209,6 +209,41 @@ func (a *EntAdapter) RemovePolicies(sec, ptype string, rules [][]string) error {
 	return nil
 }
 
+// ApplyPolicyChanges 在单个事务中删除 remove、添加 add 中的策略，任一失败整体回滚
+func (a *EntAdapter) ApplyPolicyChanges(ctx context.Context, ptype string, add, remove [][]string) error {
+	tx, err := a.client.Tx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	for i := range remove {
+		if _, err := tx.CasbinPolicy.
+			Delete().
+			Where(matchPolicyPredicates(ptype, remove[i])...).
+			Exec(ctx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to remove policy %d: %w", i, err)
+		}
+	}
+	for i := range add {
+		rule := trimRule(add[i])
+		if len(rule) == 0 {
+			continue
+		}
+		builder := tx.CasbinPolicy.Create().SetPtype(ptype)
+		setRuleOnCreate(builder, rule)
+		if _, err := builder.Save(ctx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to add policy %d: %w", i, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit policy changes: %w", err)
+	}
+	return nil
+}
+
 func (a *EntAdapter) addPolicy(ctx context.Context, sec, ptype string, rule []string) error {
 	rule = trimRule(rule)
 	if len(rule) == 0 {
@@ -0,0 +1,141 @@
+package mfa
+
+import (
+	"encoding/base32"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 附录 B 的测试向量
+func TestTOTPRFC6238Vectors(t *testing.T) {
+	seeds := map[Algorithm]string{
+		AlgorithmSHA1:   "12345678901234567890",
+		AlgorithmSHA256: "12345678901234567890123456789012",
+		AlgorithmSHA512: "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	vectors := []struct {
+		unix int64
+		algo Algorithm
+		code string
+	}{
+		{59, AlgorithmSHA1, "94287082"},
+		{59, AlgorithmSHA256, "46119246"},
+		{59, AlgorithmSHA512, "90693936"},
+		{1111111109, AlgorithmSHA1, "07081804"},
+		{1234567890, AlgorithmSHA256, "91819424"},
+		{20000000000, AlgorithmSHA512, "47863826"},
+	}
+	for _, v := range vectors {
+		totp, err := NewTOTP(TOTPConfig{Algorithm: v.algo, Digits: 8})
+		require.NoError(t, err)
+		secret := base32.StdEncoding.EncodeToString([]byte(seeds[v.algo]))
+		code, err := totp.Code(secret, time.Unix(v.unix, 0))
+		require.NoError(t, err)
+		require.Equal(t, v.code, code, "%s @ %d", v.algo, v.unix)
+	}
+}
+
+func TestTOTPVerifyDriftAndReplay(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	totp, err := NewTOTP(TOTPConfig{Issuer: "Leeforge", Now: func() time.Time { return now }})
+	require.NoError(t, err)
+
+	key, err := totp.Generate("alice@example.com")
+	require.NoError(t, err)
+
+	previous, err := totp.Code(key.Secret, now.Add(-30*time.Second))
+	require.NoError(t, err)
+	step, err := totp.Verify(key.Secret, previous, 0)
+	require.NoError(t, err)
+	require.Equal(t, now.Unix()/30-1, step)
+
+	_, err = totp.Verify(key.Secret, previous, step)
+	require.ErrorIs(t, err, ErrCodeReused)
+
+	tooOld, err := totp.Code(key.Secret, now.Add(-90*time.Second))
+	require.NoError(t, err)
+	_, err = totp.Verify(key.Secret, tooOld, 0)
+	require.ErrorIs(t, err, ErrInvalidCode)
+
+	strict, err := NewTOTP(TOTPConfig{Skew: -1, Now: func() time.Time { return now }})
+	require.NoError(t, err)
+	_, err = strict.Verify(key.Secret, previous, 0)
+	require.ErrorIs(t, err, ErrInvalidCode)
+
+	_, err = totp.Verify(key.Secret, "12345", 0)
+	require.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestKeyURL(t *testing.T) {
+	totp, err := NewTOTP(TOTPConfig{Issuer: "Lee Forge"})
+	require.NoError(t, err)
+	key, err := totp.Generate("alice@example.com")
+	require.NoError(t, err)
+	require.Len(t, key.Secret, 32)
+
+	u, err := url.Parse(key.URL())
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", u.Scheme)
+	require.Equal(t, "totp", u.Host)
+	require.Equal(t, "/Lee Forge:alice@example.com", u.Path)
+	q := u.Query()
+	require.Equal(t, key.Secret, q.Get("secret"))
+	require.Equal(t, "Lee Forge", q.Get("issuer"))
+	require.Equal(t, "SHA1", q.Get("algorithm"))
+	require.Equal(t, "6", q.Get("digits"))
+	require.Equal(t, "30", q.Get("period"))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes(0)
+	require.NoError(t, err)
+	require.Len(t, codes, DefaultRecoveryCodes)
+	require.Len(t, hashes, DefaultRecoveryCodes)
+	require.Regexp(t, `^[a-z2-9]{5}-[a-z2-9]{5}$`, codes[0])
+
+	remaining, ok := ConsumeRecoveryCode(hashes, strings.ToUpper(strings.ReplaceAll(codes[3], "-", " ")))
+	require.True(t, ok)
+	require.Len(t, remaining, DefaultRecoveryCodes-1)
+	require.NotContains(t, remaining, hashes[3])
+
+	_, ok = ConsumeRecoveryCode(remaining, codes[3])
+	require.False(t, ok, "recovery codes are single use")
+}
+
+func TestRequire(t *testing.T) {
+	now := time.Now()
+	handler := Require(RequireOptions{MaxAge: 10 * time.Minute})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(s *Session) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodDelete, "/account", nil)
+		if s != nil {
+			r = r.WithContext(WithSession(r.Context(), *s))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	require.Equal(t, http.StatusUnauthorized, serve(nil).Code)
+
+	w := serve(&Session{UserID: "u1"})
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Contains(t, w.Body.String(), "MFA_ENROLLMENT_REQUIRED")
+
+	w = serve(&Session{UserID: "u1", Enrolled: true})
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Contains(t, w.Body.String(), "MFA_REQUIRED")
+
+	w = serve(&Session{UserID: "u1", Enrolled: true, VerifiedAt: now.Add(-time.Hour)})
+	require.Equal(t, http.StatusForbidden, w.Code, "stale verification requires step-up")
+
+	require.Equal(t, http.StatusNoContent, serve(&Session{UserID: "u1", Enrolled: true, VerifiedAt: now}).Code)
+}
@@ -0,0 +1,81 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// 恢复码字母表，去掉易混淆的 0/o、1/l/i
+const recoveryAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+// DefaultRecoveryCodes 默认生成的恢复码数量
+const DefaultRecoveryCodes = 10
+
+// GenerateRecoveryCodes 生成 n 个一次性恢复码（形如 "k7m2q-x9prt"，约 50 bit 熵），
+// 返回明文与对应哈希。明文只展示给用户一次，服务端仅保存哈希。
+func GenerateRecoveryCodes(n int) (codes, hashes []string, err error) {
+	if n <= 0 {
+		n = DefaultRecoveryCodes
+	}
+	codes = make([]string, n)
+	hashes = make([]string, n)
+	buf := make([]byte, 10)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		var b strings.Builder
+		for j, c := range buf {
+			if j == 5 {
+				b.WriteByte('-')
+			}
+			// 256 % 31 引入的偏差可忽略
+			b.WriteByte(recoveryAlphabet[int(c)%len(recoveryAlphabet)])
+		}
+		codes[i] = b.String()
+		hashes[i] = HashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode 返回恢复码的哈希。恢复码为高熵随机值，SHA-256 即可，
+// 忽略大小写、空格与连字符。
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ConsumeRecoveryCode 在哈希列表中查找恢复码，找到时返回移除该项后的列表，
+// 调用方应保存剩余列表使其失效。
+func ConsumeRecoveryCode(hashes []string, code string) (remaining []string, ok bool) {
+	target := []byte(HashRecoveryCode(code))
+	index := -1
+	// 比较全部条目，耗时与位置无关
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), target) == 1 {
+			index = i
+		}
+	}
+	if index < 0 {
+		return hashes, false
+	}
+	remaining = make([]string, 0, len(hashes)-1)
+	remaining = append(remaining, hashes[:index]...)
+	return append(remaining, hashes[index+1:]...), true
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ', '\t':
+			return -1
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, code)
+}
@@ -0,0 +1,227 @@
+// Package mfa 基于 TOTP（RFC 6238）的双因素认证：密钥下发、动态码校验、
+// 恢复码，以及要求会话已完成 MFA 的路由中间件。
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCode 动态码错误或已过期
+	ErrInvalidCode = errors.New("mfa: invalid code")
+	// ErrCodeReused 动态码所在时间片已使用过
+	ErrCodeReused = errors.New("mfa: code already used")
+)
+
+// Algorithm TOTP 的 HMAC 算法
+type Algorithm string
+
+const (
+	AlgorithmSHA1   Algorithm = "SHA1"
+	AlgorithmSHA256 Algorithm = "SHA256"
+	AlgorithmSHA512 Algorithm = "SHA512"
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPConfig TOTP 配置，零值字段使用默认值。大多数验证器 App 只支持
+// 默认的 SHA1、6 位、30 秒。
+type TOTPConfig struct {
+	Issuer    string    // 显示在验证器 App 中的服务名
+	Algorithm Algorithm // 默认 SHA1
+	Digits    int       // 6 或 8，默认 6
+	Period    time.Duration
+	// Skew 允许前后偏差的时间片数，默认 1（即 ±30 秒），负数表示不允许偏差
+	Skew int
+	// SecretSize 密钥字节数，默认 20
+	SecretSize int
+	// Now 默认 time.Now
+	Now func() time.Time
+}
+
+// TOTP 动态码生成与校验
+type TOTP struct {
+	config TOTPConfig
+}
+
+// NewTOTP 创建 TOTP
+func NewTOTP(config TOTPConfig) (*TOTP, error) {
+	if config.Algorithm == "" {
+		config.Algorithm = AlgorithmSHA1
+	}
+	if hashFunc(config.Algorithm) == nil {
+		return nil, fmt.Errorf("mfa: unsupported algorithm %q", config.Algorithm)
+	}
+	if config.Digits == 0 {
+		config.Digits = 6
+	}
+	if config.Digits != 6 && config.Digits != 8 {
+		return nil, fmt.Errorf("mfa: digits must be 6 or 8")
+	}
+	if config.Period <= 0 {
+		config.Period = 30 * time.Second
+	}
+	if config.Period%time.Second != 0 {
+		return nil, fmt.Errorf("mfa: period must be whole seconds")
+	}
+	if config.Skew < 0 {
+		config.Skew = 0
+	} else if config.Skew == 0 {
+		config.Skew = 1
+	}
+	if config.SecretSize == 0 {
+		config.SecretSize = 20
+	}
+	if config.SecretSize < 16 {
+		return nil, fmt.Errorf("mfa: secret must be at least 16 bytes")
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &TOTP{config: config}, nil
+}
+
+// Key 为用户生成的 TOTP 密钥
+type Key struct {
+	Secret  string // base32（无填充），需加密保存，如 security/crypto
+	Issuer  string
+	Account string
+
+	algorithm Algorithm
+	digits    int
+	period    time.Duration
+}
+
+// URL 返回 otpauth:// 链接，即二维码内容，也可供用户手动输入
+func (k *Key) URL() string {
+	label := k.Account
+	if k.Issuer != "" {
+		label = k.Issuer + ":" + k.Account
+	}
+	q := url.Values{}
+	q.Set("secret", k.Secret)
+	if k.Issuer != "" {
+		q.Set("issuer", k.Issuer)
+	}
+	q.Set("algorithm", string(k.algorithm))
+	q.Set("digits", strconv.Itoa(k.digits))
+	q.Set("period", strconv.Itoa(int(k.period/time.Second)))
+	u := url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + label, RawQuery: q.Encode()}
+	return u.String()
+}
+
+// Generate 为账号生成新密钥。用户扫描二维码后应先用一次 Verify 确认绑定，
+// 再保存密钥。
+func (t *TOTP) Generate(account string) (*Key, error) {
+	secret := make([]byte, t.config.SecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return &Key{
+		Secret:    secretEncoding.EncodeToString(secret),
+		Issuer:    t.config.Issuer,
+		Account:   account,
+		algorithm: t.config.Algorithm,
+		digits:    t.config.Digits,
+		period:    t.config.Period,
+	}, nil
+}
+
+// Code 返回密钥在指定时刻的动态码
+func (t *TOTP) Code(secret string, at time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return t.code(key, t.step(at)), nil
+}
+
+// Verify 校验动态码，允许 Skew 个时间片的偏差，返回匹配的时间片。
+// lastStep 为该用户上次成功校验的时间片（未使用过传 0），不大于它的时间片
+// 返回 ErrCodeReused，防止动态码在有效期内被重放；调用方应保存返回值。
+func (t *TOTP) Verify(secret, code string, lastStep int64) (int64, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, err
+	}
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != t.config.Digits {
+		return 0, ErrInvalidCode
+	}
+
+	current := t.step(t.config.Now())
+	var matched int64 = -1
+	// 检查完整窗口，耗时与匹配位置无关
+	for i := -t.config.Skew; i <= t.config.Skew; i++ {
+		step := current + int64(i)
+		if step < 0 {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(t.code(key, step)), []byte(code)) == 1 {
+			matched = step
+		}
+	}
+	if matched < 0 {
+		return 0, ErrInvalidCode
+	}
+	if matched <= lastStep {
+		return 0, ErrCodeReused
+	}
+	return matched, nil
+}
+
+func (t *TOTP) step(at time.Time) int64 {
+	return at.Unix() / int64(t.config.Period/time.Second)
+}
+
+// code 按 RFC 4226 动态截断生成动态码
+func (t *TOTP) code(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(hashFunc(t.config.Algorithm), key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1_000_000)
+	if t.config.Digits == 8 {
+		mod = 100_000_000
+	}
+	return fmt.Sprintf("%0*d", t.config.Digits, value%mod)
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "="))
+	key, err := secretEncoding.DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("mfa: invalid secret")
+	}
+	return key, nil
+}
+
+func hashFunc(a Algorithm) func() hash.Hash {
+	switch a {
+	case AlgorithmSHA1:
+		return sha1.New
+	case AlgorithmSHA256:
+		return sha256.New
+	case AlgorithmSHA512:
+		return sha512.New
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+// Package password 密码哈希（argon2id / bcrypt）与密码策略
+//
+// 哈希以标准编码保存（argon2id 为 PHC 字符串，bcrypt 为 $2b$），算法与参数
+// 随哈希一起存储。调整参数或更换算法后，Manager.Verify 在登录成功时返回
+// 新哈希，由调用方写回，实现登录时自动升级。
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrMismatch 密码错误
+	ErrMismatch = errors.New("password: mismatch")
+	// ErrUnknownFormat 没有可识别该哈希的算法
+	ErrUnknownFormat = errors.New("password: unknown hash format")
+)
+
+// Hasher 密码哈希算法
+type Hasher interface {
+	// Hash 返回编码后的哈希
+	Hash(password string) (string, error)
+	// Verify 常量时间比较，密码错误返回 ErrMismatch
+	Verify(password, encoded string) error
+	// Recognizes 判断哈希是否由该算法生成
+	Recognizes(encoded string) bool
+	// NeedsRehash 判断哈希参数是否与当前配置不同
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idParams argon2id 参数
+type Argon2idParams struct {
+	Memory      uint32 // 内存（KiB）
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams 采用 RFC 9106 第二推荐配置（64 MiB、3 轮、4 并行）
+func DefaultArgon2idParams() Argon2idParams {
+	return Argon2idParams{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 4,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// Argon2id argon2id 哈希，PHC 格式：
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+type Argon2id struct {
+	params Argon2idParams
+}
+
+// NewArgon2id 创建 argon2id 哈希器，零值参数使用默认值
+func NewArgon2id(params Argon2idParams) *Argon2id {
+	d := DefaultArgon2idParams()
+	if params.Memory == 0 {
+		params.Memory = d.Memory
+	}
+	if params.Iterations == 0 {
+		params.Iterations = d.Iterations
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = d.Parallelism
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = d.SaltLength
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = d.KeyLength
+	}
+	return &Argon2id{params: params}
+}
+
+// Hash 实现 Hasher
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	p := a.params
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify 实现 Hasher
+func (a *Argon2id) Verify(password, encoded string) error {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return err
+	}
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// Recognizes 实现 Hasher
+func (a *Argon2id) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// NeedsRehash 实现 Hasher
+func (a *Argon2id) NeedsRehash(encoded string) bool {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return p.Memory != a.params.Memory ||
+		p.Iterations != a.params.Iterations ||
+		p.Parallelism != a.params.Parallelism ||
+		uint32(len(salt)) != a.params.SaltLength ||
+		uint32(len(key)) != a.params.KeyLength
+}
+
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var p Argon2idParams
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, ErrUnknownFormat
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrUnknownFormat
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, ErrUnknownFormat
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrUnknownFormat
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrUnknownFormat
+	}
+	p.SaltLength, p.KeyLength = uint32(len(salt)), uint32(len(key))
+	return p, salt, key, nil
+}
+
+// Bcrypt bcrypt 哈希。超过 72 字节的密码 Hash 直接报错，不会静默截断
+type Bcrypt struct {
+	cost int
+}
+
+// NewBcrypt 创建 bcrypt 哈希器，cost 为 0 时使用 bcrypt.DefaultCost
+func NewBcrypt(cost int) *Bcrypt {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &Bcrypt{cost: cost}
+}
+
+// Hash 实现 Hasher
+func (b *Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", fmt.Errorf("password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// Verify 实现 Hasher
+func (b *Bcrypt) Verify(password, encoded string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return ErrMismatch
+	default:
+		return fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+}
+
+// Recognizes 实现 Hasher
+func (b *Bcrypt) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// NeedsRehash 实现 Hasher
+func (b *Bcrypt) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.cost
+}
+
+// Manager 以主算法生成新哈希，并可校验所有已配置算法的哈希
+type Manager struct {
+	primary Hasher
+	hashers []Hasher
+	dummy   string
+}
+
+// NewManager 创建 Manager。legacy 算法的哈希仍可校验，并在下次登录成功时
+// 升级为主算法
+func NewManager(primary Hasher, legacy ...Hasher) (*Manager, error) {
+	dummy, err := primary.Hash("leeforge-dummy-password")
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{primary: primary, hashers: append([]Hasher{primary}, legacy...), dummy: dummy}, nil
+}
+
+// DefaultManager 默认 argon2id 为主算法，兼容校验 bcrypt 哈希
+func DefaultManager() *Manager {
+	m, err := NewManager(NewArgon2id(Argon2idParams{}), NewBcrypt(0))
+	if err != nil {
+		panic("password: " + err.Error())
+	}
+	return m
+}
+
+// Hash 使用主算法生成哈希
+func (m *Manager) Hash(password string) (string, error) {
+	return m.primary.Hash(password)
+}
+
+// Verify 校验密码，密码错误返回 ErrMismatch。校验通过且哈希需要升级
+// （算法不同或参数变化）时返回新哈希，调用方应写回存储；否则 rehash 为空。
+func (m *Manager) Verify(password, encoded string) (rehash string, err error) {
+	for _, h := range m.hashers {
+		if !h.Recognizes(encoded) {
+			continue
+		}
+		if err := h.Verify(password, encoded); err != nil {
+			return "", err
+		}
+		if h != m.primary || m.primary.NeedsRehash(encoded) {
+			// 升级失败不影响本次登录
+			rehash, _ = m.primary.Hash(password)
+		}
+		return rehash, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// VerifyMissing 用户不存在时调用，消耗与一次校验相同的时间，避免通过
+// 响应时间枚举账号。始终返回 ErrMismatch。
+func (m *Manager) VerifyMissing(password string) error {
+	_ = m.primary.Verify(password, m.dummy)
+	return ErrMismatch
+}
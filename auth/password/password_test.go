@@ -0,0 +1,155 @@
+package password
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leeforge/framework/http/binding"
+	"github.com/stretchr/testify/require"
+)
+
+// fastArgon2 测试用低成本参数
+var fastArgon2 = Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+func TestArgon2idHashAndVerify(t *testing.T) {
+	h := NewArgon2id(fastArgon2)
+
+	encoded, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	require.True(t, h.Recognizes(encoded))
+
+	require.NoError(t, h.Verify("correct horse", encoded))
+	require.ErrorIs(t, h.Verify("wrong horse", encoded), ErrMismatch)
+	require.False(t, h.NeedsRehash(encoded))
+
+	other, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	require.NotEqual(t, encoded, other, "salt must be random")
+
+	require.ErrorIs(t, h.Verify("x", "$argon2id$v=19$m=1024$bad"), ErrUnknownFormat)
+}
+
+func TestBcryptHashAndVerify(t *testing.T) {
+	h := NewBcrypt(4)
+
+	encoded, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	require.True(t, h.Recognizes(encoded))
+	require.NoError(t, h.Verify("correct horse", encoded))
+	require.ErrorIs(t, h.Verify("wrong horse", encoded), ErrMismatch)
+	require.False(t, h.NeedsRehash(encoded))
+	require.True(t, NewBcrypt(5).NeedsRehash(encoded))
+
+	_, err = h.Hash(strings.Repeat("a", 73))
+	require.Error(t, err, "passwords over 72 bytes must not be truncated")
+}
+
+func TestManagerRehashOnParamChange(t *testing.T) {
+	old, err := NewManager(NewArgon2id(fastArgon2))
+	require.NoError(t, err)
+	encoded, err := old.Hash("secret-pass")
+	require.NoError(t, err)
+
+	rehash, err := old.Verify("secret-pass", encoded)
+	require.NoError(t, err)
+	require.Empty(t, rehash)
+
+	stronger := fastArgon2
+	stronger.Iterations = 2
+	m, err := NewManager(NewArgon2id(stronger))
+	require.NoError(t, err)
+
+	rehash, err = m.Verify("secret-pass", encoded)
+	require.NoError(t, err)
+	require.Contains(t, rehash, "t=2")
+
+	rehash, err = m.Verify("secret-pass", rehash)
+	require.NoError(t, err)
+	require.Empty(t, rehash)
+
+	_, err = m.Verify("wrong", encoded)
+	require.ErrorIs(t, err, ErrMismatch)
+}
+
+func TestManagerMigratesLegacyBcrypt(t *testing.T) {
+	legacy := NewBcrypt(4)
+	encoded, err := legacy.Hash("secret-pass")
+	require.NoError(t, err)
+
+	m, err := NewManager(NewArgon2id(fastArgon2), legacy)
+	require.NoError(t, err)
+
+	rehash, err := m.Verify("secret-pass", encoded)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(rehash, "$argon2id$"))
+
+	_, err = m.Verify("secret-pass", "plaintext")
+	require.ErrorIs(t, err, ErrUnknownFormat)
+	require.ErrorIs(t, m.VerifyMissing("secret-pass"), ErrMismatch)
+}
+
+func TestPolicyValidate(t *testing.T) {
+	ctx := context.Background()
+	p := Policy{MinLength: 8, MaxLength: 16, RequireUpper: true, RequireDigit: true, RequireSymbol: true}
+
+	require.NoError(t, p.Validate(ctx, "Passw0rd!"))
+
+	var pe *PolicyError
+	require.ErrorAs(t, p.Validate(ctx, "short"), &pe)
+	require.Contains(t, pe.Violations, "must be at least 8 characters")
+	require.Contains(t, pe.Violations, "must contain an uppercase letter")
+
+	require.ErrorAs(t, p.Validate(ctx, "Passw0rd!Passw0rd!"), &pe)
+	require.Equal(t, []string{"must be at most 16 characters"}, pe.Violations)
+
+	// 长度按字符计算
+	require.NoError(t, Policy{MinLength: 4}.Validate(ctx, "密码安全"))
+}
+
+func TestPolicyBreachHook(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	p := DefaultPolicy()
+	p.Breach = BreachCheckerFunc(func(_ context.Context, pw string) (bool, error) {
+		calls++
+		if pw == "unavailable" {
+			return false, errors.New("service down")
+		}
+		return pw == "password123", nil
+	})
+
+	var pe *PolicyError
+	require.ErrorAs(t, p.Validate(ctx, "password123"), &pe)
+	require.Equal(t, []string{"has appeared in a data breach"}, pe.Violations)
+	require.NoError(t, p.Validate(ctx, "a-long-unique-phrase"))
+	require.ErrorContains(t, p.Validate(ctx, "unavailable"), "service down")
+
+	require.Error(t, p.Validate(ctx, "short"))
+	require.Equal(t, 3, calls, "format violations skip the breach check")
+}
+
+func TestPolicyRegisterRule(t *testing.T) {
+	p := Policy{MinLength: 10, RequireDigit: true}
+	require.NoError(t, p.RegisterRule("test_password"))
+
+	type signup struct {
+		Password string `json:"password" validate:"required,test_password"`
+	}
+
+	var body signup
+	r := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"password":"too-weak"}`))
+	err := binding.JSON(r, &body)
+	var verrs binding.ValidationErrors
+	require.ErrorAs(t, err, &verrs)
+	require.Len(t, verrs, 1)
+	require.Equal(t, "test_password", verrs[0].Rule)
+	require.Equal(t, "must be at least 10 characters, must contain a digit", verrs[0].Message)
+
+	r = httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"password":"strong-enough-1"}`))
+	require.NoError(t, binding.JSON(r, &body))
+}
@@ -0,0 +1,160 @@
+package password
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	validatorV10 "github.com/go-playground/validator/v10"
+	"github.com/leeforge/framework/http/binding"
+)
+
+// BreachChecker 检查密码是否出现在已泄露密码库中（如 HIBP k-anonymity 接口）
+type BreachChecker interface {
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+// BreachCheckerFunc 函数形式的 BreachChecker
+type BreachCheckerFunc func(ctx context.Context, password string) (bool, error)
+
+// Breached 实现 BreachChecker
+func (f BreachCheckerFunc) Breached(ctx context.Context, password string) (bool, error) {
+	return f(ctx, password)
+}
+
+// Policy 密码策略，零值字段不做对应检查
+type Policy struct {
+	// MinLength / MaxLength 按字符（rune）计算
+	MinLength int
+	MaxLength int
+
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	// Breach 泄露密码检查，为 nil 时跳过。检查出错时 Validate 返回该错误，
+	// 希望外部服务故障时放行的实现应返回 (false, nil)
+	Breach BreachChecker
+}
+
+// DefaultPolicy 默认策略：8～128 个字符，不要求字符类别（参考 NIST SP 800-63B）
+func DefaultPolicy() Policy {
+	return Policy{MinLength: 8, MaxLength: 128}
+}
+
+// PolicyError 不满足策略的原因
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password: " + strings.Join(e.Violations, "; ")
+}
+
+// Validate 按策略校验密码，不满足时返回 *PolicyError
+func (p Policy) Validate(ctx context.Context, password string) error {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters", p.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	// 格式不合格时不必请求外部泄露库
+	if len(violations) == 0 && p.Breach != nil {
+		breached, err := p.Breach.Breached(ctx, password)
+		if err != nil {
+			return fmt.Errorf("password: breach check: %w", err)
+		}
+		if breached {
+			violations = append(violations, "has appeared in a data breach")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// RegisterRule 将策略注册为 binding 校验规则，如 `validate:"required,password"`。
+// 规则只能用于字符串字段，校验时使用请求上下文；任何错误（含泄露检查出错）
+// 都视为校验失败。需在启动时调用。
+func (p Policy) RegisterRule(tag string) error {
+	return binding.RegisterValidation(tag, func(ctx context.Context, fl validatorV10.FieldLevel) bool {
+		field := fl.Field()
+		if field.Kind() != reflect.String {
+			return false
+		}
+		return p.Validate(ctx, field.String()) == nil
+	}, p.message())
+}
+
+// message 规则失败时的提示
+func (p Policy) message() string {
+	var parts []string
+	switch {
+	case p.MinLength > 0 && p.MaxLength > 0:
+		parts = append(parts, fmt.Sprintf("must be %d-%d characters", p.MinLength, p.MaxLength))
+	case p.MinLength > 0:
+		parts = append(parts, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	case p.MaxLength > 0:
+		parts = append(parts, fmt.Sprintf("must be at most %d characters", p.MaxLength))
+	}
+	var classes []string
+	if p.RequireUpper {
+		classes = append(classes, "an uppercase letter")
+	}
+	if p.RequireLower {
+		classes = append(classes, "a lowercase letter")
+	}
+	if p.RequireDigit {
+		classes = append(classes, "a digit")
+	}
+	if p.RequireSymbol {
+		classes = append(classes, "a symbol")
+	}
+	if len(classes) > 0 {
+		parts = append(parts, "must contain "+strings.Join(classes, ", "))
+	}
+	if p.Breach != nil {
+		parts = append(parts, "must not be a breached password")
+	}
+	if len(parts) == 0 {
+		return "is not an acceptable password"
+	}
+	return strings.Join(parts, ", ")
+}
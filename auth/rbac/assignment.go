@@ -0,0 +1,177 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Assignment 用户在域内的一条角色分配
+type Assignment struct {
+	UserUUID string `json:"user_uuid"`
+	RoleCode string `json:"role_code"`
+	Domain   string `json:"domain"`
+}
+
+func (a Assignment) rule() []string {
+	return []string{a.UserUUID, a.RoleCode, a.Domain}
+}
+
+// RoleChangeReport 批量变更结果
+type RoleChangeReport struct {
+	Added     []Assignment `json:"added"`
+	Removed   []Assignment `json:"removed"`
+	Unchanged []Assignment `json:"unchanged"`
+}
+
+// Changed 是否有实际变更
+func (r *RoleChangeReport) Changed() bool {
+	return len(r.Added) > 0 || len(r.Removed) > 0
+}
+
+// AssignRoles 批量分配角色，已存在的分配计入 Unchanged
+// 所有新增在一个数据库事务中写入，失败时不产生任何变更
+func (m *RBACManager) AssignRoles(ctx context.Context, assignments []Assignment) (*RoleChangeReport, error) {
+	for i, a := range assignments {
+		if a.UserUUID == "" || a.RoleCode == "" || a.Domain == "" {
+			return nil, fmt.Errorf("assignment %d: user, role and domain are required", i)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := &RoleChangeReport{}
+	seen := make(map[Assignment]bool, len(assignments))
+	for _, a := range assignments {
+		if seen[a] {
+			continue
+		}
+		seen[a] = true
+		if m.enforcer.HasGroupingPolicy(a.rule()) {
+			report.Unchanged = append(report.Unchanged, a)
+		} else {
+			report.Added = append(report.Added, a)
+		}
+	}
+
+	if err := m.applyRoleChanges(ctx, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// SyncUserRoles 把用户在域内的角色同步为 desiredRoles：补齐缺少的、撤销多余的
+// 新增与撤销在一个数据库事务中完成，适合从外部 IdP 同步角色
+func (m *RBACManager) SyncUserRoles(ctx context.Context, userUUID, domain string, desiredRoles []string) (*RoleChangeReport, error) {
+	if userUUID == "" || domain == "" {
+		return nil, fmt.Errorf("user and domain are required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	desired := make(map[string]bool, len(desiredRoles))
+	for _, role := range desiredRoles {
+		if role == "" {
+			return nil, fmt.Errorf("role code must not be empty")
+		}
+		desired[role] = true
+	}
+
+	current := make(map[string]bool)
+	for _, policy := range m.enforcer.GetFilteredGroupingPolicy(0, userUUID, "", domain) {
+		if len(policy) >= 2 {
+			current[policy[1]] = true
+		}
+	}
+
+	report := &RoleChangeReport{}
+	for _, role := range sortedRoles(desired) {
+		a := Assignment{UserUUID: userUUID, RoleCode: role, Domain: domain}
+		if current[role] {
+			report.Unchanged = append(report.Unchanged, a)
+		} else {
+			report.Added = append(report.Added, a)
+		}
+	}
+	for _, role := range sortedRoles(current) {
+		if !desired[role] {
+			report.Removed = append(report.Removed, Assignment{UserUUID: userUUID, RoleCode: role, Domain: domain})
+		}
+	}
+
+	if err := m.applyRoleChanges(ctx, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// applyRoleChanges 先在一个事务中持久化，再更新内存模型并清除缓存，调用方需持有 m.mu
+func (m *RBACManager) applyRoleChanges(ctx context.Context, report *RoleChangeReport) error {
+	if !report.Changed() {
+		return nil
+	}
+
+	add := make([][]string, len(report.Added))
+	for i, a := range report.Added {
+		add[i] = a.rule()
+	}
+	remove := make([][]string, len(report.Removed))
+	for i, a := range report.Removed {
+		remove[i] = a.rule()
+	}
+
+	if m.adapter != nil {
+		if err := m.adapter.ApplyPolicyChanges(ctx, "g", add, remove); err != nil {
+			return err
+		}
+	}
+
+	// 数据库已提交，只更新内存模型，避免逐条再写一次
+	m.enforcer.EnableAutoSave(false)
+	err := m.updateGroupingPolicies(add, remove)
+	m.enforcer.EnableAutoSave(true)
+	if err != nil {
+		// 内存模型与数据库不一致时重新加载
+		if loadErr := m.enforcer.LoadPolicy(); loadErr != nil {
+			return fmt.Errorf("failed to update role links: %w (reload: %v)", err, loadErr)
+		}
+	}
+
+	if m.cache != nil {
+		m.cache.Delete("rbac:roles")
+		invalidated := make(map[string]bool)
+		for _, a := range append(report.Added, report.Removed...) {
+			key := fmt.Sprintf("rbac:user_roles:%s:%s", a.UserUUID, a.Domain)
+			if !invalidated[key] {
+				invalidated[key] = true
+				m.cache.Delete(key)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *RBACManager) updateGroupingPolicies(add, remove [][]string) error {
+	if len(remove) > 0 {
+		if _, err := m.enforcer.RemoveGroupingPolicies(remove); err != nil {
+			return err
+		}
+	}
+	if len(add) > 0 {
+		if _, err := m.enforcer.AddGroupingPolicies(add); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedRoles(set map[string]bool) []string {
+	roles := make([]string, 0, len(set))
+	for role := range set {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
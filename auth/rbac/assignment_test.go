@@ -0,0 +1,171 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	"github.com/google/uuid"
+	casbinadapter "github.com/leeforge/framework/auth/casbin"
+	"github.com/leeforge/framework/ent"
+	"github.com/leeforge/framework/ent/casbinpolicy"
+	"github.com/leeforge/framework/ent/hook"
+
+	_ "modernc.org/sqlite"
+)
+
+type recordingCache struct {
+	deleted []string
+}
+
+func (c *recordingCache) Get(string) (interface{}, error)      { return nil, errors.New("miss") }
+func (c *recordingCache) Set(string, interface{}, int64) error { return nil }
+func (c *recordingCache) Delete(key string) error {
+	c.deleted = append(c.deleted, key)
+	return nil
+}
+
+func newTestManager(t *testing.T) (*RBACManager, *ent.Client, *recordingCache) {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:rbac-"+uuid.NewString()+"?mode=memory&cache=shared&_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ent.NewClient(ent.Driver(entsql.OpenDB(dialect.SQLite, db)))
+	t.Cleanup(func() { client.Close() })
+	if err := client.Schema.Create(context.Background()); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+
+	cache := &recordingCache{}
+	m, err := NewRBACManager(casbinadapter.NewEntAdapter(client), cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, client, cache
+}
+
+func storedRoles(t *testing.T, client *ent.Client, user, domain string) map[string]bool {
+	t.Helper()
+	rows, err := client.CasbinPolicy.Query().
+		Where(casbinpolicy.PtypeEQ("g"), casbinpolicy.V0EQ(user), casbinpolicy.V2EQ(domain)).
+		All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles := make(map[string]bool)
+	for _, r := range rows {
+		roles[r.V1] = true
+	}
+	return roles
+}
+
+func TestAssignRoles(t *testing.T) {
+	m, client, cache := newTestManager(t)
+	ctx := context.Background()
+	if err := m.AssignRole(ctx, "u1", "viewer", "d1"); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := m.AssignRoles(ctx, []Assignment{
+		{UserUUID: "u1", RoleCode: "viewer", Domain: "d1"},
+		{UserUUID: "u1", RoleCode: "editor", Domain: "d1"},
+		{UserUUID: "u1", RoleCode: "editor", Domain: "d1"},
+		{UserUUID: "u2", RoleCode: "admin", Domain: "d2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Added) != 2 || len(report.Unchanged) != 1 || len(report.Removed) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	if !m.enforcer.HasGroupingPolicy("u2", "admin", "d2") {
+		t.Error("in-memory model not updated")
+	}
+	if got := storedRoles(t, client, "u1", "d1"); !got["viewer"] || !got["editor"] || len(got) != 2 {
+		t.Errorf("stored roles = %v", got)
+	}
+	if !contains(cache.deleted, "rbac:user_roles:u2:d2") {
+		t.Errorf("cache not invalidated: %v", cache.deleted)
+	}
+
+	if _, err := m.AssignRoles(ctx, []Assignment{{UserUUID: "u3", RoleCode: "x"}}); err == nil {
+		t.Error("missing domain should be rejected")
+	}
+}
+
+func TestSyncUserRoles(t *testing.T) {
+	m, client, _ := newTestManager(t)
+	ctx := context.Background()
+	if _, err := m.AssignRoles(ctx, []Assignment{
+		{UserUUID: "u1", RoleCode: "viewer", Domain: "d1"},
+		{UserUUID: "u1", RoleCode: "billing", Domain: "d1"},
+		{UserUUID: "u1", RoleCode: "admin", Domain: "d2"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := m.SyncUserRoles(ctx, "u1", "d1", []string{"viewer", "editor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Added) != 1 || report.Added[0].RoleCode != "editor" ||
+		len(report.Removed) != 1 || report.Removed[0].RoleCode != "billing" ||
+		len(report.Unchanged) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	if got := storedRoles(t, client, "u1", "d1"); len(got) != 2 || !got["viewer"] || !got["editor"] {
+		t.Errorf("stored roles = %v", got)
+	}
+	if !m.enforcer.HasGroupingPolicy("u1", "admin", "d2") {
+		t.Error("roles in other domains must be untouched")
+	}
+
+	report, err = m.SyncUserRoles(ctx, "u1", "d1", []string{"editor", "viewer"})
+	if err != nil || report.Changed() {
+		t.Fatalf("second sync should be a no-op: %+v, %v", report, err)
+	}
+}
+
+func TestSyncUserRolesRollsBack(t *testing.T) {
+	m, client, _ := newTestManager(t)
+	ctx := context.Background()
+	if err := m.AssignRole(ctx, "u1", "viewer", "d1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第二条新增失败时，已执行的删除与新增都应回滚
+	inserts := 0
+	client.CasbinPolicy.Use(func(next ent.Mutator) ent.Mutator {
+		return hook.CasbinPolicyFunc(func(ctx context.Context, mut *ent.CasbinPolicyMutation) (ent.Value, error) {
+			if mut.Op().Is(ent.OpCreate) {
+				if inserts++; inserts == 2 {
+					return nil, errors.New("boom")
+				}
+			}
+			return next.Mutate(ctx, mut)
+		})
+	})
+
+	if _, err := m.SyncUserRoles(ctx, "u1", "d1", []string{"editor", "owner"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := storedRoles(t, client, "u1", "d1"); len(got) != 1 || !got["viewer"] {
+		t.Errorf("stored roles after rollback = %v", got)
+	}
+	if !m.enforcer.HasGroupingPolicy("u1", "viewer", "d1") || m.enforcer.HasGroupingPolicy("u1", "editor", "d1") {
+		t.Error("in-memory model changed despite failure")
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
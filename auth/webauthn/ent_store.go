@@ -0,0 +1,122 @@
+package webauthn
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/leeforge/framework/ent"
+	"github.com/leeforge/framework/ent/webauthncredential"
+)
+
+// EntCredentialStore 基于 Ent 的凭证存储
+type EntCredentialStore struct {
+	client *ent.Client
+}
+
+// NewEntCredentialStore 创建 Ent 凭证存储
+func NewEntCredentialStore(client *ent.Client) *EntCredentialStore {
+	return &EntCredentialStore{client: client}
+}
+
+// Create 保存凭证
+func (s *EntCredentialStore) Create(ctx context.Context, cred *Credential) error {
+	builder := s.client.WebAuthnCredential.Create().
+		SetCredentialID(cred.ID).
+		SetUserHandle(cred.UserHandle).
+		SetPublicKey(cred.PublicKey).
+		SetAttestationFormat(cred.AttestationFormat).
+		SetAaguid(cred.AAGUID).
+		SetSignCount(cred.SignCount).
+		SetTransports(cred.Transports).
+		SetBackupEligible(cred.BackupEligible).
+		SetBackupState(cred.BackupState)
+	if !cred.CreatedAt.IsZero() {
+		builder.SetCreatedAt(cred.CreatedAt)
+	}
+
+	if _, err := builder.Save(ctx); err != nil {
+		if ent.IsConstraintError(err) {
+			return ErrCredentialExists
+		}
+		return fmt.Errorf("failed to create webauthn credential: %w", err)
+	}
+	return nil
+}
+
+// GetByID 按凭证 ID 查询
+func (s *EntCredentialStore) GetByID(ctx context.Context, id []byte) (*Credential, error) {
+	row, err := s.client.WebAuthnCredential.Query().
+		Where(webauthncredential.CredentialIDEQ(id)).
+		Only(ctx)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			return nil, ErrCredentialNotFound
+		}
+		return nil, fmt.Errorf("failed to query webauthn credential: %w", err)
+	}
+	return credentialFromEnt(row), nil
+}
+
+// ListByUser 列出用户的全部凭证
+func (s *EntCredentialStore) ListByUser(ctx context.Context, userHandle []byte) ([]*Credential, error) {
+	rows, err := s.client.WebAuthnCredential.Query().
+		Where(webauthncredential.UserHandleEQ(userHandle)).
+		Order(ent.Asc(webauthncredential.FieldCreatedAt)).
+		All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webauthn credentials: %w", err)
+	}
+
+	result := make([]*Credential, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, credentialFromEnt(row))
+	}
+	return result, nil
+}
+
+// UpdateSignCount 更新签名计数与最近使用时间
+func (s *EntCredentialStore) UpdateSignCount(ctx context.Context, id []byte, signCount uint32, usedAt time.Time) error {
+	n, err := s.client.WebAuthnCredential.Update().
+		Where(webauthncredential.CredentialIDEQ(id)).
+		SetSignCount(signCount).
+		SetLastUsedAt(usedAt).
+		Save(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update webauthn credential: %w", err)
+	}
+	if n == 0 {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
+
+// Delete 删除凭证
+func (s *EntCredentialStore) Delete(ctx context.Context, id []byte) error {
+	n, err := s.client.WebAuthnCredential.Delete().
+		Where(webauthncredential.CredentialIDEQ(id)).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete webauthn credential: %w", err)
+	}
+	if n == 0 {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
+
+func credentialFromEnt(row *ent.WebAuthnCredential) *Credential {
+	return &Credential{
+		ID:                row.CredentialID,
+		UserHandle:        row.UserHandle,
+		PublicKey:         row.PublicKey,
+		AttestationFormat: row.AttestationFormat,
+		AAGUID:            row.Aaguid,
+		SignCount:         row.SignCount,
+		Transports:        row.Transports,
+		BackupEligible:    row.BackupEligible,
+		BackupState:       row.BackupState,
+		CreatedAt:         row.CreatedAt,
+		LastUsedAt:        row.LastUsedAt,
+	}
+}
@@ -0,0 +1,367 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// COSE 算法标识
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// 认证器数据标志位
+const (
+	flagUserPresent    byte = 0x01
+	flagUserVerified   byte = 0x04
+	flagBackupEligible byte = 0x08
+	flagBackupState    byte = 0x10
+	flagAttestedData   byte = 0x40
+)
+
+const (
+	clientDataTypeCreate = "webauthn.create"
+	clientDataTypeGet    = "webauthn.get"
+)
+
+// URLEncodedBytes base64url 编码的字节串
+// 序列化时不带填充，反序列化时兼容带填充的输入
+type URLEncodedBytes []byte
+
+// MarshalJSON 实现 json.Marshaler
+func (b URLEncodedBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler
+func (b *URLEncodedBytes) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*b = nil
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return fmt.Errorf("invalid base64url value: %w", err)
+	}
+	*b = decoded
+	return nil
+}
+
+// collectedClientData 浏览器生成的 clientDataJSON
+type collectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin,omitempty"`
+}
+
+// verifyClientData 校验 clientDataJSON 的类型、挑战与来源，返回其 SHA-256 摘要
+func verifyClientData(raw []byte, ceremony string, challenge []byte, origins []string) ([]byte, error) {
+	var cd collectedClientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return nil, verificationError("malformed client data: %v", err)
+	}
+	if cd.Type != ceremony {
+		return nil, verificationError("unexpected client data type %q", cd.Type)
+	}
+	got, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(cd.Challenge, "="))
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return nil, verificationError("challenge mismatch")
+	}
+	if !originAllowed(cd.Origin, origins) {
+		return nil, verificationError("origin %q is not allowed", cd.Origin)
+	}
+	sum := sha256.Sum256(raw)
+	return sum[:], nil
+}
+
+func originAllowed(origin string, allowed []string) bool {
+	for _, o := range allowed {
+		if strings.EqualFold(strings.TrimRight(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticatorData 解析后的认证器数据
+type authenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+func (a *authenticatorData) has(flag byte) bool {
+	return a.Flags&flag != 0
+}
+
+// parseAuthenticatorData 解析 authenticatorData 二进制结构
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, verificationError("authenticator data too short")
+	}
+	ad := &authenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	if !ad.has(flagAttestedData) {
+		return ad, nil
+	}
+
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return nil, verificationError("attested credential data too short")
+	}
+	ad.AAGUID = rest[:16]
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLen {
+		return nil, verificationError("credential id length exceeds data")
+	}
+	ad.CredentialID = rest[:idLen]
+	rest = rest[idLen:]
+
+	_, n, err := decodeCBOR(rest)
+	if err != nil {
+		return nil, verificationError("malformed credential public key: %v", err)
+	}
+	ad.PublicKey = rest[:n]
+	return ad, nil
+}
+
+// parseCOSEKey 将 COSE_Key 解析为 Go 公钥，并返回其声明的算法
+func parseCOSEKey(raw []byte) (crypto.PublicKey, int64, error) {
+	v, _, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, 0, err
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, 0, fmt.Errorf("cose key is not a map")
+	}
+	kty, _ := m[int64(1)].(int64)
+	alg, _ := m[int64(3)].(int64)
+
+	switch kty {
+	case 2: // EC2
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, fmt.Errorf("unsupported EC2 key")
+		}
+		point := append([]byte{0x04}, append(x, y...)...)
+		key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return nil, 0, err
+		}
+		return key, alg, nil
+	case 3: // RSA
+		n, _ := m[int64(-1)].([]byte)
+		e, _ := m[int64(-2)].([]byte)
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, fmt.Errorf("unsupported RSA key")
+		}
+		exp := new(big.Int).SetBytes(e)
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, alg, nil
+	case 1: // OKP
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, fmt.Errorf("unsupported OKP key")
+		}
+		return ed25519.PublicKey(x), alg, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported key type %d", kty)
+	}
+}
+
+// verifySignature 使用公钥校验签名
+func verifySignature(pub crypto.PublicKey, alg int64, data, sig []byte) error {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if alg != AlgES256 {
+			break
+		}
+		digest := sha256.Sum256(data)
+		if ecdsa.VerifyASN1(key, digest[:], sig) {
+			return nil
+		}
+		return verificationError("invalid signature")
+	case *rsa.PublicKey:
+		if alg != AlgRS256 {
+			break
+		}
+		digest := sha256.Sum256(data)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return verificationError("invalid signature")
+		}
+		return nil
+	case ed25519.PublicKey:
+		if alg != AlgEdDSA {
+			break
+		}
+		if ed25519.Verify(key, data, sig) {
+			return nil
+		}
+		return verificationError("invalid signature")
+	}
+	return verificationError("algorithm %d does not match key type %T", alg, pub)
+}
+
+// attestationObject 注册时返回的证明对象
+type attestationObject struct {
+	Format   string
+	Stmt     map[any]any
+	AuthData []byte
+}
+
+func parseAttestationObject(raw []byte) (*attestationObject, error) {
+	v, _, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, verificationError("malformed attestation object: %v", err)
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, verificationError("attestation object is not a map")
+	}
+	obj := &attestationObject{}
+	obj.Format, _ = m["fmt"].(string)
+	obj.Stmt, _ = m["attStmt"].(map[any]any)
+	obj.AuthData, _ = m["authData"].([]byte)
+	if obj.Format == "" || obj.AuthData == nil {
+		return nil, verificationError("attestation object is incomplete")
+	}
+	return obj, nil
+}
+
+// verifyAttestation 校验证明声明，返回证明是否链接到受信任根证书
+func verifyAttestation(policy AttestationPolicy, obj *attestationObject, ad *authenticatorData, clientDataHash []byte, credKey crypto.PublicKey, credAlg int64) (bool, error) {
+	signed := append(append([]byte{}, obj.AuthData...), clientDataHash...)
+
+	switch obj.Format {
+	case "none":
+		if len(obj.Stmt) != 0 {
+			return false, verificationError("none attestation must have an empty statement")
+		}
+		return false, nil
+
+	case "packed":
+		alg, _ := obj.Stmt["alg"].(int64)
+		sig, _ := obj.Stmt["sig"].([]byte)
+		if len(sig) == 0 {
+			return false, verificationError("packed attestation is missing a signature")
+		}
+		chain, err := parseX5C(obj.Stmt)
+		if err != nil {
+			return false, err
+		}
+		if len(chain) == 0 {
+			// 自证明：使用凭证私钥签名
+			if alg != credAlg {
+				return false, verificationError("self attestation algorithm mismatch")
+			}
+			return false, verifySignature(credKey, alg, signed, sig)
+		}
+		if err := verifySignature(chain[0].PublicKey, alg, signed, sig); err != nil {
+			return false, err
+		}
+		return verifyChain(policy, chain)
+
+	case "fido-u2f":
+		sig, _ := obj.Stmt["sig"].([]byte)
+		chain, err := parseX5C(obj.Stmt)
+		if err != nil {
+			return false, err
+		}
+		if len(chain) != 1 || len(sig) == 0 {
+			return false, verificationError("fido-u2f attestation requires exactly one certificate")
+		}
+		ecKey, ok := credKey.(*ecdsa.PublicKey)
+		if !ok {
+			return false, verificationError("fido-u2f requires an EC2 P-256 credential")
+		}
+		point, err := ecKey.Bytes()
+		if err != nil {
+			return false, verificationError("invalid credential key: %v", err)
+		}
+		var data bytes.Buffer
+		data.WriteByte(0x00)
+		data.Write(ad.RPIDHash)
+		data.Write(clientDataHash)
+		data.Write(ad.CredentialID)
+		data.Write(point)
+		if err := verifySignature(chain[0].PublicKey, AlgES256, data.Bytes(), sig); err != nil {
+			return false, err
+		}
+		return verifyChain(policy, chain)
+
+	default:
+		return false, fmt.Errorf("%w: %s", ErrUnsupportedAttestation, obj.Format)
+	}
+}
+
+func parseX5C(stmt map[any]any) ([]*x509.Certificate, error) {
+	raw, ok := stmt["x5c"].([]any)
+	if !ok {
+		return nil, nil
+	}
+	chain := make([]*x509.Certificate, 0, len(raw))
+	for _, item := range raw {
+		der, ok := item.([]byte)
+		if !ok {
+			return nil, verificationError("x5c entry is not a byte string")
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, verificationError("invalid attestation certificate: %v", err)
+		}
+		chain = append(chain, cert)
+	}
+	return chain, nil
+}
+
+func verifyChain(policy AttestationPolicy, chain []*x509.Certificate) (bool, error) {
+	if policy.RootCAs == nil {
+		return false, nil
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         policy.RootCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		if policy.RequireTrusted {
+			return false, verificationError("attestation certificate is not trusted: %v", err)
+		}
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,179 @@
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// Credential 已注册的 WebAuthn 凭证
+type Credential struct {
+	ID                []byte     `json:"id"`
+	UserHandle        []byte     `json:"user_handle"`
+	PublicKey         []byte     `json:"public_key"`
+	AttestationFormat string     `json:"attestation_format"`
+	AAGUID            []byte     `json:"aaguid,omitempty"`
+	SignCount         uint32     `json:"sign_count"`
+	Transports        []string   `json:"transports,omitempty"`
+	BackupEligible    bool       `json:"backup_eligible"`
+	BackupState       bool       `json:"backup_state"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastUsedAt        *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Descriptor 返回用于 allowCredentials / excludeCredentials 的凭证描述符
+func (c *Credential) Descriptor() CredentialDescriptor {
+	return CredentialDescriptor{
+		Type:       "public-key",
+		ID:         c.ID,
+		Transports: c.Transports,
+	}
+}
+
+// CredentialStore 凭证存储接口
+type CredentialStore interface {
+	Create(ctx context.Context, cred *Credential) error
+	GetByID(ctx context.Context, id []byte) (*Credential, error)
+	ListByUser(ctx context.Context, userHandle []byte) ([]*Credential, error)
+	UpdateSignCount(ctx context.Context, id []byte, signCount uint32, usedAt time.Time) error
+	Delete(ctx context.Context, id []byte) error
+}
+
+// Session 仪式进行中的挑战状态
+type Session struct {
+	Ceremony           string    `json:"ceremony"`
+	Challenge          []byte    `json:"challenge"`
+	UserHandle         []byte    `json:"user_handle,omitempty"`
+	AllowedCredentials [][]byte  `json:"allowed_credentials,omitempty"`
+	UserVerification   string    `json:"user_verification"`
+	ExpiresAt          time.Time `json:"expires_at"`
+}
+
+// ChallengeStore 挑战存储接口
+// Take 必须在返回时删除会话，保证挑战只能使用一次
+type ChallengeStore interface {
+	Save(ctx context.Context, id string, session *Session) error
+	Take(ctx context.Context, id string) (*Session, error)
+}
+
+// MemoryChallengeStore 内存挑战存储，适用于单实例部署
+type MemoryChallengeStore struct {
+	sessions map[string]*Session
+	mu       sync.Mutex
+}
+
+// NewMemoryChallengeStore 创建内存挑战存储
+func NewMemoryChallengeStore() *MemoryChallengeStore {
+	return &MemoryChallengeStore{
+		sessions: make(map[string]*Session),
+	}
+}
+
+// Save 保存会话，并顺带清理已过期的会话
+func (s *MemoryChallengeStore) Save(ctx context.Context, id string, session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, existing := range s.sessions {
+		if now.After(existing.ExpiresAt) {
+			delete(s.sessions, key)
+		}
+	}
+	s.sessions[id] = session
+	return nil
+}
+
+// Take 取出并删除会话
+func (s *MemoryChallengeStore) Take(ctx context.Context, id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	delete(s.sessions, id)
+	return session, nil
+}
+
+// MemoryCredentialStore 内存凭证存储，适用于测试与开发环境
+type MemoryCredentialStore struct {
+	credentials map[string]*Credential
+	mu          sync.RWMutex
+}
+
+// NewMemoryCredentialStore 创建内存凭证存储
+func NewMemoryCredentialStore() *MemoryCredentialStore {
+	return &MemoryCredentialStore{
+		credentials: make(map[string]*Credential),
+	}
+}
+
+// Create 保存凭证
+func (s *MemoryCredentialStore) Create(ctx context.Context, cred *Credential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.credentials[string(cred.ID)]; exists {
+		return ErrCredentialExists
+	}
+	copied := *cred
+	s.credentials[string(cred.ID)] = &copied
+	return nil
+}
+
+// GetByID 按凭证 ID 查询
+func (s *MemoryCredentialStore) GetByID(ctx context.Context, id []byte) (*Credential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cred, ok := s.credentials[string(id)]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	copied := *cred
+	return &copied, nil
+}
+
+// ListByUser 列出用户的全部凭证
+func (s *MemoryCredentialStore) ListByUser(ctx context.Context, userHandle []byte) ([]*Credential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*Credential
+	for _, cred := range s.credentials {
+		if bytes.Equal(cred.UserHandle, userHandle) {
+			copied := *cred
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+// UpdateSignCount 更新签名计数与最近使用时间
+func (s *MemoryCredentialStore) UpdateSignCount(ctx context.Context, id []byte, signCount uint32, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cred, ok := s.credentials[string(id)]
+	if !ok {
+		return ErrCredentialNotFound
+	}
+	cred.SignCount = signCount
+	cred.LastUsedAt = &usedAt
+	return nil
+}
+
+// Delete 删除凭证
+func (s *MemoryCredentialStore) Delete(ctx context.Context, id []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.credentials[string(id)]; !ok {
+		return ErrCredentialNotFound
+	}
+	delete(s.credentials, string(id))
+	return nil
+}
@@ -0,0 +1,551 @@
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/leeforge/framework/auth/mfa"
+)
+
+var (
+	ErrSessionNotFound        = errors.New("webauthn: session not found")
+	ErrSessionExpired         = errors.New("webauthn: session expired")
+	ErrCredentialNotFound     = errors.New("webauthn: credential not found")
+	ErrCredentialExists       = errors.New("webauthn: credential already registered")
+	ErrNoCredentials          = errors.New("webauthn: user has no registered credentials")
+	ErrVerificationFailed     = errors.New("webauthn: verification failed")
+	ErrUnsupportedAttestation = errors.New("webauthn: unsupported attestation format")
+	ErrCloneDetected          = errors.New("webauthn: signature counter regressed, authenticator may be cloned")
+	ErrNotPrimaryFactor       = errors.New("webauthn: assertion without user verification cannot be a primary factor")
+)
+
+// 用户验证要求
+const (
+	UserVerificationRequired    = "required"
+	UserVerificationPreferred   = "preferred"
+	UserVerificationDiscouraged = "discouraged"
+)
+
+const (
+	defaultTimeout = 5 * time.Minute
+	challengeSize  = 32
+)
+
+// AttestationPolicy 证明校验策略
+type AttestationPolicy struct {
+	// Conveyance 发送给客户端的 attestation 偏好：none / indirect / direct / enterprise
+	Conveyance string
+	// AllowedFormats 允许的证明格式，为空时允许全部已支持的格式（none、packed、fido-u2f）
+	AllowedFormats []string
+	// RootCAs 受信任的认证器根证书
+	RootCAs *x509.CertPool
+	// RequireTrusted 要求证明链接到 RootCAs，拒绝自证明与 none
+	RequireTrusted bool
+}
+
+// Config WebAuthn 配置
+type Config struct {
+	RPID             string
+	RPName           string
+	RPOrigins        []string
+	Timeout          time.Duration
+	UserVerification string
+	ResidentKey      string
+	Algorithms       []int64
+	Attestation      AttestationPolicy
+}
+
+// User 参与仪式的用户
+type User interface {
+	WebAuthnID() []byte
+	WebAuthnName() string
+	WebAuthnDisplayName() string
+}
+
+// WebAuthn 注册与认证仪式处理器
+type WebAuthn struct {
+	config      Config
+	rpIDHash    []byte
+	credentials CredentialStore
+	sessions    ChallengeStore
+}
+
+// New 创建 WebAuthn 处理器，sessions 为 nil 时使用内存挑战存储
+func New(cfg Config, credentials CredentialStore, sessions ChallengeStore) (*WebAuthn, error) {
+	if cfg.RPID == "" {
+		return nil, fmt.Errorf("webauthn: RPID is required")
+	}
+	if len(cfg.RPOrigins) == 0 {
+		return nil, fmt.Errorf("webauthn: at least one origin is required")
+	}
+	if credentials == nil {
+		return nil, fmt.Errorf("webauthn: credential store is required")
+	}
+	if cfg.RPName == "" {
+		cfg.RPName = cfg.RPID
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.UserVerification == "" {
+		cfg.UserVerification = UserVerificationPreferred
+	}
+	if cfg.ResidentKey == "" {
+		cfg.ResidentKey = "preferred"
+	}
+	if len(cfg.Algorithms) == 0 {
+		cfg.Algorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+	}
+	if cfg.Attestation.Conveyance == "" {
+		cfg.Attestation.Conveyance = "none"
+	}
+	if sessions == nil {
+		sessions = NewMemoryChallengeStore()
+	}
+
+	hash := sha256.Sum256([]byte(cfg.RPID))
+	return &WebAuthn{
+		config:      cfg,
+		rpIDHash:    hash[:],
+		credentials: credentials,
+		sessions:    sessions,
+	}, nil
+}
+
+// RelyingPartyEntity 依赖方信息
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity 用户信息
+type UserEntity struct {
+	ID          URLEncodedBytes `json:"id"`
+	Name        string          `json:"name"`
+	DisplayName string          `json:"displayName"`
+}
+
+// CredentialParameter 支持的公钥算法
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// CredentialDescriptor 凭证描述符
+type CredentialDescriptor struct {
+	Type       string          `json:"type"`
+	ID         URLEncodedBytes `json:"id"`
+	Transports []string        `json:"transports,omitempty"`
+}
+
+// AuthenticatorSelection 认证器选择条件
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey,omitempty"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification,omitempty"`
+}
+
+// CredentialCreationOptions navigator.credentials.create() 的 publicKey 参数
+type CredentialCreationOptions struct {
+	Challenge              URLEncodedBytes        `json:"challenge"`
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation,omitempty"`
+}
+
+// CredentialRequestOptions navigator.credentials.get() 的 publicKey 参数
+type CredentialRequestOptions struct {
+	Challenge        URLEncodedBytes        `json:"challenge"`
+	Timeout          int64                  `json:"timeout,omitempty"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                 `json:"userVerification,omitempty"`
+}
+
+// RegistrationResponse 客户端返回的注册结果
+type RegistrationResponse struct {
+	ID       string          `json:"id"`
+	RawID    URLEncodedBytes `json:"rawId"`
+	Type     string          `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"`
+		AttestationObject URLEncodedBytes `json:"attestationObject"`
+		Transports        []string        `json:"transports,omitempty"`
+	} `json:"response"`
+}
+
+// AssertionResponse 客户端返回的认证结果
+type AssertionResponse struct {
+	ID       string          `json:"id"`
+	RawID    URLEncodedBytes `json:"rawId"`
+	Type     string          `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"`
+		AuthenticatorData URLEncodedBytes `json:"authenticatorData"`
+		Signature         URLEncodedBytes `json:"signature"`
+		UserHandle        URLEncodedBytes `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+// LoginResult 认证仪式结果
+type LoginResult struct {
+	Credential   *Credential
+	UserVerified bool
+	// VerifiedAt 仪式完成时间
+	VerifiedAt time.Time
+}
+
+// CanBePrimary 是否可作为主要登录因素
+// 经过用户验证（PIN / 生物识别）的通行密钥本身即满足多因素要求；
+// 未经验证的断言仅证明持有，只能作为第二因素使用
+func (r *LoginResult) CanBePrimary() bool {
+	return r.UserVerified
+}
+
+// Session 以通行密钥登录时新会话的 MFA 状态，签发令牌时写入（如 amr / mfa_at
+// 声明），认证中间件再通过 mfa.WithSession 还原供 mfa.Require 检查。
+// 未经用户验证的断言返回 ErrNotPrimaryFactor，应先完成密码等主要因素再调用 StepUp
+func (r *LoginResult) Session(userID string) (mfa.Session, error) {
+	if !r.CanBePrimary() {
+		return mfa.Session{}, ErrNotPrimaryFactor
+	}
+	return mfa.Session{UserID: userID, Enrolled: true, VerifiedAt: r.VerifiedAt}, nil
+}
+
+// StepUp 已登录会话完成 WebAuthn 验证后的 MFA 状态，用于刷新令牌中的 MFA 时间。
+// userHandle 为会话用户的 WebAuthnID，凭证属于其他用户时返回 ErrVerificationFailed
+func (r *LoginResult) StepUp(s mfa.Session, userHandle []byte) (mfa.Session, error) {
+	if !bytes.Equal(r.Credential.UserHandle, userHandle) {
+		return mfa.Session{}, verificationError("credential belongs to a different user")
+	}
+	s.Enrolled = true
+	s.VerifiedAt = r.VerifiedAt
+	return s, nil
+}
+
+// CeremonyOption 仪式选项
+type CeremonyOption func(*ceremonyOptions)
+
+type ceremonyOptions struct {
+	userVerification string
+}
+
+// WithUserVerification 覆盖本次仪式的用户验证要求
+// 用作主要登录因素时应传入 UserVerificationRequired
+func WithUserVerification(level string) CeremonyOption {
+	return func(o *ceremonyOptions) {
+		o.userVerification = level
+	}
+}
+
+func (w *WebAuthn) applyOptions(opts []CeremonyOption) ceremonyOptions {
+	o := ceremonyOptions{userVerification: w.config.UserVerification}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// BeginRegistration 开始注册仪式，返回客户端参数与会话 ID
+func (w *WebAuthn) BeginRegistration(ctx context.Context, user User, opts ...CeremonyOption) (*CredentialCreationOptions, string, error) {
+	o := w.applyOptions(opts)
+
+	existing, err := w.credentials.ListByUser(ctx, user.WebAuthnID())
+	if err != nil {
+		return nil, "", err
+	}
+	exclude := make([]CredentialDescriptor, 0, len(existing))
+	for _, cred := range existing {
+		exclude = append(exclude, cred.Descriptor())
+	}
+
+	params := make([]CredentialParameter, 0, len(w.config.Algorithms))
+	for _, alg := range w.config.Algorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+
+	session := &Session{
+		Ceremony:         clientDataTypeCreate,
+		UserHandle:       user.WebAuthnID(),
+		UserVerification: o.userVerification,
+	}
+	sessionID, err := w.startSession(ctx, session)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &CredentialCreationOptions{
+		Challenge: session.Challenge,
+		RP:        RelyingPartyEntity{ID: w.config.RPID, Name: w.config.RPName},
+		User: UserEntity{
+			ID:          user.WebAuthnID(),
+			Name:        user.WebAuthnName(),
+			DisplayName: user.WebAuthnDisplayName(),
+		},
+		PubKeyCredParams:   params,
+		Timeout:            w.config.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        w.config.ResidentKey,
+			RequireResidentKey: w.config.ResidentKey == "required",
+			UserVerification:   o.userVerification,
+		},
+		Attestation: w.config.Attestation.Conveyance,
+	}, sessionID, nil
+}
+
+// FinishRegistration 完成注册仪式，校验通过后保存凭证
+func (w *WebAuthn) FinishRegistration(ctx context.Context, sessionID string, user User, resp *RegistrationResponse) (*Credential, error) {
+	session, err := w.takeSession(ctx, sessionID, clientDataTypeCreate)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(session.UserHandle, user.WebAuthnID()) {
+		return nil, verificationError("session belongs to a different user")
+	}
+
+	clientDataHash, err := verifyClientData(resp.Response.ClientDataJSON, clientDataTypeCreate, session.Challenge, w.config.RPOrigins)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := parseAttestationObject(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+	ad, err := parseAuthenticatorData(obj.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.checkAuthenticatorData(ad, session.UserVerification); err != nil {
+		return nil, err
+	}
+	if !ad.has(flagAttestedData) {
+		return nil, verificationError("attested credential data missing")
+	}
+	if !bytes.Equal(ad.CredentialID, resp.RawID) {
+		return nil, verificationError("credential id mismatch")
+	}
+
+	credKey, credAlg, err := parseCOSEKey(ad.PublicKey)
+	if err != nil {
+		return nil, verificationError("invalid credential public key: %v", err)
+	}
+	if !slices.Contains(w.config.Algorithms, credAlg) {
+		return nil, verificationError("credential algorithm %d is not allowed", credAlg)
+	}
+
+	policy := w.config.Attestation
+	if len(policy.AllowedFormats) > 0 && !slices.Contains(policy.AllowedFormats, obj.Format) {
+		return nil, fmt.Errorf("%w: %s is not allowed", ErrUnsupportedAttestation, obj.Format)
+	}
+	trusted, err := verifyAttestation(policy, obj, ad, clientDataHash, credKey, credAlg)
+	if err != nil {
+		return nil, err
+	}
+	if policy.RequireTrusted && !trusted {
+		return nil, verificationError("trusted attestation is required")
+	}
+
+	if _, err := w.credentials.GetByID(ctx, ad.CredentialID); err == nil {
+		return nil, ErrCredentialExists
+	} else if !errors.Is(err, ErrCredentialNotFound) {
+		return nil, err
+	}
+
+	cred := &Credential{
+		ID:                ad.CredentialID,
+		UserHandle:        session.UserHandle,
+		PublicKey:         ad.PublicKey,
+		AttestationFormat: obj.Format,
+		AAGUID:            ad.AAGUID,
+		SignCount:         ad.SignCount,
+		Transports:        resp.Response.Transports,
+		BackupEligible:    ad.has(flagBackupEligible),
+		BackupState:       ad.has(flagBackupState),
+		CreatedAt:         time.Now(),
+	}
+	if err := w.credentials.Create(ctx, cred); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// BeginLogin 开始认证仪式
+// userHandle 为空时发起无用户名（可发现凭证）登录
+func (w *WebAuthn) BeginLogin(ctx context.Context, userHandle []byte, opts ...CeremonyOption) (*CredentialRequestOptions, string, error) {
+	o := w.applyOptions(opts)
+
+	session := &Session{
+		Ceremony:         clientDataTypeGet,
+		UserHandle:       userHandle,
+		UserVerification: o.userVerification,
+	}
+
+	var allow []CredentialDescriptor
+	if len(userHandle) > 0 {
+		creds, err := w.credentials.ListByUser(ctx, userHandle)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(creds) == 0 {
+			return nil, "", ErrNoCredentials
+		}
+		for _, cred := range creds {
+			allow = append(allow, cred.Descriptor())
+			session.AllowedCredentials = append(session.AllowedCredentials, cred.ID)
+		}
+	}
+
+	sessionID, err := w.startSession(ctx, session)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &CredentialRequestOptions{
+		Challenge:        session.Challenge,
+		Timeout:          w.config.Timeout.Milliseconds(),
+		RPID:             w.config.RPID,
+		AllowCredentials: allow,
+		UserVerification: o.userVerification,
+	}, sessionID, nil
+}
+
+// FinishLogin 完成认证仪式，校验签名并更新签名计数
+func (w *WebAuthn) FinishLogin(ctx context.Context, sessionID string, resp *AssertionResponse) (*LoginResult, error) {
+	session, err := w.takeSession(ctx, sessionID, clientDataTypeGet)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(session.AllowedCredentials) > 0 && !containsID(session.AllowedCredentials, resp.RawID) {
+		return nil, verificationError("credential is not allowed for this session")
+	}
+
+	cred, err := w.credentials.GetByID(ctx, resp.RawID)
+	if err != nil {
+		return nil, err
+	}
+	if len(session.UserHandle) > 0 && !bytes.Equal(session.UserHandle, cred.UserHandle) {
+		return nil, verificationError("credential belongs to a different user")
+	}
+	if len(session.UserHandle) == 0 && len(resp.Response.UserHandle) == 0 {
+		return nil, verificationError("user handle is required for discoverable login")
+	}
+	if len(resp.Response.UserHandle) > 0 && !bytes.Equal(resp.Response.UserHandle, cred.UserHandle) {
+		return nil, verificationError("user handle mismatch")
+	}
+
+	clientDataHash, err := verifyClientData(resp.Response.ClientDataJSON, clientDataTypeGet, session.Challenge, w.config.RPOrigins)
+	if err != nil {
+		return nil, err
+	}
+	ad, err := parseAuthenticatorData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.checkAuthenticatorData(ad, session.UserVerification); err != nil {
+		return nil, err
+	}
+
+	pub, alg, err := parseCOSEKey(cred.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("webauthn: stored public key is invalid: %w", err)
+	}
+	signed := append(append([]byte{}, resp.Response.AuthenticatorData...), clientDataHash...)
+	if err := verifySignature(pub, alg, signed, resp.Response.Signature); err != nil {
+		return nil, err
+	}
+
+	// 计数器为 0 表示认证器不支持计数（常见于同步通行密钥）
+	if (ad.SignCount != 0 || cred.SignCount != 0) && ad.SignCount <= cred.SignCount {
+		return nil, ErrCloneDetected
+	}
+
+	now := time.Now()
+	if err := w.credentials.UpdateSignCount(ctx, cred.ID, ad.SignCount, now); err != nil {
+		return nil, err
+	}
+	cred.SignCount = ad.SignCount
+	cred.LastUsedAt = &now
+	cred.BackupState = ad.has(flagBackupState)
+
+	return &LoginResult{
+		Credential:   cred,
+		UserVerified: ad.has(flagUserVerified),
+		VerifiedAt:   now,
+	}, nil
+}
+
+func (w *WebAuthn) checkAuthenticatorData(ad *authenticatorData, userVerification string) error {
+	if subtle.ConstantTimeCompare(ad.RPIDHash, w.rpIDHash) != 1 {
+		return verificationError("rp id hash mismatch")
+	}
+	if !ad.has(flagUserPresent) {
+		return verificationError("user presence flag not set")
+	}
+	if userVerification == UserVerificationRequired && !ad.has(flagUserVerified) {
+		return verificationError("user verification required")
+	}
+	return nil
+}
+
+func (w *WebAuthn) startSession(ctx context.Context, session *Session) (string, error) {
+	challenge := make([]byte, challengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", fmt.Errorf("webauthn: failed to generate challenge: %w", err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("webauthn: failed to generate session id: %w", err)
+	}
+
+	session.Challenge = challenge
+	session.ExpiresAt = time.Now().Add(w.config.Timeout)
+
+	sessionID := base64.RawURLEncoding.EncodeToString(id)
+	if err := w.sessions.Save(ctx, sessionID, session); err != nil {
+		return "", fmt.Errorf("webauthn: failed to save session: %w", err)
+	}
+	return sessionID, nil
+}
+
+func (w *WebAuthn) takeSession(ctx context.Context, sessionID, ceremony string) (*Session, error) {
+	session, err := w.sessions.Take(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrSessionExpired
+	}
+	if session.Ceremony != ceremony {
+		return nil, verificationError("session is for a different ceremony")
+	}
+	return session, nil
+}
+
+func containsID(ids [][]byte, id []byte) bool {
+	for _, candidate := range ids {
+		if bytes.Equal(candidate, id) {
+			return true
+		}
+	}
+	return false
+}
+
+func verificationError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrVerificationFailed, fmt.Sprintf(format, args...))
+}
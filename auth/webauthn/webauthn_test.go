@@ -0,0 +1,303 @@
+package webauthn
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/auth/mfa"
+)
+
+const testOrigin = "https://example.com"
+
+type testUser struct{ id []byte }
+
+func (u testUser) WebAuthnID() []byte          { return u.id }
+func (u testUser) WebAuthnName() string        { return "alice" }
+func (u testUser) WebAuthnDisplayName() string { return "Alice" }
+
+// fakeAuthenticator 模拟 ES256 认证器
+type fakeAuthenticator struct {
+	key       *ecdsa.PrivateKey
+	id        []byte
+	signCount uint32
+}
+
+func newFakeAuthenticator(t *testing.T) *fakeAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return &fakeAuthenticator{key: key, id: []byte("credential-1")}
+}
+
+func (a *fakeAuthenticator) coseKey() []byte {
+	point, _ := a.key.PublicKey.Bytes()
+	return cborMap(
+		cborInt(1), cborInt(2),
+		cborInt(3), cborInt(AlgES256),
+		cborInt(-1), cborInt(1),
+		cborInt(-2), cborBytes(point[1:33]),
+		cborInt(-3), cborBytes(point[33:]),
+	)
+}
+
+func (a *fakeAuthenticator) authData(rpID string, flags byte, attested bool) []byte {
+	hash := sha256.Sum256([]byte(rpID))
+	out := append([]byte{}, hash[:]...)
+	out = append(out, flags)
+	out = binary.BigEndian.AppendUint32(out, a.signCount)
+	if attested {
+		out = append(out, make([]byte, 16)...)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(a.id)))
+		out = append(out, a.id...)
+		out = append(out, a.coseKey()...)
+	}
+	return out
+}
+
+func (a *fakeAuthenticator) register(rpID string, opts *CredentialCreationOptions) *RegistrationResponse {
+	clientData := clientDataJSON(clientDataTypeCreate, opts.Challenge)
+	authData := a.authData(rpID, flagUserPresent|flagUserVerified|flagAttestedData, true)
+	attObj := cborMap(
+		cborText("fmt"), cborText("none"),
+		cborText("attStmt"), cborMap(),
+		cborText("authData"), cborBytes(authData),
+	)
+
+	resp := &RegistrationResponse{ID: base64.RawURLEncoding.EncodeToString(a.id), RawID: a.id, Type: "public-key"}
+	resp.Response.ClientDataJSON = clientData
+	resp.Response.AttestationObject = attObj
+	return resp
+}
+
+func (a *fakeAuthenticator) assert(rpID string, challenge []byte, flags byte, userHandle []byte) *AssertionResponse {
+	a.signCount++
+	clientData := clientDataJSON(clientDataTypeGet, challenge)
+	authData := a.authData(rpID, flags, false)
+	clientHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientHash[:]...))
+	sig, _ := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+
+	resp := &AssertionResponse{RawID: a.id, Type: "public-key"}
+	resp.Response.ClientDataJSON = clientData
+	resp.Response.AuthenticatorData = authData
+	resp.Response.Signature = sig
+	resp.Response.UserHandle = userHandle
+	return resp
+}
+
+func clientDataJSON(typ string, challenge []byte) []byte {
+	raw, _ := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    testOrigin,
+	})
+	return raw
+}
+
+func cborHead(major byte, n int) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 256:
+		return []byte{major<<5 | 24, byte(n)}
+	default:
+		return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+	}
+}
+
+func cborInt(v int64) []byte {
+	if v < 0 {
+		return cborHead(1, int(-1-v))
+	}
+	return cborHead(0, int(v))
+}
+
+func cborBytes(b []byte) []byte { return append(cborHead(2, len(b)), b...) }
+func cborText(s string) []byte  { return append(cborHead(3, len(s)), s...) }
+
+func cborMap(kv ...[]byte) []byte {
+	out := cborHead(5, len(kv)/2)
+	for _, item := range kv {
+		out = append(out, item...)
+	}
+	return out
+}
+
+func newTestWebAuthn(t *testing.T) *WebAuthn {
+	t.Helper()
+	w, err := New(Config{RPID: "example.com", RPOrigins: []string{testOrigin}}, NewMemoryCredentialStore(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return w
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	ctx := context.Background()
+	w := newTestWebAuthn(t)
+	user := testUser{id: []byte("user-1")}
+	auth := newFakeAuthenticator(t)
+
+	opts, sessionID, err := w.BeginRegistration(ctx, user)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	cred, err := w.FinishRegistration(ctx, sessionID, user, auth.register("example.com", opts))
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	if cred.AttestationFormat != "none" {
+		t.Fatalf("unexpected attestation format %q", cred.AttestationFormat)
+	}
+
+	reqOpts, loginID, err := w.BeginLogin(ctx, user.id)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	if len(reqOpts.AllowCredentials) != 1 {
+		t.Fatalf("expected 1 allowed credential, got %d", len(reqOpts.AllowCredentials))
+	}
+	result, err := w.FinishLogin(ctx, loginID, auth.assert("example.com", reqOpts.Challenge, flagUserPresent|flagUserVerified, nil))
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if !result.CanBePrimary() {
+		t.Fatal("user-verified assertion should be usable as a primary factor")
+	}
+
+	// 会话只能使用一次
+	if _, err := w.FinishLogin(ctx, loginID, auth.assert("example.com", reqOpts.Challenge, flagUserPresent, nil)); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound on replay, got %v", err)
+	}
+}
+
+func TestLoginRejectsBadSignatureAndCounterRegression(t *testing.T) {
+	ctx := context.Background()
+	w := newTestWebAuthn(t)
+	user := testUser{id: []byte("user-1")}
+	auth := newFakeAuthenticator(t)
+
+	opts, sessionID, _ := w.BeginRegistration(ctx, user)
+	if _, err := w.FinishRegistration(ctx, sessionID, user, auth.register("example.com", opts)); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+
+	reqOpts, loginID, _ := w.BeginLogin(ctx, nil)
+	resp := auth.assert("example.com", reqOpts.Challenge, flagUserPresent, user.id)
+	resp.Response.Signature[len(resp.Response.Signature)-1] ^= 0xff
+	if _, err := w.FinishLogin(ctx, loginID, resp); !errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed for tampered signature, got %v", err)
+	}
+
+	reqOpts, loginID, _ = w.BeginLogin(ctx, nil)
+	result, err := w.FinishLogin(ctx, loginID, auth.assert("example.com", reqOpts.Challenge, flagUserPresent, user.id))
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if result.CanBePrimary() {
+		t.Fatal("assertion without user verification must not be a primary factor")
+	}
+
+	auth.signCount = 0
+	reqOpts, loginID, _ = w.BeginLogin(ctx, nil)
+	if _, err := w.FinishLogin(ctx, loginID, auth.assert("example.com", reqOpts.Challenge, flagUserPresent, user.id)); !errors.Is(err, ErrCloneDetected) {
+		t.Fatalf("expected ErrCloneDetected, got %v", err)
+	}
+}
+
+func TestRegistrationRejectsWrongOriginAndRPID(t *testing.T) {
+	ctx := context.Background()
+	w := newTestWebAuthn(t)
+	user := testUser{id: []byte("user-1")}
+	auth := newFakeAuthenticator(t)
+
+	opts, sessionID, _ := w.BeginRegistration(ctx, user)
+	if _, err := w.FinishRegistration(ctx, sessionID, user, auth.register("evil.com", opts)); !errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("expected rp id mismatch, got %v", err)
+	}
+
+	opts, sessionID, _ = w.BeginRegistration(ctx, user)
+	resp := auth.register("example.com", opts)
+	resp.Response.ClientDataJSON = []byte(`{"type":"webauthn.create","challenge":"` +
+		base64.RawURLEncoding.EncodeToString(opts.Challenge) + `","origin":"https://evil.com"}`)
+	if _, err := w.FinishRegistration(ctx, sessionID, user, resp); !errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("expected origin mismatch, got %v", err)
+	}
+}
+
+func TestLoginResultFeedsMFASession(t *testing.T) {
+	ctx := context.Background()
+	w := newTestWebAuthn(t)
+	user := testUser{id: []byte("user-1")}
+	auth := newFakeAuthenticator(t)
+
+	opts, sessionID, _ := w.BeginRegistration(ctx, user)
+	if _, err := w.FinishRegistration(ctx, sessionID, user, auth.register("example.com", opts)); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	login := func(flags byte) *LoginResult {
+		t.Helper()
+		reqOpts, loginID, _ := w.BeginLogin(ctx, user.id)
+		result, err := w.FinishLogin(ctx, loginID, auth.assert("example.com", reqOpts.Challenge, flags, nil))
+		if err != nil {
+			t.Fatalf("FinishLogin: %v", err)
+		}
+		return result
+	}
+
+	// 认证中间件从令牌还原会话后，敏感路由按 MFA 状态放行
+	sensitive := mfa.Require(mfa.RequireOptions{MaxAge: 15 * time.Minute})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+	serve := func(s mfa.Session) int {
+		r := httptest.NewRequest(http.MethodDelete, "/account", nil)
+		r = r.WithContext(mfa.WithSession(r.Context(), s))
+		rec := httptest.NewRecorder()
+		sensitive.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	// 经用户验证的通行密钥登录即满足 MFA
+	session, err := login(flagUserPresent | flagUserVerified).Session("u1")
+	if err != nil {
+		t.Fatalf("Session: %v", err)
+	}
+	if session.UserID != "u1" || session.VerifiedAt.IsZero() {
+		t.Fatalf("unexpected session %+v", session)
+	}
+	if code := serve(session); code != http.StatusNoContent {
+		t.Fatalf("passkey session: %d", code)
+	}
+
+	// 仅证明持有的断言不能单独登录，但可为密码会话完成 step-up
+	result := login(flagUserPresent)
+	if _, err := result.Session("u1"); !errors.Is(err, ErrNotPrimaryFactor) {
+		t.Fatalf("expected ErrNotPrimaryFactor, got %v", err)
+	}
+	password := mfa.Session{UserID: "u1"}
+	if code := serve(password); code != http.StatusForbidden {
+		t.Fatalf("password-only session: %d", code)
+	}
+	stepped, err := result.StepUp(password, user.id)
+	if err != nil {
+		t.Fatalf("StepUp: %v", err)
+	}
+	if code := serve(stepped); code != http.StatusNoContent {
+		t.Fatalf("stepped-up session: %d", code)
+	}
+	if _, err := result.StepUp(password, []byte("user-2")); !errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed for another user's credential, got %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+// Package bus dispatches commands and queries to typed handlers through a
+// middleware pipeline, giving services one application-layer shape:
+// transports decode a request into a message, the bus runs validation,
+// authorization, transactions, tracing and metrics, and the handler holds
+// only the use case.
+//
+//	b := bus.New(bus.Tracing(tracer), bus.Metrics(collector), bus.Validation(),
+//		bus.Authorization(rbacManager), bus.Transaction(txManager))
+//	bus.RegisterCommand(b, users.HandleCreate)
+//	bus.RegisterQuery(b, users.HandleGet)
+//
+//	err := bus.Send(ctx, b, users.Create{Email: "a@example.com"})
+//	user, err := bus.Ask[*ent.User](ctx, b, users.Get{ID: id})
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrNoHandler is returned when no handler is registered for a message type.
+	ErrNoHandler = errors.New("bus: no handler registered")
+	// ErrDuplicateHandler is returned when a message type already has a handler.
+	ErrDuplicateHandler = errors.New("bus: handler already registered")
+)
+
+// Kind tells commands from queries.
+type Kind string
+
+const (
+	// KindCommand marks messages that change state and return only an error.
+	KindCommand Kind = "command"
+	// KindQuery marks messages that read state and return a result.
+	KindQuery Kind = "query"
+)
+
+// Message is a dispatched command or query as seen by middleware.
+type Message struct {
+	Kind Kind
+	// Name identifies the message type in traces, metrics and logs: the
+	// MessageName of a Named payload, else its Go type, e.g. "users.Create".
+	Name    string
+	Payload any
+}
+
+// Named lets a message choose its own name.
+type Named interface {
+	MessageName() string
+}
+
+// CommandHandler handles a command of type C.
+type CommandHandler[C any] func(ctx context.Context, cmd C) error
+
+// QueryHandler handles a query of type Q returning R.
+type QueryHandler[Q, R any] func(ctx context.Context, query Q) (R, error)
+
+// HandlerFunc is the untyped form of a handler that middleware wraps.
+// Commands return a nil result.
+type HandlerFunc func(ctx context.Context, msg Message) (any, error)
+
+// Middleware wraps every dispatch; the first registered runs outermost.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// HandlerProvider is implemented by plugins that contribute handlers. The
+// runtime calls RegisterHandlers after Enable when it is configured with a Bus.
+type HandlerProvider interface {
+	RegisterHandlers(b *Bus) error
+}
+
+// Bus routes messages by their Go type to one handler each.
+type Bus struct {
+	mu         sync.RWMutex
+	handlers   map[reflect.Type]handlerEntry
+	middleware []Middleware
+}
+
+type handlerEntry struct {
+	kind Kind
+	name string
+	fn   HandlerFunc
+}
+
+// New creates a bus running middleware around every dispatch.
+func New(middleware ...Middleware) *Bus {
+	return &Bus{handlers: make(map[reflect.Type]handlerEntry), middleware: middleware}
+}
+
+// Use appends middleware; it applies to dispatches started afterwards.
+func (b *Bus) Use(middleware ...Middleware) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.middleware = append(b.middleware, middleware...)
+}
+
+// Handlers returns the registered message names by kind, sorted.
+func (b *Bus) Handlers() map[Kind][]string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make(map[Kind][]string)
+	for _, e := range b.handlers {
+		out[e.kind] = append(out[e.kind], e.name)
+	}
+	for _, names := range out {
+		sort.Strings(names)
+	}
+	return out
+}
+
+// RegisterCommand registers the handler of command type C.
+func RegisterCommand[C any](b *Bus, h CommandHandler[C]) error {
+	return b.register(reflect.TypeFor[C](), KindCommand, func(ctx context.Context, msg Message) (any, error) {
+		return nil, h(ctx, msg.Payload.(C))
+	})
+}
+
+// RegisterQuery registers the handler of query type Q.
+func RegisterQuery[Q, R any](b *Bus, h QueryHandler[Q, R]) error {
+	return b.register(reflect.TypeFor[Q](), KindQuery, func(ctx context.Context, msg Message) (any, error) {
+		return h(ctx, msg.Payload.(Q))
+	})
+}
+
+// Send dispatches a command.
+func Send[C any](ctx context.Context, b *Bus, cmd C) error {
+	_, err := b.dispatch(ctx, KindCommand, reflect.TypeFor[C](), cmd)
+	return err
+}
+
+// Ask dispatches a query and returns its result. R comes first so it can be
+// given explicitly while Q is inferred: bus.Ask[*ent.User](ctx, b, q).
+func Ask[R, Q any](ctx context.Context, b *Bus, query Q) (R, error) {
+	var zero R
+	v, err := b.dispatch(ctx, KindQuery, reflect.TypeFor[Q](), query)
+	if err != nil || v == nil {
+		return zero, err
+	}
+	r, ok := v.(R)
+	if !ok {
+		return zero, fmt.Errorf("bus: query %T returned %T, want %T", query, v, zero)
+	}
+	return r, nil
+}
+
+func (b *Bus) register(t reflect.Type, kind Kind, fn HandlerFunc) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if existing, ok := b.handlers[t]; ok {
+		return fmt.Errorf("%w: %s %s", ErrDuplicateHandler, existing.kind, existing.name)
+	}
+	b.handlers[t] = handlerEntry{kind: kind, name: typeName(t), fn: fn}
+	return nil
+}
+
+func (b *Bus) dispatch(ctx context.Context, kind Kind, t reflect.Type, payload any) (any, error) {
+	b.mu.RLock()
+	entry, ok := b.handlers[t]
+	middleware := b.middleware
+	b.mu.RUnlock()
+	if !ok || entry.kind != kind {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoHandler, kind, typeName(t))
+	}
+
+	h := entry.fn
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	name := entry.name
+	if n, ok := payload.(Named); ok {
+		name = n.MessageName()
+	}
+	return h(ctx, Message{Kind: kind, Name: name, Payload: payload})
+}
+
+// typeName returns "pkg.Type" for t, ignoring pointers.
+func typeName(t reflect.Type) string {
+	return strings.TrimLeft(t.String(), "*")
+}
@@ -0,0 +1,228 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	apperrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/tracing"
+)
+
+type createUser struct {
+	Email string `validate:"required,email"`
+}
+
+func (createUser) Permission() Permission {
+	return Permission{Domain: "default", Resource: "users", Action: "create"}
+}
+
+type getUser struct{ ID int }
+
+type renameUser struct {
+	ID   int
+	Name string
+}
+
+func (c renameUser) Validate(context.Context) error {
+	if c.Name == "root" {
+		return errors.New("reserved name")
+	}
+	return nil
+}
+
+type userStore struct {
+	mu    sync.Mutex
+	users map[int]string
+}
+
+func newTestBus(t *testing.T, middleware ...Middleware) (*Bus, *userStore) {
+	t.Helper()
+	store := &userStore{users: map[int]string{}}
+	b := New(middleware...)
+	if err := RegisterCommand(b, func(_ context.Context, cmd createUser) error {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		store.users[len(store.users)+1] = cmd.Email
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterCommand(b, func(_ context.Context, cmd renameUser) error {
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterQuery(b, func(_ context.Context, q getUser) (string, error) {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		email, ok := store.users[q.ID]
+		if !ok {
+			return "", fmt.Errorf("user %d not found", q.ID)
+		}
+		return email, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return b, store
+}
+
+func TestSendAndAsk(t *testing.T) {
+	ctx := context.Background()
+	b, _ := newTestBus(t)
+
+	if err := Send(ctx, b, createUser{Email: "a@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	email, err := Ask[string](ctx, b, getUser{ID: 1})
+	if err != nil || email != "a@example.com" {
+		t.Fatalf("Ask = %q, %v", email, err)
+	}
+
+	if err := Send(ctx, b, getUser{ID: 1}); !errors.Is(err, ErrNoHandler) {
+		t.Errorf("sending a query as a command: err = %v, want ErrNoHandler", err)
+	}
+	if _, err := Ask[string](ctx, b, struct{}{}); !errors.Is(err, ErrNoHandler) {
+		t.Errorf("unregistered query: err = %v, want ErrNoHandler", err)
+	}
+	if _, err := Ask[int](ctx, b, getUser{ID: 1}); err == nil {
+		t.Error("asking with the wrong result type should fail")
+	}
+	if err := RegisterCommand(b, func(context.Context, createUser) error { return nil }); !errors.Is(err, ErrDuplicateHandler) {
+		t.Errorf("duplicate registration: err = %v, want ErrDuplicateHandler", err)
+	}
+
+	handlers := b.Handlers()
+	if fmt.Sprint(handlers[KindCommand]) != "[bus.createUser bus.renameUser]" || fmt.Sprint(handlers[KindQuery]) != "[bus.getUser]" {
+		t.Errorf("Handlers = %v", handlers)
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, msg Message) (any, error) {
+				calls = append(calls, name+">"+msg.Name)
+				return next(ctx, msg)
+			}
+		}
+	}
+	b, _ := newTestBus(t, trace("outer"))
+	b.Use(trace("inner"))
+
+	if _, err := Ask[string](context.Background(), b, getUser{ID: 7}); err == nil {
+		t.Fatal("missing user should fail")
+	}
+	if fmt.Sprint(calls) != "[outer>bus.getUser inner>bus.getUser]" {
+		t.Errorf("calls = %v", calls)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	ctx := context.Background()
+	b, store := newTestBus(t, Validation())
+
+	if err := Send(ctx, b, createUser{Email: "not-an-email"}); err == nil {
+		t.Error("invalid email should fail validate tags")
+	}
+	if err := Send(ctx, b, renameUser{ID: 1, Name: "root"}); err == nil || err.Error() != "reserved name" {
+		t.Errorf("Validator: err = %v", err)
+	}
+	if len(store.users) != 0 {
+		t.Errorf("handlers ran for invalid messages: %v", store.users)
+	}
+}
+
+type fakeChecker map[string]bool
+
+func (f fakeChecker) CheckPermission(_ context.Context, user, domain, resource, action string) (bool, error) {
+	return f[user+":"+resource+":"+action], nil
+}
+
+func TestAuthorization(t *testing.T) {
+	b, _ := newTestBus(t, Authorization(fakeChecker{"alice:users:create": true}))
+	cmd := createUser{Email: "a@example.com"}
+
+	err := Send(context.Background(), b, cmd)
+	if !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("anonymous: err = %v, want ErrUnauthenticated", err)
+	}
+	err = Send(context.WithValue(context.Background(), "user_id", "bob"), b, cmd)
+	var appErr *apperrors.AppError
+	if !errors.Is(err, ErrForbidden) || !errors.As(err, &appErr) || appErr.HTTPStatus != 403 {
+		t.Errorf("bob: err = %v, want a 403 wrapping ErrForbidden", err)
+	}
+	if err := Send(context.WithValue(context.Background(), "user_id", "alice"), b, cmd); err != nil {
+		t.Errorf("alice: %v", err)
+	}
+	// messages without Permission are not checked
+	if err := Send(context.Background(), b, renameUser{ID: 1}); err != nil {
+		t.Errorf("unguarded command: %v", err)
+	}
+}
+
+type fakeTx struct {
+	began, committed int
+}
+
+func (f *fakeTx) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	f.began++
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	f.committed++
+	return nil
+}
+
+func TestTransactionWrapsCommandsOnly(t *testing.T) {
+	ctx := context.Background()
+	tx := &fakeTx{}
+	b, _ := newTestBus(t, Transaction(tx))
+
+	Send(ctx, b, createUser{Email: "a@example.com"})
+	Ask[string](ctx, b, getUser{ID: 1})
+	Ask[string](ctx, b, getUser{ID: 2})
+	if tx.began != 1 || tx.committed != 1 {
+		t.Errorf("transactions began %d, committed %d, want 1 and 1", tx.began, tx.committed)
+	}
+}
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*tracing.Span
+}
+
+func (r *spanRecorder) OnEnd(span *tracing.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func (r *spanRecorder) Shutdown(context.Context) error { return nil }
+
+func TestTracingAndMetrics(t *testing.T) {
+	ctx := context.Background()
+	rec := &spanRecorder{}
+	tracer, err := tracing.NewTracer(tracing.TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector := metrics.NewCollector()
+	b, _ := newTestBus(t, Tracing(tracer), Metrics(collector))
+
+	Send(ctx, b, createUser{Email: "a@example.com"})
+	Ask[string](ctx, b, getUser{ID: 9})
+
+	if len(rec.spans) != 2 || rec.spans[0].Name != "command bus.createUser" || rec.spans[1].Status.Code != tracing.StatusCodeError {
+		t.Errorf("spans = %+v", rec.spans)
+	}
+	ok := collector.GetMetric(MetricMessages, map[string]string{"kind": "command", "message": "bus.createUser", "status": "ok"})
+	failed := collector.GetMetric(MetricMessages, map[string]string{"kind": "query", "message": "bus.getUser", "status": "error"})
+	if ok == nil || failed == nil {
+		t.Errorf("counters missing: ok = %v, error = %v", ok, failed)
+	}
+}
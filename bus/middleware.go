@@ -0,0 +1,168 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/leeforge/framework/auth"
+	"github.com/leeforge/framework/auth/rbac"
+	"github.com/leeforge/framework/ent"
+	apperrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/binding"
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/tracing"
+)
+
+// Metrics recorded by the Metrics middleware, labelled by "kind" and "message";
+// the counter also carries "status" ("ok" or "error").
+const (
+	MetricMessages = "bus_messages_total"
+	MetricDuration = "bus_message_duration_seconds"
+)
+
+var (
+	// ErrUnauthenticated is wrapped by the error Authorization returns when
+	// the context has no user.
+	ErrUnauthenticated = errors.New("bus: unauthenticated")
+	// ErrForbidden is wrapped by the error Authorization returns when the
+	// user lacks the message's permission.
+	ErrForbidden = errors.New("bus: forbidden")
+)
+
+// Validator is implemented by messages with checks beyond validate tags.
+type Validator interface {
+	Validate(ctx context.Context) error
+}
+
+// Validation checks struct messages against their validate tags, as request
+// binding does, and then calls Validate on messages implementing Validator.
+func Validation() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg Message) (any, error) {
+			if v := reflect.Indirect(reflect.ValueOf(msg.Payload)); v.Kind() == reflect.Struct {
+				if err := binding.ValidateCtx(ctx, msg.Payload); err != nil {
+					return nil, err
+				}
+			}
+			if v, ok := msg.Payload.(Validator); ok {
+				if err := v.Validate(ctx); err != nil {
+					return nil, err
+				}
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Permission is an RBAC permission a message requires.
+type Permission struct {
+	Domain   string
+	Resource string
+	Action   string
+}
+
+// Authorizer is implemented by messages that require a permission; other
+// messages pass Authorization unchecked.
+type Authorizer interface {
+	Permission() Permission
+}
+
+// PermissionChecker decides whether a user holds a permission.
+type PermissionChecker interface {
+	CheckPermission(ctx context.Context, userUUID, domain, resource, action string) (bool, error)
+}
+
+var _ PermissionChecker = (*rbac.RBACManager)(nil)
+
+// Authorization checks the permission of Authorizer messages for the user
+// the auth middleware put in the context. Denials are AppErrors with HTTP
+// status 401 or 403 wrapping ErrUnauthenticated or ErrForbidden.
+func Authorization(checker PermissionChecker) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg Message) (any, error) {
+			a, ok := msg.Payload.(Authorizer)
+			if !ok {
+				return next(ctx, msg)
+			}
+			userID, _, _ := auth.GetUserInfoFromContext(ctx)
+			if userID == "" {
+				return nil, apperrors.NewUnauthorized("Authentication required").WithInnerError(ErrUnauthenticated)
+			}
+			p := a.Permission()
+			allowed, err := checker.CheckPermission(ctx, userID, p.Domain, p.Resource, p.Action)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				return nil, apperrors.NewForbidden("Access denied").
+					WithInnerError(ErrForbidden).
+					WithDetail("resource", p.Resource).
+					WithDetail("action", p.Action)
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// TxRunner runs fn in a transaction carried by its context; *ent.TxManager implements it.
+type TxRunner interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var _ TxRunner = (*ent.TxManager)(nil)
+
+// Transaction runs command handlers in a transaction, committed when the
+// handler succeeds. Queries run without one.
+func Transaction(tx TxRunner) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg Message) (any, error) {
+			if msg.Kind != KindCommand {
+				return next(ctx, msg)
+			}
+			var result any
+			err := tx.WithTx(ctx, func(ctx context.Context) error {
+				var err error
+				result, err = next(ctx, msg)
+				return err
+			})
+			return result, err
+		}
+	}
+}
+
+// Tracing wraps every dispatch in a span named "<kind> <message>".
+func Tracing(tracer *tracing.Tracer) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg Message) (any, error) {
+			ctx, span := tracer.Start(ctx, string(msg.Kind)+" "+msg.Name)
+			tracer.SetAttributes(span, map[string]interface{}{
+				"bus.kind":    string(msg.Kind),
+				"bus.message": msg.Name,
+			})
+			result, err := next(ctx, msg)
+			tracer.End(span, err)
+			return result, err
+		}
+	}
+}
+
+// Metrics counts dispatches and observes their duration.
+func Metrics(collector *metrics.Collector) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg Message) (any, error) {
+			start := time.Now()
+			result, err := next(ctx, msg)
+
+			labels := map[string]string{"kind": string(msg.Kind), "message": msg.Name}
+			collector.ObserveHistogram(MetricDuration, time.Since(start).Seconds(), labels)
+			status := "ok"
+			if err != nil {
+				status = "error"
+			}
+			collector.IncCounter(MetricMessages, map[string]string{"kind": string(msg.Kind), "message": msg.Name, "status": status})
+			return result, err
+		}
+	}
+}
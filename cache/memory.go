@@ -0,0 +1,274 @@
+package cache
+
+import (
+	stdjson "encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leeforge/framework/json"
+)
+
+// L1MetricsType L1 在 MetricsCollector 中的缓存类型
+const L1MetricsType = "l1"
+
+// EvictionPolicyType 淘汰策略类型
+type EvictionPolicyType string
+
+const (
+	// EvictLRU 淘汰最近最少使用的条目
+	EvictLRU EvictionPolicyType = "lru"
+	// EvictLFU 淘汰访问频率最低的条目，频率相同时淘汰较久未访问的
+	EvictLFU EvictionPolicyType = "lfu"
+)
+
+// EvictionTracker 跟踪访问并选出淘汰对象，LRUPolicy 与 LFUPolicy 均实现该接口
+type EvictionTracker interface {
+	RecordAccess(key string)
+	Remove(key string)
+	Victim() (string, bool)
+}
+
+// NewEvictionTracker 按类型创建淘汰策略，未知类型使用 LRU
+func NewEvictionTracker(policy EvictionPolicyType) EvictionTracker {
+	if policy == EvictLFU {
+		return NewLFUPolicy()
+	}
+	return NewLRUPolicy()
+}
+
+// MemoryStoreOptions 有界内存缓存配置
+type MemoryStoreOptions struct {
+	MaxEntries      int                           // 最大条目数，0 表示不限
+	MaxBytes        int64                         // 最大字节数（按 Sizer 估算），0 表示不限
+	Policy          EvictionTracker               // 超限时的淘汰策略，默认 LRU
+	TTL             time.Duration                 // Store 的默认过期时间，0 表示不过期
+	JanitorInterval time.Duration                 // 后台清理过期条目的间隔，0 表示只在访问时惰性清理
+	Sizer           func(value interface{}) int64 // 条目大小估算，默认按 JSON 编码长度
+	Metrics         *MetricsCollector             // 记录命中、未命中、写入、淘汰与过期
+}
+
+// MemoryStore 有界的内存缓存，作为 MultiLevelCache 的 L1
+// 超过条目数或字节数上限时按淘汰策略移除条目，过期条目在访问时或由后台清理移除
+type MemoryStore struct {
+	opts MemoryStoreOptions
+
+	mu    sync.Mutex
+	items map[string]*memoryEntry
+	bytes int64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	onRemove func(key string) // 条目被删除、淘汰或过期时调用（持有 mu）
+}
+
+type memoryEntry struct {
+	value     interface{}
+	size      int64
+	expiresAt time.Time
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// NewMemoryStore 创建有界内存缓存，JanitorInterval 大于 0 时启动后台清理，需调用 Close 停止
+func NewMemoryStore(opts MemoryStoreOptions) *MemoryStore {
+	if opts.Policy == nil {
+		opts.Policy = NewLRUPolicy()
+	}
+	if opts.Sizer == nil {
+		opts.Sizer = estimateSize
+	}
+	s := &MemoryStore{
+		opts:  opts,
+		items: make(map[string]*memoryEntry),
+		stop:  make(chan struct{}),
+	}
+	if opts.JanitorInterval > 0 {
+		go s.janitor(opts.JanitorInterval)
+	}
+	return s
+}
+
+// Load 读取条目，过期条目视为未命中并被移除
+func (s *MemoryStore) Load(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.items[key]
+	if ok && entry.expired(time.Now()) {
+		s.removeLocked(key, entry)
+		s.record((*MetricsCollector).RecordExpire)
+		ok = false
+	}
+	if !ok {
+		s.record((*MetricsCollector).RecordMiss)
+		return nil, false
+	}
+	s.opts.Policy.RecordAccess(key)
+	s.record((*MetricsCollector).RecordHit)
+	return entry.value, true
+}
+
+// Store 以默认 TTL 写入条目
+func (s *MemoryStore) Store(key string, value interface{}) {
+	s.StoreTTL(key, value, s.opts.TTL)
+}
+
+// StoreTTL 以指定 TTL 写入条目，ttl 为 0 表示不过期；超限时按策略淘汰
+func (s *MemoryStore) StoreTTL(key string, value interface{}, ttl time.Duration) {
+	entry := &memoryEntry{value: value}
+	if s.opts.MaxBytes > 0 {
+		entry.size = s.opts.Sizer(value)
+	}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := s.items[key]; ok {
+		s.bytes -= old.size
+	}
+	s.items[key] = entry
+	s.bytes += entry.size
+	s.opts.Policy.RecordAccess(key)
+	s.record((*MetricsCollector).RecordSet)
+
+	for s.overLimitLocked() {
+		victim, ok := s.opts.Policy.Victim()
+		if !ok {
+			break
+		}
+		if e, exists := s.items[victim]; exists {
+			s.removeLocked(victim, e)
+		} else {
+			s.opts.Policy.Remove(victim)
+		}
+		s.record((*MetricsCollector).RecordEvict)
+	}
+}
+
+// Delete 删除条目
+func (s *MemoryStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.items[key]; ok {
+		s.removeLocked(key, entry)
+	}
+}
+
+// Clear 清空所有条目
+func (s *MemoryStore) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, entry := range s.items {
+		s.removeLocked(key, entry)
+	}
+}
+
+// DeleteByPrefix 删除 key 以 prefix 开头的条目，返回删除数量
+func (s *MemoryStore) DeleteByPrefix(prefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for key, entry := range s.items {
+		if strings.HasPrefix(key, prefix) {
+			s.removeLocked(key, entry)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Len 当前条目数（含尚未清理的过期条目）
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// Bytes 当前估算占用字节数，仅在配置 MaxBytes 时统计
+func (s *MemoryStore) Bytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bytes
+}
+
+// DeleteExpired 移除所有过期条目，返回移除数量
+func (s *MemoryStore) DeleteExpired() int {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for key, entry := range s.items {
+		if entry.expired(now) {
+			s.removeLocked(key, entry)
+			s.record((*MetricsCollector).RecordExpire)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Close 停止后台清理
+func (s *MemoryStore) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+func (s *MemoryStore) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.DeleteExpired()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *MemoryStore) overLimitLocked() bool {
+	if s.opts.MaxEntries > 0 && len(s.items) > s.opts.MaxEntries {
+		return true
+	}
+	return s.opts.MaxBytes > 0 && s.bytes > s.opts.MaxBytes
+}
+
+func (s *MemoryStore) removeLocked(key string, entry *memoryEntry) {
+	delete(s.items, key)
+	s.bytes -= entry.size
+	s.opts.Policy.Remove(key)
+	if s.onRemove != nil {
+		s.onRemove(key)
+	}
+}
+
+func (s *MemoryStore) record(fn func(*MetricsCollector, string)) {
+	if s.opts.Metrics != nil {
+		fn(s.opts.Metrics, L1MetricsType)
+	}
+}
+
+// estimateSize 按 JSON 编码长度估算条目大小，字符串与字节切片直接取长度
+func estimateSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	case stdjson.RawMessage:
+		return int64(len(v))
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
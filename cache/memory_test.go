@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_LRUEviction(t *testing.T) {
+	metrics := NewMetricsCollector()
+	s := NewMemoryStore(MemoryStoreOptions{MaxEntries: 2, Metrics: metrics})
+
+	s.Store("a", 1)
+	s.Store("b", 2)
+	s.Load("a") // b 成为最久未访问
+	s.Store("c", 3)
+
+	if _, ok := s.Load("b"); ok {
+		t.Fatal("b should have been evicted")
+	}
+	if _, ok := s.Load("a"); !ok {
+		t.Fatal("a should survive")
+	}
+	if s.Len() != 2 {
+		t.Fatalf("Len = %d", s.Len())
+	}
+
+	m := metrics.GetMetrics(L1MetricsType)
+	if m.Evicts != 1 || m.Hits != 2 || m.Misses != 1 || m.Sets != 3 {
+		t.Fatalf("metrics = %+v", m)
+	}
+}
+
+func TestMemoryStore_LFUEviction(t *testing.T) {
+	s := NewMemoryStore(MemoryStoreOptions{MaxEntries: 2, Policy: NewLFUPolicy()})
+
+	s.Store("hot", 1)
+	s.Load("hot")
+	s.Load("hot")
+	s.Store("cold", 2)
+	s.Store("new", 3) // cold 与 new 频率相同，cold 较久未访问
+
+	if _, ok := s.Load("cold"); ok {
+		t.Fatal("cold should have been evicted")
+	}
+	if _, ok := s.Load("hot"); !ok {
+		t.Fatal("hot should survive")
+	}
+	if _, ok := s.Load("new"); !ok {
+		t.Fatal("new should survive")
+	}
+}
+
+func TestMemoryStore_MaxBytes(t *testing.T) {
+	s := NewMemoryStore(MemoryStoreOptions{MaxBytes: 10})
+
+	s.Store("a", "12345")
+	s.Store("b", "12345")
+	if s.Bytes() != 10 {
+		t.Fatalf("Bytes = %d", s.Bytes())
+	}
+	s.Store("a", "1234") // 覆盖时按新值计算
+	if s.Bytes() != 9 {
+		t.Fatalf("Bytes after overwrite = %d", s.Bytes())
+	}
+	s.Store("c", "123")
+	if _, ok := s.Load("b"); ok {
+		t.Fatal("b should have been evicted")
+	}
+
+	s.Store("huge", strings.Repeat("x", 20))
+	if s.Len() != 0 || s.Bytes() != 0 {
+		t.Fatalf("oversized entry should not be kept: len=%d bytes=%d", s.Len(), s.Bytes())
+	}
+}
+
+func TestMemoryStore_TTLAndJanitor(t *testing.T) {
+	metrics := NewMetricsCollector()
+	s := NewMemoryStore(MemoryStoreOptions{TTL: 20 * time.Millisecond, JanitorInterval: 10 * time.Millisecond, Metrics: metrics})
+	defer s.Close()
+
+	s.Store("short", 1)
+	s.StoreTTL("forever", 2, 0)
+	if _, ok := s.Load("short"); !ok {
+		t.Fatal("short should be readable before expiry")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for s.Len() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("janitor did not remove expired entry, len=%d", s.Len())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, ok := s.Load("forever"); !ok {
+		t.Fatal("entry without ttl must not expire")
+	}
+	if m := metrics.GetMetrics(L1MetricsType); m.Expired != 1 {
+		t.Fatalf("expired = %d", m.Expired)
+	}
+}
+
+func TestMultiLevelCache_BoundedL1(t *testing.T) {
+	l2 := newTTLAdapter()
+	metrics := NewMetricsCollector()
+	strategy := NewCacheStrategyBuilder().WithMaxSize(2).WithEviction(EvictLFU).WithMetrics(metrics).Build()
+	cache := NewMultiLevelCacheWithStrategy(l2, nil, strategy)
+	defer cache.Close()
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set(ctx, key, key)
+	}
+	if cache.L1.Len() != 2 {
+		t.Fatalf("L1 len = %d", cache.L1.Len())
+	}
+	// 被淘汰的 key 仍可从 L2 读回
+	if v, err := cache.Get(ctx, "a"); err != nil || v != "a" {
+		t.Fatalf("Get evicted key = %v, %v", v, err)
+	}
+	if m := metrics.GetMetrics(L1MetricsType); m.Evicts != 2 {
+		t.Fatalf("evicts = %d", m.Evicts)
+	}
+
+	cache.Clear(ctx)
+	if cache.L1.Len() != 0 {
+		t.Fatal("Clear should empty L1")
+	}
+}
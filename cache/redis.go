@@ -0,0 +1,355 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/leeforge/framework/json"
+)
+
+// ErrCacheMiss 缓存不存在或已过期
+var ErrCacheMiss = &Error{Message: "cache miss"}
+
+// CompressThreshold 开启压缩时，序列化后达到该字节数的值才会压缩
+const CompressThreshold = 1024
+
+// gzipMagic gzip 数据头，JSON 不会以该字节开头，读取时据此判断是否需要解压
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// RedisAdapter 基于 go-redis 的 CacheAdapter 实现
+// 值通过框架 json 包序列化；策略开启 Compression 时较大的值以 gzip 压缩存储；
+// 所有 key 自动加上策略的 Prefix
+type RedisAdapter struct {
+	client   redis.UniversalClient
+	prefix   string
+	compress bool
+}
+
+var (
+	_ CacheAdapter  = (*RedisAdapter)(nil)
+	_ TaggedAdapter = (*RedisAdapter)(nil)
+)
+
+// NewRedisAdapter 创建 Redis 缓存适配器，strategy 为 nil 时不加前缀、不压缩
+func NewRedisAdapter(client redis.UniversalClient, strategy *CacheStrategy) *RedisAdapter {
+	a := &RedisAdapter{client: client}
+	if strategy != nil {
+		a.prefix = strategy.config.Prefix
+		a.compress = strategy.config.Compression
+	}
+	return a
+}
+
+func (a *RedisAdapter) key(key string) string { return a.prefix + key }
+
+// Get 实现 CacheAdapter，值按 JSON 还原（对象为 map[string]interface{}），需要具体类型时使用 GetInto
+func (a *RedisAdapter) Get(key string) (interface{}, error) {
+	return a.GetContext(context.Background(), key)
+}
+
+// Set 实现 CacheAdapter
+func (a *RedisAdapter) Set(key string, value interface{}, ttl time.Duration) error {
+	return a.SetContext(context.Background(), key, value, ttl)
+}
+
+// Delete 实现 CacheAdapter
+func (a *RedisAdapter) Delete(key string) error {
+	return a.DeleteContext(context.Background(), key)
+}
+
+// Exists 实现 CacheAdapter，Redis 出错时视为不存在
+func (a *RedisAdapter) Exists(key string) bool {
+	ok, _ := a.ExistsContext(context.Background(), key)
+	return ok
+}
+
+// GetContext 读取缓存，不存在时返回 ErrCacheMiss
+func (a *RedisAdapter) GetContext(ctx context.Context, key string) (interface{}, error) {
+	var value interface{}
+	if err := a.GetInto(ctx, key, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// GetInto 读取缓存并反序列化到 dest，不存在时返回 ErrCacheMiss
+func (a *RedisAdapter) GetInto(ctx context.Context, key string, dest interface{}) error {
+	data, err := a.client.Get(ctx, a.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return ErrCacheMiss
+	}
+	if err != nil {
+		return fmt.Errorf("cache: get %s: %w", key, err)
+	}
+	return decodeValue(data, dest)
+}
+
+// SetContext 写入缓存，ttl <= 0 表示不过期
+func (a *RedisAdapter) SetContext(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := a.encode(value)
+	if err != nil {
+		return fmt.Errorf("cache: encode %s: %w", key, err)
+	}
+	if err := a.client.Set(ctx, a.key(key), data, expiration(ttl)).Err(); err != nil {
+		return fmt.Errorf("cache: set %s: %w", key, err)
+	}
+	return nil
+}
+
+// DeleteContext 删除缓存，key 不存在不视为错误
+func (a *RedisAdapter) DeleteContext(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	full := make([]string, len(keys))
+	for i, k := range keys {
+		full[i] = a.key(k)
+	}
+	if err := a.client.Del(ctx, full...).Err(); err != nil {
+		return fmt.Errorf("cache: delete: %w", err)
+	}
+	return nil
+}
+
+// ExistsContext 判断缓存是否存在
+func (a *RedisAdapter) ExistsContext(ctx context.Context, key string) (bool, error) {
+	n, err := a.client.Exists(ctx, a.key(key)).Result()
+	if err != nil {
+		return false, fmt.Errorf("cache: exists %s: %w", key, err)
+	}
+	return n > 0, nil
+}
+
+// MGet 通过 pipeline 批量读取，结果只包含命中的 key
+// 使用 pipeline 而非 MGET，集群模式下 key 可分布在不同 slot
+func (a *RedisAdapter) MGet(ctx context.Context, keys ...string) (map[string]interface{}, error) {
+	if len(keys) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := a.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, k := range keys {
+			cmds[i] = pipe.Get(ctx, a.key(k))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("cache: mget: %w", err)
+	}
+
+	result := make(map[string]interface{}, len(keys))
+	for i, cmd := range cmds {
+		data, err := cmd.Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cache: mget %s: %w", keys[i], err)
+		}
+		var value interface{}
+		if err := decodeValue(data, &value); err != nil {
+			return nil, fmt.Errorf("cache: decode %s: %w", keys[i], err)
+		}
+		result[keys[i]] = value
+	}
+	return result, nil
+}
+
+// MSet 通过 pipeline 批量写入，所有 key 使用相同的 ttl
+func (a *RedisAdapter) MSet(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+	encoded := make(map[string][]byte, len(values))
+	for k, v := range values {
+		data, err := a.encode(v)
+		if err != nil {
+			return fmt.Errorf("cache: encode %s: %w", k, err)
+		}
+		encoded[k] = data
+	}
+	_, err := a.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for k, data := range encoded {
+			pipe.Set(ctx, a.key(k), data, expiration(ttl))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cache: mset: %w", err)
+	}
+	return nil
+}
+
+// tagKey 标签集合的 key，成员为不带前缀的缓存 key
+func (a *RedisAdapter) tagKey(tag string) string { return a.prefix + "__tag:" + tag }
+
+// popTagScript 原子地读取并删除标签集合，避免读取与删除之间加入的 key 被遗漏
+var popTagScript = redis.NewScript(`
+local keys = redis.call('SMEMBERS', KEYS[1])
+redis.call('DEL', KEYS[1])
+return keys
+`)
+
+// TagKey 实现 TaggedAdapter，ttl <= 0 时标签集合不过期
+func (a *RedisAdapter) TagKey(ctx context.Context, key string, tags []string, ttl time.Duration) error {
+	_, err := a.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tag := range tags {
+			tk := a.tagKey(tag)
+			pipe.SAdd(ctx, tk, key)
+			if ttl > 0 {
+				// 新集合设置过期时间，已有集合只延长不缩短
+				pipe.ExpireNX(ctx, tk, ttl)
+				pipe.ExpireGT(ctx, tk, ttl)
+			} else {
+				pipe.Persist(ctx, tk)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cache: tag %s: %w", key, err)
+	}
+	return nil
+}
+
+// InvalidateTag 实现 TaggedAdapter
+func (a *RedisAdapter) InvalidateTag(ctx context.Context, tag string) ([]string, error) {
+	keys, err := popTagScript.Run(ctx, a.client, []string{a.tagKey(tag)}).StringSlice()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("cache: invalidate tag %s: %w", tag, err)
+	}
+	if err := a.deleteEach(ctx, a.client, keys, true); err != nil {
+		return keys, fmt.Errorf("cache: invalidate tag %s: %w", tag, err)
+	}
+	return keys, nil
+}
+
+// DeleteByPrefix 实现 TaggedAdapter，通过 SCAN 查找 key，集群模式下遍历所有主节点
+func (a *RedisAdapter) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	pattern := escapeGlob(a.prefix+prefix) + "*"
+	var (
+		mu    sync.Mutex
+		total int
+	)
+	scan := func(ctx context.Context, client redis.UniversalClient) error {
+		iter := client.Scan(ctx, 0, pattern, 500).Iterator()
+		batch := make([]string, 0, 500)
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := a.deleteEach(ctx, client, batch, false); err != nil {
+				return err
+			}
+			mu.Lock()
+			total += len(batch)
+			mu.Unlock()
+			batch = batch[:0]
+			return nil
+		}
+		for iter.Next(ctx) {
+			if batch = append(batch, iter.Val()); len(batch) == cap(batch) {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+		return flush()
+	}
+
+	var err error
+	if cluster, ok := a.client.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scan(ctx, node)
+		})
+	} else {
+		err = scan(ctx, a.client)
+	}
+	if err != nil {
+		return total, fmt.Errorf("cache: delete prefix %s: %w", prefix, err)
+	}
+	return total, nil
+}
+
+// deleteEach 逐个 DEL，集群模式下 key 可能位于不同 slot
+func (a *RedisAdapter) deleteEach(ctx context.Context, client redis.UniversalClient, keys []string, addPrefix bool) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, k := range keys {
+			if addPrefix {
+				k = a.key(k)
+			}
+			pipe.Del(ctx, k)
+		}
+		return nil
+	})
+	return err
+}
+
+// escapeGlob 转义 SCAN MATCH 模式中的特殊字符
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// encode 序列化值，开启压缩且超过阈值时 gzip 压缩
+func (a *RedisAdapter) encode(value interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if !a.compress || len(data) < CompressThreshold {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeValue 反序列化，压缩与否由数据头判断，关闭压缩后仍可读取旧的压缩值
+func decodeValue(data []byte, dest interface{}) error {
+	if bytes.HasPrefix(data, gzipMagic) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		if data, err = io.ReadAll(zr); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func expiration(ttl time.Duration) time.Duration {
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
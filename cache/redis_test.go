@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+func redisTestClient(t *testing.T) (*redis.Client, string) {
+	t.Helper()
+	addr := strings.TrimSpace(os.Getenv("REDIS_TEST_ADDR"))
+	if addr == "" {
+		t.Skip("set REDIS_TEST_ADDR to run redis integration tests")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: os.Getenv("REDIS_TEST_PASSWORD")})
+	prefix := "cache-test:" + uuid.NewString() + ":"
+	t.Cleanup(func() {
+		ctx := context.Background()
+		keys, _ := client.Keys(ctx, prefix+"*").Result()
+		if len(keys) > 0 {
+			client.Del(ctx, keys...)
+		}
+		client.Close()
+	})
+	return client, prefix
+}
+
+func TestRedisAdapterEncoding(t *testing.T) {
+	small := map[string]string{"name": "alice"}
+	large := map[string]string{"bio": strings.Repeat("x", CompressThreshold)}
+
+	plain := NewRedisAdapter(nil, nil)
+	compressed := NewRedisAdapter(nil, NewCacheStrategyBuilder().WithCompression(true).Build())
+
+	data, err := compressed.encode(small)
+	if err != nil || bytes.HasPrefix(data, gzipMagic) {
+		t.Fatalf("small value should stay uncompressed: %q, %v", data, err)
+	}
+	data, err = compressed.encode(large)
+	if err != nil || !bytes.HasPrefix(data, gzipMagic) {
+		t.Fatalf("large value should be compressed, err %v", err)
+	}
+	var got map[string]string
+	if err := decodeValue(data, &got); err != nil || got["bio"] != large["bio"] {
+		t.Fatalf("round trip failed: %v", err)
+	}
+
+	data, _ = plain.encode(large)
+	if bytes.HasPrefix(data, gzipMagic) {
+		t.Fatal("compression disabled but value compressed")
+	}
+}
+
+func TestRedisAdapter(t *testing.T) {
+	client, prefix := redisTestClient(t)
+	ctx := context.Background()
+	a := NewRedisAdapter(client, NewCacheStrategyBuilder().WithPrefix(prefix).WithCompression(true).Build())
+
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := a.SetContext(ctx, "user:1", user{ID: 1, Name: "alice"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	var u user
+	if err := a.GetInto(ctx, "user:1", &u); err != nil || u.Name != "alice" {
+		t.Fatalf("GetInto = %+v, %v", u, err)
+	}
+	if ttl := client.TTL(ctx, prefix+"user:1").Val(); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("ttl = %v, key must be prefixed and expire", ttl)
+	}
+	if !a.Exists("user:1") {
+		t.Error("Exists = false")
+	}
+	if _, err := a.Get("user:2"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("missing key err = %v, want ErrCacheMiss", err)
+	}
+
+	big := strings.Repeat("y", 2*CompressThreshold)
+	if err := a.MSet(ctx, map[string]interface{}{"a": 1, "b": big}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	values, err := a.MGet(ctx, "a", "b", "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values["a"] != float64(1) || values["b"] != big {
+		t.Errorf("MGet = %d values", len(values))
+	}
+
+	if err := a.DeleteContext(ctx, "a", "b", "user:1"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := a.ExistsContext(ctx, "user:1"); ok {
+		t.Error("key still exists after delete")
+	}
+}
@@ -1,12 +1,31 @@
 package cache
 
 import (
+	"container/list"
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/concurrency"
+	"golang.org/x/sync/singleflight"
 )
 
+// ErrNotFound L3 加载器以此表示数据不存在；开启负缓存时该结果会被短暂缓存
+var ErrNotFound = &Error{Message: "not found"}
+
+// defaultTTL MultiLevelCache 写入 L2 的默认过期时间
+const defaultTTL = 10 * time.Minute
+
+// defaultLoadTimeout 合并加载未配置 LoadTimeout 时的超时
+const defaultLoadTimeout = 30 * time.Second
+
+// negativeMarker 写入 L2 的"不存在"标记
+const negativeMarker = "__cache_negative__"
+
 // CacheStrategy 缓存策略
 type CacheStrategy struct {
 	config CacheConfig
@@ -15,9 +34,27 @@ type CacheStrategy struct {
 // CacheConfig 缓存配置
 type CacheConfig struct {
 	TTL         map[string]time.Duration
-	MaxSize     int
+	MaxSize     int // L1 最大条目数，0 表示不限
 	Compression bool
 	Prefix      string
+	// MaxBytes L1 最大字节数（按 JSON 编码长度估算），0 表示不限
+	MaxBytes int64
+	// Eviction L1 超限时的淘汰策略，默认 LRU
+	Eviction EvictionPolicyType
+	// L1TTL L1 条目的过期时间，0 表示只随容量淘汰
+	L1TTL time.Duration
+	// JanitorInterval 后台清理 L1 过期条目的间隔，0 表示只在访问时清理
+	JanitorInterval time.Duration
+	// Metrics 记录 L1 命中、未命中、淘汰与过期次数（类型为 L1MetricsType）
+	Metrics *MetricsCollector
+	// Singleflight 同一 key 并发未命中时只执行一次 L3 加载（防击穿）
+	Singleflight bool
+	// LoadTimeout 合并加载的超时，0 表示 30 秒；合并加载不随任一调用方的 ctx 取消
+	LoadTimeout time.Duration
+	// NegativeTTL 大于 0 时缓存 L3 返回 ErrNotFound 的结果（防穿透）
+	NegativeTTL time.Duration
+	// TTLJitter 写入时 TTL 随机增加 [0, TTLJitter*ttl)，避免同批 key 同时过期（防雪崩）
+	TTLJitter float64
 }
 
 // NewCacheStrategy 创建缓存策略
@@ -44,6 +81,26 @@ func (s *CacheStrategy) GetTTL(cacheType string) time.Duration {
 	return 10 * time.Minute // 默认 10 分钟
 }
 
+// Jitter 按 TTLJitter 为 ttl 增加随机抖动
+func (s *CacheStrategy) Jitter(ttl time.Duration) time.Duration {
+	if s == nil || s.config.TTLJitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Float64()*s.config.TTLJitter*float64(ttl))
+}
+
+// NewMemoryStore 按策略创建 L1
+func (s *CacheStrategy) NewMemoryStore() *MemoryStore {
+	return NewMemoryStore(MemoryStoreOptions{
+		MaxEntries:      s.config.MaxSize,
+		MaxBytes:        s.config.MaxBytes,
+		Policy:          NewEvictionTracker(s.config.Eviction),
+		TTL:             s.config.L1TTL,
+		JanitorInterval: s.config.JanitorInterval,
+		Metrics:         s.config.Metrics,
+	})
+}
+
 // CacheAdapter 缓存适配器接口
 type CacheAdapter interface {
 	Get(key string) (interface{}, error)
@@ -54,65 +111,188 @@ type CacheAdapter interface {
 
 // MultiLevelCache 多级缓存
 type MultiLevelCache struct {
-	L1 *sync.Map    // 本地内存
+	L1 *MemoryStore // 本地内存（有界）
 	L2 CacheAdapter // 二级缓存 (Redis 或其他)
 	L3 LoaderFunc   // 三级缓存 (数据库加载器)
+
+	strategy *CacheStrategy
+	flights  singleflight.Group
+	negative sync.Map // key -> L1 负缓存过期时间
+
+	id   string    // 实例 ID，忽略自己发出的失效广播
+	tags *tagIndex // L1 中 key 的标签
+	bus  InvalidationBus
 }
 
 // LoaderFunc 数据加载函数
 type LoaderFunc func(ctx context.Context) (interface{}, error)
 
-// NewMultiLevelCache 创建多级缓存
+// NewMultiLevelCache 创建多级缓存，使用默认策略（开启 singleflight，不做负缓存与 TTL 抖动）
 func NewMultiLevelCache(l2 CacheAdapter, l3 LoaderFunc) *MultiLevelCache {
-	return &MultiLevelCache{
-		L1: &sync.Map{},
-		L2: l2,
-		L3: l3,
+	return NewMultiLevelCacheWithStrategy(l2, l3, NewCacheStrategyBuilder().Build())
+}
+
+// NewMultiLevelCacheWithStrategy 按策略创建多级缓存，策略决定 L1 容量与淘汰、击穿、穿透与雪崩保护；strategy 为 nil 时使用默认策略
+func NewMultiLevelCacheWithStrategy(l2 CacheAdapter, l3 LoaderFunc, strategy *CacheStrategy) *MultiLevelCache {
+	if strategy == nil {
+		strategy = NewCacheStrategyBuilder().Build()
+	}
+	m := &MultiLevelCache{
+		L1:       strategy.NewMemoryStore(),
+		L2:       l2,
+		L3:       l3,
+		strategy: strategy,
+		id:       uuid.NewString(),
+		tags:     newTagIndex(),
 	}
+	m.L1.onRemove = m.tags.remove
+	return m
 }
 
 // Get 获取缓存，支持自动加载
+// 数据不存在（L3 返回 ErrNotFound 或命中负缓存）时返回 ErrNotFound
 func (m *MultiLevelCache) Get(ctx context.Context, key string) (interface{}, error) {
+	return m.get(ctx, key, m.strategy != nil && m.strategy.config.Singleflight)
+}
+
+func (m *MultiLevelCache) get(ctx context.Context, key string, singleflight bool) (interface{}, error) {
 	// 1. L1 缓存 (内存)
 	if val, ok := m.L1.Load(key); ok {
 		return val, nil
 	}
+	if m.negativeHit(key) {
+		return nil, ErrNotFound
+	}
 
 	// 2. L2 缓存
-	if m.L2 != nil {
-		val, err := m.L2.Get(key)
-		if err == nil {
-			// 回写 L1
-			m.L1.Store(key, val)
-			return val, nil
+	if val, ok := m.loadL2(key); ok {
+		if val == negativeMarker {
+			return nil, ErrNotFound
 		}
+		return val, nil
 	}
 
 	// 3. L3 自动加载
-	if m.L3 != nil {
-		result, err := m.L3(ctx)
-		if err != nil {
-			return nil, err
+	if m.L3 == nil {
+		return nil, fmt.Errorf("cache miss")
+	}
+	if !singleflight {
+		return m.load(ctx, key)
+	}
+	// 同一 key 的并发未命中共享一次加载。加载脱离首个调用方的取消，
+	// 只受 LoadTimeout 限制；每个调用方在自己的 ctx 结束时提前返回
+	ch := m.flights.DoChan(key, func() (interface{}, error) {
+		// 排队期间可能已被其他调用方写入
+		if val, ok := m.L1.Load(key); ok {
+			return val, nil
 		}
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.loadTimeout())
+		defer cancel()
+		return m.load(loadCtx, key)
+	})
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// loadTimeout 合并加载的超时
+func (m *MultiLevelCache) loadTimeout() time.Duration {
+	if m.strategy != nil && m.strategy.config.LoadTimeout > 0 {
+		return m.strategy.config.LoadTimeout
+	}
+	return defaultLoadTimeout
+}
+
+// loadL2 读取 L2 并回写 L1，负缓存标记只记入负缓存
+func (m *MultiLevelCache) loadL2(key string) (interface{}, bool) {
+	if m.L2 == nil {
+		return nil, false
+	}
+	val, err := m.L2.Get(key)
+	if err != nil {
+		return nil, false
+	}
+	if val == negativeMarker {
+		m.markNegative(key)
+		return val, true
+	}
+	m.L1.Store(key, val)
+	return val, true
+}
+
+// load 执行 L3 加载并回写缓存
+func (m *MultiLevelCache) load(ctx context.Context, key string) (interface{}, error) {
+	result, err := m.L3(ctx)
+	if errors.Is(err, ErrNotFound) {
+		m.setNegative(key)
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 回写缓存
+	m.Set(ctx, key, result)
+	return result, nil
+}
+
+// setNegative 按 NegativeTTL 缓存"不存在"结果
+func (m *MultiLevelCache) setNegative(key string) {
+	if m.strategy == nil || m.strategy.config.NegativeTTL <= 0 {
+		return
+	}
+	m.markNegative(key)
+	if m.L2 != nil {
+		m.L2.Set(key, negativeMarker, m.strategy.config.NegativeTTL)
+	}
+}
 
-		// 回写缓存
-		m.Set(ctx, key, result)
-		return result, nil
+func (m *MultiLevelCache) markNegative(key string) {
+	if m.strategy == nil || m.strategy.config.NegativeTTL <= 0 {
+		return
 	}
+	m.negative.Store(key, time.Now().Add(m.strategy.config.NegativeTTL))
+}
 
-	return nil, fmt.Errorf("cache miss")
+func (m *MultiLevelCache) negativeHit(key string) bool {
+	expiresAt, ok := m.negative.Load(key)
+	if !ok {
+		return false
+	}
+	if time.Now().After(expiresAt.(time.Time)) {
+		m.negative.Delete(key)
+		return false
+	}
+	return true
 }
 
-// Set 设置缓存 (L1 + L2)
-func (m *MultiLevelCache) Set(ctx context.Context, key string, value interface{}) error {
+// Set 设置缓存 (L1 + L2)，L2 的 TTL 按策略增加随机抖动
+// 通过 WithTags 关联标签后可用 InvalidateTag 批量失效
+func (m *MultiLevelCache) Set(ctx context.Context, key string, value interface{}, opts ...SetOption) error {
+	var o setOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	// L1
 	m.L1.Store(key, value)
+	m.tags.set(key, o.tags)
+	m.negative.Delete(key)
 
 	// L2
-	if m.L2 != nil {
-		return m.L2.Set(key, value, 10*time.Minute)
+	if m.L2 == nil {
+		return nil
+	}
+	ttl := m.strategy.Jitter(defaultTTL)
+	if err := m.L2.Set(key, value, ttl); err != nil {
+		return err
+	}
+	if tagged, ok := m.L2.(TaggedAdapter); ok && len(o.tags) > 0 {
+		return tagged.TagKey(ctx, key, o.tags, ttl)
 	}
-
 	return nil
 }
 
@@ -120,6 +300,7 @@ func (m *MultiLevelCache) Set(ctx context.Context, key string, value interface{}
 func (m *MultiLevelCache) Delete(ctx context.Context, key string) error {
 	// L1
 	m.L1.Delete(key)
+	m.negative.Delete(key)
 
 	// L2
 	if m.L2 != nil {
@@ -132,7 +313,11 @@ func (m *MultiLevelCache) Delete(ctx context.Context, key string) error {
 // Clear 清空缓存
 func (m *MultiLevelCache) Clear(ctx context.Context) error {
 	// 清空 L1
-	m.L1 = &sync.Map{}
+	m.L1.Clear()
+	m.negative.Range(func(key, _ interface{}) bool {
+		m.negative.Delete(key)
+		return true
+	})
 
 	// 清空 L2
 	if m.L2 != nil {
@@ -143,6 +328,11 @@ func (m *MultiLevelCache) Clear(ctx context.Context) error {
 	return nil
 }
 
+// Close 停止 L1 的后台清理；广播订阅随 EnableBroadcast 的 ctx 结束
+func (m *MultiLevelCache) Close() {
+	m.L1.Close()
+}
+
 // CacheAside 缓存旁路模式
 type CacheAside struct {
 	cache *MultiLevelCache
@@ -195,58 +385,6 @@ func (w *WriteThrough) Write(ctx context.Context, key string, value interface{})
 	return w.store.Save(ctx, key, value)
 }
 
-// WriteBack 写回
-type WriteBack struct {
-	cache         *MultiLevelCache
-	store         StoreAdapter
-	writeQueue    chan writeJob
-	flushInterval time.Duration
-}
-
-type writeJob struct {
-	key   string
-	value interface{}
-}
-
-// NewWriteBack 创建写回
-func NewWriteBack(cache *MultiLevelCache, store StoreAdapter, flushInterval time.Duration) *WriteBack {
-	wb := &WriteBack{
-		cache:         cache,
-		store:         store,
-		writeQueue:    make(chan writeJob, 100),
-		flushInterval: flushInterval,
-	}
-	go wb.flushWorker()
-	return wb
-}
-
-// Write 写入数据 (Write-Back)
-func (w *WriteBack) Write(ctx context.Context, key string, value interface{}) error {
-	// 只写缓存
-	if err := w.cache.Set(ctx, key, value); err != nil {
-		return err
-	}
-
-	// 加入写队列
-	select {
-	case w.writeQueue <- writeJob{key: key, value: value}:
-		return nil
-	default:
-		return fmt.Errorf("write queue full")
-	}
-}
-
-// flushWorker 后台刷写线程
-func (w *WriteBack) flushWorker() {
-	ticker := time.NewTicker(w.flushInterval)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		// 批量刷写
-		// 简化实现：这里应该从队列中取出并写入存储
-	}
-}
-
 // CacheStrategyBuilder 缓存策略构建器
 type CacheStrategyBuilder struct {
 	config CacheConfig
@@ -256,10 +394,11 @@ type CacheStrategyBuilder struct {
 func NewCacheStrategyBuilder() *CacheStrategyBuilder {
 	return &CacheStrategyBuilder{
 		config: CacheConfig{
-			TTL:         make(map[string]time.Duration),
-			MaxSize:     1000,
-			Compression: false,
-			Prefix:      "app:",
+			TTL:          make(map[string]time.Duration),
+			MaxSize:      1000,
+			Compression:  false,
+			Prefix:       "app:",
+			Singleflight: true,
 		},
 	}
 }
@@ -288,6 +427,55 @@ func (b *CacheStrategyBuilder) WithPrefix(prefix string) *CacheStrategyBuilder {
 	return b
 }
 
+// WithSingleflight 同一 key 并发未命中时合并 L3 加载（默认开启）
+func (b *CacheStrategyBuilder) WithSingleflight(enabled bool) *CacheStrategyBuilder {
+	b.config.Singleflight = enabled
+	return b
+}
+
+// WithLoadTimeout 设置合并加载的超时
+func (b *CacheStrategyBuilder) WithLoadTimeout(timeout time.Duration) *CacheStrategyBuilder {
+	b.config.LoadTimeout = timeout
+	return b
+}
+
+// WithNegativeTTL 缓存"不存在"结果的时长，0 表示关闭，建议远小于正常 TTL
+func (b *CacheStrategyBuilder) WithNegativeTTL(ttl time.Duration) *CacheStrategyBuilder {
+	b.config.NegativeTTL = ttl
+	return b
+}
+
+// WithTTLJitter 写入时 TTL 的随机增量比例，如 0.1 表示增加 0~10%
+func (b *CacheStrategyBuilder) WithTTLJitter(fraction float64) *CacheStrategyBuilder {
+	b.config.TTLJitter = fraction
+	return b
+}
+
+// WithMaxBytes 设置 L1 最大字节数
+func (b *CacheStrategyBuilder) WithMaxBytes(bytes int64) *CacheStrategyBuilder {
+	b.config.MaxBytes = bytes
+	return b
+}
+
+// WithEviction 设置 L1 淘汰策略
+func (b *CacheStrategyBuilder) WithEviction(policy EvictionPolicyType) *CacheStrategyBuilder {
+	b.config.Eviction = policy
+	return b
+}
+
+// WithL1TTL 设置 L1 条目过期时间，janitorInterval 大于 0 时后台定期清理过期条目
+func (b *CacheStrategyBuilder) WithL1TTL(ttl, janitorInterval time.Duration) *CacheStrategyBuilder {
+	b.config.L1TTL = ttl
+	b.config.JanitorInterval = janitorInterval
+	return b
+}
+
+// WithMetrics 设置 L1 指标收集器
+func (b *CacheStrategyBuilder) WithMetrics(collector *MetricsCollector) *CacheStrategyBuilder {
+	b.config.Metrics = collector
+	return b
+}
+
 // Build 构建策略
 func (b *CacheStrategyBuilder) Build() *CacheStrategy {
 	return NewCacheStrategy(b.config)
@@ -295,11 +483,12 @@ func (b *CacheStrategyBuilder) Build() *CacheStrategy {
 
 // CacheMetrics 缓存指标
 type CacheMetrics struct {
-	Hits   int64
-	Misses int64
-	Evicts int64
-	Sets   int64
-	Gets   int64
+	Hits    int64
+	Misses  int64
+	Evicts  int64
+	Expired int64
+	Sets    int64
+	Gets    int64
 }
 
 // MetricsCollector 指标收集器
@@ -357,12 +546,23 @@ func (c *MetricsCollector) RecordEvict(cacheType string) {
 	c.metrics[cacheType].Evicts++
 }
 
+// RecordExpire 记录过期移除
+func (c *MetricsCollector) RecordExpire(cacheType string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.metrics[cacheType]; !exists {
+		c.metrics[cacheType] = &CacheMetrics{}
+	}
+	c.metrics[cacheType].Expired++
+}
+
 // GetMetrics 获取指标
 func (c *MetricsCollector) GetMetrics(cacheType string) *CacheMetrics {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	if metrics, exists := c.metrics[cacheType]; exists {
-		return metrics
+		snapshot := *metrics
+		return &snapshot
 	}
 	return &CacheMetrics{}
 }
@@ -389,49 +589,68 @@ func NewCacheProtection(cache *MultiLevelCache) *CacheProtection {
 }
 
 // PreventCachePenetration 防止缓存穿透
+// 策略配置 NegativeTTL 时，L3 返回 ErrNotFound 的 key 在 NegativeTTL 内直接返回 ErrNotFound
 func (p *CacheProtection) PreventCachePenetration(ctx context.Context, key string) (interface{}, error) {
-	// 简化实现：使用空值缓存
-	// 实际应该检查空值标记
 	return p.cache.Get(ctx, key)
 }
 
 // PreventCacheAvalanche 防止缓存雪崩
+// 以带随机抖动的 TTL 把 keys 在 L1 中的值重新写入 L2，打散同批写入的过期时间
 func (p *CacheProtection) PreventCacheAvalanche(keys []string, ttl time.Duration) error {
-	// 为每个 key 添加随机 TTL
-	// 简化实现：需要 L2 支持随机 TTL
+	if p.cache.L2 == nil {
+		return nil
+	}
+	strategy := p.cache.strategy
+	if strategy == nil || strategy.config.TTLJitter <= 0 {
+		strategy = NewCacheStrategy(CacheConfig{TTLJitter: 0.1})
+	}
+	for _, key := range keys {
+		val, ok := p.cache.L1.Load(key)
+		if !ok {
+			continue
+		}
+		if err := p.cache.L2.Set(key, val, strategy.Jitter(ttl)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // PreventCacheBreakdown 防止缓存击穿
+// 无论策略是否开启 Singleflight，同一 key 的并发未命中都只执行一次 L3 加载（进程内）
 func (p *CacheProtection) PreventCacheBreakdown(ctx context.Context, key string) (interface{}, error) {
-	// 简化实现：使用互斥锁
-	// 需要分布式锁支持
-	return p.cache.Get(ctx, key)
+	return p.cache.get(ctx, key, true)
 }
 
 // CacheWarmup 缓存预热
 type CacheWarmup struct {
-	cache  *MultiLevelCache
-	loader LoaderFunc
+	cache   *MultiLevelCache
+	loader  LoaderFunc
+	workers int
 }
 
-// NewCacheWarmup 创建缓存预热
+// NewCacheWarmup 创建缓存预热，默认逐个加载
 func NewCacheWarmup(cache *MultiLevelCache, loader LoaderFunc) *CacheWarmup {
 	return &CacheWarmup{
-		cache:  cache,
-		loader: loader,
+		cache:   cache,
+		loader:  loader,
+		workers: 1,
 	}
 }
 
-// Warmup 预热缓存
+// WithConcurrency 设置同时加载的 key 数
+func (w *CacheWarmup) WithConcurrency(n int) *CacheWarmup {
+	w.workers = n
+	return w
+}
+
+// Warmup 预热缓存。单个 key 失败不影响其余 key，返回所有失败的汇总
+// （每项为 *concurrency.ItemError，Index 为 key 在 keys 中的下标）
 func (w *CacheWarmup) Warmup(ctx context.Context, keys []string) error {
-	for _, key := range keys {
+	return concurrency.ForEach(ctx, keys, w.workers, func(ctx context.Context, key string) error {
 		_, err := w.cache.Get(ctx, key)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+		return err
+	})
 }
 
 // CacheEvictionPolicy 缓存淘汰策略接口
@@ -440,15 +659,21 @@ type CacheEvictionPolicy interface {
 }
 
 // LFUPolicy LFU 淘汰策略
+// 按访问频率分桶，频率相同时淘汰较久未访问的 key，各操作均为 O(1)
 type LFUPolicy struct {
 	frequency map[string]int
-	mu        sync.RWMutex
+	elements  map[string]*list.Element
+	buckets   map[int]*list.List // 频率 -> key 列表，前端为最近访问
+	minFreq   int
+	mu        sync.Mutex
 }
 
 // NewLFUPolicy 创建 LFU 策略
 func NewLFUPolicy() *LFUPolicy {
 	return &LFUPolicy{
 		frequency: make(map[string]int),
+		elements:  make(map[string]*list.Element),
+		buckets:   make(map[int]*list.List),
 	}
 }
 
@@ -456,42 +681,91 @@ func NewLFUPolicy() *LFUPolicy {
 func (p *LFUPolicy) RecordAccess(key string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.frequency[key]++
-}
 
-// Evict 淘汰
-func (p *LFUPolicy) Evict(cache *MultiLevelCache) error {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	freq := p.frequency[key]
+	if freq > 0 {
+		p.unlink(key, freq)
+		if p.minFreq == freq && p.buckets[freq] == nil {
+			p.minFreq = freq + 1
+		}
+	} else {
+		p.minFreq = 1
+	}
 
-	// 找到频率最低的 key
-	var minKey string
-	minFreq := -1
+	freq++
+	p.frequency[key] = freq
+	bucket := p.buckets[freq]
+	if bucket == nil {
+		bucket = list.New()
+		p.buckets[freq] = bucket
+	}
+	p.elements[key] = bucket.PushFront(key)
+}
+
+// Remove 停止跟踪 key
+func (p *LFUPolicy) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	for key, freq := range p.frequency {
-		if minFreq == -1 || freq < minFreq {
-			minFreq = freq
-			minKey = key
+	freq, ok := p.frequency[key]
+	if !ok {
+		return
+	}
+	p.unlink(key, freq)
+	delete(p.frequency, key)
+	if p.minFreq == freq && p.buckets[freq] == nil {
+		p.minFreq = 0
+		for f := range p.buckets {
+			if p.minFreq == 0 || f < p.minFreq {
+				p.minFreq = f
+			}
 		}
 	}
+}
+
+// Victim 返回应淘汰的 key
+func (p *LFUPolicy) Victim() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	if minKey != "" {
-		return cache.Delete(context.Background(), minKey)
+	bucket := p.buckets[p.minFreq]
+	if bucket == nil {
+		return "", false
 	}
+	return bucket.Back().Value.(string), true
+}
 
-	return nil
+// Evict 淘汰
+func (p *LFUPolicy) Evict(cache *MultiLevelCache) error {
+	key, ok := p.Victim()
+	if !ok {
+		return nil
+	}
+	p.Remove(key)
+	return cache.Delete(context.Background(), key)
+}
+
+func (p *LFUPolicy) unlink(key string, freq int) {
+	bucket := p.buckets[freq]
+	bucket.Remove(p.elements[key])
+	delete(p.elements, key)
+	if bucket.Len() == 0 {
+		delete(p.buckets, freq)
+	}
 }
 
 // LRUPolicy LRU 淘汰策略
 type LRUPolicy struct {
-	accessOrder []string
-	mu          sync.RWMutex
+	order    *list.List // 前端为最近访问
+	elements map[string]*list.Element
+	mu       sync.Mutex
 }
 
 // NewLRUPolicy 创建 LRU 策略
 func NewLRUPolicy() *LRUPolicy {
 	return &LRUPolicy{
-		accessOrder: make([]string, 0),
+		order:    list.New(),
+		elements: make(map[string]*list.Element),
 	}
 }
 
@@ -500,29 +774,43 @@ func (p *LRUPolicy) RecordAccess(key string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	// 移除已存在的
-	for i, k := range p.accessOrder {
-		if k == key {
-			p.accessOrder = append(p.accessOrder[:i], p.accessOrder[i+1:]...)
-			break
-		}
+	if e, ok := p.elements[key]; ok {
+		p.order.MoveToFront(e)
+		return
 	}
+	p.elements[key] = p.order.PushFront(key)
+}
 
-	// 添加到末尾
-	p.accessOrder = append(p.accessOrder, key)
+// Remove 停止跟踪 key
+func (p *LRUPolicy) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.elements[key]; ok {
+		p.order.Remove(e)
+		delete(p.elements, key)
+	}
 }
 
-// Evict 淘汰
-func (p *LRUPolicy) Evict(cache *MultiLevelCache) error {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+// Victim 返回最久未访问的 key
+func (p *LRUPolicy) Victim() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	if len(p.accessOrder) > 0 {
-		oldestKey := p.accessOrder[0]
-		return cache.Delete(context.Background(), oldestKey)
+	if e := p.order.Back(); e != nil {
+		return e.Value.(string), true
 	}
+	return "", false
+}
 
-	return nil
+// Evict 淘汰
+func (p *LRUPolicy) Evict(cache *MultiLevelCache) error {
+	key, ok := p.Victim()
+	if !ok {
+		return nil
+	}
+	p.Remove(key)
+	return cache.Delete(context.Background(), key)
 }
 
 // TTLCache TTL 缓存
@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ttlAdapter 记录写入 TTL 的内存 L2
+type ttlAdapter struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+	ttls   map[string]time.Duration
+}
+
+func newTTLAdapter() *ttlAdapter {
+	return &ttlAdapter{values: make(map[string]interface{}), ttls: make(map[string]time.Duration)}
+}
+
+func (a *ttlAdapter) Get(key string) (interface{}, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if v, ok := a.values[key]; ok {
+		return v, nil
+	}
+	return nil, ErrCacheMiss
+}
+
+func (a *ttlAdapter) Set(key string, value interface{}, ttl time.Duration) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.values[key] = value
+	a.ttls[key] = ttl
+	return nil
+}
+
+func (a *ttlAdapter) Delete(key string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.values, key)
+	return nil
+}
+
+func (a *ttlAdapter) Exists(key string) bool {
+	_, err := a.Get(key)
+	return err == nil
+}
+
+func TestMultiLevelCache_SingleflightLoad(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "value", nil
+	}
+	cache := NewMultiLevelCache(newTTLAdapter(), loader)
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cache.Get(context.Background(), "hot")
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("loader calls = %d, want 1", got)
+	}
+	for i, v := range results {
+		if v != "value" {
+			t.Fatalf("result %d = %v", i, v)
+		}
+	}
+}
+
+func TestMultiLevelCache_NilStrategyUsesDefault(t *testing.T) {
+	loader := func(ctx context.Context) (interface{}, error) { return "loaded", nil }
+	cache := NewMultiLevelCacheWithStrategy(newTTLAdapter(), loader, nil)
+	defer cache.Close()
+
+	if v, err := cache.Get(context.Background(), "k"); err != nil || v != "loaded" {
+		t.Fatalf("Get = %v, %v", v, err)
+	}
+}
+
+func TestMultiLevelCache_NegativeCaching(t *testing.T) {
+	var calls int32
+	loader := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, ErrNotFound
+	}
+	l2 := newTTLAdapter()
+	strategy := NewCacheStrategyBuilder().WithNegativeTTL(50 * time.Millisecond).Build()
+	cache := NewMultiLevelCacheWithStrategy(l2, loader, strategy)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Get err = %v, want ErrNotFound", err)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("loader calls = %d, want 1", got)
+	}
+	if l2.ttls["missing"] != 50*time.Millisecond {
+		t.Errorf("negative ttl in L2 = %v", l2.ttls["missing"])
+	}
+	if _, ok := cache.peek("missing"); ok {
+		t.Error("negative marker must not be visible to peek")
+	}
+
+	// 写入真实值后负缓存失效
+	cache.Set(ctx, "missing", "now-exists")
+	if v, err := cache.Get(ctx, "missing"); err != nil || v != "now-exists" {
+		t.Fatalf("Get after Set = %v, %v", v, err)
+	}
+
+	// 负缓存过期后重新加载
+	cache.Delete(ctx, "missing")
+	l2.Set("other", negativeMarker, time.Second)
+	if _, err := cache.Get(ctx, "other"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("L2 marker err = %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	l2.Delete("other")
+	if _, err := cache.Get(ctx, "other"); !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("loader calls after expiry = %d, want 2", got)
+	}
+}
+
+func TestMultiLevelCache_TTLJitter(t *testing.T) {
+	l2 := newTTLAdapter()
+	strategy := NewCacheStrategyBuilder().WithTTLJitter(0.5).Build()
+	cache := NewMultiLevelCacheWithStrategy(l2, nil, strategy)
+
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 20; i++ {
+		cache.Set(context.Background(), "k", i)
+		ttl := l2.ttls["k"]
+		if ttl < defaultTTL || ttl >= defaultTTL+defaultTTL/2 {
+			t.Fatalf("jittered ttl %v out of range", ttl)
+		}
+		seen[ttl] = true
+	}
+	if len(seen) < 2 {
+		t.Error("ttl was not jittered")
+	}
+
+	protection := NewCacheProtection(cache)
+	if err := protection.PreventCacheAvalanche([]string{"k", "absent"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := l2.ttls["k"]; ttl < time.Minute || ttl >= 90*time.Second {
+		t.Errorf("avalanche ttl = %v", ttl)
+	}
+	if _, ok := l2.values["absent"]; ok {
+		t.Error("keys missing from L1 must not be written")
+	}
+}
+
+func TestCacheProtection_BreakdownWithoutStrategySingleflight(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 1, nil
+	}
+	strategy := NewCacheStrategyBuilder().WithSingleflight(false).Build()
+	protection := NewCacheProtection(NewMultiLevelCacheWithStrategy(nil, loader, strategy))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			protection.PreventCacheBreakdown(context.Background(), "hot")
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("loader calls = %d, want 1", got)
+	}
+}
+
+func TestMultiLevelCache_SingleflightSurvivesFirstCallerCancel(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		select {
+		case <-release:
+			return "value", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	cache := NewMultiLevelCache(newTTLAdapter(), loader)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := cache.Get(ctx, "hot")
+		first <- err
+	}()
+	<-started
+
+	second := make(chan interface{}, 1)
+	go func() {
+		v, _ := cache.Get(context.Background(), "hot")
+		second <- v
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first caller err = %v, want context.Canceled", err)
+	}
+	close(release)
+	if v := <-second; v != "value" {
+		t.Fatalf("second caller = %v, want value", v)
+	}
+}
+
+func TestMultiLevelCache_SingleflightLoadTimeout(t *testing.T) {
+	loader := func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	strategy := NewCacheStrategyBuilder().WithLoadTimeout(20 * time.Millisecond).Build()
+	cache := NewMultiLevelCacheWithStrategy(newTTLAdapter(), loader, strategy)
+
+	if _, err := cache.Get(context.Background(), "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get err = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,292 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/leeforge/framework/json"
+)
+
+// DefaultInvalidationChannel Redis 失效广播的默认频道
+const DefaultInvalidationChannel = "cache:invalidate"
+
+// SetOption Set 的可选参数
+type SetOption func(*setOptions)
+
+type setOptions struct {
+	tags []string
+}
+
+// WithTags 为 key 关联标签，如 "user:42"、"tenant:7"
+func WithTags(tags ...string) SetOption {
+	return func(o *setOptions) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// TaggedAdapter L2 可选实现的标签与前缀失效能力，RedisAdapter 已实现
+type TaggedAdapter interface {
+	// TagKey 把 key 加入各标签的集合，集合的过期时间不短于 ttl
+	TagKey(ctx context.Context, key string, tags []string, ttl time.Duration) error
+	// InvalidateTag 删除标签下的所有 key 及标签集合，返回被删除的 key
+	InvalidateTag(ctx context.Context, tag string) ([]string, error)
+	// DeleteByPrefix 删除以 prefix 开头的 key，返回删除数量
+	DeleteByPrefix(ctx context.Context, prefix string) (int, error)
+}
+
+// InvalidationMessage 广播给其他实例的失效消息
+type InvalidationMessage struct {
+	Origin   string   `json:"origin"`
+	Keys     []string `json:"keys,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Prefixes []string `json:"prefixes,omitempty"`
+}
+
+// InvalidationBus 在实例间广播失效消息，用于清除其他实例的 L1
+type InvalidationBus interface {
+	Publish(ctx context.Context, msg InvalidationMessage) error
+	// Subscribe 订阅成功后返回，ctx 结束时停止投递
+	Subscribe(ctx context.Context, handler func(InvalidationMessage)) error
+}
+
+// InvalidateTag 失效与标签关联的所有 key：清除本地 L1、L2 中的 key，并广播给其他实例
+// L2 未实现 TaggedAdapter 时只能删除本实例 L1 中记录过标签的 key
+func (m *MultiLevelCache) InvalidateTag(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	keys := m.tags.keys(tags)
+	var errs []error
+	if tagged, ok := m.L2.(TaggedAdapter); ok {
+		for _, tag := range tags {
+			removed, err := tagged.InvalidateTag(ctx, tag)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			keys = append(keys, removed...)
+		}
+	} else if m.L2 != nil {
+		for _, key := range keys {
+			if err := m.L2.Delete(key); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	keys = dedupe(keys)
+	for _, key := range keys {
+		m.evictLocal(key)
+	}
+	if err := m.broadcast(ctx, InvalidationMessage{Tags: tags, Keys: keys}); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// InvalidateByPrefix 失效以 prefix 开头的所有 key：清除本地 L1、L2（需实现 TaggedAdapter），并广播给其他实例
+func (m *MultiLevelCache) InvalidateByPrefix(ctx context.Context, prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("cache: invalidate by prefix: empty prefix")
+	}
+
+	m.evictLocalPrefix(prefix)
+	var errs []error
+	if tagged, ok := m.L2.(TaggedAdapter); ok {
+		if _, err := tagged.DeleteByPrefix(ctx, prefix); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := m.broadcast(ctx, InvalidationMessage{Prefixes: []string{prefix}}); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// EnableBroadcast 通过 bus 与其他实例同步失效：本实例的 InvalidateTag / InvalidateByPrefix 会广播，
+// 收到其他实例的消息时清除本地 L1。订阅持续到 ctx 结束
+func (m *MultiLevelCache) EnableBroadcast(ctx context.Context, bus InvalidationBus) error {
+	if err := bus.Subscribe(ctx, m.handleInvalidation); err != nil {
+		return err
+	}
+	m.bus = bus
+	return nil
+}
+
+func (m *MultiLevelCache) handleInvalidation(msg InvalidationMessage) {
+	if msg.Origin == m.id {
+		return
+	}
+	for _, key := range msg.Keys {
+		m.evictLocal(key)
+	}
+	for _, key := range m.tags.keys(msg.Tags) {
+		m.evictLocal(key)
+	}
+	for _, prefix := range msg.Prefixes {
+		m.evictLocalPrefix(prefix)
+	}
+}
+
+func (m *MultiLevelCache) broadcast(ctx context.Context, msg InvalidationMessage) error {
+	if m.bus == nil {
+		return nil
+	}
+	msg.Origin = m.id
+	if err := m.bus.Publish(ctx, msg); err != nil {
+		return fmt.Errorf("cache: broadcast invalidation: %w", err)
+	}
+	return nil
+}
+
+// evictLocal 只清除本实例的 L1 与负缓存
+func (m *MultiLevelCache) evictLocal(key string) {
+	m.L1.Delete(key)
+	m.negative.Delete(key)
+}
+
+func (m *MultiLevelCache) evictLocalPrefix(prefix string) {
+	m.L1.DeleteByPrefix(prefix)
+	m.negative.Range(func(key, _ interface{}) bool {
+		if strings.HasPrefix(key.(string), prefix) {
+			m.negative.Delete(key)
+		}
+		return true
+	})
+}
+
+// tagIndex 记录 L1 中 key 与标签的双向关联，key 离开 L1 时同步移除
+type tagIndex struct {
+	mu    sync.Mutex
+	byTag map[string]map[string]struct{}
+	byKey map[string][]string
+}
+
+func newTagIndex() *tagIndex {
+	return &tagIndex{
+		byTag: make(map[string]map[string]struct{}),
+		byKey: make(map[string][]string),
+	}
+}
+
+// set 替换 key 的标签
+func (t *tagIndex) set(key string, tags []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.removeLocked(key)
+	if len(tags) == 0 {
+		return
+	}
+	t.byKey[key] = tags
+	for _, tag := range tags {
+		keys := t.byTag[tag]
+		if keys == nil {
+			keys = make(map[string]struct{})
+			t.byTag[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+func (t *tagIndex) remove(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.removeLocked(key)
+}
+
+func (t *tagIndex) removeLocked(key string) {
+	for _, tag := range t.byKey[key] {
+		delete(t.byTag[tag], key)
+		if len(t.byTag[tag]) == 0 {
+			delete(t.byTag, tag)
+		}
+	}
+	delete(t.byKey, key)
+}
+
+func (t *tagIndex) keys(tags []string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var keys []string
+	for _, tag := range tags {
+		for key := range t.byTag[tag] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func dedupe(keys []string) []string {
+	seen := make(map[string]bool, len(keys))
+	out := keys[:0]
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+// RedisInvalidationBus 基于 Redis Pub/Sub 的 InvalidationBus
+// Pub/Sub 不保证送达，断线期间的消息会丢失，L1 应配置较短的 TTL 兜底
+type RedisInvalidationBus struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+var _ InvalidationBus = (*RedisInvalidationBus)(nil)
+
+// NewRedisInvalidationBus 创建 Redis 失效广播，channel 为空时使用 DefaultInvalidationChannel
+func NewRedisInvalidationBus(client redis.UniversalClient, channel string) *RedisInvalidationBus {
+	if channel == "" {
+		channel = DefaultInvalidationChannel
+	}
+	return &RedisInvalidationBus{client: client, channel: channel}
+}
+
+// Publish 发布失效消息
+func (b *RedisInvalidationBus) Publish(ctx context.Context, msg InvalidationMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, data).Err()
+}
+
+// Subscribe 订阅失效消息，确认订阅后返回
+func (b *RedisInvalidationBus) Subscribe(ctx context.Context, handler func(InvalidationMessage)) error {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("cache: subscribe %s: %w", b.channel, err)
+	}
+
+	go func() {
+		defer pubsub.Close()
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-ch:
+				if !ok {
+					return
+				}
+				var msg InvalidationMessage
+				if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+					continue
+				}
+				handler(msg)
+			}
+		}
+	}()
+	return nil
+}
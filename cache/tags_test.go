@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryBus 进程内的 InvalidationBus，同步投递给所有订阅者
+type memoryBus struct {
+	mu       sync.Mutex
+	handlers []func(InvalidationMessage)
+}
+
+func (b *memoryBus) Publish(_ context.Context, msg InvalidationMessage) error {
+	b.mu.Lock()
+	handlers := append([]func(InvalidationMessage){}, b.handlers...)
+	b.mu.Unlock()
+	for _, h := range handlers {
+		h(msg)
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(_ context.Context, handler func(InvalidationMessage)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+func TestMultiLevelCache_InvalidateTag(t *testing.T) {
+	ctx := context.Background()
+	l2 := newTTLAdapter()
+	cache := NewMultiLevelCache(l2, nil)
+
+	cache.Set(ctx, "user:42:profile", "p", WithTags("user:42", "tenant:7"))
+	cache.Set(ctx, "user:42:settings", "s", WithTags("user:42"))
+	cache.Set(ctx, "user:43:profile", "q", WithTags("tenant:7"))
+
+	if err := cache.InvalidateTag(ctx, "user:42"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"user:42:profile", "user:42:settings"} {
+		if _, ok := cache.peek(key); ok {
+			t.Errorf("%s should be invalidated", key)
+		}
+	}
+	if _, ok := cache.peek("user:43:profile"); !ok {
+		t.Error("untagged key must survive")
+	}
+
+	// 重新写入时替换标签
+	cache.Set(ctx, "user:43:profile", "q2")
+	if err := cache.InvalidateTag(ctx, "tenant:7"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.peek("user:43:profile"); !ok {
+		t.Error("tags must be replaced on Set")
+	}
+}
+
+func TestMultiLevelCache_BroadcastInvalidation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := &memoryBus{}
+	a := NewMultiLevelCache(nil, nil)
+	b := NewMultiLevelCache(nil, nil)
+	for _, c := range []*MultiLevelCache{a, b} {
+		if err := c.EnableBroadcast(ctx, bus); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a.Set(ctx, "order:1", 1, WithTags("customer:9"))
+	b.Set(ctx, "order:1", 1, WithTags("customer:9"))
+	b.Set(ctx, "report:2026", 2)
+	b.Set(ctx, "report:2027", 3)
+	b.Set(ctx, "other", 4)
+
+	if err := a.InvalidateTag(ctx, "customer:9"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.L1.Load("order:1"); ok {
+		t.Error("remote L1 not invalidated by tag")
+	}
+
+	if err := a.InvalidateByPrefix(ctx, "report:"); err != nil {
+		t.Fatal(err)
+	}
+	if b.L1.Len() != 1 {
+		t.Errorf("remote L1 len = %d, want only 'other' left", b.L1.Len())
+	}
+	if err := a.InvalidateByPrefix(ctx, ""); err == nil {
+		t.Error("empty prefix must be rejected")
+	}
+}
+
+func TestRedisAdapterTags(t *testing.T) {
+	client, prefix := redisTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	strategy := NewCacheStrategyBuilder().WithPrefix(prefix).Build()
+
+	a := NewMultiLevelCacheWithStrategy(NewRedisAdapter(client, strategy), nil, strategy)
+	b := NewMultiLevelCacheWithStrategy(NewRedisAdapter(client, strategy), nil, strategy)
+	channel := prefix + "invalidate"
+	for _, c := range []*MultiLevelCache{a, b} {
+		if err := c.EnableBroadcast(ctx, NewRedisInvalidationBus(client, channel)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a.Set(ctx, "user:42", "alice", WithTags("tenant:7"))
+	a.Set(ctx, "user:43", "bob", WithTags("tenant:7"))
+	if ttl := client.TTL(ctx, prefix+"__tag:tenant:7").Val(); ttl <= 0 {
+		t.Errorf("tag set ttl = %v, want expiring set", ttl)
+	}
+	// b 从 L2 读入 L1，本地没有标签信息，依赖广播中的 key 列表
+	if v, err := b.Get(ctx, "user:42"); err != nil || v != "alice" {
+		t.Fatalf("b.Get = %v, %v", v, err)
+	}
+
+	if err := a.InvalidateTag(ctx, "tenant:7"); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.Exists(ctx, prefix+"user:42", prefix+"user:43", prefix+"__tag:tenant:7").Val(); n != 0 {
+		t.Errorf("%d redis keys survived tag invalidation", n)
+	}
+	waitFor(t, func() bool { _, ok := b.L1.Load("user:42"); return !ok })
+
+	a.Set(ctx, "sess:1", 1)
+	a.Set(ctx, "sess:2", 2)
+	a.Set(ctx, "sess*x", 3)
+	a.Set(ctx, "keep", 4)
+	if v, _ := b.Get(ctx, "sess:1"); v == nil {
+		t.Fatal("b should read sess:1 from L2")
+	}
+	if err := a.InvalidateByPrefix(ctx, "sess:"); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.Exists(ctx, prefix+"sess:1", prefix+"sess:2").Val(); n != 0 {
+		t.Errorf("%d prefixed keys survived", n)
+	}
+	if n := client.Exists(ctx, prefix+"sess*x", prefix+"keep").Val(); n != 2 {
+		t.Error("keys outside the prefix must survive (glob characters are escaped)")
+	}
+	waitFor(t, func() bool { _, ok := b.L1.Load("sess:1"); return !ok })
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,564 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/jobs"
+	"github.com/leeforge/framework/metrics"
+	"go.uber.org/zap"
+)
+
+// 写回使用的任务类型
+const (
+	JobTypeWriteBack      = "cache.write_back"
+	JobTypeWriteBackBatch = "cache.write_back_batch"
+	JobTypeReconcile      = "cache.reconcile"
+)
+
+// 写回队列深度指标（gauge）
+const (
+	MetricWriteBackPending  = "cache_write_back_pending"  // 已投递、尚未结束的写回
+	MetricWriteBackBuffered = "cache_write_back_buffered" // 批量模式下尚未投递的写回
+)
+
+// ErrWriteBackClosed Close 之后的写入返回该错误
+var ErrWriteBackClosed = errors.New("cache: write-back closed")
+
+// BatchEntry 批量写回中的一条
+type BatchEntry struct {
+	Key   string
+	Value interface{}
+}
+
+// BatchStore 支持批量保存的存储，批量写回时优先使用
+type BatchStore interface {
+	// SaveBatch 按顺序保存 entries，失败时整批重试，需幂等
+	SaveBatch(ctx context.Context, entries []BatchEntry) error
+}
+
+// StoreLoader 可读取持久化值的存储，对账时使用
+type StoreLoader interface {
+	// Load 读取 key 的持久化值，不存在时返回 (nil, nil)
+	Load(ctx context.Context, key string) (interface{}, error)
+}
+
+// ReconcileRepair 对账发现不一致时的处理方式
+type ReconcileRepair string
+
+const (
+	// RepairInvalidate 删除缓存，下次读取从存储加载（默认）
+	RepairInvalidate ReconcileRepair = "invalidate"
+	// RepairPersist 以缓存为准重新写回存储
+	RepairPersist ReconcileRepair = "persist"
+	// RepairReport 只记录，不处理
+	RepairReport ReconcileRepair = "report"
+)
+
+// WriteBackOptions 写回配置
+type WriteBackOptions struct {
+	// Decode 将任务中的 JSON 还原为存储需要的值，默认直接传递 json.RawMessage
+	Decode func(key string, raw json.RawMessage) (interface{}, error)
+	// Keys 对账时未指定 key 则使用该函数列出需要检查的 key
+	Keys func(ctx context.Context) ([]string, error)
+	// Repair 对账发现不一致时的处理方式，默认 RepairInvalidate
+	Repair ReconcileRepair
+	// BatchSize 大于 1 时开启批量写回：写入先在内存中按 key 合并（后写覆盖先写），
+	// 攒满 BatchSize 或每隔 BatchInterval 投递一个批量任务；投递前的写入在进程崩溃时会丢失
+	BatchSize int
+	// BatchInterval 批量写回的最长等待时间，默认 1 秒
+	BatchInterval time.Duration
+	// Metrics 上报写回队列深度
+	Metrics *metrics.Collector
+	Logger  *zap.Logger
+}
+
+// ReconcileReport 对账结果
+type ReconcileReport struct {
+	Checked   int      `json:"checked"`
+	Skipped   []string `json:"skipped,omitempty"` // 仍有未完成写回的 key
+	Divergent []string `json:"divergent,omitempty"`
+	Repaired  int      `json:"repaired"`
+}
+
+// WriteBack 写回
+// 写入只更新缓存，持久化作为任务投递到 jobs：使用持久化的任务存储（如 jobs.RedisStore）时
+// 进程崩溃后未完成的写回会在重启后继续执行；失败按任务策略重试，耗尽后进入死信队列；
+// 同一 key 的写回按写入顺序串行执行
+type WriteBack struct {
+	cache *MultiLevelCache
+	store StoreAdapter
+	jobs  *jobs.Manager
+	opts  WriteBackOptions
+
+	id           string // 标记本实例投递的写回任务
+	mu           sync.Mutex
+	pending      map[string]int // 本进程投递且未结束的写回数量
+	pendingTotal int
+	buffer       []writeBackPayload // 批量模式下尚未投递的写回
+	buffered     map[string]int     // key -> buffer 下标
+	closed       bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type writeBackPayload struct {
+	Key    string          `json:"key"`
+	Value  json.RawMessage `json:"value"`
+	Origin string          `json:"origin,omitempty"` // 投递的实例，只有它计入了 pending
+}
+
+type writeBackBatchPayload struct {
+	Entries []writeBackPayload `json:"entries"`
+	Origin  string             `json:"origin,omitempty"`
+}
+
+type reconcilePayload struct {
+	Keys []string `json:"keys,omitempty"`
+}
+
+// NewWriteBack 创建写回，并在 manager 上注册写回与对账任务，需在 manager.Start 之前调用
+func NewWriteBack(cache *MultiLevelCache, store StoreAdapter, manager *jobs.Manager, opts WriteBackOptions) *WriteBack {
+	if opts.Decode == nil {
+		opts.Decode = func(_ string, raw json.RawMessage) (interface{}, error) { return raw, nil }
+	}
+	if opts.Repair == "" {
+		opts.Repair = RepairInvalidate
+	}
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	if opts.BatchInterval <= 0 {
+		opts.BatchInterval = time.Second
+	}
+
+	wb := &WriteBack{
+		id:       uuid.NewString(),
+		cache:    cache,
+		store:    store,
+		jobs:     manager,
+		opts:     opts,
+		pending:  make(map[string]int),
+		buffered: make(map[string]int),
+		stop:     make(chan struct{}),
+	}
+	manager.Register(JobTypeWriteBack, wb.persist)
+	manager.Register(JobTypeWriteBackBatch, wb.persistBatch)
+	manager.Register(JobTypeReconcile, wb.reconcileJob)
+	if wb.batching() {
+		go wb.flushLoop()
+	}
+	return wb
+}
+
+func (w *WriteBack) batching() bool { return w.opts.BatchSize > 1 }
+
+// Write 写入数据 (Write-Back)
+// 值需可 JSON 序列化；返回 nil 表示写回任务已投递，不代表已持久化
+func (w *WriteBack) Write(ctx context.Context, key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encode write-back value for %s: %w", key, err)
+	}
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+	if closed {
+		return ErrWriteBackClosed
+	}
+	if err := w.cache.Set(ctx, key, value); err != nil {
+		return err
+	}
+
+	if w.batching() {
+		if w.bufferWrite(writeBackPayload{Key: key, Value: raw}) {
+			return w.drain(ctx)
+		}
+		return nil
+	}
+
+	w.track(key, 1)
+	_, err = w.jobs.Enqueue(ctx, JobTypeWriteBack, writeBackPayload{Key: key, Value: raw, Origin: w.id},
+		jobs.WithOrderingKey("cache:"+key))
+	if err != nil {
+		w.track(key, -1)
+		return fmt.Errorf("enqueue write-back for %s: %w", key, err)
+	}
+	return nil
+}
+
+// bufferWrite 把写入并入缓冲区，返回缓冲区是否已满
+func (w *WriteBack) bufferWrite(p writeBackPayload) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if i, ok := w.buffered[p.Key]; ok {
+		w.buffer[i] = p
+	} else {
+		w.buffered[p.Key] = len(w.buffer)
+		w.buffer = append(w.buffer, p)
+		w.trackLocked(p.Key, 1)
+	}
+	w.reportLocked()
+	return len(w.buffer) >= w.opts.BatchSize
+}
+
+// drain 把缓冲区作为一个批量任务投递；投递失败时未被新写入覆盖的条目放回缓冲区
+func (w *WriteBack) drain(ctx context.Context) error {
+	w.mu.Lock()
+	entries := w.buffer
+	w.buffer = nil
+	w.buffered = make(map[string]int)
+	w.reportLocked()
+	w.mu.Unlock()
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// 批量任务串行执行，保证同一 key 的写回顺序
+	_, err := w.jobs.Enqueue(ctx, JobTypeWriteBackBatch, writeBackBatchPayload{Entries: entries, Origin: w.id},
+		jobs.WithOrderingKey("cache:write-back-batch"))
+	if err == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	restored := make([]writeBackPayload, 0, len(entries)+len(w.buffer))
+	for _, e := range entries {
+		if _, superseded := w.buffered[e.Key]; superseded {
+			w.trackLocked(e.Key, -1)
+			continue
+		}
+		restored = append(restored, e)
+	}
+	restored = append(restored, w.buffer...)
+	w.buffer = restored
+	w.buffered = make(map[string]int, len(restored))
+	for i, e := range restored {
+		w.buffered[e.Key] = i
+	}
+	w.reportLocked()
+	w.mu.Unlock()
+	return fmt.Errorf("enqueue write-back batch of %d: %w", len(entries), err)
+}
+
+func (w *WriteBack) flushLoop() {
+	ticker := time.NewTicker(w.opts.BatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.drain(context.Background()); err != nil {
+				w.opts.Logger.Warn("write-back batch enqueue failed, will retry", zap.Error(err))
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Flush 投递缓冲区中的写回，并等待本进程投递的写回全部结束（成功或进入死信）
+// 用于优雅关闭；ctx 结束时返回仍未完成的数量
+func (w *WriteBack) Flush(ctx context.Context) error {
+	if err := w.drain(ctx); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		w.mu.Lock()
+		remaining := w.pendingTotal
+		w.mu.Unlock()
+		if remaining == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("cache: write-back flush: %d writes still pending: %w", remaining, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close 停止接受写入并 Flush；ctx 结束时尚未投递的缓冲写回被丢弃并记录日志，
+// 已投递的任务仍由 jobs 继续执行（持久化任务存储下重启后继续）
+func (w *WriteBack) Close(ctx context.Context) error {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	err := w.Flush(ctx)
+	if err == nil {
+		return nil
+	}
+	w.mu.Lock()
+	shed := w.buffer
+	for _, e := range shed {
+		w.trackLocked(e.Key, -1)
+	}
+	w.buffer = nil
+	w.buffered = make(map[string]int)
+	w.reportLocked()
+	w.mu.Unlock()
+	if len(shed) > 0 {
+		keys := make([]string, len(shed))
+		for i, e := range shed {
+			keys[i] = e.Key
+		}
+		w.opts.Logger.Error("write-back closed with unsent writes", zap.Strings("keys", keys), zap.Error(err))
+	}
+	return err
+}
+
+// Pending 返回本进程尚未结束的写回数量（含批量模式下尚未投递的）
+func (w *WriteBack) Pending(key string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pending[key]
+}
+
+// persist 写回任务处理函数
+func (w *WriteBack) persist(ctx context.Context, job *jobs.Job) (any, error) {
+	var p writeBackPayload
+	if err := job.Decode(&p); err != nil {
+		return nil, err
+	}
+	value, err := w.opts.Decode(p.Key, p.Value)
+	if err == nil {
+		err = w.store.Save(ctx, p.Key, value)
+	}
+
+	final := err == nil || job.Attempts >= job.MaxAttempts
+	if final && p.Origin == w.id {
+		w.track(p.Key, -1)
+	}
+	if err != nil && final {
+		w.opts.Logger.Error("write-back exhausted retries",
+			zap.String("key", p.Key),
+			zap.String("job_id", job.ID),
+			zap.Int("attempts", job.Attempts),
+			zap.Error(err))
+	}
+	return nil, err
+}
+
+// persistBatch 批量写回任务处理函数，存储实现 BatchStore 时整批保存，否则按顺序逐条保存
+func (w *WriteBack) persistBatch(ctx context.Context, job *jobs.Job) (any, error) {
+	var p writeBackBatchPayload
+	if err := job.Decode(&p); err != nil {
+		return nil, err
+	}
+
+	entries := make([]BatchEntry, 0, len(p.Entries))
+	var err error
+	for _, e := range p.Entries {
+		var value interface{}
+		if value, err = w.opts.Decode(e.Key, e.Value); err != nil {
+			break
+		}
+		entries = append(entries, BatchEntry{Key: e.Key, Value: value})
+	}
+	if err == nil {
+		if batch, ok := w.store.(BatchStore); ok {
+			err = batch.SaveBatch(ctx, entries)
+		} else {
+			for _, e := range entries {
+				if err = w.store.Save(ctx, e.Key, e.Value); err != nil {
+					break
+				}
+			}
+		}
+	}
+
+	final := err == nil || job.Attempts >= job.MaxAttempts
+	if final && p.Origin == w.id {
+		w.mu.Lock()
+		for _, e := range p.Entries {
+			w.trackLocked(e.Key, -1)
+		}
+		w.reportLocked()
+		w.mu.Unlock()
+	}
+	if err != nil && final {
+		w.opts.Logger.Error("write-back batch exhausted retries",
+			zap.Int("entries", len(p.Entries)),
+			zap.String("job_id", job.ID),
+			zap.Int("attempts", job.Attempts),
+			zap.Error(err))
+	}
+	return nil, err
+}
+
+// ScheduleReconcile 投递对账任务，keys 为空时使用 WriteBackOptions.Keys
+// 结果（ReconcileReport）写入任务的 Result
+func (w *WriteBack) ScheduleReconcile(ctx context.Context, keys ...string) (*jobs.Job, error) {
+	return w.jobs.Enqueue(ctx, JobTypeReconcile, reconcilePayload{Keys: keys})
+}
+
+func (w *WriteBack) reconcileJob(ctx context.Context, job *jobs.Job) (any, error) {
+	var p reconcilePayload
+	if err := job.Decode(&p); err != nil {
+		return nil, err
+	}
+	return w.Reconcile(ctx, p.Keys)
+}
+
+// Reconcile 比较缓存与存储中的值，发现不一致时按 Repair 处理
+// 仍有未完成写回的 key 会跳过，包括任务存储中其他实例或上一个进程投递的写回；存储需实现 StoreLoader
+func (w *WriteBack) Reconcile(ctx context.Context, keys []string) (*ReconcileReport, error) {
+	loader, ok := w.store.(StoreLoader)
+	if !ok {
+		return nil, fmt.Errorf("write-back store %T does not implement StoreLoader", w.store)
+	}
+	if len(keys) == 0 && w.opts.Keys != nil {
+		var err error
+		if keys, err = w.opts.Keys(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	durable, err := w.durablePending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReconcileReport{}
+	for _, key := range keys {
+		if w.Pending(key) > 0 || durable[key] {
+			report.Skipped = append(report.Skipped, key)
+			continue
+		}
+		report.Checked++
+
+		cached, ok := w.cache.peek(key)
+		if !ok {
+			continue
+		}
+		stored, err := loader.Load(ctx, key)
+		if err != nil {
+			return report, fmt.Errorf("load %s: %w", key, err)
+		}
+		same, err := sameJSON(cached, stored)
+		if err != nil {
+			return report, fmt.Errorf("compare %s: %w", key, err)
+		}
+		if same {
+			continue
+		}
+
+		report.Divergent = append(report.Divergent, key)
+		w.opts.Logger.Warn("cache diverged from store",
+			zap.String("key", key),
+			zap.String("repair", string(w.opts.Repair)))
+
+		switch w.opts.Repair {
+		case RepairInvalidate:
+			err = w.cache.Delete(ctx, key)
+		case RepairPersist:
+			err = w.Write(ctx, key, cached)
+		default:
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("repair %s: %w", key, err)
+		}
+		report.Repaired++
+	}
+	return report, nil
+}
+
+// durablePending 返回任务存储中仍未结束的写回涉及的 key，任务存储不支持列举时为空
+func (w *WriteBack) durablePending(ctx context.Context) (map[string]bool, error) {
+	keys := make(map[string]bool)
+	single, err := w.jobs.Pending(ctx, JobTypeWriteBack)
+	if err != nil {
+		return nil, fmt.Errorf("list pending write-backs: %w", err)
+	}
+	for _, job := range single {
+		var p writeBackPayload
+		if err := job.Decode(&p); err == nil {
+			keys[p.Key] = true
+		}
+	}
+	batches, err := w.jobs.Pending(ctx, JobTypeWriteBackBatch)
+	if err != nil {
+		return nil, fmt.Errorf("list pending write-backs: %w", err)
+	}
+	for _, job := range batches {
+		var p writeBackBatchPayload
+		if err := job.Decode(&p); err == nil {
+			for _, e := range p.Entries {
+				keys[e.Key] = true
+			}
+		}
+	}
+	return keys, nil
+}
+
+func (w *WriteBack) track(key string, delta int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.trackLocked(key, delta)
+	w.reportLocked()
+}
+
+func (w *WriteBack) trackLocked(key string, delta int) {
+	n := w.pending[key] + delta
+	if n > 0 {
+		w.pending[key] = n
+	} else {
+		delete(w.pending, key)
+	}
+	w.pendingTotal += delta
+}
+
+// reportLocked 上报队列深度，pendingTotal 含缓冲区中的写回，上报时分开统计
+func (w *WriteBack) reportLocked() {
+	if w.opts.Metrics == nil {
+		return
+	}
+	w.opts.Metrics.SetGauge(MetricWriteBackPending, float64(w.pendingTotal-len(w.buffer)), nil)
+	w.opts.Metrics.SetGauge(MetricWriteBackBuffered, float64(len(w.buffer)), nil)
+}
+
+// peek 只读取 L1/L2，不触发 L3 加载
+func (m *MultiLevelCache) peek(key string) (interface{}, bool) {
+	if val, ok := m.L1.Load(key); ok {
+		return val, true
+	}
+	if m.L2 != nil {
+		if val, err := m.L2.Get(key); err == nil && val != negativeMarker {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// sameJSON 按 JSON 语义比较两个值，避免字段顺序或类型表示不同造成误报
+func sameJSON(a, b interface{}) (bool, error) {
+	normalize := func(v interface{}) (interface{}, error) {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var out interface{}
+		err = json.Unmarshal(raw, &out)
+		return out, err
+	}
+	na, err := normalize(a)
+	if err != nil {
+		return false, err
+	}
+	nb, err := normalize(b)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(na, nb), nil
+}
@@ -0,0 +1,332 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/leeforge/framework/jobs"
+	"github.com/leeforge/framework/metrics"
+)
+
+// memoryStore 记录写入顺序的内存存储
+type memoryStore struct {
+	mu     sync.Mutex
+	values map[string]json.RawMessage
+	writes []string
+	fail   bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{values: make(map[string]json.RawMessage)}
+}
+
+func (s *memoryStore) Save(_ context.Context, key string, value interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("store unavailable")
+	}
+	raw := value.(json.RawMessage)
+	s.values[key] = raw
+	s.writes = append(s.writes, string(raw))
+	return nil
+}
+
+func (s *memoryStore) Load(_ context.Context, key string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if raw, ok := s.values[key]; ok {
+		return raw, nil
+	}
+	return nil, nil
+}
+
+func newTestWriteBack(t *testing.T, store *memoryStore, opts jobs.Options) (*WriteBack, *jobs.Manager) {
+	t.Helper()
+	manager := jobs.NewManager(opts)
+	wb := NewWriteBack(NewMultiLevelCache(nil, nil), store, manager, WriteBackOptions{})
+	manager.Start()
+	t.Cleanup(func() { manager.Stop(context.Background()) })
+	return wb, manager
+}
+
+func waitPersisted(t *testing.T, wb *WriteBack, key string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for wb.Pending(key) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("write-back for %s did not finish", key)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWriteBack_PersistsInOrderPerKey(t *testing.T) {
+	store := newMemoryStore()
+	wb, _ := newTestWriteBack(t, store, jobs.Options{Workers: 4})
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		if err := wb.Write(ctx, "counter", i); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if v, _ := wb.cache.Get(ctx, "counter"); v != 19 {
+		t.Fatalf("cache = %v, want 19", v)
+	}
+	waitPersisted(t, wb, "counter")
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if string(store.values["counter"]) != "19" || len(store.writes) != 20 {
+		t.Fatalf("store = %s after %d writes", store.values["counter"], len(store.writes))
+	}
+	for i, w := range store.writes {
+		if w != strconv.Itoa(i) {
+			t.Fatalf("writes = %v, want 0..19 in order", store.writes)
+		}
+	}
+}
+
+func TestWriteBack_ExhaustedRetriesGoToDeadLetter(t *testing.T) {
+	store := newMemoryStore()
+	store.fail = true
+	dlq := jobs.NewMemoryDeadLetterQueue()
+	wb, manager := newTestWriteBack(t, store, jobs.Options{
+		MaxAttempts: 2,
+		Backoff:     func(int) time.Duration { return time.Millisecond },
+		DeadLetter:  dlq,
+	})
+	ctx := context.Background()
+
+	if err := wb.Write(ctx, "user:1", map[string]string{"name": "ada"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	waitPersisted(t, wb, "user:1")
+
+	dead, _ := manager.DeadLetters(ctx)
+	if len(dead) != 1 || dead[0].Type != JobTypeWriteBack || dead[0].Attempts != 2 {
+		t.Fatalf("dead letters = %+v", dead)
+	}
+
+	// 存储恢复后重放死信，写回完成
+	store.mu.Lock()
+	store.fail = false
+	store.mu.Unlock()
+	if _, err := manager.Replay(ctx, dead[0].ID); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if v, _ := store.Load(ctx, "user:1"); v != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("replayed write-back was not persisted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWriteBack_Reconcile(t *testing.T) {
+	store := newMemoryStore()
+	wb, manager := newTestWriteBack(t, store, jobs.Options{})
+	ctx := context.Background()
+
+	if err := wb.Write(ctx, "a", map[string]int{"x": 1, "y": 2}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := wb.Write(ctx, "b", 1); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	waitPersisted(t, wb, "a")
+	waitPersisted(t, wb, "b")
+
+	// 绕过写回直接修改缓存，模拟不一致
+	wb.cache.L1.Store("b", 2)
+
+	job, err := wb.ScheduleReconcile(ctx, "a", "b", "missing")
+	if err != nil {
+		t.Fatalf("ScheduleReconcile: %v", err)
+	}
+	var done *jobs.Job
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		done, _ = manager.Get(ctx, job.ID)
+		if done.State.Terminal() || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if done.State != jobs.StateSucceeded {
+		t.Fatalf("reconcile job = %+v", done)
+	}
+
+	var report ReconcileReport
+	if err := json.Unmarshal(done.Result, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Checked != 3 || len(report.Divergent) != 1 || report.Divergent[0] != "b" || report.Repaired != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	if _, ok := wb.cache.peek("b"); ok {
+		t.Error("divergent key should be invalidated")
+	}
+}
+
+// batchStore 记录每次批量保存的内存存储
+type batchStore struct {
+	*memoryStore
+	batches [][]string
+}
+
+func (s *batchStore) SaveBatch(ctx context.Context, entries []BatchEntry) error {
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+		if err := s.Save(ctx, e.Key, e.Value); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.batches = append(s.batches, keys)
+	s.mu.Unlock()
+	return nil
+}
+
+func TestWriteBack_BatchesAndFlush(t *testing.T) {
+	store := &batchStore{memoryStore: newMemoryStore()}
+	collector := metrics.NewCollector()
+	manager := jobs.NewManager(jobs.Options{})
+	wb := NewWriteBack(NewMultiLevelCache(nil, nil), store, manager, WriteBackOptions{
+		BatchSize:     3,
+		BatchInterval: time.Hour,
+		Metrics:       collector,
+	})
+	manager.Start()
+	t.Cleanup(func() { manager.Stop(context.Background()) })
+	ctx := context.Background()
+
+	// a 被合并，攒满 3 个不同 key 后投递
+	for _, kv := range [][2]string{{"a", "1"}, {"a", "2"}, {"b", "1"}} {
+		if err := wb.Write(ctx, kv[0], kv[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g := collector.GetMetric(MetricWriteBackBuffered, nil); g == nil || g.Value != 2 {
+		t.Fatalf("buffered gauge = %+v, want 2", g)
+	}
+	if err := wb.Write(ctx, "c", "1"); err != nil {
+		t.Fatal(err)
+	}
+	wb.Write(ctx, "d", "1") // 未满，等待 Flush
+
+	flushCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := wb.Flush(flushCtx); err != nil {
+		t.Fatal(err)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.batches) != 2 || len(store.batches[0]) != 3 || store.batches[1][0] != "d" {
+		t.Fatalf("batches = %v", store.batches)
+	}
+	if string(store.values["a"]) != `"2"` || len(store.writes) != 4 {
+		t.Fatalf("values = %v, writes = %v", store.values, store.writes)
+	}
+	if g := collector.GetMetric(MetricWriteBackPending, nil); g == nil || g.Value != 0 {
+		t.Fatalf("pending gauge = %+v, want 0", g)
+	}
+}
+
+func TestWriteBack_CloseRejectsWritesAndReportsPending(t *testing.T) {
+	store := newMemoryStore()
+	store.fail = true
+	wb, _ := newTestWriteBack(t, store, jobs.Options{
+		MaxAttempts: 100,
+		Backoff:     func(int) time.Duration { return 10 * time.Millisecond },
+	})
+	ctx := context.Background()
+
+	if err := wb.Write(ctx, "k", 1); err != nil {
+		t.Fatal(err)
+	}
+	closeCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := wb.Close(closeCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close err = %v, want deadline exceeded while store is failing", err)
+	}
+	if err := wb.Write(ctx, "k", 2); !errors.Is(err, ErrWriteBackClosed) {
+		t.Fatalf("Write after Close err = %v", err)
+	}
+
+	store.mu.Lock()
+	store.fail = false
+	store.mu.Unlock()
+	flushCtx, cancel2 := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel2()
+	if err := wb.Flush(flushCtx); err != nil {
+		t.Fatalf("queued write-back should complete once the store recovers: %v", err)
+	}
+}
+
+func TestWriteBack_RecoveredWritesAreTrackedDurably(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	jobStore := jobs.NewRedisStore(client, "")
+	store := newMemoryStore()
+	ctx := context.Background()
+
+	// 上一个进程投递后未执行就退出
+	crashed := NewWriteBack(NewMultiLevelCache(nil, nil), store, jobs.NewManager(jobs.Options{Store: jobStore}), WriteBackOptions{})
+	if err := crashed.Write(ctx, "a", 1); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	manager := jobs.NewManager(jobs.Options{Store: jobStore})
+	wb := NewWriteBack(NewMultiLevelCache(nil, nil), store, manager, WriteBackOptions{})
+	wb.cache.L1.Store("a", 2)
+
+	report, err := wb.Reconcile(ctx, []string{"a"})
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0] != "a" || len(report.Divergent) != 0 {
+		t.Fatalf("report = %+v, want a skipped while its recovered write-back is pending", report)
+	}
+
+	manager.Start()
+	t.Cleanup(func() { manager.Stop(context.Background()) })
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		pending, err := manager.Pending(ctx, JobTypeWriteBack)
+		if err != nil {
+			t.Fatalf("Pending: %v", err)
+		}
+		if len(pending) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("recovered write-back did not finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if stored, _ := store.Load(ctx, "a"); string(stored.(json.RawMessage)) != "1" {
+		t.Fatalf("stored a = %s, want 1", stored)
+	}
+
+	// 恢复的写回不属于本实例，不应让本实例的计数变为负数
+	flushCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	if err := wb.Flush(flushCtx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+}
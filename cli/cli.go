@@ -0,0 +1,282 @@
+// Package cli gives every service the same operational command line on top
+// of the app bootstrap: serve, migrate, seed, routes, config validate and
+// plugin list. A service supplies how to build its app and open its database
+// and gets the commands for free:
+//
+//	func main() {
+//		cli.New(cli.Options{
+//			Name: "orders",
+//			App: func(ctx context.Context, cfg *config.Config) (*app.App, error) {
+//				return app.New(app.WithConfig(cfg), app.WithPlugins(&orders.Plugin{}))
+//			},
+//			DB: func(ctx context.Context, cfg *config.Config) (*sql.DB, string, error) {
+//				db, err := sql.Open("pgx", cfg.Get("database.dsn").(string))
+//				return db, dialect.Postgres, err
+//			},
+//			Migrations: migrations,
+//			Seeds:      seedFS,
+//		}).Main()
+//	}
+//
+// Service-specific commands are added with AddCommand.
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/leeforge/framework/app"
+	"github.com/leeforge/framework/config"
+	"github.com/leeforge/framework/plugin"
+	"github.com/spf13/pflag"
+)
+
+// Options configures a CLI.
+type Options struct {
+	// Name is the program name shown in help; defaults to os.Args[0].
+	Name string
+	// Short describes the program in the root help.
+	Short string
+	// Config loads the configuration from dir, the value of --config.
+	// Defaults to config.NewConfig with config.DefaultConfigOptions and dir
+	// as BasePath.
+	Config func(dir string) (*config.Config, error)
+	// App builds the application. serve, routes, config validate and plugin
+	// list need it.
+	App func(ctx context.Context, cfg *config.Config) (*app.App, error)
+	// DB opens the database used by migrate and seed, and returns its ent
+	// dialect (dialect.Postgres, dialect.MySQL or dialect.SQLite).
+	DB func(ctx context.Context, cfg *config.Config) (db *sql.DB, driver string, err error)
+	// Migrations are the application migrations run by migrate.
+	Migrations []plugin.Migration
+	// Seeds holds the fixture files loaded by seed.
+	Seeds fs.FS
+	// Out receives command output; defaults to os.Stdout.
+	Out io.Writer
+	// Err receives errors and help; defaults to os.Stderr.
+	Err io.Writer
+}
+
+// Command is a CLI command. A command with Commands and no Run only groups
+// its subcommands.
+type Command struct {
+	// Name is the word that selects the command.
+	Name string
+	// Usage lists the arguments after the flags, e.g. "up|down [N]|status".
+	Usage string
+	// Short is the one-line description shown in help.
+	Short string
+	// Flags are the command's own flags; --help is added, and --config for
+	// CLIs built by New.
+	Flags *pflag.FlagSet
+	// Run executes the command with the positional arguments.
+	Run func(ctx context.Context, args []string) error
+	// Commands are the subcommands.
+	Commands []*Command
+}
+
+// AddCommand adds subcommands to c.
+func (c *Command) AddCommand(cmds ...*Command) {
+	c.Commands = append(c.Commands, cmds...)
+}
+
+func (c *Command) find(name string) *Command {
+	for _, sub := range c.Commands {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// CLI is a command line built by New or NewTool.
+type CLI struct {
+	opts      Options
+	root      *Command
+	global    *pflag.FlagSet
+	configDir string
+
+	cfg       *config.Config
+	cfgLoaded bool
+}
+
+// New creates the CLI with the built-in commands.
+func New(opts Options) *CLI {
+	c := newCLI(opts)
+	c.global.StringVar(&c.configDir, "config", config.DefaultConfigOptions().BasePath, "configuration directory")
+	c.root.AddCommand(
+		c.serveCommand(),
+		c.migrateCommand(),
+		c.seedCommand(),
+		c.routesCommand(),
+		c.configCommand(),
+		c.pluginCommand(),
+	)
+	return c
+}
+
+// NewTool creates a CLI running only cmds, without the built-in commands or
+// the --config flag, for developer tools such as cmd/framework.
+func NewTool(name, short string, cmds ...*Command) *CLI {
+	c := newCLI(Options{Name: name, Short: short})
+	c.root.AddCommand(cmds...)
+	return c
+}
+
+func newCLI(opts Options) *CLI {
+	if opts.Name == "" {
+		opts.Name = os.Args[0]
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+	if opts.Err == nil {
+		opts.Err = os.Stderr
+	}
+	if opts.Config == nil {
+		opts.Config = func(dir string) (*config.Config, error) {
+			cfgOpts := config.DefaultConfigOptions()
+			cfgOpts.BasePath = dir
+			return config.NewConfig(cfgOpts)
+		}
+	}
+
+	c := &CLI{opts: opts, root: &Command{Name: opts.Name, Short: opts.Short}}
+	c.global = pflag.NewFlagSet(opts.Name, pflag.ContinueOnError)
+	return c
+}
+
+// AddCommand adds service-specific commands next to the built-in ones.
+func (c *CLI) AddCommand(cmds ...*Command) {
+	c.root.AddCommand(cmds...)
+}
+
+// Config returns the configuration, loading it on first use. Custom commands
+// use it like the built-in ones.
+func (c *CLI) Config() (*config.Config, error) {
+	if !c.cfgLoaded {
+		cfg, err := c.opts.Config(c.configDir)
+		if err != nil {
+			return nil, err
+		}
+		c.cfg, c.cfgLoaded = cfg, true
+	}
+	return c.cfg, nil
+}
+
+// Execute runs the command selected by args, which exclude the program name.
+// Flags may follow the command words in any position.
+func (c *CLI) Execute(ctx context.Context, args []string) error {
+	cmd, path := c.root, []string{c.root.Name}
+	for len(args) > 0 {
+		sub := cmd.find(args[0])
+		if sub == nil {
+			break
+		}
+		cmd, path, args = sub, append(path, sub.Name), args[1:]
+	}
+
+	flags := pflag.NewFlagSet(strings.Join(path, " "), pflag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.AddFlagSet(c.global)
+	if cmd.Flags != nil {
+		flags.AddFlagSet(cmd.Flags)
+	}
+	// Flags set by an earlier Execute start from their defaults again.
+	flags.VisitAll(resetFlag)
+	help := flags.BoolP("help", "h", false, "show help")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%s: %w", strings.Join(path, " "), err)
+	}
+	args = flags.Args()
+
+	if *help || cmd.Run == nil {
+		c.usage(cmd, path, flags)
+		if !*help && len(args) > 0 {
+			return fmt.Errorf("%s: unknown command %q", strings.Join(path, " "), args[0])
+		}
+		return nil
+	}
+	return cmd.Run(ctx, args)
+}
+
+// Main executes os.Args and exits with status 1 on error.
+func (c *CLI) Main() {
+	if err := c.Execute(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(c.opts.Err, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func resetFlag(f *pflag.Flag) {
+	if !f.Changed {
+		return
+	}
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		var values []string
+		if def := strings.Trim(f.DefValue, "[]"); def != "" {
+			values = strings.Split(def, ",")
+		}
+		_ = sv.Replace(values)
+	} else {
+		_ = f.Value.Set(f.DefValue)
+	}
+	f.Changed = false
+}
+
+func (c *CLI) usage(cmd *Command, path []string, flags *pflag.FlagSet) {
+	w := c.opts.Err
+	synopsis := strings.Join(path, " ")
+	switch {
+	case cmd.Run == nil:
+		synopsis += " <command>"
+	case cmd.Usage != "":
+		synopsis += " [flags] " + cmd.Usage
+	default:
+		synopsis += " [flags]"
+	}
+	fmt.Fprintf(w, "Usage: %s\n", synopsis)
+	if cmd.Short != "" {
+		fmt.Fprintf(w, "\n%s\n", cmd.Short)
+	}
+	if len(cmd.Commands) > 0 {
+		fmt.Fprintln(w, "\nCommands:")
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, sub := range cmd.Commands {
+			fmt.Fprintf(tw, "  %s\t%s\n", sub.Name, sub.Short)
+		}
+		_ = tw.Flush()
+	}
+	fmt.Fprintf(w, "\nFlags:\n%s", flags.FlagUsages())
+}
+
+// app loads the configuration and builds the application.
+func (c *CLI) app(ctx context.Context) (*app.App, error) {
+	if c.opts.App == nil {
+		return nil, errors.New("no application configured: set cli.Options.App")
+	}
+	cfg, err := c.Config()
+	if err != nil {
+		return nil, err
+	}
+	return c.opts.App(ctx, cfg)
+}
+
+// db loads the configuration and opens the database.
+func (c *CLI) db(ctx context.Context) (*sql.DB, string, error) {
+	if c.opts.DB == nil {
+		return nil, "", errors.New("no database configured: set cli.Options.DB")
+	}
+	cfg, err := c.Config()
+	if err != nil {
+		return nil, "", err
+	}
+	return c.opts.DB(ctx, cfg)
+}
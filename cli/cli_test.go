@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"entgo.io/ent/dialect"
+	"github.com/go-chi/chi/v5"
+	"github.com/leeforge/framework/app"
+	"github.com/leeforge/framework/config"
+	"github.com/leeforge/framework/permission"
+	"github.com/leeforge/framework/plugin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	_ "modernc.org/sqlite"
+)
+
+type settings struct {
+	Endpoint string `mapstructure:"endpoint" validate:"required,url"`
+}
+
+type ordersPlugin struct {
+	settings settings
+}
+
+func (p *ordersPlugin) Name() string                                     { return "orders" }
+func (p *ordersPlugin) Version() string                                  { return "1.2.0" }
+func (p *ordersPlugin) Dependencies() []string                           { return []string{"audit"} }
+func (p *ordersPlugin) Enable(context.Context, *plugin.AppContext) error { return nil }
+func (p *ordersPlugin) ConfigSection() string                            { return "" }
+func (p *ordersPlugin) ConfigTarget() any                                { return &p.settings }
+func (p *ordersPlugin) PluginOptions() plugin.PluginOptions {
+	return plugin.PluginOptions{Description: "Order management", Optional: true}
+}
+func (p *ordersPlugin) Routes(r chi.Router) {
+	permission.Post(r, "/", func(http.ResponseWriter, *http.Request) {}, permission.Private("Create order", "orders:create"))
+}
+func (p *ordersPlugin) Migrations() []plugin.Migration {
+	return []plugin.Migration{plugin.SQLMigration("0001_orders", "CREATE TABLE orders (id INTEGER PRIMARY KEY)")}
+}
+
+type auditPlugin struct{}
+
+func (auditPlugin) Name() string                                     { return "audit" }
+func (auditPlugin) Version() string                                  { return "0.1.0" }
+func (auditPlugin) Dependencies() []string                           { return nil }
+func (auditPlugin) Enable(context.Context, *plugin.AppContext) error { return nil }
+
+// newTestCLI returns a CLI over a file-backed SQLite database and a config
+// directory holding yaml; output is collected in out.
+func newTestCLI(t *testing.T, yaml string) (*CLI, *bytes.Buffer) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(yaml), 0o644))
+	dsn := "file:" + filepath.Join(dir, "test.db")
+
+	out := &bytes.Buffer{}
+	c := New(Options{
+		Name: "svc",
+		App: func(_ context.Context, cfg *config.Config) (*app.App, error) {
+			r := chi.NewRouter()
+			permission.Get(r, "/health", func(http.ResponseWriter, *http.Request) {}, permission.Public("Health check"))
+			return app.New(
+				app.WithConfig(cfg, config.AllowSections("plugins")),
+				app.WithLogger(zap.NewNop()),
+				app.WithRouter(r),
+				app.WithPlugins(&ordersPlugin{}, auditPlugin{}),
+			)
+		},
+		DB: func(context.Context, *config.Config) (*sql.DB, string, error) {
+			db, err := sql.Open("sqlite", dsn)
+			return db, dialect.SQLite, err
+		},
+		Migrations: []plugin.Migration{
+			plugin.SQLMigrationWithDown("0001_users", "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)", "DROP TABLE users"),
+		},
+		Seeds: fstest.MapFS{
+			"users.yaml": {Data: []byte("tables:\n  - table: users\n    rows:\n      - {id: 1, name: ann}\n      - {id: 2, name: bob}\n")},
+			"demo.yaml":  {Data: []byte("environments: [demo]\ntables:\n  - table: users\n    rows:\n      - {id: 3, name: demo}\n")},
+		},
+		Out: out,
+		Err: &bytes.Buffer{},
+	})
+	c.configDir = dir
+	return c, out
+}
+
+func TestRoutes(t *testing.T) {
+	c, out := newTestCLI(t, "plugins:\n  orders:\n    endpoint: https://orders.example.com\n")
+	ctx := context.Background()
+
+	require.NoError(t, c.Execute(ctx, []string{"routes"}))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, []string{"METHOD", "PATH", "ACCESS", "PERMISSIONS", "DESCRIPTION"}, strings.Fields(lines[0]))
+	require.Equal(t, []string{"GET", "/health", "public", "-", "Health", "check"}, strings.Fields(lines[1]))
+	require.Equal(t, []string{"POST", "/orders/", "private", "orders:create", "Create", "order"}, strings.Fields(lines[2]))
+
+	// 不启动插件时只有应用自身的路由
+	c, out = newTestCLI(t, "plugins:\n  orders:\n    endpoint: https://orders.example.com\n")
+	require.NoError(t, c.Execute(ctx, []string{"routes", "--json", "--plugins=false"}))
+	var routes []routeJSON
+	require.NoError(t, json.Unmarshal(out.Bytes(), &routes))
+	require.Equal(t, []routeJSON{{Method: "GET", Path: "/health", Public: true, Permissions: []string{}, Description: "Health check"}}, routes)
+}
+
+func TestPluginList(t *testing.T) {
+	c, out := newTestCLI(t, "")
+	require.NoError(t, c.Execute(context.Background(), []string{"plugin", "list"}))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, []string{"audit", "0.1.0", "-"}, strings.Fields(lines[1]))
+	require.Equal(t, []string{"orders", "1.2.0", "audit", "Order", "management", "(optional)"}, strings.Fields(lines[2]))
+}
+
+func TestConfigValidate(t *testing.T) {
+	ctx := context.Background()
+
+	c, out := newTestCLI(t, "plugins:\n  orders:\n    endpoint: https://orders.example.com\n")
+	require.NoError(t, c.Execute(ctx, []string{"config", "validate"}))
+	require.Equal(t, "configuration is valid\n", out.String())
+
+	c, _ = newTestCLI(t, "plugins:\n  orders:\n    endpoint: not-a-url\n")
+	err := c.Execute(ctx, []string{"config", "validate"})
+	require.ErrorContains(t, err, `plugin "orders"`)
+	require.ErrorContains(t, err, "endpoint")
+
+	c, _ = newTestCLI(t, "unknown:\n  key: 1\n")
+	require.ErrorContains(t, c.Execute(ctx, []string{"config", "validate"}), "unknown sections: unknown")
+}
+
+func TestMigrateAndSeed(t *testing.T) {
+	ctx := context.Background()
+	c, out := newTestCLI(t, "")
+
+	require.NoError(t, c.Execute(ctx, []string{"migrate", "--dry-run", "up"}))
+	require.Contains(t, out.String(), "CREATE TABLE users")
+
+	out.Reset()
+	require.NoError(t, c.Execute(ctx, []string{"migrate", "up"}))
+	require.Equal(t, "applied 0001_users\n", out.String())
+
+	out.Reset()
+	require.NoError(t, c.Execute(ctx, []string{"seed", "--env", "test"}))
+	require.Equal(t, "seeded 1 fixtures for test\n", out.String())
+
+	// 再次执行是幂等的
+	out.Reset()
+	require.NoError(t, c.Execute(ctx, []string{"seed", "--env", "demo"}))
+	require.Equal(t, "seeded 2 fixtures for demo\n", out.String())
+
+	db, _, err := c.db(ctx)
+	require.NoError(t, err)
+	defer db.Close()
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count))
+	require.Equal(t, 3, count)
+
+	out.Reset()
+	require.NoError(t, c.Execute(ctx, []string{"migrate", "--plugin", "orders", "up"}))
+	require.Equal(t, "applied 0001_orders\n", out.String())
+	require.ErrorContains(t, c.Execute(ctx, []string{"migrate", "--plugin", "audit", "up"}), `plugin "audit" has no migrations`)
+
+	out.Reset()
+	require.NoError(t, c.Execute(ctx, []string{"migrate", "down"}))
+	require.Equal(t, "reverted 0001_users\n", out.String())
+}
+
+func TestHelpAndErrors(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestCLI(t, "")
+	help := c.opts.Err.(*bytes.Buffer)
+
+	require.NoError(t, c.Execute(ctx, nil))
+	require.Contains(t, help.String(), "Usage: svc <command>")
+	for _, name := range []string{"serve", "migrate", "seed", "routes", "config", "plugin"} {
+		require.Contains(t, help.String(), "  "+name+" ")
+	}
+
+	help.Reset()
+	require.NoError(t, c.Execute(ctx, []string{"migrate", "-h"}))
+	require.Contains(t, help.String(), "Usage: svc migrate [flags] up|down [N]|status")
+	require.Contains(t, help.String(), "--dry-run")
+	require.Contains(t, help.String(), "--config")
+
+	require.ErrorContains(t, c.Execute(ctx, []string{"deploy"}), `svc: unknown command "deploy"`)
+	require.ErrorContains(t, c.Execute(ctx, []string{"plugin", "remove"}), `svc plugin: unknown command "remove"`)
+	require.ErrorContains(t, c.Execute(ctx, []string{"routes", "--verbose"}), "unknown flag: --verbose")
+	require.ErrorContains(t, c.Execute(ctx, []string{"routes", "extra"}), "unexpected arguments")
+
+	var ran []string
+	c.AddCommand(&Command{
+		Name: "reindex",
+		Run: func(_ context.Context, args []string) error {
+			ran = args
+			return nil
+		},
+	})
+	require.NoError(t, c.Execute(ctx, []string{"reindex", "orders"}))
+	require.Equal(t, []string{"orders"}, ran)
+}
+
+func TestNewTool(t *testing.T) {
+	ctx := context.Background()
+	var ran bool
+	c := NewTool("tool", "Developer tools", &Command{
+		Name: "gen",
+		Run: func(context.Context, []string) error {
+			ran = true
+			return nil
+		},
+	})
+	help := &bytes.Buffer{}
+	c.opts.Err = help
+
+	require.NoError(t, c.Execute(ctx, nil))
+	require.Contains(t, help.String(), "  gen ")
+	require.NotContains(t, help.String(), "serve")
+	require.NotContains(t, help.String(), "--config")
+
+	require.NoError(t, c.Execute(ctx, []string{"gen"}))
+	require.True(t, ran)
+}
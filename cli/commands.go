@@ -0,0 +1,339 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/leeforge/framework/config"
+	"github.com/leeforge/framework/db/seed"
+	"github.com/leeforge/framework/env_mode"
+	"github.com/leeforge/framework/permission"
+	"github.com/leeforge/framework/plugin"
+	"github.com/leeforge/framework/runtime/migration"
+	"github.com/spf13/pflag"
+)
+
+func (c *CLI) serveCommand() *Command {
+	return &Command{
+		Name:  "serve",
+		Short: "Start the application and serve HTTP until interrupted",
+		Run: func(ctx context.Context, args []string) error {
+			if err := noArgs("serve", args); err != nil {
+				return err
+			}
+			a, err := c.app(ctx)
+			if err != nil {
+				return err
+			}
+			return a.Run(ctx)
+		},
+	}
+}
+
+func (c *CLI) migrateCommand() *Command {
+	flags := pflag.NewFlagSet("migrate", pflag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print the SQL instead of running it")
+	pluginName := flags.String("plugin", "", "run the migrations of this plugin instead of the application's")
+
+	return &Command{
+		Name:  "migrate",
+		Usage: "up|down [N]|status",
+		Short: "Apply, revert or list database migrations",
+		Flags: flags,
+		Run: func(ctx context.Context, args []string) error {
+			db, driver, err := c.db(ctx)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			var opts []migration.RunnerOption
+			if *dryRun {
+				opts = append(opts, migration.WithDryRun(c.opts.Out))
+			}
+
+			if *pluginName == "" {
+				runner, err := migration.NewRunner(db, driver, opts...)
+				if err != nil {
+					return err
+				}
+				return runner.Command(ctx, c.opts.Out, c.opts.Migrations, args...)
+			}
+
+			p, err := c.plugin(ctx, *pluginName)
+			if err != nil {
+				return err
+			}
+			mp, ok := p.(plugin.MigrationPlugin)
+			if !ok {
+				return fmt.Errorf("plugin %q has no migrations", *pluginName)
+			}
+			runners, err := migration.NewPluginRunner(db, driver)
+			if err != nil {
+				return err
+			}
+			return runners.Runner(*pluginName, opts...).Command(ctx, c.opts.Out, mp.Migrations(), args...)
+		},
+	}
+}
+
+func (c *CLI) seedCommand() *Command {
+	flags := pflag.NewFlagSet("seed", pflag.ContinueOnError)
+	env := flags.String("env", string(env_mode.Mode()), "load the fixtures of this environment")
+	dir := flags.String("dir", ".", "fixture directory within the seed files")
+
+	return &Command{
+		Name:  "seed",
+		Short: "Load seed fixtures into the database",
+		Flags: flags,
+		Run: func(ctx context.Context, args []string) error {
+			if err := noArgs("seed", args); err != nil {
+				return err
+			}
+			if c.opts.Seeds == nil {
+				return errors.New("no seed files configured: set cli.Options.Seeds")
+			}
+			fixtures, err := seed.LoadFS(c.opts.Seeds, *dir)
+			if err != nil {
+				return err
+			}
+
+			db, driver, err := c.db(ctx)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			// One transaction, so a failing fixture leaves nothing behind.
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+			store, err := seed.NewSQLStore(tx, driver)
+			if err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+			if _, err := seed.Load(ctx, store, *env, fixtures...); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+			if err := tx.Commit(); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.opts.Out, "seeded %d fixtures for %s\n", len(seed.ForEnv(*env, fixtures...)), *env)
+			return nil
+		},
+	}
+}
+
+// routeJSON is a route as printed by routes --json.
+type routeJSON struct {
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Public      bool     `json:"public"`
+	Permissions []string `json:"permissions"`
+	Description string   `json:"description,omitempty"`
+}
+
+func (c *CLI) routesCommand() *Command {
+	flags := pflag.NewFlagSet("routes", pflag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the routes as JSON")
+	withPlugins := flags.Bool("plugins", true, "bootstrap the plugins to include their routes")
+
+	return &Command{
+		Name:  "routes",
+		Short: "List the registered routes and their permissions",
+		Flags: flags,
+		Run: func(ctx context.Context, args []string) error {
+			if err := noArgs("routes", args); err != nil {
+				return err
+			}
+			a, err := c.app(ctx)
+			if err != nil {
+				return err
+			}
+			// Plugins mount their routes during bootstrap; the HTTP server
+			// is never started.
+			if *withPlugins {
+				if err := a.Runtime().Bootstrap(ctx); err != nil {
+					return err
+				}
+				defer a.Runtime().Shutdown(context.WithoutCancel(ctx))
+			}
+			snapshot, err := permission.SnapshotFromRouter(a.Router())
+			if err != nil {
+				return err
+			}
+
+			if *asJSON {
+				routes := make([]routeJSON, 0, len(snapshot.Routes))
+				for _, r := range snapshot.Routes {
+					routes = append(routes, routeJSON{
+						Method:      r.Method,
+						Path:        r.Path,
+						Public:      r.IsPublic,
+						Permissions: append([]string{}, r.Permissions...),
+						Description: r.Description,
+					})
+				}
+				enc := json.NewEncoder(c.opts.Out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(routes)
+			}
+
+			w := tabwriter.NewWriter(c.opts.Out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "METHOD\tPATH\tACCESS\tPERMISSIONS\tDESCRIPTION")
+			for _, r := range snapshot.Routes {
+				access := "private"
+				if r.IsPublic {
+					access = "public"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Method, r.Path, access, orDash(r.Permissions), r.Description)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func (c *CLI) configCommand() *Command {
+	validate := &Command{
+		Name:  "validate",
+		Short: "Check the configuration sections and plugin settings",
+		Run: func(ctx context.Context, args []string) error {
+			if err := noArgs("config validate", args); err != nil {
+				return err
+			}
+			if err := c.validateConfig(ctx); err != nil {
+				return err
+			}
+			fmt.Fprintln(c.opts.Out, "configuration is valid")
+			return nil
+		},
+	}
+	return &Command{
+		Name:     "config",
+		Short:    "Inspect the configuration",
+		Commands: []*Command{validate},
+	}
+}
+
+// validateConfig builds the app, which loads and validates the registered
+// sections when it uses app.WithConfig, then binds the config of every
+// ConfigPlugin the way bootstrap does. Without an app it only loads the
+// sections.
+func (c *CLI) validateConfig(ctx context.Context) error {
+	cfg, err := c.Config()
+	if err != nil {
+		return err
+	}
+	if c.opts.App == nil {
+		_, err := cfg.LoadSections()
+		return err
+	}
+
+	a, err := c.opts.App(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	plugins, err := a.Runtime().Plugins()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, p := range plugins {
+		cp, ok := p.(plugin.ConfigPlugin)
+		if !ok {
+			continue
+		}
+		section := cp.ConfigSection()
+		if section == "" {
+			section = "plugins." + p.Name()
+		}
+		if cfg != nil {
+			err = cfg.BindSection(section, cp.ConfigTarget())
+		} else {
+			err = config.DecodeSection(section, nil, cp.ConfigTarget())
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %q: %w", p.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *CLI) pluginCommand() *Command {
+	list := &Command{
+		Name:  "list",
+		Short: "List the registered plugins in boot order",
+		Run: func(ctx context.Context, args []string) error {
+			if err := noArgs("plugin list", args); err != nil {
+				return err
+			}
+			a, err := c.app(ctx)
+			if err != nil {
+				return err
+			}
+			plugins, err := a.Runtime().Plugins()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(c.opts.Out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tVERSION\tDEPENDENCIES\tDESCRIPTION")
+			for _, p := range plugins {
+				var description string
+				if cp, ok := p.(plugin.Configurable); ok {
+					opts := cp.PluginOptions()
+					description = opts.Description
+					if opts.Optional {
+						description = strings.TrimSpace(description + " (optional)")
+					}
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name(), p.Version(), orDash(p.Dependencies()), description)
+			}
+			return w.Flush()
+		},
+	}
+	return &Command{
+		Name:     "plugin",
+		Short:    "Inspect the registered plugins",
+		Commands: []*Command{list},
+	}
+}
+
+// plugin builds the app and returns the registered plugin called name.
+func (c *CLI) plugin(ctx context.Context, name string) (plugin.Plugin, error) {
+	a, err := c.app(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plugins, err := a.Runtime().Plugins()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range plugins {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("plugin %q is not registered", name)
+}
+
+func noArgs(cmd string, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%s: unexpected arguments %v", cmd, args)
+	}
+	return nil
+}
+
+func orDash(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,16 @@
+// Command framework is the developer tool of the framework. Install it with
+//
+//	go install github.com/leeforge/framework/cmd/framework@latest
+//
+// and run "framework gen -h" for scaffolding CRUD code from an ent schema or
+// struct.
+package main
+
+import (
+	"github.com/leeforge/framework/cli"
+	"github.com/leeforge/framework/gen"
+)
+
+func main() {
+	cli.NewTool("framework", "Developer tools for leeforge/framework services", gen.Command(nil)).Main()
+}
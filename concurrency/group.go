@@ -0,0 +1,190 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	apperrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/tracing"
+)
+
+// Group 结构化并发：Go 启动的任务共享 Group 的 ctx（继承父 ctx 的截止时间与取消），
+// Wait 等待全部结束。用它替代手写的 goroutine + WaitGroup
+//
+//	g := concurrency.NewGroup(ctx, concurrency.WithTracer(tracer), concurrency.WithTimeout(5*time.Second))
+//	g.SetLimit(4)
+//	for _, id := range ids {
+//		g.Go("load "+id, func(ctx context.Context) error { return load(ctx, id) })
+//	}
+//	err := g.Wait()
+type Group struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	collect bool
+	tracer  *tracing.Tracer
+
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	mu    sync.Mutex
+	first error
+	chain *apperrors.ErrorChain
+}
+
+// GroupOption 配置 Group
+type GroupOption func(*groupOptions)
+
+type groupOptions struct {
+	collect bool
+	tracer  *tracing.Tracer
+	timeout time.Duration
+}
+
+// CollectAll 收集所有任务的错误：单个任务失败不取消其他任务，Wait 返回
+// *errors.ErrorChain。默认模式下首个错误即取消 ctx，Wait 只返回该错误
+func CollectAll() GroupOption {
+	return func(o *groupOptions) { o.collect = true }
+}
+
+// WithTracer 为每个任务创建以任务名命名的子 span
+func WithTracer(tracer *tracing.Tracer) GroupOption {
+	return func(o *groupOptions) { o.tracer = tracer }
+}
+
+// WithTimeout 为 Group 的 ctx 设置超时，与父 ctx 的截止时间取较早者
+func WithTimeout(d time.Duration) GroupOption {
+	return func(o *groupOptions) { o.timeout = d }
+}
+
+// NewGroup 创建 Group，ctx 取消或到期时尚未开始的任务不再执行
+func NewGroup(ctx context.Context, opts ...GroupOption) *Group {
+	var o groupOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var cancel context.CancelFunc
+	if o.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	return &Group{
+		ctx:     ctx,
+		cancel:  cancel,
+		collect: o.collect,
+		tracer:  o.tracer,
+		chain:   apperrors.NewErrorChain(),
+	}
+}
+
+// Context 返回传给任务的 ctx，Wait 返回后被取消
+func (g *Group) Context() context.Context {
+	return g.ctx
+}
+
+// SetLimit 限制同时运行的任务数，n 小于等于 0 表示不限制。须在 Go 之前调用
+func (g *Group) SetLimit(n int) {
+	if n <= 0 {
+		g.sem = nil
+		return
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go 启动名为 name 的任务。达到并发上限时阻塞直到有任务结束；ctx 已结束时
+// 不再启动，并按 ctx 的错误记录该任务。任务中的 panic 按 Safe 转换为错误
+func (g *Group) Go(name string, fn func(ctx context.Context) error) {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		case <-g.ctx.Done():
+			g.record(name, fmt.Errorf("not started: %w", g.ctx.Err()))
+			return
+		}
+	} else if err := g.ctx.Err(); err != nil {
+		g.record(name, fmt.Errorf("not started: %w", err))
+		return
+	}
+	g.start(name, fn)
+}
+
+// TryGo 在未达到并发上限时启动任务并返回 true，否则立即返回 false
+func (g *Group) TryGo(name string, fn func(ctx context.Context) error) bool {
+	if g.ctx.Err() != nil {
+		return false
+	}
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	g.start(name, fn)
+	return true
+}
+
+// Wait 等待所有任务结束。默认返回首个错误；CollectAll 模式返回包含全部失败的
+// *errors.ErrorChain，非 AppError 的错误转换为 ErrorTypeUnknown 并以任务名开头。
+// 没有任务失败但 ctx 已到期或被取消时返回 ctx.Err()
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	err := g.ctx.Err()
+	g.cancel()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.collect {
+		if g.chain.HasErrors() {
+			return g.chain
+		}
+		return err
+	}
+	if g.first != nil {
+		return g.first
+	}
+	return err
+}
+
+func (g *Group) start(name string, fn func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			defer func() { <-g.sem }()
+		}
+
+		ctx := g.ctx
+		var span *tracing.Span
+		if g.tracer != nil {
+			ctx, span = g.tracer.Start(ctx, name)
+		}
+		err := Safe(func() error { return fn(ctx) })
+		if span != nil {
+			g.tracer.End(span, err)
+		}
+		if err != nil {
+			g.record(name, err)
+		}
+	}()
+}
+
+func (g *Group) record(name string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.collect {
+		if g.first == nil {
+			g.first = err
+			g.cancel()
+		}
+		return
+	}
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) {
+		appErr = apperrors.FromError(fmt.Errorf("%s: %w", name, err))
+	}
+	g.chain.Add(appErr)
+}
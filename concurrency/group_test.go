@@ -0,0 +1,160 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apperrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/tracing"
+)
+
+func TestGroupFirstErrorCancelsOthers(t *testing.T) {
+	boom := errors.New("boom")
+	g := NewGroup(context.Background())
+	g.Go("fail", func(context.Context) error { return boom })
+	g.Go("wait", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err := g.Wait(); !errors.Is(err, boom) {
+		t.Errorf("Wait = %v, want boom", err)
+	}
+	if g.Context().Err() == nil {
+		t.Error("group context should be canceled after Wait")
+	}
+}
+
+func TestGroupCollectAll(t *testing.T) {
+	g := NewGroup(context.Background(), CollectAll())
+	var ran atomic.Int32
+	g.Go("ok", func(context.Context) error { ran.Add(1); return nil })
+	g.Go("plain", func(context.Context) error { ran.Add(1); return errors.New("disk full") })
+	g.Go("app", func(context.Context) error { ran.Add(1); return apperrors.NewNotFound("user", 7) })
+	g.Go("panic", func(context.Context) error { ran.Add(1); panic("bad") })
+
+	err := g.Wait()
+	var chain *apperrors.ErrorChain
+	if !errors.As(err, &chain) || len(chain.Errors()) != 3 || ran.Load() != 4 {
+		t.Fatalf("Wait = %v after %d tasks", err, ran.Load())
+	}
+	var messages []string
+	for _, e := range chain.Errors() {
+		messages = append(messages, string(e.Type)+": "+e.Message)
+	}
+	sort.Strings(messages)
+	want := []string{"internal: internal server error", "not_found: user not found", "unknown: plain: disk full"}
+	for i := range want {
+		if messages[i] != want[i] {
+			t.Errorf("errors = %q, want %q", messages, want)
+			break
+		}
+	}
+	if !errors.Is(err, ErrPanic) || chain.ToHTTPStatus() != 404 {
+		t.Errorf("chain should expose members to errors.Is and rank statuses: %v", err)
+	}
+}
+
+func TestGroupSetLimit(t *testing.T) {
+	g := NewGroup(context.Background())
+	g.SetLimit(2)
+	var running, peak atomic.Int32
+	for i := 0; i < 6; i++ {
+		g.Go("task", func(context.Context) error {
+			cur := running.Add(1)
+			defer running.Add(-1)
+			for {
+				old := peak.Load()
+				if cur <= old || peak.CompareAndSwap(old, cur) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if peak.Load() != 2 {
+		t.Errorf("peak = %d, want 2", peak.Load())
+	}
+
+	g = NewGroup(context.Background())
+	g.SetLimit(1)
+	release := make(chan struct{})
+	if !g.TryGo("hold", func(context.Context) error { <-release; return nil }) {
+		t.Fatal("first TryGo should start")
+	}
+	if g.TryGo("extra", func(context.Context) error { return nil }) {
+		t.Error("TryGo over the limit should not start")
+	}
+	close(release)
+	g.Wait()
+}
+
+func TestGroupDeadline(t *testing.T) {
+	g := NewGroup(context.Background(), WithTimeout(20*time.Millisecond), CollectAll())
+	g.SetLimit(1)
+	var deadline time.Time
+	g.Go("slow", func(ctx context.Context) error {
+		deadline, _ = ctx.Deadline()
+		<-ctx.Done()
+		return nil
+	})
+	g.Go("late", func(context.Context) error { return nil })
+
+	err := g.Wait()
+	if deadline.IsZero() {
+		t.Error("tasks should see the group deadline")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait = %v, want the task that never started to report DeadlineExceeded", err)
+	}
+}
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (r *spanRecorder) OnEnd(span *tracing.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = append(r.names, span.Name)
+}
+
+func (r *spanRecorder) Shutdown(context.Context) error { return nil }
+
+func TestGroupTracing(t *testing.T) {
+	rec := &spanRecorder{}
+	tracer, err := tracing.NewTracer(tracing.TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, parent := tracer.Start(context.Background(), "warmup")
+	g := NewGroup(ctx, WithTracer(tracer))
+	var traceIDs sync.Map
+	for _, name := range []string{"a", "b"} {
+		g.Go("load "+name, func(ctx context.Context) error {
+			traceIDs.Store(name, tracing.SpanFromContext(ctx).TraceID)
+			return nil
+		})
+	}
+	g.Wait()
+
+	sort.Strings(rec.names)
+	if len(rec.names) != 2 || rec.names[0] != "load a" || rec.names[1] != "load b" {
+		t.Errorf("spans = %v", rec.names)
+	}
+	traceIDs.Range(func(_, id any) bool {
+		if id != parent.TraceID {
+			t.Errorf("task span trace %v, want parent trace %v", id, parent.TraceID)
+		}
+		return true
+	})
+}
@@ -0,0 +1,130 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	apperrors "github.com/leeforge/framework/errors"
+)
+
+// ErrPanic 由 Safe 捕获 panic 后返回的 AppError 包装，可用 errors.Is 判断
+var ErrPanic = errors.New("concurrency: panic")
+
+// Safe 执行 fn，将 panic 转换为 ErrorTypeInternal 的 *errors.AppError：
+// Message 固定为 "internal server error" 以免泄露细节，panic 值在 InnerError 中，
+// Stack 记录 panic 位置
+func Safe(fn func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = apperrors.NewInternal("internal server error").
+				WithCode(apperrors.CodeInternalError).
+				WithInnerError(fmt.Errorf("%w: %v", ErrPanic, p)).
+				WithStack()
+		}
+	}()
+	return fn()
+}
+
+// ItemError 记录失败元素在输入中的下标
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// Map 以最多 workers 个并发对 items 执行 fn，结果按输入顺序返回。
+// 任一元素失败即取消传给其余 fn 的 ctx，并返回首个错误（*ItemError）；
+// workers 小于等于 0 时取 GOMAXPROCS
+func Map[T, R any](ctx context.Context, items []T, workers int, fn func(ctx context.Context, item T) (R, error)) ([]R, error) {
+	results := make([]R, len(items))
+	errs := run(ctx, len(items), workers, true, func(ctx context.Context, i int) error {
+		r, err := fn(ctx, items[i])
+		results[i] = r
+		return err
+	})
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return results, nil
+}
+
+// ForEach 以最多 workers 个并发对 items 执行 fn。单个元素失败不影响其他元素，
+// 返回所有失败的 errors.Join（每项为 *ItemError）；ctx 取消后不再开始新元素，
+// 并在结果中附带 ctx.Err()
+func ForEach[T any](ctx context.Context, items []T, workers int, fn func(ctx context.Context, item T) error) error {
+	return errors.Join(run(ctx, len(items), workers, false, func(ctx context.Context, i int) error {
+		return fn(ctx, items[i])
+	})...)
+}
+
+// run 以 workers 个 goroutine 执行下标 0..n-1 的任务，返回按下标排序的错误；
+// failFast 时首个错误取消其余任务且只返回该错误
+func run(ctx context.Context, n, workers int, failFast bool, fn func(ctx context.Context, i int) error) []error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, n)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		next    atomic.Int64
+		started atomic.Int64
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		failed  = make([]error, n)
+		first   error
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for runCtx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				started.Add(1)
+				err := Safe(func() error { return fn(runCtx, i) })
+				if err == nil {
+					continue
+				}
+				err = &ItemError{Index: i, Err: err}
+				mu.Lock()
+				failed[i] = err
+				if failFast && first == nil {
+					first = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if first != nil {
+		return []error{first}
+	}
+	var errs []error
+	for _, err := range failed {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := ctx.Err(); err != nil && int(started.Load()) < n {
+		errs = append(errs, err)
+	}
+	return errs
+}
@@ -0,0 +1,157 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apperrors "github.com/leeforge/framework/errors"
+)
+
+func TestMapKeepsOrderAndBoundsWorkers(t *testing.T) {
+	var running, peak atomic.Int32
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	got, err := Map(context.Background(), items, 3, func(_ context.Context, n int) (string, error) {
+		cur := running.Add(1)
+		defer running.Add(-1)
+		for {
+			old := peak.Load()
+			if cur <= old || peak.CompareAndSwap(old, cur) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return strconv.Itoa(n * n), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[1 4 9 16 25 36 49 64]" {
+		t.Errorf("Map = %v", got)
+	}
+	if peak.Load() > 3 {
+		t.Errorf("peak concurrency = %d, want <= 3", peak.Load())
+	}
+}
+
+func TestMapStopsOnFirstError(t *testing.T) {
+	boom := errors.New("boom")
+	var calls atomic.Int32
+	_, err := Map(context.Background(), make([]int, 100), 1, func(ctx context.Context, _ int) (int, error) {
+		if calls.Add(1) == 3 {
+			return 0, boom
+		}
+		return 0, nil
+	})
+	var itemErr *ItemError
+	if !errors.Is(err, boom) || !errors.As(err, &itemErr) || itemErr.Index != 2 {
+		t.Fatalf("err = %v, want boom at index 2", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("fn called %d times after failure, want 3", calls.Load())
+	}
+}
+
+func TestForEachCollectsErrorsAndPanics(t *testing.T) {
+	err := ForEach(context.Background(), []int{0, 1, 2, 3}, 2, func(_ context.Context, n int) error {
+		switch n {
+		case 1:
+			return errors.New("odd")
+		case 3:
+			panic("kaboom")
+		}
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	var indexes []int
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var itemErr *ItemError
+		if errors.As(e, &itemErr) {
+			indexes = append(indexes, itemErr.Index)
+		}
+	}
+	sort.Ints(indexes)
+	if fmt.Sprint(indexes) != "[1 3]" {
+		t.Errorf("failed indexes = %v", indexes)
+	}
+
+	var appErr *apperrors.AppError
+	if !errors.Is(err, ErrPanic) || !errors.As(err, &appErr) || appErr.Type != apperrors.ErrorTypeInternal || len(appErr.Stack) == 0 {
+		t.Errorf("panic should become an internal AppError wrapping ErrPanic: %v", err)
+	}
+}
+
+func TestForEachCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	err := ForEach(ctx, make([]int, 50), 1, func(context.Context, int) error {
+		if calls.Add(1) == 5 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || calls.Load() != 5 {
+		t.Errorf("err = %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	p := NewPipeline(context.Background())
+	nums := Source(p, []int{1, 2, 3, 4, 5})
+	squares := Stage(p, nums, 3, 0, func(_ context.Context, n int) (int, error) { return n * n, nil })
+	var sum atomic.Int64
+	Sink(p, squares, 2, func(_ context.Context, n int) error {
+		sum.Add(int64(n))
+		return nil
+	})
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if sum.Load() != 55 {
+		t.Errorf("sum = %d, want 55", sum.Load())
+	}
+}
+
+func TestPipelineBackpressureAndFailure(t *testing.T) {
+	p := NewPipeline(context.Background())
+	var produced atomic.Int32
+	nums := Generate(p, 0, func(ctx context.Context, emit func(int) error) error {
+		for i := 0; ; i++ {
+			if err := emit(i); err != nil {
+				return err
+			}
+			produced.Add(1)
+		}
+	})
+	boom := errors.New("boom")
+	Sink(p, nums, 1, func(_ context.Context, n int) error {
+		if n == 10 {
+			return boom
+		}
+		return nil
+	})
+
+	if err := p.Wait(); !errors.Is(err, boom) {
+		t.Fatalf("Wait = %v, want boom", err)
+	}
+	// 无缓冲 channel：生产者最多领先消费者一个元素
+	if n := produced.Load(); n > 12 {
+		t.Errorf("producer ran ahead to %d items despite backpressure", n)
+	}
+}
+
+func TestPipelineStagePanic(t *testing.T) {
+	p := NewPipeline(context.Background())
+	out := Stage(p, Source(p, []int{1}), 1, 0, func(context.Context, int) (int, error) { panic("bad stage") })
+	Sink(p, out, 1, func(context.Context, int) error { return nil })
+	if err := p.Wait(); !errors.Is(err, ErrPanic) {
+		t.Errorf("Wait = %v, want ErrPanic", err)
+	}
+}
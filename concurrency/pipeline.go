@@ -0,0 +1,151 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+)
+
+// Pipeline 串联多个处理阶段。阶段之间通过有界 channel 传递数据，下游处理不过来时
+// 上游自动阻塞（背压）；任一阶段出错或 panic 即取消整个流水线
+//
+//	p := concurrency.NewPipeline(ctx)
+//	ids := concurrency.Source(p, userIDs)
+//	users := concurrency.Stage(p, ids, 4, 16, loadUser)
+//	concurrency.Sink(p, users, 2, indexUser)
+//	err := p.Wait()
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	once sync.Once
+	err  error
+}
+
+// NewPipeline 创建流水线，ctx 取消时所有阶段停止
+func NewPipeline(ctx context.Context) *Pipeline {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Pipeline{ctx: ctx, cancel: cancel}
+}
+
+// Context 返回流水线的 ctx，出错或 Wait 返回后被取消
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Wait 等待所有阶段结束，返回首个错误；ctx 被外部取消时返回 ctx.Err()
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	p.cancel()
+	return p.err
+}
+
+func (p *Pipeline) fail(err error) {
+	p.once.Do(func() {
+		p.err = err
+		p.cancel()
+	})
+}
+
+// spawn 启动 workers 个 goroutine 执行 fn，全部结束后调用 done
+func (p *Pipeline) spawn(workers int, fn func() error, done func()) {
+	var stage sync.WaitGroup
+	for i := 0; i < max(workers, 1); i++ {
+		stage.Add(1)
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			defer stage.Done()
+			if err := Safe(fn); err != nil {
+				p.fail(err)
+			}
+		}()
+	}
+	if done != nil {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			stage.Wait()
+			done()
+		}()
+	}
+}
+
+// send 在 ctx 取消前把 v 送入 out
+func send[T any](ctx context.Context, out chan<- T, v T) error {
+	select {
+	case out <- v:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// receive 在 ctx 取消前从 in 读取，in 关闭时 ok 为 false
+func receive[T any](ctx context.Context, in <-chan T) (v T, ok bool, err error) {
+	select {
+	case v, ok = <-in:
+		return v, ok, nil
+	case <-ctx.Done():
+		return v, false, ctx.Err()
+	}
+}
+
+// Source 将 items 依次送入流水线
+func Source[T any](p *Pipeline, items []T) <-chan T {
+	return Generate(p, 0, func(ctx context.Context, emit func(T) error) error {
+		for _, item := range items {
+			if err := emit(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Generate 由 fn 产生数据，适合逐行读取数据库或文件；emit 在流水线取消后返回错误，
+// fn 应随之返回。buffer 为输出 channel 的缓冲大小
+func Generate[T any](p *Pipeline, buffer int, fn func(ctx context.Context, emit func(T) error) error) <-chan T {
+	out := make(chan T, buffer)
+	p.spawn(1, func() error {
+		return fn(p.ctx, func(v T) error { return send(p.ctx, out, v) })
+	}, func() { close(out) })
+	return out
+}
+
+// Stage 以 workers 个并发对 in 的每个元素执行 fn，结果写入缓冲为 buffer 的输出
+// channel，不保证顺序。fn 返回错误即取消流水线
+func Stage[In, Out any](p *Pipeline, in <-chan In, workers, buffer int, fn func(ctx context.Context, item In) (Out, error)) <-chan Out {
+	out := make(chan Out, buffer)
+	p.spawn(workers, func() error {
+		for {
+			item, ok, err := receive(p.ctx, in)
+			if !ok {
+				return err
+			}
+			v, err := fn(p.ctx, item)
+			if err != nil {
+				return err
+			}
+			if err := send(p.ctx, out, v); err != nil {
+				return err
+			}
+		}
+	}, func() { close(out) })
+	return out
+}
+
+// Sink 以 workers 个并发消费 in，fn 返回错误即取消流水线
+func Sink[T any](p *Pipeline, in <-chan T, workers int, fn func(ctx context.Context, item T) error) {
+	p.spawn(workers, func() error {
+		for {
+			item, ok, err := receive(p.ctx, in)
+			if !ok {
+				return err
+			}
+			if err := fn(p.ctx, item); err != nil {
+				return err
+			}
+		}
+	}, nil)
+}
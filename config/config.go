@@ -5,13 +5,14 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/creasty/defaults"
-	"github.com/fsnotify/fsnotify"
 	"github.com/leeforge/framework/env_mode"
 	"github.com/leeforge/framework/utils"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -45,14 +46,17 @@ func NewConfig(optsArr ...ConfigOptions) (*Config, error) {
 		opts = optsArr[0]
 	}
 
-	instance, err := CreateConfig(opts)
+	instance, files, err := loadConfig(opts)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Config{
-		instance: instance,
-		opts:     opts,
+		instance:  instance,
+		opts:      opts,
+		files:     files,
+		overrides: make(map[string]any),
+		watchers:  make(map[uint64]changeWatcher),
 	}, nil
 }
 
@@ -73,23 +77,10 @@ func (c *Config) Bind(instance any) error {
 			c.opts.BasePath, c.opts.FileName, c.opts.FileType, err)
 	}
 
-	if c.opts.WatchAble {
-		c.watchOnce.Do(func() {
-			c.instance.WatchConfig()
-			c.instance.OnConfigChange(func(e fsnotify.Event) {
-				c.watchMutex.Lock()
-				defer c.watchMutex.Unlock()
-
-				if err := c.instance.Unmarshal(&instance); err != nil {
-					fmt.Printf("❌ Config watch error: %v\n", err)
-					return
-				}
+	c.bindings = append(c.bindings, instance)
 
-				if c.opts.OnChange != nil {
-					c.opts.OnChange(e)
-				}
-			})
-		})
+	if c.opts.WatchAble {
+		c.watchOnce.Do(c.startWatching)
 	}
 
 	return nil
@@ -228,15 +219,26 @@ func (c *Config) Set(key string, value any) {
 	defer c.watchMutex.Unlock()
 
 	c.instance.Set(key, value)
+	if c.overrides != nil {
+		c.overrides[key] = value
+	}
 }
 
 func CreateConfig(opts ConfigOptions) (*viper.Viper, error) {
+	v, _, err := loadConfig(opts)
+	return v, err
+}
+
+// loadConfig merges every matching config file, then environment variables,
+// then explicitly set command-line flags (lowest to highest precedence), and
+// finally resolves ${secret:name} references.
+func loadConfig(opts ConfigOptions) (*viper.Viper, []string, error) {
 	configPaths := getConfigFilePaths(opts)
 	if opts.LoadAll {
 		configPaths = getAllConfigFilePaths(opts)
 	}
 	if len(configPaths) == 0 {
-		return nil, fmt.Errorf("❌ No valid configuration files found in path: %s", opts.BasePath)
+		return nil, nil, fmt.Errorf("❌ No valid configuration files found in path: %s", opts.BasePath)
 	}
 
 	v := viper.New()
@@ -246,7 +248,7 @@ func CreateConfig(opts ConfigOptions) (*viper.Viper, error) {
 		tempV := viper.New()
 		tempV.SetConfigFile(configPath)
 		if err := tempV.ReadInConfig(); err != nil {
-			return nil, fmt.Errorf("❌ Error reading config file %s: %w", configPath, err)
+			return nil, nil, fmt.Errorf("❌ Error reading config file %s: %w", configPath, err)
 		}
 
 		for _, key := range tempV.AllKeys() {
@@ -263,7 +265,15 @@ func CreateConfig(opts ConfigOptions) (*viper.Viper, error) {
 	// Override with environment variables (higher priority than config files)
 	applyEnvOverrides(v, opts.EnvPrefix)
 
-	return v, nil
+	// Flags the user actually passed win over everything else
+	applyFlagOverrides(v, opts.Flags)
+
+	// Secret references are resolved last so env and flags may use them too
+	if err := applySecrets(v, opts.Secrets); err != nil {
+		return nil, nil, err
+	}
+
+	return v, configPaths, nil
 }
 
 // applyEnvOverrides checks all config keys and overrides with environment variables if they exist.
@@ -285,6 +295,36 @@ func applyEnvOverrides(v *viper.Viper, envPrefix string) {
 	}
 }
 
+// applyFlagOverrides sets every changed flag whose name is a config key
+// (e.g. --server.port) or maps to one with dashes as separators (--server-port).
+func applyFlagOverrides(v *viper.Viper, flags *pflag.FlagSet) {
+	if flags == nil {
+		return
+	}
+
+	flags.Visit(func(f *pflag.Flag) {
+		key := strings.ToLower(f.Name)
+		if !v.IsSet(key) {
+			if dotted := strings.ReplaceAll(key, "-", "."); v.IsSet(dotted) {
+				key = dotted
+			}
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			v.Set(key, sv.GetSlice())
+			return
+		}
+		v.Set(key, f.Value.String())
+	})
+}
+
+// fileTypes returns the config file extensions to look for, in merge order.
+func fileTypes(opts ConfigOptions) []string {
+	if len(opts.FileTypes) > 0 {
+		return opts.FileTypes
+	}
+	return []string{opts.FileType}
+}
+
 func getConfigFilePaths(opts ConfigOptions) (configFiles []string) {
 	env := env_mode.Mode()
 	fileNames := []string{
@@ -313,9 +353,11 @@ func getConfigFilePaths(opts ConfigOptions) (configFiles []string) {
 	}
 
 	for _, fileName := range fileNames {
-		file := filepath.Join(opts.BasePath, fmt.Sprintf("%s.%s", fileName, opts.FileType))
-		if isDir, exists, _ := utils.Exists(file); exists && !isDir {
-			configFiles = append(configFiles, file)
+		for _, fileType := range fileTypes(opts) {
+			file := filepath.Join(opts.BasePath, fmt.Sprintf("%s.%s", fileName, fileType))
+			if isDir, exists, _ := utils.Exists(file); exists && !isDir {
+				configFiles = append(configFiles, file)
+			}
 		}
 	}
 
@@ -323,13 +365,16 @@ func getConfigFilePaths(opts ConfigOptions) (configFiles []string) {
 }
 
 func getAllConfigFilePaths(opts ConfigOptions) (configFiles []string) {
-	baseNames := getConfigBaseNames(opts.BasePath, opts.FileType)
+	var baseNames []string
+	for _, fileType := range fileTypes(opts) {
+		baseNames = append(baseNames, getConfigBaseNames(opts.BasePath, fileType)...)
+	}
 	if len(baseNames) == 0 {
 		return nil
 	}
 
 	sort.Strings(baseNames)
-	baseNames = moveConfigFirst(baseNames)
+	baseNames = moveConfigFirst(slices.Compact(baseNames))
 	seen := make(map[string]struct{}, len(baseNames))
 	for _, baseName := range baseNames {
 		tempOpts := opts
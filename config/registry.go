@@ -0,0 +1,241 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SectionSchema describes a top-level config section owned by a module.
+// Modules register their schema from init so the loader knows every section
+// the binary understands:
+//
+//	func init() {
+//		config.RegisterSection(config.SectionSchema[Config]{
+//			Name:    "log",
+//			Default: DefaultConfig,
+//		})
+//	}
+type SectionSchema[T any] struct {
+	// Name is the top-level config key, e.g. "log".
+	Name string
+	// Required reports the section as missing when it is absent from config.
+	Required bool
+	// Default returns the starting value; `default` tags are applied on top
+	// for fields it leaves zero. Nil means start from the zero value.
+	Default func() T
+	// OnLoad receives the decoded, validated section during LoadSections.
+	OnLoad func(T) error
+}
+
+type registeredSection struct {
+	name     string
+	required bool
+	decode   func(raw any) (any, error)
+	onLoad   func(v any) error
+}
+
+type sectionRegistry struct {
+	mu     sync.RWMutex
+	order  []string
+	byName map[string]*registeredSection
+}
+
+func newSectionRegistry() *sectionRegistry {
+	return &sectionRegistry{byName: make(map[string]*registeredSection)}
+}
+
+var sectionRegistryInstance = newSectionRegistry()
+
+// RegisterSection registers a module's config section schema. It panics on an
+// empty or duplicate name or a non-struct type, since both are programming
+// errors that should surface at init.
+func RegisterSection[T any](schema SectionSchema[T]) {
+	name := strings.ToLower(strings.TrimSpace(schema.Name))
+	if name == "" || strings.Contains(name, ".") {
+		panic(fmt.Sprintf("config: invalid section name %q", schema.Name))
+	}
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("config: section %q must be a struct type, got %s", name, typ))
+	}
+
+	entry := &registeredSection{
+		name:     name,
+		required: schema.Required,
+		decode: func(raw any) (any, error) {
+			var value T
+			if schema.Default != nil {
+				value = schema.Default()
+			}
+			if err := DecodeSection(name, raw, &value); err != nil {
+				return nil, err
+			}
+			return value, nil
+		},
+	}
+	if schema.OnLoad != nil {
+		entry.onLoad = func(v any) error { return schema.OnLoad(v.(T)) }
+	}
+
+	r := sectionRegistryInstance
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.byName[name]; exists {
+		panic(fmt.Sprintf("config: section %q registered twice", name))
+	}
+	r.byName[name] = entry
+	r.order = append(r.order, name)
+}
+
+// RegisteredSections returns the names of all registered sections in
+// registration order.
+func RegisteredSections() []string {
+	r := sectionRegistryInstance
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.order)
+}
+
+// SectionsError reports every problem found by LoadSections at once.
+type SectionsError struct {
+	Unknown []string // top-level keys no module registered
+	Missing []string // required sections absent from config
+	Invalid []error  // decode or validation failures, usually *ValidationError
+}
+
+func (e *SectionsError) Error() string {
+	var parts []string
+	if len(e.Unknown) > 0 {
+		parts = append(parts, "unknown sections: "+strings.Join(e.Unknown, ", "))
+	}
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing required sections: "+strings.Join(e.Missing, ", "))
+	}
+	for _, err := range e.Invalid {
+		parts = append(parts, strings.TrimPrefix(err.Error(), "❌ "))
+	}
+	return "❌ Config sections are invalid: " + strings.Join(parts, "; ")
+}
+
+// Unwrap exposes the individual section errors to errors.Is / errors.As.
+func (e *SectionsError) Unwrap() []error {
+	return e.Invalid
+}
+
+// SectionsOption customises LoadSections.
+type SectionsOption func(*sectionsOptions)
+
+type sectionsOptions struct {
+	allowUnknown  map[string]bool
+	ignoreUnknown bool
+}
+
+// AllowSections accepts the given top-level keys even though no module
+// registered them, e.g. application-specific sections read elsewhere.
+func AllowSections(names ...string) SectionsOption {
+	return func(o *sectionsOptions) {
+		for _, name := range names {
+			o.allowUnknown[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// IgnoreUnknownSections disables the unknown-section check entirely.
+func IgnoreUnknownSections() SectionsOption {
+	return func(o *sectionsOptions) { o.ignoreUnknown = true }
+}
+
+// Sections holds the decoded values produced by LoadSections.
+type Sections struct {
+	values map[string]any
+}
+
+// Get returns the decoded value of a registered section.
+func (s *Sections) Get(name string) (any, bool) {
+	if s == nil {
+		return nil, false
+	}
+	v, ok := s.values[strings.ToLower(name)]
+	return v, ok
+}
+
+// SectionValue returns the decoded section name as T.
+func SectionValue[T any](s *Sections, name string) (T, bool) {
+	v, ok := s.Get(name)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	typed, ok := v.(T)
+	return typed, ok
+}
+
+// LoadSections decodes every registered section, rejects unknown top-level
+// keys and absent required sections, and only when everything is valid hands
+// each module its struct through OnLoad, in registration order.
+func (c *Config) LoadSections(opts ...SectionsOption) (*Sections, error) {
+	if c == nil || c.instance == nil {
+		return nil, fmt.Errorf("❌ Config instance is nil")
+	}
+
+	o := sectionsOptions{allowUnknown: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r := sectionRegistryInstance
+	r.mu.RLock()
+	entries := make([]*registeredSection, 0, len(r.order))
+	for _, name := range r.order {
+		entries = append(entries, r.byName[name])
+	}
+	r.mu.RUnlock()
+
+	c.watchMutex.RLock()
+	settings := c.instance.AllSettings()
+	c.watchMutex.RUnlock()
+
+	serr := &SectionsError{}
+	if !o.ignoreUnknown {
+		for key := range settings {
+			key = strings.ToLower(key)
+			if !o.allowUnknown[key] && !slices.ContainsFunc(entries, func(e *registeredSection) bool { return e.name == key }) {
+				serr.Unknown = append(serr.Unknown, key)
+			}
+		}
+		sort.Strings(serr.Unknown)
+	}
+
+	loaded := &Sections{values: make(map[string]any, len(entries))}
+	for _, entry := range entries {
+		raw, present := settings[entry.name]
+		if !present && entry.required {
+			serr.Missing = append(serr.Missing, entry.name)
+			continue
+		}
+		value, err := entry.decode(raw)
+		if err != nil {
+			serr.Invalid = append(serr.Invalid, err)
+			continue
+		}
+		loaded.values[entry.name] = value
+	}
+
+	if len(serr.Unknown) > 0 || len(serr.Missing) > 0 || len(serr.Invalid) > 0 {
+		return nil, serr
+	}
+
+	for _, entry := range entries {
+		if entry.onLoad == nil {
+			continue
+		}
+		if err := entry.onLoad(loaded.values[entry.name]); err != nil {
+			return nil, fmt.Errorf("❌ Failed to apply config section %q: %w", entry.name, err)
+		}
+	}
+	return loaded, nil
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type cacheSection struct {
+	Driver string `mapstructure:"driver" validate:"required,oneof=memory redis"`
+	TTL    int    `mapstructure:"ttl" default:"60"`
+}
+
+type mailSection struct {
+	Host string `mapstructure:"host" validate:"required"`
+	Port int    `mapstructure:"port"`
+}
+
+func withSectionRegistry(t *testing.T) {
+	t.Helper()
+	prev := sectionRegistryInstance
+	sectionRegistryInstance = newSectionRegistry()
+	t.Cleanup(func() { sectionRegistryInstance = prev })
+}
+
+func loadYAML(t *testing.T, content string) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.yaml"), content)
+	cfg, err := NewConfig(ConfigOptions{BasePath: dir, FileName: "config", FileType: "yaml"})
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	return cfg
+}
+
+func TestLoadSections_DecodesAndHandsOffInOrder(t *testing.T) {
+	withSectionRegistry(t)
+
+	var order []string
+	var gotCache cacheSection
+	RegisterSection(SectionSchema[cacheSection]{
+		Name:     "cache",
+		Required: true,
+		OnLoad: func(v cacheSection) error {
+			order = append(order, "cache")
+			gotCache = v
+			return nil
+		},
+	})
+	RegisterSection(SectionSchema[mailSection]{
+		Name:    "mail",
+		Default: func() mailSection { return mailSection{Host: "localhost", Port: 25} },
+		OnLoad: func(mailSection) error {
+			order = append(order, "mail")
+			return nil
+		},
+	})
+
+	cfg := loadYAML(t, "cache:\n  driver: redis\n")
+	sections, err := cfg.LoadSections()
+	if err != nil {
+		t.Fatalf("LoadSections failed: %v", err)
+	}
+
+	if gotCache.Driver != "redis" || gotCache.TTL != 60 {
+		t.Errorf("cache = %+v, want driver from file and TTL from default tag", gotCache)
+	}
+	mail, ok := SectionValue[mailSection](sections, "mail")
+	if !ok || mail.Host != "localhost" || mail.Port != 25 {
+		t.Errorf("mail = %+v, %v; want Default() for absent optional section", mail, ok)
+	}
+	if !slices.Equal(order, []string{"cache", "mail"}) {
+		t.Errorf("OnLoad order = %v, want registration order", order)
+	}
+}
+
+func TestLoadSections_ReportsAllProblems(t *testing.T) {
+	withSectionRegistry(t)
+
+	called := false
+	RegisterSection(SectionSchema[cacheSection]{
+		Name:   "cache",
+		OnLoad: func(cacheSection) error { called = true; return nil },
+	})
+	RegisterSection(SectionSchema[mailSection]{Name: "mail", Required: true})
+
+	cfg := loadYAML(t, "cache:\n  driver: disk\ncahce:\n  driver: memory\napp:\n  name: demo\n")
+	_, err := cfg.LoadSections(AllowSections("app"))
+
+	var serr *SectionsError
+	if !errors.As(err, &serr) {
+		t.Fatalf("err = %v, want *SectionsError", err)
+	}
+	if !slices.Equal(serr.Unknown, []string{"cahce"}) {
+		t.Errorf("Unknown = %v", serr.Unknown)
+	}
+	if !slices.Equal(serr.Missing, []string{"mail"}) {
+		t.Errorf("Missing = %v", serr.Missing)
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Section != "cache" {
+		t.Errorf("want *ValidationError for cache, got %v", err)
+	}
+	if called {
+		t.Error("OnLoad must not run when any section is invalid")
+	}
+
+	if _, err := cfg.LoadSections(IgnoreUnknownSections()); errors.As(err, &serr) && len(serr.Unknown) > 0 {
+		t.Errorf("IgnoreUnknownSections still reported %v", serr.Unknown)
+	}
+}
+
+func TestRegisterSection_PanicsOnDuplicate(t *testing.T) {
+	withSectionRegistry(t)
+
+	RegisterSection(SectionSchema[cacheSection]{Name: "cache"})
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate registration")
+		}
+	}()
+	RegisterSection(SectionSchema[cacheSection]{Name: "Cache"})
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// reloadDebounce coalesces the burst of events editors emit for one save.
+const reloadDebounce = 100 * time.Millisecond
+
+// ChangeEvent lists the config keys whose value changed during a reload.
+type ChangeEvent struct {
+	Keys []string
+}
+
+type changeWatcher struct {
+	prefix string
+	fn     func(ChangeEvent)
+}
+
+// Watch registers fn to be called after a reload changes any key equal to or
+// nested under prefix ("" matches every key). When WatchAble is set the config
+// files are watched for changes; otherwise fn only runs on explicit Reload.
+// The returned function removes the subscription.
+func (c *Config) Watch(prefix string, fn func(ChangeEvent)) (cancel func()) {
+	c.subMutex.Lock()
+	if c.watchers == nil {
+		c.watchers = make(map[uint64]changeWatcher)
+	}
+	c.nextWatcher++
+	id := c.nextWatcher
+	c.watchers[id] = changeWatcher{prefix: strings.ToLower(prefix), fn: fn}
+	c.subMutex.Unlock()
+
+	if c.opts.WatchAble {
+		c.watchOnce.Do(c.startWatching)
+	}
+
+	return func() {
+		c.subMutex.Lock()
+		delete(c.watchers, id)
+		c.subMutex.Unlock()
+	}
+}
+
+// Section binds the config subtree at key into a new T (see BindSection).
+func Section[T any](c *Config, key string) (T, error) {
+	var out T
+	err := c.BindSection(key, &out)
+	return out, err
+}
+
+// WatchSection calls fn with a freshly bound T whenever a reload changes a key
+// under key. Invalid values are reported through err and should not be applied.
+func WatchSection[T any](c *Config, key string, fn func(value T, err error)) (cancel func()) {
+	return c.Watch(key, func(ChangeEvent) {
+		fn(Section[T](c, key))
+	})
+}
+
+// Reload re-reads config files, environment variables and flags, refreshes
+// every Bind target and notifies watchers of the keys that changed. Values
+// applied with Set survive the reload.
+func (c *Config) Reload() error {
+	return c.reload(fsnotify.Event{})
+}
+
+func (c *Config) reload(e fsnotify.Event) error {
+	next, files, err := loadConfig(c.opts)
+	if err != nil {
+		return err
+	}
+
+	c.watchMutex.Lock()
+	for key, value := range c.overrides {
+		next.Set(key, value)
+	}
+	changed := diffSettings(flattenSettings(c.instance), flattenSettings(next))
+	c.instance = next
+	c.files = files
+
+	var bindErr error
+	for _, target := range c.bindings {
+		if err := next.Unmarshal(&target); err != nil && bindErr == nil {
+			bindErr = fmt.Errorf("❌ Failed to refresh bound config: %w", err)
+		}
+	}
+	c.watchMutex.Unlock()
+
+	if e.Name != "" && c.opts.OnChange != nil {
+		c.opts.OnChange(e)
+	}
+	if len(changed) > 0 {
+		c.notify(changed)
+	}
+	return bindErr
+}
+
+func (c *Config) notify(changed []string) {
+	c.subMutex.Lock()
+	watchers := make([]changeWatcher, 0, len(c.watchers))
+	for _, w := range c.watchers {
+		watchers = append(watchers, w)
+	}
+	c.subMutex.Unlock()
+
+	for _, w := range watchers {
+		var keys []string
+		for _, key := range changed {
+			if w.prefix == "" || key == w.prefix || strings.HasPrefix(key, w.prefix+".") {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			w.fn(ChangeEvent{Keys: keys})
+		}
+	}
+}
+
+// startWatching watches the config directory so files created after startup
+// (e.g. config.prod.local.yaml) and atomic editor renames are picked up.
+func (c *Config) startWatching() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Printf("❌ Config watch error: %v\n", err)
+		return
+	}
+	if err := watcher.Add(c.opts.BasePath); err != nil {
+		fmt.Printf("❌ Config watch error: %v\n", err)
+		watcher.Close()
+		return
+	}
+
+	c.subMutex.Lock()
+	c.fsWatcher = watcher
+	c.subMutex.Unlock()
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !c.isConfigFile(event.Name) {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDebounce, func() {
+					if err := c.reload(event); err != nil {
+						fmt.Printf("❌ Config watch error: %v\n", err)
+					}
+				})
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fmt.Printf("❌ Config watch error: %v\n", err)
+			}
+		}
+	}()
+}
+
+func (c *Config) isConfigFile(name string) bool {
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	return slices.Contains(fileTypes(c.opts), ext)
+}
+
+// Close stops watching config files.
+func (c *Config) Close() error {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+
+	if c.fsWatcher == nil {
+		return nil
+	}
+	err := c.fsWatcher.Close()
+	c.fsWatcher = nil
+	return err
+}
+
+func flattenSettings(v *viper.Viper) map[string]any {
+	settings := make(map[string]any)
+	for _, key := range v.AllKeys() {
+		settings[key] = v.Get(key)
+	}
+	return settings
+}
+
+func diffSettings(before, after map[string]any) []string {
+	var changed []string
+	for key, value := range after {
+		if old, ok := before[key]; !ok || !reflect.DeepEqual(old, value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
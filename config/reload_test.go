@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type serverConfig struct {
+	Host    string        `mapstructure:"host" default:"localhost"`
+	Port    int           `mapstructure:"port" validate:"required,min=1"`
+	Timeout time.Duration `mapstructure:"timeout" default:"30s"`
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestNewConfig_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.yaml"), "server:\n  host: file-host\n  port: 8080\ndb:\n  name: app\n")
+	writeFile(t, filepath.Join(dir, "config.toml"), "[db]\nname = \"toml-db\"\n")
+	t.Setenv("TESTAPP_SERVER_HOST", "env-host")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Int("server-port", 0, "")
+	flags.String("server.host", "", "")
+	if err := flags.Parse([]string{"--server-port=9090"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewConfig(ConfigOptions{
+		BasePath:  dir,
+		FileName:  "config",
+		FileTypes: []string{"yaml", "toml"},
+		EnvPrefix: "TESTAPP",
+		Flags:     flags,
+	})
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+
+	server, err := Section[serverConfig](cfg, "server")
+	if err != nil {
+		t.Fatalf("Section failed: %v", err)
+	}
+	if server.Host != "env-host" {
+		t.Errorf("host = %q, want env override", server.Host)
+	}
+	if server.Port != 9090 {
+		t.Errorf("port = %d, want flag override", server.Port)
+	}
+	if server.Timeout != 30*time.Second {
+		t.Errorf("timeout = %v, want default", server.Timeout)
+	}
+	if got := cfg.Get("db.name"); got != "toml-db" {
+		t.Errorf("db.name = %v, want later format to win", got)
+	}
+}
+
+func TestReload_NotifiesWatchersOfChangedKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeFile(t, path, "server:\n  port: 8080\nlog:\n  level: info\n")
+
+	cfg, err := NewConfig(ConfigOptions{BasePath: dir, FileName: "config", FileType: "yaml"})
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+
+	var serverEvents, logEvents []ChangeEvent
+	cfg.Watch("server", func(e ChangeEvent) { serverEvents = append(serverEvents, e) })
+	cfg.Watch("log", func(e ChangeEvent) { logEvents = append(logEvents, e) })
+
+	var reloaded serverConfig
+	WatchSection(cfg, "server", func(v serverConfig, err error) {
+		if err != nil {
+			t.Errorf("WatchSection error: %v", err)
+		}
+		reloaded = v
+	})
+
+	writeFile(t, path, "server:\n  port: 9000\nlog:\n  level: info\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if len(serverEvents) != 1 || len(serverEvents[0].Keys) != 1 || serverEvents[0].Keys[0] != "server.port" {
+		t.Errorf("server events = %+v", serverEvents)
+	}
+	if len(logEvents) != 0 {
+		t.Errorf("log watcher should not fire, got %+v", logEvents)
+	}
+	if reloaded.Port != 9000 {
+		t.Errorf("WatchSection port = %d, want 9000", reloaded.Port)
+	}
+}
+
+func TestReload_KeepsSetOverrides(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.yaml"), "server:\n  port: 8080\n")
+
+	cfg, err := NewConfig(ConfigOptions{BasePath: dir, FileName: "config", FileType: "yaml"})
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	cfg.Set("server.port", 7000)
+
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if got := cfg.Get("server.port"); got != 7000 {
+		t.Errorf("server.port = %v, want Set override to survive reload", got)
+	}
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// secretResolveTimeout bounds secret lookups performed while loading config.
+const secretResolveTimeout = 30 * time.Second
+
+// secretRefPattern matches ${secret:name} references inside config values.
+var secretRefPattern = regexp.MustCompile(`\$\{secret:([^}]+)\}`)
+
+// SecretResolver looks up a secret value by name.
+type SecretResolver interface {
+	Resolve(ctx context.Context, name string) (string, error)
+}
+
+// SecretResolverFunc adapts a function to SecretResolver.
+type SecretResolverFunc func(ctx context.Context, name string) (string, error)
+
+// Resolve implements SecretResolver.
+func (f SecretResolverFunc) Resolve(ctx context.Context, name string) (string, error) {
+	return f(ctx, name)
+}
+
+// EnvSecretResolver reads secrets from environment variables. The name is
+// upper-cased, "." "-" and "/" become "_", and Prefix is prepended:
+// with Prefix "APP_SECRET_", ${secret:db_password} reads APP_SECRET_DB_PASSWORD.
+type EnvSecretResolver struct {
+	Prefix string
+}
+
+// Resolve implements SecretResolver.
+func (r EnvSecretResolver) Resolve(_ context.Context, name string) (string, error) {
+	key := r.Prefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_", "/", "_").Replace(name))
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", key)
+	}
+	return value, nil
+}
+
+// FileSecretResolver reads secrets from files in Dir, one secret per file, as
+// mounted by Docker or Kubernetes (e.g. /run/secrets/db_password). A single
+// trailing newline is stripped.
+type FileSecretResolver struct {
+	Dir string
+}
+
+// Resolve implements SecretResolver.
+func (r FileSecretResolver) Resolve(_ context.Context, name string) (string, error) {
+	clean := filepath.Clean("/" + name)
+	data, err := os.ReadFile(filepath.Join(r.Dir, clean))
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(value, "\r"), nil
+}
+
+// MultiSecretResolver routes "<provider>:<name>" references to the resolver
+// registered under provider, and anything else to Default. For example
+// ${secret:vault:db#password} uses Providers["vault"] with name "db#password".
+type MultiSecretResolver struct {
+	Default   SecretResolver
+	Providers map[string]SecretResolver
+}
+
+// Resolve implements SecretResolver.
+func (r MultiSecretResolver) Resolve(ctx context.Context, name string) (string, error) {
+	if provider, rest, ok := strings.Cut(name, ":"); ok {
+		if resolver, found := r.Providers[provider]; found {
+			return resolver.Resolve(ctx, rest)
+		}
+	}
+	if r.Default == nil {
+		return "", fmt.Errorf("no secret provider for %q", name)
+	}
+	return r.Default.Resolve(ctx, name)
+}
+
+// ResolveSecrets replaces every ${secret:name} reference in s.
+func ResolveSecrets(ctx context.Context, resolver SecretResolver, s string) (string, error) {
+	var firstErr error
+	out := secretRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		if firstErr != nil {
+			return ref
+		}
+		name := strings.TrimSpace(secretRefPattern.FindStringSubmatch(ref)[1])
+		value, err := resolver.Resolve(ctx, name)
+		if err != nil {
+			firstErr = fmt.Errorf("❌ Failed to resolve secret %q: %w", name, err)
+			return ref
+		}
+		return value
+	})
+	return out, firstErr
+}
+
+// applySecrets resolves secret references in every string value of v.
+func applySecrets(v *viper.Viper, resolver SecretResolver) error {
+	if resolver == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
+
+	for _, key := range v.AllKeys() {
+		switch value := v.Get(key).(type) {
+		case string:
+			if !strings.Contains(value, "${secret:") {
+				continue
+			}
+			resolved, err := ResolveSecrets(ctx, resolver, value)
+			if err != nil {
+				return fmt.Errorf("%w (key: %s)", err, key)
+			}
+			v.Set(key, resolved)
+		case []any:
+			changed := false
+			for i, item := range value {
+				str, ok := item.(string)
+				if !ok || !strings.Contains(str, "${secret:") {
+					continue
+				}
+				resolved, err := ResolveSecrets(ctx, resolver, str)
+				if err != nil {
+					return fmt.Errorf("%w (key: %s[%d])", err, key, i)
+				}
+				value[i] = resolved
+				changed = true
+			}
+			if changed {
+				v.Set(key, value)
+			}
+		}
+	}
+	return nil
+}
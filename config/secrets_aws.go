@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AWSSecretsManagerResolver reads secrets from AWS Secrets Manager using the
+// GetSecretValue API signed with Signature V4, without pulling in the AWS SDK.
+// Names have the form "secret-id" or "secret-id#json-key"; the latter reads
+// one key of a JSON SecretString. Credentials default to the standard
+// AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY / AWS_SESSION_TOKEN variables.
+type AWSSecretsManagerResolver struct {
+	// Region defaults to $AWS_REGION, then $AWS_DEFAULT_REGION.
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Endpoint overrides https://secretsmanager.<region>.amazonaws.com.
+	Endpoint   string
+	HTTPClient *http.Client
+
+	now func() time.Time
+}
+
+// Resolve implements SecretResolver.
+func (r AWSSecretsManagerResolver) Resolve(ctx context.Context, name string) (string, error) {
+	region := firstNonEmpty(r.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	accessKey := firstNonEmpty(r.AccessKeyID, os.Getenv("AWS_ACCESS_KEY_ID"))
+	secretKey := firstNonEmpty(r.SecretAccessKey, os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	sessionToken := firstNonEmpty(r.SessionToken, os.Getenv("AWS_SESSION_TOKEN"))
+	if region == "" || accessKey == "" || secretKey == "" {
+		return "", fmt.Errorf("aws region and credentials are required")
+	}
+
+	endpoint := r.Endpoint
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid secrets manager endpoint: %w", err)
+	}
+
+	secretID, jsonKey, hasKey := strings.Cut(name, "#")
+	body, _ := json.Marshal(map[string]string{"SecretId": secretID})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+
+	now := time.Now
+	if r.now != nil {
+		now = r.now
+	}
+	signAWSRequest(req, body, u.Host, region, "secretsmanager", accessKey, secretKey, sessionToken, now().UTC())
+
+	client := r.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("secrets manager request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(respBody, &apiErr)
+		return "", fmt.Errorf("secrets manager returned %s: %s %s", resp.Status, apiErr.Type, apiErr.Message)
+	}
+
+	var out struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return "", fmt.Errorf("decode secrets manager response: %w", err)
+	}
+	if !hasKey {
+		return out.SecretString, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(out.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object: %w", secretID, err)
+	}
+	value, ok := fields[jsonKey]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %q", secretID, jsonKey)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// signAWSRequest adds AWS Signature Version 4 headers to a request whose
+// signed headers are content-type, host, x-amz-date and x-amz-target.
+func signAWSRequest(req *http.Request, body []byte, host, region, service, accessKey, secretKey, sessionToken string, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	signedHeaders := []string{"content-type", "host", "x-amz-date", "x-amz-target"}
+	values := map[string]string{
+		"content-type": req.Header.Get("Content-Type"),
+		"host":         host,
+		"x-amz-date":   amzDate,
+		"x-amz-target": req.Header.Get("X-Amz-Target"),
+	}
+	if sessionToken != "" {
+		signedHeaders = append(signedHeaders, "x-amz-security-token")
+		values["x-amz-security-token"] = sessionToken
+	}
+	slices.Sort(signedHeaders)
+
+	var canonicalHeaders strings.Builder
+	for _, h := range signedHeaders {
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(values[h]) + "\n")
+	}
+	payloadHash := sha256.Sum256(body)
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
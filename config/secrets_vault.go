@@ -0,0 +1,99 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultSecretResolver reads secrets from a HashiCorp Vault KV v2 engine.
+// Names have the form "path#field"; ${secret:vault:database/prod#password}
+// reads field "password" of secret "database/prod". Without "#field" the
+// "value" field is used.
+type VaultSecretResolver struct {
+	// Address of the Vault server; defaults to $VAULT_ADDR.
+	Address string
+	// Token used for X-Vault-Token; defaults to $VAULT_TOKEN.
+	Token string
+	// Mount is the KV v2 mount path; defaults to "secret".
+	Mount string
+	// Namespace is sent as X-Vault-Namespace (Vault Enterprise).
+	Namespace  string
+	HTTPClient *http.Client
+}
+
+// Resolve implements SecretResolver.
+func (r VaultSecretResolver) Resolve(ctx context.Context, name string) (string, error) {
+	address := r.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	token := r.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if address == "" || token == "" {
+		return "", fmt.Errorf("vault address and token are required")
+	}
+	mount := strings.Trim(r.Mount, "/")
+	if mount == "" {
+		mount = "secret"
+	}
+
+	path, field, ok := strings.Cut(name, "#")
+	if !ok {
+		field = "value"
+	}
+	path = strings.Trim(path, "/")
+
+	endpoint := strings.TrimRight(address, "/") + "/v1/" + mount + "/data/" + (&url.URL{Path: path}).EscapedPath()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if r.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", r.Namespace)
+	}
+
+	client := r.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s for %s", resp.Status, path)
+	}
+
+	var payload struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", fmt.Errorf("decode vault response: %w", err)
+	}
+	value, ok := payload.Data.Data[field]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no field %q", path, field)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/creasty/defaults"
+	validatorV10 "github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/leeforge/framework/validation"
+)
+
+var sectionValidator = newSectionValidator()
+
+func newSectionValidator() *validatorV10.Validate {
+	v := validation.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("mapstructure"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+// FieldError describes a single invalid field inside a config section.
+type FieldError struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a config section.
+type ValidationError struct {
+	Section string
+	Fields  []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + " " + f.Message
+	}
+	return fmt.Sprintf("❌ Config section %q is invalid: %s", e.Section, strings.Join(parts, "; "))
+}
+
+// BindSection decodes the config subtree at key into target, applying
+// `default` tags first and `validate` tags afterwards. A missing section is
+// treated as empty, so defaults still apply and required fields are reported.
+func (c *Config) BindSection(key string, target any) error {
+	if c == nil || c.instance == nil {
+		return fmt.Errorf("❌ Config instance is nil")
+	}
+
+	c.watchMutex.RLock()
+	raw := c.instance.Get(key)
+	c.watchMutex.RUnlock()
+
+	return DecodeSection(key, raw, target)
+}
+
+// DecodeSection applies defaults to target, decodes raw (usually a
+// map[string]any) into it using `mapstructure` tags with weak typing so that
+// env-provided strings convert to numbers, bools and durations, then validates.
+func DecodeSection(section string, raw any, target any) error {
+	if target == nil || reflect.ValueOf(target).Kind() != reflect.Ptr {
+		return fmt.Errorf("❌ Config section %q target must be a non-nil pointer", section)
+	}
+
+	if err := defaults.Set(target); err != nil {
+		return fmt.Errorf("❌ Failed to set defaults for section %q: %w", section, err)
+	}
+
+	if raw != nil {
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			Result:           target,
+			TagName:          "mapstructure",
+			WeaklyTypedInput: true,
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				mapstructure.StringToTimeDurationHookFunc(),
+				mapstructure.StringToSliceHookFunc(","),
+			),
+		})
+		if err != nil {
+			return fmt.Errorf("❌ Failed to create decoder for section %q: %w", section, err)
+		}
+		if err := decoder.Decode(raw); err != nil {
+			return fmt.Errorf("❌ Failed to decode config section %q: %w", section, err)
+		}
+	}
+
+	return validateSection(section, target)
+}
+
+func validateSection(section string, target any) error {
+	if reflect.Indirect(reflect.ValueOf(target)).Kind() != reflect.Struct {
+		return nil
+	}
+
+	err := sectionValidator.Struct(target)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs validatorV10.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return fmt.Errorf("❌ Config section %q validation failed: %w", section, err)
+	}
+
+	verr := &ValidationError{Section: section}
+	for _, fe := range fieldErrs {
+		// Namespace is "<Struct>.<field>.<nested>"; drop the root type name.
+		field := fe.Namespace()
+		if idx := strings.Index(field, "."); idx >= 0 {
+			field = field[idx+1:]
+		}
+		verr.Fields = append(verr.Fields, FieldError{
+			Field:   field,
+			Tag:     fe.Tag(),
+			Message: validation.Message(context.Background(), fe),
+		})
+	}
+	return verr
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type billingConfig struct {
+	APIKey   string        `mapstructure:"api_key" validate:"required"`
+	Timeout  time.Duration `mapstructure:"timeout" default:"5s"`
+	Retries  int           `mapstructure:"retries" default:"3" validate:"min=1,max=10"`
+	Currency string        `mapstructure:"currency" default:"USD" validate:"oneof=USD EUR CNY"`
+}
+
+func TestDecodeSection_DefaultsAndWeakTyping(t *testing.T) {
+	var cfg billingConfig
+	raw := map[string]any{"api_key": "secret", "retries": "7", "timeout": "250ms"}
+
+	if err := DecodeSection("plugins.billing", raw, &cfg); err != nil {
+		t.Fatalf("DecodeSection failed: %v", err)
+	}
+	if cfg.Retries != 7 || cfg.Timeout != 250*time.Millisecond || cfg.Currency != "USD" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestDecodeSection_ReportsEveryInvalidField(t *testing.T) {
+	var cfg billingConfig
+	err := DecodeSection("plugins.billing", map[string]any{"retries": 42, "currency": "GBP"}, &cfg)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if len(verr.Fields) != 3 {
+		t.Fatalf("fields = %+v, want 3 errors", verr.Fields)
+	}
+	msg := err.Error()
+	for _, want := range []string{"plugins.billing", "api_key is required", "retries must be at most 10", "currency must be one of"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q should mention %q", msg, want)
+		}
+	}
+}
+
+func TestDecodeSection_FrameworkRules(t *testing.T) {
+	var cfg struct {
+		TimeZone string `mapstructure:"time_zone" validate:"timezone"`
+		Slug     string `mapstructure:"slug" validate:"omitempty,slug"`
+	}
+	err := DecodeSection("app", map[string]any{"time_zone": "Mars/Olympus", "slug": "Not A Slug"}, &cfg)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Fields) != 2 {
+		t.Fatalf("expected 2 field errors, got %v", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "time_zone must be a valid IANA time zone") {
+		t.Errorf("error %q should describe the timezone rule", msg)
+	}
+}
+
+func TestDecodeSection_MissingSectionUsesDefaults(t *testing.T) {
+	var cfg struct {
+		Enabled bool `mapstructure:"enabled" default:"true"`
+	}
+	if err := DecodeSection("plugins.audit", nil, &cfg); err != nil {
+		t.Fatalf("DecodeSection failed: %v", err)
+	}
+	if !cfg.Enabled {
+		t.Error("defaults should apply when the section is absent")
+	}
+}
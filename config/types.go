@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -25,13 +26,31 @@ type Config struct {
 	watchOnce  sync.Once
 	watchMutex sync.RWMutex
 	snapshot   map[string]any
+
+	files     []string       // files merged into instance, in load order
+	overrides map[string]any // values set via Set, re-applied on reload
+	bindings  []any          // targets passed to Bind, refreshed on reload
+
+	subMutex    sync.Mutex
+	watchers    map[uint64]changeWatcher
+	nextWatcher uint64
+	fsWatcher   *fsnotify.Watcher
 }
 
 type ConfigOptions struct {
-	BasePath  string
-	FileName  string
-	FileType  string
+	BasePath string
+	FileName string
+	FileType string
+	// FileTypes loads several formats (e.g. "yaml", "json", "toml") and
+	// overrides FileType when set. Files are merged in the listed order.
+	FileTypes []string
 	EnvPrefix string
+	// Flags overrides file and env values with the flags explicitly set on
+	// the command line. Flag names are config keys: --server.port or --server-port.
+	Flags *pflag.FlagSet
+	// Secrets resolves ${secret:name} references in string values after
+	// files, env and flags are merged, so credentials stay out of config files.
+	Secrets   SecretResolver
 	WatchAble bool
 	OnChange  func(e fsnotify.Event)
 	LoadAll   bool
@@ -0,0 +1,364 @@
+// Package router splits ent traffic between a primary database and read
+// replicas. Read-only queries go to a healthy replica, everything else, and
+// any read that follows a write in the same request, goes to the primary.
+package router
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	"github.com/leeforge/framework/ent"
+	"github.com/leeforge/framework/metrics"
+	"go.uber.org/zap"
+)
+
+// Health check defaults.
+const (
+	DefaultHealthInterval   = 5 * time.Second
+	DefaultHealthTimeout    = time.Second
+	DefaultFailureThreshold = 2
+)
+
+// Metrics exported when Options.Metrics is set. Both are labelled by "pool".
+const (
+	MetricPoolHealthy = "db_pool_healthy"
+	MetricRouted      = "db_router_queries_total"
+)
+
+// PrimaryName is the pool name of the primary in metrics and Stats.
+const PrimaryName = "primary"
+
+// ErrNoPrimary is returned by New when Options.Primary is nil.
+var ErrNoPrimary = stderrors.New("router: primary database is required")
+
+// Replica is a read replica.
+type Replica struct {
+	// Name identifies the replica in metrics, logs and Stats.
+	Name string
+	DB   *sql.DB
+	// Pool applies connection limits to DB; nil keeps its current settings.
+	Pool *ent.ConnectionPool
+}
+
+// Options configures a Router.
+type Options struct {
+	// Dialect is the ent dialect of all pools, e.g. dialect.Postgres.
+	Dialect string
+	Primary *sql.DB
+	// PrimaryPool applies connection limits to Primary.
+	PrimaryPool *ent.ConnectionPool
+	Replicas    []Replica
+	// StickyWindow keeps reads on the primary for this long after a write in
+	// the same session, covering replication lag. Zero keeps them there for
+	// the rest of the session.
+	StickyWindow time.Duration
+	// HealthInterval is how often replicas are pinged.
+	HealthInterval time.Duration
+	// HealthTimeout bounds a single ping.
+	HealthTimeout time.Duration
+	// FailureThreshold is the number of consecutive failed pings after which
+	// a replica stops receiving reads. One successful ping restores it.
+	FailureThreshold int
+	Metrics          *metrics.Collector
+	Logger           *zap.Logger
+}
+
+// pool is one database with its ent driver and health state.
+type pool struct {
+	name     string
+	db       *sql.DB
+	drv      *entsql.Driver
+	healthy  atomic.Bool
+	failures int
+}
+
+// PoolStats describes a pool's health and connection usage.
+type PoolStats struct {
+	Name    string
+	Healthy bool
+	sql.DBStats
+}
+
+// Router is an ent dialect.Driver that routes statements between the primary
+// and replicas:
+//
+//	r, _ := router.New(router.Options{Dialect: dialect.Postgres, Primary: primary, Replicas: replicas})
+//	client := ent.NewClient(ent.Driver(r))
+//	handler = r.Middleware(handler) // read-your-writes within a request
+type Router struct {
+	opts     Options
+	primary  *pool
+	replicas []*pool
+	next     atomic.Uint64
+
+	// checkMu serialises CheckReplicas, which owns pool.failures
+	checkMu  sync.Mutex
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+var _ dialect.Driver = (*Router)(nil)
+
+// New creates a router and starts health checking the replicas. Call Close
+// to stop it and close all pools.
+func New(opts Options) (*Router, error) {
+	if opts.Primary == nil {
+		return nil, ErrNoPrimary
+	}
+	if opts.HealthInterval <= 0 {
+		opts.HealthInterval = DefaultHealthInterval
+	}
+	if opts.HealthTimeout <= 0 {
+		opts.HealthTimeout = DefaultHealthTimeout
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultFailureThreshold
+	}
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+
+	r := &Router{
+		opts:    opts,
+		primary: newPool(PrimaryName, opts.Dialect, opts.Primary, opts.PrimaryPool),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for i, replica := range opts.Replicas {
+		if replica.DB == nil {
+			return nil, fmt.Errorf("router: replica %d has no database", i)
+		}
+		name := replica.Name
+		if name == "" {
+			name = fmt.Sprintf("replica-%d", i)
+		}
+		r.replicas = append(r.replicas, newPool(name, opts.Dialect, replica.DB, replica.Pool))
+	}
+	r.setHealth(r.primary, true)
+	for _, p := range r.replicas {
+		r.setHealth(p, true)
+	}
+
+	go r.healthLoop()
+	return r, nil
+}
+
+func newPool(name, dialectName string, db *sql.DB, settings *ent.ConnectionPool) *pool {
+	if settings != nil {
+		settings.Apply(db)
+	}
+	return &pool{name: name, db: db, drv: entsql.OpenDB(dialectName, db)}
+}
+
+// Exec runs a statement on the primary.
+func (r *Router) Exec(ctx context.Context, query string, args, v any) error {
+	markWrite(ctx)
+	r.count(r.primary)
+	return r.primary.drv.Exec(ctx, query, args, v)
+}
+
+// Query runs read-only queries on a replica and anything else, such as
+// INSERT ... RETURNING or SELECT ... FOR UPDATE, on the primary. A replica
+// whose connection turns out bad is marked down and the query is retried on
+// the primary.
+func (r *Router) Query(ctx context.Context, query string, args, v any) error {
+	if !IsReadOnly(query) {
+		markWrite(ctx)
+		r.count(r.primary)
+		return r.primary.drv.Query(ctx, query, args, v)
+	}
+
+	p := r.reader(ctx)
+	r.count(p)
+	err := p.drv.Query(ctx, query, args, v)
+	if p != r.primary && stderrors.Is(err, driver.ErrBadConn) {
+		r.opts.Logger.Warn("replica connection failed, retrying on primary", zap.String("pool", p.name), zap.Error(err))
+		r.setHealth(p, false)
+		r.count(r.primary)
+		return r.primary.drv.Query(ctx, query, args, v)
+	}
+	return err
+}
+
+// Tx starts a transaction on the primary.
+func (r *Router) Tx(ctx context.Context) (dialect.Tx, error) {
+	markWrite(ctx)
+	return r.primary.drv.Tx(ctx)
+}
+
+// BeginTx starts a transaction with options on the primary.
+func (r *Router) BeginTx(ctx context.Context, opts *sql.TxOptions) (dialect.Tx, error) {
+	markWrite(ctx)
+	return r.primary.drv.BeginTx(ctx, opts)
+}
+
+// Dialect returns the dialect of the pools.
+func (r *Router) Dialect() string {
+	return r.opts.Dialect
+}
+
+// Close stops health checking and closes all pools.
+func (r *Router) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	<-r.done
+
+	var errs []error
+	for _, p := range append([]*pool{r.primary}, r.replicas...) {
+		if err := p.db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("router: close %s: %w", p.name, err))
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// Writer returns the primary for code that runs SQL without ent.
+func (r *Router) Writer() *sql.DB {
+	return r.primary.db
+}
+
+// Reader returns the pool a read-only query in ctx would use.
+func (r *Router) Reader(ctx context.Context) *sql.DB {
+	return r.reader(ctx).db
+}
+
+// Check pings the primary; it implements health.Checker. Replica failures
+// only degrade read capacity and are reported through Stats and metrics.
+func (r *Router) Check(ctx context.Context) error {
+	return r.primary.db.PingContext(ctx)
+}
+
+// Stats returns the primary followed by each replica.
+func (r *Router) Stats() []PoolStats {
+	stats := make([]PoolStats, 0, len(r.replicas)+1)
+	for _, p := range append([]*pool{r.primary}, r.replicas...) {
+		stats = append(stats, PoolStats{Name: p.name, Healthy: p.healthy.Load(), DBStats: p.db.Stats()})
+	}
+	return stats
+}
+
+// reader picks the pool for a read: the primary when the session is sticky
+// or no replica is healthy, otherwise the next healthy replica.
+func (r *Router) reader(ctx context.Context) *pool {
+	if len(r.replicas) == 0 || r.sticky(ctx) {
+		return r.primary
+	}
+	start := r.next.Add(1)
+	for i := range uint64(len(r.replicas)) {
+		p := r.replicas[(start+i)%uint64(len(r.replicas))]
+		if p.healthy.Load() {
+			return p
+		}
+	}
+	return r.primary
+}
+
+func (r *Router) sticky(ctx context.Context) bool {
+	if forcedPrimary(ctx) {
+		return true
+	}
+	s := sessionFrom(ctx)
+	if s == nil {
+		return false
+	}
+	wrote := s.lastWrite.Load()
+	if wrote == 0 {
+		return false
+	}
+	return r.opts.StickyWindow <= 0 || time.Since(time.Unix(0, wrote)) < r.opts.StickyWindow
+}
+
+func (r *Router) count(p *pool) {
+	if r.opts.Metrics != nil {
+		r.opts.Metrics.IncCounter(MetricRouted, map[string]string{"pool": p.name})
+	}
+}
+
+func (r *Router) healthLoop() {
+	defer close(r.done)
+	if len(r.replicas) == 0 {
+		<-r.stop
+		return
+	}
+	ticker := time.NewTicker(r.opts.HealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.CheckReplicas(context.Background())
+		}
+	}
+}
+
+// CheckReplicas pings every replica once and updates its health. It runs
+// every HealthInterval in the background.
+func (r *Router) CheckReplicas(ctx context.Context) {
+	r.checkMu.Lock()
+	defer r.checkMu.Unlock()
+	for _, p := range r.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, r.opts.HealthTimeout)
+		err := p.db.PingContext(pingCtx)
+		cancel()
+
+		if err == nil {
+			p.failures = 0
+			r.setHealth(p, true)
+			continue
+		}
+		p.failures++
+		if p.failures >= r.opts.FailureThreshold {
+			r.setHealth(p, false)
+		}
+	}
+}
+
+func (r *Router) setHealth(p *pool, healthy bool) {
+	if was := p.healthy.Swap(healthy); was != healthy && p != r.primary {
+		if healthy {
+			r.opts.Logger.Info("replica recovered", zap.String("pool", p.name))
+		} else {
+			r.opts.Logger.Warn("replica marked down", zap.String("pool", p.name))
+		}
+	}
+	if r.opts.Metrics != nil {
+		value := 0.0
+		if healthy {
+			value = 1
+		}
+		r.opts.Metrics.SetGauge(MetricPoolHealthy, value, map[string]string{"pool": p.name})
+	}
+}
+
+// IsReadOnly reports whether query can run on a replica: a SELECT (or a WITH
+// whose statement is a SELECT) that takes no row locks.
+func IsReadOnly(query string) bool {
+	statement := strings.ToUpper(ent.SanitizeSQL(query))
+	switch {
+	case strings.HasPrefix(statement, "SELECT "):
+	case strings.HasPrefix(statement, "WITH "):
+		for _, kw := range []string{"INSERT ", "UPDATE ", "DELETE ", "MERGE "} {
+			if strings.Contains(statement, kw) {
+				return false
+			}
+		}
+	default:
+		return false
+	}
+	for _, lock := range []string{" FOR UPDATE", " FOR SHARE", " FOR NO KEY UPDATE", " FOR KEY SHARE", " LOCK IN SHARE MODE"} {
+		if strings.Contains(statement, lock) {
+			return false
+		}
+	}
+	return true
+}
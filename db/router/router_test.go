@@ -0,0 +1,200 @@
+package router
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/ent"
+	"github.com/leeforge/framework/metrics"
+
+	_ "modernc.org/sqlite"
+)
+
+// openSeeded opens an in-memory database holding one policy whose V0 names
+// the database, so tests can tell which pool served a read.
+func openSeeded(t *testing.T, name string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:router-"+uuid.NewString()+"?mode=memory&cache=shared&_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an in-memory database is dropped with its last connection
+	db.SetMaxIdleConns(1)
+	client := ent.NewClient(ent.Driver(entsql.OpenDB(dialect.SQLite, db)))
+	ctx := context.Background()
+	if err := client.Schema.Create(ctx); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	client.CasbinPolicy.Create().SetPtype("p").SetV0(name).SaveX(ctx)
+	return db
+}
+
+func newTestRouter(t *testing.T, opts Options) (*Router, *ent.Client) {
+	t.Helper()
+	opts.Dialect = dialect.SQLite
+	opts.Primary = openSeeded(t, "primary")
+	if opts.Replicas == nil {
+		opts.Replicas = []Replica{{Name: "r1", DB: openSeeded(t, "r1")}}
+	}
+	r, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r, ent.NewClient(ent.Driver(r))
+}
+
+func servedBy(t *testing.T, ctx context.Context, client *ent.Client) string {
+	t.Helper()
+	policies, err := client.CasbinPolicy.Query().All(ctx)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	return policies[0].V0
+}
+
+func TestIsReadOnly(t *testing.T) {
+	tests := map[string]bool{
+		`SELECT "id" FROM "users" WHERE "id" = $1`:                      true,
+		"  select count(*) from users":                                  true,
+		"WITH recent AS (SELECT id FROM logs) SELECT * FROM recent":     true,
+		`INSERT INTO "users" ("name") VALUES ($1) RETURNING "id"`:       false,
+		"SELECT * FROM users WHERE id = $1 FOR UPDATE":                  false,
+		"SELECT * FROM jobs FOR NO KEY UPDATE SKIP LOCKED":              false,
+		"WITH moved AS (DELETE FROM a RETURNING *) SELECT * FROM moved": false,
+		"UPDATE users SET name = 'SELECT '":                             false,
+	}
+	for query, want := range tests {
+		if got := IsReadOnly(query); got != want {
+			t.Errorf("IsReadOnly(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestReadsGoToReplicasAndWritesToPrimary(t *testing.T) {
+	collector := metrics.NewCollector()
+	_, client := newTestRouter(t, Options{Metrics: collector})
+	ctx := context.Background()
+
+	if got := servedBy(t, ctx, client); got != "r1" {
+		t.Fatalf("read served by %s, want replica", got)
+	}
+	client.CasbinPolicy.Create().SetPtype("p").SetV0("written").SaveX(ctx)
+	if n := client.CasbinPolicy.Query().CountX(WithPrimary(ctx)); n != 2 {
+		t.Fatalf("primary has %d policies, want 2 after the write", n)
+	}
+	// reads without a session never stick to the primary
+	if got := servedBy(t, ctx, client); got != "r1" {
+		t.Errorf("read without session served by %s, want replica", got)
+	}
+
+	if m := collector.GetMetric(MetricPoolHealthy, map[string]string{"pool": "r1"}); m == nil || m.Value != 1 {
+		t.Errorf("%s{r1} = %+v, want 1", MetricPoolHealthy, m)
+	}
+	if m := collector.GetMetric(MetricRouted, map[string]string{"pool": "r1"}); m == nil || m.Value != 2 {
+		t.Errorf("%s{r1} = %+v, want 2", MetricRouted, m)
+	}
+}
+
+func TestSessionStickinessAfterWrite(t *testing.T) {
+	_, client := newTestRouter(t, Options{})
+	ctx := WithSession(context.Background())
+
+	if got := servedBy(t, ctx, client); got != "r1" {
+		t.Fatalf("read before write served by %s, want replica", got)
+	}
+	client.CasbinPolicy.Create().SetPtype("p").SetV0("written").SaveX(ctx)
+	if got := servedBy(t, ctx, client); got != "primary" {
+		t.Errorf("read after write served by %s, want primary", got)
+	}
+	if got := servedBy(t, WithSession(context.Background()), client); got != "r1" {
+		t.Errorf("other session served by %s, want replica", got)
+	}
+}
+
+func TestStickyWindowExpires(t *testing.T) {
+	_, client := newTestRouter(t, Options{StickyWindow: 10 * time.Millisecond})
+	ctx := WithSession(context.Background())
+
+	client.CasbinPolicy.Create().SetPtype("p").SetV0("written").SaveX(ctx)
+	if got := servedBy(t, ctx, client); got != "primary" {
+		t.Fatalf("read within window served by %s, want primary", got)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := servedBy(t, ctx, client); got != "r1" {
+		t.Errorf("read after window served by %s, want replica", got)
+	}
+}
+
+func TestMiddlewareStartsSession(t *testing.T) {
+	r, client := newTestRouter(t, Options{})
+	var got string
+	h := r.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		client.CasbinPolicy.Create().SetPtype("p").SetV0("written").SaveX(req.Context())
+		got = servedBy(t, req.Context(), client)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	if got != "primary" {
+		t.Errorf("read after write in request served by %s, want primary", got)
+	}
+}
+
+func TestUnhealthyReplicaFailsOver(t *testing.T) {
+	r1 := openSeeded(t, "r1")
+	r2 := openSeeded(t, "r2")
+	r, client := newTestRouter(t, Options{
+		Replicas:         []Replica{{Name: "r1", DB: r1}, {Name: "r2", DB: r2, Pool: ent.NewConnectionPool().WithMaxOpenConns(5)}},
+		HealthInterval:   time.Hour,
+		FailureThreshold: 2,
+	})
+	ctx := context.Background()
+
+	seen := map[string]bool{}
+	for range 4 {
+		seen[servedBy(t, ctx, client)] = true
+	}
+	if !seen["r1"] || !seen["r2"] {
+		t.Fatalf("reads served by %v, want both replicas", seen)
+	}
+	if stats := r.Stats(); stats[2].MaxOpenConnections != 5 {
+		t.Errorf("r2 max open conns = %d, want per-pool setting 5", stats[2].MaxOpenConnections)
+	}
+
+	r1.Close()
+	r.CheckReplicas(ctx)
+	if !r.Stats()[1].Healthy {
+		t.Fatal("replica should stay healthy below the failure threshold")
+	}
+	r.CheckReplicas(ctx)
+	if r.Stats()[1].Healthy {
+		t.Fatal("replica should be marked down at the failure threshold")
+	}
+	for range 4 {
+		if got := servedBy(t, ctx, client); got != "r2" {
+			t.Fatalf("read served by %s, want the healthy replica", got)
+		}
+	}
+
+	r2.Close()
+	r.CheckReplicas(ctx)
+	r.CheckReplicas(ctx)
+	if got := servedBy(t, ctx, client); got != "primary" {
+		t.Errorf("read served by %s, want primary when no replica is healthy", got)
+	}
+	if err := r.Check(ctx); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+}
+
+func TestNewRequiresPrimary(t *testing.T) {
+	if _, err := New(Options{Dialect: dialect.SQLite}); err != ErrNoPrimary {
+		t.Fatalf("err = %v, want ErrNoPrimary", err)
+	}
+}
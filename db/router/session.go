@@ -0,0 +1,56 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+type sessionKey struct{}
+
+type primaryKey struct{}
+
+// session tracks the last write of a request so that its later reads see it.
+type session struct {
+	lastWrite atomic.Int64 // unix nanoseconds, 0 before the first write
+}
+
+// WithSession returns a context whose reads stay on the primary after the
+// first write through it, for the router's StickyWindow. Reads in contexts
+// without a session always go to replicas.
+func WithSession(ctx context.Context) context.Context {
+	if sessionFrom(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// WithPrimary returns a context whose reads always go to the primary, for
+// reads that must not observe replication lag.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Middleware starts a session per HTTP request.
+func (r *Router) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(w, req.WithContext(WithSession(req.Context())))
+	})
+}
+
+func sessionFrom(ctx context.Context) *session {
+	s, _ := ctx.Value(sessionKey{}).(*session)
+	return s
+}
+
+func forcedPrimary(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryKey{}).(bool)
+	return forced
+}
+
+func markWrite(ctx context.Context) {
+	if s := sessionFrom(ctx); s != nil {
+		s.lastWrite.Store(time.Now().UnixNano())
+	}
+}
@@ -0,0 +1,249 @@
+// Package seed loads declarative fixtures into a database. Fixtures are YAML
+// or JSON files listing rows per table; rows can name themselves with "_ref"
+// and point at each other with "$ref:<name>" values, and loading them twice
+// updates the existing rows instead of duplicating them.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/leeforge/framework/json"
+	"gopkg.in/yaml.v3"
+)
+
+// RefField names a row so other rows can reference it. It is not written.
+const RefField = "_ref"
+
+// RefPrefix marks a string value as a reference: "$ref:alice" resolves to the
+// id of the row named alice, "$ref:alice.email" to its email column.
+const RefPrefix = "$ref:"
+
+// DefaultRefColumn is the column a reference without ".column" resolves to.
+const DefaultRefColumn = "id"
+
+// ErrUnresolvedRef is returned when a reference names a row that no fixture defines.
+var ErrUnresolvedRef = errors.New("seed: unresolved reference")
+
+// Fixture is one seed file.
+type Fixture struct {
+	// Name identifies the fixture in errors, usually the file name.
+	Name string `json:"-"`
+	// Environments restricts the fixture to these environments (e.g. "dev",
+	// "test", "staging"); empty means every environment.
+	Environments []string `json:"environments"`
+	Tables       []Table  `json:"tables"`
+}
+
+// Table holds the rows seeded into one table.
+type Table struct {
+	Table string `json:"table"`
+	// Key lists the columns identifying a row for upserts; default ["id"].
+	Key  []string         `json:"key"`
+	Rows []map[string]any `json:"rows"`
+}
+
+// Record is a seeded row as stored, including database-generated columns.
+type Record map[string]any
+
+// Refs maps row names to their stored records.
+type Refs map[string]Record
+
+// ID returns the id column of the named row, or nil.
+func (r Refs) ID(name string) any {
+	return r[name][DefaultRefColumn]
+}
+
+// Store writes seed rows. Upsert inserts row, or updates the row whose key
+// columns match, and returns the stored row.
+type Store interface {
+	Upsert(ctx context.Context, table string, key []string, row map[string]any) (Record, error)
+}
+
+// Parse decodes a fixture; files ending in .yaml or .yml are YAML, anything
+// else JSON. YAML is converted and decoded by the framework json package, so
+// both formats accept the same documents.
+func Parse(name string, data []byte) (*Fixture, error) {
+	if ext := path.Ext(name); ext == ".yaml" || ext == ".yml" {
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("seed: parse %s: %w", name, err)
+		}
+		converted, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("seed: parse %s: %w", name, err)
+		}
+		data = converted
+	}
+
+	f := &Fixture{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("seed: parse %s: %w", name, err)
+	}
+	f.Name = name
+	for i, t := range f.Tables {
+		if t.Table == "" {
+			return nil, fmt.Errorf("seed: %s: table #%d has no name", name, i)
+		}
+		for _, row := range t.Rows {
+			for col, v := range row {
+				row[col] = normalizeNumber(v)
+			}
+		}
+	}
+	return f, nil
+}
+
+// LoadFS parses every .yaml, .yml and .json file in dir, in file name order.
+func LoadFS(fsys fs.FS, dir string) ([]*Fixture, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("seed: read dir %q: %w", dir, err)
+	}
+	var fixtures []*Fixture
+	for _, entry := range entries {
+		switch path.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("seed: read %s: %w", entry.Name(), err)
+		}
+		f, err := Parse(entry.Name(), data)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, nil
+}
+
+// ForEnv returns the fixtures that apply to env.
+func ForEnv(env string, fixtures ...*Fixture) []*Fixture {
+	var selected []*Fixture
+	for _, f := range fixtures {
+		if len(f.Environments) == 0 || slices.ContainsFunc(f.Environments, func(e string) bool {
+			return strings.EqualFold(e, env)
+		}) {
+			selected = append(selected, f)
+		}
+	}
+	return selected
+}
+
+// Load upserts the fixtures for env into store and returns the named rows.
+// Rows are written in fixture, table and row order, except that a row
+// referencing a row defined later waits until that row is stored.
+func Load(ctx context.Context, store Store, env string, fixtures ...*Fixture) (Refs, error) {
+	type pending struct {
+		fixture string
+		table   *Table
+		row     map[string]any
+	}
+	var queue []pending
+	for _, f := range ForEnv(env, fixtures...) {
+		for i := range f.Tables {
+			for _, row := range f.Tables[i].Rows {
+				queue = append(queue, pending{fixture: f.Name, table: &f.Tables[i], row: row})
+			}
+		}
+	}
+
+	refs := Refs{}
+	for len(queue) > 0 {
+		var deferred []pending
+		var lastErr error
+		for _, p := range queue {
+			row, err := resolve(p.row, refs)
+			if errors.Is(err, ErrUnresolvedRef) {
+				deferred = append(deferred, p)
+				lastErr = fmt.Errorf("seed: %s: table %s: %w", p.fixture, p.table.Table, err)
+				continue
+			}
+			if err != nil {
+				return refs, fmt.Errorf("seed: %s: table %s: %w", p.fixture, p.table.Table, err)
+			}
+			name, _ := row[RefField].(string)
+			delete(row, RefField)
+
+			key := p.table.Key
+			if len(key) == 0 {
+				key = []string{DefaultRefColumn}
+			}
+			for _, col := range key {
+				if _, ok := row[col]; !ok {
+					return refs, fmt.Errorf("seed: %s: table %s: row has no key column %q", p.fixture, p.table.Table, col)
+				}
+			}
+
+			stored, err := store.Upsert(ctx, p.table.Table, key, row)
+			if err != nil {
+				return refs, fmt.Errorf("seed: %s: table %s: %w", p.fixture, p.table.Table, err)
+			}
+			if name != "" {
+				if _, dup := refs[name]; dup {
+					return refs, fmt.Errorf("seed: %s: duplicate ref %q", p.fixture, name)
+				}
+				refs[name] = stored
+			}
+		}
+		if len(deferred) == len(queue) {
+			return refs, lastErr
+		}
+		queue = deferred
+	}
+	return refs, nil
+}
+
+// resolve returns a copy of row with references replaced by stored values.
+func resolve(row map[string]any, refs Refs) (map[string]any, error) {
+	out := make(map[string]any, len(row))
+	for _, col := range sortedColumns(row) {
+		v := row[col]
+		s, ok := v.(string)
+		if !ok || !strings.HasPrefix(s, RefPrefix) {
+			out[col] = v
+			continue
+		}
+		name, column, found := strings.Cut(strings.TrimPrefix(s, RefPrefix), ".")
+		if !found {
+			column = DefaultRefColumn
+		}
+		rec, ok := refs[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnresolvedRef, s)
+		}
+		value, ok := rec[column]
+		if !ok {
+			return nil, fmt.Errorf("ref %s: row has no column %q", s, column)
+		}
+		out[col] = value
+	}
+	return out, nil
+}
+
+// normalizeNumber turns integral float64 values, which JSON decoding
+// produces for every number, back into int64.
+func normalizeNumber(v any) any {
+	if f, ok := v.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return int64(f)
+	}
+	return v
+}
+
+// sortedColumns returns the columns of row in a stable order.
+func sortedColumns(row map[string]any) []string {
+	return slices.Sorted(maps.Keys(row))
+}
@@ -0,0 +1,158 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"entgo.io/ent/dialect"
+	"github.com/google/uuid"
+
+	_ "modernc.org/sqlite"
+)
+
+var testFS = fstest.MapFS{
+	// users reference roles defined in a later file
+	"seeds/01_users.yaml": {Data: []byte(`
+tables:
+  - table: users
+    key: [email]
+    rows:
+      - _ref: alice
+        email: alice@example.com
+        name: Alice
+        role_id: $ref:admin
+`)},
+	"seeds/02_roles.json": {Data: []byte(`{
+  "tables": [
+    {"table": "roles", "key": ["code"], "rows": [
+      {"_ref": "admin", "code": "admin", "name": "Administrator", "level": 10}
+    ]}
+  ]
+}`)},
+	"seeds/03_demo.yaml": {Data: []byte(`
+environments: [dev, staging]
+tables:
+  - table: users
+    key: [email]
+    rows:
+      - email: demo@example.com
+        name: Demo
+        role_id: $ref:admin
+`)},
+	"seeds/README.md": {Data: []byte("ignored")},
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:seed-"+uuid.NewString()+"?mode=memory&cache=shared&_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`
+CREATE TABLE roles (id INTEGER PRIMARY KEY AUTOINCREMENT, code TEXT NOT NULL, name TEXT, level INTEGER);
+CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, email TEXT NOT NULL, name TEXT,
+	role_id INTEGER NOT NULL REFERENCES roles(id));`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func loadTestFS(t *testing.T) []*Fixture {
+	t.Helper()
+	fixtures, err := LoadFS(testFS, "seeds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) != 3 {
+		t.Fatalf("loaded %d fixtures, want 3", len(fixtures))
+	}
+	return fixtures
+}
+
+func TestLoadSQLIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	store, err := NewSQLStore(db, dialect.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := loadTestFS(t)
+
+	refs, err := Load(ctx, store, "test", fixtures...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refs["alice"]["role_id"] != refs.ID("admin") {
+		t.Errorf("alice.role_id = %v, want admin id %v", refs["alice"]["role_id"], refs.ID("admin"))
+	}
+	if refs["admin"]["level"] != int64(10) {
+		t.Errorf("admin.level = %#v, want int64 10", refs["admin"]["level"])
+	}
+
+	fixtures[1].Tables[0].Rows[0]["name"] = "Admins"
+	if _, err := Load(ctx, store, "test", fixtures...); err != nil {
+		t.Fatal(err)
+	}
+	var roles, users int
+	var name string
+	db.QueryRow("SELECT COUNT(*), MAX(name) FROM roles").Scan(&roles, &name)
+	db.QueryRow("SELECT COUNT(*) FROM users").Scan(&users)
+	if roles != 1 || users != 1 || name != "Admins" {
+		t.Errorf("after reload roles = %d (%s), users = %d, want one updated role and one user", roles, name, users)
+	}
+
+	if _, err := Load(ctx, store, "staging", fixtures...); err != nil {
+		t.Fatal(err)
+	}
+	db.QueryRow("SELECT COUNT(*) FROM users").Scan(&users)
+	if users != 2 {
+		t.Errorf("staging users = %d, want 2", users)
+	}
+}
+
+func TestLoadUnresolvedRef(t *testing.T) {
+	f, err := Parse("bad.yaml", []byte("tables:\n  - table: users\n    key: [email]\n    rows:\n      - {email: x, role_id: $ref:missing}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, _ := LoadMock(t)
+	_, err = Load(context.Background(), NewMockStore(db), TestEnv, f)
+	if !errors.Is(err, ErrUnresolvedRef) {
+		t.Fatalf("err = %v, want ErrUnresolvedRef", err)
+	}
+}
+
+func TestLoadTxRollsBack(t *testing.T) {
+	db := openTestDB(t)
+	fixtures := loadTestFS(t)
+
+	t.Run("seeded", func(t *testing.T) {
+		tx, refs := LoadTx(t, db, dialect.SQLite, fixtures...)
+		var email string
+		if err := tx.QueryRow("SELECT email FROM users WHERE id = ?", refs.ID("alice")).Scan(&email); err != nil || email != "alice@example.com" {
+			t.Fatalf("email = %q, err = %v", email, err)
+		}
+	})
+
+	var users int
+	db.QueryRow("SELECT COUNT(*) FROM users").Scan(&users)
+	if users != 0 {
+		t.Errorf("users after test = %d, want rolled back", users)
+	}
+}
+
+func TestLoadMock(t *testing.T) {
+	db, refs := LoadMock(t, loadTestFS(t)...)
+	if got := db.Find("users", nil); len(got) != 1 {
+		t.Fatalf("mock users = %d, want 1 (demo is dev/staging only)", len(got))
+	}
+	alice := db.FindOne("users", func(r map[string]any) bool { return r["email"] == "alice@example.com" })
+	if alice == nil || alice["role_id"] != refs.ID("admin") || refs.ID("admin") != int64(1) {
+		t.Errorf("alice = %v, admin id = %v", alice, refs.ID("admin"))
+	}
+}
@@ -0,0 +1,186 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"entgo.io/ent/dialect"
+	fwtesting "github.com/leeforge/framework/testing"
+)
+
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Executor is satisfied by *sql.DB, *sql.Tx and *sql.Conn.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// SQLStore upserts rows with plain SQL: it looks the row up by its key
+// columns, updates or inserts it, and reads it back so generated columns such
+// as auto-increment ids are available to references. Key columns need no
+// unique constraint.
+type SQLStore struct {
+	exec    Executor
+	dialect string
+}
+
+// NewSQLStore creates a store for the given ent dialect (dialect.Postgres,
+// dialect.MySQL or dialect.SQLite). Pass a *sql.Tx to load atomically.
+func NewSQLStore(exec Executor, driver string) (*SQLStore, error) {
+	switch driver {
+	case dialect.Postgres, dialect.MySQL, dialect.SQLite:
+	default:
+		return nil, fmt.Errorf("seed: unsupported dialect %q", driver)
+	}
+	return &SQLStore{exec: exec, dialect: driver}, nil
+}
+
+// Upsert implements Store.
+func (s *SQLStore) Upsert(ctx context.Context, table string, key []string, row map[string]any) (Record, error) {
+	columns := sortedColumns(row)
+	for _, ident := range append([]string{table}, columns...) {
+		if !identPattern.MatchString(ident) {
+			return nil, fmt.Errorf("invalid identifier %q", ident)
+		}
+	}
+
+	where, whereArgs := s.keyFilter(key, row, 1)
+	existing, err := s.selectOne(ctx, table, where, whereArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]any, 0, len(columns)+len(key))
+	if existing == nil {
+		placeholders := make([]string, len(columns))
+		for i, col := range columns {
+			placeholders[i] = s.placeholder(i + 1)
+			args = append(args, row[col])
+		}
+		_, err = s.exec.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+			table, strings.Join(columns, ", "), strings.Join(placeholders, ", ")), args...)
+	} else {
+		sets := make([]string, len(columns))
+		for i, col := range columns {
+			sets[i] = col + " = " + s.placeholder(i+1)
+			args = append(args, row[col])
+		}
+		cond, condArgs := s.keyFilter(key, row, len(columns)+1)
+		_, err = s.exec.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+			table, strings.Join(sets, ", "), cond), append(args, condArgs...)...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.selectOne(ctx, table, where, whereArgs)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, fmt.Errorf("row vanished after upsert")
+	}
+	return stored, nil
+}
+
+func (s *SQLStore) keyFilter(key []string, row map[string]any, first int) (string, []any) {
+	conds := make([]string, len(key))
+	args := make([]any, len(key))
+	for i, col := range key {
+		conds[i] = col + " = " + s.placeholder(first+i)
+		args[i] = row[col]
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+func (s *SQLStore) selectOne(ctx context.Context, table, where string, args []any) (Record, error) {
+	rows, err := s.exec.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE %s", table, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	if rows.Next() {
+		return nil, fmt.Errorf("key %s matches several rows", where)
+	}
+	rec := make(Record, len(columns))
+	for i, col := range columns {
+		if b, ok := values[i].([]byte); ok {
+			rec[col] = string(b)
+		} else {
+			rec[col] = values[i]
+		}
+	}
+	return rec, rows.Err()
+}
+
+func (s *SQLStore) placeholder(n int) string {
+	if s.dialect == dialect.Postgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// MockStore upserts rows into a testing.MockDB. Rows without an id get the
+// next integer id of their table.
+type MockStore struct {
+	db  *fwtesting.MockDB
+	mu  sync.Mutex
+	ids map[string]int64
+}
+
+// NewMockStore creates a store writing to db.
+func NewMockStore(db *fwtesting.MockDB) *MockStore {
+	return &MockStore{db: db, ids: make(map[string]int64)}
+}
+
+// Upsert implements Store.
+func (s *MockStore) Upsert(_ context.Context, table string, key []string, row map[string]any) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	match := func(rec map[string]any) bool {
+		for _, col := range key {
+			if fmt.Sprint(rec[col]) != fmt.Sprint(row[col]) {
+				return false
+			}
+		}
+		return true
+	}
+	rec := make(map[string]any)
+	if existing := s.db.FindOne(table, match); existing != nil {
+		for col, v := range existing {
+			rec[col] = v
+		}
+		s.db.Delete(table, match)
+	}
+	for col, v := range row {
+		rec[col] = v
+	}
+	if _, ok := rec[DefaultRefColumn]; !ok {
+		s.ids[table]++
+		rec[DefaultRefColumn] = s.ids[table]
+	}
+	s.db.Insert(table, rec)
+	return Record(rec), nil
+}
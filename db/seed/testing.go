@@ -0,0 +1,45 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	fwtesting "github.com/leeforge/framework/testing"
+)
+
+// TestEnv is the environment the test helpers load fixtures for.
+const TestEnv = "test"
+
+// LoadTx loads the fixtures for TestEnv into db inside a transaction that is
+// rolled back when the test ends, so every test sees the same data and leaves
+// nothing behind. The test must run its queries through the returned tx.
+func LoadTx(t testing.TB, db *sql.DB, driver string, fixtures ...*Fixture) (*sql.Tx, Refs) {
+	t.Helper()
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("seed: begin transaction: %v", err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+
+	store, err := NewSQLStore(tx, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err := Load(context.Background(), store, TestEnv, fixtures...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx, refs
+}
+
+// LoadMock loads the fixtures for TestEnv into a new MockDB.
+func LoadMock(t testing.TB, fixtures ...*Fixture) (*fwtesting.MockDB, Refs) {
+	t.Helper()
+	db := fwtesting.NewMockDB()
+	refs, err := Load(context.Background(), NewMockStore(db), TestEnv, fixtures...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, refs
+}
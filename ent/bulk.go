@@ -0,0 +1,300 @@
+package ent
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"entgo.io/ent/dialect"
+)
+
+const defaultBulkBatchSize = 1000
+
+// OnConflict 批量插入的冲突处理
+type OnConflict struct {
+	// Columns 冲突目标列（唯一约束），MySQL 忽略该字段
+	Columns []string
+	// Update 冲突时以新值覆盖的列，为空时 DO NOTHING（MySQL 为 INSERT IGNORE）
+	Update []string
+}
+
+// CopyFunc 以 COPY 协议在 tx 内写入一批行，返回写入行数
+type CopyFunc func(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]any) (int64, error)
+
+// BulkCreateOptions 批量插入配置
+type BulkCreateOptions struct {
+	Table string
+	// Columns 插入列，为空时取第一行的全部键（按名称排序）
+	Columns []string
+	// BatchSize 每批行数，默认 1000；超过绑定参数上限时自动缩小
+	BatchSize int
+	// Dialect ent 方言，决定占位符、标识符引号与冲突语法，默认 PostgreSQL
+	Dialect string
+	// OnConflict 冲突处理，nil 时冲突即报错
+	OnConflict *OnConflict
+	// Copy 非 nil 时改用 COPY 写入（仅 PostgreSQL，不支持 OnConflict），
+	// lib/pq 可直接使用 CopyIn，pgx 需包装 Conn.CopyFrom
+	Copy CopyFunc
+	// ContinueOnError 某批失败后继续写入后续批次，默认在第一个失败批次处停止
+	ContinueOnError bool
+}
+
+// BatchError 单个批次的失败信息，批次在独立事务中执行，失败时整批回滚
+type BatchError struct {
+	// Batch 批次序号，从 0 开始
+	Batch int
+	// Offset 批次第一行在输入中的下标
+	Offset int
+	// Size 批次行数
+	Size int
+	Err  error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch %d (rows %d-%d): %v", e.Batch, e.Offset, e.Offset+e.Size-1, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BulkCreateResult 批量插入结果
+type BulkCreateResult struct {
+	// Inserted 受影响行数；冲突跳过的行不计入，MySQL 更新的行计为 2
+	Inserted int64
+	// Batches 已执行的批次数
+	Batches int
+	// Failed 失败的批次
+	Failed []*BatchError
+}
+
+// BulkCreate 批量插入：按批生成多行 INSERT，整批大小相同的批次复用同一个
+// 预编译语句，每批在独立事务中执行。失败的批次记录在结果中，返回的错误为
+// 各 BatchError 的合并
+//
+//	res, err := ext.BulkCreate(ctx, rows, ent.BulkCreateOptions{
+//		Table:      "users",
+//		OnConflict: &ent.OnConflict{Columns: []string{"email"}, Update: []string{"name"}},
+//	})
+func (e *QueryExtension) BulkCreate(ctx context.Context, rows []map[string]any, opts BulkCreateOptions) (*BulkCreateResult, error) {
+	result := &BulkCreateResult{}
+	if len(rows) == 0 {
+		return result, nil
+	}
+	if opts.Dialect == "" {
+		opts.Dialect = dialect.Postgres
+	}
+	columns := opts.Columns
+	if len(columns) == 0 {
+		for col := range rows[0] {
+			columns = append(columns, col)
+		}
+		sort.Strings(columns)
+	}
+	if err := validateBulkOptions(opts, columns); err != nil {
+		return nil, err
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBulkBatchSize
+	}
+	if opts.Copy == nil {
+		batchSize = min(batchSize, bindParamLimit(opts.Dialect)/len(columns))
+	}
+
+	// 完整批次的语句相同，预编译一次
+	var stmt *sql.Stmt
+	if opts.Copy == nil && len(rows) >= batchSize {
+		var err error
+		stmt, err = e.db.PrepareContext(ctx, buildBulkInsertSQL(opts, columns, batchSize))
+		if err != nil {
+			return nil, fmt.Errorf("ent: prepare bulk insert: %w", err)
+		}
+		defer stmt.Close()
+	}
+
+	var errs []error
+	for batch, offset := 0, 0; offset < len(rows); batch, offset = batch+1, offset+batchSize {
+		if err := ctx.Err(); err != nil {
+			return result, errors.Join(append(errs, err)...)
+		}
+		chunk := rows[offset:min(offset+batchSize, len(rows))]
+		n, err := e.insertBatch(ctx, opts, columns, chunk, stmt, batchSize)
+		result.Batches++
+		if err != nil {
+			batchErr := &BatchError{Batch: batch, Offset: offset, Size: len(chunk), Err: err}
+			result.Failed = append(result.Failed, batchErr)
+			errs = append(errs, batchErr)
+			if !opts.ContinueOnError {
+				break
+			}
+			continue
+		}
+		result.Inserted += n
+	}
+	return result, errors.Join(errs...)
+}
+
+// insertBatch 在独立事务中写入一批行
+func (e *QueryExtension) insertBatch(ctx context.Context, opts BulkCreateOptions, columns []string, chunk []map[string]any, stmt *sql.Stmt, batchSize int) (n int64, err error) {
+	values := make([][]any, len(chunk))
+	for i, row := range chunk {
+		if missing := missingColumn(row, columns); missing != "" {
+			return 0, fmt.Errorf("row %d: %w: %s", i, ErrMissingColumn, missing)
+		}
+		values[i] = make([]any, len(columns))
+		for c, col := range columns {
+			values[i][c] = row[col]
+		}
+	}
+
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if opts.Copy != nil {
+		n, err = opts.Copy(ctx, tx, opts.Table, columns, values)
+		if err != nil {
+			return 0, err
+		}
+		return n, tx.Commit()
+	}
+
+	args := make([]any, 0, len(chunk)*len(columns))
+	for _, v := range values {
+		args = append(args, v...)
+	}
+	var res sql.Result
+	if stmt != nil && len(chunk) == batchSize {
+		res, err = tx.StmtContext(ctx, stmt).ExecContext(ctx, args...)
+	} else {
+		res, err = tx.ExecContext(ctx, buildBulkInsertSQL(opts, columns, len(chunk)), args...)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if n, err = res.RowsAffected(); err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+func validateBulkOptions(opts BulkCreateOptions, columns []string) error {
+	if !validTable(opts.Table) {
+		return fmt.Errorf("ent: bulk insert: invalid identifier %q", opts.Table)
+	}
+	idents := slices.Clone(columns)
+	if c := opts.OnConflict; c != nil {
+		idents = append(append(idents, c.Columns...), c.Update...)
+		if opts.Dialect != dialect.MySQL && len(c.Columns) == 0 && len(c.Update) > 0 {
+			return fmt.Errorf("ent: bulk insert: ON CONFLICT DO UPDATE requires conflict columns")
+		}
+	}
+	for _, ident := range idents {
+		if !identifierPattern.MatchString(ident) {
+			return fmt.Errorf("ent: bulk insert: invalid identifier %q", ident)
+		}
+	}
+	if opts.Copy != nil {
+		if opts.Dialect != dialect.Postgres {
+			return fmt.Errorf("ent: bulk insert: COPY requires postgres, got %s", opts.Dialect)
+		}
+		if opts.OnConflict != nil {
+			return fmt.Errorf("ent: bulk insert: COPY does not support conflict handling")
+		}
+	}
+	return nil
+}
+
+// buildBulkInsertSQL 构建 rowCount 行的 INSERT 语句
+func buildBulkInsertSQL(opts BulkCreateOptions, columns []string, rowCount int) string {
+	quote := quoteIdent
+	if opts.Dialect == dialect.MySQL {
+		quote = func(name string) string { return "`" + name + "`" }
+	}
+	quoteAll := func(names []string) string {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = quote(name)
+		}
+		return strings.Join(quoted, ", ")
+	}
+
+	var b strings.Builder
+	b.WriteString("INSERT ")
+	if opts.Dialect == dialect.MySQL && opts.OnConflict != nil && len(opts.OnConflict.Update) == 0 {
+		b.WriteString("IGNORE ")
+	}
+	// schema.table 分别引用
+	table := strings.Split(opts.Table, ".")
+	for i, part := range table {
+		table[i] = quote(part)
+	}
+	fmt.Fprintf(&b, "INTO %s (%s) VALUES ", strings.Join(table, "."), quoteAll(columns))
+
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	for r := range rowCount {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(row)
+	}
+
+	if c := opts.OnConflict; c != nil {
+		switch {
+		case opts.Dialect == dialect.MySQL:
+			if len(c.Update) > 0 {
+				sets := make([]string, len(c.Update))
+				for i, col := range c.Update {
+					sets[i] = fmt.Sprintf("%s = VALUES(%s)", quote(col), quote(col))
+				}
+				fmt.Fprintf(&b, " ON DUPLICATE KEY UPDATE %s", strings.Join(sets, ", "))
+			}
+		default:
+			b.WriteString(" ON CONFLICT")
+			if len(c.Columns) > 0 {
+				fmt.Fprintf(&b, " (%s)", quoteAll(c.Columns))
+			}
+			if len(c.Update) == 0 {
+				b.WriteString(" DO NOTHING")
+			} else {
+				sets := make([]string, len(c.Update))
+				for i, col := range c.Update {
+					sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", quote(col), quote(col))
+				}
+				fmt.Fprintf(&b, " DO UPDATE SET %s", strings.Join(sets, ", "))
+			}
+		}
+	}
+	return Rebind(PlaceholderFor(opts.Dialect), b.String())
+}
+
+// CopyIn 按 lib/pq 的约定执行 COPY：预编译 COPY ... FROM STDIN，逐行 Exec
+// 缓冲数据，最后一次无参数 Exec 提交数据
+func CopyIn(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]any) (int64, error) {
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("COPY %s (%s) FROM STDIN", quoteIdent(table), joinIdents(columns, "")))
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return 0, err
+	}
+	return int64(len(rows)), nil
+}
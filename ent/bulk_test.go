@@ -0,0 +1,201 @@
+package ent
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"github.com/google/uuid"
+)
+
+func openBulkTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:bulk-"+uuid.NewString()+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("CREATE TABLE users (email TEXT PRIMARY KEY, name TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func userRows(from, to int, name string) []map[string]any {
+	rows := make([]map[string]any, 0, to-from)
+	for i := from; i < to; i++ {
+		rows = append(rows, map[string]any{"email": fmt.Sprintf("u%d@example.com", i), "name": name})
+	}
+	return rows
+}
+
+func countUsers(t *testing.T, db *sql.DB, where string) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE " + where).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestBulkCreateBatches(t *testing.T) {
+	db := openBulkTestDB(t)
+	ext := NewQueryExtension(db)
+	ctx := context.Background()
+
+	res, err := ext.BulkCreate(ctx, userRows(0, 25, "a"), BulkCreateOptions{Table: "users", BatchSize: 10, Dialect: dialect.SQLite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Inserted != 25 || res.Batches != 3 || len(res.Failed) != 0 {
+		t.Errorf("result = %+v, want 25 rows in 3 batches", res)
+	}
+
+	// DO NOTHING skips existing rows
+	res, err = ext.BulkCreate(ctx, userRows(20, 30, "b"), BulkCreateOptions{
+		Table: "users", Dialect: dialect.SQLite,
+		OnConflict: &OnConflict{Columns: []string{"email"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Inserted != 5 || countUsers(t, db, "name = 'a'") != 25 {
+		t.Errorf("DO NOTHING inserted %d, want 5 without touching existing rows", res.Inserted)
+	}
+
+	// DO UPDATE overwrites the listed columns
+	if _, err := ext.BulkCreate(ctx, userRows(0, 10, "c"), BulkCreateOptions{
+		Table: "users", Dialect: dialect.SQLite,
+		OnConflict: &OnConflict{Columns: []string{"email"}, Update: []string{"name"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n := countUsers(t, db, "name = 'c'"); n != 10 {
+		t.Errorf("updated rows = %d, want 10", n)
+	}
+}
+
+func TestBulkCreateCapsBindParamsPerDialect(t *testing.T) {
+	db := openBulkTestDB(t)
+	rows := userRows(0, 20000, "a")
+
+	// 2 columns x 20000 rows exceeds SQLite's 32766 bind parameters
+	res, err := NewQueryExtension(db).BulkCreate(context.Background(), rows, BulkCreateOptions{
+		Table: "main.users", BatchSize: len(rows), Dialect: dialect.SQLite,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Inserted != 20000 || res.Batches != 2 {
+		t.Errorf("result = %+v, want 20000 rows in 2 batches", res)
+	}
+	if got := bindParamLimit(dialect.Postgres); got != 65535 {
+		t.Errorf("postgres limit = %d", got)
+	}
+
+	for _, table := range []string{"users;", "a.b.c", ""} {
+		if _, err := NewQueryExtension(db).BulkCreate(context.Background(), rows[:1], BulkCreateOptions{Table: table, Dialect: dialect.SQLite}); err == nil {
+			t.Errorf("table %q: want invalid identifier error", table)
+		}
+	}
+}
+
+func TestBulkCreateReportsFailedBatches(t *testing.T) {
+	db := openBulkTestDB(t)
+	ext := NewQueryExtension(db)
+	rows := userRows(0, 30, "a")
+	rows[15]["name"] = nil // NOT NULL violation in the second batch
+
+	res, err := ext.BulkCreate(context.Background(), rows, BulkCreateOptions{
+		Table: "users", BatchSize: 10, Dialect: dialect.SQLite, ContinueOnError: true,
+	})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Batch != 1 || batchErr.Offset != 10 || batchErr.Size != 10 {
+		t.Fatalf("err = %v, want batch 1 at offset 10", err)
+	}
+	if res.Inserted != 20 || res.Batches != 3 || len(res.Failed) != 1 {
+		t.Errorf("result = %+v, want 20 rows inserted and one failed batch", res)
+	}
+	if n := countUsers(t, db, "1 = 1"); n != 20 {
+		t.Errorf("rows = %d, want failed batch rolled back", n)
+	}
+
+	db = openBulkTestDB(t)
+	res, err = NewQueryExtension(db).BulkCreate(context.Background(), rows, BulkCreateOptions{
+		Table: "users", BatchSize: 10, Dialect: dialect.SQLite,
+	})
+	if err == nil || res.Batches != 2 || res.Inserted != 10 {
+		t.Errorf("without ContinueOnError: result = %+v, err = %v; want stop after the failed batch", res, err)
+	}
+
+	delete(rows[15], "name")
+	rows[15]["email"] = "missing@example.com"
+	if _, err := NewQueryExtension(openBulkTestDB(t)).BulkCreate(context.Background(), rows, BulkCreateOptions{
+		Table: "users", BatchSize: 10, Dialect: dialect.SQLite,
+	}); !errors.Is(err, ErrMissingColumn) {
+		t.Errorf("err = %v, want ErrMissingColumn", err)
+	}
+}
+
+func TestBuildBulkInsertSQL(t *testing.T) {
+	columns := []string{"email", "name"}
+	tests := []struct {
+		opts BulkCreateOptions
+		want string
+	}{
+		{
+			BulkCreateOptions{Table: "users", Dialect: dialect.Postgres, OnConflict: &OnConflict{Columns: []string{"email"}, Update: []string{"name"}}},
+			`INSERT INTO "users" ("email", "name") VALUES ($1, $2), ($3, $4) ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name"`,
+		},
+		{
+			BulkCreateOptions{Table: "app.users", Dialect: dialect.Postgres},
+			`INSERT INTO "app"."users" ("email", "name") VALUES ($1, $2), ($3, $4)`,
+		},
+		{
+			BulkCreateOptions{Table: "users", Dialect: dialect.MySQL, OnConflict: &OnConflict{}},
+			"INSERT IGNORE INTO `users` (`email`, `name`) VALUES (?, ?), (?, ?)",
+		},
+		{
+			BulkCreateOptions{Table: "users", Dialect: dialect.MySQL, OnConflict: &OnConflict{Update: []string{"name"}}},
+			"INSERT INTO `users` (`email`, `name`) VALUES (?, ?), (?, ?) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)",
+		},
+	}
+	for _, tt := range tests {
+		if got := buildBulkInsertSQL(tt.opts, columns, 2); got != tt.want {
+			t.Errorf("got  %s\nwant %s", got, tt.want)
+		}
+	}
+}
+
+func TestBulkCreateCopy(t *testing.T) {
+	db := openBulkTestDB(t)
+	ext := NewQueryExtension(db)
+
+	var batches [][][]any
+	copyFn := func(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]any) (int64, error) {
+		if table != "users" || fmt.Sprint(columns) != "[email name]" {
+			t.Errorf("copy into %s %v", table, columns)
+		}
+		batches = append(batches, rows)
+		return int64(len(rows)), nil
+	}
+	res, err := ext.BulkCreate(context.Background(), userRows(0, 5, "a"), BulkCreateOptions{Table: "users", BatchSize: 3, Copy: copyFn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Inserted != 5 || len(batches) != 2 || fmt.Sprint(batches[1][1]) != "[u4@example.com a]" {
+		t.Errorf("result = %+v, batches = %v", res, batches)
+	}
+
+	if _, err := ext.BulkCreate(context.Background(), userRows(0, 1, "a"), BulkCreateOptions{
+		Table: "users", Copy: copyFn, OnConflict: &OnConflict{Columns: []string{"email"}},
+	}); err == nil {
+		t.Error("COPY with conflict handling should be rejected")
+	}
+	if _, err := ext.BulkCreate(context.Background(), userRows(0, 1, "a"), BulkCreateOptions{Table: "users; DROP TABLE users"}); err == nil {
+		t.Error("invalid table name should be rejected")
+	}
+}
@@ -15,6 +15,9 @@ import (
 	"github.com/leeforge/framework/ent/casbinpolicy"
 	"github.com/leeforge/framework/ent/media"
 	"github.com/leeforge/framework/ent/mediaformat"
+	"github.com/leeforge/framework/ent/notificationdelivery"
+	"github.com/leeforge/framework/ent/outboxmessage"
+	"github.com/leeforge/framework/ent/webauthncredential"
 )
 
 // ent aliases to avoid import conflicts in user's code.
@@ -75,9 +78,12 @@ var (
 func checkColumn(t, c string) error {
 	initCheck.Do(func() {
 		columnCheck = sql.NewColumnCheck(map[string]func(string) bool{
-			casbinpolicy.Table: casbinpolicy.ValidColumn,
-			media.Table:        media.ValidColumn,
-			mediaformat.Table:  mediaformat.ValidColumn,
+			casbinpolicy.Table:         casbinpolicy.ValidColumn,
+			media.Table:                media.ValidColumn,
+			mediaformat.Table:          mediaformat.ValidColumn,
+			notificationdelivery.Table: notificationdelivery.ValidColumn,
+			outboxmessage.Table:        outboxmessage.ValidColumn,
+			webauthncredential.Table:   webauthncredential.ValidColumn,
 		})
 	})
 	return columnCheck(t, c)
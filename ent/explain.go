@@ -0,0 +1,565 @@
+package ent
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"entgo.io/ent/dialect"
+)
+
+// ErrExplainUnavailable IndexAnalyzer 未配置数据库连接
+var ErrExplainUnavailable = errors.New("ent: index analyzer has no database")
+
+// PlanIssueKind 执行计划问题类型
+type PlanIssueKind string
+
+const (
+	// PlanSeqScan 全表扫描
+	PlanSeqScan PlanIssueKind = "seq_scan"
+	// PlanSort 无法利用索引的排序
+	PlanSort PlanIssueKind = "sort"
+	// PlanTempTable 使用临时表，或排序溢出到磁盘
+	PlanTempTable PlanIssueKind = "temp_table"
+)
+
+// PlanIssue 执行计划中的一处问题
+type PlanIssue struct {
+	Kind  PlanIssueKind
+	Table string
+	// Columns 过滤列（等值列在前）或排序、分组列
+	Columns []string
+	// Rows 扫描或排序的行数，Analyzed 时为实际值，否则为估计值
+	Rows float64
+	// Share 节点占整条语句实际耗时（Analyzed）或估计代价的比例，0~1
+	Share  float64
+	Detail string
+}
+
+// QueryPlan 解析后的执行计划
+type QueryPlan struct {
+	Dialect string
+	// Analyzed 是否经 EXPLAIN ANALYZE 实际执行
+	Analyzed bool
+	Issues   []PlanIssue
+	// Raw EXPLAIN 原始输出
+	Raw string
+}
+
+// IndexSuggestion 索引建议
+type IndexSuggestion struct {
+	Table   string
+	Columns []string
+	// Statement 建议执行的 CREATE INDEX 语句
+	Statement string
+	Reason    string
+	// Impact 建立索引后预计消除的代价比例，0~1
+	Impact float64
+	// EstimatedSaving 按平均耗时估算的单次节省时间，由 QueryOptimizer 填充
+	EstimatedSaving time.Duration
+	// Query 触发建议的语句（脱敏后），由 QueryOptimizer 填充
+	Query string
+}
+
+// Explain 执行 EXPLAIN 并解析执行计划。PostgreSQL 使用 EXPLAIN (FORMAT JSON)，
+// WithAnalyze(true) 时对无副作用的 SELECT 改用 EXPLAIN (ANALYZE, FORMAT JSON)；
+// MySQL 使用 EXPLAIN FORMAT=JSON；SQLite 使用 EXPLAIN QUERY PLAN。args 为语句原始参数
+func (a *IndexAnalyzer) Explain(ctx context.Context, query string, args ...any) (*QueryPlan, error) {
+	if a.db == nil {
+		return nil, ErrExplainUnavailable
+	}
+	switch a.dialect {
+	case dialect.Postgres:
+		var raw string
+		if a.analyze && analyzable(query) {
+			var err error
+			if raw, err = a.explainAnalyze(ctx, query, args); err != nil {
+				return nil, fmt.Errorf("ent: explain: %w", err)
+			}
+		} else if err := a.db.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&raw); err != nil {
+			return nil, fmt.Errorf("ent: explain: %w", err)
+		}
+		return parsePostgresPlan(raw, query)
+	case dialect.MySQL:
+		var raw string
+		if err := a.db.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+query, args...).Scan(&raw); err != nil {
+			return nil, fmt.Errorf("ent: explain: %w", err)
+		}
+		return parseMySQLPlan(raw, query)
+	case dialect.SQLite:
+		rows, err := a.db.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("ent: explain: %w", err)
+		}
+		defer rows.Close()
+		var details []string
+		for rows.Next() {
+			var id, parent, notUsed int
+			var detail string
+			if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+				return nil, fmt.Errorf("ent: explain: %w", err)
+			}
+			details = append(details, detail)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("ent: explain: %w", err)
+		}
+		return parseSQLitePlan(details, query), nil
+	default:
+		return nil, fmt.Errorf("ent: explain: unsupported dialect %q", a.dialect)
+	}
+}
+
+// sideEffectPattern 会加锁、写入或改变状态的 SELECT：行锁子句、SELECT INTO
+// 与常见的有副作用的函数
+var sideEffectPattern = regexp.MustCompile(`(?i)\bFOR\s+(?:NO\s+KEY\s+)?(?:UPDATE|SHARE|KEY\s+SHARE)\b|\bINTO\b|\b(?:nextval|setval|pg_advisory\w*|pg_notify|dblink\w*)\s*\(`)
+
+// analyzable 语句能否安全地实际执行：只有不加锁、不写入的 SELECT
+func analyzable(query string) bool {
+	return sqlOperation(query) == "SELECT" && !sideEffectPattern.MatchString(query)
+}
+
+// explainAnalyze 在只读事务中执行 EXPLAIN ANALYZE 并回滚，漏判的写入会被数据库拒绝
+func (a *IndexAnalyzer) explainAnalyze(ctx context.Context, query string, args []any) (string, error) {
+	tx, err := a.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	var raw string
+	err = tx.QueryRowContext(ctx, "EXPLAIN (ANALYZE, FORMAT JSON) "+query, args...).Scan(&raw)
+	return raw, err
+}
+
+// Analyze 对语句执行 EXPLAIN，并根据全表扫描、排序、临时表等问题给出索引建议
+func (a *IndexAnalyzer) Analyze(ctx context.Context, query string, args ...any) ([]IndexSuggestion, error) {
+	plan, err := a.Explain(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return a.Suggest(plan), nil
+}
+
+// Suggest 根据执行计划生成索引建议，同一张表的过滤列与排序列合并为一个
+// 复合索引（等值列、范围列、排序列依次排列）；已通过 AddTable 登记且前缀
+// 相同的索引不再重复建议
+func (a *IndexAnalyzer) Suggest(plan *QueryPlan) []IndexSuggestion {
+	type tableIssues struct {
+		filter, order []string
+		reasons       []string
+		impact        float64
+	}
+	byTable := map[string]*tableIssues{}
+	var tables []string
+	for _, issue := range plan.Issues {
+		if issue.Table == "" || len(issue.Columns) == 0 {
+			continue
+		}
+		ti := byTable[issue.Table]
+		if ti == nil {
+			ti = &tableIssues{}
+			byTable[issue.Table] = ti
+			tables = append(tables, issue.Table)
+		}
+		switch issue.Kind {
+		case PlanSeqScan:
+			ti.filter = appendUnique(ti.filter, issue.Columns...)
+			ti.reasons = append(ti.reasons, fmt.Sprintf("全表扫描（%.0f 行）", issue.Rows))
+		case PlanSort:
+			ti.order = appendUnique(ti.order, issue.Columns...)
+			ti.reasons = append(ti.reasons, "排序未使用索引")
+		case PlanTempTable:
+			ti.order = appendUnique(ti.order, issue.Columns...)
+			ti.reasons = append(ti.reasons, "使用临时表或磁盘排序")
+		}
+		ti.impact = min(1, ti.impact+issue.Share)
+	}
+
+	var suggestions []IndexSuggestion
+	for _, table := range tables {
+		ti := byTable[table]
+		columns := appendUnique(slices.Clone(ti.filter), ti.order...)
+		if len(columns) > 4 {
+			columns = columns[:4]
+		}
+		if !validColumn(table) || slices.ContainsFunc(columns, func(c string) bool { return !identifierPattern.MatchString(c) }) {
+			continue
+		}
+		if a.hasIndex(table, columns) {
+			continue
+		}
+		name := "idx_" + strings.ReplaceAll(table, ".", "_") + "_" + strings.Join(columns, "_")
+		suggestions = append(suggestions, IndexSuggestion{
+			Table:     table,
+			Columns:   columns,
+			Statement: fmt.Sprintf("CREATE INDEX %s ON %s (%s)", name, table, strings.Join(columns, ", ")),
+			Reason:    table + " " + strings.Join(appendUnique(nil, ti.reasons...), "，"),
+			Impact:    ti.impact,
+		})
+	}
+	return suggestions
+}
+
+// hasIndex 已登记的索引是否以 columns 为前缀
+func (a *IndexAnalyzer) hasIndex(table string, columns []string) bool {
+	info, ok := a.tables[table]
+	if !ok {
+		return false
+	}
+	for _, idx := range info.Indexes {
+		if len(idx.Columns) >= len(columns) && slices.Equal(idx.Columns[:len(columns)], columns) {
+			return true
+		}
+	}
+	return false
+}
+
+// pgPlanNode PostgreSQL FORMAT JSON 计划节点
+type pgPlanNode struct {
+	NodeType            string       `json:"Node Type"`
+	RelationName        string       `json:"Relation Name"`
+	Filter              string       `json:"Filter"`
+	SortKey             []string     `json:"Sort Key"`
+	SortMethod          string       `json:"Sort Method"`
+	SortSpaceType       string       `json:"Sort Space Type"`
+	PlanRows            float64      `json:"Plan Rows"`
+	ActualRows          *float64     `json:"Actual Rows"`
+	ActualLoops         float64      `json:"Actual Loops"`
+	RowsRemovedByFilter float64      `json:"Rows Removed by Filter"`
+	TotalCost           float64      `json:"Total Cost"`
+	ActualTotalTime     float64      `json:"Actual Total Time"`
+	Plans               []pgPlanNode `json:"Plans"`
+}
+
+func parsePostgresPlan(raw, query string) (*QueryPlan, error) {
+	var out []struct {
+		Plan pgPlanNode `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(raw), &out); err != nil {
+		return nil, fmt.Errorf("ent: parse postgres plan: %w", err)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("ent: parse postgres plan: empty plan")
+	}
+	root := out[0].Plan
+	plan := &QueryPlan{Dialect: dialect.Postgres, Analyzed: root.ActualRows != nil, Raw: raw}
+
+	share := func(n *pgPlanNode) float64 {
+		if plan.Analyzed && root.ActualTotalTime > 0 {
+			return min(1, n.ActualTotalTime*max(n.ActualLoops, 1)/root.ActualTotalTime)
+		}
+		if root.TotalCost > 0 {
+			return min(1, n.TotalCost/root.TotalCost)
+		}
+		return 0
+	}
+	rows := func(n *pgPlanNode) float64 {
+		if n.ActualRows != nil {
+			return (*n.ActualRows + n.RowsRemovedByFilter) * max(n.ActualLoops, 1)
+		}
+		return n.PlanRows
+	}
+
+	var walk func(n *pgPlanNode)
+	walk = func(n *pgPlanNode) {
+		switch n.NodeType {
+		case "Seq Scan":
+			columns := conditionColumns(n.Filter, "")
+			if len(columns) == 0 {
+				columns = conditionColumns(whereClause(query), n.RelationName)
+			}
+			plan.Issues = append(plan.Issues, PlanIssue{
+				Kind: PlanSeqScan, Table: n.RelationName, Columns: columns,
+				Rows: rows(n), Share: share(n), Detail: n.Filter,
+			})
+		case "Sort", "Incremental Sort":
+			issue := PlanIssue{
+				Kind: PlanSort, Table: firstRelation(n), Columns: sortColumns(n.SortKey),
+				Rows: rows(n), Share: share(n) - childShare(n, share), Detail: n.SortMethod,
+			}
+			plan.Issues = append(plan.Issues, issue)
+			if n.SortSpaceType == "Disk" || strings.Contains(n.SortMethod, "external") {
+				issue.Kind = PlanTempTable
+				issue.Detail = n.SortMethod + " (" + n.SortSpaceType + ")"
+				plan.Issues = append(plan.Issues, issue)
+			}
+		}
+		for i := range n.Plans {
+			walk(&n.Plans[i])
+		}
+	}
+	walk(&root)
+	return plan, nil
+}
+
+// childShare 子节点的代价占比，用于计算排序节点自身的代价
+func childShare(n *pgPlanNode, share func(*pgPlanNode) float64) float64 {
+	var total float64
+	for i := range n.Plans {
+		total += share(&n.Plans[i])
+	}
+	return min(total, share(n))
+}
+
+func firstRelation(n *pgPlanNode) string {
+	if n.RelationName != "" {
+		return n.RelationName
+	}
+	for i := range n.Plans {
+		if name := firstRelation(&n.Plans[i]); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+func parseMySQLPlan(raw, query string) (*QueryPlan, error) {
+	var out struct {
+		QueryBlock map[string]any `json:"query_block"`
+	}
+	if err := json.Unmarshal([]byte(raw), &out); err != nil {
+		return nil, fmt.Errorf("ent: parse mysql plan: %w", err)
+	}
+	plan := &QueryPlan{Dialect: dialect.MySQL, Raw: raw}
+	total := mysqlCost(out.QueryBlock, "query_cost")
+	share := func(cost float64) float64 {
+		if total <= 0 {
+			return 0
+		}
+		return min(1, cost/total)
+	}
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]any:
+			if name, ok := v["table_name"].(string); ok {
+				if access, _ := v["access_type"].(string); access == "ALL" || access == "index" {
+					cond, _ := v["attached_condition"].(string)
+					columns := conditionColumns(cond, name)
+					if len(columns) == 0 {
+						columns = conditionColumns(whereClause(query), name)
+					}
+					plan.Issues = append(plan.Issues, PlanIssue{
+						Kind: PlanSeqScan, Table: name, Columns: columns,
+						Rows:   jsonNumber(v["rows_examined_per_scan"]),
+						Share:  share(mysqlCost(v, "read_cost") + mysqlCost(v, "eval_cost")),
+						Detail: "access_type " + access,
+					})
+				}
+			}
+			for key, op := range map[string]string{"ordering_operation": "ORDER", "grouping_operation": "GROUP"} {
+				block, ok := v[key].(map[string]any)
+				if !ok {
+					continue
+				}
+				columns := clauseColumns(query, op)
+				table := mysqlFirstTable(block)
+				if block["using_filesort"] == true {
+					plan.Issues = append(plan.Issues, PlanIssue{
+						Kind: PlanSort, Table: table, Columns: columns,
+						Share: share(mysqlCost(block, "sort_cost")), Detail: "using_filesort",
+					})
+				}
+				if block["using_temporary_table"] == true {
+					plan.Issues = append(plan.Issues, PlanIssue{
+						Kind: PlanTempTable, Table: table, Columns: columns, Detail: "using_temporary_table",
+					})
+				}
+			}
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				walk(v[key])
+			}
+		}
+	}
+	walk(out.QueryBlock)
+	return plan, nil
+}
+
+// mysqlCost 读取 cost_info 中的代价，MySQL 以字符串输出
+func mysqlCost(v map[string]any, key string) float64 {
+	info, _ := v["cost_info"].(map[string]any)
+	return jsonNumber(info[key])
+}
+
+func mysqlFirstTable(v any) string {
+	switch v := v.(type) {
+	case map[string]any:
+		if name, ok := v["table_name"].(string); ok {
+			return name
+		}
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			if name := mysqlFirstTable(v[key]); name != "" {
+				return name
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if name := mysqlFirstTable(item); name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func jsonNumber(v any) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
+
+var sqliteScan = regexp.MustCompile(`^SCAN (?:TABLE )?([A-Za-z_][A-Za-z0-9_]*)`)
+
+func parseSQLitePlan(details []string, query string) *QueryPlan {
+	plan := &QueryPlan{Dialect: dialect.SQLite, Raw: strings.Join(details, "\n")}
+	var scanned []string
+	for _, detail := range details {
+		m := sqliteScan.FindStringSubmatch(detail)
+		// 覆盖索引扫描不计为全表扫描
+		if m == nil || strings.Contains(detail, "COVERING INDEX") {
+			continue
+		}
+		scanned = append(scanned, m[1])
+		plan.Issues = append(plan.Issues, PlanIssue{
+			Kind: PlanSeqScan, Table: m[1], Columns: conditionColumns(whereClause(query), m[1]), Detail: detail,
+		})
+	}
+	var table string
+	if len(scanned) > 0 {
+		table = scanned[0]
+	} else if tables := fromTables(query); len(tables) > 0 {
+		table = tables[0]
+	}
+	for _, detail := range details {
+		switch {
+		case strings.Contains(detail, "TEMP B-TREE FOR ORDER BY"):
+			plan.Issues = append(plan.Issues, PlanIssue{Kind: PlanSort, Table: table, Columns: clauseColumns(query, "ORDER"), Detail: detail})
+		case strings.Contains(detail, "TEMP B-TREE FOR GROUP BY"), strings.Contains(detail, "TEMP B-TREE FOR DISTINCT"):
+			plan.Issues = append(plan.Issues, PlanIssue{Kind: PlanTempTable, Table: table, Columns: clauseColumns(query, "GROUP"), Detail: detail})
+		}
+	}
+	// SQLite 不输出代价，按问题数平均分配
+	for i := range plan.Issues {
+		plan.Issues[i].Share = 1 / float64(len(plan.Issues))
+	}
+	return plan
+}
+
+var (
+	// castPattern PostgreSQL 类型转换，如 ::text、::character varying
+	castPattern = regexp.MustCompile(`::[A-Za-z_][A-Za-z0-9_ ]*(?:\[\])?`)
+	// conditionPattern 比较运算左侧的列名
+	conditionPattern = regexp.MustCompile(`(?i)([A-Za-z_][A-Za-z0-9_.]*)\)?\s*(=|<>|!=|<=|>=|<|>|~~|\bLIKE\b|\bIN\b|\bBETWEEN\b|\bIS\b)`)
+	wherePattern     = regexp.MustCompile(`(?is)\bWHERE\b(.*?)(?:\bGROUP\s+BY\b|\bORDER\s+BY\b|\bLIMIT\b|\bHAVING\b|\bFOR\s+UPDATE\b|$)`)
+	orderByPattern   = regexp.MustCompile(`(?is)\bORDER\s+BY\b(.*?)(?:\bLIMIT\b|\bOFFSET\b|\bFOR\b|$)`)
+	groupByPattern   = regexp.MustCompile(`(?is)\bGROUP\s+BY\b(.*?)(?:\bHAVING\b|\bORDER\s+BY\b|\bLIMIT\b|$)`)
+	fromPattern      = regexp.MustCompile(`(?i)\b(?:FROM|JOIN)\s+([A-Za-z_][A-Za-z0-9_.]*)`)
+	// conditionKeywords 可能出现在运算符左侧的关键字
+	conditionKeywords = map[string]bool{"AND": true, "OR": true, "NOT": true, "NULL": true, "TRUE": true, "FALSE": true}
+)
+
+// conditionColumns 提取条件中比较运算左侧的列，等值列在前、范围列在后；
+// table 非空时只保留未限定或以 table 限定的列
+func conditionColumns(cond, table string) []string {
+	cond = strings.NewReplacer(`"`, "", "`", "").Replace(castPattern.ReplaceAllString(cond, ""))
+	var equality, ranged []string
+	for _, m := range conditionPattern.FindAllStringSubmatch(cond, -1) {
+		qualifier, column, qualified := cutLast(m[1], ".")
+		if !qualified {
+			column = qualifier
+		} else if table != "" && qualifier != table && !strings.HasSuffix(qualifier, "."+table) {
+			continue
+		}
+		if conditionKeywords[strings.ToUpper(column)] {
+			continue
+		}
+		switch strings.ToUpper(m[2]) {
+		case "=", "IN", "IS":
+			equality = appendUnique(equality, column)
+		default:
+			ranged = appendUnique(ranged, column)
+		}
+	}
+	var columns []string
+	columns = appendUnique(columns, equality...)
+	return appendUnique(columns, ranged...)
+}
+
+func whereClause(query string) string {
+	if m := wherePattern.FindStringSubmatch(query); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// clauseColumns 提取 ORDER BY 或 GROUP BY 的列
+func clauseColumns(query, clause string) []string {
+	pattern := orderByPattern
+	if clause == "GROUP" {
+		pattern = groupByPattern
+	}
+	m := pattern.FindStringSubmatch(query)
+	if m == nil {
+		return nil
+	}
+	return sortColumns(strings.Split(m[1], ","))
+}
+
+// sortColumns 去掉排序键中的表限定、引号与方向
+func sortColumns(keys []string) []string {
+	var columns []string
+	for _, key := range keys {
+		fields := strings.Fields(strings.NewReplacer(`"`, "", "`", "").Replace(key))
+		if len(fields) == 0 {
+			continue
+		}
+		_, column, qualified := cutLast(fields[0], ".")
+		if !qualified {
+			column = fields[0]
+		}
+		columns = appendUnique(columns, column)
+	}
+	return columns
+}
+
+func fromTables(query string) []string {
+	var tables []string
+	for _, m := range fromPattern.FindAllStringSubmatch(query, -1) {
+		tables = appendUnique(tables, m[1])
+	}
+	return tables
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+func appendUnique(dst []string, values ...string) []string {
+	for _, v := range values {
+		if v != "" && !slices.Contains(dst, v) {
+			dst = append(dst, v)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,183 @@
+package ent
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"github.com/google/uuid"
+)
+
+const postgresPlanFixture = `[{"Plan": {
+  "Node Type": "Sort", "Total Cost": 2100.5, "Plan Rows": 120, "Actual Rows": 118, "Actual Loops": 1,
+  "Actual Total Time": 95.0, "Sort Key": ["orders.created_at DESC"],
+  "Sort Method": "external merge", "Sort Space Type": "Disk",
+  "Plans": [{
+    "Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "orders",
+    "Total Cost": 2000.0, "Plan Rows": 120, "Actual Rows": 118, "Actual Loops": 1,
+    "Actual Total Time": 80.0, "Rows Removed by Filter": 99882,
+    "Filter": "(((status)::text = 'paid'::text) AND (created_at > '2024-01-01 00:00:00'::timestamp without time zone))"
+  }]
+}, "Execution Time": 95.4}]`
+
+const mysqlPlanFixture = `{"query_block": {
+  "select_id": 1, "cost_info": {"query_cost": "1020.00"},
+  "ordering_operation": {
+    "using_filesort": true, "cost_info": {"sort_cost": "20.00"},
+    "table": {
+      "table_name": "orders", "access_type": "ALL", "rows_examined_per_scan": 10000,
+      "cost_info": {"read_cost": "900.00", "eval_cost": "100.00", "prefix_cost": "1000.00"},
+      "attached_condition": "(` + "`shop`.`orders`.`customer_id`" + ` = 42)"
+    }
+  }
+}}`
+
+func TestParsePostgresPlan(t *testing.T) {
+	plan, err := parsePostgresPlan(postgresPlanFixture, "SELECT * FROM orders WHERE status = $1 AND created_at > $2 ORDER BY created_at DESC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Analyzed {
+		t.Error("plan with actual rows should be marked analyzed")
+	}
+	kinds := map[PlanIssueKind]PlanIssue{}
+	for _, issue := range plan.Issues {
+		kinds[issue.Kind] = issue
+	}
+	scan := kinds[PlanSeqScan]
+	if scan.Table != "orders" || fmt.Sprint(scan.Columns) != "[status created_at]" || scan.Rows != 100000 {
+		t.Errorf("seq scan issue = %+v", scan)
+	}
+	if sort := kinds[PlanSort]; fmt.Sprint(sort.Columns) != "[created_at]" || sort.Table != "orders" {
+		t.Errorf("sort issue = %+v", sort)
+	}
+	if _, ok := kinds[PlanTempTable]; !ok {
+		t.Error("disk sort should be reported as a temp table issue")
+	}
+
+	suggestions := NewIndexAnalyzer().Suggest(plan)
+	if len(suggestions) != 1 {
+		t.Fatalf("suggestions = %+v, want 1", suggestions)
+	}
+	s := suggestions[0]
+	if s.Statement != "CREATE INDEX idx_orders_status_created_at ON orders (status, created_at)" {
+		t.Errorf("statement = %s", s.Statement)
+	}
+	if s.Impact < 0.9 || s.Impact > 1 {
+		t.Errorf("impact = %v, want close to 1", s.Impact)
+	}
+}
+
+func TestParseMySQLPlan(t *testing.T) {
+	plan, err := parseMySQLPlan(mysqlPlanFixture, "SELECT * FROM orders WHERE customer_id = ? ORDER BY created_at")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzer := NewIndexAnalyzer()
+	suggestions := analyzer.Suggest(plan)
+	if len(suggestions) != 1 || suggestions[0].Statement != "CREATE INDEX idx_orders_customer_id_created_at ON orders (customer_id, created_at)" {
+		t.Fatalf("suggestions = %+v", suggestions)
+	}
+	if impact := suggestions[0].Impact; impact < 0.99 || impact > 1 {
+		t.Errorf("impact = %v, want about 1", impact)
+	}
+
+	analyzer.AddTable(TableInfo{Name: "orders", Indexes: []IndexInfo{{Name: "idx", Columns: []string{"customer_id", "created_at", "id"}}}})
+	if got := analyzer.Suggest(plan); len(got) != 0 {
+		t.Errorf("existing index should suppress the suggestion, got %+v", got)
+	}
+}
+
+func TestConditionColumns(t *testing.T) {
+	tests := map[string]string{
+		"o.tenant_id = ? AND o.total >= ? AND o.status IN (...)": "[tenant_id status total]",
+		"(deleted_at IS NULL) AND (name ~~ 'a%'::text)":          "[deleted_at name]",
+		"`db`.`users`.`email` = 'x'":                             "[email]",
+	}
+	for cond, want := range tests {
+		if got := fmt.Sprint(conditionColumns(cond, "")); got != want {
+			t.Errorf("conditionColumns(%q) = %s, want %s", cond, got, want)
+		}
+	}
+}
+
+func TestQueryOptimizerExplainsSlowQueries(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:explain-"+uuid.NewString()+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	for _, stmt := range []string{
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER, created_at TEXT)",
+		"INSERT INTO orders (customer_id, created_at) VALUES (1, '2024-01-02'), (2, '2024-01-01')",
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	monitor := NewQueryMonitorWithThreshold(time.Nanosecond)
+	idb := NewInstrumentedDB(db, InstrumentOptions{Monitor: monitor})
+	const query = "SELECT id FROM orders WHERE customer_id = ? ORDER BY created_at"
+	rows, err := idb.QueryContext(ctx, query, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if sample, args, ok := monitor.Sample(SanitizeSQL(query)); !ok || sample != query || fmt.Sprint(args) != "[1]" {
+		t.Fatalf("sample = %q %v %v", sample, args, ok)
+	}
+
+	optimizer := NewQueryOptimizer(monitor, NewIndexAnalyzerWithDB(db, dialect.SQLite))
+	suggestions, err := optimizer.IndexSuggestions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 1 {
+		t.Fatalf("suggestions = %+v, want 1", suggestions)
+	}
+	s := suggestions[0]
+	if s.Statement != "CREATE INDEX idx_orders_customer_id_created_at ON orders (customer_id, created_at)" {
+		t.Errorf("statement = %s", s.Statement)
+	}
+	if s.Query != SanitizeSQL(query) || s.EstimatedSaving <= 0 {
+		t.Errorf("query = %q, saving = %v", s.Query, s.EstimatedSaving)
+	}
+	if lines := strings.Join(optimizer.GetSuggestions(), "\n"); !strings.Contains(lines, "索引建议:") || !strings.Contains(lines, s.Statement) {
+		t.Errorf("GetSuggestions missing index suggestion:\n%s", lines)
+	}
+
+	if _, err := db.ExecContext(ctx, s.Statement); err != nil {
+		t.Fatal(err)
+	}
+	if suggestions, err := optimizer.IndexSuggestions(ctx); err != nil || len(suggestions) != 0 {
+		t.Errorf("after creating the index: suggestions = %+v, err = %v", suggestions, err)
+	}
+}
+
+func TestAnalyzable(t *testing.T) {
+	if NewIndexAnalyzerWithDB(nil, dialect.Postgres).analyze {
+		t.Error("EXPLAIN ANALYZE should be off by default")
+	}
+	for query, want := range map[string]bool{
+		"SELECT * FROM orders WHERE status = $1":                           true,
+		"(SELECT id FROM orders) UNION (SELECT id FROM refunds)":           true,
+		"SELECT * FROM orders WHERE id = $1 FOR UPDATE":                    false,
+		"SELECT * FROM orders WHERE id = $1 FOR NO KEY UPDATE SKIP LOCKED": false,
+		"select * from jobs for share":                                     false,
+		"SELECT * INTO archive FROM orders":                                false,
+		"SELECT nextval('orders_id_seq')":                                  false,
+		"SELECT pg_advisory_lock(42)":                                      false,
+		"UPDATE orders SET status = $1":                                    false,
+		"WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d":       false,
+	} {
+		if got := analyzable(query); got != want {
+			t.Errorf("analyzable(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
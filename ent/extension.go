@@ -3,7 +3,10 @@ package ent
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -20,55 +23,28 @@ func NewQueryExtension(db *sql.DB) *QueryExtension {
 	}
 }
 
-// BulkCreate 批量创建优化
-func (e *QueryExtension) BulkCreate(ctx context.Context, items []interface{}, batchSize int) error {
-	if len(items) == 0 {
-		return nil
-	}
-
-	if batchSize == 0 {
-		batchSize = 1000
-	}
-
-	// 分批处理
-	for i := 0; i < len(items); i += batchSize {
-		end := i + batchSize
-		if end > len(items) {
-			end = len(items)
-		}
-
-		batch := items[i:end]
-
-		// 使用事务
-		tx, err := e.db.BeginTx(ctx, nil)
-		if err != nil {
-			return err
-		}
-
-		// 执行批量插入
-		// 简化实现：实际应该使用 prepared statement
-		for _, item := range batch {
-			_ = item // 使用 item
-		}
-
-		if err := tx.Commit(); err != nil {
-			tx.Rollback()
-			return err
-		}
-	}
-
-	return nil
-}
-
-// OptimizedQuery 查询优化
+// OptimizedQuery 查询构建。条件中的值一律以 ? 占位符传入，Build 时按
+// 占位符风格改写并返回参数列表，值不会拼接进 SQL
+//
+//	query, args, err := ent.NewOptimizedQuery().
+//		Select("id", "name").From("users").
+//		Where("status = ?", "active").
+//		WhereNamed("created_at > :since", map[string]any{"since": since}).
+//		WithPlaceholder(ent.PlaceholderDollar).
+//		Build()
 type OptimizedQuery struct {
-	fields   []string
-	joins    []string
-	filters  []string
-	orderBy  []string
-	limit    *int
-	offset   *int
-	distinct bool
+	fields      []string
+	from        string
+	joins       []string
+	joinArgs    []any
+	filters     []string
+	filterArgs  []any
+	orderBy     []string
+	limit       *int
+	offset      *int
+	distinct    bool
+	placeholder PlaceholderStyle
+	err         error
 }
 
 // NewOptimizedQuery 创建优化查询
@@ -81,26 +57,65 @@ func NewOptimizedQuery() *OptimizedQuery {
 	}
 }
 
+// WithPlaceholder 设置占位符风格，默认 ?
+func (q *OptimizedQuery) WithPlaceholder(style PlaceholderStyle) *OptimizedQuery {
+	q.placeholder = style
+	return q
+}
+
 // Select 指定查询字段
 func (q *OptimizedQuery) Select(fields ...string) *OptimizedQuery {
 	q.fields = append(q.fields, fields...)
 	return q
 }
 
-// Join 关联查询
-func (q *OptimizedQuery) Join(table, on string) *OptimizedQuery {
+// From 指定查询表
+func (q *OptimizedQuery) From(table string) *OptimizedQuery {
+	q.from = table
+	return q
+}
+
+// Join 关联查询，on 中的值以 ? 占位符传入
+func (q *OptimizedQuery) Join(table, on string, args ...any) *OptimizedQuery {
 	q.joins = append(q.joins, fmt.Sprintf("JOIN %s ON %s", table, on))
+	q.joinArgs = append(q.joinArgs, args...)
 	return q
 }
 
-// Where 条件过滤
-func (q *OptimizedQuery) Where(condition string) *OptimizedQuery {
+// Where 条件过滤，condition 中的值以 ? 占位符传入
+func (q *OptimizedQuery) Where(condition string, args ...any) *OptimizedQuery {
 	q.filters = append(q.filters, condition)
+	q.filterArgs = append(q.filterArgs, args...)
 	return q
 }
 
-// OrderBy 排序
+// WhereNamed 以 :name 命名参数过滤，缺少参数的错误在 Build 时返回
+func (q *OptimizedQuery) WhereNamed(condition string, params map[string]any) *OptimizedQuery {
+	condition, args, err := BindNamed(condition, params)
+	if err != nil {
+		if q.err == nil {
+			q.err = err
+		}
+		return q
+	}
+	return q.Where(condition, args...)
+}
+
+// WhereIn IN 条件，values 为空时条件恒假
+func (q *OptimizedQuery) WhereIn(field string, values []any) *OptimizedQuery {
+	condition, args := buildIn(field, values)
+	return q.Where(condition, args...)
+}
+
+// OrderBy 排序，field 须为列名，direction 为 ASC 或 DESC（不区分大小写）
 func (q *OptimizedQuery) OrderBy(field, direction string) *OptimizedQuery {
+	direction = strings.ToUpper(direction)
+	if !validColumn(field) || (direction != "ASC" && direction != "DESC") {
+		if q.err == nil {
+			q.err = fmt.Errorf("ent: invalid order by %q %q", field, direction)
+		}
+		return q
+	}
 	q.orderBy = append(q.orderBy, fmt.Sprintf("%s %s", field, direction))
 	return q
 }
@@ -123,58 +138,53 @@ func (q *OptimizedQuery) Distinct() *OptimizedQuery {
 	return q
 }
 
-// Build 构建 SQL
-func (q *OptimizedQuery) Build() string {
-	sql := "SELECT "
+// Build 构建 SQL，返回按占位符风格改写后的语句与参数
+func (q *OptimizedQuery) Build() (string, []any, error) {
+	if q.err != nil {
+		return "", nil, q.err
+	}
 
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
 	if q.distinct {
-		sql += "DISTINCT "
+		sb.WriteString("DISTINCT ")
 	}
-
 	if len(q.fields) == 0 {
-		sql += "*"
+		sb.WriteString("*")
 	} else {
-		sql += q.fields[0]
-		for i := 1; i < len(q.fields); i++ {
-			sql += ", " + q.fields[i]
-		}
+		sb.WriteString(strings.Join(q.fields, ", "))
 	}
-
-	if len(q.joins) > 0 {
-		for _, join := range q.joins {
-			sql += " " + join
-		}
+	if q.from != "" {
+		sb.WriteString(" FROM " + q.from)
+	}
+	for _, join := range q.joins {
+		sb.WriteString(" " + join)
 	}
-
 	if len(q.filters) > 0 {
-		sql += " WHERE " + q.filters[0]
-		for i := 1; i < len(q.filters); i++ {
-			sql += " AND " + q.filters[i]
-		}
+		sb.WriteString(" WHERE " + strings.Join(q.filters, " AND "))
 	}
-
 	if len(q.orderBy) > 0 {
-		sql += " ORDER BY " + q.orderBy[0]
-		for i := 1; i < len(q.orderBy); i++ {
-			sql += ", " + q.orderBy[i]
-		}
+		sb.WriteString(" ORDER BY " + strings.Join(q.orderBy, ", "))
 	}
-
 	if q.limit != nil {
-		sql += fmt.Sprintf(" LIMIT %d", *q.limit)
+		fmt.Fprintf(&sb, " LIMIT %d", *q.limit)
 	}
-
 	if q.offset != nil {
-		sql += fmt.Sprintf(" OFFSET %d", *q.offset)
+		fmt.Fprintf(&sb, " OFFSET %d", *q.offset)
 	}
 
-	return sql
+	// 参数顺序与占位符在 SQL 中的位置一致：先 JOIN 后 WHERE
+	args := append(append([]any(nil), q.joinArgs...), q.filterArgs...)
+	return Rebind(q.placeholder, sb.String()), args, nil
 }
 
 // Execute 执行查询
 func (q *OptimizedQuery) Execute(ctx context.Context, db *sql.DB) (*sql.Rows, error) {
-	sql := q.Build()
-	return db.QueryContext(ctx, sql)
+	query, args, err := q.Build()
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, query, args...)
 }
 
 // ConnectionPool 连接池配置
@@ -227,10 +237,15 @@ func (p *ConnectionPool) Apply(db *sql.DB) {
 	db.SetConnMaxIdleTime(p.connMaxIdleTime)
 }
 
+// DefaultSlowQueryThreshold 默认慢查询阈值
+const DefaultSlowQueryThreshold = time.Second
+
 // QueryMonitor 查询监控
 type QueryMonitor struct {
-	queries map[string]*QueryStats
-	mu      sync.RWMutex
+	queries   map[string]*QueryStats
+	samples   map[string]querySample
+	threshold time.Duration
+	mu        sync.RWMutex
 }
 
 // QueryStats 查询统计
@@ -242,13 +257,35 @@ type QueryStats struct {
 	Errors  int64
 }
 
-// NewQueryMonitor 创建查询监控
+// querySample 慢查询的原始语句与参数，仅保存在内存中供 EXPLAIN 使用
+type querySample struct {
+	query    string
+	args     []any
+	duration time.Duration
+}
+
+// NewQueryMonitor 创建查询监控，慢查询阈值为 DefaultSlowQueryThreshold
 func NewQueryMonitor() *QueryMonitor {
+	return NewQueryMonitorWithThreshold(DefaultSlowQueryThreshold)
+}
+
+// NewQueryMonitorWithThreshold 以指定慢查询阈值创建查询监控
+func NewQueryMonitorWithThreshold(threshold time.Duration) *QueryMonitor {
+	if threshold <= 0 {
+		threshold = DefaultSlowQueryThreshold
+	}
 	return &QueryMonitor{
-		queries: make(map[string]*QueryStats),
+		queries:   make(map[string]*QueryStats),
+		samples:   make(map[string]querySample),
+		threshold: threshold,
 	}
 }
 
+// Threshold 返回慢查询阈值
+func (m *QueryMonitor) Threshold() time.Duration {
+	return m.threshold
+}
+
 // Record 记录查询
 func (m *QueryMonitor) Record(query string, duration time.Duration, err error) {
 	m.mu.Lock()
@@ -283,6 +320,28 @@ func (m *QueryMonitor) Record(query string, duration time.Duration, err error) {
 	}
 }
 
+// RecordSample 为超过阈值的语句保存一份可执行的原始语句与参数，
+// statement 为 Record 使用的键，每个键只保留最慢的一次
+func (m *QueryMonitor) RecordSample(statement, query string, args []any, duration time.Duration) {
+	if duration < m.threshold {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if prev, ok := m.samples[statement]; ok && prev.duration >= duration {
+		return
+	}
+	m.samples[statement] = querySample{query: query, args: append([]any(nil), args...), duration: duration}
+}
+
+// Sample 返回 statement 的慢查询样本
+func (m *QueryMonitor) Sample(statement string) (query string, args []any, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sample, ok := m.samples[statement]
+	return sample.query, sample.args, ok
+}
+
 // GetStats 获取统计
 func (m *QueryMonitor) GetStats(query string) *QueryStats {
 	m.mu.RLock()
@@ -304,9 +363,12 @@ func (m *QueryMonitor) GetSlowQueries(threshold time.Duration) map[string]*Query
 	return slow
 }
 
-// IndexAnalyzer 索引分析器
+// IndexAnalyzer 索引分析器，配置数据库连接后通过 EXPLAIN 分析语句
 type IndexAnalyzer struct {
-	tables map[string]TableInfo
+	tables  map[string]TableInfo
+	db      *sql.DB
+	dialect string
+	analyze bool
 }
 
 // TableInfo 表信息
@@ -332,25 +394,35 @@ type ColumnInfo struct {
 	Default  string
 }
 
-// NewIndexAnalyzer 创建索引分析器
+// NewIndexAnalyzer 创建索引分析器，仅检查登记的表结构
 func NewIndexAnalyzer() *IndexAnalyzer {
 	return &IndexAnalyzer{
 		tables: make(map[string]TableInfo),
 	}
 }
 
+// NewIndexAnalyzerWithDB 创建可执行 EXPLAIN 的索引分析器，dialectName 为 ent 方言
+// （postgres、mysql、sqlite3）。默认只取估计的执行计划，不实际执行语句
+func NewIndexAnalyzerWithDB(db *sql.DB, dialectName string) *IndexAnalyzer {
+	a := NewIndexAnalyzer()
+	a.db = db
+	a.dialect = dialectName
+	return a
+}
+
+// WithAnalyze 设置 PostgreSQL 是否使用 EXPLAIN ANALYZE。ANALYZE 会实际执行语句，
+// 只用于不含 FOR UPDATE 等行锁子句、SELECT INTO 与有副作用函数的 SELECT，
+// 并在回滚的只读事务中执行
+func (a *IndexAnalyzer) WithAnalyze(enabled bool) *IndexAnalyzer {
+	a.analyze = enabled
+	return a
+}
+
 // AddTable 添加表
 func (a *IndexAnalyzer) AddTable(table TableInfo) {
 	a.tables[table.Name] = table
 }
 
-// Analyze 分析索引使用情况
-func (a *IndexAnalyzer) Analyze(query string) []string {
-	// 简化实现：分析查询中的索引使用
-	// 实际应该解析 SQL 并检查执行计划
-	return []string{"建议在常用查询字段上添加索引"}
-}
-
 // GetMissingIndexes 获取缺失的索引
 func (a *IndexAnalyzer) GetMissingIndexes() []string {
 	var missing []string
@@ -384,12 +456,13 @@ func NewQueryOptimizer(monitor *QueryMonitor, analyzer *IndexAnalyzer) *QueryOpt
 	}
 }
 
-// GetSuggestions 获取优化建议
+// GetSuggestions 获取优化建议：超过 QueryMonitor 阈值的慢查询、缺失的主键，
+// 以及对慢查询样本执行 EXPLAIN 得到的索引建议
 func (o *QueryOptimizer) GetSuggestions() []string {
 	suggestions := []string{}
 
 	// 慢查询建议
-	slowQueries := o.monitors.GetSlowQueries(1 * time.Second)
+	slowQueries := o.monitors.GetSlowQueries(o.monitors.Threshold())
 	if len(slowQueries) > 0 {
 		suggestions = append(suggestions, "发现慢查询:")
 		for query, stats := range slowQueries {
@@ -404,9 +477,67 @@ func (o *QueryOptimizer) GetSuggestions() []string {
 		suggestions = append(suggestions, missing...)
 	}
 
+	if o.analyzer.db != nil && len(slowQueries) > 0 {
+		indexes, err := o.IndexSuggestions(context.Background())
+		if len(indexes) > 0 {
+			suggestions = append(suggestions, "索引建议:")
+			for _, s := range indexes {
+				suggestions = append(suggestions, fmt.Sprintf("  %s; -- %s，预计每次节省 %v（%.0f%%）",
+					s.Statement, s.Reason, s.EstimatedSaving, s.Impact*100))
+			}
+		}
+		if err != nil {
+			suggestions = append(suggestions, fmt.Sprintf("执行计划分析失败: %v", err))
+		}
+	}
+
 	return suggestions
 }
 
+// IndexSuggestions 对超过阈值且有样本的慢查询执行 EXPLAIN，返回按预计节省时间
+// 降序排列的索引建议；多条语句得到相同建议时合并，节省时间累加。
+// 分析失败的语句不影响其他语句，错误合并返回
+func (o *QueryOptimizer) IndexSuggestions(ctx context.Context) ([]IndexSuggestion, error) {
+	slowQueries := o.monitors.GetSlowQueries(o.monitors.Threshold())
+	statements := make([]string, 0, len(slowQueries))
+	for statement := range slowQueries {
+		statements = append(statements, statement)
+	}
+	sort.Strings(statements)
+
+	var (
+		merged []IndexSuggestion
+		index  = map[string]int{}
+		errs   []error
+	)
+	for _, statement := range statements {
+		query, args, ok := o.monitors.Sample(statement)
+		if !ok {
+			continue
+		}
+		suggestions, err := o.analyzer.Analyze(ctx, query, args...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", statement, err))
+			continue
+		}
+		for _, s := range suggestions {
+			s.Query = statement
+			s.EstimatedSaving = time.Duration(s.Impact * float64(slowQueries[statement].AvgTime))
+			if i, ok := index[s.Statement]; ok {
+				merged[i].EstimatedSaving += s.EstimatedSaving
+				merged[i].Impact = max(merged[i].Impact, s.Impact)
+				continue
+			}
+			index[s.Statement] = len(merged)
+			merged = append(merged, s)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].EstimatedSaving > merged[j].EstimatedSaving
+	})
+	return merged, errors.Join(errs...)
+}
+
 // BatchProcessor 批量处理器
 type BatchProcessor struct {
 	batchSize int
@@ -493,21 +624,17 @@ func (b *QueryBuilder) SelectWithAliases(fields map[string]string) string {
 	return result
 }
 
-// BuildIn 构建 IN 查询
-func (b *QueryBuilder) BuildIn(field string, values []interface{}) string {
-	if len(values) == 0 {
-		return "1=0"
-	}
+// BuildIn 构建 IN 条件，返回 ? 占位符形式的条件与参数，values 为空时条件恒假。
+// 条件可直接传给 OptimizedQuery.Where，或经 Rebind 改写后单独使用
+func (b *QueryBuilder) BuildIn(field string, values []any) (string, []any) {
+	return buildIn(field, values)
+}
 
-	result := field + " IN ("
-	for i, value := range values {
-		if i > 0 {
-			result += ", "
-		}
-		result += fmt.Sprintf("'%v'", value)
+func buildIn(field string, values []any) (string, []any) {
+	if len(values) == 0 {
+		return "1=0", nil
 	}
-	result += ")"
-	return result
+	return field + " IN (" + strings.Repeat("?, ", len(values)-1) + "?)", append([]any(nil), values...)
 }
 
 // BuildOr 构建 OR 查询
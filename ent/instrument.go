@@ -0,0 +1,393 @@
+package ent
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/tracing"
+)
+
+// DefaultMaxStatementLength 脱敏后语句的默认最大长度
+const DefaultMaxStatementLength = 2048
+
+// MetricDBQueryErrors 失败语句计数，按操作类型（SELECT、INSERT 等）分组
+const MetricDBQueryErrors = "db_query_errors_total"
+
+// InstrumentOptions 数据库埋点配置，Monitor、Metrics、Tracer 为 nil 时跳过对应记录
+type InstrumentOptions struct {
+	Monitor *QueryMonitor
+	Metrics *metrics.Collector
+	Tracer  *tracing.Tracer
+	// System 写入 Span 的 db.system，如 postgresql；包装 ent 驱动时默认取其方言
+	System string
+	// MaxStatementLength 脱敏后语句超过该长度时截断，默认 2048
+	MaxStatementLength int
+}
+
+// queryObserver 记录单条语句的耗时、错误与 Span
+type queryObserver struct {
+	opts InstrumentOptions
+}
+
+func newQueryObserver(opts InstrumentOptions) *queryObserver {
+	if opts.MaxStatementLength <= 0 {
+		opts.MaxStatementLength = DefaultMaxStatementLength
+	}
+	return &queryObserver{opts: opts}
+}
+
+// observe 执行 fn 并记录语句。QueryMonitor 与指标均以脱敏语句为键，
+// 参数值与字面量不会出现在监控数据或 Span 中；超过慢查询阈值的语句连同参数
+// 作为样本保存在 QueryMonitor 内存中，供 IndexAnalyzer 执行 EXPLAIN
+func (o *queryObserver) observe(ctx context.Context, query string, args any, fn func(ctx context.Context) error) error {
+	statement := SanitizeSQL(query)
+	if len(statement) > o.opts.MaxStatementLength {
+		statement = statement[:o.opts.MaxStatementLength]
+	}
+	operation := sqlOperation(statement)
+
+	var span *tracing.Span
+	if o.opts.Tracer != nil {
+		attrs := map[string]interface{}{
+			"db.statement": statement,
+			"db.operation": operation,
+		}
+		if o.opts.System != "" {
+			attrs["db.system"] = o.opts.System
+		}
+		ctx, span = o.opts.Tracer.Start(ctx, "db.query",
+			tracing.WithSpanKind(tracing.SpanKindClient),
+			tracing.WithAttributes(attrs),
+		)
+	}
+
+	start := time.Now()
+	err := fn(ctx)
+	duration := time.Since(start)
+
+	if o.opts.Monitor != nil {
+		o.opts.Monitor.Record(statement, duration, err)
+		if values, ok := args.([]any); err == nil && (ok || args == nil) {
+			o.opts.Monitor.RecordSample(statement, query, values, duration)
+		}
+	}
+	if o.opts.Metrics != nil {
+		o.opts.Metrics.RecordDBQuery(statement, duration.Seconds())
+		if err != nil {
+			o.opts.Metrics.IncCounter(MetricDBQueryErrors, map[string]string{"operation": operation})
+		}
+	}
+	if span != nil {
+		if err != nil {
+			o.opts.Tracer.RecordError(span, err, nil)
+		}
+		o.opts.Tracer.End(span, err)
+	}
+	return err
+}
+
+// InstrumentedDriver 包装 ent 驱动，自动记录每条语句的耗时与错误到
+// QueryMonitor 和指标收集器，并为其创建 DB Span，无需手动调用 ExecuteWithStats
+//
+//	drv := ent.NewInstrumentedDriver(entsql.OpenDB(dialect.Postgres, db), ent.InstrumentOptions{
+//		Monitor: monitor, Metrics: collector, Tracer: tracer,
+//	})
+//	client := ent.NewClient(ent.Driver(drv))
+type InstrumentedDriver struct {
+	dialect.Driver
+	obs *queryObserver
+}
+
+// NewInstrumentedDriver 创建埋点驱动
+func NewInstrumentedDriver(drv dialect.Driver, opts InstrumentOptions) *InstrumentedDriver {
+	if opts.System == "" {
+		opts.System = dbSystem(drv.Dialect())
+	}
+	return &InstrumentedDriver{Driver: drv, obs: newQueryObserver(opts)}
+}
+
+// Exec 执行语句并记录
+func (d *InstrumentedDriver) Exec(ctx context.Context, query string, args, v any) error {
+	return d.obs.observe(ctx, query, args, func(ctx context.Context) error {
+		return d.Driver.Exec(ctx, query, args, v)
+	})
+}
+
+// Query 执行查询并记录
+func (d *InstrumentedDriver) Query(ctx context.Context, query string, args, v any) error {
+	return d.obs.observe(ctx, query, args, func(ctx context.Context) error {
+		return d.Driver.Query(ctx, query, args, v)
+	})
+}
+
+// Tx 开启事务，事务内的语句同样被记录
+func (d *InstrumentedDriver) Tx(ctx context.Context) (dialect.Tx, error) {
+	tx, err := d.Driver.Tx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedTx{Tx: tx, obs: d.obs}, nil
+}
+
+// BeginTx 以指定选项开启事务，底层驱动须支持 BeginTx
+func (d *InstrumentedDriver) BeginTx(ctx context.Context, opts *sql.TxOptions) (dialect.Tx, error) {
+	drv, ok := d.Driver.(interface {
+		BeginTx(context.Context, *sql.TxOptions) (dialect.Tx, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("ent: driver %T does not support BeginTx", d.Driver)
+	}
+	tx, err := drv.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedTx{Tx: tx, obs: d.obs}, nil
+}
+
+type instrumentedTx struct {
+	dialect.Tx
+	obs *queryObserver
+}
+
+func (t *instrumentedTx) Exec(ctx context.Context, query string, args, v any) error {
+	return t.obs.observe(ctx, query, args, func(ctx context.Context) error {
+		return t.Tx.Exec(ctx, query, args, v)
+	})
+}
+
+func (t *instrumentedTx) Query(ctx context.Context, query string, args, v any) error {
+	return t.obs.observe(ctx, query, args, func(ctx context.Context) error {
+		return t.Tx.Query(ctx, query, args, v)
+	})
+}
+
+// InstrumentedDB 包装 *sql.DB，对直接执行的 SQL 做与 InstrumentedDriver 相同的记录。
+// 未覆盖的方法（Ping、Stats 等）直接透传
+type InstrumentedDB struct {
+	*sql.DB
+	obs *queryObserver
+}
+
+// NewInstrumentedDB 创建埋点数据库连接
+func NewInstrumentedDB(db *sql.DB, opts InstrumentOptions) *InstrumentedDB {
+	return &InstrumentedDB{DB: db, obs: newQueryObserver(opts)}
+}
+
+// ExecContext 执行语句并记录
+func (db *InstrumentedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return observeExec(ctx, db.obs, db.DB.ExecContext, query, args)
+}
+
+// QueryContext 执行查询并记录
+func (db *InstrumentedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return observeQuery(ctx, db.obs, db.DB.QueryContext, query, args)
+}
+
+// QueryRowContext 执行单行查询并记录，错误取自 Row.Err
+func (db *InstrumentedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return observeQueryRow(ctx, db.obs, db.DB.QueryRowContext, query, args)
+}
+
+// Exec 以 context.Background 执行 ExecContext
+func (db *InstrumentedDB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// Query 以 context.Background 执行 QueryContext
+func (db *InstrumentedDB) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// QueryRow 以 context.Background 执行 QueryRowContext
+func (db *InstrumentedDB) QueryRow(query string, args ...any) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// BeginTx 开启事务，事务内的语句同样被记录
+func (db *InstrumentedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*InstrumentedTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &InstrumentedTx{Tx: tx, obs: db.obs}, nil
+}
+
+// Begin 以默认选项开启事务
+func (db *InstrumentedDB) Begin() (*InstrumentedTx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// InstrumentedTx InstrumentedDB 开启的事务
+type InstrumentedTx struct {
+	*sql.Tx
+	obs *queryObserver
+}
+
+// ExecContext 执行语句并记录
+func (tx *InstrumentedTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return observeExec(ctx, tx.obs, tx.Tx.ExecContext, query, args)
+}
+
+// QueryContext 执行查询并记录
+func (tx *InstrumentedTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return observeQuery(ctx, tx.obs, tx.Tx.QueryContext, query, args)
+}
+
+// QueryRowContext 执行单行查询并记录
+func (tx *InstrumentedTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return observeQueryRow(ctx, tx.obs, tx.Tx.QueryRowContext, query, args)
+}
+
+// Exec 以 context.Background 执行 ExecContext
+func (tx *InstrumentedTx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
+// Query 以 context.Background 执行 QueryContext
+func (tx *InstrumentedTx) Query(query string, args ...any) (*sql.Rows, error) {
+	return tx.QueryContext(context.Background(), query, args...)
+}
+
+// QueryRow 以 context.Background 执行 QueryRowContext
+func (tx *InstrumentedTx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.QueryRowContext(context.Background(), query, args...)
+}
+
+func observeExec(ctx context.Context, obs *queryObserver, exec func(context.Context, string, ...any) (sql.Result, error), query string, args []any) (sql.Result, error) {
+	var res sql.Result
+	err := obs.observe(ctx, query, args, func(ctx context.Context) (err error) {
+		res, err = exec(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+func observeQuery(ctx context.Context, obs *queryObserver, query func(context.Context, string, ...any) (*sql.Rows, error), statement string, args []any) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := obs.observe(ctx, statement, args, func(ctx context.Context) (err error) {
+		rows, err = query(ctx, statement, args...)
+		return err
+	})
+	return rows, err
+}
+
+func observeQueryRow(ctx context.Context, obs *queryObserver, queryRow func(context.Context, string, ...any) *sql.Row, query string, args []any) *sql.Row {
+	var row *sql.Row
+	obs.observe(ctx, query, args, func(ctx context.Context) error {
+		row = queryRow(ctx, query, args...)
+		return row.Err()
+	})
+	return row
+}
+
+// dbSystem 将 ent 方言映射为 OpenTelemetry db.system 取值
+func dbSystem(name string) string {
+	switch name {
+	case dialect.Postgres:
+		return "postgresql"
+	default:
+		return name
+	}
+}
+
+var (
+	// placeholderList 匹配 ($1, $2)、(?, ?) 等占位符列表
+	placeholderList = regexp.MustCompile(`\(\s*(?:\?|\$\d+)(?:\s*,\s*(?:\?|\$\d+))*\s*\)`)
+	// repeatedLists 匹配多行 VALUES 折叠后的 (...), (...)
+	repeatedLists = regexp.MustCompile(`\(\.\.\.\)(?:\s*,\s*\(\.\.\.\))+`)
+)
+
+// SanitizeSQL 将语句中的字符串与数字字面量替换为 ?，去除注释并合并空白；
+// IN 列表与多行 VALUES 的占位符折叠为 (...)，使参数个数不同的同类语句
+// 得到相同结果，可安全用作监控键与 Span 属性
+func SanitizeSQL(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query))
+	space := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			// 字符串字面量，'' 为转义的单引号
+			for i++; i < len(query); i++ {
+				if query[i] == '\'' {
+					if i+1 < len(query) && query[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			c = '?'
+		case c == '"' || c == '`':
+			// 带引号的标识符原样保留
+			end := len(query) - 1
+			if j := strings.IndexByte(query[i+1:], c); j >= 0 {
+				end = i + 1 + j
+			}
+			if space && sb.Len() > 0 {
+				sb.WriteByte(' ')
+			}
+			space = false
+			sb.WriteString(query[i : end+1])
+			i = end
+			continue
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			space = true
+			continue
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 3
+			}
+			space = true
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		case isDigit(c) && !continuesIdentifier(query, i):
+			for i+1 < len(query) && (isDigit(query[i+1]) || query[i+1] == '.') {
+				i++
+			}
+			c = '?'
+		}
+		if space && sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		space = false
+		sb.WriteByte(c)
+	}
+
+	out := placeholderList.ReplaceAllString(sb.String(), "(...)")
+	return repeatedLists.ReplaceAllString(out, "(...)")
+}
+
+// continuesIdentifier 判断 query[i] 处的数字是否属于标识符或 $n 占位符
+func continuesIdentifier(query string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	p := query[i-1]
+	return p == '_' || p == '$' || isDigit(p) || (p|0x20 >= 'a' && p|0x20 <= 'z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// sqlOperation 返回语句的首个关键字（大写），如 SELECT、INSERT
+func sqlOperation(statement string) string {
+	op, _, _ := strings.Cut(strings.TrimLeft(statement, "( "), " ")
+	return strings.ToUpper(op)
+}
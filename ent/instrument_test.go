@@ -0,0 +1,181 @@
+package ent
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/tracing"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*tracing.Span
+}
+
+func (r *spanRecorder) OnEnd(span *tracing.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func (r *spanRecorder) Shutdown(context.Context) error { return nil }
+
+func newInstrumentOptions(t *testing.T) (InstrumentOptions, *spanRecorder) {
+	t.Helper()
+	rec := &spanRecorder{}
+	tracer, err := tracing.NewTracer(tracing.TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return InstrumentOptions{
+		Monitor: NewQueryMonitor(),
+		Metrics: metrics.NewCollector(),
+		Tracer:  tracer,
+	}, rec
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:instrument-"+uuid.NewString()+"?mode=memory&cache=shared&_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM users WHERE email = 'a@b.c' AND age > 30":                    "SELECT * FROM users WHERE email = ? AND age > ?",
+		"SELECT \"t1\".\"id\"\n  FROM \"users\" AS \"t1\" WHERE id IN ($1, $2, $3)": `SELECT "t1"."id" FROM "users" AS "t1" WHERE id IN (...)`,
+		"INSERT INTO logs (msg) VALUES ($1), ($2), ($3)":                            "INSERT INTO logs (msg) VALUES (...)",
+		"UPDATE t SET name = 'it''s' /* note */ WHERE col2 = 4.5 -- trailing":       "UPDATE t SET name = ? WHERE col2 = ?",
+		"SELECT v FROM `tbl1` LIMIT 10":                                             "SELECT v FROM `tbl1` LIMIT ?",
+	}
+	for in, want := range tests {
+		if got := SanitizeSQL(in); got != want {
+			t.Errorf("SanitizeSQL(%q)\n got: %s\nwant: %s", in, got, want)
+		}
+	}
+	if SanitizeSQL("SELECT * FROM t WHERE id IN ($1)") != SanitizeSQL("SELECT * FROM t WHERE id IN ($1, $2)") {
+		t.Error("IN lists of different length should sanitize to the same statement")
+	}
+}
+
+func TestInstrumentedDriverRecordsEntQueries(t *testing.T) {
+	opts, rec := newInstrumentOptions(t)
+	drv := NewInstrumentedDriver(entsql.OpenDB(dialect.SQLite, openTestDB(t)), opts)
+	client := NewClient(Driver(drv))
+	defer client.Close()
+	ctx := context.Background()
+
+	if err := client.Schema.Create(ctx); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	rec.mu.Lock()
+	rec.spans = nil
+	rec.mu.Unlock()
+	opts.Metrics.Reset()
+
+	tx, err := client.Tx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.CasbinPolicy.Create().SetPtype("p").SetV0("alice").SetV1("/data").SetV2("read").Save(ctx); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := client.CasbinPolicy.Query().Count(ctx); err != nil || n != 1 {
+		t.Fatalf("count = %d, %v", n, err)
+	}
+
+	ops := map[string]bool{}
+	for _, span := range rec.spans {
+		if span.Name != "db.query" || span.Kind != tracing.SpanKindClient {
+			t.Errorf("span %s kind %v, want db.query client span", span.Name, span.Kind)
+		}
+		if span.Attributes["db.system"] != dialect.SQLite {
+			t.Errorf("db.system = %v", span.Attributes["db.system"])
+		}
+		if stmt, _ := span.Attributes["db.statement"].(string); strings.Contains(stmt, "alice") {
+			t.Errorf("statement leaks parameter values: %s", stmt)
+		}
+		ops[span.Attributes["db.operation"].(string)] = true
+	}
+	if !ops["INSERT"] || !ops["SELECT"] {
+		t.Errorf("operations = %v, want INSERT (in tx) and SELECT", ops)
+	}
+
+	var recorded int64
+	for _, span := range rec.spans {
+		if stats := opts.Monitor.GetStats(span.Attributes["db.statement"].(string)); stats != nil {
+			recorded = max(recorded, stats.Count)
+		}
+	}
+	if recorded == 0 {
+		t.Error("QueryMonitor should have stats keyed by the sanitized statement")
+	}
+	var queries float64
+	for _, m := range opts.Metrics.GetMetrics() {
+		if m.Name == "db_queries_total" {
+			queries += m.Value
+		}
+	}
+	if queries != float64(len(rec.spans)) {
+		t.Errorf("db_queries_total = %v, want %d", queries, len(rec.spans))
+	}
+}
+
+func TestInstrumentedDBRecordsErrors(t *testing.T) {
+	opts, rec := newInstrumentOptions(t)
+	db := NewInstrumentedDB(openTestDB(t), opts)
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO items (name) VALUES (?)", "widget"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var name string
+	if err := db.QueryRowContext(ctx, "SELECT name FROM items WHERE id = 1").Scan(&name); err != nil || name != "widget" {
+		t.Fatalf("name = %q, %v", name, err)
+	}
+	if _, err := db.QueryContext(ctx, "SELECT nope FROM missing"); err == nil {
+		t.Fatal("expected error for missing table")
+	}
+
+	if len(rec.spans) != 4 {
+		t.Fatalf("got %d spans, want 4", len(rec.spans))
+	}
+	if got := rec.spans[2].Attributes["db.statement"]; got != "SELECT name FROM items WHERE id = ?" {
+		t.Errorf("statement = %v", got)
+	}
+	failed := rec.spans[3]
+	if failed.Status.Code != tracing.StatusCodeError || len(failed.Events) == 0 {
+		t.Errorf("failed span = %+v, want error status with exception event", failed.Status)
+	}
+	if stats := opts.Monitor.GetStats("SELECT nope FROM missing"); stats == nil || stats.Errors != 1 {
+		t.Errorf("monitor stats = %+v, want one error", stats)
+	}
+	if m := opts.Metrics.GetMetric(MetricDBQueryErrors, map[string]string{"operation": "SELECT"}); m == nil || m.Value != 1 {
+		t.Errorf("%s = %+v, want 1", MetricDBQueryErrors, m)
+	}
+}
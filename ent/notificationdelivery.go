@@ -0,0 +1,235 @@
+// Code generated by ent, DO NOT EDIT.
+
+package ent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"github.com/leeforge/framework/ent/notificationdelivery"
+)
+
+// NotificationDelivery is the model entity for the NotificationDelivery schema.
+type NotificationDelivery struct {
+	config `json:"-"`
+	// ID of the ent.
+	ID int `json:"id,omitempty"`
+	// Notification ID, stable across retries
+	NotificationID string `json:"notification_id,omitempty"`
+	// Channel name the notification is sent through
+	Channel string `json:"channel,omitempty"`
+	// Channel specific recipient, e.g. a phone number
+	Recipient string `json:"recipient,omitempty"`
+	// Template the payload was rendered from
+	Template string `json:"template,omitempty"`
+	// Delivery status
+	Status notificationdelivery.Status `json:"status,omitempty"`
+	// Number of delivery attempts
+	Attempts int `json:"attempts,omitempty"`
+	// Message ID returned by the provider
+	ProviderID string `json:"provider_id,omitempty"`
+	// Error of the last failed attempt
+	LastError string `json:"last_error,omitempty"`
+	// Caller supplied metadata, e.g. tenant_id
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Time the notification was accepted
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// Time of the last status change
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	// Time the provider accepted the notification
+	SentAt       *time.Time `json:"sent_at,omitempty"`
+	selectValues sql.SelectValues
+}
+
+// scanValues returns the types for scanning values from sql.Rows.
+func (*NotificationDelivery) scanValues(columns []string) ([]any, error) {
+	values := make([]any, len(columns))
+	for i := range columns {
+		switch columns[i] {
+		case notificationdelivery.FieldMetadata:
+			values[i] = new([]byte)
+		case notificationdelivery.FieldID, notificationdelivery.FieldAttempts:
+			values[i] = new(sql.NullInt64)
+		case notificationdelivery.FieldNotificationID, notificationdelivery.FieldChannel, notificationdelivery.FieldRecipient, notificationdelivery.FieldTemplate, notificationdelivery.FieldStatus, notificationdelivery.FieldProviderID, notificationdelivery.FieldLastError:
+			values[i] = new(sql.NullString)
+		case notificationdelivery.FieldCreatedAt, notificationdelivery.FieldUpdatedAt, notificationdelivery.FieldSentAt:
+			values[i] = new(sql.NullTime)
+		default:
+			values[i] = new(sql.UnknownType)
+		}
+	}
+	return values, nil
+}
+
+// assignValues assigns the values that were returned from sql.Rows (after scanning)
+// to the NotificationDelivery fields.
+func (_m *NotificationDelivery) assignValues(columns []string, values []any) error {
+	if m, n := len(values), len(columns); m < n {
+		return fmt.Errorf("mismatch number of scan values: %d != %d", m, n)
+	}
+	for i := range columns {
+		switch columns[i] {
+		case notificationdelivery.FieldID:
+			value, ok := values[i].(*sql.NullInt64)
+			if !ok {
+				return fmt.Errorf("unexpected type %T for field id", value)
+			}
+			_m.ID = int(value.Int64)
+		case notificationdelivery.FieldNotificationID:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field notification_id", values[i])
+			} else if value.Valid {
+				_m.NotificationID = value.String
+			}
+		case notificationdelivery.FieldChannel:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field channel", values[i])
+			} else if value.Valid {
+				_m.Channel = value.String
+			}
+		case notificationdelivery.FieldRecipient:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field recipient", values[i])
+			} else if value.Valid {
+				_m.Recipient = value.String
+			}
+		case notificationdelivery.FieldTemplate:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field template", values[i])
+			} else if value.Valid {
+				_m.Template = value.String
+			}
+		case notificationdelivery.FieldStatus:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field status", values[i])
+			} else if value.Valid {
+				_m.Status = notificationdelivery.Status(value.String)
+			}
+		case notificationdelivery.FieldAttempts:
+			if value, ok := values[i].(*sql.NullInt64); !ok {
+				return fmt.Errorf("unexpected type %T for field attempts", values[i])
+			} else if value.Valid {
+				_m.Attempts = int(value.Int64)
+			}
+		case notificationdelivery.FieldProviderID:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field provider_id", values[i])
+			} else if value.Valid {
+				_m.ProviderID = value.String
+			}
+		case notificationdelivery.FieldLastError:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field last_error", values[i])
+			} else if value.Valid {
+				_m.LastError = value.String
+			}
+		case notificationdelivery.FieldMetadata:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field metadata", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.Metadata); err != nil {
+					return fmt.Errorf("unmarshal field metadata: %w", err)
+				}
+			}
+		case notificationdelivery.FieldCreatedAt:
+			if value, ok := values[i].(*sql.NullTime); !ok {
+				return fmt.Errorf("unexpected type %T for field created_at", values[i])
+			} else if value.Valid {
+				_m.CreatedAt = value.Time
+			}
+		case notificationdelivery.FieldUpdatedAt:
+			if value, ok := values[i].(*sql.NullTime); !ok {
+				return fmt.Errorf("unexpected type %T for field updated_at", values[i])
+			} else if value.Valid {
+				_m.UpdatedAt = value.Time
+			}
+		case notificationdelivery.FieldSentAt:
+			if value, ok := values[i].(*sql.NullTime); !ok {
+				return fmt.Errorf("unexpected type %T for field sent_at", values[i])
+			} else if value.Valid {
+				_m.SentAt = new(time.Time)
+				*_m.SentAt = value.Time
+			}
+		default:
+			_m.selectValues.Set(columns[i], values[i])
+		}
+	}
+	return nil
+}
+
+// Value returns the ent.Value that was dynamically selected and assigned to the NotificationDelivery.
+// This includes values selected through modifiers, order, etc.
+func (_m *NotificationDelivery) Value(name string) (ent.Value, error) {
+	return _m.selectValues.Get(name)
+}
+
+// Update returns a builder for updating this NotificationDelivery.
+// Note that you need to call NotificationDelivery.Unwrap() before calling this method if this NotificationDelivery
+// was returned from a transaction, and the transaction was committed or rolled back.
+func (_m *NotificationDelivery) Update() *NotificationDeliveryUpdateOne {
+	return NewNotificationDeliveryClient(_m.config).UpdateOne(_m)
+}
+
+// Unwrap unwraps the NotificationDelivery entity that was returned from a transaction after it was closed,
+// so that all future queries will be executed through the driver which created the transaction.
+func (_m *NotificationDelivery) Unwrap() *NotificationDelivery {
+	_tx, ok := _m.config.driver.(*txDriver)
+	if !ok {
+		panic("ent: NotificationDelivery is not a transactional entity")
+	}
+	_m.config.driver = _tx.drv
+	return _m
+}
+
+// String implements the fmt.Stringer.
+func (_m *NotificationDelivery) String() string {
+	var builder strings.Builder
+	builder.WriteString("NotificationDelivery(")
+	builder.WriteString(fmt.Sprintf("id=%v, ", _m.ID))
+	builder.WriteString("notification_id=")
+	builder.WriteString(_m.NotificationID)
+	builder.WriteString(", ")
+	builder.WriteString("channel=")
+	builder.WriteString(_m.Channel)
+	builder.WriteString(", ")
+	builder.WriteString("recipient=")
+	builder.WriteString(_m.Recipient)
+	builder.WriteString(", ")
+	builder.WriteString("template=")
+	builder.WriteString(_m.Template)
+	builder.WriteString(", ")
+	builder.WriteString("status=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Status))
+	builder.WriteString(", ")
+	builder.WriteString("attempts=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Attempts))
+	builder.WriteString(", ")
+	builder.WriteString("provider_id=")
+	builder.WriteString(_m.ProviderID)
+	builder.WriteString(", ")
+	builder.WriteString("last_error=")
+	builder.WriteString(_m.LastError)
+	builder.WriteString(", ")
+	builder.WriteString("metadata=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Metadata))
+	builder.WriteString(", ")
+	builder.WriteString("created_at=")
+	builder.WriteString(_m.CreatedAt.Format(time.ANSIC))
+	builder.WriteString(", ")
+	builder.WriteString("updated_at=")
+	builder.WriteString(_m.UpdatedAt.Format(time.ANSIC))
+	builder.WriteString(", ")
+	if v := _m.SentAt; v != nil {
+		builder.WriteString("sent_at=")
+		builder.WriteString(v.Format(time.ANSIC))
+	}
+	builder.WriteByte(')')
+	return builder.String()
+}
+
+// NotificationDeliveries is a parsable slice of NotificationDelivery.
+type NotificationDeliveries []*NotificationDelivery
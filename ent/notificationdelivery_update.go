@@ -0,0 +1,528 @@
+// Code generated by ent, DO NOT EDIT.
+
+package ent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/dialect/sql/sqlgraph"
+	"entgo.io/ent/schema/field"
+	"github.com/leeforge/framework/ent/notificationdelivery"
+	"github.com/leeforge/framework/ent/predicate"
+)
+
+// NotificationDeliveryUpdate is the builder for updating NotificationDelivery entities.
+type NotificationDeliveryUpdate struct {
+	config
+	hooks    []Hook
+	mutation *NotificationDeliveryMutation
+}
+
+// Where appends a list predicates to the NotificationDeliveryUpdate builder.
+func (_u *NotificationDeliveryUpdate) Where(ps ...predicate.NotificationDelivery) *NotificationDeliveryUpdate {
+	_u.mutation.Where(ps...)
+	return _u
+}
+
+// SetStatus sets the "status" field.
+func (_u *NotificationDeliveryUpdate) SetStatus(v notificationdelivery.Status) *NotificationDeliveryUpdate {
+	_u.mutation.SetStatus(v)
+	return _u
+}
+
+// SetNillableStatus sets the "status" field if the given value is not nil.
+func (_u *NotificationDeliveryUpdate) SetNillableStatus(v *notificationdelivery.Status) *NotificationDeliveryUpdate {
+	if v != nil {
+		_u.SetStatus(*v)
+	}
+	return _u
+}
+
+// SetAttempts sets the "attempts" field.
+func (_u *NotificationDeliveryUpdate) SetAttempts(v int) *NotificationDeliveryUpdate {
+	_u.mutation.ResetAttempts()
+	_u.mutation.SetAttempts(v)
+	return _u
+}
+
+// SetNillableAttempts sets the "attempts" field if the given value is not nil.
+func (_u *NotificationDeliveryUpdate) SetNillableAttempts(v *int) *NotificationDeliveryUpdate {
+	if v != nil {
+		_u.SetAttempts(*v)
+	}
+	return _u
+}
+
+// AddAttempts adds value to the "attempts" field.
+func (_u *NotificationDeliveryUpdate) AddAttempts(v int) *NotificationDeliveryUpdate {
+	_u.mutation.AddAttempts(v)
+	return _u
+}
+
+// SetProviderID sets the "provider_id" field.
+func (_u *NotificationDeliveryUpdate) SetProviderID(v string) *NotificationDeliveryUpdate {
+	_u.mutation.SetProviderID(v)
+	return _u
+}
+
+// SetNillableProviderID sets the "provider_id" field if the given value is not nil.
+func (_u *NotificationDeliveryUpdate) SetNillableProviderID(v *string) *NotificationDeliveryUpdate {
+	if v != nil {
+		_u.SetProviderID(*v)
+	}
+	return _u
+}
+
+// ClearProviderID clears the value of the "provider_id" field.
+func (_u *NotificationDeliveryUpdate) ClearProviderID() *NotificationDeliveryUpdate {
+	_u.mutation.ClearProviderID()
+	return _u
+}
+
+// SetLastError sets the "last_error" field.
+func (_u *NotificationDeliveryUpdate) SetLastError(v string) *NotificationDeliveryUpdate {
+	_u.mutation.SetLastError(v)
+	return _u
+}
+
+// SetNillableLastError sets the "last_error" field if the given value is not nil.
+func (_u *NotificationDeliveryUpdate) SetNillableLastError(v *string) *NotificationDeliveryUpdate {
+	if v != nil {
+		_u.SetLastError(*v)
+	}
+	return _u
+}
+
+// ClearLastError clears the value of the "last_error" field.
+func (_u *NotificationDeliveryUpdate) ClearLastError() *NotificationDeliveryUpdate {
+	_u.mutation.ClearLastError()
+	return _u
+}
+
+// SetMetadata sets the "metadata" field.
+func (_u *NotificationDeliveryUpdate) SetMetadata(v map[string]string) *NotificationDeliveryUpdate {
+	_u.mutation.SetMetadata(v)
+	return _u
+}
+
+// ClearMetadata clears the value of the "metadata" field.
+func (_u *NotificationDeliveryUpdate) ClearMetadata() *NotificationDeliveryUpdate {
+	_u.mutation.ClearMetadata()
+	return _u
+}
+
+// SetUpdatedAt sets the "updated_at" field.
+func (_u *NotificationDeliveryUpdate) SetUpdatedAt(v time.Time) *NotificationDeliveryUpdate {
+	_u.mutation.SetUpdatedAt(v)
+	return _u
+}
+
+// SetSentAt sets the "sent_at" field.
+func (_u *NotificationDeliveryUpdate) SetSentAt(v time.Time) *NotificationDeliveryUpdate {
+	_u.mutation.SetSentAt(v)
+	return _u
+}
+
+// SetNillableSentAt sets the "sent_at" field if the given value is not nil.
+func (_u *NotificationDeliveryUpdate) SetNillableSentAt(v *time.Time) *NotificationDeliveryUpdate {
+	if v != nil {
+		_u.SetSentAt(*v)
+	}
+	return _u
+}
+
+// ClearSentAt clears the value of the "sent_at" field.
+func (_u *NotificationDeliveryUpdate) ClearSentAt() *NotificationDeliveryUpdate {
+	_u.mutation.ClearSentAt()
+	return _u
+}
+
+// Mutation returns the NotificationDeliveryMutation object of the builder.
+func (_u *NotificationDeliveryUpdate) Mutation() *NotificationDeliveryMutation {
+	return _u.mutation
+}
+
+// Save executes the query and returns the number of nodes affected by the update operation.
+func (_u *NotificationDeliveryUpdate) Save(ctx context.Context) (int, error) {
+	_u.defaults()
+	return withHooks(ctx, _u.sqlSave, _u.mutation, _u.hooks)
+}
+
+// SaveX is like Save, but panics if an error occurs.
+func (_u *NotificationDeliveryUpdate) SaveX(ctx context.Context) int {
+	affected, err := _u.Save(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return affected
+}
+
+// Exec executes the query.
+func (_u *NotificationDeliveryUpdate) Exec(ctx context.Context) error {
+	_, err := _u.Save(ctx)
+	return err
+}
+
+// ExecX is like Exec, but panics if an error occurs.
+func (_u *NotificationDeliveryUpdate) ExecX(ctx context.Context) {
+	if err := _u.Exec(ctx); err != nil {
+		panic(err)
+	}
+}
+
+// defaults sets the default values of the builder before save.
+func (_u *NotificationDeliveryUpdate) defaults() {
+	if _, ok := _u.mutation.UpdatedAt(); !ok {
+		v := notificationdelivery.UpdateDefaultUpdatedAt()
+		_u.mutation.SetUpdatedAt(v)
+	}
+}
+
+// check runs all checks and user-defined validators on the builder.
+func (_u *NotificationDeliveryUpdate) check() error {
+	if v, ok := _u.mutation.Status(); ok {
+		if err := notificationdelivery.StatusValidator(v); err != nil {
+			return &ValidationError{Name: "status", err: fmt.Errorf(`ent: validator failed for field "NotificationDelivery.status": %w`, err)}
+		}
+	}
+	if v, ok := _u.mutation.Attempts(); ok {
+		if err := notificationdelivery.AttemptsValidator(v); err != nil {
+			return &ValidationError{Name: "attempts", err: fmt.Errorf(`ent: validator failed for field "NotificationDelivery.attempts": %w`, err)}
+		}
+	}
+	return nil
+}
+
+func (_u *NotificationDeliveryUpdate) sqlSave(ctx context.Context) (_node int, err error) {
+	if err := _u.check(); err != nil {
+		return _node, err
+	}
+	_spec := sqlgraph.NewUpdateSpec(notificationdelivery.Table, notificationdelivery.Columns, sqlgraph.NewFieldSpec(notificationdelivery.FieldID, field.TypeInt))
+	if ps := _u.mutation.predicates; len(ps) > 0 {
+		_spec.Predicate = func(selector *sql.Selector) {
+			for i := range ps {
+				ps[i](selector)
+			}
+		}
+	}
+	if value, ok := _u.mutation.Status(); ok {
+		_spec.SetField(notificationdelivery.FieldStatus, field.TypeEnum, value)
+	}
+	if value, ok := _u.mutation.Attempts(); ok {
+		_spec.SetField(notificationdelivery.FieldAttempts, field.TypeInt, value)
+	}
+	if value, ok := _u.mutation.AddedAttempts(); ok {
+		_spec.AddField(notificationdelivery.FieldAttempts, field.TypeInt, value)
+	}
+	if value, ok := _u.mutation.ProviderID(); ok {
+		_spec.SetField(notificationdelivery.FieldProviderID, field.TypeString, value)
+	}
+	if _u.mutation.ProviderIDCleared() {
+		_spec.ClearField(notificationdelivery.FieldProviderID, field.TypeString)
+	}
+	if value, ok := _u.mutation.LastError(); ok {
+		_spec.SetField(notificationdelivery.FieldLastError, field.TypeString, value)
+	}
+	if _u.mutation.LastErrorCleared() {
+		_spec.ClearField(notificationdelivery.FieldLastError, field.TypeString)
+	}
+	if value, ok := _u.mutation.Metadata(); ok {
+		_spec.SetField(notificationdelivery.FieldMetadata, field.TypeJSON, value)
+	}
+	if _u.mutation.MetadataCleared() {
+		_spec.ClearField(notificationdelivery.FieldMetadata, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.UpdatedAt(); ok {
+		_spec.SetField(notificationdelivery.FieldUpdatedAt, field.TypeTime, value)
+	}
+	if value, ok := _u.mutation.SentAt(); ok {
+		_spec.SetField(notificationdelivery.FieldSentAt, field.TypeTime, value)
+	}
+	if _u.mutation.SentAtCleared() {
+		_spec.ClearField(notificationdelivery.FieldSentAt, field.TypeTime)
+	}
+	if _node, err = sqlgraph.UpdateNodes(ctx, _u.driver, _spec); err != nil {
+		if _, ok := err.(*sqlgraph.NotFoundError); ok {
+			err = &NotFoundError{notificationdelivery.Label}
+		} else if sqlgraph.IsConstraintError(err) {
+			err = &ConstraintError{msg: err.Error(), wrap: err}
+		}
+		return 0, err
+	}
+	_u.mutation.done = true
+	return _node, nil
+}
+
+// NotificationDeliveryUpdateOne is the builder for updating a single NotificationDelivery entity.
+type NotificationDeliveryUpdateOne struct {
+	config
+	fields   []string
+	hooks    []Hook
+	mutation *NotificationDeliveryMutation
+}
+
+// SetStatus sets the "status" field.
+func (_u *NotificationDeliveryUpdateOne) SetStatus(v notificationdelivery.Status) *NotificationDeliveryUpdateOne {
+	_u.mutation.SetStatus(v)
+	return _u
+}
+
+// SetNillableStatus sets the "status" field if the given value is not nil.
+func (_u *NotificationDeliveryUpdateOne) SetNillableStatus(v *notificationdelivery.Status) *NotificationDeliveryUpdateOne {
+	if v != nil {
+		_u.SetStatus(*v)
+	}
+	return _u
+}
+
+// SetAttempts sets the "attempts" field.
+func (_u *NotificationDeliveryUpdateOne) SetAttempts(v int) *NotificationDeliveryUpdateOne {
+	_u.mutation.ResetAttempts()
+	_u.mutation.SetAttempts(v)
+	return _u
+}
+
+// SetNillableAttempts sets the "attempts" field if the given value is not nil.
+func (_u *NotificationDeliveryUpdateOne) SetNillableAttempts(v *int) *NotificationDeliveryUpdateOne {
+	if v != nil {
+		_u.SetAttempts(*v)
+	}
+	return _u
+}
+
+// AddAttempts adds value to the "attempts" field.
+func (_u *NotificationDeliveryUpdateOne) AddAttempts(v int) *NotificationDeliveryUpdateOne {
+	_u.mutation.AddAttempts(v)
+	return _u
+}
+
+// SetProviderID sets the "provider_id" field.
+func (_u *NotificationDeliveryUpdateOne) SetProviderID(v string) *NotificationDeliveryUpdateOne {
+	_u.mutation.SetProviderID(v)
+	return _u
+}
+
+// SetNillableProviderID sets the "provider_id" field if the given value is not nil.
+func (_u *NotificationDeliveryUpdateOne) SetNillableProviderID(v *string) *NotificationDeliveryUpdateOne {
+	if v != nil {
+		_u.SetProviderID(*v)
+	}
+	return _u
+}
+
+// ClearProviderID clears the value of the "provider_id" field.
+func (_u *NotificationDeliveryUpdateOne) ClearProviderID() *NotificationDeliveryUpdateOne {
+	_u.mutation.ClearProviderID()
+	return _u
+}
+
+// SetLastError sets the "last_error" field.
+func (_u *NotificationDeliveryUpdateOne) SetLastError(v string) *NotificationDeliveryUpdateOne {
+	_u.mutation.SetLastError(v)
+	return _u
+}
+
+// SetNillableLastError sets the "last_error" field if the given value is not nil.
+func (_u *NotificationDeliveryUpdateOne) SetNillableLastError(v *string) *NotificationDeliveryUpdateOne {
+	if v != nil {
+		_u.SetLastError(*v)
+	}
+	return _u
+}
+
+// ClearLastError clears the value of the "last_error" field.
+func (_u *NotificationDeliveryUpdateOne) ClearLastError() *NotificationDeliveryUpdateOne {
+	_u.mutation.ClearLastError()
+	return _u
+}
+
+// SetMetadata sets the "metadata" field.
+func (_u *NotificationDeliveryUpdateOne) SetMetadata(v map[string]string) *NotificationDeliveryUpdateOne {
+	_u.mutation.SetMetadata(v)
+	return _u
+}
+
+// ClearMetadata clears the value of the "metadata" field.
+func (_u *NotificationDeliveryUpdateOne) ClearMetadata() *NotificationDeliveryUpdateOne {
+	_u.mutation.ClearMetadata()
+	return _u
+}
+
+// SetUpdatedAt sets the "updated_at" field.
+func (_u *NotificationDeliveryUpdateOne) SetUpdatedAt(v time.Time) *NotificationDeliveryUpdateOne {
+	_u.mutation.SetUpdatedAt(v)
+	return _u
+}
+
+// SetSentAt sets the "sent_at" field.
+func (_u *NotificationDeliveryUpdateOne) SetSentAt(v time.Time) *NotificationDeliveryUpdateOne {
+	_u.mutation.SetSentAt(v)
+	return _u
+}
+
+// SetNillableSentAt sets the "sent_at" field if the given value is not nil.
+func (_u *NotificationDeliveryUpdateOne) SetNillableSentAt(v *time.Time) *NotificationDeliveryUpdateOne {
+	if v != nil {
+		_u.SetSentAt(*v)
+	}
+	return _u
+}
+
+// ClearSentAt clears the value of the "sent_at" field.
+func (_u *NotificationDeliveryUpdateOne) ClearSentAt() *NotificationDeliveryUpdateOne {
+	_u.mutation.ClearSentAt()
+	return _u
+}
+
+// Mutation returns the NotificationDeliveryMutation object of the builder.
+func (_u *NotificationDeliveryUpdateOne) Mutation() *NotificationDeliveryMutation {
+	return _u.mutation
+}
+
+// Where appends a list predicates to the NotificationDeliveryUpdate builder.
+func (_u *NotificationDeliveryUpdateOne) Where(ps ...predicate.NotificationDelivery) *NotificationDeliveryUpdateOne {
+	_u.mutation.Where(ps...)
+	return _u
+}
+
+// Select allows selecting one or more fields (columns) of the returned entity.
+// The default is selecting all fields defined in the entity schema.
+func (_u *NotificationDeliveryUpdateOne) Select(field string, fields ...string) *NotificationDeliveryUpdateOne {
+	_u.fields = append([]string{field}, fields...)
+	return _u
+}
+
+// Save executes the query and returns the updated NotificationDelivery entity.
+func (_u *NotificationDeliveryUpdateOne) Save(ctx context.Context) (*NotificationDelivery, error) {
+	_u.defaults()
+	return withHooks(ctx, _u.sqlSave, _u.mutation, _u.hooks)
+}
+
+// SaveX is like Save, but panics if an error occurs.
+func (_u *NotificationDeliveryUpdateOne) SaveX(ctx context.Context) *NotificationDelivery {
+	node, err := _u.Save(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return node
+}
+
+// Exec executes the query on the entity.
+func (_u *NotificationDeliveryUpdateOne) Exec(ctx context.Context) error {
+	_, err := _u.Save(ctx)
+	return err
+}
+
+// ExecX is like Exec, but panics if an error occurs.
+func (_u *NotificationDeliveryUpdateOne) ExecX(ctx context.Context) {
+	if err := _u.Exec(ctx); err != nil {
+		panic(err)
+	}
+}
+
+// defaults sets the default values of the builder before save.
+func (_u *NotificationDeliveryUpdateOne) defaults() {
+	if _, ok := _u.mutation.UpdatedAt(); !ok {
+		v := notificationdelivery.UpdateDefaultUpdatedAt()
+		_u.mutation.SetUpdatedAt(v)
+	}
+}
+
+// check runs all checks and user-defined validators on the builder.
+func (_u *NotificationDeliveryUpdateOne) check() error {
+	if v, ok := _u.mutation.Status(); ok {
+		if err := notificationdelivery.StatusValidator(v); err != nil {
+			return &ValidationError{Name: "status", err: fmt.Errorf(`ent: validator failed for field "NotificationDelivery.status": %w`, err)}
+		}
+	}
+	if v, ok := _u.mutation.Attempts(); ok {
+		if err := notificationdelivery.AttemptsValidator(v); err != nil {
+			return &ValidationError{Name: "attempts", err: fmt.Errorf(`ent: validator failed for field "NotificationDelivery.attempts": %w`, err)}
+		}
+	}
+	return nil
+}
+
+func (_u *NotificationDeliveryUpdateOne) sqlSave(ctx context.Context) (_node *NotificationDelivery, err error) {
+	if err := _u.check(); err != nil {
+		return _node, err
+	}
+	_spec := sqlgraph.NewUpdateSpec(notificationdelivery.Table, notificationdelivery.Columns, sqlgraph.NewFieldSpec(notificationdelivery.FieldID, field.TypeInt))
+	id, ok := _u.mutation.ID()
+	if !ok {
+		return nil, &ValidationError{Name: "id", err: errors.New(`ent: missing "NotificationDelivery.id" for update`)}
+	}
+	_spec.Node.ID.Value = id
+	if fields := _u.fields; len(fields) > 0 {
+		_spec.Node.Columns = make([]string, 0, len(fields))
+		_spec.Node.Columns = append(_spec.Node.Columns, notificationdelivery.FieldID)
+		for _, f := range fields {
+			if !notificationdelivery.ValidColumn(f) {
+				return nil, &ValidationError{Name: f, err: fmt.Errorf("ent: invalid field %q for query", f)}
+			}
+			if f != notificationdelivery.FieldID {
+				_spec.Node.Columns = append(_spec.Node.Columns, f)
+			}
+		}
+	}
+	if ps := _u.mutation.predicates; len(ps) > 0 {
+		_spec.Predicate = func(selector *sql.Selector) {
+			for i := range ps {
+				ps[i](selector)
+			}
+		}
+	}
+	if value, ok := _u.mutation.Status(); ok {
+		_spec.SetField(notificationdelivery.FieldStatus, field.TypeEnum, value)
+	}
+	if value, ok := _u.mutation.Attempts(); ok {
+		_spec.SetField(notificationdelivery.FieldAttempts, field.TypeInt, value)
+	}
+	if value, ok := _u.mutation.AddedAttempts(); ok {
+		_spec.AddField(notificationdelivery.FieldAttempts, field.TypeInt, value)
+	}
+	if value, ok := _u.mutation.ProviderID(); ok {
+		_spec.SetField(notificationdelivery.FieldProviderID, field.TypeString, value)
+	}
+	if _u.mutation.ProviderIDCleared() {
+		_spec.ClearField(notificationdelivery.FieldProviderID, field.TypeString)
+	}
+	if value, ok := _u.mutation.LastError(); ok {
+		_spec.SetField(notificationdelivery.FieldLastError, field.TypeString, value)
+	}
+	if _u.mutation.LastErrorCleared() {
+		_spec.ClearField(notificationdelivery.FieldLastError, field.TypeString)
+	}
+	if value, ok := _u.mutation.Metadata(); ok {
+		_spec.SetField(notificationdelivery.FieldMetadata, field.TypeJSON, value)
+	}
+	if _u.mutation.MetadataCleared() {
+		_spec.ClearField(notificationdelivery.FieldMetadata, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.UpdatedAt(); ok {
+		_spec.SetField(notificationdelivery.FieldUpdatedAt, field.TypeTime, value)
+	}
+	if value, ok := _u.mutation.SentAt(); ok {
+		_spec.SetField(notificationdelivery.FieldSentAt, field.TypeTime, value)
+	}
+	if _u.mutation.SentAtCleared() {
+		_spec.ClearField(notificationdelivery.FieldSentAt, field.TypeTime)
+	}
+	_node = &NotificationDelivery{config: _u.config}
+	_spec.Assign = _node.assignValues
+	_spec.ScanValues = _node.scanValues
+	if err = sqlgraph.UpdateNode(ctx, _u.driver, _spec); err != nil {
+		if _, ok := err.(*sqlgraph.NotFoundError); ok {
+			err = &NotFoundError{notificationdelivery.Label}
+		} else if sqlgraph.IsConstraintError(err) {
+			err = &ConstraintError{msg: err.Error(), wrap: err}
+		}
+		return nil, err
+	}
+	_u.mutation.done = true
+	return _node, nil
+}
@@ -0,0 +1,344 @@
+package ent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leeforge/framework/ent/outboxmessage"
+	"github.com/leeforge/framework/events"
+	"github.com/leeforge/framework/metrics"
+	"go.uber.org/zap"
+)
+
+// ErrOutboxTxRequired 发件箱只能在事务中写入，否则无法与业务数据保持原子性
+var ErrOutboxTxRequired = errors.New("ent: outbox messages must be saved inside a transaction")
+
+// Outbox 基于 OutboxMessage 表的事务发件箱，实现 events.Outbox
+//
+// 与 events.NewOutboxPublisher 配合使用：
+//
+//	tx, _ := client.Tx(ctx)
+//	ctx = ent.NewTxContext(ctx, tx)
+//	// ... 通过 tx 写业务数据 ...
+//	topic.Publish(ctx, events.NewOutboxPublisher(ent.NewOutbox()), event)
+//	tx.Commit()
+type Outbox struct{}
+
+var _ events.Outbox = (*Outbox)(nil)
+
+// NewOutbox 创建事务发件箱
+func NewOutbox() *Outbox {
+	return &Outbox{}
+}
+
+// Save 通过 ctx 中的事务（ent.NewTxContext）写入消息，随事务一起提交或回滚
+func (o *Outbox) Save(ctx context.Context, msgs ...*events.Message) error {
+	tx := TxFromContext(ctx)
+	if tx == nil {
+		return ErrOutboxTxRequired
+	}
+	return saveOutbox(ctx, tx, msgs)
+}
+
+// OutboxHook 返回一个变更钩子：变更成功后调用 build 生成事件，并在同一事务中写入发件箱
+//
+// 钩子所在的变更必须通过事务客户端执行，否则返回 ErrOutboxTxRequired。
+// build 返回空切片时不写入任何消息。
+//
+//	client.Media.Use(ent.OutboxHook(func(ctx context.Context, m ent.Mutation, v ent.Value) ([]*events.Message, error) {
+//		media, ok := v.(*ent.Media)
+//		if !ok {
+//			return nil, nil
+//		}
+//		msg, err := mediaCreated.Message(mediaEvent{ID: media.ID})
+//		return []*events.Message{msg}, err
+//	}))
+func OutboxHook(build func(ctx context.Context, m Mutation, v Value) ([]*events.Message, error)) Hook {
+	return func(next Mutator) Mutator {
+		return MutateFunc(func(ctx context.Context, m Mutation) (Value, error) {
+			v, err := next.Mutate(ctx, m)
+			if err != nil {
+				return v, err
+			}
+			msgs, err := build(ctx, m, v)
+			if err != nil {
+				return nil, err
+			}
+			if len(msgs) == 0 {
+				return v, nil
+			}
+			txm, ok := m.(interface{ Tx() (*Tx, error) })
+			if !ok {
+				return nil, ErrOutboxTxRequired
+			}
+			tx, err := txm.Tx()
+			if err != nil {
+				return nil, ErrOutboxTxRequired
+			}
+			if err := saveOutbox(ctx, tx, msgs); err != nil {
+				return nil, err
+			}
+			return v, nil
+		})
+	}
+}
+
+func saveOutbox(ctx context.Context, tx *Tx, msgs []*events.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	builders := make([]*OutboxMessageCreate, 0, len(msgs))
+	for _, msg := range msgs {
+		// 在写入时固定 ID、时间戳和请求/追踪上下文，中继投递时原样发布
+		events.Prepare(ctx, msg)
+		builders = append(builders, tx.OutboxMessage.Create().
+			SetMessageID(msg.ID).
+			SetTopic(msg.Topic).
+			SetKey(msg.Key).
+			SetHeaders(msg.Headers).
+			SetPayload(msg.Payload).
+			SetCreatedAt(msg.Timestamp))
+	}
+	if err := tx.OutboxMessage.CreateBulk(builders...).Exec(ctx); err != nil {
+		return fmt.Errorf("ent: save outbox messages: %w", err)
+	}
+	return nil
+}
+
+// OutboxRelayOptions 发件箱中继配置
+type OutboxRelayOptions struct {
+	BatchSize   int                             // 每批认领的消息数，默认 100
+	Interval    time.Duration                   // 没有待投递消息时的轮询间隔，默认 1s
+	Lease       time.Duration                   // 认领租期，中继在租期内未确认的消息会被重新投递，默认 30s
+	MaxAttempts int                             // 最大投递次数，用尽后标记为 dead，默认 10
+	Backoff     func(attempt int) time.Duration // 投递失败后的重试间隔，默认 1s 起指数增长，上限 5m
+	Logger      *zap.Logger
+	Metrics     *metrics.Collector
+}
+
+// OutboxRelay 发件箱中继：读取待投递消息，发布到事件总线后标记为已投递
+//
+// 投递语义为至少一次，消费方应按消息 ID 去重。多个实例可以同时运行：
+// 每条消息通过乐观更新认领，同一时刻只会被一个实例投递。
+// 相同 key 的消息按写入顺序投递，前一条未投递成功时后续消息会等待。
+type OutboxRelay struct {
+	client    *Client
+	publisher events.Publisher
+	opts      OutboxRelayOptions
+	logger    *zap.Logger
+}
+
+// NewOutboxRelay 创建发件箱中继
+func NewOutboxRelay(client *Client, publisher events.Publisher, opts OutboxRelayOptions) *OutboxRelay {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = 30 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 10
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = defaultOutboxBackoff
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &OutboxRelay{client: client, publisher: publisher, opts: opts, logger: logger}
+}
+
+// defaultOutboxBackoff 1s、2s、4s……上限 5 分钟
+func defaultOutboxBackoff(attempt int) time.Duration {
+	d := time.Second << (attempt - 1)
+	if d <= 0 || d > 5*time.Minute {
+		return 5 * time.Minute
+	}
+	return d
+}
+
+// Run 持续投递直到 ctx 取消；一批满载时立即处理下一批，否则等待 Interval
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	for {
+		n, err := r.Relay(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Warn("outbox relay failed", zap.Error(err))
+		}
+		if err == nil && n >= r.opts.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.opts.Interval):
+		}
+	}
+}
+
+// Relay 认领并投递一批到期消息，返回本批认领的消息数
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	now := time.Now()
+	rows, err := r.client.OutboxMessage.Query().
+		Where(
+			outboxmessage.StatusEQ(outboxmessage.StatusPending),
+			outboxmessage.AvailableAtLTE(now),
+		).
+		Order(Asc(outboxmessage.FieldID)).
+		Limit(r.opts.BatchSize).
+		All(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("ent: query outbox: %w", err)
+	}
+
+	claimed := 0
+	blocked := make(map[string]struct{}) // 本批中投递失败的 key，后续同 key 消息不再投递
+	for _, row := range rows {
+		if row.Key != "" {
+			if _, ok := blocked[row.Key]; ok {
+				continue
+			}
+			earlier, err := r.client.OutboxMessage.Query().
+				Where(
+					outboxmessage.KeyEQ(row.Key),
+					outboxmessage.StatusEQ(outboxmessage.StatusPending),
+					outboxmessage.IDLT(row.ID),
+				).
+				Exist(ctx)
+			if err != nil {
+				return claimed, fmt.Errorf("ent: query outbox: %w", err)
+			}
+			if earlier {
+				blocked[row.Key] = struct{}{}
+				continue
+			}
+		}
+
+		ok, err := r.claim(ctx, row, now)
+		if err != nil {
+			return claimed, err
+		}
+		if !ok {
+			continue
+		}
+		claimed++
+
+		if err := r.deliver(ctx, row); err != nil {
+			if row.Key != "" {
+				blocked[row.Key] = struct{}{}
+			}
+			if ctx.Err() != nil {
+				return claimed, ctx.Err()
+			}
+		}
+	}
+
+	r.recordPending(ctx)
+	return claimed, nil
+}
+
+// claim 以 attempts 作为版本号认领消息并延长租期，返回 false 表示已被其他实例认领
+func (r *OutboxRelay) claim(ctx context.Context, row *OutboxMessage, now time.Time) (bool, error) {
+	n, err := r.client.OutboxMessage.Update().
+		Where(
+			outboxmessage.ID(row.ID),
+			outboxmessage.StatusEQ(outboxmessage.StatusPending),
+			outboxmessage.Attempts(row.Attempts),
+		).
+		SetAvailableAt(now.Add(r.opts.Lease)).
+		AddAttempts(1).
+		Save(ctx)
+	if err != nil {
+		return false, fmt.Errorf("ent: claim outbox message %s: %w", row.MessageID, err)
+	}
+	row.Attempts++
+	return n == 1, nil
+}
+
+func (r *OutboxRelay) deliver(ctx context.Context, row *OutboxMessage) error {
+	msg := &events.Message{
+		ID:        row.MessageID,
+		Topic:     row.Topic,
+		Key:       row.Key,
+		Headers:   row.Headers,
+		Payload:   row.Payload,
+		Timestamp: row.CreatedAt,
+	}
+	pubErr := r.publisher.Publish(ctx, msg)
+	if pubErr == nil {
+		err := r.client.OutboxMessage.UpdateOneID(row.ID).
+			SetStatus(outboxmessage.StatusDelivered).
+			SetDeliveredAt(time.Now()).
+			ClearLastError().
+			Exec(ctx)
+		if err != nil {
+			// 租期到期后会重新投递，消费方按消息 ID 去重
+			r.logger.Warn("outbox mark delivered failed", zap.String("message_id", row.MessageID), zap.Error(err))
+		}
+		r.record(row.Topic, "delivered")
+		return nil
+	}
+
+	update := r.client.OutboxMessage.UpdateOneID(row.ID).SetLastError(pubErr.Error())
+	status := "failed"
+	if row.Attempts >= r.opts.MaxAttempts {
+		status = "dead"
+		update.SetStatus(outboxmessage.StatusDead)
+		r.logger.Error("outbox message exhausted attempts",
+			zap.String("message_id", row.MessageID),
+			zap.String("topic", row.Topic),
+			zap.Int("attempts", row.Attempts),
+			zap.Error(pubErr))
+	} else {
+		update.SetAvailableAt(time.Now().Add(r.opts.Backoff(row.Attempts)))
+		r.logger.Warn("outbox publish failed",
+			zap.String("message_id", row.MessageID),
+			zap.String("topic", row.Topic),
+			zap.Int("attempts", row.Attempts),
+			zap.Error(pubErr))
+	}
+	if err := update.Exec(context.WithoutCancel(ctx)); err != nil {
+		r.logger.Warn("outbox mark failed failed", zap.String("message_id", row.MessageID), zap.Error(err))
+	}
+	r.record(row.Topic, status)
+	return pubErr
+}
+
+// Purge 删除投递时间早于 olderThan 的已投递消息，可交由定时任务周期执行
+func (r *OutboxRelay) Purge(ctx context.Context, olderThan time.Duration) (int, error) {
+	n, err := r.client.OutboxMessage.Delete().
+		Where(
+			outboxmessage.StatusEQ(outboxmessage.StatusDelivered),
+			outboxmessage.DeliveredAtLT(time.Now().Add(-olderThan)),
+		).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("ent: purge outbox: %w", err)
+	}
+	return n, nil
+}
+
+func (r *OutboxRelay) record(topic, status string) {
+	if r.opts.Metrics == nil {
+		return
+	}
+	r.opts.Metrics.IncCounter("outbox_relayed_total", map[string]string{"topic": topic, "status": status})
+}
+
+func (r *OutboxRelay) recordPending(ctx context.Context) {
+	if r.opts.Metrics == nil {
+		return
+	}
+	n, err := r.client.OutboxMessage.Query().
+		Where(outboxmessage.StatusEQ(outboxmessage.StatusPending)).
+		Count(ctx)
+	if err != nil {
+		return
+	}
+	r.opts.Metrics.SetGauge("outbox_pending", float64(n), nil)
+}
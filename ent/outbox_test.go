@@ -0,0 +1,168 @@
+package ent
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/ent/outboxmessage"
+	"github.com/leeforge/framework/events"
+
+	_ "modernc.org/sqlite"
+)
+
+func newOutboxTestClient(t *testing.T) *Client {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:outbox-"+uuid.NewString()+"?mode=memory&cache=shared&_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(Driver(entsql.OpenDB(dialect.SQLite, db)))
+	t.Cleanup(func() { client.Close() })
+	if err := client.Schema.Create(context.Background()); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	return client
+}
+
+type recordingPublisher struct {
+	mu   sync.Mutex
+	fail map[string]int // 消息 ID -> 剩余失败次数
+	got  []string
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, msgs ...*events.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, msg := range msgs {
+		if p.fail[msg.ID] > 0 {
+			p.fail[msg.ID]--
+			return errors.New("broker unavailable")
+		}
+		p.got = append(p.got, msg.ID)
+	}
+	return nil
+}
+
+func TestOutboxHookWritesWithinTransaction(t *testing.T) {
+	client := newOutboxTestClient(t)
+	ctx := context.Background()
+	client.CasbinPolicy.Use(OutboxHook(func(_ context.Context, _ Mutation, v Value) ([]*events.Message, error) {
+		p := v.(*CasbinPolicy)
+		return []*events.Message{{Topic: "policy.created", Key: p.V0, Payload: []byte(p.V1)}}, nil
+	}))
+
+	if err := client.CasbinPolicy.Create().SetPtype("p").SetV0("alice").Exec(ctx); !errors.Is(err, ErrOutboxTxRequired) {
+		t.Fatalf("mutation outside a transaction err = %v, want ErrOutboxTxRequired", err)
+	}
+
+	// 回滚时事件随业务数据一起丢弃
+	tx, err := client.Tx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.CasbinPolicy.Create().SetPtype("p").SetV0("bob").Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+
+	tx, err = client.Tx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.CasbinPolicy.Create().SetPtype("p").SetV0("carol").SetV1("read").Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 通过 ctx 中的事务发布的事件同样原子写入
+	txCtx := NewTxContext(ctx, tx)
+	if err := events.NewOutboxPublisher(NewOutbox()).Publish(txCtx, &events.Message{Topic: "audit"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := client.OutboxMessage.Query().Order(Asc(outboxmessage.FieldID)).All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Key != "carol" || string(rows[0].Payload) != "read" || rows[1].Topic != "audit" {
+		t.Fatalf("outbox rows = %+v", rows)
+	}
+	if rows[0].Status != outboxmessage.StatusPending || rows[0].MessageID == "" {
+		t.Errorf("stored message = %+v", rows[0])
+	}
+
+	if err := NewOutbox().Save(ctx, &events.Message{Topic: "audit"}); !errors.Is(err, ErrOutboxTxRequired) {
+		t.Errorf("Save without a transaction err = %v", err)
+	}
+}
+
+func TestOutboxRelayDeliversInKeyOrder(t *testing.T) {
+	client := newOutboxTestClient(t)
+	ctx := context.Background()
+
+	tx, err := client.Tx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []*events.Message{
+		{ID: "k-1", Topic: "order", Key: "k"},
+		{ID: "k-2", Topic: "order", Key: "k"},
+		{ID: "other", Topic: "order"},
+		{ID: "bad", Topic: "order"},
+	}
+	if err := NewOutbox().Save(NewTxContext(ctx, tx), msgs...); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	pub := &recordingPublisher{fail: map[string]int{"k-1": 1, "bad": 100}}
+	relay := NewOutboxRelay(client, pub, OutboxRelayOptions{
+		MaxAttempts: 2,
+		Backoff:     func(int) time.Duration { return 0 },
+	})
+
+	// 第一轮：k-1 失败，k-2 必须等待
+	if _, err := relay.Relay(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.got) != 1 || pub.got[0] != "other" {
+		t.Fatalf("first round published %v, want [other]", pub.got)
+	}
+
+	// 第二轮：k-1 重试成功后 k-2 按顺序投递，bad 用尽次数
+	if _, err := relay.Relay(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.got) != 3 || pub.got[1] != "k-1" || pub.got[2] != "k-2" {
+		t.Fatalf("published %v, want [other k-1 k-2]", pub.got)
+	}
+
+	bad, err := client.OutboxMessage.Query().Where(outboxmessage.MessageID("bad")).Only(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bad.Status != outboxmessage.StatusDead || bad.Attempts != 2 || bad.LastError == "" {
+		t.Errorf("bad message = %+v, want dead after 2 attempts", bad)
+	}
+	delivered, _ := client.OutboxMessage.Query().Where(outboxmessage.StatusEQ(outboxmessage.StatusDelivered)).Count(ctx)
+	if delivered != 3 {
+		t.Errorf("delivered = %d, want 3", delivered)
+	}
+
+	if n, err := relay.Relay(ctx); err != nil || n != 0 {
+		t.Errorf("third round claimed %d, err %v", n, err)
+	}
+	if n, err := relay.Purge(ctx, -time.Minute); err != nil || n != 3 {
+		t.Errorf("Purge removed %d, err %v", n, err)
+	}
+}
@@ -0,0 +1,224 @@
+// Code generated by ent, DO NOT EDIT.
+
+package ent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"github.com/leeforge/framework/ent/outboxmessage"
+)
+
+// OutboxMessage is the model entity for the OutboxMessage schema.
+type OutboxMessage struct {
+	config `json:"-"`
+	// ID of the ent.
+	ID int `json:"id,omitempty"`
+	// Event message ID, used by consumers for de-duplication
+	MessageID string `json:"message_id,omitempty"`
+	// Event topic
+	Topic string `json:"topic,omitempty"`
+	// Ordering / partition key
+	Key string `json:"key,omitempty"`
+	// Message headers, including propagated request and trace context
+	Headers map[string]string `json:"headers,omitempty"`
+	// Encoded event
+	Payload []byte `json:"payload,omitempty"`
+	// Delivery status
+	Status outboxmessage.Status `json:"status,omitempty"`
+	// Number of relay attempts
+	Attempts int `json:"attempts,omitempty"`
+	// Error of the last failed attempt
+	LastError string `json:"last_error,omitempty"`
+	// Earliest time the relay may (re)try the message
+	AvailableAt time.Time `json:"available_at,omitempty"`
+	// Time the message was stored
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// Time the message was published
+	DeliveredAt  *time.Time `json:"delivered_at,omitempty"`
+	selectValues sql.SelectValues
+}
+
+// scanValues returns the types for scanning values from sql.Rows.
+func (*OutboxMessage) scanValues(columns []string) ([]any, error) {
+	values := make([]any, len(columns))
+	for i := range columns {
+		switch columns[i] {
+		case outboxmessage.FieldHeaders, outboxmessage.FieldPayload:
+			values[i] = new([]byte)
+		case outboxmessage.FieldID, outboxmessage.FieldAttempts:
+			values[i] = new(sql.NullInt64)
+		case outboxmessage.FieldMessageID, outboxmessage.FieldTopic, outboxmessage.FieldKey, outboxmessage.FieldStatus, outboxmessage.FieldLastError:
+			values[i] = new(sql.NullString)
+		case outboxmessage.FieldAvailableAt, outboxmessage.FieldCreatedAt, outboxmessage.FieldDeliveredAt:
+			values[i] = new(sql.NullTime)
+		default:
+			values[i] = new(sql.UnknownType)
+		}
+	}
+	return values, nil
+}
+
+// assignValues assigns the values that were returned from sql.Rows (after scanning)
+// to the OutboxMessage fields.
+func (_m *OutboxMessage) assignValues(columns []string, values []any) error {
+	if m, n := len(values), len(columns); m < n {
+		return fmt.Errorf("mismatch number of scan values: %d != %d", m, n)
+	}
+	for i := range columns {
+		switch columns[i] {
+		case outboxmessage.FieldID:
+			value, ok := values[i].(*sql.NullInt64)
+			if !ok {
+				return fmt.Errorf("unexpected type %T for field id", value)
+			}
+			_m.ID = int(value.Int64)
+		case outboxmessage.FieldMessageID:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field message_id", values[i])
+			} else if value.Valid {
+				_m.MessageID = value.String
+			}
+		case outboxmessage.FieldTopic:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field topic", values[i])
+			} else if value.Valid {
+				_m.Topic = value.String
+			}
+		case outboxmessage.FieldKey:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field key", values[i])
+			} else if value.Valid {
+				_m.Key = value.String
+			}
+		case outboxmessage.FieldHeaders:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field headers", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.Headers); err != nil {
+					return fmt.Errorf("unmarshal field headers: %w", err)
+				}
+			}
+		case outboxmessage.FieldPayload:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field payload", values[i])
+			} else if value != nil {
+				_m.Payload = *value
+			}
+		case outboxmessage.FieldStatus:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field status", values[i])
+			} else if value.Valid {
+				_m.Status = outboxmessage.Status(value.String)
+			}
+		case outboxmessage.FieldAttempts:
+			if value, ok := values[i].(*sql.NullInt64); !ok {
+				return fmt.Errorf("unexpected type %T for field attempts", values[i])
+			} else if value.Valid {
+				_m.Attempts = int(value.Int64)
+			}
+		case outboxmessage.FieldLastError:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field last_error", values[i])
+			} else if value.Valid {
+				_m.LastError = value.String
+			}
+		case outboxmessage.FieldAvailableAt:
+			if value, ok := values[i].(*sql.NullTime); !ok {
+				return fmt.Errorf("unexpected type %T for field available_at", values[i])
+			} else if value.Valid {
+				_m.AvailableAt = value.Time
+			}
+		case outboxmessage.FieldCreatedAt:
+			if value, ok := values[i].(*sql.NullTime); !ok {
+				return fmt.Errorf("unexpected type %T for field created_at", values[i])
+			} else if value.Valid {
+				_m.CreatedAt = value.Time
+			}
+		case outboxmessage.FieldDeliveredAt:
+			if value, ok := values[i].(*sql.NullTime); !ok {
+				return fmt.Errorf("unexpected type %T for field delivered_at", values[i])
+			} else if value.Valid {
+				_m.DeliveredAt = new(time.Time)
+				*_m.DeliveredAt = value.Time
+			}
+		default:
+			_m.selectValues.Set(columns[i], values[i])
+		}
+	}
+	return nil
+}
+
+// Value returns the ent.Value that was dynamically selected and assigned to the OutboxMessage.
+// This includes values selected through modifiers, order, etc.
+func (_m *OutboxMessage) Value(name string) (ent.Value, error) {
+	return _m.selectValues.Get(name)
+}
+
+// Update returns a builder for updating this OutboxMessage.
+// Note that you need to call OutboxMessage.Unwrap() before calling this method if this OutboxMessage
+// was returned from a transaction, and the transaction was committed or rolled back.
+func (_m *OutboxMessage) Update() *OutboxMessageUpdateOne {
+	return NewOutboxMessageClient(_m.config).UpdateOne(_m)
+}
+
+// Unwrap unwraps the OutboxMessage entity that was returned from a transaction after it was closed,
+// so that all future queries will be executed through the driver which created the transaction.
+func (_m *OutboxMessage) Unwrap() *OutboxMessage {
+	_tx, ok := _m.config.driver.(*txDriver)
+	if !ok {
+		panic("ent: OutboxMessage is not a transactional entity")
+	}
+	_m.config.driver = _tx.drv
+	return _m
+}
+
+// String implements the fmt.Stringer.
+func (_m *OutboxMessage) String() string {
+	var builder strings.Builder
+	builder.WriteString("OutboxMessage(")
+	builder.WriteString(fmt.Sprintf("id=%v, ", _m.ID))
+	builder.WriteString("message_id=")
+	builder.WriteString(_m.MessageID)
+	builder.WriteString(", ")
+	builder.WriteString("topic=")
+	builder.WriteString(_m.Topic)
+	builder.WriteString(", ")
+	builder.WriteString("key=")
+	builder.WriteString(_m.Key)
+	builder.WriteString(", ")
+	builder.WriteString("headers=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Headers))
+	builder.WriteString(", ")
+	builder.WriteString("payload=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Payload))
+	builder.WriteString(", ")
+	builder.WriteString("status=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Status))
+	builder.WriteString(", ")
+	builder.WriteString("attempts=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Attempts))
+	builder.WriteString(", ")
+	builder.WriteString("last_error=")
+	builder.WriteString(_m.LastError)
+	builder.WriteString(", ")
+	builder.WriteString("available_at=")
+	builder.WriteString(_m.AvailableAt.Format(time.ANSIC))
+	builder.WriteString(", ")
+	builder.WriteString("created_at=")
+	builder.WriteString(_m.CreatedAt.Format(time.ANSIC))
+	builder.WriteString(", ")
+	if v := _m.DeliveredAt; v != nil {
+		builder.WriteString("delivered_at=")
+		builder.WriteString(v.Format(time.ANSIC))
+	}
+	builder.WriteByte(')')
+	return builder.String()
+}
+
+// OutboxMessages is a parsable slice of OutboxMessage.
+type OutboxMessages []*OutboxMessage
@@ -0,0 +1,404 @@
+// Code generated by ent, DO NOT EDIT.
+
+package ent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"entgo.io/ent/dialect/sql/sqlgraph"
+	"entgo.io/ent/schema/field"
+	"github.com/leeforge/framework/ent/outboxmessage"
+)
+
+// OutboxMessageCreate is the builder for creating a OutboxMessage entity.
+type OutboxMessageCreate struct {
+	config
+	mutation *OutboxMessageMutation
+	hooks    []Hook
+}
+
+// SetMessageID sets the "message_id" field.
+func (_c *OutboxMessageCreate) SetMessageID(v string) *OutboxMessageCreate {
+	_c.mutation.SetMessageID(v)
+	return _c
+}
+
+// SetTopic sets the "topic" field.
+func (_c *OutboxMessageCreate) SetTopic(v string) *OutboxMessageCreate {
+	_c.mutation.SetTopic(v)
+	return _c
+}
+
+// SetKey sets the "key" field.
+func (_c *OutboxMessageCreate) SetKey(v string) *OutboxMessageCreate {
+	_c.mutation.SetKey(v)
+	return _c
+}
+
+// SetNillableKey sets the "key" field if the given value is not nil.
+func (_c *OutboxMessageCreate) SetNillableKey(v *string) *OutboxMessageCreate {
+	if v != nil {
+		_c.SetKey(*v)
+	}
+	return _c
+}
+
+// SetHeaders sets the "headers" field.
+func (_c *OutboxMessageCreate) SetHeaders(v map[string]string) *OutboxMessageCreate {
+	_c.mutation.SetHeaders(v)
+	return _c
+}
+
+// SetPayload sets the "payload" field.
+func (_c *OutboxMessageCreate) SetPayload(v []byte) *OutboxMessageCreate {
+	_c.mutation.SetPayload(v)
+	return _c
+}
+
+// SetStatus sets the "status" field.
+func (_c *OutboxMessageCreate) SetStatus(v outboxmessage.Status) *OutboxMessageCreate {
+	_c.mutation.SetStatus(v)
+	return _c
+}
+
+// SetNillableStatus sets the "status" field if the given value is not nil.
+func (_c *OutboxMessageCreate) SetNillableStatus(v *outboxmessage.Status) *OutboxMessageCreate {
+	if v != nil {
+		_c.SetStatus(*v)
+	}
+	return _c
+}
+
+// SetAttempts sets the "attempts" field.
+func (_c *OutboxMessageCreate) SetAttempts(v int) *OutboxMessageCreate {
+	_c.mutation.SetAttempts(v)
+	return _c
+}
+
+// SetNillableAttempts sets the "attempts" field if the given value is not nil.
+func (_c *OutboxMessageCreate) SetNillableAttempts(v *int) *OutboxMessageCreate {
+	if v != nil {
+		_c.SetAttempts(*v)
+	}
+	return _c
+}
+
+// SetLastError sets the "last_error" field.
+func (_c *OutboxMessageCreate) SetLastError(v string) *OutboxMessageCreate {
+	_c.mutation.SetLastError(v)
+	return _c
+}
+
+// SetNillableLastError sets the "last_error" field if the given value is not nil.
+func (_c *OutboxMessageCreate) SetNillableLastError(v *string) *OutboxMessageCreate {
+	if v != nil {
+		_c.SetLastError(*v)
+	}
+	return _c
+}
+
+// SetAvailableAt sets the "available_at" field.
+func (_c *OutboxMessageCreate) SetAvailableAt(v time.Time) *OutboxMessageCreate {
+	_c.mutation.SetAvailableAt(v)
+	return _c
+}
+
+// SetNillableAvailableAt sets the "available_at" field if the given value is not nil.
+func (_c *OutboxMessageCreate) SetNillableAvailableAt(v *time.Time) *OutboxMessageCreate {
+	if v != nil {
+		_c.SetAvailableAt(*v)
+	}
+	return _c
+}
+
+// SetCreatedAt sets the "created_at" field.
+func (_c *OutboxMessageCreate) SetCreatedAt(v time.Time) *OutboxMessageCreate {
+	_c.mutation.SetCreatedAt(v)
+	return _c
+}
+
+// SetNillableCreatedAt sets the "created_at" field if the given value is not nil.
+func (_c *OutboxMessageCreate) SetNillableCreatedAt(v *time.Time) *OutboxMessageCreate {
+	if v != nil {
+		_c.SetCreatedAt(*v)
+	}
+	return _c
+}
+
+// SetDeliveredAt sets the "delivered_at" field.
+func (_c *OutboxMessageCreate) SetDeliveredAt(v time.Time) *OutboxMessageCreate {
+	_c.mutation.SetDeliveredAt(v)
+	return _c
+}
+
+// SetNillableDeliveredAt sets the "delivered_at" field if the given value is not nil.
+func (_c *OutboxMessageCreate) SetNillableDeliveredAt(v *time.Time) *OutboxMessageCreate {
+	if v != nil {
+		_c.SetDeliveredAt(*v)
+	}
+	return _c
+}
+
+// Mutation returns the OutboxMessageMutation object of the builder.
+func (_c *OutboxMessageCreate) Mutation() *OutboxMessageMutation {
+	return _c.mutation
+}
+
+// Save creates the OutboxMessage in the database.
+func (_c *OutboxMessageCreate) Save(ctx context.Context) (*OutboxMessage, error) {
+	_c.defaults()
+	return withHooks(ctx, _c.sqlSave, _c.mutation, _c.hooks)
+}
+
+// SaveX calls Save and panics if Save returns an error.
+func (_c *OutboxMessageCreate) SaveX(ctx context.Context) *OutboxMessage {
+	v, err := _c.Save(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Exec executes the query.
+func (_c *OutboxMessageCreate) Exec(ctx context.Context) error {
+	_, err := _c.Save(ctx)
+	return err
+}
+
+// ExecX is like Exec, but panics if an error occurs.
+func (_c *OutboxMessageCreate) ExecX(ctx context.Context) {
+	if err := _c.Exec(ctx); err != nil {
+		panic(err)
+	}
+}
+
+// defaults sets the default values of the builder before save.
+func (_c *OutboxMessageCreate) defaults() {
+	if _, ok := _c.mutation.Key(); !ok {
+		v := outboxmessage.DefaultKey
+		_c.mutation.SetKey(v)
+	}
+	if _, ok := _c.mutation.Status(); !ok {
+		v := outboxmessage.DefaultStatus
+		_c.mutation.SetStatus(v)
+	}
+	if _, ok := _c.mutation.Attempts(); !ok {
+		v := outboxmessage.DefaultAttempts
+		_c.mutation.SetAttempts(v)
+	}
+	if _, ok := _c.mutation.AvailableAt(); !ok {
+		v := outboxmessage.DefaultAvailableAt()
+		_c.mutation.SetAvailableAt(v)
+	}
+	if _, ok := _c.mutation.CreatedAt(); !ok {
+		v := outboxmessage.DefaultCreatedAt()
+		_c.mutation.SetCreatedAt(v)
+	}
+}
+
+// check runs all checks and user-defined validators on the builder.
+func (_c *OutboxMessageCreate) check() error {
+	if _, ok := _c.mutation.MessageID(); !ok {
+		return &ValidationError{Name: "message_id", err: errors.New(`ent: missing required field "OutboxMessage.message_id"`)}
+	}
+	if v, ok := _c.mutation.MessageID(); ok {
+		if err := outboxmessage.MessageIDValidator(v); err != nil {
+			return &ValidationError{Name: "message_id", err: fmt.Errorf(`ent: validator failed for field "OutboxMessage.message_id": %w`, err)}
+		}
+	}
+	if _, ok := _c.mutation.Topic(); !ok {
+		return &ValidationError{Name: "topic", err: errors.New(`ent: missing required field "OutboxMessage.topic"`)}
+	}
+	if v, ok := _c.mutation.Topic(); ok {
+		if err := outboxmessage.TopicValidator(v); err != nil {
+			return &ValidationError{Name: "topic", err: fmt.Errorf(`ent: validator failed for field "OutboxMessage.topic": %w`, err)}
+		}
+	}
+	if _, ok := _c.mutation.Key(); !ok {
+		return &ValidationError{Name: "key", err: errors.New(`ent: missing required field "OutboxMessage.key"`)}
+	}
+	if _, ok := _c.mutation.Status(); !ok {
+		return &ValidationError{Name: "status", err: errors.New(`ent: missing required field "OutboxMessage.status"`)}
+	}
+	if v, ok := _c.mutation.Status(); ok {
+		if err := outboxmessage.StatusValidator(v); err != nil {
+			return &ValidationError{Name: "status", err: fmt.Errorf(`ent: validator failed for field "OutboxMessage.status": %w`, err)}
+		}
+	}
+	if _, ok := _c.mutation.Attempts(); !ok {
+		return &ValidationError{Name: "attempts", err: errors.New(`ent: missing required field "OutboxMessage.attempts"`)}
+	}
+	if v, ok := _c.mutation.Attempts(); ok {
+		if err := outboxmessage.AttemptsValidator(v); err != nil {
+			return &ValidationError{Name: "attempts", err: fmt.Errorf(`ent: validator failed for field "OutboxMessage.attempts": %w`, err)}
+		}
+	}
+	if _, ok := _c.mutation.AvailableAt(); !ok {
+		return &ValidationError{Name: "available_at", err: errors.New(`ent: missing required field "OutboxMessage.available_at"`)}
+	}
+	if _, ok := _c.mutation.CreatedAt(); !ok {
+		return &ValidationError{Name: "created_at", err: errors.New(`ent: missing required field "OutboxMessage.created_at"`)}
+	}
+	return nil
+}
+
+func (_c *OutboxMessageCreate) sqlSave(ctx context.Context) (*OutboxMessage, error) {
+	if err := _c.check(); err != nil {
+		return nil, err
+	}
+	_node, _spec := _c.createSpec()
+	if err := sqlgraph.CreateNode(ctx, _c.driver, _spec); err != nil {
+		if sqlgraph.IsConstraintError(err) {
+			err = &ConstraintError{msg: err.Error(), wrap: err}
+		}
+		return nil, err
+	}
+	id := _spec.ID.Value.(int64)
+	_node.ID = int(id)
+	_c.mutation.id = &_node.ID
+	_c.mutation.done = true
+	return _node, nil
+}
+
+func (_c *OutboxMessageCreate) createSpec() (*OutboxMessage, *sqlgraph.CreateSpec) {
+	var (
+		_node = &OutboxMessage{config: _c.config}
+		_spec = sqlgraph.NewCreateSpec(outboxmessage.Table, sqlgraph.NewFieldSpec(outboxmessage.FieldID, field.TypeInt))
+	)
+	if value, ok := _c.mutation.MessageID(); ok {
+		_spec.SetField(outboxmessage.FieldMessageID, field.TypeString, value)
+		_node.MessageID = value
+	}
+	if value, ok := _c.mutation.Topic(); ok {
+		_spec.SetField(outboxmessage.FieldTopic, field.TypeString, value)
+		_node.Topic = value
+	}
+	if value, ok := _c.mutation.Key(); ok {
+		_spec.SetField(outboxmessage.FieldKey, field.TypeString, value)
+		_node.Key = value
+	}
+	if value, ok := _c.mutation.Headers(); ok {
+		_spec.SetField(outboxmessage.FieldHeaders, field.TypeJSON, value)
+		_node.Headers = value
+	}
+	if value, ok := _c.mutation.Payload(); ok {
+		_spec.SetField(outboxmessage.FieldPayload, field.TypeBytes, value)
+		_node.Payload = value
+	}
+	if value, ok := _c.mutation.Status(); ok {
+		_spec.SetField(outboxmessage.FieldStatus, field.TypeEnum, value)
+		_node.Status = value
+	}
+	if value, ok := _c.mutation.Attempts(); ok {
+		_spec.SetField(outboxmessage.FieldAttempts, field.TypeInt, value)
+		_node.Attempts = value
+	}
+	if value, ok := _c.mutation.LastError(); ok {
+		_spec.SetField(outboxmessage.FieldLastError, field.TypeString, value)
+		_node.LastError = value
+	}
+	if value, ok := _c.mutation.AvailableAt(); ok {
+		_spec.SetField(outboxmessage.FieldAvailableAt, field.TypeTime, value)
+		_node.AvailableAt = value
+	}
+	if value, ok := _c.mutation.CreatedAt(); ok {
+		_spec.SetField(outboxmessage.FieldCreatedAt, field.TypeTime, value)
+		_node.CreatedAt = value
+	}
+	if value, ok := _c.mutation.DeliveredAt(); ok {
+		_spec.SetField(outboxmessage.FieldDeliveredAt, field.TypeTime, value)
+		_node.DeliveredAt = &value
+	}
+	return _node, _spec
+}
+
+// OutboxMessageCreateBulk is the builder for creating many OutboxMessage entities in bulk.
+type OutboxMessageCreateBulk struct {
+	config
+	err      error
+	builders []*OutboxMessageCreate
+}
+
+// Save creates the OutboxMessage entities in the database.
+func (_c *OutboxMessageCreateBulk) Save(ctx context.Context) ([]*OutboxMessage, error) {
+	if _c.err != nil {
+		return nil, _c.err
+	}
+	specs := make([]*sqlgraph.CreateSpec, len(_c.builders))
+	nodes := make([]*OutboxMessage, len(_c.builders))
+	mutators := make([]Mutator, len(_c.builders))
+	for i := range _c.builders {
+		func(i int, root context.Context) {
+			builder := _c.builders[i]
+			builder.defaults()
+			var mut Mutator = MutateFunc(func(ctx context.Context, m Mutation) (Value, error) {
+				mutation, ok := m.(*OutboxMessageMutation)
+				if !ok {
+					return nil, fmt.Errorf("unexpected mutation type %T", m)
+				}
+				if err := builder.check(); err != nil {
+					return nil, err
+				}
+				builder.mutation = mutation
+				var err error
+				nodes[i], specs[i] = builder.createSpec()
+				if i < len(mutators)-1 {
+					_, err = mutators[i+1].Mutate(root, _c.builders[i+1].mutation)
+				} else {
+					spec := &sqlgraph.BatchCreateSpec{Nodes: specs}
+					// Invoke the actual operation on the latest mutation in the chain.
+					if err = sqlgraph.BatchCreate(ctx, _c.driver, spec); err != nil {
+						if sqlgraph.IsConstraintError(err) {
+							err = &ConstraintError{msg: err.Error(), wrap: err}
+						}
+					}
+				}
+				if err != nil {
+					return nil, err
+				}
+				mutation.id = &nodes[i].ID
+				if specs[i].ID.Value != nil {
+					id := specs[i].ID.Value.(int64)
+					nodes[i].ID = int(id)
+				}
+				mutation.done = true
+				return nodes[i], nil
+			})
+			for i := len(builder.hooks) - 1; i >= 0; i-- {
+				mut = builder.hooks[i](mut)
+			}
+			mutators[i] = mut
+		}(i, ctx)
+	}
+	if len(mutators) > 0 {
+		if _, err := mutators[0].Mutate(ctx, _c.builders[0].mutation); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// SaveX is like Save, but panics if an error occurs.
+func (_c *OutboxMessageCreateBulk) SaveX(ctx context.Context) []*OutboxMessage {
+	v, err := _c.Save(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Exec executes the query.
+func (_c *OutboxMessageCreateBulk) Exec(ctx context.Context) error {
+	_, err := _c.Save(ctx)
+	return err
+}
+
+// ExecX is like Exec, but panics if an error occurs.
+func (_c *OutboxMessageCreateBulk) ExecX(ctx context.Context) {
+	if err := _c.Exec(ctx); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,450 @@
+// Code generated by ent, DO NOT EDIT.
+
+package ent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/dialect/sql/sqlgraph"
+	"entgo.io/ent/schema/field"
+	"github.com/leeforge/framework/ent/outboxmessage"
+	"github.com/leeforge/framework/ent/predicate"
+)
+
+// OutboxMessageUpdate is the builder for updating OutboxMessage entities.
+type OutboxMessageUpdate struct {
+	config
+	hooks    []Hook
+	mutation *OutboxMessageMutation
+}
+
+// Where appends a list predicates to the OutboxMessageUpdate builder.
+func (_u *OutboxMessageUpdate) Where(ps ...predicate.OutboxMessage) *OutboxMessageUpdate {
+	_u.mutation.Where(ps...)
+	return _u
+}
+
+// SetStatus sets the "status" field.
+func (_u *OutboxMessageUpdate) SetStatus(v outboxmessage.Status) *OutboxMessageUpdate {
+	_u.mutation.SetStatus(v)
+	return _u
+}
+
+// SetNillableStatus sets the "status" field if the given value is not nil.
+func (_u *OutboxMessageUpdate) SetNillableStatus(v *outboxmessage.Status) *OutboxMessageUpdate {
+	if v != nil {
+		_u.SetStatus(*v)
+	}
+	return _u
+}
+
+// SetAttempts sets the "attempts" field.
+func (_u *OutboxMessageUpdate) SetAttempts(v int) *OutboxMessageUpdate {
+	_u.mutation.ResetAttempts()
+	_u.mutation.SetAttempts(v)
+	return _u
+}
+
+// SetNillableAttempts sets the "attempts" field if the given value is not nil.
+func (_u *OutboxMessageUpdate) SetNillableAttempts(v *int) *OutboxMessageUpdate {
+	if v != nil {
+		_u.SetAttempts(*v)
+	}
+	return _u
+}
+
+// AddAttempts adds value to the "attempts" field.
+func (_u *OutboxMessageUpdate) AddAttempts(v int) *OutboxMessageUpdate {
+	_u.mutation.AddAttempts(v)
+	return _u
+}
+
+// SetLastError sets the "last_error" field.
+func (_u *OutboxMessageUpdate) SetLastError(v string) *OutboxMessageUpdate {
+	_u.mutation.SetLastError(v)
+	return _u
+}
+
+// SetNillableLastError sets the "last_error" field if the given value is not nil.
+func (_u *OutboxMessageUpdate) SetNillableLastError(v *string) *OutboxMessageUpdate {
+	if v != nil {
+		_u.SetLastError(*v)
+	}
+	return _u
+}
+
+// ClearLastError clears the value of the "last_error" field.
+func (_u *OutboxMessageUpdate) ClearLastError() *OutboxMessageUpdate {
+	_u.mutation.ClearLastError()
+	return _u
+}
+
+// SetAvailableAt sets the "available_at" field.
+func (_u *OutboxMessageUpdate) SetAvailableAt(v time.Time) *OutboxMessageUpdate {
+	_u.mutation.SetAvailableAt(v)
+	return _u
+}
+
+// SetNillableAvailableAt sets the "available_at" field if the given value is not nil.
+func (_u *OutboxMessageUpdate) SetNillableAvailableAt(v *time.Time) *OutboxMessageUpdate {
+	if v != nil {
+		_u.SetAvailableAt(*v)
+	}
+	return _u
+}
+
+// SetDeliveredAt sets the "delivered_at" field.
+func (_u *OutboxMessageUpdate) SetDeliveredAt(v time.Time) *OutboxMessageUpdate {
+	_u.mutation.SetDeliveredAt(v)
+	return _u
+}
+
+// SetNillableDeliveredAt sets the "delivered_at" field if the given value is not nil.
+func (_u *OutboxMessageUpdate) SetNillableDeliveredAt(v *time.Time) *OutboxMessageUpdate {
+	if v != nil {
+		_u.SetDeliveredAt(*v)
+	}
+	return _u
+}
+
+// ClearDeliveredAt clears the value of the "delivered_at" field.
+func (_u *OutboxMessageUpdate) ClearDeliveredAt() *OutboxMessageUpdate {
+	_u.mutation.ClearDeliveredAt()
+	return _u
+}
+
+// Mutation returns the OutboxMessageMutation object of the builder.
+func (_u *OutboxMessageUpdate) Mutation() *OutboxMessageMutation {
+	return _u.mutation
+}
+
+// Save executes the query and returns the number of nodes affected by the update operation.
+func (_u *OutboxMessageUpdate) Save(ctx context.Context) (int, error) {
+	return withHooks(ctx, _u.sqlSave, _u.mutation, _u.hooks)
+}
+
+// SaveX is like Save, but panics if an error occurs.
+func (_u *OutboxMessageUpdate) SaveX(ctx context.Context) int {
+	affected, err := _u.Save(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return affected
+}
+
+// Exec executes the query.
+func (_u *OutboxMessageUpdate) Exec(ctx context.Context) error {
+	_, err := _u.Save(ctx)
+	return err
+}
+
+// ExecX is like Exec, but panics if an error occurs.
+func (_u *OutboxMessageUpdate) ExecX(ctx context.Context) {
+	if err := _u.Exec(ctx); err != nil {
+		panic(err)
+	}
+}
+
+// check runs all checks and user-defined validators on the builder.
+func (_u *OutboxMessageUpdate) check() error {
+	if v, ok := _u.mutation.Status(); ok {
+		if err := outboxmessage.StatusValidator(v); err != nil {
+			return &ValidationError{Name: "status", err: fmt.Errorf(`ent: validator failed for field "OutboxMessage.status": %w`, err)}
+		}
+	}
+	if v, ok := _u.mutation.Attempts(); ok {
+		if err := outboxmessage.AttemptsValidator(v); err != nil {
+			return &ValidationError{Name: "attempts", err: fmt.Errorf(`ent: validator failed for field "OutboxMessage.attempts": %w`, err)}
+		}
+	}
+	return nil
+}
+
+func (_u *OutboxMessageUpdate) sqlSave(ctx context.Context) (_node int, err error) {
+	if err := _u.check(); err != nil {
+		return _node, err
+	}
+	_spec := sqlgraph.NewUpdateSpec(outboxmessage.Table, outboxmessage.Columns, sqlgraph.NewFieldSpec(outboxmessage.FieldID, field.TypeInt))
+	if ps := _u.mutation.predicates; len(ps) > 0 {
+		_spec.Predicate = func(selector *sql.Selector) {
+			for i := range ps {
+				ps[i](selector)
+			}
+		}
+	}
+	if _u.mutation.HeadersCleared() {
+		_spec.ClearField(outboxmessage.FieldHeaders, field.TypeJSON)
+	}
+	if _u.mutation.PayloadCleared() {
+		_spec.ClearField(outboxmessage.FieldPayload, field.TypeBytes)
+	}
+	if value, ok := _u.mutation.Status(); ok {
+		_spec.SetField(outboxmessage.FieldStatus, field.TypeEnum, value)
+	}
+	if value, ok := _u.mutation.Attempts(); ok {
+		_spec.SetField(outboxmessage.FieldAttempts, field.TypeInt, value)
+	}
+	if value, ok := _u.mutation.AddedAttempts(); ok {
+		_spec.AddField(outboxmessage.FieldAttempts, field.TypeInt, value)
+	}
+	if value, ok := _u.mutation.LastError(); ok {
+		_spec.SetField(outboxmessage.FieldLastError, field.TypeString, value)
+	}
+	if _u.mutation.LastErrorCleared() {
+		_spec.ClearField(outboxmessage.FieldLastError, field.TypeString)
+	}
+	if value, ok := _u.mutation.AvailableAt(); ok {
+		_spec.SetField(outboxmessage.FieldAvailableAt, field.TypeTime, value)
+	}
+	if value, ok := _u.mutation.DeliveredAt(); ok {
+		_spec.SetField(outboxmessage.FieldDeliveredAt, field.TypeTime, value)
+	}
+	if _u.mutation.DeliveredAtCleared() {
+		_spec.ClearField(outboxmessage.FieldDeliveredAt, field.TypeTime)
+	}
+	if _node, err = sqlgraph.UpdateNodes(ctx, _u.driver, _spec); err != nil {
+		if _, ok := err.(*sqlgraph.NotFoundError); ok {
+			err = &NotFoundError{outboxmessage.Label}
+		} else if sqlgraph.IsConstraintError(err) {
+			err = &ConstraintError{msg: err.Error(), wrap: err}
+		}
+		return 0, err
+	}
+	_u.mutation.done = true
+	return _node, nil
+}
+
+// OutboxMessageUpdateOne is the builder for updating a single OutboxMessage entity.
+type OutboxMessageUpdateOne struct {
+	config
+	fields   []string
+	hooks    []Hook
+	mutation *OutboxMessageMutation
+}
+
+// SetStatus sets the "status" field.
+func (_u *OutboxMessageUpdateOne) SetStatus(v outboxmessage.Status) *OutboxMessageUpdateOne {
+	_u.mutation.SetStatus(v)
+	return _u
+}
+
+// SetNillableStatus sets the "status" field if the given value is not nil.
+func (_u *OutboxMessageUpdateOne) SetNillableStatus(v *outboxmessage.Status) *OutboxMessageUpdateOne {
+	if v != nil {
+		_u.SetStatus(*v)
+	}
+	return _u
+}
+
+// SetAttempts sets the "attempts" field.
+func (_u *OutboxMessageUpdateOne) SetAttempts(v int) *OutboxMessageUpdateOne {
+	_u.mutation.ResetAttempts()
+	_u.mutation.SetAttempts(v)
+	return _u
+}
+
+// SetNillableAttempts sets the "attempts" field if the given value is not nil.
+func (_u *OutboxMessageUpdateOne) SetNillableAttempts(v *int) *OutboxMessageUpdateOne {
+	if v != nil {
+		_u.SetAttempts(*v)
+	}
+	return _u
+}
+
+// AddAttempts adds value to the "attempts" field.
+func (_u *OutboxMessageUpdateOne) AddAttempts(v int) *OutboxMessageUpdateOne {
+	_u.mutation.AddAttempts(v)
+	return _u
+}
+
+// SetLastError sets the "last_error" field.
+func (_u *OutboxMessageUpdateOne) SetLastError(v string) *OutboxMessageUpdateOne {
+	_u.mutation.SetLastError(v)
+	return _u
+}
+
+// SetNillableLastError sets the "last_error" field if the given value is not nil.
+func (_u *OutboxMessageUpdateOne) SetNillableLastError(v *string) *OutboxMessageUpdateOne {
+	if v != nil {
+		_u.SetLastError(*v)
+	}
+	return _u
+}
+
+// ClearLastError clears the value of the "last_error" field.
+func (_u *OutboxMessageUpdateOne) ClearLastError() *OutboxMessageUpdateOne {
+	_u.mutation.ClearLastError()
+	return _u
+}
+
+// SetAvailableAt sets the "available_at" field.
+func (_u *OutboxMessageUpdateOne) SetAvailableAt(v time.Time) *OutboxMessageUpdateOne {
+	_u.mutation.SetAvailableAt(v)
+	return _u
+}
+
+// SetNillableAvailableAt sets the "available_at" field if the given value is not nil.
+func (_u *OutboxMessageUpdateOne) SetNillableAvailableAt(v *time.Time) *OutboxMessageUpdateOne {
+	if v != nil {
+		_u.SetAvailableAt(*v)
+	}
+	return _u
+}
+
+// SetDeliveredAt sets the "delivered_at" field.
+func (_u *OutboxMessageUpdateOne) SetDeliveredAt(v time.Time) *OutboxMessageUpdateOne {
+	_u.mutation.SetDeliveredAt(v)
+	return _u
+}
+
+// SetNillableDeliveredAt sets the "delivered_at" field if the given value is not nil.
+func (_u *OutboxMessageUpdateOne) SetNillableDeliveredAt(v *time.Time) *OutboxMessageUpdateOne {
+	if v != nil {
+		_u.SetDeliveredAt(*v)
+	}
+	return _u
+}
+
+// ClearDeliveredAt clears the value of the "delivered_at" field.
+func (_u *OutboxMessageUpdateOne) ClearDeliveredAt() *OutboxMessageUpdateOne {
+	_u.mutation.ClearDeliveredAt()
+	return _u
+}
+
+// Mutation returns the OutboxMessageMutation object of the builder.
+func (_u *OutboxMessageUpdateOne) Mutation() *OutboxMessageMutation {
+	return _u.mutation
+}
+
+// Where appends a list predicates to the OutboxMessageUpdate builder.
+func (_u *OutboxMessageUpdateOne) Where(ps ...predicate.OutboxMessage) *OutboxMessageUpdateOne {
+	_u.mutation.Where(ps...)
+	return _u
+}
+
+// Select allows selecting one or more fields (columns) of the returned entity.
+// The default is selecting all fields defined in the entity schema.
+func (_u *OutboxMessageUpdateOne) Select(field string, fields ...string) *OutboxMessageUpdateOne {
+	_u.fields = append([]string{field}, fields...)
+	return _u
+}
+
+// Save executes the query and returns the updated OutboxMessage entity.
+func (_u *OutboxMessageUpdateOne) Save(ctx context.Context) (*OutboxMessage, error) {
+	return withHooks(ctx, _u.sqlSave, _u.mutation, _u.hooks)
+}
+
+// SaveX is like Save, but panics if an error occurs.
+func (_u *OutboxMessageUpdateOne) SaveX(ctx context.Context) *OutboxMessage {
+	node, err := _u.Save(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return node
+}
+
+// Exec executes the query on the entity.
+func (_u *OutboxMessageUpdateOne) Exec(ctx context.Context) error {
+	_, err := _u.Save(ctx)
+	return err
+}
+
+// ExecX is like Exec, but panics if an error occurs.
+func (_u *OutboxMessageUpdateOne) ExecX(ctx context.Context) {
+	if err := _u.Exec(ctx); err != nil {
+		panic(err)
+	}
+}
+
+// check runs all checks and user-defined validators on the builder.
+func (_u *OutboxMessageUpdateOne) check() error {
+	if v, ok := _u.mutation.Status(); ok {
+		if err := outboxmessage.StatusValidator(v); err != nil {
+			return &ValidationError{Name: "status", err: fmt.Errorf(`ent: validator failed for field "OutboxMessage.status": %w`, err)}
+		}
+	}
+	if v, ok := _u.mutation.Attempts(); ok {
+		if err := outboxmessage.AttemptsValidator(v); err != nil {
+			return &ValidationError{Name: "attempts", err: fmt.Errorf(`ent: validator failed for field "OutboxMessage.attempts": %w`, err)}
+		}
+	}
+	return nil
+}
+
+func (_u *OutboxMessageUpdateOne) sqlSave(ctx context.Context) (_node *OutboxMessage, err error) {
+	if err := _u.check(); err != nil {
+		return _node, err
+	}
+	_spec := sqlgraph.NewUpdateSpec(outboxmessage.Table, outboxmessage.Columns, sqlgraph.NewFieldSpec(outboxmessage.FieldID, field.TypeInt))
+	id, ok := _u.mutation.ID()
+	if !ok {
+		return nil, &ValidationError{Name: "id", err: errors.New(`ent: missing "OutboxMessage.id" for update`)}
+	}
+	_spec.Node.ID.Value = id
+	if fields := _u.fields; len(fields) > 0 {
+		_spec.Node.Columns = make([]string, 0, len(fields))
+		_spec.Node.Columns = append(_spec.Node.Columns, outboxmessage.FieldID)
+		for _, f := range fields {
+			if !outboxmessage.ValidColumn(f) {
+				return nil, &ValidationError{Name: f, err: fmt.Errorf("ent: invalid field %q for query", f)}
+			}
+			if f != outboxmessage.FieldID {
+				_spec.Node.Columns = append(_spec.Node.Columns, f)
+			}
+		}
+	}
+	if ps := _u.mutation.predicates; len(ps) > 0 {
+		_spec.Predicate = func(selector *sql.Selector) {
+			for i := range ps {
+				ps[i](selector)
+			}
+		}
+	}
+	if _u.mutation.HeadersCleared() {
+		_spec.ClearField(outboxmessage.FieldHeaders, field.TypeJSON)
+	}
+	if _u.mutation.PayloadCleared() {
+		_spec.ClearField(outboxmessage.FieldPayload, field.TypeBytes)
+	}
+	if value, ok := _u.mutation.Status(); ok {
+		_spec.SetField(outboxmessage.FieldStatus, field.TypeEnum, value)
+	}
+	if value, ok := _u.mutation.Attempts(); ok {
+		_spec.SetField(outboxmessage.FieldAttempts, field.TypeInt, value)
+	}
+	if value, ok := _u.mutation.AddedAttempts(); ok {
+		_spec.AddField(outboxmessage.FieldAttempts, field.TypeInt, value)
+	}
+	if value, ok := _u.mutation.LastError(); ok {
+		_spec.SetField(outboxmessage.FieldLastError, field.TypeString, value)
+	}
+	if _u.mutation.LastErrorCleared() {
+		_spec.ClearField(outboxmessage.FieldLastError, field.TypeString)
+	}
+	if value, ok := _u.mutation.AvailableAt(); ok {
+		_spec.SetField(outboxmessage.FieldAvailableAt, field.TypeTime, value)
+	}
+	if value, ok := _u.mutation.DeliveredAt(); ok {
+		_spec.SetField(outboxmessage.FieldDeliveredAt, field.TypeTime, value)
+	}
+	if _u.mutation.DeliveredAtCleared() {
+		_spec.ClearField(outboxmessage.FieldDeliveredAt, field.TypeTime)
+	}
+	_node = &OutboxMessage{config: _u.config}
+	_spec.Assign = _node.assignValues
+	_spec.ScanValues = _node.scanValues
+	if err = sqlgraph.UpdateNode(ctx, _u.driver, _spec); err != nil {
+		if _, ok := err.(*sqlgraph.NotFoundError); ok {
+			err = &NotFoundError{outboxmessage.Label}
+		} else if sqlgraph.IsConstraintError(err) {
+			err = &ConstraintError{msg: err.Error(), wrap: err}
+		}
+		return nil, err
+	}
+	_u.mutation.done = true
+	return _node, nil
+}
@@ -0,0 +1,132 @@
+package ent
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"entgo.io/ent/dialect"
+)
+
+// PlaceholderStyle 参数占位符风格
+type PlaceholderStyle int
+
+const (
+	// PlaceholderQuestion ?，MySQL 与 SQLite
+	PlaceholderQuestion PlaceholderStyle = iota
+	// PlaceholderDollar $1、$2，PostgreSQL
+	PlaceholderDollar
+)
+
+// PlaceholderFor 返回 ent 方言对应的占位符风格
+func PlaceholderFor(dialectName string) PlaceholderStyle {
+	if dialectName == dialect.Postgres {
+		return PlaceholderDollar
+	}
+	return PlaceholderQuestion
+}
+
+// Rebind 将语句中的 ? 占位符按出现顺序改写为 style 风格，引号内的 ? 保持不变
+func Rebind(style PlaceholderStyle, query string) string {
+	if style == PlaceholderQuestion || !strings.Contains(query, "?") {
+		return query
+	}
+	var sb strings.Builder
+	sb.Grow(len(query) + 8)
+	n := 0
+	scanSQL(query, func(literal string) { sb.WriteString(literal) }, func(c byte, rest string) int {
+		if c != '?' {
+			sb.WriteByte(c)
+			return 0
+		}
+		n++
+		sb.WriteByte('$')
+		sb.WriteString(strconv.Itoa(n))
+		return 0
+	})
+	return sb.String()
+}
+
+// BindNamed 将 :name 形式的命名参数改写为 ? 占位符，按出现顺序返回参数。
+// 同一名称可多次出现；PostgreSQL 的 :: 类型转换与引号内内容不受影响。
+// 语句引用了 params 中不存在的名称时返回错误
+func BindNamed(query string, params map[string]any) (string, []any, error) {
+	var sb strings.Builder
+	sb.Grow(len(query))
+	var args []any
+	var missing []string
+	scanSQL(query, func(literal string) { sb.WriteString(literal) }, func(c byte, rest string) int {
+		if c != ':' {
+			sb.WriteByte(c)
+			return 0
+		}
+		if strings.HasPrefix(rest, ":") {
+			// :: 类型转换
+			sb.WriteString("::")
+			return 1
+		}
+		name := rest[:identLen(rest)]
+		if name == "" {
+			sb.WriteByte(c)
+			return 0
+		}
+		value, ok := params[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		args = append(args, value)
+		sb.WriteByte('?')
+		return len(name)
+	})
+	if len(missing) > 0 {
+		return "", nil, fmt.Errorf("ent: missing named parameters: %s", strings.Join(missing, ", "))
+	}
+	return sb.String(), args, nil
+}
+
+// scanSQL 遍历语句，引号内的字符串与标识符整体交给 literal，其余字节交给
+// other；other 返回额外消费的字节数
+func scanSQL(query string, literal func(string), other func(c byte, rest string) int) {
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == '\'' || c == '"' || c == '`' {
+			end := i + 1
+			for end < len(query) {
+				if query[end] == c {
+					// 连续两个引号为转义
+					if end+1 < len(query) && query[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end, len(query)-1)
+			literal(query[i : end+1])
+			i = end
+			continue
+		}
+		i += other(c, query[i+1:])
+	}
+}
+
+func identLen(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '_' && !isDigit(c) && (c|0x20 < 'a' || c|0x20 > 'z') {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// validColumn 校验列名，允许 table.column 形式
+func validColumn(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if !identifierPattern.MatchString(part) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package ent
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"github.com/google/uuid"
+)
+
+func TestRebind(t *testing.T) {
+	query := "SELECT * FROM t WHERE a = ? AND b IN (?, ?) AND c = 'what?' AND \"we?ird\" = ?"
+	want := "SELECT * FROM t WHERE a = $1 AND b IN ($2, $3) AND c = 'what?' AND \"we?ird\" = $4"
+	if got := Rebind(PlaceholderDollar, query); got != want {
+		t.Errorf("Rebind dollar\n got: %s\nwant: %s", got, want)
+	}
+	if got := Rebind(PlaceholderQuestion, query); got != query {
+		t.Errorf("Rebind question should not change the query, got %s", got)
+	}
+	if PlaceholderFor(dialect.Postgres) != PlaceholderDollar || PlaceholderFor(dialect.MySQL) != PlaceholderQuestion {
+		t.Error("PlaceholderFor maps dialects incorrectly")
+	}
+}
+
+func TestBindNamed(t *testing.T) {
+	query, args, err := BindNamed(
+		"SELECT id::text FROM t WHERE owner = :user AND (editor = :user OR status = :status) AND note <> ':literal'",
+		map[string]any{"user": 7, "status": "open", "unused": true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT id::text FROM t WHERE owner = ? AND (editor = ? OR status = ?) AND note <> ':literal'"
+	if query != want {
+		t.Errorf("query\n got: %s\nwant: %s", query, want)
+	}
+	if fmt.Sprint(args) != "[7 7 open]" {
+		t.Errorf("args = %v, want [7 7 open]", args)
+	}
+
+	if _, _, err := BindNamed("a = :a AND b = :b", map[string]any{"a": 1}); err == nil {
+		t.Error("expected error for missing parameter")
+	}
+}
+
+func TestOptimizedQueryBuildsParameterizedSQL(t *testing.T) {
+	query, args, err := NewOptimizedQuery().
+		Select("u.id", "u.name").
+		From("users u").
+		Where("u.status = ?", "active").
+		Join("teams t", "t.id = u.team_id AND t.region = ?", "eu").
+		WhereNamed("u.created_at > :since AND u.score >= :score", map[string]any{"since": "2024-01-01", "score": 10}).
+		WhereIn("u.role", []any{"admin", "owner'; DROP TABLE users; --"}).
+		OrderBy("u.name", "asc").
+		Limit(20).
+		Offset(40).
+		WithPlaceholder(PlaceholderDollar).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT u.id, u.name FROM users u JOIN teams t ON t.id = u.team_id AND t.region = $1" +
+		" WHERE u.status = $2 AND u.created_at > $3 AND u.score >= $4 AND u.role IN ($5, $6)" +
+		" ORDER BY u.name ASC LIMIT 20 OFFSET 40"
+	if query != want {
+		t.Errorf("query\n got: %s\nwant: %s", query, want)
+	}
+	if fmt.Sprint(args) != "[eu active 2024-01-01 10 admin owner'; DROP TABLE users; --]" {
+		t.Errorf("args = %v", args)
+	}
+
+	if _, _, err := NewOptimizedQuery().OrderBy("name; DROP TABLE users", "ASC").Build(); err == nil {
+		t.Error("expected error for invalid order by column")
+	}
+	if _, _, err := NewOptimizedQuery().OrderBy("name", "sideways").Build(); err == nil {
+		t.Error("expected error for invalid order by direction")
+	}
+	if _, _, err := NewOptimizedQuery().WhereNamed("id = :id", nil).Build(); err == nil {
+		t.Error("expected error for missing named parameter")
+	}
+}
+
+func TestQueryBuilderBuildIn(t *testing.T) {
+	b := NewQueryBuilder("users")
+	cond, args := b.BuildIn("id", []any{1, 2, 3})
+	if cond != "id IN (?, ?, ?)" || fmt.Sprint(args) != "[1 2 3]" {
+		t.Errorf("BuildIn = %q %v", cond, args)
+	}
+	if cond, args := b.BuildIn("id", nil); cond != "1=0" || args != nil {
+		t.Errorf("empty BuildIn = %q %v", cond, args)
+	}
+}
+
+func TestOptimizedQueryExecuteBindsValues(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:query-"+uuid.NewString()+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE users (id INTEGER, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO users VALUES (1, 'alice'), (2, 'bob')"); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := NewOptimizedQuery().Select("id").From("users").
+		WhereIn("name", []any{"alice", "x' OR '1'='1"}).
+		Execute(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if fmt.Sprint(ids) != "[1]" {
+		t.Errorf("ids = %v, want [1]; injected value must not match every row", ids)
+	}
+}
@@ -9,7 +9,10 @@ import (
 	"github.com/leeforge/framework/ent/casbinpolicy"
 	"github.com/leeforge/framework/ent/media"
 	"github.com/leeforge/framework/ent/mediaformat"
+	"github.com/leeforge/framework/ent/notificationdelivery"
+	"github.com/leeforge/framework/ent/outboxmessage"
 	"github.com/leeforge/framework/ent/schema"
+	"github.com/leeforge/framework/ent/webauthncredential"
 )
 
 // The init function reads all schema descriptors with runtime code
@@ -102,4 +105,100 @@ func init() {
 	mediaformatDescID := mediaformatMixinFields0[0].Descriptor()
 	// mediaformat.DefaultID holds the default value on creation for the id field.
 	mediaformat.DefaultID = mediaformatDescID.Default.(func() uuid.UUID)
+	notificationdeliveryFields := schema.NotificationDelivery{}.Fields()
+	_ = notificationdeliveryFields
+	// notificationdeliveryDescNotificationID is the schema descriptor for notification_id field.
+	notificationdeliveryDescNotificationID := notificationdeliveryFields[0].Descriptor()
+	// notificationdelivery.NotificationIDValidator is a validator for the "notification_id" field. It is called by the builders before save.
+	notificationdelivery.NotificationIDValidator = notificationdeliveryDescNotificationID.Validators[0].(func(string) error)
+	// notificationdeliveryDescChannel is the schema descriptor for channel field.
+	notificationdeliveryDescChannel := notificationdeliveryFields[1].Descriptor()
+	// notificationdelivery.ChannelValidator is a validator for the "channel" field. It is called by the builders before save.
+	notificationdelivery.ChannelValidator = notificationdeliveryDescChannel.Validators[0].(func(string) error)
+	// notificationdeliveryDescRecipient is the schema descriptor for recipient field.
+	notificationdeliveryDescRecipient := notificationdeliveryFields[2].Descriptor()
+	// notificationdelivery.DefaultRecipient holds the default value on creation for the recipient field.
+	notificationdelivery.DefaultRecipient = notificationdeliveryDescRecipient.Default.(string)
+	// notificationdeliveryDescTemplate is the schema descriptor for template field.
+	notificationdeliveryDescTemplate := notificationdeliveryFields[3].Descriptor()
+	// notificationdelivery.DefaultTemplate holds the default value on creation for the template field.
+	notificationdelivery.DefaultTemplate = notificationdeliveryDescTemplate.Default.(string)
+	// notificationdeliveryDescAttempts is the schema descriptor for attempts field.
+	notificationdeliveryDescAttempts := notificationdeliveryFields[5].Descriptor()
+	// notificationdelivery.DefaultAttempts holds the default value on creation for the attempts field.
+	notificationdelivery.DefaultAttempts = notificationdeliveryDescAttempts.Default.(int)
+	// notificationdelivery.AttemptsValidator is a validator for the "attempts" field. It is called by the builders before save.
+	notificationdelivery.AttemptsValidator = notificationdeliveryDescAttempts.Validators[0].(func(int) error)
+	// notificationdeliveryDescCreatedAt is the schema descriptor for created_at field.
+	notificationdeliveryDescCreatedAt := notificationdeliveryFields[9].Descriptor()
+	// notificationdelivery.DefaultCreatedAt holds the default value on creation for the created_at field.
+	notificationdelivery.DefaultCreatedAt = notificationdeliveryDescCreatedAt.Default.(func() time.Time)
+	// notificationdeliveryDescUpdatedAt is the schema descriptor for updated_at field.
+	notificationdeliveryDescUpdatedAt := notificationdeliveryFields[10].Descriptor()
+	// notificationdelivery.DefaultUpdatedAt holds the default value on creation for the updated_at field.
+	notificationdelivery.DefaultUpdatedAt = notificationdeliveryDescUpdatedAt.Default.(func() time.Time)
+	// notificationdelivery.UpdateDefaultUpdatedAt holds the default value on update for the updated_at field.
+	notificationdelivery.UpdateDefaultUpdatedAt = notificationdeliveryDescUpdatedAt.UpdateDefault.(func() time.Time)
+	outboxmessageFields := schema.OutboxMessage{}.Fields()
+	_ = outboxmessageFields
+	// outboxmessageDescMessageID is the schema descriptor for message_id field.
+	outboxmessageDescMessageID := outboxmessageFields[0].Descriptor()
+	// outboxmessage.MessageIDValidator is a validator for the "message_id" field. It is called by the builders before save.
+	outboxmessage.MessageIDValidator = outboxmessageDescMessageID.Validators[0].(func(string) error)
+	// outboxmessageDescTopic is the schema descriptor for topic field.
+	outboxmessageDescTopic := outboxmessageFields[1].Descriptor()
+	// outboxmessage.TopicValidator is a validator for the "topic" field. It is called by the builders before save.
+	outboxmessage.TopicValidator = outboxmessageDescTopic.Validators[0].(func(string) error)
+	// outboxmessageDescKey is the schema descriptor for key field.
+	outboxmessageDescKey := outboxmessageFields[2].Descriptor()
+	// outboxmessage.DefaultKey holds the default value on creation for the key field.
+	outboxmessage.DefaultKey = outboxmessageDescKey.Default.(string)
+	// outboxmessageDescAttempts is the schema descriptor for attempts field.
+	outboxmessageDescAttempts := outboxmessageFields[6].Descriptor()
+	// outboxmessage.DefaultAttempts holds the default value on creation for the attempts field.
+	outboxmessage.DefaultAttempts = outboxmessageDescAttempts.Default.(int)
+	// outboxmessage.AttemptsValidator is a validator for the "attempts" field. It is called by the builders before save.
+	outboxmessage.AttemptsValidator = outboxmessageDescAttempts.Validators[0].(func(int) error)
+	// outboxmessageDescAvailableAt is the schema descriptor for available_at field.
+	outboxmessageDescAvailableAt := outboxmessageFields[8].Descriptor()
+	// outboxmessage.DefaultAvailableAt holds the default value on creation for the available_at field.
+	outboxmessage.DefaultAvailableAt = outboxmessageDescAvailableAt.Default.(func() time.Time)
+	// outboxmessageDescCreatedAt is the schema descriptor for created_at field.
+	outboxmessageDescCreatedAt := outboxmessageFields[9].Descriptor()
+	// outboxmessage.DefaultCreatedAt holds the default value on creation for the created_at field.
+	outboxmessage.DefaultCreatedAt = outboxmessageDescCreatedAt.Default.(func() time.Time)
+	webauthncredentialFields := schema.WebAuthnCredential{}.Fields()
+	_ = webauthncredentialFields
+	// webauthncredentialDescCredentialID is the schema descriptor for credential_id field.
+	webauthncredentialDescCredentialID := webauthncredentialFields[0].Descriptor()
+	// webauthncredential.CredentialIDValidator is a validator for the "credential_id" field. It is called by the builders before save.
+	webauthncredential.CredentialIDValidator = webauthncredentialDescCredentialID.Validators[0].(func([]byte) error)
+	// webauthncredentialDescUserHandle is the schema descriptor for user_handle field.
+	webauthncredentialDescUserHandle := webauthncredentialFields[1].Descriptor()
+	// webauthncredential.UserHandleValidator is a validator for the "user_handle" field. It is called by the builders before save.
+	webauthncredential.UserHandleValidator = webauthncredentialDescUserHandle.Validators[0].(func([]byte) error)
+	// webauthncredentialDescPublicKey is the schema descriptor for public_key field.
+	webauthncredentialDescPublicKey := webauthncredentialFields[2].Descriptor()
+	// webauthncredential.PublicKeyValidator is a validator for the "public_key" field. It is called by the builders before save.
+	webauthncredential.PublicKeyValidator = webauthncredentialDescPublicKey.Validators[0].(func([]byte) error)
+	// webauthncredentialDescAttestationFormat is the schema descriptor for attestation_format field.
+	webauthncredentialDescAttestationFormat := webauthncredentialFields[3].Descriptor()
+	// webauthncredential.DefaultAttestationFormat holds the default value on creation for the attestation_format field.
+	webauthncredential.DefaultAttestationFormat = webauthncredentialDescAttestationFormat.Default.(string)
+	// webauthncredentialDescSignCount is the schema descriptor for sign_count field.
+	webauthncredentialDescSignCount := webauthncredentialFields[5].Descriptor()
+	// webauthncredential.DefaultSignCount holds the default value on creation for the sign_count field.
+	webauthncredential.DefaultSignCount = webauthncredentialDescSignCount.Default.(uint32)
+	// webauthncredentialDescBackupEligible is the schema descriptor for backup_eligible field.
+	webauthncredentialDescBackupEligible := webauthncredentialFields[7].Descriptor()
+	// webauthncredential.DefaultBackupEligible holds the default value on creation for the backup_eligible field.
+	webauthncredential.DefaultBackupEligible = webauthncredentialDescBackupEligible.Default.(bool)
+	// webauthncredentialDescBackupState is the schema descriptor for backup_state field.
+	webauthncredentialDescBackupState := webauthncredentialFields[8].Descriptor()
+	// webauthncredential.DefaultBackupState holds the default value on creation for the backup_state field.
+	webauthncredential.DefaultBackupState = webauthncredentialDescBackupState.Default.(bool)
+	// webauthncredentialDescCreatedAt is the schema descriptor for created_at field.
+	webauthncredentialDescCreatedAt := webauthncredentialFields[9].Descriptor()
+	// webauthncredential.DefaultCreatedAt holds the default value on creation for the created_at field.
+	webauthncredential.DefaultCreatedAt = webauthncredentialDescCreatedAt.Default.(func() time.Time)
 }
@@ -0,0 +1,75 @@
+package schema
+
+import (
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+)
+
+// NotificationDelivery holds the schema definition for the NotificationDelivery entity.
+// 通知投递状态：每条通知一行，记录最近一次尝试的结果
+type NotificationDelivery struct {
+	ent.Schema
+}
+
+// Fields of the NotificationDelivery.
+func (NotificationDelivery) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("notification_id").
+			NotEmpty().
+			Unique().
+			Immutable().
+			Comment("Notification ID, stable across retries"),
+		field.String("channel").
+			NotEmpty().
+			Immutable().
+			Comment("Channel name the notification is sent through"),
+		field.String("recipient").
+			Default("").
+			Immutable().
+			Comment("Channel specific recipient, e.g. a phone number"),
+		field.String("template").
+			Default("").
+			Immutable().
+			Comment("Template the payload was rendered from"),
+		field.Enum("status").
+			Values("pending", "sent", "failed", "rejected").
+			Default("pending").
+			Comment("Delivery status"),
+		field.Int("attempts").
+			Default(0).
+			NonNegative().
+			Comment("Number of delivery attempts"),
+		field.String("provider_id").
+			Optional().
+			Comment("Message ID returned by the provider"),
+		field.Text("last_error").
+			Optional().
+			Comment("Error of the last failed attempt"),
+		field.JSON("metadata", map[string]string{}).
+			Optional().
+			Comment("Caller supplied metadata, e.g. tenant_id"),
+		field.Time("created_at").
+			Default(time.Now).
+			Immutable().
+			Comment("Time the notification was accepted"),
+		field.Time("updated_at").
+			Default(time.Now).
+			UpdateDefault(time.Now).
+			Comment("Time of the last status change"),
+		field.Time("sent_at").
+			Optional().
+			Nillable().
+			Comment("Time the provider accepted the notification"),
+	}
+}
+
+// Indexes of the NotificationDelivery.
+func (NotificationDelivery) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("channel", "status"),
+		index.Fields("status", "updated_at"),
+	}
+}
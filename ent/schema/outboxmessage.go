@@ -0,0 +1,72 @@
+package schema
+
+import (
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+)
+
+// OutboxMessage holds the schema definition for the OutboxMessage entity.
+// 事务发件箱：与业务数据在同一事务中写入，由中继投递到消息中间件
+type OutboxMessage struct {
+	ent.Schema
+}
+
+// Fields of the OutboxMessage.
+func (OutboxMessage) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("message_id").
+			NotEmpty().
+			Unique().
+			Immutable().
+			Comment("Event message ID, used by consumers for de-duplication"),
+		field.String("topic").
+			NotEmpty().
+			Immutable().
+			Comment("Event topic"),
+		field.String("key").
+			Default("").
+			Immutable().
+			Comment("Ordering / partition key"),
+		field.JSON("headers", map[string]string{}).
+			Optional().
+			Immutable().
+			Comment("Message headers, including propagated request and trace context"),
+		field.Bytes("payload").
+			Optional().
+			Immutable().
+			Comment("Encoded event"),
+		field.Enum("status").
+			Values("pending", "delivered", "dead").
+			Default("pending").
+			Comment("Delivery status"),
+		field.Int("attempts").
+			Default(0).
+			NonNegative().
+			Comment("Number of relay attempts"),
+		field.Text("last_error").
+			Optional().
+			Comment("Error of the last failed attempt"),
+		field.Time("available_at").
+			Default(time.Now).
+			Comment("Earliest time the relay may (re)try the message"),
+		field.Time("created_at").
+			Default(time.Now).
+			Immutable().
+			Comment("Time the message was stored"),
+		field.Time("delivered_at").
+			Optional().
+			Nillable().
+			Comment("Time the message was published"),
+	}
+}
+
+// Indexes of the OutboxMessage.
+func (OutboxMessage) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("status", "available_at"),
+		index.Fields("key", "status"),
+	}
+}
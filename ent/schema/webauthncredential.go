@@ -0,0 +1,64 @@
+package schema
+
+import (
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+)
+
+// WebAuthnCredential holds the schema definition for the WebAuthnCredential entity.
+// 用于存储用户注册的 WebAuthn / Passkey 凭证
+type WebAuthnCredential struct {
+	ent.Schema
+}
+
+// Fields of the WebAuthnCredential.
+func (WebAuthnCredential) Fields() []ent.Field {
+	return []ent.Field{
+		field.Bytes("credential_id").
+			NotEmpty().
+			Unique().
+			Comment("Credential ID issued by the authenticator"),
+		field.Bytes("user_handle").
+			NotEmpty().
+			Comment("WebAuthn user handle"),
+		field.Bytes("public_key").
+			NotEmpty().
+			Comment("COSE encoded credential public key"),
+		field.String("attestation_format").
+			Default("none").
+			Comment("Attestation statement format"),
+		field.Bytes("aaguid").
+			Optional().
+			Comment("Authenticator AAGUID"),
+		field.Uint32("sign_count").
+			Default(0).
+			Comment("Last seen signature counter"),
+		field.JSON("transports", []string{}).
+			Optional().
+			Comment("Authenticator transports hint"),
+		field.Bool("backup_eligible").
+			Default(false).
+			Comment("Whether the credential may be synced"),
+		field.Bool("backup_state").
+			Default(false).
+			Comment("Whether the credential is currently backed up"),
+		field.Time("created_at").
+			Default(time.Now).
+			Immutable().
+			Comment("Registration time"),
+		field.Time("last_used_at").
+			Optional().
+			Nillable().
+			Comment("Last successful assertion time"),
+	}
+}
+
+// Indexes of the WebAuthnCredential.
+func (WebAuthnCredential) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("user_handle"),
+	}
+}
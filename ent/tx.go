@@ -18,6 +18,12 @@ type Tx struct {
 	Media *MediaClient
 	// MediaFormat is the client for interacting with the MediaFormat builders.
 	MediaFormat *MediaFormatClient
+	// NotificationDelivery is the client for interacting with the NotificationDelivery builders.
+	NotificationDelivery *NotificationDeliveryClient
+	// OutboxMessage is the client for interacting with the OutboxMessage builders.
+	OutboxMessage *OutboxMessageClient
+	// WebAuthnCredential is the client for interacting with the WebAuthnCredential builders.
+	WebAuthnCredential *WebAuthnCredentialClient
 
 	// lazily loaded.
 	client     *Client
@@ -152,6 +158,9 @@ func (tx *Tx) init() {
 	tx.CasbinPolicy = NewCasbinPolicyClient(tx.config)
 	tx.Media = NewMediaClient(tx.config)
 	tx.MediaFormat = NewMediaFormatClient(tx.config)
+	tx.NotificationDelivery = NewNotificationDeliveryClient(tx.config)
+	tx.OutboxMessage = NewOutboxMessageClient(tx.config)
+	tx.WebAuthnCredential = NewWebAuthnCredentialClient(tx.config)
 }
 
 // txDriver wraps the given dialect.Tx with a nop dialect.Driver implementation.
@@ -0,0 +1,240 @@
+package ent
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/leeforge/framework/retry"
+)
+
+// TxManagerOptions 事务管理器配置
+type TxManagerOptions struct {
+	// TxOptions 最外层事务的隔离级别等选项
+	TxOptions *sql.TxOptions
+	// Retry 死锁或序列化冲突时重试整个事务，nil 时最多执行 3 次；
+	// Classify 为空时使用 IsRetryableTxError。设置 MaxAttempts 为 1 可关闭重试
+	Retry *retry.Policy
+}
+
+// TxManager 管理事务边界：WithTx 开启事务并放入 ctx，嵌套调用共享同一事务，
+// 嵌套作用域以保存点隔离
+//
+//	txm := ent.NewTxManager(client, ent.TxManagerOptions{})
+//	err := txm.WithTx(ctx, func(ctx context.Context) error {
+//		user, err := ent.TxClient(ctx, client).User.Create().Save(ctx)
+//		ent.AfterCommit(ctx, func(ctx context.Context) { cache.Delete(ctx, "users") })
+//		return err
+//	})
+type TxManager struct {
+	client *Client
+	opts   TxManagerOptions
+	policy retry.Policy
+}
+
+// NewTxManager 创建事务管理器
+func NewTxManager(client *Client, opts TxManagerOptions) *TxManager {
+	policy := retry.DefaultPolicy()
+	if opts.Retry != nil {
+		policy = *opts.Retry
+	}
+	if policy.Classify == nil {
+		policy.Classify = IsRetryableTxError
+	}
+	return &TxManager{client: client, opts: opts, policy: policy}
+}
+
+// WithTx 在事务中执行 fn。ctx 中没有事务时开启新事务，fn 返回 nil 时提交、
+// 返回错误或 panic 时回滚，提交后依次执行 AfterCommit 注册的钩子；死锁等
+// 可重试错误会重新执行整个 fn，fn 须可重入。ctx 中已有事务时在保存点内执行 fn，
+// 失败只回滚到保存点，错误原样返回给外层
+func (m *TxManager) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if tx := TxFromContext(ctx); tx != nil {
+		return withSavepoint(ctx, tx, fn)
+	}
+	return m.policy.Do(ctx, func(ctx context.Context) error {
+		return m.run(ctx, fn)
+	})
+}
+
+// run 执行一次最外层事务
+func (m *TxManager) run(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	tx, err := m.client.BeginTx(ctx, m.opts.TxOptions)
+	if err != nil {
+		return err
+	}
+	scope := &txScope{tx: tx}
+	txCtx := context.WithValue(NewTxContext(ctx, tx), txScopeKey{}, scope)
+
+	defer func() {
+		if v := recover(); v != nil {
+			tx.Rollback()
+			panic(v)
+		}
+	}()
+
+	if err := fn(txCtx); err != nil {
+		return rollback(tx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	// 钩子使用不含事务的 ctx，其中的查询不会落在已结束的事务上
+	for _, hook := range scope.takeHooks() {
+		hook(ctx)
+	}
+	return nil
+}
+
+// savepointSeq 保存点名称序号，进程内唯一即可
+var savepointSeq atomic.Uint64
+
+// withSavepoint 在 tx 的保存点内执行 fn
+func withSavepoint(ctx context.Context, tx *Tx, fn func(ctx context.Context) error) (err error) {
+	name := fmt.Sprintf("ent_sp_%d", savepointSeq.Add(1))
+	if err := tx.config.driver.Exec(ctx, "SAVEPOINT "+name, []any{}, nil); err != nil {
+		return fmt.Errorf("ent: savepoint: %w", err)
+	}
+	scope := &txScope{tx: tx, parent: scopeFrom(ctx, tx)}
+	spCtx := context.WithValue(ctx, txScopeKey{}, scope)
+
+	defer func() {
+		if v := recover(); v != nil {
+			tx.config.driver.Exec(ctx, "ROLLBACK TO SAVEPOINT "+name, []any{}, nil)
+			panic(v)
+		}
+	}()
+
+	if err := fn(spCtx); err != nil {
+		if rerr := tx.config.driver.Exec(ctx, "ROLLBACK TO SAVEPOINT "+name, []any{}, nil); rerr != nil {
+			return stderrors.Join(err, fmt.Errorf("ent: rollback to savepoint: %w", rerr))
+		}
+		// 回滚的作用域内注册的钩子随之丢弃
+		return err
+	}
+	if err := tx.config.driver.Exec(ctx, "RELEASE SAVEPOINT "+name, []any{}, nil); err != nil {
+		return fmt.Errorf("ent: release savepoint: %w", err)
+	}
+	for _, hook := range scope.takeHooks() {
+		afterCommit(ctx, tx, scope.parent, hook)
+	}
+	return nil
+}
+
+func rollback(tx *Tx, err error) error {
+	if rerr := tx.Rollback(); rerr != nil {
+		return stderrors.Join(err, fmt.Errorf("ent: rollback: %w", rerr))
+	}
+	return err
+}
+
+// AfterCommit 注册在 ctx 中的事务提交后执行的钩子，用于缓存失效、发件箱中继
+// 唤醒等。事务或所在保存点回滚时钩子不执行；ctx 中没有事务时立即执行
+func AfterCommit(ctx context.Context, hook func(ctx context.Context)) {
+	tx := TxFromContext(ctx)
+	if tx == nil {
+		hook(ctx)
+		return
+	}
+	afterCommit(ctx, tx, scopeFrom(ctx, tx), hook)
+}
+
+func afterCommit(ctx context.Context, tx *Tx, scope *txScope, hook func(ctx context.Context)) {
+	if scope != nil {
+		scope.addHook(hook)
+		return
+	}
+	// 事务不由 TxManager 开启时挂到 ent 的提交钩子上
+	tx.OnCommit(func(next Committer) Committer {
+		return CommitFunc(func(commitCtx context.Context, tx *Tx) error {
+			if err := next.Commit(commitCtx, tx); err != nil {
+				return err
+			}
+			hook(context.WithoutCancel(ctx))
+			return nil
+		})
+	})
+}
+
+// TxClient 返回 ctx 中事务绑定的客户端，没有事务时返回 client。
+// 仓储层通过它使嵌套调用自动加入外层事务
+func TxClient(ctx context.Context, client *Client) *Client {
+	if tx := TxFromContext(ctx); tx != nil {
+		return tx.Client()
+	}
+	return client
+}
+
+type txScopeKey struct{}
+
+// txScope 一层事务或保存点作用域，收集提交后执行的钩子
+type txScope struct {
+	tx     *Tx
+	parent *txScope
+
+	mu    sync.Mutex
+	hooks []func(ctx context.Context)
+}
+
+// scopeFrom 返回 ctx 中属于 tx 的作用域
+func scopeFrom(ctx context.Context, tx *Tx) *txScope {
+	scope, _ := ctx.Value(txScopeKey{}).(*txScope)
+	if scope == nil || scope.tx != tx {
+		return nil
+	}
+	return scope
+}
+
+func (s *txScope) addHook(hook func(ctx context.Context)) {
+	s.mu.Lock()
+	s.hooks = append(s.hooks, hook)
+	s.mu.Unlock()
+}
+
+func (s *txScope) takeHooks() []func(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hooks := s.hooks
+	s.hooks = nil
+	return hooks
+}
+
+// retryableTxStates 死锁与序列化失败的 SQLSTATE
+var retryableTxStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+}
+
+// retryableTxMessages 无法取得 SQLSTATE 时按错误信息识别
+var retryableTxMessages = []string{
+	"deadlock",
+	"could not serialize access",
+	"sqlstate 40001",
+	"sqlstate 40p01",
+	"error 1213", // MySQL ER_LOCK_DEADLOCK
+	"error 1205", // MySQL ER_LOCK_WAIT_TIMEOUT
+	"database is locked",
+	"sqlite_busy",
+}
+
+// IsRetryableTxError 判断错误是否为死锁、序列化冲突等重试整个事务即可解决的错误
+func IsRetryableTxError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var state interface{ SQLState() string }
+	if stderrors.As(err, &state) {
+		return retryableTxStates[state.SQLState()]
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range retryableTxMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
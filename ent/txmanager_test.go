@@ -0,0 +1,241 @@
+package ent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/ent/casbinpolicy"
+	"github.com/leeforge/framework/retry"
+)
+
+type sqlStateError struct{ state string }
+
+func (e *sqlStateError) Error() string    { return "pq: error " + e.state }
+func (e *sqlStateError) SQLState() string { return e.state }
+
+func createPolicy(ctx context.Context, client *Client, v0 string) error {
+	_, err := TxClient(ctx, client).CasbinPolicy.Create().SetPtype("p").SetV0(v0).Save(ctx)
+	return err
+}
+
+func policyNames(t *testing.T, client *Client) map[string]bool {
+	t.Helper()
+	names := map[string]bool{}
+	for _, p := range client.CasbinPolicy.Query().AllX(context.Background()) {
+		names[p.V0] = true
+	}
+	return names
+}
+
+func TestWithTxCommitsAndRunsHooks(t *testing.T) {
+	client := newOutboxTestClient(t)
+	txm := NewTxManager(client, TxManagerOptions{})
+
+	var hookHadTx, hookRan bool
+	err := txm.WithTx(context.Background(), func(ctx context.Context) error {
+		if TxFromContext(ctx) == nil {
+			t.Fatal("fn should receive a transactional context")
+		}
+		AfterCommit(ctx, func(ctx context.Context) {
+			hookRan = true
+			hookHadTx = TxFromContext(ctx) != nil
+		})
+		if hookRan {
+			t.Fatal("hook ran before commit")
+		}
+		return createPolicy(ctx, client, "alice")
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if !hookRan || hookHadTx {
+		t.Errorf("hook ran = %v, saw tx = %v; want run after commit without tx", hookRan, hookHadTx)
+	}
+	if !policyNames(t, client)["alice"] {
+		t.Error("committed row missing")
+	}
+}
+
+func TestWithTxRollsBackOnError(t *testing.T) {
+	client := newOutboxTestClient(t)
+	txm := NewTxManager(client, TxManagerOptions{})
+	boom := errors.New("boom")
+
+	hookRan := false
+	err := txm.WithTx(context.Background(), func(ctx context.Context) error {
+		AfterCommit(ctx, func(context.Context) { hookRan = true })
+		if err := createPolicy(ctx, client, "alice"); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+	if hookRan {
+		t.Error("hook should not run after rollback")
+	}
+	if len(policyNames(t, client)) != 0 {
+		t.Error("rolled back row persisted")
+	}
+}
+
+func TestWithTxRollsBackOnPanic(t *testing.T) {
+	client := newOutboxTestClient(t)
+	txm := NewTxManager(client, TxManagerOptions{})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("panic should propagate")
+			}
+		}()
+		txm.WithTx(context.Background(), func(ctx context.Context) error {
+			createPolicy(ctx, client, "alice")
+			panic("boom")
+		})
+	}()
+	if len(policyNames(t, client)) != 0 {
+		t.Error("row persisted after panic")
+	}
+}
+
+func TestNestedWithTxUsesSavepoints(t *testing.T) {
+	client := newOutboxTestClient(t)
+	txm := NewTxManager(client, TxManagerOptions{})
+	var hooks []string
+
+	err := txm.WithTx(context.Background(), func(ctx context.Context) error {
+		outer := TxFromContext(ctx)
+		if err := createPolicy(ctx, client, "outer"); err != nil {
+			return err
+		}
+		AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "outer") })
+
+		innerErr := txm.WithTx(ctx, func(ctx context.Context) error {
+			if TxFromContext(ctx) != outer {
+				t.Error("nested scope should share the outer transaction")
+			}
+			AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "failed") })
+			if err := createPolicy(ctx, client, "failed"); err != nil {
+				return err
+			}
+			return errors.New("inner failure")
+		})
+		if innerErr == nil {
+			t.Error("inner error should be returned")
+		}
+
+		return txm.WithTx(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "released") })
+			return createPolicy(ctx, client, "released")
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	names := policyNames(t, client)
+	if !names["outer"] || !names["released"] || names["failed"] {
+		t.Errorf("rows = %v, want outer and released only", names)
+	}
+	if fmt.Sprint(hooks) != "[outer released]" {
+		t.Errorf("hooks = %v, want [outer released]", hooks)
+	}
+}
+
+func TestWithTxRetriesDeadlocks(t *testing.T) {
+	client := newOutboxTestClient(t)
+	var retries int
+	txm := NewTxManager(client, TxManagerOptions{Retry: &retry.Policy{
+		MaxAttempts:     3,
+		InitialInterval: time.Millisecond,
+		OnRetry:         func(int, error, time.Duration) { retries++ },
+	}})
+
+	attempts := 0
+	err := txm.WithTx(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if err := createPolicy(ctx, client, fmt.Sprintf("attempt-%d", attempts)); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return &sqlStateError{state: "40P01"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if attempts != 2 || retries != 1 {
+		t.Errorf("attempts = %d, retries = %d, want 2 and 1", attempts, retries)
+	}
+	if names := policyNames(t, client); names["attempt-1"] || !names["attempt-2"] {
+		t.Errorf("rows = %v, want only the successful attempt", names)
+	}
+
+	attempts = 0
+	err = txm.WithTx(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return errors.New("constraint violation")
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("non-retryable error: attempts = %d, err = %v", attempts, err)
+	}
+}
+
+func TestAfterCommitOutsideManagedTx(t *testing.T) {
+	client := newOutboxTestClient(t)
+
+	ran := false
+	AfterCommit(context.Background(), func(context.Context) { ran = true })
+	if !ran {
+		t.Error("hook without a transaction should run immediately")
+	}
+
+	tx, err := client.Tx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := NewTxContext(context.Background(), tx)
+	ran = false
+	AfterCommit(ctx, func(context.Context) { ran = true })
+	if err := createPolicy(ctx, client, "external"); err != nil {
+		t.Fatal(err)
+	}
+	if ran {
+		t.Fatal("hook ran before commit")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if !ran {
+		t.Error("hook should run when an externally started tx commits")
+	}
+	if n := client.CasbinPolicy.Query().Where(casbinpolicy.V0("external")).CountX(context.Background()); n != 1 {
+		t.Errorf("TxClient should write through the context tx, got %d rows", n)
+	}
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&sqlStateError{state: "40001"}, true},
+		{fmt.Errorf("wrapped: %w", &sqlStateError{state: "40P01"}), true},
+		{&sqlStateError{state: "23505"}, false},
+		{errors.New("Error 1213 (40001): Deadlock found when trying to get lock"), true},
+		{errors.New("database is locked (5) (SQLITE_BUSY)"), true},
+		{errors.New("duplicate key"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryableTxError(tt.err); got != tt.want {
+			t.Errorf("IsRetryableTxError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package ent
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"entgo.io/ent/dialect"
+)
+
+// UpsertOutcome 单行 upsert 结果
+type UpsertOutcome int
+
+const (
+	UpsertInserted UpsertOutcome = iota + 1
+	UpsertUpdated
+	UpsertSkipped
+	UpsertFailed
+)
+
+// String 返回结果名称
+func (o UpsertOutcome) String() string {
+	switch o {
+	case UpsertInserted:
+		return "inserted"
+	case UpsertUpdated:
+		return "updated"
+	case UpsertSkipped:
+		return "skipped"
+	case UpsertFailed:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// UpsertResult 与输入行一一对应的 upsert 结果
+type UpsertResult struct {
+	Index   int           `json:"index"`
+	Outcome UpsertOutcome `json:"outcome"`
+	Err     error         `json:"-"`
+}
+
+var (
+	// ErrDuplicateConflictKey 同一批次中出现重复的冲突键，仅第一行会被写入
+	ErrDuplicateConflictKey = errors.New("duplicate conflict key in batch")
+	// ErrMissingColumn 行缺少批次要求的列
+	ErrMissingColumn = errors.New("row is missing a column")
+)
+
+const (
+	defaultUpsertChunkSize = 500
+	// PostgreSQL 与 MySQL 单条语句最多 65535 个绑定参数
+	maxBindParams = 65535
+	// SQLite（3.32 起）单条语句最多 32766 个绑定参数
+	maxSQLiteBindParams = 32766
+)
+
+// bindParamLimit 返回方言单条语句允许的绑定参数上限
+func bindParamLimit(dialectName string) int {
+	if dialectName == dialect.SQLite {
+		return maxSQLiteBindParams
+	}
+	return maxBindParams
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// UpsertOptions 批量 upsert 配置
+type UpsertOptions struct {
+	// Table 表名，可带 schema，如 "catalog.products"
+	Table string
+	// ConflictColumns 冲突目标列（唯一约束），每行都必须包含
+	ConflictColumns []string
+	// UpdateColumns 冲突时以新值覆盖的列，每行都必须包含；为空时冲突行保持不变
+	UpdateColumns []string
+	// Dialect ent 方言，支持 PostgreSQL（默认）与 SQLite（3.35+）
+	Dialect string
+}
+
+// UpsertMany 批量 upsert（PostgreSQL），见 Upsert
+func (e *QueryExtension) UpsertMany(ctx context.Context, table string, rows []map[string]any, conflictCols, updateCols []string) ([]UpsertResult, error) {
+	return e.Upsert(ctx, rows, UpsertOptions{Table: table, ConflictColumns: conflictCols, UpdateColumns: updateCols})
+}
+
+// Upsert 批量 upsert
+// 使用多行 INSERT ... ON CONFLICT 分块写入，返回每一行的结果而不是让整批失败：
+//   - 行按列集合分组，每组单独生成语句，缺少的列不会以 NULL 补齐
+//   - UpdateColumns 为空时冲突行不做修改，记为 skipped
+//   - 冲突行的更新列与现有值完全一致时不触发写入，同样记为 skipped
+//   - 每个分块在独立事务中执行；分块失败时回滚到保存点并逐行重试（每行一个保存点），
+//     只有真正出错的行记为 error，事务不会因某行出错进入中止状态
+func (e *QueryExtension) Upsert(ctx context.Context, rows []map[string]any, opts UpsertOptions) ([]UpsertResult, error) {
+	results := make([]UpsertResult, len(rows))
+	if len(rows) == 0 {
+		return results, nil
+	}
+	if opts.Dialect == "" {
+		opts.Dialect = dialect.Postgres
+	}
+	if opts.Dialect != dialect.Postgres && opts.Dialect != dialect.SQLite {
+		return nil, fmt.Errorf("upsert: unsupported dialect %q", opts.Dialect)
+	}
+	if len(opts.ConflictColumns) == 0 {
+		return nil, fmt.Errorf("upsert requires at least one conflict column")
+	}
+	if !validTable(opts.Table) {
+		return nil, fmt.Errorf("invalid identifier %q", opts.Table)
+	}
+	required := append(append([]string{}, opts.ConflictColumns...), opts.UpdateColumns...)
+	for _, ident := range required {
+		if !identifierPattern.MatchString(ident) {
+			return nil, fmt.Errorf("invalid identifier %q", ident)
+		}
+	}
+	for _, col := range opts.ConflictColumns {
+		if !slices.ContainsFunc(rows, func(row map[string]any) bool { _, ok := row[col]; return ok }) {
+			return nil, fmt.Errorf("conflict column %q is not present in rows", col)
+		}
+	}
+
+	// 预检：列完整性与批内重复键，并按列集合分组
+	type group struct {
+		columns []string
+		rows    []int
+	}
+	var groups []*group
+	bySignature := make(map[string]*group)
+	seen := make(map[string]int, len(rows))
+	for i, row := range rows {
+		results[i].Index = i
+		columns := make([]string, 0, len(row))
+		for col := range row {
+			if !identifierPattern.MatchString(col) {
+				return nil, fmt.Errorf("invalid identifier %q", col)
+			}
+			columns = append(columns, col)
+		}
+		sort.Strings(columns)
+
+		if missing := missingColumn(row, required); missing != "" {
+			results[i].Outcome = UpsertFailed
+			results[i].Err = fmt.Errorf("%w: %s", ErrMissingColumn, missing)
+			continue
+		}
+		key := conflictKey(row, opts.ConflictColumns)
+		if first, dup := seen[key]; dup {
+			results[i].Outcome = UpsertFailed
+			results[i].Err = fmt.Errorf("%w (first seen at row %d)", ErrDuplicateConflictKey, first)
+			continue
+		}
+		seen[key] = i
+
+		signature := strings.Join(columns, ",")
+		g, ok := bySignature[signature]
+		if !ok {
+			g = &group{columns: columns}
+			bySignature[signature] = g
+			groups = append(groups, g)
+		}
+		g.rows = append(g.rows, i)
+	}
+
+	for _, g := range groups {
+		chunkSize := min(defaultUpsertChunkSize, bindParamLimit(opts.Dialect)/len(g.columns))
+		for start := 0; start < len(g.rows); start += chunkSize {
+			if err := ctx.Err(); err != nil {
+				return results, err
+			}
+			chunk := g.rows[start:min(start+chunkSize, len(g.rows))]
+			if err := e.upsertInTx(ctx, opts, g.columns, rows, chunk, results); err != nil {
+				for _, idx := range chunk {
+					results[idx].Outcome = UpsertFailed
+					results[idx].Err = err
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// upsertInTx 在独立事务中写入一个分块
+func (e *QueryExtension) upsertInTx(ctx context.Context, opts UpsertOptions, columns []string, rows []map[string]any, chunk []int, results []UpsertResult) (err error) {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	chunkErr := sqlSavepoint(ctx, tx, "upsert_chunk", func() error {
+		return upsertChunk(ctx, tx, opts, columns, rows, chunk, results)
+	})
+	if chunkErr != nil {
+		// 分块失败时逐行重试，定位出错的行
+		for _, idx := range chunk {
+			rowErr := sqlSavepoint(ctx, tx, "upsert_row", func() error {
+				return upsertChunk(ctx, tx, opts, columns, rows, []int{idx}, results)
+			})
+			if rowErr != nil {
+				results[idx].Outcome = UpsertFailed
+				results[idx].Err = rowErr
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// sqlSavepoint 在 database/sql 事务的保存点内执行 fn，失败时回滚到保存点，
+// 事务仍可继续使用
+func sqlSavepoint(ctx context.Context, tx *sql.Tx, name string, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("ent: savepoint: %w", err)
+	}
+	if err := fn(); err != nil {
+		if _, rerr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rerr != nil {
+			return errors.Join(err, fmt.Errorf("ent: rollback to savepoint: %w", rerr))
+		}
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("ent: release savepoint: %w", err)
+	}
+	return nil
+}
+
+func upsertChunk(ctx context.Context, tx *sql.Tx, opts UpsertOptions, columns []string, rows []map[string]any, chunk []int, results []UpsertResult) error {
+	conflictCols := opts.ConflictColumns
+
+	// SQLite 没有 xmax，写入前在同一事务中查出已存在的键
+	var existing map[string]bool
+	if opts.Dialect == dialect.SQLite {
+		var err error
+		if existing, err = existingKeys(ctx, tx, opts, rows, chunk); err != nil {
+			return err
+		}
+	}
+
+	query := buildUpsertSQL(opts.Dialect, opts.Table, columns, conflictCols, opts.UpdateColumns, len(chunk))
+	args := make([]any, 0, len(chunk)*len(columns))
+	for _, idx := range chunk {
+		for _, col := range columns {
+			args = append(args, rows[idx][col])
+		}
+	}
+
+	sqlRows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer sqlRows.Close()
+
+	returned := make(map[string]bool, len(chunk))
+	for sqlRows.Next() {
+		values := make([]any, len(conflictCols))
+		dest := make([]any, len(conflictCols), len(conflictCols)+1)
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		var inserted bool
+		if existing == nil {
+			dest = append(dest, &inserted)
+		}
+		if err := sqlRows.Scan(dest...); err != nil {
+			return err
+		}
+		key := keyOf(values)
+		if existing != nil {
+			inserted = !existing[key]
+		}
+		returned[key] = inserted
+	}
+	if err := sqlRows.Err(); err != nil {
+		return err
+	}
+
+	for _, idx := range chunk {
+		inserted, ok := returned[conflictKey(rows[idx], conflictCols)]
+		switch {
+		case !ok:
+			results[idx].Outcome = UpsertSkipped
+		case inserted:
+			results[idx].Outcome = UpsertInserted
+		default:
+			results[idx].Outcome = UpsertUpdated
+		}
+		results[idx].Err = nil
+	}
+	return nil
+}
+
+// existingKeys 查询分块中已存在的冲突键
+func existingKeys(ctx context.Context, tx *sql.Tx, opts UpsertOptions, rows []map[string]any, chunk []int) (map[string]bool, error) {
+	cols := joinIdents(opts.ConflictColumns, "")
+	match := "(" + cols + ") = (" + strings.TrimSuffix(strings.Repeat("?, ", len(opts.ConflictColumns)), ", ") + ")"
+	conds := make([]string, len(chunk))
+	args := make([]any, 0, len(chunk)*len(opts.ConflictColumns))
+	for i, idx := range chunk {
+		conds[i] = match
+		for _, col := range opts.ConflictColumns {
+			args = append(args, rows[idx][col])
+		}
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", cols, quoteTable(opts.Table), strings.Join(conds, " OR "))
+	sqlRows, err := tx.QueryContext(ctx, Rebind(PlaceholderFor(opts.Dialect), query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer sqlRows.Close()
+
+	existing := make(map[string]bool, len(chunk))
+	for sqlRows.Next() {
+		values := make([]any, len(opts.ConflictColumns))
+		dest := make([]any, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := sqlRows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		existing[keyOf(values)] = true
+	}
+	return existing, sqlRows.Err()
+}
+
+// buildUpsertSQL 构建多行 upsert 语句，RETURNING 冲突列；PostgreSQL 另返回
+// xmax = 0（新插入的行）
+func buildUpsertSQL(dialectName, table string, columns, conflictCols, updateCols []string, rowCount int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", quoteTable(table), joinIdents(columns, ""))
+
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	for r := 0; r < rowCount; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(row)
+	}
+
+	fmt.Fprintf(&b, " ON CONFLICT (%s)", joinIdents(conflictCols, ""))
+	if len(updateCols) == 0 {
+		b.WriteString(" DO NOTHING")
+	} else {
+		sets := make([]string, len(updateCols))
+		for i, col := range updateCols {
+			sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", quoteIdent(col), quoteIdent(col))
+		}
+		fmt.Fprintf(&b, " DO UPDATE SET %s WHERE (%s) IS DISTINCT FROM (%s)",
+			strings.Join(sets, ", "),
+			joinIdents(updateCols, quoteTable(table)+"."),
+			joinIdents(updateCols, "EXCLUDED."),
+		)
+	}
+	fmt.Fprintf(&b, " RETURNING %s", joinIdents(conflictCols, ""))
+	if dialectName == dialect.Postgres {
+		b.WriteString(", (xmax = 0) AS inserted")
+	}
+	return Rebind(PlaceholderFor(dialectName), b.String())
+}
+
+// validTable 校验表名，允许 schema.table
+func validTable(table string) bool {
+	parts := strings.Split(table, ".")
+	if len(parts) > 2 {
+		return false
+	}
+	for _, part := range parts {
+		if !identifierPattern.MatchString(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteTable 引用表名，schema.table 分别引用
+func quoteTable(table string) string {
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdent(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func quoteIdent(name string) string {
+	return `"` + name + `"`
+}
+
+func joinIdents(names []string, prefix string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = prefix + quoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func missingColumn(row map[string]any, columns []string) string {
+	for _, col := range columns {
+		if _, ok := row[col]; !ok {
+			return col
+		}
+	}
+	return ""
+}
+
+func conflictKey(row map[string]any, conflictCols []string) string {
+	values := make([]any, len(conflictCols))
+	for i, col := range conflictCols {
+		values[i] = row[col]
+	}
+	return keyOf(values)
+}
+
+// keyOf 归一化冲突键，使输入值与数据库返回值可比较
+func keyOf(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		switch val := v.(type) {
+		case nil:
+			parts[i] = "\x00"
+		case []byte:
+			parts[i] = string(val)
+		case time.Time:
+			parts[i] = val.UTC().Format(time.RFC3339Nano)
+		case fmt.Stringer:
+			parts[i] = val.String()
+		default:
+			parts[i] = fmt.Sprint(val)
+		}
+	}
+	return strings.Join(parts, "\x1f")
+}
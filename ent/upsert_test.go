@@ -0,0 +1,138 @@
+package ent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"entgo.io/ent/dialect"
+)
+
+func TestBuildUpsertSQL(t *testing.T) {
+	got := buildUpsertSQL(dialect.Postgres, "users", []string{"email", "name"}, []string{"email"}, []string{"name"}, 2)
+	want := `INSERT INTO "users" ("email", "name") VALUES ($1, $2), ($3, $4)` +
+		` ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name"` +
+		` WHERE ("users"."name") IS DISTINCT FROM (EXCLUDED."name")` +
+		` RETURNING "email", (xmax = 0) AS inserted`
+	if got != want {
+		t.Fatalf("unexpected SQL:\n got: %s\nwant: %s", got, want)
+	}
+
+	got = buildUpsertSQL(dialect.Postgres, "users", []string{"email"}, []string{"email"}, nil, 1)
+	want = `INSERT INTO "users" ("email") VALUES ($1) ON CONFLICT ("email") DO NOTHING RETURNING "email", (xmax = 0) AS inserted`
+	if got != want {
+		t.Fatalf("unexpected SQL:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestBuildUpsertSQLDialectsAndSchema(t *testing.T) {
+	got := buildUpsertSQL(dialect.SQLite, "catalog.users", []string{"email", "name"}, []string{"email"}, []string{"name"}, 1)
+	want := `INSERT INTO "catalog"."users" ("email", "name") VALUES (?, ?)` +
+		` ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name"` +
+		` WHERE ("catalog"."users"."name") IS DISTINCT FROM (EXCLUDED."name")` +
+		` RETURNING "email"`
+	if got != want {
+		t.Fatalf("unexpected SQL:\n got: %s\nwant: %s", got, want)
+	}
+
+	for table, ok := range map[string]bool{"users": true, "catalog.users": true, "a.b.c": false, "catalog.": false, `users"`: false} {
+		if validTable(table) != ok {
+			t.Errorf("validTable(%q) = %v, want %v", table, !ok, ok)
+		}
+	}
+}
+
+func TestUpsertOutcomesSQLite(t *testing.T) {
+	db := openBulkTestDB(t)
+	if _, err := db.Exec(`INSERT INTO users (email, name) VALUES ('same@x', 'a'), ('changed@x', 'a')`); err != nil {
+		t.Fatal(err)
+	}
+	ext := NewQueryExtension(db)
+
+	results, err := ext.Upsert(context.Background(), []map[string]any{
+		{"email": "new@x", "name": "b"},
+		{"email": "changed@x", "name": "b"},
+		{"email": "same@x", "name": "a"},
+		{"email": "new@x", "name": "c"},
+	}, UpsertOptions{Table: "main.users", ConflictColumns: []string{"email"}, UpdateColumns: []string{"name"}, Dialect: dialect.SQLite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []UpsertOutcome{UpsertInserted, UpsertUpdated, UpsertSkipped, UpsertFailed}
+	for i, r := range results {
+		if r.Outcome != want[i] {
+			t.Errorf("row %d: outcome = %s, want %s (%v)", i, r.Outcome, want[i], r.Err)
+		}
+	}
+	if !errors.Is(results[3].Err, ErrDuplicateConflictKey) {
+		t.Errorf("row 3: err = %v", results[3].Err)
+	}
+	if countUsers(t, db, "name = 'b'") != 2 {
+		t.Error("inserted and updated rows were not written")
+	}
+}
+
+func TestUpsertFallsBackPerRowWithSavepoints(t *testing.T) {
+	db := openBulkTestDB(t)
+	ext := NewQueryExtension(db)
+
+	// name NOT NULL：第二行让整块失败，逐行重试时其余行仍在同一事务中写入
+	results, err := ext.Upsert(context.Background(), []map[string]any{
+		{"email": "a@x", "name": "a"},
+		{"email": "b@x", "name": nil},
+		{"email": "c@x", "name": "c"},
+	}, UpsertOptions{Table: "users", ConflictColumns: []string{"email"}, UpdateColumns: []string{"name"}, Dialect: dialect.SQLite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Outcome != UpsertInserted || results[1].Outcome != UpsertFailed || results[2].Outcome != UpsertInserted {
+		t.Fatalf("results = %+v", results)
+	}
+	if countUsers(t, db, "1 = 1") != 2 {
+		t.Error("rows around the failing one were not committed")
+	}
+}
+
+func TestUpsertGroupsRowsByColumns(t *testing.T) {
+	db := openBulkTestDB(t)
+	if _, err := db.Exec(`ALTER TABLE users ADD COLUMN note TEXT DEFAULT 'none'`); err != nil {
+		t.Fatal(err)
+	}
+	ext := NewQueryExtension(db)
+
+	results, err := ext.Upsert(context.Background(), []map[string]any{
+		{"email": "a@x", "name": "a"},
+		{"email": "b@x", "name": "b", "note": "vip"},
+		{"email": "c@x", "note": "no name"},
+	}, UpsertOptions{Table: "users", ConflictColumns: []string{"email"}, UpdateColumns: []string{"name"}, Dialect: dialect.SQLite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Outcome != UpsertInserted || results[1].Outcome != UpsertInserted {
+		t.Fatalf("results = %+v", results)
+	}
+	if !errors.Is(results[2].Err, ErrMissingColumn) {
+		t.Errorf("row without an update column: err = %v", results[2].Err)
+	}
+	// 缺少的列不以 NULL 补齐，保留默认值
+	if countUsers(t, db, "email = 'a@x' AND note = 'none'") != 1 || countUsers(t, db, "email = 'b@x' AND note = 'vip'") != 1 {
+		t.Error("columns were not written per row")
+	}
+}
+
+func TestUpsertManyValidatesRows(t *testing.T) {
+	ext := NewQueryExtension(nil)
+
+	if _, err := ext.UpsertMany(context.Background(), "users; drop", []map[string]any{{"email": "a"}}, []string{"email"}, nil); err == nil {
+		t.Fatal("expected invalid identifier error")
+	}
+	if _, err := ext.UpsertMany(context.Background(), "users", []map[string]any{{"name": "a"}}, []string{"email"}, nil); err == nil {
+		t.Fatal("expected missing conflict column error")
+	}
+}
+
+func TestKeyOfNormalizesDriverValues(t *testing.T) {
+	if keyOf([]any{"a@b.c", int64(7)}) != keyOf([]any{[]byte("a@b.c"), 7}) {
+		t.Fatal("string/[]byte and int/int64 keys should match")
+	}
+}
@@ -0,0 +1,226 @@
+// Code generated by ent, DO NOT EDIT.
+
+package ent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"github.com/leeforge/framework/ent/webauthncredential"
+)
+
+// WebAuthnCredential is the model entity for the WebAuthnCredential schema.
+type WebAuthnCredential struct {
+	config `json:"-"`
+	// ID of the ent.
+	ID int `json:"id,omitempty"`
+	// Credential ID issued by the authenticator
+	CredentialID []byte `json:"credential_id,omitempty"`
+	// WebAuthn user handle
+	UserHandle []byte `json:"user_handle,omitempty"`
+	// COSE encoded credential public key
+	PublicKey []byte `json:"public_key,omitempty"`
+	// Attestation statement format
+	AttestationFormat string `json:"attestation_format,omitempty"`
+	// Authenticator AAGUID
+	Aaguid []byte `json:"aaguid,omitempty"`
+	// Last seen signature counter
+	SignCount uint32 `json:"sign_count,omitempty"`
+	// Authenticator transports hint
+	Transports []string `json:"transports,omitempty"`
+	// Whether the credential may be synced
+	BackupEligible bool `json:"backup_eligible,omitempty"`
+	// Whether the credential is currently backed up
+	BackupState bool `json:"backup_state,omitempty"`
+	// Registration time
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// Last successful assertion time
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	selectValues sql.SelectValues
+}
+
+// scanValues returns the types for scanning values from sql.Rows.
+func (*WebAuthnCredential) scanValues(columns []string) ([]any, error) {
+	values := make([]any, len(columns))
+	for i := range columns {
+		switch columns[i] {
+		case webauthncredential.FieldCredentialID, webauthncredential.FieldUserHandle, webauthncredential.FieldPublicKey, webauthncredential.FieldAaguid, webauthncredential.FieldTransports:
+			values[i] = new([]byte)
+		case webauthncredential.FieldBackupEligible, webauthncredential.FieldBackupState:
+			values[i] = new(sql.NullBool)
+		case webauthncredential.FieldID, webauthncredential.FieldSignCount:
+			values[i] = new(sql.NullInt64)
+		case webauthncredential.FieldAttestationFormat:
+			values[i] = new(sql.NullString)
+		case webauthncredential.FieldCreatedAt, webauthncredential.FieldLastUsedAt:
+			values[i] = new(sql.NullTime)
+		default:
+			values[i] = new(sql.UnknownType)
+		}
+	}
+	return values, nil
+}
+
+// assignValues assigns the values that were returned from sql.Rows (after scanning)
+// to the WebAuthnCredential fields.
+func (_m *WebAuthnCredential) assignValues(columns []string, values []any) error {
+	if m, n := len(values), len(columns); m < n {
+		return fmt.Errorf("mismatch number of scan values: %d != %d", m, n)
+	}
+	for i := range columns {
+		switch columns[i] {
+		case webauthncredential.FieldID:
+			value, ok := values[i].(*sql.NullInt64)
+			if !ok {
+				return fmt.Errorf("unexpected type %T for field id", value)
+			}
+			_m.ID = int(value.Int64)
+		case webauthncredential.FieldCredentialID:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field credential_id", values[i])
+			} else if value != nil {
+				_m.CredentialID = *value
+			}
+		case webauthncredential.FieldUserHandle:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field user_handle", values[i])
+			} else if value != nil {
+				_m.UserHandle = *value
+			}
+		case webauthncredential.FieldPublicKey:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field public_key", values[i])
+			} else if value != nil {
+				_m.PublicKey = *value
+			}
+		case webauthncredential.FieldAttestationFormat:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field attestation_format", values[i])
+			} else if value.Valid {
+				_m.AttestationFormat = value.String
+			}
+		case webauthncredential.FieldAaguid:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field aaguid", values[i])
+			} else if value != nil {
+				_m.Aaguid = *value
+			}
+		case webauthncredential.FieldSignCount:
+			if value, ok := values[i].(*sql.NullInt64); !ok {
+				return fmt.Errorf("unexpected type %T for field sign_count", values[i])
+			} else if value.Valid {
+				_m.SignCount = uint32(value.Int64)
+			}
+		case webauthncredential.FieldTransports:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field transports", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.Transports); err != nil {
+					return fmt.Errorf("unmarshal field transports: %w", err)
+				}
+			}
+		case webauthncredential.FieldBackupEligible:
+			if value, ok := values[i].(*sql.NullBool); !ok {
+				return fmt.Errorf("unexpected type %T for field backup_eligible", values[i])
+			} else if value.Valid {
+				_m.BackupEligible = value.Bool
+			}
+		case webauthncredential.FieldBackupState:
+			if value, ok := values[i].(*sql.NullBool); !ok {
+				return fmt.Errorf("unexpected type %T for field backup_state", values[i])
+			} else if value.Valid {
+				_m.BackupState = value.Bool
+			}
+		case webauthncredential.FieldCreatedAt:
+			if value, ok := values[i].(*sql.NullTime); !ok {
+				return fmt.Errorf("unexpected type %T for field created_at", values[i])
+			} else if value.Valid {
+				_m.CreatedAt = value.Time
+			}
+		case webauthncredential.FieldLastUsedAt:
+			if value, ok := values[i].(*sql.NullTime); !ok {
+				return fmt.Errorf("unexpected type %T for field last_used_at", values[i])
+			} else if value.Valid {
+				_m.LastUsedAt = new(time.Time)
+				*_m.LastUsedAt = value.Time
+			}
+		default:
+			_m.selectValues.Set(columns[i], values[i])
+		}
+	}
+	return nil
+}
+
+// Value returns the ent.Value that was dynamically selected and assigned to the WebAuthnCredential.
+// This includes values selected through modifiers, order, etc.
+func (_m *WebAuthnCredential) Value(name string) (ent.Value, error) {
+	return _m.selectValues.Get(name)
+}
+
+// Update returns a builder for updating this WebAuthnCredential.
+// Note that you need to call WebAuthnCredential.Unwrap() before calling this method if this WebAuthnCredential
+// was returned from a transaction, and the transaction was committed or rolled back.
+func (_m *WebAuthnCredential) Update() *WebAuthnCredentialUpdateOne {
+	return NewWebAuthnCredentialClient(_m.config).UpdateOne(_m)
+}
+
+// Unwrap unwraps the WebAuthnCredential entity that was returned from a transaction after it was closed,
+// so that all future queries will be executed through the driver which created the transaction.
+func (_m *WebAuthnCredential) Unwrap() *WebAuthnCredential {
+	_tx, ok := _m.config.driver.(*txDriver)
+	if !ok {
+		panic("ent: WebAuthnCredential is not a transactional entity")
+	}
+	_m.config.driver = _tx.drv
+	return _m
+}
+
+// String implements the fmt.Stringer.
+func (_m *WebAuthnCredential) String() string {
+	var builder strings.Builder
+	builder.WriteString("WebAuthnCredential(")
+	builder.WriteString(fmt.Sprintf("id=%v, ", _m.ID))
+	builder.WriteString("credential_id=")
+	builder.WriteString(fmt.Sprintf("%v", _m.CredentialID))
+	builder.WriteString(", ")
+	builder.WriteString("user_handle=")
+	builder.WriteString(fmt.Sprintf("%v", _m.UserHandle))
+	builder.WriteString(", ")
+	builder.WriteString("public_key=")
+	builder.WriteString(fmt.Sprintf("%v", _m.PublicKey))
+	builder.WriteString(", ")
+	builder.WriteString("attestation_format=")
+	builder.WriteString(_m.AttestationFormat)
+	builder.WriteString(", ")
+	builder.WriteString("aaguid=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Aaguid))
+	builder.WriteString(", ")
+	builder.WriteString("sign_count=")
+	builder.WriteString(fmt.Sprintf("%v", _m.SignCount))
+	builder.WriteString(", ")
+	builder.WriteString("transports=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Transports))
+	builder.WriteString(", ")
+	builder.WriteString("backup_eligible=")
+	builder.WriteString(fmt.Sprintf("%v", _m.BackupEligible))
+	builder.WriteString(", ")
+	builder.WriteString("backup_state=")
+	builder.WriteString(fmt.Sprintf("%v", _m.BackupState))
+	builder.WriteString(", ")
+	builder.WriteString("created_at=")
+	builder.WriteString(_m.CreatedAt.Format(time.ANSIC))
+	builder.WriteString(", ")
+	if v := _m.LastUsedAt; v != nil {
+		builder.WriteString("last_used_at=")
+		builder.WriteString(v.Format(time.ANSIC))
+	}
+	builder.WriteByte(')')
+	return builder.String()
+}
+
+// WebAuthnCredentials is a parsable slice of WebAuthnCredential.
+type WebAuthnCredentials []*WebAuthnCredential
@@ -0,0 +1,515 @@
+// Code generated by ent, DO NOT EDIT.
+
+package webauthncredential
+
+import (
+	"time"
+
+	"entgo.io/ent/dialect/sql"
+	"github.com/leeforge/framework/ent/predicate"
+)
+
+// ID filters vertices based on their ID field.
+func ID(id int) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldID, id))
+}
+
+// IDEQ applies the EQ predicate on the ID field.
+func IDEQ(id int) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldID, id))
+}
+
+// IDNEQ applies the NEQ predicate on the ID field.
+func IDNEQ(id int) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNEQ(FieldID, id))
+}
+
+// IDIn applies the In predicate on the ID field.
+func IDIn(ids ...int) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldIn(FieldID, ids...))
+}
+
+// IDNotIn applies the NotIn predicate on the ID field.
+func IDNotIn(ids ...int) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNotIn(FieldID, ids...))
+}
+
+// IDGT applies the GT predicate on the ID field.
+func IDGT(id int) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGT(FieldID, id))
+}
+
+// IDGTE applies the GTE predicate on the ID field.
+func IDGTE(id int) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGTE(FieldID, id))
+}
+
+// IDLT applies the LT predicate on the ID field.
+func IDLT(id int) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLT(FieldID, id))
+}
+
+// IDLTE applies the LTE predicate on the ID field.
+func IDLTE(id int) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLTE(FieldID, id))
+}
+
+// CredentialID applies equality check predicate on the "credential_id" field. It's identical to CredentialIDEQ.
+func CredentialID(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldCredentialID, v))
+}
+
+// UserHandle applies equality check predicate on the "user_handle" field. It's identical to UserHandleEQ.
+func UserHandle(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldUserHandle, v))
+}
+
+// PublicKey applies equality check predicate on the "public_key" field. It's identical to PublicKeyEQ.
+func PublicKey(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldPublicKey, v))
+}
+
+// AttestationFormat applies equality check predicate on the "attestation_format" field. It's identical to AttestationFormatEQ.
+func AttestationFormat(v string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldAttestationFormat, v))
+}
+
+// Aaguid applies equality check predicate on the "aaguid" field. It's identical to AaguidEQ.
+func Aaguid(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldAaguid, v))
+}
+
+// SignCount applies equality check predicate on the "sign_count" field. It's identical to SignCountEQ.
+func SignCount(v uint32) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldSignCount, v))
+}
+
+// BackupEligible applies equality check predicate on the "backup_eligible" field. It's identical to BackupEligibleEQ.
+func BackupEligible(v bool) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldBackupEligible, v))
+}
+
+// BackupState applies equality check predicate on the "backup_state" field. It's identical to BackupStateEQ.
+func BackupState(v bool) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldBackupState, v))
+}
+
+// CreatedAt applies equality check predicate on the "created_at" field. It's identical to CreatedAtEQ.
+func CreatedAt(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldCreatedAt, v))
+}
+
+// LastUsedAt applies equality check predicate on the "last_used_at" field. It's identical to LastUsedAtEQ.
+func LastUsedAt(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldLastUsedAt, v))
+}
+
+// CredentialIDEQ applies the EQ predicate on the "credential_id" field.
+func CredentialIDEQ(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldCredentialID, v))
+}
+
+// CredentialIDNEQ applies the NEQ predicate on the "credential_id" field.
+func CredentialIDNEQ(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNEQ(FieldCredentialID, v))
+}
+
+// CredentialIDIn applies the In predicate on the "credential_id" field.
+func CredentialIDIn(vs ...[]byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldIn(FieldCredentialID, vs...))
+}
+
+// CredentialIDNotIn applies the NotIn predicate on the "credential_id" field.
+func CredentialIDNotIn(vs ...[]byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNotIn(FieldCredentialID, vs...))
+}
+
+// CredentialIDGT applies the GT predicate on the "credential_id" field.
+func CredentialIDGT(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGT(FieldCredentialID, v))
+}
+
+// CredentialIDGTE applies the GTE predicate on the "credential_id" field.
+func CredentialIDGTE(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGTE(FieldCredentialID, v))
+}
+
+// CredentialIDLT applies the LT predicate on the "credential_id" field.
+func CredentialIDLT(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLT(FieldCredentialID, v))
+}
+
+// CredentialIDLTE applies the LTE predicate on the "credential_id" field.
+func CredentialIDLTE(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLTE(FieldCredentialID, v))
+}
+
+// UserHandleEQ applies the EQ predicate on the "user_handle" field.
+func UserHandleEQ(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldUserHandle, v))
+}
+
+// UserHandleNEQ applies the NEQ predicate on the "user_handle" field.
+func UserHandleNEQ(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNEQ(FieldUserHandle, v))
+}
+
+// UserHandleIn applies the In predicate on the "user_handle" field.
+func UserHandleIn(vs ...[]byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldIn(FieldUserHandle, vs...))
+}
+
+// UserHandleNotIn applies the NotIn predicate on the "user_handle" field.
+func UserHandleNotIn(vs ...[]byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNotIn(FieldUserHandle, vs...))
+}
+
+// UserHandleGT applies the GT predicate on the "user_handle" field.
+func UserHandleGT(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGT(FieldUserHandle, v))
+}
+
+// UserHandleGTE applies the GTE predicate on the "user_handle" field.
+func UserHandleGTE(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGTE(FieldUserHandle, v))
+}
+
+// UserHandleLT applies the LT predicate on the "user_handle" field.
+func UserHandleLT(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLT(FieldUserHandle, v))
+}
+
+// UserHandleLTE applies the LTE predicate on the "user_handle" field.
+func UserHandleLTE(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLTE(FieldUserHandle, v))
+}
+
+// PublicKeyEQ applies the EQ predicate on the "public_key" field.
+func PublicKeyEQ(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldPublicKey, v))
+}
+
+// PublicKeyNEQ applies the NEQ predicate on the "public_key" field.
+func PublicKeyNEQ(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNEQ(FieldPublicKey, v))
+}
+
+// PublicKeyIn applies the In predicate on the "public_key" field.
+func PublicKeyIn(vs ...[]byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldIn(FieldPublicKey, vs...))
+}
+
+// PublicKeyNotIn applies the NotIn predicate on the "public_key" field.
+func PublicKeyNotIn(vs ...[]byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNotIn(FieldPublicKey, vs...))
+}
+
+// PublicKeyGT applies the GT predicate on the "public_key" field.
+func PublicKeyGT(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGT(FieldPublicKey, v))
+}
+
+// PublicKeyGTE applies the GTE predicate on the "public_key" field.
+func PublicKeyGTE(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGTE(FieldPublicKey, v))
+}
+
+// PublicKeyLT applies the LT predicate on the "public_key" field.
+func PublicKeyLT(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLT(FieldPublicKey, v))
+}
+
+// PublicKeyLTE applies the LTE predicate on the "public_key" field.
+func PublicKeyLTE(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLTE(FieldPublicKey, v))
+}
+
+// AttestationFormatEQ applies the EQ predicate on the "attestation_format" field.
+func AttestationFormatEQ(v string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldAttestationFormat, v))
+}
+
+// AttestationFormatNEQ applies the NEQ predicate on the "attestation_format" field.
+func AttestationFormatNEQ(v string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNEQ(FieldAttestationFormat, v))
+}
+
+// AttestationFormatIn applies the In predicate on the "attestation_format" field.
+func AttestationFormatIn(vs ...string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldIn(FieldAttestationFormat, vs...))
+}
+
+// AttestationFormatNotIn applies the NotIn predicate on the "attestation_format" field.
+func AttestationFormatNotIn(vs ...string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNotIn(FieldAttestationFormat, vs...))
+}
+
+// AttestationFormatGT applies the GT predicate on the "attestation_format" field.
+func AttestationFormatGT(v string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGT(FieldAttestationFormat, v))
+}
+
+// AttestationFormatGTE applies the GTE predicate on the "attestation_format" field.
+func AttestationFormatGTE(v string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGTE(FieldAttestationFormat, v))
+}
+
+// AttestationFormatLT applies the LT predicate on the "attestation_format" field.
+func AttestationFormatLT(v string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLT(FieldAttestationFormat, v))
+}
+
+// AttestationFormatLTE applies the LTE predicate on the "attestation_format" field.
+func AttestationFormatLTE(v string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLTE(FieldAttestationFormat, v))
+}
+
+// AttestationFormatContains applies the Contains predicate on the "attestation_format" field.
+func AttestationFormatContains(v string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldContains(FieldAttestationFormat, v))
+}
+
+// AttestationFormatHasPrefix applies the HasPrefix predicate on the "attestation_format" field.
+func AttestationFormatHasPrefix(v string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldHasPrefix(FieldAttestationFormat, v))
+}
+
+// AttestationFormatHasSuffix applies the HasSuffix predicate on the "attestation_format" field.
+func AttestationFormatHasSuffix(v string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldHasSuffix(FieldAttestationFormat, v))
+}
+
+// AttestationFormatEqualFold applies the EqualFold predicate on the "attestation_format" field.
+func AttestationFormatEqualFold(v string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEqualFold(FieldAttestationFormat, v))
+}
+
+// AttestationFormatContainsFold applies the ContainsFold predicate on the "attestation_format" field.
+func AttestationFormatContainsFold(v string) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldContainsFold(FieldAttestationFormat, v))
+}
+
+// AaguidEQ applies the EQ predicate on the "aaguid" field.
+func AaguidEQ(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldAaguid, v))
+}
+
+// AaguidNEQ applies the NEQ predicate on the "aaguid" field.
+func AaguidNEQ(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNEQ(FieldAaguid, v))
+}
+
+// AaguidIn applies the In predicate on the "aaguid" field.
+func AaguidIn(vs ...[]byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldIn(FieldAaguid, vs...))
+}
+
+// AaguidNotIn applies the NotIn predicate on the "aaguid" field.
+func AaguidNotIn(vs ...[]byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNotIn(FieldAaguid, vs...))
+}
+
+// AaguidGT applies the GT predicate on the "aaguid" field.
+func AaguidGT(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGT(FieldAaguid, v))
+}
+
+// AaguidGTE applies the GTE predicate on the "aaguid" field.
+func AaguidGTE(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGTE(FieldAaguid, v))
+}
+
+// AaguidLT applies the LT predicate on the "aaguid" field.
+func AaguidLT(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLT(FieldAaguid, v))
+}
+
+// AaguidLTE applies the LTE predicate on the "aaguid" field.
+func AaguidLTE(v []byte) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLTE(FieldAaguid, v))
+}
+
+// AaguidIsNil applies the IsNil predicate on the "aaguid" field.
+func AaguidIsNil() predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldIsNull(FieldAaguid))
+}
+
+// AaguidNotNil applies the NotNil predicate on the "aaguid" field.
+func AaguidNotNil() predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNotNull(FieldAaguid))
+}
+
+// SignCountEQ applies the EQ predicate on the "sign_count" field.
+func SignCountEQ(v uint32) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldSignCount, v))
+}
+
+// SignCountNEQ applies the NEQ predicate on the "sign_count" field.
+func SignCountNEQ(v uint32) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNEQ(FieldSignCount, v))
+}
+
+// SignCountIn applies the In predicate on the "sign_count" field.
+func SignCountIn(vs ...uint32) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldIn(FieldSignCount, vs...))
+}
+
+// SignCountNotIn applies the NotIn predicate on the "sign_count" field.
+func SignCountNotIn(vs ...uint32) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNotIn(FieldSignCount, vs...))
+}
+
+// SignCountGT applies the GT predicate on the "sign_count" field.
+func SignCountGT(v uint32) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGT(FieldSignCount, v))
+}
+
+// SignCountGTE applies the GTE predicate on the "sign_count" field.
+func SignCountGTE(v uint32) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGTE(FieldSignCount, v))
+}
+
+// SignCountLT applies the LT predicate on the "sign_count" field.
+func SignCountLT(v uint32) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLT(FieldSignCount, v))
+}
+
+// SignCountLTE applies the LTE predicate on the "sign_count" field.
+func SignCountLTE(v uint32) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLTE(FieldSignCount, v))
+}
+
+// TransportsIsNil applies the IsNil predicate on the "transports" field.
+func TransportsIsNil() predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldIsNull(FieldTransports))
+}
+
+// TransportsNotNil applies the NotNil predicate on the "transports" field.
+func TransportsNotNil() predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNotNull(FieldTransports))
+}
+
+// BackupEligibleEQ applies the EQ predicate on the "backup_eligible" field.
+func BackupEligibleEQ(v bool) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldBackupEligible, v))
+}
+
+// BackupEligibleNEQ applies the NEQ predicate on the "backup_eligible" field.
+func BackupEligibleNEQ(v bool) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNEQ(FieldBackupEligible, v))
+}
+
+// BackupStateEQ applies the EQ predicate on the "backup_state" field.
+func BackupStateEQ(v bool) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldBackupState, v))
+}
+
+// BackupStateNEQ applies the NEQ predicate on the "backup_state" field.
+func BackupStateNEQ(v bool) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNEQ(FieldBackupState, v))
+}
+
+// CreatedAtEQ applies the EQ predicate on the "created_at" field.
+func CreatedAtEQ(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldCreatedAt, v))
+}
+
+// CreatedAtNEQ applies the NEQ predicate on the "created_at" field.
+func CreatedAtNEQ(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNEQ(FieldCreatedAt, v))
+}
+
+// CreatedAtIn applies the In predicate on the "created_at" field.
+func CreatedAtIn(vs ...time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldIn(FieldCreatedAt, vs...))
+}
+
+// CreatedAtNotIn applies the NotIn predicate on the "created_at" field.
+func CreatedAtNotIn(vs ...time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNotIn(FieldCreatedAt, vs...))
+}
+
+// CreatedAtGT applies the GT predicate on the "created_at" field.
+func CreatedAtGT(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGT(FieldCreatedAt, v))
+}
+
+// CreatedAtGTE applies the GTE predicate on the "created_at" field.
+func CreatedAtGTE(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGTE(FieldCreatedAt, v))
+}
+
+// CreatedAtLT applies the LT predicate on the "created_at" field.
+func CreatedAtLT(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLT(FieldCreatedAt, v))
+}
+
+// CreatedAtLTE applies the LTE predicate on the "created_at" field.
+func CreatedAtLTE(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLTE(FieldCreatedAt, v))
+}
+
+// LastUsedAtEQ applies the EQ predicate on the "last_used_at" field.
+func LastUsedAtEQ(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldEQ(FieldLastUsedAt, v))
+}
+
+// LastUsedAtNEQ applies the NEQ predicate on the "last_used_at" field.
+func LastUsedAtNEQ(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNEQ(FieldLastUsedAt, v))
+}
+
+// LastUsedAtIn applies the In predicate on the "last_used_at" field.
+func LastUsedAtIn(vs ...time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldIn(FieldLastUsedAt, vs...))
+}
+
+// LastUsedAtNotIn applies the NotIn predicate on the "last_used_at" field.
+func LastUsedAtNotIn(vs ...time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNotIn(FieldLastUsedAt, vs...))
+}
+
+// LastUsedAtGT applies the GT predicate on the "last_used_at" field.
+func LastUsedAtGT(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGT(FieldLastUsedAt, v))
+}
+
+// LastUsedAtGTE applies the GTE predicate on the "last_used_at" field.
+func LastUsedAtGTE(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldGTE(FieldLastUsedAt, v))
+}
+
+// LastUsedAtLT applies the LT predicate on the "last_used_at" field.
+func LastUsedAtLT(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLT(FieldLastUsedAt, v))
+}
+
+// LastUsedAtLTE applies the LTE predicate on the "last_used_at" field.
+func LastUsedAtLTE(v time.Time) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldLTE(FieldLastUsedAt, v))
+}
+
+// LastUsedAtIsNil applies the IsNil predicate on the "last_used_at" field.
+func LastUsedAtIsNil() predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldIsNull(FieldLastUsedAt))
+}
+
+// LastUsedAtNotNil applies the NotNil predicate on the "last_used_at" field.
+func LastUsedAtNotNil() predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.FieldNotNull(FieldLastUsedAt))
+}
+
+// And groups predicates with the AND operator between them.
+func And(predicates ...predicate.WebAuthnCredential) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.AndPredicates(predicates...))
+}
+
+// Or groups predicates with the OR operator between them.
+func Or(predicates ...predicate.WebAuthnCredential) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.OrPredicates(predicates...))
+}
+
+// Not applies the not operator on the given predicate.
+func Not(p predicate.WebAuthnCredential) predicate.WebAuthnCredential {
+	return predicate.WebAuthnCredential(sql.NotPredicates(p))
+}
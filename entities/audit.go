@@ -0,0 +1,135 @@
+package entities
+
+import (
+	"context"
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+	"entgo.io/ent/schema/mixin"
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/auth"
+)
+
+// Audit column names shared by AuditMixin, SoftDeleteMixin and AuditedEntitySchema.
+const (
+	FieldCreatedAt   = "created_at"
+	FieldCreatedByID = "created_by_id"
+	FieldUpdatedAt   = "updated_at"
+	FieldUpdatedByID = "updated_by_id"
+	FieldDeletedAt   = "deleted_at"
+	FieldDeletedByID = "deleted_by_id"
+)
+
+// AuditMixin adds created_at/updated_at/created_by_id/updated_by_id and a hook
+// that fills the actor fields from the request context.
+// Use it for schemas that do not embed AuditedEntitySchema.
+type AuditMixin struct {
+	mixin.Schema
+}
+
+func (AuditMixin) Fields() []ent.Field {
+	return []ent.Field{
+		field.UUID(FieldCreatedByID, uuid.UUID{}).
+			SchemaType(uuidSchemaType).
+			Immutable().
+			Optional().
+			Comment("创建者ID").
+			StructTag(`json:"createdById,omitempty"`),
+		field.Time(FieldCreatedAt).
+			Default(time.Now).
+			Immutable().
+			Optional().
+			Comment("创建时间").
+			StructTag(`json:"createdAt,omitempty"`),
+		field.UUID(FieldUpdatedByID, uuid.UUID{}).
+			SchemaType(uuidSchemaType).
+			Optional().
+			Comment("更新者ID").
+			StructTag(`json:"updatedById,omitempty"`),
+		field.Time(FieldUpdatedAt).
+			Default(time.Now).
+			UpdateDefault(time.Now).
+			Optional().
+			Comment("更新时间").
+			StructTag(`json:"updatedAt,omitempty"`),
+	}
+}
+
+func (AuditMixin) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields(FieldCreatedAt),
+		index.Fields(FieldUpdatedAt),
+	}
+}
+
+// Hooks returns AuditActorHook.
+func (AuditMixin) Hooks() []ent.Hook {
+	return []ent.Hook{AuditActorHook}
+}
+
+// AuditActorHook sets created_by_id and updated_by_id on create and
+// updated_by_id on update from ActorFromContext. Values set explicitly on the
+// mutation are kept, and nothing is written when there is no actor.
+// It only needs the columns, so schemas embedding AuditedEntitySchema can add it via Hooks().
+func AuditActorHook(next ent.Mutator) ent.Mutator {
+	return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+		actor, ok := ActorFromContext(ctx)
+		if !ok {
+			return next.Mutate(ctx, m)
+		}
+		switch op := m.Op(); {
+		case op.Is(ent.OpCreate):
+			if err := setActor(m, FieldCreatedByID, actor); err != nil {
+				return nil, err
+			}
+			if err := setActor(m, FieldUpdatedByID, actor); err != nil {
+				return nil, err
+			}
+		case op.Is(ent.OpUpdate | ent.OpUpdateOne):
+			if err := setActor(m, FieldUpdatedByID, actor); err != nil {
+				return nil, err
+			}
+		}
+		return next.Mutate(ctx, m)
+	})
+}
+
+type actorKey struct{}
+
+// WithActor stores the acting user for audit fields, taking precedence over the auth context.
+// Background jobs and system tasks use it to attribute their writes.
+func WithActor(ctx context.Context, actor uuid.UUID) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the acting user: the WithActor value if set,
+// otherwise the user ID placed by the auth middleware when it is a valid UUID.
+func ActorFromContext(ctx context.Context) (uuid.UUID, bool) {
+	if actor, ok := ctx.Value(actorKey{}).(uuid.UUID); ok && actor != uuid.Nil {
+		return actor, true
+	}
+	userID, _, _ := auth.GetUserInfoFromContext(ctx)
+	actor, err := uuid.Parse(userID)
+	if err != nil || actor == uuid.Nil {
+		return uuid.Nil, false
+	}
+	return actor, true
+}
+
+// setActor sets an actor field unless it is already set on the mutation.
+func setActor(m ent.Mutation, name string, actor uuid.UUID) error {
+	if _, set := m.Field(name); set {
+		return nil
+	}
+	return m.SetField(name, actor)
+}
+
+// uuidSchemaType is the column type of UUID fields per dialect.
+var uuidSchemaType = map[string]string{
+	dialect.Postgres: "uuid",
+	dialect.MySQL:    "char(36)",
+	dialect.SQLite:   "text",
+}
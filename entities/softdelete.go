@@ -0,0 +1,195 @@
+package entities
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+	"entgo.io/ent/schema/mixin"
+	"github.com/google/uuid"
+)
+
+// SoftDeleteMixin adds deleted_at/deleted_by_id and turns deletes into updates
+// that stamp them. Queries and updates skip soft-deleted rows unless the
+// context says otherwise:
+//
+//	client.Article.DeleteOneID(id).Exec(ctx)                               // soft delete
+//	client.Article.Query().All(entities.IncludeDeleted(ctx))               // include deleted rows
+//	client.Article.UpdateOneID(id).Exec(entities.Restore(ctx))             // undo a soft delete
+//	client.Article.Delete().Where(article.DeletedAtLT(t)).Exec(entities.Purge(ctx)) // hard delete
+type SoftDeleteMixin struct {
+	mixin.Schema
+}
+
+func (SoftDeleteMixin) Fields() []ent.Field {
+	return []ent.Field{
+		field.UUID(FieldDeletedByID, uuid.UUID{}).
+			SchemaType(uuidSchemaType).
+			Optional().
+			Comment("删除者ID").
+			StructTag(`json:"deletedById,omitempty"`),
+		field.Time(FieldDeletedAt).
+			Optional().
+			Comment("删除时间").
+			StructTag(`json:"deletedAt,omitempty"`),
+	}
+}
+
+func (SoftDeleteMixin) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields(FieldDeletedAt),
+	}
+}
+
+// Interceptors returns SoftDeleteInterceptor.
+func (SoftDeleteMixin) Interceptors() []ent.Interceptor {
+	return []ent.Interceptor{SoftDeleteInterceptor()}
+}
+
+// Hooks returns SoftDeleteHook.
+func (SoftDeleteMixin) Hooks() []ent.Hook {
+	return []ent.Hook{SoftDeleteHook}
+}
+
+type softDeleteModeKey struct{}
+
+type softDeleteMode int
+
+const (
+	modeIncludeDeleted softDeleteMode = iota + 1
+	modeRestore
+	modePurge
+)
+
+// IncludeDeleted returns a context whose queries and updates also see soft-deleted rows.
+func IncludeDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, softDeleteModeKey{}, modeIncludeDeleted)
+}
+
+// Restore returns a context whose updates clear deleted_at/deleted_by_id,
+// bringing soft-deleted rows back.
+func Restore(ctx context.Context) context.Context {
+	return context.WithValue(ctx, softDeleteModeKey{}, modeRestore)
+}
+
+// Purge returns a context whose deletes remove rows physically, soft-deleted or not.
+func Purge(ctx context.Context) context.Context {
+	return context.WithValue(ctx, softDeleteModeKey{}, modePurge)
+}
+
+func softDeleteModeFrom(ctx context.Context) softDeleteMode {
+	mode, _ := ctx.Value(softDeleteModeKey{}).(softDeleteMode)
+	return mode
+}
+
+// SoftDeleteInterceptor filters out rows whose deleted_at is set.
+// It works with generated queries with or without the intercept feature.
+func SoftDeleteInterceptor() ent.Interceptor {
+	return ent.TraverseFunc(func(ctx context.Context, q ent.Query) error {
+		if softDeleteModeFrom(ctx) != 0 {
+			return nil
+		}
+		return whereNotDeleted(q)
+	})
+}
+
+// SoftDeleteHook converts deletes into updates setting deleted_at and
+// deleted_by_id, and keeps updates from touching soft-deleted rows.
+// Under Restore it clears both fields instead; under Purge deletes are physical.
+func SoftDeleteHook(next ent.Mutator) ent.Mutator {
+	return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+		mode := softDeleteModeFrom(ctx)
+		switch op := m.Op(); {
+		case op.Is(ent.OpDelete | ent.OpDeleteOne):
+			if mode == modePurge {
+				return next.Mutate(ctx, m)
+			}
+			return softDelete(ctx, m)
+		case op.Is(ent.OpUpdate | ent.OpUpdateOne):
+			switch mode {
+			case modeRestore:
+				if err := m.ClearField(FieldDeletedAt); err != nil {
+					return nil, err
+				}
+				if err := m.ClearField(FieldDeletedByID); err != nil {
+					return nil, err
+				}
+			case modeIncludeDeleted, modePurge:
+			default:
+				if err := whereNotDeleted(m); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return next.Mutate(ctx, m)
+	})
+}
+
+// softDelete re-issues a delete mutation as an update through its client,
+// so the returned affected-row count keeps the delete builder's semantics.
+func softDelete(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+	mx, ok := m.(interface{ SetOp(ent.Op) })
+	if !ok {
+		return nil, fmt.Errorf("entities: soft delete: unexpected mutation %T", m)
+	}
+	if err := whereNotDeleted(m); err != nil {
+		return nil, err
+	}
+	mx.SetOp(ent.OpUpdate)
+	if err := m.SetField(FieldDeletedAt, time.Now()); err != nil {
+		return nil, err
+	}
+	if actor, ok := ActorFromContext(ctx); ok {
+		if err := m.SetField(FieldDeletedByID, actor); err != nil {
+			return nil, err
+		}
+	}
+
+	// generated mutations expose Client() returning the typed client, whose
+	// Mutate dispatches by mutation type and operation
+	clientFn := reflect.ValueOf(m).MethodByName("Client")
+	if !clientFn.IsValid() {
+		return nil, fmt.Errorf("entities: soft delete: mutation %T has no Client method", m)
+	}
+	client, ok := clientFn.Call(nil)[0].Interface().(interface {
+		Mutate(context.Context, ent.Mutation) (ent.Value, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("entities: soft delete: client of %T cannot mutate", m)
+	}
+	return client.Mutate(ctx, m)
+}
+
+// whereNotDeleted appends "deleted_at IS NULL" to a generated query or mutation.
+func whereNotDeleted(v any) error {
+	return whereP(v, func(s *sql.Selector) {
+		s.Where(sql.IsNull(s.C(FieldDeletedAt)))
+	})
+}
+
+// whereP appends a storage-level predicate to a generated query or mutation.
+func whereP(v any, pred func(*sql.Selector)) error {
+	if w, ok := v.(interface{ WhereP(...func(*sql.Selector)) }); ok {
+		w.WhereP(pred)
+		return nil
+	}
+
+	// queries generated without the intercept feature only have
+	// Where(...predicate.T), where predicate.T is a named func(*sql.Selector)
+	where := reflect.ValueOf(v).MethodByName("Where")
+	if !where.IsValid() || !where.Type().IsVariadic() || where.Type().NumIn() != 1 {
+		return fmt.Errorf("entities: %T has no Where method", v)
+	}
+	predType := where.Type().In(0).Elem()
+	pv := reflect.ValueOf(pred)
+	if !pv.Type().ConvertibleTo(predType) {
+		return fmt.Errorf("entities: %T is not a SQL query", v)
+	}
+	where.Call([]reflect.Value{pv.Convert(predType)})
+	return nil
+}
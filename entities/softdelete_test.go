@@ -0,0 +1,126 @@
+package entities_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/ent"
+	"github.com/leeforge/framework/ent/media"
+	"github.com/leeforge/framework/entities"
+
+	_ "modernc.org/sqlite"
+)
+
+// newMediaClient opens an in-memory client whose Media entity uses the audit
+// and soft delete hooks, as a schema embedding the mixins would after codegen.
+func newMediaClient(t *testing.T) *ent.Client {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:entities-"+uuid.NewString()+"?mode=memory&cache=shared&_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ent.NewClient(ent.Driver(entsql.OpenDB(dialect.SQLite, db)))
+	t.Cleanup(func() { client.Close() })
+	if err := client.Schema.Create(context.Background()); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	client.Media.Use(entities.AuditMixin{}.Hooks()...)
+	client.Media.Use(entities.SoftDeleteMixin{}.Hooks()...)
+	client.Media.Intercept(entities.SoftDeleteMixin{}.Interceptors()...)
+	return client
+}
+
+func createMedia(t *testing.T, ctx context.Context, client *ent.Client, name string) *ent.Media {
+	t.Helper()
+	m, err := client.Media.Create().SetName(name).SetURL("/uploads/" + name).Save(ctx)
+	if err != nil {
+		t.Fatalf("create %s: %v", name, err)
+	}
+	return m
+}
+
+func TestAuditActorFromContext(t *testing.T) {
+	client := newMediaClient(t)
+	creator, editor := uuid.New(), uuid.New()
+
+	m := createMedia(t, entities.WithActor(context.Background(), creator), client, "a.png")
+	if m.CreatedByID != creator || m.UpdatedByID != creator {
+		t.Errorf("created_by = %s, updated_by = %s, want %s", m.CreatedByID, m.UpdatedByID, creator)
+	}
+
+	// the auth middleware stores the user ID under "user_id"
+	authCtx := context.WithValue(context.Background(), "user_id", editor.String())
+	m, err := client.Media.UpdateOne(m).SetCaption("edited").Save(authCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.CreatedByID != creator || m.UpdatedByID != editor {
+		t.Errorf("after update created_by = %s, updated_by = %s, want %s and %s", m.CreatedByID, m.UpdatedByID, creator, editor)
+	}
+
+	explicit := uuid.New()
+	m = createMedia(t, context.Background(), client, "b.png")
+	if m.CreatedByID != uuid.Nil {
+		t.Errorf("created_by without actor = %s, want empty", m.CreatedByID)
+	}
+	m, err = client.Media.Create().SetName("c.png").SetURL("/c.png").SetCreatedByID(explicit).
+		Save(entities.WithActor(context.Background(), creator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.CreatedByID != explicit {
+		t.Errorf("explicit created_by overwritten with %s", m.CreatedByID)
+	}
+}
+
+func TestSoftDeleteRestoreAndPurge(t *testing.T) {
+	client := newMediaClient(t)
+	actor := uuid.New()
+	ctx := entities.WithActor(context.Background(), actor)
+
+	kept := createMedia(t, ctx, client, "kept.png")
+	gone := createMedia(t, ctx, client, "gone.png")
+
+	if err := client.Media.DeleteOne(gone).Exec(ctx); err != nil {
+		t.Fatalf("soft delete: %v", err)
+	}
+	if n := client.Media.Query().CountX(ctx); n != 1 {
+		t.Fatalf("visible rows = %d, want 1", n)
+	}
+	if _, err := client.Media.Get(ctx, gone.ID); !ent.IsNotFound(err) {
+		t.Errorf("Get soft-deleted row: err = %v, want not found", err)
+	}
+	deleted := client.Media.GetX(entities.IncludeDeleted(ctx), gone.ID)
+	if deleted.DeletedAt.IsZero() || deleted.DeletedByID != actor {
+		t.Errorf("deleted_at = %v, deleted_by = %s, want stamped by %s", deleted.DeletedAt, deleted.DeletedByID, actor)
+	}
+
+	// deleting again finds nothing, and updates skip soft-deleted rows
+	if err := client.Media.DeleteOne(gone).Exec(ctx); !ent.IsNotFound(err) {
+		t.Errorf("second delete: err = %v, want not found", err)
+	}
+	if n := client.Media.Update().SetCaption("bulk").SaveX(ctx); n != 1 {
+		t.Errorf("bulk update touched %d rows, want 1", n)
+	}
+
+	if err := client.Media.UpdateOneID(gone.ID).Exec(entities.Restore(ctx)); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	restored := client.Media.GetX(ctx, gone.ID)
+	if !restored.DeletedAt.IsZero() || restored.DeletedByID != uuid.Nil {
+		t.Errorf("restored row still marked deleted: %v %s", restored.DeletedAt, restored.DeletedByID)
+	}
+
+	client.Media.DeleteOne(kept).ExecX(ctx)
+	n, err := client.Media.Delete().Where(media.NameEQ("kept.png")).Exec(entities.Purge(ctx))
+	if err != nil || n != 1 {
+		t.Fatalf("purge: n = %d, err = %v", n, err)
+	}
+	if n := client.Media.Query().CountX(entities.IncludeDeleted(ctx)); n != 1 {
+		t.Errorf("rows after purge = %d, want 1", n)
+	}
+}
@@ -0,0 +1,238 @@
+package entities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+	"entgo.io/ent/schema/mixin"
+	"github.com/leeforge/framework/auth"
+)
+
+// FieldTenantID is the tenant column used by TenantScopeMixin and TenantEntitySchema.
+const FieldTenantID = "tenant_id"
+
+var (
+	// ErrMissingDataFilter is returned when a scoped query or mutation runs
+	// without a value for one of its filter columns and outside AsSystem.
+	ErrMissingDataFilter = errors.New("entities: missing data filter")
+	// ErrDataFilterViolation is returned when a mutation writes a filter
+	// column with a value the context does not allow.
+	ErrDataFilterViolation = errors.New("entities: data filter violation")
+)
+
+// TenantScopeMixin adds a tenant_id column and enforces row isolation on it:
+// every query and update/delete is restricted to the tenant in the context,
+// and creates get the tenant set from the context. A missing tenant is an
+// error rather than an unfiltered query.
+type TenantScopeMixin struct {
+	mixin.Schema
+}
+
+func (TenantScopeMixin) Fields() []ent.Field {
+	return []ent.Field{
+		field.String(FieldTenantID).
+			NotEmpty().
+			Immutable().
+			Comment("租户ID").
+			StructTag(`json:"tenantId"`),
+	}
+}
+
+func (TenantScopeMixin) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields(FieldTenantID),
+	}
+}
+
+// Interceptors returns DataFilterInterceptor for tenant_id.
+func (TenantScopeMixin) Interceptors() []ent.Interceptor {
+	return []ent.Interceptor{DataFilterInterceptor(FieldTenantID)}
+}
+
+// Hooks returns DataFilterHook for tenant_id.
+func (TenantScopeMixin) Hooks() []ent.Hook {
+	return []ent.Hook{DataFilterHook(FieldTenantID)}
+}
+
+type dataFiltersKey struct{}
+
+type systemKey struct{}
+
+// WithTenant restricts scoped queries and mutations in ctx to tenantID.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return WithDataFilters(ctx, map[string]any{FieldTenantID: tenantID})
+}
+
+// WithDataFilters adds column filters to ctx, overriding earlier values for
+// the same columns. A slice value allows any of its elements.
+func WithDataFilters(ctx context.Context, filters map[string]any) context.Context {
+	merged := map[string]any{}
+	if prev, ok := ctx.Value(dataFiltersKey{}).(map[string]any); ok {
+		maps.Copy(merged, prev)
+	}
+	maps.Copy(merged, filters)
+	return context.WithValue(ctx, dataFiltersKey{}, merged)
+}
+
+// DataFiltersFromContext returns the filters placed by auth.DataFilterMiddleware
+// (from the API key), overlaid with those added by WithDataFilters and WithTenant.
+func DataFiltersFromContext(ctx context.Context) map[string]any {
+	filters := map[string]any{}
+	_, _, authFilters := auth.GetUserInfoFromContext(ctx)
+	maps.Copy(filters, authFilters)
+	if own, ok := ctx.Value(dataFiltersKey{}).(map[string]any); ok {
+		maps.Copy(filters, own)
+	}
+	return filters
+}
+
+// AsSystem returns a context that bypasses data filters, for migrations,
+// background jobs and cross-tenant administration. Use it deliberately.
+func AsSystem(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemKey{}, true)
+}
+
+// IsSystem reports whether ctx was created by AsSystem.
+func IsSystem(ctx context.Context) bool {
+	system, _ := ctx.Value(systemKey{}).(bool)
+	return system
+}
+
+// ScopeKey identifies the rows queries in ctx can see: the data filters,
+// AsSystem and the soft-delete mode. It is empty for an unscoped context.
+// Caches of query results key on it, so one tenant never reads another's
+// cached rows.
+func ScopeKey(ctx context.Context) string {
+	var parts []string
+	filters := DataFiltersFromContext(ctx)
+	for _, col := range slices.Sorted(maps.Keys(filters)) {
+		parts = append(parts, fmt.Sprintf("%s=%#v", col, filters[col]))
+	}
+	if IsSystem(ctx) {
+		parts = append(parts, "system")
+	}
+	if mode := softDeleteModeFrom(ctx); mode != 0 {
+		parts = append(parts, fmt.Sprintf("deleted=%d", mode))
+	}
+	return strings.Join(parts, ";")
+}
+
+// DataFilterInterceptor restricts queries to rows whose columns match the
+// context filters. It fails the query with ErrMissingDataFilter when a column
+// has no filter, so a forgotten tenant never turns into a cross-tenant read.
+func DataFilterInterceptor(columns ...string) ent.Interceptor {
+	return ent.TraverseFunc(func(ctx context.Context, q ent.Query) error {
+		if IsSystem(ctx) {
+			return nil
+		}
+		allowed, err := allowedValues(ctx, columns)
+		if err != nil {
+			return err
+		}
+		return whereP(q, filterPredicate(allowed))
+	})
+}
+
+// DataFilterHook enforces the context filters on writes: creates get single
+// valued filter columns set (or checked when several values are allowed),
+// updates may not move rows out of the filter, and updates and deletes only
+// match rows inside it.
+func DataFilterHook(columns ...string) ent.Hook {
+	return func(next ent.Mutator) ent.Mutator {
+		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			if IsSystem(ctx) {
+				return next.Mutate(ctx, m)
+			}
+			allowed, err := allowedValues(ctx, columns)
+			if err != nil {
+				return nil, err
+			}
+			switch op := m.Op(); {
+			case op.Is(ent.OpCreate):
+				for _, col := range columns {
+					values := allowed[col]
+					if v, set := m.Field(col); set && containsValue(values, v) {
+						continue
+					}
+					// defaults are applied before hooks, so a set value is
+					// replaced when the filter names exactly one value
+					if len(values) != 1 {
+						return nil, fmt.Errorf("%w: %s must be one of %v", ErrDataFilterViolation, col, values)
+					}
+					if err := m.SetField(col, values[0]); err != nil {
+						return nil, err
+					}
+				}
+			case op.Is(ent.OpUpdate | ent.OpUpdateOne | ent.OpDelete | ent.OpDeleteOne):
+				for _, col := range columns {
+					if v, set := m.Field(col); set && !containsValue(allowed[col], v) {
+						return nil, fmt.Errorf("%w: %s = %v", ErrDataFilterViolation, col, v)
+					}
+				}
+				if err := whereP(m, filterPredicate(allowed)); err != nil {
+					return nil, err
+				}
+			}
+			return next.Mutate(ctx, m)
+		})
+	}
+}
+
+// allowedValues resolves the allowed values of each column from ctx.
+func allowedValues(ctx context.Context, columns []string) (map[string][]any, error) {
+	filters := DataFiltersFromContext(ctx)
+	allowed := make(map[string][]any, len(columns))
+	for _, col := range columns {
+		v, ok := filters[col]
+		if !ok || v == nil || v == "" {
+			return nil, fmt.Errorf("%w: %s", ErrMissingDataFilter, col)
+		}
+		allowed[col] = filterValues(v)
+	}
+	return allowed, nil
+}
+
+func filterPredicate(allowed map[string][]any) func(*sql.Selector) {
+	return func(s *sql.Selector) {
+		for _, col := range slices.Sorted(maps.Keys(allowed)) {
+			values := allowed[col]
+			if len(values) == 1 {
+				s.Where(sql.EQ(s.C(col), values[0]))
+			} else {
+				s.Where(sql.In(s.C(col), values...))
+			}
+		}
+	}
+}
+
+// filterValues flattens a filter value; slices allow any element, and an
+// empty slice allows nothing.
+func filterValues(v any) []any {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return []any{v}
+	}
+	values := make([]any, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values
+}
+
+func containsValue(values []any, v any) bool {
+	for _, allowed := range values {
+		if fmt.Sprint(allowed) == fmt.Sprint(v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package entities_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/leeforge/framework/ent"
+	"github.com/leeforge/framework/ent/media"
+	"github.com/leeforge/framework/entities"
+)
+
+// newTenantClient returns a client whose Media entity is tenant scoped, as a
+// schema embedding TenantScopeMixin would be after codegen.
+func newTenantClient(t *testing.T) *ent.Client {
+	t.Helper()
+	client := newMediaClient(t)
+	client.Media.Use(entities.TenantScopeMixin{}.Hooks()...)
+	client.Media.Intercept(entities.TenantScopeMixin{}.Interceptors()...)
+	return client
+}
+
+func TestTenantScopeRequiresTenant(t *testing.T) {
+	client := newTenantClient(t)
+	ctx := context.Background()
+
+	if _, err := client.Media.Query().All(ctx); !errors.Is(err, entities.ErrMissingDataFilter) {
+		t.Errorf("query without tenant: err = %v, want ErrMissingDataFilter", err)
+	}
+	if _, err := client.Media.Create().SetName("a.png").SetURL("/a.png").Save(ctx); !errors.Is(err, entities.ErrMissingDataFilter) {
+		t.Errorf("create without tenant: err = %v, want ErrMissingDataFilter", err)
+	}
+	if _, err := client.Media.Delete().Exec(ctx); !errors.Is(err, entities.ErrMissingDataFilter) {
+		t.Errorf("delete without tenant: err = %v, want ErrMissingDataFilter", err)
+	}
+}
+
+func TestTenantScopePreventsCrossTenantAccess(t *testing.T) {
+	client := newTenantClient(t)
+	ctxA := entities.WithTenant(context.Background(), "tenant-a")
+	ctxB := entities.WithTenant(context.Background(), "tenant-b")
+
+	// an explicit tenant is replaced by the one in the context
+	a, err := client.Media.Create().SetName("a.png").SetURL("/a.png").SetTenantID("tenant-b").Save(ctxA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.TenantID != "tenant-a" {
+		t.Fatalf("tenant_id = %q, want tenant-a", a.TenantID)
+	}
+	createMedia(t, ctxB, client, "b.png")
+
+	if n := client.Media.Query().CountX(ctxB); n != 1 {
+		t.Errorf("tenant-b sees %d rows, want 1", n)
+	}
+	if _, err := client.Media.Get(ctxB, a.ID); !ent.IsNotFound(err) {
+		t.Errorf("cross-tenant Get: err = %v, want not found", err)
+	}
+	if n := client.Media.Query().Where(media.NameEQ("a.png")).CountX(ctxB); n != 0 {
+		t.Errorf("cross-tenant filtered query returned %d rows", n)
+	}
+	if err := client.Media.UpdateOneID(a.ID).SetCaption("hijacked").Exec(ctxB); !ent.IsNotFound(err) {
+		t.Errorf("cross-tenant update: err = %v, want not found", err)
+	}
+	if n := client.Media.Update().SetCaption("bulk").SaveX(ctxB); n != 1 {
+		t.Errorf("bulk update touched %d rows, want 1", n)
+	}
+	if err := client.Media.DeleteOneID(a.ID).Exec(entities.Purge(ctxB)); !ent.IsNotFound(err) {
+		t.Errorf("cross-tenant delete: err = %v, want not found", err)
+	}
+	if err := client.Media.UpdateOneID(a.ID).SetTenantID("tenant-b").Exec(ctxA); !errors.Is(err, entities.ErrDataFilterViolation) {
+		t.Errorf("moving a row to another tenant: err = %v, want ErrDataFilterViolation", err)
+	}
+
+	if got := client.Media.GetX(ctxA, a.ID); got.Caption != nil {
+		t.Errorf("tenant-a row modified by tenant-b: caption = %q", *got.Caption)
+	}
+	if n := client.Media.Query().CountX(entities.AsSystem(context.Background())); n != 2 {
+		t.Errorf("system context sees %d rows, want 2", n)
+	}
+}
+
+func TestTenantScopeUsesAuthDataFilters(t *testing.T) {
+	client := newTenantClient(t)
+	createMedia(t, entities.WithTenant(context.Background(), "tenant-a"), client, "a.png")
+	createMedia(t, entities.WithTenant(context.Background(), "tenant-b"), client, "b.png")
+	createMedia(t, entities.WithTenant(context.Background(), "tenant-c"), client, "c.png")
+
+	// auth.DataFilterMiddleware stores the API key filters under "data_filters"
+	ctx := context.WithValue(context.Background(), "data_filters", map[string]interface{}{
+		"tenant_id": []string{"tenant-a", "tenant-b"},
+	})
+	if n := client.Media.Query().CountX(ctx); n != 2 {
+		t.Errorf("multi-tenant key sees %d rows, want 2", n)
+	}
+	if _, err := client.Media.Create().SetName("d.png").SetURL("/d.png").Save(ctx); !errors.Is(err, entities.ErrDataFilterViolation) {
+		t.Errorf("ambiguous create: err = %v, want ErrDataFilterViolation", err)
+	}
+	if _, err := client.Media.Create().SetName("d.png").SetURL("/d.png").SetTenantID("tenant-b").Save(ctx); err != nil {
+		t.Errorf("create with an allowed tenant: %v", err)
+	}
+
+	empty := context.WithValue(context.Background(), "data_filters", map[string]interface{}{
+		"tenant_id": []string{},
+	})
+	if n := client.Media.Query().CountX(empty); n != 0 {
+		t.Errorf("empty filter sees %d rows, want 0", n)
+	}
+}
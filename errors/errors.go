@@ -1,11 +1,13 @@
 package errors
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"runtime"
 	"strings"
+	"time"
 )
 
 // ErrorType represents the type of error
@@ -249,16 +251,23 @@ const (
 // ErrorRegistry manages error definitions
 type ErrorRegistry struct {
 	errors map[string]*AppError
+	// translations maps code (or error type) -> language tag -> message template
+	translations map[string]map[string]string
+	// sourceLang is the language registered messages are written in
+	sourceLang string
 }
 
 // NewErrorRegistry creates a new error registry
 func NewErrorRegistry() *ErrorRegistry {
 	return &ErrorRegistry{
-		errors: make(map[string]*AppError),
+		errors:       make(map[string]*AppError),
+		translations: make(map[string]map[string]string),
+		sourceLang:   "en",
 	}
 }
 
-// Register registers an error template
+// Register registers an error template. The template's Message may contain
+// {name} placeholders, filled from the error's Details by Create and Localize
 func (r *ErrorRegistry) Register(code string, err *AppError) {
 	r.errors[code] = err
 }
@@ -277,7 +286,6 @@ func (r *ErrorRegistry) Create(code string, details map[string]interface{}) *App
 		err := &AppError{
 			Type:       template.Type,
 			Code:       code,
-			Message:    template.Message,
 			Details:    make(map[string]interface{}),
 			HTTPStatus: template.HTTPStatus,
 		}
@@ -287,6 +295,7 @@ func (r *ErrorRegistry) Create(code string, details map[string]interface{}) *App
 		for k, v := range details {
 			err.Details[k] = v
 		}
+		err.Message = renderTemplate(template.Message, err.Details)
 		return err
 	}
 	return New(ErrorTypeUnknown, "Unknown error code").WithDetail("code", code)
@@ -298,12 +307,12 @@ func DefaultErrorRegistry() *ErrorRegistry {
 
 	// Validation errors
 	registry.Register(CodeValidationFailed, NewValidation("Validation failed"))
-	registry.Register(CodeRequiredField, NewRequired("field"))
-	registry.Register(CodeInvalidField, NewInvalid("field", "value", "reason"))
+	registry.Register(CodeRequiredField, NewRequired("field").WithMessage("{field} is required"))
+	registry.Register(CodeInvalidField, NewInvalid("field", "value", "reason").WithMessage("invalid value for {field}: {value}"))
 
 	// Database errors
-	registry.Register(CodeNotFound, NewNotFound("resource", "id"))
-	registry.Register(CodeConflict, NewConflict("resource", "id"))
+	registry.Register(CodeNotFound, NewNotFound("resource", "id").WithMessage("{resource} not found"))
+	registry.Register(CodeConflict, NewConflict("resource", "id").WithMessage("{resource} already exists"))
 
 	// Authorization errors
 	registry.Register(CodeUnauthorized, NewUnauthorized("Authentication required"))
@@ -314,6 +323,8 @@ func DefaultErrorRegistry() *ErrorRegistry {
 	registry.Register(CodeInternalError, NewInternal("Internal server error"))
 	registry.Register(CodeServiceUnavailable, NewExternal("Service unavailable"))
 
+	registerDefaultTranslations(registry)
+
 	return registry
 }
 
@@ -352,6 +363,11 @@ func (h *ErrorHandler) HandleFunc(errType ErrorType, fn func(*AppError) *AppErro
 	h.handlers[errType] = fn
 }
 
+// Registry returns the registry the handler was created with
+func (h *ErrorHandler) Registry() *ErrorRegistry {
+	return h.registry
+}
+
 // Wrap wraps an error with context
 func (h *ErrorHandler) Wrap(err error, message string) *AppError {
 	return h.Handle(Wrap(err, message))
@@ -359,7 +375,8 @@ func (h *ErrorHandler) Wrap(err error, message string) *AppError {
 
 // ErrorConverter converts errors to HTTP responses
 type ErrorConverter struct {
-	errorHandler *ErrorHandler
+	errorHandler    *ErrorHandler
+	problemTypeBase string
 }
 
 // NewErrorConverter creates a new error converter
@@ -369,10 +386,27 @@ func NewErrorConverter(errorHandler *ErrorHandler) *ErrorConverter {
 	}
 }
 
-// ToHTTPResponse converts an error to an HTTP response
+// ToHTTPResponse converts an error to an HTTP response. Aggregate errors
+// (ErrorChain, errors.Join) list every member in Error.Errors
 func (c *ErrorConverter) ToHTTPResponse(err error) HTTPErrorResponse {
-	appErr := c.errorHandler.Handle(err)
+	return c.toHTTPResponse(err, "")
+}
+
+func (c *ErrorConverter) toHTTPResponse(err error, lang string) HTTPErrorResponse {
+	if summary, members, _, ok := c.aggregate(err, lang); ok {
+		response := c.httpResponse(summary)
+		if len(members) > 0 {
+			response.Error.Errors = fieldErrors(members)
+		}
+		return response
+	}
+	if lang == "" {
+		return c.httpResponse(c.errorHandler.Handle(err))
+	}
+	return c.httpResponse(c.Localize(err, lang))
+}
 
+func (c *ErrorConverter) httpResponse(appErr *AppError) HTTPErrorResponse {
 	response := HTTPErrorResponse{
 		Error: ErrorResponse{
 			Type:    string(appErr.Type),
@@ -406,6 +440,7 @@ type ErrorResponse struct {
 	Code    string                 `json:"code"`
 	Message string                 `json:"message"`
 	Details map[string]interface{} `json:"details,omitempty"`
+	Errors  []FieldError           `json:"errors,omitempty"`
 }
 
 // ErrorFormatter formats errors for display
@@ -542,6 +577,9 @@ func ErrorRecoverWithHandler(handler func(*AppError)) {
 }
 
 // ErrorRetryer retries operations that may fail
+//
+// Deprecated: use the retry package, which adds jitter, elapsed-time limits
+// and attempt hooks
 type ErrorRetryer struct {
 	maxAttempts int
 	retryDelay  func(attempt int) int64
@@ -566,7 +604,7 @@ func NewErrorRetryer(maxAttempts int) *ErrorRetryer {
 	}
 }
 
-// WithRetryDelay sets the retry delay function
+// WithRetryDelay sets the retry delay function, in milliseconds
 func (r *ErrorRetryer) WithRetryDelay(fn func(int) int64) *ErrorRetryer {
 	r.retryDelay = fn
 	return r
@@ -580,24 +618,34 @@ func (r *ErrorRetryer) WithRetryable(fn func(error) bool) *ErrorRetryer {
 
 // Do executes a function with retry logic
 func (r *ErrorRetryer) Do(fn func() error) error {
+	return r.DoContext(context.Background(), func(context.Context) error { return fn() })
+}
+
+// DoContext executes a function with retry logic, waiting the retry delay
+// between attempts. It stops waiting when ctx is done and returns ctx.Err()
+// joined with the last error
+func (r *ErrorRetryer) DoContext(ctx context.Context, fn func(ctx context.Context) error) error {
 	var lastErr error
 	for attempt := 1; attempt <= r.maxAttempts; attempt++ {
-		err := fn()
+		err := fn(ctx)
 		if err == nil {
 			return nil
 		}
 
 		lastErr = err
 
-		// Check if we should retry
-		if attempt < r.maxAttempts && r.retryable(err) {
-			// Wait before retry (in production, use time.Sleep)
-			_ = r.retryDelay(attempt)
-			continue
+		// Don't retry or max attempts reached
+		if attempt >= r.maxAttempts || !r.retryable(err) {
+			break
 		}
 
-		// Don't retry or max attempts reached
-		break
+		timer := time.NewTimer(time.Duration(r.retryDelay(attempt)) * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), lastErr)
+		case <-timer.C:
+		}
 	}
 
 	return lastErr
@@ -645,6 +693,12 @@ func (v *ErrorValidator) Validate(err error) error {
 	return err
 }
 
+// CaptureStack captures up to ten frames of the call stack, formatted as
+// "file:line func". skip 0 starts at the caller of CaptureStack.
+func CaptureStack(skip int) []string {
+	return captureStack(skip + 2)
+}
+
 // captureStack captures the call stack
 func captureStack(skip int) []string {
 	var stack []string
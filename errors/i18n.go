@@ -0,0 +1,191 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AddTranslation registers a message template for code in lang. code may
+// also be an ErrorType (e.g. "not_found") to translate errors built with the
+// New* constructors, which carry their type as code. Templates use the same
+// {name} placeholders as registered messages
+func (r *ErrorRegistry) AddTranslation(code, lang, template string) {
+	byLang, ok := r.translations[code]
+	if !ok {
+		byLang = make(map[string]string)
+		r.translations[code] = byLang
+	}
+	byLang[strings.ToLower(lang)] = template
+}
+
+// SetSourceLanguage sets the language registered messages are written in
+// ("en" by default). Localize serves that language with the registered
+// message instead of skipping to a lower-ranked translation
+func (r *ErrorRegistry) SetSourceLanguage(lang string) {
+	r.sourceLang = strings.ToLower(lang)
+}
+
+// AddTranslations registers templates for one language, keyed by code
+func (r *ErrorRegistry) AddTranslations(lang string, templates map[string]string) {
+	for code, template := range templates {
+		r.AddTranslation(code, lang, template)
+	}
+}
+
+// Localize returns a copy of err with its message translated for lang, which
+// may be a single tag ("zh-CN") or an Accept-Language header value. Tags fall
+// back to their base language, and the source language matches the
+// registered message. When no translation matches, the error is returned
+// unchanged
+func (r *ErrorRegistry) Localize(err error, lang string) *AppError {
+	if err == nil {
+		return nil
+	}
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		appErr = FromError(err)
+	}
+	localized, _ := r.localize(appErr, lang)
+	return localized
+}
+
+// localize translates appErr and reports the matched language tag
+func (r *ErrorRegistry) localize(appErr *AppError, lang string) (*AppError, string) {
+	if r == nil || appErr == nil || lang == "" {
+		return appErr, ""
+	}
+	byLang, ok := r.translations[appErr.Code]
+	if !ok {
+		byLang, ok = r.translations[string(appErr.Type)]
+	}
+	if !ok {
+		return appErr, ""
+	}
+
+	for _, tag := range ParseAcceptLanguage(lang) {
+		for candidate := tag; candidate != ""; candidate = parentTag(candidate) {
+			template, ok := byLang[candidate]
+			if !ok {
+				if candidate == r.sourceLang {
+					return appErr, candidate
+				}
+				continue
+			}
+			localized := *appErr
+			localized.Message = renderTemplate(template, appErr.Details)
+			return &localized, candidate
+		}
+	}
+	return appErr, ""
+}
+
+// Localize translates err for lang using the converter's registry
+func (c *ErrorConverter) Localize(err error, lang string) *AppError {
+	localized, _ := c.errorHandler.Registry().localize(c.errorHandler.Handle(err), lang)
+	return localized
+}
+
+// ToLocalizedHTTPResponse converts an error to an HTTP response with its
+// message translated for the Accept-Language header value
+func (c *ErrorConverter) ToLocalizedHTTPResponse(err error, acceptLanguage string) HTTPErrorResponse {
+	return c.toHTTPResponse(err, acceptLanguage)
+}
+
+// ParseAcceptLanguage returns the language tags of an Accept-Language header
+// ordered by quality, lowercased. Tags with q=0 and the "*" wildcard are
+// dropped
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag: tag, q: q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.tag
+	}
+	return out
+}
+
+// parentTag strips the last subtag: "zh-hant-tw" -> "zh-hant" -> "zh" -> ""
+func parentTag(tag string) string {
+	if i := strings.LastIndexByte(tag, '-'); i > 0 {
+		return tag[:i]
+	}
+	return ""
+}
+
+// renderTemplate replaces {name} placeholders with values from params;
+// unknown placeholders are kept as is
+func renderTemplate(template string, params map[string]interface{}) string {
+	if !strings.Contains(template, "{") {
+		return template
+	}
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		b.WriteString(template[:start])
+		if v, ok := params[template[start+1:end]]; ok {
+			fmt.Fprint(&b, v)
+		} else {
+			b.WriteString(template[start : end+1])
+		}
+		template = template[end+1:]
+	}
+	b.WriteString(template)
+	return b.String()
+}
+
+func registerDefaultTranslations(registry *ErrorRegistry) {
+	registry.AddTranslations("zh", map[string]string{
+		CodeValidationFailed:   "参数校验失败",
+		CodeRequiredField:      "{field} 不能为空",
+		CodeInvalidField:       "{field} 的值无效：{value}",
+		CodeNotFound:           "{resource} 不存在",
+		CodeConflict:           "{resource} 已存在",
+		CodeUnauthorized:       "需要登录认证",
+		CodeForbidden:          "无权访问",
+		CodeRateLimit:          "请求过于频繁，请稍后再试",
+		CodeInternalError:      "服务器内部错误",
+		CodeServiceUnavailable: "服务暂不可用",
+
+		// Errors built with the New* constructors keep their type as code;
+		// only types whose message is derived from details are translated
+		string(ErrorTypeRequired): "{field} 不能为空",
+		string(ErrorTypeInvalid):  "{field} 的值无效：{value}",
+		string(ErrorTypeNotFound): "{resource} 不存在",
+		string(ErrorTypeConflict): "{resource} 已存在",
+	})
+}
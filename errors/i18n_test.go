@@ -0,0 +1,91 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorRegistry_CreateRendersTemplate(t *testing.T) {
+	registry := NewErrorRegistry()
+	registry.Register("QUOTA_EXCEEDED", NewBusiness("{resource} quota of {limit} exceeded {missing}"))
+
+	err := registry.Create("QUOTA_EXCEEDED", map[string]interface{}{"resource": "project", "limit": 5})
+	require.Equal(t, "project quota of 5 exceeded {missing}", err.Message)
+	require.Equal(t, "project not found", DefaultErrorRegistry().Create(CodeNotFound, map[string]interface{}{"resource": "project"}).Message)
+}
+
+func TestErrorRegistry_Localize(t *testing.T) {
+	registry := DefaultErrorRegistry()
+	registry.AddTranslations("zh-TW", map[string]string{CodeNotFound: "{resource} 不存在（繁）"})
+	registry.AddTranslation(CodeNotFound, "fr", "{resource} introuvable")
+
+	err := fmt.Errorf("repo: %w", registry.Create(CodeNotFound, map[string]interface{}{"resource": "user"}))
+
+	cases := map[string]string{
+		"zh-CN":                   "user 不存在",
+		"zh-TW":                   "user 不存在（繁）",
+		"de, fr;q=0.8, zh;q=0.5":  "user introuvable",
+		"fr;q=0, zh":              "user 不存在",
+		"de":                      "user not found",
+		"en-US,en;q=0.9,zh;q=0.8": "user not found",
+		"en-GB, fr;q=0.9":         "user not found",
+		"":                        "user not found",
+	}
+	for lang, want := range cases {
+		require.Equal(t, want, registry.Localize(err, lang).Message, "lang %q", lang)
+	}
+
+	// Constructors carry their type as code and fall back to type translations
+	require.Equal(t, "email 不能为空", registry.Localize(NewRequired("email"), "zh").Message)
+	require.Equal(t, "token expired", registry.Localize(NewUnauthorized("token expired"), "zh").Message)
+
+	original := registry.Create(CodeForbidden, nil)
+	registry.Localize(original, "zh")
+	require.Equal(t, "Access denied", original.Message, "Localize must not mutate the error")
+}
+
+func TestErrorRegistry_LocalizeSourceLanguage(t *testing.T) {
+	registry := NewErrorRegistry()
+	registry.Register("QUOTA_EXCEEDED", NewBusiness("{resource} 配额已用完"))
+	registry.SetSourceLanguage("zh")
+	registry.AddTranslation("QUOTA_EXCEEDED", "en", "{resource} quota exceeded")
+
+	err := registry.Create("QUOTA_EXCEEDED", map[string]interface{}{"resource": "project"})
+	require.Equal(t, "project 配额已用完", registry.Localize(err, "zh-CN,en;q=0.8").Message)
+	require.Equal(t, "project quota exceeded", registry.Localize(err, "en,zh;q=0.8").Message)
+
+	converter := newTestConverter()
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.Header.Set("Accept-Language", "en-US,en;q=0.9,zh;q=0.8")
+	rec := httptest.NewRecorder()
+	converter.WriteProblem(rec, r, NewNotFound("user", 1))
+
+	require.Equal(t, "en", rec.Header().Get("Content-Language"))
+	require.Contains(t, rec.Body.String(), `"detail":"user not found"`)
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	require.Equal(t, []string{"fr-ch", "fr", "en", "de"},
+		ParseAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5"))
+	require.Empty(t, ParseAcceptLanguage("en;q=0, *"))
+}
+
+func TestErrorConverter_Localized(t *testing.T) {
+	converter := newTestConverter()
+
+	resp := converter.ToLocalizedHTTPResponse(NewForbidden("Access denied").WithCode(CodeForbidden), "zh-CN,en;q=0.5")
+	require.Equal(t, http.StatusForbidden, resp.HTTPStatus)
+	require.Equal(t, "无权访问", resp.Error.Message)
+
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.Header.Set("Accept-Language", "zh")
+	rec := httptest.NewRecorder()
+	converter.WriteProblem(rec, r, NewNotFound("user", 1))
+
+	require.Equal(t, "zh", rec.Header().Get("Content-Language"))
+	require.Contains(t, rec.Body.String(), `"detail":"user 不存在"`)
+}
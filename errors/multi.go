@@ -0,0 +1,177 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+)
+
+// FieldError is one member of an aggregated error response
+type FieldError struct {
+	Field   string                 `json:"field,omitempty"`
+	Type    string                 `json:"type"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Unwrap returns the chain's members so errors.Is and errors.As inspect
+// each of them
+func (c *ErrorChain) Unwrap() []error {
+	errs := make([]error, len(c.errors))
+	for i, err := range c.errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Err returns the chain as an error, or nil when it is empty. Use it when
+// returning a chain through an error result to avoid a non-nil interface
+// holding an empty chain
+func (c *ErrorChain) Err() error {
+	if !c.HasErrors() {
+		return nil
+	}
+	return c
+}
+
+// FromJoin decomposes an aggregate error (errors.Join, ErrorChain or any
+// error with Unwrap() []error) into an ErrorChain, flattening nested
+// aggregates. Members wrapping an AppError contribute that AppError; others
+// are converted with FromError. A non-aggregate error yields a single-member
+// chain; nil yields nil
+func FromJoin(err error) *ErrorChain {
+	if err == nil {
+		return nil
+	}
+	chain := NewErrorChain()
+	chain.addJoined(err)
+	return chain
+}
+
+func (c *ErrorChain) addJoined(err error) {
+	if members := joinedMembers(err); members != nil {
+		for _, member := range members {
+			if member != nil {
+				c.addJoined(member)
+			}
+		}
+		return
+	}
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		c.Add(appErr)
+		return
+	}
+	c.Add(FromError(err))
+}
+
+// joinedMembers returns the members of the first aggregate found by
+// following single-error wraps. An AppError is a leaf even if its inner
+// error is an aggregate
+func joinedMembers(err error) []error {
+	for err != nil {
+		switch e := err.(type) {
+		case *AppError:
+			return nil
+		case interface{ Unwrap() []error }:
+			return e.Unwrap()
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// aggregate handles each member of an aggregate error and builds the error
+// that represents the whole response. When every member is a 400 the summary
+// is the registry's VALIDATION_FAILED error; otherwise it is the member
+// selected by ErrorChain.ToHTTPStatus. Members without a status count as
+// 500. ok is false for non-aggregate errors; an aggregate with a single
+// member yields that member as the summary and no member list
+func (c *ErrorConverter) aggregate(err error, lang string) (summary *AppError, members []*AppError, contentLang string, ok bool) {
+	if joinedMembers(err) == nil {
+		return nil, nil, "", false
+	}
+	source := FromJoin(err)
+	if !source.HasErrors() {
+		return nil, nil, "", false
+	}
+
+	registry := c.errorHandler.Registry()
+	handled := NewErrorChain()
+	for _, member := range source.errors {
+		appErr, matched := registry.localize(c.errorHandler.Handle(member), lang)
+		if contentLang == "" {
+			contentLang = matched
+		}
+		if appErr.HTTPStatus == 0 {
+			copied := *appErr
+			copied.HTTPStatus = http.StatusInternalServerError
+			appErr = &copied
+		}
+		handled.Add(appErr)
+	}
+	if len(handled.errors) == 1 {
+		return handled.errors[0], nil, contentLang, true
+	}
+	members = handled.errors
+
+	status := handled.ToHTTPStatus()
+	allBadRequest := true
+	for _, member := range members {
+		if member.HTTPStatus != http.StatusBadRequest {
+			allBadRequest = false
+			break
+		}
+	}
+
+	if allBadRequest {
+		if registry != nil && registry.Get(CodeValidationFailed) != nil {
+			summary = registry.Create(CodeValidationFailed, nil)
+		} else {
+			summary = NewValidation("Validation failed").WithCode(CodeValidationFailed)
+		}
+		summary, _ = registry.localize(summary, lang)
+	} else {
+		summary = members[0]
+		for _, member := range members {
+			if member.HTTPStatus == status {
+				summary = member
+				break
+			}
+		}
+		copied := *summary
+		summary = &copied
+	}
+	summary.HTTPStatus = status
+	return summary, members, contentLang, true
+}
+
+// fieldErrors lists members for a response body. The "field" detail is
+// lifted into FieldError.Field
+func fieldErrors(members []*AppError) []FieldError {
+	out := make([]FieldError, 0, len(members))
+	for _, member := range members {
+		fe := FieldError{
+			Type:    string(member.Type),
+			Code:    member.Code,
+			Message: member.Message,
+		}
+		for k, v := range member.Details {
+			if k == "field" {
+				if field, ok := v.(string); ok {
+					fe.Field = field
+					continue
+				}
+			}
+			if fe.Details == nil {
+				fe.Details = make(map[string]interface{})
+			}
+			fe.Details[k] = v
+		}
+		out = append(out, fe)
+	}
+	return out
+}
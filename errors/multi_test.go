@@ -0,0 +1,91 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorChain_StdlibIsAs(t *testing.T) {
+	notFound := NewNotFound("user", 1)
+	chain := NewErrorChain().Add(NewRequired("email")).Add(notFound)
+	err := fmt.Errorf("create user: %w", chain.Err())
+
+	require.True(t, errors.Is(err, New(ErrorTypeNotFound, "")))
+	require.False(t, errors.Is(err, New(ErrorTypeConflict, "")))
+
+	var found *AppError
+	require.True(t, errors.As(err, &found))
+	require.Equal(t, ErrorTypeRequired, found.Type)
+
+	require.NoError(t, NewErrorChain().Err())
+}
+
+func TestFromJoin(t *testing.T) {
+	plain := fmt.Errorf("disk full")
+	joined := errors.Join(
+		NewRequired("name"),
+		fmt.Errorf("nested: %w", errors.Join(NewInvalid("age", -1, "negative"), plain)),
+		fmt.Errorf("ctx: %w", NewForbidden("no")),
+	)
+
+	chain := FromJoin(joined)
+	require.Len(t, chain.Errors(), 4)
+	require.Equal(t, ErrorTypeRequired, chain.Errors()[0].Type)
+	require.Equal(t, ErrorTypeInvalid, chain.Errors()[1].Type)
+	require.Equal(t, ErrorTypeUnknown, chain.Errors()[2].Type)
+	require.Same(t, plain, chain.Errors()[2].InnerError)
+	require.Equal(t, ErrorTypeForbidden, chain.Errors()[3].Type)
+
+	require.Len(t, FromJoin(NewForbidden("no")).Errors(), 1)
+	require.Nil(t, FromJoin(nil))
+}
+
+func TestToHTTPResponse_ValidationErrors(t *testing.T) {
+	err := errors.Join(NewRequired("email"), NewInvalid("age", -1, "must be positive"))
+
+	resp := newTestConverter().ToHTTPResponse(err)
+	require.Equal(t, http.StatusBadRequest, resp.HTTPStatus)
+	require.Equal(t, CodeValidationFailed, resp.Error.Code)
+	require.Len(t, resp.Error.Errors, 2)
+	require.Equal(t, "email", resp.Error.Errors[0].Field)
+	require.Equal(t, "email is required", resp.Error.Errors[0].Message)
+	require.Equal(t, "age", resp.Error.Errors[1].Field)
+	require.Equal(t, "must be positive", resp.Error.Errors[1].Details["reason"])
+	require.NotContains(t, resp.Error.Errors[1].Details, "field")
+
+	localized := newTestConverter().ToLocalizedHTTPResponse(err, "zh-CN")
+	require.NotEqual(t, resp.Error.Message, localized.Error.Message)
+	require.NotEqual(t, resp.Error.Errors[0].Message, localized.Error.Errors[0].Message)
+}
+
+func TestToHTTPResponse_MixedAggregate(t *testing.T) {
+	chain := NewErrorChain().Add(NewRequired("email")).Add(NewUnauthorized("token expired"))
+
+	resp := newTestConverter().ToHTTPResponse(chain)
+	require.Equal(t, http.StatusUnauthorized, resp.HTTPStatus)
+	require.Equal(t, "token expired", resp.Error.Message)
+	require.Len(t, resp.Error.Errors, 2)
+
+	// A single-member aggregate renders as that member
+	single := newTestConverter().ToHTTPResponse(errors.Join(NewNotFound("order", 1)))
+	require.Equal(t, http.StatusNotFound, single.HTTPStatus)
+	require.Empty(t, single.Error.Errors)
+}
+
+func TestToProblemDetails_Aggregate(t *testing.T) {
+	err := errors.Join(NewRequired("email"), fmt.Errorf("dial tcp: refused"))
+
+	p := newTestConverter().ToProblemDetails(err, "/users")
+	// ErrorChain.ToHTTPStatus ranks 400 above 500
+	require.Equal(t, http.StatusBadRequest, p.Status)
+	require.Equal(t, string(ErrorTypeRequired), p.Extensions["code"])
+	list, ok := p.Extensions["errors"].([]FieldError)
+	require.True(t, ok)
+	require.Len(t, list, 2)
+	require.Equal(t, "email", list[0].Field)
+	require.Equal(t, "Internal Server Error", list[1].Message)
+}
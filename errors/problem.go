@@ -0,0 +1,221 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem documents
+const ProblemContentType = "application/problem+json"
+
+// ProblemDetails is an RFC 7807 problem document
+type ProblemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Extensions are serialized as top-level members; they never replace
+	// the standard members above
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON flattens Extensions into the document
+func (p ProblemDetails) MarshalJSON() ([]byte, error) {
+	doc := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		doc[k] = v
+	}
+	doc["type"] = p.Type
+	if p.Title != "" {
+		doc["title"] = p.Title
+	}
+	if p.Status != 0 {
+		doc["status"] = p.Status
+	}
+	if p.Detail != "" {
+		doc["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		doc["instance"] = p.Instance
+	}
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON collects non-standard members into Extensions
+func (p *ProblemDetails) UnmarshalJSON(data []byte) error {
+	type standard ProblemDetails
+	if err := json.Unmarshal(data, (*standard)(p)); err != nil {
+		return err
+	}
+	var all map[string]interface{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for _, k := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(all, k)
+	}
+	if len(all) > 0 {
+		p.Extensions = all
+	}
+	return nil
+}
+
+// Write writes the problem document with its status code
+func (p ProblemDetails) Write(w http.ResponseWriter) {
+	status := p.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		body = []byte(`{"type":"about:blank","title":"Internal Server Error","status":500}`)
+		status = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// WithProblemTypeBase sets the URI prefix for problem types, e.g.
+// "https://errors.example.com/" turns code NOT_FOUND into
+// "https://errors.example.com/not-found". Without it the type is "about:blank"
+func (c *ErrorConverter) WithProblemTypeBase(base string) *ErrorConverter {
+	c.problemTypeBase = base
+	return c
+}
+
+// ToProblemDetails converts an error to an RFC 7807 problem document
+//
+// An *AppError anywhere in err's chain provides status, detail and the "code"
+// extension; its Details become extension members. Errors that are not
+// AppErrors get no detail so internal messages are not exposed. Aggregate
+// errors (ErrorChain, errors.Join) list their members in the "errors"
+// extension
+func (c *ErrorConverter) ToProblemDetails(err error, instance string) ProblemDetails {
+	problem, _ := c.toProblemDetails(err, instance, "")
+	return problem
+}
+
+// toProblemDetails builds the problem document with the detail translated for
+// lang and reports the language used
+func (c *ErrorConverter) toProblemDetails(err error, instance, lang string) (ProblemDetails, string) {
+	summary, members, contentLang, aggregated := c.aggregate(err, lang)
+	var appErr *AppError
+	known := aggregated
+	if aggregated {
+		appErr = summary
+	} else {
+		known = errors.As(err, &appErr)
+		if known {
+			err = appErr
+		}
+		appErr = c.errorHandler.Handle(err)
+		if appErr == nil {
+			appErr = New(ErrorTypeInternal, "")
+		}
+		if known {
+			appErr, contentLang = c.errorHandler.Registry().localize(appErr, lang)
+		}
+	}
+
+	status := appErr.HTTPStatus
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	problem := ProblemDetails{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: instance,
+	}
+	if known && appErr.Type != ErrorTypeUnknown {
+		problem.Detail = appErr.Message
+	}
+	if appErr.Code != "" {
+		if c.problemTypeBase != "" {
+			problem.Type = c.problemTypeBase + strings.ToLower(strings.ReplaceAll(appErr.Code, "_", "-"))
+		}
+		problem.Extensions = map[string]interface{}{"code": appErr.Code}
+	}
+	if len(appErr.Details) > 0 {
+		if problem.Extensions == nil {
+			problem.Extensions = make(map[string]interface{}, len(appErr.Details))
+		}
+		for k, v := range appErr.Details {
+			problem.Extensions[k] = v
+		}
+	}
+	if len(members) > 0 {
+		list := fieldErrors(members)
+		// Members that are not AppErrors keep their text out of responses
+		for i := range list {
+			if members[i].Type == ErrorTypeUnknown {
+				list[i].Message = http.StatusText(http.StatusInternalServerError)
+			}
+		}
+		if problem.Extensions == nil {
+			problem.Extensions = make(map[string]interface{}, 1)
+		}
+		problem.Extensions["errors"] = list
+	}
+	return problem, contentLang
+}
+
+// WriteProblem writes err as a problem document for the request, with the
+// detail translated according to its Accept-Language header
+func (c *ErrorConverter) WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem, lang := c.toProblemDetails(err, r.URL.RequestURI(), r.Header.Get("Accept-Language"))
+	if lang != "" {
+		w.Header().Set("Content-Language", lang)
+	}
+	problem.Write(w)
+}
+
+// ProblemRecoverer returns middleware that recovers panics and renders them
+// as 500 problem documents. onPanic, when non-nil, receives the recovered
+// error with its stack for logging. http.ErrAbortHandler is re-panicked so
+// net/http can abort the connection as intended
+func ProblemRecoverer(converter *ErrorConverter, onPanic func(r *http.Request, err *AppError)) func(http.Handler) http.Handler {
+	if converter == nil {
+		converter = NewErrorConverter(NewErrorHandler(DefaultErrorRegistry()))
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				var cause error
+				switch v := rec.(type) {
+				case error:
+					cause = v
+				default:
+					cause = fmt.Errorf("%v", v)
+				}
+				appErr := WrapWithType(cause, ErrorTypeInternal, "panic recovered").
+					WithCode(CodeInternalError).
+					WithHTTPStatus(http.StatusInternalServerError).
+					WithStack()
+				if onPanic != nil {
+					onPanic(r, appErr)
+				}
+
+				// Render a generic problem: the panic value is not for clients
+				converter.WriteProblem(w, r, New(ErrorTypeInternal, http.StatusText(http.StatusInternalServerError)).
+					WithCode(CodeInternalError).
+					WithHTTPStatus(http.StatusInternalServerError))
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConverter() *ErrorConverter {
+	return NewErrorConverter(NewErrorHandler(DefaultErrorRegistry()))
+}
+
+func TestToProblemDetails_AppError(t *testing.T) {
+	err := fmt.Errorf("loading order: %w",
+		NewNotFound("order", 7).WithCode("ORDER_NOT_FOUND"))
+
+	p := newTestConverter().
+		WithProblemTypeBase("https://errors.example.com/").
+		ToProblemDetails(err, "/orders/7")
+
+	require.Equal(t, "https://errors.example.com/order-not-found", p.Type)
+	require.Equal(t, "Not Found", p.Title)
+	require.Equal(t, http.StatusNotFound, p.Status)
+	require.Equal(t, "/orders/7", p.Instance)
+	require.NotEmpty(t, p.Detail)
+	require.Equal(t, "ORDER_NOT_FOUND", p.Extensions["code"])
+	require.Equal(t, "order", p.Extensions["resource"])
+}
+
+func TestToProblemDetails_PlainErrorDoesNotLeak(t *testing.T) {
+	p := newTestConverter().ToProblemDetails(fmt.Errorf("dial tcp 10.0.0.3:5432: refused"), "/x")
+
+	require.Equal(t, "about:blank", p.Type)
+	require.Equal(t, http.StatusInternalServerError, p.Status)
+	require.Empty(t, p.Detail)
+}
+
+func TestProblemDetails_JSONRoundTrip(t *testing.T) {
+	p := ProblemDetails{
+		Type:   "about:blank",
+		Title:  "Bad Request",
+		Status: http.StatusBadRequest,
+		Extensions: map[string]interface{}{
+			"field":  "email",
+			"status": "shadowed",
+		},
+	}
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	require.Equal(t, float64(http.StatusBadRequest), doc["status"], "extensions must not override members")
+	require.Equal(t, "email", doc["field"])
+	require.NotContains(t, doc, "detail")
+
+	var back ProblemDetails
+	require.NoError(t, json.Unmarshal(data, &back))
+	require.Equal(t, http.StatusBadRequest, back.Status)
+	require.Equal(t, map[string]interface{}{"field": "email"}, back.Extensions)
+}
+
+func TestProblemRecoverer(t *testing.T) {
+	var recovered *AppError
+	h := ProblemRecoverer(nil, func(_ *http.Request, err *AppError) { recovered = err })(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("secret: nil map") }))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom?x=1", nil))
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+	require.NotContains(t, rec.Body.String(), "secret")
+
+	var p ProblemDetails
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	require.Equal(t, "/boom?x=1", p.Instance)
+	require.Equal(t, CodeInternalError, p.Extensions["code"])
+
+	require.NotNil(t, recovered)
+	require.EqualError(t, recovered.InnerError, "secret: nil map")
+	require.NotEmpty(t, recovered.Stack)
+}
+
+func TestProblemRecoverer_RepanicsAbortHandler(t *testing.T) {
+	h := ProblemRecoverer(nil, nil)(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic(http.ErrAbortHandler) }))
+
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
@@ -0,0 +1,37 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorRetryer_WaitsBetweenAttempts(t *testing.T) {
+	calls := 0
+	start := time.Now()
+	err := NewErrorRetryer(3).
+		WithRetryDelay(func(int) int64 { return 10 }).
+		Do(func() error {
+			calls++
+			return NewExternal("down")
+		})
+
+	require.Error(t, err)
+	require.Equal(t, 3, calls)
+	require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestErrorRetryer_DoContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := NewErrorRetryer(5).
+		WithRetryDelay(func(int) int64 { return 60_000 }).
+		DoContext(ctx, func(context.Context) error { return NewTimeout("slow") })
+
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.True(t, errors.Is(err, New(ErrorTypeTimeout, "")))
+}
@@ -0,0 +1,92 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+)
+
+// DetailViolations is the Details key holding a validation error's
+// []FieldViolation
+const DetailViolations = "violations"
+
+// ViolationType is the FieldViolation type, matching the type the binding
+// package reports for struct-tag validation failures
+const ViolationType = "validation_error"
+
+// FieldViolation is one failed rule. Its JSON form is the same as a
+// binding.BindError so business-rule and binding-layer errors render alike
+type FieldViolation struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+	// Rule is the validate tag that failed, when known
+	Rule string `json:"rule,omitempty"`
+}
+
+// ValidationBuilder collects field violations into a single validation error
+//
+//	err := errors.Validation().
+//		Check(req.Amount > 0, "amount", "must be positive").
+//		Field("currency", "unsupported").
+//		Err()
+type ValidationBuilder struct {
+	violations []FieldViolation
+}
+
+// Validation starts a validation error builder
+func Validation() *ValidationBuilder {
+	return &ValidationBuilder{}
+}
+
+// Field records a violation of field. An empty field records an object-level
+// violation
+func (b *ValidationBuilder) Field(field, message string) *ValidationBuilder {
+	b.violations = append(b.violations, FieldViolation{Type: ViolationType, Message: message, Field: field})
+	return b
+}
+
+// Check records a violation of field unless ok
+func (b *ValidationBuilder) Check(ok bool, field, message string) *ValidationBuilder {
+	if !ok {
+		b.Field(field, message)
+	}
+	return b
+}
+
+// HasErrors reports whether any violation was recorded
+func (b *ValidationBuilder) HasErrors() bool {
+	return len(b.violations) > 0
+}
+
+// Build returns a 400 validation AppError listing the violations under
+// DetailViolations, or nil when there are none. Return Err instead from
+// functions whose result is error to avoid a non-nil interface holding nil
+func (b *ValidationBuilder) Build() *AppError {
+	if !b.HasErrors() {
+		return nil
+	}
+	violations := make([]FieldViolation, len(b.violations))
+	copy(violations, b.violations)
+	return New(ErrorTypeValidation, "Validation Failed").
+		WithDetail(DetailViolations, violations).
+		WithHTTPStatus(http.StatusBadRequest)
+}
+
+// Err returns Build as an error, or nil when there are no violations
+func (b *ValidationBuilder) Err() error {
+	if err := b.Build(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Violations returns the field violations of a validation error built by
+// Validation, found anywhere in err's chain
+func Violations(err error) ([]FieldViolation, bool) {
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		return nil, false
+	}
+	violations, ok := appErr.Details[DetailViolations].([]FieldViolation)
+	return violations, ok
+}
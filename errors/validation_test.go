@@ -0,0 +1,40 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationBuilder(t *testing.T) {
+	amount := -5
+	b := Validation().
+		Check(amount > 0, "amount", "must be positive").
+		Check(true, "name", "never recorded").
+		Field("currency", "unsupported")
+
+	require.True(t, b.HasErrors())
+	appErr := b.Build()
+	require.Equal(t, ErrorTypeValidation, appErr.Type)
+	require.Equal(t, http.StatusBadRequest, appErr.HTTPStatus)
+
+	violations, ok := Violations(fmt.Errorf("create payment: %w", appErr))
+	require.True(t, ok)
+	require.Equal(t, []FieldViolation{
+		{Type: ViolationType, Field: "amount", Message: "must be positive"},
+		{Type: ViolationType, Field: "currency", Message: "unsupported"},
+	}, violations)
+}
+
+func TestValidationBuilder_Empty(t *testing.T) {
+	b := Validation().Check(true, "amount", "must be positive")
+
+	require.False(t, b.HasErrors())
+	require.Nil(t, b.Build())
+	require.NoError(t, b.Err())
+
+	_, ok := Violations(NewNotFound("user", 1))
+	require.False(t, ok)
+}
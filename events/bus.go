@@ -0,0 +1,246 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// BusOptions configures a Bus.
+type BusOptions struct {
+	Options
+	// Workers is the number of goroutines running handlers (default 4).
+	Workers int
+	// QueueSize bounds deliveries waiting for a worker (default 1024).
+	// Publish blocks while the queue is full.
+	QueueSize int
+}
+
+// Bus is an in-process Broker. Handlers run asynchronously on a worker
+// pool; messages are lost if the process exits before they are handled,
+// and handler errors are logged but not retried.
+type Bus struct {
+	opts  BusOptions
+	queue chan delivery
+
+	mu     sync.RWMutex
+	topics map[string]*topicSubs
+	closed bool
+	// done is closed by Close to release publishers blocked on a full
+	// queue; publishing counts those still sending so the queue is only
+	// closed once they return.
+	done       chan struct{}
+	publishing sync.WaitGroup
+
+	wg sync.WaitGroup
+}
+
+var _ Broker = (*Bus)(nil)
+
+type delivery struct {
+	msg     *Message
+	handler Handler
+	sub     *busSubscription
+}
+
+// topicSubs holds the subscriptions of a topic: ungrouped ones receive
+// every message, groups receive each message once, round-robin.
+type topicSubs struct {
+	broadcast []*busSubscription
+	groups    map[string]*busGroup
+}
+
+type busGroup struct {
+	members []*busSubscription
+	next    atomic.Uint64
+}
+
+type busSubscription struct {
+	bus      *Bus
+	topic    string
+	group    string
+	handler  Handler
+	inflight sync.WaitGroup
+	active   atomic.Bool
+}
+
+// NewBus creates a Bus and starts its workers.
+func NewBus(opts BusOptions) *Bus {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+	b := &Bus{
+		opts:   opts,
+		queue:  make(chan delivery, opts.QueueSize),
+		topics: make(map[string]*topicSubs),
+		done:   make(chan struct{}),
+	}
+	for i := 0; i < opts.Workers; i++ {
+		b.wg.Add(1)
+		go b.worker()
+	}
+	return b
+}
+
+// Publish prepares msgs and queues one delivery per ungrouped subscription
+// and per group. It blocks while the queue is full until ctx is done or the
+// bus is closed.
+//
+// The targets are resolved under the lock, which is released before
+// queueing: a handler that publishes into a full queue must not hold it,
+// or a pending Subscribe would block every worker behind it.
+func (b *Bus) Publish(ctx context.Context, msgs ...*Message) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	batches := make([][]delivery, len(msgs))
+	for i, msg := range msgs {
+		Prepare(ctx, msg)
+		for _, sub := range b.targetsLocked(msg.Topic) {
+			copied := *msg
+			// Counted under the lock so Unsubscribe waits for it.
+			sub.inflight.Add(1)
+			batches[i] = append(batches[i], delivery{msg: &copied, handler: sub.handler, sub: sub})
+		}
+	}
+	b.publishing.Add(1)
+	b.mu.RUnlock()
+	defer b.publishing.Done()
+
+	for i, batch := range batches {
+		for j, d := range batch {
+			var err error
+			select {
+			case b.queue <- d:
+				continue
+			case <-ctx.Done():
+				err = fmt.Errorf("events: publish %s: %w", msgs[i].Topic, ctx.Err())
+			case <-b.done:
+				err = ErrClosed
+			}
+			for _, rest := range batches[i][j:] {
+				rest.sub.inflight.Done()
+			}
+			for _, later := range batches[i+1:] {
+				for _, rest := range later {
+					rest.sub.inflight.Done()
+				}
+			}
+			return err
+		}
+		RecordPublish(b.opts.Options, msgs[i])
+	}
+	return nil
+}
+
+func (b *Bus) targetsLocked(topic string) []*busSubscription {
+	subs := b.topics[topic]
+	if subs == nil {
+		return nil
+	}
+	targets := append([]*busSubscription{}, subs.broadcast...)
+	for _, g := range subs.groups {
+		n := uint64(len(g.members))
+		targets = append(targets, g.members[(g.next.Add(1)-1)%n])
+	}
+	return targets
+}
+
+// Subscribe registers handler for topic. ctx is not used after Subscribe
+// returns; call Unsubscribe to stop delivery.
+func (b *Bus) Subscribe(_ context.Context, topic string, handler Handler, opts ...SubscribeOption) (Subscription, error) {
+	o := ApplySubscribeOptions(opts)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	sub := &busSubscription{bus: b, topic: topic, group: o.Group, handler: handler}
+	sub.active.Store(true)
+
+	subs := b.topics[topic]
+	if subs == nil {
+		subs = &topicSubs{groups: make(map[string]*busGroup)}
+		b.topics[topic] = subs
+	}
+	if o.Group == "" {
+		subs.broadcast = append(subs.broadcast, sub)
+	} else {
+		g := subs.groups[o.Group]
+		if g == nil {
+			g = &busGroup{}
+			subs.groups[o.Group] = g
+		}
+		g.members = append(g.members, sub)
+	}
+	return sub, nil
+}
+
+// Unsubscribe removes the subscription and waits for its queued and
+// running deliveries. Queued deliveries are discarded. It must not be
+// called from the subscription's own handler.
+func (s *busSubscription) Unsubscribe() error {
+	if !s.active.Swap(false) {
+		return nil
+	}
+	b := s.bus
+	b.mu.Lock()
+	if subs := b.topics[s.topic]; subs != nil {
+		if s.group == "" {
+			subs.broadcast = removeSub(subs.broadcast, s)
+		} else if g := subs.groups[s.group]; g != nil {
+			g.members = removeSub(g.members, s)
+			if len(g.members) == 0 {
+				delete(subs.groups, s.group)
+			}
+		}
+	}
+	b.mu.Unlock()
+	s.inflight.Wait()
+	return nil
+}
+
+func removeSub(subs []*busSubscription, s *busSubscription) []*busSubscription {
+	out := subs[:0]
+	for _, sub := range subs {
+		if sub != s {
+			out = append(out, sub)
+		}
+	}
+	return out
+}
+
+// Close stops accepting messages, handles the queued ones and waits for
+// the workers to exit. Publishers still blocked on a full queue return
+// ErrClosed.
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	b.mu.Unlock()
+	b.publishing.Wait()
+	close(b.queue)
+	b.wg.Wait()
+	return nil
+}
+
+func (b *Bus) worker() {
+	defer b.wg.Done()
+	for d := range b.queue {
+		if d.sub.active.Load() {
+			_ = Dispatch(context.Background(), b.opts.Options, d.msg, d.handler)
+		}
+		d.sub.inflight.Done()
+	}
+}
@@ -0,0 +1,234 @@
+// Package events publishes and consumes messages between components and
+// services. One Publisher/Subscriber pair is implemented by the in-process
+// Bus and by the broker adapters in the subpackages (Redis streams, NATS,
+// Kafka), so code written against the interfaces can move between them.
+//
+// Every implementation copies the publisher's RequestContext and span link
+// into the message headers and restores them for the handler.
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/request"
+	"github.com/leeforge/framework/tracing"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrClosed is returned when publishing to or subscribing on a closed
+	// publisher or subscriber.
+	ErrClosed = errors.New("events: closed")
+	// ErrMalformed marks a message that can never be handled, e.g. one whose
+	// payload does not decode. Such messages are logged and acknowledged
+	// instead of being redelivered.
+	ErrMalformed = errors.New("events: malformed message")
+	// ErrGroupRequired is returned by subscribers that only support
+	// consumer groups when none is given.
+	ErrGroupRequired = errors.New("events: consumer group required")
+)
+
+// Message is the transport form of an event.
+type Message struct {
+	// ID is unique per message; Prepare assigns one when empty.
+	ID    string
+	Topic string
+	// Key orders and partitions messages on brokers that support it.
+	Key       string
+	Headers   map[string]string
+	Payload   []byte
+	Timestamp time.Time
+}
+
+// Handler processes a message. Returning an error asks brokers with
+// at-least-once delivery to redeliver it.
+type Handler func(ctx context.Context, msg *Message) error
+
+// Publisher sends messages.
+type Publisher interface {
+	Publish(ctx context.Context, msgs ...*Message) error
+}
+
+// Subscriber delivers messages of a topic to a handler until the returned
+// Subscription is cancelled.
+type Subscriber interface {
+	Subscribe(ctx context.Context, topic string, handler Handler, opts ...SubscribeOption) (Subscription, error)
+}
+
+// Subscription is an active subscription.
+type Subscription interface {
+	// Unsubscribe stops delivery and waits for the in-flight handler.
+	Unsubscribe() error
+}
+
+// Broker is a Publisher and Subscriber that owns connections or workers.
+type Broker interface {
+	Publisher
+	Subscriber
+	Close() error
+}
+
+// SubscribeOption configures a subscription.
+type SubscribeOption func(*SubscribeOptions)
+
+// SubscribeOptions holds the resolved subscription settings.
+type SubscribeOptions struct {
+	// Group makes subscriptions with the same group share the topic's
+	// messages, each message going to one member. Without a group every
+	// subscription receives every message.
+	Group string
+}
+
+// WithGroup sets the consumer group.
+func WithGroup(name string) SubscribeOption {
+	return func(o *SubscribeOptions) { o.Group = name }
+}
+
+// ApplySubscribeOptions resolves opts; it is meant for Subscriber
+// implementations.
+func ApplySubscribeOptions(opts []SubscribeOption) SubscribeOptions {
+	var o SubscribeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Options are shared by the Bus and the broker adapters.
+type Options struct {
+	// Tracer, when set, wraps each delivery in a consumer span linked to
+	// the publishing span.
+	Tracer *tracing.Tracer
+	// Metrics, when set, receives publish and handle counters and handler
+	// durations per topic.
+	Metrics *metrics.Collector
+	// Logger receives handler failures (default no-op).
+	Logger *zap.Logger
+}
+
+func (o Options) logger() *zap.Logger {
+	if o.Logger == nil {
+		return zap.NewNop()
+	}
+	return o.Logger
+}
+
+// Prepare fills in the ID, timestamp and propagation headers of a message
+// about to be published. Headers already set are kept. Publisher
+// implementations call it for every message.
+func Prepare(ctx context.Context, msg *Message) {
+	if msg.ID == "" {
+		msg.ID = uuid.NewString()
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	if msg.Headers == nil {
+		msg.Headers = make(map[string]string)
+	}
+	carrier := make(map[string]string)
+	Inject(ctx, carrier)
+	for k, v := range carrier {
+		if _, ok := msg.Headers[k]; !ok {
+			msg.Headers[k] = v
+		}
+	}
+}
+
+// Inject writes the RequestContext and span link of ctx into headers.
+func Inject(ctx context.Context, headers map[string]string) {
+	for k, v := range request.FromContext(ctx).ToHeaders() {
+		if len(v) > 0 {
+			headers[k] = v[0]
+		}
+	}
+	tracing.InjectLink(ctx, headers)
+}
+
+// Extract returns ctx carrying the RequestContext found in headers, if any.
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+	h := make(http.Header, len(headers))
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	rc := request.FromHeaders(h)
+	if rc.RequestID == "" && rc.TraceID == "" && rc.CorrelationID == "" {
+		return ctx
+	}
+	return rc.WithContext(ctx)
+}
+
+type messageKey struct{}
+
+// MessageFromContext returns the message being handled.
+func MessageFromContext(ctx context.Context) (*Message, bool) {
+	msg, ok := ctx.Value(messageKey{}).(*Message)
+	return msg, ok
+}
+
+// RecordPublish counts published messages; Publisher implementations call
+// it after a successful publish.
+func RecordPublish(opts Options, msgs ...*Message) {
+	if opts.Metrics == nil {
+		return
+	}
+	for _, msg := range msgs {
+		opts.Metrics.IncCounter("events_published_total", map[string]string{"topic": msg.Topic})
+	}
+}
+
+// Dispatch runs handler for msg with the propagated context, an optional
+// consumer span, panic recovery and metrics. Malformed messages are logged
+// and reported as handled. Subscriber implementations call it for every
+// delivery.
+func Dispatch(ctx context.Context, opts Options, msg *Message, handler Handler) (err error) {
+	ctx = Extract(ctx, msg.Headers)
+	ctx = context.WithValue(ctx, messageKey{}, msg)
+
+	var span *tracing.Span
+	if opts.Tracer != nil {
+		producer, _ := tracing.ExtractLink(msg.Headers)
+		ctx, span = opts.Tracer.StartConsumer(ctx, "event "+msg.Topic, producer,
+			tracing.WithAttributes(map[string]interface{}{
+				"messaging.destination": msg.Topic,
+				"messaging.message_id":  msg.ID,
+			}))
+	}
+
+	start := time.Now()
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("events: handler panic: %v", r)
+			}
+		}()
+		err = handler(ctx, msg)
+	}()
+	if span != nil {
+		opts.Tracer.End(span, err)
+	}
+
+	status := "ok"
+	switch {
+	case errors.Is(err, ErrMalformed):
+		status = "malformed"
+		opts.logger().Error("events: dropping malformed message",
+			zap.String("topic", msg.Topic), zap.String("id", msg.ID), zap.Error(err))
+		err = nil
+	case err != nil:
+		status = "error"
+		opts.logger().Warn("events: handler failed",
+			zap.String("topic", msg.Topic), zap.String("id", msg.ID), zap.Error(err))
+	}
+	if opts.Metrics != nil {
+		opts.Metrics.IncCounter("events_handled_total", map[string]string{"topic": msg.Topic, "status": status})
+		opts.Metrics.ObserveHistogram("events_handle_duration_seconds", time.Since(start).Seconds(), map[string]string{"topic": msg.Topic})
+	}
+	return err
+}
@@ -0,0 +1,267 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/request"
+	"github.com/leeforge/framework/tracing"
+)
+
+type userCreated struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+var userCreatedTopic = NewTopic[userCreated]("user.created")
+
+type recordingProcessor struct {
+	spans chan *tracing.Span
+}
+
+func (p *recordingProcessor) OnEnd(span *tracing.Span)       { p.spans <- span }
+func (p *recordingProcessor) Shutdown(context.Context) error { return nil }
+
+func TestBusTypedDeliveryAndPropagation(t *testing.T) {
+	rec := &recordingProcessor{spans: make(chan *tracing.Span, 8)}
+	tracer, _ := tracing.NewTracer(tracing.TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: rec})
+	bus := NewBus(BusOptions{Options: Options{Tracer: tracer}})
+	defer bus.Close()
+
+	type delivered struct {
+		event     userCreated
+		requestID string
+		key       string
+	}
+	got := make(chan delivered, 1)
+	_, err := userCreatedTopic.Subscribe(context.Background(), bus, func(ctx context.Context, e userCreated) error {
+		msg, _ := MessageFromContext(ctx)
+		got <- delivered{event: e, requestID: request.FromContext(ctx).RequestID, key: msg.Key}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc := request.NewRequestContext()
+	ctx, producer := tracer.Start(rc.WithContext(context.Background()), "http.request")
+	if err := userCreatedTopic.Publish(ctx, bus, userCreated{ID: "u1", Email: "a@b.c"}, WithKey("u1")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case d := <-got:
+		if d.event.Email != "a@b.c" || d.requestID != rc.RequestID || d.key != "u1" {
+			t.Errorf("delivered = %+v, want request id %s", d, rc.RequestID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not delivered")
+	}
+	for {
+		select {
+		case span := <-rec.spans:
+			if span.Kind != tracing.SpanKindConsumer {
+				continue
+			}
+			if span.Name != "event user.created" || len(span.Links) != 1 || span.Links[0].SpanID != producer.SpanID {
+				t.Errorf("consumer span %q links = %+v, want link to %s", span.Name, span.Links, producer.SpanID)
+			}
+			return
+		case <-time.After(time.Second):
+			t.Fatal("consumer span not recorded")
+		}
+	}
+}
+
+func TestBusGroupsShareMessages(t *testing.T) {
+	bus := NewBus(BusOptions{Workers: 2})
+	ctx := context.Background()
+
+	var grouped, broadcast atomic.Int32
+	for i := 0; i < 3; i++ {
+		bus.Subscribe(ctx, "job", func(context.Context, *Message) error { grouped.Add(1); return nil }, WithGroup("workers"))
+	}
+	bus.Subscribe(ctx, "job", func(context.Context, *Message) error { broadcast.Add(1); return nil })
+	sub, _ := bus.Subscribe(ctx, "job", func(context.Context, *Message) error { broadcast.Add(1); return nil })
+	sub.Unsubscribe()
+
+	for i := 0; i < 6; i++ {
+		if err := bus.Publish(ctx, &Message{Topic: "job"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bus.Close() // 处理完队列中的消息
+
+	if grouped.Load() != 6 || broadcast.Load() != 6 {
+		t.Errorf("grouped = %d, broadcast = %d, want 6 each", grouped.Load(), broadcast.Load())
+	}
+	if err := bus.Publish(ctx, &Message{Topic: "job"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish after Close err = %v", err)
+	}
+}
+
+func TestBusHandlerPublishesWhileSubscribeWaits(t *testing.T) {
+	bus := NewBus(BusOptions{Workers: 1, QueueSize: 1})
+	defer bus.Close()
+	ctx := context.Background()
+
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	published := make(chan error, 1)
+	var first atomic.Bool
+	bus.Subscribe(ctx, "order.paid", func(ctx context.Context, _ *Message) error {
+		started <- struct{}{}
+		<-release
+		if first.CompareAndSwap(false, true) {
+			published <- bus.Publish(ctx, &Message{Topic: "order.audited"})
+		}
+		return nil
+	})
+
+	// The worker is busy and the queue is full: the third Publish blocks
+	// and a Subscribe arrives behind it.
+	bus.Publish(ctx, &Message{Topic: "order.paid"})
+	<-started
+	bus.Publish(ctx, &Message{Topic: "order.paid"})
+	go bus.Publish(ctx, &Message{Topic: "order.paid"})
+	time.Sleep(20 * time.Millisecond)
+	subscribed := make(chan struct{})
+	go func() {
+		bus.Subscribe(ctx, "order.refunded", func(context.Context, *Message) error { return nil })
+		close(subscribed)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	select {
+	case <-subscribed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Subscribe deadlocked behind a blocked Publish")
+	}
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Fatalf("Publish from handler: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Publish from handler deadlocked")
+	}
+}
+
+func TestBusCloseReleasesBlockedPublisher(t *testing.T) {
+	bus := NewBus(BusOptions{Workers: 1, QueueSize: 1})
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	bus.Subscribe(ctx, "job", func(context.Context, *Message) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil
+	})
+	bus.Publish(ctx, &Message{Topic: "job"})
+	<-started
+	bus.Publish(ctx, &Message{Topic: "job"})
+
+	blocked := make(chan error, 1)
+	go func() { blocked <- bus.Publish(ctx, &Message{Topic: "job"}) }()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		bus.Close()
+		close(closed)
+	}()
+	select {
+	case err := <-blocked:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("blocked Publish err = %v, want ErrClosed", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not release the blocked publisher")
+	}
+	close(release)
+	<-closed
+}
+
+func TestDispatchOutcomes(t *testing.T) {
+	collector := metrics.NewCollector()
+	opts := Options{Metrics: collector}
+	msg := &Message{Topic: "t", Payload: []byte("not json")}
+
+	err := Dispatch(context.Background(), opts, msg, func(context.Context, *Message) error { panic("boom") })
+	if err == nil {
+		t.Error("panic must surface as an error")
+	}
+
+	handler := captureTyped()
+	if err := Dispatch(context.Background(), opts, msg, handler); err != nil {
+		t.Errorf("malformed payload must be acknowledged, got %v", err)
+	}
+
+	statuses := make(map[string]float64)
+	for _, m := range collector.Export() {
+		if m.Name == "events_handled_total" {
+			statuses[m.Labels["status"]] += m.Value
+		}
+	}
+	if statuses["error"] != 1 || statuses["malformed"] != 1 {
+		t.Errorf("handled statuses = %v", statuses)
+	}
+}
+
+// captureTyped 返回 Topic.Subscribe 注册的解码处理函数
+func captureTyped() Handler {
+	var s capturingSubscriber
+	userCreatedTopic.Subscribe(context.Background(), &s, func(context.Context, userCreated) error { return nil })
+	return s.handler
+}
+
+type capturingSubscriber struct {
+	handler Handler
+}
+
+func (s *capturingSubscriber) Subscribe(_ context.Context, _ string, h Handler, _ ...SubscribeOption) (Subscription, error) {
+	s.handler = h
+	return nil, nil
+}
+
+type memoryOutbox struct {
+	mu   sync.Mutex
+	msgs []*Message
+}
+
+func (o *memoryOutbox) Save(_ context.Context, msgs ...*Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.msgs = append(o.msgs, msgs...)
+	return nil
+}
+
+func TestOutboxPublisherPreparesMessages(t *testing.T) {
+	outbox := &memoryOutbox{}
+	rc := request.NewRequestContext()
+	ctx := rc.WithContext(context.Background())
+
+	if err := userCreatedTopic.Publish(ctx, NewOutboxPublisher(outbox), userCreated{ID: "u1"}, WithHeader("X-Source", "signup")); err != nil {
+		t.Fatal(err)
+	}
+	if len(outbox.msgs) != 1 {
+		t.Fatalf("outbox has %d messages", len(outbox.msgs))
+	}
+	msg := outbox.msgs[0]
+	if msg.ID == "" || msg.Timestamp.IsZero() || msg.Headers["X-Source"] != "signup" {
+		t.Errorf("message = %+v", msg)
+	}
+	if got := Extract(context.Background(), msg.Headers); request.FromContext(got).RequestID != rc.RequestID {
+		t.Error("request context not stored in the outbox headers")
+	}
+}
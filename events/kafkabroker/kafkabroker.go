@@ -0,0 +1,246 @@
+// Package kafkabroker implements events.Broker on Kafka. Message keys pick
+// the partition, so messages with the same key keep their order. Every
+// subscription belongs to a consumer group and offsets are committed only
+// after the handler succeeds (at-least-once); a failing message is retried
+// in place, holding back its partition until it succeeds.
+package kafkabroker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/leeforge/framework/events"
+	"github.com/leeforge/framework/retry"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Header names carrying message fields that have no Kafka equivalent.
+const (
+	HeaderID = "events-id"
+)
+
+// Options configures a Broker.
+type Options struct {
+	events.Options
+	// Brokers are the bootstrap addresses, e.g. "kafka-1:9092".
+	Brokers []string
+	// Prefix is prepended to topic names, e.g. "app.".
+	Prefix string
+	// Group is used for subscriptions that do not pass events.WithGroup.
+	Group string
+	// RetryBackoff is the initial delay between handler retries; it
+	// doubles up to RetryMaxBackoff (defaults 500ms and 30s).
+	RetryBackoff    time.Duration
+	RetryMaxBackoff time.Duration
+}
+
+// Broker publishes through one shared writer and consumes with one reader
+// per subscription.
+type Broker struct {
+	opts   Options
+	writer *kafka.Writer
+
+	mu     sync.Mutex
+	subs   map[*subscription]struct{}
+	closed bool
+}
+
+var _ events.Broker = (*Broker)(nil)
+
+// New creates a Broker.
+func New(opts Options) *Broker {
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 500 * time.Millisecond
+	}
+	if opts.RetryMaxBackoff <= 0 {
+		opts.RetryMaxBackoff = 30 * time.Second
+	}
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	return &Broker{
+		opts: opts,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(opts.Brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		},
+		subs: make(map[*subscription]struct{}),
+	}
+}
+
+// Publish writes msgs and waits for all in-sync replicas to acknowledge.
+func (b *Broker) Publish(ctx context.Context, msgs ...*events.Message) error {
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+	if closed {
+		return events.ErrClosed
+	}
+
+	out := make([]kafka.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		events.Prepare(ctx, msg)
+		km := kafka.Message{
+			Topic: b.opts.Prefix + msg.Topic,
+			Value: msg.Payload,
+			Time:  msg.Timestamp,
+			Headers: []kafka.Header{
+				{Key: HeaderID, Value: []byte(msg.ID)},
+			},
+		}
+		if msg.Key != "" {
+			km.Key = []byte(msg.Key)
+		}
+		for k, v := range msg.Headers {
+			km.Headers = append(km.Headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+		out = append(out, km)
+	}
+	if err := b.writer.WriteMessages(ctx, out...); err != nil {
+		return fmt.Errorf("kafkabroker: publish: %w", err)
+	}
+	events.RecordPublish(b.opts.Options, msgs...)
+	return nil
+}
+
+// Subscribe starts a group reader for topic. A new group starts at the
+// newest offset.
+func (b *Broker) Subscribe(_ context.Context, topic string, handler events.Handler, opts ...events.SubscribeOption) (events.Subscription, error) {
+	o := events.ApplySubscribeOptions(opts)
+	if o.Group == "" {
+		o.Group = b.opts.Group
+	}
+	if o.Group == "" {
+		return nil, events.ErrGroupRequired
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, events.ErrClosed
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     b.opts.Brokers,
+		GroupID:     o.Group,
+		Topic:       b.opts.Prefix + topic,
+		StartOffset: kafka.LastOffset,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &subscription{broker: b, topic: topic, handler: handler, reader: reader, cancel: cancel, done: make(chan struct{})}
+	b.subs[s] = struct{}{}
+	go s.run(ctx)
+	return s, nil
+}
+
+// Close stops all subscriptions and closes the writer.
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	subs := make([]*subscription, 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.Unsubscribe()
+	}
+	return b.writer.Close()
+}
+
+type subscription struct {
+	broker  *Broker
+	topic   string
+	handler events.Handler
+	reader  *kafka.Reader
+	cancel  context.CancelFunc
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (s *subscription) Unsubscribe() error {
+	var err error
+	s.once.Do(func() {
+		s.cancel()
+		<-s.done
+		err = s.reader.Close()
+		s.broker.mu.Lock()
+		delete(s.broker.subs, s)
+		s.broker.mu.Unlock()
+	})
+	return err
+}
+
+func (s *subscription) run(ctx context.Context) {
+	defer close(s.done)
+	opts := s.broker.opts
+	// Retry until the handler succeeds or the subscription stops; a message
+	// that can never succeed should be rejected with events.ErrMalformed.
+	policy := retry.Policy{
+		MaxAttempts:     math.MaxInt,
+		InitialInterval: opts.RetryBackoff,
+		MaxInterval:     opts.RetryMaxBackoff,
+		Jitter:          0.2,
+		Classify:        func(error) bool { return true },
+	}
+
+	for {
+		km, err := s.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			opts.Logger.Warn("kafkabroker: fetch failed", zap.String("topic", s.topic), zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		msg := decode(s.topic, km)
+		err = retry.Do(ctx, policy, func(ctx context.Context) error {
+			return events.Dispatch(ctx, opts.Options, msg, s.handler)
+		})
+		if err != nil {
+			// the subscription stopped; the offset stays uncommitted so the
+			// message is redelivered to the group
+			return
+		}
+		if err := s.reader.CommitMessages(context.Background(), km); err != nil {
+			opts.Logger.Warn("kafkabroker: commit failed", zap.String("topic", s.topic), zap.Error(err))
+		}
+	}
+}
+
+func decode(topic string, km kafka.Message) *events.Message {
+	msg := &events.Message{
+		Topic:     topic,
+		Key:       string(km.Key),
+		Payload:   km.Value,
+		Timestamp: km.Time,
+		Headers:   make(map[string]string, len(km.Headers)),
+	}
+	for _, h := range km.Headers {
+		if h.Key == HeaderID {
+			msg.ID = string(h.Value)
+			continue
+		}
+		msg.Headers[h.Key] = string(h.Value)
+	}
+	if msg.ID == "" {
+		msg.ID = fmt.Sprintf("%s/%d/%d", km.Topic, km.Partition, km.Offset)
+	}
+	return msg
+}
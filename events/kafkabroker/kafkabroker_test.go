@@ -0,0 +1,89 @@
+package kafkabroker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/events"
+	"github.com/leeforge/framework/request"
+	"github.com/segmentio/kafka-go"
+)
+
+func TestDecode(t *testing.T) {
+	msg := decode("order.placed", kafka.Message{
+		Topic:     "app.order.placed",
+		Partition: 2,
+		Offset:    7,
+		Key:       []byte("order-9"),
+		Headers:   []kafka.Header{{Key: "trace.trace_id", Value: []byte("abc")}},
+	})
+	if msg.ID != "app.order.placed/2/7" || msg.Key != "order-9" || msg.Headers["trace.trace_id"] != "abc" {
+		t.Errorf("decoded = %+v", msg)
+	}
+}
+
+func TestSubscribeRequiresGroup(t *testing.T) {
+	b := New(Options{Brokers: []string{"127.0.0.1:1"}})
+	defer b.Close()
+	if _, err := b.Subscribe(context.Background(), "t", nil); !errors.Is(err, events.ErrGroupRequired) {
+		t.Errorf("err = %v, want ErrGroupRequired", err)
+	}
+}
+
+func TestBrokerRoundTrip(t *testing.T) {
+	brokers := strings.TrimSpace(os.Getenv("KAFKA_TEST_BROKERS"))
+	if brokers == "" {
+		t.Skip("set KAFKA_TEST_BROKERS to run kafka integration tests")
+	}
+	prefix := "test-" + uuid.NewString()[:8] + "."
+	conn, err := kafka.Dial("tcp", strings.Split(brokers, ",")[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.CreateTopics(kafka.TopicConfig{Topic: prefix + "order.placed", NumPartitions: 1, ReplicationFactor: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	b := New(Options{Brokers: strings.Split(brokers, ","), Prefix: prefix, Group: "billing", RetryBackoff: 10 * time.Millisecond})
+	defer b.Close()
+	ctx := context.Background()
+
+	got := make(chan string, 1)
+	attempts := 0
+	_, err = b.Subscribe(ctx, "order.placed", func(ctx context.Context, msg *events.Message) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("retry me")
+		}
+		got <- request.FromContext(ctx).RequestID
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc := request.NewRequestContext()
+	deadline := time.After(30 * time.Second)
+	// 新建的消费组从最新位点开始，重复发送直到被消费
+	for {
+		if err := b.Publish(rc.WithContext(ctx), &events.Message{Topic: "order.placed", Key: "o-1"}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case id := <-got:
+			if id != rc.RequestID {
+				t.Errorf("request id = %q, want %q", id, rc.RequestID)
+			}
+			return
+		case <-time.After(time.Second):
+		case <-deadline:
+			t.Fatal("message not delivered")
+		}
+	}
+}
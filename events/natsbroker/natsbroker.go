@@ -0,0 +1,202 @@
+// Package natsbroker implements events.Broker on core NATS. Topics map to
+// subjects and consumer groups to queue groups. Core NATS delivers at most
+// once: handler errors are logged, not redelivered, so pair it with the
+// outbox only where losing a message on a crashed consumer is acceptable.
+package natsbroker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/leeforge/framework/events"
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// Header names carrying message fields that have no NATS equivalent.
+// Nats-Msg-Id is also what JetStream uses for de-duplication.
+const (
+	HeaderID        = "Nats-Msg-Id"
+	HeaderKey       = "Events-Key"
+	HeaderTimestamp = "Events-Timestamp"
+)
+
+// Options configures a Broker.
+type Options struct {
+	events.Options
+	// Prefix is prepended to topic names to form subjects, e.g. "app.".
+	Prefix string
+}
+
+// Broker publishes to and subscribes on NATS subjects.
+type Broker struct {
+	conn *nats.Conn
+	opts Options
+
+	mu     sync.Mutex
+	subs   map[*subscription]struct{}
+	closed bool
+}
+
+var _ events.Broker = (*Broker)(nil)
+
+// New creates a Broker. Close stops its subscriptions but not the
+// connection.
+func New(conn *nats.Conn, opts Options) *Broker {
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	return &Broker{conn: conn, opts: opts, subs: make(map[*subscription]struct{})}
+}
+
+// Publish sends msgs. Like core NATS publishing it returns once the
+// messages are buffered on the connection.
+func (b *Broker) Publish(ctx context.Context, msgs ...*events.Message) error {
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+	if closed {
+		return events.ErrClosed
+	}
+
+	for _, msg := range msgs {
+		events.Prepare(ctx, msg)
+		m := nats.NewMsg(b.opts.Prefix + msg.Topic)
+		for k, v := range msg.Headers {
+			m.Header.Set(k, v)
+		}
+		m.Header.Set(HeaderID, msg.ID)
+		m.Header.Set(HeaderTimestamp, msg.Timestamp.UTC().Format(time.RFC3339Nano))
+		if msg.Key != "" {
+			m.Header.Set(HeaderKey, msg.Key)
+		}
+		m.Data = msg.Payload
+		if err := b.conn.PublishMsg(m); err != nil {
+			return fmt.Errorf("natsbroker: publish %s: %w", msg.Topic, err)
+		}
+	}
+	events.RecordPublish(b.opts.Options, msgs...)
+	return nil
+}
+
+// Subscribe subscribes to the topic's subject, as a queue subscription
+// when a group is given. It returns after the server has registered the
+// subscription.
+func (b *Broker) Subscribe(ctx context.Context, topic string, handler events.Handler, opts ...events.SubscribeOption) (events.Subscription, error) {
+	o := events.ApplySubscribeOptions(opts)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, events.ErrClosed
+	}
+
+	subCtx, cancel := context.WithCancel(context.Background())
+	s := &subscription{broker: b, topic: topic, handler: handler, ctx: subCtx, cancel: cancel}
+	subject := b.opts.Prefix + topic
+	var err error
+	if o.Group != "" {
+		s.sub, err = b.conn.QueueSubscribe(subject, o.Group, s.deliver)
+	} else {
+		s.sub, err = b.conn.Subscribe(subject, s.deliver)
+	}
+	if err == nil {
+		err = b.conn.Flush()
+	}
+	if err != nil {
+		cancel()
+		if s.sub != nil {
+			s.sub.Unsubscribe()
+		}
+		return nil, fmt.Errorf("natsbroker: subscribe %s: %w", subject, err)
+	}
+	b.subs[s] = struct{}{}
+	return s, nil
+}
+
+// Close stops all subscriptions and waits for their in-flight handlers.
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	subs := make([]*subscription, 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.Unsubscribe()
+	}
+	return nil
+}
+
+type subscription struct {
+	broker  *Broker
+	topic   string
+	handler events.Handler
+	sub     *nats.Subscription
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu       sync.Mutex
+	stopped  bool
+	inflight sync.WaitGroup
+}
+
+func (s *subscription) deliver(m *nats.Msg) {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.inflight.Add(1)
+	s.mu.Unlock()
+	defer s.inflight.Done()
+
+	_ = events.Dispatch(s.ctx, s.broker.opts.Options, decode(s.topic, m), s.handler)
+}
+
+func (s *subscription) Unsubscribe() error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopped = true
+	s.mu.Unlock()
+
+	err := s.sub.Unsubscribe()
+	s.cancel()
+	s.inflight.Wait()
+
+	s.broker.mu.Lock()
+	delete(s.broker.subs, s)
+	s.broker.mu.Unlock()
+	if err != nil && err != nats.ErrConnectionClosed {
+		return fmt.Errorf("natsbroker: unsubscribe %s: %w", s.topic, err)
+	}
+	return nil
+}
+
+func decode(topic string, m *nats.Msg) *events.Message {
+	msg := &events.Message{
+		Topic:   topic,
+		Payload: m.Data,
+		Headers: make(map[string]string, len(m.Header)),
+	}
+	for k := range m.Header {
+		switch k {
+		case HeaderID:
+			msg.ID = m.Header.Get(k)
+		case HeaderKey:
+			msg.Key = m.Header.Get(k)
+		case HeaderTimestamp:
+			msg.Timestamp, _ = time.Parse(time.RFC3339Nano, m.Header.Get(k))
+		default:
+			msg.Headers[k] = m.Header.Get(k)
+		}
+	}
+	return msg
+}
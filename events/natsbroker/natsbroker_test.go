@@ -0,0 +1,75 @@
+package natsbroker
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/events"
+	"github.com/leeforge/framework/request"
+	"github.com/nats-io/nats.go"
+)
+
+func TestDecode(t *testing.T) {
+	m := nats.NewMsg("app.order.placed")
+	m.Header.Set(HeaderID, "m-1")
+	m.Header.Set(HeaderKey, "order-9")
+	m.Header.Set(HeaderTimestamp, "2026-03-01T10:00:00Z")
+	m.Header.Set("trace.trace_id", "abc")
+	m.Data = []byte(`{}`)
+
+	msg := decode("order.placed", m)
+	if msg.ID != "m-1" || msg.Key != "order-9" || msg.Timestamp.IsZero() {
+		t.Errorf("decoded = %+v", msg)
+	}
+	if msg.Headers["trace.trace_id"] != "abc" || len(msg.Headers) != 1 {
+		t.Errorf("headers = %v, want only propagation headers", msg.Headers)
+	}
+}
+
+func TestBrokerQueueGroup(t *testing.T) {
+	url := strings.TrimSpace(os.Getenv("NATS_TEST_URL"))
+	if url == "" {
+		t.Skip("set NATS_TEST_URL to run nats integration tests")
+	}
+	conn, err := nats.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	b := New(conn, Options{Prefix: "test." + nats.NewInbox()[7:] + "."})
+	defer b.Close()
+	ctx := context.Background()
+
+	got := make(chan string, 10)
+	for i := 0; i < 2; i++ {
+		_, err := b.Subscribe(ctx, "order.placed", func(ctx context.Context, msg *events.Message) error {
+			got <- request.FromContext(ctx).RequestID
+			return nil
+		}, events.WithGroup("billing"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rc := request.NewRequestContext()
+	if err := b.Publish(rc.WithContext(ctx), &events.Message{Topic: "order.placed", Payload: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case id := <-got:
+		if id != rc.RequestID {
+			t.Errorf("request id = %q, want %q", id, rc.RequestID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not delivered")
+	}
+	select {
+	case <-got:
+		t.Error("queue group delivered the message twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,34 @@
+package events
+
+import "context"
+
+// Outbox stores messages in the same transaction as the business change
+// that produced them; a relay later publishes the stored messages to a
+// broker. This gives at-least-once delivery without two-phase commit.
+type Outbox interface {
+	// Save must write through the transaction carried by ctx, so the
+	// messages commit or roll back together with it.
+	Save(ctx context.Context, msgs ...*Message) error
+}
+
+// OutboxPublisher is a Publisher that writes to an Outbox. Inject it where
+// events are published inside transactions.
+type OutboxPublisher struct {
+	outbox Outbox
+}
+
+var _ Publisher = (*OutboxPublisher)(nil)
+
+// NewOutboxPublisher creates an OutboxPublisher.
+func NewOutboxPublisher(outbox Outbox) *OutboxPublisher {
+	return &OutboxPublisher{outbox: outbox}
+}
+
+// Publish prepares msgs, including propagation headers, and saves them to
+// the outbox.
+func (p *OutboxPublisher) Publish(ctx context.Context, msgs ...*Message) error {
+	for _, msg := range msgs {
+		Prepare(ctx, msg)
+	}
+	return p.outbox.Save(ctx, msgs...)
+}
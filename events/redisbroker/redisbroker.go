@@ -0,0 +1,333 @@
+// Package redisbroker implements events.Broker on Redis streams. Each topic
+// is a stream; grouped subscriptions use consumer groups with explicit
+// acknowledgement (at-least-once), ungrouped ones read new entries with
+// XREAD (at-most-once).
+package redisbroker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/leeforge/framework/events"
+	"github.com/leeforge/framework/json"
+	"go.uber.org/zap"
+)
+
+// Options configures a Broker.
+type Options struct {
+	events.Options
+	// Prefix is prepended to topic names to form stream keys
+	// (default "events:").
+	Prefix string
+	// MaxLen caps each stream approximately; zero keeps every entry.
+	MaxLen int64
+	// Consumer names this process within consumer groups (default host-pid).
+	Consumer string
+	// Block is how long a read waits for new entries (default 2s).
+	Block time.Duration
+	// BatchSize is the number of entries read at once (default 16).
+	BatchSize int64
+	// ClaimIdle is how long a delivered but unacknowledged entry may stay
+	// pending before another consumer of the group takes it over
+	// (default 1m).
+	ClaimIdle time.Duration
+}
+
+// Broker publishes to and consumes from Redis streams.
+type Broker struct {
+	client redis.UniversalClient
+	opts   Options
+
+	mu     sync.Mutex
+	subs   map[*subscription]struct{}
+	closed bool
+}
+
+var _ events.Broker = (*Broker)(nil)
+
+// New creates a Broker. Close stops its subscriptions but not the client.
+func New(client redis.UniversalClient, opts Options) *Broker {
+	if opts.Prefix == "" {
+		opts.Prefix = "events:"
+	}
+	if opts.Consumer == "" {
+		host, _ := os.Hostname()
+		opts.Consumer = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if opts.Block <= 0 {
+		opts.Block = 2 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 16
+	}
+	if opts.ClaimIdle <= 0 {
+		opts.ClaimIdle = time.Minute
+	}
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	return &Broker{client: client, opts: opts, subs: make(map[*subscription]struct{})}
+}
+
+// Publish appends msgs to their streams in one pipeline.
+func (b *Broker) Publish(ctx context.Context, msgs ...*events.Message) error {
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+	if closed {
+		return events.ErrClosed
+	}
+
+	pipe := b.client.Pipeline()
+	for _, msg := range msgs {
+		events.Prepare(ctx, msg)
+		headers, err := json.Marshal(msg.Headers)
+		if err != nil {
+			return fmt.Errorf("redisbroker: encode headers: %w", err)
+		}
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: b.opts.Prefix + msg.Topic,
+			MaxLen: b.opts.MaxLen,
+			Approx: b.opts.MaxLen > 0,
+			Values: map[string]interface{}{
+				"id":      msg.ID,
+				"key":     msg.Key,
+				"headers": headers,
+				"payload": msg.Payload,
+				"ts":      msg.Timestamp.UnixMilli(),
+			},
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redisbroker: publish: %w", err)
+	}
+	events.RecordPublish(b.opts.Options, msgs...)
+	return nil
+}
+
+// Subscribe starts consuming topic. With a group the group is created at
+// the end of the stream if it does not exist, so only entries appended
+// afterwards are delivered to a new group.
+func (b *Broker) Subscribe(ctx context.Context, topic string, handler events.Handler, opts ...events.SubscribeOption) (events.Subscription, error) {
+	o := events.ApplySubscribeOptions(opts)
+	stream := b.opts.Prefix + topic
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, events.ErrClosed
+	}
+
+	start := "$"
+	if o.Group != "" {
+		err := b.client.XGroupCreateMkStream(ctx, stream, o.Group, "$").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return nil, fmt.Errorf("redisbroker: create group %s on %s: %w", o.Group, stream, err)
+		}
+	} else {
+		// Resolve "$" now so entries published right after Subscribe
+		// returns are not missed.
+		last, err := b.client.XRevRangeN(ctx, stream, "+", "-", 1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redisbroker: read %s: %w", stream, err)
+		}
+		start = "0-0"
+		if len(last) > 0 {
+			start = last[0].ID
+		}
+	}
+
+	subCtx, cancel := context.WithCancel(context.Background())
+	s := &subscription{broker: b, topic: topic, stream: stream, group: o.Group, handler: handler, cancel: cancel, done: make(chan struct{})}
+	b.subs[s] = struct{}{}
+	go s.run(subCtx, start)
+	return s, nil
+}
+
+// Close stops all subscriptions and waits for their in-flight handlers.
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	subs := make([]*subscription, 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.Unsubscribe()
+	}
+	return nil
+}
+
+type subscription struct {
+	broker  *Broker
+	topic   string
+	stream  string
+	group   string
+	handler events.Handler
+	cancel  context.CancelFunc
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (s *subscription) Unsubscribe() error {
+	s.once.Do(func() {
+		s.cancel()
+		<-s.done
+		s.broker.mu.Lock()
+		delete(s.broker.subs, s)
+		s.broker.mu.Unlock()
+	})
+	return nil
+}
+
+func (s *subscription) run(ctx context.Context, start string) {
+	defer close(s.done)
+	opts := s.broker.opts
+	lastID := start
+	nextClaim := time.Now()
+
+	for ctx.Err() == nil {
+		var (
+			entries []redis.XMessage
+			err     error
+		)
+		if s.group != "" {
+			if time.Now().After(nextClaim) {
+				entries, err = s.claim(ctx)
+				nextClaim = time.Now().Add(opts.ClaimIdle / 2)
+			}
+			if err == nil && len(entries) == 0 {
+				entries, err = s.readGroup(ctx)
+			}
+		} else {
+			entries, err = s.read(ctx, lastID)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			opts.Logger.Warn("redisbroker: read failed", zap.String("stream", s.stream), zap.Error(err))
+			sleep(ctx, time.Second)
+			continue
+		}
+
+		for _, entry := range entries {
+			lastID = entry.ID
+			msg := decode(s.topic, entry)
+			if err := events.Dispatch(ctx, opts.Options, msg, s.handler); err != nil || s.group == "" {
+				// left pending for redelivery, or nothing to acknowledge
+				continue
+			}
+			if err := s.broker.client.XAck(context.Background(), s.stream, s.group, entry.ID).Err(); err != nil {
+				opts.Logger.Warn("redisbroker: ack failed", zap.String("stream", s.stream), zap.String("entry", entry.ID), zap.Error(err))
+			}
+		}
+	}
+}
+
+func (s *subscription) readGroup(ctx context.Context) ([]redis.XMessage, error) {
+	opts := s.broker.opts
+	streams, err := s.broker.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    s.group,
+		Consumer: opts.Consumer,
+		Streams:  []string{s.stream, ">"},
+		Count:    opts.BatchSize,
+		Block:    opts.Block,
+	}).Result()
+	return firstStream(streams, err)
+}
+
+func (s *subscription) read(ctx context.Context, lastID string) ([]redis.XMessage, error) {
+	opts := s.broker.opts
+	streams, err := s.broker.client.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{s.stream, lastID},
+		Count:   opts.BatchSize,
+		Block:   opts.Block,
+	}).Result()
+	return firstStream(streams, err)
+}
+
+// claim takes over entries another consumer (possibly a crashed one, or
+// this one after a handler error) left pending for longer than ClaimIdle.
+// XPENDING + XCLAIM is used rather than XAUTOCLAIM, whose reply changed
+// shape in Redis 7.
+func (s *subscription) claim(ctx context.Context) ([]redis.XMessage, error) {
+	opts := s.broker.opts
+	pending, err := s.broker.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: s.stream,
+		Group:  s.group,
+		Idle:   opts.ClaimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  opts.BatchSize,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return nil, err
+	}
+	ids := make([]string, len(pending))
+	for i, p := range pending {
+		ids[i] = p.ID
+	}
+	return s.broker.client.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   s.stream,
+		Group:    s.group,
+		Consumer: opts.Consumer,
+		MinIdle:  opts.ClaimIdle,
+		Messages: ids,
+	}).Result()
+}
+
+func firstStream(streams []redis.XStream, err error) ([]redis.XMessage, error) {
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil || len(streams) == 0 {
+		return nil, err
+	}
+	return streams[0].Messages, nil
+}
+
+func decode(topic string, entry redis.XMessage) *events.Message {
+	msg := &events.Message{
+		ID:      field(entry, "id"),
+		Topic:   topic,
+		Key:     field(entry, "key"),
+		Payload: []byte(field(entry, "payload")),
+		Headers: make(map[string]string),
+	}
+	if raw := field(entry, "headers"); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &msg.Headers)
+	}
+	if ms, err := strconv.ParseInt(field(entry, "ts"), 10, 64); err == nil {
+		msg.Timestamp = time.UnixMilli(ms)
+	}
+	if msg.ID == "" {
+		msg.ID = entry.ID
+	}
+	return msg
+}
+
+func field(entry redis.XMessage, name string) string {
+	if v, ok := entry.Values[name].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
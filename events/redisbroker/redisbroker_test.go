@@ -0,0 +1,136 @@
+package redisbroker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/leeforge/framework/events"
+	"github.com/leeforge/framework/request"
+)
+
+type orderPlaced struct {
+	ID string `json:"id"`
+}
+
+var orders = events.NewTopic[orderPlaced]("order.placed")
+
+func newBroker(t *testing.T, opts Options) (*Broker, *redis.Client) {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	opts.Block = 50 * time.Millisecond
+	b := New(client, opts)
+	t.Cleanup(func() { b.Close() })
+	return b, client
+}
+
+func TestBrokerGroupDeliversOnceWithPropagation(t *testing.T) {
+	b, client := newBroker(t, Options{})
+	other := New(client, Options{Consumer: "other", Block: 50 * time.Millisecond})
+	defer other.Close()
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	got := make(map[string]int)
+	var requestIDs []string
+	handler := func(ctx context.Context, e orderPlaced) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got[e.ID]++
+		requestIDs = append(requestIDs, request.FromContext(ctx).RequestID)
+		return nil
+	}
+	for _, s := range []events.Subscriber{b, other} {
+		if _, err := orders.Subscribe(ctx, s, handler, events.WithGroup("billing")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rc := request.NewRequestContext()
+	pubCtx := rc.WithContext(ctx)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		if err := orders.Publish(pubCtx, b, orderPlaced{ID: id}, events.WithKey(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 4
+	})
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	for id, n := range got {
+		if n != 1 {
+			t.Errorf("order %s handled %d times within the group", id, n)
+		}
+	}
+	for _, id := range requestIDs {
+		if id != rc.RequestID {
+			t.Errorf("request id = %q, want %q", id, rc.RequestID)
+		}
+	}
+	if n := client.XPending(ctx, "events:order.placed", "billing").Val().Count; n != 0 {
+		t.Errorf("%d entries left pending", n)
+	}
+}
+
+func TestBrokerRedeliversFailedEntries(t *testing.T) {
+	b, _ := newBroker(t, Options{ClaimIdle: 50 * time.Millisecond})
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	_, err := b.Subscribe(ctx, "mail", func(context.Context, *events.Message) error {
+		if attempts.Add(1) == 1 {
+			return errors.New("smtp down")
+		}
+		return nil
+	}, events.WithGroup("mailer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Publish(ctx, &events.Message{Topic: "mail", Payload: []byte("hi")}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return attempts.Load() >= 2 })
+}
+
+func TestBrokerBroadcastWithoutGroup(t *testing.T) {
+	b, _ := newBroker(t, Options{})
+	ctx := context.Background()
+
+	// 订阅前的消息不会投递
+	b.Publish(ctx, &events.Message{Topic: "cache", Payload: []byte("old")})
+
+	var a, c atomic.Int32
+	sa, _ := b.Subscribe(ctx, "cache", func(context.Context, *events.Message) error { a.Add(1); return nil })
+	b.Subscribe(ctx, "cache", func(context.Context, *events.Message) error { c.Add(1); return nil })
+	b.Publish(ctx, &events.Message{Topic: "cache", Payload: []byte("new")})
+
+	waitFor(t, func() bool { return a.Load() == 1 && c.Load() == 1 })
+	sa.Unsubscribe()
+	b.Close()
+	if err := b.Publish(ctx, &events.Message{Topic: "cache"}); !errors.Is(err, events.ErrClosed) {
+		t.Errorf("Publish after Close err = %v", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leeforge/framework/json"
+)
+
+// Topic binds a topic name to its event type so publishers and subscribers
+// cannot disagree on the payload. Events are encoded as JSON.
+//
+//	var UserCreated = events.NewTopic[UserCreatedEvent]("user.created")
+//
+//	UserCreated.Publish(ctx, bus, UserCreatedEvent{ID: id})
+//	UserCreated.Subscribe(ctx, bus, func(ctx context.Context, e UserCreatedEvent) error { ... })
+type Topic[T any] struct {
+	name string
+}
+
+// NewTopic creates a typed topic.
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{name: name}
+}
+
+// Name returns the topic name.
+func (t Topic[T]) Name() string { return t.name }
+
+// PublishOption configures a typed publish.
+type PublishOption func(*Message)
+
+// WithKey sets the ordering/partition key.
+func WithKey(key string) PublishOption {
+	return func(m *Message) { m.Key = key }
+}
+
+// WithHeader sets a message header.
+func WithHeader(key, value string) PublishOption {
+	return func(m *Message) {
+		if m.Headers == nil {
+			m.Headers = make(map[string]string)
+		}
+		m.Headers[key] = value
+	}
+}
+
+// Message encodes event into a message for the topic.
+func (t Topic[T]) Message(event T, opts ...PublishOption) (*Message, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("events: encode %s: %w", t.name, err)
+	}
+	msg := &Message{Topic: t.name, Payload: payload}
+	for _, opt := range opts {
+		opt(msg)
+	}
+	return msg, nil
+}
+
+// Publish encodes and publishes event.
+func (t Topic[T]) Publish(ctx context.Context, p Publisher, event T, opts ...PublishOption) error {
+	msg, err := t.Message(event, opts...)
+	if err != nil {
+		return err
+	}
+	return p.Publish(ctx, msg)
+}
+
+// Subscribe decodes messages of the topic and passes them to handler. The
+// raw message is available through MessageFromContext. Payloads that do not
+// decode are dropped as ErrMalformed.
+func (t Topic[T]) Subscribe(ctx context.Context, s Subscriber, handler func(ctx context.Context, event T) error, opts ...SubscribeOption) (Subscription, error) {
+	return s.Subscribe(ctx, t.name, func(ctx context.Context, msg *Message) error {
+		var event T
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			return fmt.Errorf("%w: decode %s: %v", ErrMalformed, t.name, err)
+		}
+		return handler(ctx, event)
+	}, opts...)
+}
@@ -0,0 +1,67 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/leeforge/framework/cli"
+	"github.com/spf13/pflag"
+)
+
+// Command returns the gen command for a cli.CLI, printing the written files
+// to out (os.Stdout when nil).
+func Command(out io.Writer) *cli.Command {
+	if out == nil {
+		out = os.Stdout
+	}
+	flags := pflag.NewFlagSet("gen", pflag.ContinueOnError)
+	typeName := flags.String("type", "", "schema or struct to generate for when the file declares several")
+	dir := flags.String("out", "", "output directory (default: next to a struct; required for an ent schema)")
+	pkg := flags.String("package", "", "package name of the generated files (default: from the output directory)")
+	entPkg := flags.String("ent", "", "import path of the ent generated code, e.g. example.com/shop/ent")
+	resource := flags.String("resource", "", "plural used in permission codes (default: from the type name)")
+	force := flags.Bool("force", false, "overwrite existing files")
+
+	return &cli.Command{
+		Name:  "gen",
+		Usage: "FILE",
+		Short: "Generate request types, repository, handler, permissions and tests from an ent schema or struct",
+		Flags: flags,
+		Run: func(_ context.Context, args []string) error {
+			if len(args) != 1 {
+				return errors.New("gen: expected one source file")
+			}
+			opts := Options{
+				Source:     args[0],
+				Type:       *typeName,
+				Dir:        *dir,
+				Package:    *pkg,
+				EntPackage: *entPkg,
+				Resource:   *resource,
+				Force:      *force,
+			}
+			e, err := ParseFile(opts.Source, opts.Type)
+			if err != nil {
+				return err
+			}
+			files, err := Render(e, opts)
+			if err != nil {
+				return err
+			}
+			paths, err := WriteFiles(outputDir(opts), files, opts.Force)
+			if err != nil {
+				return err
+			}
+			for _, p := range paths {
+				fmt.Fprintln(out, "wrote", p)
+			}
+			for _, column := range e.Skipped {
+				fmt.Fprintf(out, "skipped field %s: its type is declared in the schema package\n", column)
+			}
+			return nil
+		},
+	}
+}
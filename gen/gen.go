@@ -0,0 +1,202 @@
+// Package gen scaffolds the CRUD layer of a domain plugin from an ent schema
+// or a plain struct: request and response types with binding, sanitize and
+// validate tags, a repository over the ent client, a handler built on
+// httpx.Handler with its routes registered through permission, the
+// permission codes, and a handler test against an in-memory repository.
+//
+//	framework gen --ent example.com/shop/ent --out internal/orders ent/schema/order.go
+//
+// The generated code is a starting point to edit, not a layer to regenerate:
+// Write refuses to overwrite files unless Options.Force is set. For a struct,
+// the files are generated next to it and the caller supplies the repository.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Options configures Generate.
+type Options struct {
+	// Source is the Go file declaring the ent schema or struct.
+	Source string
+	// Type selects the schema or struct when Source declares several.
+	Type string
+	// Dir is the output directory. Defaults to the directory of Source for
+	// a struct; required for an ent schema, as the schema package only holds
+	// schemas.
+	Dir string
+	// Package names the generated package. Defaults to the source package
+	// for a struct generated next to it, else to the base name of Dir.
+	Package string
+	// EntPackage is the import path of the ent generated code, e.g.
+	// "example.com/shop/ent"; required for an ent schema.
+	EntPackage string
+	// Resource is the plural used in permission codes, e.g. "order_items"
+	// for "order_items:read". Defaults to the plural of the snake_case type
+	// name.
+	Resource string
+	// Force overwrites existing files.
+	Force bool
+}
+
+// File is a generated file.
+type File struct {
+	// Name is the file name within the output directory.
+	Name string
+	// Content is the gofmt-ed source.
+	Content []byte
+}
+
+// Generate parses opts.Source and renders the files for its entity.
+func Generate(opts Options) ([]File, error) {
+	e, err := ParseFile(opts.Source, opts.Type)
+	if err != nil {
+		return nil, err
+	}
+	return Render(e, opts)
+}
+
+// Write generates the files and writes them to the output directory,
+// returning their paths.
+func Write(opts Options) ([]string, error) {
+	files, err := Generate(opts)
+	if err != nil {
+		return nil, err
+	}
+	return WriteFiles(outputDir(opts), files, opts.Force)
+}
+
+// WriteFiles writes files to dir. It writes nothing when one of them exists
+// and force is unset.
+func WriteFiles(dir string, files []File, force bool) ([]string, error) {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = filepath.Join(dir, f.Name)
+		if _, err := os.Stat(paths[i]); err == nil && !force {
+			return nil, fmt.Errorf("%s exists; use --force to overwrite", paths[i])
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	for i, f := range files {
+		if err := os.WriteFile(paths[i], f.Content, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// outputDir is opts.Dir or, for a struct, the directory of opts.Source.
+// Render rejects an ent schema without Dir before files are written.
+func outputDir(opts Options) string {
+	if opts.Dir != "" {
+		return opts.Dir
+	}
+	return filepath.Dir(opts.Source)
+}
+
+// Render renders the files for e. opts.Source is not read.
+func Render(e *Entity, opts Options) ([]File, error) {
+	v, err := newView(e, opts)
+	if err != nil {
+		return nil, err
+	}
+	prefix := snake(e.Name)
+	files := []struct {
+		name string
+		tmpl *template.Template
+	}{
+		{prefix + "_types.go", typesTmpl},
+		{prefix + "_repository.go", repositoryTmpl},
+		{prefix + "_handler.go", handlerTmpl},
+		{prefix + "_permissions.go", permissionsTmpl},
+		{prefix + "_handler_test.go", testTmpl},
+	}
+
+	var out []File
+	for _, f := range files {
+		if f.tmpl == repositoryTmpl && !e.Ent {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := f.tmpl.Execute(&buf, v); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		src, err := finish(f.name, buf.Bytes(), v.imports)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, File{Name: f.name, Content: src})
+	}
+	return out, nil
+}
+
+// importSpec is an import the generated files may use, by the name they
+// refer to it with.
+type importSpec struct {
+	name, path string
+	alias      bool
+}
+
+// finish adds the imports src uses and formats it.
+func finish(name string, src []byte, imports []importSpec) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("%s: generated invalid code: %w", name, err)
+	}
+	used := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+
+	var std, other []string
+	for _, imp := range imports {
+		if !used[imp.name] {
+			continue
+		}
+		used[imp.name] = false
+		line := strconv.Quote(imp.path)
+		if imp.alias {
+			line = imp.name + " " + line
+		}
+		if strings.Contains(strings.Split(imp.path, "/")[0], ".") {
+			other = append(other, line)
+		} else {
+			std = append(std, line)
+		}
+	}
+	slices.Sort(std)
+	slices.Sort(other)
+
+	var block string
+	switch {
+	case len(std) > 0 && len(other) > 0:
+		block = strings.Join(std, "\n") + "\n\n" + strings.Join(other, "\n")
+	default:
+		block = strings.Join(append(std, other...), "\n")
+	}
+	src = bytes.Replace(src, []byte("import ()"), []byte("import (\n"+block+"\n)"), 1)
+	formatted, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: generated invalid code: %w", name, err)
+	}
+	return formatted, nil
+}
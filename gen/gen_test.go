@@ -0,0 +1,309 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/cli"
+	"github.com/stretchr/testify/require"
+)
+
+const orderSchema = `package schema
+
+import (
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/mixin"
+)
+
+type Address struct{ City string }
+
+type Order struct {
+	ent.Schema
+}
+
+func (Order) Mixin() []ent.Mixin {
+	return []ent.Mixin{mixin.Time{}}
+}
+
+func (Order) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("customer_email").NotEmpty().MaxLen(120),
+		field.Enum("status").Values("pending", "paid").Default("pending"),
+		field.Enum("channel").Values("web", "store").Optional().Nillable(),
+		field.Int("quantity").Positive(),
+		field.String("note").Optional().Nillable().StructTag(` + "`json:\"remark\"`" + `),
+		field.String("token").Sensitive().Default(""),
+		field.Time("due_at").Default(time.Now).Immutable(),
+		field.JSON("tags", []string{}).Optional(),
+		field.JSON("address", Address{}).Optional(),
+	}
+}
+
+type Other struct {
+	ent.Schema
+}
+`
+
+const articleStruct = `package blog
+
+import "time"
+
+type Article struct {
+	ID          int64
+	Title       string    ` + "`json:\"title\" validate:\"required,max=120\"`" + `
+	Body        *string
+	PublishedAt time.Time
+	Secret      string ` + "`json:\"-\"`" + `
+	draft       bool
+}
+`
+
+func TestNames(t *testing.T) {
+	require.Equal(t, "PreviewURL", pascal("preview_url"))
+	require.Equal(t, "previewUrl", lowerCamel("preview_url"))
+	require.Equal(t, "order_item", snake("OrderItem"))
+	require.Equal(t, "preview_url", snake("PreviewURL"))
+	require.Equal(t, "categories", plural("category"))
+	require.Equal(t, "addresses", plural("address"))
+	require.Equal(t, "orders", plural("order"))
+}
+
+func TestParseSchema(t *testing.T) {
+	_, err := Parse("order.go", []byte(orderSchema), "")
+	require.ErrorContains(t, err, "several types (Order, Other)")
+
+	e, err := Parse("order.go", []byte(orderSchema), "Order")
+	require.NoError(t, err)
+	require.True(t, e.Ent)
+	require.Equal(t, "int", e.IDType)
+	require.Equal(t, []string{"address"}, e.Skipped)
+
+	byColumn := map[string]*Field{}
+	var columns []string
+	for _, f := range e.Fields {
+		byColumn[f.Column] = f
+		columns = append(columns, f.Column)
+	}
+	require.Equal(t, []string{"customer_email", "status", "channel", "quantity", "note", "token", "due_at", "tags", "create_time", "update_time"}, columns)
+
+	require.True(t, byColumn["customer_email"].Required)
+	require.Equal(t, []string{"max=120"}, byColumn["customer_email"].Rules)
+	require.Equal(t, []string{"pending", "paid"}, byColumn["status"].Enum)
+	require.Equal(t, "pending", byColumn["status"].Default)
+	require.True(t, byColumn["channel"].Nillable)
+	require.Equal(t, []string{"gt=0"}, byColumn["quantity"].Rules)
+	require.Equal(t, "remark", byColumn["note"].JSON)
+	require.True(t, byColumn["token"].Sensitive)
+	require.True(t, byColumn["due_at"].HasDefault)
+	require.Empty(t, byColumn["due_at"].Default)
+	require.True(t, byColumn["due_at"].Immutable)
+	require.Equal(t, "[]string", byColumn["tags"].Type)
+	require.True(t, byColumn["update_time"].ReadOnly)
+}
+
+func TestParseMixins(t *testing.T) {
+	src := `package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"github.com/leeforge/framework/entities"
+)
+
+type Invoice struct {
+	ent.Schema
+}
+
+func (Invoice) Mixin() []ent.Mixin {
+	return []ent.Mixin{entities.TenantEntitySchema{}, Numbered{}}
+}
+
+func (Invoice) Fields() []ent.Field {
+	return []ent.Field{field.Float("total")}
+}
+
+type Numbered struct {
+	mixin.Schema
+}
+
+func (Numbered) Fields() []ent.Field {
+	return []ent.Field{field.String("number").NotEmpty()}
+}
+`
+	e, err := Parse("invoice.go", []byte(src), "Invoice")
+	require.NoError(t, err)
+	require.Equal(t, "uuid.UUID", e.IDType)
+	var columns []string
+	for _, f := range e.Fields {
+		columns = append(columns, f.Column)
+	}
+	require.Equal(t, []string{"total", "created_at", "updated_at", "tenant_id", "number"}, columns)
+	require.True(t, e.Fields[3].ReadOnly)
+	require.False(t, e.Fields[4].ReadOnly)
+}
+
+func TestParseStruct(t *testing.T) {
+	e, err := Parse("article.go", []byte(articleStruct), "")
+	require.NoError(t, err)
+	require.False(t, e.Ent)
+	require.Equal(t, "blog", e.Package)
+	require.Equal(t, "int64", e.IDType)
+	require.Len(t, e.Fields, 3)
+	require.Equal(t, "title", e.Fields[0].JSON)
+	require.True(t, e.Fields[0].Required)
+	require.Equal(t, []string{"max=120"}, e.Fields[0].Rules)
+	require.True(t, e.Fields[1].Nillable)
+	require.Equal(t, "publishedAt", e.Fields[2].JSON)
+
+	_, err = Parse("x.go", []byte("package x\n\ntype Point struct{ X int }\n"), "")
+	require.ErrorContains(t, err, "no ID field")
+}
+
+// files renders e and returns the generated sources by name, with runs of
+// white space collapsed so assertions do not depend on alignment.
+func files(t *testing.T, e *Entity, opts Options) map[string]string {
+	t.Helper()
+	out, err := Render(e, opts)
+	require.NoError(t, err)
+	m := map[string]string{}
+	for _, f := range out {
+		m[f.Name] = strings.Join(strings.Fields(string(f.Content)), " ")
+	}
+	return m
+}
+
+func TestRenderSchema(t *testing.T) {
+	e, err := Parse("order.go", []byte(orderSchema), "Order")
+	require.NoError(t, err)
+
+	_, err = Render(e, Options{Dir: "internal/orders"})
+	require.ErrorContains(t, err, "set the ent package import path")
+
+	src := files(t, e, Options{Dir: "internal/orders", EntPackage: "example.com/shop/ent"})
+	require.Len(t, src, 5)
+
+	types := src["order_types.go"]
+	require.True(t, strings.HasPrefix(types, "package orders "))
+	require.Contains(t, types, "CustomerEmail string `json:\"customerEmail\" mod:\"trim\" validate:\"required,max=120\"`")
+	require.Contains(t, types, "Status string `json:\"status\" default:\"pending\" validate:\"oneof=pending paid\"`")
+	require.Contains(t, types, "Channel *string `json:\"channel,omitempty\" validate:\"omitempty,oneof=web store\"`")
+	require.Contains(t, types, "DueAt *time.Time")
+	require.Contains(t, types, "ID int `path:\"id\" json:\"-\"`")
+	require.NotContains(t, types, "Token: e.Token")
+	require.Contains(t, types, "Status: string(e.Status),")
+	require.Contains(t, types, "r.Channel = &value")
+
+	repository := src["order_repository.go"]
+	require.Contains(t, repository, `"example.com/shop/ent"`)
+	require.Contains(t, repository, `WithColumns("id", "customer_email", "status", "channel", "quantity", "note", "due_at", "create_time", "update_time")`)
+	require.Contains(t, repository, "SetNillableChannel(e.Channel)")
+	require.Contains(t, repository, "if !e.DueAt.IsZero() { c.SetDueAt(e.DueAt)")
+	require.NotContains(t, repository, "SetDueAt(e.DueAt).")
+
+	handler := src["order_handler.go"]
+	require.Contains(t, handler, `fwent "github.com/leeforge/framework/ent"`)
+	require.Contains(t, handler, `"example.com/shop/ent/order"`)
+	require.Contains(t, handler, "Status: order.Status(req.Status),")
+	require.Contains(t, handler, `permission.Patch(r, "/{id}", httpx.Handler(h.Update), permission.Private("Update order", PermOrderWrite))`)
+	require.Contains(t, handler, `errors.NewNotFound("order", id)`)
+
+	require.Contains(t, src["order_permissions.go"], `PermOrderDelete = "orders:delete"`)
+	test := src["order_handler_test.go"]
+	require.Contains(t, test, "`{\"customerEmail\":\"example\",\"status\":\"pending\",\"quantity\":1,\"tags\":[\"example\"]}`")
+	require.Contains(t, test, `require.Equal(t, "updated", updated.CustomerEmail)`)
+	require.Contains(t, test, "http.StatusUnprocessableEntity")
+	require.Contains(t, test, "e.ID = m.seq")
+
+	src = files(t, e, Options{Dir: "internal/orders", EntPackage: "github.com/leeforge/framework/ent", Resource: "sales_orders"})
+	require.Contains(t, src["order_handler.go"], "ent.NewPagination(req.Page, req.PerPage)")
+	require.NotContains(t, src["order_handler.go"], "fwent")
+	require.Contains(t, src["order_permissions.go"], `PermOrderRead = "sales_orders:read"`)
+}
+
+func TestSampleUUID(t *testing.T) {
+	for rules, version := range map[string]uuid.Version{"uuid4": 4, "uuid": 4, "uuid7": 7} {
+		s, ok := sampleString([]string{"required", rules}, "example")
+		require.True(t, ok)
+		require.Equal(t, version, uuid.MustParse(s).Version(), rules)
+	}
+	s, ok := sample(&Field{Type: "uuid.UUID", Rules: []string{"uuid4"}})
+	require.True(t, ok)
+	require.Equal(t, uuid.Version(4), uuid.MustParse(s[1:len(s)-1]).Version())
+}
+
+func TestRenderStruct(t *testing.T) {
+	e, err := Parse("article.go", []byte(articleStruct), "")
+	require.NoError(t, err)
+	src := files(t, e, Options{Source: "blog/article.go"})
+
+	require.NotContains(t, src, "article_repository.go")
+	require.True(t, strings.HasPrefix(src["article_types.go"], "package blog "))
+	require.Contains(t, src["article_handler.go"], "repo.Repository[*Article, int64]")
+	require.Contains(t, src["article_handler.go"], "e := &Article{")
+	require.Contains(t, src["article_handler_test.go"], "e.ID = int64(m.seq)")
+}
+
+const ticketStruct = `package support
+
+import "github.com/google/uuid"
+
+type Ticket struct {
+	ID       int64
+	TenantID string    ` + "`json:\"tenantId\" validate:\"required,uuid4\"`" + `
+	OwnerID  uuid.UUID ` + "`json:\"ownerId\" validate:\"uuid4\"`" + `
+	Subject  string    ` + "`json:\"subject\" validate:\"required,min=3,max=80\"`" + `
+}
+`
+
+// TestGeneratedPackageBuildsAndPasses generates a package inside the module,
+// so it resolves the framework imports, then vets it and runs its tests.
+func TestGeneratedPackageBuildsAndPasses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and tests the generated package")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	// The leading underscore keeps the package out of ./... patterns.
+	dir, err := os.MkdirTemp(".", "_generated")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	source := filepath.Join(dir, "ticket.go")
+	require.NoError(t, os.WriteFile(source, []byte(ticketStruct), 0o644))
+
+	_, err = Write(Options{Source: source})
+	require.NoError(t, err)
+
+	for _, args := range [][]string{{"vet", "./" + dir}, {"test", "-count=1", "./" + dir}} {
+		out, err := exec.Command(goTool, args...).CombinedOutput()
+		require.NoError(t, err, "go %s:\n%s", strings.Join(args, " "), out)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "article.go")
+	require.NoError(t, os.WriteFile(source, []byte(articleStruct), 0o644))
+
+	out := &bytes.Buffer{}
+	tool := cli.NewTool("framework", "", Command(out))
+	ctx := context.Background()
+	require.NoError(t, tool.Execute(ctx, []string{"gen", source}))
+	require.Equal(t, 4, strings.Count(out.String(), "wrote "))
+	_, err := os.Stat(filepath.Join(dir, "article_handler.go"))
+	require.NoError(t, err)
+
+	require.ErrorContains(t, tool.Execute(ctx, []string{"gen", source}), "exists; use --force")
+	require.NoError(t, tool.Execute(ctx, []string{"gen", "--force", source}))
+	require.ErrorContains(t, tool.Execute(ctx, []string{"gen"}), "expected one source file")
+}
@@ -0,0 +1,89 @@
+package gen
+
+import (
+	"strings"
+	"unicode"
+)
+
+// initialisms are the words ent writes in upper case in generated names.
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "AWS": true, "CPU": true, "CSS": true,
+	"DNS": true, "EOF": true, "GB": true, "GUID": true, "HCL": true, "HTML": true,
+	"HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "KB": true,
+	"LHS": true, "MAC": true, "MB": true, "QPS": true, "RAM": true, "RHS": true,
+	"RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "SSO": true,
+	"TCP": true, "TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true,
+	"URI": true, "URL": true, "UTF8": true, "UUID": true, "VM": true, "XML": true,
+	"XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// pascal converts a snake_case column to the Go field name ent generates,
+// e.g. "preview_url" to "PreviewURL".
+func pascal(s string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(s, "_") {
+		if word == "" {
+			continue
+		}
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+// lowerCamel converts a snake_case column to a JSON name, e.g.
+// "preview_url" to "previewUrl", like entities.IDField does.
+func lowerCamel(s string) string {
+	var b strings.Builder
+	for i, word := range strings.Split(s, "_") {
+		if word == "" {
+			continue
+		}
+		word = strings.ToLower(word)
+		if i > 0 {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+// snake converts a Go name to snake_case, keeping initialisms together,
+// e.g. "OrderItem" to "order_item" and "PreviewURL" to "preview_url".
+func snake(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// plural returns the English plural of a lower-case word.
+func plural(s string) string {
+	switch {
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsRune("aeiou", rune(s[len(s)-2])):
+		return s[:len(s)-1] + "ies"
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "z"),
+		strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	default:
+		return s + "s"
+	}
+}
+
+// words turns a snake_case name into space separated words for route
+// descriptions, e.g. "order_item" to "order item".
+func words(s string) string {
+	return strings.ReplaceAll(s, "_", " ")
+}
@@ -0,0 +1,567 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Entity is the model code is generated for, read from an ent schema or a
+// plain struct.
+type Entity struct {
+	// Name is the Go type name, e.g. "OrderItem".
+	Name string
+	// Package is the package of the source file.
+	Package string
+	// Ent reports whether the source is an ent schema; the model is then the
+	// ent generated type and a repository is generated.
+	Ent bool
+	// IDType is the Go type of the ID, e.g. "uuid.UUID" or "int".
+	IDType string
+	// Fields are the model fields besides the ID, in declaration order.
+	Fields []*Field
+	// Skipped lists fields whose type generated code cannot refer to.
+	Skipped []string
+
+	idImports []string
+}
+
+// Field is a model field.
+type Field struct {
+	// Name is the Go field name, e.g. "PreviewURL".
+	Name string
+	// Column is the snake_case name, e.g. "preview_url".
+	Column string
+	// JSON is the name in requests and responses, e.g. "previewUrl".
+	JSON string
+	// Type is the Go type in requests and responses, e.g. "time.Time".
+	// Enums are strings there.
+	Type string
+	// Enum lists the values of an ent enum field.
+	Enum []string
+	// Nillable reports whether the model field is a pointer.
+	Nillable bool
+	// Optional fields may be left out on create.
+	Optional bool
+	// Immutable fields are set on create only.
+	Immutable bool
+	// Sensitive fields are never returned in responses.
+	Sensitive bool
+	// ReadOnly fields are maintained by the database or a mixin and never
+	// set from requests.
+	ReadOnly bool
+	// Required fields must be present and non-zero on create.
+	Required bool
+	// HasDefault reports a schema default, literal or computed.
+	HasDefault bool
+	// Default is the literal default as a `default` tag value, or "".
+	Default string
+	// Rules are the validate rules besides required and omitempty.
+	Rules []string
+
+	imports []string
+}
+
+// ParseFile reads the ent schema or struct called typeName from the Go file
+// filename. typeName may be empty when the file declares a single ent schema
+// or, without schemas, a single exported struct.
+func ParseFile(filename, typeName string) (*Entity, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(filename, src, typeName)
+}
+
+// Parse is ParseFile for source already in memory; filename is used in
+// errors only.
+func Parse(filename string, src []byte, typeName string) (*Entity, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	p := &fileParser{fset: fset, file: file, imports: map[string]string{}}
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		p.imports[name] = importPath
+	}
+
+	var schemas, structs []*ast.TypeSpec
+	var named *ast.TypeSpec
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			if ts.Name.Name == typeName {
+				named = ts
+			}
+			switch {
+			case p.isSchema(st):
+				schemas = append(schemas, ts)
+			case ts.Name.IsExported():
+				structs = append(structs, ts)
+			}
+		}
+	}
+
+	spec := named
+	if typeName == "" {
+		candidates := schemas
+		if len(candidates) == 0 {
+			candidates = structs
+		}
+		switch len(candidates) {
+		case 0:
+			return nil, fmt.Errorf("%s: no ent schema or exported struct", filename)
+		case 1:
+			spec = candidates[0]
+		default:
+			names := make([]string, len(candidates))
+			for i, c := range candidates {
+				names[i] = c.Name.Name
+			}
+			return nil, fmt.Errorf("%s: several types (%s); choose one with --type", filename, strings.Join(names, ", "))
+		}
+	}
+	if spec == nil {
+		return nil, fmt.Errorf("%s: struct %s not found", filename, typeName)
+	}
+
+	e := &Entity{Name: spec.Name.Name, Package: file.Name.Name}
+	st := spec.Type.(*ast.StructType)
+	if p.isSchema(st) {
+		e.Ent = true
+		err = p.parseSchema(e)
+	} else {
+		err = p.parseStruct(e, st)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", filename, e.Name, err)
+	}
+	return e, nil
+}
+
+type fileParser struct {
+	fset    *token.FileSet
+	file    *ast.File
+	imports map[string]string // name -> import path
+}
+
+// isSchema reports whether st embeds ent.Schema.
+func (p *fileParser) isSchema(st *ast.StructType) bool {
+	for _, f := range st.Fields.List {
+		if len(f.Names) > 0 {
+			continue
+		}
+		if sel, ok := f.Type.(*ast.SelectorExpr); ok && sel.Sel.Name == "Schema" {
+			if id, ok := sel.X.(*ast.Ident); ok && p.imports[id.Name] == "entgo.io/ent" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *fileParser) print(expr ast.Expr) string {
+	var buf bytes.Buffer
+	_ = printer.Fprint(&buf, p.fset, expr)
+	return buf.String()
+}
+
+// typeImports returns the import paths the type expression refers to, and
+// false when it refers to a type declared in the source package.
+func (p *fileParser) typeImports(expr ast.Expr) ([]string, bool) {
+	var paths []string
+	local := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if id, ok := n.X.(*ast.Ident); ok {
+				if path, ok := p.imports[id.Name]; ok && !slices.Contains(paths, path) {
+					paths = append(paths, path)
+				}
+			}
+			return false
+		case *ast.Ident:
+			if n.Obj != nil || n.IsExported() {
+				local = true
+			}
+		}
+		return true
+	})
+	return paths, !local
+}
+
+// method returns the method called name declared on the type typeName.
+func (p *fileParser) method(typeName, name string) *ast.FuncDecl {
+	for _, decl := range p.file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv == nil || fd.Name.Name != name || len(fd.Recv.List) != 1 {
+			continue
+		}
+		recv := fd.Recv.List[0].Type
+		if star, ok := recv.(*ast.StarExpr); ok {
+			recv = star.X
+		}
+		if id, ok := recv.(*ast.Ident); ok && id.Name == typeName {
+			return fd
+		}
+	}
+	return nil
+}
+
+// returnedList returns the elements of the slice literal method returns.
+func (p *fileParser) returnedList(fd *ast.FuncDecl) ([]ast.Expr, error) {
+	if fd == nil || fd.Body == nil {
+		return nil, nil
+	}
+	for _, stmt := range fd.Body.List {
+		ret, ok := stmt.(*ast.ReturnStmt)
+		if !ok || len(ret.Results) != 1 {
+			continue
+		}
+		lit, ok := ret.Results[0].(*ast.CompositeLit)
+		if !ok {
+			return nil, fmt.Errorf("%s must return a slice literal", fd.Name.Name)
+		}
+		return lit.Elts, nil
+	}
+	return nil, fmt.Errorf("%s must return a slice literal", fd.Name.Name)
+}
+
+// knownMixin describes a mixin declared outside the schema file.
+type knownMixin struct {
+	// uuid reports that the mixin declares a UUID "id" field.
+	uuid bool
+	// fields are the mixin fields responses expose; the rest is bookkeeping.
+	fields []*Field
+}
+
+// knownMixins are the mixins of ent/schema, entities and
+// entgo.io/ent/schema/mixin, by type name qualified with the package name
+// for imported ones.
+var knownMixins = map[string]knownMixin{
+	"BaseEntitySchema": {uuid: true, fields: []*Field{
+		readOnly("tenant_id", "string"), readOnly("created_at", "time.Time"), readOnly("updated_at", "time.Time"),
+	}},
+	"entities.AuditedEntitySchema": {uuid: true, fields: auditFields},
+	"entities.GlobalEntitySchema":  {uuid: true, fields: auditFields},
+	"entities.BaseEntitySchema":    {uuid: true, fields: append(slices.Clone(auditFields), ownerDomainField)},
+	"entities.TenantEntitySchema":  {uuid: true, fields: append(slices.Clone(auditFields), readOnly("tenant_id", "string"))},
+	"entities.AuditMixin":          {fields: auditFields},
+	"entities.DomainScopeMixin":    {fields: []*Field{ownerDomainField}},
+	"mixin.Time":                   {fields: []*Field{readOnly("create_time", "time.Time"), readOnly("update_time", "time.Time")}},
+	"mixin.CreateTime":             {fields: []*Field{readOnly("create_time", "time.Time")}},
+	"mixin.UpdateTime":             {fields: []*Field{readOnly("update_time", "time.Time")}},
+}
+
+var (
+	auditFields      = []*Field{readOnly("created_at", "time.Time"), readOnly("updated_at", "time.Time")}
+	ownerDomainField = &Field{
+		Name: "OwnerDomainID", Column: "owner_domain_id", JSON: "ownerDomainId", Type: "uuid.UUID",
+		Nillable: true, Optional: true, ReadOnly: true, imports: []string{"github.com/google/uuid"},
+	}
+)
+
+func readOnly(column, typ string) *Field {
+	f := &Field{Name: pascal(column), Column: column, JSON: lowerCamel(column), Type: typ, ReadOnly: true}
+	if typ == "time.Time" {
+		f.imports = []string{"time"}
+	}
+	return f
+}
+
+func (p *fileParser) parseSchema(e *Entity) error {
+	mixins, err := p.returnedList(p.method(e.Name, "Mixin"))
+	if err != nil {
+		return err
+	}
+	var mixinFields []*Field
+	for _, m := range mixins {
+		if lit, ok := m.(*ast.CompositeLit); ok {
+			m = lit.Type
+		}
+		name := p.print(m)
+		// A mixin declared next to the schema is read like the schema.
+		if fd := p.method(name, "Fields"); fd != nil {
+			if err := p.parseFields(e, fd, &mixinFields); err != nil {
+				return err
+			}
+			continue
+		}
+		if sel, ok := m.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				name = path.Base(p.imports[id.Name]) + "." + sel.Sel.Name
+			}
+		}
+		known, ok := knownMixins[name]
+		if !ok {
+			continue
+		}
+		if known.uuid {
+			e.IDType, e.idImports = "uuid.UUID", []string{"github.com/google/uuid"}
+		}
+		for _, f := range known.fields {
+			copied := *f
+			mixinFields = append(mixinFields, &copied)
+		}
+	}
+
+	if err := p.parseFields(e, p.method(e.Name, "Fields"), &e.Fields); err != nil {
+		return err
+	}
+	if e.IDType == "" {
+		e.IDType = "int"
+	}
+	for _, f := range mixinFields {
+		if !slices.ContainsFunc(e.Fields, func(g *Field) bool { return g.Column == f.Column }) {
+			e.Fields = append(e.Fields, f)
+		}
+	}
+	return nil
+}
+
+// parseFields appends the fields returned by the Fields method fd to dst;
+// an "id" field sets the ID type instead.
+func (p *fileParser) parseFields(e *Entity, fd *ast.FuncDecl, dst *[]*Field) error {
+	elts, err := p.returnedList(fd)
+	if err != nil {
+		return err
+	}
+	for _, elt := range elts {
+		f, column, err := p.parseField(elt)
+		if err != nil {
+			return err
+		}
+		switch {
+		case f == nil:
+			e.Skipped = append(e.Skipped, column)
+		case f.Column == "id":
+			e.IDType, e.idImports = f.Type, f.imports
+		default:
+			*dst = append(*dst, f)
+		}
+	}
+	return nil
+}
+
+// entKinds maps field builders to the Go types ent generates for them.
+var entKinds = map[string]string{
+	"String": "string", "Text": "string", "Bool": "bool", "Bytes": "[]byte",
+	"Int": "int", "Int8": "int8", "Int16": "int16", "Int32": "int32", "Int64": "int64",
+	"Uint": "uint", "Uint8": "uint8", "Uint16": "uint16", "Uint32": "uint32", "Uint64": "uint64",
+	"Float": "float64", "Float32": "float32", "Time": "time.Time", "Enum": "string",
+}
+
+// parseField reads a field.<Kind>("column").<Modifier>()... chain. A field
+// whose type cannot be referred to is returned as nil with its column.
+func (p *fileParser) parseField(expr ast.Expr) (*Field, string, error) {
+	var mods []*ast.CallExpr
+	var kind string
+	var root *ast.CallExpr
+	for root == nil {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			return nil, "", fmt.Errorf("unsupported field %s", p.print(expr))
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return nil, "", fmt.Errorf("unsupported field %s", p.print(expr))
+		}
+		if id, ok := sel.X.(*ast.Ident); ok && id.Name == "field" {
+			kind, root = sel.Sel.Name, call
+			break
+		}
+		mods = append(mods, call)
+		expr = sel.X
+	}
+	if len(root.Args) == 0 {
+		return nil, "", fmt.Errorf("field.%s without a name", kind)
+	}
+	column, err := strconv.Unquote(p.print(root.Args[0]))
+	if err != nil {
+		return nil, "", fmt.Errorf("field.%s: name must be a string literal", kind)
+	}
+
+	f := &Field{Name: pascal(column), Column: column, JSON: lowerCamel(column), Type: entKinds[kind]}
+	switch kind {
+	case "Time":
+		f.imports = []string{"time"}
+	case "UUID", "JSON", "Other":
+		if kind == "Other" || len(root.Args) < 2 {
+			return nil, column, nil
+		}
+		typ := root.Args[1]
+		if lit, ok := typ.(*ast.CompositeLit); ok {
+			typ = lit.Type
+		} else if u, ok := typ.(*ast.UnaryExpr); ok && u.Op == token.AND {
+			if lit, ok := u.X.(*ast.CompositeLit); ok {
+				typ = &ast.StarExpr{X: lit.Type}
+			}
+		}
+		imports, ok := p.typeImports(typ)
+		if !ok {
+			return nil, column, nil
+		}
+		f.Type, f.imports = p.print(typ), imports
+	}
+	if f.Type == "" {
+		return nil, column, nil
+	}
+
+	for _, m := range mods {
+		args := make([]string, len(m.Args))
+		for i, a := range m.Args {
+			args[i] = p.print(a)
+		}
+		switch m.Fun.(*ast.SelectorExpr).Sel.Name {
+		case "Optional":
+			f.Optional = true
+		case "Nillable":
+			f.Nillable = true
+		case "Immutable":
+			f.Immutable = true
+		case "Sensitive":
+			f.Sensitive = true
+		case "NotEmpty":
+			f.Required = true
+		case "Default":
+			f.HasDefault = true
+			if lit, ok := m.Args[0].(*ast.BasicLit); ok {
+				f.Default = lit.Value
+				if lit.Kind == token.STRING {
+					f.Default, _ = strconv.Unquote(lit.Value)
+				}
+			} else if args[0] == "true" || args[0] == "false" || isNumber(args[0]) {
+				f.Default = args[0]
+			}
+		case "DefaultFunc":
+			f.HasDefault = true
+		case "UpdateDefault":
+			f.ReadOnly = f.ReadOnly || f.Type == "time.Time"
+		case "MaxLen":
+			f.Rules = append(f.Rules, "max="+args[0])
+		case "MinLen":
+			f.Rules = append(f.Rules, "min="+args[0])
+		case "Positive":
+			f.Rules = append(f.Rules, "gt=0")
+		case "Negative":
+			f.Rules = append(f.Rules, "lt=0")
+		case "NonNegative":
+			f.Rules = append(f.Rules, "gte=0")
+		case "Min":
+			f.Rules = append(f.Rules, "gte="+args[0])
+		case "Max":
+			f.Rules = append(f.Rules, "lte="+args[0])
+		case "Range":
+			f.Rules = append(f.Rules, "gte="+args[0], "lte="+args[1])
+		case "Values":
+			for _, a := range args {
+				if v, err := strconv.Unquote(a); err == nil {
+					f.Enum = append(f.Enum, v)
+				}
+			}
+		case "GoType", "ValueScanner":
+			// The model holds a type of the schema package.
+			return nil, column, nil
+		case "StructTag":
+			tag, _ := strconv.Unquote(args[0])
+			if name, _, _ := strings.Cut(reflect.StructTag(tag).Get("json"), ","); name != "" && name != "-" {
+				f.JSON = name
+			}
+		}
+	}
+	// Mods were collected outermost first.
+	slices.Reverse(f.Rules)
+
+	switch {
+	case f.Type == "string" && f.Enum == nil:
+		// NotEmpty already decided.
+	case f.Enum != nil, f.Type == "time.Time", f.Type == "uuid.UUID":
+		f.Required = !f.Optional && !f.HasDefault
+	default:
+		f.Required = false
+	}
+	return f, "", nil
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+func (p *fileParser) parseStruct(e *Entity, st *ast.StructType) error {
+	for _, field := range st.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			s, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(s)
+		}
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			typ := field.Type
+			nillable := false
+			if star, ok := typ.(*ast.StarExpr); ok {
+				typ, nillable = star.X, true
+			}
+			imports, _ := p.typeImports(typ)
+			if name.Name == "ID" {
+				e.IDType, e.idImports = p.print(typ), imports
+				continue
+			}
+
+			f := &Field{
+				Name:     name.Name,
+				Column:   snake(name.Name),
+				Type:     p.print(typ),
+				Nillable: nillable,
+				Optional: nillable,
+				imports:  imports,
+			}
+			f.JSON = lowerCamel(f.Column)
+			if jsonName, _, _ := strings.Cut(tag.Get("json"), ","); jsonName == "-" {
+				continue
+			} else if jsonName != "" {
+				f.JSON = jsonName
+			}
+			for _, rule := range strings.Split(tag.Get("validate"), ",") {
+				switch rule {
+				case "":
+				case "required":
+					f.Required = true
+				case "omitempty":
+				default:
+					f.Rules = append(f.Rules, rule)
+				}
+			}
+			e.Fields = append(e.Fields, f)
+		}
+	}
+	if e.IDType == "" {
+		return fmt.Errorf("struct has no ID field")
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+package gen
+
+import "text/template"
+
+var funcs = template.FuncMap{"quote": func(s string) string { return "`" + s + "`" }}
+
+func parse(name, text string) *template.Template {
+	return template.Must(template.New(name).Funcs(funcs).Parse(text))
+}
+
+var typesTmpl = parse("types", `package {{.Package}}
+
+import ()
+
+// Create{{.Name}}Request is the body of a create {{.Singular}} request.
+type Create{{.Name}}Request struct {
+{{- range .CreateFields}}
+	{{.}}
+{{- end}}
+}
+
+// Update{{.Name}}Request is the body of an update {{.Singular}} request.
+// Fields absent from the body keep their value.
+type Update{{.Name}}Request struct {
+	ID {{.IDType}} {{quote "path:\"id\" json:\"-\""}}
+{{- range .UpdateFields}}
+	{{.}}
+{{- end}}
+}
+
+// {{.Name}}IDRequest selects {{.ASingular}} by the {id} route parameter.
+type {{.Name}}IDRequest struct {
+	ID {{.IDType}} {{quote "path:\"id\""}}
+}
+
+// List{{.Name}}sRequest selects a page of {{.Plural}}. Sort lists columns,
+// a "-" prefix sorting descending.
+type List{{.Name}}sRequest struct {
+	Page    int      {{quote "query:\"page\" default:\"1\" validate:\"gte=1\""}}
+	PerPage int      {{quote "query:\"perPage\" default:\"20\" validate:\"gte=1,lte=100\""}}
+	Sort    []string {{quote "query:\"sort\""}}
+}
+
+// {{.Name}}Response is {{.ASingular}} as returned by the API.
+type {{.Name}}Response struct {
+	ID {{.IDType}} {{quote "json:\"id\""}}
+{{- range .ResponseFields}}
+	{{.}}
+{{- end}}
+}
+
+// New{{.Name}}Response converts e to its API representation.
+func New{{.Name}}Response(e {{.Model}}) *{{.Name}}Response {
+	r := &{{.Name}}Response{
+		ID: e.ID,
+{{- range .ResponseInit}}
+		{{.}}
+{{- end}}
+	}
+{{- range .ResponseSet}}
+	{{.}}
+{{- end}}
+	return r
+}
+`)
+
+var repositoryTmpl = parse("repository", `package {{.Package}}
+
+import ()
+
+// New{{.Name}}Repository returns the repository of {{.Plural}} stored with
+// client.
+func New{{.Name}}Repository(client *{{.EntName}}.Client) repo.Repository[{{.Model}}, {{.IDType}}] {
+	return repo.NewEnt("{{.Singular}}", client.{{.Name}}.Query, func(e {{.Model}}) {{.IDType}} { return e.ID }).
+		WithColumns({{range $i, $c := .Columns}}{{if $i}}, {{end}}"{{$c}}"{{end}}).
+		WithCreate(func(ctx context.Context, e {{.Model}}) ({{.Model}}, error) {
+{{- if .RepoCreateIf}}
+			c := client.{{.Name}}.Create(){{range .RepoCreate}}.
+				{{.}}{{end}}
+{{- range .RepoCreateIf}}
+			{{.}}
+{{- end}}
+			return c.Save(ctx)
+{{- else}}
+			return client.{{.Name}}.Create(){{range .RepoCreate}}.
+				{{.}}{{end}}.
+				Save(ctx)
+{{- end}}
+		}).
+		WithUpdate(func(ctx context.Context, id {{.IDType}}, e {{.Model}}) ({{.Model}}, error) {
+			return client.{{.Name}}.UpdateOneID(id){{range .RepoUpdate}}.
+				{{.}}{{end}}.
+				Save(ctx)
+		}).
+		WithDelete(func(ctx context.Context, id {{.IDType}}) error {
+			return client.{{.Name}}.DeleteOneID(id).Exec(ctx)
+		})
+}
+`)
+
+var handlerTmpl = parse("handler", `package {{.Package}}
+
+import ()
+
+// {{.Name}}Handler serves the CRUD endpoints of {{.Plural}}.
+type {{.Name}}Handler struct {
+	repo repo.Repository[{{.Model}}, {{.IDType}}]
+}
+
+// New{{.Name}}Handler returns a handler storing {{.Plural}} in r.
+func New{{.Name}}Handler(r repo.Repository[{{.Model}}, {{.IDType}}]) *{{.Name}}Handler {
+	return &{{.Name}}Handler{repo: r}
+}
+
+// Routes registers the endpoints and their permissions on r, usually
+// mounted with r.Route("/{{.Resource}}", h.Routes).
+func (h *{{.Name}}Handler) Routes(r chi.Router) {
+	permission.Get(r, "/", httpx.Handler(h.List), permission.Private("List {{.Plural}}", Perm{{.Name}}Read))
+	permission.Post(r, "/", httpx.Handler(h.Create, httpx.WithStatus(http.StatusCreated)), permission.Private("Create {{.Singular}}", Perm{{.Name}}Write))
+	permission.Get(r, "/{id}", httpx.Handler(h.Get), permission.Private("Get {{.Singular}}", Perm{{.Name}}Read))
+	permission.Patch(r, "/{id}", httpx.Handler(h.Update), permission.Private("Update {{.Singular}}", Perm{{.Name}}Write))
+	permission.Delete(r, "/{id}", httpx.Handler(h.Delete), permission.Private("Delete {{.Singular}}", Perm{{.Name}}Delete))
+}
+
+// List returns a page of {{.Plural}}.
+func (h *{{.Name}}Handler) List(ctx context.Context, req List{{.Name}}sRequest) (*repo.Page[*{{.Name}}Response], error) {
+	page, err := h.repo.List(ctx, repo.ListOptions{
+		Pagination: {{.Pagination}}(req.Page, req.PerPage),
+		OrderBy:    req.Sort,
+	})
+	if stderrors.Is(err, repo.ErrInvalidColumn) {
+		return nil, errors.NewInvalid("sort", req.Sort, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*{{.Name}}Response, len(page.Items))
+	for i, e := range page.Items {
+		items[i] = New{{.Name}}Response(e)
+	}
+	return &repo.Page[*{{.Name}}Response]{
+		Items:      items,
+		Total:      page.Total,
+		Page:       page.Page,
+		PerPage:    page.PerPage,
+		TotalPages: page.TotalPages,
+	}, nil
+}
+
+// Get returns {{.ASingular}}.
+func (h *{{.Name}}Handler) Get(ctx context.Context, req {{.Name}}IDRequest) (*{{.Name}}Response, error) {
+	e, err := h.find(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return New{{.Name}}Response(e), nil
+}
+
+// Create stores a new {{.Singular}}.
+func (h *{{.Name}}Handler) Create(ctx context.Context, req Create{{.Name}}Request) (*{{.Name}}Response, error) {
+	e := &{{.ModelType}}{
+{{- range .CreateInit}}
+		{{.}}
+{{- end}}
+	}
+{{- range .CreateSet}}
+	{{.}}
+{{- end}}
+	created, err := h.repo.Create(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	return New{{.Name}}Response(created), nil
+}
+
+// Update changes the fields present in the request.
+func (h *{{.Name}}Handler) Update(ctx context.Context, req Update{{.Name}}Request) (*{{.Name}}Response, error) {
+	e, err := h.find(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+{{- range .UpdateSet}}
+	{{.}}
+{{- end}}
+	updated, err := h.repo.Update(ctx, req.ID, e)
+	if err != nil {
+		return nil, err
+	}
+	return New{{.Name}}Response(updated), nil
+}
+
+// Delete removes {{.ASingular}}.
+func (h *{{.Name}}Handler) Delete(ctx context.Context, req {{.Name}}IDRequest) (httpx.Empty, error) {
+	if _, err := h.find(ctx, req.ID); err != nil {
+		return httpx.Empty{}, err
+	}
+	return httpx.Empty{}, h.repo.Delete(ctx, req.ID)
+}
+
+// find returns the {{.Singular}} with id, or a not found error.
+func (h *{{.Name}}Handler) find(ctx context.Context, id {{.IDType}}) ({{.Model}}, error) {
+	e, err := h.repo.Get(ctx, id)
+	if stderrors.Is(err, repo.ErrNotFound) {
+		return nil, errors.NewNotFound("{{.Singular}}", id)
+	}
+	return e, err
+}
+`)
+
+var permissionsTmpl = parse("permissions", `package {{.Package}}
+
+import ()
+
+// Permission codes of the {{.Singular}} endpoints.
+const (
+	Perm{{.Name}}Read   = "{{.Resource}}:read"
+	Perm{{.Name}}Write  = "{{.Resource}}:write"
+	Perm{{.Name}}Delete = "{{.Resource}}:delete"
+)
+
+// {{.Name}}Permissions describes the permission codes of the {{.Singular}}
+// endpoints, for seeding the permission store.
+func {{.Name}}Permissions() []permission.Permission {
+	return []permission.Permission{
+		{Code: Perm{{.Name}}Read, Name: "Read {{.Plural}}", Description: "List and view {{.Plural}}", Scope: permission.ScopeAPI, Status: permission.StatusActive},
+		{Code: Perm{{.Name}}Write, Name: "Write {{.Plural}}", Description: "Create and update {{.Plural}}", Scope: permission.ScopeAPI, Status: permission.StatusActive},
+		{Code: Perm{{.Name}}Delete, Name: "Delete {{.Plural}}", Description: "Delete {{.Plural}}", Scope: permission.ScopeAPI, Status: permission.StatusActive},
+	}
+}
+`)
+
+var testTmpl = parse("test", `package {{.Package}}
+
+import ()
+
+// memory{{.Name}}Repository keeps {{.Plural}} in memory for handler tests.
+type memory{{.Name}}Repository struct {
+	items map[{{.IDType}}]{{.Model}}
+	ids   []{{.IDType}}
+	seq   int
+}
+
+func newMemory{{.Name}}Repository() *memory{{.Name}}Repository {
+	return &memory{{.Name}}Repository{items: map[{{.IDType}}]{{.Model}}{}}
+}
+
+func (m *memory{{.Name}}Repository) Get(_ context.Context, id {{.IDType}}) ({{.Model}}, error) {
+	e, ok := m.items[id]
+	if !ok {
+		return nil, repo.ErrNotFound
+	}
+	return e, nil
+}
+
+func (m *memory{{.Name}}Repository) List(_ context.Context, opts repo.ListOptions) (*repo.Page[{{.Model}}], error) {
+	items := make([]{{.Model}}, 0, len(m.ids))
+	for _, id := range m.ids {
+		items = append(items, m.items[id])
+	}
+	return &repo.Page[{{.Model}}]{Items: items, Total: len(items), Page: opts.Pagination.Page, PerPage: opts.Pagination.PerPage, TotalPages: 1}, nil
+}
+
+func (m *memory{{.Name}}Repository) Create(_ context.Context, e {{.Model}}) ({{.Model}}, error) {
+	m.seq++
+	e.ID = {{.NewID}}
+	m.items[e.ID] = e
+	m.ids = append(m.ids, e.ID)
+	return e, nil
+}
+
+func (m *memory{{.Name}}Repository) Update(_ context.Context, id {{.IDType}}, e {{.Model}}) ({{.Model}}, error) {
+	if _, ok := m.items[id]; !ok {
+		return nil, repo.ErrNotFound
+	}
+	m.items[id] = e
+	return e, nil
+}
+
+func (m *memory{{.Name}}Repository) Delete(_ context.Context, id {{.IDType}}) error {
+	if _, ok := m.items[id]; !ok {
+		return repo.ErrNotFound
+	}
+	delete(m.items, id)
+	m.ids = slices.DeleteFunc(m.ids, func(v {{.IDType}}) bool { return v == id })
+	return nil
+}
+
+func (m *memory{{.Name}}Repository) BatchGet(_ context.Context, ids []{{.IDType}}) (map[{{.IDType}}]{{.Model}}, error) {
+	found := map[{{.IDType}}]{{.Model}}{}
+	for _, id := range ids {
+		if e, ok := m.items[id]; ok {
+			found[id] = e
+		}
+	}
+	return found, nil
+}
+
+func new{{.Name}}Router() chi.Router {
+	r := chi.NewRouter()
+	New{{.Name}}Handler(newMemory{{.Name}}Repository()).Routes(r)
+	return r
+}
+
+func Test{{.Name}}Handler(t *testing.T) {
+	r := new{{.Name}}Router()
+	do := func(method, target, body string) (*httptest.ResponseRecorder, {{.Name}}Response) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		var resp struct {
+			Data {{.Name}}Response {{quote "json:\"data\""}}
+		}
+		if rec.Code == http.StatusOK || rec.Code == http.StatusCreated {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		}
+		return rec, resp.Data
+	}
+
+	rec, created := do(http.MethodPost, "/", {{quote .CreateBody}})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	path := fmt.Sprintf("/%v", created.ID)
+
+	rec, got := do(http.MethodGet, path, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, created.ID, got.ID)
+
+	rec, _ = do(http.MethodGet, "/?page=1&perPage=10", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), {{quote "\"total\":1"}})
+{{- if .UpdateBody}}
+
+	rec, updated := do(http.MethodPatch, path, {{quote .UpdateBody}})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	{{.UpdateCheck}}
+{{- else}}
+
+	rec, _ = do(http.MethodPatch, path, "{}")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+{{- end}}
+
+	rec, _ = do(http.MethodDelete, path, "")
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	rec, _ = do(http.MethodGet, path, "")
+	require.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+{{- if .Required}}
+
+	rec, _ = do(http.MethodPost, "/", "{}")
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+{{- end}}
+}
+
+func Test{{.Name}}RoutesRequirePermissions(t *testing.T) {
+	snapshot, err := permission.SnapshotFromRouter(new{{.Name}}Router())
+	require.NoError(t, err)
+	require.Len(t, snapshot.Routes, 5)
+	codes := map[string]bool{}
+	for _, p := range {{.Name}}Permissions() {
+		codes[p.Code] = true
+	}
+	for _, route := range snapshot.Routes {
+		require.False(t, route.IsPublic, route.Path)
+		require.Len(t, route.Permissions, 1, route.Path)
+		require.True(t, codes[route.Permissions[0]], route.Permissions[0])
+	}
+}
+`)
@@ -0,0 +1,468 @@
+package gen
+
+import (
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// view is what the templates render. The per-field code is prepared here so
+// the templates stay declarative.
+type view struct {
+	*Entity
+	Package string
+	// Model is the model type, e.g. "*ent.Order", and ModelType the type
+	// without the pointer.
+	Model, ModelType string
+	// Ent is the name the ent client package is imported under.
+	EntName string
+	// Pagination is the qualified framework ent.Pagination constructor.
+	Pagination string
+	// Resource is the plural in permission codes; Singular and Plural are
+	// the words in descriptions and messages.
+	Resource, Singular, Plural string
+	// ASingular is Singular with its indefinite article, e.g. "an order".
+	ASingular string
+
+	CreateFields, UpdateFields, ResponseFields []string
+	// CreateInit are the key-value pairs of the model literal in Create,
+	// CreateSet the statements after it.
+	CreateInit, CreateSet []string
+	UpdateSet             []string
+	ResponseInit          []string
+	ResponseSet           []string
+
+	// RepoCreate are the setter calls chained on the ent create builder,
+	// RepoCreateIf the setters applied only to non-zero values.
+	RepoCreate, RepoCreateIf, RepoUpdate []string
+	Columns                              []string
+
+	// NewID is the expression the test repository assigns IDs with.
+	NewID string
+	// CreateBody and UpdateBody are test request bodies; UpdateCheck is the
+	// assertion on the updated response.
+	CreateBody, UpdateBody, UpdateCheck string
+	// Required reports that an empty create body fails validation.
+	Required bool
+
+	imports []importSpec
+}
+
+func newView(e *Entity, opts Options) (*view, error) {
+	v := &view{Entity: e, Package: opts.Package, Resource: opts.Resource}
+	if v.Package == "" {
+		switch {
+		case !e.Ent && (opts.Dir == "" || filepath.Dir(opts.Source) == filepath.Clean(opts.Dir)):
+			v.Package = e.Package
+		case opts.Dir != "":
+			v.Package = packageName(filepath.Base(filepath.Clean(opts.Dir)))
+		default:
+			return nil, fmt.Errorf("gen: set the output directory or package for %s", e.Name)
+		}
+	}
+	if v.Resource == "" {
+		v.Resource = plural(snake(e.Name))
+	}
+	v.Singular = words(snake(e.Name))
+	v.Plural = words(v.Resource)
+	v.ASingular = "a " + v.Singular
+	if strings.ContainsRune("aeiou", rune(v.Singular[0])) {
+		v.ASingular = "an " + v.Singular
+	}
+
+	v.imports = []importSpec{
+		{name: "context", path: "context"},
+		{name: "stderrors", path: "errors", alias: true},
+		{name: "fmt", path: "fmt"},
+		{name: "http", path: "net/http"},
+		{name: "httptest", path: "net/http/httptest"},
+		{name: "json", path: "encoding/json"},
+		{name: "slices", path: "slices"},
+		{name: "strconv", path: "strconv"},
+		{name: "strings", path: "strings"},
+		{name: "testing", path: "testing"},
+		{name: "time", path: "time"},
+		{name: "chi", path: "github.com/go-chi/chi/v5"},
+		{name: "uuid", path: "github.com/google/uuid"},
+		{name: "errors", path: "github.com/leeforge/framework/errors"},
+		{name: "httpx", path: "github.com/leeforge/framework/http/httpx"},
+		{name: "permission", path: "github.com/leeforge/framework/permission"},
+		{name: "repo", path: "github.com/leeforge/framework/repo"},
+		{name: "require", path: "github.com/stretchr/testify/require"},
+	}
+	const frameworkEnt = "github.com/leeforge/framework/ent"
+	v.Pagination = "ent.NewPagination"
+	v.Model, v.ModelType = "*"+e.Name, e.Name
+	if e.Ent {
+		if opts.EntPackage == "" {
+			return nil, fmt.Errorf("gen: %s is an ent schema; set the ent package import path", e.Name)
+		}
+		v.EntName = packageName(filepath.Base(opts.EntPackage))
+		v.Model, v.ModelType = "*"+v.EntName+"."+e.Name, v.EntName+"."+e.Name
+		v.imports = append(v.imports,
+			importSpec{name: v.EntName, path: opts.EntPackage, alias: v.EntName != filepath.Base(opts.EntPackage)},
+			importSpec{name: strings.ToLower(e.Name), path: opts.EntPackage + "/" + strings.ToLower(e.Name)},
+		)
+		if opts.EntPackage != frameworkEnt {
+			v.Pagination = "fwent.NewPagination"
+			v.imports = append(v.imports, importSpec{name: "fwent", path: frameworkEnt, alias: true})
+		}
+	} else {
+		v.imports = append(v.imports, importSpec{name: "ent", path: frameworkEnt})
+	}
+	for _, path := range e.idImports {
+		v.addImport(path)
+	}
+
+	switch {
+	case e.IDType == "uuid.UUID":
+		v.NewID = "uuid.New()"
+	case e.IDType == "int":
+		v.NewID = "m.seq"
+	case e.IDType == "string":
+		v.NewID = "strconv.Itoa(m.seq)"
+	case strings.HasPrefix(e.IDType, "int") || strings.HasPrefix(e.IDType, "uint"):
+		v.NewID = e.IDType + "(m.seq)"
+	default:
+		return nil, fmt.Errorf("gen: %s: unsupported ID type %s", e.Name, e.IDType)
+	}
+
+	v.Columns = append(v.Columns, "id")
+	var body []string
+	for _, f := range e.Fields {
+		for _, path := range f.imports {
+			v.addImport(path)
+		}
+		if !f.Sensitive {
+			v.response(f)
+			if !nilable(f.Type) {
+				v.Columns = append(v.Columns, f.Column)
+			}
+		}
+		if f.ReadOnly {
+			continue
+		}
+		if sample, ok := v.create(f); ok {
+			body = append(body, strconv.Quote(f.JSON)+":"+sample)
+		}
+		if !f.Immutable {
+			v.update(f)
+		}
+		v.repository(f)
+	}
+	v.CreateBody = "{" + strings.Join(body, ",") + "}"
+	return v, nil
+}
+
+func (v *view) addImport(path string) {
+	name := filepath.Base(path)
+	if slices.ContainsFunc(v.imports, func(imp importSpec) bool { return imp.path == path }) {
+		return
+	}
+	v.imports = append(v.imports, importSpec{name: name, path: path})
+}
+
+// packageName turns a directory name into a package name.
+func packageName(dir string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' || r == '_' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(dir))
+}
+
+// nilable reports whether the zero value of typ is nil, so the type itself
+// marks an absent value.
+func nilable(typ string) bool {
+	return strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || strings.HasPrefix(typ, "*")
+}
+
+// enumType is the ent generated type of an enum field.
+func (v *view) enumType(f *Field) string {
+	return strings.ToLower(v.Entity.Name) + "." + pascal(f.Column)
+}
+
+// toModel converts a request value to the model field type.
+func (v *view) toModel(f *Field, expr string) string {
+	if f.Enum != nil {
+		return v.enumType(f) + "(" + expr + ")"
+	}
+	return expr
+}
+
+// local names a variable holding the value of f.
+func local(f *Field) string {
+	name := lowerCamel(f.Column)
+	if token.IsKeyword(name) || slices.Contains([]string{"e", "r", "h", "req", "ctx", "err"}, name) {
+		name += "Value"
+	}
+	return name
+}
+
+// tag renders a struct tag from name-value pairs, skipping empty values.
+func tag(pairs ...string) string {
+	var parts []string
+	for i := 0; i < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			parts = append(parts, pairs[i]+":"+strconv.Quote(pairs[i+1]))
+		}
+	}
+	return "`" + strings.Join(parts, " ") + "`"
+}
+
+// rules returns the validate rules of f besides required and omitempty.
+func rules(f *Field) []string {
+	r := slices.Clone(f.Rules)
+	if f.Enum != nil {
+		r = append(r, "oneof="+strings.Join(f.Enum, " "))
+	}
+	return r
+}
+
+func mod(f *Field) string {
+	if f.Type == "string" && f.Enum == nil {
+		return "trim"
+	}
+	return ""
+}
+
+// create adds f to the create request and returns its sample value for the
+// test body, if it has one.
+func (v *view) create(f *Field) (string, bool) {
+	typ, jsonTag, def := f.Type, f.JSON, f.Default
+	pointer := def == "" && (f.Optional || f.Nillable || f.HasDefault) && !nilable(f.Type)
+	r := rules(f)
+	switch {
+	case pointer || nilable(f.Type):
+		jsonTag += ",omitempty"
+		if len(r) > 0 {
+			r = append([]string{"omitempty"}, r...)
+		}
+	case f.Required && def == "":
+		r = append([]string{"required"}, r...)
+	}
+	if pointer {
+		typ = "*" + typ
+	}
+	v.CreateFields = append(v.CreateFields, f.Name+" "+typ+" "+
+		tag("json", jsonTag, "default", def, "mod", mod(f), "validate", strings.Join(r, ",")))
+	if f.Required && def == "" {
+		v.Required = true
+	}
+
+	switch {
+	case !pointer && !f.Nillable:
+		v.CreateInit = append(v.CreateInit, f.Name+": "+v.toModel(f, "req."+f.Name)+",")
+	case !pointer && f.Nillable:
+		name := local(f)
+		v.CreateSet = append(v.CreateSet, name+" := "+v.toModel(f, "req."+f.Name), "e."+f.Name+" = &"+name)
+	case f.Nillable && f.Enum == nil:
+		v.CreateInit = append(v.CreateInit, f.Name+": req."+f.Name+",")
+	case f.Nillable:
+		v.CreateSet = append(v.CreateSet, "if req."+f.Name+" != nil {", "value := "+v.toModel(f, "*req."+f.Name), "e."+f.Name+" = &value", "}")
+	default:
+		v.CreateSet = append(v.CreateSet, "if req."+f.Name+" != nil {", "e."+f.Name+" = "+v.toModel(f, "*req."+f.Name), "}")
+	}
+	if pointer {
+		return "", false
+	}
+	return sample(f)
+}
+
+// update adds f to the update request, where an absent field keeps its
+// value.
+func (v *view) update(f *Field) {
+	typ, r := f.Type, rules(f)
+	if f.Required && f.Type == "string" && f.Enum == nil && !slices.ContainsFunc(r, func(s string) bool { return strings.HasPrefix(s, "min=") }) {
+		r = append([]string{"min=1"}, r...)
+	}
+	if len(r) > 0 {
+		r = append([]string{"omitempty"}, r...)
+	}
+	if !nilable(typ) {
+		typ = "*" + typ
+	}
+	v.UpdateFields = append(v.UpdateFields, f.Name+" "+typ+" "+
+		tag("json", f.JSON+",omitempty", "mod", mod(f), "validate", strings.Join(r, ",")))
+
+	set := "e." + f.Name + " = " + v.toModel(f, "*req."+f.Name)
+	switch {
+	case nilable(f.Type) || (f.Nillable && f.Enum == nil):
+		set = "e." + f.Name + " = req." + f.Name
+	case f.Nillable:
+		set = "value := " + v.toModel(f, "*req."+f.Name) + "\ne." + f.Name + " = &value"
+	}
+	v.UpdateSet = append(v.UpdateSet, "if req."+f.Name+" != nil {", set, "}")
+
+	if v.UpdateBody == "" && f.Type == "string" && f.Enum == nil && !f.Sensitive {
+		if s, ok := sampleString(f.Rules, "updated"); ok && s == "updated" {
+			deref := ""
+			if f.Nillable {
+				deref = "*"
+			}
+			v.UpdateBody = `{"` + f.JSON + `":"updated"}`
+			v.UpdateCheck = `require.Equal(t, "updated", ` + deref + "updated." + f.Name + ")"
+		}
+	}
+}
+
+// response adds f to the response.
+func (v *view) response(f *Field) {
+	typ, jsonTag := f.Type, f.JSON
+	if f.Nillable {
+		typ, jsonTag = "*"+typ, jsonTag+",omitempty"
+	}
+	v.ResponseFields = append(v.ResponseFields, f.Name+" "+typ+" "+tag("json", jsonTag))
+	switch {
+	case f.Enum == nil:
+		v.ResponseInit = append(v.ResponseInit, f.Name+": e."+f.Name+",")
+	case !f.Nillable:
+		v.ResponseInit = append(v.ResponseInit, f.Name+": string(e."+f.Name+"),")
+	default:
+		v.ResponseSet = append(v.ResponseSet, "if e."+f.Name+" != nil {", "value := string(*e."+f.Name+")", "r."+f.Name+" = &value", "}")
+	}
+}
+
+// repository adds the ent setters of f.
+func (v *view) repository(f *Field) {
+	if !v.Entity.Ent {
+		return
+	}
+	set := "Set" + f.Name + "(e." + f.Name + ")"
+	if f.Nillable {
+		set = "SetNillable" + f.Name + "(e." + f.Name + ")"
+	}
+	if !f.Immutable {
+		v.RepoUpdate = append(v.RepoUpdate, set)
+	}
+	// Leave computed defaults to ent when the request did not set a value.
+	if f.HasDefault && f.Default == "" && !f.Nillable {
+		var nonZero string
+		switch {
+		case f.Type == "time.Time":
+			nonZero = "!e." + f.Name + ".IsZero()"
+		case f.Type == "uuid.UUID":
+			nonZero = "e." + f.Name + " != uuid.Nil"
+		case f.Type == "string":
+			nonZero = "e." + f.Name + ` != ""`
+		case nilable(f.Type):
+			nonZero = "e." + f.Name + " != nil"
+		case isNumeric(f.Type):
+			nonZero = "e." + f.Name + " != 0"
+		}
+		if nonZero != "" {
+			v.RepoCreateIf = append(v.RepoCreateIf, "if "+nonZero+" {", "c."+set, "}")
+			return
+		}
+	}
+	v.RepoCreate = append(v.RepoCreate, set)
+}
+
+func isNumeric(typ string) bool {
+	return strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint") || strings.HasPrefix(typ, "float")
+}
+
+var ruleParam = regexp.MustCompile(`^(min|max|len|gte|lte|gt|lt)=(-?[0-9.]+)$`)
+
+// sample returns a JSON value of f that passes its rules, for tests.
+func sample(f *Field) (string, bool) {
+	switch {
+	case f.Enum != nil:
+		return strconv.Quote(f.Enum[0]), true
+	case f.Type == "string":
+		s, ok := sampleString(f.Rules, "example")
+		return strconv.Quote(s), ok
+	case f.Type == "bool":
+		return "true", true
+	case f.Type == "time.Time":
+		return `"2024-01-02T15:04:05Z"`, true
+	case f.Type == "uuid.UUID":
+		return strconv.Quote(sampleUUID(f.Rules)), true
+	case f.Type == "[]string":
+		s, ok := sampleString(f.Rules, "example")
+		return "[" + strconv.Quote(s) + "]", ok
+	case isNumeric(f.Type):
+		n := 1.0
+		for _, r := range f.Rules {
+			m := ruleParam.FindStringSubmatch(r)
+			if m == nil {
+				continue
+			}
+			p, _ := strconv.ParseFloat(m[2], 64)
+			switch m[1] {
+			case "gte", "min":
+				n = max(n, p)
+			case "gt":
+				n = max(n, p+1)
+			case "lte", "max":
+				n = min(n, p)
+			case "lt":
+				n = min(n, p-1)
+			}
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// sampleUUID returns a version 7 UUID when the rules ask for one and a
+// version 4 UUID otherwise, which also satisfies uuid.
+func sampleUUID(rules []string) string {
+	if slices.Contains(rules, "uuid7") {
+		return "0190a8d1-5a3e-7c4e-9b1d-2f6a8c3e4d5b"
+	}
+	return "3f2b8c1e-9d4a-4e7b-8c6d-1a2b3c4d5e6f"
+}
+
+// sampleString adapts s to the string rules, reporting false for rules it
+// does not know how to satisfy.
+func sampleString(rules []string, s string) (string, bool) {
+	for _, r := range rules {
+		switch r {
+		case "email":
+			return "user@example.com", true
+		case "url", "uri", "http_url":
+			return "https://example.com", true
+		case "uuid", "uuid4", "uuid7":
+			return sampleUUID(rules), true
+		case "numeric", "number":
+			s = strings.Repeat("1", len(s))
+		case "alpha", "alphanum", "alphaunicode", "alphanumunicode", "lowercase", "ascii", "printascii", "trim", "required", "omitempty":
+		default:
+			m := ruleParam.FindStringSubmatch(r)
+			if m == nil {
+				if strings.HasPrefix(r, "oneof=") {
+					return strings.Fields(strings.TrimPrefix(r, "oneof="))[0], true
+				}
+				return s, false
+			}
+			n, _ := strconv.Atoi(m[2])
+			switch m[1] {
+			case "min", "gte":
+				if len(s) < n {
+					s += strings.Repeat(s[:1], n-len(s))
+				}
+			case "gt":
+				if len(s) <= n {
+					s += strings.Repeat(s[:1], n+1-len(s))
+				}
+			case "max", "lte":
+				if len(s) > n {
+					s = s[:max(n, 1)]
+				}
+			case "lt":
+				if len(s) >= n {
+					s = s[:max(n-1, 1)]
+				}
+			case "len":
+				s = strings.Repeat(s[:1], n)
+			}
+		}
+	}
+	return s, true
+}
@@ -0,0 +1,149 @@
+package interceptor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	framerrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/middleware"
+	"github.com/leeforge/framework/request"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// UnaryRequestContext builds a request.RequestContext from the incoming
+// metadata (the lower-cased request.RequestContext headers, e.g.
+// "x-request-id"), generates the missing IDs and stores it in the context as
+// request.RequestIDMiddleware does. The request, trace, span and
+// correlation IDs are sent back as response header metadata.
+func UnaryRequestContext() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		rc := requestContext(ctx, info.FullMethod)
+		_ = grpc.SetHeader(ctx, responseHeaders(rc))
+		return handler(withRequestContext(ctx, rc), req)
+	}
+}
+
+// StreamRequestContext is the stream counterpart of UnaryRequestContext.
+func StreamRequestContext() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		rc := requestContext(ss.Context(), info.FullMethod)
+		_ = ss.SetHeader(responseHeaders(rc))
+		return handler(srv, withContext(ss, withRequestContext(ss.Context(), rc)))
+	}
+}
+
+// UnaryClientPropagation copies the request context of the calling context
+// into the outgoing metadata, as httpclient does for HTTP calls. Keys the
+// caller has already set are kept.
+func UnaryClientPropagation() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(propagate(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientPropagation is the stream counterpart of UnaryClientPropagation.
+func StreamClientPropagation() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(propagate(ctx), desc, cc, method, opts...)
+	}
+}
+
+func requestContext(ctx context.Context, fullMethod string) *request.RequestContext {
+	md, _ := metadata.FromIncomingContext(ctx)
+	h := make(http.Header, len(md))
+	for key, values := range md {
+		h[http.CanonicalHeaderKey(key)] = values
+	}
+	rc := request.FromHeaders(h)
+	rc.Method = "POST"
+	rc.Path = fullMethod
+	rc.UserAgent = h.Get("User-Agent")
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		rc.IPAddress = p.Addr.String()
+	}
+
+	if rc.RequestID == "" {
+		rc.RequestID = request.GenerateShortID(16)
+	}
+	if rc.TraceID == "" {
+		rc.TraceID = request.GenerateTraceID()
+	}
+	if rc.SpanID == "" {
+		rc.SpanID = request.GenerateSpanID()
+	}
+	if rc.CorrelationID == "" {
+		rc.CorrelationID = request.GenerateCorrelationID()
+	}
+	return rc
+}
+
+func withRequestContext(ctx context.Context, rc *request.RequestContext) context.Context {
+	ctx = rc.WithContext(ctx)
+	// the trace ID read by the responder and logging helpers
+	return context.WithValue(ctx, middleware.TraceIDKey, rc.TraceID)
+}
+
+func responseHeaders(rc *request.RequestContext) metadata.MD {
+	return metadata.Pairs(
+		"x-request-id", rc.RequestID,
+		"x-trace-id", rc.TraceID,
+		"x-span-id", rc.SpanID,
+		"x-correlation-id", rc.CorrelationID,
+	)
+}
+
+func propagate(ctx context.Context) context.Context {
+	out, _ := metadata.FromOutgoingContext(ctx)
+	var pairs []string
+	for key, values := range request.FromContext(ctx).ToHeaders() {
+		key = strings.ToLower(key)
+		if len(values) == 0 || values[0] == "" || len(out.Get(key)) > 0 {
+			continue
+		}
+		pairs = append(pairs, key, values[0])
+	}
+	if len(pairs) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
+// metadataValue returns the first incoming metadata value of key.
+func metadataValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// splitMethod splits "/package.Service/Method" into service and method.
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+func recoverPanic(ctx context.Context, logger *zap.Logger, fullMethod string, err *error) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	if logger == nil {
+		logger = zap.L()
+	}
+	rc := request.FromContext(ctx)
+	logger.Error("grpc.panic.recovered",
+		zap.Any("error", p),
+		zap.String("method", fullMethod),
+		zap.String("request_id", rc.RequestID),
+		zap.String("trace_id", rc.TraceID),
+		zap.Stack("stack"),
+	)
+	*err = framerrors.NewInternal("internal server error")
+}
@@ -0,0 +1,247 @@
+// Package interceptor gives gRPC services the cross-cutting behavior of the
+// HTTP middlewares: request context propagation, authentication with the
+// auth package, server spans, metrics, panic recovery and mapping of
+// errors.AppError to gRPC status codes.
+//
+//	srv := grpc.NewServer(interceptor.ServerOptions(interceptor.Options{
+//		Auth:    authMiddleware,
+//		Metrics: collector,
+//		Logger:  logger,
+//	})...)
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"github.com/leeforge/framework/auth"
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/tracing"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Metric names, labelled by service, method and code (the gRPC status code
+// name, e.g. "OK", "NotFound").
+const (
+	MetricRequests        = "grpc_server_requests_total"
+	MetricRequestDuration = "grpc_server_request_duration_seconds"
+)
+
+// Options configures the interceptor chain built by ServerOptions.
+type Options struct {
+	// Auth authenticates calls from the "x-api-key" and "authorization"
+	// metadata. Nil disables authentication.
+	Auth *auth.AuthMiddleware
+	// SkipAuth reports whether a method, e.g. "/grpc.health.v1.Health/Check",
+	// is served without authentication.
+	SkipAuth func(fullMethod string) bool
+	// Tracer, when set, wraps each call in a server span.
+	Tracer *tracing.Tracer
+	// Metrics, when set, receives a counter and a duration histogram per call.
+	Metrics *metrics.Collector
+	// Logger records recovered panics (default zap.L()).
+	Logger *zap.Logger
+}
+
+// ServerOptions returns the grpc.NewServer options installing the unary and
+// stream interceptor chains.
+func ServerOptions(opts Options) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptors(opts)...),
+		grpc.ChainStreamInterceptor(StreamServerInterceptors(opts)...),
+	}
+}
+
+// UnaryServerInterceptors returns the unary chain, outermost first: request
+// context, tracing, metrics, error mapping, recovery and authentication.
+// Recovery runs inside error mapping so a panic is reported as Internal,
+// and metrics see the final status code.
+func UnaryServerInterceptors(opts Options) []grpc.UnaryServerInterceptor {
+	chain := []grpc.UnaryServerInterceptor{UnaryRequestContext()}
+	if opts.Tracer != nil {
+		chain = append(chain, UnaryTracing(opts.Tracer))
+	}
+	if opts.Metrics != nil {
+		chain = append(chain, UnaryMetrics(opts.Metrics))
+	}
+	chain = append(chain, UnaryErrors(), UnaryRecovery(opts.Logger))
+	if opts.Auth != nil {
+		chain = append(chain, UnaryAuth(opts.Auth, opts.SkipAuth))
+	}
+	return chain
+}
+
+// StreamServerInterceptors returns the stream chain in the same order as
+// UnaryServerInterceptors.
+func StreamServerInterceptors(opts Options) []grpc.StreamServerInterceptor {
+	chain := []grpc.StreamServerInterceptor{StreamRequestContext()}
+	if opts.Tracer != nil {
+		chain = append(chain, StreamTracing(opts.Tracer))
+	}
+	if opts.Metrics != nil {
+		chain = append(chain, StreamMetrics(opts.Metrics))
+	}
+	chain = append(chain, StreamErrors(), StreamRecovery(opts.Logger))
+	if opts.Auth != nil {
+		chain = append(chain, StreamAuth(opts.Auth, opts.SkipAuth))
+	}
+	return chain
+}
+
+// UnaryAuth authenticates calls with a.Authenticate, so API keys and JWTs
+// are checked exactly as by the HTTP middleware. skip may be nil.
+func UnaryAuth(a *auth.AuthMiddleware, skip func(fullMethod string) bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if skip != nil && skip(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, a)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuth is the stream counterpart of UnaryAuth.
+func StreamAuth(a *auth.AuthMiddleware, skip func(fullMethod string) bool) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if skip != nil && skip(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), a)
+		if err != nil {
+			return err
+		}
+		return handler(srv, withContext(ss, ctx))
+	}
+}
+
+func authenticate(ctx context.Context, a *auth.AuthMiddleware) (context.Context, error) {
+	return a.Authenticate(ctx, metadataValue(ctx, "x-api-key"), metadataValue(ctx, "authorization"))
+}
+
+// UnaryTracing wraps each call in a server span whose parent is the span ID
+// sent by the caller.
+func UnaryTracing(t *tracing.Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span := startSpan(ctx, t, info.FullMethod)
+		resp, err := handler(ctx, req)
+		endSpan(t, span, err)
+		return resp, err
+	}
+}
+
+// StreamTracing is the stream counterpart of UnaryTracing.
+func StreamTracing(t *tracing.Tracer) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startSpan(ss.Context(), t, info.FullMethod)
+		err := handler(srv, withContext(ss, ctx))
+		endSpan(t, span, err)
+		return err
+	}
+}
+
+func startSpan(ctx context.Context, t *tracing.Tracer, fullMethod string) (context.Context, *tracing.Span) {
+	service, method := splitMethod(fullMethod)
+	opts := []tracing.SpanStartOption{
+		tracing.WithSpanKind(tracing.SpanKindServer),
+		tracing.WithAttributes(map[string]interface{}{
+			"rpc.system":  "grpc",
+			"rpc.service": service,
+			"rpc.method":  method,
+		}),
+	}
+	if parent := metadataValue(ctx, "x-span-id"); parent != "" {
+		opts = append(opts, tracing.WithParentID(parent))
+	}
+	return t.Start(ctx, fullMethod, opts...)
+}
+
+func endSpan(t *tracing.Tracer, span *tracing.Span, err error) {
+	t.SetAttributes(span, map[string]interface{}{"rpc.grpc.status_code": status.Code(err).String()})
+	t.End(span, err)
+}
+
+// UnaryMetrics records MetricRequests and MetricRequestDuration.
+func UnaryMetrics(c *metrics.Collector) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		record(c, info.FullMethod, err, time.Since(start))
+		return resp, err
+	}
+}
+
+// StreamMetrics is the stream counterpart of UnaryMetrics; the duration
+// covers the whole stream.
+func StreamMetrics(c *metrics.Collector) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		record(c, info.FullMethod, err, time.Since(start))
+		return err
+	}
+}
+
+func record(c *metrics.Collector, fullMethod string, err error, elapsed time.Duration) {
+	service, method := splitMethod(fullMethod)
+	labels := map[string]string{"service": service, "method": method, "code": status.Code(err).String()}
+	c.IncCounter(MetricRequests, labels)
+	c.ObserveHistogram(MetricRequestDuration, elapsed.Seconds(), labels)
+}
+
+// UnaryErrors converts errors returned by handlers with ToStatus.
+func UnaryErrors() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, ToStatus(err).Err()
+		}
+		return resp, nil
+	}
+}
+
+// StreamErrors is the stream counterpart of UnaryErrors.
+func StreamErrors() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return ToStatus(err).Err()
+		}
+		return nil
+	}
+}
+
+// UnaryRecovery turns a handler panic into an internal error and logs it
+// with the stack. A nil logger uses zap.L().
+func UnaryRecovery(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer recoverPanic(ctx, logger, info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery is the stream counterpart of UnaryRecovery.
+func StreamRecovery(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recoverPanic(ss.Context(), logger, info.FullMethod, &err)
+		return handler(srv, ss)
+	}
+}
+
+// serverStream replaces the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+func withContext(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	if s, ok := ss.(*serverStream); ok {
+		return &serverStream{ServerStream: s.ServerStream, ctx: ctx}
+	}
+	return &serverStream{ServerStream: ss, ctx: ctx}
+}
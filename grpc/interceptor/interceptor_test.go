@@ -0,0 +1,216 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/auth"
+	framerrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/request"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// testService 以 ServiceDesc 手工注册，避免生成 proto 代码
+type testService struct {
+	unary  func(ctx context.Context, in string) (string, error)
+	stream func(ss grpc.ServerStream) error
+}
+
+var testDesc = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Unary",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, icpt grpc.UnaryServerInterceptor) (any, error) {
+			in := new(wrapperspb.StringValue)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			h := func(ctx context.Context, req any) (any, error) {
+				out, err := srv.(*testService).unary(ctx, req.(*wrapperspb.StringValue).Value)
+				if err != nil {
+					return nil, err
+				}
+				return wrapperspb.String(out), nil
+			}
+			return icpt(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Echo/Unary"}, h)
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Stream",
+		ServerStreams: true,
+		Handler: func(srv any, ss grpc.ServerStream) error {
+			return srv.(*testService).stream(ss)
+		},
+	}},
+}
+
+func dial(t *testing.T, svc *testService, opts Options, clientOpts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(ServerOptions(opts)...)
+	srv.RegisterService(&testDesc, svc)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	clientOpts = append(clientOpts,
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", clientOpts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func callUnary(ctx context.Context, conn *grpc.ClientConn, in string, opts ...grpc.CallOption) (string, error) {
+	out := new(wrapperspb.StringValue)
+	err := conn.Invoke(ctx, "/test.Echo/Unary", wrapperspb.String(in), out, opts...)
+	return out.Value, err
+}
+
+type keyStore map[string]*auth.APIKeyInfo
+
+func (s keyStore) GetByKey(_ context.Context, key string) (*auth.APIKeyInfo, error) {
+	if info, ok := s[key]; ok {
+		return info, nil
+	}
+	return nil, errors.New("unknown key")
+}
+
+func (s keyStore) Validate(context.Context, string) error { return nil }
+
+// counterValue sums the series of name whose labels equal labels.
+func counterValue(c *metrics.Collector, name string, labels map[string]string) float64 {
+	var total float64
+	for key, m := range c.GetMetrics() {
+		if !strings.HasPrefix(key, name+":") || len(m.Labels) != len(labels) {
+			continue
+		}
+		match := true
+		for k, v := range labels {
+			match = match && m.Labels[k] == v
+		}
+		if match {
+			total += m.Value
+		}
+	}
+	return total
+}
+
+func TestRequestContextPropagation(t *testing.T) {
+	svc := &testService{unary: func(ctx context.Context, in string) (string, error) {
+		return request.FromContext(ctx).RequestID + "|" + request.FromContext(ctx).TenantID, nil
+	}}
+	conn := dial(t, svc, Options{}, grpc.WithUnaryInterceptor(UnaryClientPropagation()))
+
+	rc := request.NewRequestContext()
+	rc.RequestID = "req-1"
+	rc.TenantID = "acme"
+	var header metadata.MD
+	out, err := callUnary(rc.ToContext(), conn, "x", grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, "req-1|acme", out)
+	require.Equal(t, []string{"req-1"}, header.Get("x-request-id"))
+	require.Equal(t, []string{rc.TraceID}, header.Get("x-trace-id"))
+
+	// 未携带时生成
+	out, err = callUnary(context.Background(), conn, "x", grpc.Header(&header))
+	require.NoError(t, err)
+	require.NotEqual(t, "|", out)
+	require.Equal(t, []string{out[:len(out)-1]}, header.Get("x-request-id"))
+}
+
+func TestAuthRecoveryAndMetrics(t *testing.T) {
+	collector := metrics.NewCollector()
+	store := keyStore{"k1": {Key: "k1", CreatedBy: "u1", Permissions: []auth.Permission{{Resource: "a", Action: "read"}}}}
+	svc := &testService{unary: func(ctx context.Context, in string) (string, error) {
+		if in == "panic" {
+			panic("boom")
+		}
+		_, keyInfo, _ := auth.GetUserInfoFromContext(ctx)
+		return keyInfo.CreatedBy, nil
+	}}
+	conn := dial(t, svc, Options{
+		Auth:    auth.NewAuthMiddleware(auth.AuthConfig{RequireAPIKey: true}, store, "", nil),
+		Metrics: collector,
+	})
+
+	_, err := callUnary(context.Background(), conn, "x")
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.Equal(t, "API-Key is required", status.Convert(err).Message())
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "k1")
+	out, err := callUnary(ctx, conn, "x")
+	require.NoError(t, err)
+	require.Equal(t, "u1", out)
+
+	_, err = callUnary(ctx, conn, "panic")
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	labels := map[string]string{"service": "test.Echo", "method": "Unary", "code": "Unauthenticated"}
+	require.Equal(t, float64(1), counterValue(collector, MetricRequests, labels))
+	labels["code"] = "Internal"
+	require.Equal(t, float64(1), counterValue(collector, MetricRequests, labels))
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	svc := &testService{stream: func(ss grpc.ServerStream) error {
+		if err := ss.SendMsg(wrapperspb.String(request.FromContext(ss.Context()).RequestID)); err != nil {
+			return err
+		}
+		return framerrors.NewNotFound("order", 7)
+	}}
+	conn := dial(t, svc, Options{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", "req-s")
+	stream, err := conn.NewStream(ctx, &testDesc.Streams[0], "/test.Echo/Stream")
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(wrapperspb.String("")))
+	require.NoError(t, stream.CloseSend())
+
+	msg := new(wrapperspb.StringValue)
+	require.NoError(t, stream.RecvMsg(msg))
+	require.Equal(t, "req-s", msg.Value)
+	err = stream.RecvMsg(msg)
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestToStatus(t *testing.T) {
+	st := ToStatus(framerrors.NewNotFound("user", 1).WithCode("USER_NOT_FOUND"))
+	require.Equal(t, codes.NotFound, st.Code())
+	info := st.Details()[0].(*errdetails.ErrorInfo)
+	require.Equal(t, "USER_NOT_FOUND", info.Reason)
+	require.Equal(t, "user", info.Metadata["resource"])
+
+	st = ToStatus(framerrors.Validation().Field("email", "is required").Build())
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	bad := st.Details()[1].(*errdetails.BadRequest)
+	require.Equal(t, "email", bad.FieldViolations[0].Field)
+
+	// HTTPStatus 覆盖类型默认值，与 HTTP 响应一致
+	st = ToStatus(framerrors.NewInternal("draining").WithHTTPStatus(503))
+	require.Equal(t, codes.Unavailable, st.Code())
+
+	st = ToStatus(errors.New("dial tcp 10.0.0.1: refused"))
+	require.Equal(t, codes.Internal, st.Code())
+	require.NotContains(t, st.Message(), "10.0.0.1")
+
+	require.Equal(t, codes.DeadlineExceeded, ToStatus(context.DeadlineExceeded).Code())
+	require.Equal(t, codes.PermissionDenied, ToStatus(status.Error(codes.PermissionDenied, "no")).Code())
+}
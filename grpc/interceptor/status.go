@@ -0,0 +1,111 @@
+package interceptor
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+
+	framerrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/response"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the ErrorInfo domain of statuses built by ToStatus.
+const ErrorDomain = "leeforge"
+
+// ToStatus maps err to a gRPC status. An *errors.AppError anywhere in the
+// chain gets the code matching the HTTP status response.WriteError would
+// send, its message, and an ErrorInfo detail carrying its Code as reason
+// and its Details as metadata; violations built with errors.Validation are
+// sent as a BadRequest detail. Statuses and context errors pass through;
+// any other error becomes Internal without its message.
+func ToStatus(err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+	var appErr *framerrors.AppError
+	if !stderrors.As(err, &appErr) {
+		switch {
+		case stderrors.Is(err, context.Canceled):
+			return status.New(codes.Canceled, err.Error())
+		case stderrors.Is(err, context.DeadlineExceeded):
+			return status.New(codes.DeadlineExceeded, err.Error())
+		}
+	}
+
+	httpStatus, body := response.FromError(err)
+	st := status.New(CodeFromHTTP(httpStatus), body.Message)
+	if appErr == nil {
+		return st
+	}
+
+	info := &errdetails.ErrorInfo{Reason: appErr.Code, Domain: ErrorDomain}
+	if info.Reason == "" {
+		info.Reason = string(appErr.Type)
+	}
+	var badRequest *errdetails.BadRequest
+	for key, value := range appErr.Details {
+		if violations, ok := value.([]framerrors.FieldViolation); ok && key == framerrors.DetailViolations {
+			badRequest = &errdetails.BadRequest{}
+			for _, v := range violations {
+				badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+					Field:       v.Field,
+					Description: v.Message,
+				})
+			}
+			continue
+		}
+		if info.Metadata == nil {
+			info.Metadata = make(map[string]string, len(appErr.Details))
+		}
+		info.Metadata[key] = fmt.Sprint(value)
+	}
+
+	if badRequest != nil {
+		if withDetails, err := st.WithDetails(info, badRequest); err == nil {
+			return withDetails
+		}
+	} else if withDetails, err := st.WithDetails(info); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+// CodeFromHTTP returns the gRPC code for an HTTP status, following the
+// mapping of grpc-gateway.
+func CodeFromHTTP(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case 499:
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
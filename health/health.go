@@ -0,0 +1,413 @@
+// Package health aggregates named component checks into liveness and
+// readiness reports. Each check runs with its own timeout, state changes are
+// logged, and every check's status is exported as a gauge.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+	"go.uber.org/zap"
+)
+
+// Status is the outcome of a check or of a whole probe.
+type Status string
+
+const (
+	StatusUp   Status = "ok"
+	StatusDown Status = "down"
+	// StatusDegraded means only non-critical checks are failing; the probe
+	// still reports HTTP 200.
+	StatusDegraded Status = "degraded"
+)
+
+// Gauge names exported to the metrics collector. Values are 1 for up and 0
+// otherwise.
+const (
+	MetricCheckStatus = "health_check_status"
+	MetricProbeStatus = "health_status"
+)
+
+// DefaultTimeout bounds a check registered without WithTimeout.
+const DefaultTimeout = 5 * time.Second
+
+// Checker reports a component's health; a nil error means healthy.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function, such as a plugin's HealthCheck method, to
+// Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f.
+func (f CheckerFunc) Check(ctx context.Context) error { return f(ctx) }
+
+// Result is one check's outcome within a Report.
+type Result struct {
+	Status   Status        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"-"`
+	// Since is when the check last changed status.
+	Since    time.Time `json:"since"`
+	Critical bool      `json:"critical"`
+}
+
+// Report is the aggregated outcome of a probe.
+type Report struct {
+	Status   Status            `json:"status"`
+	Checks   map[string]Result `json:"checks,omitempty"`
+	Duration time.Duration     `json:"-"`
+}
+
+// Err returns an error naming every failing critical check, or nil.
+func (r Report) Err() error {
+	var names []string
+	for name, res := range r.Checks {
+		if res.Status == StatusDown && res.Critical {
+			names = append(names, fmt.Sprintf("%s: %s", name, res.Error))
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return errors.New(strings.Join(names, "; "))
+}
+
+// MarshalJSON renders durations as strings such as "1.2ms".
+func (r Result) MarshalJSON() ([]byte, error) {
+	type alias Result
+	return json.Marshal(struct {
+		alias
+		Duration string `json:"duration"`
+	}{alias(r), r.Duration.String()})
+}
+
+// MarshalJSON renders the probe duration as a string.
+func (r Report) MarshalJSON() ([]byte, error) {
+	type alias Report
+	return json.Marshal(struct {
+		alias
+		Duration string `json:"duration"`
+	}{alias(r), r.Duration.String()})
+}
+
+// CheckOption configures a registered check.
+type CheckOption func(*check)
+
+// WithTimeout bounds the check. A check that ignores its context is reported
+// down once the timeout elapses.
+func WithTimeout(d time.Duration) CheckOption {
+	return func(c *check) {
+		if d > 0 {
+			c.timeout = d
+		}
+	}
+}
+
+// Liveness also runs the check for the liveness probe. Reserve it for
+// failures that only a restart can fix, such as a deadlocked worker.
+func Liveness() CheckOption {
+	return func(c *check) { c.liveness = true }
+}
+
+// NonCritical reports the check but lets the probe pass while it fails; the
+// probe status becomes degraded instead of down.
+func NonCritical() CheckOption {
+	return func(c *check) { c.critical = false }
+}
+
+// Option configures a Registry.
+type Option func(*Registry)
+
+// WithLogger logs every status change.
+func WithLogger(logger *zap.Logger) Option {
+	return func(r *Registry) {
+		if logger != nil {
+			r.logger = logger
+		}
+	}
+}
+
+// WithMetrics exports check and probe status gauges to collector.
+func WithMetrics(collector *metrics.Collector) Option {
+	return func(r *Registry) { r.collector = collector }
+}
+
+type check struct {
+	name     string
+	checker  Checker
+	timeout  time.Duration
+	liveness bool
+	critical bool
+
+	// status and since are guarded by Registry.mu.
+	status Status
+	since  time.Time
+}
+
+// Registry holds named checks. It is safe for concurrent use.
+type Registry struct {
+	logger    *zap.Logger
+	collector *metrics.Collector
+
+	mu     sync.Mutex
+	checks map[string]*check
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{
+		logger: zap.NewNop(),
+		checks: make(map[string]*check),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register adds a readiness check under name. It fails when name is empty or
+// already registered.
+func (r *Registry) Register(name string, checker Checker, opts ...CheckOption) error {
+	if name == "" {
+		return errors.New("health: check name is required")
+	}
+	if checker == nil {
+		return fmt.Errorf("health: check %q has no checker", name)
+	}
+	c := &check{
+		name:     name,
+		checker:  checker,
+		timeout:  DefaultTimeout,
+		critical: true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.checks[name]; exists {
+		return fmt.Errorf("health: check %q already registered", name)
+	}
+	r.checks[name] = c
+	return nil
+}
+
+// RegisterFunc is Register for a plain function.
+func (r *Registry) RegisterFunc(name string, fn func(ctx context.Context) error, opts ...CheckOption) error {
+	if fn == nil {
+		return r.Register(name, nil, opts...)
+	}
+	return r.Register(name, CheckerFunc(fn), opts...)
+}
+
+// Unregister removes the named check and its gauge.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	_, ok := r.checks[name]
+	delete(r.checks, name)
+	r.mu.Unlock()
+	if ok && r.collector != nil {
+		r.collector.SetGauge(MetricCheckStatus, 0, map[string]string{"check": name})
+	}
+}
+
+// Names returns the registered check names in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Liveness runs the checks registered with the Liveness option. With none
+// registered the process is reported up.
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.run(ctx, "liveness", func(c *check) bool { return c.liveness })
+}
+
+// Readiness runs every registered check.
+func (r *Registry) Readiness(ctx context.Context) Report {
+	return r.run(ctx, "readiness", func(*check) bool { return true })
+}
+
+// ReadyCheck adapts Readiness to the func(ctx) error shape used by
+// server.Config.ReadyCheck.
+func (r *Registry) ReadyCheck(ctx context.Context) error {
+	return r.Readiness(ctx).Err()
+}
+
+func (r *Registry) run(ctx context.Context, probe string, include func(*check) bool) Report {
+	start := time.Now()
+
+	r.mu.Lock()
+	selected := make([]*check, 0, len(r.checks))
+	for _, c := range r.checks {
+		if include(c) {
+			selected = append(selected, c)
+		}
+	}
+	r.mu.Unlock()
+
+	results := make([]Result, len(selected))
+	var wg sync.WaitGroup
+	for i, c := range selected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(selected))}
+	for i, c := range selected {
+		res := r.record(c, results[i])
+		report.Checks[c.name] = res
+		if res.Status != StatusDown {
+			continue
+		}
+		if res.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+	report.Duration = time.Since(start)
+
+	if r.collector != nil {
+		value := 0.0
+		if report.Status != StatusDown {
+			value = 1
+		}
+		r.collector.SetGauge(MetricProbeStatus, value, map[string]string{"probe": probe})
+	}
+	return report
+}
+
+// runCheck runs c within its timeout. The checker runs in its own goroutine
+// so a checker that ignores ctx cannot hold up the probe.
+func runCheck(ctx context.Context, c *check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("panic: %v", p)
+			}
+		}()
+		done <- c.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", c.timeout)
+	}
+
+	res := Result{Status: StatusUp, Duration: time.Since(start), Critical: c.critical}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// record stores the result on c, logging and exporting status changes.
+func (r *Registry) record(c *check, res Result) Result {
+	r.mu.Lock()
+	previous := c.status
+	if previous != res.Status {
+		c.status = res.Status
+		c.since = time.Now()
+	}
+	res.Since = c.since
+	r.mu.Unlock()
+
+	if r.collector != nil {
+		value := 0.0
+		if res.Status == StatusUp {
+			value = 1
+		}
+		r.collector.SetGauge(MetricCheckStatus, value, map[string]string{"check": c.name})
+	}
+
+	if previous == res.Status {
+		return res
+	}
+	fields := []zap.Field{
+		zap.String("check", c.name),
+		zap.String("status", string(res.Status)),
+		zap.Duration("duration", res.Duration),
+	}
+	if previous != "" {
+		fields = append(fields, zap.String("previous", string(previous)))
+	}
+	switch {
+	case res.Status == StatusUp:
+		r.logger.Info("health check up", fields...)
+	case c.critical:
+		r.logger.Error("health check down", append(fields, zap.String("error", res.Error))...)
+	default:
+		r.logger.Warn("health check down", append(fields, zap.String("error", res.Error))...)
+	}
+	return res
+}
+
+// LivenessHandler serves the liveness report: 200 unless a liveness check is
+// down, then 503.
+func (r *Registry) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		WriteReport(w, r.Liveness(req.Context()))
+	})
+}
+
+// ReadinessHandler serves the readiness report: 200 when up or degraded,
+// 503 when a critical check is down.
+func (r *Registry) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		WriteReport(w, r.Readiness(req.Context()))
+	})
+}
+
+// WriteReport writes report as JSON with 503 when it is down.
+func WriteReport(w http.ResponseWriter, report Report) {
+	code := http.StatusOK
+	if report.Status == StatusDown {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// FromMetrics adapts a metrics threshold check; its issues become the error.
+func FromMetrics(check *metrics.MetricsHealthCheck) Checker {
+	return CheckerFunc(func(context.Context) error {
+		result := check.Check()
+		if result.Healthy {
+			return nil
+		}
+		return errors.New(strings.Join(result.Issues, "; "))
+	})
+}
@@ -0,0 +1,145 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRegistry_AggregatesReadiness(t *testing.T) {
+	r := NewRegistry()
+	dbErr := errors.New("connection refused")
+	require.NoError(t, r.RegisterFunc("db", func(context.Context) error { return dbErr }))
+	require.NoError(t, r.RegisterFunc("cache", func(context.Context) error { return errors.New("miss") }, NonCritical()))
+	require.NoError(t, r.RegisterFunc("queue", func(context.Context) error { return nil }))
+
+	report := r.Readiness(context.Background())
+	require.Equal(t, StatusDown, report.Status)
+	require.Equal(t, StatusDown, report.Checks["db"].Status)
+	require.Equal(t, "connection refused", report.Checks["db"].Error)
+	require.Equal(t, StatusUp, report.Checks["queue"].Status)
+	require.EqualError(t, report.Err(), "db: connection refused")
+
+	dbErr = nil
+	report = r.Readiness(context.Background())
+	require.Equal(t, StatusDegraded, report.Status)
+	require.NoError(t, report.Err())
+}
+
+func TestRegistry_RejectsDuplicatesAndEmptyNames(t *testing.T) {
+	r := NewRegistry()
+	ok := func(context.Context) error { return nil }
+	require.NoError(t, r.RegisterFunc("db", ok))
+	require.Error(t, r.RegisterFunc("db", ok))
+	require.Error(t, r.RegisterFunc("", ok))
+	require.Error(t, r.Register("nil", nil))
+
+	r.Unregister("db")
+	require.Empty(t, r.Names())
+}
+
+func TestRegistry_TimeoutEvenIfCheckerIgnoresContext(t *testing.T) {
+	r := NewRegistry()
+	release := make(chan struct{})
+	defer close(release)
+	require.NoError(t, r.RegisterFunc("stuck", func(context.Context) error {
+		<-release
+		return nil
+	}, WithTimeout(20*time.Millisecond)))
+
+	start := time.Now()
+	report := r.Readiness(context.Background())
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, StatusDown, report.Status)
+	require.Contains(t, report.Checks["stuck"].Error, "timed out")
+}
+
+func TestRegistry_LivenessOnlyRunsLivenessChecks(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.RegisterFunc("db", func(context.Context) error { return errors.New("down") }))
+	require.Equal(t, StatusUp, r.Liveness(context.Background()).Status)
+
+	require.NoError(t, r.RegisterFunc("worker", func(context.Context) error { return errors.New("deadlocked") }, Liveness()))
+	report := r.Liveness(context.Background())
+	require.Equal(t, StatusDown, report.Status)
+	require.Len(t, report.Checks, 1)
+}
+
+func TestRegistry_LogsAndExportsStateChanges(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	collector := metrics.NewCollector()
+	r := NewRegistry(WithLogger(zap.New(core)), WithMetrics(collector))
+
+	var failing bool
+	require.NoError(t, r.RegisterFunc("db", func(context.Context) error {
+		if failing {
+			return errors.New("down")
+		}
+		return nil
+	}))
+
+	gauge := func() float64 {
+		return collector.GetMetric(MetricCheckStatus, map[string]string{"check": "db"}).Value
+	}
+
+	r.Readiness(context.Background())
+	r.Readiness(context.Background())
+	require.Equal(t, 1, logs.FilterMessage("health check up").Len(), "unchanged status logs once")
+	require.Equal(t, 1.0, gauge())
+
+	failing = true
+	r.Readiness(context.Background())
+	require.Equal(t, 1, logs.FilterMessage("health check down").Len())
+	require.Equal(t, 0.0, gauge())
+	require.Equal(t, 0.0, collector.GetMetric(MetricProbeStatus, map[string]string{"probe": "readiness"}).Value)
+
+	failing = false
+	r.Readiness(context.Background())
+	require.Equal(t, 2, logs.FilterMessage("health check up").Len())
+}
+
+func TestRegistry_Handlers(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.RegisterFunc("db", func(context.Context) error { return errors.New("refused") }))
+
+	rec := httptest.NewRecorder()
+	r.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var body struct {
+		Status string `json:"status"`
+		Checks map[string]struct {
+			Status   string `json:"status"`
+			Error    string `json:"error"`
+			Duration string `json:"duration"`
+		} `json:"checks"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, "down", body.Status)
+	require.Equal(t, "refused", body.Checks["db"].Error)
+	require.NotEmpty(t, body.Checks["db"].Duration)
+
+	rec = httptest.NewRecorder()
+	r.LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestFromMetrics(t *testing.T) {
+	collector := metrics.NewCollector()
+	collector.RecordRequest("GET", "/", 200, 0.01)
+	collector.RecordError("GET", "/", errors.New("boom"))
+
+	check := FromMetrics(metrics.NewMetricsHealthCheck(collector, metrics.MetricsHealthThreshold{
+		MaxErrorRate: 0.5, MaxAvgDuration: 1, MaxCacheMissRate: 1,
+	}))
+	require.ErrorContains(t, check.Check(context.Background()), "Error rate too high")
+}
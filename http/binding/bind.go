@@ -0,0 +1,85 @@
+package binding
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/sanitize"
+)
+
+// Bind 绑定查询参数、路径参数与 JSON 请求体到 v 后统一校验。
+// 请求体仅在 POST/PUT/PATCH 且非空时解析，写入顺序为查询参数、路径参数、
+// 请求体，同名字段以后者为准；校验在全部绑定完成后进行，
+// 以免仅出现在请求体中的必填字段在解析查询参数时报错。
+// 校验前按 mod 标签规范化字段（见 sanitize 包）。
+func Bind(r *http.Request, v any) error {
+	parser := NewQueryParser()
+	if err := parser.bind(r.URL.Query(), v); err != nil {
+		return err
+	}
+	if err := parser.bindPath(r, reflect.ValueOf(v).Elem()); err != nil {
+		return err
+	}
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		if err := decodeJSON(r, v, true); err != nil {
+			return err
+		}
+	}
+	sanitize.Struct(r.Context(), v)
+	return ValidateCtx(r.Context(), v)
+}
+
+// AppError 将校验错误转换为 422 的 errors.AppError，
+// 响应 details 为字段、失败规则与提示信息列表
+func (ve ValidationErrors) AppError() *errors.AppError {
+	b := errors.Validation()
+	for _, e := range ve {
+		b.Field(e.Field, e.Message)
+	}
+	appErr := b.Build()
+	if appErr == nil {
+		return nil
+	}
+	violations := appErr.Details[errors.DetailViolations].([]errors.FieldViolation)
+	for i, e := range ve {
+		violations[i].Rule = e.Rule
+	}
+	return appErr.WithHTTPStatus(http.StatusUnprocessableEntity)
+}
+
+// bindPath 将带 path 标签的字段设置为 r.PathValue 的值，
+// chi 与 net/http 的 ServeMux 都会填充路径参数
+func (qp *QueryParser) bindPath(r *http.Request, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Field(i)
+		fieldType := rt.Field(i)
+
+		if fieldType.Anonymous && field.Kind() == reflect.Struct {
+			if err := qp.bindPath(r, field); err != nil {
+				return err
+			}
+			continue
+		}
+		tag, ok := fieldType.Tag.Lookup("path")
+		if !ok || !field.CanSet() {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		value := r.PathValue(name)
+		if value == "" {
+			continue
+		}
+		if err := qp.setFieldValueWithDefault(field, url.Values{name: {value}}, name, fieldType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,12 +1,14 @@
 package binding
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 
 	validatorV10 "github.com/go-playground/validator/v10"
 	"github.com/leeforge/framework/json"
+	"github.com/leeforge/framework/sanitize"
 )
 
 type BindInterface interface {
@@ -22,6 +24,8 @@ type BindError struct {
 	Type    string `json:"type"`
 	Message string `json:"message"`
 	Field   string `json:"field,omitempty"`
+	// Rule is the validate tag that failed, e.g. "required"
+	Rule string `json:"rule,omitempty"`
 }
 
 func (e BindError) Error() string {
@@ -47,7 +51,20 @@ func Query(r *http.Request, v any) error {
 }
 
 func JSON(r *http.Request, v any) error {
-	if r.Body == nil {
+	if err := decodeJSON(r, v, false); err != nil {
+		return err
+	}
+	sanitize.Struct(r.Context(), v)
+	return ValidateCtx(r.Context(), v)
+}
+
+// decodeJSON unmarshals the request body into v. An empty body is an error
+// unless allowEmpty.
+func decodeJSON(r *http.Request, v any, allowEmpty bool) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		if allowEmpty {
+			return nil
+		}
 		return &BindError{
 			Type:    "bind_error",
 			Message: "request body is empty",
@@ -65,6 +82,9 @@ func JSON(r *http.Request, v any) error {
 	}
 
 	if len(body) == 0 {
+		if allowEmpty {
+			return nil
+		}
 		return &BindError{
 			Type:    "bind_error",
 			Message: "request body is empty",
@@ -77,15 +97,27 @@ func JSON(r *http.Request, v any) error {
 			Message: "failed to unmarshal JSON: " + err.Error(),
 		}
 	}
+	return nil
+}
+
+// Validate checks v against its validate tags. Rule failures are returned
+// as ValidationErrors carrying the field, the failed rule and a message.
+func Validate(v any) error {
+	return ValidateCtx(context.Background(), v)
+}
 
-	if err := validator.Struct(v); err != nil {
+// ValidateCtx is Validate passing ctx to rules registered with
+// RegisterValidation. Bind, JSON and Query use the request context.
+func ValidateCtx(ctx context.Context, v any) error {
+	if err := validator.StructCtx(ctx, v); err != nil {
 		if validationErrors, ok := err.(validatorV10.ValidationErrors); ok {
 			var bindErrors ValidationErrors
 			for _, ve := range validationErrors {
 				bindErrors = append(bindErrors, BindError{
 					Type:    "validation_error",
 					Field:   ve.Field(),
-					Message: getValidationMessage(ve),
+					Rule:    ve.Tag(),
+					Message: getValidationMessage(ctx, ve),
 				})
 			}
 			return bindErrors
@@ -1,13 +1,14 @@
 package binding
 
 import (
+	"encoding"
 	"net/http"
 	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
 
-	validatorV10 "github.com/go-playground/validator/v10"
+	"github.com/leeforge/framework/sanitize"
 )
 
 // QueryUnmarshaler 自定义类型可以实现此接口来自定义 query 参数解析
@@ -43,6 +44,12 @@ func NewQueryParser() *QueryParser {
 	}
 }
 
+// SetTagName 设置读取参数名的结构体标签（默认 "query"），如表单绑定使用 "form"；
+// 没有该标签的字段仍回退到 json 标签与小写字段名
+func (qp *QueryParser) SetTagName(tag string) {
+	qp.tagName = tag
+}
+
 // SetArrayStrategy 设置数组解析策略
 func (qp *QueryParser) SetArrayStrategy(strategy ArrayStrategy) {
 	qp.arrayStrategy = strategy
@@ -229,6 +236,20 @@ func (qp *QueryParser) setField(field reflect.Value, values []string, fieldName
 	kind := field.Kind()
 	firstValue := values[0]
 
+	// 实现 encoding.TextUnmarshaler 的类型（如 uuid.UUID、net.IP）按文本解析
+	if field.CanAddr() {
+		if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			if err := u.UnmarshalText([]byte(firstValue)); err != nil {
+				return &BindError{
+					Type:    "bind_error",
+					Field:   fieldName,
+					Message: "invalid value: " + err.Error(),
+				}
+			}
+			return nil
+		}
+	}
+
 	switch kind {
 	case reflect.String:
 		field.SetString(firstValue)
@@ -488,8 +509,15 @@ func (qp *QueryParser) setFieldValueWithDefault(field reflect.Value, values url.
 
 // QueryWithParser 使用自定义解析器解析查询参数
 func QueryWithParser(r *http.Request, v any, parser *QueryParser) error {
-	queryValues := r.URL.Query()
+	if err := parser.bind(r.URL.Query(), v); err != nil {
+		return err
+	}
+	sanitize.Struct(r.Context(), v)
+	return ValidateCtx(r.Context(), v)
+}
 
+// bind 解析查询参数到结构体，不做校验
+func (qp *QueryParser) bind(queryValues url.Values, v any) error {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
 		return &BindError{
@@ -506,27 +534,5 @@ func QueryWithParser(r *http.Request, v any, parser *QueryParser) error {
 		}
 	}
 
-	if err := parser.parseQueryWithDefaults(queryValues, rv, ""); err != nil {
-		return err
-	}
-
-	if err := validator.Struct(v); err != nil {
-		if validationErrors, ok := err.(validatorV10.ValidationErrors); ok {
-			var bindErrors ValidationErrors
-			for _, ve := range validationErrors {
-				bindErrors = append(bindErrors, BindError{
-					Type:    "validation_error",
-					Field:   ve.Field(),
-					Message: getValidationMessage(ve),
-				})
-			}
-			return bindErrors
-		}
-		return &BindError{
-			Type:    "validation_error",
-			Message: err.Error(),
-		}
-	}
-
-	return nil
+	return qp.parseQueryWithDefaults(queryValues, rv, "")
 }
@@ -2,9 +2,12 @@ package binding
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"testing"
+
+	"github.com/google/uuid"
 )
 
 // TestBasicTypes 测试基础类型
@@ -553,6 +556,34 @@ func TestCustomUnmarshaler(t *testing.T) {
 	}
 }
 
+// TestTextUnmarshaler 测试 encoding.TextUnmarshaler 类型
+func TestTextUnmarshaler(t *testing.T) {
+	type QueryParams struct {
+		ID   uuid.UUID  `query:"id"`
+		Prev *uuid.UUID `query:"prev"`
+		IP   net.IP     `query:"ip"`
+	}
+
+	var params QueryParams
+	err := Query(createRequest("id=9b2f8c1e-3d4a-4f6b-8a7c-1e2d3c4b5a69&prev=01890a5d-ac96-774b-bcce-b302099a8057&ip=10.0.0.1"), &params)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if params.ID.String() != "9b2f8c1e-3d4a-4f6b-8a7c-1e2d3c4b5a69" {
+		t.Errorf("ID = %s", params.ID)
+	}
+	if params.Prev == nil || params.Prev.String() != "01890a5d-ac96-774b-bcce-b302099a8057" {
+		t.Errorf("Prev = %v", params.Prev)
+	}
+	if params.IP.String() != "10.0.0.1" {
+		t.Errorf("IP = %s", params.IP)
+	}
+
+	if err := Query(createRequest("id=nope"), &params); err == nil {
+		t.Error("Query() should reject an invalid UUID")
+	}
+}
+
 // TestInvalidInput 测试无效输入
 func TestInvalidInput(t *testing.T) {
 	tests := []struct {
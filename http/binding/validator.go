@@ -1,50 +1,51 @@
 package binding
 
 import (
-	"fmt"
+	"context"
 
 	validatorV10 "github.com/go-playground/validator/v10"
+	"github.com/leeforge/framework/validation"
 )
 
 var validator *validatorV10.Validate
 
+// MessageTranslator returns the message of a failed rule in the language of
+// ctx, or false to keep the default English one. param is the rule's
+// parameter, e.g. "2" for min=2.
+type MessageTranslator = validation.Translator
+
+// SetMessageTranslator localizes validation messages, e.g. with
+// i18n.Bundle.TranslateRule. It is validation.SetTranslator, so config
+// section messages are translated as well; nil removes it.
+func SetMessageTranslator(fn MessageTranslator) {
+	validation.SetTranslator(fn)
+}
+
 func init() {
-	validator = validatorV10.New()
+	// The framework rules (uuid4, slug, phone, tenant_exists, ...) are
+	// available in every request struct.
+	validator = validation.New()
 }
 
-func getValidationMessage(fe validatorV10.FieldError) string {
-	switch fe.Tag() {
-	case "required":
-		return "is required"
-	case "email":
-		return "must be a valid email address"
-	case "min":
-		return fmt.Sprintf("must be at least %s characters long", fe.Param())
-	case "max":
-		return fmt.Sprintf("must be at most %s characters long", fe.Param())
-	case "len":
-		return fmt.Sprintf("must be exactly %s characters long", fe.Param())
-	case "gte":
-		return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
-	case "lte":
-		return fmt.Sprintf("must be less than or equal to %s", fe.Param())
-	case "gt":
-		return fmt.Sprintf("must be greater than %s", fe.Param())
-	case "lt":
-		return fmt.Sprintf("must be less than %s", fe.Param())
-	case "alphanum":
-		return "must contain only alphanumeric characters"
-	case "alpha":
-		return "must contain only alphabetic characters"
-	case "numeric":
-		return "must be a valid number"
-	case "url":
-		return "must be a valid URL"
-	case "uri":
-		return "must be a valid URI"
-	case "oneof":
-		return fmt.Sprintf("must be one of: %s", fe.Param())
-	default:
-		return fmt.Sprintf("failed validation for tag '%s'", fe.Tag())
+// RegisterValidation adds a validate rule usable in struct tags, e.g.
+// `validate:"required,password"`. message is reported when it fails. The
+// validator is shared, so register rules at startup before binding
+// requests.
+func RegisterValidation(tag string, fn validatorV10.FuncCtx, message string) error {
+	if err := validator.RegisterValidationCtx(tag, fn); err != nil {
+		return err
 	}
+	validation.SetMessage(tag, message)
+	return nil
+}
+
+// RegisterStructRules runs cross-field rules, such as
+// validation.RequireOneOf or validation.Ordered, whenever a value of the
+// type of example is validated. Register at startup.
+func RegisterStructRules(example any, rules ...validation.StructRule) {
+	validation.RegisterStruct(validator, example, rules...)
+}
+
+func getValidationMessage(ctx context.Context, fe validatorV10.FieldError) string {
+	return validation.Message(ctx, fe)
 }
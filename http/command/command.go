@@ -0,0 +1,269 @@
+// Package command provides HTTP helpers for asynchronous, CQRS-style command
+// endpoints: the request is validated, enqueued as a job and acknowledged with
+// 202 Accepted plus a tracking URL that clients poll for the job state.
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	framerrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/binding"
+	"github.com/leeforge/framework/http/middleware"
+	"github.com/leeforge/framework/http/responder"
+	"github.com/leeforge/framework/jobs"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client-chosen idempotency key.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set to "true" when a response replays an
+	// earlier acknowledgement for the same idempotency key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// MaxIdempotencyKeyLength bounds accepted idempotency keys.
+	MaxIdempotencyKeyLength = 255
+)
+
+// Validatable is implemented by command payloads with checks that go beyond
+// `validate` struct tags. Errors built with errors.Validation are reported
+// field by field.
+type Validatable interface {
+	Validate() error
+}
+
+// Acknowledgement is the 202 response body of a command endpoint.
+type Acknowledgement struct {
+	JobID     string     `json:"jobId"`
+	State     jobs.State `json:"state"`
+	StatusURL string     `json:"statusUrl"`
+}
+
+// Status is the response body of the status endpoint.
+type Status struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	State      jobs.State      `json:"state"`
+	Attempts   int             `json:"attempts"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	StartedAt  *time.Time      `json:"startedAt,omitempty"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+}
+
+type options struct {
+	statusPath   string
+	idParam      string
+	retryAfter   time.Duration
+	enqueueOpts  func(r *http.Request) []jobs.EnqueueOption
+	requireKey   bool
+	authorizeJob func(r *http.Request, job *jobs.Job) bool
+}
+
+// Option configures Endpoint and StatusHandler.
+type Option func(*options)
+
+// WithStatusPath sets the path prefix of the status endpoint; tracking URLs
+// are built as "<prefix>/<job id>". Defaults to "/jobs".
+func WithStatusPath(prefix string) Option {
+	return func(o *options) {
+		o.statusPath = strings.TrimRight(prefix, "/")
+	}
+}
+
+// WithIDParam sets the chi URL parameter holding the job ID. Defaults to "id".
+func WithIDParam(name string) Option {
+	return func(o *options) {
+		o.idParam = name
+	}
+}
+
+// WithRetryAfter sets the Retry-After hint returned while a job is not
+// finished. Defaults to one second.
+func WithRetryAfter(d time.Duration) Option {
+	return func(o *options) {
+		o.retryAfter = d
+	}
+}
+
+// WithEnqueueOptions derives per-request job options, e.g. the tenant ID.
+func WithEnqueueOptions(fn func(r *http.Request) []jobs.EnqueueOption) Option {
+	return func(o *options) {
+		o.enqueueOpts = fn
+	}
+}
+
+// RequireIdempotencyKey rejects commands sent without an Idempotency-Key.
+func RequireIdempotencyKey() Option {
+	return func(o *options) {
+		o.requireKey = true
+	}
+}
+
+// WithAuthorizer restricts which jobs a caller may read on the status
+// endpoint. Unauthorized lookups respond 404 so job IDs are not disclosed.
+func WithAuthorizer(fn func(r *http.Request, job *jobs.Job) bool) Option {
+	return func(o *options) {
+		o.authorizeJob = fn
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		statusPath: "/jobs",
+		idParam:    "id",
+		retryAfter: time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) statusURL(id string) string {
+	return o.statusPath + "/" + id
+}
+
+// Endpoint returns a handler that binds the JSON body into T, validates it and
+// enqueues it as a jobType job, answering 202 Accepted with a Location header
+// pointing at the status endpoint.
+//
+// Requests carrying an Idempotency-Key header that was already used replay the
+// original acknowledgement; reusing a key with a different body yields 409.
+func Endpoint[T any](manager *jobs.Manager, jobType string, opts ...Option) http.HandlerFunc {
+	o := newOptions(opts)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		res := responder.New(w, r, nil)
+		traceOpt := responder.WithTraceID(middleware.GetTraceIDFromRequest(r))
+
+		key := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+		if key == "" && o.requireKey {
+			res.BadRequest(IdempotencyKeyHeader+" header is required", traceOpt)
+			return
+		}
+		if len(key) > MaxIdempotencyKeyLength {
+			res.BadRequest(IdempotencyKeyHeader+" header is too long", traceOpt)
+			return
+		}
+
+		var cmd T
+		if err := binding.JSON(r, &cmd); err != nil {
+			var verrs binding.ValidationErrors
+			if errors.As(err, &verrs) {
+				res.ValidationError(verrs, traceOpt)
+				return
+			}
+			res.BindError(err, traceOpt)
+			return
+		}
+		if v, ok := any(&cmd).(Validatable); ok {
+			if err := v.Validate(); err != nil {
+				if violations, ok := framerrors.Violations(err); ok {
+					res.ValidationError(violations, traceOpt)
+					return
+				}
+				res.ValidationError([]responder.FieldError{{Message: err.Error()}}, traceOpt)
+				return
+			}
+		}
+
+		var enqueueOpts []jobs.EnqueueOption
+		if o.enqueueOpts != nil {
+			enqueueOpts = o.enqueueOpts(r)
+		}
+		if key != "" {
+			enqueueOpts = append(enqueueOpts, jobs.WithIdempotencyKey(key))
+		}
+
+		job, err := manager.Enqueue(r.Context(), jobType, cmd, enqueueOpts...)
+		switch {
+		case err == nil:
+		case errors.Is(err, jobs.ErrDuplicate):
+			w.Header().Set(IdempotentReplayedHeader, "true")
+		case errors.Is(err, jobs.ErrIdempotencyConflict):
+			res.Conflict(IdempotencyKeyHeader+" was already used with a different request", traceOpt)
+			return
+		case errors.Is(err, jobs.ErrQueueFull), errors.Is(err, jobs.ErrStopped):
+			w.Header().Set("Retry-After", retryAfterSeconds(o.retryAfter))
+			res.ServiceUnavailable("command queue is unavailable", traceOpt)
+			return
+		default:
+			res.InternalServerError("failed to enqueue command", traceOpt)
+			return
+		}
+
+		statusURL := o.statusURL(job.ID)
+		w.Header().Set("Location", statusURL)
+		res.Write(http.StatusAccepted, Acknowledgement{
+			JobID:     job.ID,
+			State:     job.State,
+			StatusURL: statusURL,
+		}, traceOpt)
+	}
+}
+
+// StatusHandler returns a handler reporting the state of the job identified by
+// the chi URL parameter (see WithIDParam). While the job is still pending or
+// running a Retry-After hint is included.
+func StatusHandler(manager *jobs.Manager, opts ...Option) http.HandlerFunc {
+	o := newOptions(opts)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		res := responder.New(w, r, nil)
+		traceOpt := responder.WithTraceID(middleware.GetTraceIDFromRequest(r))
+
+		id := chi.URLParam(r, o.idParam)
+		if id == "" {
+			id = r.PathValue(o.idParam)
+		}
+		if id == "" {
+			res.BadRequest("job id is required", traceOpt)
+			return
+		}
+
+		job, err := manager.Get(r.Context(), id)
+		if errors.Is(err, jobs.ErrNotFound) || (err == nil && o.authorizeJob != nil && !o.authorizeJob(r, job)) {
+			res.NotFound("job not found", traceOpt)
+			return
+		}
+		if err != nil {
+			res.InternalServerError("failed to load job", traceOpt)
+			return
+		}
+
+		if !job.State.Terminal() {
+			w.Header().Set("Retry-After", retryAfterSeconds(o.retryAfter))
+		}
+		res.OK(Status{
+			ID:         job.ID,
+			Type:       job.Type,
+			State:      job.State,
+			Attempts:   job.Attempts,
+			Result:     job.Result,
+			Error:      job.Error,
+			CreatedAt:  job.CreatedAt,
+			StartedAt:  job.StartedAt,
+			FinishedAt: job.FinishedAt,
+		}, traceOpt)
+	}
+}
+
+// Mount registers the status endpoint on r under the configured status path.
+func Mount(r chi.Router, manager *jobs.Manager, opts ...Option) {
+	o := newOptions(opts)
+	r.Get(o.statusPath+"/{"+o.idParam+"}", StatusHandler(manager, opts...))
+}
+
+func retryAfterSeconds(d time.Duration) string {
+	secs := int(d.Round(time.Second) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	return strconv.Itoa(secs)
+}
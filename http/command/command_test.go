@@ -0,0 +1,170 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	framerrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/jobs"
+)
+
+type renameCommand struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func newTestRouter(t *testing.T) (chi.Router, *jobs.Manager) {
+	t.Helper()
+	manager := jobs.NewManager(jobs.Options{})
+	manager.Register("rename", func(_ context.Context, job *jobs.Job) (any, error) {
+		var cmd renameCommand
+		if err := job.Decode(&cmd); err != nil {
+			return nil, err
+		}
+		return map[string]string{"renamed": cmd.Name}, nil
+	})
+	manager.Start()
+	t.Cleanup(func() { manager.Stop(context.Background()) })
+
+	r := chi.NewRouter()
+	r.Post("/rename", Endpoint[renameCommand](manager, "rename"))
+	Mount(r, manager)
+	return r, manager
+}
+
+func post(r http.Handler, body, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/rename", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeData(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+}
+
+func TestEndpointAcceptsAndTracksJob(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	rec := post(r, `{"name":"alice"}`, "")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var ack Acknowledgement
+	decodeData(t, rec, &ack)
+	if ack.StatusURL != "/jobs/"+ack.JobID || rec.Header().Get("Location") != ack.StatusURL {
+		t.Fatalf("unexpected tracking URL: %+v, Location = %q", ack, rec.Header().Get("Location"))
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		statusRec := httptest.NewRecorder()
+		r.ServeHTTP(statusRec, httptest.NewRequest(http.MethodGet, ack.StatusURL, nil))
+		if statusRec.Code != http.StatusOK {
+			t.Fatalf("status endpoint = %d", statusRec.Code)
+		}
+		var status Status
+		decodeData(t, statusRec, &status)
+		if status.State == jobs.StateSucceeded {
+			if string(status.Result) != `{"renamed":"alice"}` {
+				t.Errorf("result = %s", status.Result)
+			}
+			if statusRec.Header().Get("Retry-After") != "" {
+				t.Error("finished job should not carry Retry-After")
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job did not finish, last state %s", status.State)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEndpointIdempotencyKey(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	first := post(r, `{"name":"bob"}`, "key-1")
+	replay := post(r, `{"name":"bob"}`, "key-1")
+	if replay.Code != http.StatusAccepted || replay.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatalf("replay status = %d, headers = %v", replay.Code, replay.Header())
+	}
+	if first.Header().Get("Location") != replay.Header().Get("Location") {
+		t.Error("replayed request should point at the original job")
+	}
+
+	conflict := post(r, `{"name":"carol"}`, "key-1")
+	if conflict.Code != http.StatusConflict {
+		t.Fatalf("conflict status = %d", conflict.Code)
+	}
+}
+
+func TestEndpointValidatesBody(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	if rec := post(r, `{}`, ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	if rec := post(r, `{not json`, ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestStatusHandlerUnknownJob(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/does-not-exist", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+}
+
+type transferCommand struct {
+	Amount int `json:"amount"`
+}
+
+func (c *transferCommand) Validate() error {
+	return framerrors.Validation().Check(c.Amount > 0, "amount", "must be positive").Err()
+}
+
+func TestEndpointReportsBusinessRuleViolations(t *testing.T) {
+	manager := jobs.NewManager(jobs.Options{})
+	handler := Endpoint[transferCommand](manager, "transfer")
+
+	req := httptest.NewRequest(http.MethodPost, "/transfer", strings.NewReader(`{"amount":-1}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	var envelope struct {
+		Error struct {
+			Details []framerrors.FieldViolation `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(envelope.Error.Details) != 1 || envelope.Error.Details[0].Field != "amount" {
+		t.Fatalf("details = %+v", envelope.Error.Details)
+	}
+}
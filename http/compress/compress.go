@@ -0,0 +1,325 @@
+// Package compress encodes responses with zstd, brotli or gzip according to
+// the request's Accept-Encoding. Only allowlisted content types at least
+// MinSize bytes long are compressed, and FileServer serves pre-compressed
+// static assets (app.js.br, app.js.gz, ...) without encoding at request
+// time. Mount Middleware outside the metrics middleware (http/server does)
+// so request metrics see the handler's status and uncompressed size.
+package compress
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported content codings.
+const (
+	Zstd   = "zstd"
+	Brotli = "br"
+	Gzip   = "gzip"
+)
+
+// DefaultMinSize is the smallest body compressed by default. Smaller bodies
+// rarely shrink enough to pay for the encoding.
+const DefaultMinSize = 1024
+
+// DefaultContentTypes are the media types compressed by default. A trailing
+// "/*" matches a whole type; "+json" and "+xml" suffixes are always
+// accepted alongside application/json and application/xml.
+var DefaultContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/xml",
+	"application/javascript",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+// Options configures Middleware.
+type Options struct {
+	// Encodings lists the codings offered, most preferred first (default
+	// zstd, br, gzip). The first one the client accepts is used.
+	Encodings []string
+	// ContentTypes is the allowlist of compressible media types (default
+	// DefaultContentTypes).
+	ContentTypes []string
+	// MinSize is the smallest body compressed (default DefaultMinSize).
+	// Bodies are buffered up to this size before deciding.
+	MinSize int
+}
+
+func (o *Options) applyDefaults() {
+	if len(o.Encodings) == 0 {
+		o.Encodings = []string{Zstd, Brotli, Gzip}
+	}
+	if len(o.ContentTypes) == 0 {
+		o.ContentTypes = DefaultContentTypes
+	}
+	if o.MinSize <= 0 {
+		o.MinSize = DefaultMinSize
+	}
+}
+
+// Middleware compresses eligible responses. Responses that already carry a
+// Content-Encoding, partial content, HEAD requests and protocol upgrades
+// pass through unchanged; strong ETags of compressed responses are
+// weakened since the bytes differ from the identity representation.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	opts.applyDefaults()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			encoding := Negotiate(r.Header.Get("Accept-Encoding"), opts.Encodings)
+			if encoding == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, opts: &opts, encoding: encoding, status: http.StatusOK}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// Negotiate returns the first of offers accepted by an Accept-Encoding
+// header, or "" when none is (identity is then used).
+func Negotiate(acceptEncoding string, offers []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	q := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		weight := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		q[coding] = weight
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		weight, ok := q[offer]
+		if !ok {
+			weight, ok = q["*"]
+		}
+		if ok && weight > bestQ {
+			best, bestQ = offer, weight
+		}
+	}
+	return best
+}
+
+// encoder is the interface shared by the gzip, brotli and zstd writers.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	Gzip: {New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}},
+	Brotli: {New: func() any {
+		// level 4 keeps brotli faster than default gzip for dynamic content
+		return brotli.NewWriterLevel(nil, 4)
+	}},
+	Zstd: {New: func() any {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstd.SpeedDefault))
+		return w
+	}},
+}
+
+// compressWriter buffers the body until MinSize bytes or the end of the
+// response, then decides between compressing and passing through.
+type compressWriter struct {
+	http.ResponseWriter
+	opts     *Options
+	encoding string
+
+	status      int
+	wroteHeader bool // WriteHeader called by the handler
+	decided     bool
+	enc         encoder
+	buf         []byte
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.wroteHeader || w.decided {
+		return
+	}
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.opts.MinSize {
+			return len(p), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush decides immediately, compressing regardless of MinSize when the
+// content type is eligible, and flushes the encoder.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return
+		}
+	}
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the response: short bodies are written uncompressed and
+// the encoder is closed and returned to its pool.
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if len(w.buf) < w.opts.MinSize {
+			w.passThrough()
+		} else if err := w.decide(); err != nil {
+			return err
+		}
+	}
+	if w.enc == nil {
+		return nil
+	}
+	err := w.enc.Close()
+	w.enc.Reset(nil)
+	encoderPools[w.encoding].Put(w.enc)
+	w.enc = nil
+	return err
+}
+
+// Hijack supports protocol switches that were not detected up front.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) decide() error {
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if !w.compressible() {
+		return w.passThrough()
+	}
+
+	h.Add("Vary", "Accept-Encoding")
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+	h.Del("Accept-Ranges")
+	if tag := h.Get("ETag"); tag != "" && !strings.HasPrefix(tag, "W/") {
+		h.Set("ETag", "W/"+tag)
+	}
+	w.decided = true
+	w.ResponseWriter.WriteHeader(w.status)
+
+	w.enc = encoderPools[w.encoding].Get().(encoder)
+	w.enc.Reset(w.ResponseWriter)
+	if len(w.buf) > 0 {
+		if _, err := w.enc.Write(w.buf); err != nil {
+			return err
+		}
+	}
+	w.buf = nil
+	return nil
+}
+
+func (w *compressWriter) passThrough() error {
+	w.decided = true
+	if w.eligibleType() && w.Header().Get("Content-Encoding") == "" {
+		// caches must still key on the coding the client asked for
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *compressWriter) compressible() bool {
+	switch w.status {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+	return w.Header().Get("Content-Encoding") == "" && w.eligibleType()
+}
+
+func (w *compressWriter) eligibleType() bool {
+	return Allowed(w.Header().Get("Content-Type"), w.opts.ContentTypes)
+}
+
+// Allowed reports whether contentType matches the allowlist.
+func Allowed(contentType string, allowlist []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range allowlist {
+		allowed = strings.ToLower(allowed)
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if mediaType == allowed {
+			return true
+		}
+		if (allowed == "application/json" && strings.HasSuffix(mediaType, "+json")) ||
+			(allowed == "application/xml" && strings.HasSuffix(mediaType, "+xml")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/leeforge/framework/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case Gzip:
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		r = zr
+	case Brotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case Zstd:
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		defer zr.Close()
+		r = zr
+	default:
+		return string(body)
+	}
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func do(h http.Handler, target, acceptEncoding string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{Zstd, Brotli, Gzip}
+	require.Equal(t, Zstd, Negotiate("gzip, br, zstd", offers))
+	require.Equal(t, Brotli, Negotiate("gzip;q=0.5, br", []string{Brotli, Gzip}))
+	require.Equal(t, Gzip, Negotiate("gzip;q=1.0, br;q=0.5", offers))
+	require.Equal(t, Brotli, Negotiate("zstd;q=0, *", offers[1:]))
+	require.Equal(t, "", Negotiate("identity", offers))
+	require.Equal(t, "", Negotiate("", offers))
+}
+
+func TestMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"name":"leeforge"},`, 200)
+	h := Middleware(Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{}`))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte(large))
+		case "/error":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.Header().Set("ETag", `"v1"`)
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(large[:600]))
+			_, _ = w.Write([]byte(large[600:]))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(large))
+		}
+	}))
+
+	for _, encoding := range []string{Gzip, Brotli, Zstd} {
+		rec := do(h, "/list", encoding)
+		require.Equal(t, encoding, rec.Header().Get("Content-Encoding"))
+		require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+		require.Less(t, rec.Body.Len(), len(large))
+		require.Equal(t, large, decode(t, encoding, rec.Body.Bytes()))
+	}
+
+	// 状态码保留，强 ETag 被弱化
+	rec := do(h, "/error", "gzip")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, `W/"v1"`, rec.Header().Get("ETag"))
+	require.Equal(t, large, decode(t, Gzip, rec.Body.Bytes()))
+
+	rec = do(h, "/small", "gzip")
+	require.Empty(t, rec.Header().Get("Content-Encoding"))
+	require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+	require.Equal(t, `{}`, rec.Body.String())
+
+	rec = do(h, "/image", "gzip")
+	require.Empty(t, rec.Header().Get("Content-Encoding"))
+	require.Equal(t, large, rec.Body.String())
+
+	rec = do(h, "/list", "")
+	require.Empty(t, rec.Header().Get("Content-Encoding"))
+	require.Equal(t, large, rec.Body.String())
+}
+
+func TestMiddlewareFlush(t *testing.T) {
+	h := Middleware(Options{ContentTypes: []string{"text/event-stream"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("data: 2\n\n"))
+	}))
+	rec := do(h, "/events", "gzip")
+	require.True(t, rec.Flushed)
+	require.Equal(t, Gzip, rec.Header().Get("Content-Encoding"))
+	require.Equal(t, "data: 1\n\ndata: 2\n\n", decode(t, Gzip, rec.Body.Bytes()))
+}
+
+func TestMetricsRecordUncompressedSize(t *testing.T) {
+	collector := metrics.NewCollector()
+	body := strings.Repeat("a", 4096)
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(body))
+	})
+	// 与 http/server 相同的顺序：压缩在指标外层
+	h := Middleware(Options{})(metrics.NewMetricsMiddleware(collector).Middleware(app))
+
+	rec := do(h, "/items", "gzip")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Less(t, rec.Body.Len(), 100)
+
+	var sizes []float64
+	for _, m := range collector.GetMetrics() {
+		if m.Name == "http_response_size_bytes" && m.Labels["status"] == "201" {
+			sizes = append(sizes, m.History...)
+		}
+	}
+	require.Equal(t, []float64{4096}, sizes)
+}
+
+func TestFileServer(t *testing.T) {
+	root := fstest.MapFS{
+		"app.js":    {Data: []byte("console.log(1)")},
+		"app.js.br": {Data: []byte("BROTLI")},
+		"app.js.gz": {Data: []byte("GZIP")},
+		"logo.png":  {Data: []byte("PNG")},
+	}
+	h := FileServer(root)
+
+	rec := do(h, "/app.js", "gzip, br")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, Brotli, rec.Header().Get("Content-Encoding"))
+	require.Equal(t, "text/javascript; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.Equal(t, "BROTLI", rec.Body.String())
+
+	rec = do(h, "/app.js", "gzip")
+	require.Equal(t, "GZIP", rec.Body.String())
+
+	rec = do(h, "/app.js", "")
+	require.Empty(t, rec.Header().Get("Content-Encoding"))
+	require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+	require.Equal(t, "console.log(1)", rec.Body.String())
+
+	// 已压缩的文件不会被中间件再次压缩
+	rec = do(Middleware(Options{MinSize: 1})(h), "/app.js", "gzip")
+	require.Equal(t, "GZIP", rec.Body.String())
+
+	rec = do(h, "/logo.png", "gzip")
+	require.Empty(t, rec.Header().Get("Content-Encoding"))
+	require.Equal(t, "PNG", rec.Body.String())
+}
@@ -0,0 +1,91 @@
+package compress
+
+import (
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// precompressedExt maps codings to the file suffix of pre-compressed assets.
+var precompressedExt = map[string]string{
+	Zstd:   ".zst",
+	Brotli: ".br",
+	Gzip:   ".gz",
+}
+
+// FileServer serves files from root like http.FileServerFS and, when the
+// client accepts it, a pre-compressed sibling built at deploy time
+// ("app.js.br", "app.js.zst", "app.js.gz") with the matching
+// Content-Encoding. encodings lists the codings looked for, most preferred
+// first (default zstd, br, gzip). Directory listings and files without a
+// sibling fall back to http.FileServerFS, which Middleware may still
+// compress.
+func FileServer(root fs.FS, encodings ...string) http.Handler {
+	if len(encodings) == 0 {
+		encodings = []string{Zstd, Brotli, Gzip}
+	}
+	fallback := http.FileServerFS(root)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" || strings.HasSuffix(r.URL.Path, "/") {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+		info, err := fs.Stat(root, name)
+		if err != nil || info.IsDir() {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+
+		accept := r.Header.Get("Accept-Encoding")
+		var offers []string
+		for _, enc := range encodings {
+			if ext, ok := precompressedExt[enc]; ok {
+				if sibling, err := fs.Stat(root, name+ext); err == nil && !sibling.IsDir() {
+					offers = append(offers, enc)
+				}
+			}
+		}
+		if len(offers) > 0 {
+			w.Header().Add("Vary", "Accept-Encoding")
+		}
+		encoding := Negotiate(accept, offers)
+		if encoding == "" {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+
+		f, err := root.Open(name + precompressedExt[encoding])
+		if err != nil {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+
+		// the type of the original file, not of the compressed sibling
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Encoding", encoding)
+		if seeker, ok := f.(io.ReadSeeker); ok {
+			http.ServeContent(w, r, name, info.ModTime(), seeker)
+			return
+		}
+		w.Header().Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodHead {
+			_, _ = io.CopyN(w, f, stat.Size())
+		}
+	})
+}
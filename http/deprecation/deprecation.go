@@ -0,0 +1,276 @@
+// Package deprecation marks routes and parameters as deprecated. Responses
+// carry Deprecation (RFC 9745), Sunset (RFC 8594) and Link headers, every
+// use is counted per client, and a report lists which clients still rely on
+// deprecated surface before it is removed.
+package deprecation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/metrics"
+)
+
+// MetricUsage counts requests using deprecated surface, labelled by
+// surface, kind ("route" or "param") and client.
+const MetricUsage = "api_deprecated_usage_total"
+
+// Kinds of deprecated surface.
+const (
+	KindRoute = "route"
+	KindParam = "param"
+)
+
+// OverflowClient replaces client IDs beyond Options.MaxClients for a surface.
+const OverflowClient = "other"
+
+// Notice describes a deprecation.
+type Notice struct {
+	// Since is when the surface was deprecated. Zero sends "Deprecation: true".
+	Since time.Time `json:"since,omitempty"`
+	// Sunset is when the surface will be removed. Zero omits the Sunset header.
+	Sunset time.Time `json:"sunset,omitempty"`
+	// Link points to migration documentation (rel="deprecation").
+	Link string `json:"link,omitempty"`
+	// Successor points to the replacement (rel="successor-version").
+	Successor string `json:"successor,omitempty"`
+}
+
+// Options configures a Tracker.
+type Options struct {
+	// Metrics, when set, receives MetricUsage.
+	Metrics *metrics.Collector
+	// ClientID identifies the caller. The default is a fingerprint of the
+	// X-API-Key header ("key:<12 hex>") or "anonymous"; raw keys never
+	// become labels.
+	ClientID func(r *http.Request) string
+	// MaxClients bounds the distinct clients tracked per surface (default
+	// 1000); further clients are counted as OverflowClient.
+	MaxClients int
+	// Now returns the current time (default time.Now).
+	Now func() time.Time
+}
+
+// ClientUsage is one client's use of a deprecated surface.
+type ClientUsage struct {
+	Client    string    `json:"client"`
+	Count     int64     `json:"count"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// SurfaceReport lists the clients using one deprecated surface, busiest
+// first.
+type SurfaceReport struct {
+	Surface string        `json:"surface"`
+	Kind    string        `json:"kind"`
+	Notice  Notice        `json:"notice"`
+	Total   int64         `json:"total"`
+	Clients []ClientUsage `json:"clients"`
+}
+
+type surface struct {
+	name    string
+	kind    string
+	notice  Notice
+	clients map[string]*ClientUsage
+}
+
+// Tracker sets deprecation headers and records who uses deprecated surface.
+type Tracker struct {
+	opts Options
+
+	mu       sync.Mutex
+	surfaces map[string]*surface
+}
+
+// NewTracker creates a Tracker.
+func NewTracker(opts Options) *Tracker {
+	if opts.ClientID == nil {
+		opts.ClientID = APIKeyClient
+	}
+	if opts.MaxClients <= 0 {
+		opts.MaxClients = 1000
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Tracker{opts: opts, surfaces: make(map[string]*surface)}
+}
+
+// APIKeyClient identifies clients by a SHA-256 fingerprint of their
+// X-API-Key header.
+func APIKeyClient(r *http.Request) string {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		return "anonymous"
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "key:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// Route returns middleware marking every request it handles as using the
+// deprecated route name, e.g. "GET /v1/users".
+func (t *Tracker) Route(name string, n Notice) func(http.Handler) http.Handler {
+	t.register(name, KindRoute, n)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Mark(w, r, name, KindRoute, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Param returns middleware marking requests that carry the query parameter
+// param as using the deprecated surface name, e.g.
+// Param("GET /v1/users?sort", "sort", n). Requests without it pass through
+// untouched.
+func (t *Tracker) Param(surfaceName, param string, n Notice) func(http.Handler) http.Handler {
+	t.register(surfaceName, KindParam, n)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Has(param) {
+				t.Mark(w, r, surfaceName, KindParam, n)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Mark sets the deprecation headers on w and records the use. Handlers call
+// it directly for surface middleware cannot see, such as body fields; it
+// must run before the response is written.
+func (t *Tracker) Mark(w http.ResponseWriter, r *http.Request, name, kind string, n Notice) {
+	SetHeaders(w.Header(), n)
+	t.record(name, kind, n, t.opts.ClientID(r))
+}
+
+// SetHeaders writes the Deprecation, Sunset and Link headers for n.
+func SetHeaders(h http.Header, n Notice) {
+	if n.Since.IsZero() {
+		h.Set("Deprecation", "true")
+	} else {
+		h.Set("Deprecation", "@"+strconv.FormatInt(n.Since.Unix(), 10))
+	}
+	if !n.Sunset.IsZero() {
+		h.Set("Sunset", n.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if n.Link != "" {
+		h.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"; type="text/html"`, n.Link))
+	}
+	if n.Successor != "" {
+		h.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, n.Successor))
+	}
+}
+
+// register makes the surface appear in the report before its first use.
+func (t *Tracker) register(name, kind string, n Notice) *surface {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.surfaceLocked(name, kind, n)
+}
+
+func (t *Tracker) surfaceLocked(name, kind string, n Notice) *surface {
+	key := kind + " " + name
+	s, ok := t.surfaces[key]
+	if !ok {
+		s = &surface{name: name, kind: kind, notice: n, clients: make(map[string]*ClientUsage)}
+		t.surfaces[key] = s
+	}
+	return s
+}
+
+func (t *Tracker) record(name, kind string, n Notice, client string) {
+	now := t.opts.Now()
+
+	t.mu.Lock()
+	s := t.surfaceLocked(name, kind, n)
+	usage, ok := s.clients[client]
+	if !ok && len(s.clients) >= t.opts.MaxClients {
+		client = OverflowClient
+		usage, ok = s.clients[client]
+	}
+	if !ok {
+		usage = &ClientUsage{Client: client, FirstSeen: now}
+		s.clients[client] = usage
+	}
+	usage.Count++
+	usage.LastSeen = now
+	t.mu.Unlock()
+
+	if t.opts.Metrics != nil {
+		t.opts.Metrics.IncCounter(MetricUsage, map[string]string{
+			"surface": name,
+			"kind":    kind,
+			"client":  client,
+		})
+	}
+}
+
+// Report returns every registered surface sorted by name, with clients
+// ordered by request count.
+func (t *Tracker) Report() []SurfaceReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	reports := make([]SurfaceReport, 0, len(t.surfaces))
+	for _, s := range t.surfaces {
+		rep := SurfaceReport{Surface: s.name, Kind: s.kind, Notice: s.notice, Clients: make([]ClientUsage, 0, len(s.clients))}
+		for _, usage := range s.clients {
+			rep.Total += usage.Count
+			rep.Clients = append(rep.Clients, *usage)
+		}
+		sort.Slice(rep.Clients, func(i, j int) bool {
+			if rep.Clients[i].Count != rep.Clients[j].Count {
+				return rep.Clients[i].Count > rep.Clients[j].Count
+			}
+			return rep.Clients[i].Client < rep.Clients[j].Client
+		})
+		reports = append(reports, rep)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Surface != reports[j].Surface {
+			return reports[i].Surface < reports[j].Surface
+		}
+		return reports[i].Kind < reports[j].Kind
+	})
+	return reports
+}
+
+// Handler serves the report as JSON. ?client= restricts it to surfaces
+// used by that client; ?unused=false drops surfaces nobody calls anymore.
+func (t *Tracker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := r.URL.Query().Get("client")
+		hideUnused := r.URL.Query().Get("unused") == "false"
+
+		reports := t.Report()
+		filtered := reports[:0]
+		for _, rep := range reports {
+			if client != "" {
+				var clients []ClientUsage
+				for _, usage := range rep.Clients {
+					if usage.Client == client {
+						clients = append(clients, usage)
+					}
+				}
+				if len(clients) == 0 {
+					continue
+				}
+				rep.Clients, rep.Total = clients, clients[0].Count
+			}
+			if hideUnused && rep.Total == 0 {
+				continue
+			}
+			filtered = append(filtered, rep)
+		}
+		response.WriteJSON(w, r, http.StatusOK, filtered)
+	})
+}
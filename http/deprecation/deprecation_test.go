@@ -0,0 +1,123 @@
+package deprecation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteSetsHeadersAndCountsClients(t *testing.T) {
+	collector := metrics.NewCollector()
+	tracker := NewTracker(Options{Metrics: collector})
+	notice := Notice{
+		Since:     time.Unix(1700000000, 0),
+		Sunset:    time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		Link:      "https://docs.example.com/migrate-users",
+		Successor: "/v2/users",
+	}
+	h := tracker.Route("GET /v1/users", notice)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, key := range []string{"alpha", "alpha", "beta"} {
+		r := httptest.NewRequest(http.MethodGet, "/v1/users", nil)
+		r.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		require.Equal(t, "@1700000000", rec.Header().Get("Deprecation"))
+		require.Equal(t, "Fri, 01 Jan 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+		require.Equal(t, []string{
+			`<https://docs.example.com/migrate-users>; rel="deprecation"; type="text/html"`,
+			`</v2/users>; rel="successor-version"`,
+		}, rec.Header().Values("Link"))
+	}
+
+	report := tracker.Report()
+	require.Len(t, report, 1)
+	require.Equal(t, int64(3), report[0].Total)
+	require.Len(t, report[0].Clients, 2)
+	alpha := APIKeyClient(&http.Request{Header: http.Header{"X-Api-Key": {"alpha"}}})
+	require.Equal(t, alpha, report[0].Clients[0].Client)
+	require.Equal(t, int64(2), report[0].Clients[0].Count)
+	require.NotContains(t, alpha, "alpha", "raw keys must not leak into labels")
+
+	var counted float64
+	for _, m := range collector.Export() {
+		if m.Name == MetricUsage && m.Labels["client"] == alpha && m.Labels["surface"] == "GET /v1/users" {
+			counted += m.Value
+		}
+	}
+	require.Equal(t, float64(2), counted)
+}
+
+func TestParamOnlyMarksRequestsUsingIt(t *testing.T) {
+	tracker := NewTracker(Options{})
+	h := tracker.Param("GET /v1/users?sort", "sort", Notice{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/users", nil))
+	require.Empty(t, rec.Header().Get("Deprecation"))
+
+	report := tracker.Report()
+	require.Len(t, report, 1, "registered surfaces are reported before first use")
+	require.Zero(t, report[0].Total)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/users?sort=name", nil))
+	require.Equal(t, "true", rec.Header().Get("Deprecation"))
+	require.Empty(t, rec.Header().Get("Sunset"))
+	require.Equal(t, "anonymous", tracker.Report()[0].Clients[0].Client)
+}
+
+func TestMaxClientsOverflow(t *testing.T) {
+	tracker := NewTracker(Options{
+		MaxClients: 2,
+		ClientID:   func(r *http.Request) string { return r.Header.Get("X-Client") },
+	})
+	h := tracker.Route("old", Notice{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, c := range []string{"a", "b", "c", "d", "a"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Client", c)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	clients := map[string]int64{}
+	for _, u := range tracker.Report()[0].Clients {
+		clients[u.Client] = u.Count
+	}
+	require.Equal(t, map[string]int64{"a": 2, "b": 1, OverflowClient: 2}, clients)
+}
+
+func TestHandlerFiltersByClient(t *testing.T) {
+	tracker := NewTracker(Options{ClientID: func(r *http.Request) string { return r.Header.Get("X-Client") }})
+	route := tracker.Route("GET /v1/a", Notice{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tracker.Route("GET /v1/b", Notice{})
+
+	for _, c := range []string{"x", "y", "y"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Client", c)
+		route.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	var body struct {
+		Data []SurfaceReport `json:"data"`
+	}
+	rec := httptest.NewRecorder()
+	tracker.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?client=y", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Data, 1)
+	require.Equal(t, "GET /v1/a", body.Data[0].Surface)
+	require.Equal(t, int64(2), body.Data[0].Total)
+
+	rec = httptest.NewRecorder()
+	tracker.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?unused=false", nil))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Data, 1)
+}
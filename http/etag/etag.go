@@ -0,0 +1,354 @@
+// Package etag adds entity tags and conditional request handling to JSON
+// endpoints. GET responses get an ETag computed from the body or from a
+// version the handler (or Options.Version) supplies; If-None-Match answers
+// 304 without a body, and If-Match/If-None-Match on writes answer 412 when
+// the precondition fails. With a cache.CacheAdapter, representations are
+// stored per version so unchanged resources are served without running the
+// handler.
+package etag
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/leeforge/framework/auth"
+	"github.com/leeforge/framework/cache"
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/json"
+)
+
+// Defaults for Options.
+const (
+	DefaultMaxBody = 1 << 20
+	DefaultTTL     = 5 * time.Minute
+)
+
+// Options configures Middleware.
+type Options struct {
+	// Weak emits weak tags (W/"..."), for representations that are
+	// semantically but not byte-for-byte equal, e.g. when a compression
+	// middleware sits in front.
+	Weak bool
+	// Version returns the current version of the requested resource, e.g.
+	// its revision or updated-at timestamp, without building the response.
+	// It lets GET answer 304 and writes check If-Match before the handler
+	// runs. An empty version means the resource does not exist.
+	Version func(r *http.Request) (string, error)
+	// Store, when set, keeps the representation of each version so a
+	// GET for an unchanged resource is served from it. Requires Version.
+	Store cache.CacheAdapter
+	// TTL of stored representations (default DefaultTTL).
+	TTL time.Duration
+	// Key returns the store key of a request. The default combines the
+	// tenant, user and a fingerprint of the API key from the auth context
+	// with the URI and Accept header, so one caller's representation is
+	// never served to another; a custom Key must keep per-caller responses
+	// apart too, and keep secrets out of the key.
+	Key func(r *http.Request) string
+	// ContentTypes lists the media types tagged (default application/json
+	// and any +json type).
+	ContentTypes []string
+	// MaxBody is the largest body buffered for hashing (default
+	// DefaultMaxBody); larger responses are streamed without a tag.
+	MaxBody int
+}
+
+// Representation is a stored response.
+type Representation struct {
+	ETag        string `json:"etag"`
+	ContentType string `json:"contentType"`
+	Body        []byte `json:"body"`
+}
+
+type versionKey struct{}
+
+// SetVersion lets a handler tag its response with version instead of a
+// hash of the body. Handlers behind Middleware call it with the request
+// context before writing the response.
+func SetVersion(ctx context.Context, version string) {
+	if v, ok := ctx.Value(versionKey{}).(*string); ok {
+		*v = version
+	}
+}
+
+// Tag returns the entity tag for version.
+func Tag(version string, weak bool) string {
+	return hashTag([]byte(version), weak)
+}
+
+func hashTag(data []byte, weak bool) string {
+	sum := sha256.Sum256(data)
+	tag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// Middleware tags responses and evaluates conditional headers.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	if opts.MaxBody <= 0 {
+		opts.MaxBody = DefaultMaxBody
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.Key == nil {
+		opts.Key = defaultKey
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+			current, known := "", false
+			if opts.Version != nil {
+				version, err := opts.Version(r)
+				if err != nil {
+					response.WriteError(w, r, err)
+					return
+				}
+				if version != "" {
+					current = Tag(version, opts.Weak)
+				}
+				known = true
+			}
+
+			if !safe {
+				if known && !preconditionsHold(r, current) {
+					response.WriteError(w, r, errors.New(errors.ErrorTypeConflict, "Precondition Failed").
+						WithCode("PRECONDITION_FAILED").
+						WithHTTPStatus(http.StatusPreconditionFailed))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if current != "" {
+				if match(r.Header.Get("If-None-Match"), current, false) {
+					notModified(w, current)
+					return
+				}
+				if opts.Store != nil {
+					if rep, ok := load(opts.Store, opts.Key(r)); ok && rep.ETag == current {
+						w.Header().Set("ETag", rep.ETag)
+						w.Header().Set("Content-Type", rep.ContentType)
+						w.WriteHeader(http.StatusOK)
+						if r.Method != http.MethodHead {
+							_, _ = w.Write(rep.Body)
+						}
+						return
+					}
+				}
+			}
+
+			var version string
+			ctx := context.WithValue(r.Context(), versionKey{}, &version)
+			bw := &bufferWriter{ResponseWriter: w, opts: &opts}
+			next.ServeHTTP(bw, r.WithContext(ctx))
+			if bw.passthrough {
+				return
+			}
+			if !bw.wroteHeader {
+				bw.status = http.StatusOK
+			}
+
+			tag := w.Header().Get("ETag")
+			switch {
+			case tag != "":
+			case version != "":
+				tag = Tag(version, opts.Weak)
+			case current != "":
+				tag = current
+			default:
+				tag = hashTag(bw.buf.Bytes(), opts.Weak)
+			}
+			w.Header().Set("ETag", tag)
+
+			if opts.Store != nil && (version != "" || current != "") {
+				rep := Representation{ETag: tag, ContentType: w.Header().Get("Content-Type"), Body: bw.buf.Bytes()}
+				_ = opts.Store.Set(opts.Key(r), rep, opts.TTL)
+			}
+			if match(r.Header.Get("If-None-Match"), tag, false) {
+				notModified(w, tag)
+				return
+			}
+			w.WriteHeader(bw.status)
+			_, _ = w.Write(bw.buf.Bytes())
+		})
+	}
+}
+
+// preconditionsHold evaluates If-Match (strong comparison) and
+// If-None-Match for a state-changing request against the current tag.
+func preconditionsHold(r *http.Request, current string) bool {
+	if header := r.Header.Get("If-Match"); header != "" {
+		if current == "" || !match(header, current, true) {
+			return false
+		}
+	}
+	if header := r.Header.Get("If-None-Match"); header != "" && current != "" {
+		return !match(header, current, false)
+	}
+	return true
+}
+
+// match reports whether header, an If-Match or If-None-Match list, matches
+// tag. Strong comparison never matches weak tags.
+func match(header, tag string, strong bool) bool {
+	if header == "" || tag == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if strong && strings.HasPrefix(tag, "W/") {
+		return false
+	}
+	opaque := strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strong && strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if strings.TrimPrefix(candidate, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified answers 304, keeping the validator and caching headers but
+// dropping representation headers.
+func notModified(w http.ResponseWriter, tag string) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	h.Set("ETag", tag)
+	w.WriteHeader(http.StatusNotModified)
+}
+
+func defaultKey(r *http.Request) string {
+	userID, keyInfo, filters := auth.GetUserInfoFromContext(r.Context())
+	var keyID, tenant string
+	if keyInfo != nil && keyInfo.Key != "" {
+		// a fingerprint keeps the secret out of store key names
+		sum := sha256.Sum256([]byte(keyInfo.Key))
+		keyID = hex.EncodeToString(sum[:16])
+	}
+	if t, ok := filters["tenant_id"]; ok && t != nil {
+		tenant = fmt.Sprint(t)
+	}
+	return fmt.Sprintf("etag:%q:%q:%q:GET %s %s", tenant, userID, keyID, r.URL.RequestURI(), r.Header.Get("Accept"))
+}
+
+// load reads a stored representation. Adapters that encode values as JSON
+// (cache.RedisAdapter) return it as a map, which is decoded again.
+func load(store cache.CacheAdapter, key string) (Representation, bool) {
+	value, err := store.Get(key)
+	if err != nil || value == nil {
+		return Representation{}, false
+	}
+	switch rep := value.(type) {
+	case Representation:
+		return rep, true
+	case *Representation:
+		return *rep, true
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return Representation{}, false
+	}
+	var rep Representation
+	if err := json.Unmarshal(raw, &rep); err != nil || rep.ETag == "" {
+		return Representation{}, false
+	}
+	return rep, true
+}
+
+// bufferWriter buffers 200 responses of a tagged content type. Anything
+// else, oversized bodies and flushed responses pass straight through.
+type bufferWriter struct {
+	http.ResponseWriter
+	opts        *Options
+	status      int
+	wroteHeader bool
+	passthrough bool
+	buf         bytes.Buffer
+}
+
+func (w *bufferWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	if status != http.StatusOK || !w.tagged() {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *bufferWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+	if w.buf.Len()+len(p) > w.opts.MaxBody {
+		w.release()
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+// Flush sends the buffered body untagged; a streaming response cannot be
+// hashed.
+func (w *bufferWriter) Flush() {
+	if !w.passthrough {
+		w.release()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *bufferWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *bufferWriter) release() {
+	w.passthrough = true
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+}
+
+func (w *bufferWriter) tagged() bool {
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if len(w.opts.ContentTypes) == 0 {
+		return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+	}
+	for _, allowed := range w.opts.ContentTypes {
+		if strings.EqualFold(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
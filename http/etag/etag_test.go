@@ -0,0 +1,198 @@
+package etag
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/auth"
+	"github.com/leeforge/framework/cache"
+	"github.com/leeforge/framework/http/response"
+	"github.com/stretchr/testify/require"
+)
+
+// mapStore 是测试用的 cache.CacheAdapter
+type mapStore struct {
+	mu sync.Mutex
+	m  map[string]any
+}
+
+func (s *mapStore) Get(key string) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.m[key]; ok {
+		return v, nil
+	}
+	return nil, cache.ErrCacheMiss
+}
+
+func (s *mapStore) Set(key string, value any, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[key] = value
+	return nil
+}
+
+func (s *mapStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, key)
+	return nil
+}
+
+func (s *mapStore) Exists(key string) bool {
+	_, err := s.Get(key)
+	return err == nil
+}
+
+func do(h http.Handler, method, target string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestBodyHash(t *testing.T) {
+	h := Middleware(Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/text" {
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("plain"))
+			return
+		}
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		response.Write(w, r, http.StatusOK, []string{"a", "b"})
+	}))
+
+	rec := do(h, http.MethodGet, "/items")
+	require.Equal(t, http.StatusOK, rec.Code)
+	tag := rec.Header().Get("ETag")
+	require.True(t, strings.HasPrefix(tag, `"`))
+	require.Contains(t, rec.Body.String(), `"data":["a","b"]`)
+
+	rec = do(h, http.MethodGet, "/items", "If-None-Match", `"other", `+tag)
+	require.Equal(t, http.StatusNotModified, rec.Code)
+	require.Zero(t, rec.Body.Len())
+	require.Equal(t, tag, rec.Header().Get("ETag"))
+	require.Empty(t, rec.Header().Get("Content-Type"))
+
+	// 非 JSON 与非 200 响应不打标签
+	require.Empty(t, do(h, http.MethodGet, "/text").Header().Get("ETag"))
+	rec = do(h, http.MethodGet, "/missing")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Empty(t, rec.Header().Get("ETag"))
+}
+
+func TestHandlerVersionAndWeak(t *testing.T) {
+	h := Middleware(Options{Weak: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetVersion(r.Context(), "rev-3")
+		response.Write(w, r, http.StatusOK, map[string]int{"rev": 3})
+	}))
+
+	rec := do(h, http.MethodGet, "/doc")
+	require.Equal(t, Tag("rev-3", true), rec.Header().Get("ETag"))
+	require.True(t, strings.HasPrefix(rec.Header().Get("ETag"), `W/"`))
+
+	// If-None-Match 使用弱比较
+	rec = do(h, http.MethodGet, "/doc", "If-None-Match", strings.TrimPrefix(Tag("rev-3", true), "W/"))
+	require.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func TestVersionPreconditionsAndStore(t *testing.T) {
+	version := "1"
+	calls := 0
+	store := &mapStore{m: map[string]any{}}
+	h := Middleware(Options{
+		Version: func(r *http.Request) (string, error) { return version, nil },
+		Store:   store,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		response.Write(w, r, http.StatusOK, map[string]string{"version": version})
+	}))
+
+	rec := do(h, http.MethodGet, "/doc")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, Tag("1", false), rec.Header().Get("ETag"))
+	body := rec.Body.String()
+
+	// 版本未变：304 与存储的表示都不调用处理函数
+	rec = do(h, http.MethodGet, "/doc", "If-None-Match", Tag("1", false))
+	require.Equal(t, http.StatusNotModified, rec.Code)
+	rec = do(h, http.MethodGet, "/doc")
+	require.Equal(t, body, rec.Body.String())
+	require.Equal(t, Tag("1", false), rec.Header().Get("ETag"))
+	require.Equal(t, 1, calls)
+
+	// If-Match 不匹配时返回 412 且不执行写入
+	rec = do(h, http.MethodPut, "/doc", "If-Match", Tag("0", false))
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	require.Equal(t, 1, calls)
+	rec = do(h, http.MethodPut, "/doc", "If-Match", Tag("1", false))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	// 仅在不存在时创建
+	rec = do(h, http.MethodPut, "/doc", "If-None-Match", "*")
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	// 新版本重新生成
+	version = "2"
+	rec = do(h, http.MethodGet, "/doc", "If-None-Match", Tag("1", false))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"version":"2"`)
+	require.Equal(t, 3, calls)
+}
+
+func TestStoreKeepsCallersApart(t *testing.T) {
+	store := &mapStore{m: map[string]any{}}
+	h := Middleware(Options{
+		Version: func(r *http.Request) (string, error) { return "1", nil },
+		Store:   store,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _, _ := auth.GetUserInfoFromContext(r.Context())
+		response.Write(w, r, http.StatusOK, map[string]string{"owner": userID})
+	}))
+	// 模拟认证中间件写入的上下文
+	authed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), "user_id", r.Header.Get("X-User"))
+		ctx = context.WithValue(ctx, "data_filters", map[string]interface{}{"tenant_id": r.Header.Get("X-Tenant")})
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+
+	require.Contains(t, do(authed, http.MethodGet, "/me", "X-User", "alice", "X-Tenant", "t1").Body.String(), `"owner":"alice"`)
+	require.Contains(t, do(authed, http.MethodGet, "/me", "X-User", "bob", "X-Tenant", "t1").Body.String(), `"owner":"bob"`)
+	require.Contains(t, do(authed, http.MethodGet, "/me", "X-User", "alice", "X-Tenant", "t2").Body.String(), `"owner":"alice"`)
+	require.Len(t, store.m, 3)
+}
+
+func TestDefaultKeyFingerprintsAPIKey(t *testing.T) {
+	const secret = "sk-live-abcdef"
+	r := httptest.NewRequest(http.MethodGet, "/doc", nil)
+	key := defaultKey(r.WithContext(context.WithValue(r.Context(), "api_key_info", &auth.APIKeyInfo{Key: secret})))
+	require.NotContains(t, key, secret)
+
+	other := defaultKey(r.WithContext(context.WithValue(r.Context(), "api_key_info", &auth.APIKeyInfo{Key: "sk-live-other"})))
+	require.NotEqual(t, key, other)
+}
+
+func TestLargeBodyPassesThrough(t *testing.T) {
+	h := Middleware(Options{MaxBody: 8})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.Write(w, r, http.StatusOK, strings.Repeat("x", 64))
+	}))
+	rec := do(h, http.MethodGet, "/big")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get("ETag"))
+	require.Contains(t, rec.Body.String(), strings.Repeat("x", 64))
+}
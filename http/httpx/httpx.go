@@ -0,0 +1,113 @@
+// Package httpx adapts typed business functions to http.Handlers. The
+// request type is decoded from the query string, path parameters and JSON
+// body and validated, the function's error is mapped through an
+// errors.ErrorConverter, and its result is written in the standard response
+// envelope, so handlers hold no decoding or encoding code.
+package httpx
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/binding"
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/http/validate"
+	"github.com/leeforge/framework/i18n"
+)
+
+// Empty is a request or response type without fields. An Empty response is
+// sent as 204 No Content unless a status is configured.
+type Empty struct{}
+
+// Option configures Handler.
+type Option func(*options)
+
+type options struct {
+	status    int
+	converter *errors.ErrorConverter
+}
+
+// WithStatus sets the success status (default 200, or 204 for Empty).
+func WithStatus(status int) Option {
+	return func(o *options) {
+		o.status = status
+	}
+}
+
+// WithConverter sets the converter mapping business errors, e.g. one whose
+// ErrorHandler turns sql.ErrNoRows into a not-found error. The default uses
+// errors.DefaultErrorRegistry.
+func WithConverter(c *errors.ErrorConverter) Option {
+	return func(o *options) {
+		o.converter = c
+	}
+}
+
+// Handler returns an http.HandlerFunc calling fn with the decoded Req.
+//
+// Req is bound with binding.Bind: fields tagged path:"" are read from the
+// route parameters, query:"" from the query string, and json fields from the
+// body of POST, PUT and PATCH requests; validate rules are then checked and
+// failures rendered by validate.WriteError (422). The error returned by fn is
+// mapped through the converter, its message translated for the request's
+// language, and written with response.WriteError; errors that are not
+// *errors.AppError stay a generic 500. Resp is written with response.Write.
+//
+//	r.Get("/users/{id}", httpx.Handler(func(ctx context.Context, req GetUserRequest) (*User, error) {
+//		return svc.Get(ctx, req.ID)
+//	}))
+func Handler[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error), opts ...Option) http.HandlerFunc {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.converter == nil {
+		o.converter = errors.NewErrorConverter(errors.NewErrorHandler(errors.DefaultErrorRegistry()))
+	}
+	status := o.status
+	if status == 0 {
+		status = http.StatusOK
+		if _, empty := any(*new(Resp)).(Empty); empty {
+			status = http.StatusNoContent
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if err := binding.Bind(r, &req); err != nil {
+			validate.WriteError(w, r, err)
+			return
+		}
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			WriteError(w, r, o.converter, err)
+			return
+		}
+		response.Write(w, r, status, resp)
+	}
+}
+
+// WriteError writes err mapped through c. An *errors.AppError in err's chain
+// is passed to c's ErrorHandler; other errors are passed as is, so handlers
+// registered for errors.ErrorTypeUnknown can classify them. Errors that are
+// still unknown afterwards are sent as a generic 500 without their message.
+// The message is translated for the language negotiated by
+// i18n.Bundle.Middleware, or else for the Accept-Language header.
+func WriteError(w http.ResponseWriter, r *http.Request, c *errors.ErrorConverter, err error) {
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) {
+		err = appErr
+	}
+	lang := i18n.Locale(r.Context())
+	if lang == "" {
+		lang = r.Header.Get("Accept-Language")
+	}
+	mapped := c.Localize(err, lang)
+	if mapped == nil || mapped.Type == errors.ErrorTypeUnknown {
+		response.WriteError(w, r, err)
+		return
+	}
+	response.WriteError(w, r, mapped)
+}
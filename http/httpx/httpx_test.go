@@ -0,0 +1,132 @@
+package httpx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/i18n"
+	"github.com/stretchr/testify/require"
+)
+
+type updateRequest struct {
+	ID     int64  `path:"id"`
+	Notify bool   `query:"notify"`
+	Name   string `json:"name" validate:"required"`
+}
+
+type user struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Notify bool   `json:"notify"`
+}
+
+type envelope struct {
+	Data  json.RawMessage `json:"data"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func do(t *testing.T, h http.Handler, method, target, body string, header ...string) (*httptest.ResponseRecorder, envelope) {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	var env envelope
+	if rec.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &env))
+	}
+	return rec, env
+}
+
+func TestHandler(t *testing.T) {
+	r := chi.NewRouter()
+	r.Put("/users/{id}", Handler(func(ctx context.Context, req updateRequest) (user, error) {
+		switch req.Name {
+		case "missing":
+			return user{}, errors.NewNotFound("user", req.ID)
+		case "db":
+			return user{}, fmt.Errorf("query: %w", sql.ErrConnDone)
+		}
+		return user{ID: req.ID, Name: req.Name, Notify: req.Notify}, nil
+	}))
+	r.Delete("/users/{id}", Handler(func(ctx context.Context, req struct {
+		ID int64 `path:"id" validate:"gt=0"`
+	}) (Empty, error) {
+		return Empty{}, nil
+	}))
+
+	rec, env := do(t, r, http.MethodPut, "/users/7?notify=true", `{"name":"ann"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"id":7,"name":"ann","notify":true}`, string(env.Data))
+
+	rec, _ = do(t, r, http.MethodPut, "/users/7", `{}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec, _ = do(t, r, http.MethodPut, "/users/abc", `{"name":"ann"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec, _ = do(t, r, http.MethodPut, "/users/7", `{"name":"missing"}`)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	// 非 AppError 不泄露内部信息
+	rec, env = do(t, r, http.MethodPut, "/users/7", `{"name":"db"}`)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.NotContains(t, env.Error.Message, "connection")
+
+	rec, _ = do(t, r, http.MethodDelete, "/users/7", "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Zero(t, rec.Body.Len())
+	rec, _ = do(t, r, http.MethodDelete, "/users/0", "")
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestHandlerConverter(t *testing.T) {
+	handler := errors.NewErrorHandler(errors.DefaultErrorRegistry())
+	handler.HandleFunc(errors.ErrorTypeUnknown, func(e *errors.AppError) *errors.AppError {
+		if stderrors.Is(e.InnerError, sql.ErrNoRows) {
+			return errors.NewNotFound("user", nil)
+		}
+		return e
+	})
+	h := Handler(func(ctx context.Context, _ Empty) (*user, error) {
+		return nil, sql.ErrNoRows
+	}, WithConverter(errors.NewErrorConverter(handler)), WithStatus(http.StatusAccepted))
+
+	rec, _ := do(t, h, http.MethodGet, "/", "")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	ok := Handler(func(ctx context.Context, _ Empty) (*user, error) {
+		return &user{ID: 1}, nil
+	}, WithStatus(http.StatusAccepted))
+	rec, _ = do(t, ok, http.MethodPost, "/", "")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+}
+
+func TestWriteErrorUsesContextLocale(t *testing.T) {
+	h := Handler(func(ctx context.Context, _ Empty) (*user, error) {
+		return nil, errors.NewNotFound("user", 1)
+	})
+	// i18n 中间件协商出的语言优先于 Accept-Language
+	withLocale := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(i18n.WithLocale(r.Context(), "zh-CN")))
+	})
+	_, env := do(t, withLocale, http.MethodGet, "/", "", "Accept-Language", "en")
+	require.Equal(t, "user 不存在", env.Error.Message)
+
+	_, env = do(t, h, http.MethodGet, "/", "", "Accept-Language", "zh")
+	require.Equal(t, "user 不存在", env.Error.Message)
+}
@@ -0,0 +1,82 @@
+package openapi
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/json"
+)
+
+// DefaultSwaggerUIURL is the swagger-ui-dist base URL used by Handler.
+const DefaultSwaggerUIURL = "https://cdn.jsdelivr.net/npm/swagger-ui-dist@5"
+
+// HandlerOptions configures Handler.
+type HandlerOptions struct {
+	Options
+	// SwaggerUIURL is the base URL of swagger-ui-dist, for deployments
+	// that host the assets themselves.
+	SwaggerUIURL string
+}
+
+// Handler serves the document generated from routes as
+// "<mount>/openapi.json" and Swagger UI on every other path. The document
+// is generated on the first request, after all routes are registered:
+//
+//	r.Handle("/docs*", openapi.Handler(r, openapi.HandlerOptions{
+//		Options: openapi.Options{Info: openapi.Info{Title: "Orders", Version: "1.0.0"}},
+//	}))
+func Handler(routes chi.Routes, opts HandlerOptions) http.Handler {
+	if opts.SwaggerUIURL == "" {
+		opts.SwaggerUIURL = DefaultSwaggerUIURL
+	}
+	var (
+		once sync.Once
+		spec []byte
+		err  error
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			var doc *Document
+			if doc, err = Generate(routes, opts.Options); err == nil {
+				spec, err = json.Marshal(doc)
+			}
+		})
+		if err != nil {
+			response.WriteError(w, r, err)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/openapi.json") {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(spec)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = swaggerUI.Execute(w, map[string]string{
+			"Title":   opts.Info.Title,
+			"Assets":  strings.TrimSuffix(opts.SwaggerUIURL, "/"),
+			"SpecURL": strings.TrimSuffix(r.URL.Path, "/") + "/openapi.json",
+		})
+	})
+}
+
+var swaggerUI = template.Must(template.New("swagger-ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Assets}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.Assets}}/swagger-ui-bundle.js"></script>
+<script>
+window.ui = SwaggerUIBundle({url: {{.SpecURL}}, dom_id: "#swagger-ui", deepLinking: true});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,525 @@
+// Package openapi generates an OpenAPI 3.1 document from a chi router.
+// Routes contribute their permission.Meta (summary, public or protected,
+// permission codes) and, when wrapped with Doc, the request and response
+// types: path, query and header parameters and the JSON body are derived
+// from struct tags as the binding package reads them, constraints from
+// validate rules, and responses use the standard responder envelope.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leeforge/framework/http/responder"
+	"github.com/leeforge/framework/permission"
+)
+
+// Version is the OpenAPI version of generated documents.
+const Version = "3.1.0"
+
+// Security scheme names declared in every document.
+const (
+	SchemeBearer = "bearerAuth"
+	SchemeAPIKey = "apiKey"
+)
+
+// Operation documents a route. Wrap the route's handler with Doc to
+// attach it.
+type Operation struct {
+	// Summary defaults to the permission.Meta description.
+	Summary     string
+	Description string
+	// OperationID defaults to the method and path, e.g. "getUsersById".
+	OperationID string
+	Tags        []string
+	// Request is a struct, or pointer to one, describing the input. Fields
+	// tagged path:"", query:"" or header:"" are parameters; the remaining
+	// json fields are the body for POST, PUT and PATCH and query
+	// parameters otherwise, matching binding.Query's fallback to json tags.
+	Request any
+	// Response is the value returned in the envelope's data field. Nil
+	// documents a response without data.
+	Response any
+	// Status is the success status (default 200, or 201 for POST).
+	Status int
+	// Errors lists documented error statuses besides the defaults (400
+	// when there is input, 401 and 403 for protected routes).
+	Errors     []int
+	Deprecated bool
+}
+
+// DocHandler wraps a handler with its Operation.
+type DocHandler struct {
+	handler   http.Handler
+	Operation Operation
+}
+
+func (h *DocHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+// Unwrap returns the wrapped handler.
+func (h *DocHandler) Unwrap() http.Handler {
+	return h.handler
+}
+
+// Doc attaches op to handler. The result can be registered directly or
+// wrapped by permission.Wrap:
+//
+//	permission.Register(r, http.MethodGet, "/users/{id}",
+//		openapi.Doc(h.Get, openapi.Operation{Request: GetUserRequest{}, Response: User{}}),
+//		permission.Private("获取用户详情", "users:read"))
+func Doc(handler http.HandlerFunc, op Operation) http.Handler {
+	return &DocHandler{handler: handler, Operation: op}
+}
+
+// ExtractOperation returns the Operation attached to handler, looking
+// through chi middleware chains and other wrappers.
+func ExtractOperation(handler http.Handler) (Operation, bool) {
+	for handler != nil {
+		switch h := handler.(type) {
+		case *DocHandler:
+			return h.Operation, true
+		case *chi.ChainHandler:
+			handler = h.Endpoint
+			continue
+		}
+		if u, ok := handler.(interface{ Unwrap() http.Handler }); ok {
+			handler = u.Unwrap()
+			continue
+		}
+		break
+	}
+	return Operation{}, false
+}
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []SecurityRequirement `json:"security,omitempty"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server is a base URL of the API.
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag groups operations.
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower-case methods to operations.
+type PathItem map[string]*OperationObject
+
+// OperationObject is a documented operation.
+type OperationObject struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	// Permissions are the permission codes of the route's permission.Meta.
+	Permissions []string `json:"x-permissions,omitempty"`
+}
+
+// Parameter is a path, query or header parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is a JSON request body.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a content type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds reusable schemas and the security schemes.
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme is an authentication method.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// SecurityRequirement names the schemes an operation accepts. An empty
+// list on an operation marks it public.
+type SecurityRequirement map[string][]string
+
+// Options configures Generate.
+type Options struct {
+	Info    Info
+	Servers []Server
+	Tags    []Tag
+	// Include reports whether a route is documented. By default every
+	// route is, except wildcard mounts without an Operation (static files,
+	// the docs handler itself).
+	Include func(method, path string) bool
+}
+
+// Generate walks routes and builds the document.
+func Generate(routes chi.Routes, opts Options) (*Document, error) {
+	g := &generator{registry: newSchemaRegistry()}
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    opts.Info,
+		Servers: opts.Servers,
+		Tags:    opts.Tags,
+		Paths:   make(map[string]PathItem),
+		Security: []SecurityRequirement{
+			{SchemeBearer: {}},
+			{SchemeAPIKey: {}},
+		},
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = "API"
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "0.0.0"
+	}
+
+	err := chi.Walk(routes, func(method, route string, handler http.Handler, _ ...func(http.Handler) http.Handler) error {
+		op, documented := ExtractOperation(handler)
+		if opts.Include != nil {
+			if !opts.Include(method, route) {
+				return nil
+			}
+		} else if !documented && strings.HasSuffix(route, "*") {
+			return nil
+		}
+		meta, hasMeta := permission.ExtractMeta(handler)
+		path, pathParams := convertPath(route)
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(method)] = g.operation(strings.ToUpper(method), path, pathParams, op, meta, hasMeta)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Components = Components{
+		Schemas: g.registry.schemas,
+		SecuritySchemes: map[string]SecurityScheme{
+			SchemeBearer: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			SchemeAPIKey: {Type: "apiKey", In: "header", Name: "X-API-Key"},
+		},
+	}
+	return doc, nil
+}
+
+type generator struct {
+	registry *schemaRegistry
+}
+
+func (g *generator) operation(method, path string, pathParams []Parameter, op Operation, meta permission.Meta, hasMeta bool) *OperationObject {
+	out := &OperationObject{
+		OperationID: op.OperationID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Deprecated:  op.Deprecated,
+		Permissions: meta.Permissions,
+		Responses:   make(map[string]Response),
+	}
+	if out.OperationID == "" {
+		out.OperationID = operationID(method, path)
+	}
+	if out.Summary == "" {
+		out.Summary = meta.Description
+	}
+	if meta.IsPublic {
+		out.Security = []SecurityRequirement{}
+	}
+
+	params, body := g.request(method, op.Request)
+	// declared path parameters replace the generated string ones
+	for _, p := range pathParams {
+		if !hasParam(params, p.Name, "path") {
+			params = append(params, p)
+		}
+	}
+	sort.SliceStable(params, func(i, j int) bool { return paramOrder(params[i].In) < paramOrder(params[j].In) })
+	out.Parameters = params
+	if body != nil {
+		out.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: body}},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+		if method == http.MethodPost {
+			status = http.StatusCreated
+		}
+	}
+	out.Responses[strconv.Itoa(status)] = g.success(status, op.Response)
+
+	errorStatuses := append([]int(nil), op.Errors...)
+	if len(params) > 0 || body != nil {
+		errorStatuses = append(errorStatuses, http.StatusBadRequest)
+	}
+	if hasMeta && !meta.IsPublic {
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	for _, code := range errorStatuses {
+		out.Responses[strconv.Itoa(code)] = g.failure(code)
+	}
+	return out
+}
+
+// request derives parameters and the body schema from the request type.
+func (g *generator) request(method string, v any) ([]Parameter, *Schema) {
+	if v == nil {
+		return nil, nil
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, g.registry.schemaFor(t)
+	}
+
+	hasBody := method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+	var params []Parameter
+	bodyFields := 0
+	g.collectParams(t, "", hasBody, &params, &bodyFields)
+	if !hasBody || bodyFields == 0 {
+		return params, nil
+	}
+	if len(params) == 0 && t.Name() != "" {
+		return nil, g.registry.schemaFor(t)
+	}
+	body := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.registry.addFields(body, t, isBodyField)
+	return params, body
+}
+
+// collectParams appends the parameters of t. Without a body, json fields
+// are query parameters and nested structs use dotted names, as
+// binding.Query parses them.
+func (g *generator) collectParams(t reflect.Type, prefix string, hasBody bool, params *[]Parameter, bodyFields *int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		in, name := paramLocation(f)
+		if in == "" {
+			if f.Anonymous && f.Tag.Get("json") == "" && ft.Kind() == reflect.Struct {
+				g.collectParams(ft, prefix, hasBody, params, bodyFields)
+				continue
+			}
+			if !f.IsExported() || f.Tag.Get("json") == "-" {
+				continue
+			}
+			if hasBody {
+				*bodyFields++
+				continue
+			}
+			in, name = "query", queryName(f)
+		}
+		if name == "-" {
+			continue
+		}
+		if in == "query" && ft.Kind() == reflect.Struct && ft != timeType && !reflect.PointerTo(ft).Implements(textMarshalerType) {
+			g.collectParams(ft, prefix+name+".", false, params, bodyFields)
+			continue
+		}
+
+		schema := g.registry.schemaFor(f.Type)
+		required := applyValidate(schema, f.Tag.Get("validate"))
+		if def, ok := f.Tag.Lookup("default"); ok {
+			schema.Default = parseDefault(schema.Type, def)
+		}
+		if in == "path" {
+			required = true
+		} else if in == "query" {
+			name = prefix + name
+		}
+		*params = append(*params, Parameter{
+			Name:        name,
+			In:          in,
+			Description: f.Tag.Get("doc"),
+			Required:    required,
+			Schema:      schema,
+		})
+	}
+}
+
+// paramLocation returns where an explicitly tagged parameter is sent.
+func paramLocation(f reflect.StructField) (in, name string) {
+	for _, loc := range []string{"path", "query", "header"} {
+		if tag, ok := f.Tag.Lookup(loc); ok {
+			name, _, _ = strings.Cut(tag, ",")
+			if name == "" {
+				name = f.Name
+			}
+			return loc, name
+		}
+	}
+	return "", ""
+}
+
+// queryName returns the query parameter name binding.Query uses for f.
+func queryName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" {
+		return name
+	}
+	return strings.ToLower(f.Name)
+}
+
+// isBodyField reports whether f belongs to the JSON body.
+func isBodyField(f reflect.StructField) bool {
+	in, _ := paramLocation(f)
+	return in == ""
+}
+
+func (g *generator) success(status int, data any) Response {
+	envelope := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"meta": g.registry.schemaFor(reflect.TypeOf(responder.Meta{})),
+		},
+		Required: []string{"meta"},
+	}
+	if data != nil {
+		envelope.Properties["data"] = g.registry.schemaFor(reflect.TypeOf(data))
+	}
+	if status == http.StatusNoContent {
+		return Response{Description: http.StatusText(status)}
+	}
+	return Response{
+		Description: http.StatusText(status),
+		Content:     map[string]MediaType{"application/json": {Schema: envelope}},
+	}
+}
+
+func (g *generator) failure(status int) Response {
+	envelope := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"error": g.registry.schemaFor(reflect.TypeOf(responder.Error{})),
+			"meta":  g.registry.schemaFor(reflect.TypeOf(responder.Meta{})),
+		},
+		Required: []string{"error", "meta"},
+	}
+	return Response{
+		Description: http.StatusText(status),
+		Content:     map[string]MediaType{"application/json": {Schema: envelope}},
+	}
+}
+
+var pathParamRex = regexp.MustCompile(`\{([^}:]+)(?::([^}]+))?\}`)
+
+// convertPath turns a chi pattern into an OpenAPI path, dropping regexp
+// constraints into the parameter schemas.
+func convertPath(route string) (string, []Parameter) {
+	var params []Parameter
+	path := pathParamRex.ReplaceAllStringFunc(route, func(m string) string {
+		sub := pathParamRex.FindStringSubmatch(m)
+		schema := &Schema{Type: "string"}
+		if sub[2] != "" {
+			schema.Pattern = "^" + sub[2] + "$"
+		}
+		params = append(params, Parameter{Name: sub[1], In: "path", Required: true, Schema: schema})
+		return "{" + sub[1] + "}"
+	})
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path, params
+}
+
+// operationID builds an ID such as "getUsersById" from method and path.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, seg := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '_' || r == '.' }) {
+		if strings.HasPrefix(seg, "{") {
+			b.WriteString("By")
+			seg = strings.Trim(seg, "{}")
+		}
+		if seg == "*" || seg == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(seg[:1]) + seg[1:])
+	}
+	return b.String()
+}
+
+func hasParam(params []Parameter, name, in string) bool {
+	for _, p := range params {
+		if p.Name == name && p.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+func paramOrder(in string) int {
+	switch in {
+	case "path":
+		return 0
+	case "query":
+		return 1
+	default:
+		return 2
+	}
+}
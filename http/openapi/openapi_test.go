@@ -0,0 +1,171 @@
+package openapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leeforge/framework/json"
+	"github.com/leeforge/framework/permission"
+	"github.com/stretchr/testify/require"
+)
+
+type Address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type User struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name" doc:"display name"`
+	Email     string    `json:"email,omitempty"`
+	Address   *Address  `json:"address,omitempty"`
+	Friends   []User    `json:"friends,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	secret    string
+}
+
+type ListUsersRequest struct {
+	Page     int      `query:"page" default:"1" validate:"min=1"`
+	PageSize int      `json:"pageSize" validate:"max=100"`
+	Status   string   `json:"status" validate:"omitempty,oneof=active disabled"`
+	Tags     []string `json:"tags"`
+}
+
+type UpdateUserRequest struct {
+	ID      int64    `path:"id"`
+	TraceID string   `header:"X-Trace-Id"`
+	Name    string   `json:"name" validate:"required,min=2,max=32"`
+	Email   string   `json:"email" validate:"omitempty,email"`
+	Roles   []string `json:"roles" validate:"dive,oneof=admin user"`
+}
+
+type CreateUserRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func noop(http.ResponseWriter, *http.Request) {}
+
+func newRouter() chi.Router {
+	r := chi.NewRouter()
+	r.Route("/users", func(r chi.Router) {
+		permission.Get(r, "/", noop, permission.Private("用户列表", "users:read"))
+		permission.Register(r, http.MethodPost, "/",
+			Doc(noop, Operation{Request: CreateUserRequest{}, Response: User{}, Tags: []string{"users"}}),
+			permission.Private("创建用户", "users:write"))
+		permission.Register(r, http.MethodPut, "/{id:[0-9]+}",
+			Doc(noop, Operation{Request: &UpdateUserRequest{}, Response: User{}, Errors: []int{http.StatusNotFound}}),
+			permission.Private("更新用户", "users:write"))
+	})
+	r.With(func(next http.Handler) http.Handler { return next }).
+		Method(http.MethodGet, "/search", permission.Wrap(
+			Doc(noop, Operation{Summary: "Search", Request: ListUsersRequest{}, Response: []User{}}),
+			permission.Public("搜索")))
+	r.Handle("/static/*", http.HandlerFunc(noop))
+	return r
+}
+
+func TestGenerate(t *testing.T) {
+	doc, err := Generate(newRouter(), Options{Info: Info{Title: "Users", Version: "1.0.0"}})
+	require.NoError(t, err)
+	require.Equal(t, Version, doc.OpenAPI)
+	require.NotContains(t, doc.Paths, "/static/*")
+
+	// 仅有 permission.Meta 的路由
+	list := doc.Paths["/users"]["get"]
+	require.Equal(t, "用户列表", list.Summary)
+	require.Equal(t, "getUsers", list.OperationID)
+	require.Equal(t, []string{"users:read"}, list.Permissions)
+	require.Nil(t, list.Security)
+	require.Contains(t, list.Responses, "401")
+	require.Contains(t, list.Responses, "403")
+
+	// POST 请求体引用组件，默认 201
+	create := doc.Paths["/users"]["post"]
+	require.Equal(t, "#/components/schemas/CreateUserRequest", create.RequestBody.Content["application/json"].Schema.Ref)
+	require.Contains(t, create.Responses, "201")
+	require.Equal(t, []string{"name"}, doc.Components.Schemas["CreateUserRequest"].Required)
+	data := create.Responses["201"].Content["application/json"].Schema.Properties["data"]
+	require.Equal(t, "#/components/schemas/User", data.Ref)
+
+	user := doc.Components.Schemas["User"]
+	require.NotContains(t, user.Properties, "secret")
+	require.Equal(t, "display name", user.Properties["name"].Description)
+	require.Equal(t, "date-time", user.Properties["createdAt"].Format)
+	require.Equal(t, "#/components/schemas/User", user.Properties["friends"].Items.Ref)
+	require.Equal(t, []string{"city"}, doc.Components.Schemas["Address"].Required)
+
+	// 路径、头参数与请求体子集
+	update := doc.Paths["/users/{id}"]["put"]
+	require.Len(t, update.Parameters, 2)
+	require.Equal(t, Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}}, update.Parameters[0])
+	require.Equal(t, "header", update.Parameters[1].In)
+	body := update.RequestBody.Content["application/json"].Schema
+	require.Equal(t, []string{"name"}, body.Required)
+	require.NotContains(t, body.Properties, "ID")
+	require.Equal(t, 2, *body.Properties["name"].MinLength)
+	require.Equal(t, 32, *body.Properties["name"].MaxLength)
+	require.Equal(t, "email", body.Properties["email"].Format)
+	require.Equal(t, []any{"admin", "user"}, body.Properties["roles"].Items.Enum)
+	require.Contains(t, update.Responses, "404")
+	require.Contains(t, update.Responses, "400")
+
+	// 公开路由与查询参数
+	search := doc.Paths["/search"]["get"]
+	require.Equal(t, "Search", search.Summary)
+	require.NotNil(t, search.Security)
+	require.Empty(t, search.Security)
+	require.NotContains(t, search.Responses, "401")
+	params := map[string]Parameter{}
+	for _, p := range search.Parameters {
+		require.Equal(t, "query", p.In)
+		params[p.Name] = p
+	}
+	require.Equal(t, int64(1), params["page"].Schema.Default)
+	require.Equal(t, float64(1), *params["page"].Schema.Minimum)
+	require.Equal(t, float64(100), *params["pageSize"].Schema.Maximum)
+	require.Equal(t, []any{"active", "disabled"}, params["status"].Schema.Enum)
+	require.Equal(t, "array", params["tags"].Schema.Type)
+	require.Equal(t, "array", search.Responses["200"].Content["application/json"].Schema.Properties["data"].Type)
+}
+
+func TestPathWithoutRequest(t *testing.T) {
+	r := chi.NewRouter()
+	r.Delete("/orders/{orderID:[a-z0-9-]+}/items/{item}", noop)
+
+	doc, err := Generate(r, Options{})
+	require.NoError(t, err)
+	op := doc.Paths["/orders/{orderID}/items/{item}"]["delete"]
+	require.Equal(t, "deleteOrdersByOrderIDItemsByItem", op.OperationID)
+	require.Len(t, op.Parameters, 2)
+	require.Equal(t, "^[a-z0-9-]+$", op.Parameters[0].Schema.Pattern)
+	require.Equal(t, "item", op.Parameters[1].Name)
+}
+
+func TestHandler(t *testing.T) {
+	r := newRouter()
+	r.Handle("/docs*", Handler(r, HandlerOptions{Options: Options{Info: Info{Title: "Users"}}}))
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/docs/openapi.json")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	var doc Document
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&doc))
+	require.Contains(t, doc.Paths, "/users/{id}")
+	require.NotContains(t, doc.Paths, "/docs*")
+	require.Contains(t, doc.Components.SecuritySchemes, SchemeBearer)
+
+	res, err = http.Get(srv.URL + "/docs")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	html, _ := io.ReadAll(res.Body)
+	require.True(t, strings.HasPrefix(res.Header.Get("Content-Type"), "text/html"))
+	require.Contains(t, string(html), DefaultSwaggerUIURL+"/swagger-ui-bundle.js")
+	require.Contains(t, string(html), `"/docs/openapi.json"`)
+}
@@ -0,0 +1,308 @@
+package openapi
+
+import (
+	"encoding"
+	stdjson "encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is an OpenAPI 3.1 (JSON Schema 2020-12) schema.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	rawMessageType      = reflect.TypeOf(stdjson.RawMessage{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	invalidNameCharsRex = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// schemaRegistry turns Go types into schemas. Named structs become
+// components referenced with $ref so recursive types terminate.
+type schemaRegistry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// schemaFor returns the schema of t.
+func (g *schemaRegistry) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "string", Description: "duration, e.g. 1m30s"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Kind() != reflect.Struct && reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Struct && reflect.PointerTo(t).Implements(textMarshalerType):
+		// uuid.UUID and similar value types encode as strings
+		s := &Schema{Type: "string"}
+		if t.Name() == "UUID" {
+			s.Format = "uuid"
+		}
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + g.component(t)}
+	default:
+		// interface{} and anything else accepts any value
+		return &Schema{}
+	}
+}
+
+// component registers the named struct t and returns its component name.
+func (g *schemaRegistry) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := invalidNameCharsRex.ReplaceAllString(t.Name(), "_")
+	if _, taken := g.schemas[name]; taken {
+		pkg := t.PkgPath()
+		name = invalidNameCharsRex.ReplaceAllString(pkg[strings.LastIndex(pkg, "/")+1:], "_") + "." + name
+	}
+	g.names[t] = name
+	g.schemas[name] = &Schema{} // placeholder for recursive references
+	*g.schemas[name] = *g.structSchema(t)
+	return name
+}
+
+// structSchema builds an object schema from the json fields of t.
+func (g *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t, nil)
+	return s
+}
+
+// addFields adds the json fields of t to s. Fields of embedded structs are
+// promoted as encoding/json does. keep, when set, selects fields.
+func (g *schemaRegistry) addFields(s *Schema, t reflect.Type, keep func(reflect.StructField) bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		name, skip := jsonName(f)
+		if skip {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" && ft.Kind() == reflect.Struct {
+			g.addFields(s, ft, keep)
+			continue
+		}
+		if !f.IsExported() || (keep != nil && !keep(f)) {
+			continue
+		}
+
+		prop := g.schemaFor(f.Type)
+		if desc := f.Tag.Get("doc"); desc != "" {
+			// OpenAPI 3.1 allows keywords next to $ref
+			prop.Description = desc
+		}
+		if def, ok := f.Tag.Lookup("default"); ok && prop.Ref == "" {
+			prop.Default = parseDefault(prop.Type, def)
+		}
+		s.Properties[name] = prop
+		if applyValidate(prop, f.Tag.Get("validate")) {
+			s.Required = appendUnique(s.Required, name)
+		}
+	}
+}
+
+// jsonName returns the JSON property name of f.
+func jsonName(f reflect.StructField) (name string, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+	return name, false
+}
+
+// applyValidate maps go-playground/validator rules onto s and reports
+// whether the field is required.
+func applyValidate(s *Schema, tag string) bool {
+	required := false
+	target := s
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		if name == "dive" && s.Items != nil {
+			// the following rules apply to the elements
+			target = s.Items
+			continue
+		}
+		switch name {
+		case "required":
+			required = required || target == s
+		case "email":
+			target.Format = "email"
+		case "url", "uri", "http_url":
+			target.Format = "uri"
+		case "uuid", "uuid4", "uuid7":
+			target.Format = "uuid"
+		case "ip", "ipv4":
+			target.Format = "ipv4"
+		case "ipv6":
+			target.Format = "ipv6"
+		case "datetime":
+			target.Format = "date-time"
+		case "alphanum":
+			target.Pattern = "^[a-zA-Z0-9]+$"
+		case "alpha":
+			target.Pattern = "^[a-zA-Z]+$"
+		case "numeric":
+			target.Pattern = "^[-+]?[0-9]+(\\.[0-9]+)?$"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				target.Enum = append(target.Enum, parseDefault(target.Type, v))
+			}
+		case "min", "gte":
+			setBound(target, param, true, false)
+		case "max", "lte":
+			setBound(target, param, false, false)
+		case "gt":
+			setBound(target, param, true, true)
+		case "lt":
+			setBound(target, param, false, true)
+		case "len":
+			setBound(target, param, true, false)
+			setBound(target, param, false, false)
+		}
+	}
+	return required
+}
+
+// setBound sets a length, item count or numeric bound depending on the
+// schema type, as the validator interprets min/max.
+func setBound(s *Schema, param string, lower, exclusive bool) {
+	switch s.Type {
+	case "string", "array", "object":
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return
+		}
+		if exclusive {
+			if lower {
+				n++
+			} else {
+				n--
+			}
+		}
+		switch {
+		case s.Type == "string" && lower:
+			s.MinLength = &n
+		case s.Type == "string":
+			s.MaxLength = &n
+		case s.Type == "array" && lower:
+			s.MinItems = &n
+		case s.Type == "array":
+			s.MaxItems = &n
+		}
+	case "integer", "number":
+		v, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		switch {
+		case lower && exclusive:
+			s.ExclusiveMinimum = &v
+		case lower:
+			s.Minimum = &v
+		case exclusive:
+			s.ExclusiveMaximum = &v
+		default:
+			s.Maximum = &v
+		}
+	}
+}
+
+// parseDefault converts a tag value to the JSON type of the schema.
+func parseDefault(typ, value string) any {
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, s := range list {
+		if s == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
@@ -0,0 +1,91 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// assetHasher computes and caches short content hashes of assets.
+type assetHasher struct {
+	fsys   fs.FS
+	reload bool
+
+	mu     sync.RWMutex
+	hashes map[string]string
+}
+
+func newAssetHasher(fsys fs.FS, reload bool) *assetHasher {
+	return &assetHasher{fsys: fsys, reload: reload, hashes: make(map[string]string)}
+}
+
+// hash returns the first 12 hex digits of the SHA-256 of the asset at name.
+func (h *assetHasher) hash(name string) (string, error) {
+	if !h.reload {
+		h.mu.RLock()
+		sum, ok := h.hashes[name]
+		h.mu.RUnlock()
+		if ok {
+			return sum, nil
+		}
+	}
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	s := sha256.New()
+	if _, err := io.Copy(s, f); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(s.Sum(nil))[:12]
+	if !h.reload {
+		h.mu.Lock()
+		h.hashes[name] = sum
+		h.mu.Unlock()
+	}
+	return sum, nil
+}
+
+// asset is the "asset" template function: {{asset "css/app.css"}} returns
+// "/static/css/app.css?v=<hash>", so URLs change with the content and can
+// be cached forever.
+func (r *Renderer) asset(name string) (string, error) {
+	if r.opts.Assets == nil {
+		return "", fmt.Errorf("asset %s: no Options.Assets configured", name)
+	}
+	clean := strings.TrimPrefix(path.Clean("/"+name), "/")
+	sum, err := r.assets.hash(clean)
+	if err != nil {
+		return "", fmt.Errorf("asset %s: %w", name, err)
+	}
+	return r.opts.AssetPrefix + clean + "?v=" + sum, nil
+}
+
+// AssetHandler serves Options.Assets under Options.AssetPrefix. Requests
+// whose ?v= matches the current content hash are cacheable for a year;
+// others must be revalidated.
+func (r *Renderer) AssetHandler() http.Handler {
+	if r.opts.Assets == nil {
+		return http.NotFoundHandler()
+	}
+	files := http.FileServerFS(r.opts.Assets)
+	return http.StripPrefix(strings.TrimSuffix(r.opts.AssetPrefix, "/"), http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name := strings.TrimPrefix(path.Clean(req.URL.Path), "/")
+		if v := req.URL.Query().Get("v"); v != "" {
+			if sum, err := r.assets.hash(name); err == nil && sum == v {
+				w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			}
+		}
+		if w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		files.ServeHTTP(w, req)
+	}))
+}
@@ -0,0 +1,318 @@
+// Package render renders server-side HTML pages with html/template. Pages
+// are composed with a layout and shared partials, receive a per-request
+// View carrying the handler's data, the current user, the CSRF token and
+// flash messages, and can translate text and link content-hashed assets.
+// Templates are parsed once and cached; with Reload they are re-parsed
+// whenever a file changes, for development.
+//
+//	r, err := render.New(render.Options{
+//		Templates: os.DirFS("web/templates"),
+//		Assets:    os.DirFS("web/static"),
+//		User:      currentUser, // func(*http.Request) any
+//		Reload:    env_mode.Mode() == env_mode.DevMode,
+//	})
+//	router.Handle("/static/*", r.AssetHandler())
+//
+//	func (h *Handler) Users(w http.ResponseWriter, req *http.Request) {
+//		h.render.HTML(w, req, http.StatusOK, "users/index", users)
+//	}
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+// ErrTemplateNotFound is returned when rendering an unknown page.
+var ErrTemplateNotFound = errors.New("render: template not found")
+
+// Options configures a Renderer.
+type Options struct {
+	// Templates holds the page templates:
+	//
+	//	layouts/default.html   shared chrome around {{template "content" .}}
+	//	partials/nav.html      {{define "nav"}}...{{end}}, usable by every page
+	//	users/index.html       rendered as "users/index"
+	//
+	// A page's top-level content is the "content" block of its layout;
+	// blocks the page defines, such as {{define "title"}}, replace the
+	// layout's {{block "title" .}} defaults.
+	Templates fs.FS
+	// Extension of template files (default ".html").
+	Extension string
+	// Layout is the layout pages use unless they define a "layout" block
+	// naming another one, or an empty one to opt out (default "default").
+	// Pages are rendered without a layout when the default one is missing.
+	Layout string
+	// Funcs are available to every template, next to the built-ins.
+	Funcs template.FuncMap
+	// Reload re-parses the templates when a file changed and re-hashes
+	// assets on every use. Enable it in development only.
+	Reload bool
+
+	// User returns the current user for View.User.
+	User func(r *http.Request) any
+	// Locale returns the request's locale for View.Locale.
+	Locale func(r *http.Request) string
+	// Translate backs View.T; without it T formats the key with args.
+	Translate func(ctx context.Context, key string, args ...any) string
+
+	// Assets holds static files linked with the "asset" function.
+	Assets fs.FS
+	// AssetPrefix is the URL path Assets are served under (default
+	// "/static/").
+	AssetPrefix string
+
+	// FlashCookie names the flash message cookie (default "_flash").
+	FlashCookie string
+	// FlashSecret signs flash cookies so clients cannot forge messages;
+	// without it they are only encoded.
+	FlashSecret []byte
+	// Secure marks the flash cookie Secure.
+	Secure bool
+}
+
+// Renderer renders pages. It is safe for concurrent use.
+type Renderer struct {
+	opts   Options
+	assets *assetHasher
+
+	mu          sync.RWMutex
+	pages       map[string]*template.Template
+	fingerprint string
+}
+
+// New parses the templates and returns a Renderer.
+func New(opts Options) (*Renderer, error) {
+	if opts.Templates == nil {
+		return nil, errors.New("render: Options.Templates is required")
+	}
+	if opts.Extension == "" {
+		opts.Extension = ".html"
+	}
+	if opts.Layout == "" {
+		opts.Layout = "default"
+	}
+	if opts.AssetPrefix == "" {
+		opts.AssetPrefix = "/static/"
+	}
+	if !strings.HasSuffix(opts.AssetPrefix, "/") {
+		opts.AssetPrefix += "/"
+	}
+	if opts.FlashCookie == "" {
+		opts.FlashCookie = "_flash"
+	}
+	r := &Renderer{opts: opts, assets: newAssetHasher(opts.Assets, opts.Reload)}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// HTML renders the page called name with data and writes it with status.
+// The page is rendered into a buffer first, so on error nothing is
+// written and the caller can still send an error page.
+func (r *Renderer) HTML(w http.ResponseWriter, req *http.Request, status int, name string, data any) error {
+	view := r.NewView(w, req, data)
+	var buf bytes.Buffer
+	if err := r.execute(&buf, name, view); err != nil {
+		return err
+	}
+	if view.flashesRead {
+		clearFlashes(w, r.flashCookie())
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// execute renders the page called name with view into buf.
+func (r *Renderer) execute(buf *bytes.Buffer, name string, view *View) error {
+	if r.opts.Reload {
+		if err := r.reload(); err != nil {
+			return err
+		}
+	}
+	r.mu.RLock()
+	tmpl, ok := r.pages[name]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	if err := tmpl.Execute(buf, view); err != nil {
+		return fmt.Errorf("render: %s: %w", name, err)
+	}
+	return nil
+}
+
+// Names returns the page names.
+func (r *Renderer) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.pages))
+	for name := range r.pages {
+		names = append(names, name)
+	}
+	return names
+}
+
+// source is a template file without its extension.
+type source struct {
+	name, text string
+}
+
+// reload re-parses the templates when the fingerprint of the files changed.
+func (r *Renderer) reload() error {
+	fp, err := fingerprint(r.opts.Templates, r.opts.Extension)
+	if err != nil {
+		return err
+	}
+	r.mu.RLock()
+	same := fp == r.fingerprint
+	r.mu.RUnlock()
+	if same {
+		return nil
+	}
+	return r.load()
+}
+
+// load parses every page into its own template set: the layout as the
+// entry point, then the partials, then the page, so the page's blocks win.
+func (r *Renderer) load() error {
+	fp, err := fingerprint(r.opts.Templates, r.opts.Extension)
+	if err != nil {
+		return err
+	}
+	var partials, pages []source
+	layouts := map[string]string{}
+	err = fs.WalkDir(r.opts.Templates, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != r.opts.Extension {
+			return err
+		}
+		text, err := fs.ReadFile(r.opts.Templates, p)
+		if err != nil {
+			return err
+		}
+		s := source{name: strings.TrimSuffix(p, r.opts.Extension), text: string(text)}
+		switch {
+		case strings.HasPrefix(s.name, "partials/"):
+			partials = append(partials, s)
+		case strings.HasPrefix(s.name, "layouts/"):
+			layouts[strings.TrimPrefix(s.name, "layouts/")] = s.text
+		default:
+			pages = append(pages, s)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("render: read templates: %w", err)
+	}
+
+	funcs := r.funcs()
+	parsed := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		entry := `{{template "content" .}}`
+		layout, explicit := r.layoutOf(page.text, funcs)
+		if text, ok := layouts[layout]; ok {
+			entry = text
+		} else if explicit && layout != "" {
+			return fmt.Errorf("render: template %s: layout %q not found", page.name, layout)
+		}
+		tmpl, err := template.New(page.name).Funcs(funcs).Parse(entry)
+		if err != nil {
+			return fmt.Errorf("render: parse layout %q for %s: %w", layout, page.name, err)
+		}
+		for _, p := range partials {
+			if _, err := tmpl.New(p.name).Parse(p.text); err != nil {
+				return fmt.Errorf("render: parse template %s for %s: %w", p.name, page.name, err)
+			}
+		}
+		if _, err := tmpl.New("content").Parse(page.text); err != nil {
+			return fmt.Errorf("render: parse template %s: %w", page.name, err)
+		}
+		parsed[page.name] = tmpl
+	}
+
+	r.mu.Lock()
+	r.pages, r.fingerprint = parsed, fp
+	r.mu.Unlock()
+	return nil
+}
+
+// layoutOf returns the layout named by the page's "layout" block, or the
+// default layout when the page has none. It uses text/template because an
+// executed html/template can no longer be parsed into.
+func (r *Renderer) layoutOf(page string, funcs template.FuncMap) (string, bool) {
+	t, err := texttemplate.New("page").Funcs(texttemplate.FuncMap(funcs)).Parse(page)
+	if err != nil || t.Lookup("layout") == nil {
+		// Parse errors surface when the page set is parsed.
+		return r.opts.Layout, false
+	}
+	var b strings.Builder
+	if err := t.ExecuteTemplate(&b, "layout", nil); err != nil {
+		return r.opts.Layout, false
+	}
+	return strings.TrimSpace(b.String()), true
+}
+
+// fingerprint summarises the names, sizes and modification times of the
+// template files.
+func fingerprint(fsys fs.FS, ext string) (string, error) {
+	var b strings.Builder
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ext {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", p, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("render: read templates: %w", err)
+	}
+	return b.String(), nil
+}
+
+// funcs returns the built-in functions merged with Options.Funcs.
+func (r *Renderer) funcs() template.FuncMap {
+	funcs := template.FuncMap{
+		"asset": r.asset,
+		"dict":  dict,
+		"now":   time.Now,
+	}
+	for name, fn := range r.opts.Funcs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// dict builds a map from key/value pairs, to pass several values to a
+// partial: {{template "field" dict "Label" "Email" "View" .}}.
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict: odd number of arguments")
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
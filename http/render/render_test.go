@@ -0,0 +1,233 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/leeforge/framework/security"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/default.html": {Data: []byte(`<title>{{block "title" .}}Admin{{end}}</title>{{template "nav" .}}<main>{{template "content" .}}</main>`)},
+		"layouts/bare.html":    {Data: []byte(`<body>{{template "content" .}}</body>`)},
+		"partials/nav.html":    {Data: []byte(`{{define "nav"}}<nav>{{with .User}}{{.}}{{else}}guest{{end}}</nav>{{end}}`)},
+		"users/index.html":     {Data: []byte(`{{define "title"}}Users{{end}}{{range .Data}}<li>{{.}}</li>{{end}}`)},
+		"login.html":           {Data: []byte(`{{define "layout"}}bare{{end}}<form>{{.CSRFField}}</form>{{range .Flashes}}<p class="{{.Kind}}">{{.Message}}</p>{{end}}`)},
+		"raw.html":             {Data: []byte(`{{define "layout"}}{{end}}{{.T "hello %s" .Data}}`)},
+		"assets.html":          {Data: []byte(`{{define "layout"}}{{end}}<link href="{{asset "css/app.css"}}">`)},
+	}
+}
+
+func newTestRenderer(t *testing.T, opts Options) *Renderer {
+	t.Helper()
+	if opts.Templates == nil {
+		opts.Templates = testFS()
+	}
+	r, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func render(t *testing.T, r *Renderer, req *http.Request, name string, data any) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	if err := r.HTML(rec, req, http.StatusOK, name, data); err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestLayoutsPartialsAndEscaping(t *testing.T) {
+	r := newTestRenderer(t, Options{User: func(*http.Request) any { return "ada" }})
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+
+	rec := render(t, r, req, "users/index", []string{"<script>x</script>"})
+	want := `<title>Users</title><nav>ada</nav><main><li>&lt;script&gt;x&lt;/script&gt;</li></main>`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("body = %s\nwant   %s", got, want)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("content type = %q", ct)
+	}
+
+	// 页面指定布局或不使用布局
+	rec = render(t, r, req, "login", nil)
+	if !strings.HasPrefix(rec.Body.String(), "<body><form>") {
+		t.Errorf("bare layout body = %s", rec.Body.String())
+	}
+	rec = render(t, r, req, "raw", "<b>")
+	if got := rec.Body.String(); got != "hello &lt;b&gt;" {
+		t.Errorf("no layout body = %s", got)
+	}
+}
+
+func TestErrorsWriteNothing(t *testing.T) {
+	r := newTestRenderer(t, Options{})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	if err := r.HTML(rec, req, http.StatusOK, "missing", nil); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("missing err = %v", err)
+	}
+	// 未配置 Assets 时 asset 函数报错，响应保持未写入
+	if err := r.HTML(rec, req, http.StatusOK, "assets", nil); err == nil {
+		t.Error("asset without Options.Assets rendered")
+	}
+	if rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "" {
+		t.Errorf("partial response written: %q", rec.Body.String())
+	}
+
+	_, err := New(Options{Templates: fstest.MapFS{
+		"page.html": {Data: []byte(`{{define "layout"}}nope{{end}}x`)},
+	}})
+	if err == nil || !strings.Contains(err.Error(), `layout "nope" not found`) {
+		t.Errorf("unknown layout err = %v", err)
+	}
+}
+
+func TestCSRFAndTranslate(t *testing.T) {
+	csrf, err := security.NewCSRF(security.CSRFConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRenderer(t, Options{
+		Locale: func(req *http.Request) string { return "zh-CN" },
+		Translate: func(ctx context.Context, key string, args ...any) string {
+			return "你好 " + args[0].(string)
+		},
+	})
+
+	var body string
+	h := csrf.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		view := r.NewView(w, req, nil)
+		if view.CSRFToken == "" || view.Locale != "zh-CN" {
+			t.Errorf("view = %+v", view)
+		}
+		if got := view.T("hello %s", "ada"); got != "你好 ada" {
+			t.Errorf("T = %q", got)
+		}
+		rec := httptest.NewRecorder()
+		r.HTML(rec, req, http.StatusOK, "login", nil)
+		body = rec.Body.String()
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/login", nil))
+	if !strings.Contains(body, `<input type="hidden" name="csrf_token" value="`) {
+		t.Errorf("body = %s", body)
+	}
+}
+
+func TestFlashes(t *testing.T) {
+	r := newTestRenderer(t, Options{FlashSecret: []byte("flash-secret")})
+
+	// POST 处理函数添加两条消息后重定向
+	post := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	if err := r.AddFlash(post, req, FlashError, "Wrong <password>"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddFlash(post, req, FlashInfo, "Try again"); err != nil {
+		t.Fatal(err)
+	}
+	cookies := post.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("cookies = %v", cookies)
+	}
+
+	get := httptest.NewRequest(http.MethodGet, "/login", nil)
+	get.AddCookie(cookies[0])
+	rec := render(t, r, get, "login", nil)
+	body := rec.Body.String()
+	if !strings.Contains(body, `<p class="error">Wrong &lt;password&gt;</p><p class="info">Try again</p>`) {
+		t.Errorf("body = %s", body)
+	}
+	cleared := rec.Result().Cookies()
+	if len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("flash cookie not cleared: %v", cleared)
+	}
+
+	// 伪造或篡改的 Cookie 被忽略
+	forged := httptest.NewRequest(http.MethodGet, "/login", nil)
+	forged.AddCookie(&http.Cookie{Name: "_flash", Value: strings.Split(cookies[0].Value, ".")[0] + ".AAAA"})
+	if body := render(t, r, forged, "login", nil).Body.String(); strings.Contains(body, "<p") {
+		t.Errorf("forged flash rendered: %s", body)
+	}
+
+	// 不读取消息的页面不会清除 Cookie
+	if got := render(t, r, get, "users/index", nil).Result().Cookies(); len(got) != 0 {
+		t.Errorf("cookies = %v", got)
+	}
+}
+
+func TestAssets(t *testing.T) {
+	assets := fstest.MapFS{"css/app.css": {Data: []byte("body{}")}}
+	r := newTestRenderer(t, Options{Assets: assets})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	body := render(t, r, req, "assets", nil).Body.String()
+	const prefix = `<link href="/static/css/app.css?v=`
+	if !strings.HasPrefix(body, prefix) {
+		t.Fatalf("body = %s", body)
+	}
+	version := strings.TrimSuffix(strings.TrimPrefix(body, prefix), `">`)
+	if len(version) != 12 {
+		t.Fatalf("version = %q", version)
+	}
+
+	h := r.AssetHandler()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/css/app.css?v="+version, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "body{}" ||
+		rec.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+		t.Errorf("versioned: %d %q %q", rec.Code, rec.Body.String(), rec.Header().Get("Cache-Control"))
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/css/app.css?v=stale", nil))
+	if rec.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("stale version cache control = %q", rec.Header().Get("Cache-Control"))
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "home.html")
+	if err := os.WriteFile(page, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cached := newTestRenderer(t, Options{Templates: os.DirFS(dir)})
+	reloading := newTestRenderer(t, Options{Templates: os.DirFS(dir), Reload: true})
+
+	if err := os.WriteFile(page, []byte("version 2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 确保修改时间变化
+	later := time.Now().Add(time.Second)
+	os.Chtimes(page, later, later)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if got := render(t, cached, req, "home", nil).Body.String(); got != "v1" {
+		t.Errorf("cached = %q", got)
+	}
+	if got := render(t, reloading, req, "home", nil).Body.String(); got != "version 2" {
+		t.Errorf("reloaded = %q", got)
+	}
+}
+
+func TestDict(t *testing.T) {
+	r := newTestRenderer(t, Options{Templates: fstest.MapFS{
+		"partials/field.html": {Data: []byte(`{{define "field"}}<label>{{.Label}}</label>{{end}}`)},
+		"form.html":           {Data: []byte(`{{template "field" dict "Label" .Data}}`)},
+	}})
+	if got := render(t, r, httptest.NewRequest(http.MethodGet, "/", nil), "form", "E&mail").Body.String(); got != "<label>E&amp;mail</label>" {
+		t.Errorf("body = %s", got)
+	}
+}
@@ -0,0 +1,220 @@
+package render
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/leeforge/framework/security"
+)
+
+// View is the dot of every page, layout and partial:
+//
+//	<title>{{block "title" .}}Admin{{end}}</title>
+//	{{range .Flashes}}<div class="alert-{{.Kind}}">{{.Message}}</div>{{end}}
+//	<form method="post">{{.CSRFField}} ...</form>
+//	{{with .Data}}{{range .Users}}...{{end}}{{end}}
+//	<p>{{.T "users.count" (len .Data.Users)}}</p>
+type View struct {
+	// Data is what the handler passed to HTML.
+	Data any
+	// User is Options.User's result, nil without it.
+	User any
+	// Locale is Options.Locale's result.
+	Locale string
+	// CSRFToken is the masked token of security.CSRF's middleware, empty
+	// when the request did not pass through it.
+	CSRFToken string
+	// CSRFField is a hidden form input carrying CSRFToken.
+	CSRFField template.HTML
+	// Request is the request being served.
+	Request *http.Request
+
+	renderer    *Renderer
+	w           http.ResponseWriter
+	flashes     []Flash
+	flashesRead bool
+}
+
+// NewView returns the View HTML would render with; useful for rendering
+// pages elsewhere, such as in tests or emails.
+func (r *Renderer) NewView(w http.ResponseWriter, req *http.Request, data any) *View {
+	v := &View{
+		Data:      data,
+		CSRFToken: security.CSRFToken(req),
+		CSRFField: security.CSRFField(req),
+		Request:   req,
+		renderer:  r,
+		w:         w,
+	}
+	if r.opts.User != nil {
+		v.User = r.opts.User(req)
+	}
+	if r.opts.Locale != nil {
+		v.Locale = r.opts.Locale(req)
+	}
+	return v
+}
+
+// T translates key with args through Options.Translate.
+func (v *View) T(key string, args ...any) string {
+	if t := v.renderer.opts.Translate; t != nil {
+		return t(v.Request.Context(), key, args...)
+	}
+	if len(args) == 0 {
+		return key
+	}
+	return fmt.Sprintf(key, args...)
+}
+
+// Flashes returns the flash messages set by previous responses and not yet
+// shown, plus those added while serving this request. Reading them clears
+// the flash cookie once the page renders.
+func (v *View) Flashes() []Flash {
+	if !v.flashesRead {
+		v.flashesRead = true
+		name := v.renderer.opts.FlashCookie
+		if c, err := v.Request.Cookie(name); err == nil {
+			v.flashes = v.renderer.decodeFlashes(c.Value)
+		}
+		v.flashes = append(v.flashes, v.renderer.pendingFlashes(v.w)...)
+	}
+	return v.flashes
+}
+
+// Flash kinds; any string works as a kind.
+const (
+	FlashSuccess = "success"
+	FlashInfo    = "info"
+	FlashWarning = "warning"
+	FlashError   = "error"
+)
+
+// Flash is a one-time message shown on the next rendered page, typically
+// after a redirect.
+type Flash struct {
+	Kind    string `json:"k"`
+	Message string `json:"m"`
+}
+
+// maxFlashCookie keeps the flash cookie well under browsers' 4 KiB limit.
+const maxFlashCookie = 3072
+
+// AddFlash queues a message for the next page this client renders. It sets
+// a cookie, so call it before writing the response, e.g. before
+// http.Redirect. Messages are HTML-escaped when rendered.
+func (r *Renderer) AddFlash(w http.ResponseWriter, req *http.Request, kind, message string) error {
+	flashes := r.pendingFlashes(w)
+	if flashes == nil {
+		// Carry over messages that were never shown.
+		if c, err := req.Cookie(r.opts.FlashCookie); err == nil {
+			flashes = r.decodeFlashes(c.Value)
+		}
+	}
+	flashes = append(flashes, Flash{Kind: kind, Message: message})
+	value, err := r.encodeFlashes(flashes)
+	if err != nil {
+		return err
+	}
+	if len(value) > maxFlashCookie {
+		return fmt.Errorf("render: flash messages exceed %d bytes", maxFlashCookie)
+	}
+	removeSetCookie(w, r.opts.FlashCookie)
+	http.SetCookie(w, &http.Cookie{
+		Name:     r.opts.FlashCookie,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.opts.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func (r *Renderer) flashCookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     r.opts.FlashCookie,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.opts.Secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// clearFlashes replaces any flash cookie set on w with an expired one.
+func clearFlashes(w http.ResponseWriter, c *http.Cookie) {
+	removeSetCookie(w, c.Name)
+	c.MaxAge = -1
+	http.SetCookie(w, c)
+}
+
+// pendingFlashes returns the flashes already set on w by AddFlash.
+func (r *Renderer) pendingFlashes(w http.ResponseWriter) []Flash {
+	if w == nil {
+		return nil
+	}
+	values := w.Header().Values("Set-Cookie")
+	for i := len(values) - 1; i >= 0; i-- {
+		c, err := http.ParseSetCookie(values[i])
+		if err == nil && c.Name == r.opts.FlashCookie && c.MaxAge >= 0 {
+			return r.decodeFlashes(c.Value)
+		}
+	}
+	return nil
+}
+
+func removeSetCookie(w http.ResponseWriter, name string) {
+	values := w.Header().Values("Set-Cookie")
+	kept := values[:0:0]
+	for _, v := range values {
+		if !strings.HasPrefix(v, name+"=") {
+			kept = append(kept, v)
+		}
+	}
+	w.Header()["Set-Cookie"] = kept
+}
+
+// encodeFlashes encodes flashes as base64url JSON, followed by an HMAC
+// when Options.FlashSecret is set.
+func (r *Renderer) encodeFlashes(flashes []Flash) (string, error) {
+	raw, err := json.Marshal(flashes)
+	if err != nil {
+		return "", fmt.Errorf("render: encode flashes: %w", err)
+	}
+	value := base64.RawURLEncoding.EncodeToString(raw)
+	if len(r.opts.FlashSecret) > 0 {
+		value += "." + base64.RawURLEncoding.EncodeToString(r.signFlashes(value))
+	}
+	return value, nil
+}
+
+// decodeFlashes returns nil for malformed or wrongly signed values.
+func (r *Renderer) decodeFlashes(value string) []Flash {
+	payload, sig, signed := strings.Cut(value, ".")
+	if len(r.opts.FlashSecret) > 0 {
+		got, err := base64.RawURLEncoding.DecodeString(sig)
+		if !signed || err != nil || !hmac.Equal(got, r.signFlashes(payload)) {
+			return nil
+		}
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil
+	}
+	var flashes []Flash
+	if json.Unmarshal(raw, &flashes) != nil {
+		return nil
+	}
+	return flashes
+}
+
+func (r *Renderer) signFlashes(payload string) []byte {
+	mac := hmac.New(sha256.New, r.opts.FlashSecret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -65,6 +65,16 @@ func (r *Responder) NotFound(message string, opts ...Option) {
 	r.WriteError(http.StatusNotFound, err, opts...)
 }
 
+// MethodNotAllowed responds with 405 Method Not Allowed.
+// Callers must set the Allow header before calling.
+func (r *Responder) MethodNotAllowed(message string, opts ...Option) {
+	err := ErrMethodNotAllowed
+	if message != "" {
+		err.Message = message
+	}
+	r.WriteError(http.StatusMethodNotAllowed, err, opts...)
+}
+
 // Conflict responds with 409 Conflict
 func (r *Responder) Conflict(message string, opts ...Option) {
 	err := ErrConflict
@@ -14,6 +14,7 @@ const (
 	ErrCodeConflict         = 4008 // 数据冲突
 	ErrCodeTooManyRequests  = 4009 // 请求过于频繁
 	ErrCodeRateLimitExceeded = 4009 // 速率限制超出 (别名)
+	ErrCodeMethodNotAllowed = 4010 // 请求方法不允许
 
 	// 5xxx - 服务端错误
 	ErrCodeInternalServer  = 5000 // 内部服务器错误
@@ -37,6 +38,7 @@ var errorMessages = map[int]string{
 	ErrCodeDuplicate:        "Resource Already Exists",
 	ErrCodeConflict:         "Data Conflict",
 	ErrCodeTooManyRequests:  "Too Many Requests",
+	ErrCodeMethodNotAllowed: "Method Not Allowed",
 	ErrCodeInternalServer:   "Internal Server Error",
 	ErrCodeDatabase:         "Database Error",
 	ErrCodeBusinessLogic:    "Business Logic Error",
@@ -89,6 +91,7 @@ var (
 	ErrDuplicate        = NewError(ErrCodeDuplicate, "")
 	ErrConflict         = NewError(ErrCodeConflict, "")
 	ErrTooManyRequests  = NewError(ErrCodeTooManyRequests, "")
+	ErrMethodNotAllowed = NewError(ErrCodeMethodNotAllowed, "")
 	ErrInternalServer   = NewError(ErrCodeInternalServer, "")
 	ErrDatabase         = NewError(ErrCodeDatabase, "")
 	ErrBusinessLogic    = NewError(ErrCodeBusinessLogic, "")
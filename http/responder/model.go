@@ -11,6 +11,8 @@ type Response struct {
 type Error struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	// Reason is an optional machine-readable identifier, e.g. "USER_NOT_FOUND"
+	Reason  string `json:"reason,omitempty"`
 	Details any    `json:"details,omitempty"`
 }
 
@@ -0,0 +1,142 @@
+package response
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/leeforge/framework/http/responder"
+	"github.com/leeforge/framework/json"
+)
+
+// Built-in media types.
+const (
+	MediaTypeJSON = "application/json"
+	MediaTypeXML  = "application/xml"
+)
+
+// Encoder writes v in one media type.
+type Encoder func(w io.Writer, v any) error
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]Encoder{
+		MediaTypeJSON: func(w io.Writer, v any) error {
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(raw)
+			return err
+		},
+		MediaTypeXML: func(w io.Writer, v any) error {
+			if _, err := io.WriteString(w, xml.Header); err != nil {
+				return err
+			}
+			return xml.NewEncoder(w).Encode(v)
+		},
+	}
+	// offerOrder breaks ties between equally preferred types; JSON first.
+	offerOrder = []string{MediaTypeJSON, MediaTypeXML}
+)
+
+// RegisterEncoder adds or replaces the encoder for mediaType, making it
+// available to content negotiation.
+func RegisterEncoder(mediaType string, enc Encoder) {
+	mediaType = strings.ToLower(mediaType)
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	if _, exists := encoders[mediaType]; !exists {
+		offerOrder = append(offerOrder, mediaType)
+	}
+	encoders[mediaType] = enc
+}
+
+// Negotiate returns the registered media type the request's Accept header
+// prefers. Missing, unparsable or unsatisfiable Accept headers yield JSON,
+// so clients always get a readable body rather than 406.
+func Negotiate(r *http.Request) string {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return MediaTypeJSON
+	}
+
+	encodersMu.RLock()
+	offers := append([]string(nil), offerOrder...)
+	encodersMu.RUnlock()
+
+	best, bestQ, bestSpecificity := MediaTypeJSON, 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		for _, offer := range offers {
+			specificity, ok := matchMediaType(mediaType, offer)
+			if !ok {
+				continue
+			}
+			if q > bestQ || (q == bestQ && specificity > bestSpecificity) {
+				best, bestQ, bestSpecificity = offer, q, specificity
+			}
+			// Offers are in preference order; only the first match per range counts.
+			break
+		}
+	}
+	return best
+}
+
+// matchMediaType reports whether the Accept range covers offer and how
+// specific the range is (0 for */*, 1 for type/*, 2 for an exact type).
+func matchMediaType(accepted, offer string) (int, bool) {
+	switch {
+	case accepted == "*/*":
+		return 0, true
+	case strings.HasSuffix(accepted, "/*"):
+		return 1, strings.HasPrefix(offer, strings.TrimSuffix(accepted, "*"))
+	default:
+		return 2, accepted == offer
+	}
+}
+
+// encode writes payload with the encoder for mediaType. Encoding happens
+// before the header is written so a failure can still become a 500.
+func encode(w http.ResponseWriter, r *http.Request, mediaType string, status int, payload *responder.Response) {
+	encodersMu.RLock()
+	enc := encoders[mediaType]
+	encodersMu.RUnlock()
+
+	var buf bytes.Buffer
+	if err := enc(&buf, payload); err != nil {
+		if mediaType != MediaTypeJSON {
+			// Fall back to JSON, which can represent any payload XML cannot.
+			encode(w, r, MediaTypeJSON, status, payload)
+			return
+		}
+		w.Header().Set("Content-Type", MediaTypeJSON)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":{"code":5000,"message":"encode failed"}}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead && status != http.StatusNoContent {
+		_, _ = w.Write(buf.Bytes())
+	}
+}
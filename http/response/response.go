@@ -0,0 +1,119 @@
+// Package response writes the standard responder envelope with the trace ID
+// filled in, content negotiation, pagination metadata, and status/code
+// mapping for errors.AppError, so handlers never hand-build response bodies.
+package response
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/middleware"
+	"github.com/leeforge/framework/http/responder"
+)
+
+// Write sends data in the standard envelope, encoded in the media type the
+// request's Accept header prefers (JSON by default).
+func Write(w http.ResponseWriter, r *http.Request, status int, data any, opts ...responder.Option) {
+	encode(w, r, Negotiate(r), status, &responder.Response{Data: data, Meta: meta(r, opts)})
+}
+
+// WriteJSON sends data in the standard envelope as JSON regardless of Accept.
+func WriteJSON(w http.ResponseWriter, r *http.Request, status int, data any, opts ...responder.Option) {
+	encode(w, r, MediaTypeJSON, status, &responder.Response{Data: data, Meta: meta(r, opts)})
+}
+
+// WriteList sends a 200 page of items with pagination metadata.
+func WriteList(w http.ResponseWriter, r *http.Request, items any, page *responder.PaginationMeta, opts ...responder.Option) {
+	opts = append(opts, responder.WithPagination(page))
+	Write(w, r, http.StatusOK, items, opts...)
+}
+
+// NewPagination builds pagination metadata, deriving TotalPages and HasMore.
+// A pageSize below 1 is treated as 1.
+func NewPagination(page, pageSize int, total int64) *responder.PaginationMeta {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 1
+	}
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	return &responder.PaginationMeta{
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: totalPages,
+		HasMore:    page < totalPages,
+	}
+}
+
+// WriteError sends err in the standard envelope. An *errors.AppError anywhere
+// in err's chain sets the status (HTTPStatus, or a default for its Type),
+// the numeric code, the message, Reason (its Code) and Details; errors built
+// with errors.Validation send their violation list as Details. Any other
+// error is reported as a generic 500 so internal messages never leak.
+func WriteError(w http.ResponseWriter, r *http.Request, err error, opts ...responder.Option) {
+	status, body := FromError(err)
+	encode(w, r, Negotiate(r), status, &responder.Response{Error: &body, Meta: meta(r, opts)})
+}
+
+// FromError maps err to an HTTP status and envelope error as WriteError does.
+func FromError(err error) (int, responder.Error) {
+	var appErr *errors.AppError
+	if !stderrors.As(err, &appErr) {
+		return http.StatusInternalServerError, responder.ErrInternalServer
+	}
+
+	status, code := typeMapping(appErr.Type)
+	if appErr.HTTPStatus > 0 {
+		status = appErr.HTTPStatus
+	}
+	body := responder.NewError(code, appErr.Message)
+	if appErr.Code != "" && appErr.Code != string(appErr.Type) {
+		body.Reason = appErr.Code
+	}
+	if violations, ok := appErr.Details[errors.DetailViolations].([]errors.FieldViolation); ok && len(appErr.Details) == 1 {
+		// Same body as responder.ValidationError with binding.ValidationErrors
+		body.Details = violations
+	} else if len(appErr.Details) > 0 {
+		body.Details = appErr.Details
+	}
+	return status, body
+}
+
+// typeMapping returns the default HTTP status and responder code for t.
+func typeMapping(t errors.ErrorType) (int, int) {
+	switch t {
+	case errors.ErrorTypeValidation, errors.ErrorTypeRequired, errors.ErrorTypeInvalid:
+		return http.StatusBadRequest, responder.ErrCodeValidationFailed
+	case errors.ErrorTypeNotFound:
+		return http.StatusNotFound, responder.ErrCodeNotFound
+	case errors.ErrorTypeConflict:
+		return http.StatusConflict, responder.ErrCodeConflict
+	case errors.ErrorTypeUnauthorized:
+		return http.StatusUnauthorized, responder.ErrCodeUnauthorized
+	case errors.ErrorTypeForbidden:
+		return http.StatusForbidden, responder.ErrCodeForbidden
+	case errors.ErrorTypeRateLimit:
+		return http.StatusTooManyRequests, responder.ErrCodeTooManyRequests
+	case errors.ErrorTypeBusiness:
+		return http.StatusBadRequest, responder.ErrCodeBusinessLogic
+	case errors.ErrorTypeTimeout:
+		return http.StatusRequestTimeout, responder.ErrCodeTimeout
+	case errors.ErrorTypeDatabase:
+		return http.StatusInternalServerError, responder.ErrCodeDatabase
+	case errors.ErrorTypeExternal:
+		return http.StatusBadGateway, responder.ErrCodeExternalService
+	default:
+		return http.StatusInternalServerError, responder.ErrCodeInternalServer
+	}
+}
+
+func meta(r *http.Request, opts []responder.Option) responder.Meta {
+	m := responder.Meta{TraceId: middleware.GetTraceIDFromRequest(r)}
+	for _, opt := range opts {
+		opt(&m)
+	}
+	return m
+}
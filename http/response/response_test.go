@@ -0,0 +1,169 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/binding"
+	"github.com/leeforge/framework/http/middleware"
+	"github.com/leeforge/framework/http/responder"
+	"github.com/stretchr/testify/require"
+)
+
+type envelope struct {
+	Data  any              `json:"data"`
+	Error *responder.Error `json:"error"`
+	Meta  responder.Meta   `json:"meta"`
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) envelope {
+	t.Helper()
+	var env envelope
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &env))
+	return env
+}
+
+func TestWriteJSON_FillsTraceID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), middleware.TraceIDKey, "trace-1"))
+	rec := httptest.NewRecorder()
+
+	WriteJSON(rec, r, http.StatusCreated, map[string]string{"id": "42"})
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	env := decode(t, rec)
+	require.Equal(t, map[string]any{"id": "42"}, env.Data)
+	require.Equal(t, "trace-1", env.Meta.TraceId)
+}
+
+func TestWriteList_Pagination(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteList(rec, httptest.NewRequest(http.MethodGet, "/", nil), []int{1, 2}, NewPagination(2, 10, 25))
+
+	env := decode(t, rec)
+	require.Equal(t, &responder.PaginationMeta{Page: 2, PageSize: 10, Total: 25, TotalPages: 3, HasMore: true}, env.Meta.Pagination)
+
+	last := NewPagination(3, 10, 25)
+	require.False(t, last.HasMore)
+	require.Equal(t, 0, NewPagination(1, 10, 0).TotalPages)
+}
+
+func TestWriteError_AppError(t *testing.T) {
+	err := fmt.Errorf("loading user: %w",
+		errors.NewNotFound("user", 42).WithCode("USER_NOT_FOUND").WithDetail("id", 42))
+
+	rec := httptest.NewRecorder()
+	WriteError(rec, httptest.NewRequest(http.MethodGet, "/", nil), err)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	env := decode(t, rec)
+	require.Nil(t, env.Data)
+	require.Equal(t, responder.ErrCodeNotFound, env.Error.Code)
+	require.Equal(t, "USER_NOT_FOUND", env.Error.Reason)
+	require.Equal(t, map[string]any{"resource": "user", "id": float64(42)}, env.Error.Details)
+}
+
+func TestFromError_Mapping(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   int
+	}{
+		{errors.NewValidation("bad"), http.StatusBadRequest, responder.ErrCodeValidationFailed},
+		{errors.NewUnauthorized("no"), http.StatusUnauthorized, responder.ErrCodeUnauthorized},
+		{errors.NewForbidden("no"), http.StatusForbidden, responder.ErrCodeForbidden},
+		{errors.NewRateLimit("slow down"), http.StatusTooManyRequests, responder.ErrCodeTooManyRequests},
+		{errors.New(errors.ErrorTypeConflict, "taken"), http.StatusConflict, responder.ErrCodeConflict},
+		{errors.NewBusiness("nope").WithHTTPStatus(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity, responder.ErrCodeBusinessLogic},
+	}
+	for _, tc := range cases {
+		status, body := FromError(tc.err)
+		require.Equal(t, tc.status, status, tc.err.Error())
+		require.Equal(t, tc.code, body.Code, tc.err.Error())
+		require.Empty(t, body.Reason, "type-derived codes are not repeated as Reason")
+	}
+
+	status, body := FromError(stderrors.New("pq: password authentication failed"))
+	require.Equal(t, http.StatusInternalServerError, status)
+	require.Equal(t, "Internal Server Error", body.Message, "plain errors must not leak")
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                MediaTypeJSON,
+		"*/*":             MediaTypeJSON,
+		"application/xml": MediaTypeXML,
+		"text/html, application/xml;q=0.9, */*;q=0.8": MediaTypeXML,
+		"application/json;q=0.5, application/xml":     MediaTypeXML,
+		"application/*":       MediaTypeJSON,
+		"text/html":           MediaTypeJSON,
+		"application/xml;q=0": MediaTypeJSON,
+	}
+	for accept, want := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", accept)
+		require.Equal(t, want, Negotiate(r), "Accept: %q", accept)
+	}
+}
+
+func TestWrite_NegotiatesXMLAndFallsBackToJSON(t *testing.T) {
+	type user struct {
+		ID   int    `xml:"id"`
+		Name string `xml:"name"`
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/xml")
+
+	rec := httptest.NewRecorder()
+	Write(rec, r, http.StatusOK, user{ID: 1, Name: "ada"})
+	require.Equal(t, "application/xml; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), "<name>ada</name>")
+	require.Equal(t, "Accept", rec.Header().Get("Vary"))
+
+	// Maps cannot be encoded as XML.
+	rec = httptest.NewRecorder()
+	Write(rec, r, http.StatusOK, map[string]int{"n": 1})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), MediaTypeJSON))
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("text/plain", func(w io.Writer, v any) error {
+		_, err := fmt.Fprint(w, v.(*responder.Response).Data)
+		return err
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/plain")
+
+	rec := httptest.NewRecorder()
+	Write(rec, r, http.StatusOK, "hello")
+	require.Equal(t, "hello", rec.Body.String())
+}
+
+func TestWriteError_ValidationBuilderMatchesBinding(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	fromBinding := httptest.NewRecorder()
+	responder.ValidationError(fromBinding, r, binding.ValidationErrors{
+		{Type: "validation_error", Field: "amount", Message: "must be positive"},
+		{Type: "validation_error", Field: "currency", Message: "unsupported"},
+	})
+
+	fromHandler := httptest.NewRecorder()
+	WriteError(fromHandler, r, errors.Validation().
+		Field("amount", "must be positive").
+		Field("currency", "unsupported").
+		Build())
+
+	require.Equal(t, fromBinding.Code, fromHandler.Code)
+	require.JSONEq(t, fromBinding.Body.String(), fromHandler.Body.String())
+}
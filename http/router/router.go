@@ -0,0 +1,145 @@
+// Package router provides chi helpers that answer OPTIONS with the allowed
+// methods, serve HEAD from GET handlers, and reply 405 with a complete Allow
+// header and the standard JSON error body.
+package router
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leeforge/framework/http/middleware"
+	"github.com/leeforge/framework/http/responder"
+)
+
+// probeMethods are checked, in this order, when building an Allow header.
+var probeMethods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodTrace,
+}
+
+// New returns a chi router with AutoMethods installed and JSON NotFound and
+// MethodNotAllowed handlers.
+func New() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(AutoMethods)
+	r.NotFound(NotFound)
+	r.MethodNotAllowed(MethodNotAllowed)
+	return r
+}
+
+// AutoMethods answers OPTIONS for routes without an explicit OPTIONS handler
+// with 204 and an Allow header, and routes HEAD requests without an explicit
+// HEAD handler to the GET handler with the body discarded. Install it with
+// Use on the root router so it sees every request before routing.
+func AutoMethods(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rctx := chi.RouteContext(r.Context())
+		if rctx == nil || rctx.Routes == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodOptions:
+			path := requestPath(r)
+			if matches(rctx.Routes, http.MethodOptions, path) {
+				break
+			}
+			allowed := AllowedMethods(rctx.Routes, path)
+			if len(allowed) == 0 {
+				break
+			}
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			w.WriteHeader(http.StatusNoContent)
+			return
+
+		case http.MethodHead:
+			path := requestPath(r)
+			if matches(rctx.Routes, http.MethodHead, path) || !matches(rctx.Routes, http.MethodGet, path) {
+				break
+			}
+			// Route as GET but keep r.Method so handlers can skip work for HEAD.
+			rctx.RouteMethod = http.MethodGet
+			next.ServeHTTP(&headResponseWriter{ResponseWriter: w}, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AllowedMethods lists the methods routes accepts for path, including HEAD
+// when GET is routed and OPTIONS whenever anything is. It returns nil when
+// no route matches the path at all.
+func AllowedMethods(routes chi.Routes, path string) []string {
+	var allowed []string
+	hasGet, hasHead, hasOptions := false, false, false
+	for _, method := range probeMethods {
+		if !matches(routes, method, path) {
+			continue
+		}
+		allowed = append(allowed, method)
+		if method == http.MethodGet {
+			hasGet = true
+		}
+	}
+	hasHead = matches(routes, http.MethodHead, path)
+	hasOptions = matches(routes, http.MethodOptions, path)
+
+	if len(allowed) == 0 && !hasHead && !hasOptions {
+		return nil
+	}
+	if hasGet || hasHead {
+		allowed = append(allowed, http.MethodHead)
+	}
+	return append(allowed, http.MethodOptions)
+}
+
+// MethodNotAllowed replies 405 with an Allow header listing every method the
+// path supports.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.Routes != nil {
+		if allowed := AllowedMethods(rctx.Routes, requestPath(r)); len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+		}
+	}
+	responder.New(w, r, nil).MethodNotAllowed("",
+		responder.WithTraceID(middleware.GetTraceIDFromRequest(r)))
+}
+
+// NotFound replies 404 with the standard route-not-found error body.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	responder.New(w, r, nil).WriteError(http.StatusNotFound, responder.ErrRouteNotFound,
+		responder.WithTraceID(middleware.GetTraceIDFromRequest(r)))
+}
+
+func matches(routes chi.Routes, method, path string) bool {
+	return routes.Match(chi.NewRouteContext(), method, path)
+}
+
+func requestPath(r *http.Request) string {
+	if r.URL.RawPath != "" {
+		return r.URL.RawPath
+	}
+	return r.URL.Path
+}
+
+// headResponseWriter keeps headers and status but drops the body.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,98 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter() *chi.Mux {
+	r := New()
+	r.Get("/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-User", chi.URLParam(req, "id"))
+		w.Header().Set("X-Method", req.Method)
+		_, _ = w.Write([]byte("user"))
+	})
+	r.Put("/users/{id}", func(w http.ResponseWriter, _ *http.Request) {})
+	r.Post("/users", func(w http.ResponseWriter, _ *http.Request) {})
+	r.Route("/admin", func(r chi.Router) {
+		r.Delete("/cache", func(w http.ResponseWriter, _ *http.Request) {})
+	})
+	r.Options("/cors", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.WriteHeader(http.StatusOK)
+	})
+	r.Get("/cors", func(w http.ResponseWriter, _ *http.Request) {})
+	return r
+}
+
+func serve(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestAutoMethods_Options(t *testing.T) {
+	r := newTestRouter()
+
+	rec := serve(r, http.MethodOptions, "/users/42")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, "GET, PUT, HEAD, OPTIONS", rec.Header().Get("Allow"))
+	require.Empty(t, rec.Body.String())
+
+	rec = serve(r, http.MethodOptions, "/admin/cache")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, "DELETE, OPTIONS", rec.Header().Get("Allow"))
+
+	// An explicit OPTIONS route wins.
+	rec = serve(r, http.MethodOptions, "/cors")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+
+	rec = serve(r, http.MethodOptions, "/missing")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAutoMethods_HeadUsesGetWithoutBody(t *testing.T) {
+	r := newTestRouter()
+
+	rec := serve(r, http.MethodHead, "/users/42")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "42", rec.Header().Get("X-User"))
+	require.Equal(t, http.MethodHead, rec.Header().Get("X-Method"))
+	require.Empty(t, rec.Body.String())
+
+	rec = serve(r, http.MethodHead, "/users")
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.Equal(t, "POST, OPTIONS", rec.Header().Get("Allow"))
+}
+
+func TestMethodNotAllowed_SetsAllowAndJSONError(t *testing.T) {
+	r := newTestRouter()
+
+	rec := serve(r, http.MethodDelete, "/users/42")
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.Equal(t, "GET, PUT, HEAD, OPTIONS", rec.Header().Get("Allow"))
+
+	var body struct {
+		Error struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, 4010, body.Error.Code)
+
+	rec = serve(r, http.MethodGet, "/admin/cache")
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.Equal(t, "DELETE, OPTIONS", rec.Header().Get("Allow"))
+}
+
+func TestNotFound_JSONError(t *testing.T) {
+	rec := serve(newTestRouter(), http.MethodGet, "/missing")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Contains(t, rec.Body.String(), "4004")
+}
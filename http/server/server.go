@@ -0,0 +1,373 @@
+// Package server runs an http.Server with production timeouts, built-in
+// /healthz, /readyz and /metrics endpoints, and graceful shutdown that drains
+// in-flight requests.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/leeforge/framework/health"
+	"github.com/leeforge/framework/http/compress"
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/request"
+	"go.uber.org/zap"
+)
+
+// Config configures a Server. Zero values fall back to DefaultConfig.
+type Config struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+
+	// TLSConfig enables HTTPS. CertFile/KeyFile may be empty when the config
+	// already carries certificates or GetCertificate.
+	TLSConfig *tls.Config
+	CertFile  string
+	KeyFile   string
+
+	// ShutdownTimeout bounds Shutdown when the caller's context has no deadline.
+	ShutdownTimeout time.Duration
+	// ShutdownDelay keeps serving after /readyz turns unhealthy so load
+	// balancers stop routing new traffic before connections are closed.
+	ShutdownDelay time.Duration
+
+	// HealthPath serves liveness; ReadyPath serves readiness. "-" disables.
+	HealthPath string
+	ReadyPath  string
+	// ReadyCheck reports whether the process can take traffic; nil means ready.
+	ReadyCheck func(ctx context.Context) error
+	// Health, when set, serves both endpoints from its liveness and
+	// readiness reports. ReadyCheck is still consulted for readiness.
+	Health *health.Registry
+
+	// Metrics, when set, records every request and serves MetricsPath.
+	Metrics     *metrics.Collector
+	MetricsPath string
+	// Compression, when set, compresses responses. It wraps the metrics
+	// middleware so recorded sizes and statuses are the handler's.
+	Compression *compress.Options
+	// RealIP, when set, resolves the client IP behind trusted proxies once
+	// per request; request.GetClientIP then returns it to IP filters, rate
+	// limiters and logs.
+	RealIP *request.RealIP
+
+	Logger *zap.Logger
+}
+
+// DefaultConfig returns the timeouts and endpoint paths used for zero fields.
+func DefaultConfig() Config {
+	return Config{
+		Addr:              ":8080",
+		ReadTimeout:       30 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		MaxHeaderBytes:    1 << 20,
+		ShutdownTimeout:   30 * time.Second,
+		HealthPath:        "/healthz",
+		ReadyPath:         "/readyz",
+		MetricsPath:       "/metrics",
+	}
+}
+
+func (c *Config) applyDefaults() {
+	d := DefaultConfig()
+	if c.Addr == "" {
+		c.Addr = d.Addr
+	}
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = d.ReadTimeout
+	}
+	if c.ReadHeaderTimeout == 0 {
+		c.ReadHeaderTimeout = d.ReadHeaderTimeout
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = d.WriteTimeout
+	}
+	if c.IdleTimeout == 0 {
+		c.IdleTimeout = d.IdleTimeout
+	}
+	if c.MaxHeaderBytes == 0 {
+		c.MaxHeaderBytes = d.MaxHeaderBytes
+	}
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = d.ShutdownTimeout
+	}
+	if c.HealthPath == "" {
+		c.HealthPath = d.HealthPath
+	}
+	if c.ReadyPath == "" {
+		c.ReadyPath = d.ReadyPath
+	}
+	if c.MetricsPath == "" {
+		c.MetricsPath = d.MetricsPath
+	}
+	if c.Logger == nil {
+		c.Logger = zap.NewNop()
+	}
+}
+
+// Server wraps http.Server with lifecycle management.
+type Server struct {
+	config  Config
+	handler http.Handler
+	srv     *http.Server
+
+	inFlight atomic.Int64
+	draining atomic.Bool
+
+	mu       sync.Mutex
+	listener net.Listener
+	done     chan struct{}
+	serveErr error
+}
+
+// New creates a server for handler. Built-in endpoints take precedence over
+// handler for their paths; every other request is passed through.
+func New(handler http.Handler, config Config) *Server {
+	config.applyDefaults()
+
+	s := &Server{
+		config: config,
+		done:   make(chan struct{}),
+	}
+	s.handler = s.buildHandler(handler)
+	s.srv = &http.Server{
+		Addr:              config.Addr,
+		Handler:           s.handler,
+		TLSConfig:         config.TLSConfig,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+		ErrorLog:          zap.NewStdLog(config.Logger),
+	}
+	return s
+}
+
+func (s *Server) buildHandler(app http.Handler) http.Handler {
+	if app == nil {
+		app = http.NotFoundHandler()
+	}
+
+	endpoints := make(map[string]http.Handler)
+	if s.config.HealthPath != "-" {
+		endpoints[s.config.HealthPath] = http.HandlerFunc(s.serveHealth)
+	}
+	if s.config.ReadyPath != "-" {
+		endpoints[s.config.ReadyPath] = http.HandlerFunc(s.serveReady)
+	}
+	if s.config.Metrics != nil {
+		endpoints[s.config.MetricsPath] = metrics.NewMetricsHandler(s.config.Metrics)
+		app = metrics.NewMetricsMiddleware(s.config.Metrics).Middleware(app)
+	}
+	if s.config.Compression != nil {
+		app = compress.Middleware(*s.config.Compression)(app)
+	}
+	if s.config.RealIP != nil {
+		app = s.config.RealIP.Middleware(app)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h, ok := endpoints[r.URL.Path]; ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		app.ServeHTTP(w, r)
+	})
+}
+
+// serveHealth reports liveness: the process is up and serving, and no
+// liveness check in Health is down.
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	if s.config.Health != nil {
+		health.WriteReport(w, s.config.Health.Liveness(r.Context()))
+		return
+	}
+	writeStatus(w, http.StatusOK, "ok", "")
+}
+
+// serveReady reports readiness: not draining, ReadyCheck passes and no
+// critical check in Health is down.
+func (s *Server) serveReady(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() {
+		writeStatus(w, http.StatusServiceUnavailable, "draining", "")
+		return
+	}
+	if s.config.ReadyCheck != nil {
+		if err := s.config.ReadyCheck(r.Context()); err != nil {
+			writeStatus(w, http.StatusServiceUnavailable, "unavailable", err.Error())
+			return
+		}
+	}
+	if s.config.Health != nil {
+		health.WriteReport(w, s.config.Health.Readiness(r.Context()))
+		return
+	}
+	writeStatus(w, http.StatusOK, "ok", "")
+}
+
+func writeStatus(w http.ResponseWriter, code int, status, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	body := map[string]string{"status": status}
+	if reason != "" {
+		body["error"] = reason
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// Start listens on the configured address and serves in the background.
+// Listen errors are returned immediately.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve serves on ln in the background.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.listener != nil {
+		s.mu.Unlock()
+		ln.Close()
+		return errors.New("server already started")
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	useTLS := s.config.TLSConfig != nil || s.config.CertFile != ""
+	s.config.Logger.Info("http server listening",
+		zap.String("addr", ln.Addr().String()), zap.Bool("tls", useTLS))
+
+	go func() {
+		var err error
+		if useTLS {
+			err = s.srv.ServeTLS(ln, s.config.CertFile, s.config.KeyFile)
+		} else {
+			err = s.srv.Serve(ln)
+		}
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		if err != nil {
+			s.config.Logger.Error("http server failed", zap.Error(err))
+		}
+		s.mu.Lock()
+		s.serveErr = err
+		s.mu.Unlock()
+		close(s.done)
+	}()
+	return nil
+}
+
+// Done is closed when the server stops serving, whether by Shutdown or error.
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error that stopped the server, or nil after a clean shutdown.
+func (s *Server) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.serveErr
+}
+
+// Shutdown drains the server: /readyz turns unhealthy, the server keeps
+// serving for ShutdownDelay, then stops accepting connections and waits for
+// in-flight requests. When ctx (or ShutdownTimeout if ctx has no deadline)
+// expires, remaining connections are closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	started := s.listener != nil
+	s.mu.Unlock()
+	if !started {
+		s.draining.Store(true)
+		return nil
+	}
+	if !s.draining.CompareAndSwap(false, true) {
+		<-s.done
+		return nil
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.ShutdownTimeout)
+		defer cancel()
+	}
+
+	if s.config.ShutdownDelay > 0 {
+		s.config.Logger.Info("http server draining", zap.Duration("delay", s.config.ShutdownDelay))
+		select {
+		case <-time.After(s.config.ShutdownDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	s.config.Logger.Info("http server shutting down", zap.Int64("in_flight", s.inFlight.Load()))
+	err := s.srv.Shutdown(ctx)
+	if err != nil {
+		s.config.Logger.Warn("drain timeout exceeded, closing remaining connections",
+			zap.Int64("in_flight", s.inFlight.Load()))
+		err = errors.Join(err, s.srv.Close())
+	}
+	<-s.done
+	return err
+}
+
+// Run starts the server and blocks until ctx is canceled or the server
+// fails, then shuts down gracefully.
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.Start(); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return s.Shutdown(context.WithoutCancel(ctx))
+	case <-s.done:
+		return s.Err()
+	}
+}
+
+// Addr returns the listening address, or the configured one before Start.
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		return s.listener.Addr().String()
+	}
+	return s.config.Addr
+}
+
+// InFlight returns the number of application requests being served.
+func (s *Server) InFlight() int64 {
+	return s.inFlight.Load()
+}
+
+// Handler returns the full handler including built-in endpoints.
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// HTTPServer exposes the underlying http.Server for advanced settings such
+// as ConnState or BaseContext. Changes must happen before Start.
+func (s *Server) HTTPServer() *http.Server {
+	return s.srv
+}
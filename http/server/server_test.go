@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/health"
+	"github.com/leeforge/framework/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func getJSON(t *testing.T, url string) (int, map[string]string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var body map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return resp.StatusCode, body
+}
+
+func TestServer_BuiltinEndpoints(t *testing.T) {
+	ready := errors.New("database not connected")
+	collector := metrics.NewCollector()
+	app := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("app")) })
+
+	s := New(app, Config{
+		Addr:       "127.0.0.1:0",
+		Metrics:    collector,
+		ReadyCheck: func(context.Context) error { return ready },
+	})
+	require.NoError(t, s.Start())
+	defer s.Shutdown(context.Background())
+	base := "http://" + s.Addr()
+
+	code, body := getJSON(t, base+"/healthz")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "ok", body["status"])
+
+	code, body = getJSON(t, base+"/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "database not connected", body["error"])
+
+	ready = nil
+	code, _ = getJSON(t, base+"/readyz")
+	require.Equal(t, http.StatusOK, code)
+
+	resp, err := http.Get(base + "/orders")
+	require.NoError(t, err)
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, "app", string(b))
+
+	resp, err = http.Get(base + "/metrics")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServer_DisabledEndpointsFallThrough(t *testing.T) {
+	s := New(http.NotFoundHandler(), Config{HealthPath: "-", ReadyPath: "-"})
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_ShutdownDrainsInFlightRequests(t *testing.T) {
+	entered := make(chan struct{})
+	app := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(entered)
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	s := New(app, Config{Addr: "127.0.0.1:0", ShutdownDelay: 50 * time.Millisecond})
+	require.NoError(t, s.Start())
+	base := "http://" + s.Addr()
+
+	result := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err != nil {
+			result <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		result <- string(b)
+	}()
+	<-entered
+	require.EqualValues(t, 1, s.InFlight())
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- s.Shutdown(context.Background()) }()
+
+	// During ShutdownDelay the server still answers, but reports not ready.
+	time.Sleep(10 * time.Millisecond)
+	code, body := getJSON(t, base+"/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "draining", body["status"])
+
+	require.Equal(t, "done", <-result)
+	require.NoError(t, <-shutdownErr)
+	require.NoError(t, s.Err())
+	require.NoError(t, s.Shutdown(context.Background()), "second Shutdown is a no-op")
+}
+
+func TestServer_ShutdownTimeoutClosesConnections(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+
+	s := New(app, Config{Addr: "127.0.0.1:0"})
+	require.NoError(t, s.Start())
+	defer close(release)
+
+	go func() {
+		resp, err := http.Get("http://" + s.Addr() + "/stuck")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := s.Shutdown(ctx)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestServer_RunStopsOnContextCancel(t *testing.T) {
+	s := New(http.NotFoundHandler(), Config{Addr: "127.0.0.1:0"})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	require.Eventually(t, func() bool { return !strings.HasSuffix(s.Addr(), ":0") }, time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return")
+	}
+}
+
+func TestServer_HealthRegistry(t *testing.T) {
+	registry := health.NewRegistry()
+	dbErr := errors.New("refused")
+	require.NoError(t, registry.RegisterFunc("db", func(context.Context) error { return dbErr }))
+
+	s := New(http.NotFoundHandler(), Config{Health: registry})
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Contains(t, rec.Body.String(), `"db"`)
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	dbErr = nil
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
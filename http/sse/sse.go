@@ -0,0 +1,278 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	framerrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/events"
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/metrics"
+	"go.uber.org/zap"
+)
+
+// Options configures a Server.
+type Options struct {
+	// Buffer is the per-client queue (default 64). A client whose queue is
+	// full is disconnected; the browser reconnects and catches up through
+	// Last-Event-ID.
+	Buffer int
+	// Heartbeat is the interval of keep-alive comments (default 15s).
+	Heartbeat time.Duration
+	// Replay is the number of recent events retained for reconnecting
+	// clients (default 256, negative disables replay).
+	Replay int
+	// Retry, when positive, is sent to clients as their reconnection delay.
+	Retry   time.Duration
+	Metrics *metrics.Collector
+	Logger  *zap.Logger
+}
+
+// TopicResolver returns the topics a request subscribes to. Returning an
+// *errors.AppError rejects the request with the matching HTTP status.
+type TopicResolver func(r *http.Request) ([]string, error)
+
+// Topics returns a TopicResolver with a fixed set of topics.
+func Topics(names ...string) TopicResolver {
+	return func(*http.Request) ([]string, error) { return names, nil }
+}
+
+// Server fans published events out to connected clients.
+type Server struct {
+	opts Options
+	done chan struct{}
+
+	mu      sync.Mutex
+	seq     uint64
+	history []entry
+	clients map[*client]struct{}
+	closed  bool
+}
+
+type entry struct {
+	topic string
+	ev    Event
+}
+
+type client struct {
+	topics  map[string]struct{}
+	ch      chan Event
+	dropped chan struct{}
+}
+
+// New creates a Server.
+func New(opts Options) *Server {
+	if opts.Buffer <= 0 {
+		opts.Buffer = 64
+	}
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = 15 * time.Second
+	}
+	if opts.Replay == 0 {
+		opts.Replay = 256
+	}
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	return &Server{opts: opts, done: make(chan struct{}), clients: make(map[*client]struct{})}
+}
+
+// Publish sends ev to the clients subscribed to topic and returns its ID.
+// Events without an ID get the next number of a server-wide sequence.
+func (s *Server) Publish(topic string, ev Event) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ev.ID
+	}
+	if ev.ID == "" {
+		s.seq++
+		ev.ID = strconv.FormatUint(s.seq, 10)
+	}
+	if s.opts.Replay > 0 {
+		if len(s.history) == s.opts.Replay {
+			copy(s.history, s.history[1:])
+			s.history = s.history[:len(s.history)-1]
+		}
+		s.history = append(s.history, entry{topic: topic, ev: ev})
+	}
+
+	for c := range s.clients {
+		if _, ok := c.topics[topic]; !ok {
+			continue
+		}
+		select {
+		case c.ch <- ev:
+		default:
+			s.dropLocked(c)
+		}
+	}
+	if s.opts.Metrics != nil {
+		s.opts.Metrics.IncCounter("sse_events_total", map[string]string{"topic": topic})
+	}
+	return ev.ID
+}
+
+// Forward publishes every message of the bus topic to the SSE topic of the
+// same name, with the message ID as event ID and the topic as event name.
+// Each instance should subscribe without a group so that all its clients
+// see every message.
+func (s *Server) Forward(ctx context.Context, sub events.Subscriber, topic string, opts ...events.SubscribeOption) (events.Subscription, error) {
+	return sub.Subscribe(ctx, topic, func(_ context.Context, msg *events.Message) error {
+		s.Publish(topic, Event{ID: msg.ID, Event: msg.Topic, Data: msg.Payload})
+		return nil
+	}, opts...)
+}
+
+// Handler streams the topics chosen by resolve until the client
+// disconnects or the server is closed. Reconnecting clients first receive
+// the retained events published after their Last-Event-ID; when that ID is
+// no longer retained they receive every retained event of their topics.
+func (s *Server) Handler(resolve TopicResolver) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		topics, err := resolve(r)
+		if err != nil {
+			response.WriteError(w, r, err)
+			return
+		}
+		if s.isClosed() {
+			response.WriteError(w, r, framerrors.New(framerrors.ErrorTypeInternal, "server shutting down").WithHTTPStatus(http.StatusServiceUnavailable))
+			return
+		}
+		stream, err := NewStream(w, r)
+		if err != nil {
+			s.opts.Logger.Error("sse: cannot stream", zap.Error(err))
+			response.WriteError(w, r, framerrors.NewInternal("streaming is not supported"))
+			return
+		}
+
+		c := &client{
+			topics:  make(map[string]struct{}, len(topics)),
+			ch:      make(chan Event, s.opts.Buffer),
+			dropped: make(chan struct{}),
+		}
+		for _, t := range topics {
+			c.topics[t] = struct{}{}
+		}
+		replay, ok := s.register(c, stream.LastEventID())
+		if !ok {
+			return
+		}
+		defer s.unregister(c)
+
+		if s.opts.Retry > 0 {
+			if err := stream.Send(Event{Retry: s.opts.Retry}); err != nil {
+				return
+			}
+		}
+		for _, ev := range replay {
+			if err := stream.Send(ev); err != nil {
+				return
+			}
+		}
+
+		ticker := time.NewTicker(s.opts.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case ev := <-c.ch:
+				if err := stream.Send(ev); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if err := stream.Comment("ping"); err != nil {
+					return
+				}
+			case <-c.dropped:
+				return
+			case <-r.Context().Done():
+				return
+			case <-s.done:
+				return
+			}
+		}
+	})
+}
+
+// Len returns the number of connected clients.
+func (s *Server) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+// Close ends all streams and rejects new clients. Call it before shutting
+// down the HTTP server, which otherwise waits for the streams to end.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.done)
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// register adds c and returns the events to replay. Both happen under the
+// same lock so no event is missed or sent twice in between.
+func (s *Server) register(c *client, lastID string) ([]Event, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, false
+	}
+	s.clients[c] = struct{}{}
+	s.gaugeLocked()
+
+	if lastID == "" {
+		return nil, true
+	}
+	start := 0
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if s.history[i].ev.ID == lastID {
+			start = i + 1
+			break
+		}
+	}
+	var replay []Event
+	for _, e := range s.history[start:] {
+		if _, ok := c.topics[e.topic]; ok {
+			replay = append(replay, e.ev)
+		}
+	}
+	return replay, true
+}
+
+func (s *Server) unregister(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.clients[c]; ok {
+		delete(s.clients, c)
+		s.gaugeLocked()
+	}
+}
+
+// dropLocked disconnects a client that cannot keep up.
+func (s *Server) dropLocked(c *client) {
+	delete(s.clients, c)
+	close(c.dropped)
+	s.gaugeLocked()
+	if s.opts.Metrics != nil {
+		s.opts.Metrics.IncCounter("sse_dropped_clients_total", nil)
+	}
+}
+
+func (s *Server) gaugeLocked() {
+	if s.opts.Metrics != nil {
+		s.opts.Metrics.SetGauge("sse_clients", float64(len(s.clients)), nil)
+	}
+}
@@ -0,0 +1,175 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/events"
+	"github.com/stretchr/testify/require"
+)
+
+// eventReader 解析 text/event-stream，注释行以 Event{Event: ":comment"} 返回
+type eventReader struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+func connect(t *testing.T, url, lastEventID string) *eventReader {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set(LastEventIDHeader, lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	t.Cleanup(func() { resp.Body.Close() })
+	return &eventReader{body: resp.Body, scanner: bufio.NewScanner(resp.Body)}
+}
+
+func (er *eventReader) next(t *testing.T) Event {
+	t.Helper()
+	got := make(chan Event, 1)
+	go func() {
+		var ev Event
+		var data []string
+		for er.scanner.Scan() {
+			line := er.scanner.Text()
+			switch {
+			case line == "":
+				ev.Data = []byte(strings.Join(data, "\n"))
+				got <- ev
+				return
+			case strings.HasPrefix(line, ": "):
+				ev.Event = ":" + line[2:]
+			case strings.HasPrefix(line, "id: "):
+				ev.ID = line[4:]
+			case strings.HasPrefix(line, "event: "):
+				ev.Event = line[7:]
+			case strings.HasPrefix(line, "retry: "):
+				ms, _ := strconv.Atoi(line[7:])
+				ev.Retry = time.Duration(ms) * time.Millisecond
+			case strings.HasPrefix(line, "data: "):
+				data = append(data, line[6:])
+			}
+		}
+		close(got)
+	}()
+	select {
+	case ev, ok := <-got:
+		if !ok {
+			t.Fatal("stream ended")
+		}
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+func waitClients(t *testing.T, s *Server, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return s.Len() == n }, time.Second, 5*time.Millisecond)
+}
+
+func TestServerStreamsSubscribedTopics(t *testing.T) {
+	s := New(Options{})
+	srv := httptest.NewServer(s.Handler(Topics("orders")))
+	defer srv.Close()
+	defer s.Close() // 先结束流，否则 srv.Close 会等待连接
+
+	s.Publish("orders", Event{Data: []byte("before connect")})
+	stream := connect(t, srv.URL, "")
+	waitClients(t, s, 1)
+
+	s.Publish("users", Event{Data: []byte("other topic")})
+	id := s.Publish("orders", Event{Event: "order.placed", Data: []byte("line 1\nline 2")})
+
+	ev := stream.next(t)
+	require.Equal(t, id, ev.ID)
+	require.Equal(t, "order.placed", ev.Event)
+	require.Equal(t, "line 1\nline 2", string(ev.Data))
+}
+
+func TestServerReplaysAfterLastEventID(t *testing.T) {
+	s := New(Options{Replay: 3})
+	srv := httptest.NewServer(s.Handler(Topics("orders")))
+	defer srv.Close()
+	defer s.Close()
+
+	first := s.Publish("orders", Event{Data: []byte("1")})
+	s.Publish("orders", Event{Data: []byte("2")})
+	s.Publish("users", Event{Data: []byte("skip")})
+	s.Publish("orders", Event{Data: []byte("3")})
+
+	// 重连时只补发 Last-Event-ID 之后的订阅主题事件
+	stream := connect(t, srv.URL, first)
+	require.Equal(t, "2", string(stream.next(t).Data))
+	require.Equal(t, "3", string(stream.next(t).Data))
+
+	// 超出保留窗口的 ID 补发全部保留事件
+	stale := connect(t, srv.URL, first)
+	require.Equal(t, "2", string(stale.next(t).Data))
+}
+
+func TestServerHeartbeatAndClose(t *testing.T) {
+	s := New(Options{Heartbeat: 20 * time.Millisecond, Retry: 3 * time.Second})
+	srv := httptest.NewServer(s.Handler(Topics("orders")))
+	defer srv.Close()
+
+	stream := connect(t, srv.URL, "")
+	require.Equal(t, 3*time.Second, stream.next(t).Retry)
+	require.Equal(t, ":ping", stream.next(t).Event)
+
+	s.Close()
+	waitClients(t, s, 0)
+	_, err := io.ReadAll(stream.body)
+	require.NoError(t, err)
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestServerForwardsBusEvents(t *testing.T) {
+	bus := events.NewBus(events.BusOptions{})
+	defer bus.Close()
+	s := New(Options{})
+	srv := httptest.NewServer(s.Handler(Topics("user.created")))
+	defer srv.Close()
+	defer s.Close()
+
+	_, err := s.Forward(context.Background(), bus, "user.created")
+	require.NoError(t, err)
+	stream := connect(t, srv.URL, "")
+	waitClients(t, s, 1)
+
+	require.NoError(t, bus.Publish(context.Background(), &events.Message{ID: "m-1", Topic: "user.created", Payload: []byte(`{"id":"u1"}`)}))
+	ev := stream.next(t)
+	require.Equal(t, Event{ID: "m-1", Event: "user.created", Data: []byte(`{"id":"u1"}`)}, ev)
+}
+
+func TestSlowClientIsDropped(t *testing.T) {
+	s := New(Options{Buffer: 1})
+	c := &client{topics: map[string]struct{}{"t": {}}, ch: make(chan Event, 1), dropped: make(chan struct{})}
+	_, ok := s.register(c, "")
+	require.True(t, ok)
+
+	s.Publish("t", Event{Data: []byte("1")})
+	s.Publish("t", Event{Data: []byte("2")})
+	select {
+	case <-c.dropped:
+	default:
+		t.Fatal("slow client not dropped")
+	}
+	require.Equal(t, 0, s.Len())
+}
@@ -0,0 +1,149 @@
+// Package sse streams Server-Sent Events to browsers. Stream writes the
+// text/event-stream format on a single response; Server fans published
+// events out to connected clients with per-client buffers, heartbeats and
+// replay from Last-Event-ID, and can forward topics of the event bus.
+package sse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leeforge/framework/json"
+)
+
+// LastEventIDHeader is sent by browsers when they reconnect.
+const LastEventIDHeader = "Last-Event-ID"
+
+// LastEventIDParam is the query parameter accepted in place of the header,
+// for clients that reconnect manually with a new EventSource.
+const LastEventIDParam = "lastEventId"
+
+// ErrStreamingUnsupported is returned when the response cannot be flushed.
+var ErrStreamingUnsupported = errors.New("sse: response writer does not support flushing")
+
+// Event is one server-sent event.
+type Event struct {
+	// ID is stored by the browser and sent back as Last-Event-ID.
+	ID string
+	// Event is the event name; browsers dispatch unnamed events as "message".
+	Event string
+	// Data is the payload; newlines are sent as separate data lines. An
+	// event without data is not dispatched by browsers, which is how a
+	// bare Retry is sent.
+	Data []byte
+	// Retry, when positive, tells the browser how long to wait before
+	// reconnecting.
+	Retry time.Duration
+}
+
+// JSON returns an event named name with v encoded as its data.
+func JSON(name string, v any) (Event, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return Event{}, fmt.Errorf("sse: encode %s: %w", name, err)
+	}
+	return Event{Event: name, Data: data}, nil
+}
+
+// Stream writes events to one client.
+type Stream struct {
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	lastEventID string
+}
+
+// NewStream sends the event-stream response headers and returns a Stream.
+// It fails before writing anything when the response cannot be flushed.
+func NewStream(w http.ResponseWriter, r *http.Request) (*Stream, error) {
+	if !canFlush(w) {
+		return nil, ErrStreamingUnsupported
+	}
+	rc := http.NewResponseController(w)
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	// disable proxy buffering (nginx)
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return nil, err
+	}
+	// streams outlive the server's write timeout
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	last := r.Header.Get(LastEventIDHeader)
+	if last == "" {
+		last = r.URL.Query().Get(LastEventIDParam)
+	}
+	return &Stream{w: w, rc: rc, lastEventID: last}, nil
+}
+
+// LastEventID returns the ID of the last event the client received before
+// reconnecting, or "" on a first connection.
+func (s *Stream) LastEventID() string { return s.lastEventID }
+
+// Send writes ev and flushes it.
+func (s *Stream) Send(ev Event) error {
+	var buf bytes.Buffer
+	if ev.ID != "" {
+		buf.WriteString("id: ")
+		buf.WriteString(singleLine(ev.ID))
+		buf.WriteByte('\n')
+	}
+	if ev.Event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(singleLine(ev.Event))
+		buf.WriteByte('\n')
+	}
+	if ev.Retry > 0 {
+		buf.WriteString("retry: ")
+		buf.WriteString(strconv.FormatInt(ev.Retry.Milliseconds(), 10))
+		buf.WriteByte('\n')
+	}
+	if len(ev.Data) > 0 {
+		for _, line := range bytes.Split(bytes.ReplaceAll(ev.Data, []byte("\r\n"), []byte("\n")), []byte("\n")) {
+			buf.WriteString("data: ")
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+	}
+	buf.WriteByte('\n')
+	return s.write(buf.Bytes())
+}
+
+// Comment writes a comment line, which clients ignore; used as heartbeat
+// so proxies do not close idle streams.
+func (s *Stream) Comment(text string) error {
+	return s.write([]byte(": " + singleLine(text) + "\n\n"))
+}
+
+func (s *Stream) write(p []byte) error {
+	if _, err := s.w.Write(p); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// canFlush reports whether w, or a writer it wraps, implements http.Flusher.
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		if _, ok := w.(http.Flusher); ok {
+			return true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = u.Unwrap()
+	}
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
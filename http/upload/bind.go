@@ -0,0 +1,104 @@
+package upload
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	apperrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/binding"
+	"github.com/leeforge/framework/sanitize"
+)
+
+var (
+	fileType  = reflect.TypeOf((*UploadedFile)(nil))
+	filesType = reflect.TypeOf([]*UploadedFile(nil))
+)
+
+// Bind fills v from the Result stored by Middleware, applies its mod tags
+// with sanitize.Struct and validates it with binding.ValidateCtx. Fields of
+// type *UploadedFile receive the first file of their form field and
+// []*UploadedFile fields receive all of them; other fields are bound from the
+// text values like binding.Query does. Field names come from the form tag,
+// then the json tag, then the lowercased field name.
+func Bind(r *http.Request, v any) error {
+	res, ok := FromContext(r.Context())
+	if !ok {
+		return apperrors.NewInternal("upload.Bind called without upload.Middleware")
+	}
+	return bindResult(r, res, v)
+}
+
+// Bind handles the upload of r and binds it into v, for routes that do not
+// use Middleware. On error the stored files are removed again.
+func (u *Uploader) Bind(r *http.Request, v any) error {
+	res, err := u.Handle(r)
+	if err != nil {
+		return err
+	}
+	if err := bindResult(r, res, v); err != nil {
+		u.Remove(r.Context(), res.Files...)
+		return err
+	}
+	return nil
+}
+
+func bindResult(r *http.Request, res *Result, v any) error {
+	values := url.Values{}
+	for k, vs := range res.Values {
+		values[k] = vs
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct {
+		bindFiles(rv.Elem(), res.Files, values)
+	}
+
+	parser := binding.NewQueryParser()
+	parser.SetTagName("form")
+	if err := parser.Parse(values, v); err != nil {
+		return err
+	}
+	sanitize.Struct(r.Context(), v)
+	return binding.ValidateCtx(r.Context(), v)
+}
+
+// bindFiles sets the file fields of rv and drops their names from values so
+// the text parser leaves them alone.
+func bindFiles(rv reflect.Value, files []*UploadedFile, values url.Values) {
+	rt := rv.Type()
+	for i := 0; i < rv.NumField(); i++ {
+		field, sf := rv.Field(i), rt.Field(i)
+		if sf.Anonymous && field.Kind() == reflect.Struct {
+			bindFiles(field, files, values)
+			continue
+		}
+		if !field.CanSet() || (sf.Type != fileType && sf.Type != filesType) {
+			continue
+		}
+		name := formName(sf)
+		if name == "-" {
+			continue
+		}
+		delete(values, name)
+		for _, f := range files {
+			if f.Field != name {
+				continue
+			}
+			if sf.Type == fileType {
+				field.Set(reflect.ValueOf(f))
+				break
+			}
+			field.Set(reflect.Append(field, reflect.ValueOf(f)))
+		}
+	}
+}
+
+func formName(sf reflect.StructField) string {
+	for _, tag := range []string{"form", "json"} {
+		if name, _, _ := strings.Cut(sf.Tag.Get(tag), ","); name != "" {
+			return name
+		}
+	}
+	return strings.ToLower(sf.Name)
+}
@@ -0,0 +1,378 @@
+// Package upload streams multipart/form-data file parts straight to a
+// storage.StorageProvider without buffering whole files in memory or on
+// disk. Each file is checked while it streams: its content type is sniffed
+// from the first bytes (the client's Content-Type is ignored), images have
+// their dimensions read from the header, the size limit is enforced as bytes
+// arrive, and a SHA-256 checksum is computed on the way through. Files
+// already stored are removed again when a later part of the request fails.
+//
+//	u := upload.New(provider, upload.Config{
+//		MaxFileSize:  5 << 20,
+//		AllowedTypes: []string{"image/*"},
+//		Image:        &upload.ImageRules{MaxWidth: 4096, MaxHeight: 4096},
+//		Folder:       "avatars",
+//	})
+//	r.With(u.Middleware).Post("/avatar", h.SetAvatar)
+//
+//	type SetAvatarRequest struct {
+//		Avatar  *upload.UploadedFile `form:"avatar" validate:"required"`
+//		Caption string               `form:"caption" validate:"max=200"`
+//	}
+//	var req SetAvatarRequest
+//	err := upload.Bind(r, &req)
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for DecodeConfig
+	_ "image/jpeg" // register JPEG for DecodeConfig
+	_ "image/png"  // register PNG for DecodeConfig
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	apperrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/media/storage"
+)
+
+// Defaults for Config.
+const (
+	DefaultMaxFileSize   = 10 << 20
+	DefaultMaxFiles      = 10
+	DefaultMaxFieldsSize = 1 << 20
+)
+
+const (
+	// sniffLen is how many bytes http.DetectContentType looks at.
+	sniffLen = 512
+	// maxImageHeader bounds how much of an image DecodeConfig may read.
+	maxImageHeader = 1 << 20
+)
+
+// Errors wrapped by the AppErrors Handle returns; all but ErrNotMultipart
+// carry the form field in their violations.
+var (
+	ErrNotMultipart    = errors.New("upload: request is not multipart/form-data")
+	ErrTooLarge        = errors.New("upload: file too large")
+	ErrTooManyFiles    = errors.New("upload: too many files")
+	ErrFieldsTooLarge  = errors.New("upload: form values too large")
+	ErrEmptyFile       = errors.New("upload: empty file")
+	ErrTypeNotAllowed  = errors.New("upload: file type not allowed")
+	ErrInvalidImage    = errors.New("upload: invalid image")
+	ErrImageDimensions = errors.New("upload: image dimensions out of range")
+)
+
+// Config configures an Uploader.
+type Config struct {
+	// MaxFileSize limits each file in bytes (default DefaultMaxFileSize).
+	MaxFileSize int64
+	// MaxFiles limits the number of files per request (default DefaultMaxFiles).
+	MaxFiles int
+	// MaxFieldsSize limits the total size of non-file values
+	// (default DefaultMaxFieldsSize).
+	MaxFieldsSize int64
+	// AllowedTypes lists accepted sniffed media types; "image/*" accepts a
+	// whole type. Empty accepts everything.
+	AllowedTypes []string
+	// Image constrains the dimensions of image files; GIF, JPEG and PNG
+	// images are measured. With rules set, images that cannot be measured
+	// are rejected.
+	Image *ImageRules
+	// Folder is where files are stored in the provider.
+	Folder string
+	// IsPrivate is passed to the provider.
+	IsPrivate bool
+	// Naming returns the stored file name; the default is a random UUID
+	// plus an extension matching the sniffed content type.
+	Naming func(f *UploadedFile) string
+}
+
+// ImageRules bounds image dimensions in pixels; zero means no bound.
+type ImageRules struct {
+	MinWidth, MinHeight int
+	MaxWidth, MaxHeight int
+}
+
+// UploadedFile describes a stored file.
+type UploadedFile struct {
+	// Field is the form field the file was sent in.
+	Field string `json:"field"`
+	// Filename is the client's file name, without directories.
+	Filename string `json:"filename"`
+	// StoredName and Folder locate the file in the provider.
+	StoredName  string `json:"storedName"`
+	Folder      string `json:"folder"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	// Checksum is the hex SHA-256 of the content.
+	Checksum string `json:"checksum"`
+	// Width and Height are set for images that could be measured.
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+}
+
+// Result is everything a multipart request carried.
+type Result struct {
+	Files  []*UploadedFile
+	Values url.Values
+}
+
+// Uploader stores the files of multipart requests.
+type Uploader struct {
+	provider storage.StorageProvider
+	cfg      Config
+}
+
+// New creates an Uploader storing files in provider.
+func New(provider storage.StorageProvider, cfg Config) *Uploader {
+	if cfg.MaxFileSize <= 0 {
+		cfg.MaxFileSize = DefaultMaxFileSize
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = DefaultMaxFiles
+	}
+	if cfg.MaxFieldsSize <= 0 {
+		cfg.MaxFieldsSize = DefaultMaxFieldsSize
+	}
+	if cfg.Naming == nil {
+		cfg.Naming = defaultName
+	}
+	return &Uploader{provider: provider, cfg: cfg}
+}
+
+type contextKey struct{}
+
+// Middleware handles the upload before next runs, answering 4xx with the
+// standard error envelope when it fails. Handlers read the result with
+// FromContext or Bind.
+func (u *Uploader) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, err := u.Handle(r)
+		if err != nil {
+			response.WriteError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, res)))
+	})
+}
+
+// FromContext returns the result Middleware stored.
+func FromContext(ctx context.Context) (*Result, bool) {
+	res, ok := ctx.Value(contextKey{}).(*Result)
+	return res, ok
+}
+
+// Handle reads the multipart body of r, storing every file part and
+// collecting the other values. On error, files stored so far are removed.
+func (u *Uploader) Handle(r *http.Request) (*Result, error) {
+	ctx := r.Context()
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, apperrors.NewValidation("Request must be multipart/form-data").
+			WithHTTPStatus(http.StatusUnsupportedMediaType).
+			WithInnerError(fmt.Errorf("%w: %v", ErrNotMultipart, err))
+	}
+
+	res := &Result{Values: url.Values{}}
+	fail := func(err error) (*Result, error) {
+		u.Remove(context.WithoutCancel(ctx), res.Files...)
+		return nil, err
+	}
+	var fieldsSize int64
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return fail(apperrors.NewValidation("Malformed multipart body").WithInnerError(err))
+		}
+
+		field := part.FormName()
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, u.cfg.MaxFieldsSize-fieldsSize+1))
+			part.Close()
+			if err != nil {
+				return fail(apperrors.NewValidation("Malformed multipart body").WithInnerError(err))
+			}
+			fieldsSize += int64(len(value))
+			if fieldsSize > u.cfg.MaxFieldsSize {
+				return fail(violation(field, ErrFieldsTooLarge, http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("form values exceed %d bytes", u.cfg.MaxFieldsSize)))
+			}
+			res.Values.Add(field, string(value))
+			continue
+		}
+
+		if len(res.Files) == u.cfg.MaxFiles {
+			part.Close()
+			return fail(violation(field, ErrTooManyFiles, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("at most %d files are allowed", u.cfg.MaxFiles)))
+		}
+		f, err := u.store(ctx, field, part)
+		part.Close()
+		if err != nil {
+			return fail(err)
+		}
+		res.Files = append(res.Files, f)
+	}
+}
+
+// Remove deletes stored files, e.g. when the handler fails after Handle.
+func (u *Uploader) Remove(ctx context.Context, files ...*UploadedFile) error {
+	var errs []error
+	for _, f := range files {
+		if err := u.provider.Delete(ctx, storage.DeleteInput{Filename: f.StoredName, Folder: f.Folder}); err != nil {
+			errs = append(errs, fmt.Errorf("upload: remove %s: %w", f.StoredName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (u *Uploader) store(ctx context.Context, field string, part *multipart.Part) (*UploadedFile, error) {
+	f := &UploadedFile{Field: field, Filename: cleanFilename(part.FileName()), Folder: u.cfg.Folder}
+
+	br := bufio.NewReaderSize(part, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if len(head) == 0 {
+		if err != nil && err != io.EOF {
+			return nil, apperrors.NewValidation("Malformed multipart body").WithInnerError(err)
+		}
+		return nil, violation(field, ErrEmptyFile, http.StatusBadRequest, "file is empty")
+	}
+	f.ContentType, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+	if !u.allowed(f.ContentType) {
+		return nil, violation(field, ErrTypeNotAllowed, http.StatusUnsupportedMediaType,
+			fmt.Sprintf("file type %s is not allowed", f.ContentType)).
+			WithDetail("allowedTypes", u.cfg.AllowedTypes)
+	}
+
+	var body io.Reader = br
+	if strings.HasPrefix(f.ContentType, "image/") {
+		var header bytes.Buffer
+		cfg, _, err := image.DecodeConfig(io.TeeReader(io.LimitReader(br, maxImageHeader), &header))
+		body = io.MultiReader(&header, br)
+		if err == nil {
+			f.Width, f.Height = cfg.Width, cfg.Height
+		}
+		if u.cfg.Image != nil {
+			if err != nil {
+				return nil, violation(field, ErrInvalidImage, http.StatusUnprocessableEntity, "image could not be read")
+			}
+			if msg := u.cfg.Image.check(f.Width, f.Height); msg != "" {
+				return nil, violation(field, ErrImageDimensions, http.StatusUnprocessableEntity, msg)
+			}
+		}
+	}
+
+	f.StoredName = u.cfg.Naming(f)
+	lr := &limitedReader{r: body, max: u.cfg.MaxFileSize}
+	sum := sha256.New()
+	out, err := u.provider.Upload(ctx, storage.UploadInput{
+		File:      io.TeeReader(lr, sum),
+		Filename:  f.StoredName,
+		Folder:    f.Folder,
+		IsPrivate: u.cfg.IsPrivate,
+		Metadata:  map[string]interface{}{"originalName": f.Filename, "contentType": f.ContentType},
+	})
+	if lr.exceeded {
+		u.Remove(context.WithoutCancel(ctx), f)
+		return nil, violation(field, ErrTooLarge, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("file exceeds %d bytes", u.cfg.MaxFileSize)).
+			WithDetail("maxSize", u.cfg.MaxFileSize)
+	}
+	if err != nil {
+		return nil, apperrors.NewInternal("Failed to store file").WithInnerError(err)
+	}
+	f.URL = out.URL
+	f.Size = lr.n
+	f.Checksum = hex.EncodeToString(sum.Sum(nil))
+	return f, nil
+}
+
+func (u *Uploader) allowed(contentType string) bool {
+	if len(u.cfg.AllowedTypes) == 0 {
+		return true
+	}
+	for _, t := range u.cfg.AllowedTypes {
+		if t == contentType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ImageRules) check(width, height int) string {
+	switch {
+	case r.MinWidth > 0 && width < r.MinWidth, r.MinHeight > 0 && height < r.MinHeight:
+		return fmt.Sprintf("image must be at least %dx%d pixels", r.MinWidth, r.MinHeight)
+	case r.MaxWidth > 0 && width > r.MaxWidth, r.MaxHeight > 0 && height > r.MaxHeight:
+		return fmt.Sprintf("image must be at most %dx%d pixels", r.MaxWidth, r.MaxHeight)
+	}
+	return ""
+}
+
+// violation builds the validation AppError for a rejected form field.
+func violation(field string, cause error, status int, message string) *apperrors.AppError {
+	return apperrors.Validation().Field(field, message).Build().
+		WithHTTPStatus(status).
+		WithInnerError(cause)
+}
+
+// limitedReader counts bytes and fails with ErrTooLarge past max.
+type limitedReader struct {
+	r        io.Reader
+	n, max   int64
+	exceeded bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.n > l.max {
+		l.exceeded = true
+		return n, ErrTooLarge
+	}
+	return n, err
+}
+
+// cleanFilename drops directories from a client file name, which some
+// browsers send with Windows separators.
+func cleanFilename(name string) string {
+	return path.Base(strings.ReplaceAll(name, `\`, "/"))
+}
+
+// defaultName is a random UUID plus an extension of the sniffed content
+// type: the client's, when it maps to that type, else the type's first
+// known one. A file named evil.html that sniffs as PNG is stored as .png, so
+// the extension never makes the content be served as something it is not.
+func defaultName(f *UploadedFile) string {
+	return uuid.NewString() + extension(f.Filename, f.ContentType)
+}
+
+func extension(filename, contentType string) string {
+	ext := strings.ToLower(path.Ext(filename))
+	if ext != "" {
+		if t, _, err := mime.ParseMediaType(mime.TypeByExtension(ext)); err == nil && t == contentType {
+			return ext
+		}
+	}
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
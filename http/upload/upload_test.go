@@ -0,0 +1,268 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	apperrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/media/storage"
+)
+
+type part struct {
+	field, filename string
+	data            []byte
+}
+
+func multipartRequest(t *testing.T, parts ...part) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, p := range parts {
+		var err error
+		if p.filename == "" {
+			err = mw.WriteField(p.field, string(p.data))
+		} else {
+			var w io.Writer
+			if w, err = mw.CreateFormFile(p.field, p.filename); err == nil {
+				_, err = w.Write(p.data)
+			}
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func pngImage(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newUploader(t *testing.T, cfg Config) (*Uploader, string) {
+	t.Helper()
+	dir := t.TempDir()
+	provider, err := storage.NewLocalStorageProvider(dir, "/files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(provider, cfg), dir
+}
+
+func storedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var names []string
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			names = append(names, path)
+		}
+		return nil
+	})
+	return names
+}
+
+func TestHandleStoresFiles(t *testing.T) {
+	u, dir := newUploader(t, Config{Folder: "docs"})
+	img := pngImage(t, 30, 20)
+	res, err := u.Handle(multipartRequest(t,
+		part{field: "title", data: []byte("holiday")},
+		part{field: "photo", filename: `C:\pics\Beach.PNG`, data: img},
+		part{field: "notes", filename: "notes.txt", data: []byte("hello world")},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Values.Get("title") != "holiday" || len(res.Files) != 2 {
+		t.Fatalf("result = %+v", res)
+	}
+
+	photo := res.Files[0]
+	sum := sha256.Sum256(img)
+	if photo.Filename != "Beach.PNG" || !strings.HasSuffix(photo.StoredName, ".png") ||
+		photo.ContentType != "image/png" || photo.Width != 30 || photo.Height != 20 ||
+		photo.Size != int64(len(img)) || photo.Checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("photo = %+v", photo)
+	}
+	stored, err := os.ReadFile(filepath.Join(dir, "docs", photo.StoredName))
+	if err != nil || !bytes.Equal(stored, img) {
+		t.Errorf("stored image differs from upload: %v", err)
+	}
+	if notes := res.Files[1]; notes.ContentType != "text/plain" || notes.Width != 0 {
+		t.Errorf("notes = %+v", notes)
+	}
+}
+
+func TestDefaultNameFollowsSniffedType(t *testing.T) {
+	u, _ := newUploader(t, Config{})
+	res, err := u.Handle(multipartRequest(t, part{field: "avatar", filename: "evil.html", data: pngImage(t, 10, 10)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := res.Files[0]; f.ContentType != "image/png" || !strings.HasSuffix(f.StoredName, ".png") {
+		t.Errorf("file = %+v, want a .png stored name", f)
+	}
+
+	// The extension chosen for a mismatch depends on the system MIME table;
+	// it must map back to the sniffed type.
+	cases := []struct{ filename, contentType string }{
+		{"photo.JPG", "image/jpeg"},
+		{"photo.png", "image/jpeg"},
+		{"page.html", "image/gif"},
+		{"report", "application/pdf"},
+	}
+	for _, c := range cases {
+		got := extension(c.filename, c.contentType)
+		if typ, _, _ := mime.ParseMediaType(mime.TypeByExtension(got)); typ != c.contentType {
+			t.Errorf("extension(%q, %q) = %q, which maps to %q", c.filename, c.contentType, got, typ)
+		}
+	}
+	if got := extension("photo.JPG", "image/jpeg"); got != ".jpg" {
+		t.Errorf("matching client extension = %q, want .jpg", got)
+	}
+	if got := extension("data.html", "application/x-unknown"); got != "" {
+		t.Errorf("unknown type extension = %q, want none", got)
+	}
+}
+
+func TestHandleRejects(t *testing.T) {
+	valid := pngImage(t, 200, 200)
+	tests := []struct {
+		name   string
+		cfg    Config
+		parts  []part
+		cause  error
+		status int
+	}{
+		{
+			name:   "too large",
+			cfg:    Config{MaxFileSize: 1024},
+			parts:  []part{{field: "f", filename: "big.bin", data: bytes.Repeat([]byte("a"), 4096)}},
+			cause:  ErrTooLarge,
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "too many files",
+			cfg:    Config{MaxFiles: 1},
+			parts:  []part{{field: "b", filename: "b.txt", data: []byte("b")}},
+			cause:  ErrTooManyFiles,
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "type not allowed",
+			cfg:    Config{AllowedTypes: []string{"image/*"}},
+			parts:  []part{{field: "avatar", filename: "fake.png", data: []byte("plain text pretending")}},
+			cause:  ErrTypeNotAllowed,
+			status: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "image too small",
+			cfg:    Config{Image: &ImageRules{MinWidth: 100, MinHeight: 100}},
+			parts:  []part{{field: "avatar", filename: "a.png", data: pngImage(t, 10, 10)}},
+			cause:  ErrImageDimensions,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "empty file",
+			parts:  []part{{field: "f", filename: "empty.txt"}},
+			cause:  ErrEmptyFile,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "fields too large",
+			cfg:    Config{MaxFieldsSize: 8},
+			parts:  []part{{field: "a", data: []byte("0123456789")}},
+			cause:  ErrFieldsTooLarge,
+			status: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, dir := newUploader(t, tt.cfg)
+			// 每个用例先成功存储一张合规图片，后续失败时它应被清理
+			parts := append([]part{{field: "first", filename: "first.png", data: valid}}, tt.parts...)
+			_, err := u.Handle(multipartRequest(t, parts...))
+			var appErr *apperrors.AppError
+			if !errors.Is(err, tt.cause) || !errors.As(err, &appErr) || appErr.HTTPStatus != tt.status {
+				t.Fatalf("err = %v, want %v with status %d", err, tt.cause, tt.status)
+			}
+			if files := storedFiles(t, dir); len(files) != 0 {
+				t.Errorf("files left behind: %v", files)
+			}
+		})
+	}
+}
+
+func TestHandleNotMultipart(t *testing.T) {
+	u, _ := newUploader(t, Config{})
+	r := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	if _, err := u.Handle(r); !errors.Is(err, ErrNotMultipart) {
+		t.Errorf("err = %v, want ErrNotMultipart", err)
+	}
+}
+
+type galleryRequest struct {
+	Title  string          `form:"title" validate:"required"`
+	Cover  *UploadedFile   `form:"cover" validate:"required"`
+	Photos []*UploadedFile `form:"photos"`
+}
+
+func TestMiddlewareAndBind(t *testing.T) {
+	u, _ := newUploader(t, Config{AllowedTypes: []string{"image/png"}})
+	img := pngImage(t, 4, 4)
+	var got galleryRequest
+	h := u.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := Bind(r, &got); err != nil {
+			t.Errorf("Bind: %v", err)
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, multipartRequest(t,
+		part{field: "title", data: []byte("trip")},
+		part{field: "cover", filename: "c.png", data: img},
+		part{field: "photos", filename: "1.png", data: img},
+		part{field: "photos", filename: "2.png", data: img},
+	))
+	if got.Title != "trip" || got.Cover == nil || got.Cover.Filename != "c.png" || len(got.Photos) != 2 {
+		t.Errorf("bound = %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, multipartRequest(t, part{field: "cover", filename: "c.txt", data: []byte("text")}))
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("status = %d, want 415", rec.Code)
+	}
+}
+
+func TestUploaderBindValidates(t *testing.T) {
+	u, dir := newUploader(t, Config{})
+	var req galleryRequest
+	err := u.Bind(multipartRequest(t, part{field: "photos", filename: "1.txt", data: []byte("x")}), &req)
+	if err == nil {
+		t.Fatal("missing title and cover should fail validation")
+	}
+	if files := storedFiles(t, dir); len(files) != 0 {
+		t.Errorf("files left behind after failed bind: %v", files)
+	}
+}
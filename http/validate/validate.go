@@ -0,0 +1,81 @@
+// Package validate binds and validates request structs before the handler
+// runs. Validation failures are rendered as a structured 422 response
+// listing the field, the failed rule and a message for each violation.
+package validate
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/binding"
+	"github.com/leeforge/framework/http/response"
+)
+
+// WriteError 输出 binding.Bind 失败的响应：校验错误为 422，附带逐字段的
+// field/rule/message；请求体读取或 JSON 解析错误为 400
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var verrs binding.ValidationErrors
+	if stderrors.As(err, &verrs) {
+		response.WriteError(w, r, verrs.AppError())
+		return
+	}
+	message := err.Error()
+	var bindErr *binding.BindError
+	if stderrors.As(err, &bindErr) {
+		if bindErr.Type == "validation_error" {
+			response.WriteError(w, r, errors.NewValidation(bindErr.Message).WithHTTPStatus(http.StatusUnprocessableEntity))
+			return
+		}
+		message = bindErr.Message
+	}
+	response.WriteError(w, r, errors.New(errors.ErrorTypeInvalid, message))
+}
+
+// Handle 将业务函数包装为 http.HandlerFunc：绑定并校验 T（查询参数 + 请求体），
+// 失败时输出 WriteError 的响应而不调用 fn；fn 返回的错误经 response.WriteError
+// 映射，成功结果以 200 写入标准响应信封。
+//
+//	r.Post("/users", validate.Handle(func(ctx context.Context, req CreateUserRequest) (any, error) {
+//		return svc.Create(ctx, req)
+//	}))
+func Handle[T any](fn func(ctx context.Context, req T) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req T
+		if err := binding.Bind(r, &req); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		data, err := fn(r.Context(), req)
+		if err != nil {
+			response.WriteError(w, r, err)
+			return
+		}
+		response.Write(w, r, http.StatusOK, data)
+	}
+}
+
+type requestKey[T any] struct{}
+
+// Middleware 是 Handle 的中间件形式：绑定并校验 T 后存入请求上下文，
+// 下游通过 Request 取出。请求体已被读取，下游不应再次解析。
+func Middleware[T any]() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req T
+			if err := binding.Bind(r, &req); err != nil {
+				WriteError(w, r, err)
+				return
+			}
+			ctx := context.WithValue(r.Context(), requestKey[T]{}, req)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Request 返回 Middleware 存入上下文的 T
+func Request[T any](ctx context.Context) (T, bool) {
+	req, ok := ctx.Value(requestKey[T]{}).(T)
+	return req, ok
+}
@@ -0,0 +1,111 @@
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leeforge/framework/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type createRequest struct {
+	DryRun bool   `query:"dryRun"`
+	Name   string `json:"name" validate:"required,min=2"`
+	Role   string `json:"role" validate:"omitempty,oneof=admin user"`
+}
+
+type envelope struct {
+	Data  any `json:"data"`
+	Error *struct {
+		Code    int                     `json:"code"`
+		Message string                  `json:"message"`
+		Details []errors.FieldViolation `json:"details"`
+	} `json:"error"`
+}
+
+func serve(h http.Handler, method, target, body string) (*httptest.ResponseRecorder, envelope) {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	var env envelope
+	_ = json.Unmarshal(rec.Body.Bytes(), &env)
+	return rec, env
+}
+
+func TestHandle(t *testing.T) {
+	called := false
+	h := Handle(func(ctx context.Context, req createRequest) (any, error) {
+		called = true
+		if req.Name == "taken" {
+			return nil, errors.NewConflict("user", req.Name)
+		}
+		return map[string]any{"name": req.Name, "dryRun": req.DryRun}, nil
+	})
+
+	rec, env := serve(h, http.MethodPost, "/users?dryRun=true", `{"name":"ann"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, map[string]any{"name": "ann", "dryRun": true}, env.Data)
+
+	// 校验失败不调用业务函数，返回 422 与逐字段明细
+	called = false
+	rec, env = serve(h, http.MethodPost, "/users", `{"name":"a","role":"root"}`)
+	require.False(t, called)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Equal(t, []errors.FieldViolation{
+		{Type: errors.ViolationType, Field: "Name", Rule: "min", Message: "must be at least 2 characters long"},
+		{Type: errors.ViolationType, Field: "Role", Rule: "oneof", Message: "must be one of: admin user"},
+	}, env.Error.Details)
+
+	// 空请求体按缺少必填字段处理
+	rec, env = serve(h, http.MethodPost, "/users", "")
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Equal(t, "required", env.Error.Details[0].Rule)
+
+	rec, env = serve(h, http.MethodPost, "/users", `{"name":`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, env.Error.Message, "failed to unmarshal JSON")
+
+	rec, _ = serve(h, http.MethodPost, "/users", `{"name":"taken"}`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestMiddleware(t *testing.T) {
+	type listRequest struct {
+		Page int `query:"page" default:"1" validate:"min=1"`
+	}
+	h := Middleware[listRequest]()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, ok := Request[listRequest](r.Context())
+		require.True(t, ok)
+		require.Equal(t, 3, req.Page)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec, _ := serve(h, http.MethodGet, "/items?page=3", "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec, env := serve(h, http.MethodGet, "/items?page=0", "")
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Equal(t, "Page", env.Error.Details[0].Field)
+}
+
+func TestHandleSanitizesBeforeValidation(t *testing.T) {
+	type inviteRequest struct {
+		Team  string `query:"team" mod:"trim,lower" validate:"required,slug"`
+		Email string `json:"email" mod:"trim,lower" validate:"required,email"`
+	}
+	h := Handle(func(ctx context.Context, req inviteRequest) (any, error) {
+		return req, nil
+	})
+
+	// 先规范化再校验：带空格与大写的输入可以通过校验
+	rec, env := serve(h, http.MethodPost, "/invites?team=%20Core-Team%20", `{"email":"  Ann@Example.COM "}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, map[string]any{"Team": "core-team", "email": "ann@example.com"}, env.Data)
+
+	rec, _ = serve(h, http.MethodPost, "/invites?team=%20%20", `{"email":"ann@example.com"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
@@ -0,0 +1,98 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned when a delivery would connect to an address
+// that is not publicly routable.
+var ErrBlockedAddress = errors.New("webhooks: endpoint address is not allowed")
+
+var (
+	// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which
+	// also holds some cloud metadata services, e.g. 100.100.100.200.
+	sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+	thisNetwork        = netip.MustParsePrefix("0.0.0.0/8")
+)
+
+// blockedIP reports whether ip is loopback, private (RFC 1918, fc00::/7),
+// link-local (including the 169.254.169.254 metadata service), multicast,
+// unspecified or shared address space.
+func blockedIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		ip.IsUnspecified() || sharedAddressSpace.Contains(ip) || thisNetwork.Contains(ip)
+}
+
+// validURL reports whether raw is an absolute http or https URL.
+func validURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// blockedHost reports whether the host of raw is localhost or a literal
+// blocked IP. Host names are checked again after resolution when dialing.
+func blockedHost(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && blockedIP(ip)
+}
+
+// dialControl runs after DNS resolution, so it also catches host names
+// that resolve, or are rebound, to blocked addresses.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if blockedIP(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	return nil
+}
+
+// NewClient returns the default delivery client: it refuses to connect to
+// loopback, private, link-local and other non-public addresses, does not
+// use a proxy (which would resolve the host itself) and does not follow
+// redirects, so a 3xx reply is a failed attempt. Pass it, adjusted, as
+// Options.Client to keep the protection with other settings.
+func NewClient(timeout time.Duration) *http.Client {
+	return newClient(timeout, true)
+}
+
+func newClient(timeout time.Duration, guard bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	if guard {
+		dialer.Control = dialControl
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,193 @@
+package webhooks
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/binding"
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/http/router"
+	"github.com/leeforge/framework/jobs"
+)
+
+type endpointRequest struct {
+	Subscriber string   `json:"subscriber"`
+	URL        string   `json:"url"`
+	Events     []string `json:"events"`
+	Secret     string   `json:"secret"`
+	Disabled   bool     `json:"disabled"`
+}
+
+// endpointWithSecret exposes the signing secret, which Endpoint hides, in
+// the responses that create it.
+type endpointWithSecret struct {
+	*Endpoint
+	Secret string `json:"secret"`
+}
+
+// Handler returns the management API, meant to be mounted behind admin
+// authentication:
+//
+//	GET    /endpoints?subscriber=          list endpoints
+//	POST   /endpoints                      register (response includes the secret)
+//	GET    /endpoints/{id}                 get an endpoint
+//	PUT    /endpoints/{id}                 update url, events or disabled
+//	DELETE /endpoints/{id}                 remove an endpoint
+//	GET    /deliveries?endpoint_id=&subscriber=&event_id=&failed=&limit=
+//	GET    /dead-letters                   dead-lettered delivery jobs
+//	POST   /dead-letters/{id}/redeliver    queue a dead-lettered delivery again
+func (s *Sender) Handler() http.Handler {
+	r := router.New()
+	r.Get("/endpoints", s.listEndpoints)
+	r.Post("/endpoints", s.createEndpoint)
+	r.Get("/endpoints/{id}", s.getEndpoint)
+	r.Put("/endpoints/{id}", s.updateEndpoint)
+	r.Delete("/endpoints/{id}", s.deleteEndpoint)
+	r.Get("/deliveries", s.listDeliveries)
+	r.Get("/dead-letters", s.listDeadLetters)
+	r.Post("/dead-letters/{id}/redeliver", s.redeliver)
+	return r
+}
+
+func (s *Sender) listEndpoints(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := s.opts.Endpoints.List(r.Context(), r.URL.Query().Get("subscriber"))
+	if err != nil {
+		response.WriteError(w, r, err)
+		return
+	}
+	response.WriteJSON(w, r, http.StatusOK, endpoints)
+}
+
+func (s *Sender) createEndpoint(w http.ResponseWriter, r *http.Request) {
+	req, ok := bindEndpoint(w, r)
+	if !ok {
+		return
+	}
+	e, err := s.Register(r.Context(), &Endpoint{
+		Subscriber: req.Subscriber,
+		URL:        req.URL,
+		Events:     req.Events,
+		Secret:     req.Secret,
+		Disabled:   req.Disabled,
+	})
+	if err != nil {
+		response.WriteError(w, r, err)
+		return
+	}
+	response.WriteJSON(w, r, http.StatusCreated, endpointWithSecret{Endpoint: e, Secret: e.Secret})
+}
+
+func (s *Sender) getEndpoint(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	e, err := s.opts.Endpoints.Get(r.Context(), id)
+	if err != nil {
+		response.WriteError(w, r, endpointError(err, id))
+		return
+	}
+	response.WriteJSON(w, r, http.StatusOK, e)
+}
+
+func (s *Sender) updateEndpoint(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	e, err := s.opts.Endpoints.Get(r.Context(), id)
+	if err != nil {
+		response.WriteError(w, r, endpointError(err, id))
+		return
+	}
+	req, ok := bindEndpoint(w, r)
+	if !ok {
+		return
+	}
+	e.Subscriber, e.URL, e.Events, e.Disabled = req.Subscriber, req.URL, req.Events, req.Disabled
+	if req.Secret != "" {
+		e.Secret = req.Secret
+	}
+	if e, err = s.Register(r.Context(), e); err != nil {
+		response.WriteError(w, r, err)
+		return
+	}
+	response.WriteJSON(w, r, http.StatusOK, e)
+}
+
+func (s *Sender) deleteEndpoint(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := s.opts.Endpoints.Delete(r.Context(), id); err != nil {
+		response.WriteError(w, r, endpointError(err, id))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Sender) listDeliveries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := DeliveryQuery{
+		EndpointID: q.Get("endpoint_id"),
+		Subscriber: q.Get("subscriber"),
+		EventID:    q.Get("event_id"),
+	}
+	v := errors.Validation()
+	if raw := q.Get("failed"); raw != "" {
+		failed, err := strconv.ParseBool(raw)
+		v.Check(err == nil, "failed", "must be a boolean")
+		query.Failed = failed
+	}
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		v.Check(err == nil && limit > 0, "limit", "must be a positive integer")
+		query.Limit = limit
+	}
+	if err := v.Err(); err != nil {
+		response.WriteError(w, r, err)
+		return
+	}
+
+	deliveries, err := s.opts.Deliveries.List(r.Context(), query)
+	if err != nil {
+		response.WriteError(w, r, err)
+		return
+	}
+	response.WriteJSON(w, r, http.StatusOK, deliveries)
+}
+
+func (s *Sender) listDeadLetters(w http.ResponseWriter, r *http.Request) {
+	dead, err := s.DeadLetters(r.Context())
+	if err != nil {
+		response.WriteError(w, r, err)
+		return
+	}
+	response.WriteJSON(w, r, http.StatusOK, dead)
+}
+
+func (s *Sender) redeliver(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	job, err := s.Redeliver(r.Context(), id)
+	switch {
+	case stderrors.Is(err, jobs.ErrNotFound):
+		response.WriteError(w, r, errors.NewNotFound("delivery", id))
+	case err != nil:
+		response.WriteError(w, r, errors.WrapWithType(err, errors.ErrorTypeConflict, err.Error()))
+	default:
+		response.WriteJSON(w, r, http.StatusAccepted, job)
+	}
+}
+
+// bindEndpoint decodes an endpoint body, writing a 400 on failure. Register
+// validates the fields.
+func bindEndpoint(w http.ResponseWriter, r *http.Request) (*endpointRequest, bool) {
+	var req endpointRequest
+	if err := binding.JSON(r, &req); err != nil {
+		response.WriteError(w, r, errors.Validation().Field("", err.Error()).Build())
+		return nil, false
+	}
+	return &req, true
+}
+
+func endpointError(err error, id string) error {
+	if stderrors.Is(err, ErrEndpointNotFound) {
+		return errors.NewNotFound("endpoint", id)
+	}
+	return err
+}
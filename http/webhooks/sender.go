@@ -0,0 +1,375 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	framerrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/jobs"
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/plugin"
+	"github.com/leeforge/framework/resilience"
+	"go.uber.org/zap"
+)
+
+// JobTypeDeliver is the job type of a single endpoint delivery.
+const JobTypeDeliver = "webhooks.deliver"
+
+// Metric names, labelled by "endpoint" and, for the counter, "outcome"
+// (success, failure or rejected).
+const (
+	MetricDeliveries       = "webhook_deliveries_total"
+	MetricDeliveryDuration = "webhook_delivery_duration_seconds"
+)
+
+// Options configures a Sender.
+type Options struct {
+	// Endpoints stores subscriber endpoints (default in memory).
+	Endpoints EndpointStore
+	// Deliveries records every attempt (default in memory, 1000 entries).
+	Deliveries DeliveryLog
+	// Client sends deliveries (default NewClient with a 10s timeout). A
+	// custom client must guard against internal addresses itself.
+	Client *http.Client
+	// AllowPrivateNetworks accepts endpoints on loopback, private and
+	// link-local addresses, for receivers on an internal network. It turns
+	// off the registration check and the dial check of the default Client.
+	AllowPrivateNetworks bool
+	// MaxAttempts overrides the job manager's attempt limit per delivery.
+	MaxAttempts int
+	// Breaker is the template for per-endpoint circuit breakers; Name is
+	// set to "webhook:<endpoint id>". Attempts rejected by an open breaker
+	// fail without a request and count as an attempt.
+	Breaker resilience.BreakerConfig
+	// UserAgent is sent with every delivery (default "leeforge-webhooks/1").
+	UserAgent string
+	// MaxResponseBytes bounds the response body kept in the log (default 1 KiB).
+	MaxResponseBytes int
+	// Metrics receives delivery counters, durations and breaker states.
+	Metrics *metrics.Collector
+	Logger  *zap.Logger
+}
+
+// Sender fans events out to matching endpoints. Each endpoint delivery is
+// a job, so retries, backoff and dead-lettering follow the job manager's
+// Options; use a durable jobs.Store to survive restarts.
+type Sender struct {
+	jobs *jobs.Manager
+	opts Options
+
+	mu       sync.Mutex
+	breakers map[string]*resilience.Breaker
+}
+
+type deliveryPayload struct {
+	EndpointID string `json:"endpointId"`
+	Event      Event  `json:"event"`
+}
+
+// NewSender creates a sender and registers the delivery job on manager. It
+// must be called before manager.Start.
+func NewSender(manager *jobs.Manager, opts Options) *Sender {
+	if opts.Endpoints == nil {
+		opts.Endpoints = NewMemoryEndpointStore()
+	}
+	if opts.Deliveries == nil {
+		opts.Deliveries = NewMemoryDeliveryLog(0)
+	}
+	if opts.Client == nil {
+		opts.Client = newClient(10*time.Second, !opts.AllowPrivateNetworks)
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "leeforge-webhooks/1"
+	}
+	if opts.MaxResponseBytes <= 0 {
+		opts.MaxResponseBytes = 1024
+	}
+	if opts.Breaker.Metrics == nil {
+		opts.Breaker.Metrics = opts.Metrics
+	}
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+
+	s := &Sender{jobs: manager, opts: opts, breakers: make(map[string]*resilience.Breaker)}
+	manager.Register(JobTypeDeliver, s.deliver)
+	return s
+}
+
+// Endpoints returns the endpoint store.
+func (s *Sender) Endpoints() EndpointStore {
+	return s.opts.Endpoints
+}
+
+// Deliveries returns the delivery log.
+func (s *Sender) Deliveries() DeliveryLog {
+	return s.opts.Deliveries
+}
+
+// Register validates and saves an endpoint, assigning an ID and a signing
+// secret when they are empty. Validation failures are framework validation
+// errors naming the field.
+func (s *Sender) Register(ctx context.Context, endpoint *Endpoint) (*Endpoint, error) {
+	if err := s.validate(endpoint); err != nil {
+		return nil, err
+	}
+	e := *endpoint
+	if e.ID == "" {
+		e.ID = uuid.NewString()
+	}
+	if e.Secret == "" {
+		secret, err := GenerateSecret()
+		if err != nil {
+			return nil, err
+		}
+		e.Secret = secret
+	}
+	now := time.Now()
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = now
+	}
+	e.UpdatedAt = now
+	if err := s.opts.Endpoints.Save(ctx, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *Sender) validate(e *Endpoint) error {
+	return framerrors.Validation().
+		Check(e.Subscriber != "", "subscriber", "is required").
+		Check(validURL(e.URL), "url", "must be an absolute http or https URL").
+		Check(s.opts.AllowPrivateNetworks || !blockedHost(e.URL), "url", "must not target a loopback, private or link-local address").
+		Err()
+}
+
+// Send publishes an event of eventType with data to every enabled endpoint
+// subscribed to it and returns the event. A nil error means the deliveries
+// were queued, not that they succeeded.
+func (s *Sender) Send(ctx context.Context, eventType string, data any) (*Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("webhooks: encode %s data: %w", eventType, err)
+	}
+	event := &Event{ID: uuid.NewString(), Type: eventType, CreatedAt: time.Now(), Data: raw}
+	return event, s.Dispatch(ctx, event)
+}
+
+// Dispatch queues event for every enabled endpoint subscribed to its type.
+// Dispatching the same event twice queues each endpoint delivery once.
+func (s *Sender) Dispatch(ctx context.Context, event *Event) error {
+	endpoints, err := s.opts.Endpoints.List(ctx, "")
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, e := range endpoints {
+		if e.Disabled || !e.Matches(event.Type) {
+			continue
+		}
+		opts := []jobs.EnqueueOption{jobs.WithIdempotencyKey("webhook:" + event.ID + ":" + e.ID)}
+		if s.opts.MaxAttempts > 0 {
+			opts = append(opts, jobs.WithMaxAttempts(s.opts.MaxAttempts))
+		}
+		_, err := s.jobs.Enqueue(ctx, JobTypeDeliver, deliveryPayload{EndpointID: e.ID, Event: *event}, opts...)
+		if err != nil && !errors.Is(err, jobs.ErrDuplicate) {
+			errs = append(errs, fmt.Errorf("webhooks: queue %s for endpoint %s: %w", event.ID, e.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Subscribe forwards events published on bus under topics to Send, using
+// the event name as the webhook event type.
+func (s *Sender) Subscribe(bus plugin.EventBus, topics ...string) []plugin.Subscription {
+	subs := make([]plugin.Subscription, 0, len(topics))
+	for _, topic := range topics {
+		subs = append(subs, bus.Subscribe(topic, func(ctx context.Context, e plugin.Event) error {
+			raw, err := json.Marshal(e.Data)
+			if err != nil {
+				return fmt.Errorf("webhooks: encode %s data: %w", e.Name, err)
+			}
+			created := e.Timestamp
+			if created.IsZero() {
+				created = time.Now()
+			}
+			return s.Dispatch(ctx, &Event{ID: uuid.NewString(), Type: e.Name, CreatedAt: created, Data: raw})
+		}))
+	}
+	return subs
+}
+
+// DeadLetters returns dead-lettered deliveries. It requires the job
+// manager to have a DeadLetter queue.
+func (s *Sender) DeadLetters(ctx context.Context) ([]*jobs.Job, error) {
+	all, err := s.jobs.DeadLetters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []*jobs.Job
+	for _, job := range all {
+		if job.Type == JobTypeDeliver {
+			out = append(out, job)
+		}
+	}
+	return out, nil
+}
+
+// Redeliver queues a failed delivery job again with fresh attempts.
+func (s *Sender) Redeliver(ctx context.Context, jobID string) (*jobs.Job, error) {
+	job, err := s.jobs.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Type != JobTypeDeliver {
+		return nil, jobs.ErrNotFound
+	}
+	return s.jobs.Replay(ctx, jobID)
+}
+
+// BreakerState returns the circuit state of an endpoint.
+func (s *Sender) BreakerState(endpointID string) resilience.BreakerState {
+	return s.breaker(endpointID).State()
+}
+
+func (s *Sender) breaker(endpointID string) *resilience.Breaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.breakers[endpointID]
+	if !ok {
+		config := s.opts.Breaker
+		config.Name = "webhook:" + endpointID
+		b = resilience.NewBreaker(config)
+		s.breakers[endpointID] = b
+	}
+	return b
+}
+
+// deliver is the JobTypeDeliver handler.
+func (s *Sender) deliver(ctx context.Context, job *jobs.Job) (any, error) {
+	var p deliveryPayload
+	if err := job.Decode(&p); err != nil {
+		return nil, err
+	}
+	endpoint, err := s.opts.Endpoints.Get(ctx, p.EndpointID)
+	if errors.Is(err, ErrEndpointNotFound) {
+		return "endpoint removed", nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Disabled {
+		return "endpoint disabled", nil
+	}
+
+	d := &Delivery{
+		ID:         deliveryID(p.Event.ID, endpoint.ID),
+		JobID:      job.ID,
+		EventID:    p.Event.ID,
+		EventType:  p.Event.Type,
+		EndpointID: endpoint.ID,
+		Subscriber: endpoint.Subscriber,
+		URL:        endpoint.URL,
+		Attempt:    job.Attempts,
+		CreatedAt:  time.Now(),
+	}
+
+	done, err := s.breaker(endpoint.ID).Allow()
+	if err != nil {
+		d.Error = err.Error()
+		s.record(ctx, d, "rejected")
+		return nil, err
+	}
+
+	start := time.Now()
+	status, err := s.post(ctx, endpoint, &p.Event, job.Attempts, d)
+	done(err)
+	elapsed := time.Since(start)
+	d.DurationMs = elapsed.Milliseconds()
+	if s.opts.Metrics != nil {
+		s.opts.Metrics.ObserveHistogram(MetricDeliveryDuration, elapsed.Seconds(), map[string]string{"endpoint": endpoint.ID})
+	}
+
+	if status == http.StatusGone {
+		// The receiver asked us to stop; retrying cannot succeed.
+		endpoint.Disabled = true
+		endpoint.UpdatedAt = time.Now()
+		if err := s.opts.Endpoints.Save(ctx, endpoint); err != nil {
+			s.opts.Logger.Error("disabling webhook endpoint failed", zap.String("endpoint", endpoint.ID), zap.Error(err))
+		}
+		d.Error = err.Error()
+		s.record(ctx, d, "failure")
+		return "endpoint gone", nil
+	}
+	if err != nil {
+		d.Error = err.Error()
+		s.record(ctx, d, "failure")
+		s.opts.Logger.Warn("webhook delivery failed",
+			zap.String("endpoint", endpoint.ID),
+			zap.String("event_id", p.Event.ID),
+			zap.Int("attempt", job.Attempts),
+			zap.Error(err))
+		return nil, err
+	}
+	d.Success = true
+	s.record(ctx, d, "success")
+	return map[string]int{"statusCode": status}, nil
+}
+
+// post sends one signed request and fills d's response fields. It returns
+// the status code and an error for transport failures and non-2xx replies.
+func (s *Sender) post(ctx context.Context, endpoint *Endpoint, event *Event, attempt int, d *Delivery) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", s.opts.UserAgent)
+	req.Header.Set(HeaderID, event.ID)
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(attempt))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, time.Now(), body))
+
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, int64(s.opts.MaxResponseBytes)))
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	d.StatusCode = resp.StatusCode
+	d.Response = string(snippet)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhooks: endpoint responded %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// deliveryID derives the delivery ID from the event and endpoint, so every
+// attempt, and a redelivery, of the same delivery shares it.
+func deliveryID(eventID, endpointID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(eventID+":"+endpointID)).String()
+}
+
+func (s *Sender) record(ctx context.Context, d *Delivery, outcome string) {
+	if err := s.opts.Deliveries.Record(context.WithoutCancel(ctx), d); err != nil {
+		s.opts.Logger.Error("recording webhook delivery failed", zap.String("delivery", d.ID), zap.Error(err))
+	}
+	if s.opts.Metrics != nil {
+		s.opts.Metrics.IncCounter(MetricDeliveries, map[string]string{"endpoint": d.EndpointID, "outcome": outcome})
+	}
+}
@@ -0,0 +1,153 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	framerrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/response"
+)
+
+// Headers set on every delivery.
+const (
+	// HeaderID carries Event.ID, the idempotency key for receivers.
+	HeaderID = "Webhook-Id"
+	// HeaderEvent carries Event.Type.
+	HeaderEvent = "Webhook-Event"
+	// HeaderAttempt carries the 1-based delivery attempt.
+	HeaderAttempt = "Webhook-Attempt"
+	// HeaderSignature carries "t=<unix seconds>,v1=<hex HMAC-SHA256>" where
+	// the MAC covers "<t>.<body>".
+	HeaderSignature = "Webhook-Signature"
+)
+
+// GenerateSecret returns a random signing secret.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign returns the HeaderSignature value for body signed at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac(secret, ts, body))
+}
+
+func mac(secret, ts string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte{'.'})
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// Verify checks a HeaderSignature value against body. Any of secrets may
+// match, so receivers can rotate secrets without downtime. A positive
+// tolerance rejects signatures whose timestamp is further than tolerance
+// from now.
+func Verify(header string, body []byte, tolerance time.Duration, secrets ...string) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+	var ts string
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		if skew := time.Since(time.Unix(unix, 0)); skew > tolerance || skew < -tolerance {
+			return ErrSignatureExpired
+		}
+	}
+	for _, secret := range secrets {
+		expected := mac(secret, ts, body)
+		for _, sig := range sigs {
+			if hmac.Equal(sig, expected) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// Receiver verifies incoming deliveries.
+type Receiver struct {
+	// Secrets are the accepted signing secrets.
+	Secrets []string
+	// Tolerance bounds the signature timestamp skew, limiting replays.
+	// Defaults to 5 minutes; negative disables the check.
+	Tolerance time.Duration
+	// MaxBodyBytes bounds the request body. Defaults to 1 MiB.
+	MaxBodyBytes int64
+}
+
+// Verify reads and verifies r's body and decodes the event. The body is
+// restored so r can be read again.
+func (rv Receiver) Verify(r *http.Request) (*Event, error) {
+	limit := rv.MaxBodyBytes
+	if limit <= 0 {
+		limit = 1 << 20
+	}
+	tolerance := rv.Tolerance
+	if tolerance == 0 {
+		tolerance = 5 * time.Minute
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("webhooks: read body: %w", err)
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("webhooks: body exceeds %d bytes", limit)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := Verify(r.Header.Get(HeaderSignature), body, tolerance, rv.Secrets...); err != nil {
+		return nil, err
+	}
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("webhooks: decode event: %w", err)
+	}
+	return &event, nil
+}
+
+// Middleware rejects deliveries that fail verification with 401 and passes
+// the rest to next with the body intact.
+func (rv Receiver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := rv.Verify(r); err != nil {
+			response.WriteError(w, r, framerrors.NewUnauthorized(err.Error()))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,50 @@
+package webhooks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Now()
+	header := Sign("old", now, body)
+
+	require.NoError(t, Verify(header, body, time.Minute, "new", "old"), "rotated secrets must be accepted")
+	require.ErrorIs(t, Verify(header, body, time.Minute, "other"), ErrInvalidSignature)
+	require.ErrorIs(t, Verify(header, []byte(`{"id":"evt_2"}`), time.Minute, "old"), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("", body, time.Minute, "old"), ErrMissingSignature)
+	require.ErrorIs(t, Verify("garbage", body, time.Minute, "old"), ErrInvalidSignature)
+
+	stale := Sign("old", now.Add(-10*time.Minute), body)
+	require.ErrorIs(t, Verify(stale, body, 5*time.Minute, "old"), ErrSignatureExpired)
+	require.NoError(t, Verify(stale, body, 0, "old"))
+}
+
+func TestReceiverMiddleware(t *testing.T) {
+	body := `{"id":"evt_1","type":"order.paid","createdAt":"2024-01-01T00:00:00Z"}`
+	var got string
+	h := Receiver{Secrets: []string{"s"}}.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(body))
+	req.Header.Set(HeaderSignature, Sign("s", time.Now(), []byte(body)))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, body, got, "body must be readable after verification")
+
+	req = httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(body))
+	req.Header.Set(HeaderSignature, Sign("wrong", time.Now(), []byte(body)))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
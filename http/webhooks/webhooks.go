@@ -0,0 +1,232 @@
+// Package webhooks delivers events to subscriber HTTP endpoints. Deliveries
+// are signed with a per-endpoint secret, carry a stable idempotency ID, run
+// as jobs so they are retried with backoff and dead-lettered, pass through
+// a per-endpoint circuit breaker, and are recorded in a queryable log. The
+// receiving side verifies them with Receiver.
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrEndpointNotFound is returned when an endpoint does not exist.
+	ErrEndpointNotFound = errors.New("webhooks: endpoint not found")
+	// ErrInvalidSignature is returned when no secret produces the signature.
+	ErrInvalidSignature = errors.New("webhooks: invalid signature")
+	// ErrMissingSignature is returned when a request has no signature header.
+	ErrMissingSignature = errors.New("webhooks: missing signature")
+	// ErrSignatureExpired is returned when the signed timestamp is outside
+	// the receiver's tolerance.
+	ErrSignatureExpired = errors.New("webhooks: signature timestamp outside tolerance")
+)
+
+// Endpoint is a subscriber's delivery target.
+type Endpoint struct {
+	ID         string `json:"id"`
+	Subscriber string `json:"subscriber"`
+	URL        string `json:"url"`
+	// Secret signs deliveries. It is never included in JSON output.
+	Secret string `json:"-"`
+	// Events lists the event types delivered to the endpoint. An entry
+	// ending in ".*" matches a prefix, "*" or an empty list matches all.
+	Events    []string  `json:"events,omitempty"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Matches reports whether the endpoint subscribes to eventType.
+func (e *Endpoint) Matches(eventType string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, pattern := range e.Events {
+		switch {
+		case pattern == "*" || pattern == eventType:
+			return true
+		case strings.HasSuffix(pattern, ".*") && strings.HasPrefix(eventType, pattern[:len(pattern)-1]):
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the body POSTed to endpoints. ID stays the same across retries
+// and endpoints so receivers can deduplicate on it.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// Delivery records one delivery attempt. ID identifies the delivery of an
+// event to an endpoint and is shared by all its attempts.
+type Delivery struct {
+	ID         string    `json:"id"`
+	JobID      string    `json:"jobId"`
+	EventID    string    `json:"eventId"`
+	EventType  string    `json:"eventType"`
+	EndpointID string    `json:"endpointId"`
+	Subscriber string    `json:"subscriber"`
+	URL        string    `json:"url"`
+	Attempt    int       `json:"attempt"`
+	Success    bool      `json:"success"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Response   string    `json:"response,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// DeliveryQuery filters the delivery log. Zero fields match everything.
+type DeliveryQuery struct {
+	EndpointID string
+	Subscriber string
+	EventID    string
+	// Failed restricts the result to failed attempts.
+	Failed bool
+	// Limit caps the result, newest first. Zero means 100.
+	Limit int
+}
+
+func (q DeliveryQuery) match(d *Delivery) bool {
+	return (q.EndpointID == "" || d.EndpointID == q.EndpointID) &&
+		(q.Subscriber == "" || d.Subscriber == q.Subscriber) &&
+		(q.EventID == "" || d.EventID == q.EventID) &&
+		(!q.Failed || !d.Success)
+}
+
+// EndpointStore persists endpoints.
+type EndpointStore interface {
+	Save(ctx context.Context, endpoint *Endpoint) error
+	Get(ctx context.Context, id string) (*Endpoint, error)
+	Delete(ctx context.Context, id string) error
+	// List returns the endpoints of subscriber, or all endpoints when
+	// subscriber is empty.
+	List(ctx context.Context, subscriber string) ([]*Endpoint, error)
+}
+
+// DeliveryLog records delivery attempts.
+type DeliveryLog interface {
+	Record(ctx context.Context, delivery *Delivery) error
+	// List returns matching deliveries, newest first.
+	List(ctx context.Context, query DeliveryQuery) ([]*Delivery, error)
+}
+
+// MemoryEndpointStore is an in-process EndpointStore.
+type MemoryEndpointStore struct {
+	mu        sync.RWMutex
+	endpoints map[string]*Endpoint
+}
+
+// NewMemoryEndpointStore creates an empty endpoint store.
+func NewMemoryEndpointStore() *MemoryEndpointStore {
+	return &MemoryEndpointStore{endpoints: make(map[string]*Endpoint)}
+}
+
+// Save implements EndpointStore.
+func (s *MemoryEndpointStore) Save(_ context.Context, endpoint *Endpoint) error {
+	cp := *endpoint
+	cp.Events = append([]string(nil), endpoint.Events...)
+	s.mu.Lock()
+	s.endpoints[endpoint.ID] = &cp
+	s.mu.Unlock()
+	return nil
+}
+
+// Get implements EndpointStore.
+func (s *MemoryEndpointStore) Get(_ context.Context, id string) (*Endpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.endpoints[id]
+	if !ok {
+		return nil, ErrEndpointNotFound
+	}
+	cp := *e
+	return &cp, nil
+}
+
+// Delete implements EndpointStore.
+func (s *MemoryEndpointStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.endpoints[id]; !ok {
+		return ErrEndpointNotFound
+	}
+	delete(s.endpoints, id)
+	return nil
+}
+
+// List implements EndpointStore, ordered by creation time.
+func (s *MemoryEndpointStore) List(_ context.Context, subscriber string) ([]*Endpoint, error) {
+	s.mu.RLock()
+	out := make([]*Endpoint, 0, len(s.endpoints))
+	for _, e := range s.endpoints {
+		if subscriber == "" || e.Subscriber == subscriber {
+			cp := *e
+			out = append(out, &cp)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+// MemoryDeliveryLog keeps the most recent deliveries in memory.
+type MemoryDeliveryLog struct {
+	mu         sync.RWMutex
+	capacity   int
+	deliveries []*Delivery
+}
+
+// NewMemoryDeliveryLog creates a log holding at most capacity deliveries
+// (default 1000); the oldest are dropped first.
+func NewMemoryDeliveryLog(capacity int) *MemoryDeliveryLog {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &MemoryDeliveryLog{capacity: capacity}
+}
+
+// Record implements DeliveryLog.
+func (l *MemoryDeliveryLog) Record(_ context.Context, delivery *Delivery) error {
+	cp := *delivery
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.deliveries) >= l.capacity {
+		l.deliveries = append(l.deliveries[:0], l.deliveries[1:]...)
+	}
+	l.deliveries = append(l.deliveries, &cp)
+	return nil
+}
+
+// List implements DeliveryLog.
+func (l *MemoryDeliveryLog) List(_ context.Context, query DeliveryQuery) ([]*Delivery, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var out []*Delivery
+	for i := len(l.deliveries) - 1; i >= 0 && len(out) < limit; i-- {
+		if d := l.deliveries[i]; query.match(d) {
+			cp := *d
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
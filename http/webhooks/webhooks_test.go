@@ -0,0 +1,250 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/jobs"
+	"github.com/leeforge/framework/resilience"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSender(t *testing.T, opts Options) (*Sender, *jobs.Manager) {
+	t.Helper()
+	m := jobs.NewManager(jobs.Options{
+		Workers:    2,
+		Backoff:    func(int) time.Duration { return time.Millisecond },
+		DeadLetter: jobs.NewMemoryDeadLetterQueue(),
+	})
+	s := NewSender(m, opts)
+	m.Start()
+	t.Cleanup(func() { m.Stop(context.Background()) })
+	return s, m
+}
+
+func waitForDeliveries(t *testing.T, s *Sender, n int) []*Delivery {
+	t.Helper()
+	var got []*Delivery
+	require.Eventually(t, func() bool {
+		got, _ = s.Deliveries().List(context.Background(), DeliveryQuery{})
+		return len(got) >= n
+	}, 2*time.Second, 5*time.Millisecond)
+	return got
+}
+
+func TestSenderSignsAndRetries(t *testing.T) {
+	var mu sync.Mutex
+	var ids []string
+	var secret string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event, err := Receiver{Secrets: []string{secret}}.Verify(r)
+		require.NoError(t, err)
+		require.Equal(t, "order.paid", event.Type)
+		require.JSONEq(t, `{"orderId":42}`, string(event.Data))
+
+		mu.Lock()
+		defer mu.Unlock()
+		ids = append(ids, r.Header.Get(HeaderID))
+		if len(ids) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s, _ := newTestSender(t, Options{AllowPrivateNetworks: true})
+	ctx := context.Background()
+	e, err := s.Register(ctx, &Endpoint{Subscriber: "shop", URL: srv.URL, Events: []string{"order.*"}})
+	require.NoError(t, err)
+	secret = e.Secret
+	_, err = s.Register(ctx, &Endpoint{Subscriber: "crm", URL: srv.URL, Events: []string{"user.created"}})
+	require.NoError(t, err)
+
+	event, err := s.Send(ctx, "order.paid", map[string]int{"orderId": 42})
+	require.NoError(t, err)
+	require.NoError(t, s.Dispatch(ctx, event), "re-dispatching must not queue a second delivery")
+
+	deliveries := waitForDeliveries(t, s, 2)
+	require.Len(t, deliveries, 2)
+	require.True(t, deliveries[0].Success)
+	require.Equal(t, 2, deliveries[0].Attempt)
+	require.False(t, deliveries[1].Success)
+	require.Equal(t, http.StatusServiceUnavailable, deliveries[1].StatusCode)
+	require.Equal(t, deliveries[1].ID, deliveries[0].ID, "attempts share the delivery ID")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{event.ID, event.ID}, ids)
+}
+
+func TestSenderBreakerAndDeadLetter(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	s, _ := newTestSender(t, Options{
+		AllowPrivateNetworks: true,
+		MaxAttempts:          3,
+		Breaker:              resilience.BreakerConfig{FailureThreshold: 2, Cooldown: time.Hour},
+	})
+	ctx := context.Background()
+	e, err := s.Register(ctx, &Endpoint{Subscriber: "shop", URL: srv.URL})
+	require.NoError(t, err)
+	_, err = s.Send(ctx, "order.paid", nil)
+	require.NoError(t, err)
+
+	deliveries := waitForDeliveries(t, s, 3)
+	require.Contains(t, deliveries[0].Error, "circuit open")
+	require.Equal(t, resilience.BreakerOpen, s.BreakerState(e.ID))
+	mu.Lock()
+	require.Equal(t, 2, hits)
+	mu.Unlock()
+
+	var dead []*jobs.Job
+	require.Eventually(t, func() bool {
+		dead, _ = s.DeadLetters(ctx)
+		return len(dead) == 1
+	}, 2*time.Second, 5*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/dead-letters/"+dead[0].ID+"/redeliver", nil))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	waitForDeliveries(t, s, 4)
+}
+
+func TestSenderDisablesGoneEndpoint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer srv.Close()
+
+	s, _ := newTestSender(t, Options{AllowPrivateNetworks: true})
+	ctx := context.Background()
+	e, err := s.Register(ctx, &Endpoint{Subscriber: "shop", URL: srv.URL})
+	require.NoError(t, err)
+	_, err = s.Send(ctx, "order.paid", nil)
+	require.NoError(t, err)
+
+	waitForDeliveries(t, s, 1)
+	require.Eventually(t, func() bool {
+		got, _ := s.Endpoints().Get(ctx, e.ID)
+		return got.Disabled
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestSenderRejectsInternalEndpoints(t *testing.T) {
+	s, _ := newTestSender(t, Options{})
+	ctx := context.Background()
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hooks",
+		"http://localhost/hooks",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.5/hooks",
+		"http://[::1]/hooks",
+		"http://[::ffff:192.168.1.1]/hooks",
+	} {
+		_, err := s.Register(ctx, &Endpoint{Subscriber: "shop", URL: url})
+		var appErr *errors.AppError
+		require.ErrorAs(t, err, &appErr, url)
+		require.Equal(t, http.StatusBadRequest, appErr.HTTPStatus, url)
+	}
+	_, err := s.Register(ctx, &Endpoint{Subscriber: "shop", URL: "https://hooks.example.com/in"})
+	require.NoError(t, err)
+}
+
+func TestClientRefusesInternalAddressesAndRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(time.Second).Get(srv.URL)
+	require.ErrorIs(t, err, ErrBlockedAddress)
+
+	s, _ := newTestSender(t, Options{AllowPrivateNetworks: true, MaxAttempts: 1})
+	ctx := context.Background()
+	_, err = s.Register(ctx, &Endpoint{Subscriber: "shop", URL: srv.URL})
+	require.NoError(t, err)
+	_, err = s.Send(ctx, "order.paid", nil)
+	require.NoError(t, err)
+
+	deliveries := waitForDeliveries(t, s, 1)
+	require.False(t, deliveries[0].Success)
+	require.Equal(t, http.StatusFound, deliveries[0].StatusCode)
+}
+
+func TestEndpointMatches(t *testing.T) {
+	cases := []struct {
+		events []string
+		typ    string
+		want   bool
+	}{
+		{nil, "order.paid", true},
+		{[]string{"*"}, "order.paid", true},
+		{[]string{"order.*"}, "order.paid", true},
+		{[]string{"order.*"}, "orders.paid", false},
+		{[]string{"user.created", "order.paid"}, "order.paid", true},
+		{[]string{"user.created"}, "order.paid", false},
+	}
+	for _, c := range cases {
+		e := &Endpoint{Events: c.events}
+		require.Equal(t, c.want, e.Matches(c.typ), "%v matching %s", c.events, c.typ)
+	}
+}
+
+func TestHandlerEndpoints(t *testing.T) {
+	s, _ := newTestSender(t, Options{})
+	h := s.Handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/endpoints",
+		strings.NewReader(`{"subscriber":"shop","url":"https://shop.example.com/hooks","events":["order.*"]}`)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created struct {
+		Data struct {
+			ID     string `json:"id"`
+			Secret string `json:"secret"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.NotEmpty(t, created.Data.ID)
+	require.True(t, strings.HasPrefix(created.Data.Secret, "whsec_"))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/endpoints?subscriber=shop", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), created.Data.ID)
+	require.NotContains(t, rec.Body.String(), created.Data.Secret)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/endpoints", strings.NewReader(`{"url":"ftp://x"}`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `"field":"subscriber"`)
+	require.Contains(t, rec.Body.String(), `"field":"url"`)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/endpoints/"+created.Data.ID, nil))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/endpoints/"+created.Data.ID, nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/deliveries?limit=0", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
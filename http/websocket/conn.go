@@ -0,0 +1,254 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	gws "github.com/gorilla/websocket"
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/json"
+)
+
+// Conn is one client connection. Its methods are safe for concurrent use.
+type Conn struct {
+	hub    *Hub
+	ws     *gws.Conn
+	id     string
+	header http.Header
+	ctx    context.Context
+	cancel context.CancelFunc
+	send   chan []byte
+	done   chan struct{} // closed when the writer has stopped
+
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+
+	mu     sync.Mutex
+	values map[string]any
+
+	topics map[string]struct{} // guarded by hub.mu
+}
+
+func newConn(h *Hub, ws *gws.Conn, r *http.Request) *Conn {
+	// keep the handshake's values (auth, request ID) but not its lifetime
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	return &Conn{
+		hub:    h,
+		ws:     ws,
+		id:     uuid.NewString(),
+		header: r.Header.Clone(),
+		ctx:    ctx,
+		cancel: cancel,
+		send:   make(chan []byte, h.opts.SendQueue),
+		done:   make(chan struct{}),
+		values: make(map[string]any),
+		topics: make(map[string]struct{}),
+	}
+}
+
+// ID returns the connection's unique ID.
+func (c *Conn) ID() string { return c.id }
+
+// Context returns the connection context: it carries the values of the
+// handshake request and is canceled when the connection closes.
+func (c *Conn) Context() context.Context { return c.ctx }
+
+// Header returns the handshake request headers.
+func (c *Conn) Header() http.Header { return c.header }
+
+// Set stores a per-connection value.
+func (c *Conn) Set(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+}
+
+// Get returns a value stored with Set.
+func (c *Conn) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// Join subscribes the connection to topic.
+func (c *Conn) Join(topic string) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.conns[c]; !ok {
+		return
+	}
+	c.topics[topic] = struct{}{}
+	members := h.topics[topic]
+	if members == nil {
+		members = make(map[*Conn]struct{})
+		h.topics[topic] = members
+	}
+	members[c] = struct{}{}
+}
+
+// Leave unsubscribes the connection from topic.
+func (c *Conn) Leave(topic string) {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	c.hub.leave(c, topic)
+}
+
+// Topics returns the topics the connection is subscribed to, sorted.
+func (c *Conn) Topics() []string {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	topics := make([]string, 0, len(c.topics))
+	for t := range c.topics {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Send queues an envelope of msgType with data encoded as JSON.
+func (c *Conn) Send(msgType string, data any) error {
+	env, err := newEnvelope(msgType, data)
+	if err != nil {
+		return err
+	}
+	return c.SendEnvelope(env)
+}
+
+// SendEnvelope queues env. It does not wait for the write; a full send
+// queue closes the connection and returns ErrSlowConsumer.
+func (c *Conn) SendEnvelope(env *Envelope) error {
+	frame, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("websocket: encode %s: %w", env.Type, err)
+	}
+	if err := c.enqueue(frame); err != nil {
+		return err
+	}
+	c.hub.count("out", env.Type)
+	return nil
+}
+
+// Close sends a normal close frame after the queued messages.
+func (c *Conn) Close() error {
+	c.closeWith(gws.CloseNormalClosure, "")
+	return nil
+}
+
+func (c *Conn) enqueue(frame []byte) error {
+	if c.ctx.Err() != nil {
+		return ErrClosed
+	}
+	select {
+	case c.send <- frame:
+		return nil
+	case <-c.ctx.Done():
+		return ErrClosed
+	default:
+		if c.hub.opts.Metrics != nil {
+			c.hub.opts.Metrics.IncCounter("websocket_slow_consumers_total", nil)
+		}
+		c.closeWith(gws.CloseTryAgainLater, "send queue full")
+		return ErrSlowConsumer
+	}
+}
+
+func (c *Conn) sendError(id string, err error) {
+	_, body := response.FromError(err)
+	_ = c.SendEnvelope(&Envelope{Type: TypeError, ID: id, Error: &body})
+}
+
+// closeWith starts the close handshake; the first call wins.
+func (c *Conn) closeWith(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		c.cancel()
+	})
+}
+
+func (c *Conn) readLoop() error {
+	opts := c.hub.opts
+	c.ws.SetReadLimit(opts.ReadLimit)
+	c.ws.SetReadDeadline(time.Now().Add(opts.PongWait))
+	c.ws.SetPongHandler(func(string) error {
+		if c.ctx.Err() == nil {
+			c.ws.SetReadDeadline(time.Now().Add(opts.PongWait))
+		}
+		return nil
+	})
+	for {
+		_, frame, err := c.ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if c.ctx.Err() != nil {
+			// closing: wait for the peer's close frame without handling more
+			continue
+		}
+		c.ws.SetReadDeadline(time.Now().Add(opts.PongWait))
+		c.hub.dispatch(c, frame)
+	}
+}
+
+// writeLoop owns all data writes to the connection.
+func (c *Conn) writeLoop() {
+	defer close(c.done)
+	opts := c.hub.opts
+	ticker := time.NewTicker(opts.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case frame := <-c.send:
+			if err := c.write(frame); err != nil {
+				c.abort()
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(gws.PingMessage, nil, time.Now().Add(opts.WriteWait)); err != nil {
+				c.abort()
+				return
+			}
+		case <-c.ctx.Done():
+			c.flush()
+			deadline := time.Now().Add(opts.WriteWait)
+			_ = c.ws.WriteControl(gws.CloseMessage, gws.FormatCloseMessage(c.closeCode, c.closeText), deadline)
+			// give the peer until the deadline to answer the close frame
+			c.ws.SetReadDeadline(deadline)
+			return
+		}
+	}
+}
+
+// flush writes the messages queued before the connection started closing.
+func (c *Conn) flush() {
+	for {
+		select {
+		case frame := <-c.send:
+			if err := c.write(frame); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (c *Conn) write(frame []byte) error {
+	c.ws.SetWriteDeadline(time.Now().Add(c.hub.opts.WriteWait))
+	return c.ws.WriteMessage(gws.TextMessage, frame)
+}
+
+// abort drops a connection whose writes fail, unblocking the reader.
+func (c *Conn) abort() {
+	c.closeWith(gws.CloseAbnormalClosure, "")
+	c.ws.Close()
+}
@@ -0,0 +1,436 @@
+// Package websocket serves WebSocket connections behind the framework's HTTP
+// middleware. A Hub runs the configured middleware chain (typically
+// authentication) on the handshake request, keeps that request's context for
+// the lifetime of the connection, groups connections into topics and
+// exchanges JSON Envelopes encoded with the framework json package.
+package websocket
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+	framerrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/responder"
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/json"
+	"github.com/leeforge/framework/metrics"
+	"go.uber.org/zap"
+)
+
+// Built-in envelope types. Clients send subscribe/unsubscribe with a Topic
+// and receive subscribed/unsubscribed or error replies carrying the same ID.
+const (
+	TypeSubscribe    = "subscribe"
+	TypeUnsubscribe  = "unsubscribe"
+	TypeSubscribed   = "subscribed"
+	TypeUnsubscribed = "unsubscribed"
+	TypeError        = "error"
+)
+
+var (
+	// ErrClosed is returned when sending on a closed connection.
+	ErrClosed = errors.New("websocket: connection closed")
+	// ErrSlowConsumer is returned when a connection's send queue is full;
+	// the connection is closed so it cannot hold up broadcasts.
+	ErrSlowConsumer = errors.New("websocket: send queue full")
+)
+
+// Envelope is the JSON frame exchanged in both directions.
+type Envelope struct {
+	// Type selects the handler, e.g. "subscribe" or an application type.
+	Type string `json:"type"`
+	// ID is an optional client-chosen correlation ID echoed in replies.
+	ID    string             `json:"id,omitempty"`
+	Topic string             `json:"topic,omitempty"`
+	Data  stdjson.RawMessage `json:"data,omitempty"`
+	// Error is set on "error" replies, in the same shape as HTTP error
+	// responses.
+	Error *responder.Error `json:"error,omitempty"`
+}
+
+// Decode unmarshals the envelope data into v.
+func (e *Envelope) Decode(v any) error {
+	if len(e.Data) == 0 {
+		return framerrors.NewValidation("message data is required")
+	}
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return framerrors.NewValidation("malformed message data")
+	}
+	return nil
+}
+
+// HandlerFunc handles one client message. ctx is the connection context.
+// A returned error is sent back as an "error" envelope; *errors.AppError
+// messages are shown to the client, other errors as an internal error.
+type HandlerFunc func(ctx context.Context, c *Conn, msg *Envelope) error
+
+// Options configures a Hub.
+type Options struct {
+	// Middlewares run on the handshake request before the upgrade, outermost
+	// first. Values they add to the request context (user, tenant, request
+	// ID) stay available through Conn.Context.
+	Middlewares []func(http.Handler) http.Handler
+	// TokenQueryParam, when set, copies "?<param>=<token>" into a bearer
+	// Authorization header if the request has none, since browsers cannot
+	// set headers on WebSocket handshakes.
+	TokenQueryParam string
+	// CheckOrigin validates the Origin header (default: same host only).
+	CheckOrigin func(r *http.Request) bool
+	// ReadLimit is the maximum size of a client message (default 64 KiB).
+	ReadLimit int64
+	// SendQueue is the per-connection outbound buffer (default 64).
+	SendQueue int
+	// WriteWait bounds each write (default 10s).
+	WriteWait time.Duration
+	// PongWait is how long a connection may stay silent before it is
+	// dropped (default 60s); pings are sent every PingInterval (default
+	// 9/10 of PongWait).
+	PongWait     time.Duration
+	PingInterval time.Duration
+	// OnConnect runs after the upgrade; returning an error closes the
+	// connection with the error as reason. Use it to join per-user topics.
+	OnConnect func(c *Conn) error
+	// OnDisconnect runs after the connection is closed.
+	OnDisconnect func(c *Conn)
+	// Authorize decides whether c may subscribe to topic (default: allow).
+	Authorize func(c *Conn, topic string) bool
+	Metrics   *metrics.Collector
+	Logger    *zap.Logger
+}
+
+// Hub accepts WebSocket connections and routes messages between them and
+// the application.
+type Hub struct {
+	opts     Options
+	upgrader gws.Upgrader
+	handler  http.Handler
+
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+	conns    map[*Conn]struct{}
+	topics   map[string]map[*Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// New creates a Hub. Mount it as an http.Handler, e.g. r.Get("/ws", hub).
+func New(opts Options) *Hub {
+	if opts.ReadLimit <= 0 {
+		opts.ReadLimit = 64 << 10
+	}
+	if opts.SendQueue <= 0 {
+		opts.SendQueue = 64
+	}
+	if opts.WriteWait <= 0 {
+		opts.WriteWait = 10 * time.Second
+	}
+	if opts.PongWait <= 0 {
+		opts.PongWait = 60 * time.Second
+	}
+	if opts.PingInterval <= 0 || opts.PingInterval >= opts.PongWait {
+		opts.PingInterval = opts.PongWait * 9 / 10
+	}
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+
+	h := &Hub{
+		opts: opts,
+		upgrader: gws.Upgrader{
+			HandshakeTimeout: opts.WriteWait,
+			CheckOrigin:      opts.CheckOrigin,
+		},
+		handlers: make(map[string]HandlerFunc),
+		conns:    make(map[*Conn]struct{}),
+		topics:   make(map[string]map[*Conn]struct{}),
+	}
+	var handler http.Handler = http.HandlerFunc(h.serve)
+	for i := len(opts.Middlewares) - 1; i >= 0; i-- {
+		handler = opts.Middlewares[i](handler)
+	}
+	h.handler = handler
+	return h
+}
+
+// Handle registers fn for client messages of msgType. Registering a
+// built-in type replaces its default behavior.
+func (h *Hub) Handle(msgType string, fn HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[msgType] = fn
+}
+
+// ServeHTTP runs the middleware chain and upgrades the connection.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p := h.opts.TokenQueryParam; p != "" && r.Header.Get("Authorization") == "" {
+		if token := r.URL.Query().Get(p); token != "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	h.handler.ServeHTTP(w, r)
+}
+
+// Broadcast sends an envelope to every connection subscribed to topic and
+// returns the number of connections it was queued for.
+func (h *Hub) Broadcast(topic, msgType string, data any) (int, error) {
+	env, err := newEnvelope(msgType, data)
+	if err != nil {
+		return 0, err
+	}
+	env.Topic = topic
+	frame, err := json.Marshal(env)
+	if err != nil {
+		return 0, fmt.Errorf("websocket: encode %s: %w", msgType, err)
+	}
+
+	h.mu.RLock()
+	conns := make([]*Conn, 0, len(h.topics[topic]))
+	for c := range h.topics[topic] {
+		conns = append(conns, c)
+	}
+	h.mu.RUnlock()
+
+	sent := 0
+	for _, c := range conns {
+		if c.enqueue(frame) == nil {
+			sent++
+		}
+	}
+	if h.opts.Metrics != nil && sent > 0 {
+		h.opts.Metrics.AddCounter("websocket_messages_total", float64(sent), map[string]string{"direction": "out", "type": msgType})
+	}
+	return sent, nil
+}
+
+// Len returns the number of open connections.
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// Shutdown stops accepting connections, closes open ones with "going away"
+// after flushing their queued messages, and waits for them to finish. When
+// ctx expires first the remaining connections are dropped.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	conns := make([]*Conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range conns {
+		c.closeWith(gws.CloseGoingAway, "server shutting down")
+	}
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		for _, c := range conns {
+			c.ws.Close()
+		}
+		return ctx.Err()
+	}
+}
+
+func (h *Hub) serve(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		response.WriteError(w, r, framerrors.New(framerrors.ErrorTypeInternal, "server shutting down").WithHTTPStatus(http.StatusServiceUnavailable))
+		return
+	}
+	h.wg.Add(1)
+	h.mu.Unlock()
+	defer h.wg.Done()
+
+	ws, err := h.upgrader.Upgrade(hijackable(w), r, nil)
+	if err != nil {
+		// the upgrader has already written the error response
+		h.opts.Logger.Debug("websocket: upgrade failed", zap.Error(err))
+		return
+	}
+	c := newConn(h, ws, r)
+	h.register(c)
+	go c.writeLoop()
+
+	if h.opts.OnConnect != nil {
+		if err := h.opts.OnConnect(c); err != nil {
+			c.closeWith(gws.ClosePolicyViolation, closeReason(err))
+		}
+	}
+	if err := c.readLoop(); err != nil && gws.IsUnexpectedCloseError(err, gws.CloseNormalClosure, gws.CloseGoingAway, gws.CloseNoStatusReceived) {
+		h.opts.Logger.Debug("websocket: connection lost", zap.String("conn", c.id), zap.Error(err))
+	}
+	c.closeWith(gws.CloseNormalClosure, "")
+	<-c.done
+	ws.Close()
+
+	h.unregister(c)
+	if h.opts.OnDisconnect != nil {
+		h.opts.OnDisconnect(c)
+	}
+}
+
+func (h *Hub) register(c *Conn) {
+	h.mu.Lock()
+	h.conns[c] = struct{}{}
+	n := len(h.conns)
+	closed := h.closed
+	h.mu.Unlock()
+	h.gauge(n)
+	if closed {
+		// Shutdown started during the upgrade and missed this connection
+		c.closeWith(gws.CloseGoingAway, "server shutting down")
+	}
+}
+
+func (h *Hub) unregister(c *Conn) {
+	h.mu.Lock()
+	delete(h.conns, c)
+	for topic := range c.topics {
+		h.leave(c, topic)
+	}
+	n := len(h.conns)
+	h.mu.Unlock()
+	h.gauge(n)
+}
+
+// leave removes c from topic; h.mu must be held.
+func (h *Hub) leave(c *Conn, topic string) {
+	delete(c.topics, topic)
+	if members := h.topics[topic]; members != nil {
+		delete(members, c)
+		if len(members) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+}
+
+func (h *Hub) gauge(n int) {
+	if h.opts.Metrics != nil {
+		h.opts.Metrics.SetGauge("websocket_connections", float64(n), nil)
+	}
+}
+
+// dispatch decodes and handles one client frame.
+func (h *Hub) dispatch(c *Conn, frame []byte) {
+	var env Envelope
+	if err := json.Unmarshal(frame, &env); err != nil || env.Type == "" {
+		h.count("in", "malformed")
+		c.sendError("", framerrors.NewValidation("malformed message"))
+		return
+	}
+	h.mu.RLock()
+	fn, ok := h.handlers[env.Type]
+	h.mu.RUnlock()
+	if !ok {
+		switch env.Type {
+		case TypeSubscribe:
+			fn = h.subscribe
+		case TypeUnsubscribe:
+			fn = h.unsubscribe
+		default:
+			// Client-chosen types must not become label values.
+			h.count("in", "unknown")
+			c.sendError(env.ID, framerrors.NewValidation("unknown message type "+env.Type))
+			return
+		}
+	}
+	h.count("in", env.Type)
+
+	start := time.Now()
+	err := h.call(fn, c, &env)
+	if h.opts.Metrics != nil {
+		h.opts.Metrics.ObserveHistogram("websocket_message_duration_seconds", time.Since(start).Seconds(), map[string]string{"type": env.Type})
+	}
+	if err != nil {
+		c.sendError(env.ID, err)
+	}
+}
+
+func (h *Hub) call(fn HandlerFunc, c *Conn, env *Envelope) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			h.opts.Logger.Error("websocket: handler panic", zap.String("type", env.Type), zap.Any("panic", p), zap.Stack("stack"))
+			err = fmt.Errorf("websocket: handler panic: %v", p)
+		}
+	}()
+	return fn(c.ctx, c, env)
+}
+
+func (h *Hub) subscribe(_ context.Context, c *Conn, msg *Envelope) error {
+	if msg.Topic == "" {
+		return framerrors.NewRequired("topic")
+	}
+	if h.opts.Authorize != nil && !h.opts.Authorize(c, msg.Topic) {
+		return framerrors.NewForbidden("subscription to " + msg.Topic + " is not allowed")
+	}
+	c.Join(msg.Topic)
+	return c.SendEnvelope(&Envelope{Type: TypeSubscribed, ID: msg.ID, Topic: msg.Topic})
+}
+
+func (h *Hub) unsubscribe(_ context.Context, c *Conn, msg *Envelope) error {
+	if msg.Topic == "" {
+		return framerrors.NewRequired("topic")
+	}
+	c.Leave(msg.Topic)
+	return c.SendEnvelope(&Envelope{Type: TypeUnsubscribed, ID: msg.ID, Topic: msg.Topic})
+}
+
+func (h *Hub) count(direction, msgType string) {
+	if h.opts.Metrics != nil {
+		h.opts.Metrics.IncCounter("websocket_messages_total", map[string]string{"direction": direction, "type": msgType})
+	}
+}
+
+func newEnvelope(msgType string, data any) (*Envelope, error) {
+	env := &Envelope{Type: msgType}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("websocket: encode %s: %w", msgType, err)
+		}
+		env.Data = raw
+	}
+	return env, nil
+}
+
+// hijackable unwraps middleware response writers until one supports
+// hijacking, which the upgrade requires.
+func hijackable(w http.ResponseWriter) http.ResponseWriter {
+	for {
+		if _, ok := w.(http.Hijacker); ok {
+			return w
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return w
+		}
+		w = u.Unwrap()
+	}
+}
+
+// closeReason fits err into a close frame, whose payload is limited to
+// 123 bytes.
+func closeReason(err error) string {
+	reason := err.Error()
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	return reason
+}
@@ -0,0 +1,169 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/leeforge/framework/http/responder"
+	"github.com/leeforge/framework/json"
+	"github.com/leeforge/framework/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+type userKey struct{}
+
+// fakeAuth 模拟认证中间件：校验 Bearer token 并把用户写入请求上下文
+func fakeAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || user == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+func dial(t *testing.T, srv *httptest.Server, query string) *gws.Conn {
+	t.Helper()
+	conn, resp, err := gws.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+query, nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readEnvelope(t *testing.T, conn *gws.Conn) Envelope {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, frame, err := conn.ReadMessage()
+	require.NoError(t, err)
+	var env Envelope
+	require.NoError(t, json.Unmarshal(frame, &env))
+	return env
+}
+
+func writeEnvelope(t *testing.T, conn *gws.Conn, env Envelope) {
+	t.Helper()
+	frame, err := json.Marshal(&env)
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(gws.TextMessage, frame))
+}
+
+func TestHubAuthContextAndTopics(t *testing.T) {
+	collector := metrics.NewCollector()
+	hub := New(Options{
+		Middlewares:     []func(http.Handler) http.Handler{fakeAuth},
+		TokenQueryParam: "access_token",
+		Metrics:         collector,
+		OnConnect: func(c *Conn) error {
+			c.Join("user:" + c.Context().Value(userKey{}).(string))
+			return nil
+		},
+		Authorize: func(_ *Conn, topic string) bool { return !strings.HasPrefix(topic, "admin") },
+	})
+	hub.Handle("whoami", func(ctx context.Context, c *Conn, msg *Envelope) error {
+		var req struct {
+			Greeting string `json:"greeting"`
+		}
+		if err := msg.Decode(&req); err != nil {
+			return err
+		}
+		return c.SendEnvelope(&Envelope{Type: "whoami", ID: msg.ID, Data: []byte(`"` + req.Greeting + " " + ctx.Value(userKey{}).(string) + `"`)})
+	})
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	// 未认证的握手由中间件拒绝
+	_, resp, err := gws.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	conn := dial(t, srv, "?access_token=alice")
+
+	writeEnvelope(t, conn, Envelope{Type: "whoami", ID: "1", Data: []byte(`{"greeting":"hi"}`)})
+	env := readEnvelope(t, conn)
+	require.Equal(t, "1", env.ID)
+	require.JSONEq(t, `"hi alice"`, string(env.Data))
+
+	writeEnvelope(t, conn, Envelope{Type: TypeSubscribe, ID: "2", Topic: "news"})
+	require.Equal(t, Envelope{Type: TypeSubscribed, ID: "2", Topic: "news"}, readEnvelope(t, conn))
+
+	writeEnvelope(t, conn, Envelope{Type: TypeSubscribe, ID: "3", Topic: "admin.audit"})
+	env = readEnvelope(t, conn)
+	require.Equal(t, TypeError, env.Type)
+	require.Equal(t, "3", env.ID)
+	require.Equal(t, responder.ErrCodeForbidden, env.Error.Code)
+
+	writeEnvelope(t, conn, Envelope{Type: "nope", ID: "4"})
+	require.Equal(t, TypeError, readEnvelope(t, conn).Type)
+
+	n, err := hub.Broadcast("news", "headline", map[string]string{"title": "hello"})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	env = readEnvelope(t, conn)
+	require.Equal(t, "headline", env.Type)
+	require.Equal(t, "news", env.Topic)
+	require.JSONEq(t, `{"title":"hello"}`, string(env.Data))
+
+	// OnConnect 加入的按用户主题
+	n, _ = hub.Broadcast("user:alice", "notice", nil)
+	require.Equal(t, 1, n)
+	require.Equal(t, "notice", readEnvelope(t, conn).Type)
+
+	in := 0.0
+	for _, m := range collector.Export() {
+		if m.Name == "websocket_messages_total" && m.Labels["direction"] == "in" {
+			require.NotEqual(t, "nope", m.Labels["type"], "unregistered types are counted as unknown")
+			in += m.Value
+		}
+	}
+	require.Equal(t, 4.0, in)
+	require.Equal(t, 1.0, collector.GetMetric("websocket_messages_total", map[string]string{"direction": "in", "type": "unknown"}).Value)
+	require.Equal(t, 1, hub.Len())
+}
+
+func TestHubShutdownFlushesAndCloses(t *testing.T) {
+	var server *Conn
+	connected := make(chan struct{})
+	disconnected := make(chan string, 1)
+	hub := New(Options{
+		OnConnect: func(c *Conn) error {
+			server = c
+			close(connected)
+			return nil
+		},
+		OnDisconnect: func(c *Conn) { disconnected <- c.ID() },
+	})
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	conn := dial(t, srv, "")
+	<-connected
+	require.NoError(t, server.Send("bye", "see you"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- hub.Shutdown(ctx) }()
+
+	// 关闭前先投递已排队的消息
+	require.Equal(t, "bye", readEnvelope(t, conn).Type)
+	_, _, err := conn.ReadMessage()
+	require.True(t, gws.IsCloseError(err, gws.CloseGoingAway), "err = %v", err)
+
+	require.NoError(t, <-shutdown)
+	require.Equal(t, server.ID(), <-disconnected)
+	require.Equal(t, 0, hub.Len())
+	require.ErrorIs(t, server.Send("late", nil), ErrClosed)
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
@@ -0,0 +1,426 @@
+// Package httpclient is the standard way to call other services. Its
+// Transport propagates the request context headers (request, trace,
+// correlation, user and tenant IDs), wraps each call in a client span,
+// records per-host metrics, retries idempotent requests and breaks the
+// circuit to failing hosts.
+package httpclient
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/request"
+	"github.com/leeforge/framework/resilience"
+	"github.com/leeforge/framework/retry"
+	"github.com/leeforge/framework/tracing"
+)
+
+// Metric names, labelled by host, method, route and status ("error" for
+// transport failures, "rejected" when the circuit is open).
+const (
+	MetricRequests        = "http_client_requests_total"
+	MetricRequestDuration = "http_client_request_duration_seconds"
+)
+
+// StatusError reports a response whose status is listed in
+// Options.RetryStatuses. Retry classifiers receive it for those responses;
+// RoundTrip itself returns the final response, not this error.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("httpclient: server responded %d", e.StatusCode)
+}
+
+// Options configures a Transport.
+type Options struct {
+	// Base performs the requests (default http.DefaultTransport).
+	Base http.RoundTripper
+	// Timeout bounds a whole call including retries; New sets it as the
+	// client timeout. Zero means no limit.
+	Timeout time.Duration
+	// AttemptTimeout bounds each attempt. Zero means no limit.
+	AttemptTimeout time.Duration
+	// Retry enables retries of idempotent requests (GET, HEAD, OPTIONS,
+	// PUT, DELETE, or any request carrying an Idempotency-Key header) whose
+	// body can be replayed. Nil disables retries.
+	Retry *retry.Policy
+	// RetryStatuses are the response codes worth retrying (default 429,
+	// 502, 503, 504). A Retry-After header extends the wait.
+	RetryStatuses []int
+	// Breaker enables a circuit breaker per host, built from this template.
+	// Transport errors and 5xx responses count as failures.
+	Breaker *resilience.BreakerConfig
+	// Tracer, when set, wraps each call in a client span.
+	Tracer *tracing.Tracer
+	// Metrics, when set, receives a counter and a duration histogram per
+	// attempt.
+	Metrics *metrics.Collector
+	// Route returns the route label for metrics and spans when the request
+	// has none set with WithRoute. Defaults to "unknown" so raw paths never
+	// become labels.
+	Route func(req *http.Request) string
+	// DisablePropagation stops copying request context headers.
+	DisablePropagation bool
+	// Signer, when set, signs every attempt after context headers are
+	// added, so retries carry a fresh timestamp and nonce. See
+	// security.RequestSigner.
+	Signer Signer
+}
+
+// Signer signs an outgoing request in place.
+type Signer interface {
+	Sign(req *http.Request) error
+}
+
+// Transport is an http.RoundTripper applying Options to every request.
+type Transport struct {
+	opts      Options
+	retryable map[int]bool
+
+	mu       sync.Mutex
+	breakers map[string]*resilience.Breaker
+}
+
+// NewTransport creates a Transport.
+func NewTransport(opts Options) *Transport {
+	if opts.Base == nil {
+		opts.Base = http.DefaultTransport
+	}
+	if opts.RetryStatuses == nil {
+		opts.RetryStatuses = []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+	if opts.Route == nil {
+		opts.Route = func(*http.Request) string { return "unknown" }
+	}
+	t := &Transport{opts: opts, retryable: make(map[int]bool), breakers: make(map[string]*resilience.Breaker)}
+	for _, code := range opts.RetryStatuses {
+		t.retryable[code] = true
+	}
+	return t
+}
+
+// New returns an *http.Client using a Transport built from opts.
+func New(opts Options) *http.Client {
+	return &http.Client{Transport: NewTransport(opts), Timeout: opts.Timeout}
+}
+
+// BreakerState returns the circuit state for host. It is always closed
+// when Options.Breaker is nil.
+func (t *Transport) BreakerState(host string) resilience.BreakerState {
+	if b := t.breaker(host); b != nil {
+		return b.State()
+	}
+	return resilience.BreakerClosed
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ro := optionsFrom(req.Context())
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if ro.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, ro.timeout)
+	}
+	route := ro.route
+	if route == "" {
+		route = t.opts.Route(req)
+	}
+
+	var span *tracing.Span
+	if t.opts.Tracer != nil {
+		startOpts := []tracing.SpanStartOption{
+			tracing.WithSpanKind(tracing.SpanKindClient),
+			tracing.WithAttributes(map[string]interface{}{
+				"http.method": req.Method,
+				"http.url":    req.URL.Redacted(),
+				"http.host":   req.URL.Host,
+				"http.route":  route,
+			}),
+		}
+		ctx, span = t.opts.Tracer.Start(ctx, "HTTP "+req.Method+" "+route, startOpts...)
+	}
+
+	policy := retry.Policy{MaxAttempts: 1}
+	if t.opts.Retry != nil && !ro.noRetry && canRetry(req) {
+		policy = *t.opts.Retry
+		if span != nil {
+			onRetry := policy.OnRetry
+			policy.OnRetry = func(attempt int, err error, wait time.Duration) {
+				t.opts.Tracer.AddEvent(span, "http.retry", map[string]interface{}{
+					"attempt": attempt,
+					"error":   err.Error(),
+					"wait_ms": wait.Milliseconds(),
+				})
+				if onRetry != nil {
+					onRetry(attempt, err, wait)
+				}
+			}
+		}
+	}
+
+	attempts := 0
+	var resp *http.Response
+	err := policy.Do(ctx, func(ctx context.Context) error {
+		if resp != nil {
+			discard(resp)
+			resp = nil
+		}
+		attempts++
+		var err error
+		resp, err = t.attempt(ctx, req, attempts, route, span)
+		return err
+	})
+
+	var statusErr *StatusError
+	if stderrors.As(err, &statusErr) && resp != nil && ctx.Err() == nil {
+		// Out of attempts on a retryable status: hand back the response.
+		err = nil
+	}
+	if err != nil && resp != nil {
+		discard(resp)
+		resp = nil
+	}
+
+	if span != nil {
+		attrs := map[string]interface{}{"http.attempts": attempts}
+		spanErr := err
+		if resp != nil {
+			attrs["http.status_code"] = resp.StatusCode
+			if resp.StatusCode >= 500 {
+				spanErr = &StatusError{StatusCode: resp.StatusCode}
+			}
+		}
+		t.opts.Tracer.SetAttributes(span, attrs)
+		t.opts.Tracer.End(span, spanErr)
+	}
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// attempt sends one try of req. It returns a StatusError together with the
+// response for retryable statuses.
+func (t *Transport) attempt(ctx context.Context, req *http.Request, n int, route string, span *tracing.Span) (*http.Response, error) {
+	parent, cancel := ctx, context.CancelFunc(func() {})
+	if t.opts.AttemptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, t.opts.AttemptTimeout)
+	}
+	out := req.Clone(ctx)
+	if n > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, retry.Permanent(err)
+		}
+		out.Body = body
+	}
+	if !t.opts.DisablePropagation {
+		propagate(ctx, out, span)
+	}
+	if t.opts.Signer != nil {
+		if err := t.opts.Signer.Sign(out); err != nil {
+			cancel()
+			return nil, retry.Permanent(err)
+		}
+	}
+
+	labels := map[string]string{"host": req.URL.Host, "method": req.Method, "route": route}
+	var done func(error)
+	if b := t.breaker(req.URL.Host); b != nil {
+		var err error
+		if done, err = b.Allow(); err != nil {
+			cancel()
+			labels["status"] = "rejected"
+			t.count(labels)
+			return nil, retry.Permanent(fmt.Errorf("httpclient: %s: %w", req.URL.Host, err))
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.opts.Base.RoundTrip(out)
+	elapsed := time.Since(start)
+
+	switch {
+	case err != nil:
+		labels["status"] = "error"
+	default:
+		labels["status"] = strconv.Itoa(resp.StatusCode)
+	}
+	if done != nil {
+		if err == nil && resp.StatusCode >= 500 {
+			done(&StatusError{StatusCode: resp.StatusCode})
+		} else {
+			done(err)
+		}
+	}
+	t.count(labels)
+	if t.opts.Metrics != nil {
+		t.opts.Metrics.ObserveHistogram(MetricRequestDuration, elapsed.Seconds(), labels)
+	}
+
+	if err != nil {
+		cancel()
+		if ctx.Err() != nil && parent.Err() == nil {
+			// Only this attempt timed out; the call may still be retried.
+			return nil, fmt.Errorf("httpclient: attempt timed out after %s", t.opts.AttemptTimeout)
+		}
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	if t.retryable[resp.StatusCode] {
+		var retryErr error = &StatusError{StatusCode: resp.StatusCode}
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			retryErr = retry.After(retryErr, wait)
+		}
+		return resp, retryErr
+	}
+	return resp, nil
+}
+
+func (t *Transport) count(labels map[string]string) {
+	if t.opts.Metrics != nil {
+		t.opts.Metrics.IncCounter(MetricRequests, labels)
+	}
+}
+
+func (t *Transport) breaker(host string) *resilience.Breaker {
+	if t.opts.Breaker == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.breakers[host]
+	if !ok {
+		config := *t.opts.Breaker
+		config.Name = "http:" + host
+		if config.Metrics == nil {
+			config.Metrics = t.opts.Metrics
+		}
+		b = resilience.NewBreaker(config)
+		t.breakers[host] = b
+	}
+	return b
+}
+
+// propagate copies the request context headers that the caller has not set
+// and points X-Span-ID at the client span.
+func propagate(ctx context.Context, out *http.Request, span *tracing.Span) {
+	for key, values := range request.FromContext(ctx).ToHeaders() {
+		if out.Header.Get(key) == "" {
+			out.Header[key] = values
+		}
+	}
+	if span != nil {
+		if out.Header.Get("X-Trace-ID") == "" {
+			out.Header.Set("X-Trace-ID", span.TraceID)
+		}
+		out.Header.Set("X-Span-ID", span.SpanID)
+	}
+}
+
+// canRetry reports whether req may be sent more than once.
+func canRetry(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// retryAfter parses a Retry-After header in seconds or as an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
+
+// cancelBody releases a request's timeout context once the body is closed,
+// so the deadline still covers reading the body.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// RequestOption customizes a single call.
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	route   string
+	timeout time.Duration
+	noRetry bool
+}
+
+type optionsKey struct{}
+
+// WithRoute sets the route label, e.g. "/users/{id}", for metrics and spans.
+func WithRoute(route string) RequestOption {
+	return func(o *requestOptions) { o.route = route }
+}
+
+// WithTimeout bounds the call, including retries and reading the body.
+func WithTimeout(d time.Duration) RequestOption {
+	return func(o *requestOptions) { o.timeout = d }
+}
+
+// WithoutRetry sends the request once even when retries are enabled.
+func WithoutRetry() RequestOption {
+	return func(o *requestOptions) { o.noRetry = true }
+}
+
+// NewContext returns ctx carrying per-call options; pass it to
+// http.NewRequestWithContext.
+//
+//	ctx = httpclient.NewContext(ctx, httpclient.WithRoute("/users/{id}"), httpclient.WithTimeout(2*time.Second))
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, base+"/users/"+id, nil)
+func NewContext(ctx context.Context, opts ...RequestOption) context.Context {
+	o := optionsFrom(ctx)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return context.WithValue(ctx, optionsKey{}, o)
+}
+
+func optionsFrom(ctx context.Context) requestOptions {
+	o, _ := ctx.Value(optionsKey{}).(requestOptions)
+	return o
+}
@@ -0,0 +1,228 @@
+package httpclient
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/request"
+	"github.com/leeforge/framework/resilience"
+	"github.com/leeforge/framework/retry"
+	"github.com/leeforge/framework/tracing"
+)
+
+type recordingProcessor struct {
+	mu    sync.Mutex
+	spans []*tracing.Span
+}
+
+func (p *recordingProcessor) OnEnd(s *tracing.Span) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.spans = append(p.spans, s)
+}
+
+func (p *recordingProcessor) Shutdown(context.Context) error { return nil }
+
+// counterValue sums the series of name whose labels equal labels.
+func counterValue(c *metrics.Collector, name string, labels map[string]string) float64 {
+	var total float64
+	for key, m := range c.GetMetrics() {
+		if !strings.HasPrefix(key, name+":") || len(m.Labels) != len(labels) {
+			continue
+		}
+		match := true
+		for k, v := range labels {
+			match = match && m.Labels[k] == v
+		}
+		if match {
+			total += m.Value
+		}
+	}
+	return total
+}
+
+func fastRetry() *retry.Policy {
+	return &retry.Policy{MaxAttempts: 3, InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond}
+}
+
+func TestPropagatesHeadersAndTraces(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	processor := &recordingProcessor{}
+	tracer, err := tracing.NewTracer(tracing.TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: processor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(Options{Tracer: tracer})
+
+	rc := &request.RequestContext{RequestID: "req-1", CorrelationID: "corr-1", TenantID: "tenant-1"}
+	ctx := NewContext(rc.ToContext(), WithRoute("/users/{id}"))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/users/42", nil)
+	req.Header.Set("X-Correlation-ID", "caller-set")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got.Get("X-Request-ID") != "req-1" || got.Get("X-Tenant-ID") != "tenant-1" {
+		t.Errorf("context headers not propagated: %v", got)
+	}
+	if got.Get("X-Correlation-ID") != "caller-set" {
+		t.Errorf("caller header overwritten: %q", got.Get("X-Correlation-ID"))
+	}
+	if len(processor.spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(processor.spans))
+	}
+	span := processor.spans[0]
+	if span.Kind != tracing.SpanKindClient || span.Name != "HTTP GET /users/{id}" {
+		t.Errorf("span = %s kind %d", span.Name, span.Kind)
+	}
+	if got.Get("X-Span-ID") != span.SpanID {
+		t.Errorf("X-Span-ID = %q, want client span %q", got.Get("X-Span-ID"), span.SpanID)
+	}
+	if span.Attributes["http.status_code"] != http.StatusOK {
+		t.Errorf("status attribute = %v", span.Attributes["http.status_code"])
+	}
+}
+
+func TestRetriesIdempotentRequests(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "" && string(body) != "payload" {
+			t.Errorf("body = %q on attempt %d", body, hits.Load()+1)
+		}
+		if hits.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	collector := metrics.NewCollector()
+	client := New(Options{Retry: fastRetry(), Metrics: collector})
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" || hits.Load() != 3 {
+		t.Fatalf("status %d body %q after %d hits", resp.StatusCode, body, hits.Load())
+	}
+	host := strings.TrimPrefix(srv.URL, "http://")
+	labels := map[string]string{"host": host, "method": "GET", "route": "unknown", "status": "503"}
+	if v := counterValue(collector, MetricRequests, labels); v != 2 {
+		t.Errorf("503 counter = %v, want 2", v)
+	}
+
+	// POST is retried only with an idempotency key, replaying the body.
+	hits.Store(0)
+	resp, err = client.Post(srv.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || hits.Load() != 1 {
+		t.Fatalf("plain POST: status %d after %d hits", resp.StatusCode, hits.Load())
+	}
+
+	hits.Store(0)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+	req.Header.Set("Idempotency-Key", "k1")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || hits.Load() != 3 {
+		t.Fatalf("keyed POST: status %d after %d hits", resp.StatusCode, hits.Load())
+	}
+}
+
+func TestCircuitBreakerPerHost(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	transport := NewTransport(Options{Breaker: &resilience.BreakerConfig{FailureThreshold: 2, Cooldown: time.Hour}})
+	client := &http.Client{Transport: transport}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	_, err := client.Get(srv.URL)
+	if !stderrors.Is(err, resilience.ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("hits = %d, want 2", hits.Load())
+	}
+	u, _ := url.Parse(srv.URL)
+	if transport.BreakerState(u.Host) != resilience.BreakerOpen {
+		t.Error("breaker should be open")
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte("fast"))
+	}))
+	defer srv.Close()
+
+	// A slow first attempt is cut off and retried.
+	client := New(Options{Retry: fastRetry(), AttemptTimeout: 50 * time.Millisecond})
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "fast" || hits.Load() != 2 {
+		t.Fatalf("body %q after %d hits", body, hits.Load())
+	}
+
+	// A per-call timeout bounds the whole call.
+	hits.Store(0)
+	ctx := NewContext(context.Background(), WithTimeout(30*time.Millisecond), WithoutRetry())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	start := time.Now()
+	if _, err := New(Options{Retry: fastRetry()}).Do(req); !stderrors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if time.Since(start) > 500*time.Millisecond || hits.Load() != 1 {
+		t.Errorf("took %v with %d hits", time.Since(start), hits.Load())
+	}
+}
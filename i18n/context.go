@@ -0,0 +1,72 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/leeforge/framework/errors"
+)
+
+type localeKey struct{}
+
+// WithLocale returns a copy of ctx carrying lang.
+func WithLocale(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, localeKey{}, Normalize(lang))
+}
+
+// Locale returns the language stored by WithLocale or Bundle.Middleware,
+// or "" when there is none; translating for "" uses the default language.
+func Locale(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	lang, _ := ctx.Value(localeKey{}).(string)
+	return lang
+}
+
+// T translates key into the language of ctx with the default bundle, see
+// Bundle.Translate. Its signature matches render.Options.Translate.
+func T(ctx context.Context, key string, args ...any) string {
+	return Default().T(ctx, key, args...)
+}
+
+// Match returns the supported language best matching an Accept-Language
+// header value, or the default language. Each requested tag, by quality,
+// is tried with its parent tags and then against the supported languages
+// sharing its base language, so "zh" matches a "zh-CN" catalog.
+func (b *Bundle) Match(acceptLanguage string) string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, tag := range errors.ParseAcceptLanguage(acceptLanguage) {
+		tag = strings.ToLower(Normalize(tag))
+		for candidate := tag; candidate != ""; candidate = parentTag(candidate) {
+			if _, ok := b.catalogs[candidate]; ok {
+				return b.tags[candidate]
+			}
+		}
+		base, _, _ := strings.Cut(tag, "-")
+		best := ""
+		for supported := range b.catalogs {
+			if strings.HasPrefix(supported, base+"-") && (best == "" || supported < best) {
+				best = supported
+			}
+		}
+		if best != "" {
+			return b.tags[best]
+		}
+	}
+	return b.defaultLang
+}
+
+// Middleware stores the language negotiated from the Accept-Language header
+// in the request context, for T and Locale, and announces it with
+// Content-Language.
+func (b *Bundle) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := b.Match(r.Header.Get("Accept-Language"))
+		w.Header().Add("Vary", "Accept-Language")
+		w.Header().Set("Content-Language", lang)
+		next.ServeHTTP(w, r.WithContext(WithLocale(r.Context(), lang)))
+	})
+}
@@ -0,0 +1,63 @@
+package i18n
+
+import (
+	"context"
+	"strings"
+
+	"github.com/leeforge/framework/errors"
+)
+
+// Catalog key prefixes read by RegisterErrors and TranslateRule.
+const (
+	ErrorsPrefix     = "errors."
+	ValidationPrefix = "validation."
+)
+
+// RegisterErrors adds the messages under "errors." of every catalog to
+// registry as translations, keyed by the rest of the key: an error code
+// such as NOT_FOUND or an errors.ErrorType such as not_found. Messages use
+// the same {name} placeholders, filled from the error's details; only the
+// "other" form of plural messages is used. Errors mapped through registry,
+// e.g. by httpx.WriteError or ErrorConverter.WriteProblem, are then
+// returned in the language of the request's Accept-Language.
+//
+//	# zh-CN.yaml
+//	errors:
+//	  NOT_FOUND: "{resource} 不存在"
+//	  QUOTA_EXCEEDED: "{resource} 配额 {limit} 已用完"
+//
+//	bundle.RegisterErrors(errors.DefaultErrorRegistry())
+//
+// The registry is not safe for concurrent updates; call RegisterErrors at
+// startup.
+func (b *Bundle) RegisterErrors(registry *errors.ErrorRegistry) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for tag, catalog := range b.catalogs {
+		for key, m := range catalog {
+			if code, ok := strings.CutPrefix(key, ErrorsPrefix); ok {
+				registry.AddTranslation(code, b.tags[tag], m["other"])
+			}
+		}
+	}
+}
+
+// TranslateRule translates the message of a failed validation rule from
+// the "validation.<rule>" key in the language of ctx, with {field} and
+// {param} filled; it reports false when no catalog has the key. It is a
+// binding.MessageTranslator:
+//
+//	# zh-CN.yaml
+//	validation:
+//	  required: 不能为空
+//	  min: "长度不能少于 {param} 个字符"
+//
+//	binding.SetMessageTranslator(bundle.TranslateRule)
+func (b *Bundle) TranslateRule(ctx context.Context, rule, field, param string) (string, bool) {
+	key := ValidationPrefix + rule
+	lang := Locale(ctx)
+	if !b.Has(lang, key) {
+		return "", false
+	}
+	return b.Translate(lang, key, Params{"field": field, "param": param}), true
+}
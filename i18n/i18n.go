@@ -0,0 +1,416 @@
+// Package i18n translates messages from per-language catalogs. Catalogs are
+// JSON or YAML files of nested keys; a message may have plural forms picked
+// by the language's plural rules. The request's language is negotiated from
+// Accept-Language by Bundle.Middleware and carried in the context, so code
+// deep in a request translates with T(ctx, key, args...):
+//
+//	bundle := i18n.NewBundle("en")
+//	if err := bundle.LoadFS(os.DirFS("locales")); err != nil { // en.yaml, zh-CN.json, ...
+//		return err
+//	}
+//	i18n.SetDefault(bundle)
+//	router.Use(bundle.Middleware)
+//
+//	i18n.T(ctx, "cart.items", i18n.Params{"count": n}) // "3 items" / "3 件商品"
+//
+// Bundle.RegisterErrors and Bundle.TranslateRule feed the catalogs to
+// errors.ErrorRegistry and binding, so API errors and validation messages
+// are returned in the client's language.
+package i18n
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Params are the named values of a message's {name} placeholders. The
+// "count" param also selects the plural form.
+type Params map[string]any
+
+// message is a translation: a single "other" form, or plural forms keyed by
+// category.
+type message map[string]string
+
+// Bundle holds the catalogs of every language. It is safe for concurrent
+// use; add messages at startup.
+type Bundle struct {
+	defaultLang string
+
+	mu       sync.RWMutex
+	catalogs map[string]map[string]message // lowercase tag -> key -> message
+	tags     map[string]string             // lowercase tag -> canonical tag
+	rules    map[string]PluralRule         // lowercase tag -> rule
+}
+
+// NewBundle returns an empty Bundle falling back to defaultLang for
+// requests in unsupported languages and keys missing from a catalog.
+func NewBundle(defaultLang string) *Bundle {
+	b := &Bundle{
+		defaultLang: Normalize(defaultLang),
+		catalogs:    make(map[string]map[string]message),
+		tags:        make(map[string]string),
+		rules:       make(map[string]PluralRule),
+	}
+	return b
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultBundle = NewBundle("en")
+)
+
+// Default returns the bundle used by the package-level T.
+func Default() *Bundle {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultBundle
+}
+
+// SetDefault replaces the bundle used by the package-level T.
+func SetDefault(b *Bundle) {
+	defaultMu.Lock()
+	defaultBundle = b
+	defaultMu.Unlock()
+}
+
+// DefaultLanguage returns the fallback language.
+func (b *Bundle) DefaultLanguage() string {
+	return b.defaultLang
+}
+
+// Languages returns the languages with a catalog, sorted.
+func (b *Bundle) Languages() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	langs := make([]string, 0, len(b.catalogs))
+	for tag := range b.catalogs {
+		langs = append(langs, b.tags[tag])
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// AddMessages adds messages for lang, replacing existing keys. Nested maps
+// are flattened into dotted keys; a map whose keys are all plural
+// categories (zero, one, two, few, many, other) and include "other" is a
+// message with plural forms:
+//
+//	bundle.AddMessages("en", map[string]any{
+//		"cart": map[string]any{
+//			"title": "Your cart",
+//			"items": map[string]any{"one": "{count} item", "other": "{count} items"},
+//		},
+//	})
+func (b *Bundle) AddMessages(lang string, messages map[string]any) error {
+	flat := make(map[string]message)
+	if err := flatten(flat, "", messages); err != nil {
+		return fmt.Errorf("i18n: %s: %w", lang, err)
+	}
+	tag := Normalize(lang)
+	key := strings.ToLower(tag)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	catalog, ok := b.catalogs[key]
+	if !ok {
+		catalog = make(map[string]message, len(flat))
+		b.catalogs[key] = catalog
+		b.tags[key] = tag
+	}
+	for k, m := range flat {
+		catalog[k] = m
+	}
+	return nil
+}
+
+// AddJSON adds the messages of a JSON catalog for lang.
+func (b *Bundle) AddJSON(lang string, data []byte) error {
+	var messages map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&messages); err != nil {
+		return fmt.Errorf("i18n: %s: parse JSON: %w", lang, err)
+	}
+	return b.AddMessages(lang, messages)
+}
+
+// AddYAML adds the messages of a YAML catalog for lang.
+func (b *Bundle) AddYAML(lang string, data []byte) error {
+	var messages map[string]any
+	if err := yaml.Unmarshal(data, &messages); err != nil {
+		return fmt.Errorf("i18n: %s: parse YAML: %w", lang, err)
+	}
+	return b.AddMessages(lang, messages)
+}
+
+// LoadFS adds every .json, .yaml and .yml catalog in fsys. A file at the
+// root is named after its language (en.json, zh-CN.yaml); files in a
+// directory belong to the directory's language, so a catalog can be split:
+//
+//	locales/en.yaml
+//	locales/zh-CN/errors.yaml
+//	locales/zh-CN/validation.json
+func (b *Bundle) LoadFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := path.Ext(p)
+		if ext != ".json" && ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		lang, _, nested := strings.Cut(p, "/")
+		if !nested {
+			lang = strings.TrimSuffix(p, ext)
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("i18n: read %s: %w", p, err)
+		}
+		if ext == ".json" {
+			err = b.AddJSON(lang, data)
+		} else {
+			err = b.AddYAML(lang, data)
+		}
+		if err != nil {
+			return fmt.Errorf("%w (%s)", err, p)
+		}
+		return nil
+	})
+}
+
+// T translates key into the language of ctx, see Translate.
+func (b *Bundle) T(ctx context.Context, key string, args ...any) string {
+	return b.Translate(Locale(ctx), key, args...)
+}
+
+// Translate translates key into lang, falling back to its parent tags
+// (zh-Hant-TW, zh-Hant, zh) and then to the default language; a key found
+// nowhere is returned as is.
+//
+// args fill the message's placeholders: Params fill {name}, other values
+// fill {0}, {1}, ... in order. The plural form is chosen by the "count"
+// param, or else by the first numeric argument; "zero" is used for a count
+// of 0 when the message has it, in any language.
+func (b *Bundle) Translate(lang, key string, args ...any) string {
+	m, tag, ok := b.lookup(lang, key)
+	if !ok {
+		return key
+	}
+	params, count, counted := collect(args)
+	template := m["other"]
+	if counted && len(m) > 1 {
+		template = m.form(b.pluralRule(tag)(count), count)
+	}
+	return format(template, params)
+}
+
+// Has reports whether key translates into lang or the default language.
+func (b *Bundle) Has(lang, key string) bool {
+	_, _, ok := b.lookup(lang, key)
+	return ok
+}
+
+// lookup finds key for lang and returns the catalog tag it was found in.
+func (b *Bundle) lookup(lang, key string) (message, string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, tag := range []string{strings.ToLower(Normalize(lang)), strings.ToLower(b.defaultLang)} {
+		for candidate := tag; candidate != ""; candidate = parentTag(candidate) {
+			if m, ok := b.catalogs[candidate][key]; ok {
+				return m, candidate, true
+			}
+		}
+	}
+	return nil, "", false
+}
+
+// form returns the template for category, falling back to "other".
+func (m message) form(category string, count float64) string {
+	if count == 0 {
+		if t, ok := m["zero"]; ok {
+			return t
+		}
+	}
+	if t, ok := m[category]; ok {
+		return t
+	}
+	return m["other"]
+}
+
+var pluralCategories = map[string]bool{"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true}
+
+// flatten adds the messages of src to dst under prefix.
+func flatten(dst map[string]message, prefix string, src map[string]any) error {
+	for k, v := range src {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[any]any); ok {
+			converted := make(map[string]any, len(nested))
+			for nk, nv := range nested {
+				converted[fmt.Sprint(nk)] = nv
+			}
+			v = converted
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			if forms, ok := pluralForms(v); ok {
+				dst[key] = forms
+				continue
+			}
+			if err := flatten(dst, key, v); err != nil {
+				return err
+			}
+		case string:
+			dst[key] = message{"other": v}
+		case json.Number, int, int64, uint64, float64, bool:
+			dst[key] = message{"other": fmt.Sprint(v)}
+		default:
+			return fmt.Errorf("key %s: unsupported value %T", key, v)
+		}
+	}
+	return nil
+}
+
+// pluralForms returns v as a plural message when it is one.
+func pluralForms(v map[string]any) (message, bool) {
+	if _, ok := v["other"]; !ok {
+		return nil, false
+	}
+	m := make(message, len(v))
+	for category, form := range v {
+		s, ok := form.(string)
+		if !ok || !pluralCategories[category] {
+			return nil, false
+		}
+		m[category] = s
+	}
+	return m, true
+}
+
+// collect merges args into params, numbering positional values, and
+// returns the plural count.
+func collect(args []any) (Params, float64, bool) {
+	if len(args) == 0 {
+		return nil, 0, false
+	}
+	params := make(Params, len(args))
+	var count float64
+	counted := false
+	positional := 0
+	for _, arg := range args {
+		if p, ok := arg.(Params); ok {
+			for k, v := range p {
+				params[k] = v
+			}
+			continue
+		}
+		if n, ok := number(arg); ok && !counted {
+			count, counted = n, true
+		}
+		params[strconv.Itoa(positional)] = arg
+		positional++
+	}
+	if n, ok := number(params["count"]); ok {
+		count, counted = n, true
+	}
+	return params, count, counted
+}
+
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// format replaces {name} placeholders with values from params; unknown
+// placeholders are kept as is.
+func format(template string, params Params) string {
+	if len(params) == 0 || !strings.Contains(template, "{") {
+		return template
+	}
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		b.WriteString(template[:start])
+		if v, ok := params[template[start+1:end]]; ok {
+			fmt.Fprint(&b, v)
+		} else {
+			b.WriteString(template[start : end+1])
+		}
+		template = template[end+1:]
+	}
+	b.WriteString(template)
+	return b.String()
+}
+
+// Normalize returns tag in its conventional case with "-" separators:
+// "zh_hant_tw" becomes "zh-Hant-TW", "EN-us" becomes "en-US".
+func Normalize(tag string) string {
+	parts := strings.FieldsFunc(strings.TrimSpace(tag), func(r rune) bool { return r == '-' || r == '_' })
+	for i, part := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(part)
+		case len(part) == 4:
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		case len(part) == 2:
+			parts[i] = strings.ToUpper(part)
+		default:
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// parentTag strips the last subtag: "zh-hant-tw" -> "zh-hant" -> "zh" -> "".
+func parentTag(tag string) string {
+	if i := strings.LastIndexByte(tag, '-'); i > 0 {
+		return tag[:i]
+	}
+	return ""
+}
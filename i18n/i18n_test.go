@@ -0,0 +1,195 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/binding"
+	"github.com/stretchr/testify/require"
+)
+
+func testBundle(t *testing.T) *Bundle {
+	t.Helper()
+	b := NewBundle("en")
+	require.NoError(t, b.LoadFS(fstest.MapFS{
+		"en.yaml": {Data: []byte(`
+greeting: "Hello, {name}"
+cart:
+  items:
+    zero: Your cart is empty
+    one: "{count} item"
+    other: "{count} items"
+welcome: "Welcome {0}, you have {1} messages"
+only_en: English only
+`)},
+		"zh-CN.json": {Data: []byte(`{
+			"greeting": "你好，{name}",
+			"cart": {"items": {"other": "{count} 件商品"}}
+		}`)},
+		"ru/cart.yml": {Data: []byte(`
+cart:
+  items:
+    one: "{count} товар"
+    few: "{count} товара"
+    many: "{count} товаров"
+    other: "{count} товара"
+`)},
+		"README.md": {Data: []byte("ignored")},
+	}))
+	return b
+}
+
+func TestTranslate(t *testing.T) {
+	b := testBundle(t)
+	require.Equal(t, []string{"en", "ru", "zh-CN"}, b.Languages())
+
+	require.Equal(t, "Hello, ann", b.Translate("en", "greeting", Params{"name": "ann"}))
+	require.Equal(t, "你好，ann", b.Translate("zh-CN", "greeting", Params{"name": "ann"}))
+	// 子标签回退到父标签，缺失的键回退到默认语言，未知键原样返回
+	require.Equal(t, "Hello, ann", b.Translate("en-GB", "greeting", Params{"name": "ann"}))
+	require.Equal(t, "English only", b.Translate("zh-CN", "only_en"))
+	require.Equal(t, "missing.key", b.Translate("zh-CN", "missing.key"))
+	// 位置参数与未知占位符
+	require.Equal(t, "Welcome ann, you have 3 messages", b.Translate("en", "welcome", "ann", 3))
+	require.Equal(t, "Hello, {name}", b.Translate("en", "greeting"))
+	require.True(t, b.Has("de", "greeting"))
+	require.False(t, b.Has("en", "cart"))
+}
+
+func TestPlurals(t *testing.T) {
+	b := testBundle(t)
+	cases := []struct {
+		lang  string
+		count any
+		want  string
+	}{
+		{"en", 0, "Your cart is empty"},
+		{"en", 1, "1 item"},
+		{"en", 2, "2 items"},
+		{"zh-CN", 1, "1 件商品"},
+		{"ru", 1, "1 товар"},
+		{"ru", 3, "3 товара"},
+		{"ru", 5, "5 товаров"},
+		{"ru", 11, "11 товаров"},
+		{"ru", 21, "21 товар"},
+		{"ru", 1.5, "1.5 товара"},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.want, b.Translate(c.lang, "cart.items", Params{"count": c.count}), "%s %v", c.lang, c.count)
+	}
+	// 第一个数值位置参数同样决定复数形式
+	require.NoError(t, b.AddMessages("en", map[string]any{"files": map[string]any{"one": "{0} file", "other": "{0} files"}}))
+	require.Equal(t, "1 file", b.Translate("en", "files", 1))
+
+	b.SetPluralRule("en", func(n float64) string { return PluralOther })
+	require.Equal(t, "1 items", b.Translate("en", "cart.items", Params{"count": 1}))
+
+	require.Equal(t, PluralFew, pluralRuleOf("pl", 22))
+	require.Equal(t, PluralMany, pluralRuleOf("pl", 12))
+	require.Equal(t, PluralOne, pluralRuleOf("fr", 0))
+	require.Equal(t, PluralTwo, pluralRuleOf("ar", 2))
+}
+
+func pluralRuleOf(lang string, n float64) string {
+	return NewBundle("en").pluralRule(lang)(n)
+}
+
+func TestAddMessagesErrors(t *testing.T) {
+	b := NewBundle("en")
+	require.ErrorContains(t, b.AddMessages("en", map[string]any{"a": []any{"x"}}), "key a")
+	require.Error(t, b.AddJSON("en", []byte(`{`)))
+	// 键不全是复数类别的映射按命名空间展开
+	require.NoError(t, b.AddYAML("en", []byte("form:\n  other: Other\n  title: Form\n")))
+	require.Equal(t, "Other", b.Translate("en", "form.other"))
+}
+
+func TestMatchAndMiddleware(t *testing.T) {
+	b := testBundle(t)
+	cases := map[string]string{
+		"zh-CN,zh;q=0.9":     "zh-CN",
+		"zh":                 "zh-CN",
+		"zh-TW":              "zh-CN",
+		"ru-RU, en;q=0.5":    "ru",
+		"de, fr;q=0.8":       "en",
+		"de, EN-us;q=0.1":    "en",
+		"":                   "en",
+		"zh-cn;q=0, ru;q=.5": "ru",
+	}
+	for header, want := range cases {
+		require.Equal(t, want, b.Match(header), "Accept-Language %q", header)
+	}
+
+	var got string
+	h := b.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = b.T(r.Context(), "cart.items", Params{"count": 2})
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "zh")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	require.Equal(t, "2 件商品", got)
+	require.Equal(t, "zh-CN", rec.Header().Get("Content-Language"))
+	require.Equal(t, "Accept-Language", rec.Header().Get("Vary"))
+
+	require.Equal(t, "zh-Hant-TW", Locale(WithLocale(context.Background(), "zh_hant_tw")))
+	require.Empty(t, Locale(context.Background()))
+}
+
+func TestDefaultBundle(t *testing.T) {
+	old := Default()
+	t.Cleanup(func() { SetDefault(old) })
+	SetDefault(testBundle(t))
+
+	ctx := WithLocale(context.Background(), "zh-CN")
+	require.Equal(t, "你好，ann", T(ctx, "greeting", Params{"name": "ann"}))
+	require.Equal(t, "Hello, ann", T(context.Background(), "greeting", Params{"name": "ann"}))
+}
+
+func TestRegisterErrors(t *testing.T) {
+	b := NewBundle("en")
+	require.NoError(t, b.AddYAML("zh-CN", []byte(`
+errors:
+  NOT_FOUND: "找不到{resource}"
+  QUOTA_EXCEEDED: "{resource} 配额 {limit} 已用完"
+`)))
+	registry := errors.NewErrorRegistry()
+	registry.Register("QUOTA_EXCEEDED", errors.NewBusiness("{resource} quota of {limit} exceeded"))
+	b.RegisterErrors(registry)
+
+	err := registry.Create("QUOTA_EXCEEDED", map[string]interface{}{"resource": "project", "limit": 5})
+	require.Equal(t, "project 配额 5 已用完", registry.Localize(err, "zh-CN").Message)
+	require.Equal(t, "project quota of 5 exceeded", registry.Localize(err, "en").Message)
+	require.Equal(t, "找不到user", registry.Localize(errors.NewNotFound("user", 1).WithCode(errors.CodeNotFound), "zh-cn").Message)
+}
+
+type signupRequest struct {
+	Name string `json:"name" validate:"required,min=3"`
+	Age  int    `json:"age" validate:"gte=18"`
+}
+
+func TestTranslateRule(t *testing.T) {
+	b := NewBundle("en")
+	require.NoError(t, b.AddYAML("zh-CN", []byte(`
+validation:
+  required: 不能为空
+  min: "{field} 长度不能少于 {param} 个字符"
+`)))
+	binding.SetMessageTranslator(b.TranslateRule)
+	t.Cleanup(func() { binding.SetMessageTranslator(nil) })
+
+	ctx := WithLocale(context.Background(), "zh-CN")
+	err := binding.ValidateCtx(ctx, &signupRequest{Name: "al", Age: 10})
+	var verrs binding.ValidationErrors
+	require.ErrorAs(t, err, &verrs)
+	require.Equal(t, "Name 长度不能少于 3 个字符", verrs[0].Message)
+	// 目录中没有的规则保留默认英文提示
+	require.Equal(t, "must be greater than or equal to 18", verrs[1].Message)
+
+	err = binding.ValidateCtx(context.Background(), &signupRequest{Age: 20})
+	require.ErrorAs(t, err, &verrs)
+	require.Equal(t, "is required", verrs[0].Message)
+}
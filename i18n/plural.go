@@ -0,0 +1,152 @@
+package i18n
+
+import (
+	"math"
+	"strings"
+)
+
+// Plural categories, as defined by CLDR.
+const (
+	PluralZero  = "zero"
+	PluralOne   = "one"
+	PluralTwo   = "two"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+// PluralRule returns the plural category of n.
+type PluralRule func(n float64) string
+
+// SetPluralRule sets the plural rule of lang and its sub-tags, replacing
+// the built-in one.
+func (b *Bundle) SetPluralRule(lang string, rule PluralRule) {
+	b.mu.Lock()
+	b.rules[strings.ToLower(Normalize(lang))] = rule
+	b.mu.Unlock()
+}
+
+// pluralRule returns the rule for tag: one set with SetPluralRule for the
+// tag or a parent, or else the built-in rule of its base language.
+func (b *Bundle) pluralRule(tag string) PluralRule {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for candidate := tag; candidate != ""; candidate = parentTag(candidate) {
+		if rule, ok := b.rules[candidate]; ok {
+			return rule
+		}
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	if rule, ok := builtinRules[base]; ok {
+		return rule
+	}
+	return pluralOneOther
+}
+
+// builtinRules are the CLDR cardinal rules of common languages, simplified
+// for integers. Languages not listed use one for 1 and other otherwise.
+var builtinRules = map[string]PluralRule{}
+
+func init() {
+	for _, lang := range []string{"zh", "ja", "ko", "vi", "th", "id", "ms", "km", "lo", "my"} {
+		builtinRules[lang] = pluralOther
+	}
+	for _, lang := range []string{"fr", "pt", "hi", "fa", "bn"} {
+		builtinRules[lang] = pluralZeroOneOther
+	}
+	for _, lang := range []string{"ru", "uk", "be"} {
+		builtinRules[lang] = pluralEastSlavic
+	}
+	builtinRules["pl"] = pluralPolish
+	builtinRules["cs"] = pluralCzech
+	builtinRules["sk"] = pluralCzech
+	builtinRules["ar"] = pluralArabic
+}
+
+func pluralOther(float64) string { return PluralOther }
+
+func pluralOneOther(n float64) string {
+	if n == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+// pluralZeroOneOther treats 0 and 1 (and fractions below 2) as singular.
+func pluralZeroOneOther(n float64) string {
+	if n >= 0 && n < 2 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralEastSlavic(n float64) string {
+	i, ok := integer(n)
+	switch {
+	case !ok:
+		return PluralOther
+	case i%10 == 1 && i%100 != 11:
+		return PluralOne
+	case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+		return PluralFew
+	default:
+		return PluralMany
+	}
+}
+
+func pluralPolish(n float64) string {
+	i, ok := integer(n)
+	switch {
+	case !ok:
+		return PluralOther
+	case i == 1:
+		return PluralOne
+	case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+		return PluralFew
+	default:
+		return PluralMany
+	}
+}
+
+func pluralCzech(n float64) string {
+	i, ok := integer(n)
+	switch {
+	case !ok:
+		return PluralMany
+	case i == 1:
+		return PluralOne
+	case i >= 2 && i <= 4:
+		return PluralFew
+	default:
+		return PluralOther
+	}
+}
+
+func pluralArabic(n float64) string {
+	i, ok := integer(n)
+	switch {
+	case !ok:
+		return PluralOther
+	case i == 0:
+		return PluralZero
+	case i == 1:
+		return PluralOne
+	case i == 2:
+		return PluralTwo
+	case i%100 >= 3 && i%100 <= 10:
+		return PluralFew
+	case i%100 >= 11:
+		return PluralMany
+	default:
+		return PluralOther
+	}
+}
+
+// integer returns |n| when it is a whole number.
+func integer(n float64) (int64, bool) {
+	n = math.Abs(n)
+	if n != math.Trunc(n) {
+		return 0, false
+	}
+	return int64(n), true
+}
@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// DeadLetterQueue holds jobs that failed after exhausting their attempts.
+type DeadLetterQueue interface {
+	// Add records a failed job.
+	Add(ctx context.Context, job *Job) error
+	// List returns dead-lettered jobs, oldest failure first.
+	List(ctx context.Context) ([]*Job, error)
+	// Remove drops a job, e.g. after it was replayed.
+	Remove(ctx context.Context, id string) error
+}
+
+// MemoryDeadLetterQueue is an in-process DeadLetterQueue.
+type MemoryDeadLetterQueue struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewMemoryDeadLetterQueue creates an empty in-memory dead-letter queue.
+func NewMemoryDeadLetterQueue() *MemoryDeadLetterQueue {
+	return &MemoryDeadLetterQueue{jobs: make(map[string]*Job)}
+}
+
+// Add implements DeadLetterQueue.
+func (q *MemoryDeadLetterQueue) Add(_ context.Context, job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.jobs[job.ID] = job.clone()
+	return nil
+}
+
+// List implements DeadLetterQueue.
+func (q *MemoryDeadLetterQueue) List(_ context.Context) ([]*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]*Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		out = append(out, job.clone())
+	}
+	sortByFailure(out)
+	return out, nil
+}
+
+// Remove implements DeadLetterQueue.
+func (q *MemoryDeadLetterQueue) Remove(_ context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.jobs, id)
+	return nil
+}
+
+func sortByFailure(jobs []*Job) {
+	sort.Slice(jobs, func(i, j int) bool {
+		a, b := jobs[i].FinishedAt, jobs[j].FinishedAt
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.Before(*b)
+	})
+}
@@ -0,0 +1,153 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOrderingKeySerializesJobs(t *testing.T) {
+	m := NewManager(Options{Workers: 4, Backoff: func(int) time.Duration { return 5 * time.Millisecond }})
+	var mu sync.Mutex
+	var order []int
+	var running, peak atomic.Int32
+	failedOnce := false
+	m.Register("write", func(_ context.Context, job *Job) (any, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		if n > peak.Load() {
+			peak.Store(n)
+		}
+		var seq int
+		if err := job.Decode(&seq); err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		// The first write fails once; later writes must wait for its retry.
+		if seq == 0 && !failedOnce {
+			failedOnce = true
+			return nil, errors.New("transient")
+		}
+		order = append(order, seq)
+		time.Sleep(time.Millisecond)
+		return nil, nil
+	})
+
+	var last *Job
+	for i := 0; i < 5; i++ {
+		job, err := m.Enqueue(context.Background(), "write", i, WithOrderingKey("user:1"))
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		last = job
+	}
+	m.Start()
+	defer m.Stop(context.Background())
+	waitForState(t, m, last.ID, StateSucceeded)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !sort.IntsAreSorted(order) || len(order) != 5 {
+		t.Errorf("order = %v, want 0..4", order)
+	}
+	if peak.Load() != 1 {
+		t.Errorf("peak concurrency for one key = %d, want 1", peak.Load())
+	}
+}
+
+func TestDeadLetterAndReplay(t *testing.T) {
+	dlq := NewMemoryDeadLetterQueue()
+	m := NewManager(Options{MaxAttempts: 1, DeadLetter: dlq})
+	var fail atomic.Bool
+	fail.Store(true)
+	m.Register("send", func(context.Context, *Job) (any, error) {
+		if fail.Load() {
+			return nil, errors.New("smtp down")
+		}
+		return nil, nil
+	})
+	m.Start()
+	defer m.Stop(context.Background())
+
+	job, err := m.Enqueue(context.Background(), "send", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	waitForState(t, m, job.ID, StateFailed)
+
+	dead, err := m.DeadLetters(context.Background())
+	if err != nil || len(dead) != 1 || dead[0].ID != job.ID || dead[0].Error != "smtp down" {
+		t.Fatalf("DeadLetters = %+v, %v", dead, err)
+	}
+
+	fail.Store(false)
+	if _, err := m.Replay(context.Background(), job.ID); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	done := waitForState(t, m, job.ID, StateSucceeded)
+	if done.Attempts != 1 {
+		t.Errorf("attempts after replay = %d, want 1", done.Attempts)
+	}
+	if dead, _ := m.DeadLetters(context.Background()); len(dead) != 0 {
+		t.Errorf("replayed job still dead-lettered: %+v", dead)
+	}
+	if _, err := m.Replay(context.Background(), job.ID); err == nil {
+		t.Error("replaying a succeeded job must fail")
+	}
+}
+
+// listingStore adds PendingLister to MemoryStore to simulate a durable store.
+type listingStore struct{ *MemoryStore }
+
+func (s listingStore) ListPending(context.Context) ([]*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*Job
+	for _, job := range s.jobs {
+		if !job.State.Terminal() {
+			out = append(out, job.clone())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func TestStartRecoversPendingJobs(t *testing.T) {
+	store := listingStore{NewMemoryStore()}
+	noop := func(context.Context, *Job) (any, error) { return nil, nil }
+
+	// The first process enqueues and exits before running anything.
+	before := NewManager(Options{Store: store})
+	before.Register("noop", noop)
+	job, err := before.Enqueue(context.Background(), "noop", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	before.Stop(context.Background())
+
+	var runs atomic.Int32
+	after := NewManager(Options{Store: store})
+	after.Register("noop", func(ctx context.Context, j *Job) (any, error) {
+		runs.Add(1)
+		return noop(ctx, j)
+	})
+	// Enqueued before Start: recovery must not queue it twice.
+	fresh, err := after.Enqueue(context.Background(), "noop", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	after.Start()
+	defer after.Stop(context.Background())
+
+	waitForState(t, after, job.ID, StateSucceeded)
+	waitForState(t, after, fresh.ID, StateSucceeded)
+	time.Sleep(20 * time.Millisecond)
+	if runs.Load() != 2 {
+		t.Errorf("runs = %d, want 2", runs.Load())
+	}
+}
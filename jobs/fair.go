@@ -0,0 +1,313 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+)
+
+// TenantMetadataKey is the Job.Metadata key consulted for the tenant when
+// Job.TenantID is empty, so jobs tagged by tenant-aware middleware are
+// scheduled fairly without WithTenant.
+const TenantMetadataKey = "tenant_id"
+
+// Metric names exported when Options.Metrics is set. All carry a "tenant"
+// label.
+const (
+	MetricQueueWait  = "jobs_queue_wait_seconds"
+	MetricQueueDepth = "jobs_queue_depth"
+	MetricRunning    = "jobs_running"
+	MetricStarved    = "jobs_starved_total"
+)
+
+// FairnessOptions keeps one tenant's burst from monopolizing workers. Jobs
+// are dispatched across tenants by weighted fair queuing (stride
+// scheduling), so a tenant with weight 2 gets twice the dispatches of a
+// tenant with weight 1 while both have work queued; within a tenant jobs run
+// in FIFO order. The zero value gives every tenant equal weight and no cap.
+type FairnessOptions struct {
+	// MaxConcurrentPerTenant caps running jobs per tenant; zero means no cap.
+	MaxConcurrentPerTenant int
+	// TenantConcurrency overrides MaxConcurrentPerTenant for specific tenants.
+	TenantConcurrency map[string]int
+	// TenantWeights sets dispatch weights; tenants not listed weigh 1.
+	TenantWeights map[string]float64
+	// StarvationThreshold counts a job as starved when it waits longer than
+	// this before dispatch (default 30s).
+	StarvationThreshold time.Duration
+	// TenantOf extracts the scheduling tenant (default TenantOf).
+	TenantOf func(*Job) string
+}
+
+// TenantOf returns job.TenantID, or the TenantMetadataKey metadata entry when
+// TenantID is empty.
+func TenantOf(job *Job) string {
+	if job.TenantID != "" {
+		return job.TenantID
+	}
+	return job.Metadata[TenantMetadataKey]
+}
+
+// TenantStats is a snapshot of one tenant's scheduling state.
+type TenantStats struct {
+	Queued     int
+	Running    int
+	Dispatched int64
+	Starved    int64
+	// MaxWait is the longest time a dispatched job spent queued.
+	MaxWait time.Duration
+	// OldestQueued is how long the job at the head of the tenant's queue has
+	// been waiting; it grows without bound while the tenant is starved.
+	OldestQueued time.Duration
+}
+
+type queuedJob struct {
+	id     string
+	tenant string
+	// key is the job's ordering key; holder marks a retry of the job that
+	// currently holds it.
+	key      string
+	holder   bool
+	enqueued time.Time
+}
+
+type tenantQueue struct {
+	name    string
+	jobs    []queuedJob
+	running int
+	// pass is the tenant's virtual time; the eligible tenant with the lowest
+	// pass is dispatched next and advances by 1/weight.
+	pass float64
+	seq  uint64 // activation order, breaks ties between equal passes
+
+	dispatched int64
+	starved    int64
+	maxWait    time.Duration
+}
+
+// fairScheduler holds queued job IDs per tenant and hands them to workers.
+type fairScheduler struct {
+	opts      FairnessOptions
+	capacity  int
+	collector *metrics.Collector
+
+	mu      sync.Mutex
+	tenants map[string]*tenantQueue
+	queued  int
+	ids     map[string]struct{} // queued job IDs
+	held    map[string]bool     // ordering keys of running or retrying jobs
+	vtime   float64             // pass of the last dispatch; idle tenants rejoin here
+	seq     uint64
+	wake    chan struct{}
+}
+
+func newFairScheduler(opts FairnessOptions, capacity int, collector *metrics.Collector) *fairScheduler {
+	if opts.StarvationThreshold <= 0 {
+		opts.StarvationThreshold = 30 * time.Second
+	}
+	if opts.TenantOf == nil {
+		opts.TenantOf = TenantOf
+	}
+	return &fairScheduler{
+		opts:      opts,
+		capacity:  capacity,
+		collector: collector,
+		tenants:   make(map[string]*tenantQueue),
+		ids:       make(map[string]struct{}),
+		held:      make(map[string]bool),
+		wake:      make(chan struct{}),
+	}
+}
+
+// push queues item for its tenant. It reports false when the queue is at
+// capacity unless force is set, which retries use so a backoff never drops a
+// job.
+func (s *fairScheduler) push(item queuedJob, force bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !force && s.queued >= s.capacity {
+		return false
+	}
+
+	t := s.tenants[item.tenant]
+	if t == nil {
+		t = &tenantQueue{name: item.tenant}
+		s.tenants[item.tenant] = t
+	}
+	if len(t.jobs) == 0 && t.running == 0 {
+		// An idle tenant must not bank credit while it had nothing queued.
+		if t.pass < s.vtime {
+			t.pass = s.vtime
+		}
+		s.seq++
+		t.seq = s.seq
+	}
+	item.enqueued = time.Now()
+	t.jobs = append(t.jobs, item)
+	s.queued++
+	s.ids[item.id] = struct{}{}
+	s.gauge(MetricQueueDepth, t.name, float64(len(t.jobs)))
+	s.broadcast()
+	return true
+}
+
+// pop blocks until a job from an eligible tenant is available or ctx ends.
+func (s *fairScheduler) pop(ctx context.Context) (queuedJob, bool) {
+	for {
+		s.mu.Lock()
+		if item, ok := s.next(); ok {
+			s.mu.Unlock()
+			return item, true
+		}
+		wake := s.wake
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return queuedJob{}, false
+		case <-wake:
+		}
+	}
+}
+
+// next dispatches from the eligible tenant with the lowest pass. Callers
+// hold s.mu.
+func (s *fairScheduler) next() (queuedJob, bool) {
+	var best *tenantQueue
+	bestIdx := -1
+	for _, t := range s.tenants {
+		if len(t.jobs) == 0 {
+			continue
+		}
+		if limit := s.limit(t.name); limit > 0 && t.running >= limit {
+			continue
+		}
+		if best != nil && (t.pass > best.pass || (t.pass == best.pass && t.seq > best.seq)) {
+			continue
+		}
+		if idx := s.eligible(t); idx >= 0 {
+			best, bestIdx = t, idx
+		}
+	}
+	if best == nil {
+		return queuedJob{}, false
+	}
+
+	item := best.jobs[bestIdx]
+	if bestIdx == 0 {
+		best.jobs[0] = queuedJob{}
+		best.jobs = best.jobs[1:]
+	} else {
+		best.jobs = append(best.jobs[:bestIdx], best.jobs[bestIdx+1:]...)
+	}
+	delete(s.ids, item.id)
+	if item.key != "" {
+		s.held[item.key] = true
+	}
+	best.running++
+	best.dispatched++
+	s.queued--
+	s.vtime = best.pass
+	best.pass += 1 / s.weight(best.name)
+
+	wait := time.Since(item.enqueued)
+	if wait > best.maxWait {
+		best.maxWait = wait
+	}
+	if wait > s.opts.StarvationThreshold {
+		best.starved++
+		if s.collector != nil {
+			s.collector.IncCounter(MetricStarved, map[string]string{"tenant": best.name})
+		}
+	}
+	if s.collector != nil {
+		s.collector.ObserveHistogram(MetricQueueWait, wait.Seconds(), map[string]string{"tenant": best.name})
+	}
+	s.gauge(MetricQueueDepth, best.name, float64(len(best.jobs)))
+	s.gauge(MetricRunning, best.name, float64(best.running))
+	return item, true
+}
+
+// eligible returns the index of the first job in t that is not blocked by
+// its ordering key, or -1. Callers hold s.mu.
+func (s *fairScheduler) eligible(t *tenantQueue) int {
+	for i, item := range t.jobs {
+		if item.key == "" || item.holder || !s.held[item.key] {
+			return i
+		}
+	}
+	return -1
+}
+
+// done releases a tenant's concurrency slot after its job ran. The job's
+// ordering key is released too unless the job will be retried.
+func (s *fairScheduler) done(item queuedJob, release bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if release && item.key != "" {
+		delete(s.held, item.key)
+	}
+	t := s.tenants[item.tenant]
+	if t == nil {
+		return
+	}
+	t.running--
+	s.gauge(MetricRunning, item.tenant, float64(t.running))
+	s.broadcast()
+}
+
+// queuedID reports whether id is waiting in the queue.
+func (s *fairScheduler) queuedID(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.ids[id]
+	return ok
+}
+
+func (s *fairScheduler) stats() map[string]TenantStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]TenantStats, len(s.tenants))
+	for name, t := range s.tenants {
+		st := TenantStats{
+			Queued:     len(t.jobs),
+			Running:    t.running,
+			Dispatched: t.dispatched,
+			Starved:    t.starved,
+			MaxWait:    t.maxWait,
+		}
+		if len(t.jobs) > 0 {
+			st.OldestQueued = time.Since(t.jobs[0].enqueued)
+		}
+		out[name] = st
+	}
+	return out
+}
+
+func (s *fairScheduler) limit(tenant string) int {
+	if n, ok := s.opts.TenantConcurrency[tenant]; ok {
+		return n
+	}
+	return s.opts.MaxConcurrentPerTenant
+}
+
+func (s *fairScheduler) weight(tenant string) float64 {
+	if w, ok := s.opts.TenantWeights[tenant]; ok && w > 0 {
+		return w
+	}
+	return 1
+}
+
+func (s *fairScheduler) gauge(name, tenant string, value float64) {
+	if s.collector != nil {
+		s.collector.SetGauge(name, value, map[string]string{"tenant": tenant})
+	}
+}
+
+// broadcast wakes every waiting worker. Callers hold s.mu.
+func (s *fairScheduler) broadcast() {
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
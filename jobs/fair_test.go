@@ -0,0 +1,166 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+)
+
+// recordOrder registers a handler that appends each job's tenant to order.
+func recordOrder(m *Manager) (order func() []string) {
+	var mu sync.Mutex
+	var tenants []string
+	m.Register("work", func(_ context.Context, job *Job) (any, error) {
+		mu.Lock()
+		tenants = append(tenants, TenantOf(job))
+		mu.Unlock()
+		return nil, nil
+	})
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), tenants...)
+	}
+}
+
+func enqueueN(t *testing.T, m *Manager, tenant string, n int) []*Job {
+	t.Helper()
+	jobs := make([]*Job, n)
+	for i := range jobs {
+		job, err := m.Enqueue(context.Background(), "work", nil, WithTenant(tenant))
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		jobs[i] = job
+	}
+	return jobs
+}
+
+func TestFairness_BurstDoesNotStarveOtherTenants(t *testing.T) {
+	m := NewManager(Options{Workers: 1})
+	order := recordOrder(m)
+	enqueueN(t, m, "noisy", 20)
+	quiet := enqueueN(t, m, "quiet", 2)
+
+	m.Start()
+	defer m.Stop(context.Background())
+	waitForState(t, m, quiet[1].ID, StateSucceeded)
+
+	got := order()
+	want := []string{"noisy", "quiet", "noisy", "quiet"}
+	for i, tenant := range want {
+		if got[i] != tenant {
+			t.Fatalf("dispatch order %v, want prefix %v", got[:len(want)], want)
+		}
+	}
+}
+
+func TestFairness_WeightsShareDispatches(t *testing.T) {
+	m := NewManager(Options{
+		Workers:  1,
+		Fairness: FairnessOptions{TenantWeights: map[string]float64{"gold": 3}},
+	})
+	order := recordOrder(m)
+	gold := enqueueN(t, m, "gold", 8)
+	enqueueN(t, m, "free", 8)
+
+	m.Start()
+	defer m.Stop(context.Background())
+	waitForState(t, m, gold[7].ID, StateSucceeded)
+
+	counts := map[string]int{}
+	for _, tenant := range order()[:8] {
+		counts[tenant]++
+	}
+	if counts["gold"] != 6 || counts["free"] != 2 {
+		t.Errorf("first 8 dispatches = %v, want gold:6 free:2", counts)
+	}
+}
+
+func TestFairness_PerTenantConcurrencyCap(t *testing.T) {
+	m := NewManager(Options{
+		Workers: 4,
+		Fairness: FairnessOptions{
+			MaxConcurrentPerTenant: 1,
+			TenantConcurrency:      map[string]int{"vip": 2},
+		},
+	})
+	var mu sync.Mutex
+	running, peak := map[string]int{}, map[string]int{}
+	m.Register("work", func(_ context.Context, job *Job) (any, error) {
+		mu.Lock()
+		running[job.TenantID]++
+		peak[job.TenantID] = max(peak[job.TenantID], running[job.TenantID])
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running[job.TenantID]--
+		mu.Unlock()
+		return nil, nil
+	})
+	bulk := enqueueN(t, m, "bulk", 4)
+	vip := enqueueN(t, m, "vip", 4)
+
+	m.Start()
+	defer m.Stop(context.Background())
+	waitForState(t, m, bulk[3].ID, StateSucceeded)
+	waitForState(t, m, vip[3].ID, StateSucceeded)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if peak["bulk"] != 1 || peak["vip"] != 2 {
+		t.Errorf("peak concurrency = %v, want bulk:1 vip:2", peak)
+	}
+}
+
+func TestFairness_TenantFromMetadataAndStats(t *testing.T) {
+	collector := metrics.NewCollector()
+	m := NewManager(Options{
+		Workers:  1,
+		Metrics:  collector,
+		Fairness: FairnessOptions{StarvationThreshold: time.Nanosecond},
+	})
+	var ran atomic.Int32
+	m.Register("work", func(context.Context, *Job) (any, error) {
+		ran.Add(1)
+		return nil, nil
+	})
+
+	job, err := m.Enqueue(context.Background(), "work", nil, WithMetadata(TenantMetadataKey, "acme"))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if st := m.TenantStats()["acme"]; st.Queued != 1 {
+		t.Fatalf("stats before start = %+v", st)
+	}
+
+	m.Start()
+	defer m.Stop(context.Background())
+	waitForState(t, m, job.ID, StateSucceeded)
+
+	st := m.TenantStats()["acme"]
+	if st.Dispatched != 1 || st.Starved != 1 || st.Queued != 0 || st.MaxWait <= 0 {
+		t.Errorf("stats = %+v", st)
+	}
+	labels := map[string]string{"tenant": "acme"}
+	if c := collector.GetMetric(MetricStarved, labels); c == nil || c.Value != 1 {
+		t.Errorf("%s = %+v", MetricStarved, c)
+	}
+	if h := collector.GetMetric(MetricQueueWait, labels); h == nil || len(h.History) != 1 {
+		t.Errorf("%s = %+v", MetricQueueWait, h)
+	}
+}
+
+func TestFairness_QueueSizeBoundsAllTenants(t *testing.T) {
+	m := NewManager(Options{Workers: 1, QueueSize: 2})
+	m.Register("work", func(context.Context, *Job) (any, error) { return nil, nil })
+	enqueueN(t, m, "a", 1)
+	enqueueN(t, m, "b", 1)
+	if _, err := m.Enqueue(context.Background(), "work", nil, WithTenant("c")); err != ErrQueueFull {
+		t.Fatalf("Enqueue on full queue = %v, want ErrQueueFull", err)
+	}
+}
@@ -0,0 +1,148 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// State is the lifecycle state of a job.
+type State string
+
+const (
+	StatePending   State = "pending"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCanceled  State = "canceled"
+)
+
+// Terminal reports whether the job will not change state anymore.
+func (s State) Terminal() bool {
+	return s == StateSucceeded || s == StateFailed || s == StateCanceled
+}
+
+var (
+	// ErrUnknownType is returned when no handler is registered for a job type.
+	ErrUnknownType = errors.New("jobs: unknown job type")
+	// ErrNotFound is returned when a job does not exist in the store.
+	ErrNotFound = errors.New("jobs: job not found")
+	// ErrQueueFull is returned when the queue cannot accept more jobs.
+	ErrQueueFull = errors.New("jobs: queue is full")
+	// ErrStopped is returned when enqueueing on a stopped manager.
+	ErrStopped = errors.New("jobs: manager is stopped")
+	// ErrDuplicate is returned together with the existing job when an
+	// idempotency key has already been used with the same payload.
+	ErrDuplicate = errors.New("jobs: duplicate idempotency key")
+	// ErrIdempotencyConflict is returned when an idempotency key is reused
+	// with a different job type or payload.
+	ErrIdempotencyConflict = errors.New("jobs: idempotency key reused with different request")
+	// ErrClaimed is returned by Claimer.Claim while another Manager holds
+	// the job.
+	ErrClaimed = errors.New("jobs: job claimed by another manager")
+)
+
+// Job is a unit of asynchronous work.
+type Job struct {
+	ID             string            `json:"id"`
+	Type           string            `json:"type"`
+	Payload        json.RawMessage   `json:"payload,omitempty"`
+	State          State             `json:"state"`
+	Attempts       int               `json:"attempts"`
+	MaxAttempts    int               `json:"maxAttempts"`
+	Result         json.RawMessage   `json:"result,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	TenantID       string            `json:"tenantId,omitempty"`
+	IdempotencyKey string            `json:"idempotencyKey,omitempty"`
+	OrderingKey    string            `json:"orderingKey,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	StartedAt      *time.Time        `json:"startedAt,omitempty"`
+	FinishedAt     *time.Time        `json:"finishedAt,omitempty"`
+	// Owner is the Manager that claimed the job last and LeaseUntil when
+	// that claim lapses; both are set only with a Claimer store.
+	Owner      string     `json:"owner,omitempty"`
+	LeaseUntil *time.Time `json:"leaseUntil,omitempty"`
+}
+
+// Decode unmarshals the job payload into v.
+func (j *Job) Decode(v any) error {
+	if len(j.Payload) == 0 {
+		return nil
+	}
+	return json.Unmarshal(j.Payload, v)
+}
+
+// clone returns a deep copy so callers can't mutate stored jobs.
+func (j *Job) clone() *Job {
+	c := *j
+	c.Payload = append(json.RawMessage(nil), j.Payload...)
+	c.Result = append(json.RawMessage(nil), j.Result...)
+	if j.Metadata != nil {
+		c.Metadata = make(map[string]string, len(j.Metadata))
+		for k, v := range j.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+	if j.StartedAt != nil {
+		t := *j.StartedAt
+		c.StartedAt = &t
+	}
+	if j.FinishedAt != nil {
+		t := *j.FinishedAt
+		c.FinishedAt = &t
+	}
+	if j.LeaseUntil != nil {
+		t := *j.LeaseUntil
+		c.LeaseUntil = &t
+	}
+	return &c
+}
+
+// Handler executes a job. The returned value is JSON-encoded into Job.Result.
+type Handler func(ctx context.Context, job *Job) (any, error)
+
+// EnqueueOption customizes a job at enqueue time.
+type EnqueueOption func(*Job)
+
+// WithIdempotencyKey deduplicates enqueues sharing the same key.
+func WithIdempotencyKey(key string) EnqueueOption {
+	return func(j *Job) {
+		j.IdempotencyKey = key
+	}
+}
+
+// WithTenant scopes the job to a tenant.
+func WithTenant(tenantID string) EnqueueOption {
+	return func(j *Job) {
+		j.TenantID = tenantID
+	}
+}
+
+// WithOrderingKey serializes jobs sharing key: they run one at a time in
+// enqueue order, and a job waiting to be retried holds back the later ones.
+func WithOrderingKey(key string) EnqueueOption {
+	return func(j *Job) {
+		j.OrderingKey = key
+	}
+}
+
+// WithMaxAttempts overrides the manager's default attempt limit.
+func WithMaxAttempts(n int) EnqueueOption {
+	return func(j *Job) {
+		if n > 0 {
+			j.MaxAttempts = n
+		}
+	}
+}
+
+// WithMetadata attaches a metadata entry to the job.
+func WithMetadata(key, value string) EnqueueOption {
+	return func(j *Job) {
+		if j.Metadata == nil {
+			j.Metadata = make(map[string]string)
+		}
+		j.Metadata[key] = value
+	}
+}
@@ -0,0 +1,526 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/metrics"
+	"github.com/leeforge/framework/tracing"
+	"go.uber.org/zap"
+)
+
+// Options configures a Manager.
+type Options struct {
+	// Workers is the number of concurrent workers (default 4).
+	Workers int
+	// QueueSize bounds the number of jobs waiting for a worker (default 1024).
+	QueueSize int
+	// MaxAttempts is the default attempt limit per job (default 3).
+	MaxAttempts int
+	// Backoff returns the delay before retrying after the given attempt.
+	Backoff func(attempt int) time.Duration
+	// Timeout bounds a single handler execution; zero means no limit.
+	Timeout time.Duration
+	// Store persists jobs (default NewMemoryStore()).
+	Store Store
+	// Logger receives worker diagnostics (default no-op).
+	Logger *zap.Logger
+	// Tracer, when set, wraps each execution in a consumer span linked to
+	// the span that enqueued the job.
+	Tracer *tracing.Tracer
+	// Fairness controls how workers are shared between tenants.
+	Fairness FairnessOptions
+	// Metrics, when set, receives per-tenant queue depth, wait time,
+	// running and starvation metrics.
+	Metrics *metrics.Collector
+	// DeadLetter, when set, receives jobs that exhausted their attempts so
+	// they can be inspected and replayed.
+	DeadLetter DeadLetterQueue
+	// Lease is how long a job stays claimed by a Manager that stopped
+	// renewing it, when the store implements Claimer (default 1m). Running
+	// jobs renew it every Lease/3; a job waiting for a retry stays claimed
+	// until its backoff plus Lease has elapsed.
+	Lease time.Duration
+}
+
+// DefaultBackoff waits 1s, 2s, 4s, ... capped at one minute.
+func DefaultBackoff(attempt int) time.Duration {
+	d := time.Second << (attempt - 1)
+	if d <= 0 || d > time.Minute {
+		return time.Minute
+	}
+	return d
+}
+
+// Manager dispatches enqueued jobs to registered handlers on a worker pool.
+type Manager struct {
+	opts     Options
+	id       string // owner recorded in claims
+	store    Store
+	logger   *zap.Logger
+	handlers map[string]Handler
+	queue    *fairScheduler
+
+	mu      sync.RWMutex
+	started bool
+	stopped bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewManager creates a job manager. Call Start to begin processing.
+func NewManager(opts Options) *Manager {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = DefaultBackoff
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore()
+	}
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		opts:     opts,
+		id:       uuid.NewString(),
+		store:    opts.Store,
+		logger:   opts.Logger,
+		handlers: make(map[string]Handler),
+		queue:    newFairScheduler(opts.Fairness, opts.QueueSize, opts.Metrics),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Register binds a handler to a job type. It must be called before Start.
+func (m *Manager) Register(jobType string, handler Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[jobType] = handler
+}
+
+// Handles reports whether a handler is registered for jobType.
+func (m *Manager) Handles(jobType string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.handlers[jobType]
+	return ok
+}
+
+// Enqueue persists a new job and schedules it for execution.
+//
+// When an idempotency key is supplied and a job with that key already exists,
+// the existing job is returned together with ErrDuplicate; if the earlier job
+// had a different type or payload, ErrIdempotencyConflict is returned instead.
+func (m *Manager) Enqueue(ctx context.Context, jobType string, payload any, opts ...EnqueueOption) (*Job, error) {
+	if !m.Handles(jobType) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, jobType)
+	}
+
+	m.mu.RLock()
+	stopped := m.stopped
+	m.mu.RUnlock()
+	if stopped {
+		return nil, ErrStopped
+	}
+
+	raw, err := marshalPayload(payload)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: encode payload: %w", err)
+	}
+
+	job := &Job{
+		ID:          uuid.NewString(),
+		Type:        jobType,
+		Payload:     raw,
+		State:       StatePending,
+		MaxAttempts: m.opts.MaxAttempts,
+		CreatedAt:   time.Now(),
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+	if _, ok := tracing.LinkFromContext(ctx); ok {
+		if job.Metadata == nil {
+			job.Metadata = make(map[string]string, 2)
+		}
+		tracing.InjectLink(ctx, job.Metadata)
+	}
+
+	stored, err := m.store.Create(ctx, job)
+	if errors.Is(err, ErrDuplicate) {
+		if stored.Type != job.Type || !bytes.Equal(stored.Payload, job.Payload) {
+			return stored, ErrIdempotencyConflict
+		}
+		return stored, ErrDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if m.queue.push(m.queued(stored), false) {
+		return stored, nil
+	}
+	// The job never ran: drop it so its idempotency key does not turn every
+	// retry into ErrDuplicate.
+	_ = m.store.Delete(ctx, stored.ID)
+	return nil, ErrQueueFull
+}
+
+// Get returns the current state of a job.
+func (m *Manager) Get(ctx context.Context, id string) (*Job, error) {
+	return m.store.Get(ctx, id)
+}
+
+// Pending returns the non-terminal jobs of jobType across every Manager
+// sharing the store, oldest first. It returns nil when the store does not
+// implement PendingLister.
+func (m *Manager) Pending(ctx context.Context, jobType string) ([]*Job, error) {
+	lister, ok := m.store.(PendingLister)
+	if !ok {
+		return nil, nil
+	}
+	all, err := lister.ListPending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []*Job
+	for _, job := range all {
+		if job.Type == jobType {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// Start launches the worker pool. Calling Start more than once is a no-op.
+//
+// When the store implements PendingLister, jobs left pending by a previous
+// process (including ones interrupted while running) are queued again first.
+// When it also implements Claimer, a job another Manager holds is run only
+// once that Manager's lease has lapsed.
+func (m *Manager) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.started || m.stopped {
+		return
+	}
+	m.started = true
+	m.recover()
+
+	for i := 0; i < m.opts.Workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+}
+
+// Stop stops accepting jobs and waits for in-flight handlers to return or for
+// ctx to expire. Jobs still queued remain pending in the store.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return nil
+	}
+	m.stopped = true
+	m.mu.Unlock()
+
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DeadLetters returns the jobs in the dead-letter queue. It returns nil
+// when Options.DeadLetter is not set.
+func (m *Manager) DeadLetters(ctx context.Context) ([]*Job, error) {
+	if m.opts.DeadLetter == nil {
+		return nil, nil
+	}
+	return m.opts.DeadLetter.List(ctx)
+}
+
+// Replay resets a failed job's attempts and queues it again, removing it
+// from the dead-letter queue.
+func (m *Manager) Replay(ctx context.Context, id string) (*Job, error) {
+	job, err := m.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.State != StateFailed {
+		return nil, fmt.Errorf("jobs: cannot replay job %s in state %s", id, job.State)
+	}
+	job.State = StatePending
+	job.Attempts = 0
+	job.Error = ""
+	job.FinishedAt = nil
+	if err := m.store.Update(ctx, job); err != nil {
+		return nil, err
+	}
+	if m.opts.DeadLetter != nil {
+		if err := m.opts.DeadLetter.Remove(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	m.queue.push(m.queued(job), true)
+	return job, nil
+}
+
+// TenantStats returns per-tenant scheduling state keyed by tenant ID; jobs
+// without a tenant are reported under "".
+func (m *Manager) TenantStats() map[string]TenantStats {
+	return m.queue.stats()
+}
+
+func (m *Manager) worker() {
+	defer m.wg.Done()
+	for {
+		item, ok := m.queue.pop(m.ctx)
+		if !ok {
+			return
+		}
+		retry := m.process(item.id)
+		m.queue.done(item, !retry)
+	}
+}
+
+// recover queues the store's pending jobs that are not queued yet. Callers
+// hold m.mu.
+func (m *Manager) recover() {
+	lister, ok := m.store.(PendingLister)
+	if !ok {
+		return
+	}
+	pending, err := lister.ListPending(m.ctx)
+	if err != nil {
+		m.logger.Error("listing pending jobs failed", zap.Error(err))
+		return
+	}
+	recovered := 0
+	for _, job := range pending {
+		if m.handlers[job.Type] == nil || m.queue.queuedID(job.ID) {
+			continue
+		}
+		m.queue.push(m.queued(job), true)
+		recovered++
+	}
+	if recovered > 0 {
+		m.logger.Info("recovered pending jobs", zap.Int("count", recovered))
+	}
+}
+
+// process runs one job and reports whether it was scheduled for a retry.
+func (m *Manager) process(id string) bool {
+	job, err := m.acquire(id)
+	if errors.Is(err, ErrClaimed) {
+		// Another Manager holds the job; look again once its lease lapses.
+		m.retryLater(job, time.Until(*job.LeaseUntil))
+		return true
+	}
+	if err != nil {
+		m.logger.Error("job lookup failed", zap.String("job_id", id), zap.Error(err))
+		return false
+	}
+	if job.State.Terminal() {
+		return false
+	}
+
+	m.mu.RLock()
+	handler := m.handlers[job.Type]
+	m.mu.RUnlock()
+
+	now := time.Now()
+	job.State = StateRunning
+	job.Attempts++
+	if job.StartedAt == nil {
+		job.StartedAt = &now
+	}
+	if err := m.store.Update(m.ctx, job); err != nil {
+		m.logger.Error("job state update failed", zap.String("job_id", id), zap.Error(err))
+	}
+
+	stopRenewing := m.renewLease(id)
+	result, runErr := m.run(handler, job)
+	stopRenewing()
+
+	finished := time.Now()
+	retry := false
+	var delay time.Duration
+	switch {
+	case runErr == nil:
+		job.State = StateSucceeded
+		job.Error = ""
+		if result != nil {
+			if job.Result, err = json.Marshal(result); err != nil {
+				job.State = StateFailed
+				job.Error = fmt.Sprintf("encode result: %v", err)
+			}
+		}
+		job.FinishedAt = &finished
+	case m.ctx.Err() != nil:
+		// Shutting down: leave the job pending so a durable store can resume
+		// it, and release the claim so another Manager need not wait.
+		job.State = StatePending
+		job.Error = runErr.Error()
+		job.Owner, job.LeaseUntil = "", nil
+	case job.Attempts < job.MaxAttempts:
+		job.State = StatePending
+		job.Error = runErr.Error()
+		retry = true
+		delay = m.opts.Backoff(job.Attempts)
+		if job.LeaseUntil != nil {
+			until := finished.Add(delay + m.opts.Lease)
+			job.LeaseUntil = &until
+		}
+	default:
+		job.State = StateFailed
+		job.Error = runErr.Error()
+		job.FinishedAt = &finished
+		m.logger.Warn("job failed",
+			zap.String("job_id", job.ID),
+			zap.String("type", job.Type),
+			zap.Int("attempts", job.Attempts),
+			zap.Error(runErr))
+	}
+
+	ctx := context.WithoutCancel(m.ctx)
+	if err := m.store.Update(ctx, job); err != nil {
+		m.logger.Error("job state update failed", zap.String("job_id", id), zap.Error(err))
+	}
+	if job.State == StateFailed && m.opts.DeadLetter != nil {
+		if err := m.opts.DeadLetter.Add(ctx, job); err != nil {
+			m.logger.Error("dead-letter enqueue failed", zap.String("job_id", id), zap.Error(err))
+		}
+	}
+	if retry {
+		m.retryLater(job, delay)
+	}
+	return retry
+}
+
+// acquire loads the job to run, claiming it when the store is a Claimer.
+func (m *Manager) acquire(id string) (*Job, error) {
+	if claimer, ok := m.store.(Claimer); ok {
+		return claimer.Claim(m.ctx, id, m.id, time.Now().Add(m.opts.Lease))
+	}
+	return m.store.Get(m.ctx, id)
+}
+
+// renewLease keeps the claim on a running job alive. The returned func stops
+// renewing and returns once no renewal is in flight.
+func (m *Manager) renewLease(id string) func() {
+	claimer, ok := m.store.(Claimer)
+	if !ok {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(m.opts.Lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctx := context.WithoutCancel(m.ctx)
+				if _, err := claimer.Claim(ctx, id, m.id, time.Now().Add(m.opts.Lease)); err != nil {
+					m.logger.Warn("job lease renewal failed", zap.String("job_id", id), zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (m *Manager) run(handler Handler, job *Job) (result any, err error) {
+	ctx := m.ctx
+	if m.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.opts.Timeout)
+		defer cancel()
+	}
+
+	if t := m.opts.Tracer; t != nil {
+		producer, _ := tracing.ExtractLink(job.Metadata)
+		var span *tracing.Span
+		ctx, span = t.StartConsumer(ctx, "job "+job.Type, producer, tracing.WithAttributes(map[string]interface{}{
+			"job.id":      job.ID,
+			"job.type":    job.Type,
+			"job.attempt": job.Attempts,
+		}))
+		defer func() { t.End(span, err) }()
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("jobs: handler panic: %v", rec)
+		}
+	}()
+	return handler(ctx, job.clone())
+}
+
+func (m *Manager) retryLater(job *Job, delay time.Duration) {
+	item := m.queued(job)
+	item.holder = true
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-m.ctx.Done():
+		case <-timer.C:
+			m.queue.push(item, true)
+		}
+	}()
+}
+
+func (m *Manager) queued(job *Job) queuedJob {
+	return queuedJob{id: job.ID, tenant: m.queue.opts.TenantOf(job), key: job.OrderingKey}
+}
+
+func marshalPayload(payload any) (json.RawMessage, error) {
+	switch p := payload.(type) {
+	case nil:
+		return nil, nil
+	case json.RawMessage:
+		return p, nil
+	default:
+		return json.Marshal(payload)
+	}
+}
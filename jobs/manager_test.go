@@ -0,0 +1,178 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/tracing"
+)
+
+func waitForState(t *testing.T, m *Manager, id string, want State) *Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if job.State == want {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not reach state %s", id, want)
+	return nil
+}
+
+func TestManagerRunsJobAndStoresResult(t *testing.T) {
+	m := NewManager(Options{Workers: 2})
+	m.Register("sum", func(_ context.Context, job *Job) (any, error) {
+		var in struct{ A, B int }
+		if err := job.Decode(&in); err != nil {
+			return nil, err
+		}
+		return map[string]int{"sum": in.A + in.B}, nil
+	})
+	m.Start()
+	defer m.Stop(context.Background())
+
+	job, err := m.Enqueue(context.Background(), "sum", map[string]int{"A": 2, "B": 3})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	done := waitForState(t, m, job.ID, StateSucceeded)
+	if string(done.Result) != `{"sum":5}` {
+		t.Errorf("result = %s", done.Result)
+	}
+	if done.Attempts != 1 || done.FinishedAt == nil {
+		t.Errorf("attempts = %d, finishedAt = %v", done.Attempts, done.FinishedAt)
+	}
+}
+
+func TestManagerRetriesUntilMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	m := NewManager(Options{
+		MaxAttempts: 3,
+		Backoff:     func(int) time.Duration { return time.Millisecond },
+	})
+	m.Register("flaky", func(context.Context, *Job) (any, error) {
+		calls.Add(1)
+		return nil, errors.New("boom")
+	})
+	m.Start()
+	defer m.Stop(context.Background())
+
+	job, err := m.Enqueue(context.Background(), "flaky", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	failed := waitForState(t, m, job.ID, StateFailed)
+	if failed.Attempts != 3 || calls.Load() != 3 {
+		t.Errorf("attempts = %d, calls = %d, want 3", failed.Attempts, calls.Load())
+	}
+	if failed.Error != "boom" {
+		t.Errorf("error = %q", failed.Error)
+	}
+}
+
+func TestManagerIdempotencyKey(t *testing.T) {
+	m := NewManager(Options{})
+	m.Register("noop", func(context.Context, *Job) (any, error) { return nil, nil })
+
+	first, err := m.Enqueue(context.Background(), "noop", map[string]string{"a": "1"}, WithIdempotencyKey("k1"))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	again, err := m.Enqueue(context.Background(), "noop", map[string]string{"a": "1"}, WithIdempotencyKey("k1"))
+	if !errors.Is(err, ErrDuplicate) || again.ID != first.ID {
+		t.Fatalf("replay: err = %v, id = %v", err, again)
+	}
+
+	_, err = m.Enqueue(context.Background(), "noop", map[string]string{"a": "2"}, WithIdempotencyKey("k1"))
+	if !errors.Is(err, ErrIdempotencyConflict) {
+		t.Fatalf("conflict: err = %v", err)
+	}
+
+	other, err := m.Enqueue(context.Background(), "noop", map[string]string{"a": "1"}, WithIdempotencyKey("k1"), WithTenant("t2"))
+	if err != nil || other.ID == first.ID {
+		t.Fatalf("keys must be scoped per tenant: err = %v", err)
+	}
+}
+
+func TestManagerQueueFullReleasesIdempotencyKey(t *testing.T) {
+	m := NewManager(Options{Workers: 1, QueueSize: 1})
+	var runs atomic.Int32
+	m.Register("noop", func(context.Context, *Job) (any, error) {
+		runs.Add(1)
+		return nil, nil
+	})
+
+	first, err := m.Enqueue(context.Background(), "noop", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if _, err := m.Enqueue(context.Background(), "noop", nil, WithIdempotencyKey("k1")); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Enqueue on full queue = %v, want ErrQueueFull", err)
+	}
+
+	m.Start()
+	defer m.Stop(context.Background())
+	waitForState(t, m, first.ID, StateSucceeded)
+
+	retried, err := m.Enqueue(context.Background(), "noop", nil, WithIdempotencyKey("k1"))
+	if err != nil {
+		t.Fatalf("retry after drain: %v", err)
+	}
+	waitForState(t, m, retried.ID, StateSucceeded)
+	if got := runs.Load(); got != 2 {
+		t.Fatalf("runs = %d, want 2", got)
+	}
+}
+
+func TestManagerRejectsUnknownType(t *testing.T) {
+	m := NewManager(Options{})
+	if _, err := m.Enqueue(context.Background(), "missing", nil); !errors.Is(err, ErrUnknownType) {
+		t.Fatalf("err = %v, want ErrUnknownType", err)
+	}
+}
+
+type recordingProcessor struct {
+	spans chan *tracing.Span
+}
+
+func (p *recordingProcessor) OnEnd(span *tracing.Span)       { p.spans <- span }
+func (p *recordingProcessor) Shutdown(context.Context) error { return nil }
+
+func TestManagerLinksJobSpanToProducer(t *testing.T) {
+	rec := &recordingProcessor{spans: make(chan *tracing.Span, 2)}
+	tracer, _ := tracing.NewTracer(tracing.TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: rec})
+
+	m := NewManager(Options{Tracer: tracer})
+	m.Register("noop", func(context.Context, *Job) (any, error) { return nil, nil })
+	m.Start()
+	defer m.Stop(context.Background())
+
+	ctx, producer := tracer.Start(context.Background(), "http.request")
+	job, err := m.Enqueue(ctx, "noop", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if job.Metadata[tracing.LinkSpanIDKey] != producer.SpanID {
+		t.Fatalf("metadata = %v, want producer span id", job.Metadata)
+	}
+
+	select {
+	case span := <-rec.spans:
+		if span.Name != "job noop" || len(span.Links) != 1 || span.Links[0].SpanID != producer.SpanID {
+			t.Fatalf("span %q links = %+v", span.Name, span.Links)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("job span not recorded")
+	}
+}
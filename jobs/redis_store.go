@@ -0,0 +1,257 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+)
+
+// RedisStore is a durable Store backed by Redis. Non-terminal jobs are
+// indexed so a restarted Manager resumes them (it implements PendingLister),
+// and claimed with WATCH so Managers sharing it run each job once (it
+// implements Claimer).
+//
+// Keys, under the configured prefix:
+//
+//	<prefix>job:<id>             job JSON
+//	<prefix>idem:<tenant>:<key>  idempotency key -> job ID
+//	<prefix>pending              sorted set of non-terminal job IDs by creation time
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore creates a Redis-backed store. prefix defaults to "jobs:".
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "jobs:"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) jobKey(id string) string { return s.prefix + "job:" + id }
+func (s *RedisStore) pendingKey() string      { return s.prefix + "pending" }
+
+func (s *RedisStore) idemKey(job *Job) string {
+	return s.prefix + "idem:" + job.TenantID + ":" + job.IdempotencyKey
+}
+
+// Create implements Store.
+func (s *RedisStore) Create(ctx context.Context, job *Job) (*Job, error) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: encode job: %w", err)
+	}
+
+	if job.IdempotencyKey != "" {
+		idemKey := s.idemKey(job)
+		ok, err := s.client.SetNX(ctx, idemKey, job.ID, 0).Result()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			id, err := s.client.Get(ctx, idemKey).Result()
+			if err != nil {
+				return nil, err
+			}
+			existing, err := s.Get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return existing, ErrDuplicate
+		}
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.jobKey(job.ID), data, 0)
+		pipe.ZAdd(ctx, s.pendingKey(), &redis.Z{Score: float64(job.CreatedAt.UnixNano()), Member: job.ID})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Update implements Store.
+func (s *RedisStore) Update(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("jobs: encode job: %w", err)
+	}
+	// SET XX only replaces existing jobs.
+	ok, err := s.client.SetXX(ctx, s.jobKey(job.ID), data, 0).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	if job.State.Terminal() {
+		return s.client.ZRem(ctx, s.pendingKey(), job.ID).Err()
+	}
+	return s.client.ZAdd(ctx, s.pendingKey(), &redis.Z{Score: float64(job.CreatedAt.UnixNano()), Member: job.ID}).Err()
+}
+
+// Get implements Store.
+func (s *RedisStore) Get(ctx context.Context, id string) (*Job, error) {
+	data, err := s.client.Get(ctx, s.jobKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("jobs: decode job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+// Delete implements Store.
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	job, err := s.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if job.IdempotencyKey != "" {
+			pipe.Del(ctx, s.idemKey(job))
+		}
+		pipe.Del(ctx, s.jobKey(id))
+		pipe.ZRem(ctx, s.pendingKey(), id)
+		return nil
+	})
+	return err
+}
+
+// Claim implements Claimer.
+func (s *RedisStore) Claim(ctx context.Context, id, owner string, until time.Time) (*Job, error) {
+	key := s.jobKey(id)
+	for {
+		var job Job
+		err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+			data, err := tx.Get(ctx, key).Bytes()
+			if errors.Is(err, redis.Nil) {
+				return ErrNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(data, &job); err != nil {
+				return fmt.Errorf("jobs: decode job %s: %w", id, err)
+			}
+			if err := claim(&job, owner, until, time.Now()); err != nil || job.State.Terminal() {
+				return err
+			}
+			if data, err = json.Marshal(&job); err != nil {
+				return fmt.Errorf("jobs: encode job: %w", err)
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, data, 0)
+				return nil
+			})
+			return err
+		}, key)
+		switch {
+		case errors.Is(err, redis.TxFailedErr):
+			// The job changed between GET and EXEC; read it again.
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		case errors.Is(err, ErrNotFound):
+			return nil, err
+		}
+		return &job, err
+	}
+}
+
+// ListPending implements PendingLister.
+func (s *RedisStore) ListPending(ctx context.Context) ([]*Job, error) {
+	ids, err := s.client.ZRange(ctx, s.pendingKey(), 0, -1).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.jobKey(id)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(values))
+	for i, v := range values {
+		raw, ok := v.(string)
+		if !ok {
+			// Job data is gone; drop the stale index entry.
+			s.client.ZRem(ctx, s.pendingKey(), ids[i])
+			continue
+		}
+		var job Job
+		if err := json.Unmarshal([]byte(raw), &job); err != nil {
+			return nil, fmt.Errorf("jobs: decode job %s: %w", ids[i], err)
+		}
+		if !job.State.Terminal() {
+			jobs = append(jobs, &job)
+		}
+	}
+	return jobs, nil
+}
+
+// RedisDeadLetterQueue is a DeadLetterQueue stored in a Redis hash.
+type RedisDeadLetterQueue struct {
+	client redis.UniversalClient
+	key    string
+}
+
+// NewRedisDeadLetterQueue creates a dead-letter queue stored under
+// <prefix>dead. prefix defaults to "jobs:".
+func NewRedisDeadLetterQueue(client redis.UniversalClient, prefix string) *RedisDeadLetterQueue {
+	if prefix == "" {
+		prefix = "jobs:"
+	}
+	return &RedisDeadLetterQueue{client: client, key: prefix + "dead"}
+}
+
+// Add implements DeadLetterQueue.
+func (q *RedisDeadLetterQueue) Add(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("jobs: encode job: %w", err)
+	}
+	return q.client.HSet(ctx, q.key, job.ID, data).Err()
+}
+
+// List implements DeadLetterQueue.
+func (q *RedisDeadLetterQueue) List(ctx context.Context) ([]*Job, error) {
+	values, err := q.client.HGetAll(ctx, q.key).Result()
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*Job, 0, len(values))
+	for id, raw := range values {
+		var job Job
+		if err := json.Unmarshal([]byte(raw), &job); err != nil {
+			return nil, fmt.Errorf("jobs: decode job %s: %w", id, err)
+		}
+		jobs = append(jobs, &job)
+	}
+	sortByFailure(jobs)
+	return jobs, nil
+}
+
+// Remove implements DeadLetterQueue.
+func (q *RedisDeadLetterQueue) Remove(ctx context.Context, id string) error {
+	return q.client.HDel(ctx, q.key, id).Err()
+}
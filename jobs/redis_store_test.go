@@ -0,0 +1,214 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+func redisTestClient(t *testing.T) (*redis.Client, string) {
+	t.Helper()
+	addr := strings.TrimSpace(os.Getenv("REDIS_TEST_ADDR"))
+	if addr == "" {
+		t.Skip("set REDIS_TEST_ADDR to run redis integration tests")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: os.Getenv("REDIS_TEST_PASSWORD")})
+	prefix := "jobs-test:" + uuid.NewString() + ":"
+	t.Cleanup(func() {
+		ctx := context.Background()
+		keys, _ := client.Keys(ctx, prefix+"*").Result()
+		if len(keys) > 0 {
+			client.Del(ctx, keys...)
+		}
+		client.Close()
+	})
+	return client, prefix
+}
+
+func TestRedisStore_ResumesAfterRestart(t *testing.T) {
+	client, prefix := redisTestClient(t)
+	ctx := context.Background()
+
+	before := NewManager(Options{Store: NewRedisStore(client, prefix)})
+	before.Register("noop", func(context.Context, *Job) (any, error) { return nil, nil })
+	job, err := before.Enqueue(ctx, "noop", map[string]int{"n": 1}, WithIdempotencyKey("k"))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if _, err := before.Enqueue(ctx, "noop", map[string]int{"n": 1}, WithIdempotencyKey("k")); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("duplicate enqueue: err = %v", err)
+	}
+	before.Stop(ctx)
+
+	store := NewRedisStore(client, prefix)
+	pending, err := store.ListPending(ctx)
+	if err != nil || len(pending) != 1 || pending[0].ID != job.ID {
+		t.Fatalf("ListPending = %v, %v", pending, err)
+	}
+
+	after := NewManager(Options{Store: store})
+	after.Register("noop", func(context.Context, *Job) (any, error) { return nil, nil })
+	after.Start()
+	defer after.Stop(ctx)
+	waitForState(t, after, job.ID, StateSucceeded)
+
+	if pending, _ := store.ListPending(ctx); len(pending) != 0 {
+		t.Errorf("finished job still pending: %v", pending)
+	}
+}
+
+func TestRedisDeadLetterQueue(t *testing.T) {
+	client, prefix := redisTestClient(t)
+	ctx := context.Background()
+	dlq := NewRedisDeadLetterQueue(client, prefix)
+
+	if err := dlq.Add(ctx, &Job{ID: "a", State: StateFailed}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	jobs, err := dlq.List(ctx)
+	if err != nil || len(jobs) != 1 || jobs[0].ID != "a" {
+		t.Fatalf("List = %v, %v", jobs, err)
+	}
+	if err := dlq.Remove(ctx, "a"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if jobs, _ := dlq.List(ctx); len(jobs) != 0 {
+		t.Errorf("List after Remove = %v", jobs)
+	}
+}
+
+func miniredisStore(t *testing.T) *RedisStore {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client, "")
+}
+
+func TestRedisStore_Claim(t *testing.T) {
+	store := miniredisStore(t)
+	ctx := context.Background()
+	job, err := store.Create(ctx, &Job{ID: "j1", Type: "noop", State: StatePending, CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	lease := time.Now().Add(time.Minute)
+	if claimed, err := store.Claim(ctx, job.ID, "a", lease); err != nil || claimed.Owner != "a" {
+		t.Fatalf("Claim(a) = %+v, %v", claimed, err)
+	}
+	held, err := store.Claim(ctx, job.ID, "b", lease)
+	if !errors.Is(err, ErrClaimed) || held.Owner != "a" || !held.LeaseUntil.Equal(lease) {
+		t.Fatalf("Claim(b) while held = %+v, %v", held, err)
+	}
+	if _, err := store.Claim(ctx, job.ID, "a", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("renew by owner: %v", err)
+	}
+	if claimed, err := store.Claim(ctx, job.ID, "b", lease); err != nil || claimed.Owner != "b" {
+		t.Fatalf("Claim(b) after lapse = %+v, %v", claimed, err)
+	}
+
+	claimed, _ := store.Get(ctx, job.ID)
+	claimed.State = StateSucceeded
+	if err := store.Update(ctx, claimed); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if done, err := store.Claim(ctx, job.ID, "c", lease); err != nil || done.Owner != "b" {
+		t.Fatalf("Claim(c) of finished job = %+v, %v", done, err)
+	}
+	if _, err := store.Claim(ctx, "missing", "a", lease); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Claim(missing) err = %v", err)
+	}
+}
+
+func TestManagersSharingStoreRunJobOnce(t *testing.T) {
+	store := miniredisStore(t)
+	ctx := context.Background()
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	unblock := func() { releaseOnce.Do(func() { close(release) }) }
+	started := make(chan struct{}, 6)
+	var runs atomic.Int32
+	handler := func(context.Context, *Job) (any, error) {
+		runs.Add(1)
+		started <- struct{}{}
+		<-release
+		return nil, nil
+	}
+
+	// The lease is short so the second manager retries while the first
+	// keeps renewing.
+	first := NewManager(Options{Store: store, Lease: 60 * time.Millisecond})
+	first.Register("slow", handler)
+	var ids []string
+	for i := 0; i < 3; i++ {
+		job, err := first.Enqueue(ctx, "slow", i)
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		ids = append(ids, job.ID)
+	}
+	first.Start()
+	defer first.Stop(ctx)
+	for range ids {
+		<-started
+	}
+
+	// A second instance starting now finds the jobs running.
+	second := NewManager(Options{Store: store, Lease: 60 * time.Millisecond})
+	second.Register("slow", handler)
+	second.Start()
+	defer second.Stop(ctx)
+	defer unblock()
+	time.Sleep(200 * time.Millisecond)
+	if n := runs.Load(); n != 3 {
+		t.Fatalf("runs while held = %d, want 3", n)
+	}
+
+	unblock()
+	for _, id := range ids {
+		waitForState(t, first, id, StateSucceeded)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := runs.Load(); n != 3 {
+		t.Errorf("runs = %d, want 3", n)
+	}
+}
+
+func TestManagerResumesJobAfterLeaseLapses(t *testing.T) {
+	store := miniredisStore(t)
+	ctx := context.Background()
+
+	// A job left running by a manager that crashed, its lease not yet expired.
+	lease := time.Now().Add(100 * time.Millisecond)
+	job := &Job{ID: "j1", Type: "noop", State: StateRunning, Attempts: 1, MaxAttempts: 3,
+		CreatedAt: time.Now(), Owner: "crashed", LeaseUntil: &lease}
+	if _, err := store.Create(ctx, job); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	var ranAt atomic.Int64
+	m := NewManager(Options{Store: store})
+	m.Register("noop", func(context.Context, *Job) (any, error) {
+		ranAt.Store(time.Now().UnixNano())
+		return nil, nil
+	})
+	m.Start()
+	defer m.Stop(ctx)
+
+	done := waitForState(t, m, job.ID, StateSucceeded)
+	if time.Unix(0, ranAt.Load()).Before(lease) {
+		t.Error("job ran before the crashed manager's lease lapsed")
+	}
+	if done.Owner != m.id || done.Attempts != 2 {
+		t.Errorf("owner = %q, attempts = %d", done.Owner, done.Attempts)
+	}
+}
@@ -0,0 +1,142 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store persists jobs and their state transitions.
+type Store interface {
+	// Create stores a new job. If the job carries an idempotency key that is
+	// already known, Create returns the existing job and ErrDuplicate.
+	Create(ctx context.Context, job *Job) (*Job, error)
+	// Update replaces the stored state of an existing job.
+	Update(ctx context.Context, job *Job) error
+	// Get returns the job with the given ID or ErrNotFound.
+	Get(ctx context.Context, id string) (*Job, error)
+	// Delete removes a job and releases its idempotency key. Deleting an
+	// unknown job is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// PendingLister is implemented by durable stores so a restarted Manager can
+// resume the jobs that were queued or running when the previous process
+// stopped.
+type PendingLister interface {
+	// ListPending returns non-terminal jobs, oldest first.
+	ListPending(ctx context.Context) ([]*Job, error)
+}
+
+// Claimer is implemented by stores shared by several Managers so that each
+// job runs on one of them at a time. Without it, every Manager started over
+// the store resumes every pending job, including ones running elsewhere.
+type Claimer interface {
+	// Claim atomically makes owner the job's owner until until and returns
+	// the updated job. While another owner's lease has not expired it
+	// returns the stored job and ErrClaimed; a terminal job is returned
+	// unchanged.
+	Claim(ctx context.Context, id, owner string, until time.Time) (*Job, error)
+}
+
+// claim applies the Claimer rules to job in place.
+func claim(job *Job, owner string, until, now time.Time) error {
+	if job.State.Terminal() {
+		return nil
+	}
+	if job.Owner != "" && job.Owner != owner && job.LeaseUntil != nil && now.Before(*job.LeaseUntil) {
+		return ErrClaimed
+	}
+	job.Owner = owner
+	job.LeaseUntil = &until
+	return nil
+}
+
+// MemoryStore is an in-process Store, suitable for tests and single-node use.
+type MemoryStore struct {
+	mu          sync.RWMutex
+	jobs        map[string]*Job
+	idempotency map[string]string
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs:        make(map[string]*Job),
+		idempotency: make(map[string]string),
+	}
+}
+
+// Create implements Store.
+func (s *MemoryStore) Create(_ context.Context, job *Job) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job.IdempotencyKey != "" {
+		scoped := job.TenantID + "\x00" + job.IdempotencyKey
+		if id, ok := s.idempotency[scoped]; ok {
+			return s.jobs[id].clone(), ErrDuplicate
+		}
+		s.idempotency[scoped] = job.ID
+	}
+	s.jobs[job.ID] = job.clone()
+	return job, nil
+}
+
+// Update implements Store.
+func (s *MemoryStore) Update(_ context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.ID]; !ok {
+		return ErrNotFound
+	}
+	s.jobs[job.ID] = job.clone()
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return job.clone(), nil
+}
+
+// Claim implements Claimer.
+func (s *MemoryStore) Claim(_ context.Context, id, owner string, until time.Time) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if err := claim(job, owner, until, time.Now()); err != nil {
+		return job.clone(), err
+	}
+	return job.clone(), nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil
+	}
+	if job.IdempotencyKey != "" {
+		scoped := job.TenantID + "\x00" + job.IdempotencyKey
+		if s.idempotency[scoped] == id {
+			delete(s.idempotency, scoped)
+		}
+	}
+	delete(s.jobs, id)
+	return nil
+}
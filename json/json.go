@@ -2,6 +2,7 @@ package json
 
 import (
 	"io"
+	"reflect"
 
 	"github.com/creasty/defaults"
 	jsoniter "github.com/json-iterator/go"
@@ -21,7 +22,7 @@ func NewEncoder(w io.Writer) *Encoder {
 
 // Encode 覆盖嵌入的 Encode 方法，添加 defaults.Set 逻辑
 func (e *Encoder) Encode(v any) error {
-	if err := defaults.Set(v); err != nil {
+	if err := setDefaults(v); err != nil {
 		return err
 	}
 	return e.Encoder.Encode(v)
@@ -39,36 +40,46 @@ func NewDecoder(r io.Reader) *Decoder {
 
 // Decode 覆盖嵌入的 Decode 方法，添加 defaults.Set 逻辑
 func (d *Decoder) Decode(v any) error {
-	if err := defaults.Set(v); err != nil {
+	if err := setDefaults(v); err != nil {
 		return err
 	}
 	return d.Decoder.Decode(v)
 }
 
 func Marshal(v any) ([]byte, error) {
-	if err := defaults.Set(v); err != nil {
+	if err := setDefaults(v); err != nil {
 		return nil, err
 	}
 	return jsoniter.Marshal(v)
 }
 
 func MarshalIndent(v any, prefix, indent string) ([]byte, error) {
-	if err := defaults.Set(v); err != nil {
+	if err := setDefaults(v); err != nil {
 		return nil, err
 	}
 	return jsoniter.MarshalIndent(v, prefix, indent)
 }
 
 func MarshalToString(v any) (string, error) {
-	if err := defaults.Set(v); err != nil {
+	if err := setDefaults(v); err != nil {
 		return "", err
 	}
 	return jsoniter.MarshalToString(v)
 }
 
 func Unmarshal(data []byte, v any) error {
-	if err := defaults.Set(v); err != nil {
+	if err := setDefaults(v); err != nil {
 		return err
 	}
 	return jsoniter.Unmarshal(data, v)
 }
+
+// setDefaults 仅对非 nil 的结构体指针应用 default 标签，
+// map、切片、基本类型等其他值直接跳过
+func setDefaults(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return defaults.Set(v)
+}
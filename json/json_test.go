@@ -198,3 +198,21 @@ func TestEncoderSetEscapeHTML(t *testing.T) {
 
 	t.Logf("correctly preserved HTML without escaping: %s", output)
 }
+
+func TestNonStructValuesSkipDefaults(t *testing.T) {
+	data, err := Marshal(map[string]int{"a": 1})
+	if err != nil || string(data) != `{"a":1}` {
+		t.Fatalf("Marshal(map) = %s, %v", data, err)
+	}
+	if _, err := Marshal(testUser{Name: "Dana"}); err != nil {
+		t.Fatalf("Marshal(struct value) returned error: %v", err)
+	}
+
+	var v interface{}
+	if err := Unmarshal([]byte(`{"a":[1,2]}`), &v); err != nil {
+		t.Fatalf("Unmarshal into interface{} returned error: %v", err)
+	}
+	if m, ok := v.(map[string]interface{}); !ok || len(m["a"].([]interface{})) != 2 {
+		t.Fatalf("unexpected decoded value %#v", v)
+	}
+}
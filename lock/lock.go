@@ -0,0 +1,185 @@
+// Package lock provides short-lived mutual exclusion across instances. A
+// lock is a key with an owner token and a TTL: only the owner can refresh
+// or release it, and it frees itself if the owner dies.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+)
+
+var (
+	// ErrNotObtained is returned by Obtain when another owner holds the key.
+	ErrNotObtained = errors.New("lock: not obtained")
+	// ErrNotHeld is returned by Refresh and Release when the lock expired
+	// or was taken over.
+	ErrNotHeld = errors.New("lock: not held")
+)
+
+// Locker obtains locks.
+type Locker interface {
+	// Obtain acquires key for ttl without waiting. It returns ErrNotObtained
+	// when the key is held by someone else.
+	Obtain(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+}
+
+// Lock is a held lock.
+type Lock interface {
+	Key() string
+	// Refresh extends the lock to ttl from now.
+	Refresh(ctx context.Context, ttl time.Duration) error
+	// Release frees the lock. Releasing an expired lock returns ErrNotHeld.
+	Release(ctx context.Context) error
+}
+
+func newToken() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("lock: read random token: %v", err))
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// RedisLocker implements Locker with SET NX PX and owner-checked Lua
+// scripts, which is safe for a single Redis primary. Keys are prefixed.
+type RedisLocker struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisLocker creates a RedisLocker. prefix defaults to "lock:".
+func NewRedisLocker(client redis.UniversalClient, prefix string) *RedisLocker {
+	if prefix == "" {
+		prefix = "lock:"
+	}
+	return &RedisLocker{client: client, prefix: prefix}
+}
+
+var (
+	releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+	refreshScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+)
+
+// Obtain implements Locker.
+func (l *RedisLocker) Obtain(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	token := newToken()
+	ok, err := l.client.SetNX(ctx, l.prefix+key, token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("lock: obtain %s: %w", key, err)
+	}
+	if !ok {
+		return nil, ErrNotObtained
+	}
+	return &redisLock{client: l.client, key: key, fullKey: l.prefix + key, token: token}, nil
+}
+
+type redisLock struct {
+	client  redis.UniversalClient
+	key     string
+	fullKey string
+	token   string
+}
+
+func (l *redisLock) Key() string { return l.key }
+
+func (l *redisLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	n, err := refreshScript.Run(ctx, l.client, []string{l.fullKey}, l.token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("lock: refresh %s: %w", l.key, err)
+	}
+	if n == 0 {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+func (l *redisLock) Release(ctx context.Context) error {
+	n, err := releaseScript.Run(ctx, l.client, []string{l.fullKey}, l.token).Int()
+	if err != nil {
+		return fmt.Errorf("lock: release %s: %w", l.key, err)
+	}
+	if n == 0 {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// MemoryLocker implements Locker within one process, for tests and
+// single-instance deployments.
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	token     string
+	expiresAt time.Time
+}
+
+// NewMemoryLocker creates a MemoryLocker.
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{locks: make(map[string]memoryEntry)}
+}
+
+// Obtain implements Locker.
+func (l *MemoryLocker) Obtain(_ context.Context, key string, ttl time.Duration) (Lock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if e, ok := l.locks[key]; ok && now.Before(e.expiresAt) {
+		return nil, ErrNotObtained
+	}
+	token := newToken()
+	l.locks[key] = memoryEntry{token: token, expiresAt: now.Add(ttl)}
+	return &memoryLock{locker: l, key: key, token: token}, nil
+}
+
+type memoryLock struct {
+	locker *MemoryLocker
+	key    string
+	token  string
+}
+
+func (l *memoryLock) Key() string { return l.key }
+
+func (l *memoryLock) Refresh(_ context.Context, ttl time.Duration) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+	if !l.heldLocked() {
+		return ErrNotHeld
+	}
+	l.locker.locks[l.key] = memoryEntry{token: l.token, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (l *memoryLock) Release(_ context.Context) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+	if !l.heldLocked() {
+		return ErrNotHeld
+	}
+	delete(l.locker.locks, l.key)
+	return nil
+}
+
+func (l *memoryLock) heldLocked() bool {
+	e, ok := l.locker.locks[l.key]
+	return ok && e.token == l.token && time.Now().Before(e.expiresAt)
+}
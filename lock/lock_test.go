@@ -0,0 +1,70 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// testLocker 验证 Locker 的通用语义，expire 使 key 立即失效以模拟持有者超时
+func testLocker(t *testing.T, l Locker, expire func(key string)) {
+	t.Helper()
+	ctx := context.Background()
+	key := "job:" + uuid.NewString()
+
+	first, err := l.Obtain(ctx, key, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Obtain: %v", err)
+	}
+	if _, err := l.Obtain(ctx, key, time.Second); !errors.Is(err, ErrNotObtained) {
+		t.Fatalf("second Obtain err = %v, want ErrNotObtained", err)
+	}
+	if err := first.Refresh(ctx, 300*time.Millisecond); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if err := first.Release(ctx); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := first.Release(ctx); !errors.Is(err, ErrNotHeld) {
+		t.Fatalf("double Release err = %v, want ErrNotHeld", err)
+	}
+
+	// 过期后可被其他持有者获取，原持有者不能再释放
+	stale, err := l.Obtain(ctx, key, 30*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expire(key)
+	next, err := l.Obtain(ctx, key, time.Second)
+	if err != nil {
+		t.Fatalf("Obtain after expiry: %v", err)
+	}
+	if err := stale.Release(ctx); !errors.Is(err, ErrNotHeld) {
+		t.Fatalf("stale Release err = %v, want ErrNotHeld", err)
+	}
+	if err := next.Release(ctx); err != nil {
+		t.Fatalf("stale release must not free the new owner's lock: %v", err)
+	}
+}
+
+func TestMemoryLocker(t *testing.T) {
+	testLocker(t, NewMemoryLocker(), func(string) { time.Sleep(60 * time.Millisecond) })
+}
+
+func TestRedisLocker(t *testing.T) {
+	addr := strings.TrimSpace(os.Getenv("REDIS_TEST_ADDR"))
+	if addr == "" {
+		t.Skip("set REDIS_TEST_ADDR to run redis integration tests")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: os.Getenv("REDIS_TEST_PASSWORD")})
+	defer client.Close()
+	testLocker(t, NewRedisLocker(client, "lock-test:"), func(key string) {
+		client.Del(context.Background(), "lock-test:"+key)
+	})
+}
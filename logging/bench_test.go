@@ -0,0 +1,150 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// discardLogger returns a JSON logger at level writing to io.Discard.
+func discardLogger(level zapcore.Level) Logger {
+	cfg := DefaultConfig()
+	core := zapcore.NewCore(GetEncoder(cfg), zapcore.AddSync(io.Discard), level)
+	return FromZap(zap.New(core))
+}
+
+// withGlobal swaps in logger as the global logger for the duration of tb.
+func withGlobal(tb testing.TB, logger Logger) {
+	tb.Helper()
+	prev := Global()
+	SetGlobal(logger)
+	tb.Cleanup(func() { SetGlobal(prev) })
+}
+
+func benchContext() context.Context {
+	ctx := SetTraceID(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	ctx = SetRequestID(ctx, "req-1")
+	return SetTenantID(ctx, "tenant-1")
+}
+
+// Allocation targets of the package-level helpers: nothing when the level
+// is disabled, and no field slice when enabled once the pool is warm. The
+// one remaining allocation of an enabled entry is CusTimeEncoder formatting
+// the timestamp.
+func TestPackageHelpersAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not meaningful with -race")
+	}
+	withGlobal(t, discardLogger(zapcore.InfoLevel))
+	ctx := benchContext()
+
+	cases := []struct {
+		name string
+		max  float64
+		fn   func()
+	}{
+		{"Debug disabled", 0, func() { Debug("hot path", zap.Int("n", 1)) }},
+		{"DebugCtx disabled", 0, func() { DebugCtx(ctx, "hot path", zap.Int("n", 1)) }},
+		{"Info", 1, func() { Info("hot path", zap.Int("n", 1)) }},
+		{"InfoCtx", 1, func() { InfoCtx(ctx, "hot path", zap.Int("n", 1)) }},
+	}
+	for _, c := range cases {
+		if allocs := testing.AllocsPerRun(1000, c.fn); allocs > c.max {
+			t.Errorf("%s: %v allocs per call, want <= %v", c.name, allocs, c.max)
+		}
+	}
+}
+
+func TestSamplingConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Director = t.TempDir()
+	cfg.LogInTerminal = false
+	cfg.Sampling = &SamplingConfig{
+		Tick:       time.Minute,
+		Initial:    2,
+		Thereafter: 0,
+		Levels:     map[string]SamplingRule{"error": {}},
+	}
+
+	if _, ok := cfg.Sampling.rule(zapcore.InfoLevel); !ok {
+		t.Error("info should be sampled")
+	}
+	if _, ok := cfg.Sampling.rule(zapcore.ErrorLevel); ok {
+		t.Error("error override should disable sampling")
+	}
+	if _, ok := (*SamplingConfig)(nil).rule(zapcore.InfoLevel); ok {
+		t.Error("nil config should not sample")
+	}
+
+	var infos, errs int
+	logger := NewLogger(cfg).Zap().WithOptions(zap.Hooks(func(e zapcore.Entry) error {
+		switch e.Level {
+		case zapcore.InfoLevel:
+			infos++
+		case zapcore.ErrorLevel:
+			errs++
+		}
+		return nil
+	}))
+	for i := 0; i < 10; i++ {
+		logger.Info("repeated")
+		logger.Error("repeated")
+	}
+	if infos != 2 {
+		t.Errorf("expected 2 sampled info entries, got %d", infos)
+	}
+	if errs != 10 {
+		t.Errorf("expected all 10 error entries, got %d", errs)
+	}
+}
+
+func BenchmarkInfoDisabled(b *testing.B) {
+	withGlobal(b, discardLogger(zapcore.WarnLevel))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Info("hot path", zap.Int("n", i), zap.String("k", "v"))
+	}
+}
+
+func BenchmarkInfo(b *testing.B) {
+	withGlobal(b, discardLogger(zapcore.InfoLevel))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Info("hot path", zap.Int("n", i), zap.String("k", "v"))
+	}
+}
+
+func BenchmarkInfoCtx(b *testing.B) {
+	withGlobal(b, discardLogger(zapcore.InfoLevel))
+	ctx := benchContext()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		InfoCtx(ctx, "hot path", zap.Int("n", i), zap.String("k", "v"))
+	}
+}
+
+// BenchmarkWithContextInfo is the InfoCtx equivalent built on WithContext,
+// which clones the logger core on every call.
+func BenchmarkWithContextInfo(b *testing.B) {
+	logger := discardLogger(zapcore.InfoLevel)
+	ctx := benchContext()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		WithContext(logger, ctx).Info("hot path", zap.Int("n", i), zap.String("k", "v"))
+	}
+}
+
+func BenchmarkInfoSampled(b *testing.B) {
+	cfg := DefaultConfig()
+	cfg.Sampling = &SamplingConfig{Initial: 100, Thereafter: 100}
+	core := zapcore.NewCore(GetEncoder(cfg), zapcore.AddSync(io.Discard), zapcore.InfoLevel)
+	withGlobal(b, FromZap(zap.New(sampleCore(cfg, zapcore.InfoLevel, core))))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Info("hot path", zap.Int("n", i), zap.String("k", "v"))
+	}
+}
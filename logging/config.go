@@ -3,9 +3,20 @@ package logging
 import (
 	"strings"
 
+	"github.com/leeforge/framework/config"
 	"go.uber.org/zap/zapcore"
 )
 
+// SectionName is the top-level config key holding the logger Config.
+const SectionName = "log"
+
+func init() {
+	config.RegisterSection(config.SectionSchema[Config]{
+		Name:    SectionName,
+		Default: DefaultConfig,
+	})
+}
+
 // Config represents the logger configuration.
 type Config struct {
 	// Director is the directory where log files will be stored.
@@ -64,6 +75,13 @@ type Config struct {
 
 	// ShowLineNumber enables adding caller information to log entries.
 	ShowLineNumber bool `mapstructure:"show-line-number" json:"showLineNumber" yaml:"show-line-number" toml:"show-line-number"`
+
+	// Sampling drops repeated entries per level; nil logs everything.
+	Sampling *SamplingConfig `mapstructure:"sampling" json:"sampling,omitempty" yaml:"sampling,omitempty" toml:"sampling,omitempty"`
+
+	// Sinks ship entries to remote systems (syslog, Loki, Kafka) in
+	// addition to files and console.
+	Sinks []SinkConfig `mapstructure:"sinks" json:"sinks,omitempty" yaml:"sinks,omitempty" toml:"sinks,omitempty"`
 }
 
 // DefaultConfig returns a Config with sensible defaults.
@@ -18,17 +18,30 @@ const (
 	RequestIDKey ctxKey = "request_id"
 	// UserIDKey is the context key for user ID.
 	UserIDKey ctxKey = "user_id"
+	// TenantIDKey is the context key for tenant ID.
+	TenantIDKey ctxKey = "tenant_id"
 )
 
 // WithContext creates a child logger with fields extracted from the context.
-// It extracts trace_id, span_id, request_id, and user_id if present.
+// It extracts trace_id, span_id, request_id, user_id, and tenant_id if present.
 func WithContext(logger Logger, ctx context.Context) Logger {
 	if ctx == nil {
 		return logger
 	}
 
-	var fields []zap.Field
+	fields := appendContextFields(nil, ctx)
+	if len(fields) == 0 {
+		return logger
+	}
+	return logger.With(fields...)
+}
 
+// appendContextFields appends the trace_id, span_id, request_id, user_id
+// and tenant_id fields present in ctx to fields.
+func appendContextFields(fields []zap.Field, ctx context.Context) []zap.Field {
+	if ctx == nil {
+		return fields
+	}
 	if traceID := GetTraceID(ctx); traceID != "" {
 		fields = append(fields, zap.String("trace_id", traceID))
 	}
@@ -41,11 +54,10 @@ func WithContext(logger Logger, ctx context.Context) Logger {
 	if userID := GetUserID(ctx); userID != "" {
 		fields = append(fields, zap.String("user_id", userID))
 	}
-
-	if len(fields) == 0 {
-		return logger
+	if tenantID := GetTenantID(ctx); tenantID != "" {
+		fields = append(fields, zap.String("tenant_id", tenantID))
 	}
-	return logger.With(fields...)
+	return fields
 }
 
 // GetTraceID extracts trace ID from context.
@@ -100,6 +112,19 @@ func GetUserID(ctx context.Context) string {
 	return ""
 }
 
+// GetTenantID extracts tenant ID from context.
+func GetTenantID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if v := ctx.Value(TenantIDKey); v != nil {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
 // SetTraceID adds trace ID to context.
 func SetTraceID(ctx context.Context, traceID string) context.Context {
 	return context.WithValue(ctx, TraceIDKey, traceID)
@@ -120,6 +145,11 @@ func SetUserID(ctx context.Context, userID string) context.Context {
 	return context.WithValue(ctx, UserIDKey, userID)
 }
 
+// SetTenantID adds tenant ID to context.
+func SetTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, TenantIDKey, tenantID)
+}
+
 // loggerKey is the context key for storing a logger in context.
 type loggerKey struct{}
 
@@ -54,11 +54,14 @@ func getLevelPriority(level zapcore.Level) zap.LevelEnablerFunc {
 	}
 }
 
-// getZapCores creates zapcore.Core instances for all levels >= config.Level.
+// getZapCores creates zapcore.Core instances for all levels >= config.Level,
+// sampled per level as configured by config.Sampling, followed by the cores
+// of config.Sinks.
 func getZapCores(config Config) []zapcore.Core {
 	cores := make([]zapcore.Core, 0, 7)
 	for level := config.TransportLevel(); level <= zapcore.FatalLevel; level++ {
-		cores = append(cores, getEncoderCore(config, level, getLevelPriority(level)))
+		core := getEncoderCore(config, level, getLevelPriority(level))
+		cores = append(cores, sampleCore(config, level, core))
 	}
-	return cores
+	return append(cores, getSinkCores(config)...)
 }
@@ -7,7 +7,7 @@ import (
 // Factory creates and manages named loggers.
 type Factory struct {
 	config  Config
-	loggers sync.Map // map[string]Logger
+	loggers sync.Map // map[string]*namedLogger
 }
 
 // NewFactory creates a new Factory with the given config.
@@ -20,20 +20,10 @@ func NewFactory(config Config) *Factory {
 
 // GetLogger returns a named logger, creating it if necessary.
 // Named loggers share the same configuration but have different names
-// for identification in log output.
+// for identification in log output. Each has its own level, which
+// SetLevel changes at runtime.
 func (f *Factory) GetLogger(name string) Logger {
-	// Fast path: check if logger exists
-	if v, ok := f.loggers.Load(name); ok {
-		return v.(Logger)
-	}
-
-	// Slow path: create new logger
-	logger := NewLogger(f.config).Named(name)
-	actual, loaded := f.loggers.LoadOrStore(name, logger)
-	if loaded {
-		return actual.(Logger)
-	}
-	return logger
+	return f.named(name).logger
 }
 
 // Config returns a copy of the factory's configuration.
@@ -1,45 +1,56 @@
 package logging
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+// global holds the global logger together with the *zap.Logger the
+// package-level helpers write through, so the hot path is a single atomic
+// load with no lock and no per-call logger allocation.
+type global struct {
+	logger Logger
+	// zl skips one more frame than logger.Zap(), the package-level helper,
+	// so caller information points at the helper's caller.
+	zl *zap.Logger
+}
+
 var (
-	globalLogger Logger
-	globalMu     sync.RWMutex
+	globalLogger atomic.Pointer[global]
 	once         sync.Once
 )
 
 // initGlobal initializes the global logger with default config.
 func initGlobal() {
 	once.Do(func() {
-		globalLogger = NewLogger(DefaultConfig())
+		globalLogger.CompareAndSwap(nil, newGlobal(NewLogger(DefaultConfig())))
 	})
 }
 
-// Global returns the global logger instance.
-func Global() Logger {
-	globalMu.RLock()
-	if globalLogger != nil {
-		defer globalMu.RUnlock()
-		return globalLogger
-	}
-	globalMu.RUnlock()
+func newGlobal(logger Logger) *global {
+	return &global{logger: logger, zl: logger.Zap().WithOptions(zap.AddCallerSkip(1))}
+}
 
+func loadGlobal() *global {
+	if g := globalLogger.Load(); g != nil {
+		return g
+	}
 	initGlobal()
+	return globalLogger.Load()
+}
 
-	globalMu.RLock()
-	defer globalMu.RUnlock()
-	return globalLogger
+// Global returns the global logger instance.
+func Global() Logger {
+	return loadGlobal().logger
 }
 
 // SetGlobal replaces the global logger with the given logger.
 func SetGlobal(logger Logger) {
-	globalMu.Lock()
-	defer globalMu.Unlock()
-	globalLogger = logger
+	globalLogger.Store(newGlobal(logger))
 }
 
 // Init initializes the global logger with the given config.
@@ -47,26 +58,61 @@ func Init(config Config) {
 	SetGlobal(NewLogger(config))
 }
 
+// fieldsPool recycles the field slices the package-level helpers hand to
+// zap. Copying the call's fields into a pooled slice keeps the caller's
+// variadic slice from escaping, so a helper call allocates nothing for its
+// fields, and the *Ctx variants add the context fields to the same slice.
+var fieldsPool = sync.Pool{
+	New: func() any {
+		s := make([]zap.Field, 0, 16)
+		return &s
+	},
+}
+
+// maxPooledFields keeps unusually large slices out of the pool.
+const maxPooledFields = 64
+
+// logAt writes through the global logger, prepending the trace fields of ctx
+// (see WithContext) when ctx is not nil. Disabled levels return after the
+// level check. Cores must not retain the fields slice after Write returns;
+// zap's own cores encode synchronously.
+func logAt(ctx context.Context, level zapcore.Level, msg string, fields []zap.Field) {
+	ce := loadGlobal().zl.Check(level, msg)
+	if ce == nil {
+		return
+	}
+	buf := fieldsPool.Get().(*[]zap.Field)
+	all := appendContextFields((*buf)[:0], ctx)
+	all = append(all, fields...)
+	ce.Write(all...)
+
+	if cap(all) <= maxPooledFields {
+		clear(all)
+		*buf = all[:0]
+		fieldsPool.Put(buf)
+	}
+}
+
 // Package-level convenience functions that delegate to the global logger.
 
 // Debug logs a message at DebugLevel using the global logger.
 func Debug(msg string, fields ...zap.Field) {
-	Global().Debug(msg, fields...)
+	logAt(nil, zapcore.DebugLevel, msg, fields)
 }
 
 // Info logs a message at InfoLevel using the global logger.
 func Info(msg string, fields ...zap.Field) {
-	Global().Info(msg, fields...)
+	logAt(nil, zapcore.InfoLevel, msg, fields)
 }
 
 // Warn logs a message at WarnLevel using the global logger.
 func Warn(msg string, fields ...zap.Field) {
-	Global().Warn(msg, fields...)
+	logAt(nil, zapcore.WarnLevel, msg, fields)
 }
 
 // Error logs a message at ErrorLevel using the global logger.
 func Error(msg string, fields ...zap.Field) {
-	Global().Error(msg, fields...)
+	logAt(nil, zapcore.ErrorLevel, msg, fields)
 }
 
 // Fatal logs a message at FatalLevel using the global logger and exits.
@@ -74,6 +120,30 @@ func Fatal(msg string, fields ...zap.Field) {
 	Global().Fatal(msg, fields...)
 }
 
+// DebugCtx logs a message at DebugLevel using the global logger, adding the
+// trace fields of ctx without creating a child logger.
+func DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	logAt(ctx, zapcore.DebugLevel, msg, fields)
+}
+
+// InfoCtx logs a message at InfoLevel using the global logger, adding the
+// trace fields of ctx without creating a child logger.
+func InfoCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	logAt(ctx, zapcore.InfoLevel, msg, fields)
+}
+
+// WarnCtx logs a message at WarnLevel using the global logger, adding the
+// trace fields of ctx without creating a child logger.
+func WarnCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	logAt(ctx, zapcore.WarnLevel, msg, fields)
+}
+
+// ErrorCtx logs a message at ErrorLevel using the global logger, adding the
+// trace fields of ctx without creating a child logger.
+func ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	logAt(ctx, zapcore.ErrorLevel, msg, fields)
+}
+
 // Debugf logs a formatted message at DebugLevel using the global logger.
 func Debugf(format string, args ...any) {
 	Global().Debugf(format, args...)
@@ -0,0 +1,91 @@
+// Package kafkasink adds the "kafka" log sink. Import it for its side
+// effect to enable SinkConfig{Type: "kafka"}:
+//
+//	import _ "github.com/leeforge/framework/logging/kafkasink"
+//
+// It lives outside package logging so services that do not ship logs to
+// Kafka do not link the client.
+package kafkasink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leeforge/framework/logging"
+	"github.com/segmentio/kafka-go"
+)
+
+// HeaderLevel carries the entry level, letting consumers filter without
+// decoding the value.
+const HeaderLevel = "level"
+
+func init() {
+	logging.RegisterSink("kafka", New)
+}
+
+// Writer is a logging.BatchWriter producing one message per entry.
+type Writer struct {
+	writer   *kafka.Writer
+	keyField string
+}
+
+var _ logging.BatchWriter = (*Writer)(nil)
+
+// New creates a Writer from config.Kafka.
+func New(config logging.SinkConfig) (logging.BatchWriter, error) {
+	c := config.Kafka
+	if len(c.Brokers) == 0 {
+		return nil, errors.New("kafka sink requires brokers")
+	}
+	if c.Topic == "" {
+		return nil, errors.New("kafka sink requires a topic")
+	}
+	batchSize := config.BatchSize
+	if batchSize <= 0 {
+		batchSize = logging.DefaultSinkBatchSize
+	}
+	return &Writer{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(c.Brokers...),
+			Topic:    c.Topic,
+			Balancer: &kafka.Hash{},
+			// batches are already formed by the sink
+			BatchSize:    batchSize,
+			BatchTimeout: 10 * time.Millisecond,
+			RequiredAcks: kafka.RequireOne,
+		},
+		keyField: c.KeyField,
+	}, nil
+}
+
+// WriteBatch implements logging.BatchWriter.
+func (w *Writer) WriteBatch(ctx context.Context, entries []logging.SinkEntry) error {
+	msgs := make([]kafka.Message, len(entries))
+	for i, e := range entries {
+		msgs[i] = w.message(e)
+	}
+	return w.writer.WriteMessages(ctx, msgs...)
+}
+
+// message builds the Kafka message of e, keyed by the KeyField value when
+// the entry has one.
+func (w *Writer) message(e logging.SinkEntry) kafka.Message {
+	msg := kafka.Message{
+		Value:   e.Line,
+		Time:    e.Time,
+		Headers: []kafka.Header{{Key: HeaderLevel, Value: []byte(e.Level.String())}},
+	}
+	if w.keyField != "" {
+		if v, ok := e.Fields()[w.keyField]; ok && v != nil {
+			msg.Key = []byte(fmt.Sprint(v))
+		}
+	}
+	return msg
+}
+
+// Close implements logging.BatchWriter.
+func (w *Writer) Close() error {
+	return w.writer.Close()
+}
@@ -0,0 +1,83 @@
+package kafkasink
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/logging"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestMessage(t *testing.T) {
+	bw, err := New(logging.SinkConfig{Kafka: logging.KafkaConfig{Brokers: []string{"127.0.0.1:1"}, Topic: "logs", KeyField: "tenant_id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bw.Close()
+	w := bw.(*Writer)
+
+	now := time.Now()
+	msg := w.message(logging.SinkEntry{Time: now, Level: zapcore.WarnLevel, Line: []byte(`{"msg":"hi","tenant_id":"t-1"}`)})
+	if string(msg.Key) != "t-1" || !msg.Time.Equal(now) {
+		t.Errorf("message = %+v", msg)
+	}
+	if len(msg.Headers) != 1 || string(msg.Headers[0].Value) != "warn" {
+		t.Errorf("headers = %+v", msg.Headers)
+	}
+
+	msg = w.message(logging.SinkEntry{Level: zapcore.InfoLevel, Line: []byte(`{"msg":"hi"}`)})
+	if msg.Key != nil {
+		t.Errorf("entries without the key field should be unkeyed, got %q", msg.Key)
+	}
+}
+
+func TestNewRequiresBrokersAndTopic(t *testing.T) {
+	if _, err := New(logging.SinkConfig{Kafka: logging.KafkaConfig{Topic: "logs"}}); err == nil {
+		t.Error("expected an error without brokers")
+	}
+	if _, err := New(logging.SinkConfig{Kafka: logging.KafkaConfig{Brokers: []string{"127.0.0.1:1"}}}); err == nil {
+		t.Error("expected an error without a topic")
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	brokers := strings.TrimSpace(os.Getenv("KAFKA_TEST_BROKERS"))
+	if brokers == "" {
+		t.Skip("set KAFKA_TEST_BROKERS to run kafka integration tests")
+	}
+	topic := "test-logs-" + uuid.NewString()[:8]
+	conn, err := kafka.Dial("tcp", strings.Split(brokers, ",")[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.CreateTopics(kafka.TopicConfig{Topic: topic, NumPartitions: 1, ReplicationFactor: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(logging.SinkConfig{Kafka: logging.KafkaConfig{Brokers: strings.Split(brokers, ","), Topic: topic}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := w.WriteBatch(ctx, []logging.SinkEntry{{Time: time.Now(), Level: zapcore.InfoLevel, Line: []byte(`{"msg":"shipped"}`)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{Brokers: strings.Split(brokers, ","), Topic: topic})
+	defer r.Close()
+	msg, err := r.ReadMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Value) != `{"msg":"shipped"}` {
+		t.Errorf("value = %s", msg.Value)
+	}
+}
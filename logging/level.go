@@ -0,0 +1,237 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LoggerLevel is the current level of a named logger.
+type LoggerLevel struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+	// ExpiresAt is when a temporary level reverts to the configured one.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// namedLogger is a Factory logger whose level can change at runtime.
+type namedLogger struct {
+	logger Logger
+	level  zap.AtomicLevel
+
+	mu      sync.Mutex
+	expires time.Time
+	revert  *time.Timer
+	gen     uint64 // bumped on every change; stale reverts are ignored
+}
+
+// atomicLevelCore gates core with a level that can change at runtime. The
+// wrapped core is built for all levels, so lowering the level below the
+// configured one takes effect without rebuilding the logger.
+type atomicLevelCore struct {
+	zapcore.Core
+	level zap.AtomicLevel
+}
+
+func (c *atomicLevelCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level) && c.Core.Enabled(level)
+}
+
+// Level lets zapcore.LevelOf and (*zap.Logger).Level report the current level.
+func (c *atomicLevelCore) Level() zapcore.Level {
+	return c.level.Level()
+}
+
+func (c *atomicLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &atomicLevelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *atomicLevelCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.level.Enabled(entry.Level) {
+		return ce
+	}
+	return c.Core.Check(entry, ce)
+}
+
+// newNamedLogger builds the logger for name at the configured level.
+func (f *Factory) newNamedLogger(name string) *namedLogger {
+	config := f.config
+	config.Level = zapcore.DebugLevel.String()
+	level := zap.NewAtomicLevelAt(f.config.TransportLevel())
+
+	zl := NewLogger(config).Zap().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &atomicLevelCore{Core: core, level: level}
+	}))
+	return &namedLogger{logger: newZapLogger(zl).Named(name), level: level}
+}
+
+func (f *Factory) named(name string) *namedLogger {
+	if v, ok := f.loggers.Load(name); ok {
+		return v.(*namedLogger)
+	}
+	actual, _ := f.loggers.LoadOrStore(name, f.newNamedLogger(name))
+	return actual.(*namedLogger)
+}
+
+// Level returns the current level of the named logger.
+func (f *Factory) Level(name string) zapcore.Level {
+	return f.named(name).level.Level()
+}
+
+// SetLevel changes the level of the named logger, creating it if needed.
+// With ttl > 0 the level reverts to the configured one once ttl elapses, so
+// debug logging enabled in production does not stay on by accident.
+func (f *Factory) SetLevel(name string, level zapcore.Level, ttl time.Duration) LoggerLevel {
+	n := f.named(name)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.stopRevert()
+	n.level.SetLevel(level)
+	if ttl > 0 {
+		gen := n.gen
+		n.expires = time.Now().Add(ttl)
+		n.revert = time.AfterFunc(ttl, func() {
+			n.mu.Lock()
+			defer n.mu.Unlock()
+			if n.gen == gen {
+				n.stopRevert()
+				n.level.SetLevel(f.config.TransportLevel())
+			}
+		})
+	}
+	return n.snapshot(name)
+}
+
+// ResetLevel restores the configured level of the named logger.
+func (f *Factory) ResetLevel(name string) {
+	v, ok := f.loggers.Load(name)
+	if !ok {
+		return
+	}
+	n := v.(*namedLogger)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stopRevert()
+	n.level.SetLevel(f.config.TransportLevel())
+}
+
+// Levels returns the levels of all loggers created by the factory, ordered
+// by name.
+func (f *Factory) Levels() []LoggerLevel {
+	var out []LoggerLevel
+	f.loggers.Range(func(key, value any) bool {
+		n := value.(*namedLogger)
+		n.mu.Lock()
+		out = append(out, n.snapshot(key.(string)))
+		n.mu.Unlock()
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// stopRevert cancels a pending revert. n.mu must be held.
+func (n *namedLogger) stopRevert() {
+	n.gen++
+	if n.revert != nil {
+		n.revert.Stop()
+		n.revert = nil
+	}
+	n.expires = time.Time{}
+}
+
+// snapshot reports the level of n. n.mu must be held.
+func (n *namedLogger) snapshot(name string) LoggerLevel {
+	l := LoggerLevel{Name: name, Level: n.level.Level().String()}
+	if !n.expires.IsZero() {
+		expires := n.expires
+		l.ExpiresAt = &expires
+	}
+	return l
+}
+
+// LevelHandlerOptions configures Factory.LevelHandler.
+type LevelHandlerOptions struct {
+	// Authorize guards level changes (PUT, POST, DELETE). When nil, or when
+	// it returns false, changes are rejected with 403; GET is always
+	// allowed.
+	Authorize func(r *http.Request) bool
+
+	// MaxTTL caps the lifetime of a change. A change without ttl then uses
+	// MaxTTL, so levels always revert. Zero allows permanent changes.
+	MaxTTL time.Duration
+}
+
+// loggerLevelRequest is the body of a PUT to the level handler.
+type loggerLevelRequest struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+	TTL   string `json:"ttl"`
+}
+
+// LevelHandler returns the runtime level endpoint, usually mounted at
+// /debug/loglevel:
+//
+//	GET                                  list logger levels
+//	PUT    {"name","level","ttl":"15m"}  change a level, reverting after ttl
+//	DELETE ?name=...                     restore the configured level
+func (f *Factory) LevelHandler(opts LevelHandlerOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && (opts.Authorize == nil || !opts.Authorize(r)) {
+			writeJSONError(w, http.StatusForbidden, "level changes are not authorized")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, f.Levels())
+
+		case http.MethodPut, http.MethodPost:
+			var req loggerLevelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+				return
+			}
+			if req.Name == "" {
+				writeJSONError(w, http.StatusBadRequest, "name is required")
+				return
+			}
+			level, err := zapcore.ParseLevel(req.Level)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			var ttl time.Duration
+			if req.TTL != "" {
+				if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl < 0 {
+					writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid ttl %q", req.TTL))
+					return
+				}
+			}
+			if opts.MaxTTL > 0 && (ttl == 0 || ttl > opts.MaxTTL) {
+				ttl = opts.MaxTTL
+			}
+			writeJSON(w, http.StatusOK, f.SetLevel(req.Name, level, ttl))
+
+		case http.MethodDelete:
+			name := r.URL.Query().Get("name")
+			if name == "" {
+				writeJSONError(w, http.StatusBadRequest, "name is required")
+				return
+			}
+			f.ResetLevel(name)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	})
+}
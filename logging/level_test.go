@@ -0,0 +1,136 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func newLevelTestFactory(t *testing.T) *Factory {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Director = t.TempDir()
+	cfg.LogInTerminal = false
+	return NewFactory(cfg)
+}
+
+func TestFactorySetLevel(t *testing.T) {
+	f := newLevelTestFactory(t)
+	logger := f.GetLogger("orders")
+	child := logger.With() // children share the level
+
+	if logger.Zap().Core().Enabled(zapcore.DebugLevel) {
+		t.Fatal("debug should be disabled at the configured info level")
+	}
+
+	got := f.SetLevel("orders", zapcore.DebugLevel, 0)
+	if got.Level != "debug" || got.ExpiresAt != nil {
+		t.Errorf("unexpected level %+v", got)
+	}
+	if !logger.Zap().Core().Enabled(zapcore.DebugLevel) || !child.Zap().Core().Enabled(zapcore.DebugLevel) {
+		t.Error("debug should be enabled after SetLevel")
+	}
+	if f.GetLogger("payments").Zap().Core().Enabled(zapcore.DebugLevel) {
+		t.Error("other loggers must keep their level")
+	}
+	if lvl := logger.Zap().Level(); lvl != zapcore.DebugLevel {
+		t.Errorf("expected zap logger to report debug, got %s", lvl)
+	}
+
+	f.SetLevel("orders", zapcore.ErrorLevel, 0)
+	if logger.Zap().Core().Enabled(zapcore.WarnLevel) {
+		t.Error("raising the level should silence warn")
+	}
+
+	f.ResetLevel("orders")
+	if f.Level("orders") != zapcore.InfoLevel {
+		t.Errorf("expected info after reset, got %s", f.Level("orders"))
+	}
+}
+
+func TestFactorySetLevelTTL(t *testing.T) {
+	f := newLevelTestFactory(t)
+
+	got := f.SetLevel("orders", zapcore.DebugLevel, 20*time.Millisecond)
+	if got.ExpiresAt == nil {
+		t.Fatal("expected expiry")
+	}
+	// a later change without ttl cancels the pending revert
+	f.SetLevel("payments", zapcore.DebugLevel, 20*time.Millisecond)
+	f.SetLevel("payments", zapcore.WarnLevel, 0)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for f.Level("orders") != zapcore.InfoLevel {
+		if time.Now().After(deadline) {
+			t.Fatal("level did not revert after ttl")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if f.Level("payments") != zapcore.WarnLevel {
+		t.Errorf("stale revert overrode a newer level: %s", f.Level("payments"))
+	}
+}
+
+func TestFactoryLevelHandler(t *testing.T) {
+	f := newLevelTestFactory(t)
+	f.GetLogger("orders")
+	handler := f.LevelHandler(LevelHandlerOptions{
+		Authorize: func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer admin" },
+		MaxTTL:    time.Hour,
+	})
+
+	do := func(method, target, body string, authorized bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		if authorized {
+			r.Header.Set("Authorization", "Bearer admin")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(http.MethodGet, "/debug/loglevel", "", false)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET: %d", w.Code)
+	}
+	var levels []LoggerLevel
+	if err := json.Unmarshal(w.Body.Bytes(), &levels); err != nil || len(levels) != 1 || levels[0].Level != "info" {
+		t.Fatalf("unexpected levels %s", w.Body.String())
+	}
+
+	if w := do(http.MethodPut, "/debug/loglevel", `{"name":"orders","level":"debug"}`, false); w.Code != http.StatusForbidden {
+		t.Errorf("unauthorized PUT: %d", w.Code)
+	}
+	if f.Level("orders") != zapcore.InfoLevel {
+		t.Error("unauthorized PUT must not change the level")
+	}
+
+	w = do(http.MethodPut, "/debug/loglevel", `{"name":"orders","level":"debug"}`, true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT: %d %s", w.Code, w.Body.String())
+	}
+	var set LoggerLevel
+	if err := json.Unmarshal(w.Body.Bytes(), &set); err != nil || set.ExpiresAt == nil {
+		t.Fatalf("MaxTTL should apply to changes without ttl: %s", w.Body.String())
+	}
+	if f.Level("orders") != zapcore.DebugLevel {
+		t.Error("PUT did not change the level")
+	}
+
+	if w := do(http.MethodPut, "/debug/loglevel", `{"name":"orders","level":"loud"}`, true); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid level: %d", w.Code)
+	}
+
+	if w := do(http.MethodDelete, "/debug/loglevel?name=orders", "", true); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE: %d", w.Code)
+	}
+	if f.Level("orders") != zapcore.InfoLevel {
+		t.Error("DELETE did not restore the level")
+	}
+}
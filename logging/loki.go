@@ -0,0 +1,155 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LokiConfig configures the "loki" sink, which pushes entries to the
+// Grafana Loki push API.
+type LokiConfig struct {
+	// URL is the Loki base URL, e.g. "http://loki:3100". The push path
+	// /loki/api/v1/push is appended unless already present.
+	URL string `mapstructure:"url" json:"url,omitempty" yaml:"url,omitempty" toml:"url,omitempty"`
+
+	// Labels are static stream labels, e.g. {"app": "api", "env": "prod"}.
+	Labels map[string]string `mapstructure:"labels" json:"labels,omitempty" yaml:"labels,omitempty" toml:"labels,omitempty"`
+
+	// LabelFields maps entry fields to stream labels, e.g.
+	// {"tenant_id": "tenant"}. The level is always a label. Keep the
+	// mapped fields low-cardinality: every label set is a Loki stream.
+	LabelFields map[string]string `mapstructure:"label-fields" json:"labelFields,omitempty" yaml:"label-fields,omitempty" toml:"label-fields,omitempty"`
+
+	// TenantID is sent as X-Scope-OrgID for multi-tenant Loki.
+	TenantID string `mapstructure:"tenant-id" json:"tenantId,omitempty" yaml:"tenant-id,omitempty" toml:"tenant-id,omitempty"`
+
+	// Username and Password enable basic auth.
+	Username string `mapstructure:"username" json:"username,omitempty" yaml:"username,omitempty" toml:"username,omitempty"`
+	Password string `mapstructure:"password" json:"password,omitempty" yaml:"password,omitempty" toml:"password,omitempty"`
+}
+
+const lokiPushPath = "/loki/api/v1/push"
+
+// lokiWriter groups entries into streams by label set and pushes them in
+// one request per batch.
+type lokiWriter struct {
+	url    string
+	config LokiConfig
+	client *http.Client
+}
+
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func newLokiWriter(config SinkConfig) (BatchWriter, error) {
+	c := config.Loki
+	if c.URL == "" {
+		return nil, fmt.Errorf("loki sink requires a url")
+	}
+	url := strings.TrimRight(c.URL, "/")
+	if !strings.HasSuffix(url, lokiPushPath) {
+		url += lokiPushPath
+	}
+	return &lokiWriter{url: url, config: c, client: &http.Client{}}, nil
+}
+
+// labels returns the stream labels of e.
+func (w *lokiWriter) labels(e SinkEntry) map[string]string {
+	labels := make(map[string]string, len(w.config.Labels)+len(w.config.LabelFields)+1)
+	for k, v := range w.config.Labels {
+		labels[k] = v
+	}
+	labels["level"] = e.Level.String()
+	if len(w.config.LabelFields) == 0 {
+		return labels
+	}
+	fields := e.Fields()
+	for field, label := range w.config.LabelFields {
+		if v, ok := fields[field]; ok && v != nil {
+			labels[label] = fmt.Sprint(v)
+		}
+	}
+	return labels
+}
+
+// streamKey identifies a label set independent of map order.
+func streamKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// WriteBatch implements BatchWriter.
+func (w *lokiWriter) WriteBatch(ctx context.Context, entries []SinkEntry) error {
+	var push lokiPush
+	index := map[string]int{}
+	for _, e := range entries {
+		labels := w.labels(e)
+		key := streamKey(labels)
+		i, ok := index[key]
+		if !ok {
+			i = len(push.Streams)
+			index[key] = i
+			push.Streams = append(push.Streams, lokiStream{Stream: labels})
+		}
+		push.Streams[i].Values = append(push.Streams[i].Values,
+			[2]string{strconv.FormatInt(e.Time.UnixNano(), 10), string(e.Line)})
+	}
+
+	body, err := json.Marshal(push)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.config.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", w.config.TenantID)
+	}
+	if w.config.Username != "" {
+		req.SetBasicAuth(w.config.Username, w.config.Password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("loki push: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Close implements BatchWriter.
+func (w *lokiWriter) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/leeforge/framework/request"
 	"go.uber.org/zap"
 )
 
@@ -28,6 +29,7 @@ func HTTPMiddleware(logger Logger) func(http.Handler) http.Handler {
 				zap.String("path", r.URL.Path),
 				zap.String("query", r.URL.RawQuery),
 				zap.String("remote_addr", r.RemoteAddr),
+				zap.String("client_ip", request.GetClientIP(r)),
 				zap.String("user_agent", r.UserAgent()),
 			)
 
@@ -0,0 +1,7 @@
+//go:build race
+
+package logging
+
+// raceEnabled reports whether tests run with -race, which makes sync.Pool
+// drop items at random and so breaks allocation counts.
+const raceEnabled = true
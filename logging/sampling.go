@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingConfig caps repeated log entries per level. Within each Tick, the
+// first Initial entries with the same level and message are logged, then
+// every Thereafter-th one; the rest are dropped. This bounds the cost of hot
+// loops that log the same message while keeping distinct messages intact.
+type SamplingConfig struct {
+	// Tick is the sampling window. Defaults to one second.
+	Tick time.Duration `mapstructure:"tick" json:"tick" yaml:"tick" toml:"tick"`
+
+	// Initial is the number of entries logged per window before sampling.
+	// Zero or negative disables sampling for levels without an override.
+	Initial int `mapstructure:"initial" json:"initial" yaml:"initial" toml:"initial"`
+
+	// Thereafter logs every Thereafter-th entry after Initial; zero drops
+	// all of them.
+	Thereafter int `mapstructure:"thereafter" json:"thereafter" yaml:"thereafter" toml:"thereafter"`
+
+	// Levels overrides Initial and Thereafter per level name (debug, info,
+	// warn, error, ...). An override with Initial <= 0 turns sampling off
+	// for that level, e.g. to never drop errors.
+	Levels map[string]SamplingRule `mapstructure:"levels" json:"levels" yaml:"levels" toml:"levels"`
+}
+
+// SamplingRule is the sampling setting of a single level.
+type SamplingRule struct {
+	Initial    int `mapstructure:"initial" json:"initial" yaml:"initial" toml:"initial"`
+	Thereafter int `mapstructure:"thereafter" json:"thereafter" yaml:"thereafter" toml:"thereafter"`
+}
+
+// rule returns the sampling rule for level, or false if entries of level
+// are not sampled.
+func (s *SamplingConfig) rule(level zapcore.Level) (SamplingRule, bool) {
+	if s == nil {
+		return SamplingRule{}, false
+	}
+	r := SamplingRule{Initial: s.Initial, Thereafter: s.Thereafter}
+	for name, override := range s.Levels {
+		if strings.EqualFold(name, level.String()) {
+			r = override
+			break
+		}
+	}
+	return r, r.Initial > 0
+}
+
+// sampleCore wraps core in a sampler if config samples level.
+func sampleCore(config Config, level zapcore.Level, core zapcore.Core) zapcore.Core {
+	rule, ok := config.Sampling.rule(level)
+	if !ok {
+		return core
+	}
+	tick := config.Sampling.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	return zapcore.NewSamplerWithOptions(core, tick, rule.Initial, rule.Thereafter)
+}
@@ -0,0 +1,423 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+	"go.uber.org/zap/zapcore"
+)
+
+// Metric names reported by sinks, labelled with sink=<name>.
+const (
+	MetricSinkWritten = "log_sink_written_total"
+	MetricSinkDropped = "log_sink_dropped_total"
+	MetricSinkFailed  = "log_sink_failed_total"
+)
+
+// Sink defaults.
+const (
+	DefaultSinkBufferSize    = 4096
+	DefaultSinkBatchSize     = 500
+	DefaultSinkFlushInterval = time.Second
+	DefaultSinkTimeout       = 10 * time.Second
+)
+
+// SinkConfig ships log entries to a remote system in addition to the
+// files and console. Entries are JSON encoded, buffered in memory and sent
+// in batches from a background goroutine; when the buffer is full new
+// entries are dropped and counted rather than blocking the caller.
+type SinkConfig struct {
+	// Type selects the sink: "syslog", "loki", or "kafka" (requires
+	// importing logging/kafkasink). Custom types are added with RegisterSink.
+	Type string `mapstructure:"type" json:"type" yaml:"type" toml:"type"`
+
+	// Name labels the sink's metrics. Defaults to Type.
+	Name string `mapstructure:"name" json:"name,omitempty" yaml:"name,omitempty" toml:"name,omitempty"`
+
+	// Level is the minimum level shipped; the logger's Level still applies.
+	// Empty ships every level the logger writes.
+	Level string `mapstructure:"level" json:"level,omitempty" yaml:"level,omitempty" toml:"level,omitempty"`
+
+	// BufferSize is the number of entries held while the sink is slow or
+	// unreachable. Defaults to DefaultSinkBufferSize.
+	BufferSize int `mapstructure:"buffer-size" json:"bufferSize,omitempty" yaml:"buffer-size,omitempty" toml:"buffer-size,omitempty"`
+
+	// BatchSize is the maximum number of entries per send. Defaults to
+	// DefaultSinkBatchSize.
+	BatchSize int `mapstructure:"batch-size" json:"batchSize,omitempty" yaml:"batch-size,omitempty" toml:"batch-size,omitempty"`
+
+	// FlushInterval sends partial batches. Defaults to DefaultSinkFlushInterval.
+	FlushInterval time.Duration `mapstructure:"flush-interval" json:"flushInterval,omitempty" yaml:"flush-interval,omitempty" toml:"flush-interval,omitempty"`
+
+	// Timeout bounds a single send. Defaults to DefaultSinkTimeout.
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout,omitempty" yaml:"timeout,omitempty" toml:"timeout,omitempty"`
+
+	Syslog SyslogConfig `mapstructure:"syslog" json:"syslog,omitempty" yaml:"syslog,omitempty" toml:"syslog,omitempty"`
+	Loki   LokiConfig   `mapstructure:"loki" json:"loki,omitempty" yaml:"loki,omitempty" toml:"loki,omitempty"`
+	Kafka  KafkaConfig  `mapstructure:"kafka" json:"kafka,omitempty" yaml:"kafka,omitempty" toml:"kafka,omitempty"`
+}
+
+// KafkaConfig configures the "kafka" sink implemented by logging/kafkasink.
+type KafkaConfig struct {
+	Brokers []string `mapstructure:"brokers" json:"brokers,omitempty" yaml:"brokers,omitempty" toml:"brokers,omitempty"`
+	Topic   string   `mapstructure:"topic" json:"topic,omitempty" yaml:"topic,omitempty" toml:"topic,omitempty"`
+	// KeyField names the entry field used as message key, e.g. "tenant_id",
+	// keeping a tenant's entries in order on one partition.
+	KeyField string `mapstructure:"key-field" json:"keyField,omitempty" yaml:"key-field,omitempty" toml:"key-field,omitempty"`
+}
+
+// SinkEntry is one encoded log entry handed to a BatchWriter.
+type SinkEntry struct {
+	Time  time.Time
+	Level zapcore.Level
+	// Line is the JSON encoded entry without the trailing line ending.
+	Line []byte
+}
+
+// Fields decodes the top-level fields of the entry.
+func (e SinkEntry) Fields() map[string]any {
+	var fields map[string]any
+	_ = json.Unmarshal(e.Line, &fields)
+	return fields
+}
+
+// BatchWriter sends batches of entries to a remote system. It must not
+// retain the slice after WriteBatch returns.
+type BatchWriter interface {
+	WriteBatch(ctx context.Context, entries []SinkEntry) error
+	Close() error
+}
+
+// SinkFactory creates the BatchWriter of a sink type.
+type SinkFactory func(config SinkConfig) (BatchWriter, error)
+
+var (
+	sinkFactoriesMu sync.RWMutex
+	sinkFactories   = map[string]SinkFactory{
+		"syslog": newSyslogWriter,
+		"loki":   newLokiWriter,
+	}
+)
+
+// RegisterSink makes a sink type available to SinkConfig.Type.
+func RegisterSink(typ string, factory SinkFactory) {
+	sinkFactoriesMu.Lock()
+	defer sinkFactoriesMu.Unlock()
+	sinkFactories[typ] = factory
+}
+
+var sinkMetrics atomic.Pointer[metrics.Collector]
+
+// SetSinkMetrics reports written, dropped and failed entry counts of all
+// sinks to c. Pass nil to stop reporting.
+func SetSinkMetrics(c *metrics.Collector) {
+	sinkMetrics.Store(c)
+}
+
+// SinkStats are the entry counts of a sink since it was created.
+type SinkStats struct {
+	Name    string `json:"name"`
+	Queued  int    `json:"queued"`
+	Written uint64 `json:"written"`
+	Dropped uint64 `json:"dropped"`
+	Failed  uint64 `json:"failed"`
+}
+
+// asyncSink buffers entries and ships them in batches from one goroutine.
+type asyncSink struct {
+	name      string
+	writer    BatchWriter
+	queue     chan SinkEntry
+	batchSize int
+	interval  time.Duration
+	timeout   time.Duration
+
+	flush     chan chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+
+	written atomic.Uint64
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+}
+
+func newAsyncSink(config SinkConfig, writer BatchWriter) *asyncSink {
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultSinkBufferSize
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultSinkBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultSinkFlushInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultSinkTimeout
+	}
+	s := &asyncSink{
+		name:      sinkName(config),
+		writer:    writer,
+		queue:     make(chan SinkEntry, config.BufferSize),
+		batchSize: config.BatchSize,
+		interval:  config.FlushInterval,
+		timeout:   config.Timeout,
+		flush:     make(chan chan struct{}),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func sinkName(config SinkConfig) string {
+	if config.Name != "" {
+		return config.Name
+	}
+	return config.Type
+}
+
+// enqueue copies p, which zap reuses, and queues it without blocking.
+func (s *asyncSink) enqueue(level zapcore.Level, p []byte) {
+	n := len(p)
+	for n > 0 && (p[n-1] == '\n' || p[n-1] == '\r') {
+		n--
+	}
+	line := make([]byte, n)
+	copy(line, p)
+
+	select {
+	case s.queue <- SinkEntry{Time: time.Now(), Level: level, Line: line}:
+	default:
+		s.dropped.Add(1)
+		s.count(MetricSinkDropped, 1)
+	}
+}
+
+func (s *asyncSink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	batch := make([]SinkEntry, 0, s.batchSize)
+	send := func() {
+		if len(batch) > 0 {
+			s.send(batch)
+			clear(batch)
+			batch = batch[:0]
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case e := <-s.queue:
+				if batch = append(batch, e); len(batch) >= s.batchSize {
+					send()
+				}
+			default:
+				send()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case e := <-s.queue:
+			if batch = append(batch, e); len(batch) >= s.batchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ack := <-s.flush:
+			drain()
+			close(ack)
+		case <-s.stop:
+			drain()
+			return
+		}
+	}
+}
+
+func (s *asyncSink) send(batch []SinkEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	n := uint64(len(batch))
+	if err := s.writer.WriteBatch(ctx, batch); err != nil {
+		s.failed.Add(n)
+		s.count(MetricSinkFailed, float64(n))
+		return
+	}
+	s.written.Add(n)
+	s.count(MetricSinkWritten, float64(n))
+}
+
+func (s *asyncSink) count(metric string, n float64) {
+	if c := sinkMetrics.Load(); c != nil {
+		c.AddCounter(metric, n, map[string]string{"sink": s.name})
+	}
+}
+
+// Sync sends the buffered entries and waits for them, up to the send
+// timeout.
+func (s *asyncSink) Sync() error {
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+
+	ack := make(chan struct{})
+	select {
+	case s.flush <- ack:
+	case <-s.done:
+		return nil
+	case <-timer.C:
+		return nil
+	}
+	select {
+	case <-ack:
+	case <-timer.C:
+	}
+	return nil
+}
+
+// Close flushes the buffer and closes the writer. Entries written after
+// Close are dropped.
+func (s *asyncSink) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		err = s.writer.Close()
+	})
+	return err
+}
+
+func (s *asyncSink) stats() SinkStats {
+	return SinkStats{
+		Name:    s.name,
+		Queued:  len(s.queue),
+		Written: s.written.Load(),
+		Dropped: s.dropped.Load(),
+		Failed:  s.failed.Load(),
+	}
+}
+
+// sinkLevelWriter is the zapcore.WriteSyncer of one level of a sink, so
+// entries reach the BatchWriter with their level.
+type sinkLevelWriter struct {
+	sink  *asyncSink
+	level zapcore.Level
+}
+
+func (w sinkLevelWriter) Write(p []byte) (int, error) {
+	w.sink.enqueue(w.level, p)
+	return len(p), nil
+}
+
+func (w sinkLevelWriter) Sync() error {
+	return w.sink.Sync()
+}
+
+// sinkRegistry shares one sink between loggers built from the same sink
+// configuration, e.g. the named loggers of a Factory.
+var (
+	sinkRegistryMu sync.Mutex
+	sinkRegistry   = map[string]*asyncSink{}
+)
+
+func getSink(config SinkConfig) (*asyncSink, error) {
+	key, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	sinkRegistryMu.Lock()
+	defer sinkRegistryMu.Unlock()
+	if s, ok := sinkRegistry[string(key)]; ok {
+		return s, nil
+	}
+
+	sinkFactoriesMu.RLock()
+	factory, ok := sinkFactories[config.Type]
+	sinkFactoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("logging: unknown sink type %q", config.Type)
+	}
+	writer, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("logging: sink %s: %w", sinkName(config), err)
+	}
+	s := newAsyncSink(config, writer)
+	sinkRegistry[string(key)] = s
+	return s, nil
+}
+
+// closeSinks flushes and closes all sinks.
+func closeSinks() error {
+	sinkRegistryMu.Lock()
+	sinks := sinkRegistry
+	sinkRegistry = map[string]*asyncSink{}
+	sinkRegistryMu.Unlock()
+
+	var lastErr error
+	for _, s := range sinks {
+		if err := s.Close(); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// Sinks returns the counts of all active sinks ordered by name.
+func Sinks() []SinkStats {
+	sinkRegistryMu.Lock()
+	out := make([]SinkStats, 0, len(sinkRegistry))
+	for _, s := range sinkRegistry {
+		out = append(out, s.stats())
+	}
+	sinkRegistryMu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// sinkEncoderConfig encodes sink entries as JSON with RFC 3339 timestamps,
+// whatever the console and file format.
+func sinkEncoderConfig(config Config) zapcore.EncoderConfig {
+	ec := getEncoderConfig(config)
+	ec.EncodeLevel = zapcore.LowercaseLevelEncoder
+	ec.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	ec.LineEnding = "\n"
+	return ec
+}
+
+// getSinkCores creates the cores of config.Sinks for all levels the logger
+// and each sink admit. A sink that cannot be created is reported on stderr
+// and skipped, so a bad sink configuration does not stop the service.
+func getSinkCores(config Config) []zapcore.Core {
+	var cores []zapcore.Core
+	for _, sc := range config.Sinks {
+		sink, err := getSink(sc)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		from := config.TransportLevel()
+		if sc.Level != "" {
+			if level, err := zapcore.ParseLevel(sc.Level); err == nil && level > from {
+				from = level
+			}
+		}
+		encoder := zapcore.NewJSONEncoder(sinkEncoderConfig(config))
+		for level := from; level <= zapcore.FatalLevel; level++ {
+			core := zapcore.NewCore(encoder.Clone(), sinkLevelWriter{sink: sink, level: level}, getLevelPriority(level))
+			cores = append(cores, sampleCore(config, level, core))
+		}
+	}
+	return cores
+}
@@ -0,0 +1,229 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+	"go.uber.org/zap/zapcore"
+)
+
+// blockingWriter records batches and blocks in WriteBatch while gate is
+// open.
+type blockingWriter struct {
+	started chan struct{}
+	gate    chan struct{}
+
+	mu      sync.Mutex
+	entries []SinkEntry
+}
+
+func (w *blockingWriter) WriteBatch(ctx context.Context, entries []SinkEntry) error {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.gate
+	w.mu.Lock()
+	w.entries = append(w.entries, entries...)
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *blockingWriter) Close() error { return nil }
+
+func TestSinkDropsOnOverflow(t *testing.T) {
+	t.Cleanup(func() { closeSinks() })
+	collector := metrics.NewCollector()
+	SetSinkMetrics(collector)
+	t.Cleanup(func() { SetSinkMetrics(nil) })
+
+	bw := &blockingWriter{started: make(chan struct{}, 1), gate: make(chan struct{})}
+	RegisterSink("blocking", func(SinkConfig) (BatchWriter, error) { return bw, nil })
+
+	sink, err := getSink(SinkConfig{Type: "blocking", Name: "overflow", BufferSize: 2, BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := sinkLevelWriter{sink: sink, level: zapcore.InfoLevel}
+
+	w.Write([]byte("{\"n\":1}\n"))
+	<-bw.started // the first entry is in flight, the buffer is empty
+	for i := 0; i < 5; i++ {
+		w.Write([]byte("{}\n"))
+	}
+
+	stats := sink.stats()
+	if stats.Queued != 2 || stats.Dropped != 3 {
+		t.Errorf("expected 2 queued and 3 dropped, got %+v", stats)
+	}
+	if m := collector.GetMetric(MetricSinkDropped, map[string]string{"sink": "overflow"}); m == nil || m.Value != 3 {
+		t.Errorf("dropped metric = %+v", m)
+	}
+
+	close(bw.gate)
+	sink.Sync()
+	if got := sink.stats().Written; got != 3 {
+		t.Errorf("expected 3 written, got %d", got)
+	}
+	if string(bw.entries[0].Line) != `{"n":1}` {
+		t.Errorf("line ending should be trimmed, got %q", bw.entries[0].Line)
+	}
+}
+
+func TestLokiWriter(t *testing.T) {
+	var (
+		push   lokiPush
+		header http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != lokiPushPath {
+			http.NotFound(w, r)
+			return
+		}
+		header = r.Header
+		json.NewDecoder(r.Body).Decode(&push)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	bw, err := newLokiWriter(SinkConfig{Loki: LokiConfig{
+		URL:         srv.URL,
+		Labels:      map[string]string{"app": "api"},
+		LabelFields: map[string]string{"tenant_id": "tenant"},
+		TenantID:    "ops",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bw.Close()
+
+	now := time.Unix(1700000000, 5)
+	err = bw.WriteBatch(context.Background(), []SinkEntry{
+		{Time: now, Level: zapcore.InfoLevel, Line: []byte(`{"msg":"a","tenant_id":"t1"}`)},
+		{Time: now, Level: zapcore.InfoLevel, Line: []byte(`{"msg":"b","tenant_id":"t1"}`)},
+		{Time: now, Level: zapcore.ErrorLevel, Line: []byte(`{"msg":"c"}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header.Get("X-Scope-OrgID") != "ops" {
+		t.Errorf("tenant header = %q", header.Get("X-Scope-OrgID"))
+	}
+	if len(push.Streams) != 2 {
+		t.Fatalf("expected 2 streams, got %+v", push.Streams)
+	}
+	first := push.Streams[0]
+	if first.Stream["app"] != "api" || first.Stream["tenant"] != "t1" || first.Stream["level"] != "info" {
+		t.Errorf("unexpected labels %v", first.Stream)
+	}
+	if len(first.Values) != 2 || first.Values[0][0] != "1700000000000000005" || first.Values[0][1] != `{"msg":"a","tenant_id":"t1"}` {
+		t.Errorf("unexpected values %v", first.Values)
+	}
+	if _, ok := push.Streams[1].Stream["tenant"]; ok || push.Streams[1].Stream["level"] != "error" {
+		t.Errorf("unexpected labels %v", push.Streams[1].Stream)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "entry out of order", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	bw, _ = newLokiWriter(SinkConfig{Loki: LokiConfig{URL: failing.URL}})
+	if err := bw.WriteBatch(context.Background(), []SinkEntry{{Time: now, Line: []byte(`{}`)}}); err == nil || !strings.Contains(err.Error(), "out of order") {
+		t.Errorf("expected push error, got %v", err)
+	}
+}
+
+func TestSyslogWriter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	bw, err := newSyslogWriter(SinkConfig{Syslog: SyslogConfig{
+		Network:  "udp",
+		Address:  conn.LocalAddr().String(),
+		Facility: "local3",
+		AppName:  "orders api",
+		Hostname: "web-1",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bw.Close()
+
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := bw.WriteBatch(context.Background(), []SinkEntry{{Time: ts, Level: zapcore.ErrorLevel, Line: []byte(`{"msg":"boom"}`)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// local3 (19) * 8 + err (3)
+	want := `<155>1 2024-05-01T12:00:00Z web-1 orders_api `
+	if got := string(buf[:n]); !strings.HasPrefix(got, want) || !strings.HasSuffix(got, ` - - {"msg":"boom"}`) {
+		t.Errorf("unexpected message %q", got)
+	}
+
+	if _, err := newSyslogWriter(SinkConfig{Syslog: SyslogConfig{Network: "udp", Address: "x", Facility: "nope"}}); err == nil {
+		t.Error("expected an error for an unknown facility")
+	}
+}
+
+func TestLoggerWithSinks(t *testing.T) {
+	t.Cleanup(func() { closeSinks() })
+
+	var (
+		mu    sync.Mutex
+		lines []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var push lokiPush
+		json.NewDecoder(r.Body).Decode(&push)
+		mu.Lock()
+		for _, s := range push.Streams {
+			for _, v := range s.Values {
+				lines = append(lines, v[1])
+			}
+		}
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Director = t.TempDir()
+	cfg.LogInTerminal = false
+	cfg.Sinks = []SinkConfig{
+		{Type: "loki", Name: "loki-test", Level: "warn", Loki: LokiConfig{URL: srv.URL}},
+		{Type: "unknown"}, // reported and skipped
+	}
+
+	logger := NewLogger(cfg)
+	logger.Info("not shipped")
+	logger.Warn("shipped")
+	logger.Sync()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(lines) != 1 || !strings.Contains(lines[0], `"message":"shipped"`) || !strings.Contains(lines[0], `"level":"warn"`) {
+		t.Errorf("unexpected shipped lines %v", lines)
+	}
+	stats := Sinks()
+	if len(stats) != 1 || stats[0].Name != "loki-test" || stats[0].Written != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,292 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// slogHandler is a slog.Handler writing to the core of a Logger.
+type slogHandler struct {
+	core zapcore.Core
+	name string
+	// groups are the open groups, each with the attributes added to it.
+	// Attributes added before the first group are already in core.
+	groups []slogGroup
+}
+
+type slogGroup struct {
+	name  string
+	attrs []slog.Attr
+}
+
+var _ slog.Handler = (*slogHandler)(nil)
+
+// NewSlogHandler returns a slog.Handler that writes through logger, so code
+// written against log/slog, including third-party libraries, shares the
+// framework's outputs, levels and hooks:
+//
+//	slog.SetDefault(slog.New(logging.NewSlogHandler(logging.Global())))
+//
+// Records keep their time, caller and attributes; groups become nested
+// objects. The trace_id, span_id, request_id, user_id and tenant_id of the
+// context passed to the *Context methods are added as with WithContext.
+func NewSlogHandler(logger Logger) slog.Handler {
+	zl := logger.Zap()
+	return &slogHandler{core: zl.Core(), name: zl.Name()}
+}
+
+// Enabled implements slog.Handler.
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.core.Enabled(zapLevelFromSlog(level))
+}
+
+// Handle implements slog.Handler.
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	entry := zapcore.Entry{
+		Level:      zapLevelFromSlog(r.Level),
+		Time:       r.Time,
+		Message:    r.Message,
+		LoggerName: h.name,
+	}
+	ce := h.core.Check(entry, nil)
+	if ce == nil {
+		return nil
+	}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+		ce.Caller.Function = frame.Function
+	}
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	fields := appendContextFields(make([]zap.Field, 0, len(attrs)+5), ctx)
+	if len(h.groups) == 0 {
+		for _, a := range attrs {
+			fields = appendSlogAttr(fields, a)
+		}
+	} else {
+		// nest the record attributes in the open groups, innermost first
+		for i := len(h.groups) - 1; i >= 0; i-- {
+			g := h.groups[i]
+			attrs = []slog.Attr{{Key: g.name, Value: slog.GroupValue(append(slices.Clip(g.attrs), attrs...)...)}}
+		}
+		fields = appendSlogAttr(fields, attrs[0])
+	}
+	ce.Write(fields...)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	c := *h
+	if len(h.groups) == 0 {
+		var fields []zap.Field
+		for _, a := range attrs {
+			fields = appendSlogAttr(fields, a)
+		}
+		c.core = h.core.With(fields)
+		return &c
+	}
+	c.groups = slices.Clone(h.groups)
+	last := &c.groups[len(c.groups)-1]
+	last.attrs = append(slices.Clip(last.attrs), attrs...)
+	return &c
+}
+
+// WithGroup implements slog.Handler.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.groups = append(slices.Clip(h.groups), slogGroup{name: name})
+	return &c
+}
+
+// appendSlogAttr appends the zap field of a to fields, following the
+// slog.Handler rules: empty attributes and empty groups are dropped, and
+// groups without a key are inlined.
+func appendSlogAttr(fields []zap.Field, a slog.Attr) []zap.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, a.Value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, a.Value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, a.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, a.Value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, a.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, a.Value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, a.Value.Time()))
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			for _, ga := range attrs {
+				fields = appendSlogAttr(fields, ga)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, slogGroupMarshaler(attrs)))
+	default:
+		if err, ok := a.Value.Any().(error); ok {
+			return append(fields, zap.NamedError(a.Key, err))
+		}
+		return append(fields, zap.Any(a.Key, a.Value.Any()))
+	}
+}
+
+// slogGroupMarshaler encodes the attributes of a group as an object.
+type slogGroupMarshaler []slog.Attr
+
+func (m slogGroupMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, a := range m {
+		for _, f := range appendSlogAttr(nil, a) {
+			f.AddTo(enc)
+		}
+	}
+	return nil
+}
+
+// zapLevelFromSlog maps slog levels onto the nearest zap level at or below
+// them; levels above error stay error.
+func zapLevelFromSlog(level slog.Level) zapcore.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case level < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case level < slog.LevelError:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+// slogLevelFromZap maps zap levels onto slog; dpanic, panic and fatal
+// become ERROR+4, ERROR+8 and ERROR+12.
+func slogLevelFromZap(level zapcore.Level) slog.Level {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return slog.LevelDebug
+	case level == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case level == zapcore.WarnLevel:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError + slog.Level(4*(level-zapcore.ErrorLevel))
+	}
+}
+
+// slogCore is a zapcore.Core writing to a slog.Handler.
+type slogCore struct {
+	handler slog.Handler
+}
+
+// NewSlogCore returns a zapcore.Core writing entries to handler, for
+// example to capture framework logs with a test handler. Fields become
+// attributes; namespaces become groups.
+func NewSlogCore(handler slog.Handler) zapcore.Core {
+	return &slogCore{handler: handler}
+}
+
+// FromSlog returns a Logger writing to l.
+func FromSlog(l *slog.Logger) Logger {
+	return FromZap(zap.New(NewSlogCore(l.Handler())))
+}
+
+func (c *slogCore) Enabled(level zapcore.Level) bool {
+	return c.handler.Enabled(context.Background(), slogLevelFromZap(level))
+}
+
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	if len(fields) == 0 {
+		return c
+	}
+	return &slogCore{handler: c.handler.WithAttrs(slogAttrsFromFields(fields))}
+}
+
+func (c *slogCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+func (c *slogCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	r := slog.NewRecord(entry.Time, slogLevelFromZap(entry.Level), entry.Message, entry.Caller.PC)
+	if entry.LoggerName != "" {
+		r.AddAttrs(slog.String("logger", entry.LoggerName))
+	}
+	r.AddAttrs(slogAttrsFromFields(fields)...)
+	return c.handler.Handle(context.Background(), r)
+}
+
+func (c *slogCore) Sync() error {
+	return nil
+}
+
+// slogAttrsFromFields encodes fields into attributes ordered by key.
+func slogAttrsFromFields(fields []zapcore.Field) []slog.Attr {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	keys := make([]string, 0, len(enc.Fields))
+	for k := range enc.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, len(keys))
+	for i, k := range keys {
+		attrs[i] = slogAttrFromValue(k, enc.Fields[k])
+	}
+	return attrs
+}
+
+// slogAttrFromValue turns nested maps, as produced by namespaces and
+// objects, into groups.
+func slogAttrFromValue(key string, v any) slog.Attr {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return slog.Any(key, v)
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, len(keys))
+	for i, k := range keys {
+		attrs[i] = slogAttrFromValue(k, m[k])
+	}
+	return slog.Attr{Key: key, Value: slog.GroupValue(attrs...)}
+}
@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSlogHandler(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := slog.New(NewSlogHandler(FromZap(zap.New(core)).Named("lib")))
+
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("debug should follow the framework level")
+	}
+	logger.Debug("dropped")
+
+	ctx := SetRequestID(SetTraceID(context.Background(), "trace-1"), "req-1")
+	logger.With("component", "cache").
+		WithGroup("req").With("method", "GET").
+		WarnContext(ctx, "slow", "took", 2*time.Second, slog.Group("db", "rows", 3), slog.Group("empty"))
+	logger.Error("failed", "err", errors.New("boom"), slog.Group("", "inline", true))
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	warn := entries[0]
+	if warn.Level != zapcore.WarnLevel || warn.Message != "slow" || warn.LoggerName != "lib" {
+		t.Errorf("unexpected entry %+v", warn.Entry)
+	}
+	if !strings.HasSuffix(warn.Caller.File, "slog_test.go") {
+		t.Errorf("caller should be the slog call site, got %s", warn.Caller.File)
+	}
+	fields := warn.ContextMap()
+	if fields["component"] != "cache" || fields["trace_id"] != "trace-1" || fields["request_id"] != "req-1" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	req, _ := fields["req"].(map[string]any)
+	db, _ := req["db"].(map[string]any)
+	if req["method"] != "GET" || req["took"] != 2*time.Second || db["rows"] != int64(3) {
+		t.Errorf("unexpected group %v", fields["req"])
+	}
+	if _, ok := req["empty"]; ok {
+		t.Error("empty groups should be dropped")
+	}
+
+	fields = entries[1].ContextMap()
+	if fields["err"] != "boom" || fields["inline"] != true {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}
+
+func TestFromSlog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := FromSlog(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	logger.Debug("dropped")
+	logger.Named("orders").With(zap.String("tenant_id", "t1")).Warn("low stock", zap.Int("left", 2), zap.Namespace("item"), zap.String("sku", "A1"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %q", buf.String())
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	item, _ := got["item"].(map[string]any)
+	if got["level"] != "WARN" || got["msg"] != "low stock" || got["logger"] != "orders" ||
+		got["tenant_id"] != "t1" || got["left"] != float64(2) || item["sku"] != "A1" {
+		t.Errorf("unexpected record %v", got)
+	}
+}
+
+func TestSlogLevels(t *testing.T) {
+	cases := []struct {
+		slog slog.Level
+		zap  zapcore.Level
+	}{
+		{slog.LevelDebug - 4, zapcore.DebugLevel},
+		{slog.LevelDebug, zapcore.DebugLevel},
+		{slog.LevelInfo, zapcore.InfoLevel},
+		{slog.LevelInfo + 2, zapcore.InfoLevel},
+		{slog.LevelWarn, zapcore.WarnLevel},
+		{slog.LevelError, zapcore.ErrorLevel},
+		{slog.LevelError + 4, zapcore.ErrorLevel},
+	}
+	for _, c := range cases {
+		if got := zapLevelFromSlog(c.slog); got != c.zap {
+			t.Errorf("zapLevelFromSlog(%s) = %s, want %s", c.slog, got, c.zap)
+		}
+	}
+	if got := slogLevelFromZap(zapcore.FatalLevel); got != slog.LevelError+12 {
+		t.Errorf("fatal maps to %s", got)
+	}
+}
@@ -0,0 +1,492 @@
+package logging
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TenantOptions configures per-tenant log routing and level overrides.
+type TenantOptions struct {
+	// Field is the record field carrying the tenant ID. Defaults to "tenant_id".
+	Field string
+
+	// Sink returns the destination for a tenant's records, or nil to leave
+	// them in the base logger only. See TenantFileSink.
+	Sink func(tenantID string) zapcore.WriteSyncer
+
+	// Exclusive writes routed records only to the tenant sink.
+	Exclusive bool
+
+	// MaxSinks bounds the open tenant sinks. The least recently used one is
+	// synced and, if it implements io.Closer, closed when a new tenant needs
+	// a sink; it is reopened on the tenant's next record. Defaults to 256.
+	MaxSinks int
+
+	// DefaultTTL is the override lifetime when none is given. Defaults to 15m.
+	DefaultTTL time.Duration
+
+	// MaxTTL caps override lifetimes. Defaults to 24h.
+	MaxTTL time.Duration
+}
+
+// TenantLevel is an active per-tenant level override.
+type TenantLevel struct {
+	TenantID  string    `json:"tenant_id"`
+	Level     string    `json:"level"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type tenantOverride struct {
+	level   zapcore.Level
+	expires time.Time
+}
+
+// TenantLogging segregates log records by tenant and lowers the level for
+// individual tenants temporarily, so one customer can be debugged without
+// enabling debug logs for everyone.
+//
+// Records a tenant override admits below the base level are written to the
+// per-level files of the config (e.g. debug.log), and to the tenant sink
+// when one is configured.
+type TenantLogging struct {
+	config Config
+	opts   TenantOptions
+	now    func() time.Time
+
+	mu        sync.RWMutex
+	overrides map[string]tenantOverride
+	levels    map[zapcore.Level]zapcore.Core
+
+	sinkMu   sync.Mutex
+	sinks    map[string]*list.Element // of *tenantSink
+	sinksLRU *list.List               // most recently used first
+}
+
+// tenantSink is an open tenant sink; core and ws are nil when Sink returned
+// nil for the tenant.
+type tenantSink struct {
+	tenant string
+	core   zapcore.Core
+	ws     zapcore.WriteSyncer
+}
+
+// NewTenantLogging creates tenant routing for loggers built from config.
+func NewTenantLogging(config Config, opts TenantOptions) *TenantLogging {
+	config.applyDefaults()
+	if opts.Field == "" {
+		opts.Field = "tenant_id"
+	}
+	if opts.DefaultTTL <= 0 {
+		opts.DefaultTTL = 15 * time.Minute
+	}
+	if opts.MaxTTL <= 0 {
+		opts.MaxTTL = 24 * time.Hour
+	}
+	if opts.MaxSinks <= 0 {
+		opts.MaxSinks = 256
+	}
+	return &TenantLogging{
+		config:    config,
+		opts:      opts,
+		now:       time.Now,
+		overrides: make(map[string]tenantOverride),
+		levels:    make(map[zapcore.Level]zapcore.Core),
+		sinks:     make(map[string]*list.Element),
+		sinksLRU:  list.New(),
+	}
+}
+
+// Wrap returns a logger applying tenant routing and overrides on top of
+// logger. The tenant is read from fields added with With (including
+// WithContext) or passed with the record.
+func (t *TenantLogging) Wrap(logger Logger) Logger {
+	core := &tenantCore{base: logger.Zap().Core(), t: t}
+	return newZapLogger(logger.Zap().WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core { return core })))
+}
+
+// SetLevel enables records at level and above for tenantID until ttl
+// elapses. Overrides only add records; a level above the base level does not
+// silence the tenant. ttl <= 0 uses DefaultTTL; it is capped at MaxTTL.
+func (t *TenantLogging) SetLevel(tenantID string, level zapcore.Level, ttl time.Duration) TenantLevel {
+	if ttl <= 0 {
+		ttl = t.opts.DefaultTTL
+	}
+	if ttl > t.opts.MaxTTL {
+		ttl = t.opts.MaxTTL
+	}
+	o := tenantOverride{level: level, expires: t.now().Add(ttl)}
+
+	t.mu.Lock()
+	t.overrides[tenantID] = o
+	t.mu.Unlock()
+	return TenantLevel{TenantID: tenantID, Level: level.String(), ExpiresAt: o.expires}
+}
+
+// ClearLevel removes the override for tenantID.
+func (t *TenantLogging) ClearLevel(tenantID string) {
+	t.mu.Lock()
+	delete(t.overrides, tenantID)
+	t.mu.Unlock()
+}
+
+// Overrides returns the active overrides ordered by tenant ID.
+func (t *TenantLogging) Overrides() []TenantLevel {
+	now := t.now()
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	out := make([]TenantLevel, 0, len(t.overrides))
+	for id, o := range t.overrides {
+		if now.Before(o.expires) {
+			out = append(out, TenantLevel{TenantID: id, Level: o.level.String(), ExpiresAt: o.expires})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TenantID < out[j].TenantID })
+	return out
+}
+
+// override returns the active override for tenantID, dropping it once expired.
+func (t *TenantLogging) override(tenantID string) (zapcore.Level, bool) {
+	if tenantID == "" {
+		return 0, false
+	}
+	t.mu.RLock()
+	o, ok := t.overrides[tenantID]
+	t.mu.RUnlock()
+	if !ok {
+		return 0, false
+	}
+	if !t.now().Before(o.expires) {
+		t.mu.Lock()
+		if cur, ok := t.overrides[tenantID]; ok && cur == o {
+			delete(t.overrides, tenantID)
+		}
+		t.mu.Unlock()
+		return 0, false
+	}
+	return o.level, true
+}
+
+// admitsAny reports whether some active override enables level.
+func (t *TenantLogging) admitsAny(level zapcore.Level) bool {
+	now := t.now()
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, o := range t.overrides {
+		if level >= o.level && now.Before(o.expires) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *TenantLogging) sinkCore(tenantID string) zapcore.Core {
+	if t.opts.Sink == nil || tenantID == "" {
+		return nil
+	}
+	t.sinkMu.Lock()
+	defer t.sinkMu.Unlock()
+	if e, ok := t.sinks[tenantID]; ok {
+		t.sinksLRU.MoveToFront(e)
+		return e.Value.(*tenantSink).core
+	}
+
+	for t.sinksLRU.Len() >= t.opts.MaxSinks {
+		t.evictSink(t.sinksLRU.Back())
+	}
+	sink := &tenantSink{tenant: tenantID}
+	if sink.ws = t.opts.Sink(tenantID); sink.ws != nil {
+		sink.core = zapcore.NewCore(GetEncoder(t.config), sink.ws, zapcore.DebugLevel)
+	}
+	t.sinks[tenantID] = t.sinksLRU.PushFront(sink)
+	return sink.core
+}
+
+// evictSink flushes and closes the sink in e. t.sinkMu must be held.
+func (t *TenantLogging) evictSink(e *list.Element) {
+	sink := t.sinksLRU.Remove(e).(*tenantSink)
+	delete(t.sinks, sink.tenant)
+	if sink.ws == nil {
+		return
+	}
+	_ = sink.ws.Sync()
+	if c, ok := sink.ws.(io.Closer); ok {
+		_ = c.Close()
+	}
+}
+
+// levelCore writes to the config's file for level, like NewLogger would for
+// a logger configured at that level.
+func (t *TenantLogging) levelCore(level zapcore.Level) zapcore.Core {
+	t.mu.RLock()
+	core, ok := t.levels[level]
+	t.mu.RUnlock()
+	if ok {
+		return core
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if core, ok := t.levels[level]; ok {
+		return core
+	}
+	core = getEncoderCore(t.config, level, getLevelPriority(level))
+	t.levels[level] = core
+	return core
+}
+
+func (t *TenantLogging) syncSinks() error {
+	t.sinkMu.Lock()
+	defer t.sinkMu.Unlock()
+	var firstErr error
+	for e := t.sinksLRU.Front(); e != nil; e = e.Next() {
+		core := e.Value.(*tenantSink).core
+		if core == nil {
+			continue
+		}
+		if err := core.Sync(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// tenantCore routes records by tenant and applies level overrides.
+type tenantCore struct {
+	base   zapcore.Core
+	t      *TenantLogging
+	tenant string
+	fields []zapcore.Field // fields added with With, for sink and level cores
+}
+
+// Enabled implements zapcore.Core.
+func (c *tenantCore) Enabled(level zapcore.Level) bool {
+	// Write filters by the record's tenant; fields passed with the record
+	// are not known here.
+	return c.base.Enabled(level) || c.t.admitsAny(level)
+}
+
+// With implements zapcore.Core.
+func (c *tenantCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &tenantCore{
+		base:   c.base.With(fields),
+		t:      c.t,
+		tenant: c.tenant,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+	if id := c.t.tenantOf(fields); id != "" {
+		clone.tenant = id
+	}
+	return clone
+}
+
+// Check implements zapcore.Core.
+func (c *tenantCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *tenantCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	tenant := c.tenant
+	if id := c.t.tenantOf(fields); id != "" {
+		tenant = id
+	}
+
+	byBase := c.base.Enabled(entry.Level)
+	if !byBase {
+		min, ok := c.t.override(tenant)
+		if !ok || entry.Level < min {
+			return nil
+		}
+	}
+
+	if sink := c.t.sinkCore(tenant); sink != nil {
+		err := sink.With(c.fields).Write(entry, fields)
+		if c.t.opts.Exclusive {
+			return err
+		}
+	}
+
+	if byBase {
+		// The base core may be a tee of per-level cores; Check selects the
+		// ones enabled for this level.
+		if ce := c.base.Check(entry, nil); ce != nil {
+			ce.Write(fields...)
+		}
+		return nil
+	}
+	return c.t.levelCore(entry.Level).With(c.fields).Write(entry, fields)
+}
+
+// Sync implements zapcore.Core.
+func (c *tenantCore) Sync() error {
+	err := c.base.Sync()
+	if serr := c.t.syncSinks(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+func (t *TenantLogging) tenantOf(fields []zapcore.Field) string {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == t.opts.Field && fields[i].Type == zapcore.StringType {
+			return fields[i].String
+		}
+	}
+	return ""
+}
+
+// TenantFileSink writes each tenant's records to
+// <Director>/tenants/<tenant>/<date>/all.log with the rotation settings of
+// config. Writers are closed when TenantLogging evicts them and by
+// CloseAllWriters.
+func TenantFileSink(config Config) func(tenantID string) zapcore.WriteSyncer {
+	config.applyDefaults()
+	return func(tenantID string) zapcore.WriteSyncer {
+		c := config
+		c.Director = filepath.Join(config.Director, "tenants", safeTenantDir(tenantID))
+		w := newLevelWriter(c, "all")
+		registerWriter(w)
+		return &tenantFileWriter{w: w}
+	}
+}
+
+// tenantFileWriter is a tenant's file sink. Close releases its files and
+// unregisters it; a record racing the eviction reopens and re-registers it.
+type tenantFileWriter struct {
+	mu     sync.Mutex
+	w      *levelWriter
+	closed bool
+}
+
+func (f *tenantFileWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	if f.closed {
+		registerWriter(f.w)
+		f.closed = false
+	}
+	f.mu.Unlock()
+	return f.w.Write(p)
+}
+
+func (f *tenantFileWriter) Sync() error { return f.w.Sync() }
+
+func (f *tenantFileWriter) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	unregisterWriter(f.w)
+	return f.w.Close()
+}
+
+// safeTenantDir keeps tenant IDs from escaping the tenants directory. IDs
+// made only of letters, digits, '-' and '_' are used as is; others are
+// sanitised and suffixed with a hash of the ID after a '.', which kept IDs
+// cannot contain, so distinct tenants never share a directory.
+func safeTenantDir(tenantID string) string {
+	dir := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, tenantID)
+	if dir == tenantID && dir != "" {
+		return dir
+	}
+	if dir == "" {
+		dir = "_"
+	}
+	sum := sha256.Sum256([]byte(tenantID))
+	return dir + "." + hex.EncodeToString(sum[:8])
+}
+
+// tenantLevelRequest is the body of a PUT to the admin handler.
+type tenantLevelRequest struct {
+	TenantID string `json:"tenant_id"`
+	Level    string `json:"level"`
+	TTL      string `json:"ttl"`
+}
+
+// Handler returns the admin endpoint for tenant level overrides:
+//
+//	GET                                           list active overrides
+//	PUT    {"tenant_id","level","ttl":"30m"}      set an override
+//	DELETE ?tenant_id=...                         clear an override
+//
+// Mount it behind authentication; it has no access control of its own.
+func (t *TenantLogging) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, t.Overrides())
+
+		case http.MethodPut, http.MethodPost:
+			var req tenantLevelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+				return
+			}
+			if req.TenantID == "" {
+				writeJSONError(w, http.StatusBadRequest, "tenant_id is required")
+				return
+			}
+			level, err := zapcore.ParseLevel(req.Level)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			var ttl time.Duration
+			if req.TTL != "" {
+				if ttl, err = time.ParseDuration(req.TTL); err != nil {
+					writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid ttl %q", req.TTL))
+					return
+				}
+			}
+			writeJSON(w, http.StatusOK, t.SetLevel(req.TenantID, level, ttl))
+
+		case http.MethodDelete:
+			id := r.URL.Query().Get("tenant_id")
+			if id == "" {
+				writeJSONError(w, http.StatusBadRequest, "tenant_id is required")
+				return
+			}
+			t.ClearLevel(id)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,232 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Sync() error { return nil }
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newTenantTestLogger(t *testing.T, opts TenantOptions) (Logger, *TenantLogging, *syncBuffer, Config) {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Director = t.TempDir()
+	cfg.LogInTerminal = false
+	t.Cleanup(func() { _ = CloseAllWriters() })
+
+	base := &syncBuffer{}
+	core := zapcore.NewCore(GetEncoder(cfg), base, zapcore.InfoLevel)
+	tenants := NewTenantLogging(cfg, opts)
+	return tenants.Wrap(FromZap(zap.New(core))), tenants, base, cfg
+}
+
+func TestTenantLevelOverride(t *testing.T) {
+	logger, tenants, base, cfg := newTenantTestLogger(t, TenantOptions{})
+	now := time.Now()
+	tenants.now = func() time.Time { return now }
+
+	acme := WithContext(logger, SetTenantID(context.Background(), "acme"))
+	other := logger.With(zap.String("tenant_id", "other"))
+
+	acme.Debug("acme before override")
+	tenants.SetLevel("acme", zapcore.DebugLevel, time.Minute)
+	acme.Debug("acme debug")
+	other.Debug("other debug")
+	logger.Debug("tenant per record", zap.String("tenant_id", "acme"))
+	acme.Info("acme info")
+
+	now = now.Add(2 * time.Minute)
+	acme.Debug("acme after expiry")
+
+	debugLog := readLogs(t, filepath.Join(cfg.Director, time.Now().Format("2006-01-02"), "debug.log"))
+	for _, want := range []string{"acme debug", "tenant per record"} {
+		if !strings.Contains(debugLog, want) {
+			t.Errorf("debug.log missing %q:\n%s", want, debugLog)
+		}
+	}
+	for _, unwanted := range []string{"before override", "other debug", "after expiry"} {
+		if strings.Contains(debugLog, unwanted) {
+			t.Errorf("debug.log should not contain %q", unwanted)
+		}
+	}
+	if !strings.Contains(base.String(), "acme info") || strings.Contains(base.String(), "acme debug") {
+		t.Errorf("base output = %s", base.String())
+	}
+	if len(tenants.Overrides()) != 0 {
+		t.Errorf("expired override still listed: %+v", tenants.Overrides())
+	}
+}
+
+func TestTenantSinkRouting(t *testing.T) {
+	sinks := map[string]*syncBuffer{"acme": {}}
+	logger, _, base, _ := newTenantTestLogger(t, TenantOptions{
+		Exclusive: true,
+		Sink: func(id string) zapcore.WriteSyncer {
+			if b, ok := sinks[id]; ok {
+				return b
+			}
+			return nil
+		},
+	})
+
+	logger.With(zap.String("tenant_id", "acme")).Info("for acme")
+	logger.Info("for other", zap.String("tenant_id", "other"))
+	logger.Info("no tenant")
+
+	if got := sinks["acme"].String(); !strings.Contains(got, "for acme") || strings.Contains(got, "for other") {
+		t.Errorf("acme sink = %s", got)
+	}
+	out := base.String()
+	if strings.Contains(out, "for acme") || !strings.Contains(out, "for other") || !strings.Contains(out, "no tenant") {
+		t.Errorf("base output = %s", out)
+	}
+}
+
+func TestTenantFileSink(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Director = t.TempDir()
+	defer CloseAllWriters()
+
+	ws := TenantFileSink(cfg)("../acme")
+	if _, err := ws.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(cfg.Director, "tenants", safeTenantDir("../acme"), time.Now().Format("2006-01-02"), "all.log")
+	if got := readLogs(t, path); got != "hello\n" {
+		t.Errorf("tenant file = %q", got)
+	}
+}
+
+func TestSafeTenantDir(t *testing.T) {
+	if got := safeTenantDir("acme-1_eu"); got != "acme-1_eu" {
+		t.Errorf("safeTenantDir(acme-1_eu) = %q", got)
+	}
+	seen := map[string]string{}
+	for _, id := range []string{"a_b", "a/b", "a.b", "a b", "", "_", "..", "../acme"} {
+		dir := safeTenantDir(id)
+		if strings.ContainsAny(dir, `/\`) || dir == "." || dir == ".." || strings.HasPrefix(dir, ".") {
+			t.Errorf("safeTenantDir(%q) = %q escapes the tenants directory", id, dir)
+		}
+		if other, ok := seen[dir]; ok {
+			t.Errorf("safeTenantDir(%q) = safeTenantDir(%q) = %q", id, other, dir)
+		}
+		seen[dir] = id
+	}
+}
+
+type closingBuffer struct {
+	syncBuffer
+	closed bool
+}
+
+func (b *closingBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestTenantSinksBounded(t *testing.T) {
+	opened := map[string]int{}
+	buffers := map[string]*closingBuffer{}
+	logger, tenants, _, _ := newTenantTestLogger(t, TenantOptions{
+		MaxSinks: 2,
+		Sink: func(id string) zapcore.WriteSyncer {
+			opened[id]++
+			buffers[id] = &closingBuffer{}
+			return buffers[id]
+		},
+	})
+
+	logger.Info("a1", zap.String("tenant_id", "a"))
+	logger.Info("b1", zap.String("tenant_id", "b"))
+	logger.Info("a2", zap.String("tenant_id", "a"))
+	first := buffers["b"]
+	logger.Info("c1", zap.String("tenant_id", "c")) // evicts b, the least recently used
+
+	if len(tenants.sinks) != 2 {
+		t.Errorf("open sinks = %d, want 2", len(tenants.sinks))
+	}
+	if !first.closed || buffers["a"].closed {
+		t.Errorf("closed: a=%v b=%v, want only b", buffers["a"].closed, first.closed)
+	}
+	logger.Info("b2", zap.String("tenant_id", "b"))
+	if opened["b"] != 2 || opened["a"] != 1 {
+		t.Errorf("opened = %v", opened)
+	}
+	if got := buffers["b"].String(); !strings.Contains(got, "b2") || strings.Contains(got, "b1") {
+		t.Errorf("reopened sink = %q", got)
+	}
+}
+
+func TestTenantAdminHandler(t *testing.T) {
+	_, tenants, _, _ := newTenantTestLogger(t, TenantOptions{MaxTTL: time.Hour})
+	h := tenants.Handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"tenant_id":"acme","level":"debug","ttl":"48h"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", rec.Code, rec.Body)
+	}
+	var set TenantLevel
+	_ = json.Unmarshal(rec.Body.Bytes(), &set)
+	if set.Level != "debug" || time.Until(set.ExpiresAt) > time.Hour {
+		t.Errorf("override = %+v, want debug capped at 1h", set)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"tenant_id":"acme","level":"loud"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid level status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var list []TenantLevel
+	_ = json.Unmarshal(rec.Body.Bytes(), &list)
+	if len(list) != 1 || list[0].TenantID != "acme" {
+		t.Errorf("GET = %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/?tenant_id=acme", nil))
+	if rec.Code != http.StatusNoContent || len(tenants.Overrides()) != 0 {
+		t.Errorf("DELETE status = %d, overrides = %+v", rec.Code, tenants.Overrides())
+	}
+}
+
+func readLogs(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
@@ -173,11 +174,24 @@ func registerWriter(w *levelWriter) {
 	writerRegistry.writers = append(writerRegistry.writers, w)
 }
 
-// CloseAllWriters closes all registered writers.
+// unregisterWriter removes a levelWriter closed by its owner.
+func unregisterWriter(w *levelWriter) {
+	writerRegistryMu.Lock()
+	defer writerRegistryMu.Unlock()
+	writerRegistry.writers = slices.DeleteFunc(writerRegistry.writers, func(r *levelWriter) bool { return r == w })
+}
+
+// CloseAllWriters closes all registered writers, flushing and closing the
+// remote sinks first.
 func CloseAllWriters() error {
+	sinkErr := closeSinks()
+
 	writerRegistryMu.Lock()
 	defer writerRegistryMu.Unlock()
-	return writerRegistry.Close()
+	if err := writerRegistry.Close(); err != nil {
+		return err
+	}
+	return sinkErr
 }
 
 // getWriteSyncerWithRegistry creates a WriteSyncer and registers its levelWriter for cleanup.
@@ -0,0 +1,342 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/leeforge/framework/retry"
+)
+
+// SESConfig configures an SESSender. Credentials default to the standard
+// AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY / AWS_SESSION_TOKEN variables
+// and the region to $AWS_REGION.
+type SESConfig struct {
+	Region          string `mapstructure:"region"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	SessionToken    string `mapstructure:"session_token"`
+	// ConfigurationSet selects an SES configuration set for event
+	// publishing.
+	ConfigurationSet string `mapstructure:"configuration_set"`
+	// Endpoint overrides https://email.<region>.amazonaws.com.
+	Endpoint   string       `mapstructure:"endpoint"`
+	HTTPClient *http.Client `mapstructure:"-"`
+}
+
+// SESSender sends raw MIME messages through the Amazon SES v2 SendEmail
+// API, signed with Signature V4 without pulling in the AWS SDK. Message
+// metadata becomes SES message tags.
+type SESSender struct {
+	cfg SESConfig
+	now func() time.Time
+}
+
+// NewSESSender creates an SES sender.
+func NewSESSender(cfg SESConfig) *SESSender {
+	if cfg.Region == "" {
+		cfg.Region = firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	}
+	cfg.AccessKeyID = firstNonEmpty(cfg.AccessKeyID, os.Getenv("AWS_ACCESS_KEY_ID"))
+	cfg.SecretAccessKey = firstNonEmpty(cfg.SecretAccessKey, os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	cfg.SessionToken = firstNonEmpty(cfg.SessionToken, os.Getenv("AWS_SESSION_TOKEN"))
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://email." + cfg.Region + ".amazonaws.com"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &SESSender{cfg: cfg, now: time.Now}
+}
+
+// sesTagValue matches the characters SES allows in tag names and values.
+var sesTagValue = regexp.MustCompile(`[^A-Za-z0-9_\-]`)
+
+// Send implements Sender and returns the SES message ID.
+func (s *SESSender) Send(ctx context.Context, msg *Message) (string, error) {
+	if err := msg.Validate(); err != nil {
+		return "", retry.Permanent(err)
+	}
+	if s.cfg.Region == "" || s.cfg.AccessKeyID == "" || s.cfg.SecretAccessKey == "" {
+		return "", retry.Permanent(fmt.Errorf("mail: ses region and credentials are required"))
+	}
+	raw, err := msg.Build()
+	if err != nil {
+		return "", retry.Permanent(err)
+	}
+
+	type tag struct {
+		Name  string `json:"Name"`
+		Value string `json:"Value"`
+	}
+	var tags []tag
+	for k, v := range msg.Metadata {
+		tags = append(tags, tag{Name: sesTagValue.ReplaceAllString(k, "_"), Value: sesTagValue.ReplaceAllString(v, "_")})
+	}
+	destination := map[string][]string{}
+	for key, addrs := range map[string][]Address{"ToAddresses": msg.To, "CcAddresses": msg.Cc, "BccAddresses": msg.Bcc} {
+		for _, a := range addrs {
+			destination[key] = append(destination[key], a.String())
+		}
+	}
+	body, err := json.Marshal(struct {
+		FromEmailAddress     string
+		Destination          map[string][]string
+		Content              map[string]any
+		EmailTags            []tag  `json:",omitempty"`
+		ConfigurationSetName string `json:",omitempty"`
+	}{
+		FromEmailAddress:     msg.From.String(),
+		Destination:          destination,
+		Content:              map[string]any{"Raw": map[string]string{"Data": base64.StdEncoding.EncodeToString(raw)}},
+		EmailTags:            tags,
+		ConfigurationSetName: s.cfg.ConfigurationSet,
+	})
+	if err != nil {
+		return "", retry.Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.cfg.Endpoint, "/")+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return "", retry.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s.sign(req, body)
+
+	var out struct {
+		MessageID string `json:"MessageId"`
+	}
+	if err := doJSON(s.cfg.HTTPClient, req, "ses", &out); err != nil {
+		return "", err
+	}
+	return out.MessageID, nil
+}
+
+// sign adds AWS Signature Version 4 headers covering content-type, host
+// and x-amz-date.
+func (s *SESSender) sign(req *http.Request, body []byte) {
+	t := s.now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	signedHeaders := "content-type;host;x-amz-date"
+	canonicalHeaders := "content-type:" + req.Header.Get("Content-Type") + "\nhost:" + req.URL.Host + "\nx-amz-date:" + amzDate + "\n"
+	if s.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+		signedHeaders += ";x-amz-security-token"
+		canonicalHeaders += "x-amz-security-token:" + s.cfg.SessionToken + "\n"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/ses/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "ses")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// SendGridConfig configures a SendGridSender.
+type SendGridConfig struct {
+	APIKey string `mapstructure:"api_key" validate:"required"`
+	// Endpoint overrides https://api.sendgrid.com.
+	Endpoint   string       `mapstructure:"endpoint"`
+	HTTPClient *http.Client `mapstructure:"-"`
+}
+
+// SendGridSender sends messages through the SendGrid v3 mail send API.
+// Message metadata becomes custom_args, which SendGrid echoes in its event
+// webhooks.
+type SendGridSender struct {
+	cfg SendGridConfig
+}
+
+// NewSendGridSender creates a SendGrid sender.
+func NewSendGridSender(cfg SendGridConfig) *SendGridSender {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://api.sendgrid.com"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &SendGridSender{cfg: cfg}
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+func sendGridAddresses(addrs []Address) []sendGridAddress {
+	if len(addrs) == 0 {
+		return nil
+	}
+	out := make([]sendGridAddress, len(addrs))
+	for i, a := range addrs {
+		out[i] = sendGridAddress{Email: a.Email, Name: a.Name}
+	}
+	return out
+}
+
+// Send implements Sender and returns the X-Message-Id SendGrid assigns.
+func (s *SendGridSender) Send(ctx context.Context, msg *Message) (string, error) {
+	if err := msg.Validate(); err != nil {
+		return "", retry.Permanent(err)
+	}
+
+	type content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	type attachment struct {
+		Content     string `json:"content"`
+		Type        string `json:"type,omitempty"`
+		Filename    string `json:"filename"`
+		Disposition string `json:"disposition"`
+		ContentID   string `json:"content_id,omitempty"`
+	}
+	var contents []content
+	if msg.Text != "" {
+		contents = append(contents, content{Type: "text/plain", Value: msg.Text})
+	}
+	if msg.HTML != "" {
+		contents = append(contents, content{Type: "text/html", Value: msg.HTML})
+	}
+	var attachments []attachment
+	for _, a := range msg.Attachments {
+		disposition := "attachment"
+		if a.Inline {
+			disposition = "inline"
+		}
+		attachments = append(attachments, attachment{
+			Content:     base64.StdEncoding.EncodeToString(a.Data),
+			Type:        a.ContentType,
+			Filename:    a.Filename,
+			Disposition: disposition,
+			ContentID:   a.ContentID,
+		})
+	}
+	headers := map[string]string{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	if id := msg.headerID(); id != "" {
+		headers["Message-ID"] = id
+	}
+	type personalization struct {
+		To         []sendGridAddress `json:"to,omitempty"`
+		Cc         []sendGridAddress `json:"cc,omitempty"`
+		Bcc        []sendGridAddress `json:"bcc,omitempty"`
+		CustomArgs map[string]string `json:"custom_args,omitempty"`
+	}
+	payload := struct {
+		Personalizations []personalization `json:"personalizations"`
+		From             sendGridAddress   `json:"from"`
+		ReplyToList      []sendGridAddress `json:"reply_to_list,omitempty"`
+		Subject          string            `json:"subject"`
+		Content          []content         `json:"content"`
+		Attachments      []attachment      `json:"attachments,omitempty"`
+		Headers          map[string]string `json:"headers,omitempty"`
+	}{
+		Personalizations: []personalization{{
+			To:         sendGridAddresses(msg.To),
+			Cc:         sendGridAddresses(msg.Cc),
+			Bcc:        sendGridAddresses(msg.Bcc),
+			CustomArgs: msg.Metadata,
+		}},
+		From:        sendGridAddress{Email: msg.From.Email, Name: msg.From.Name},
+		ReplyToList: sendGridAddresses(msg.ReplyTo),
+		Subject:     msg.Subject,
+		Content:     contents,
+		Attachments: attachments,
+		Headers:     headers,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", retry.Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.cfg.Endpoint, "/")+"/v3/mail/send", bytes.NewReader(body))
+	if err != nil {
+		return "", retry.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.cfg.APIKey)
+	var id string
+	err = do(s.cfg.HTTPClient, req, "sendgrid", func(resp *http.Response) error {
+		id = resp.Header.Get("X-Message-Id")
+		return nil
+	})
+	return id, err
+}
+
+// doJSON sends req and decodes a successful JSON response into out.
+func doJSON(client *http.Client, req *http.Request, provider string, out any) error {
+	return do(client, req, provider, func(resp *http.Response) error {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("mail: decode %s response: %w", provider, err)
+		}
+		return nil
+	})
+}
+
+// do sends req and hands 2xx responses to ok. Other 4xx replies except 408
+// and 429 are permanent errors; 5xx and transport failures are retryable.
+func do(client *http.Client, req *http.Request, provider string, ok func(*http.Response) error) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mail: %s request failed: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return ok(resp)
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("mail: %s returned %s: %s", provider, resp.Status, bytes.TrimSpace(detail))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return retry.Permanent(err)
+	}
+	return err
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
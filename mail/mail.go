@@ -0,0 +1,136 @@
+// Package mail sends email through interchangeable providers. SMTPSender,
+// SESSender and SendGridSender implement Sender; a Mailer in front of them
+// renders templates with layouts, fills in defaults, records every attempt
+// in an AuditLog and, given a jobs.Manager, sends in the background with the
+// manager's retries and dead-lettering.
+//
+//	tmpl, err := mail.ParseTemplates(os.DirFS("templates/mail"), mail.TemplateOptions{})
+//	mailer := mail.NewMailer(mail.NewSMTPSender(cfg), mail.Options{
+//		From:      mail.Address{Name: "Acme", Email: "no-reply@acme.io"},
+//		Templates: tmpl,
+//		Jobs:      manager,
+//	})
+//	err = mailer.SendTemplate(ctx, "welcome", data, &mail.Message{
+//		To:       []mail.Address{{Email: user.Email}},
+//		Metadata: map[string]string{"tenant_id": tenantID, "user_id": userID},
+//	})
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	netmail "net/mail"
+	"strings"
+)
+
+var (
+	// ErrNoRecipients is returned for messages without To, Cc or Bcc.
+	ErrNoRecipients = errors.New("mail: message has no recipients")
+	// ErrNoSender is returned when neither the message nor the Mailer
+	// options set a From address.
+	ErrNoSender = errors.New("mail: message has no sender")
+	// ErrNoBody is returned for messages without a text or HTML body.
+	ErrNoBody = errors.New("mail: message has no body")
+	// ErrTemplateNotFound is returned when rendering an unknown template.
+	ErrTemplateNotFound = errors.New("mail: template not found")
+)
+
+// Address is a mailbox with an optional display name.
+type Address struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email"`
+}
+
+// ParseAddress parses "Name <user@example.com>" or a bare address.
+func ParseAddress(s string) (Address, error) {
+	a, err := netmail.ParseAddress(s)
+	if err != nil {
+		return Address{}, fmt.Errorf("mail: invalid address %q: %w", s, err)
+	}
+	return Address{Name: a.Name, Email: a.Address}, nil
+}
+
+// String formats the address for a header, encoding the name if needed.
+func (a Address) String() string {
+	return (&netmail.Address{Name: a.Name, Address: a.Email}).String()
+}
+
+// Attachment is a file sent with a message. Inline attachments are
+// referenced from the HTML body as "cid:<ContentID>".
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType,omitempty"`
+	Data        []byte `json:"data"`
+	Inline      bool   `json:"inline,omitempty"`
+	ContentID   string `json:"contentId,omitempty"`
+}
+
+// Message is an email. It is JSON-encoded into the job payload when the
+// Mailer sends in the background, attachments included, so large files are
+// better linked than attached.
+type Message struct {
+	// ID identifies the message in the audit log and the Message-ID header;
+	// the Mailer assigns one when empty.
+	ID          string            `json:"id"`
+	From        Address           `json:"from"`
+	ReplyTo     []Address         `json:"replyTo,omitempty"`
+	To          []Address         `json:"to,omitempty"`
+	Cc          []Address         `json:"cc,omitempty"`
+	Bcc         []Address         `json:"bcc,omitempty"`
+	Subject     string            `json:"subject"`
+	Text        string            `json:"text,omitempty"`
+	HTML        string            `json:"html,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	// Template is the name the body was rendered from, for the audit log.
+	Template string `json:"template,omitempty"`
+	// Metadata is recorded with every attempt in the audit log and passed
+	// to API providers as custom arguments or tags, e.g. tenant_id, user_id
+	// or category. The "tenant_id" entry also scopes the delivery job.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Recipients returns the To, Cc and Bcc addresses.
+func (m *Message) Recipients() []Address {
+	out := make([]Address, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	out = append(out, m.To...)
+	out = append(out, m.Cc...)
+	return append(out, m.Bcc...)
+}
+
+// Validate reports missing senders, recipients or bodies and malformed
+// addresses.
+func (m *Message) Validate() error {
+	if m.From.Email == "" {
+		return ErrNoSender
+	}
+	recipients := m.Recipients()
+	if len(recipients) == 0 {
+		return ErrNoRecipients
+	}
+	if m.Text == "" && m.HTML == "" {
+		return ErrNoBody
+	}
+	for _, a := range append(append(recipients, m.From), m.ReplyTo...) {
+		if _, err := netmail.ParseAddress(a.Email); err != nil || strings.ContainsAny(a.Email, "\r\n") {
+			return fmt.Errorf("mail: invalid address %q", a.Email)
+		}
+	}
+	return nil
+}
+
+// Sender delivers a message through one provider and returns the
+// provider's message ID (empty when the provider has none). Errors that
+// retrying cannot fix are wrapped with retry.Permanent.
+type Sender interface {
+	Send(ctx context.Context, msg *Message) (string, error)
+}
+
+// SenderFunc adapts a function to Sender.
+type SenderFunc func(ctx context.Context, msg *Message) (string, error)
+
+// Send implements Sender.
+func (f SenderFunc) Send(ctx context.Context, msg *Message) (string, error) {
+	return f(ctx, msg)
+}
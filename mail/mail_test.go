@@ -0,0 +1,400 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	netmail "net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/leeforge/framework/jobs"
+	"github.com/leeforge/framework/retry"
+)
+
+func testMessage() *Message {
+	return &Message{
+		ID:      "m1",
+		From:    Address{Name: "Acme", Email: "no-reply@acme.io"},
+		To:      []Address{{Name: "Zoë", Email: "zoe@example.com"}},
+		Bcc:     []Address{{Email: "audit@acme.io"}},
+		Subject: "Héllo",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	}
+}
+
+func TestBuild(t *testing.T) {
+	msg := testMessage()
+	msg.Attachments = []Attachment{
+		{Filename: "logo.png", Data: []byte("png"), Inline: true, ContentID: "logo"},
+		{Filename: "report.csv", Data: []byte(strings.Repeat("a,b\n", 40))},
+	}
+	raw, err := msg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := netmail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if subject != "Héllo" || parsed.Header.Get("Bcc") != "" || parsed.Header.Get("Message-Id") != "<m1@acme.io>" {
+		t.Errorf("headers = %v", parsed.Header)
+	}
+
+	// mixed[related[alternative[text, html], logo], report]
+	mediaType, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("top-level type = %s", mediaType)
+	}
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	related, _ := mr.NextPart()
+	if ct := related.Header.Get("Content-Type"); !strings.HasPrefix(ct, "multipart/related") {
+		t.Errorf("first part = %s", ct)
+	}
+	report, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(report)
+	decoded, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(data), "\r\n", ""))
+	if report.FileName() != "report.csv" || string(decoded) != strings.Repeat("a,b\n", 40) {
+		t.Errorf("attachment %q = %q", report.FileName(), decoded)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tt := range []struct {
+		mutate func(*Message)
+		want   error
+	}{
+		{func(m *Message) { m.From = Address{} }, ErrNoSender},
+		{func(m *Message) { m.To, m.Bcc = nil, nil }, ErrNoRecipients},
+		{func(m *Message) { m.Text, m.HTML = "", "" }, ErrNoBody},
+	} {
+		msg := testMessage()
+		tt.mutate(msg)
+		if err := msg.Validate(); !errors.Is(err, tt.want) {
+			t.Errorf("Validate = %v, want %v", err, tt.want)
+		}
+	}
+	msg := testMessage()
+	msg.To[0].Email = "victim@example.com\r\nBcc: x@evil.io"
+	if err := msg.Validate(); err == nil {
+		t.Error("header injection in an address should be rejected")
+	}
+}
+
+func testTemplates(t *testing.T) *Templates {
+	t.Helper()
+	tmpl, err := ParseTemplates(fstest.MapFS{
+		"layouts/default.html": {Data: []byte(`<html>{{template "content" .}}{{template "footer"}}</html>`)},
+		"layouts/default.txt":  {Data: []byte("{{template \"content\" .}}\n-- Acme")},
+		"layouts/bare.html":    {Data: []byte(`<div>{{template "content" .}}</div>`)},
+		"partials/footer.html": {Data: []byte(`{{define "footer"}}<footer>bye</footer>{{end}}`)},
+		"welcome.html":         {Data: []byte(`{{define "subject"}}Welcome, {{.Name}} & co{{end}}<p>Hi {{.Name}}</p>`)},
+		"welcome.txt":          {Data: []byte(`Hi {{.Name}}`)},
+		"auth/reset.html":      {Data: []byte(`{{define "layout"}}bare{{end}}{{define "subject"}}Reset{{end}}<a href="{{.URL}}">reset</a>`)},
+	}, TemplateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpl
+}
+
+func TestTemplates(t *testing.T) {
+	tmpl := testTemplates(t)
+	c, err := tmpl.Render("welcome", map[string]string{"Name": "<Bob>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Subject != "Welcome, <Bob> & co" {
+		t.Errorf("subject = %q", c.Subject)
+	}
+	if c.HTML != "<html><p>Hi &lt;Bob&gt;</p><footer>bye</footer></html>" {
+		t.Errorf("html = %q", c.HTML)
+	}
+	if c.Text != "Hi <Bob>\n-- Acme" {
+		t.Errorf("text = %q", c.Text)
+	}
+
+	c, err = tmpl.Render("auth/reset", map[string]string{"URL": "javascript:alert(1)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Subject != "Reset" || c.HTML != `<div><a href="#ZgotmplZ">reset</a></div>` || c.Text != "" {
+		t.Errorf("reset = %+v", c)
+	}
+
+	if _, err := tmpl.Render("missing", nil); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Render(missing) = %v", err)
+	}
+	if names := tmpl.Names(); strings.Join(names, ",") != "auth/reset,welcome" {
+		t.Errorf("Names = %v", names)
+	}
+}
+
+// scriptedSender fails with the queued errors, then succeeds.
+type scriptedSender struct {
+	mu   sync.Mutex
+	errs []error
+	sent []Message
+}
+
+func (s *scriptedSender) Send(_ context.Context, msg *Message) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return "", err
+	}
+	s.sent = append(s.sent, *msg)
+	return "provider-" + msg.ID, nil
+}
+
+func TestMailerSendTemplate(t *testing.T) {
+	sender := &scriptedSender{}
+	m := NewMailer(sender, Options{From: Address{Email: "no-reply@acme.io"}, Templates: testTemplates(t)})
+	msg := &Message{To: []Address{{Email: "bob@example.com"}}, Metadata: map[string]string{"user_id": "u1"}}
+	if err := m.SendTemplate(context.Background(), "welcome", map[string]string{"Name": "Bob"}, msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID == "" || len(sender.sent) != 1 || sender.sent[0].From.Email != "no-reply@acme.io" || sender.sent[0].Subject != "Welcome, Bob & co" {
+		t.Fatalf("sent = %+v", sender.sent)
+	}
+	records, _ := m.Audit().List(context.Background(), AuditQuery{Metadata: map[string]string{"user_id": "u1"}})
+	if len(records) != 1 || records[0].Status != StatusSent || records[0].Template != "welcome" || records[0].ProviderID != "provider-"+msg.ID {
+		t.Errorf("audit = %+v", records)
+	}
+}
+
+func newJobManager(t *testing.T) *jobs.Manager {
+	t.Helper()
+	manager := jobs.NewManager(jobs.Options{
+		Workers:     2,
+		MaxAttempts: 3,
+		Backoff:     func(int) time.Duration { return time.Millisecond },
+	})
+	t.Cleanup(func() { manager.Stop(context.Background()) })
+	return manager
+}
+
+func waitForRecords(t *testing.T, log AuditLog, query AuditQuery, n int) []*Record {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		records, _ := log.List(context.Background(), query)
+		if len(records) >= n || time.Now().After(deadline) {
+			return records
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMailerRetriesThroughJobs(t *testing.T) {
+	sender := &scriptedSender{errs: []error{errors.New("connection reset")}}
+	manager := newJobManager(t)
+	m := NewMailer(sender, Options{Jobs: manager})
+	manager.Start()
+
+	msg := testMessage()
+	msg.ID = ""
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	// 同一 ID 再次发送只入队一次
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	records := waitForRecords(t, m.Audit(), AuditQuery{MessageID: msg.ID}, 2)
+	if len(records) != 2 || records[0].Status != StatusSent || records[0].Attempt != 2 ||
+		records[1].Status != StatusFailed || records[1].JobID == "" {
+		t.Fatalf("audit = %+v", records)
+	}
+	if len(sender.sent) != 1 {
+		t.Errorf("sent %d times, want once", len(sender.sent))
+	}
+}
+
+func TestMailerPermanentRejectionIsNotRetried(t *testing.T) {
+	sender := &scriptedSender{errs: []error{retry.Permanent(errors.New("550 mailbox unavailable"))}}
+	manager := newJobManager(t)
+	m := NewMailer(sender, Options{Jobs: manager})
+	manager.Start()
+
+	msg := testMessage()
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	waitForRecords(t, m.Audit(), AuditQuery{MessageID: msg.ID}, 1)
+	// 等待可能的重试
+	time.Sleep(20 * time.Millisecond)
+	records, _ := m.Audit().List(context.Background(), AuditQuery{MessageID: msg.ID})
+	if len(records) != 1 || records[0].Status != StatusRejected {
+		t.Errorf("audit = %+v", records)
+	}
+}
+
+// fakeSMTP accepts one session and records the envelope and data.
+func fakeSMTP(t *testing.T, rcptReply string) (addr string, got chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	got = make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		var session strings.Builder
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250 fake")
+			case strings.HasPrefix(cmd, "MAIL"):
+				session.WriteString(strings.TrimSpace(line) + "\n")
+				reply("250 ok")
+			case strings.HasPrefix(cmd, "RCPT"):
+				session.WriteString(strings.TrimSpace(line) + "\n")
+				reply(rcptReply)
+			case cmd == "DATA":
+				reply("354 go ahead")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					session.WriteString(l)
+				}
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				got <- session.String()
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), got
+}
+
+func smtpSender(addr string) *SMTPSender {
+	host, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	return NewSMTPSender(SMTPConfig{Host: host, Port: p, Security: SMTPPlain, Timeout: 2 * time.Second})
+}
+
+func TestSMTPSender(t *testing.T) {
+	addr, got := fakeSMTP(t, "250 ok")
+	id, err := smtpSender(addr).Send(context.Background(), testMessage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := <-got
+	if id != "<m1@acme.io>" || !strings.Contains(session, "RCPT TO:<audit@acme.io>") ||
+		!strings.Contains(session, "Subject: =?utf-8?q?H=C3=A9llo?=") || strings.Contains(session, "Bcc:") {
+		t.Errorf("id %q, session:\n%s", id, session)
+	}
+}
+
+func TestSMTPSenderPermanentReply(t *testing.T) {
+	addr, _ := fakeSMTP(t, "550 no such user")
+	_, err := smtpSender(addr).Send(context.Background(), testMessage())
+	if !retry.IsPermanent(err) {
+		t.Errorf("err = %v, want permanent", err)
+	}
+}
+
+func TestSendGridSender(t *testing.T) {
+	var payload map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/mail/send" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("request %s auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Header().Set("X-Message-Id", "sg-1")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	msg := testMessage()
+	msg.Metadata = map[string]string{"tenant_id": "t1"}
+	id, err := NewSendGridSender(SendGridConfig{APIKey: "key", Endpoint: srv.URL}).Send(context.Background(), msg)
+	if err != nil || id != "sg-1" {
+		t.Fatalf("Send = %q, %v", id, err)
+	}
+	p := payload["personalizations"].([]any)[0].(map[string]any)
+	if p["custom_args"].(map[string]any)["tenant_id"] != "t1" || len(p["bcc"].([]any)) != 1 || p["cc"] != nil {
+		t.Errorf("personalization = %v", p)
+	}
+	if contents := payload["content"].([]any); contents[0].(map[string]any)["type"] != "text/plain" {
+		t.Errorf("content = %v", contents)
+	}
+}
+
+func TestSESSender(t *testing.T) {
+	status := http.StatusOK
+	var body map[string]any
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(status)
+		io.WriteString(w, `{"MessageId":"ses-1"}`)
+	}))
+	defer srv.Close()
+
+	s := NewSESSender(SESConfig{Region: "eu-west-1", AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: srv.URL})
+	s.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	msg := testMessage()
+	msg.Metadata = map[string]string{"category": "sign up"}
+	id, err := s.Send(context.Background(), msg)
+	if err != nil || id != "ses-1" {
+		t.Fatalf("Send = %q, %v", id, err)
+	}
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20260102/eu-west-1/ses/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=") {
+		t.Errorf("Authorization = %q", auth)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(body["Content"].(map[string]any)["Raw"].(map[string]any)["Data"].(string))
+	tags := body["EmailTags"].([]any)
+	if !strings.Contains(string(raw), "Message-Id: <m1@acme.io>") || tags[0].(map[string]any)["Value"] != "sign_up" {
+		t.Errorf("body = %v", body)
+	}
+
+	status = http.StatusBadRequest
+	if _, err := s.Send(context.Background(), msg); !retry.IsPermanent(err) {
+		t.Errorf("400 should be permanent: %v", err)
+	}
+	status = http.StatusServiceUnavailable
+	if _, err := s.Send(context.Background(), msg); err == nil || retry.IsPermanent(err) {
+		t.Errorf("503 should be retryable: %v", err)
+	}
+}
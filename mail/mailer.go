@@ -0,0 +1,281 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/jobs"
+	"github.com/leeforge/framework/retry"
+	"go.uber.org/zap"
+)
+
+// JobTypeSend is the job type of a background delivery.
+const JobTypeSend = "mail.send"
+
+// Status is the outcome of a delivery attempt.
+type Status string
+
+const (
+	// StatusSent means the provider accepted the message.
+	StatusSent Status = "sent"
+	// StatusFailed means the attempt failed and may be retried.
+	StatusFailed Status = "failed"
+	// StatusRejected means the provider refused the message permanently;
+	// it is not retried.
+	StatusRejected Status = "rejected"
+)
+
+// Record is one delivery attempt in the AuditLog.
+type Record struct {
+	ID         string            `json:"id"`
+	MessageID  string            `json:"messageId"`
+	ProviderID string            `json:"providerId,omitempty"`
+	JobID      string            `json:"jobId,omitempty"`
+	Template   string            `json:"template,omitempty"`
+	Subject    string            `json:"subject"`
+	From       string            `json:"from"`
+	To         []string          `json:"to"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Attempt    int               `json:"attempt"`
+	Status     Status            `json:"status"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+}
+
+// AuditQuery filters AuditLog.List.
+type AuditQuery struct {
+	MessageID string
+	Status    Status
+	// Metadata matches records carrying every given entry.
+	Metadata map[string]string
+	// Limit defaults to 100.
+	Limit int
+}
+
+func (q AuditQuery) match(r *Record) bool {
+	if q.MessageID != "" && r.MessageID != q.MessageID {
+		return false
+	}
+	if q.Status != "" && r.Status != q.Status {
+		return false
+	}
+	for k, v := range q.Metadata {
+		if r.Metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// AuditLog records delivery attempts.
+type AuditLog interface {
+	Record(ctx context.Context, record *Record) error
+	// List returns matching records, newest first.
+	List(ctx context.Context, query AuditQuery) ([]*Record, error)
+}
+
+// MemoryAuditLog keeps the most recent records in memory.
+type MemoryAuditLog struct {
+	mu       sync.RWMutex
+	capacity int
+	records  []*Record
+}
+
+// NewMemoryAuditLog creates a log holding at most capacity records
+// (default 1000); the oldest are dropped first.
+func NewMemoryAuditLog(capacity int) *MemoryAuditLog {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &MemoryAuditLog{capacity: capacity}
+}
+
+// Record implements AuditLog.
+func (l *MemoryAuditLog) Record(_ context.Context, record *Record) error {
+	cp := *record
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.records) >= l.capacity {
+		l.records = append(l.records[:0], l.records[1:]...)
+	}
+	l.records = append(l.records, &cp)
+	return nil
+}
+
+// List implements AuditLog.
+func (l *MemoryAuditLog) List(_ context.Context, query AuditQuery) ([]*Record, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var out []*Record
+	for i := len(l.records) - 1; i >= 0 && len(out) < limit; i-- {
+		if r := l.records[i]; query.match(r) {
+			cp := *r
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+// Options configures a Mailer.
+type Options struct {
+	// From is used for messages without a sender.
+	From Address
+	// ReplyTo is used for messages without Reply-To addresses.
+	ReplyTo []Address
+	// Templates renders SendTemplate bodies.
+	Templates *Templates
+	// Jobs, when set, makes Send queue a JobTypeSend job and return; the
+	// manager's backoff, attempt limit and dead-letter queue apply.
+	// Without it Send delivers synchronously, once.
+	Jobs *jobs.Manager
+	// MaxAttempts overrides the job manager's attempt limit per message.
+	MaxAttempts int
+	// Audit records every attempt (default in memory, 1000 records).
+	Audit  AuditLog
+	Logger *zap.Logger
+}
+
+// Mailer sends messages through a Sender, filling defaults, rendering
+// templates and auditing every attempt.
+type Mailer struct {
+	sender Sender
+	opts   Options
+}
+
+// NewMailer creates a mailer. With Options.Jobs it registers the delivery
+// job, so it must be called before the manager starts.
+func NewMailer(sender Sender, opts Options) *Mailer {
+	if opts.Audit == nil {
+		opts.Audit = NewMemoryAuditLog(0)
+	}
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	m := &Mailer{sender: sender, opts: opts}
+	if opts.Jobs != nil {
+		opts.Jobs.Register(JobTypeSend, m.deliverJob)
+	}
+	return m
+}
+
+// Audit returns the audit log.
+func (m *Mailer) Audit() AuditLog {
+	return m.opts.Audit
+}
+
+// Send validates msg and delivers it, or queues it when the Mailer has a
+// job manager; a nil error then means the message was queued. Send assigns
+// msg.ID when empty. Sending a message with the same ID twice queues it
+// once.
+func (m *Mailer) Send(ctx context.Context, msg *Message) error {
+	if msg.ID == "" {
+		msg.ID = uuid.NewString()
+	}
+	if msg.From.Email == "" {
+		msg.From = m.opts.From
+	}
+	if len(msg.ReplyTo) == 0 {
+		msg.ReplyTo = m.opts.ReplyTo
+	}
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+
+	if m.opts.Jobs == nil {
+		_, err := m.deliver(ctx, msg, "", 1)
+		return err
+	}
+	opts := []jobs.EnqueueOption{jobs.WithIdempotencyKey("mail:" + msg.ID)}
+	if m.opts.MaxAttempts > 0 {
+		opts = append(opts, jobs.WithMaxAttempts(m.opts.MaxAttempts))
+	}
+	if tenant := msg.Metadata[jobs.TenantMetadataKey]; tenant != "" {
+		opts = append(opts, jobs.WithTenant(tenant))
+	}
+	_, err := m.opts.Jobs.Enqueue(ctx, JobTypeSend, msg, opts...)
+	if err != nil && !errors.Is(err, jobs.ErrDuplicate) {
+		return fmt.Errorf("mail: queue %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// SendTemplate renders the template called name with data into msg and
+// sends it. A subject already set on msg wins over the template's.
+func (m *Mailer) SendTemplate(ctx context.Context, name string, data any, msg *Message) error {
+	if m.opts.Templates == nil {
+		return fmt.Errorf("%w: %s (no templates configured)", ErrTemplateNotFound, name)
+	}
+	content, err := m.opts.Templates.Render(name, data)
+	if err != nil {
+		return err
+	}
+	if msg.Subject == "" {
+		msg.Subject = content.Subject
+	}
+	msg.HTML, msg.Text, msg.Template = content.HTML, content.Text, name
+	return m.Send(ctx, msg)
+}
+
+// deliverJob is the JobTypeSend handler. Permanent rejections end the job
+// without further attempts.
+func (m *Mailer) deliverJob(ctx context.Context, job *jobs.Job) (any, error) {
+	var msg Message
+	if err := job.Decode(&msg); err != nil {
+		return nil, err
+	}
+	providerID, err := m.deliver(ctx, &msg, job.ID, job.Attempts)
+	if retry.IsPermanent(err) {
+		return map[string]string{"status": string(StatusRejected), "error": err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"status": string(StatusSent), "providerId": providerID}, nil
+}
+
+func (m *Mailer) deliver(ctx context.Context, msg *Message, jobID string, attempt int) (string, error) {
+	providerID, err := m.sender.Send(ctx, msg)
+
+	to := make([]string, 0, len(msg.To)+len(msg.Cc)+len(msg.Bcc))
+	for _, a := range msg.Recipients() {
+		to = append(to, a.Email)
+	}
+	r := &Record{
+		ID:         uuid.NewString(),
+		MessageID:  msg.ID,
+		ProviderID: providerID,
+		JobID:      jobID,
+		Template:   msg.Template,
+		Subject:    msg.Subject,
+		From:       msg.From.Email,
+		To:         to,
+		Metadata:   msg.Metadata,
+		Attempt:    attempt,
+		Status:     StatusSent,
+		CreatedAt:  time.Now(),
+	}
+	if err != nil {
+		r.Status, r.Error = StatusFailed, err.Error()
+		if retry.IsPermanent(err) {
+			r.Status = StatusRejected
+		}
+		m.opts.Logger.Warn("mail delivery failed",
+			zap.String("message_id", msg.ID),
+			zap.String("to", strings.Join(to, ",")),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+	}
+	if auditErr := m.opts.Audit.Record(context.WithoutCancel(ctx), r); auditErr != nil {
+		m.opts.Logger.Error("mail audit record failed", zap.String("message_id", msg.ID), zap.Error(auditErr))
+	}
+	return providerID, err
+}
@@ -0,0 +1,195 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Build encodes msg as an RFC 5322 message for SMTP and raw-message APIs.
+// Bcc recipients are left out of the headers. The body is
+// multipart/alternative when both Text and HTML are set, wrapped in
+// multipart/related for inline attachments and multipart/mixed for the
+// others.
+func (m *Message) Build() ([]byte, error) {
+	h := textproto.MIMEHeader{}
+	h.Set("From", m.From.String())
+	if len(m.To) > 0 {
+		h.Set("To", joinAddresses(m.To))
+	}
+	if len(m.Cc) > 0 {
+		h.Set("Cc", joinAddresses(m.Cc))
+	}
+	if len(m.ReplyTo) > 0 {
+		h.Set("Reply-To", joinAddresses(m.ReplyTo))
+	}
+	h.Set("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	h.Set("Date", time.Now().Format(time.RFC1123Z))
+	if id := m.headerID(); id != "" {
+		h.Set("Message-ID", id)
+	}
+	h.Set("MIME-Version", "1.0")
+	for k, v := range m.Headers {
+		if strings.ContainsAny(k+v, "\r\n") {
+			return nil, fmt.Errorf("mail: invalid header %q", k)
+		}
+		h.Set(k, v)
+	}
+
+	body := textEntity("text/plain", m.Text)
+	switch {
+	case m.Text != "" && m.HTML != "":
+		body = multipartEntity("alternative", body, textEntity("text/html", m.HTML))
+	case m.HTML != "":
+		body = textEntity("text/html", m.HTML)
+	}
+	var inline, attached []entity
+	for _, a := range m.Attachments {
+		if a.Inline {
+			inline = append(inline, attachmentEntity(a))
+		} else {
+			attached = append(attached, attachmentEntity(a))
+		}
+	}
+	if len(inline) > 0 {
+		body = multipartEntity("related", append([]entity{body}, inline...)...)
+	}
+	if len(attached) > 0 {
+		body = multipartEntity("mixed", append([]entity{body}, attached...)...)
+	}
+
+	for k, v := range body.header {
+		h[k] = v
+	}
+	var buf bytes.Buffer
+	writeHeader(&buf, h)
+	if err := body.write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// entity is a MIME part: its content headers and a body writer.
+type entity struct {
+	header textproto.MIMEHeader
+	write  func(w io.Writer) error
+}
+
+func textEntity(contentType, body string) entity {
+	return entity{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		},
+		write: func(w io.Writer) error {
+			qp := quotedprintable.NewWriter(w)
+			if _, err := io.WriteString(qp, body); err != nil {
+				return err
+			}
+			return qp.Close()
+		},
+	}
+}
+
+func multipartEntity(subtype string, parts ...entity) entity {
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	return entity{
+		header: textproto.MIMEHeader{
+			"Content-Type": {"multipart/" + subtype + "; boundary=" + boundary},
+		},
+		write: func(w io.Writer) error {
+			mw := multipart.NewWriter(w)
+			if err := mw.SetBoundary(boundary); err != nil {
+				return err
+			}
+			for _, p := range parts {
+				pw, err := mw.CreatePart(p.header)
+				if err != nil {
+					return err
+				}
+				if err := p.write(pw); err != nil {
+					return err
+				}
+			}
+			return mw.Close()
+		},
+	}
+}
+
+func attachmentEntity(a Attachment) entity {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(a.Filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	disposition := "attachment"
+	if a.Inline {
+		disposition = "inline"
+	}
+	h := textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename})},
+	}
+	if a.ContentID != "" {
+		h.Set("Content-ID", "<"+a.ContentID+">")
+	}
+	return entity{header: h, write: func(w io.Writer) error {
+		// RFC 2045 limits base64 lines to 76 characters.
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+				return err
+			}
+			encoded = encoded[76:]
+		}
+		_, err := io.WriteString(w, encoded+"\r\n")
+		return err
+	}}
+}
+
+// writeHeader writes h in sorted order followed by the blank line.
+func writeHeader(w io.Writer, h textproto.MIMEHeader) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			fmt.Fprintf(w, "%s: %s\r\n", k, v)
+		}
+	}
+	io.WriteString(w, "\r\n")
+}
+
+func joinAddresses(addrs []Address) string {
+	parts := make([]string, len(addrs))
+	for i, a := range addrs {
+		parts[i] = a.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// headerID returns the Message-ID header value for m.ID, or "" without ID.
+func (m *Message) headerID() string {
+	if m.ID == "" {
+		return ""
+	}
+	domain := "localhost"
+	if i := strings.LastIndexByte(m.From.Email, '@'); i >= 0 {
+		domain = m.From.Email[i+1:]
+	}
+	return "<" + m.ID + "@" + domain + ">"
+}
@@ -0,0 +1,172 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"github.com/leeforge/framework/retry"
+)
+
+// SMTPSecurity selects how an SMTP connection is encrypted.
+type SMTPSecurity string
+
+const (
+	// SMTPStartTLS upgrades the connection with STARTTLS and fails when
+	// the server does not offer it (default).
+	SMTPStartTLS SMTPSecurity = "starttls"
+	// SMTPImplicitTLS connects over TLS, usually on port 465.
+	SMTPImplicitTLS SMTPSecurity = "tls"
+	// SMTPPlain sends unencrypted, e.g. to a local relay or test server.
+	SMTPPlain SMTPSecurity = "none"
+)
+
+// SMTPConfig configures an SMTPSender.
+type SMTPConfig struct {
+	Host string `mapstructure:"host" validate:"required"`
+	// Port defaults to 465 for SMTPImplicitTLS and 587 otherwise.
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// Security defaults to SMTPStartTLS.
+	Security SMTPSecurity `mapstructure:"security"`
+	// TLSConfig overrides the TLS settings; ServerName defaults to Host.
+	TLSConfig *tls.Config `mapstructure:"-"`
+	// LocalName is sent in EHLO (default "localhost").
+	LocalName string `mapstructure:"local_name"`
+	// Timeout bounds a whole delivery when ctx has no earlier deadline
+	// (default 30s).
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// SMTPSender sends each message over a new SMTP connection.
+type SMTPSender struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPSender creates an SMTP sender.
+func NewSMTPSender(cfg SMTPConfig) *SMTPSender {
+	if cfg.Security == "" {
+		cfg.Security = SMTPStartTLS
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+		if cfg.Security == SMTPImplicitTLS {
+			cfg.Port = 465
+		}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &SMTPSender{cfg: cfg}
+}
+
+// Send implements Sender. 5xx replies are permanent errors; the returned
+// ID is the Message-ID header value.
+func (s *SMTPSender) Send(ctx context.Context, msg *Message) (string, error) {
+	if err := msg.Validate(); err != nil {
+		return "", retry.Permanent(err)
+	}
+	raw, err := msg.Build()
+	if err != nil {
+		return "", retry.Permanent(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return "", fmt.Errorf("mail: smtp connect: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// Abort a blocked exchange as soon as ctx is canceled.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := s.deliver(conn, msg, raw); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("mail: smtp: %w", ctx.Err())
+		}
+		var reply *textproto.Error
+		if errors.As(err, &reply) && reply.Code >= 500 {
+			return "", retry.Permanent(fmt.Errorf("mail: smtp: %w", err))
+		}
+		return "", fmt.Errorf("mail: smtp: %w", err)
+	}
+	return msg.headerID(), nil
+}
+
+func (s *SMTPSender) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	if s.cfg.Security == SMTPImplicitTLS {
+		d := &tls.Dialer{Config: s.tlsConfig()}
+		return d.DialContext(ctx, "tcp", addr)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+func (s *SMTPSender) deliver(conn net.Conn, msg *Message, raw []byte) error {
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if s.cfg.LocalName != "" {
+		if err := c.Hello(s.cfg.LocalName); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Security == SMTPStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS")
+		}
+		if err := c.StartTLS(s.tlsConfig()); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(msg.From.Email); err != nil {
+		return err
+	}
+	for _, rcpt := range msg.Recipients() {
+		if err := c.Rcpt(rcpt.Email); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (s *SMTPSender) tlsConfig() *tls.Config {
+	cfg := &tls.Config{}
+	if s.cfg.TLSConfig != nil {
+		cfg = s.cfg.TLSConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = s.cfg.Host
+	}
+	return cfg
+}
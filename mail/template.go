@@ -0,0 +1,228 @@
+package mail
+
+import (
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// TemplateOptions configures ParseTemplates.
+type TemplateOptions struct {
+	// Layout is the layout pages use unless they define a "layout" block
+	// naming another one, or an empty one to opt out (default "default").
+	// Pages are rendered without a layout when the default one is missing.
+	Layout string
+	// Funcs are available to every template.
+	Funcs map[string]any
+}
+
+// Content is a rendered template.
+type Content struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Templates renders email bodies from a directory of templates:
+//
+//	layouts/default.html   shared chrome around {{template "content" .}}
+//	layouts/default.txt
+//	partials/button.html   {{define "button"}}...{{end}}, usable by every page
+//	welcome.html           the HTML body, optionally {{define "subject"}}...{{end}}
+//	welcome.txt            the text body
+//	auth/reset.html        rendered as "auth/reset"
+//
+// A page's top-level content is the "content" block of its layout. A page
+// needs an .html or a .txt file, or both; the subject comes from the
+// "subject" block of the text page, else of the HTML page. HTML pages use
+// html/template, so data is escaped for its context.
+type Templates struct {
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+}
+
+// source is a template file without its extension.
+type source struct {
+	name, text string
+}
+
+// ParseTemplates parses every .html and .txt file in fsys.
+func ParseTemplates(fsys fs.FS, opts TemplateOptions) (*Templates, error) {
+	if opts.Layout == "" {
+		opts.Layout = "default"
+	}
+	files := map[string][]source{}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := path.Ext(p)
+		if ext != ".html" && ext != ".txt" {
+			return nil
+		}
+		text, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		files[ext] = append(files[ext], source{name: strings.TrimSuffix(p, ext), text: string(text)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mail: read templates: %w", err)
+	}
+
+	t := &Templates{html: map[string]*htmltemplate.Template{}, text: map[string]*texttemplate.Template{}}
+	htmlSets, err := pageSets(files[".html"], opts)
+	if err != nil {
+		return nil, err
+	}
+	textSets, err := pageSets(files[".txt"], opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, set := range htmlSets {
+		tmpl, err := parseSet(htmltemplate.New("content").Funcs(opts.Funcs), set)
+		if err != nil {
+			return nil, err
+		}
+		t.html[set[0].name] = tmpl
+	}
+	for _, set := range textSets {
+		tmpl, err := parseSet(texttemplate.New("content").Funcs(opts.Funcs), set)
+		if err != nil {
+			return nil, err
+		}
+		t.text[set[0].name] = tmpl
+	}
+	return t, nil
+}
+
+// pageSets returns, for every page, the sources of its template set: the
+// page, the partials, and the entry point "mail" that renders the layout.
+func pageSets(sources []source, opts TemplateOptions) ([][]source, error) {
+	var partials []source
+	layouts := map[string]string{}
+	var pages []source
+	for _, s := range sources {
+		switch {
+		case strings.HasPrefix(s.name, "partials/"):
+			partials = append(partials, s)
+		case strings.HasPrefix(s.name, "layouts/"):
+			layouts[strings.TrimPrefix(s.name, "layouts/")] = s.text
+		default:
+			pages = append(pages, s)
+		}
+	}
+
+	sets := make([][]source, 0, len(pages))
+	for _, page := range pages {
+		entry := `{{template "content" .}}`
+		layout, explicit := layoutOf(page.text, opts)
+		if text, ok := layouts[layout]; ok {
+			entry = text
+		} else if explicit && layout != "" {
+			return nil, fmt.Errorf("mail: template %s: layout %q not found", page.name, layout)
+		}
+		set := append([]source{page}, partials...)
+		sets = append(sets, append(set, source{name: "mail", text: entry}))
+	}
+	return sets, nil
+}
+
+// layoutOf returns the layout named by the page's "layout" block, or the
+// default layout when the page has none.
+func layoutOf(page string, opts TemplateOptions) (string, bool) {
+	t, err := texttemplate.New("page").Funcs(opts.Funcs).Parse(page)
+	if err != nil || t.Lookup("layout") == nil {
+		// Parse errors surface when the page set is parsed.
+		return opts.Layout, false
+	}
+	var b strings.Builder
+	if err := t.ExecuteTemplate(&b, "layout", nil); err != nil {
+		return opts.Layout, false
+	}
+	return strings.TrimSpace(b.String()), true
+}
+
+// parser is what parseSet needs from html/template and text/template.
+type parser[T any] interface {
+	New(name string) T
+	Parse(text string) (T, error)
+}
+
+// parseSet parses set into root; the first source is parsed as root itself.
+func parseSet[T parser[T]](root T, set []source) (T, error) {
+	if _, err := root.Parse(set[0].text); err != nil {
+		return root, fmt.Errorf("mail: parse template %s: %w", set[0].name, err)
+	}
+	for _, s := range set[1:] {
+		if _, err := root.New(s.name).Parse(s.text); err != nil {
+			return root, fmt.Errorf("mail: parse template %s for %s: %w", s.name, set[0].name, err)
+		}
+	}
+	return root, nil
+}
+
+// Names returns the page names, sorted.
+func (t *Templates) Names() []string {
+	seen := map[string]bool{}
+	for name := range t.html {
+		seen[name] = true
+	}
+	for name := range t.text {
+		seen[name] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render executes the HTML and text pages called name with data.
+func (t *Templates) Render(name string, data any) (*Content, error) {
+	htmlTmpl, hasHTML := t.html[name]
+	textTmpl, hasText := t.text[name]
+	if !hasHTML && !hasText {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	c := &Content{}
+	var b strings.Builder
+	if hasHTML {
+		if err := htmlTmpl.ExecuteTemplate(&b, "mail", data); err != nil {
+			return nil, fmt.Errorf("mail: render %s.html: %w", name, err)
+		}
+		c.HTML = b.String()
+		if htmlTmpl.Lookup("subject") != nil {
+			b.Reset()
+			if err := htmlTmpl.ExecuteTemplate(&b, "subject", data); err != nil {
+				return nil, fmt.Errorf("mail: render %s.html subject: %w", name, err)
+			}
+			// The subject is a header, not markup.
+			c.Subject = html.UnescapeString(b.String())
+		}
+	}
+	if hasText {
+		b.Reset()
+		if err := textTmpl.ExecuteTemplate(&b, "mail", data); err != nil {
+			return nil, fmt.Errorf("mail: render %s.txt: %w", name, err)
+		}
+		c.Text = b.String()
+		if textTmpl.Lookup("subject") != nil {
+			b.Reset()
+			if err := textTmpl.ExecuteTemplate(&b, "subject", data); err != nil {
+				return nil, fmt.Errorf("mail: render %s.txt subject: %w", name, err)
+			}
+			c.Subject = b.String()
+		}
+	}
+	c.Subject = strings.Join(strings.Fields(c.Subject), " ")
+	return c, nil
+}
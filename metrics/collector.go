@@ -3,120 +3,295 @@ package metrics
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"math"
 	"net/http"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/leeforge/framework/config"
+	"github.com/leeforge/framework/security"
 )
 
-// Collector 指标收集器
+// Collector 指标收集器。序列按键分片存储，counter 与 gauge 以原子操作更新，
+// 已存在序列的写入只需分片读锁；mu 仅保护配置与首次创建序列时的命名校验
 type Collector struct {
-	metrics map[string]*Metric
-	mu      sync.RWMutex
+	series *store
+	mu     sync.RWMutex
+
+	hotRoutes   *TopK
+	slowQueries *TopK
+	errorCodes  *TopK
+
+	naming    *namingChecker
+	relabeler *Relabeler
+
+	buckets   map[string][]float64
+	summaries map[string]SummaryOptions
 }
 
 // Metric 指标
 type Metric struct {
-	Type      string            `json:"type"`
-	Value     float64           `json:"value"`
-	Labels    map[string]string `json:"labels,omitempty"`
-	History   []float64         `json:"history,omitempty"`
-	Timestamp int64             `json:"timestamp"`
+	Name   string            `json:"name,omitempty"`
+	Type   string            `json:"type"`
+	Value  float64           `json:"value"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// History 直方图与摘要最近 100 个观测值，仅供调试；分布统计使用
+	// Buckets 与 Quantile
+	History []float64 `json:"history,omitempty"`
+	// Count、Sum 直方图与摘要自创建以来的观测次数与总和
+	Count uint64  `json:"count,omitempty"`
+	Sum   float64 `json:"sum,omitempty"`
+	// Buckets 直方图的累计桶，+Inf 桶即 Count
+	Buckets []Bucket `json:"buckets,omitempty"`
+	// Quantiles 摘要各目标分位数在窗口内的估计值，键为分位数（如 "0.99"），
+	// 由 Export 填充
+	Quantiles map[string]float64 `json:"quantiles,omitempty"`
+	Timestamp int64              `json:"timestamp"`
+
+	summary *Summary
+}
+
+// historySize 直方图与摘要保留的最近观测值数量
+const historySize = 100
+
+// Quantile 估计 q 分位数：摘要使用窗口内的流式估计，直方图在桶内线性插值。
+// 其他类型或没有观测值时返回 NaN
+func (m *Metric) Quantile(q float64) float64 {
+	switch {
+	case m.summary != nil:
+		return m.summary.Quantile(q)
+	case m.Type == "histogram":
+		return bucketQuantile(q, m.Buckets, m.Count)
+	default:
+		return math.NaN()
+	}
+}
+
+// Mean 返回直方图与摘要的平均值，没有观测值时返回 0
+func (m *Metric) Mean() float64 {
+	if m.Count == 0 {
+		return 0
+	}
+	return m.Sum / float64(m.Count)
+}
+
+// clone 复制指标，切片与映射不与原指标共享
+func (m *Metric) clone() *Metric {
+	cp := *m
+	cp.History = append([]float64(nil), m.History...)
+	cp.Buckets = append([]Bucket(nil), m.Buckets...)
+	cp.Quantiles = maps.Clone(m.Quantiles)
+	cp.fillQuantiles()
+	return &cp
+}
+
+// fillQuantiles 为摘要填充 Quantiles
+func (m *Metric) fillQuantiles() {
+	if m.summary == nil || m.Quantiles != nil {
+		return
+	}
+	m.Quantiles = make(map[string]float64, len(m.summary.objectives))
+	for q, v := range m.summary.Quantiles() {
+		m.Quantiles[formatFloat(q)] = v
+	}
+}
+
+// observe 记录直方图或摘要的观测值，调用方需持有序列的锁
+func (m *Metric) observe(value float64) {
+	m.History = append(m.History, value)
+	if len(m.History) > historySize {
+		m.History = m.History[1:]
+	}
+	m.Value = value
+	m.Count++
+	m.Sum += value
+	if m.Buckets != nil {
+		observeBuckets(m.Buckets, value)
+	}
+	if m.summary != nil {
+		m.summary.Observe(value)
+	}
+	m.Timestamp = time.Now().Unix()
+}
+
+// merge 将同类型指标 o 的分布合并进 m。桶上界不一致时不合并桶；
+// 摘要分位数无法合并，保留观测次数较多的一方
+func (m *Metric) merge(o *Metric) {
+	m.History = append(m.History, o.History...)
+	if o.Count > m.Count && o.Quantiles != nil {
+		m.Quantiles = o.Quantiles
+	}
+	m.Count += o.Count
+	m.Sum += o.Sum
+	mergeBuckets(m.Buckets, o.Buckets)
 }
 
 // NewCollector 创建指标收集器
 func NewCollector() *Collector {
 	return &Collector{
-		metrics: make(map[string]*Metric),
+		series:      newStore(),
+		hotRoutes:   NewTopK(DefaultTopKCapacity),
+		slowQueries: NewTopK(DefaultTopKCapacity),
+		errorCodes:  NewTopK(DefaultTopKCapacity),
 	}
 }
 
-// IncCounter 增加计数器
-func (c *Collector) IncCounter(name string, labels map[string]string) {
+// SetNamingPolicy 设置指标命名校验策略，之后首次使用的指标会按策略校验
+func (c *Collector) SetNamingPolicy(policy NamingPolicy) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.naming = newNamingChecker(policy)
+}
 
-	key := c.buildKey(name, labels)
-	if metric, exists := c.metrics[key]; exists {
-		metric.Value++
-		metric.Timestamp = time.Now().Unix()
-	} else {
-		c.metrics[key] = &Metric{
-			Type:      "counter",
-			Value:     1,
-			Labels:    labels,
-			Timestamp: time.Now().Unix(),
-		}
+// SetHistogramBuckets 设置直方图 name 的桶上界，需在该指标首次观测前调用。
+// 未设置时 _bytes 结尾的指标使用 DefaultSizeBuckets，其余使用 DefaultBuckets
+func (c *Collector) SetHistogramBuckets(name string, buckets []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.buckets == nil {
+		c.buckets = make(map[string][]float64)
 	}
+	c.buckets[name] = append([]float64(nil), buckets...)
 }
 
-// AddCounter 增加计数器值
-func (c *Collector) AddCounter(name string, value float64, labels map[string]string) {
+// SetSummaryOptions 设置摘要 name 的分位数目标与窗口，需在该指标首次观测前调用
+func (c *Collector) SetSummaryOptions(name string, opts SummaryOptions) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-
-	key := c.buildKey(name, labels)
-	if metric, exists := c.metrics[key]; exists {
-		metric.Value += value
-		metric.Timestamp = time.Now().Unix()
-	} else {
-		c.metrics[key] = &Metric{
-			Type:      "counter",
-			Value:     value,
-			Labels:    labels,
-			Timestamp: time.Now().Unix(),
-		}
+	if c.summaries == nil {
+		c.summaries = make(map[string]SummaryOptions)
 	}
+	c.summaries[name] = opts
 }
 
-// SetGauge 设置仪表值
-func (c *Collector) SetGauge(name string, value float64, labels map[string]string) {
+// SetRelabeler 设置导出时的重写规则，nil 表示不重写。只影响 Export 的结果，
+// GetMetrics / GetMetric 仍返回原始数据
+func (c *Collector) SetRelabeler(r *Relabeler) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.relabeler = r
+}
 
+// checkNaming 在指标首次使用时校验命名
+func (c *Collector) checkNaming(metricType, name string, labels map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.naming != nil {
+		c.naming.check(metricType, name, labels)
+	}
+}
+
+// newSeries 返回创建 counter 或 gauge 序列的函数
+func (c *Collector) newSeries(metricType, name string, labels map[string]string) func() *series {
+	return func() *series {
+		c.checkNaming(metricType, name, labels)
+		return &series{name: name, typ: metricType, labels: labels}
+	}
+}
+
+// IncCounter 增加计数器
+func (c *Collector) IncCounter(name string, labels map[string]string) {
+	c.AddCounter(name, 1, labels)
+}
+
+// AddCounter 增加计数器值
+func (c *Collector) AddCounter(name string, value float64, labels map[string]string) {
+	key := c.buildKey(name, labels)
+	c.series.getOrCreate(key, c.newSeries("counter", name, labels)).add(value)
+}
+
+// SetGauge 设置仪表值
+func (c *Collector) SetGauge(name string, value float64, labels map[string]string) {
 	key := c.buildKey(name, labels)
-	c.metrics[key] = &Metric{
-		Type:      "gauge",
-		Value:     value,
-		Labels:    labels,
-		Timestamp: time.Now().Unix(),
+	sr := c.series.getOrCreate(key, c.newSeries("gauge", name, labels))
+	if sr.typ != "gauge" {
+		// 同名同标签的序列改作 gauge 时整体替换
+		sr = c.series.replace(key, func() *series { return &series{name: name, typ: "gauge", labels: labels} })
 	}
+	sr.set(value)
 }
 
-// ObserveHistogram 观察直方图
+// ObserveHistogram 观察直方图。观测值计入固定的累计桶，内存不随流量增长，
+// 分位数通过 Metric.Quantile 在桶内插值估计
 func (c *Collector) ObserveHistogram(name string, value float64, labels map[string]string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	key := c.buildKey(name, labels)
-	if metric, exists := c.metrics[key]; exists {
-		metric.History = append(metric.History, value)
-		if len(metric.History) > 100 {
-			metric.History = metric.History[1:]
-		}
-		metric.Timestamp = time.Now().Unix()
-	} else {
-		c.metrics[key] = &Metric{
-			Type:      "histogram",
-			Value:     value,
-			Labels:    labels,
-			History:   []float64{value},
-			Timestamp: time.Now().Unix(),
+	sr := c.series.getOrCreate(key, func() *series {
+		c.checkNaming("histogram", name, labels)
+		c.mu.RLock()
+		bounds, ok := c.buckets[name]
+		c.mu.RUnlock()
+		if !ok {
+			bounds = defaultBucketsFor(name)
 		}
+		return &series{name: name, typ: "histogram", labels: labels, dist: &Metric{
+			Name:    name,
+			Type:    "histogram",
+			Labels:  labels,
+			Buckets: newBuckets(bounds),
+		}}
+	})
+	if sr.dist != nil {
+		sr.observe(value)
+	}
+}
+
+// ObserveSummary 观察摘要。与直方图相比，摘要用 CKMS 流式算法直接估计
+// 目标分位数，精度不受桶划分影响，但不同序列的分位数无法合并
+func (c *Collector) ObserveSummary(name string, value float64, labels map[string]string) {
+	key := c.buildKey(name, labels)
+	sr := c.series.getOrCreate(key, func() *series {
+		c.checkNaming("summary", name, labels)
+		c.mu.RLock()
+		opts := c.summaries[name]
+		c.mu.RUnlock()
+		return &series{name: name, typ: "summary", labels: labels, dist: &Metric{
+			Name:    name,
+			Type:    "summary",
+			Labels:  labels,
+			summary: NewSummary(opts),
+		}}
+	})
+	if sr.dist != nil {
+		sr.observe(value)
 	}
 }
 
-// RecordRequest 记录 HTTP 请求
+// RecordRequest 记录 HTTP 请求。path 应为路由模板而非原始路径，
+// 否则 /users/{id} 这类路由的每个 ID 都会产生一个序列
 func (c *Collector) RecordRequest(method, path string, status int, duration float64) {
 	labels := map[string]string{
 		"method": method,
 		"path":   path,
 		"status": strconv.Itoa(status),
 	}
+	c.recordRequest(labels, method+" "+path, status, duration)
+}
 
+// recordRequest 以给定标签记录请求次数与耗时，route 计入热点路由统计
+func (c *Collector) recordRequest(labels map[string]string, route string, status int, duration float64) {
 	c.IncCounter("http_requests_total", labels)
 	c.ObserveHistogram("http_request_duration_seconds", duration, labels)
+
+	c.hotRoutes.Add(route, 1)
+	if status >= 400 {
+		c.errorCodes.Add(strconv.Itoa(status), 1)
+	}
+}
+
+// RecordResponseSize 记录 HTTP 响应体大小（字节）
+func (c *Collector) RecordResponseSize(method, path string, status int, size int64) {
+	labels := map[string]string{
+		"method": method,
+		"path":   path,
+		"status": strconv.Itoa(status),
+	}
+
+	c.ObserveHistogram("http_response_size_bytes", float64(size), labels)
 }
 
 // RecordDBQuery 记录数据库查询
@@ -127,6 +302,9 @@ func (c *Collector) RecordDBQuery(query string, duration float64) {
 
 	c.IncCounter("db_queries_total", labels)
 	c.ObserveHistogram("db_query_duration_seconds", duration, labels)
+
+	// 按累计耗时排序，兼顾慢且频繁的查询
+	c.slowQueries.Add(query, duration)
 }
 
 // RecordCacheHit 记录缓存命中
@@ -158,57 +336,105 @@ func (c *Collector) RecordError(method, path string, err error) {
 	c.IncCounter("http_errors_total", labels)
 }
 
-// buildKey 构建指标键
-func (c *Collector) buildKey(name string, labels map[string]string) string {
-	key := name
-	if len(labels) > 0 {
-		// 简化实现：按顺序添加标签
-		for k, v := range labels {
-			key += ":" + k + "=" + v
-		}
+// RecordErrorCode 记录业务错误码，用于高频错误码统计
+func (c *Collector) RecordErrorCode(code string) {
+	if code == "" {
+		return
+	}
+	c.errorCodes.Add(code, 1)
+}
+
+// TopK 获取热点路由、慢查询与高频错误码的前 n 项
+func (c *Collector) TopK(n int) TopKSummary {
+	return TopKSummary{
+		HotRoutes:   c.hotRoutes.Top(n),
+		SlowQueries: c.slowQueries.Top(n),
+		ErrorCodes:  c.errorCodes.Top(n),
 	}
-	return key
 }
 
-// GetMetrics 获取所有指标
+// buildKey 构建指标键，见 MetricKey
+func (c *Collector) buildKey(name string, labels map[string]string) string {
+	return MetricKey(name, labels)
+}
+
+// GetMetrics 获取所有指标的快照
 func (c *Collector) GetMetrics() map[string]*Metric {
+	result := make(map[string]*Metric, c.series.len())
+	c.series.each(func(key string, sr *series) {
+		result[key] = sr.snapshot()
+	})
+	return result
+}
+
+// Export 返回导出用的指标：应用重写规则并以 SeriesKey 为键。
+// 各导出端点（JSON、Prometheus、事件转发、导出器注册表）都经由此方法取数
+func (c *Collector) Export() map[string]*Metric {
 	c.mu.RLock()
-	defer c.mu.RUnlock()
+	relabeler := c.relabeler
+	c.mu.RUnlock()
 
-	// 返回副本
-	result := make(map[string]*Metric)
-	for k, v := range c.metrics {
-		result[k] = v
+	metrics := c.GetMetrics()
+	for _, m := range metrics {
+		m.fillQuantiles()
 	}
-	return result
+	if relabeler == nil {
+		relabeler = &Relabeler{}
+	}
+	return relabeler.Relabel(metrics)
 }
 
-// GetMetric 获取单个指标
+// GetMetric 获取单个指标的快照，不存在时返回 nil
 func (c *Collector) GetMetric(name string, labels map[string]string) *Metric {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	return c.GetMetricByKey(c.buildKey(name, labels))
+}
 
-	key := c.buildKey(name, labels)
-	return c.metrics[key]
+// GetMetricByKey 按键获取单个指标的快照，兼容旧版未排序的键
+func (c *Collector) GetMetricByKey(key string) *Metric {
+	sr := c.series.get(key)
+	if sr == nil {
+		sr = c.series.get(MigrateKey(key))
+	}
+	if sr == nil {
+		return nil
+	}
+	return sr.snapshot()
 }
 
 // Reset 重置指标
 func (c *Collector) Reset() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.metrics = make(map[string]*Metric)
+	c.series.reset()
+	c.hotRoutes.Reset()
+	c.slowQueries.Reset()
+	c.errorCodes.Reset()
 }
 
-// MetricsMiddleware 指标中间件
+// MetricsMiddleware 指标中间件。path 标签取路由模板（chi 或 http.ServeMux），
+// 取不到时折叠原始路径中的 ID 段，并限制取值数
 type MetricsMiddleware struct {
 	collector *Collector
+	labels    map[string]bool // nil 表示记录全部标签
+	paths     *pathLimiter
 }
 
-// NewMetricsMiddleware 创建指标中间件
+// NewMetricsMiddleware 创建指标中间件，使用默认配置
 func NewMetricsMiddleware(collector *Collector) *MetricsMiddleware {
-	return &MetricsMiddleware{
+	return NewMetricsMiddlewareWithConfig(collector, HTTPMetricsConfig{})
+}
+
+// NewMetricsMiddlewareWithConfig 按配置创建指标中间件
+func NewMetricsMiddlewareWithConfig(collector *Collector, config HTTPMetricsConfig) *MetricsMiddleware {
+	m := &MetricsMiddleware{
 		collector: collector,
+		paths:     newPathLimiter(config.MaxPaths),
+	}
+	if len(config.Labels) > 0 {
+		m.labels = make(map[string]bool, len(config.Labels))
+		for _, l := range config.Labels {
+			m.labels[l] = true
+		}
 	}
+	return m
 }
 
 // Middleware HTTP 指标中间件
@@ -218,27 +444,75 @@ func (m *MetricsMiddleware) Middleware(next http.Handler) http.Handler {
 
 		// 包装 ResponseWriter
 		ww := &responseWriter{ResponseWriter: w, statusCode: 200}
+		r = withRouteContext(r)
 
 		next.ServeHTTP(ww, r)
 
 		duration := time.Since(start).Seconds()
 
 		// 记录指标
-		m.collector.RecordRequest(r.Method, r.URL.Path, ww.statusCode, duration)
+		path := m.routeLabel(r, ww.statusCode)
+		labels := m.requestLabels(r.Method, path, ww.statusCode)
+		m.collector.recordRequest(labels, r.Method+" "+path, ww.statusCode, duration)
+		m.collector.ObserveHistogram("http_response_size_bytes", float64(ww.size), labels)
 	})
 }
 
-// responseWriter 包装器
+// requestLabels 按白名单构建请求标签
+func (m *MetricsMiddleware) requestLabels(method, path string, status int) map[string]string {
+	labels := make(map[string]string, 3)
+	if m.allow(LabelMethod) {
+		labels[LabelMethod] = method
+	}
+	if m.allow(LabelPath) {
+		labels[LabelPath] = path
+	}
+	if m.allow(LabelStatus) {
+		labels[LabelStatus] = strconv.Itoa(status)
+	}
+	return labels
+}
+
+func (m *MetricsMiddleware) allow(label string) bool {
+	return m.labels == nil || m.labels[label]
+}
+
+// responseWriter 包装器，记录状态码与写入的字节数
+// 压缩中间件挂在外层时，这里看到的是压缩前的大小
 type responseWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode  int
+	wroteHeader bool
+	size        int64
 }
 
 func (w *responseWriter) WriteHeader(code int) {
-	w.statusCode = code
+	if !w.wroteHeader && code >= 200 {
+		w.statusCode = code
+		w.wroteHeader = true
+	}
 	w.ResponseWriter.WriteHeader(code)
 }
 
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush 透传给原始 ResponseWriter，流式响应（SSE）依赖它
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap 返回原始 ResponseWriter，供 http.ResponseController 与 WebSocket 升级使用
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // MetricsHandler 指标处理器
 type MetricsHandler struct {
 	collector *Collector
@@ -255,18 +529,29 @@ func NewMetricsHandler(collector *Collector) *MetricsHandler {
 func (h *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	metrics := h.collector.GetMetrics()
+	metrics := h.collector.Export()
 
 	// 格式化输出
 	data := make(map[string]interface{})
 	for key, metric := range metrics {
-		data[key] = map[string]interface{}{
+		item := map[string]interface{}{
 			"type":      metric.Type,
 			"value":     metric.Value,
 			"labels":    metric.Labels,
 			"history":   metric.History,
 			"timestamp": metric.Timestamp,
 		}
+		if metric.Type == "histogram" || metric.Type == "summary" {
+			item["count"] = metric.Count
+			item["sum"] = metric.Sum
+		}
+		if metric.Buckets != nil {
+			item["buckets"] = metric.Buckets
+		}
+		if metric.Quantiles != nil {
+			item["quantiles"] = metric.Quantiles
+		}
+		data[key] = item
 	}
 
 	json.NewEncoder(w).Encode(data)
@@ -274,24 +559,70 @@ func (h *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // MetricsConfig 指标配置
 type MetricsConfig struct {
-	EnableHTTPMetrics     bool
-	EnableDBMetrics       bool
-	EnableCacheMetrics    bool
-	EnableBusinessMetrics bool
+	EnableHTTPMetrics     bool `mapstructure:"enable-http-metrics"`
+	EnableDBMetrics       bool `mapstructure:"enable-db-metrics"`
+	EnableCacheMetrics    bool `mapstructure:"enable-cache-metrics"`
+	EnableBusinessMetrics bool `mapstructure:"enable-business-metrics"`
+	// Naming 指标命名校验模式：off / warn / strict / auto
+	Naming NamingMode `mapstructure:"naming" validate:"omitempty,oneof=off warn strict auto"`
+	// Relabel 导出时的序列过滤与标签重写规则
+	Relabel RelabelConfig `mapstructure:"relabel"`
+	// Runtime Go 运行时与进程指标自动采集
+	Runtime RuntimeMetricsConfig `mapstructure:"runtime"`
+	// Push 推送式导出（StatsD、OTLP）
+	Push PushConfig `mapstructure:"push"`
+	// HTTP HTTP 指标中间件的标签白名单与路径基数上限
+	HTTP HTTPMetricsConfig `mapstructure:"http"`
+}
+
+// newConfiguredCollector 按配置创建收集器。重写规则在配置加载时已校验，
+// 此处编译失败（绕过配置直接构造）时不启用重写
+func newConfiguredCollector(config MetricsConfig) *Collector {
+	collector := NewCollector()
+	collector.SetNamingPolicy(NamingPolicy{Mode: config.Naming})
+	if relabeler, err := NewRelabeler(config.Relabel); err == nil {
+		collector.SetRelabeler(relabeler)
+	}
+	return collector
 }
 
 // MetricsManager 指标管理器
 type MetricsManager struct {
 	collector *Collector
 	config    MetricsConfig
+	runtime   *RuntimeCollector
+	exporters *MetricsExporterRegistry
 }
 
-// NewMetricsManager 创建指标管理器
+// NewMetricsManager 创建指标管理器。启用 Runtime 时立即开始采集运行时指标，
+// 启用的推送导出器随即开始推送；应用退出时调用 Stop 停止
 func NewMetricsManager(config MetricsConfig) *MetricsManager {
-	return &MetricsManager{
-		collector: NewCollector(),
+	collector := newConfiguredCollector(config)
+	m := &MetricsManager{
+		collector: collector,
 		config:    config,
 	}
+	if config.Runtime.Enabled {
+		m.runtime = NewRuntimeCollector(collector, config.Runtime.Interval)
+		m.runtime.Start()
+	}
+	m.exporters = NewMetricsExporterRegistry()
+	registerPushExporters(m.exporters, config.Push)
+	m.exporters.Start(collector)
+	return m
+}
+
+// Stop 停止后台采集，推送导出器在停止前推送最后一次
+func (m *MetricsManager) Stop() {
+	if m.runtime != nil {
+		m.runtime.Stop()
+	}
+	m.exporters.Stop()
+}
+
+// GetExporterRegistry 获取导出器注册表，可在其上注册自定义推送导出器
+func (m *MetricsManager) GetExporterRegistry() *MetricsExporterRegistry {
+	return m.exporters
 }
 
 // GetCollector 获取收集器
@@ -299,9 +630,9 @@ func (m *MetricsManager) GetCollector() *Collector {
 	return m.collector
 }
 
-// GetHTTPMiddleware 获取 HTTP 中间件
+// GetHTTPMiddleware 获取按配置创建的 HTTP 中间件
 func (m *MetricsManager) GetHTTPMiddleware() *MetricsMiddleware {
-	return NewMetricsMiddleware(m.collector)
+	return NewMetricsMiddlewareWithConfig(m.collector, m.config.HTTP)
 }
 
 // GetMetricsHandler 获取指标处理器
@@ -322,18 +653,18 @@ func NewBusinessMetrics(collector *Collector) *BusinessMetrics {
 }
 
 // RecordUserAction 记录用户行为
+// userID 不作为标签：用户 ID 属于高基数标签（见 DefaultDeniedLabels），参数仅为兼容保留
 func (b *BusinessMetrics) RecordUserAction(userID, action string) {
 	labels := map[string]string{
-		"user_id": userID,
-		"action":  action,
+		"action": action,
 	}
 	b.collector.IncCounter("user_actions_total", labels)
 }
 
 // RecordOrder 记录订单
+// userID 不作为标签，原因同 RecordUserAction
 func (b *BusinessMetrics) RecordOrder(userID string, amount float64, success bool) {
 	labels := map[string]string{
-		"user_id": userID,
 		"success": strconv.FormatBool(success),
 	}
 	b.collector.IncCounter("orders_total", labels)
@@ -402,8 +733,7 @@ func (d *MetricsDashboard) GetSummary() map[string]interface{} {
 
 	// 计算总数
 	var httpRequests, dbQueries, cacheHits, cacheMisses int64
-	var avgHTTPDuration, avgDBDuration float64
-	var httpCount, dbCount int
+	var httpDuration, dbDuration Metric
 
 	for key, metric := range metrics {
 		switch {
@@ -419,20 +749,12 @@ func (d *MetricsDashboard) GetSummary() map[string]interface{} {
 				cacheMisses += int64(metric.Value)
 			}
 		case metric.Type == "histogram":
-			if len(metric.History) > 0 {
-				var sum float64
-				for _, v := range metric.History {
-					sum += v
-				}
-				avg := sum / float64(len(metric.History))
-				switch {
-				case keyContains(key, "http_request_duration"):
-					avgHTTPDuration += avg
-					httpCount++
-				case keyContains(key, "db_query_duration"):
-					avgDBDuration += avg
-					dbCount++
-				}
+			// 同名直方图的桶上界一致，合并所有序列后估计整体分位数
+			switch {
+			case keyContains(key, "http_request_duration"):
+				mergeDistribution(&httpDuration, metric)
+			case keyContains(key, "db_query_duration"):
+				mergeDistribution(&dbDuration, metric)
 			}
 		}
 	}
@@ -443,16 +765,42 @@ func (d *MetricsDashboard) GetSummary() map[string]interface{} {
 	if cacheHits+cacheMisses > 0 {
 		summary["cache_hit_rate"] = float64(cacheHits) / float64(cacheHits+cacheMisses) * 100
 	}
-	if httpCount > 0 {
-		summary["avg_http_duration"] = avgHTTPDuration / float64(httpCount)
-	}
-	if dbCount > 0 {
-		summary["avg_db_duration"] = avgDBDuration / float64(dbCount)
-	}
+	addDurationSummary(summary, "http", &httpDuration)
+	addDurationSummary(summary, "db", &dbDuration)
+
+	top := d.collector.TopK(10)
+	summary["top_routes"] = top.HotRoutes
+	summary["top_slow_queries"] = top.SlowQueries
+	summary["top_error_codes"] = top.ErrorCodes
 
 	return summary
 }
 
+// mergeDistribution 将直方图 m 的观测次数、总和与桶累加到 dst
+func mergeDistribution(dst *Metric, m *Metric) {
+	if dst.Buckets == nil {
+		dst.Type = m.Type
+		dst.Buckets = append([]Bucket(nil), m.Buckets...)
+		dst.Count = m.Count
+		dst.Sum = m.Sum
+		return
+	}
+	dst.Count += m.Count
+	dst.Sum += m.Sum
+	mergeBuckets(dst.Buckets, m.Buckets)
+}
+
+// addDurationSummary 写入 avg_<prefix>_duration 与 <prefix>_duration_p50/p95/p99
+func addDurationSummary(summary map[string]interface{}, prefix string, m *Metric) {
+	if m.Count == 0 {
+		return
+	}
+	summary["avg_"+prefix+"_duration"] = m.Mean()
+	summary[prefix+"_duration_p50"] = m.Quantile(0.5)
+	summary[prefix+"_duration_p95"] = m.Quantile(0.95)
+	summary[prefix+"_duration_p99"] = m.Quantile(0.99)
+}
+
 // keyContains 检查 key 是否包含指定字符串
 func keyContains(key, substr string) bool {
 	return len(key) >= len(substr) && (key == substr || len(key) > len(substr) && (key[:len(substr)] == substr || key[len(key)-len(substr):] == substr || containsSubstring(key, substr)))
@@ -473,15 +821,8 @@ func (d *MetricsDashboard) GetSlowQueries(threshold float64) []string {
 	var slow []string
 
 	for key, metric := range metrics {
-		if metric.Type == "histogram" && len(metric.History) > 0 {
-			var sum float64
-			for _, v := range metric.History {
-				sum += v
-			}
-			avg := sum / float64(len(metric.History))
-			if avg > threshold {
-				slow = append(slow, key)
-			}
+		if metric.Type == "histogram" && metric.Count > 0 && metric.Mean() > threshold {
+			slow = append(slow, key)
 		}
 	}
 
@@ -512,14 +853,8 @@ func NewMetricsHTTPExporter(collector *Collector, config MetricsExporterConfig)
 
 // ServeHTTP 实现 http.Handler
 func (e *MetricsHTTPExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// 认证检查
-	if e.config.EnableAuth {
-		username, password, ok := r.BasicAuth()
-		if !ok || username != e.config.Username || password != e.config.Password {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("Unauthorized"))
-			return
-		}
+	if !e.authorize(w, r) {
+		return
 	}
 
 	// 导出指标
@@ -527,9 +862,60 @@ func (e *MetricsHTTPExporter) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	handler.ServeHTTP(w, r)
 }
 
+// TopKHandler 返回与导出器共用认证配置的 TopK 管理端点
+func (e *MetricsHTTPExporter) TopKHandler() http.Handler {
+	handler := NewTopKHandler(e.collector)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !e.authorize(w, r) {
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// authorize 认证检查
+func (e *MetricsHTTPExporter) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if !e.config.EnableAuth {
+		return true
+	}
+	username, password, ok := r.BasicAuth()
+	if !ok || !credentialsMatch(username, password, e.config.Username, e.config.Password) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Unauthorized"))
+		return false
+	}
+	return true
+}
+
+// constantTimeEqual 比较凭证，测试中可替换以统计调用
+var constantTimeEqual = security.ConstantTimeEqual
+
+// credentialsMatch 用 security.ConstantTimeEqual 比较用户名与密码
+// 两项总会都比较（不短路），耗时不暴露哪一项不匹配、匹配的前缀或凭证长度
+func credentialsMatch(username, password, wantUsername, wantPassword string) bool {
+	userOK := constantTimeEqual(username, wantUsername)
+	passOK := constantTimeEqual(password, wantPassword)
+	return userOK && passOK
+}
+
 // RegisterMetricsRoutes 注册指标路由
 func RegisterMetricsRoutes(mux *http.ServeMux, exporter *MetricsHTTPExporter) {
 	mux.Handle("/metrics", exporter)
+	mux.Handle("/metrics/topk", exporter.TopKHandler())
+}
+
+// SectionName 指标配置在配置文件中的顶层节点名
+const SectionName = "metrics"
+
+func init() {
+	config.RegisterSection(config.SectionSchema[MetricsConfig]{
+		Name:    SectionName,
+		Default: DefaultMetricsConfig,
+		OnLoad: func(c MetricsConfig) error {
+			_, err := NewRelabeler(c.Relabel)
+			return err
+		},
+	})
 }
 
 // DefaultMetricsConfig 默认配置
@@ -539,6 +925,10 @@ func DefaultMetricsConfig() MetricsConfig {
 		EnableDBMetrics:       true,
 		EnableCacheMetrics:    true,
 		EnableBusinessMetrics: true,
+		Runtime: RuntimeMetricsConfig{
+			Enabled:  true,
+			Interval: DefaultRuntimeInterval,
+		},
 	}
 }
 
@@ -588,18 +978,26 @@ func (e *PrometheusExporter) Export(metrics map[string]*Metric) error {
 }
 
 func (e *PrometheusExporter) GetPrometheusFormat() string {
-	metrics := e.collector.GetMetrics()
+	metrics := e.collector.Export()
 	var sb strings.Builder
 
-	for key, metric := range metrics {
+	keys := make([]string, 0, len(metrics))
+	for key := range metrics {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		metric := metrics[key]
 		labels := ""
-		if metric.Labels != nil && len(metric.Labels) > 0 {
+		if len(metric.Labels) > 0 {
 			var labelPairs []string
-			for k, v := range metric.Labels {
-				labelPairs = append(labelPairs, k+"=\""+v+"\"")
+			for _, k := range sortedKeys(metric.Labels) {
+				labelPairs = append(labelPairs, k+"=\""+metric.Labels[k]+"\"")
 			}
 			labels = "{" + strings.Join(labelPairs, ",") + "}"
 		}
+		key = metric.Name
 
 		switch metric.Type {
 		case "counter":
@@ -607,21 +1005,38 @@ func (e *PrometheusExporter) GetPrometheusFormat() string {
 		case "gauge":
 			sb.WriteString(fmt.Sprintf("%s%s %.2f\n", key, labels, metric.Value))
 		case "histogram":
-			if len(metric.History) > 0 {
-				var sum float64
-				for _, v := range metric.History {
-					sum += v
-				}
-				avg := sum / float64(len(metric.History))
-				sb.WriteString(fmt.Sprintf("%s_avg%s %.2f\n", key, labels, avg))
-				sb.WriteString(fmt.Sprintf("%s_count%s %d\n", key, labels, len(metric.History)))
+			for _, b := range metric.Buckets {
+				sb.WriteString(fmt.Sprintf("%s_bucket%s %d\n", key, withLabel(labels, "le", formatFloat(b.UpperBound)), b.Count))
 			}
+			sb.WriteString(fmt.Sprintf("%s_bucket%s %d\n", key, withLabel(labels, "le", "+Inf"), metric.Count))
+			sb.WriteString(fmt.Sprintf("%s_sum%s %s\n", key, labels, formatFloat(metric.Sum)))
+			sb.WriteString(fmt.Sprintf("%s_count%s %d\n", key, labels, metric.Count))
+		case "summary":
+			for _, q := range slices.Sorted(maps.Keys(metric.Quantiles)) {
+				sb.WriteString(fmt.Sprintf("%s%s %s\n", key, withLabel(labels, "quantile", q), formatFloat(metric.Quantiles[q])))
+			}
+			sb.WriteString(fmt.Sprintf("%s_sum%s %s\n", key, labels, formatFloat(metric.Sum)))
+			sb.WriteString(fmt.Sprintf("%s_count%s %d\n", key, labels, metric.Count))
 		}
 	}
 
 	return sb.String()
 }
 
+// withLabel 在 Prometheus 标签串 labels（可为空）末尾追加 name="value"
+func withLabel(labels, name, value string) string {
+	pair := name + "=\"" + value + "\""
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+// formatFloat 以最短的精确形式格式化浮点数，用于 Prometheus 样本值与 le/quantile 标签
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
 type MetricsHealthCheck struct {
 	collector *Collector
 	threshold MetricsHealthThreshold
@@ -670,11 +1085,9 @@ func (h *MetricsHealthCheck) Check() HealthResult {
 
 	var totalDuration, durationCount float64
 	for key, metric := range metrics {
-		if metric.Type == "histogram" && keyContains(key, "http_request_duration") && len(metric.History) > 0 {
-			for _, v := range metric.History {
-				totalDuration += v
-			}
-			durationCount += float64(len(metric.History))
+		if metric.Type == "histogram" && keyContains(key, "http_request_duration") {
+			totalDuration += metric.Sum
+			durationCount += float64(metric.Count)
 		}
 	}
 
@@ -790,17 +1203,9 @@ func (a *MetricsAggregator) Aggregate() map[string]*Metric {
 		for key, metric := range metrics {
 			if existing, exists := aggregated[key]; exists {
 				existing.Value += metric.Value
-				if metric.History != nil {
-					existing.History = append(existing.History, metric.History...)
-				}
+				existing.merge(metric)
 			} else {
-				aggregated[key] = &Metric{
-					Type:      metric.Type,
-					Value:     metric.Value,
-					Labels:    metric.Labels,
-					History:   metric.History,
-					Timestamp: metric.Timestamp,
-				}
+				aggregated[key] = metric.clone()
 			}
 		}
 	}
@@ -808,6 +1213,9 @@ func (a *MetricsAggregator) Aggregate() map[string]*Metric {
 	return aggregated
 }
 
+// MetricsRateLimiter 按 key 限流并统计放行与拒绝次数
+//
+// Deprecated: 请求时间戳全部保存在内存中，使用 ratelimit.Limiter 替代
 type MetricsRateLimiter struct {
 	collector *Collector
 	limit     int64
@@ -842,13 +1250,13 @@ func (l *MetricsRateLimiter) Allow(key string) bool {
 		l.requests[key] = valid
 
 		if len(valid) >= int(l.limit) {
-			l.collector.IncCounter("rate_limit_exceeded", map[string]string{"key": key})
+			l.collector.IncCounter("rate_limit_exceeded_total", map[string]string{"key": key})
 			return false
 		}
 	}
 
 	l.requests[key] = append(l.requests[key], now)
-	l.collector.IncCounter("rate_limit_allowed", map[string]string{"key": key})
+	l.collector.IncCounter("rate_limit_allowed_total", map[string]string{"key": key})
 	return true
 }
 
@@ -918,7 +1326,7 @@ func NewMetricsEventForwarder(collector *Collector, forwarder func(string, map[s
 }
 
 func (f *MetricsEventForwarder) Forward() error {
-	metrics := f.collector.GetMetrics()
+	metrics := f.collector.Export()
 	data := make(map[string]interface{})
 	for key, metric := range metrics {
 		data[key] = map[string]interface{}{
@@ -949,14 +1357,20 @@ func TakeSnapshot(collector *Collector) MetricsSnapshot {
 	}
 }
 
+// MetricsExporterRegistry 导出器注册表。Register 注册的导出器由 ExportAll
+// 按需触发；RegisterPush 注册的导出器在 Start 后定期推送，见 push.go
 type MetricsExporterRegistry struct {
 	exporters map[string]MetricsExporter
+	pushes    map[string]*pushLoop
+	collector *Collector // Start 后非 nil
+	stopped   bool
 	mu        sync.RWMutex
 }
 
 func NewMetricsExporterRegistry() *MetricsExporterRegistry {
 	return &MetricsExporterRegistry{
 		exporters: make(map[string]MetricsExporter),
+		pushes:    make(map[string]*pushLoop),
 	}
 }
 
@@ -970,7 +1384,7 @@ func (r *MetricsExporterRegistry) ExportAll(collector *Collector) error {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	metrics := collector.GetMetrics()
+	metrics := collector.Export()
 	for name, exporter := range r.exporters {
 		if err := exporter.Export(metrics); err != nil {
 			return fmt.Errorf("exporter %s failed: %w", name, err)
@@ -994,7 +1408,7 @@ type MetricsCollector struct {
 }
 
 func NewMetricsCollector(config MetricsConfig) *MetricsCollector {
-	collector := NewCollector()
+	collector := newConfiguredCollector(config)
 	return &MetricsCollector{
 		Collector:       collector,
 		MetricsManager:  NewMetricsManager(config),
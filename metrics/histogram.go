@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// DefaultBuckets 默认直方图桶上界（秒），与 Prometheus 客户端一致
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets 以 _bytes 结尾的直方图默认桶上界：100B 到 100MB
+var DefaultSizeBuckets = ExponentialBuckets(100, 10, 7)
+
+// LinearBuckets 返回从 start 开始、间隔 width 的 count 个桶上界
+func LinearBuckets(start, width float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start + float64(i)*width
+	}
+	return buckets
+}
+
+// ExponentialBuckets 返回从 start 开始、按 factor 倍增的 count 个桶上界
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// Bucket 直方图累计桶：观测值 <= UpperBound 的次数。
+// +Inf 桶不单独存储，其计数即 Metric.Count
+type Bucket struct {
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
+
+// newBuckets 按上界创建空桶，上界去重并升序，忽略 +Inf
+func newBuckets(bounds []float64) []Bucket {
+	sorted := append([]float64(nil), bounds...)
+	sort.Float64s(sorted)
+	buckets := make([]Bucket, 0, len(sorted))
+	for _, b := range sorted {
+		if math.IsInf(b, +1) || math.IsNaN(b) {
+			continue
+		}
+		if n := len(buckets); n > 0 && buckets[n-1].UpperBound == b {
+			continue
+		}
+		buckets = append(buckets, Bucket{UpperBound: b})
+	}
+	return buckets
+}
+
+// defaultBucketsFor 按指标名选择默认桶：_bytes 结尾用大小桶，其余用耗时桶
+func defaultBucketsFor(name string) []float64 {
+	if strings.HasSuffix(name, "_bytes") {
+		return DefaultSizeBuckets
+	}
+	return DefaultBuckets
+}
+
+// observeBuckets 将 v 计入所有上界 >= v 的累计桶
+func observeBuckets(buckets []Bucket, v float64) {
+	i := sort.Search(len(buckets), func(i int) bool { return buckets[i].UpperBound >= v })
+	for ; i < len(buckets); i++ {
+		buckets[i].Count++
+	}
+}
+
+// mergeBuckets 将 src 累加到 dst，上界不一致时返回 false 且不修改 dst
+func mergeBuckets(dst, src []Bucket) bool {
+	if len(dst) != len(src) {
+		return false
+	}
+	for i := range dst {
+		if dst[i].UpperBound != src[i].UpperBound {
+			return false
+		}
+	}
+	for i := range dst {
+		dst[i].Count += src[i].Count
+	}
+	return true
+}
+
+// bucketQuantile 按 Prometheus histogram_quantile 的方式估计分位数：
+// 定位 q 所在的桶并在桶内线性插值。落在 +Inf 桶时返回最大的有限上界，
+// 没有观测值时返回 NaN
+func bucketQuantile(q float64, buckets []Bucket, count uint64) float64 {
+	if count == 0 || math.IsNaN(q) {
+		return math.NaN()
+	}
+	if q < 0 {
+		return math.Inf(-1)
+	}
+	if q > 1 {
+		return math.Inf(+1)
+	}
+
+	rank := q * float64(count)
+	i := sort.Search(len(buckets), func(i int) bool { return float64(buckets[i].Count) >= rank })
+	if i == len(buckets) {
+		if len(buckets) == 0 {
+			return math.NaN()
+		}
+		return buckets[len(buckets)-1].UpperBound
+	}
+
+	var lower, below float64
+	if i > 0 {
+		lower = buckets[i-1].UpperBound
+		below = float64(buckets[i-1].Count)
+	} else if buckets[0].UpperBound <= 0 {
+		// 第一个桶上界非正时没有可插值的下界
+		return buckets[0].UpperBound
+	}
+	inBucket := float64(buckets[i].Count) - below
+	if inBucket == 0 {
+		return buckets[i].UpperBound
+	}
+	return lower + (buckets[i].UpperBound-lower)*((rank-below)/inBucket)
+}
@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogram_BucketsAndQuantiles(t *testing.T) {
+	c := NewCollector()
+	c.SetHistogramBuckets("job_duration_seconds", LinearBuckets(0.1, 0.1, 10))
+	labels := map[string]string{"job": "sync"}
+	for i := 1; i <= 1000; i++ {
+		c.ObserveHistogram("job_duration_seconds", float64(i)/1000, labels)
+	}
+
+	m := c.GetMetric("job_duration_seconds", labels)
+	if m.Count != 1000 || math.Abs(m.Sum-500.5) > 1e-9 {
+		t.Fatalf("count = %d, sum = %v", m.Count, m.Sum)
+	}
+	if len(m.Buckets) != 10 || m.Buckets[0].Count != 100 || m.Buckets[9].Count != 1000 {
+		t.Errorf("buckets = %+v", m.Buckets)
+	}
+	if len(m.History) != historySize {
+		t.Errorf("history must stay bounded, got %d samples", len(m.History))
+	}
+	for _, q := range []float64{0.5, 0.95, 0.99} {
+		if got := m.Quantile(q); math.Abs(got-q) > 0.01 {
+			t.Errorf("p%v = %v, want ~%v", q*100, got, q)
+		}
+	}
+
+	c.ObserveHistogram("http_response_size_bytes", 2048, nil)
+	if b := c.GetMetric("http_response_size_bytes", nil).Buckets; len(b) != len(DefaultSizeBuckets) {
+		t.Errorf("_bytes histograms should default to size buckets, got %+v", b)
+	}
+}
+
+func TestBucketQuantile_Edges(t *testing.T) {
+	buckets := newBuckets([]float64{1, 2, math.Inf(+1), 1})
+	if len(buckets) != 2 {
+		t.Fatalf("bounds must be deduplicated without +Inf: %+v", buckets)
+	}
+	if !math.IsNaN(bucketQuantile(0.5, buckets, 0)) {
+		t.Error("empty histogram must return NaN")
+	}
+	observeBuckets(buckets, 5)
+	if got := bucketQuantile(0.5, buckets, 1); got != 2 {
+		t.Errorf("quantile in +Inf bucket = %v, want highest bound 2", got)
+	}
+}
+
+func TestSummary_Accuracy(t *testing.T) {
+	s := NewSummary(SummaryOptions{})
+	rng := rand.New(rand.NewSource(1))
+	values := make([]float64, 100000)
+	for i := range values {
+		values[i] = rng.ExpFloat64()
+		s.Observe(values[i])
+	}
+	sort.Float64s(values)
+
+	for q, eps := range DefaultObjectives {
+		got := s.Quantile(q)
+		// 估计值的秩与目标秩的偏差不超过 eps
+		rank := float64(sort.SearchFloat64s(values, got)) / float64(len(values))
+		if math.Abs(rank-q) > eps {
+			t.Errorf("q%v: estimate %v has rank %v, want within %v", q, got, rank, eps)
+		}
+	}
+	if n := len(s.streams[s.head].samples); n > 2000 {
+		t.Errorf("stream keeps %d samples, want bounded memory", n)
+	}
+}
+
+func TestSummary_Window(t *testing.T) {
+	s := NewSummary(SummaryOptions{MaxAge: time.Minute, AgeBuckets: 2})
+	for i := 0; i < 100; i++ {
+		s.Observe(1000)
+	}
+
+	s.mu.Lock()
+	s.rotate(time.Now().Add(30 * time.Second))
+	s.mu.Unlock()
+	s.Observe(1)
+	if got := s.Quantile(0.5); got != 1000 {
+		t.Errorf("old observations must stay in the window, got %v", got)
+	}
+
+	s.mu.Lock()
+	s.rotate(time.Now().Add(61 * time.Second))
+	s.mu.Unlock()
+	if got := s.Quantile(0.5); got != 1 {
+		t.Errorf("observations older than MaxAge must expire, got %v", got)
+	}
+}
+
+func TestPrometheusFormat_HistogramAndSummary(t *testing.T) {
+	c := NewCollector()
+	c.SetHistogramBuckets("rpc_duration_seconds", []float64{0.1, 1})
+	c.ObserveHistogram("rpc_duration_seconds", 0.05, map[string]string{"method": "Get"})
+	c.ObserveHistogram("rpc_duration_seconds", 0.5, map[string]string{"method": "Get"})
+	c.ObserveSummary("payload_size_bytes", 10, nil)
+
+	prom := NewPrometheusExporter(c).GetPrometheusFormat()
+	for _, want := range []string{
+		`rpc_duration_seconds_bucket{method="Get",le="0.1"} 1`,
+		`rpc_duration_seconds_bucket{method="Get",le="1"} 2`,
+		`rpc_duration_seconds_bucket{method="Get",le="+Inf"} 2`,
+		`rpc_duration_seconds_sum{method="Get"} 0.55`,
+		`rpc_duration_seconds_count{method="Get"} 2`,
+		`payload_size_bytes{quantile="0.99"} 10`,
+		`payload_size_bytes_count 1`,
+	} {
+		if !strings.Contains(prom, want) {
+			t.Errorf("missing %q in:\n%s", want, prom)
+		}
+	}
+
+	exported := c.Export()[`payload_size_bytes`]
+	if exported == nil || exported.Quantiles["0.5"] != 10 {
+		t.Errorf("exported summary = %+v", exported)
+	}
+}
+
+func TestDashboard_Percentiles(t *testing.T) {
+	c := NewCollector()
+	for i := 1; i <= 100; i++ {
+		path := "/a"
+		if i%2 == 0 {
+			path = "/b"
+		}
+		c.RecordRequest("GET", path, 200, float64(i)/100)
+	}
+
+	summary := NewMetricsDashboard(c).GetSummary()
+	p99, ok := summary["http_duration_p99"].(float64)
+	if !ok || p99 < 0.9 || p99 > 1 {
+		t.Errorf("p99 = %v, want across all routes", summary["http_duration_p99"])
+	}
+	if avg := summary["avg_http_duration"].(float64); math.Abs(avg-0.505) > 1e-9 {
+		t.Errorf("avg = %v", avg)
+	}
+}
+
+func BenchmarkObserveHistogram(b *testing.B) {
+	c := NewCollector()
+	labels := map[string]string{"route": "/users"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.ObserveHistogram("request_duration_seconds", float64(i%1000)/1000, labels)
+	}
+}
+
+func BenchmarkObserveSummary(b *testing.B) {
+	c := NewCollector()
+	labels := map[string]string{"route": "/users"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.ObserveSummary("request_duration_seconds", float64(i%1000)/1000, labels)
+	}
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 指标键格式：name:k1=v1:k2=v2，标签按名称升序；名称、标签名与标签值中的
+// '\'、':'、'=' 以 '\' 转义。同一组名称与标签总是得到同一个键
+const (
+	keySeparator = ':'
+	keyAssign    = '='
+	keyEscape    = '\\'
+)
+
+// inlineLabels 标签数不超过该值时在栈上排序，避免分配
+const inlineLabels = 8
+
+// MetricKey 返回名称与标签的规范键，与 Collector 内部键、GetMetrics 的键一致
+func MetricKey(name string, labels map[string]string) string {
+	if len(labels) == 0 && !needsEscape(name) {
+		return name
+	}
+
+	var inline [inlineLabels]labelPair
+	pairs := inline[:0]
+	if len(labels) > inlineLabels {
+		pairs = make([]labelPair, 0, len(labels))
+	}
+	size := len(name)
+	for k, v := range labels {
+		pairs = append(pairs, labelPair{k, v})
+		size += len(k) + len(v) + 2
+	}
+	// 标签通常很少，插入排序比 sort.Slice 更快且不分配
+	for i := 1; i < len(pairs); i++ {
+		for j := i; j > 0 && pairs[j].name < pairs[j-1].name; j-- {
+			pairs[j], pairs[j-1] = pairs[j-1], pairs[j]
+		}
+	}
+
+	var sb strings.Builder
+	sb.Grow(size)
+	writeEscaped(&sb, name)
+	for _, p := range pairs {
+		sb.WriteByte(keySeparator)
+		writeEscaped(&sb, p.name)
+		sb.WriteByte(keyAssign)
+		writeEscaped(&sb, p.value)
+	}
+	return sb.String()
+}
+
+type labelPair struct {
+	name, value string
+}
+
+func needsEscape(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case keyEscape, keySeparator, keyAssign:
+			return true
+		}
+	}
+	return false
+}
+
+func writeEscaped(sb *strings.Builder, s string) {
+	if !needsEscape(s) {
+		sb.WriteString(s)
+		return
+	}
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case keyEscape, keySeparator, keyAssign:
+			sb.WriteByte(keyEscape)
+		}
+		sb.WriteByte(s[i])
+	}
+}
+
+// ParseMetricKey 解析 MetricKey 生成的键。也接受旧版未排序、未转义的键，
+// 但旧键中的值若含 ':' 或 '=' 无法还原
+func ParseMetricKey(key string) (name string, labels map[string]string, err error) {
+	parts := splitUnescaped(key, keySeparator)
+	name = unescape(parts[0])
+	if len(parts) == 1 {
+		return name, nil, nil
+	}
+	labels = make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		kv := splitUnescaped(part, keyAssign)
+		if len(kv) < 2 {
+			return "", nil, fmt.Errorf("metrics: malformed label %q in key %q", part, key)
+		}
+		// 旧键的值可能含未转义的 '='
+		labels[unescape(kv[0])] = unescape(strings.Join(kv[1:], string(keyAssign)))
+	}
+	return name, labels, nil
+}
+
+// MigrateKey 将旧版键（标签顺序随机、未转义）改写为规范键，
+// 用于读取升级前保存的快照或按旧键查询。无法解析时原样返回
+func MigrateKey(key string) string {
+	name, labels, err := ParseMetricKey(key)
+	if err != nil {
+		return key
+	}
+	return MetricKey(name, labels)
+}
+
+// MigrateMetrics 按规范键重建指标映射，合并旧版键拆分出的重复序列，
+// 合并规则与导出重写一致
+func MigrateMetrics(metrics map[string]*Metric) map[string]*Metric {
+	keys := make([]string, 0, len(metrics))
+	for k := range metrics {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make(map[string]*Metric, len(metrics))
+	for _, k := range keys {
+		m := metrics[k]
+		key := MigrateKey(k)
+		if m.Name != "" {
+			key = MetricKey(m.Name, m.Labels)
+		}
+		existing, ok := out[key]
+		if !ok {
+			out[key] = m.clone()
+			continue
+		}
+		mergeSeries(existing, m)
+	}
+	return out
+}
+
+// splitUnescaped 按未转义的 sep 切分，保留转义符
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case keyEscape:
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescape(s string) string {
+	if strings.IndexByte(s, keyEscape) < 0 {
+		return s
+	}
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == keyEscape && i+1 < len(s) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMetricKey_Deterministic(t *testing.T) {
+	labels := map[string]string{"status": "200", "method": "GET", "path": "/users", "region": "eu", "zone": "a"}
+	want := "http_requests_total:method=GET:path=/users:region=eu:status=200:zone=a"
+	for i := 0; i < 50; i++ {
+		if got := MetricKey("http_requests_total", labels); got != want {
+			t.Fatalf("key = %q, want %q", got, want)
+		}
+	}
+	if got := MetricKey("up", nil); got != "up" {
+		t.Errorf("key without labels = %q", got)
+	}
+
+	many := make(map[string]string)
+	for i := 0; i < 12; i++ {
+		many[fmt.Sprintf("l%02d", i)] = "v"
+	}
+	if got := MetricKey("m", many); got[:12] != "m:l00=v:l01=" {
+		t.Errorf("key with many labels = %q", got)
+	}
+}
+
+func TestMetricKey_EscapingRoundTrip(t *testing.T) {
+	// 未转义时这两组标签会得到相同的键
+	a := map[string]string{"a": "1:b=2"}
+	b := map[string]string{"a": "1", "b": "2"}
+	if MetricKey("m", a) == MetricKey("m", b) {
+		t.Fatal("distinct label sets must not collide")
+	}
+
+	for _, labels := range []map[string]string{a, b, {`we\ird`: `x=y\`}} {
+		name, got, err := ParseMetricKey(MetricKey("ns:m", labels))
+		if err != nil || name != "ns:m" || len(got) != len(labels) {
+			t.Fatalf("parse = %q %v %v", name, got, err)
+		}
+		for k, v := range labels {
+			if got[k] != v {
+				t.Errorf("label %q = %q, want %q", k, got[k], v)
+			}
+		}
+	}
+
+	if _, _, err := ParseMetricKey("m:novalue"); err == nil {
+		t.Error("expected malformed label error")
+	}
+}
+
+func TestMigrateKeys(t *testing.T) {
+	if got := MigrateKey("hits_total:path=/a:method=GET"); got != "hits_total:method=GET:path=/a" {
+		t.Errorf("migrated = %q", got)
+	}
+
+	c := NewCollector()
+	c.IncCounter("hits_total", map[string]string{"path": "/a", "method": "GET"})
+	if m := c.GetMetricByKey("hits_total:path=/a:method=GET"); m == nil || m.Value != 1 {
+		t.Errorf("legacy key lookup = %+v", m)
+	}
+
+	// 升级前的快照中同一序列可能以不同顺序的键出现
+	migrated := MigrateMetrics(map[string]*Metric{
+		"hits_total:path=/a:method=GET": {Type: "counter", Value: 2},
+		"hits_total:method=GET:path=/a": {Type: "counter", Value: 3},
+		"temp:room=1":                   {Type: "gauge", Value: 20, Timestamp: 1},
+	})
+	if len(migrated) != 2 || migrated["hits_total:method=GET:path=/a"].Value != 5 || migrated["temp:room=1"].Value != 20 {
+		t.Errorf("migrated = %+v", migrated)
+	}
+}
+
+func BenchmarkMetricKey(b *testing.B) {
+	labels := map[string]string{"method": "GET", "path": "/users/{id}", "status": "200"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		MetricKey("http_requests_total", labels)
+	}
+}
+
+// BenchmarkLegacyKey 为改造前按 map 顺序拼接的实现，作为对照
+func BenchmarkLegacyKey(b *testing.B) {
+	labels := map[string]string{"method": "GET", "path": "/users/{id}", "status": "200"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		key := "http_requests_total"
+		for k, v := range labels {
+			key += ":" + k + "=" + v
+		}
+		_ = key
+	}
+}
+
+func BenchmarkIncCounter(b *testing.B) {
+	c := NewCollector()
+	labels := map[string]string{"method": "GET", "path": "/users/{id}", "status": "200"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.IncCounter("http_requests_total", labels)
+	}
+}
@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/leeforge/framework/env_mode"
+	"go.uber.org/zap"
+)
+
+// NamingMode 指标命名校验模式
+type NamingMode string
+
+const (
+	// NamingOff 不校验（默认）
+	NamingOff NamingMode = "off"
+	// NamingWarn 首次使用不合规指标时记录警告
+	NamingWarn NamingMode = "warn"
+	// NamingStrict 首次使用不合规指标时 panic，尽早暴露问题
+	NamingStrict NamingMode = "strict"
+	// NamingAuto 生产环境为 NamingWarn，开发/测试环境为 NamingStrict
+	NamingAuto NamingMode = "auto"
+)
+
+// DefaultDeniedLabels 默认禁止的高基数标签名
+var DefaultDeniedLabels = []string{
+	"user_id", "request_id", "trace_id", "span_id", "session_id",
+	"email", "ip", "client_ip", "uuid", "url",
+}
+
+// NamingPolicy 指标命名规范策略
+//
+// 规则遵循 Prometheus / OpenTelemetry 约定：
+//   - 指标名与标签名为 snake_case
+//   - counter 以 _total 结尾，其他类型不得以 _total 结尾
+//   - 使用基础单位：时长为 _seconds，大小为 _bytes，禁止 _ms、_kb 等后缀
+//   - 名称含 duration / latency 时必须带 _seconds
+//   - 同名指标不得以不同类型使用
+//   - 标签名不得为 DeniedLabels 中的高基数字段
+type NamingPolicy struct {
+	Mode NamingMode
+	// DeniedLabels 为空时使用 DefaultDeniedLabels
+	DeniedLabels []string
+	// Logger 用于 NamingWarn 输出，为空时使用 zap.L()
+	Logger *zap.Logger
+}
+
+// NamingError 指标命名不合规错误
+type NamingError struct {
+	Metric   string
+	Type     string
+	Problems []string
+}
+
+func (e *NamingError) Error() string {
+	return fmt.Sprintf("metrics: %s %q violates naming conventions: %s",
+		e.Type, e.Metric, strings.Join(e.Problems, "; "))
+}
+
+var (
+	snakeCasePattern = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+	// 非基础单位后缀及建议替换
+	nonBaseUnits = map[string]string{
+		"ms": "_seconds", "millis": "_seconds", "milliseconds": "_seconds",
+		"us": "_seconds", "microseconds": "_seconds",
+		"ns": "_seconds", "nanoseconds": "_seconds",
+		"minutes": "_seconds", "hours": "_seconds",
+		"kb": "_bytes", "kilobytes": "_bytes",
+		"mb": "_bytes", "megabytes": "_bytes",
+		"gb": "_bytes", "gigabytes": "_bytes",
+		"percent": "_ratio",
+	}
+)
+
+// ValidateMetricName 按命名规范校验指标，合规时返回 nil，否则返回 *NamingError
+//
+// denied 为空时使用 DefaultDeniedLabels。
+func ValidateMetricName(metricType, name string, labels map[string]string, denied []string) error {
+	if len(denied) == 0 {
+		denied = DefaultDeniedLabels
+	}
+
+	var problems []string
+	if !snakeCasePattern.MatchString(name) {
+		problems = append(problems, "name must be snake_case")
+	}
+
+	base := name
+	if metricType == "counter" {
+		if !strings.HasSuffix(name, "_total") {
+			problems = append(problems, "counter name must end with _total")
+		}
+		base = strings.TrimSuffix(name, "_total")
+	} else if strings.HasSuffix(name, "_total") {
+		problems = append(problems, fmt.Sprintf("%s name must not end with _total", metricType))
+	}
+
+	parts := strings.Split(base, "_")
+	if want, ok := nonBaseUnits[parts[len(parts)-1]]; ok {
+		problems = append(problems, fmt.Sprintf("use base unit suffix %s instead of _%s", want, parts[len(parts)-1]))
+	} else if (slices.Contains(parts, "duration") || slices.Contains(parts, "latency")) &&
+		!strings.HasSuffix(base, "_seconds") {
+		problems = append(problems, "duration metrics must end with _seconds")
+	}
+
+	names := make([]string, 0, len(labels))
+	for label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+	for _, label := range names {
+		switch {
+		case strings.HasPrefix(label, "__"):
+			problems = append(problems, fmt.Sprintf("label %q is reserved", label))
+		case !snakeCasePattern.MatchString(label):
+			problems = append(problems, fmt.Sprintf("label %q must be snake_case", label))
+		case slices.Contains(denied, label):
+			problems = append(problems, fmt.Sprintf("label %q is high-cardinality", label))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &NamingError{Metric: name, Type: metricType, Problems: problems}
+}
+
+// namingChecker 在指标首次使用时校验命名，由 Collector.mu 保护
+type namingChecker struct {
+	mode   NamingMode
+	denied []string
+	logger *zap.Logger
+
+	types map[string]string   // 指标名 -> 首次使用的类型
+	seen  map[string]struct{} // 已校验的 类型|名称|标签名 组合
+}
+
+func newNamingChecker(policy NamingPolicy) *namingChecker {
+	mode := policy.Mode
+	if mode == NamingAuto {
+		mode = NamingStrict
+		if env_mode.Mode() == env_mode.ProMode {
+			mode = NamingWarn
+		}
+	}
+	if mode == "" || mode == NamingOff {
+		return nil
+	}
+	logger := policy.Logger
+	if logger == nil {
+		logger = zap.L()
+	}
+	return &namingChecker{
+		mode:   mode,
+		denied: policy.DeniedLabels,
+		logger: logger,
+		types:  make(map[string]string),
+		seen:   make(map[string]struct{}),
+	}
+}
+
+func (n *namingChecker) check(metricType, name string, labels map[string]string) {
+	labelNames := make([]string, 0, len(labels))
+	for label := range labels {
+		labelNames = append(labelNames, label)
+	}
+	sort.Strings(labelNames)
+	seenKey := metricType + "|" + name + "|" + strings.Join(labelNames, ",")
+	if _, ok := n.seen[seenKey]; ok {
+		return
+	}
+	n.seen[seenKey] = struct{}{}
+
+	err := ValidateMetricName(metricType, name, labels, n.denied)
+	if prev, ok := n.types[name]; ok && prev != metricType {
+		problem := fmt.Sprintf("already used as %s", prev)
+		if ne, ok := err.(*NamingError); ok {
+			ne.Problems = append(ne.Problems, problem)
+		} else {
+			err = &NamingError{Metric: name, Type: metricType, Problems: []string{problem}}
+		}
+	} else if !ok {
+		n.types[name] = metricType
+	}
+	if err == nil {
+		return
+	}
+
+	if n.mode == NamingStrict {
+		panic(err)
+	}
+	n.logger.Warn("metric violates naming conventions",
+		zap.String("metric", name),
+		zap.String("type", metricType),
+		zap.Strings("problems", err.(*NamingError).Problems),
+	)
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestValidateMetricName(t *testing.T) {
+	cases := []struct {
+		typ, name string
+		labels    map[string]string
+		problem   string
+	}{
+		{"counter", "http_requests_total", map[string]string{"method": "GET"}, ""},
+		{"histogram", "http_request_duration_seconds", nil, ""},
+		{"gauge", "queue_size", nil, ""},
+		{"counter", "http_requests_total", nil, ""},
+		{"counter", "httpRequests_total", nil, "snake_case"},
+		{"counter", "http_requests", nil, "must end with _total"},
+		{"gauge", "queue_size_total", nil, "must not end with _total"},
+		{"histogram", "db_query_duration_ms", nil, "_seconds instead of _ms"},
+		{"gauge", "upload_size_kb", nil, "_bytes instead of _kb"},
+		{"histogram", "api_latency", nil, "must end with _seconds"},
+		{"counter", "gc_pause_duration_seconds_total", nil, ""},
+		{"counter", "logins_total", map[string]string{"user_id": "42"}, `"user_id" is high-cardinality`},
+		{"counter", "logins_total", map[string]string{"userId": "42"}, "must be snake_case"},
+		{"counter", "logins_total", map[string]string{"__name": "x"}, "reserved"},
+	}
+	for _, tc := range cases {
+		err := ValidateMetricName(tc.typ, tc.name, tc.labels, nil)
+		if tc.problem == "" {
+			if err != nil {
+				t.Errorf("%s %s: unexpected error %v", tc.typ, tc.name, err)
+			}
+			continue
+		}
+		var ne *NamingError
+		if !errors.As(err, &ne) || !strings.Contains(err.Error(), tc.problem) {
+			t.Errorf("%s %s: error %v, want %q", tc.typ, tc.name, err, tc.problem)
+		}
+	}
+
+	if err := ValidateMetricName("counter", "logins_total", map[string]string{"user_id": "1"}, []string{"tenant"}); err != nil {
+		t.Errorf("custom denylist replaces the default: %v", err)
+	}
+}
+
+func TestCollector_StrictNamingPanicsOnFirstUse(t *testing.T) {
+	c := NewCollector()
+	c.SetNamingPolicy(NamingPolicy{Mode: NamingStrict})
+
+	c.IncCounter("http_requests_total", map[string]string{"method": "GET"})
+	c.ObserveHistogram("http_request_duration_seconds", 0.1, nil)
+
+	defer func() {
+		r := recover()
+		if _, ok := r.(*NamingError); !ok {
+			t.Fatalf("recover() = %v, want *NamingError", r)
+		}
+		// The collector stays usable after the panic.
+		c.SetGauge("queue_size", 1, nil)
+	}()
+	c.SetGauge("requestLatencyMs", 12, nil)
+}
+
+func TestCollector_StrictNamingRejectsTypeConflicts(t *testing.T) {
+	c := NewCollector()
+	c.SetNamingPolicy(NamingPolicy{Mode: NamingStrict})
+	c.SetGauge("queue_depth", 1, nil)
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(error).Error(), "already used as gauge") {
+			t.Fatalf("recover() = %v", r)
+		}
+	}()
+	c.ObserveHistogram("queue_depth", 1, map[string]string{"queue": "mail"})
+}
+
+func TestCollector_WarnNamingLogsOncePerMetric(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	c := NewCollector()
+	c.SetNamingPolicy(NamingPolicy{Mode: NamingWarn, Logger: zap.New(core)})
+
+	for _, user := range []string{"1", "2", "3"} {
+		c.IncCounter("user_actions_total", map[string]string{"user_id": user})
+	}
+	c.IncCounter("http_requests_total", nil)
+
+	if logs.Len() != 1 {
+		t.Fatalf("logged %d warnings, want 1", logs.Len())
+	}
+	if got := logs.All()[0].ContextMap()["metric"]; got != "user_actions_total" {
+		t.Errorf("metric field = %v", got)
+	}
+	if m := c.GetMetric("user_actions_total", map[string]string{"user_id": "1"}); m == nil || m.Value != 1 {
+		t.Errorf("warn mode must still record the metric, got %+v", m)
+	}
+}
+
+func TestBuiltInRecorders_PassStrictNaming(t *testing.T) {
+	c := NewCollector()
+	c.SetNamingPolicy(NamingPolicy{Mode: NamingStrict})
+
+	biz := NewBusinessMetrics(c)
+	biz.RecordUserAction("user-123", "login")
+	biz.RecordOrder("user-123", 99.99, true)
+	biz.RecordAPICall("payment-service", "Charge", 200, 0.05)
+
+	gauges := NewGaugeManager(c)
+	gauges.SetSystemMetrics(10, 512, 42)
+	gauges.SetQueueMetrics("mail", 3, 1)
+	gauges.SetConnectionMetrics(5, 2)
+
+	if m := c.GetMetric("user_actions_total", map[string]string{"action": "login"}); m == nil || m.Value != 1 {
+		t.Errorf("user_actions_total = %+v", m)
+	}
+}
+
+func TestCollector_NamingOffByDefault(t *testing.T) {
+	c := NewCollector()
+	c.SetGauge("whateverName", 1, map[string]string{"user_id": "1"})
+}
+
+func TestMetricsRateLimiter_StrictNaming(t *testing.T) {
+	c := NewCollector()
+	c.SetNamingPolicy(NamingPolicy{Mode: NamingStrict})
+	l := NewMetricsRateLimiter(c, 1, time.Minute)
+
+	if !l.Allow("client") || l.Allow("client") {
+		t.Fatal("want the first request allowed and the second rejected")
+	}
+	if m := c.GetMetric("rate_limit_allowed_total", map[string]string{"key": "client"}); m == nil || m.Value != 1 {
+		t.Errorf("rate_limit_allowed_total = %+v", m)
+	}
+	if m := c.GetMetric("rate_limit_exceeded_total", map[string]string{"key": "client"}); m == nil || m.Value != 1 {
+		t.Errorf("rate_limit_exceeded_total = %+v", m)
+	}
+}
@@ -0,0 +1,311 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OTLP 默认值
+const (
+	DefaultOTLPEndpoint = "http://localhost:4318/v1/metrics"
+	DefaultOTLPTimeout  = 10 * time.Second
+)
+
+// otlpScopeName OTLP instrumentation scope 名称
+const otlpScopeName = "github.com/leeforge/framework/metrics"
+
+// otlpCumulative AGGREGATION_TEMPORALITY_CUMULATIVE，收集器中的值均自创建起累计
+const otlpCumulative = 2
+
+// OTLPConfig OTLP/HTTP 指标推送配置
+type OTLPConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Endpoint 完整的推送地址，默认 http://localhost:4318/v1/metrics
+	Endpoint string `mapstructure:"endpoint"`
+	// Headers 附加请求头，如认证令牌
+	Headers map[string]string `mapstructure:"headers"`
+	// ResourceAttributes 资源属性，如 service.name、deployment.environment
+	ResourceAttributes map[string]string `mapstructure:"resource-attributes"`
+	// Timeout 单次推送超时，默认 10 秒
+	Timeout    time.Duration `mapstructure:"timeout"`
+	Interval   time.Duration `mapstructure:"interval"`
+	MaxBackoff time.Duration `mapstructure:"max-backoff"`
+}
+
+// OTLPExporter 以 OTLP/HTTP JSON 编码推送指标。counter 映射为单调累计 Sum，
+// gauge 映射为 Gauge，直方图与摘要分别映射为 Histogram 与 Summary
+type OTLPExporter struct {
+	config OTLPConfig
+	client *http.Client
+	start  time.Time
+}
+
+// NewOTLPExporter 创建 OTLP 导出器
+func NewOTLPExporter(config OTLPConfig) *OTLPExporter {
+	if config.Endpoint == "" {
+		config.Endpoint = DefaultOTLPEndpoint
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultOTLPTimeout
+	}
+	return &OTLPExporter{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		start:  time.Now(),
+	}
+}
+
+// PushOptions 返回配置中的推送间隔与退避上限
+func (e *OTLPExporter) PushOptions() PushOptions {
+	return PushOptions{Interval: e.config.Interval, MaxBackoff: e.config.MaxBackoff}
+}
+
+// Export 实现 MetricsExporter
+func (e *OTLPExporter) Export(metrics map[string]*Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(e.encode(metrics, time.Now()))
+	if err != nil {
+		return fmt.Errorf("metrics: otlp encode: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("metrics: otlp request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("metrics: otlp push: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("metrics: otlp push: status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// OTLP JSON 编码（opentelemetry-proto 的 JSON 映射），64 位整数编码为字符串
+type (
+	otlpRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes,omitempty"`
+	}
+	otlpScopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpAttribute struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpMetric struct {
+		Name      string         `json:"name"`
+		Sum       *otlpSum       `json:"sum,omitempty"`
+		Gauge     *otlpGauge     `json:"gauge,omitempty"`
+		Histogram *otlpHistogram `json:"histogram,omitempty"`
+		Summary   *otlpSummary   `json:"summary,omitempty"`
+	}
+	otlpSum struct {
+		DataPoints             []otlpNumberPoint `json:"dataPoints"`
+		AggregationTemporality int               `json:"aggregationTemporality"`
+		IsMonotonic            bool              `json:"isMonotonic"`
+	}
+	otlpGauge struct {
+		DataPoints []otlpNumberPoint `json:"dataPoints"`
+	}
+	otlpHistogram struct {
+		DataPoints             []otlpHistogramPoint `json:"dataPoints"`
+		AggregationTemporality int                  `json:"aggregationTemporality"`
+	}
+	otlpSummary struct {
+		DataPoints []otlpSummaryPoint `json:"dataPoints"`
+	}
+	otlpNumberPoint struct {
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		AsDouble          float64         `json:"asDouble"`
+	}
+	otlpHistogramPoint struct {
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		Count             string          `json:"count"`
+		Sum               float64         `json:"sum"`
+		BucketCounts      []string        `json:"bucketCounts"`
+		ExplicitBounds    []float64       `json:"explicitBounds"`
+	}
+	otlpSummaryPoint struct {
+		Attributes        []otlpAttribute     `json:"attributes,omitempty"`
+		StartTimeUnixNano string              `json:"startTimeUnixNano"`
+		TimeUnixNano      string              `json:"timeUnixNano"`
+		Count             string              `json:"count"`
+		Sum               float64             `json:"sum"`
+		QuantileValues    []otlpQuantileValue `json:"quantileValues,omitempty"`
+	}
+	otlpQuantileValue struct {
+		Quantile float64 `json:"quantile"`
+		Value    float64 `json:"value"`
+	}
+)
+
+// encode 将快照编码为 ExportMetricsServiceRequest，同名序列合并为一个指标的多个数据点
+func (e *OTLPExporter) encode(metrics map[string]*Metric, now time.Time) otlpRequest {
+	keys := make([]string, 0, len(metrics))
+	for k := range metrics {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	start := strconv.FormatInt(e.start.UnixNano(), 10)
+	ts := strconv.FormatInt(now.UnixNano(), 10)
+
+	var out []otlpMetric
+	index := make(map[string]int)
+	for _, key := range keys {
+		m := metrics[key]
+		name := m.Name
+		if name == "" {
+			name, _, _ = ParseMetricKey(key)
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(out)
+			index[name] = i
+			out = append(out, otlpMetric{Name: name})
+		}
+		om := &out[i]
+		attrs := otlpAttributes(m.Labels)
+
+		switch m.Type {
+		case "counter":
+			if om.Sum == nil {
+				om.Sum = &otlpSum{AggregationTemporality: otlpCumulative, IsMonotonic: true}
+			}
+			om.Sum.DataPoints = append(om.Sum.DataPoints, otlpNumberPoint{
+				Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: ts, AsDouble: m.Value,
+			})
+		case "gauge":
+			if om.Gauge == nil {
+				om.Gauge = &otlpGauge{}
+			}
+			om.Gauge.DataPoints = append(om.Gauge.DataPoints, otlpNumberPoint{
+				Attributes: attrs, TimeUnixNano: ts, AsDouble: m.Value,
+			})
+		case "histogram":
+			if om.Histogram == nil {
+				om.Histogram = &otlpHistogram{AggregationTemporality: otlpCumulative}
+			}
+			om.Histogram.DataPoints = append(om.Histogram.DataPoints, otlpHistogramPointOf(m, attrs, start, ts))
+		case "summary":
+			if om.Summary == nil {
+				om.Summary = &otlpSummary{}
+			}
+			om.Summary.DataPoints = append(om.Summary.DataPoints, otlpSummaryPointOf(m, attrs, start, ts))
+		}
+	}
+
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: otlpAttributes(e.config.ResourceAttributes)},
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   otlpScope{Name: otlpScopeName},
+			Metrics: out,
+		}},
+	}}}
+}
+
+// otlpHistogramPointOf 将累计桶转换为 OTLP 的逐桶计数，最后一个为 +Inf 桶
+func otlpHistogramPointOf(m *Metric, attrs []otlpAttribute, start, ts string) otlpHistogramPoint {
+	p := otlpHistogramPoint{
+		Attributes:        attrs,
+		StartTimeUnixNano: start,
+		TimeUnixNano:      ts,
+		Count:             strconv.FormatUint(m.Count, 10),
+		Sum:               m.Sum,
+		BucketCounts:      make([]string, 0, len(m.Buckets)+1),
+		ExplicitBounds:    make([]float64, 0, len(m.Buckets)),
+	}
+	var below uint64
+	for _, b := range m.Buckets {
+		p.ExplicitBounds = append(p.ExplicitBounds, b.UpperBound)
+		p.BucketCounts = append(p.BucketCounts, strconv.FormatUint(b.Count-below, 10))
+		below = b.Count
+	}
+	p.BucketCounts = append(p.BucketCounts, strconv.FormatUint(m.Count-below, 10))
+	return p
+}
+
+func otlpSummaryPointOf(m *Metric, attrs []otlpAttribute, start, ts string) otlpSummaryPoint {
+	p := otlpSummaryPoint{
+		Attributes:        attrs,
+		StartTimeUnixNano: start,
+		TimeUnixNano:      ts,
+		Count:             strconv.FormatUint(m.Count, 10),
+		Sum:               m.Sum,
+	}
+	for _, k := range sortedFloatKeys(m.Quantiles) {
+		q, err := strconv.ParseFloat(k, 64)
+		v := m.Quantiles[k]
+		// JSON 不能表示 NaN，窗口内没有观测值时省略
+		if err != nil || math.IsNaN(v) {
+			continue
+		}
+		p.QuantileValues = append(p.QuantileValues, otlpQuantileValue{Quantile: q, Value: v})
+	}
+	return p
+}
+
+func otlpAttributes(labels map[string]string) []otlpAttribute {
+	if len(labels) == 0 {
+		return nil
+	}
+	attrs := make([]otlpAttribute, 0, len(labels))
+	for _, k := range sortedKeys(labels) {
+		attrs = append(attrs, otlpAttribute{Key: k, Value: otlpAnyValue{StringValue: labels[k]}})
+	}
+	return attrs
+}
+
+// sortedFloatKeys 按数值升序返回以浮点数为键的映射的键
+func sortedFloatKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := strconv.ParseFloat(keys[i], 64)
+		b, _ := strconv.ParseFloat(keys[j], 64)
+		return a < b
+	})
+	return keys
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// 推送默认值
+const (
+	DefaultPushInterval   = 15 * time.Second
+	DefaultPushMaxBackoff = 5 * time.Minute
+)
+
+// MetricPushErrors 推送失败次数，标签 exporter 为注册名
+const MetricPushErrors = "metrics_push_errors_total"
+
+// PushConfig 推送式导出配置，用于无法被抓取的环境
+type PushConfig struct {
+	StatsD StatsDConfig `mapstructure:"statsd"`
+	OTLP   OTLPConfig   `mapstructure:"otlp"`
+}
+
+// registerPushExporters 按配置向 registry 注册启用的推送导出器
+func registerPushExporters(registry *MetricsExporterRegistry, config PushConfig) {
+	if config.StatsD.Enabled {
+		e := NewStatsDExporter(config.StatsD)
+		registry.RegisterPush("statsd", e, e.PushOptions())
+	}
+	if config.OTLP.Enabled {
+		e := NewOTLPExporter(config.OTLP)
+		registry.RegisterPush("otlp", e, e.PushOptions())
+	}
+}
+
+// PushOptions 推送式导出器的调度选项
+type PushOptions struct {
+	// Interval 推送间隔，默认 DefaultPushInterval
+	Interval time.Duration
+	// MaxBackoff 连续失败时推送间隔按 2 倍递增的上限，默认 DefaultPushMaxBackoff；
+	// 成功一次后恢复为 Interval
+	MaxBackoff time.Duration
+	// OnError 推送失败回调，可为 nil
+	OnError func(name string, err error)
+}
+
+func (o PushOptions) withDefaults() PushOptions {
+	if o.Interval <= 0 {
+		o.Interval = DefaultPushInterval
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultPushMaxBackoff
+	}
+	if o.MaxBackoff < o.Interval {
+		o.MaxBackoff = o.Interval
+	}
+	return o
+}
+
+// pushDelay 返回连续失败 failures 次后距下次推送的间隔
+func pushDelay(opts PushOptions, failures int) time.Duration {
+	d := opts.Interval
+	for i := 0; i < failures && d < opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, opts.MaxBackoff)
+}
+
+// pushLoop 单个导出器的推送循环
+type pushLoop struct {
+	name     string
+	exporter MetricsExporter
+	opts     PushOptions
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func (p *pushLoop) run(collector *Collector) {
+	defer close(p.done)
+
+	failures := 0
+	timer := time.NewTimer(p.opts.Interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			if p.push(collector) {
+				failures = 0
+			} else {
+				failures++
+			}
+			timer.Reset(pushDelay(p.opts, failures))
+		case <-p.stop:
+			// 退出前推送最后一次，短生命周期的进程不丢失最后一个周期的数据
+			p.push(collector)
+			return
+		}
+	}
+}
+
+func (p *pushLoop) push(collector *Collector) bool {
+	err := p.exporter.Export(collector.Export())
+	if err == nil {
+		return true
+	}
+	collector.IncCounter(MetricPushErrors, map[string]string{"exporter": p.name})
+	if p.opts.OnError != nil {
+		p.opts.OnError(p.name, err)
+	}
+	return false
+}
+
+// RegisterPush 注册推送式导出器：Start 后按 opts.Interval 定期将 Export 的快照
+// 推送给 exporter，失败时退避重试。同名注册会替换之前的导出器
+func (r *MetricsExporterRegistry) RegisterPush(name string, exporter MetricsExporter, opts PushOptions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	running := r.collector != nil && !r.stopped
+	if old, ok := r.pushes[name]; ok && running {
+		close(old.stop)
+		<-old.done
+	}
+	r.exporters[name] = exporter
+	p := &pushLoop{
+		name:     name,
+		exporter: exporter,
+		opts:     opts.withDefaults(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	r.pushes[name] = p
+	if running {
+		go p.run(r.collector)
+	}
+}
+
+// Start 开始推送 collector 的指标。重复调用无效
+func (r *MetricsExporterRegistry) Start(collector *Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.collector != nil || r.stopped {
+		return
+	}
+	r.collector = collector
+	for _, p := range r.pushes {
+		go p.run(collector)
+	}
+}
+
+// Stop 停止推送：每个导出器推送最后一次后退出，实现了 Close 的导出器随后被关闭。
+// 停止后注册表不能再次启动
+func (r *MetricsExporterRegistry) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.collector == nil || r.stopped {
+		return
+	}
+	r.stopped = true
+	var wg sync.WaitGroup
+	for _, p := range r.pushes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			close(p.stop)
+			<-p.done
+			if c, ok := p.exporter.(interface{ Close() error }); ok {
+				c.Close()
+			}
+		}()
+	}
+	wg.Wait()
+}
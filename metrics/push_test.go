@@ -0,0 +1,358 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/config"
+)
+
+func listenStatsD(t *testing.T) net.PacketConn {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	return pc
+}
+
+// readLines 读取数据包直到超时，返回所有行
+func readLines(t *testing.T, pc net.PacketConn) []string {
+	t.Helper()
+	var lines []string
+	buf := make([]byte, 65536)
+	for {
+		pc.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			return lines
+		}
+		lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+	}
+}
+
+func containsLine(lines []string, want string) bool {
+	for _, l := range lines {
+		if l == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStatsDExporter_Deltas(t *testing.T) {
+	pc := listenStatsD(t)
+	e := NewStatsDExporter(StatsDConfig{
+		Address: pc.LocalAddr().String(),
+		Prefix:  "app",
+		Tags:    map[string]string{"env": "test"},
+	})
+	defer e.Close()
+
+	c := NewCollector()
+	labels := map[string]string{"method": "GET"}
+	c.AddCounter("http_requests_total", 3, labels)
+	c.SetGauge("queue_depth", 7, nil)
+	c.ObserveHistogram("job_duration_seconds", 0.2, nil)
+	c.ObserveHistogram("job_duration_seconds", 0.4, nil)
+
+	if err := e.Export(c.Export()); err != nil {
+		t.Fatal(err)
+	}
+	lines := readLines(t, pc)
+	for _, want := range []string{
+		"app.http_requests_total:3|c|#env:test,method:GET",
+		"app.queue_depth:7|g|#env:test",
+		"app.job_duration_seconds.count:2|c|#env:test",
+		"app.job_duration_seconds.sum:0.6000000000000001|c|#env:test",
+	} {
+		if !containsLine(lines, want) {
+			t.Errorf("missing %q in %q", want, lines)
+		}
+	}
+
+	c.AddCounter("http_requests_total", 2, labels)
+	if err := e.Export(c.Export()); err != nil {
+		t.Fatal(err)
+	}
+	lines = readLines(t, pc)
+	if !containsLine(lines, "app.http_requests_total:2|c|#env:test,method:GET") {
+		t.Errorf("second push should send the delta, got %q", lines)
+	}
+	for _, l := range lines {
+		if strings.HasPrefix(l, "app.job_duration_seconds.count") {
+			t.Errorf("unchanged count pushed again: %q", l)
+		}
+	}
+}
+
+// flakyConn 第 failAt 次写入失败
+type flakyConn struct {
+	net.Conn
+	writes, failAt int
+}
+
+func (c *flakyConn) Write(b []byte) (int, error) {
+	c.writes++
+	if c.writes == c.failAt {
+		return 0, errors.New("network unreachable")
+	}
+	return c.Conn.Write(b)
+}
+
+func TestStatsDExporter_PartialFailureKeepsDeliveredDeltas(t *testing.T) {
+	pc := listenStatsD(t)
+	// 每行单独成包
+	e := NewStatsDExporter(StatsDConfig{Address: pc.LocalAddr().String(), MaxPacketSize: 8})
+	defer e.Close()
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.conn = &flakyConn{Conn: conn, failAt: 2}
+
+	c := NewCollector()
+	c.AddCounter("a", 3, nil)
+	c.AddCounter("b", 5, nil)
+	if err := e.Export(c.Export()); err == nil {
+		t.Fatal("Export should report the failed packet")
+	}
+	if lines := readLines(t, pc); !slices.Equal(lines, []string{"a:3|c"}) {
+		t.Fatalf("first push delivered %q, want only a", lines)
+	}
+
+	if err := e.Export(c.Export()); err != nil {
+		t.Fatal(err)
+	}
+	if lines := readLines(t, pc); !slices.Equal(lines, []string{"b:5|c"}) {
+		t.Errorf("retry sent %q, want only the undelivered b delta", lines)
+	}
+}
+
+func TestStatsDExporter_FormatAndPackets(t *testing.T) {
+	pc := listenStatsD(t)
+	e := NewStatsDExporter(StatsDConfig{
+		Address:       pc.LocalAddr().String(),
+		Format:        StatsDFormatStatsD,
+		MaxPacketSize: 64,
+	})
+	defer e.Close()
+
+	c := NewCollector()
+	for _, region := range []string{"us:east", "eu", "ap", "sa", "af"} {
+		c.SetGauge("pool_size", 1, map[string]string{"region": region})
+	}
+	if err := e.Export(c.Export()); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	var lines []string
+	for {
+		pc.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		if n > 64 {
+			t.Errorf("packet of %d bytes exceeds MaxPacketSize", n)
+		}
+		lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+	}
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want 5: %q", len(lines), lines)
+	}
+	if !containsLine(lines, "pool_size;region=us_east:1|g") {
+		t.Errorf("statsd tag format not applied: %q", lines)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		got  map[string]any
+		auth string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	e := NewOTLPExporter(OTLPConfig{
+		Endpoint:           srv.URL,
+		Headers:            map[string]string{"Authorization": "Bearer token"},
+		ResourceAttributes: map[string]string{"service.name": "api"},
+	})
+	c := NewCollector()
+	c.SetHistogramBuckets("job_duration_seconds", []float64{0.1, 1})
+	c.AddCounter("http_requests_total", 3, map[string]string{"method": "GET"})
+	c.AddCounter("http_requests_total", 1, map[string]string{"method": "POST"})
+	c.ObserveHistogram("job_duration_seconds", 0.05, nil)
+	c.ObserveHistogram("job_duration_seconds", 0.5, nil)
+	c.ObserveHistogram("job_duration_seconds", 5, nil)
+	c.ObserveSummary("rpc_latency_seconds", 0.3, nil)
+
+	if err := e.Export(c.Export()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if auth != "Bearer token" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	rm := got["resourceMetrics"].([]any)[0].(map[string]any)
+	attr := rm["resource"].(map[string]any)["attributes"].([]any)[0].(map[string]any)
+	if attr["key"] != "service.name" {
+		t.Errorf("resource attribute = %v", attr)
+	}
+	byName := map[string]map[string]any{}
+	for _, m := range rm["scopeMetrics"].([]any)[0].(map[string]any)["metrics"].([]any) {
+		mm := m.(map[string]any)
+		byName[mm["name"].(string)] = mm
+	}
+
+	sum := byName["http_requests_total"]["sum"].(map[string]any)
+	if sum["isMonotonic"] != true || sum["aggregationTemporality"] != float64(otlpCumulative) {
+		t.Errorf("sum = %v", sum)
+	}
+	if n := len(sum["dataPoints"].([]any)); n != 2 {
+		t.Errorf("counter data points = %d, want 2", n)
+	}
+
+	hp := byName["job_duration_seconds"]["histogram"].(map[string]any)["dataPoints"].([]any)[0].(map[string]any)
+	counts, _ := json.Marshal(hp["bucketCounts"])
+	if string(counts) != `["1","1","1"]` || hp["count"] != "3" {
+		t.Errorf("histogram point = %v", hp)
+	}
+
+	sp := byName["rpc_latency_seconds"]["summary"].(map[string]any)["dataPoints"].([]any)[0].(map[string]any)
+	if len(sp["quantileValues"].([]any)) != len(DefaultObjectives) {
+		t.Errorf("summary point = %v", sp)
+	}
+}
+
+func TestOTLPExporter_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c := NewCollector()
+	c.IncCounter("jobs_total", nil)
+	err := NewOTLPExporter(OTLPConfig{Endpoint: srv.URL}).Export(c.Export())
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("err = %v, want status 429", err)
+	}
+}
+
+func TestPushDelay(t *testing.T) {
+	opts := PushOptions{Interval: time.Second, MaxBackoff: 5 * time.Second}.withDefaults()
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for failures, w := range want {
+		if got := pushDelay(opts, failures); got != w {
+			t.Errorf("pushDelay(%d) = %v, want %v", failures, got, w)
+		}
+	}
+	if d := pushDelay(PushOptions{}.withDefaults(), 100); d != DefaultPushMaxBackoff {
+		t.Errorf("pushDelay with defaults = %v, want %v", d, DefaultPushMaxBackoff)
+	}
+}
+
+type fakePushExporter struct {
+	calls  atomic.Int32
+	fail   int32
+	closed atomic.Bool
+}
+
+func (f *fakePushExporter) Export(map[string]*Metric) error {
+	if f.calls.Add(1) <= f.fail {
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func (f *fakePushExporter) Close() error {
+	f.closed.Store(true)
+	return nil
+}
+
+func TestMetricsExporterRegistry_Push(t *testing.T) {
+	c := NewCollector()
+	r := NewMetricsExporterRegistry()
+	exp := &fakePushExporter{fail: 2}
+	var errs atomic.Int32
+	r.RegisterPush("fake", exp, PushOptions{
+		Interval:   5 * time.Millisecond,
+		MaxBackoff: 20 * time.Millisecond,
+		OnError:    func(string, error) { errs.Add(1) },
+	})
+	r.Start(c)
+	r.Start(c)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for exp.calls.Load() < 4 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if exp.calls.Load() < 4 {
+		t.Fatalf("exporter called %d times, want retries after failures", exp.calls.Load())
+	}
+
+	before := exp.calls.Load()
+	r.Stop()
+	r.Stop()
+	if exp.calls.Load() <= before {
+		t.Error("Stop did not flush a final push")
+	}
+	if !exp.closed.Load() {
+		t.Error("Stop did not close the exporter")
+	}
+	if errs.Load() != 2 {
+		t.Errorf("OnError called %d times, want 2", errs.Load())
+	}
+	if m := c.GetMetric(MetricPushErrors, map[string]string{"exporter": "fake"}); m == nil || m.Value != 2 {
+		t.Errorf("%s = %+v, want 2", MetricPushErrors, m)
+	}
+	if r.GetExporter("fake") != exp {
+		t.Error("push exporter not available via GetExporter")
+	}
+}
+
+func TestPushConfigSection(t *testing.T) {
+	raw := map[string]any{
+		"push": map[string]any{
+			"statsd": map[string]any{"enabled": true, "address": "statsd:8125", "interval": "10s", "tags": map[string]any{"env": "prod"}},
+			"otlp":   map[string]any{"enabled": true, "endpoint": "http://otel:4318/v1/metrics", "max-backoff": "1m"},
+		},
+	}
+	var cfg MetricsConfig
+	if err := config.DecodeSection(SectionName, raw, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Push.StatsD.Enabled || cfg.Push.StatsD.Interval != 10*time.Second || cfg.Push.StatsD.Tags["env"] != "prod" {
+		t.Errorf("statsd = %+v", cfg.Push.StatsD)
+	}
+	if cfg.Push.OTLP.MaxBackoff != time.Minute {
+		t.Errorf("otlp = %+v", cfg.Push.OTLP)
+	}
+
+	raw["push"] = map[string]any{"statsd": map[string]any{"format": "graphite"}}
+	if err := config.DecodeSection(SectionName, raw, &MetricsConfig{}); err == nil {
+		t.Error("invalid statsd format accepted")
+	}
+}
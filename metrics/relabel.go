@@ -0,0 +1,286 @@
+package metrics
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NameLabel 在 SourceLabels / TargetLabel 中代表指标名
+const NameLabel = "__name__"
+
+// RelabelAction 重写规则动作
+type RelabelAction string
+
+const (
+	// RelabelKeep 源值不匹配 Regex 的序列被丢弃
+	RelabelKeep RelabelAction = "keep"
+	// RelabelDrop 源值匹配 Regex 的序列被丢弃
+	RelabelDrop RelabelAction = "drop"
+	// RelabelReplace 源值匹配时把 Replacement 展开后写入 TargetLabel，结果为空则删除该标签
+	RelabelReplace RelabelAction = "replace"
+	// RelabelRename 标签名匹配 Regex 的标签改名为 Replacement 展开后的名字
+	RelabelRename RelabelAction = "rename"
+	// RelabelLabelDrop 删除标签名匹配 Regex 的标签
+	RelabelLabelDrop RelabelAction = "labeldrop"
+	// RelabelHash 把 SourceLabels 中各标签的值替换为哈希，Modulus > 0 时替换为哈希取模后的分桶
+	RelabelHash RelabelAction = "hash"
+)
+
+// RelabelRule 单条重写规则，语义参照 Prometheus relabel_configs
+type RelabelRule struct {
+	Action RelabelAction `mapstructure:"action" json:"action" validate:"required,oneof=keep drop replace rename labeldrop hash"`
+	// SourceLabels 参与匹配的标签，值以 Separator 连接；__name__ 表示指标名
+	SourceLabels []string `mapstructure:"source-labels" json:"sourceLabels,omitempty"`
+	// Separator 默认 ";"
+	Separator string `mapstructure:"separator" json:"separator,omitempty"`
+	// Regex 完整匹配（自动加 ^$），默认 "(.*)"
+	Regex string `mapstructure:"regex" json:"regex,omitempty"`
+	// TargetLabel replace 动作写入的标签，__name__ 表示改写指标名
+	TargetLabel string `mapstructure:"target-label" json:"targetLabel,omitempty"`
+	// Replacement 支持 $1 / ${name} 引用分组，默认 "$1"
+	Replacement *string `mapstructure:"replacement" json:"replacement,omitempty"`
+	// Modulus hash 动作的分桶数，0 表示输出 16 位十六进制哈希
+	Modulus uint64 `mapstructure:"modulus" json:"modulus,omitempty"`
+}
+
+// RelabelConfig 导出时的重写配置
+type RelabelConfig struct {
+	// StaticLabels 附加到每个序列的静态标签（如 env、region），已有同名标签时不覆盖
+	StaticLabels map[string]string `mapstructure:"static-labels" json:"staticLabels,omitempty"`
+	// Rules 按顺序执行的重写规则
+	Rules []RelabelRule `mapstructure:"rules" json:"rules,omitempty" validate:"dive"`
+}
+
+type compiledRule struct {
+	RelabelRule
+	regex       *regexp.Regexp
+	replacement string
+}
+
+// Relabeler 在导出时重写指标：过滤序列、改写标签、附加静态标签、哈希高基数标签，
+// 不影响埋点代码与收集器中的原始数据
+type Relabeler struct {
+	static map[string]string
+	rules  []compiledRule
+}
+
+// NewRelabeler 编译重写配置，规则无效时返回错误
+func NewRelabeler(config RelabelConfig) (*Relabeler, error) {
+	r := &Relabeler{static: config.StaticLabels}
+	for i, rule := range config.Rules {
+		c := compiledRule{RelabelRule: rule, replacement: "$1"}
+		if c.Separator == "" {
+			c.Separator = ";"
+		}
+		if rule.Replacement != nil {
+			c.replacement = *rule.Replacement
+		}
+		pattern := rule.Regex
+		if pattern == "" {
+			pattern = "(.*)"
+		}
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("relabel rule %d: invalid regex %q: %w", i, rule.Regex, err)
+		}
+		c.regex = re
+
+		switch rule.Action {
+		case RelabelKeep, RelabelDrop, RelabelHash:
+			if len(rule.SourceLabels) == 0 {
+				return nil, fmt.Errorf("relabel rule %d: %s requires source-labels", i, rule.Action)
+			}
+		case RelabelReplace:
+			if len(rule.SourceLabels) == 0 || rule.TargetLabel == "" {
+				return nil, fmt.Errorf("relabel rule %d: replace requires source-labels and target-label", i)
+			}
+		case RelabelRename, RelabelLabelDrop:
+		default:
+			return nil, fmt.Errorf("relabel rule %d: unknown action %q", i, rule.Action)
+		}
+		r.rules = append(r.rules, c)
+	}
+	return r, nil
+}
+
+// Apply 重写单个序列，返回新的指标名与标签；keep 为 false 表示该序列不导出。
+// 传入的 labels 不会被修改
+func (r *Relabeler) Apply(name string, labels map[string]string) (string, map[string]string, bool) {
+	out := make(map[string]string, len(labels)+len(r.static))
+	for k, v := range labels {
+		out[k] = v
+	}
+	for k, v := range r.static {
+		if _, exists := out[k]; !exists {
+			out[k] = v
+		}
+	}
+
+	for _, rule := range r.rules {
+		switch rule.Action {
+		case RelabelKeep, RelabelDrop:
+			matched := rule.regex.MatchString(sourceValue(rule, name, out))
+			if matched != (rule.Action == RelabelKeep) {
+				return "", nil, false
+			}
+		case RelabelReplace:
+			value := sourceValue(rule, name, out)
+			match := rule.regex.FindStringSubmatchIndex(value)
+			if match == nil {
+				continue
+			}
+			result := string(rule.regex.ExpandString(nil, rule.replacement, value, match))
+			switch {
+			case rule.TargetLabel == NameLabel:
+				if result != "" {
+					name = result
+				}
+			case result == "":
+				delete(out, rule.TargetLabel)
+			default:
+				out[rule.TargetLabel] = result
+			}
+		case RelabelRename:
+			for _, k := range sortedKeys(out) {
+				match := rule.regex.FindStringSubmatchIndex(k)
+				if match == nil {
+					continue
+				}
+				renamed := string(rule.regex.ExpandString(nil, rule.replacement, k, match))
+				if renamed == k || renamed == "" {
+					continue
+				}
+				out[renamed] = out[k]
+				delete(out, k)
+			}
+		case RelabelLabelDrop:
+			for k := range out {
+				if rule.regex.MatchString(k) {
+					delete(out, k)
+				}
+			}
+		case RelabelHash:
+			for _, k := range rule.SourceLabels {
+				if v, ok := out[k]; ok {
+					out[k] = hashValue(v, rule.Modulus)
+				}
+			}
+		}
+	}
+	return name, out, true
+}
+
+// Relabel 重写一组指标。重写后名字与标签相同的序列会合并：counter 相加、
+// gauge 取最新值、histogram 合并观测值。结果以确定性的 SeriesKey 为键
+func (r *Relabeler) Relabel(metrics map[string]*Metric) map[string]*Metric {
+	// 按原始键排序，保证合并结果稳定
+	keys := make([]string, 0, len(metrics))
+	for k := range metrics {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make(map[string]*Metric, len(metrics))
+	for _, k := range keys {
+		m := metrics[k]
+		name := m.Name
+		if name == "" {
+			name = metricName(k)
+		}
+		name, labels, keep := r.Apply(name, m.Labels)
+		if !keep {
+			continue
+		}
+		key := SeriesKey(name, labels)
+		existing, ok := out[key]
+		if !ok {
+			cp := m.clone()
+			cp.Name = name
+			cp.Labels = labels
+			out[key] = cp
+			continue
+		}
+		mergeSeries(existing, m)
+	}
+	return out
+}
+
+// mergeSeries 将落到同一序列的 m 合并进 existing：counter 累加，
+// gauge 取最新，直方图与摘要合并分布
+func mergeSeries(existing, m *Metric) {
+	switch m.Type {
+	case "counter":
+		existing.Value += m.Value
+	case "gauge":
+		if m.Timestamp >= existing.Timestamp {
+			existing.Value = m.Value
+		}
+	default:
+		existing.merge(m)
+	}
+	if m.Timestamp > existing.Timestamp {
+		existing.Timestamp = m.Timestamp
+	}
+}
+
+// SeriesKey 返回按标签名排序的序列键，形如 name{a="1",b="2"}
+func SeriesKey(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	var sb strings.Builder
+	sb.WriteString(name)
+	sb.WriteByte('{')
+	for i, k := range sortedKeys(labels) {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(k)
+		sb.WriteString(`="`)
+		sb.WriteString(labels[k])
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+func sourceValue(rule compiledRule, name string, labels map[string]string) string {
+	values := make([]string, len(rule.SourceLabels))
+	for i, l := range rule.SourceLabels {
+		if l == NameLabel {
+			values[i] = name
+		} else {
+			values[i] = labels[l]
+		}
+	}
+	return strings.Join(values, rule.Separator)
+}
+
+func hashValue(v string, modulus uint64) string {
+	h := fnv.New64a()
+	h.Write([]byte(v))
+	sum := h.Sum64()
+	if modulus > 0 {
+		return strconv.FormatUint(sum%modulus, 10)
+	}
+	return fmt.Sprintf("%016x", sum)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// metricName 从收集器的内部键中取出指标名
+func metricName(key string) string {
+	name, _, _ := ParseMetricKey(key)
+	return name
+}
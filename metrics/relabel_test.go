@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leeforge/framework/config"
+)
+
+func TestRelabelerApply(t *testing.T) {
+	empty := ""
+	r, err := NewRelabeler(RelabelConfig{
+		StaticLabels: map[string]string{"env": "prod", "method": "ignored"},
+		Rules: []RelabelRule{
+			{Action: RelabelDrop, SourceLabels: []string{NameLabel}, Regex: "go_.*"},
+			{Action: RelabelKeep, SourceLabels: []string{"status"}, Regex: "[0-9]{3}|"},
+			{Action: RelabelRename, Regex: "http_(.*)"},
+			{Action: RelabelLabelDrop, Regex: "debug_.*"},
+			{Action: RelabelReplace, SourceLabels: []string{"status"}, Regex: "([0-9])..", Replacement: strPtr("${1}xx"), TargetLabel: "status_class"},
+			{Action: RelabelReplace, SourceLabels: []string{"internal"}, Regex: ".*", Replacement: &empty, TargetLabel: "internal"},
+			{Action: RelabelHash, SourceLabels: []string{"user"}, Modulus: 8},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := map[string]string{"method": "GET", "http_status": "503", "debug_trace": "x", "user": "alice", "internal": "y"}
+	name, out, keep := r.Apply("requests_total", labels)
+	if !keep || name != "requests_total" {
+		t.Fatalf("Apply = %q keep=%v", name, keep)
+	}
+	want := map[string]string{"method": "GET", "env": "prod", "status": "503", "status_class": "5xx", "user": out["user"]}
+	if len(out) != len(want) {
+		t.Fatalf("labels = %v, want %v", out, want)
+	}
+	for k, v := range want {
+		if out[k] != v {
+			t.Errorf("label %s = %q, want %q", k, out[k], v)
+		}
+	}
+	if out["user"] == "alice" || len(out["user"]) != 1 {
+		t.Errorf("user not bucketed: %q", out["user"])
+	}
+	if _, ok := labels["env"]; ok {
+		t.Error("input labels must not be modified")
+	}
+
+	if _, _, keep := r.Apply("go_goroutines", nil); keep {
+		t.Error("go_* should be dropped")
+	}
+	if _, _, keep := r.Apply("requests_total", map[string]string{"status": "abc"}); keep {
+		t.Error("non-numeric status should not be kept")
+	}
+
+	// 改写指标名
+	r, _ = NewRelabeler(RelabelConfig{Rules: []RelabelRule{
+		{Action: RelabelReplace, SourceLabels: []string{NameLabel}, Regex: "legacy_(.*)", TargetLabel: NameLabel},
+	}})
+	if name, _, _ := r.Apply("legacy_jobs_total", nil); name != "jobs_total" {
+		t.Errorf("renamed metric = %q", name)
+	}
+}
+
+func TestNewRelabelerErrors(t *testing.T) {
+	cases := []RelabelRule{
+		{Action: RelabelKeep},
+		{Action: RelabelReplace, SourceLabels: []string{"a"}},
+		{Action: RelabelRename, Regex: "("},
+		{Action: "bogus"},
+	}
+	for _, rule := range cases {
+		if _, err := NewRelabeler(RelabelConfig{Rules: []RelabelRule{rule}}); err == nil {
+			t.Errorf("rule %+v: expected error", rule)
+		}
+	}
+}
+
+func TestCollectorExportRelabels(t *testing.T) {
+	c := NewCollector()
+	c.IncCounter("logins_total", map[string]string{"user": "a", "region": "eu"})
+	c.IncCounter("logins_total", map[string]string{"user": "b", "region": "eu"})
+	c.AddCounter("logins_total", 3, map[string]string{"user": "c", "region": "us"})
+	c.SetGauge("go_goroutines", 10, nil)
+	c.ObserveHistogram("job_duration_seconds", 1, map[string]string{"job": "a"})
+	c.ObserveHistogram("job_duration_seconds", 2, map[string]string{"job": "b"})
+
+	r, err := NewRelabeler(RelabelConfig{
+		StaticLabels: map[string]string{"env": "staging"},
+		Rules: []RelabelRule{
+			{Action: RelabelDrop, SourceLabels: []string{NameLabel}, Regex: "go_.*"},
+			{Action: RelabelLabelDrop, Regex: "user|job"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRelabeler(r)
+
+	exported := c.Export()
+	if len(exported) != 3 {
+		t.Fatalf("exported %d series: %v", len(exported), exported)
+	}
+	eu := exported[`logins_total{env="staging",region="eu"}`]
+	if eu == nil || eu.Value != 2 || eu.Name != "logins_total" {
+		t.Errorf("eu series = %+v, want merged value 2", eu)
+	}
+	hist := exported[`job_duration_seconds{env="staging"}`]
+	if hist == nil || len(hist.History) != 2 {
+		t.Errorf("histogram = %+v, want merged history", hist)
+	}
+	if len(c.GetMetrics()) != 6 {
+		t.Error("GetMetrics must stay raw")
+	}
+
+	prom := NewPrometheusExporter(c).GetPrometheusFormat()
+	if !strings.Contains(prom, `logins_total{env="staging",region="us"} 3.00`) {
+		t.Errorf("prometheus output:\n%s", prom)
+	}
+	if strings.Contains(prom, "go_goroutines") {
+		t.Error("dropped series exported")
+	}
+}
+
+func TestRelabelConfigSection(t *testing.T) {
+	raw := map[string]any{
+		"naming": "off",
+		"relabel": map[string]any{
+			"static-labels": map[string]any{"env": "prod"},
+			"rules": []any{
+				map[string]any{"action": "hash", "source-labels": "user,session", "modulus": "16"},
+			},
+		},
+	}
+	var cfg MetricsConfig
+	if err := config.DecodeSection(SectionName, raw, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	rules := cfg.Relabel.Rules
+	if len(rules) != 1 || len(rules[0].SourceLabels) != 2 || rules[0].Modulus != 16 || cfg.Relabel.StaticLabels["env"] != "prod" {
+		t.Fatalf("decoded = %+v", cfg.Relabel)
+	}
+
+	raw["relabel"] = map[string]any{"rules": []any{map[string]any{"action": "explode"}}}
+	err := config.DecodeSection(SectionName, raw, &MetricsConfig{})
+	if err == nil || !strings.Contains(err.Error(), "action") {
+		t.Errorf("err = %v, want action validation error", err)
+	}
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// path 标签的特殊取值与默认上限
+const (
+	// DefaultMaxPaths path 标签默认的最大取值数
+	DefaultMaxPaths = 500
+	// PathOther 超出 MaxPaths 的路径聚合到的取值
+	PathOther = "other"
+	// PathUnmatched 未匹配任何路由的 404 请求的取值，避免扫描随机路径放大基数
+	PathUnmatched = "unmatched"
+)
+
+// HTTP 请求指标的标签名
+const (
+	LabelMethod = "method"
+	LabelPath   = "path"
+	LabelStatus = "status"
+)
+
+// HTTPMetricsConfig HTTP 指标中间件配置
+type HTTPMetricsConfig struct {
+	// Labels 记录的标签白名单，可选 method、path、status，为空时全部记录
+	Labels []string `mapstructure:"labels" validate:"dive,oneof=method path status"`
+	// MaxPaths path 标签的最大取值数，超出后新路径记为 "other"；
+	// 0 使用 DefaultMaxPaths，负数不限制
+	MaxPaths int `mapstructure:"max-paths"`
+}
+
+// RoutePattern 返回请求匹配的路由模板，如 /users/{id}。
+// 依次读取 chi 的路由上下文与 http.ServeMux 设置的 Request.Pattern，都没有时返回空串
+func RoutePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if p := rctx.RoutePattern(); p != "" {
+			return p
+		}
+	}
+	// ServeMux 的模板形如 "GET example.com/users/{id}"，只保留路径部分
+	if i := strings.IndexByte(r.Pattern, '/'); i >= 0 {
+		return r.Pattern[i:]
+	}
+	return ""
+}
+
+// NormalizePath 将路径中的 ID 段（纯数字、UUID、16 位以上的十六进制串）折叠为 {id}，
+// 用于无法取得路由模板时控制 path 标签的基数
+func NormalizePath(path string) string {
+	segments := strings.Split(path, "/")
+	changed := false
+	for i, s := range segments {
+		if isIDSegment(s) {
+			segments[i] = "{id}"
+			changed = true
+		}
+	}
+	if !changed {
+		return path
+	}
+	return strings.Join(segments, "/")
+}
+
+func isIDSegment(s string) bool {
+	if s == "" {
+		return false
+	}
+	if isDigits(s) {
+		return true
+	}
+	if len(s) == 36 && s[8] == '-' && s[13] == '-' && s[18] == '-' && s[23] == '-' {
+		return isHex(strings.ReplaceAll(s, "-", ""))
+	}
+	return len(s) >= 16 && isHex(s)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// pathLimiter 限制 path 标签的取值数，先到先得，超出的路径聚合为 PathOther
+type pathLimiter struct {
+	max  int
+	mu   sync.RWMutex
+	seen map[string]struct{}
+}
+
+func newPathLimiter(max int) *pathLimiter {
+	if max == 0 {
+		max = DefaultMaxPaths
+	}
+	return &pathLimiter{max: max, seen: make(map[string]struct{})}
+}
+
+func (l *pathLimiter) limit(path string) string {
+	if l.max < 0 {
+		return path
+	}
+	l.mu.RLock()
+	_, ok := l.seen[path]
+	full := len(l.seen) >= l.max
+	l.mu.RUnlock()
+	if ok {
+		return path
+	}
+	if full {
+		return PathOther
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.seen[path]; ok {
+		return path
+	}
+	if len(l.seen) >= l.max {
+		return PathOther
+	}
+	l.seen[path] = struct{}{}
+	return path
+}
+
+// routeLabel 返回请求的 path 标签：优先路由模板，其次折叠 ID 段后的原始路径
+func (m *MetricsMiddleware) routeLabel(r *http.Request, status int) string {
+	if p := RoutePattern(r); p != "" {
+		return m.paths.limit(p)
+	}
+	if status == http.StatusNotFound {
+		return PathUnmatched
+	}
+	return m.paths.limit(NormalizePath(r.URL.Path))
+}
+
+// withRouteContext 在请求尚无 chi 路由上下文时预先放入一个。chi 会复用已有的
+// 上下文并在其中记录匹配的模板，中间件挂在路由器外层时也能在请求结束后读到
+func withRouteContext(r *http.Request) *http.Request {
+	if chi.RouteContext(r.Context()) != nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chi.NewRouteContext()))
+}
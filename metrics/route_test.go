@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/leeforge/framework/config"
+)
+
+func serve(h http.Handler, method, path string) {
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+}
+
+func TestMetricsMiddleware_ChiPattern(t *testing.T) {
+	c := NewCollector()
+	r := chi.NewRouter()
+	r.Route("/api", func(r chi.Router) {
+		r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	})
+	// 挂在路由器外层
+	h := NewMetricsMiddleware(c).Middleware(r)
+	serve(h, "GET", "/api/users/1")
+	serve(h, "GET", "/api/users/2")
+
+	m := c.GetMetric("http_requests_total", map[string]string{"method": "GET", "path": "/api/users/{id}", "status": "200"})
+	if m == nil || m.Value != 2 {
+		t.Fatalf("pattern series = %+v, metrics = %v", m, c.GetMetrics())
+	}
+
+	// 挂在路由器内部
+	c2 := NewCollector()
+	r2 := chi.NewRouter()
+	r2.Use(NewMetricsMiddleware(c2).Middleware)
+	r2.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	serve(r2, "GET", "/orders/9")
+	if c2.GetMetric("http_requests_total", map[string]string{"method": "GET", "path": "/orders/{id}", "status": "200"}) == nil {
+		t.Errorf("pattern not recorded inside router: %v", c2.GetMetrics())
+	}
+}
+
+func TestMetricsMiddleware_ServeMuxPattern(t *testing.T) {
+	c := NewCollector()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	serve(NewMetricsMiddleware(c).Middleware(mux), "GET", "/items/abc")
+
+	if c.GetMetric("http_requests_total", map[string]string{"method": "GET", "path": "/items/{id}", "status": "200"}) == nil {
+		t.Errorf("ServeMux pattern not recorded: %v", c.GetMetrics())
+	}
+}
+
+func TestMetricsMiddleware_Fallbacks(t *testing.T) {
+	c := NewCollector()
+	plain := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	})
+	h := NewMetricsMiddleware(c).Middleware(plain)
+	serve(h, "GET", "/files/123/7b0e4f7a-1c2d-4e5f-9a8b-0c1d2e3f4a5b")
+	serve(h, "GET", "/missing")
+
+	if c.GetMetric("http_requests_total", map[string]string{"method": "GET", "path": "/files/{id}/{id}", "status": "200"}) == nil {
+		t.Errorf("normalized path not recorded: %v", c.GetMetrics())
+	}
+	if c.GetMetric("http_requests_total", map[string]string{"method": "GET", "path": PathUnmatched, "status": "404"}) == nil {
+		t.Errorf("404 not recorded as %q: %v", PathUnmatched, c.GetMetrics())
+	}
+}
+
+func TestMetricsMiddleware_LimitAndAllowlist(t *testing.T) {
+	c := NewCollector()
+	plain := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := NewMetricsMiddlewareWithConfig(c, HTTPMetricsConfig{
+		Labels:   []string{LabelMethod, LabelPath},
+		MaxPaths: 2,
+	}).Middleware(plain)
+	for i := 0; i < 5; i++ {
+		serve(h, "GET", "/page-"+strconv.Itoa(i))
+	}
+
+	if m := c.GetMetric("http_requests_total", map[string]string{"method": "GET", "path": PathOther}); m == nil || m.Value != 3 {
+		t.Errorf("overflow series = %+v, want 3", m)
+	}
+	if c.GetMetric("http_requests_total", map[string]string{"method": "GET", "path": "/page-0"}) == nil {
+		t.Error("first path should keep its own series")
+	}
+	for key, m := range c.GetMetrics() {
+		if _, ok := m.Labels[LabelStatus]; ok {
+			t.Errorf("status label recorded despite allowlist: %s", key)
+		}
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	cases := map[string]string{
+		"/":                             "/",
+		"/users":                        "/users",
+		"/users/42":                     "/users/{id}",
+		"/users/42/posts/7":             "/users/{id}/posts/{id}",
+		"/objects/0123456789abcdef0123": "/objects/{id}",
+		"/v1/7B0E4F7A-1C2D-4E5F-9A8B-0C1D2E3F4A5B": "/v1/{id}",
+		"/files/report-2024":                       "/files/report-2024",
+		"/deadbeef":                                "/deadbeef",
+	}
+	for in, want := range cases {
+		if got := NormalizePath(in); got != want {
+			t.Errorf("NormalizePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestHTTPMetricsConfigSection(t *testing.T) {
+	raw := map[string]any{"http": map[string]any{"labels": "method,path", "max-paths": 100}}
+	var cfg MetricsConfig
+	if err := config.DecodeSection(SectionName, raw, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.HTTP.Labels) != 2 || cfg.HTTP.MaxPaths != 100 {
+		t.Errorf("decoded = %+v", cfg.HTTP)
+	}
+
+	raw["http"] = map[string]any{"labels": "method,user_id"}
+	if err := config.DecodeSection(SectionName, raw, &MetricsConfig{}); err == nil {
+		t.Error("unknown label accepted")
+	}
+}
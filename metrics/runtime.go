@@ -0,0 +1,225 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRuntimeInterval 运行时指标默认采集间隔
+const DefaultRuntimeInterval = 15 * time.Second
+
+// 运行时与进程指标名
+const (
+	MetricGoGoroutines     = "go_goroutines"
+	MetricGoHeapAllocBytes = "go_memstats_heap_alloc_bytes"
+	MetricGoHeapInuseBytes = "go_memstats_heap_inuse_bytes"
+	MetricGoHeapIdleBytes  = "go_memstats_heap_idle_bytes"
+	MetricGoHeapObjects    = "go_memstats_heap_objects"
+	MetricGoSysBytes       = "go_memstats_sys_bytes"
+	MetricGoNextGCBytes    = "go_memstats_next_gc_bytes"
+	MetricGoGCCycles       = "go_gc_cycles_total"
+	MetricGoGCPause        = "go_gc_pause_seconds"
+	MetricGoGCLastPause    = "go_gc_last_pause_seconds"
+	MetricGoGCCPURatio     = "go_gc_cpu_ratio"
+	MetricProcessOpenFDs   = "process_open_fds"
+	MetricProcessMaxFDs    = "process_max_fds"
+	MetricProcessCPU       = "process_cpu_seconds_total"
+	MetricProcessRSSBytes  = "process_resident_memory_bytes"
+	MetricProcessUptime    = "process_uptime_seconds"
+)
+
+const (
+	// linuxClockTicks Linux USER_HZ，/proc 中的 CPU 时间以此为单位
+	linuxClockTicks = 100
+	// maxPausesPerObservation runtime.MemStats.PauseNs 环形缓冲区大小
+	maxPausesPerObservation = 256
+)
+
+// RuntimeMetricsConfig 运行时与进程指标采集配置
+type RuntimeMetricsConfig struct {
+	// Enabled 是否随 MetricsManager 自动采集
+	Enabled bool `mapstructure:"enabled"`
+	// Interval 采集间隔，默认 15 秒
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// RuntimeCollector 基于 MetricsSampler 定期采集 Go 运行时（协程、堆、GC）与
+// 进程（文件描述符、CPU、RSS）指标。进程指标读取 /proc，仅在 Linux 上可用
+type RuntimeCollector struct {
+	collector *Collector
+	sampler   *MetricsSampler
+	start     time.Time
+
+	mu        sync.Mutex
+	lastNumGC uint32
+	lastCPU   float64
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// NewRuntimeCollector 创建运行时指标采集器，interval <= 0 时使用 DefaultRuntimeInterval
+func NewRuntimeCollector(collector *Collector, interval time.Duration) *RuntimeCollector {
+	if interval <= 0 {
+		interval = DefaultRuntimeInterval
+	}
+	// GC 暂停通常在微秒级，默认耗时桶过粗：10µs 到约 160ms
+	collector.SetHistogramBuckets(MetricGoGCPause, ExponentialBuckets(1e-5, 4, 8))
+	return &RuntimeCollector{
+		collector: collector,
+		sampler:   NewMetricsSampler(collector, interval),
+		start:     time.Now(),
+	}
+}
+
+// Start 立即采集一次，之后在后台按间隔采集。重复调用无效
+func (r *RuntimeCollector) Start() {
+	r.startOnce.Do(func() {
+		for name, value := range r.Sample() {
+			r.collector.SetGauge(name, value, nil)
+		}
+		go r.sampler.Start(r.Sample)
+	})
+}
+
+// Stop 停止采集。重复调用无效
+func (r *RuntimeCollector) Stop() {
+	r.stopOnce.Do(r.sampler.Stop)
+}
+
+// Sample 采集一次，返回 gauge 指标；GC 次数、CPU 时间等累计值以 counter
+// 增量写入，GC 暂停写入直方图
+func (r *RuntimeCollector) Sample() map[string]float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	gauges := map[string]float64{
+		MetricGoGoroutines:     float64(runtime.NumGoroutine()),
+		MetricGoHeapAllocBytes: float64(ms.HeapAlloc),
+		MetricGoHeapInuseBytes: float64(ms.HeapInuse),
+		MetricGoHeapIdleBytes:  float64(ms.HeapIdle),
+		MetricGoHeapObjects:    float64(ms.HeapObjects),
+		MetricGoSysBytes:       float64(ms.Sys),
+		MetricGoNextGCBytes:    float64(ms.NextGC),
+		MetricGoGCCPURatio:     ms.GCCPUFraction,
+		MetricProcessUptime:    time.Since(r.start).Seconds(),
+	}
+	if ms.NumGC > 0 {
+		gauges[MetricGoGCLastPause] = float64(ms.PauseNs[(ms.NumGC+255)%256]) / 1e9
+	}
+	r.observeGC(&ms)
+
+	if fds, ok := openFDs(); ok {
+		gauges[MetricProcessOpenFDs] = fds
+	}
+	if limit, ok := maxFDs(); ok {
+		gauges[MetricProcessMaxFDs] = limit
+	}
+	if rss, ok := residentMemory(); ok {
+		gauges[MetricProcessRSSBytes] = rss
+	}
+	if cpu, ok := cpuSeconds(); ok {
+		if delta := cpu - r.lastCPU; delta > 0 {
+			r.collector.AddCounter(MetricProcessCPU, delta, nil)
+		}
+		r.lastCPU = cpu
+	}
+	return gauges
+}
+
+// observeGC 将上次采集以来的 GC 次数与暂停时间写入 counter 与直方图。
+// 两次采集间超过 256 次 GC 时，更早的暂停已被运行时覆盖
+func (r *RuntimeCollector) observeGC(ms *runtime.MemStats) {
+	if ms.NumGC <= r.lastNumGC {
+		return
+	}
+	r.collector.AddCounter(MetricGoGCCycles, float64(ms.NumGC-r.lastNumGC), nil)
+	from := r.lastNumGC + 1
+	if ms.NumGC-r.lastNumGC > maxPausesPerObservation {
+		from = ms.NumGC - maxPausesPerObservation + 1
+	}
+	for i := from; i <= ms.NumGC; i++ {
+		r.collector.ObserveHistogram(MetricGoGCPause, float64(ms.PauseNs[(i+255)%256])/1e9, nil)
+	}
+	r.lastNumGC = ms.NumGC
+}
+
+func openFDs() (float64, bool) {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, false
+	}
+	return float64(len(entries)), true
+}
+
+// maxFDs 读取 /proc/self/limits 中 "Max open files" 的软限制
+func maxFDs() (float64, bool) {
+	data, err := os.ReadFile("/proc/self/limits")
+	if err != nil {
+		return 0, false
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "Max open files"))
+		if len(fields) == 0 || fields[0] == "unlimited" {
+			return 0, false
+		}
+		v, err := strconv.ParseFloat(fields[0], 64)
+		return v, err == nil
+	}
+	return 0, false
+}
+
+// residentMemory 读取 /proc/self/statm 的常驻页数
+func residentMemory() (float64, bool) {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0, false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, false
+	}
+	pages, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return pages * float64(os.Getpagesize()), true
+}
+
+// cpuSeconds 读取 /proc/self/stat 的用户态与内核态 CPU 时间
+func cpuSeconds() (float64, bool) {
+	data, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return 0, false
+	}
+	// 第 2 个字段为括号包围的进程名，可能含空格，从右括号之后开始计数
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return 0, false
+	}
+	// 右括号后依次为第 3 个字段起，utime、stime 为第 14、15 个字段
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 13 {
+		return 0, false
+	}
+	utime, err1 := strconv.ParseFloat(fields[11], 64)
+	stime, err2 := strconv.ParseFloat(fields[12], 64)
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+	return (utime + stime) / linuxClockTicks, true
+}
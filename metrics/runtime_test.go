@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/config"
+)
+
+func TestRuntimeCollector_Sample(t *testing.T) {
+	c := NewCollector()
+	r := NewRuntimeCollector(c, time.Hour)
+
+	gauges := r.Sample()
+	for _, name := range []string{MetricGoGoroutines, MetricGoHeapAllocBytes, MetricGoSysBytes, MetricProcessUptime} {
+		if _, ok := gauges[name]; !ok {
+			t.Errorf("sample missing %s", name)
+		}
+	}
+	if gauges[MetricGoGoroutines] < 1 {
+		t.Errorf("%s = %v, want >= 1", MetricGoGoroutines, gauges[MetricGoGoroutines])
+	}
+	if runtime.GOOS == "linux" {
+		for _, name := range []string{MetricProcessOpenFDs, MetricProcessRSSBytes} {
+			if gauges[name] <= 0 {
+				t.Errorf("%s = %v, want > 0", name, gauges[name])
+			}
+		}
+	}
+}
+
+func TestRuntimeCollector_GCDeltas(t *testing.T) {
+	c := NewCollector()
+	r := NewRuntimeCollector(c, time.Hour)
+	r.Sample()
+	before := c.GetMetric(MetricGoGCCycles, nil)
+	var cycles float64
+	if before != nil {
+		cycles = before.Value
+	}
+
+	runtime.GC()
+	runtime.GC()
+	r.Sample()
+
+	after := c.GetMetric(MetricGoGCCycles, nil)
+	if after == nil || after.Value-cycles < 2 {
+		t.Fatalf("%s = %+v, want at least 2 new cycles", MetricGoGCCycles, after)
+	}
+	pause := c.GetMetric(MetricGoGCPause, nil)
+	if pause == nil || pause.Count < 2 {
+		t.Fatalf("%s = %+v, want at least 2 observations", MetricGoGCPause, pause)
+	}
+	if pause.Buckets[0].UpperBound != 1e-5 {
+		t.Errorf("pause buckets start at %v, want 1e-5", pause.Buckets[0].UpperBound)
+	}
+
+	// 没有新 GC 时不重复计数
+	r.mu.Lock()
+	r.lastNumGC = ^uint32(0)
+	r.mu.Unlock()
+	r.Sample()
+	if got := c.GetMetric(MetricGoGCCycles, nil).Value; got != after.Value {
+		t.Errorf("%s = %v after no new GC, want %v", MetricGoGCCycles, got, after.Value)
+	}
+}
+
+func TestRuntimeCollector_StartStop(t *testing.T) {
+	c := NewCollector()
+	r := NewRuntimeCollector(c, 10*time.Millisecond)
+	r.Start()
+	r.Start()
+
+	if c.GetMetric(MetricGoGoroutines, nil) == nil {
+		t.Fatalf("%s not recorded on Start", MetricGoGoroutines)
+	}
+	uptime := c.GetMetric(MetricProcessUptime, nil).Value
+	deadline := time.Now().Add(time.Second)
+	for c.GetMetric(MetricProcessUptime, nil).Value == uptime && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if c.GetMetric(MetricProcessUptime, nil).Value == uptime {
+		t.Error("sampler did not update gauges")
+	}
+
+	r.Stop()
+	r.Stop()
+}
+
+func TestMetricsManager_Runtime(t *testing.T) {
+	cfg := DefaultMetricsConfig()
+	cfg.Runtime.Interval = time.Hour
+	m := NewMetricsManager(cfg)
+	defer m.Stop()
+	if m.GetCollector().GetMetric(MetricGoGoroutines, nil) == nil {
+		t.Errorf("%s not collected with runtime enabled", MetricGoGoroutines)
+	}
+
+	cfg.Runtime.Enabled = false
+	off := NewMetricsManager(cfg)
+	defer off.Stop()
+	if off.GetCollector().GetMetric(MetricGoGoroutines, nil) != nil {
+		t.Errorf("%s collected with runtime disabled", MetricGoGoroutines)
+	}
+}
+
+func TestRuntimeConfigSection(t *testing.T) {
+	raw := map[string]any{
+		"runtime": map[string]any{"enabled": true, "interval": "30s"},
+	}
+	var cfg MetricsConfig
+	if err := config.DecodeSection(SectionName, raw, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Runtime.Enabled || cfg.Runtime.Interval != 30*time.Second {
+		t.Errorf("decoded = %+v", cfg.Runtime)
+	}
+}
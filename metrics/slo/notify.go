@@ -0,0 +1,48 @@
+package slo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultWebhookTimeout Webhook 通知的默认超时
+const DefaultWebhookTimeout = 5 * time.Second
+
+// Webhook 返回将 Event 以 JSON POST 到 url 的 Notifier。client 为 nil 时使用
+// 超时 5 秒的默认客户端；投递失败交给 onError（可为 nil），不重试。
+// 需要签名、重试与投递记录时，在 Notifier 中调用 webhooks.Sender.Send
+func Webhook(url string, client *http.Client, onError func(Event, error)) Notifier {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	return func(e Event) {
+		if err := postEvent(client, url, e); err != nil && onError != nil {
+			onError(e, err)
+		}
+	}
+}
+
+func postEvent(client *http.Client, url string, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("slo: encode event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slo: webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("slo: webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// Package slo 基于 metrics.Collector 中的请求计数与耗时直方图跟踪服务等级目标（SLO）：
+// 计算滚动窗口内的 SLI、剩余错误预算与燃烧率，以 gauge 写回收集器，
+// 并在燃烧率越过阈值时通知回调或 Webhook。
+package slo
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Kind SLO 类型
+type Kind string
+
+const (
+	// Availability 可用性：非错误请求占比
+	Availability Kind = "availability"
+	// Latency 延迟：耗时不超过阈值的请求占比
+	Latency Kind = "latency"
+)
+
+// 默认值
+const (
+	DefaultWindow         = 30 * 24 * time.Hour
+	DefaultRequestsMetric = "http_requests_total"
+	DefaultDurationMetric = "http_request_duration_seconds"
+)
+
+// SLO 服务等级目标
+type SLO struct {
+	// Name 唯一名称，作为 gauge 的 slo 标签
+	Name string
+	Kind Kind
+	// Objective 目标比例，如 0.999
+	Objective float64
+	// Window 错误预算的滚动窗口，默认 30 天
+	Window time.Duration
+	// Labels 选择参与计算的序列，序列标签须包含全部键值，如
+	// {"method": "GET", "path": "/users/{id}"}；为空时选择全部序列
+	Labels map[string]string
+	// Threshold 延迟阈值（秒），仅 Latency 使用。应与直方图某个桶上界一致，
+	// 否则按不超过阈值的最大上界计算，结果偏保守
+	Threshold float64
+	// RequestsMetric 可用性使用的请求计数器，默认 http_requests_total
+	RequestsMetric string
+	// DurationMetric 延迟使用的耗时直方图，默认 http_request_duration_seconds
+	DurationMetric string
+	// IsError 判断序列是否为错误请求，仅 Availability 使用；默认 status 标签 >= 500
+	IsError func(labels map[string]string) bool
+}
+
+// validate 校验并填充默认值
+func (s *SLO) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("slo: name is required")
+	}
+	if s.Objective <= 0 || s.Objective >= 1 {
+		return fmt.Errorf("slo: %s: objective must be between 0 and 1, got %v", s.Name, s.Objective)
+	}
+	if s.Window <= 0 {
+		s.Window = DefaultWindow
+	}
+	switch s.Kind {
+	case Availability:
+		if s.RequestsMetric == "" {
+			s.RequestsMetric = DefaultRequestsMetric
+		}
+		if s.IsError == nil {
+			s.IsError = ServerError
+		}
+	case Latency:
+		if s.Threshold <= 0 {
+			return fmt.Errorf("slo: %s: latency threshold must be positive", s.Name)
+		}
+		if s.DurationMetric == "" {
+			s.DurationMetric = DefaultDurationMetric
+		}
+	default:
+		return fmt.Errorf("slo: %s: unknown kind %q", s.Name, s.Kind)
+	}
+	return nil
+}
+
+// ServerError 默认的错误判定：status 标签为 5xx
+func ServerError(labels map[string]string) bool {
+	status, err := strconv.Atoi(labels["status"])
+	return err == nil && status >= 500
+}
+
+// BurnRateAlert 多窗口燃烧率告警：长窗口与短窗口的燃烧率都超过 BurnRate 时触发，
+// 短窗口回落后恢复。燃烧率 1 表示恰好在窗口结束时耗尽错误预算
+type BurnRateAlert struct {
+	Name        string
+	LongWindow  time.Duration
+	ShortWindow time.Duration
+	BurnRate    float64
+}
+
+// DefaultAlerts Google SRE Workbook 推荐的 30 天窗口告警：
+// 1 小时内消耗 2% 预算时 page，6 小时内消耗 5% 时 ticket
+var DefaultAlerts = []BurnRateAlert{
+	{Name: "page", LongWindow: time.Hour, ShortWindow: 5 * time.Minute, BurnRate: 14.4},
+	{Name: "ticket", LongWindow: 6 * time.Hour, ShortWindow: 30 * time.Minute, BurnRate: 6},
+}
+
+// Event 告警状态变化
+type Event struct {
+	SLO       string    `json:"slo"`
+	Alert     string    `json:"alert"`
+	Firing    bool      `json:"firing"`
+	BurnRate  float64   `json:"burn_rate"`
+	ShortRate float64   `json:"short_burn_rate"`
+	Threshold float64   `json:"threshold"`
+	Budget    float64   `json:"error_budget_remaining"`
+	Time      time.Time `json:"time"`
+}
+
+// Notifier 接收告警状态变化，在评估协程中同步调用
+type Notifier func(Event)
+
+// Status SLO 的当前状态
+type Status struct {
+	Name      string  `json:"name"`
+	Objective float64 `json:"objective"`
+	// SLI 窗口内的实际达标比例，没有请求时为 1
+	SLI float64 `json:"sli"`
+	// ErrorBudgetRemaining 窗口内剩余的错误预算比例，超支时为负
+	ErrorBudgetRemaining float64 `json:"error_budget_remaining"`
+	// BurnRates 各告警窗口的燃烧率，键为窗口时长（如 "1h0m0s"）
+	BurnRates map[string]float64 `json:"burn_rates"`
+	// Firing 正在触发的告警名
+	Firing []string `json:"firing,omitempty"`
+}
@@ -0,0 +1,203 @@
+package slo
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+)
+
+func record(c *metrics.Collector, path string, good, bad int) {
+	for i := 0; i < good; i++ {
+		c.RecordRequest("GET", path, 200, 0.01)
+	}
+	for i := 0; i < bad; i++ {
+		c.RecordRequest("GET", path, 503, 0.01)
+	}
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestTracker_Availability(t *testing.T) {
+	c := metrics.NewCollector()
+	tr := NewTracker(c, Options{Alerts: []BurnRateAlert{}})
+	if err := tr.Add(SLO{
+		Name: "users-api", Kind: Availability, Objective: 0.99,
+		Labels: map[string]string{"path": "/users/{id}"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Now()
+	tr.Evaluate(t0)
+	record(c, "/users/{id}", 990, 10)
+	record(c, "/health", 0, 500) // 不在选择范围内
+	st := tr.Evaluate(t0.Add(time.Minute))[0]
+
+	if !near(st.SLI, 0.99) || !near(st.ErrorBudgetRemaining, 0) {
+		t.Errorf("status = %+v, want SLI 0.99 and budget 0", st)
+	}
+	labels := map[string]string{"slo": "users-api"}
+	if m := c.GetMetric(MetricBudget, labels); m == nil || !near(m.Value, 0) {
+		t.Errorf("%s = %+v", MetricBudget, m)
+	}
+	if m := c.GetMetric(MetricObjective, labels); m == nil || m.Value != 0.99 {
+		t.Errorf("%s = %+v", MetricObjective, m)
+	}
+}
+
+func TestTracker_Latency(t *testing.T) {
+	c := metrics.NewCollector()
+	c.SetHistogramBuckets("rpc_duration_seconds", []float64{0.1, 0.25, 1})
+	tr := NewTracker(c, Options{Alerts: []BurnRateAlert{}})
+	if err := tr.Add(SLO{
+		Name: "rpc-latency", Kind: Latency, Objective: 0.95,
+		Threshold: 0.25, DurationMetric: "rpc_duration_seconds",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Now()
+	tr.Evaluate(t0)
+	for i := 0; i < 9; i++ {
+		c.ObserveHistogram("rpc_duration_seconds", 0.2, nil)
+	}
+	c.ObserveHistogram("rpc_duration_seconds", 0.5, nil)
+	st := tr.Evaluate(t0.Add(time.Minute))[0]
+
+	if !near(st.SLI, 0.9) || !near(st.ErrorBudgetRemaining, -1) {
+		t.Errorf("status = %+v, want SLI 0.9 and budget -1", st)
+	}
+}
+
+func TestTracker_BurnRateAlerts(t *testing.T) {
+	c := metrics.NewCollector()
+	var events []Event
+	tr := NewTracker(c, Options{
+		Alerts:    []BurnRateAlert{{Name: "fast", LongWindow: 10 * time.Minute, ShortWindow: time.Minute, BurnRate: 2}},
+		Notifiers: []Notifier{func(e Event) { events = append(events, e) }},
+	})
+	if err := tr.Add(SLO{Name: "api", Kind: Availability, Objective: 0.99}); err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Now()
+	tr.Evaluate(t0)
+	record(c, "/", 50, 50)
+	st := tr.Evaluate(t0.Add(time.Minute))[0]
+	if len(events) != 1 || !events[0].Firing || events[0].Alert != "fast" || !near(events[0].BurnRate, 50) {
+		t.Fatalf("events = %+v, want one firing event with burn rate 50", events)
+	}
+	if len(st.Firing) != 1 || !near(st.BurnRates["10m0s"], 50) {
+		t.Errorf("status = %+v", st)
+	}
+	if m := c.GetMetric(MetricAlertsFiring, map[string]string{"slo": "api", "alert": "fast"}); m == nil || m.Value != 1 {
+		t.Errorf("%s = %+v", MetricAlertsFiring, m)
+	}
+
+	// 仍在触发时不重复通知
+	record(c, "/", 50, 50)
+	tr.Evaluate(t0.Add(2 * time.Minute))
+	if len(events) != 1 {
+		t.Fatalf("events = %d, want no repeat while firing", len(events))
+	}
+
+	// 短窗口恢复后告警解除，长窗口的燃烧率仍然很高
+	record(c, "/", 1000, 0)
+	tr.Evaluate(t0.Add(4 * time.Minute))
+	if len(events) != 2 || events[1].Firing {
+		t.Fatalf("events = %+v, want resolved event", events)
+	}
+	if events[1].BurnRate <= 2 || events[1].ShortRate != 0 {
+		t.Errorf("resolved event = %+v", events[1])
+	}
+}
+
+func TestTracker_RollingWindowAndReset(t *testing.T) {
+	c := metrics.NewCollector()
+	tr := NewTracker(c, Options{Alerts: []BurnRateAlert{}})
+	if err := tr.Add(SLO{Name: "api", Kind: Availability, Objective: 0.9, Window: 10 * time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Now()
+	tr.Evaluate(t0)
+	record(c, "/", 0, 10)
+	tr.Evaluate(t0.Add(time.Minute))
+
+	// 收集器重置后读数变小，按重新计数处理
+	c.Reset()
+	record(c, "/", 5, 0)
+	st := tr.Evaluate(t0.Add(2 * time.Minute))[0]
+	if !near(st.SLI, 5.0/15) {
+		t.Errorf("SLI after reset = %v, want 1/3", st.SLI)
+	}
+
+	// 错误移出窗口
+	for m := 3; m <= 15; m++ {
+		record(c, "/", 1, 0)
+		st = tr.Evaluate(t0.Add(time.Duration(m) * time.Minute))[0]
+	}
+	if !near(st.SLI, 1) || !near(st.ErrorBudgetRemaining, 1) {
+		t.Errorf("status = %+v, want errors outside the window ignored", st)
+	}
+	if len(tr.slos[0].samples) > 12 {
+		t.Errorf("kept %d samples for a 10 minute window", len(tr.slos[0].samples))
+	}
+}
+
+func TestTracker_Validation(t *testing.T) {
+	tr := NewTracker(metrics.NewCollector(), Options{})
+	cases := []struct {
+		slo  SLO
+		want string
+	}{
+		{SLO{Kind: Availability, Objective: 0.9}, "name"},
+		{SLO{Name: "a", Kind: Availability, Objective: 1}, "objective"},
+		{SLO{Name: "a", Kind: Latency, Objective: 0.9}, "threshold"},
+		{SLO{Name: "a", Kind: "errors", Objective: 0.9}, "kind"},
+	}
+	for _, tc := range cases {
+		if err := tr.Add(tc.slo); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Add(%+v) = %v, want error mentioning %q", tc.slo, err, tc.want)
+		}
+	}
+	if err := tr.Add(SLO{Name: "a", Kind: Availability, Objective: 0.9}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Add(SLO{Name: "a", Kind: Availability, Objective: 0.9}); err == nil {
+		t.Error("duplicate name accepted")
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	got := make(chan Event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		got <- e
+	}))
+	defer srv.Close()
+
+	Webhook(srv.URL, nil, nil)(Event{SLO: "api", Alert: "page", Firing: true, BurnRate: 20})
+	if e := <-got; e.SLO != "api" || !e.Firing || e.BurnRate != 20 {
+		t.Errorf("received %+v", e)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	var errs []error
+	Webhook(failing.URL, nil, func(_ Event, err error) { errs = append(errs, err) })(Event{SLO: "api"})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "502") {
+		t.Errorf("errors = %v, want status 502", errs)
+	}
+}
@@ -0,0 +1,311 @@
+package slo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+)
+
+// 写回收集器的 gauge
+const (
+	MetricObjective    = "slo_objective_ratio"
+	MetricSLI          = "slo_sli_ratio"
+	MetricBudget       = "slo_error_budget_remaining_ratio"
+	MetricBurnRate     = "slo_burn_rate"
+	MetricAlertsFiring = "slo_alert_firing"
+)
+
+// DefaultInterval 默认评估间隔，也是滚动窗口的采样粒度
+const DefaultInterval = time.Minute
+
+// Options 跟踪器选项
+type Options struct {
+	// Interval 评估间隔，默认 1 分钟。每个 SLO 在窗口内每个间隔保留一个采样点
+	Interval time.Duration
+	// Alerts 燃烧率告警，为 nil 时使用 DefaultAlerts
+	Alerts []BurnRateAlert
+	// Notifiers 告警触发与恢复时依次调用
+	Notifiers []Notifier
+}
+
+// Tracker 定期从收集器读取累计值，计算各 SLO 的滚动窗口指标
+type Tracker struct {
+	collector *metrics.Collector
+	opts      Options
+
+	mu    sync.Mutex
+	slos  []*tracked
+	names map[string]bool
+
+	stop      chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// tracked 单个 SLO 的累计值与采样
+type tracked struct {
+	slo SLO
+
+	// 收集器中的原始读数，用于计算增量；收集器重置时读数变小
+	rawGood, rawTotal float64
+	seen              bool
+	// 自跟踪开始以来单调递增的累计值
+	good, total float64
+
+	samples []sample
+	firing  map[string]bool
+	status  Status
+}
+
+type sample struct {
+	at          time.Time
+	good, total float64
+}
+
+// NewTracker 创建跟踪器
+func NewTracker(collector *metrics.Collector, opts Options) *Tracker {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Alerts == nil {
+		opts.Alerts = DefaultAlerts
+	}
+	return &Tracker{
+		collector: collector,
+		opts:      opts,
+		names:     make(map[string]bool),
+		stop:      make(chan struct{}),
+	}
+}
+
+// Add 添加 SLO，名称重复或定义无效时返回错误
+func (t *Tracker) Add(s SLO) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.names[s.Name] {
+		return fmt.Errorf("slo: duplicate name %q", s.Name)
+	}
+	t.names[s.Name] = true
+	t.slos = append(t.slos, &tracked{slo: s, firing: make(map[string]bool)})
+	t.collector.SetGauge(MetricObjective, s.Objective, map[string]string{"slo": s.Name})
+	return nil
+}
+
+// Start 立即评估一次，之后按间隔在后台评估。重复调用无效
+func (t *Tracker) Start() {
+	t.startOnce.Do(func() {
+		t.Evaluate(time.Now())
+		go func() {
+			ticker := time.NewTicker(t.opts.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case now := <-ticker.C:
+					t.Evaluate(now)
+				case <-t.stop:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Stop 停止后台评估。重复调用无效
+func (t *Tracker) Stop() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+// Evaluate 以 now 为当前时间评估所有 SLO，更新 gauge 并在告警状态变化时通知
+func (t *Tracker) Evaluate(now time.Time) []Status {
+	snapshot := t.collector.GetMetrics()
+
+	t.mu.Lock()
+	var events []Event
+	statuses := make([]Status, 0, len(t.slos))
+	for _, tr := range t.slos {
+		good, total := read(&tr.slo, snapshot)
+		tr.record(now, good, total, t.retention(tr.slo))
+		events = append(events, t.evaluate(tr, now)...)
+		statuses = append(statuses, tr.status)
+	}
+	t.mu.Unlock()
+
+	// 通知可能较慢（Webhook），在锁外调用
+	for _, e := range events {
+		for _, n := range t.opts.Notifiers {
+			n(e)
+		}
+	}
+	return statuses
+}
+
+// Status 返回最近一次评估的结果
+func (t *Tracker) Status() []Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	statuses := make([]Status, 0, len(t.slos))
+	for _, tr := range t.slos {
+		statuses = append(statuses, tr.status)
+	}
+	return statuses
+}
+
+// retention 采样需覆盖的最长时长：SLO 窗口与最长告警窗口中的较大者
+func (t *Tracker) retention(s SLO) time.Duration {
+	d := s.Window
+	for _, a := range t.opts.Alerts {
+		d = max(d, a.LongWindow)
+	}
+	return d
+}
+
+// evaluate 计算状态、写回 gauge，返回告警状态变化
+func (t *Tracker) evaluate(tr *tracked, now time.Time) []Event {
+	name := tr.slo.Name
+	errRate := tr.errorRate(now, tr.slo.Window)
+	budget := 1 - errRate/(1-tr.slo.Objective)
+	st := Status{
+		Name:                 name,
+		Objective:            tr.slo.Objective,
+		SLI:                  1 - errRate,
+		ErrorBudgetRemaining: budget,
+		BurnRates:            make(map[string]float64),
+	}
+	labels := map[string]string{"slo": name}
+	t.collector.SetGauge(MetricSLI, st.SLI, labels)
+	t.collector.SetGauge(MetricBudget, budget, labels)
+
+	var events []Event
+	for _, a := range t.opts.Alerts {
+		long := tr.burnRate(now, a.LongWindow)
+		short := tr.burnRate(now, a.ShortWindow)
+		st.BurnRates[a.LongWindow.String()] = long
+		st.BurnRates[a.ShortWindow.String()] = short
+
+		firing := long >= a.BurnRate && short >= a.BurnRate
+		if firing {
+			st.Firing = append(st.Firing, a.Name)
+		}
+		t.collector.SetGauge(MetricAlertsFiring, boolGauge(firing), map[string]string{"slo": name, "alert": a.Name})
+		if firing != tr.firing[a.Name] {
+			tr.firing[a.Name] = firing
+			events = append(events, Event{
+				SLO: name, Alert: a.Name, Firing: firing,
+				BurnRate: long, ShortRate: short, Threshold: a.BurnRate,
+				Budget: budget, Time: now,
+			})
+		}
+	}
+	for window, rate := range st.BurnRates {
+		t.collector.SetGauge(MetricBurnRate, rate, map[string]string{"slo": name, "window": window})
+	}
+	tr.status = st
+	return events
+}
+
+// read 从快照中汇总 SLO 选择的序列，返回达标与总请求数
+func read(s *SLO, snapshot map[string]*metrics.Metric) (good, total float64) {
+	for _, m := range snapshot {
+		if !matches(m.Labels, s.Labels) {
+			continue
+		}
+		switch s.Kind {
+		case Availability:
+			if m.Name != s.RequestsMetric || m.Type != "counter" {
+				continue
+			}
+			total += m.Value
+			if !s.IsError(m.Labels) {
+				good += m.Value
+			}
+		case Latency:
+			if m.Name != s.DurationMetric || m.Type != "histogram" {
+				continue
+			}
+			total += float64(m.Count)
+			// 累计桶：取不超过阈值的最大上界
+			i := sort.Search(len(m.Buckets), func(i int) bool { return m.Buckets[i].UpperBound > s.Threshold })
+			if i > 0 {
+				good += float64(m.Buckets[i-1].Count)
+			}
+		}
+	}
+	return good, total
+}
+
+func matches(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// record 将原始读数换算为单调累计值并追加采样，丢弃超出 retention 的采样，
+// 但保留窗口起点之前的最后一个作为基线
+func (tr *tracked) record(now time.Time, rawGood, rawTotal float64, retention time.Duration) {
+	switch {
+	case !tr.seen:
+		// 首次读数之前的请求不计入窗口：它们发生的时间未知
+		tr.seen = true
+	case rawGood < tr.rawGood || rawTotal < tr.rawTotal:
+		// 读数变小说明收集器被重置，重置后的读数即增量
+		tr.good += rawGood
+		tr.total += rawTotal
+	default:
+		tr.good += rawGood - tr.rawGood
+		tr.total += rawTotal - tr.rawTotal
+	}
+	tr.rawGood, tr.rawTotal = rawGood, rawTotal
+	tr.samples = append(tr.samples, sample{at: now, good: tr.good, total: tr.total})
+
+	cutoff := now.Add(-retention)
+	i := sort.Search(len(tr.samples), func(i int) bool { return !tr.samples[i].at.Before(cutoff) })
+	if i > 1 {
+		tr.samples = append(tr.samples[:0], tr.samples[i-1:]...)
+	}
+}
+
+// errorRate 窗口 d 内的错误率，没有请求时为 0
+func (tr *tracked) errorRate(now time.Time, d time.Duration) float64 {
+	if len(tr.samples) == 0 {
+		return 0
+	}
+	last := tr.samples[len(tr.samples)-1]
+	base := tr.baseline(now.Add(-d))
+	total := last.total - base.total
+	if total <= 0 {
+		return 0
+	}
+	bad := total - (last.good - base.good)
+	return math.Max(0, bad/total)
+}
+
+// burnRate 窗口 d 内的燃烧率：错误率与允许错误率之比
+func (tr *tracked) burnRate(now time.Time, d time.Duration) float64 {
+	return tr.errorRate(now, d) / (1 - tr.slo.Objective)
+}
+
+// baseline 返回 at 时刻或之前的最后一个采样；采样晚于 at 时返回最早的采样
+func (tr *tracked) baseline(at time.Time) sample {
+	i := sort.Search(len(tr.samples), func(i int) bool { return tr.samples[i].at.After(at) })
+	if i == 0 {
+		return tr.samples[0]
+	}
+	return tr.samples[i-1]
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,283 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsD 标签格式
+const (
+	// StatsDFormatDogStatsD 标签写为 |#k:v,k2:v2（DogStatsD、Telegraf 等支持）
+	StatsDFormatDogStatsD = "dogstatsd"
+	// StatsDFormatStatsD 标签以 Graphite 标签形式拼入名称：name;k=v;k2=v2
+	StatsDFormatStatsD = "statsd"
+)
+
+// StatsD 默认值
+const (
+	DefaultStatsDAddress = "127.0.0.1:8125"
+	// DefaultStatsDPacketSize 以太网 MTU 下不分片的 UDP 负载上限
+	DefaultStatsDPacketSize = 1432
+)
+
+// statsdQuantiles 直方图推送的分位数
+var statsdQuantiles = []float64{0.5, 0.95, 0.99}
+
+// StatsDConfig StatsD / DogStatsD 推送配置
+type StatsDConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Network 默认 udp
+	Network string `mapstructure:"network"`
+	// Address 默认 127.0.0.1:8125
+	Address string `mapstructure:"address"`
+	// Prefix 指标名前缀，以 "." 与指标名连接
+	Prefix string `mapstructure:"prefix"`
+	// Format 标签格式：dogstatsd（默认）/ statsd
+	Format string `mapstructure:"format" validate:"omitempty,oneof=dogstatsd statsd"`
+	// Tags 附加到每个指标的静态标签
+	Tags map[string]string `mapstructure:"tags"`
+	// MaxPacketSize 单个数据包的最大字节数，默认 1432
+	MaxPacketSize int           `mapstructure:"max-packet-size"`
+	Interval      time.Duration `mapstructure:"interval"`
+	MaxBackoff    time.Duration `mapstructure:"max-backoff"`
+}
+
+// StatsDExporter 以 StatsD 行协议推送指标。
+// counter 推送两次推送间的增量（|c），gauge 推送当前值（|g）；
+// 直方图与摘要推送 .count、.sum 增量及 .p50 等分位数（|g）
+type StatsDExporter struct {
+	config StatsDConfig
+
+	mu   sync.Mutex
+	conn net.Conn
+	last map[string]float64 // 各累计值上次送达时的值，用于计算增量
+}
+
+// statsdLine 一行 StatsD 数据；增量行记录其累计值，所在数据包送达后并入 last
+type statsdLine struct {
+	text  string
+	key   string // 非增量行为空
+	value float64
+}
+
+// NewStatsDExporter 创建 StatsD 导出器，连接在首次推送时建立
+func NewStatsDExporter(config StatsDConfig) *StatsDExporter {
+	if config.Network == "" {
+		config.Network = "udp"
+	}
+	if config.Address == "" {
+		config.Address = DefaultStatsDAddress
+	}
+	if config.Format == "" {
+		config.Format = StatsDFormatDogStatsD
+	}
+	if config.MaxPacketSize <= 0 {
+		config.MaxPacketSize = DefaultStatsDPacketSize
+	}
+	return &StatsDExporter{
+		config: config,
+		last:   make(map[string]float64),
+	}
+}
+
+// PushOptions 返回配置中的推送间隔与退避上限
+func (e *StatsDExporter) PushOptions() PushOptions {
+	return PushOptions{Interval: e.config.Interval, MaxBackoff: e.config.MaxBackoff}
+}
+
+// Export 实现 MetricsExporter
+func (e *StatsDExporter) Export(metrics map[string]*Metric) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	keys := make([]string, 0, len(metrics))
+	for k := range metrics {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var lines []statsdLine
+	for _, key := range keys {
+		lines = e.appendLines(lines, key, metrics[key])
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	// 只有送达的数据包中的增量并入 last，未送达的下次推送时重新计算
+	return e.send(lines)
+}
+
+// Close 关闭连接
+func (e *StatsDExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conn == nil {
+		return nil
+	}
+	err := e.conn.Close()
+	e.conn = nil
+	return err
+}
+
+func (e *StatsDExporter) appendLines(lines []statsdLine, key string, m *Metric) []statsdLine {
+	name := m.Name
+	if name == "" {
+		name, _, _ = ParseMetricKey(key)
+	}
+	switch m.Type {
+	case "counter":
+		lines = e.appendDelta(lines, key, name, m.Labels, m.Value)
+	case "gauge":
+		lines = append(lines, statsdLine{text: e.line(name, m.Labels, formatFloat(m.Value), "g")})
+	case "histogram", "summary":
+		lines = e.appendDelta(lines, key+".count", name+".count", m.Labels, float64(m.Count))
+		lines = e.appendDelta(lines, key+".sum", name+".sum", m.Labels, m.Sum)
+		for _, q := range statsdQuantiles {
+			if v := m.Quantile(q); !math.IsNaN(v) && !math.IsInf(v, 0) {
+				lines = append(lines, statsdLine{text: e.line(name+"."+quantileSuffix(q), m.Labels, formatFloat(v), "g")})
+			}
+		}
+	}
+	return lines
+}
+
+// appendDelta 追加累计值自上次送达以来的增量（|c），没有变化时只更新 last。
+// 值变小（收集器被重置）时按重新开始计数处理
+func (e *StatsDExporter) appendDelta(lines []statsdLine, key, name string, labels map[string]string, value float64) []statsdLine {
+	last, seen := e.last[key]
+	d := value - last
+	if seen && d < 0 {
+		d = value
+	}
+	if d == 0 {
+		e.last[key] = value
+		return lines
+	}
+	return append(lines, statsdLine{text: e.line(name, labels, formatFloat(d), "c"), key: key, value: value})
+}
+
+// line 生成一行 StatsD 数据：name:value|type，按格式附加标签
+func (e *StatsDExporter) line(name string, labels map[string]string, value, typ string) string {
+	var sb strings.Builder
+	if e.config.Prefix != "" {
+		sb.WriteString(statsdSanitize(e.config.Prefix))
+		sb.WriteByte('.')
+	}
+	sb.WriteString(statsdSanitize(name))
+
+	tags := e.tags(labels)
+	if e.config.Format == StatsDFormatStatsD {
+		for _, t := range tags {
+			sb.WriteByte(';')
+			sb.WriteString(t[0])
+			sb.WriteByte('=')
+			sb.WriteString(t[1])
+		}
+	}
+	sb.WriteByte(':')
+	sb.WriteString(value)
+	sb.WriteByte('|')
+	sb.WriteString(typ)
+	if e.config.Format == StatsDFormatDogStatsD && len(tags) > 0 {
+		sb.WriteString("|#")
+		for i, t := range tags {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(t[0])
+			sb.WriteByte(':')
+			sb.WriteString(t[1])
+		}
+	}
+	return sb.String()
+}
+
+// tags 合并静态标签与指标标签（后者优先），按名称排序
+func (e *StatsDExporter) tags(labels map[string]string) [][2]string {
+	merged := make(map[string]string, len(e.config.Tags)+len(labels))
+	for k, v := range e.config.Tags {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	tags := make([][2]string, 0, len(merged))
+	for _, k := range sortedKeys(merged) {
+		tags = append(tags, [2]string{statsdSanitize(k), statsdSanitize(merged[k])})
+	}
+	return tags
+}
+
+// send 将多行按 MaxPacketSize 打包发送，每个数据包送达后记录其中的增量；
+// 发送失败时断开，下次推送重新连接
+func (e *StatsDExporter) send(lines []statsdLine) error {
+	if e.conn == nil {
+		conn, err := net.Dial(e.config.Network, e.config.Address)
+		if err != nil {
+			return fmt.Errorf("metrics: statsd dial %s: %w", e.config.Address, err)
+		}
+		e.conn = conn
+	}
+
+	packet := make([]byte, 0, e.config.MaxPacketSize)
+	var inPacket []statsdLine
+	flush := func() error {
+		if len(packet) == 0 {
+			return nil
+		}
+		if _, err := e.conn.Write(packet); err != nil {
+			return err
+		}
+		for _, l := range inPacket {
+			if l.key != "" {
+				e.last[l.key] = l.value
+			}
+		}
+		packet, inPacket = packet[:0], inPacket[:0]
+		return nil
+	}
+	for _, l := range lines {
+		if len(packet) > 0 && len(packet)+1+len(l.text) > e.config.MaxPacketSize {
+			if err := flush(); err != nil {
+				return e.fail(err)
+			}
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, l.text...)
+		inPacket = append(inPacket, l)
+	}
+	if err := flush(); err != nil {
+		return e.fail(err)
+	}
+	return nil
+}
+
+func (e *StatsDExporter) fail(err error) error {
+	e.conn.Close()
+	e.conn = nil
+	return fmt.Errorf("metrics: statsd write: %w", err)
+}
+
+// statsdSanitize 替换 StatsD 协议中的保留字符
+func statsdSanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', ';', '=', '\n', ' ':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// quantileSuffix 返回分位数的名称后缀，如 0.99 -> p99、0.999 -> p99.9
+func quantileSuffix(q float64) string {
+	return "p" + strconv.FormatFloat(math.Round(q*1e4)/1e2, 'f', -1, 64)
+}
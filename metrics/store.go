@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"hash/maphash"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shardCount 序列按键哈希分布到的分片数，须为 2 的幂
+const shardCount = 64
+
+// store 分片存储序列。已存在的序列只需分片读锁即可定位，counter 与 gauge
+// 随后以原子操作更新，不同序列、同一序列的并发写都不会争用同一把锁
+type store struct {
+	seed   maphash.Seed
+	shards [shardCount]shard
+}
+
+type shard struct {
+	mu     sync.RWMutex
+	series map[string]*series
+	_      [32]byte // 填充到 64 字节缓存行，避免相邻分片的伪共享
+}
+
+// series 单个序列。counter 与 gauge 的值以 float64 位存于 bits；
+// 直方图与摘要的分布存于 dist，由 mu 保护
+type series struct {
+	name   string
+	typ    string
+	labels map[string]string
+
+	bits atomic.Uint64
+	ts   atomic.Int64
+
+	mu   sync.Mutex
+	dist *Metric
+}
+
+func newStore() *store {
+	s := &store{seed: maphash.MakeSeed()}
+	for i := range s.shards {
+		s.shards[i].series = make(map[string]*series)
+	}
+	return s
+}
+
+func (s *store) shard(key string) *shard {
+	return &s.shards[maphash.String(s.seed, key)&(shardCount-1)]
+}
+
+// get 返回键对应的序列，不存在时返回 nil
+func (s *store) get(key string) *series {
+	sh := s.shard(key)
+	sh.mu.RLock()
+	sr := sh.series[key]
+	sh.mu.RUnlock()
+	return sr
+}
+
+// getOrCreate 返回键对应的序列，不存在时用 create 创建。create 在分片写锁内
+// 调用，且每个键只调用一次
+func (s *store) getOrCreate(key string, create func() *series) *series {
+	if sr := s.get(key); sr != nil {
+		return sr
+	}
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if sr, ok := sh.series[key]; ok {
+		return sr
+	}
+	sr := create()
+	sh.series[key] = sr
+	return sr
+}
+
+// replace 用 create 创建的序列替换键对应的序列（类型变化时使用）
+func (s *store) replace(key string, create func() *series) *series {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sr := create()
+	sh.series[key] = sr
+	return sr
+}
+
+// each 按分片依次遍历所有序列，遍历期间只持有当前分片的读锁
+func (s *store) each(fn func(key string, sr *series)) {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		for k, sr := range sh.series {
+			fn(k, sr)
+		}
+		sh.mu.RUnlock()
+	}
+}
+
+func (s *store) len() int {
+	n := 0
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		n += len(sh.series)
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+func (s *store) reset() {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		sh.series = make(map[string]*series)
+		sh.mu.Unlock()
+	}
+}
+
+// add 原子地累加 counter 值
+func (sr *series) add(delta float64) {
+	for {
+		old := sr.bits.Load()
+		if sr.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			break
+		}
+	}
+	sr.ts.Store(time.Now().Unix())
+}
+
+// set 原子地设置 gauge 值
+func (sr *series) set(value float64) {
+	sr.bits.Store(math.Float64bits(value))
+	sr.ts.Store(time.Now().Unix())
+}
+
+// observe 记录直方图或摘要的观测值
+func (sr *series) observe(value float64) {
+	sr.mu.Lock()
+	sr.dist.observe(value)
+	sr.mu.Unlock()
+}
+
+// snapshot 返回序列当前值的副本
+func (sr *series) snapshot() *Metric {
+	if sr.dist != nil {
+		sr.mu.Lock()
+		m := *sr.dist
+		m.History = append([]float64(nil), sr.dist.History...)
+		m.Buckets = append([]Bucket(nil), sr.dist.Buckets...)
+		sr.mu.Unlock()
+		return &m
+	}
+	return &Metric{
+		Name:      sr.name,
+		Type:      sr.typ,
+		Value:     math.Float64frombits(sr.bits.Load()),
+		Labels:    sr.labels,
+		Timestamp: sr.ts.Load(),
+	}
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestCollector_ConcurrentWrites(t *testing.T) {
+	c := NewCollector()
+	const workers, perWorker = 16, 1000
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			labels := map[string]string{"worker": strconv.Itoa(w % 4)}
+			for i := 0; i < perWorker; i++ {
+				c.IncCounter("jobs_total", labels)
+				c.AddCounter("bytes_total", 0.5, nil)
+				c.SetGauge("queue_depth", float64(i), labels)
+				c.ObserveHistogram("job_duration_seconds", 0.01, labels)
+			}
+		}(w)
+	}
+	// 读与写并发进行
+	for i := 0; i < 10; i++ {
+		c.GetMetrics()
+		c.Export()
+	}
+	wg.Wait()
+
+	var jobs float64
+	for w := 0; w < 4; w++ {
+		jobs += c.GetMetric("jobs_total", map[string]string{"worker": strconv.Itoa(w)}).Value
+	}
+	if jobs != workers*perWorker {
+		t.Errorf("jobs_total = %v, want %d", jobs, workers*perWorker)
+	}
+	if got := c.GetMetric("bytes_total", nil).Value; got != workers*perWorker/2 {
+		t.Errorf("bytes_total = %v", got)
+	}
+	if h := c.GetMetric("job_duration_seconds", map[string]string{"worker": "0"}); h.Count != 4*perWorker {
+		t.Errorf("histogram count = %d", h.Count)
+	}
+	if len(c.GetMetrics()) != 13 {
+		t.Errorf("series = %d, want 13", len(c.GetMetrics()))
+	}
+}
+
+func TestCollector_SnapshotsAndTypeChange(t *testing.T) {
+	c := NewCollector()
+	c.IncCounter("x", nil)
+	snap := c.GetMetric("x", nil)
+	c.IncCounter("x", nil)
+	if snap.Value != 1 || c.GetMetric("x", nil).Value != 2 {
+		t.Error("GetMetric must return a snapshot")
+	}
+
+	c.SetGauge("x", 7, nil)
+	if m := c.GetMetric("x", nil); m.Type != "gauge" || m.Value != 7 {
+		t.Errorf("gauge replacing counter = %+v", m)
+	}
+
+	c.Reset()
+	if c.GetMetric("x", nil) != nil || len(c.GetMetrics()) != 0 {
+		t.Error("Reset must drop all series")
+	}
+}
+
+func BenchmarkIncCounterParallel(b *testing.B) {
+	c := NewCollector()
+	labels := map[string]string{"method": "GET", "path": "/users/{id}", "status": "200"}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.IncCounter("http_requests_total", labels)
+		}
+	})
+}
+
+func BenchmarkSetGaugeParallel(b *testing.B) {
+	c := NewCollector()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			i++
+			c.SetGauge("queue_depth", float64(i), map[string]string{"queue": strconv.Itoa(i % 8)})
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultObjectives 摘要默认的分位数目标及允许的绝对误差
+var DefaultObjectives = map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.95: 0.005, 0.99: 0.001}
+
+// 摘要默认窗口
+const (
+	DefaultSummaryMaxAge     = 10 * time.Minute
+	DefaultSummaryAgeBuckets = 5
+)
+
+// summaryBufferSize 观测值先缓冲再批量并入流，减少排序与压缩次数
+const summaryBufferSize = 500
+
+// SummaryOptions 摘要配置
+type SummaryOptions struct {
+	// Objectives 分位数 -> 允许的绝对误差，默认 DefaultObjectives
+	Objectives map[float64]float64
+	// MaxAge 分位数统计的滑动窗口，默认 10 分钟
+	MaxAge time.Duration
+	// AgeBuckets 窗口内轮转的流数量，默认 5；窗口每 MaxAge/AgeBuckets 前进一格
+	AgeBuckets int
+}
+
+// Summary 基于 CKMS 算法的流式分位数摘要。
+// 只保留满足误差目标所需的样本，且按 MaxAge 窗口轮转，内存不随流量增长
+type Summary struct {
+	mu         sync.Mutex
+	objectives []quantileTarget
+	streams    []*quantileStream
+	head       int // 覆盖时间最长的流，查询使用它
+	interval   time.Duration
+	expires    time.Time
+	buf        []float64
+}
+
+type quantileTarget struct {
+	quantile float64
+	epsilon  float64
+}
+
+// NewSummary 创建摘要
+func NewSummary(opts SummaryOptions) *Summary {
+	if len(opts.Objectives) == 0 {
+		opts.Objectives = DefaultObjectives
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultSummaryMaxAge
+	}
+	if opts.AgeBuckets <= 0 {
+		opts.AgeBuckets = DefaultSummaryAgeBuckets
+	}
+
+	s := &Summary{
+		interval: opts.MaxAge / time.Duration(opts.AgeBuckets),
+		buf:      make([]float64, 0, summaryBufferSize),
+	}
+	for q, eps := range opts.Objectives {
+		s.objectives = append(s.objectives, quantileTarget{quantile: q, epsilon: eps})
+	}
+	sort.Slice(s.objectives, func(i, j int) bool { return s.objectives[i].quantile < s.objectives[j].quantile })
+	s.streams = make([]*quantileStream, opts.AgeBuckets)
+	for i := range s.streams {
+		s.streams[i] = &quantileStream{targets: s.objectives}
+	}
+	s.expires = time.Now().Add(s.interval)
+	return s
+}
+
+// Observe 记录一个观测值
+func (s *Summary) Observe(v float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rotate(time.Now())
+	s.buf = append(s.buf, v)
+	if len(s.buf) == cap(s.buf) {
+		s.flush()
+	}
+}
+
+// Quantile 返回窗口内 q 分位数的估计值，没有观测值时返回 NaN。
+// q 不在 Objectives 中时误差没有保证
+func (s *Summary) Quantile(q float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rotate(time.Now())
+	s.flush()
+	return s.streams[s.head].query(q)
+}
+
+// Quantiles 返回所有目标分位数的估计值
+func (s *Summary) Quantiles() map[float64]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rotate(time.Now())
+	s.flush()
+	out := make(map[float64]float64, len(s.objectives))
+	for _, t := range s.objectives {
+		out[t.quantile] = s.streams[s.head].query(t.quantile)
+	}
+	return out
+}
+
+// flush 将缓冲的观测值并入所有流，调用方需持有 s.mu
+func (s *Summary) flush() {
+	if len(s.buf) == 0 {
+		return
+	}
+	sort.Float64s(s.buf)
+	for _, st := range s.streams {
+		st.merge(s.buf)
+	}
+	s.buf = s.buf[:0]
+}
+
+// rotate 清空过期的流，调用方需持有 s.mu
+func (s *Summary) rotate(now time.Time) {
+	if now.Before(s.expires) {
+		return
+	}
+	// 过期前缓冲的观测值属于旧窗口
+	s.flush()
+	for !now.Before(s.expires) {
+		s.streams[s.head].reset()
+		s.head = (s.head + 1) % len(s.streams)
+		s.expires = s.expires.Add(s.interval)
+	}
+}
+
+// quantileStream 实现 Cormode、Korn、Muthukrishnan、Srivastava 的
+// 有偏分位数算法（CKMS），针对一组目标分位数保证误差
+type quantileStream struct {
+	targets []quantileTarget
+	n       float64
+	samples []ckmsSample
+}
+
+// ckmsSample 中 width 为与前一样本的最小秩差，delta 为秩的不确定度
+type ckmsSample struct {
+	value float64
+	width float64
+	delta float64
+}
+
+func (st *quantileStream) reset() {
+	st.n = 0
+	st.samples = st.samples[:0]
+}
+
+// invariant 返回秩 r 处允许的最大误差
+func (st *quantileStream) invariant(r float64) float64 {
+	m := math.MaxFloat64
+	for _, t := range st.targets {
+		var f float64
+		if t.quantile*st.n <= r {
+			f = 2 * t.epsilon * r / t.quantile
+		} else {
+			f = 2 * t.epsilon * (st.n - r) / (1 - t.quantile)
+		}
+		if f < m {
+			m = f
+		}
+	}
+	return m
+}
+
+// merge 并入升序的观测值后压缩
+func (st *quantileStream) merge(sorted []float64) {
+	var r float64
+	i := 0
+	for _, v := range sorted {
+		for ; i < len(st.samples) && st.samples[i].value <= v; i++ {
+			r += st.samples[i].width
+		}
+		delta := 0.0
+		if i > 0 && i < len(st.samples) {
+			delta = math.Max(0, math.Floor(st.invariant(r))-1)
+		}
+		st.samples = append(st.samples, ckmsSample{})
+		copy(st.samples[i+1:], st.samples[i:])
+		st.samples[i] = ckmsSample{value: v, width: 1, delta: delta}
+		i++
+		st.n++
+		r++
+	}
+	st.compress()
+}
+
+// compress 合并误差允许范围内的相邻样本
+func (st *quantileStream) compress() {
+	if len(st.samples) < 2 {
+		return
+	}
+	xi := len(st.samples) - 1
+	x := st.samples[xi]
+	r := st.n - 1 - x.width
+	for i := len(st.samples) - 2; i >= 0; i-- {
+		c := st.samples[i]
+		if c.width+x.width+x.delta <= st.invariant(r) {
+			x.width += c.width
+			st.samples[xi] = x
+			st.samples = append(st.samples[:i], st.samples[i+1:]...)
+			xi--
+		} else {
+			x = c
+			xi = i
+		}
+		r -= c.width
+	}
+}
+
+func (st *quantileStream) query(q float64) float64 {
+	if len(st.samples) == 0 {
+		return math.NaN()
+	}
+	t := math.Ceil(q * st.n)
+	t += math.Ceil(st.invariant(t) / 2)
+	prev := st.samples[0]
+	var r float64
+	for _, c := range st.samples[1:] {
+		r += prev.width
+		if r+c.width+c.delta > t {
+			return prev.value
+		}
+		prev = c
+	}
+	return prev.value
+}
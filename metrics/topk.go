@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"container/heap"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultTopKCapacity 默认每个 TopK 跟踪器保留的计数槽数量
+const DefaultTopKCapacity = 100
+
+// TopKItem TopK 结果项
+// Count 为估计值，真实值落在 [Count-Error, Count] 区间内
+type TopKItem struct {
+	Key   string  `json:"key"`
+	Count float64 `json:"count"`
+	Error float64 `json:"error"`
+}
+
+// TopK 基于 Space-Saving 算法的重量级元素跟踪器
+// 内存占用固定为 capacity 个槽位，不随键数量增长
+type TopK struct {
+	capacity int
+	index    map[string]*topKEntry
+	heap     topKHeap
+	mu       sync.Mutex
+}
+
+type topKEntry struct {
+	key   string
+	count float64
+	err   float64
+	pos   int
+}
+
+// NewTopK 创建 TopK 跟踪器
+func NewTopK(capacity int) *TopK {
+	if capacity <= 0 {
+		capacity = DefaultTopKCapacity
+	}
+	return &TopK{
+		capacity: capacity,
+		index:    make(map[string]*topKEntry, capacity),
+		heap:     make(topKHeap, 0, capacity),
+	}
+}
+
+// Add 为 key 累加权重（计数场景传 1，耗时场景传耗时）
+func (t *TopK) Add(key string, weight float64) {
+	if weight <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if entry, ok := t.index[key]; ok {
+		entry.count += weight
+		heap.Fix(&t.heap, entry.pos)
+		return
+	}
+
+	if len(t.heap) < t.capacity {
+		entry := &topKEntry{key: key, count: weight}
+		heap.Push(&t.heap, entry)
+		t.index[key] = entry
+		return
+	}
+
+	// 替换当前最小槽位，继承其计数作为误差上界
+	min := t.heap[0]
+	delete(t.index, min.key)
+	min.err = min.count
+	min.count += weight
+	min.key = key
+	t.index[key] = min
+	heap.Fix(&t.heap, 0)
+}
+
+// Top 返回按计数降序排列的前 n 项，n <= 0 时返回全部
+func (t *TopK) Top(n int) []TopKItem {
+	t.mu.Lock()
+	items := make([]TopKItem, 0, len(t.heap))
+	for _, entry := range t.heap {
+		items = append(items, TopKItem{Key: entry.key, Count: entry.count, Error: entry.err})
+	}
+	t.mu.Unlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Key < items[j].Key
+	})
+	if n > 0 && n < len(items) {
+		items = items[:n]
+	}
+	return items
+}
+
+// Reset 清空跟踪器
+func (t *TopK) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.index = make(map[string]*topKEntry, t.capacity)
+	t.heap = make(topKHeap, 0, t.capacity)
+}
+
+// topKHeap 按计数排序的最小堆
+type topKHeap []*topKEntry
+
+func (h topKHeap) Len() int           { return len(h) }
+func (h topKHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h topKHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos = i
+	h[j].pos = j
+}
+
+func (h *topKHeap) Push(x any) {
+	entry := x.(*topKEntry)
+	entry.pos = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *topKHeap) Pop() any {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	*h = old[:n-1]
+	return entry
+}
+
+// TopKSummary 热点路由、慢查询与高频错误码
+type TopKSummary struct {
+	HotRoutes   []TopKItem `json:"hot_routes"`
+	SlowQueries []TopKItem `json:"slow_queries"`
+	ErrorCodes  []TopKItem `json:"error_codes"`
+}
+
+// TopKHandler TopK 管理端点
+type TopKHandler struct {
+	collector *Collector
+}
+
+// NewTopKHandler 创建 TopK 管理端点，支持 ?n= 指定返回条数（默认 10）
+func NewTopKHandler(collector *Collector) *TopKHandler {
+	return &TopKHandler{
+		collector: collector,
+	}
+}
+
+// ServeHTTP 实现 http.Handler
+func (h *TopKHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := 10
+	if raw := r.URL.Query().Get("n"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "invalid n", http.StatusBadRequest)
+			return
+		}
+		n = parsed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.collector.TopK(n))
+}
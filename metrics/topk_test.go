@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestTopK_TracksHeavyHitters(t *testing.T) {
+	tk := NewTopK(5)
+	for i := 0; i < 1000; i++ {
+		tk.Add("hot", 1)
+		if i%2 == 0 {
+			tk.Add("warm", 1)
+		}
+		tk.Add(fmt.Sprintf("noise-%d", i), 1)
+	}
+
+	top := tk.Top(2)
+	if len(top) != 2 || top[0].Key != "hot" || top[1].Key != "warm" {
+		t.Fatalf("top = %+v, want hot then warm", top)
+	}
+	if top[0].Count < 1000 {
+		t.Errorf("count %v must not underestimate the true frequency", top[0].Count)
+	}
+	if len(tk.Top(0)) != 5 {
+		t.Errorf("tracker must stay bounded to its capacity")
+	}
+}
+
+func TestCollector_TopKSummaryAndHandler(t *testing.T) {
+	c := NewCollector()
+	c.RecordRequest("GET", "/users", 200, 0.01)
+	c.RecordRequest("GET", "/users", 500, 0.01)
+	c.RecordRequest("POST", "/orders", 404, 0.01)
+	c.RecordDBQuery("SELECT slow", 2.0)
+	c.RecordDBQuery("SELECT fast", 0.001)
+
+	top := c.TopK(1)
+	if top.HotRoutes[0].Key != "GET /users" || top.SlowQueries[0].Key != "SELECT slow" {
+		t.Fatalf("unexpected summary: %+v", top)
+	}
+	if len(c.TopK(10).ErrorCodes) != 2 {
+		t.Errorf("expected two error codes, got %+v", c.TopK(10).ErrorCodes)
+	}
+
+	rec := httptest.NewRecorder()
+	NewTopKHandler(c).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics/topk?n=x", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 for invalid n", rec.Code)
+	}
+
+	c.Reset()
+	if len(c.TopK(10).HotRoutes) != 0 {
+		t.Error("Reset should clear TopK trackers")
+	}
+}
+
+func TestMetricsHTTPExporter_BasicAuth(t *testing.T) {
+	e := NewMetricsHTTPExporter(NewCollector(), MetricsExporterConfig{EnableAuth: true, Username: "prom", Password: "s3cret"})
+	cases := []struct {
+		user, pass string
+		set        bool
+		want       int
+	}{
+		{"prom", "s3cret", true, http.StatusOK},
+		{"prom", "wrong", true, http.StatusUnauthorized},
+		{"other", "s3cret", true, http.StatusUnauthorized},
+		{"prom", "s3cret-longer", true, http.StatusUnauthorized},
+		{"", "", false, http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/metrics/topk", nil)
+		if c.set {
+			req.SetBasicAuth(c.user, c.pass)
+		}
+		rec := httptest.NewRecorder()
+		e.TopKHandler().ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("%q/%q: status = %d, want %d", c.user, c.pass, rec.Code, c.want)
+		}
+	}
+}
+
+func TestCredentialsMatch_ComparesBothFields(t *testing.T) {
+	var compared []string
+	orig := constantTimeEqual
+	constantTimeEqual = func(a, b string) bool {
+		compared = append(compared, b)
+		return orig(a, b)
+	}
+	defer func() { constantTimeEqual = orig }()
+
+	cases := []struct {
+		user, pass string
+		want       bool
+	}{
+		{"prom", "s3cret", true},
+		{"other", "s3cret", false},
+		{"prom", "wrong", false},
+		{"other", "wrong", false},
+	}
+	for _, c := range cases {
+		compared = nil
+		if got := credentialsMatch(c.user, c.pass, "prom", "s3cret"); got != c.want {
+			t.Errorf("%q/%q: match = %v, want %v", c.user, c.pass, got, c.want)
+		}
+		if want := []string{"prom", "s3cret"}; !slices.Equal(compared, want) {
+			t.Errorf("%q/%q: compared %v, want both fields %v", c.user, c.pass, compared, want)
+		}
+	}
+}
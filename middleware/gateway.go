@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/leeforge/framework/request"
+	"github.com/leeforge/framework/security"
 )
 
 type LoggerAdapter interface {
@@ -235,8 +238,7 @@ func (c *MiddlewareChain) Then(handler http.Handler) http.Handler {
 type SecurityMiddleware struct {
 	cors        CORSConfig
 	helmet      bool
-	ipWhitelist []string
-	ipBlacklist []string
+	ipFilter    *security.IPFilter
 	requestSize int64
 }
 
@@ -249,12 +251,17 @@ type CORSConfig struct {
 	MaxAge           int
 }
 
+// NewSecurityMiddleware panics when an IP list entry is invalid; use
+// SetIPLists for lists loaded at runtime.
 func NewSecurityMiddleware(config SecurityConfig) *SecurityMiddleware {
+	filter, err := security.NewIPFilter(config.IPWhitelist, config.IPBlacklist)
+	if err != nil {
+		panic("middleware: " + err.Error())
+	}
 	return &SecurityMiddleware{
 		cors:        config.CORS,
 		helmet:      config.Helmet,
-		ipWhitelist: config.IPWhitelist,
-		ipBlacklist: config.IPBlacklist,
+		ipFilter:    filter,
 		requestSize: config.RequestSize,
 	}
 }
@@ -269,7 +276,7 @@ type SecurityConfig struct {
 
 func (s *SecurityMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !s.checkIP(r.RemoteAddr) {
+		if !s.ipFilter.Allow(request.GetClientIP(r)) {
 			http.Error(w, "IP not allowed", http.StatusForbidden)
 			return
 		}
@@ -295,30 +302,15 @@ func (s *SecurityMiddleware) Middleware(next http.Handler) http.Handler {
 	})
 }
 
-func (s *SecurityMiddleware) checkIP(remoteAddr string) bool {
-	ip := remoteAddr
-
-	for _, black := range s.ipBlacklist {
-		if ip == black {
-			return false
-		}
-	}
-
-	if len(s.ipWhitelist) > 0 {
-		for _, white := range s.ipWhitelist {
-			if ip == white {
-				return true
-			}
-		}
-		return false
-	}
-
-	return true
+// SetIPLists replaces the IP whitelist and blacklist at runtime. On an
+// invalid entry the current lists are kept.
+func (s *SecurityMiddleware) SetIPLists(whitelist, blacklist []string) error {
+	return s.ipFilter.Update(whitelist, blacklist)
 }
 
 func (s *SecurityMiddleware) applyCORS(w http.ResponseWriter, r *http.Request) {
 	for _, origin := range s.cors.AllowedOrigins {
-		if origin == "*" || origin == r.Header.Get("Origin") {
+		if origin == "*" || security.ConstantTimeEqual(origin, r.Header.Get("Origin")) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			break
 		}
@@ -331,11 +323,7 @@ func (s *SecurityMiddleware) applyCORS(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *SecurityMiddleware) applyHelmet(w http.ResponseWriter) {
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.Header().Set("X-Frame-Options", "DENY")
-	w.Header().Set("X-XSS-Protection", "1; mode=block")
-	w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
-	w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
+	security.DefaultHelmetConfig().SetHeaders(w.Header())
 }
 
 func joinStrings(items []string, sep string) string {
@@ -6,9 +6,13 @@ import (
 	"strconv"
 	"sync"
 	"time"
+
+	"github.com/leeforge/framework/request"
 )
 
 // RateLimiter 限流器
+//
+// Deprecated: 使用 ratelimit.Limiter，支持令牌桶/滑动窗口、Redis 存储与标准 RateLimit-* 响应头
 type RateLimiter struct {
 	backend BackendAdapter
 	config  RateLimitConfig
@@ -107,7 +111,8 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 		// 获取 API Key
 		apiKey := r.Header.Get("X-API-Key")
 		if apiKey == "" {
-			apiKey = "anonymous"
+			// 匿名请求按客户端真实 IP 限流
+			apiKey = "ip:" + request.GetClientIP(r)
 		}
 
 		// 获取限流配置
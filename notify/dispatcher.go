@@ -0,0 +1,267 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/jobs"
+	"github.com/leeforge/framework/ratelimit"
+	"github.com/leeforge/framework/retry"
+	"go.uber.org/zap"
+)
+
+// JobTypeDeliver is the job type of a background delivery.
+const JobTypeDeliver = "notify.deliver"
+
+// Options configures a Dispatcher.
+type Options struct {
+	// Templates renders SendTemplate content.
+	Templates *Templates
+	// Jobs, when set, delivers in the background: each failed attempt is
+	// recorded as StatusFailed and retried by the manager until it succeeds
+	// or runs out of attempts. Without it Send makes one attempt before
+	// returning.
+	Jobs *jobs.Manager
+	// MaxAttempts overrides the job manager's attempt limit per notification.
+	MaxAttempts int
+	// RateLimits caps how often each channel, by name, sends. Channels
+	// without an entry are not limited.
+	RateLimits map[string]ratelimit.Limit
+	// RateStore keeps rate limit state (default in memory); use a Redis
+	// store to share limits between instances.
+	RateStore ratelimit.Store
+	// MaxWait is how long a delivery waits for a rate limited channel
+	// before the attempt fails with ErrRateLimited (default 5s).
+	MaxWait time.Duration
+	// Status tracks every notification (default in memory, 1000 entries).
+	Status StatusStore
+	Logger *zap.Logger
+}
+
+// Dispatcher routes notifications to channels by name.
+type Dispatcher struct {
+	channels map[string]Channel
+	limiter  *ratelimit.Limiter
+	opts     Options
+}
+
+// NewDispatcher creates a dispatcher over channels. With Options.Jobs it
+// registers JobTypeDeliver, which has to happen before manager.Start so
+// that deliveries left over from a previous run find their handler.
+func NewDispatcher(opts Options, channels ...Channel) *Dispatcher {
+	if opts.RateStore == nil {
+		opts.RateStore = ratelimit.NewMemoryStore(0)
+	}
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = 5 * time.Second
+	}
+	if opts.Status == nil {
+		opts.Status = NewMemoryStatusStore(0)
+	}
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	d := &Dispatcher{
+		channels: make(map[string]Channel, len(channels)),
+		// Every channel counts against its own limit via AllowLimit.
+		limiter: ratelimit.New(opts.RateStore, ratelimit.PerSecond(1), ratelimit.WithPrefix("notify:")),
+		opts:    opts,
+	}
+	for _, ch := range channels {
+		d.channels[ch.Name()] = ch
+	}
+	if opts.Jobs != nil {
+		opts.Jobs.Register(JobTypeDeliver, d.deliverJob)
+	}
+	return d
+}
+
+// Status returns the status store.
+func (d *Dispatcher) Status() StatusStore {
+	return d.opts.Status
+}
+
+// Send validates n and delivers it, or queues it when the Dispatcher has a
+// job manager; a nil error then means the notification was queued. Send
+// assigns n.ID when empty. Sending a notification whose ID is already
+// queued, sent or rejected does nothing; one whose last attempt or enqueue
+// failed is tried again.
+func (d *Dispatcher) Send(ctx context.Context, n *Notification) error {
+	if n.ID == "" {
+		n.ID = uuid.NewString()
+	}
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	if _, ok := d.channels[n.Channel]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownChannel, n.Channel)
+	}
+
+	status, err := d.opts.Status.Get(ctx, n.ID)
+	switch {
+	case err == nil && status.Status != StatusFailed:
+		return nil
+	case errors.Is(err, ErrNotFound):
+		status = newDelivery(n)
+		if err := d.opts.Status.Save(ctx, status); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	}
+
+	if d.opts.Jobs == nil {
+		_, err := d.deliver(ctx, n, status, status.Attempts+1)
+		return err
+	}
+	opts := []jobs.EnqueueOption{jobs.WithIdempotencyKey("notify:" + n.ID)}
+	if d.opts.MaxAttempts > 0 {
+		opts = append(opts, jobs.WithMaxAttempts(d.opts.MaxAttempts))
+	}
+	if tenant := n.Metadata[jobs.TenantMetadataKey]; tenant != "" {
+		opts = append(opts, jobs.WithTenant(tenant))
+	}
+	// A job still holding the idempotency key is retrying this
+	// notification; ErrDuplicate leaves it to that job.
+	_, err = d.opts.Jobs.Enqueue(ctx, JobTypeDeliver, n, opts...)
+	if err != nil && !errors.Is(err, jobs.ErrDuplicate) {
+		err = fmt.Errorf("notify: queue %s: %w", n.ID, err)
+		// Mark it failed so that sending it again is not taken for a duplicate.
+		status.Status, status.Error, status.UpdatedAt = StatusFailed, err.Error(), time.Now()
+		if saveErr := d.opts.Status.Save(context.WithoutCancel(ctx), status); saveErr != nil {
+			d.opts.Logger.Error("notification status update failed", zap.String("notification_id", n.ID), zap.Error(saveErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// SendTemplate renders the template called name for n.Channel with data
+// into n and sends it. Content already set on n wins over the template's.
+func (d *Dispatcher) SendTemplate(ctx context.Context, name string, data any, n *Notification) error {
+	if d.opts.Templates == nil {
+		return fmt.Errorf("%w: %s (no templates configured)", ErrTemplateNotFound, name)
+	}
+	content, err := d.opts.Templates.Render(name, n.Channel, data)
+	if err != nil {
+		return err
+	}
+	if n.Subject == "" {
+		n.Subject = content.Subject
+	}
+	if n.Text == "" {
+		n.Text = content.Text
+	}
+	if len(n.Payload) == 0 {
+		n.Payload = content.Payload
+	}
+	n.Template = name
+	return d.Send(ctx, n)
+}
+
+// deliverJob is the JobTypeDeliver handler. A rejection marked with
+// retry.Permanent is recorded as StatusRejected and succeeds the job, so the
+// notification is neither retried nor dead-lettered.
+func (d *Dispatcher) deliverJob(ctx context.Context, job *jobs.Job) (any, error) {
+	var n Notification
+	if err := job.Decode(&n); err != nil {
+		return nil, err
+	}
+	status, err := d.opts.Status.Get(ctx, n.ID)
+	if errors.Is(err, ErrNotFound) {
+		status = newDelivery(&n)
+	} else if err != nil {
+		return nil, err
+	}
+	providerID, err := d.deliver(ctx, &n, status, job.Attempts)
+	if retry.IsPermanent(err) {
+		return map[string]string{"status": string(StatusRejected), "error": err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"status": string(StatusSent), "providerId": providerID}, nil
+}
+
+// deliver makes one attempt and records its outcome in status.
+func (d *Dispatcher) deliver(ctx context.Context, n *Notification, status *Delivery, attempt int) (string, error) {
+	var providerID string
+	ch, ok := d.channels[n.Channel]
+	err := retry.Permanent(fmt.Errorf("%w: %s", ErrUnknownChannel, n.Channel))
+	if ok {
+		if err = d.wait(ctx, n.Channel); err == nil {
+			providerID, err = ch.Send(ctx, n)
+		}
+	}
+
+	now := time.Now()
+	status.Attempts = attempt
+	status.UpdatedAt = now
+	status.ProviderID = providerID
+	switch {
+	case err == nil:
+		status.Status, status.Error, status.SentAt = StatusSent, "", &now
+	case retry.IsPermanent(err):
+		status.Status, status.Error = StatusRejected, err.Error()
+	default:
+		status.Status, status.Error = StatusFailed, err.Error()
+	}
+	if err != nil {
+		d.opts.Logger.Warn("notification delivery failed",
+			zap.String("notification_id", n.ID),
+			zap.String("channel", n.Channel),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+	}
+	if saveErr := d.opts.Status.Save(context.WithoutCancel(ctx), status); saveErr != nil {
+		d.opts.Logger.Error("notification status update failed", zap.String("notification_id", n.ID), zap.Error(saveErr))
+	}
+	return providerID, err
+}
+
+// wait blocks until channel is under its rate limit, for at most MaxWait.
+func (d *Dispatcher) wait(ctx context.Context, channel string) error {
+	limit, ok := d.opts.RateLimits[channel]
+	if !ok {
+		return nil
+	}
+	deadline := time.Now().Add(d.opts.MaxWait)
+	for {
+		res, err := d.limiter.AllowLimit(ctx, channel, limit)
+		if err != nil {
+			return fmt.Errorf("notify: rate limit %s: %w", channel, err)
+		}
+		if res.Allowed {
+			return nil
+		}
+		if time.Now().Add(res.RetryAfter).After(deadline) {
+			return fmt.Errorf("%w: %s, retry after %s", ErrRateLimited, channel, res.RetryAfter)
+		}
+		timer := time.NewTimer(res.RetryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func newDelivery(n *Notification) *Delivery {
+	return &Delivery{
+		NotificationID: n.ID,
+		Channel:        n.Channel,
+		To:             n.To,
+		Template:       n.Template,
+		Status:         StatusPending,
+		Metadata:       n.Metadata,
+		CreatedAt:      n.CreatedAt,
+		UpdatedAt:      n.CreatedAt,
+	}
+}
@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leeforge/framework/ent"
+	"github.com/leeforge/framework/ent/notificationdelivery"
+)
+
+// EntStatusStore persists statuses in the NotificationDelivery table.
+type EntStatusStore struct {
+	client *ent.Client
+}
+
+var _ StatusStore = (*EntStatusStore)(nil)
+
+// NewEntStatusStore creates a StatusStore over client.
+func NewEntStatusStore(client *ent.Client) *EntStatusStore {
+	return &EntStatusStore{client: client}
+}
+
+// Save implements StatusStore. The notification, channel, recipient and
+// template of an existing status are immutable and kept.
+func (s *EntStatusStore) Save(ctx context.Context, d *Delivery) error {
+	n, err := s.update(ctx, d)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	builder := s.client.NotificationDelivery.Create().
+		SetNotificationID(d.NotificationID).
+		SetChannel(d.Channel).
+		SetRecipient(d.To).
+		SetTemplate(d.Template).
+		SetStatus(notificationdelivery.Status(d.Status)).
+		SetAttempts(d.Attempts).
+		SetProviderID(d.ProviderID).
+		SetLastError(d.Error).
+		SetMetadata(d.Metadata).
+		SetNillableSentAt(d.SentAt)
+	if !d.CreatedAt.IsZero() {
+		builder.SetCreatedAt(d.CreatedAt)
+	}
+	if !d.UpdatedAt.IsZero() {
+		builder.SetUpdatedAt(d.UpdatedAt)
+	}
+	if _, err := builder.Save(ctx); err != nil {
+		if ent.IsConstraintError(err) {
+			// Created concurrently: update the winner instead.
+			_, err = s.update(ctx, d)
+			return err
+		}
+		return fmt.Errorf("failed to create notification delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *EntStatusStore) update(ctx context.Context, d *Delivery) (int, error) {
+	builder := s.client.NotificationDelivery.Update().
+		Where(notificationdelivery.NotificationIDEQ(d.NotificationID)).
+		SetStatus(notificationdelivery.Status(d.Status)).
+		SetAttempts(d.Attempts).
+		SetProviderID(d.ProviderID).
+		SetLastError(d.Error).
+		SetMetadata(d.Metadata)
+	if d.SentAt != nil {
+		builder.SetSentAt(*d.SentAt)
+	} else {
+		builder.ClearSentAt()
+	}
+	if !d.UpdatedAt.IsZero() {
+		builder.SetUpdatedAt(d.UpdatedAt)
+	}
+	n, err := builder.Save(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update notification delivery: %w", err)
+	}
+	return n, nil
+}
+
+// Get implements StatusStore.
+func (s *EntStatusStore) Get(ctx context.Context, notificationID string) (*Delivery, error) {
+	row, err := s.client.NotificationDelivery.Query().
+		Where(notificationdelivery.NotificationIDEQ(notificationID)).
+		Only(ctx)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to query notification delivery: %w", err)
+	}
+	return deliveryFromEnt(row), nil
+}
+
+// List implements StatusStore.
+func (s *EntStatusStore) List(ctx context.Context, query StatusQuery) ([]*Delivery, error) {
+	q := s.client.NotificationDelivery.Query()
+	if query.Channel != "" {
+		q.Where(notificationdelivery.ChannelEQ(query.Channel))
+	}
+	if query.Status != "" {
+		q.Where(notificationdelivery.StatusEQ(notificationdelivery.Status(query.Status)))
+	}
+	rows, err := q.
+		Order(ent.Desc(notificationdelivery.FieldCreatedAt), ent.Desc(notificationdelivery.FieldID)).
+		Limit(query.limit()).
+		All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification deliveries: %w", err)
+	}
+
+	result := make([]*Delivery, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, deliveryFromEnt(row))
+	}
+	return result, nil
+}
+
+func deliveryFromEnt(row *ent.NotificationDelivery) *Delivery {
+	return &Delivery{
+		NotificationID: row.NotificationID,
+		Channel:        row.Channel,
+		To:             row.Recipient,
+		Template:       row.Template,
+		Status:         Status(row.Status),
+		Attempts:       row.Attempts,
+		ProviderID:     row.ProviderID,
+		Error:          row.LastError,
+		Metadata:       row.Metadata,
+		CreatedAt:      row.CreatedAt,
+		UpdatedAt:      row.UpdatedAt,
+		SentAt:         row.SentAt,
+	}
+}
@@ -0,0 +1,105 @@
+// Package notify sends notifications through named channels: signed
+// webhooks, Slack incoming webhooks and SMS providers. A Dispatcher in front
+// of them renders templated payloads, enforces per-channel rate limits,
+// tracks the delivery status of every notification in a StatusStore (in
+// memory or through ent) and, given a jobs.Manager, delivers in the
+// background with the manager's retries and dead-lettering.
+//
+//	tmpl, err := notify.ParseTemplates(os.DirFS("templates/notify"), notify.TemplateOptions{})
+//	d := notify.NewDispatcher(notify.Options{
+//		Templates:  tmpl,
+//		Jobs:       manager,
+//		RateLimits: map[string]ratelimit.Limit{"sms": ratelimit.PerSecond(1)},
+//		Status:     notify.NewEntStatusStore(client),
+//	},
+//		notify.NewSlackChannel(notify.SlackConfig{WebhookURL: slackURL}),
+//		notify.NewSMSChannel(notify.NewTwilioProvider(twilioCfg), notify.SMSConfig{From: "+15550100"}),
+//	)
+//	err = d.SendTemplate(ctx, "order.shipped", order, &notify.Notification{Channel: "sms", To: phone})
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrUnknownChannel is returned for notifications addressed to a
+	// channel the Dispatcher does not have.
+	ErrUnknownChannel = errors.New("notify: unknown channel")
+	// ErrNoBody is returned for notifications without text or payload.
+	ErrNoBody = errors.New("notify: notification has no body")
+	// ErrNoRecipient is returned by channels that need Notification.To.
+	ErrNoRecipient = errors.New("notify: notification has no recipient")
+	// ErrRateLimited is returned when a channel's rate limit does not free
+	// up within Options.MaxWait.
+	ErrRateLimited = errors.New("notify: channel rate limited")
+	// ErrTemplateNotFound is returned when rendering an unknown template.
+	ErrTemplateNotFound = errors.New("notify: template not found")
+	// ErrNotFound is returned by StatusStore.Get for unknown notifications.
+	ErrNotFound = errors.New("notify: notification not found")
+)
+
+// Notification is a message for one channel.
+type Notification struct {
+	// ID identifies the notification across retries; the Dispatcher
+	// assigns one when empty.
+	ID string `json:"id"`
+	// Channel is the name of the channel to send through.
+	Channel string `json:"channel"`
+	// To is the channel specific recipient: a phone number for SMS, a
+	// Slack channel override, unused by webhooks.
+	To      string `json:"to,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Text    string `json:"text,omitempty"`
+	// Payload is a JSON document sent instead of, or with, Text: the data
+	// of a webhook body or a Slack message with blocks.
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// Template is the template the content was rendered from.
+	Template string `json:"template,omitempty"`
+	// Metadata is recorded with the delivery status; "tenant_id" also
+	// sets the job's tenant.
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// Validate checks that n has a channel and a body.
+func (n *Notification) Validate() error {
+	if n.Channel == "" {
+		return fmt.Errorf("%w: empty channel", ErrUnknownChannel)
+	}
+	if n.Text == "" && len(n.Payload) == 0 {
+		return ErrNoBody
+	}
+	if len(n.Payload) > 0 && !json.Valid(n.Payload) {
+		return errors.New("notify: payload is not valid JSON")
+	}
+	return nil
+}
+
+// Channel delivers notifications through one provider and returns the
+// provider's message ID (empty when the provider has none). Errors that
+// retrying cannot fix are wrapped with retry.Permanent.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, n *Notification) (string, error)
+}
+
+// ChannelFunc adapts a function to a Channel called name.
+func ChannelFunc(name string, send func(ctx context.Context, n *Notification) (string, error)) Channel {
+	return funcChannel{name: name, send: send}
+}
+
+type funcChannel struct {
+	name string
+	send func(ctx context.Context, n *Notification) (string, error)
+}
+
+func (c funcChannel) Name() string { return c.name }
+
+func (c funcChannel) Send(ctx context.Context, n *Notification) (string, error) {
+	return c.send(ctx, n)
+}
@@ -0,0 +1,537 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/ent"
+	"github.com/leeforge/framework/jobs"
+	"github.com/leeforge/framework/ratelimit"
+	"github.com/leeforge/framework/retry"
+	"github.com/leeforge/framework/security"
+
+	_ "modernc.org/sqlite"
+)
+
+// scriptedChannel fails with errs in order, then succeeds.
+type scriptedChannel struct {
+	name string
+	mu   sync.Mutex
+	errs []error
+	sent []Notification
+}
+
+func (c *scriptedChannel) Name() string { return c.name }
+
+func (c *scriptedChannel) Send(_ context.Context, n *Notification) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return "", err
+	}
+	c.sent = append(c.sent, *n)
+	return "p-" + n.ID, nil
+}
+
+func (c *scriptedChannel) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sent)
+}
+
+func newJobManager(t *testing.T) *jobs.Manager {
+	t.Helper()
+	manager := jobs.NewManager(jobs.Options{
+		Workers:     2,
+		MaxAttempts: 3,
+		Backoff:     func(int) time.Duration { return time.Millisecond },
+	})
+	t.Cleanup(func() { manager.Stop(context.Background()) })
+	return manager
+}
+
+func waitForStatus(t *testing.T, store StatusStore, id string, want Status) *Delivery {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		d, err := store.Get(context.Background(), id)
+		if err == nil && d.Status == want {
+			return d
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status of %s = %+v, %v; want %s", id, d, err, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDispatcherSendsSynchronously(t *testing.T) {
+	ch := &scriptedChannel{name: "chat"}
+	d := NewDispatcher(Options{}, ch)
+
+	n := &Notification{Channel: "chat", Text: "hello", Metadata: map[string]string{"tenant_id": "t1"}}
+	if err := d.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if n.ID == "" || ch.count() != 1 {
+		t.Fatalf("id = %q, sent = %d", n.ID, ch.count())
+	}
+	status, err := d.Status().Get(context.Background(), n.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != StatusSent || status.Attempts != 1 || status.ProviderID != "p-"+n.ID ||
+		status.SentAt == nil || status.Metadata["tenant_id"] != "t1" {
+		t.Errorf("status = %+v", status)
+	}
+
+	// 已跟踪的 ID 不会重复发送
+	if err := d.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if ch.count() != 1 {
+		t.Errorf("sent %d times, want once", ch.count())
+	}
+}
+
+func TestDispatcherValidates(t *testing.T) {
+	d := NewDispatcher(Options{}, &scriptedChannel{name: "chat"})
+	if err := d.Send(context.Background(), &Notification{Channel: "pager", Text: "x"}); !errors.Is(err, ErrUnknownChannel) {
+		t.Errorf("unknown channel err = %v", err)
+	}
+	if err := d.Send(context.Background(), &Notification{Channel: "chat"}); !errors.Is(err, ErrNoBody) {
+		t.Errorf("empty body err = %v", err)
+	}
+	if err := d.Send(context.Background(), &Notification{Channel: "chat", Payload: json.RawMessage(`{`)}); err == nil {
+		t.Error("invalid payload accepted")
+	}
+}
+
+func TestDispatcherRetriesThroughJobs(t *testing.T) {
+	ch := &scriptedChannel{name: "chat", errs: []error{errors.New("connection reset")}}
+	manager := newJobManager(t)
+	d := NewDispatcher(Options{Jobs: manager}, ch)
+	manager.Start()
+
+	n := &Notification{Channel: "chat", Text: "hello"}
+	if err := d.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	status := waitForStatus(t, d.Status(), n.ID, StatusSent)
+	if status.Attempts != 2 || status.Error != "" {
+		t.Errorf("status = %+v", status)
+	}
+	if ch.count() != 1 {
+		t.Errorf("sent %d times, want once", ch.count())
+	}
+}
+
+// failingJobStore fails Create while fail is set.
+type failingJobStore struct {
+	*jobs.MemoryStore
+	fail bool
+}
+
+func (s *failingJobStore) Create(ctx context.Context, job *jobs.Job) (*jobs.Job, error) {
+	if s.fail {
+		return nil, errors.New("store unavailable")
+	}
+	return s.MemoryStore.Create(ctx, job)
+}
+
+func TestDispatcherRetriesSendAfterEnqueueFailure(t *testing.T) {
+	ch := &scriptedChannel{name: "chat"}
+	store := &failingJobStore{MemoryStore: jobs.NewMemoryStore(), fail: true}
+	manager := jobs.NewManager(jobs.Options{Store: store})
+	t.Cleanup(func() { manager.Stop(context.Background()) })
+	d := NewDispatcher(Options{Jobs: manager}, ch)
+	manager.Start()
+
+	n := &Notification{Channel: "chat", Text: "hello"}
+	if err := d.Send(context.Background(), n); err == nil {
+		t.Fatal("Send succeeded although the job could not be queued")
+	}
+	if status, _ := d.Status().Get(context.Background(), n.ID); status.Status != StatusFailed {
+		t.Errorf("status after enqueue failure = %+v, want failed", status)
+	}
+
+	store.fail = false
+	if err := d.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	waitForStatus(t, d.Status(), n.ID, StatusSent)
+	if ch.count() != 1 {
+		t.Errorf("sent %d times, want once", ch.count())
+	}
+}
+
+func TestDispatcherRetriesSendAfterFailedDelivery(t *testing.T) {
+	ch := &scriptedChannel{name: "chat", errs: []error{errors.New("connection reset")}}
+	d := NewDispatcher(Options{}, ch)
+
+	n := &Notification{Channel: "chat", Text: "hello"}
+	if err := d.Send(context.Background(), n); err == nil {
+		t.Fatal("Send succeeded although delivery failed")
+	}
+	if err := d.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	status, _ := d.Status().Get(context.Background(), n.ID)
+	if status.Status != StatusSent || status.Attempts != 2 || ch.count() != 1 {
+		t.Errorf("status = %+v, sent = %d", status, ch.count())
+	}
+}
+
+func TestDispatcherPermanentRejectionIsNotRetried(t *testing.T) {
+	ch := &scriptedChannel{name: "chat", errs: []error{retry.Permanent(errors.New("invalid number"))}}
+	manager := newJobManager(t)
+	d := NewDispatcher(Options{Jobs: manager}, ch)
+	manager.Start()
+
+	n := &Notification{Channel: "chat", Text: "hello"}
+	if err := d.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	waitForStatus(t, d.Status(), n.ID, StatusRejected)
+	// 等待可能的重试
+	time.Sleep(20 * time.Millisecond)
+	status, _ := d.Status().Get(context.Background(), n.ID)
+	if status.Status != StatusRejected || status.Attempts != 1 || ch.count() != 0 {
+		t.Errorf("status = %+v, sent = %d", status, ch.count())
+	}
+}
+
+func TestDispatcherRateLimitsPerChannel(t *testing.T) {
+	limited := &scriptedChannel{name: "sms"}
+	free := &scriptedChannel{name: "chat"}
+	d := NewDispatcher(Options{
+		RateLimits: map[string]ratelimit.Limit{"sms": {Rate: 1, Period: time.Hour}},
+		MaxWait:    10 * time.Millisecond,
+	}, limited, free)
+
+	ctx := context.Background()
+	if err := d.Send(ctx, &Notification{Channel: "sms", To: "+15550100", Text: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	second := &Notification{Channel: "sms", To: "+15550100", Text: "b"}
+	if err := d.Send(ctx, second); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("second sms err = %v, want ErrRateLimited", err)
+	}
+	if status, _ := d.Status().Get(ctx, second.ID); status.Status != StatusFailed {
+		t.Errorf("status = %+v", status)
+	}
+	for i := 0; i < 3; i++ {
+		if err := d.Send(ctx, &Notification{Channel: "chat", Text: "c"}); err != nil {
+			t.Fatalf("unlimited channel: %v", err)
+		}
+	}
+}
+
+func TestDispatcherRateLimitWaits(t *testing.T) {
+	ch := &scriptedChannel{name: "chat"}
+	d := NewDispatcher(Options{
+		RateLimits: map[string]ratelimit.Limit{"chat": {Rate: 1, Period: 30 * time.Millisecond}},
+		MaxWait:    time.Second,
+	}, ch)
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := d.Send(context.Background(), &Notification{Channel: "chat", Text: "x"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("second send did not wait for the limit: %s", elapsed)
+	}
+}
+
+func testTemplates(t *testing.T) *Templates {
+	t.Helper()
+	tmpl, err := ParseTemplates(fstest.MapFS{
+		"partials/footer.txt":      {Data: []byte(`{{define "footer"}}-- Acme{{end}}`)},
+		"order/shipped.txt":        {Data: []byte("{{define \"subject\"}}Order {{.ID}}\n shipped{{end}}Your order {{.ID}} shipped.\n{{template \"footer\"}}")},
+		"order/shipped.json":       {Data: []byte(`{"order": {{json .ID}}, "note": {{json .Note}}}`)},
+		"order/shipped.slack.json": {Data: []byte(`{"blocks": [{"type": "section", "text": {"type": "mrkdwn", "text": {{json .Note}}}}]}`)},
+		"broken.json":              {Data: []byte(`{"a": {{.Note}}}`)},
+		"README.md":                {Data: []byte("ignored")},
+	}, TemplateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpl
+}
+
+func TestTemplatesRender(t *testing.T) {
+	tmpl := testTemplates(t)
+	data := map[string]string{"ID": "A-1", "Note": `"fragile" <box>`}
+
+	c, err := tmpl.Render("order/shipped", "webhook", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Subject != "Order A-1 shipped" || c.Text != "Your order A-1 shipped.\n-- Acme" {
+		t.Errorf("content = %+v", c)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(c.Payload, &payload); err != nil || payload["note"] != data["Note"] {
+		t.Errorf("payload = %s, %v", c.Payload, err)
+	}
+
+	// 渠道专属模板优先
+	c, err = tmpl.Render("order/shipped", "slack", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(c.Payload), `"blocks"`) || c.Text == "" {
+		t.Errorf("slack content = %+v", c)
+	}
+
+	if _, err := tmpl.Render("broken", "", map[string]string{"Note": "not json"}); err == nil {
+		t.Error("invalid JSON payload rendered")
+	}
+	if _, err := tmpl.Render("missing", "", nil); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("missing err = %v", err)
+	}
+	if names := tmpl.Names(); strings.Join(names, ",") != "broken,order/shipped,order/shipped.slack" {
+		t.Errorf("names = %v", names)
+	}
+}
+
+func TestDispatcherSendTemplate(t *testing.T) {
+	ch := &scriptedChannel{name: "sms"}
+	d := NewDispatcher(Options{Templates: testTemplates(t)}, ch)
+
+	n := &Notification{Channel: "sms", To: "+15550100"}
+	if err := d.SendTemplate(context.Background(), "order/shipped", map[string]string{"ID": "A-1"}, n); err != nil {
+		t.Fatal(err)
+	}
+	if ch.count() != 1 || ch.sent[0].Template != "order/shipped" || ch.sent[0].Subject != "Order A-1 shipped" {
+		t.Errorf("sent = %+v", ch.sent)
+	}
+	status, _ := d.Status().Get(context.Background(), n.ID)
+	if status.Template != "order/shipped" {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestWebhookChannelSignsRequests(t *testing.T) {
+	secret := []byte("s3cret")
+	var got webhookBody
+	var gotID string
+	srv := httptest.NewServer(security.SignatureMiddleware(security.SignatureOptions{
+		Keys: security.StaticSigningKeys{"notify": secret},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = r.Header.Get(HeaderNotificationID)
+		json.NewDecoder(r.Body).Decode(&got)
+	})))
+	defer srv.Close()
+
+	ch := NewWebhookChannel(WebhookConfig{URL: srv.URL, Signer: &security.RequestSigner{KeyID: "notify", Secret: secret}})
+	n := &Notification{ID: "n-1", Channel: "webhook", Text: "hi", Payload: json.RawMessage(`{"a":1}`)}
+	id, err := ch.Send(context.Background(), n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "n-1" || gotID != "n-1" || got.Text != "hi" || string(got.Data) != `{"a":1}` {
+		t.Errorf("id = %q, header = %q, body = %+v", id, gotID, got)
+	}
+
+	// 密钥不匹配时校验失败，4xx 视为永久错误
+	bad := NewWebhookChannel(WebhookConfig{URL: srv.URL, Signer: &security.RequestSigner{KeyID: "notify", Secret: []byte("wrong")}})
+	if _, err := bad.Send(context.Background(), n); !retry.IsPermanent(err) {
+		t.Errorf("bad signature err = %v, want permanent", err)
+	}
+}
+
+func TestWebhookChannelRetriesServerErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := NewWebhookChannel(WebhookConfig{URL: srv.URL}).Send(context.Background(), &Notification{ID: "n-1", Text: "hi"})
+	if err == nil || retry.IsPermanent(err) {
+		t.Errorf("err = %v, want a retryable error", err)
+	}
+}
+
+func TestSlackChannel(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	ch := NewSlackChannel(SlackConfig{WebhookURL: srv.URL, Channel: "#ops", Username: "bot"})
+	if _, err := ch.Send(context.Background(), &Notification{Subject: "Deploy", Text: "done"}); err != nil {
+		t.Fatal(err)
+	}
+	if got["text"] != "*Deploy*\ndone" || got["channel"] != "#ops" || got["username"] != "bot" {
+		t.Errorf("message = %v", got)
+	}
+
+	payload := json.RawMessage(`{"text": "fallback", "blocks": []}`)
+	if _, err := ch.Send(context.Background(), &Notification{To: "#alerts", Text: "ignored", Payload: payload}); err != nil {
+		t.Fatal(err)
+	}
+	if got["text"] != "fallback" || got["channel"] != "#alerts" || got["blocks"] == nil {
+		t.Errorf("message = %v", got)
+	}
+
+	if _, err := ch.Send(context.Background(), &Notification{Payload: json.RawMessage(`[1]`)}); !retry.IsPermanent(err) {
+		t.Errorf("array payload err = %v", err)
+	}
+}
+
+func TestSMSChannelWithTwilio(t *testing.T) {
+	var form map[string]string
+	var user, pass string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2010-04-01/Accounts/AC1/Messages.json" {
+			http.NotFound(w, r)
+			return
+		}
+		user, pass, _ = r.BasicAuth()
+		r.ParseForm()
+		form = map[string]string{"To": r.PostForm.Get("To"), "From": r.PostForm.Get("From"), "Body": r.PostForm.Get("Body")}
+		if form["To"] == "+15550000" {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"code": 21211, "message": "invalid To"}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"sid": "SM123"}`)
+	}))
+	defer srv.Close()
+
+	ch := NewSMSChannel(NewTwilioProvider(TwilioConfig{AccountSID: "AC1", AuthToken: "tok", BaseURL: srv.URL}),
+		SMSConfig{From: "+15550199", MaxLength: 5})
+	sid, err := ch.Send(context.Background(), &Notification{To: "+1 (555) 010-0100", Text: "hello world"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sid != "SM123" || user != "AC1" || pass != "tok" ||
+		form["To"] != "+15550100100" || form["From"] != "+15550199" || form["Body"] != "hello" {
+		t.Errorf("sid = %q, auth = %s:%s, form = %v", sid, user, pass, form)
+	}
+
+	if _, err := ch.Send(context.Background(), &Notification{To: "+15550000", Text: "x"}); !retry.IsPermanent(err) {
+		t.Errorf("provider 400 err = %v, want permanent", err)
+	}
+	if _, err := ch.Send(context.Background(), &Notification{To: "555-0100", Text: "x"}); !errors.Is(err, ErrInvalidPhone) || !retry.IsPermanent(err) {
+		t.Errorf("local number err = %v", err)
+	}
+	if _, err := ch.Send(context.Background(), &Notification{Text: "x"}); !errors.Is(err, ErrNoRecipient) {
+		t.Errorf("no recipient err = %v", err)
+	}
+}
+
+func newEntClient(t *testing.T) *ent.Client {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:notify-"+uuid.NewString()+"?mode=memory&cache=shared&_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ent.NewClient(ent.Driver(entsql.OpenDB(dialect.SQLite, db)))
+	t.Cleanup(func() { client.Close() })
+	if err := client.Schema.Create(context.Background()); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	return client
+}
+
+func TestEntStatusStore(t *testing.T) {
+	store := NewEntStatusStore(newEntClient(t))
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing err = %v", err)
+	}
+
+	created := time.Now().Add(-time.Minute).Truncate(time.Second)
+	d := &Delivery{
+		NotificationID: "n-1", Channel: "sms", To: "+15550100", Template: "otp",
+		Status: StatusPending, Metadata: map[string]string{"tenant_id": "t1"},
+		CreatedAt: created, UpdatedAt: created,
+	}
+	if err := store.Save(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(ctx, &Delivery{NotificationID: "n-2", Channel: "slack", Status: StatusPending, CreatedAt: created.Add(time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := time.Now().Truncate(time.Second)
+	d.Status, d.Attempts, d.ProviderID, d.SentAt = StatusSent, 2, "SM1", &sent
+	if err := store.Save(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Get(ctx, "n-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusSent || got.Attempts != 2 || got.ProviderID != "SM1" || got.To != "+15550100" ||
+		got.SentAt == nil || !got.SentAt.Equal(sent) || !got.CreatedAt.Equal(created) || got.Metadata["tenant_id"] != "t1" {
+		t.Errorf("got = %+v", got)
+	}
+
+	all, err := store.List(ctx, StatusQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].NotificationID != "n-2" {
+		t.Errorf("list = %+v", all)
+	}
+	sms, _ := store.List(ctx, StatusQuery{Channel: "sms", Status: StatusSent})
+	if len(sms) != 1 || sms[0].NotificationID != "n-1" {
+		t.Errorf("filtered list = %+v", sms)
+	}
+}
+
+func TestDispatcherWithEntStatusStore(t *testing.T) {
+	ch := &scriptedChannel{name: "chat", errs: []error{errors.New("timeout")}}
+	manager := newJobManager(t)
+	store := NewEntStatusStore(newEntClient(t))
+	d := NewDispatcher(Options{Jobs: manager, Status: store}, ch)
+	manager.Start()
+
+	n := &Notification{Channel: "chat", Text: "hello"}
+	if err := d.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	status := waitForStatus(t, store, n.ID, StatusSent)
+	if status.Attempts != 2 || status.ProviderID != "p-"+n.ID {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestMemoryStatusStoreCapacity(t *testing.T) {
+	store := NewMemoryStatusStore(2)
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "c"} {
+		store.Save(ctx, &Delivery{NotificationID: id, Channel: "chat", Status: StatusPending})
+	}
+	if _, err := store.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("oldest entry kept: %v", err)
+	}
+	list, _ := store.List(ctx, StatusQuery{})
+	if len(list) != 2 || list[0].NotificationID != "c" {
+		t.Errorf("list = %+v", list)
+	}
+}
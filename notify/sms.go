@@ -0,0 +1,156 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/leeforge/framework/retry"
+)
+
+// ErrInvalidPhone is returned for recipients that are not E.164 numbers.
+var ErrInvalidPhone = errors.New("notify: invalid phone number")
+
+// SMSProvider sends a text message and returns the provider's message ID.
+// Errors that retrying cannot fix are wrapped with Permanent.
+type SMSProvider interface {
+	SendSMS(ctx context.Context, from, to, body string) (string, error)
+}
+
+// SMSConfig configures an SMSChannel.
+type SMSConfig struct {
+	// Name is the channel name (default "sms").
+	Name string
+	// From is the sender number or alphanumeric sender ID.
+	From string
+	// MaxLength truncates longer texts, counted in characters (default
+	// 1600, ten concatenated segments).
+	MaxLength int
+}
+
+// SMSChannel sends Notification.Text to the phone number in
+// Notification.To through an SMSProvider.
+type SMSChannel struct {
+	provider SMSProvider
+	cfg      SMSConfig
+}
+
+// NewSMSChannel creates an SMS channel over provider.
+func NewSMSChannel(provider SMSProvider, cfg SMSConfig) *SMSChannel {
+	if cfg.Name == "" {
+		cfg.Name = "sms"
+	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = 1600
+	}
+	return &SMSChannel{provider: provider, cfg: cfg}
+}
+
+// Name implements Channel.
+func (c *SMSChannel) Name() string { return c.cfg.Name }
+
+// Send implements Channel. Invalid numbers and notifications without text
+// are rejected permanently; the subject and payload are not sent.
+func (c *SMSChannel) Send(ctx context.Context, n *Notification) (string, error) {
+	if n.To == "" {
+		return "", retry.Permanent(ErrNoRecipient)
+	}
+	to, err := NormalizePhone(n.To)
+	if err != nil {
+		return "", retry.Permanent(err)
+	}
+	if n.Text == "" {
+		return "", retry.Permanent(ErrNoBody)
+	}
+	text := n.Text
+	if r := []rune(text); len(r) > c.cfg.MaxLength {
+		text = string(r[:c.cfg.MaxLength])
+	}
+	return c.provider.SendSMS(ctx, c.cfg.From, to, text)
+}
+
+// NormalizePhone strips spaces, dashes, dots and parentheses from an E.164
+// number such as "+1 (555) 010-0199" and checks its shape.
+func NormalizePhone(s string) (string, error) {
+	n := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')', '.':
+			return -1
+		}
+		return r
+	}, s)
+	if len(n) < 8 || len(n) > 16 || n[0] != '+' || n[1] == '0' {
+		return "", fmt.Errorf("%w: %q", ErrInvalidPhone, s)
+	}
+	for _, r := range n[1:] {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("%w: %q", ErrInvalidPhone, s)
+		}
+	}
+	return n, nil
+}
+
+// TwilioConfig configures the Twilio Messages API.
+type TwilioConfig struct {
+	AccountSID string `mapstructure:"account_sid"`
+	AuthToken  string `mapstructure:"auth_token"`
+	// MessagingServiceSID, when set, sends through a messaging service
+	// instead of the From number.
+	MessagingServiceSID string `mapstructure:"messaging_service_sid"`
+	// BaseURL defaults to https://api.twilio.com.
+	BaseURL string `mapstructure:"base_url"`
+	// Client defaults to a client with a 10s timeout.
+	Client *http.Client `mapstructure:"-"`
+}
+
+// TwilioProvider sends SMS through the Twilio Messages API.
+type TwilioProvider struct {
+	cfg TwilioConfig
+}
+
+// NewTwilioProvider creates a Twilio provider.
+func NewTwilioProvider(cfg TwilioConfig) *TwilioProvider {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.twilio.com"
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &TwilioProvider{cfg: cfg}
+}
+
+// SendSMS implements SMSProvider and returns the message SID.
+func (p *TwilioProvider) SendSMS(ctx context.Context, from, to, body string) (string, error) {
+	form := url.Values{"To": {to}, "Body": {body}}
+	if p.cfg.MessagingServiceSID != "" {
+		form.Set("MessagingServiceSid", p.cfg.MessagingServiceSID)
+	} else {
+		form.Set("From", from)
+	}
+	endpoint := strings.TrimRight(p.cfg.BaseURL, "/") +
+		"/2010-04-01/Accounts/" + url.PathEscape(p.cfg.AccountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", retry.Permanent(fmt.Errorf("notify: twilio request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.cfg.AccountSID, p.cfg.AuthToken)
+
+	var sid string
+	err = do(p.cfg.Client, req, "twilio", func(resp *http.Response) error {
+		var out struct {
+			SID string `json:"sid"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			return fmt.Errorf("notify: decode twilio response: %w", err)
+		}
+		sid = out.SID
+		return nil
+	})
+	return sid, err
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status is the delivery state of a notification.
+type Status string
+
+const (
+	// StatusPending means the notification is accepted and not sent yet.
+	StatusPending Status = "pending"
+	// StatusSent means the provider accepted the notification.
+	StatusSent Status = "sent"
+	// StatusFailed means the last attempt failed; it is retried while the
+	// job has attempts left.
+	StatusFailed Status = "failed"
+	// StatusRejected means the provider refused the notification
+	// permanently; it is not retried.
+	StatusRejected Status = "rejected"
+)
+
+// Delivery is the delivery status of one notification.
+type Delivery struct {
+	NotificationID string            `json:"notificationId"`
+	Channel        string            `json:"channel"`
+	To             string            `json:"to,omitempty"`
+	Template       string            `json:"template,omitempty"`
+	Status         Status            `json:"status"`
+	Attempts       int               `json:"attempts"`
+	ProviderID     string            `json:"providerId,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	UpdatedAt      time.Time         `json:"updatedAt"`
+	SentAt         *time.Time        `json:"sentAt,omitempty"`
+}
+
+// StatusQuery filters StatusStore.List.
+type StatusQuery struct {
+	Channel string
+	Status  Status
+	// Limit defaults to 100.
+	Limit int
+}
+
+func (q StatusQuery) match(d *Delivery) bool {
+	return (q.Channel == "" || d.Channel == q.Channel) && (q.Status == "" || d.Status == q.Status)
+}
+
+func (q StatusQuery) limit() int {
+	if q.Limit <= 0 {
+		return 100
+	}
+	return q.Limit
+}
+
+// StatusStore persists delivery statuses, one per notification.
+type StatusStore interface {
+	// Save creates or replaces the status of d.NotificationID.
+	Save(ctx context.Context, d *Delivery) error
+	// Get returns ErrNotFound for unknown notifications.
+	Get(ctx context.Context, notificationID string) (*Delivery, error)
+	// List returns matching statuses, newest first.
+	List(ctx context.Context, query StatusQuery) ([]*Delivery, error)
+}
+
+// MemoryStatusStore keeps the most recent statuses in memory.
+type MemoryStatusStore struct {
+	mu       sync.RWMutex
+	capacity int
+	order    []string
+	byID     map[string]*Delivery
+}
+
+// NewMemoryStatusStore creates a store holding at most capacity
+// notifications (default 1000); the oldest are dropped first.
+func NewMemoryStatusStore(capacity int) *MemoryStatusStore {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &MemoryStatusStore{capacity: capacity, byID: make(map[string]*Delivery)}
+}
+
+// Save implements StatusStore.
+func (s *MemoryStatusStore) Save(_ context.Context, d *Delivery) error {
+	cp := *d
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byID[d.NotificationID]; !ok {
+		if len(s.order) >= s.capacity {
+			delete(s.byID, s.order[0])
+			s.order = append(s.order[:0], s.order[1:]...)
+		}
+		s.order = append(s.order, d.NotificationID)
+	}
+	s.byID[d.NotificationID] = &cp
+	return nil
+}
+
+// Get implements StatusStore.
+func (s *MemoryStatusStore) Get(_ context.Context, notificationID string) (*Delivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, ok := s.byID[notificationID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cp := *d
+	return &cp, nil
+}
+
+// List implements StatusStore.
+func (s *MemoryStatusStore) List(_ context.Context, query StatusQuery) ([]*Delivery, error) {
+	limit := query.limit()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*Delivery
+	for i := len(s.order) - 1; i >= 0 && len(out) < limit; i-- {
+		if d := s.byID[s.order[i]]; query.match(d) {
+			cp := *d
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// TemplateOptions configures ParseTemplates.
+type TemplateOptions struct {
+	// Funcs are available to every template, next to the built-in "json".
+	Funcs map[string]any
+}
+
+// Content is a rendered template.
+type Content struct {
+	Subject string
+	Text    string
+	Payload json.RawMessage
+}
+
+// Templates renders notification content from a directory of templates:
+//
+//	partials/footer.txt        {{define "footer"}}...{{end}}, usable by every page
+//	order.shipped.txt          the text, optionally {{define "subject"}}...{{end}}
+//	order.shipped.json         a JSON payload, e.g. {"order": {{json .ID}}}
+//	order.shipped.slack.json   used instead of order.shipped.json on the "slack" channel
+//
+// A page needs a .txt or a .json file, or both. A file whose name ends in
+// ".<channel>" overrides the generic one on that channel, so one template
+// can carry an SMS text and a Slack message with blocks. Templates use
+// text/template; insert values into JSON with the "json" function, which
+// encodes and escapes them.
+type Templates struct {
+	text    map[string]*template.Template
+	payload map[string]*template.Template
+}
+
+// ParseTemplates parses every .txt and .json file in fsys.
+func ParseTemplates(fsys fs.FS, opts TemplateOptions) (*Templates, error) {
+	funcs := template.FuncMap{"json": jsonFunc}
+	for name, fn := range opts.Funcs {
+		funcs[name] = fn
+	}
+
+	type source struct{ name, ext, text string }
+	var partials, pages []source
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := path.Ext(p)
+		if ext != ".txt" && ext != ".json" {
+			return nil
+		}
+		text, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		s := source{name: strings.TrimSuffix(p, ext), ext: ext, text: string(text)}
+		if strings.HasPrefix(p, "partials/") {
+			partials = append(partials, s)
+		} else {
+			pages = append(pages, s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("notify: read templates: %w", err)
+	}
+
+	t := &Templates{text: map[string]*template.Template{}, payload: map[string]*template.Template{}}
+	for _, page := range pages {
+		tmpl, err := template.New(page.name).Funcs(funcs).Parse(page.text)
+		if err != nil {
+			return nil, fmt.Errorf("notify: parse template %s%s: %w", page.name, page.ext, err)
+		}
+		for _, p := range partials {
+			if _, err := tmpl.New(p.name).Parse(p.text); err != nil {
+				return nil, fmt.Errorf("notify: parse template %s%s for %s: %w", p.name, p.ext, page.name, err)
+			}
+		}
+		if page.ext == ".json" {
+			t.payload[page.name] = tmpl
+		} else {
+			t.text[page.name] = tmpl
+		}
+	}
+	return t, nil
+}
+
+func jsonFunc(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// Names returns the page names, channel overrides included, sorted.
+func (t *Templates) Names() []string {
+	seen := map[string]bool{}
+	for name := range t.text {
+		seen[name] = true
+	}
+	for name := range t.payload {
+		seen[name] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render executes the pages called name for channel with data.
+func (t *Templates) Render(name, channel string, data any) (*Content, error) {
+	textTmpl := lookup(t.text, name, channel)
+	payloadTmpl := lookup(t.payload, name, channel)
+	if textTmpl == nil && payloadTmpl == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	c := &Content{}
+	var b strings.Builder
+	if textTmpl != nil {
+		if err := textTmpl.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("notify: render %s.txt: %w", textTmpl.Name(), err)
+		}
+		c.Text = strings.TrimSpace(b.String())
+		if textTmpl.Lookup("subject") != nil {
+			b.Reset()
+			if err := textTmpl.ExecuteTemplate(&b, "subject", data); err != nil {
+				return nil, fmt.Errorf("notify: render %s.txt subject: %w", textTmpl.Name(), err)
+			}
+			c.Subject = strings.Join(strings.Fields(b.String()), " ")
+		}
+	}
+	if payloadTmpl != nil {
+		b.Reset()
+		if err := payloadTmpl.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("notify: render %s.json: %w", payloadTmpl.Name(), err)
+		}
+		if !json.Valid([]byte(b.String())) {
+			return nil, fmt.Errorf("notify: render %s.json: output is not valid JSON", payloadTmpl.Name())
+		}
+		c.Payload = json.RawMessage(b.String())
+	}
+	return c, nil
+}
+
+// lookup returns the channel override of name, else name itself.
+func lookup(set map[string]*template.Template, name, channel string) *template.Template {
+	if tmpl, ok := set[name+"."+channel]; ok && channel != "" {
+		return tmpl
+	}
+	return set[name]
+}
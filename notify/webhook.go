@@ -0,0 +1,214 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/leeforge/framework/retry"
+	"github.com/leeforge/framework/security"
+)
+
+// HeaderNotificationID carries Notification.ID on webhook requests, so
+// receivers can deduplicate retries.
+const HeaderNotificationID = "X-Notification-ID"
+
+// WebhookConfig configures a WebhookChannel.
+type WebhookConfig struct {
+	// Name is the channel name (default "webhook").
+	Name string
+	URL  string
+	// Signer signs every request; receivers verify it with
+	// security.SignatureMiddleware and the same key.
+	Signer *security.RequestSigner
+	// Headers are added to every request.
+	Headers map[string]string
+	// UserAgent defaults to "leeforge-notify/1".
+	UserAgent string
+	// Client defaults to a client with a 10s timeout.
+	Client *http.Client
+}
+
+// WebhookChannel POSTs notifications as JSON to a fixed URL, signed with
+// an HMAC request signature.
+type WebhookChannel struct {
+	cfg WebhookConfig
+}
+
+// NewWebhookChannel creates a webhook channel.
+func NewWebhookChannel(cfg WebhookConfig) *WebhookChannel {
+	if cfg.Name == "" {
+		cfg.Name = "webhook"
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = "leeforge-notify/1"
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &WebhookChannel{cfg: cfg}
+}
+
+// Name implements Channel.
+func (c *WebhookChannel) Name() string { return c.cfg.Name }
+
+// webhookBody is the JSON body of a webhook request.
+type webhookBody struct {
+	ID        string            `json:"id"`
+	Subject   string            `json:"subject,omitempty"`
+	Text      string            `json:"text,omitempty"`
+	Data      json.RawMessage   `json:"data,omitempty"`
+	Template  string            `json:"template,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// Send implements Channel. The payload is sent as the body's "data"; the
+// notification ID doubles as the provider ID.
+func (c *WebhookChannel) Send(ctx context.Context, n *Notification) (string, error) {
+	body, err := json.Marshal(webhookBody{
+		ID:        n.ID,
+		Subject:   n.Subject,
+		Text:      n.Text,
+		Data:      n.Payload,
+		Template:  n.Template,
+		Metadata:  n.Metadata,
+		CreatedAt: n.CreatedAt,
+	})
+	if err != nil {
+		return "", retry.Permanent(fmt.Errorf("notify: encode webhook body: %w", err))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", retry.Permanent(fmt.Errorf("notify: webhook request: %w", err))
+	}
+	if req.URL.Path == "" {
+		// The receiver sees "/", and the path is part of the signature.
+		req.URL.Path = "/"
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", c.cfg.UserAgent)
+	req.Header.Set(HeaderNotificationID, n.ID)
+	for k, v := range c.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if c.cfg.Signer != nil {
+		if err := c.cfg.Signer.Sign(req); err != nil {
+			return "", err
+		}
+	}
+	if err := do(c.cfg.Client, req, c.cfg.Name, nil); err != nil {
+		return "", err
+	}
+	return n.ID, nil
+}
+
+// SlackConfig configures a SlackChannel.
+type SlackConfig struct {
+	// Name is the channel name (default "slack").
+	Name string
+	// WebhookURL is the incoming webhook URL.
+	WebhookURL string
+	// Channel, Username and IconEmoji override the webhook's defaults
+	// where the workspace allows it. Notification.To overrides Channel.
+	Channel   string
+	Username  string
+	IconEmoji string
+	// Client defaults to a client with a 10s timeout.
+	Client *http.Client
+}
+
+// SlackChannel posts notifications to a Slack incoming webhook.
+type SlackChannel struct {
+	cfg SlackConfig
+}
+
+// NewSlackChannel creates a Slack channel.
+func NewSlackChannel(cfg SlackConfig) *SlackChannel {
+	if cfg.Name == "" {
+		cfg.Name = "slack"
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &SlackChannel{cfg: cfg}
+}
+
+// Name implements Channel.
+func (c *SlackChannel) Name() string { return c.cfg.Name }
+
+// Send implements Channel. A payload is posted as the Slack message, so it
+// can carry blocks and attachments; otherwise the message is the text,
+// preceded by the subject in bold. Slack returns no message ID.
+func (c *SlackChannel) Send(ctx context.Context, n *Notification) (string, error) {
+	msg := map[string]any{}
+	if len(n.Payload) > 0 {
+		if err := json.Unmarshal(n.Payload, &msg); err != nil {
+			return "", retry.Permanent(fmt.Errorf("notify: slack payload must be a JSON object: %w", err))
+		}
+	}
+	if _, ok := msg["text"]; !ok && n.Text != "" {
+		text := n.Text
+		if n.Subject != "" {
+			text = "*" + n.Subject + "*\n" + text
+		}
+		msg["text"] = text
+	}
+	setDefault(msg, "channel", firstNonEmpty(n.To, c.cfg.Channel))
+	setDefault(msg, "username", c.cfg.Username)
+	setDefault(msg, "icon_emoji", c.cfg.IconEmoji)
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return "", retry.Permanent(fmt.Errorf("notify: encode slack message: %w", err))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return "", retry.Permanent(fmt.Errorf("notify: slack request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return "", do(c.cfg.Client, req, c.cfg.Name, nil)
+}
+
+func setDefault(msg map[string]any, key, value string) {
+	if _, ok := msg[key]; !ok && value != "" {
+		msg[key] = value
+	}
+}
+
+// do sends req and calls ok, if set, with a 2xx response. Other 4xx
+// replies than 408 and 429 are permanent errors.
+func do(client *http.Client, req *http.Request, channel string, ok func(*http.Response) error) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify: %s request failed: %w", channel, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if ok != nil {
+			return ok(resp)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("notify: %s returned %s: %s", channel, resp.Status, bytes.TrimSpace(detail))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return retry.Permanent(err)
+	}
+	return err
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -23,6 +23,11 @@ func (h *MetaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.handler.ServeHTTP(w, r)
 }
 
+// Unwrap returns the wrapped handler.
+func (h *MetaHandler) Unwrap() http.Handler {
+	return h.handler
+}
+
 // Wrap attaches metadata to a handler.
 func Wrap(handler http.Handler, meta Meta) http.Handler {
 	if handler == nil {
@@ -43,6 +48,11 @@ func ExtractMeta(handler http.Handler) (Meta, bool) {
 			handler = chained.Endpoint
 			continue
 		}
+		// other metadata wrappers, e.g. openapi.Doc
+		if u, ok := handler.(interface{ Unwrap() http.Handler }); ok {
+			handler = u.Unwrap()
+			continue
+		}
 		break
 	}
 	return Meta{}, false
@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -42,6 +43,29 @@ type MiddlewareProvider interface {
 	RegisterMiddlewares(router chi.Router)
 }
 
+// HTTPPlugin -- contribute endpoints mounted by the runtime under the plugin's
+// base path ("/<name>" unless the plugin implements BasePathProvider).
+type HTTPPlugin interface {
+	Routes(r chi.Router)
+}
+
+// BasePathProvider -- override the mount prefix of an HTTPPlugin.
+// Returning "/" mounts the routes at the router root.
+type BasePathProvider interface {
+	BasePath() string
+}
+
+// RouteMiddlewareProvider -- middleware scoped to an HTTPPlugin's routes only.
+type RouteMiddlewareProvider interface {
+	RouteMiddlewares() []func(http.Handler) http.Handler
+}
+
+// MigrationPlugin -- expose versioned schema migrations (SQL files or ent).
+// The runtime applies them in dependency order before Install.
+type MigrationPlugin interface {
+	Migrations() []Migration
+}
+
 // ModelProvider -- declare Ent ORM models for auto-migration.
 type ModelProvider interface {
 	RegisterModels() []any
@@ -62,6 +86,15 @@ type Configurable interface {
 	PluginOptions() PluginOptions
 }
 
+// ConfigPlugin -- declare a typed configuration struct. The runtime binds the
+// app config section ConfigSection() (default "plugins.<name>" when empty) into
+// ConfigTarget(), applying `default` tags and checking `validate` tags before
+// any other lifecycle phase. ConfigTarget must return a pointer to a struct.
+type ConfigPlugin interface {
+	ConfigSection() string
+	ConfigTarget() any
+}
+
 // PluginOptions holds declarative metadata about a plugin.
 type PluginOptions struct {
 	Optional    bool   // If true, failure does not abort bootstrap.
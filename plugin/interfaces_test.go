@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
@@ -20,9 +21,17 @@ func (p *testFullPlugin) Uninstall(context.Context, *AppContext) error { return
 func (p *testFullPlugin) Disable(context.Context, *AppContext) error   { return nil }
 func (p *testFullPlugin) RegisterRoutes(chi.Router)                    {}
 func (p *testFullPlugin) RegisterMiddlewares(chi.Router)               {}
-func (p *testFullPlugin) RegisterModels() []any                        { return nil }
-func (p *testFullPlugin) SubscribeEvents(EventBus)                     {}
-func (p *testFullPlugin) HealthCheck(context.Context) error            { return nil }
+func (p *testFullPlugin) Routes(chi.Router)                            {}
+func (p *testFullPlugin) BasePath() string                             { return "/full" }
+func (p *testFullPlugin) RouteMiddlewares() []func(http.Handler) http.Handler {
+	return nil
+}
+func (p *testFullPlugin) Migrations() []Migration           { return nil }
+func (p *testFullPlugin) RegisterModels() []any             { return nil }
+func (p *testFullPlugin) SubscribeEvents(EventBus)          {}
+func (p *testFullPlugin) HealthCheck(context.Context) error { return nil }
+func (p *testFullPlugin) ConfigSection() string             { return "" }
+func (p *testFullPlugin) ConfigTarget() any                 { return &struct{}{} }
 func (p *testFullPlugin) PluginOptions() PluginOptions {
 	return PluginOptions{Optional: false, Description: "test"}
 }
@@ -34,10 +43,15 @@ var _ Uninstallable = (*testFullPlugin)(nil)
 var _ Disableable = (*testFullPlugin)(nil)
 var _ RouteProvider = (*testFullPlugin)(nil)
 var _ MiddlewareProvider = (*testFullPlugin)(nil)
+var _ HTTPPlugin = (*testFullPlugin)(nil)
+var _ BasePathProvider = (*testFullPlugin)(nil)
+var _ RouteMiddlewareProvider = (*testFullPlugin)(nil)
+var _ MigrationPlugin = (*testFullPlugin)(nil)
 var _ ModelProvider = (*testFullPlugin)(nil)
 var _ EventSubscriber = (*testFullPlugin)(nil)
 var _ HealthReporter = (*testFullPlugin)(nil)
 var _ Configurable = (*testFullPlugin)(nil)
+var _ ConfigPlugin = (*testFullPlugin)(nil)
 
 // testMinimalPlugin implements ONLY the core interface -- proves ISP works.
 type testMinimalPlugin struct{}
@@ -0,0 +1,120 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// MigrationExecutor is the subset of *sql.DB / *sql.Tx a migration may use.
+type MigrationExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Migration is a single versioned schema change owned by a plugin.
+// Versions are compared as strings, so use zero-padded or timestamp prefixes
+// (e.g. "0001_create_invoices", "20260101120000_add_index").
+type Migration struct {
+	Version     string
+	Description string
+	Up          func(ctx context.Context, exec MigrationExecutor) error
+	// Down reverts Up; nil means the migration cannot be rolled back.
+	Down func(ctx context.Context, exec MigrationExecutor) error
+	// UpSQL and DownSQL hold the scripts of SQL migrations so dry runs can
+	// print them. They are empty for Go migrations.
+	UpSQL   string
+	DownSQL string
+	// DisableTx runs Up outside a transaction (e.g. ent auto-migration,
+	// CREATE INDEX CONCURRENTLY). The version is recorded after Up succeeds.
+	DisableTx bool
+}
+
+// SQLMigration creates a migration that executes a raw SQL script.
+func SQLMigration(version, script string) Migration {
+	return Migration{
+		Version: version,
+		Up:      execScript(script),
+		UpSQL:   script,
+	}
+}
+
+// SQLMigrationWithDown creates a reversible SQL migration.
+func SQLMigrationWithDown(version, up, down string) Migration {
+	m := SQLMigration(version, up)
+	m.Down = execScript(down)
+	m.DownSQL = down
+	return m
+}
+
+func execScript(script string) func(ctx context.Context, exec MigrationExecutor) error {
+	return func(ctx context.Context, exec MigrationExecutor) error {
+		_, err := exec.ExecContext(ctx, script)
+		return err
+	}
+}
+
+// EntMigration wraps an ent schema migration (typically client.Schema.Create).
+// Ent manages its own connection, so the migration runs outside a transaction.
+func EntMigration(version string, migrate func(ctx context.Context) error) Migration {
+	return Migration{
+		Version:     version,
+		Description: "ent schema migration",
+		DisableTx:   true,
+		Up: func(ctx context.Context, _ MigrationExecutor) error {
+			return migrate(ctx)
+		},
+	}
+}
+
+// MigrationsFromFS loads "*.sql" files from dir as migrations, using the file
+// name without extension as the version. A ".up" suffix is stripped and a
+// matching "<version>.down.sql" becomes the Down script, so golang-migrate
+// style layouts work unchanged.
+func MigrationsFromFS(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations dir %q: %w", dir, err)
+	}
+
+	downs := make(map[string]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".down.sql") {
+			continue
+		}
+		script, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("read migration %q: %w", name, err)
+		}
+		downs[strings.TrimSuffix(name, ".down.sql")] = string(script)
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") || strings.HasSuffix(name, ".down.sql") {
+			continue
+		}
+		script, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("read migration %q: %w", name, err)
+		}
+		version := strings.TrimSuffix(strings.TrimSuffix(name, ".sql"), ".up")
+		m := SQLMigration(version, string(script))
+		if down, ok := downs[version]; ok {
+			m = SQLMigrationWithDown(version, string(script), down)
+		}
+		m.Description = name
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
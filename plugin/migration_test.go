@@ -0,0 +1,41 @@
+package plugin
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrationsFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_index.up.sql":   {Data: []byte("CREATE INDEX idx ON invoices (number);")},
+		"migrations/0002_add_index.down.sql": {Data: []byte("DROP INDEX idx;")},
+		"migrations/0001_create.sql":         {Data: []byte("CREATE TABLE invoices (id INT);")},
+		"migrations/README.md":               {Data: []byte("ignored")},
+	}
+
+	migrations, err := MigrationsFromFS(fsys, "migrations")
+	if err != nil {
+		t.Fatalf("MigrationsFromFS failed: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("len = %d, want 2", len(migrations))
+	}
+	if migrations[0].Version != "0001_create" || migrations[1].Version != "0002_add_index" {
+		t.Errorf("versions = %q, %q", migrations[0].Version, migrations[1].Version)
+	}
+	if migrations[0].Up == nil || migrations[0].DisableTx {
+		t.Error("SQL migrations should run inside a transaction")
+	}
+	if migrations[0].Down != nil {
+		t.Error("0001_create has no down script")
+	}
+	if migrations[1].Down == nil || migrations[1].DownSQL != "DROP INDEX idx;" {
+		t.Errorf("0002_add_index down = %q, want the .down.sql script", migrations[1].DownSQL)
+	}
+}
+
+func TestMigrationsFromFS_MissingDir(t *testing.T) {
+	if _, err := MigrationsFromFS(fstest.MapFS{}, "nope"); err == nil {
+		t.Fatal("expected error for missing directory")
+	}
+}
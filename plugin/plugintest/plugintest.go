@@ -0,0 +1,248 @@
+// Package plugintest boots a minimal host around selected plugins for
+// integration tests: an in-memory SQLite database, an in-process Redis, an
+// observed logger and a real HTTP server in front of the plugin routes.
+package plugintest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/config"
+	"github.com/leeforge/framework/plugin"
+	"github.com/leeforge/framework/runtime"
+	"github.com/leeforge/framework/runtime/migration"
+	fwtesting "github.com/leeforge/framework/testing"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	// Registers the pure-Go "sqlite" database/sql driver.
+	_ "modernc.org/sqlite"
+)
+
+// Option configures a test host.
+type Option func(*options)
+
+type options struct {
+	plugins     []plugin.Plugin
+	settings    runtime.SectionBinder
+	services    map[string]any
+	middlewares []func(http.Handler) http.Handler
+	noDB        bool
+	noRedis     bool
+	logLevel    zapcore.Level
+}
+
+// WithPlugins registers plugins with the host, in addition to any passed
+// earlier. Dependencies are resolved by the runtime as in production.
+func WithPlugins(plugins ...plugin.Plugin) Option {
+	return func(o *options) { o.plugins = append(o.plugins, plugins...) }
+}
+
+// WithConfig provides config sections to ConfigPlugin plugins. Pass a
+// *config.Config or a Settings map.
+func WithConfig(settings runtime.SectionBinder) Option {
+	return func(o *options) { o.settings = settings }
+}
+
+// WithService pre-registers a core service before bootstrap, e.g. a fake
+// mailer that a plugin resolves in Enable.
+func WithService(key string, svc any) Option {
+	return func(o *options) { o.services[key] = svc }
+}
+
+// WithMiddleware installs router middleware ahead of plugin routes, e.g. a
+// fake authentication middleware.
+func WithMiddleware(mw ...func(http.Handler) http.Handler) Option {
+	return func(o *options) { o.middlewares = append(o.middlewares, mw...) }
+}
+
+// WithoutDB leaves AppContext.DB nil and skips plugin migrations.
+func WithoutDB() Option {
+	return func(o *options) { o.noDB = true }
+}
+
+// WithoutRedis leaves AppContext.Redis nil.
+func WithoutRedis() Option {
+	return func(o *options) { o.noRedis = true }
+}
+
+// WithLogLevel sets the minimum level recorded in TestHost.Logs (default Debug).
+func WithLogLevel(level zapcore.Level) Option {
+	return func(o *options) { o.logLevel = level }
+}
+
+// TestHost is a bootstrapped runtime with its dependencies exposed for
+// assertions. It is shut down automatically when the test ends.
+type TestHost struct {
+	t testing.TB
+
+	Runtime *runtime.Runtime
+	App     *plugin.AppContext
+	Router  chi.Router
+	Client  *fwtesting.HTTPTestClient
+
+	// DB is the in-memory SQLite database behind AppContext.DB (dialect.SQLite);
+	// nil with WithoutDB.
+	DB *sql.DB
+	// Redis is the in-process Redis server behind AppContext.Redis, usable to
+	// inspect keys or fast-forward TTLs; nil with WithoutRedis.
+	Redis *miniredis.Miniredis
+	// Logs records everything the runtime and plugins logged.
+	Logs *observer.ObservedLogs
+}
+
+// Host boots a runtime with the given plugins and fails the test if
+// bootstrap fails.
+func Host(t testing.TB, opts ...Option) *TestHost {
+	t.Helper()
+
+	o := &options{services: make(map[string]any), logLevel: zapcore.DebugLevel}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	core, logs := observer.New(o.logLevel)
+	h := &TestHost{t: t, Router: chi.NewRouter(), Logs: logs}
+	h.Router.Use(o.middlewares...)
+
+	cfg := runtime.Config{
+		Router:   h.Router,
+		Logger:   zap.New(core),
+		Settings: o.settings,
+	}
+	if !o.noDB {
+		db, err := openSQLite()
+		if err != nil {
+			t.Fatalf("plugintest: open database: %v", err)
+		}
+		runner, err := migration.NewPluginRunner(db, dialect.SQLite)
+		if err != nil {
+			db.Close()
+			t.Fatalf("plugintest: migration runner: %v", err)
+		}
+		h.DB = db
+		cfg.DB = db
+		cfg.Migrations = runner
+	}
+	if !o.noRedis {
+		mr, err := miniredis.Run()
+		if err != nil {
+			t.Fatalf("plugintest: start redis: %v", err)
+		}
+		h.Redis = mr
+		cfg.Redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	}
+
+	h.Runtime = runtime.NewRuntime(cfg)
+	h.App = h.Runtime.AppContext()
+	t.Cleanup(h.shutdown)
+
+	for key, svc := range o.services {
+		if err := h.App.Services.Register(key, svc); err != nil {
+			t.Fatalf("plugintest: %v", err)
+		}
+	}
+	for _, p := range o.plugins {
+		if err := h.Runtime.Register(p); err != nil {
+			t.Fatalf("plugintest: %v", err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := h.Runtime.Bootstrap(ctx); err != nil {
+		t.Fatalf("plugintest: bootstrap: %v", err)
+	}
+
+	h.Client = fwtesting.NewHTTPTestClient(h.Router)
+	return h
+}
+
+// State returns the lifecycle state of a registered plugin.
+func (h *TestHost) State(name string) plugin.PluginState {
+	state, _ := h.Runtime.GetPluginState(name)
+	return state
+}
+
+// Publish sends an event through the host's event bus.
+func (h *TestHost) Publish(ctx context.Context, name string, data any) error {
+	return h.Runtime.Publish(ctx, plugin.Event{Name: name, Data: data, Source: "plugintest", Timestamp: time.Now()})
+}
+
+// shutdown disables plugins and releases the database, Redis and server.
+func (h *TestHost) shutdown() {
+	if h.Client != nil {
+		h.Client.Close()
+	}
+	if err := h.Runtime.Shutdown(context.Background()); err != nil {
+		h.t.Errorf("plugintest: shutdown: %v", err)
+	}
+	if h.Redis != nil {
+		h.Redis.Close()
+	}
+}
+
+// Service resolves a service registered by a plugin (or WithService), failing
+// the test if it is missing or has another type.
+func Service[T any](h *TestHost, key string) T {
+	h.t.Helper()
+	svc, err := plugin.Resolve[T](h.App.Services, key)
+	if err != nil {
+		h.t.Fatalf("plugintest: %v", err)
+	}
+	return svc
+}
+
+// Settings is an in-memory SectionBinder for WithConfig. Keys are config
+// paths; nested maps are walked for dotted keys, so both
+// Settings{"plugins.billing": {...}} and Settings{"plugins": {"billing": {...}}}
+// bind the "plugins.billing" section.
+type Settings map[string]any
+
+// BindSection implements runtime.SectionBinder using the same decoding,
+// defaults and validation as *config.Config.
+func (s Settings) BindSection(key string, target any) error {
+	return config.DecodeSection(key, s.lookup(key), target)
+}
+
+func (s Settings) lookup(key string) any {
+	if v, ok := s[key]; ok {
+		return v
+	}
+	var current any = map[string]any(s)
+	for _, part := range strings.Split(key, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		if current, ok = m[part]; !ok {
+			return nil
+		}
+	}
+	return current
+}
+
+// openSQLite opens a private in-memory database. A shared-cache URI with a
+// unique name lets every pooled connection see the same data.
+func openSQLite() (*sql.DB, error) {
+	dsn := fmt.Sprintf("file:plugintest-%s?mode=memory&cache=shared&_pragma=foreign_keys(1)", uuid.NewString())
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
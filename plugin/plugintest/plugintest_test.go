@@ -0,0 +1,149 @@
+package plugintest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/leeforge/framework/plugin"
+)
+
+type notesConfig struct {
+	Greeting string        `mapstructure:"greeting" validate:"required"`
+	TTL      time.Duration `mapstructure:"ttl" default:"1m"`
+}
+
+// notesPlugin exercises config binding, migrations, services, Redis and routes.
+type notesPlugin struct {
+	cfg notesConfig
+	app *plugin.AppContext
+}
+
+func (p *notesPlugin) Name() string           { return "notes" }
+func (p *notesPlugin) Version() string        { return "1.0.0" }
+func (p *notesPlugin) Dependencies() []string { return nil }
+func (p *notesPlugin) ConfigSection() string  { return "" }
+func (p *notesPlugin) ConfigTarget() any      { return &p.cfg }
+
+func (p *notesPlugin) Migrations() []plugin.Migration {
+	return []plugin.Migration{
+		plugin.SQLMigration("0001_create_notes", "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT NOT NULL)"),
+	}
+}
+
+func (p *notesPlugin) Enable(ctx context.Context, app *plugin.AppContext) error {
+	p.app = app
+	return app.Services.Register("notes.service", p)
+}
+
+func (p *notesPlugin) Routes(r chi.Router) {
+	r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		db := p.app.DB.(*sql.DB)
+		if _, err := db.ExecContext(r.Context(), "INSERT INTO notes (body) VALUES (?)", in.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	r.Get("/greeting", func(w http.ResponseWriter, r *http.Request) {
+		p.app.Redis.Set(r.Context(), "notes:last", r.Header.Get("X-User"), p.cfg.TTL)
+		w.Write([]byte(p.cfg.Greeting))
+	})
+}
+
+// auditPlugin depends on notes and counts published events.
+type auditPlugin struct {
+	events chan string
+}
+
+func (p *auditPlugin) Name() string           { return "audit" }
+func (p *auditPlugin) Version() string        { return "1.0.0" }
+func (p *auditPlugin) Dependencies() []string { return []string{"notes"} }
+func (p *auditPlugin) Enable(ctx context.Context, app *plugin.AppContext) error {
+	_, err := plugin.Resolve[*notesPlugin](app.Services, "notes.service")
+	return err
+}
+func (p *auditPlugin) SubscribeEvents(bus plugin.EventBus) {
+	bus.Subscribe("note.created", func(ctx context.Context, e plugin.Event) error {
+		p.events <- e.Data.(string)
+		return nil
+	})
+}
+
+func TestHostBootsPlugins(t *testing.T) {
+	notes := &notesPlugin{}
+	audit := &auditPlugin{events: make(chan string, 1)}
+	h := Host(t,
+		WithPlugins(audit, notes),
+		WithConfig(Settings{"plugins": map[string]any{"notes": map[string]any{"greeting": "hello"}}}),
+		WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.Header.Set("X-User", "tester")
+				next.ServeHTTP(w, r)
+			})
+		}),
+	)
+
+	if h.State("notes") != plugin.StateEnabled || h.State("audit") != plugin.StateEnabled {
+		t.Fatalf("states: notes=%s audit=%s", h.State("notes"), h.State("audit"))
+	}
+	if Service[*notesPlugin](h, "notes.service") != notes {
+		t.Error("Service returned a different instance")
+	}
+	if notes.cfg.TTL != time.Minute {
+		t.Errorf("config defaults not applied: %+v", notes.cfg)
+	}
+
+	resp, body, err := h.Client.Get("/notes/greeting", nil)
+	if err != nil || resp.StatusCode != http.StatusOK || body != "hello" {
+		t.Fatalf("GET /notes/greeting = %v %q %v", resp, body, err)
+	}
+	if got, _ := h.Redis.Get("notes:last"); got != "tester" {
+		t.Errorf("redis value = %q, want middleware-provided user", got)
+	}
+
+	resp, _, err = h.Client.Post("/notes/", map[string]string{"body": "first"}, nil)
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /notes/ = %v %v", resp, err)
+	}
+	var count int
+	if err := h.DB.QueryRow("SELECT COUNT(*) FROM notes").Scan(&count); err != nil || count != 1 {
+		t.Errorf("notes rows = %d, %v", count, err)
+	}
+
+	if err := h.Publish(context.Background(), "note.created", "first"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-audit.events:
+		if got != "first" {
+			t.Errorf("event data = %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("event not delivered")
+	}
+	if h.Logs.FilterMessage("bootstrap completed").Len() != 1 {
+		t.Error("runtime logs not captured")
+	}
+}
+
+func TestSettingsLookup(t *testing.T) {
+	var cfg notesConfig
+	if err := (Settings{"plugins.notes": map[string]any{"greeting": "hi"}}).BindSection("plugins.notes", &cfg); err != nil || cfg.Greeting != "hi" {
+		t.Fatalf("dotted key: %+v %v", cfg, err)
+	}
+	if err := (Settings{}).BindSection("plugins.notes", &notesConfig{}); err == nil {
+		t.Error("missing required field should fail validation")
+	}
+}
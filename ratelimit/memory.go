@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps limiter state in process, for tests and single-instance
+// deployments. Expired keys are dropped when touched and by an optional
+// background janitor.
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]*memoryEntry
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type memoryEntry struct {
+	bucket    bucket
+	window    window
+	expiresAt time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates a MemoryStore. A positive janitorInterval starts a
+// goroutine deleting expired keys at that interval; call Close to stop it.
+func NewMemoryStore(janitorInterval time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		items: make(map[string]*memoryEntry),
+		stop:  make(chan struct{}),
+	}
+	if janitorInterval > 0 {
+		go s.janitor(janitorInterval)
+	}
+	return s
+}
+
+// TakeToken implements Store.
+func (s *MemoryStore) TakeToken(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entryLocked(key, now)
+	res, ttl := e.bucket.take(limit, now)
+	e.expiresAt = now.Add(ttl)
+	return res, nil
+}
+
+// Hit implements Store.
+func (s *MemoryStore) Hit(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entryLocked(key, now)
+	res, ttl := e.window.hit(limit, now)
+	e.expiresAt = now.Add(ttl)
+	return res, nil
+}
+
+// Reset implements Store.
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+	return nil
+}
+
+// Len returns the number of keys held, including expired ones not yet deleted.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// DeleteExpired deletes keys whose state has expired and returns how many.
+func (s *MemoryStore) DeleteExpired() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	removed := 0
+	for key, e := range s.items {
+		if !now.Before(e.expiresAt) {
+			delete(s.items, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Close stops the janitor.
+func (s *MemoryStore) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+func (s *MemoryStore) entryLocked(key string, now time.Time) *memoryEntry {
+	e, ok := s.items[key]
+	if !ok || !now.Before(e.expiresAt) {
+		e = &memoryEntry{}
+		s.items[key] = e
+	}
+	return e
+}
+
+func (s *MemoryStore) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.DeleteExpired()
+		case <-s.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/leeforge/framework/auth"
+	apperrors "github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/request"
+)
+
+// Response headers set by Middleware, following the IETF RateLimit header
+// fields draft; Reset and Retry-After are in whole seconds.
+const (
+	HeaderLimit      = "RateLimit-Limit"
+	HeaderRemaining  = "RateLimit-Remaining"
+	HeaderReset      = "RateLimit-Reset"
+	HeaderPolicy     = "RateLimit-Policy"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// KeyFunc returns the key a request is limited by; "" leaves it unlimited.
+type KeyFunc func(r *http.Request) string
+
+// ByIP keys requests by client IP as resolved by the request package.
+func ByIP() KeyFunc {
+	return func(r *http.Request) string {
+		if ip := request.GetClientIP(r); ip != "" {
+			return "ip:" + ip
+		}
+		return ""
+	}
+}
+
+// ByAPIKey keys requests by the API key the auth middleware validated and
+// put in the context, so unauthenticated callers cannot mint keys by varying
+// a header; requests without one fall through to the next KeyFunc of
+// FirstOf. The key is hashed so secrets never reach the store.
+func ByAPIKey() KeyFunc {
+	return func(r *http.Request) string {
+		_, info, _ := auth.GetUserInfoFromContext(r.Context())
+		if info == nil || info.Key == "" {
+			return ""
+		}
+		sum := sha256.Sum256([]byte(info.Key))
+		return "key:" + hex.EncodeToString(sum[:16])
+	}
+}
+
+// ByUser keys requests by the user the auth middleware put in the context.
+func ByUser() KeyFunc {
+	return func(r *http.Request) string {
+		if userID, _, _ := auth.GetUserInfoFromContext(r.Context()); userID != "" {
+			return "user:" + userID
+		}
+		return ""
+	}
+}
+
+// FirstOf uses the first non-empty key, e.g. the user, else the IP.
+func FirstOf(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		for _, key := range keys {
+			if k := key(r); k != "" {
+				return k
+			}
+		}
+		return ""
+	}
+}
+
+// LimitFunc overrides the limiter's default limit for a request; ok false
+// keeps the default.
+type LimitFunc func(r *http.Request) (limit Limit, ok bool)
+
+// APIKeyLimit applies the per-minute quota of the authenticated API key
+// (auth.APIKeyInfo.RateLimit.Minute and Burst) when it has one.
+func APIKeyLimit() LimitFunc {
+	return func(r *http.Request) (Limit, bool) {
+		_, info, _ := auth.GetUserInfoFromContext(r.Context())
+		if info == nil || info.RateLimit.Minute <= 0 {
+			return Limit{}, false
+		}
+		return Limit{Rate: info.RateLimit.Minute, Period: time.Minute, Burst: info.RateLimit.Burst}, true
+	}
+}
+
+// MiddlewareOption configures Middleware.
+type MiddlewareOption func(*middlewareConfig)
+
+type middlewareConfig struct {
+	limit      LimitFunc
+	onError    func(r *http.Request, err error)
+	failClosed bool
+}
+
+// WithLimitFunc picks the limit per request.
+func WithLimitFunc(fn LimitFunc) MiddlewareOption {
+	return func(c *middlewareConfig) { c.limit = fn }
+}
+
+// WithErrorHandler is called when the store fails, e.g. to log the error.
+func WithErrorHandler(fn func(r *http.Request, err error)) MiddlewareOption {
+	return func(c *middlewareConfig) { c.onError = fn }
+}
+
+// FailClosed rejects requests with 503 when the store fails; by default they
+// are let through so a store outage does not take the API down.
+func FailClosed() MiddlewareOption {
+	return func(c *middlewareConfig) { c.failClosed = true }
+}
+
+// Middleware limits requests by key, sets the RateLimit-* headers on every
+// limited response and rejects requests over the limit with 429, Retry-After
+// and the standard error envelope.
+func (l *Limiter) Middleware(key KeyFunc, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	cfg := &middlewareConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limit := l.limit
+			if cfg.limit != nil {
+				if override, ok := cfg.limit(r); ok {
+					limit = override
+				}
+			}
+
+			res, err := l.AllowLimit(r.Context(), k, limit)
+			if err != nil {
+				if cfg.onError != nil {
+					cfg.onError(r, err)
+				}
+				if cfg.failClosed {
+					response.WriteError(w, r, apperrors.NewExternal("Rate limiter unavailable").WithHTTPStatus(http.StatusServiceUnavailable).WithInnerError(err))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			writeHeaders(w.Header(), l.algorithm, limit, res)
+			if !res.Allowed {
+				w.Header().Set(HeaderRetryAfter, strconv.FormatInt(seconds(res.RetryAfter), 10))
+				response.WriteError(w, r, apperrors.NewRateLimit("Rate limit exceeded"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeHeaders(h http.Header, algorithm Algorithm, limit Limit, res Result) {
+	h.Set(HeaderLimit, strconv.Itoa(res.Limit))
+	h.Set(HeaderRemaining, strconv.Itoa(res.Remaining))
+	h.Set(HeaderReset, strconv.FormatInt(seconds(res.ResetAfter), 10))
+	policy := strconv.Itoa(limit.Rate) + ";w=" + strconv.FormatInt(seconds(limit.Period), 10)
+	if algorithm == TokenBucket {
+		policy += ";burst=" + strconv.Itoa(limit.capacity())
+	}
+	h.Set(HeaderPolicy, policy)
+}
+
+// seconds rounds d up to whole seconds.
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,237 @@
+// Package ratelimit limits how often a key (an IP, API key or user) may act,
+// with token-bucket and sliding-window algorithms over an in-memory or Redis
+// store. State is kept per key with a TTL, so idle keys cost nothing.
+//
+//	limiter := ratelimit.New(ratelimit.NewRedisStore(client), ratelimit.PerMinute(100))
+//	r.Use(limiter.Middleware(ratelimit.FirstOf(ratelimit.ByAPIKey(), ratelimit.ByIP())))
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrInvalidLimit is returned for limits without a positive rate or with a
+// period under a millisecond.
+var ErrInvalidLimit = errors.New("ratelimit: invalid limit")
+
+// Algorithm selects how requests are counted.
+type Algorithm string
+
+const (
+	// TokenBucket refills Rate tokens per Period up to Burst and spends one
+	// per request, allowing short bursts above the average rate.
+	TokenBucket Algorithm = "token_bucket"
+	// SlidingWindow allows Rate requests in any Period, estimated from the
+	// counts of the current and previous fixed windows.
+	SlidingWindow Algorithm = "sliding_window"
+)
+
+// Limit is a request quota.
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	// Burst is the token bucket capacity; zero means Rate. Sliding windows
+	// ignore it.
+	Burst int
+}
+
+// PerSecond returns a limit of n requests per second.
+func PerSecond(n int) Limit { return Limit{Rate: n, Period: time.Second} }
+
+// PerMinute returns a limit of n requests per minute.
+func PerMinute(n int) Limit { return Limit{Rate: n, Period: time.Minute} }
+
+// PerHour returns a limit of n requests per hour.
+func PerHour(n int) Limit { return Limit{Rate: n, Period: time.Hour} }
+
+func (l Limit) capacity() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Rate
+}
+
+func (l Limit) validate() error {
+	if l.Rate <= 0 || l.Period < time.Millisecond {
+		return fmt.Errorf("%w: %d per %s", ErrInvalidLimit, l.Rate, l.Period)
+	}
+	return nil
+}
+
+// Result is the outcome of one request against a limit.
+type Result struct {
+	Allowed bool
+	// Limit is the quota the request counted against: the bucket capacity
+	// or the window rate.
+	Limit     int
+	Remaining int
+	// RetryAfter is how long a denied request should wait; zero when allowed.
+	RetryAfter time.Duration
+	// ResetAfter is when the quota is fully available again (token bucket)
+	// or the current window ends (sliding window).
+	ResetAfter time.Duration
+}
+
+// Store keeps limiter state. Each call updates the state at key atomically
+// and lets it expire once it no longer affects future results.
+type Store interface {
+	TakeToken(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+	Hit(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// Limiter applies a default limit with one algorithm over a store.
+type Limiter struct {
+	store     Store
+	limit     Limit
+	algorithm Algorithm
+	prefix    string
+	now       func() time.Time
+}
+
+// Option configures a Limiter.
+type Option func(*Limiter)
+
+// WithAlgorithm selects the algorithm; the default is TokenBucket.
+func WithAlgorithm(a Algorithm) Option {
+	return func(l *Limiter) { l.algorithm = a }
+}
+
+// WithPrefix sets the prefix of store keys (default "ratelimit:"), so
+// several limiters can share a store.
+func WithPrefix(prefix string) Option {
+	return func(l *Limiter) { l.prefix = prefix }
+}
+
+// New creates a Limiter enforcing limit.
+func New(store Store, limit Limit, opts ...Option) *Limiter {
+	l := &Limiter{
+		store:     store,
+		limit:     limit,
+		algorithm: TokenBucket,
+		prefix:    "ratelimit:",
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Limit returns the default limit.
+func (l *Limiter) Limit() Limit { return l.limit }
+
+// Allow counts one request for key against the default limit.
+func (l *Limiter) Allow(ctx context.Context, key string) (Result, error) {
+	return l.AllowLimit(ctx, key, l.limit)
+}
+
+// AllowLimit counts one request for key against limit, e.g. a per-plan quota.
+func (l *Limiter) AllowLimit(ctx context.Context, key string, limit Limit) (Result, error) {
+	if err := limit.validate(); err != nil {
+		return Result{}, err
+	}
+	switch l.algorithm {
+	case TokenBucket:
+		return l.store.TakeToken(ctx, l.storeKey(key), limit, l.now())
+	case SlidingWindow:
+		return l.store.Hit(ctx, l.storeKey(key), limit, l.now())
+	default:
+		return Result{}, fmt.Errorf("ratelimit: unknown algorithm %q", l.algorithm)
+	}
+}
+
+// Reset forgets the state of key, restoring its full quota.
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.store.Reset(ctx, l.storeKey(key))
+}
+
+func (l *Limiter) storeKey(key string) string {
+	return l.prefix + string(l.algorithm) + ":" + key
+}
+
+// bucket is token bucket state.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills b up to now and spends a token if one is available. It
+// returns the result and how long the state matters: after ttl the bucket
+// is full again and equals a missing one.
+func (b *bucket) take(limit Limit, now time.Time) (Result, time.Duration) {
+	capacity := float64(limit.capacity())
+	perNano := float64(limit.Rate) / float64(limit.Period)
+	if b.last.IsZero() {
+		b.tokens = capacity
+	} else if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+float64(elapsed)*perNano)
+	}
+	b.last = now
+
+	res := Result{Limit: limit.capacity()}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = ceilDuration((1 - b.tokens) / perNano)
+	}
+	res.Remaining = int(b.tokens)
+	res.ResetAfter = ceilDuration((capacity - b.tokens) / perNano)
+	return res, max(res.ResetAfter, time.Millisecond)
+}
+
+// window is sliding window state: request counts of the fixed window with
+// the given index and of the one before it.
+type window struct {
+	index     int64
+	cur, prev int
+}
+
+// hit counts a request at now if the weighted count leaves room for it. It
+// returns the result and how long the state matters: until the window after
+// the current one ends.
+func (w *window) hit(limit Limit, now time.Time) (Result, time.Duration) {
+	period := int64(limit.Period)
+	index := now.UnixNano() / period
+	switch w.index {
+	case index:
+	case index - 1:
+		w.prev, w.cur = w.cur, 0
+	default:
+		w.prev, w.cur = 0, 0
+	}
+	w.index = index
+
+	elapsed := now.UnixNano() - index*period
+	windowEnd := time.Duration(period - elapsed)
+	count := float64(w.prev)*(1-float64(elapsed)/float64(period)) + float64(w.cur)
+	res := Result{Limit: limit.Rate, ResetAfter: windowEnd}
+	if count+1 > float64(limit.Rate) {
+		res.RetryAfter = retryAfter(limit, w.cur, w.prev, elapsed)
+		return res, windowEnd + limit.Period
+	}
+	w.cur++
+	res.Allowed = true
+	res.Remaining = max(0, int(float64(limit.Rate)-count-1))
+	return res, windowEnd + limit.Period
+}
+
+// retryAfter solves prev*(1-e/period) + cur + 1 <= rate for the elapsed
+// time e at which a denied request fits: later in this window while the
+// current count alone fits, else in the next window, where cur becomes prev.
+func retryAfter(limit Limit, cur, prev int, elapsed int64) time.Duration {
+	period := float64(limit.Period)
+	if cur+1 <= limit.Rate {
+		return ceilDuration(period*(1-float64(limit.Rate-cur-1)/float64(prev)) - float64(elapsed))
+	}
+	return time.Duration(int64(limit.Period)-elapsed) + ceilDuration(period*(1-float64(limit.Rate-1)/float64(cur)))
+}
+
+func ceilDuration(ns float64) time.Duration {
+	return time.Duration(math.Ceil(ns))
+}
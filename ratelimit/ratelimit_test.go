@@ -0,0 +1,226 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/leeforge/framework/auth"
+)
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newClock() *clock {
+	return &clock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// stores 返回待测的存储实现，两者必须给出相同结果
+func stores(t *testing.T) map[string]Store {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	mem := NewMemoryStore(0)
+	t.Cleanup(mem.Close)
+	return map[string]Store{"memory": mem, "redis": NewRedisStore(client)}
+}
+
+func allow(t *testing.T, l *Limiter, key string) Result {
+	t.Helper()
+	res, err := l.Allow(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestTokenBucket(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			c := newClock()
+			l := New(store, Limit{Rate: 2, Period: time.Second, Burst: 4})
+			l.now = c.now
+
+			for i := 3; i >= 0; i-- {
+				res := allow(t, l, "a")
+				if !res.Allowed || res.Remaining != i || res.Limit != 4 {
+					t.Fatalf("burst request: %+v, want allowed with %d remaining", res, i)
+				}
+			}
+			res := allow(t, l, "a")
+			if res.Allowed || res.RetryAfter != 500*time.Millisecond || res.ResetAfter != 2*time.Second {
+				t.Fatalf("over burst: %+v", res)
+			}
+			if other := allow(t, l, "b"); !other.Allowed {
+				t.Fatal("keys must not share a bucket")
+			}
+
+			c.advance(500 * time.Millisecond)
+			if res := allow(t, l, "a"); !res.Allowed || res.Remaining != 0 {
+				t.Fatalf("after refill: %+v", res)
+			}
+			c.advance(time.Hour)
+			if res := allow(t, l, "a"); res.Remaining != 3 {
+				t.Fatalf("refill must cap at burst: %+v", res)
+			}
+		})
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			c := newClock()
+			l := New(store, PerMinute(4), WithAlgorithm(SlidingWindow))
+			l.now = c.now
+
+			c.advance(30 * time.Second)
+			for i := 0; i < 4; i++ {
+				if res := allow(t, l, "a"); !res.Allowed || res.Remaining != 3-i {
+					t.Fatalf("request %d: %+v", i, res)
+				}
+			}
+			res := allow(t, l, "a")
+			// 当前窗口已满，下一窗口中 4*(1-e/60s)+1 <= 4 需 e >= 15s
+			if res.Allowed || res.ResetAfter != 30*time.Second || res.RetryAfter != 45*time.Second {
+				t.Fatalf("over limit: %+v", res)
+			}
+
+			// 下一窗口开始 15s 后，上一窗口权重降到 3/4
+			c.advance(45 * time.Second)
+			if res := allow(t, l, "a"); !res.Allowed || res.Remaining != 0 {
+				t.Fatalf("after slide: %+v", res)
+			}
+			res = allow(t, l, "a")
+			// 4*(1-e/60s)+1+1 <= 4 需 e >= 30s，即再等 15s
+			if res.Allowed || res.RetryAfter != 15*time.Second {
+				t.Fatalf("second request after slide: %+v", res)
+			}
+
+			c.advance(2 * time.Minute)
+			if res := allow(t, l, "a"); !res.Allowed || res.Remaining != 3 {
+				t.Fatalf("after idle windows: %+v", res)
+			}
+		})
+	}
+}
+
+func TestResetAndInvalidLimit(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			l := New(store, PerMinute(1))
+			allow(t, l, "a")
+			if allow(t, l, "a").Allowed {
+				t.Fatal("second request should be limited")
+			}
+			if err := l.Reset(ctx, "a"); err != nil {
+				t.Fatal(err)
+			}
+			if !allow(t, l, "a").Allowed {
+				t.Fatal("Reset should restore the quota")
+			}
+			if _, err := l.AllowLimit(ctx, "a", Limit{Rate: 1}); !errors.Is(err, ErrInvalidLimit) {
+				t.Fatalf("zero period: err = %v, want ErrInvalidLimit", err)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreExpiresIdleKeys(t *testing.T) {
+	store := NewMemoryStore(0)
+	defer store.Close()
+	l := New(store, Limit{Rate: 100, Period: 10 * time.Millisecond}, WithAlgorithm(SlidingWindow))
+	for _, key := range []string{"a", "b", "c"} {
+		allow(t, l, key)
+	}
+	if store.Len() != 3 {
+		t.Fatalf("Len = %d, want 3", store.Len())
+	}
+	time.Sleep(30 * time.Millisecond)
+	if n := store.DeleteExpired(); n != 3 || store.Len() != 0 {
+		t.Errorf("DeleteExpired = %d, Len = %d", n, store.Len())
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	l := New(NewMemoryStore(0), Limit{Rate: 1, Period: time.Minute})
+	handler := l.Middleware(FirstOf(ByAPIKey(), ByIP()), WithLimitFunc(APIKeyLimit()))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+	anon := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		return r
+	}
+
+	rec := serve(anon())
+	if rec.Code != http.StatusNoContent || rec.Header().Get(HeaderLimit) != "1" || rec.Header().Get(HeaderRemaining) != "0" ||
+		rec.Header().Get(HeaderReset) != "60" || rec.Header().Get(HeaderPolicy) != "1;w=60;burst=1" {
+		t.Fatalf("first request: %d %v", rec.Code, rec.Header())
+	}
+	rec = serve(anon())
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get(HeaderRetryAfter) != "60" {
+		t.Fatalf("second request: %d %v", rec.Code, rec.Header())
+	}
+
+	// API key 请求独立计数，并使用 key 自带的配额
+	keyed := func() *http.Request {
+		r := anon()
+		info := &auth.APIKeyInfo{Key: "key-1", RateLimit: auth.RateLimitConfig{Minute: 3}}
+		return r.WithContext(context.WithValue(r.Context(), "api_key_info", info))
+	}
+	for i := 0; i < 3; i++ {
+		if rec := serve(keyed()); rec.Code != http.StatusNoContent {
+			t.Fatalf("keyed request %d: %d", i, rec.Code)
+		}
+	}
+	if rec := serve(keyed()); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("keyed request over quota: %d", rec.Code)
+	}
+
+	// 未经验证的 API Key 请求头不产生新 key，仍按 IP 计数
+	forged := anon()
+	forged.Header.Set("X-API-Key", "random-1")
+	if rec := serve(forged); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("unvalidated key header: %d", rec.Code)
+	}
+}
+
+type failingStore struct{ MemoryStore }
+
+func (*failingStore) TakeToken(context.Context, string, Limit, time.Time) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func TestMiddlewareStoreFailure(t *testing.T) {
+	l := New(&failingStore{}, PerMinute(1))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	var logged error
+
+	rec := httptest.NewRecorder()
+	l.Middleware(ByIP(), WithErrorHandler(func(_ *http.Request, err error) { logged = err }))(next).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || logged == nil {
+		t.Errorf("fail open: status %d, logged %v", rec.Code, logged)
+	}
+
+	rec = httptest.NewRecorder()
+	l.Middleware(ByIP(), FailClosed())(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("fail closed: status %d", rec.Code)
+	}
+}
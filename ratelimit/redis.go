@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+)
+
+// RedisStore keeps limiter state in Redis hashes updated by Lua scripts, so
+// every instance shares one quota per key. Times are passed in by the caller
+// at millisecond precision; keep instance clocks in sync.
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore creates a RedisStore. Key prefixes are set on the Limiter.
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Both scripts return {allowed, remaining, retry_after_ms, reset_after_ms}
+// and mirror bucket.take and window.hit.
+var (
+	tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local per_ms = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil or last == nil then
+	tokens = capacity
+elseif now > last then
+	tokens = math.min(capacity, tokens + (now - last) * per_ms)
+end
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / per_ms)
+end
+local reset = math.ceil((capacity - tokens) / per_ms)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.max(reset, 1))
+return {allowed, math.floor(tokens), retry, reset}
+`)
+	slidingWindowScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local index = math.floor(now / period)
+local state = redis.call('HMGET', KEYS[1], 'index', 'cur', 'prev')
+local cur = tonumber(state[2]) or 0
+local prev = tonumber(state[3]) or 0
+local stored = tonumber(state[1])
+if stored == index - 1 then
+	prev = cur
+	cur = 0
+elseif stored ~= index then
+	prev = 0
+	cur = 0
+end
+local elapsed = now - index * period
+local window_end = period - elapsed
+local count = prev * (1 - elapsed / period) + cur
+local allowed = 0
+local remaining = 0
+local retry = 0
+if count + 1 > rate then
+	if cur + 1 <= rate then
+		retry = math.ceil(period * (1 - (rate - cur - 1) / prev) - elapsed)
+	else
+		retry = window_end + math.ceil(period * (1 - (rate - 1) / cur))
+	end
+else
+	cur = cur + 1
+	allowed = 1
+	remaining = math.max(0, math.floor(rate - count - 1))
+end
+redis.call('HSET', KEYS[1], 'index', tostring(index), 'cur', tostring(cur), 'prev', tostring(prev))
+redis.call('PEXPIRE', KEYS[1], window_end + period)
+return {allowed, remaining, retry, window_end}
+`)
+)
+
+// TakeToken implements Store.
+func (s *RedisStore) TakeToken(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	perMs := float64(limit.Rate) / float64(limit.Period.Milliseconds())
+	vals, err := tokenBucketScript.Run(ctx, s.client, []string{key}, limit.capacity(), perMs, now.UnixMilli()).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: take token %s: %w", key, err)
+	}
+	return scriptResult(limit.capacity(), vals), nil
+}
+
+// Hit implements Store.
+func (s *RedisStore) Hit(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	vals, err := slidingWindowScript.Run(ctx, s.client, []string{key}, limit.Rate, limit.Period.Milliseconds(), now.UnixMilli()).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: hit %s: %w", key, err)
+	}
+	return scriptResult(limit.Rate, vals), nil
+}
+
+// Reset implements Store.
+func (s *RedisStore) Reset(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("ratelimit: reset %s: %w", key, err)
+	}
+	return nil
+}
+
+func scriptResult(limit int, vals []int64) Result {
+	return Result{
+		Allowed:    vals[0] == 1,
+		Limit:      limit,
+		Remaining:  int(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+		ResetAfter: time.Duration(vals[3]) * time.Millisecond,
+	}
+}
@@ -0,0 +1,344 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"entgo.io/ent/dialect/sql"
+	"github.com/leeforge/framework/cache"
+	"github.com/leeforge/framework/entities"
+)
+
+// DefaultIDColumn is the primary key column of ent entities.
+const DefaultIDColumn = "id"
+
+var columnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Query is the subset of a generated ent query builder EntRepository uses,
+// e.g. *ent.MediaQuery with P = predicate.Media and O = media.OrderOption.
+type Query[Q any, T any, P ~func(*sql.Selector), O ~func(*sql.Selector)] interface {
+	Where(...P) Q
+	Order(...O) Q
+	Limit(int) Q
+	Offset(int) Q
+	All(context.Context) ([]T, error)
+	Count(context.Context) (int, error)
+}
+
+// Cache stores entities for Get and BatchGet; *cache.MultiLevelCache implements it.
+type Cache interface {
+	Get(ctx context.Context, key string) (any, error)
+	Set(ctx context.Context, key string, value any, opts ...cache.SetOption) error
+	Delete(ctx context.Context, key string) error
+	InvalidateTag(ctx context.Context, tags ...string) error
+}
+
+var _ Cache = (*cache.MultiLevelCache)(nil)
+
+// EntRepository implements Repository on a generated ent client. Reads go
+// through the client's query builder, so interceptors such as soft delete
+// and tenant filters still apply; writes call the configured funcs, since
+// mapping an entity onto a create or update builder is entity specific.
+//
+//	media := repo.NewEnt("media", client.Media.Query, func(m *ent.Media) uuid.UUID { return m.ID }).
+//		WithCreate(func(ctx context.Context, m *ent.Media) (*ent.Media, error) {
+//			return client.Media.Create().SetName(m.Name).SetURL(m.URL).Save(ctx)
+//		}).
+//		WithDelete(func(ctx context.Context, id uuid.UUID) error {
+//			return client.Media.DeleteOneID(id).Exec(ctx)
+//		}).
+//		WithCache(mlc)
+type EntRepository[T any, ID comparable, Q Query[Q, T, P, O], P ~func(*sql.Selector), O ~func(*sql.Selector)] struct {
+	name     string
+	query    func() Q
+	id       func(T) ID
+	idColumn string
+	columns  map[string]bool
+
+	create func(context.Context, T) (T, error)
+	update func(context.Context, ID, T) (T, error)
+	remove func(context.Context, ID) error
+
+	cache Cache
+}
+
+// NewEnt creates a repository named name (used in cache keys) reading through
+// query, typically client.X.Query, and identifying entities with id.
+func NewEnt[T any, ID comparable, Q Query[Q, T, P, O], P ~func(*sql.Selector), O ~func(*sql.Selector)](name string, query func() Q, id func(T) ID) *EntRepository[T, ID, Q, P, O] {
+	return &EntRepository[T, ID, Q, P, O]{name: name, query: query, id: id, idColumn: DefaultIDColumn}
+}
+
+// WithIDColumn overrides the primary key column, "id" by default.
+func (r *EntRepository[T, ID, Q, P, O]) WithIDColumn(column string) *EntRepository[T, ID, Q, P, O] {
+	r.idColumn = column
+	return r
+}
+
+// WithColumns restricts List filters and ordering to columns.
+func (r *EntRepository[T, ID, Q, P, O]) WithColumns(columns ...string) *EntRepository[T, ID, Q, P, O] {
+	r.columns = make(map[string]bool, len(columns))
+	for _, col := range columns {
+		r.columns[col] = true
+	}
+	return r
+}
+
+// WithCreate sets the func Create delegates to.
+func (r *EntRepository[T, ID, Q, P, O]) WithCreate(fn func(context.Context, T) (T, error)) *EntRepository[T, ID, Q, P, O] {
+	r.create = fn
+	return r
+}
+
+// WithUpdate sets the func Update delegates to.
+func (r *EntRepository[T, ID, Q, P, O]) WithUpdate(fn func(context.Context, ID, T) (T, error)) *EntRepository[T, ID, Q, P, O] {
+	r.update = fn
+	return r
+}
+
+// WithDelete sets the func Delete delegates to.
+func (r *EntRepository[T, ID, Q, P, O]) WithDelete(fn func(context.Context, ID) error) *EntRepository[T, ID, Q, P, O] {
+	r.remove = fn
+	return r
+}
+
+// WithCache caches entities read by Get and BatchGet. Entries are keyed on
+// the query scope of the context (entities.ScopeKey), so a read under one
+// tenant, AsSystem or IncludeDeleted never serves another. Writes refresh or
+// evict the entity in every scope, and InvalidateCache drops every entry of
+// the repository; call it after writes that bypass the repository,
+// including soft deletes.
+func (r *EntRepository[T, ID, Q, P, O]) WithCache(c Cache) *EntRepository[T, ID, Q, P, O] {
+	r.cache = c
+	return r
+}
+
+// CacheKey returns the cache key of an entity read under the query scope of ctx.
+func (r *EntRepository[T, ID, Q, P, O]) CacheKey(ctx context.Context, id ID) string {
+	key := r.entityTag(id)
+	if scope := entities.ScopeKey(ctx); scope != "" {
+		sum := sha256.Sum256([]byte(scope))
+		key += "@" + hex.EncodeToString(sum[:8])
+	}
+	return key
+}
+
+// CacheTag returns the tag attached to every cache entry of the repository.
+func (r *EntRepository[T, ID, Q, P, O]) CacheTag() string {
+	return "repo:" + r.name
+}
+
+// InvalidateCache drops every cached entity of the repository, e.g. after a
+// bulk update that bypassed it.
+func (r *EntRepository[T, ID, Q, P, O]) InvalidateCache(ctx context.Context) error {
+	if r.cache == nil {
+		return nil
+	}
+	return r.cache.InvalidateTag(ctx, r.CacheTag())
+}
+
+// Get returns the entity with id, or ErrNotFound.
+func (r *EntRepository[T, ID, Q, P, O]) Get(ctx context.Context, id ID) (T, error) {
+	if v, ok := r.cached(ctx, id); ok {
+		return v, nil
+	}
+	var zero T
+	items, err := r.query().Where(r.where(r.idColumn, id)).Limit(1).All(ctx)
+	if err != nil {
+		return zero, err
+	}
+	if len(items) == 0 {
+		return zero, fmt.Errorf("%w: %s %v", ErrNotFound, r.name, id)
+	}
+	r.store(ctx, items[0])
+	return items[0], nil
+}
+
+// BatchGet loads the entities for ids in one query, serving cached ones
+// from the cache.
+func (r *EntRepository[T, ID, Q, P, O]) BatchGet(ctx context.Context, ids []ID) (map[ID]T, error) {
+	found := make(map[ID]T, len(ids))
+	var missing []any
+	seen := make(map[ID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if v, ok := r.cached(ctx, id); ok {
+			found[id] = v
+			continue
+		}
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return found, nil
+	}
+
+	items, err := r.query().Where(r.where(r.idColumn, missing)).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		found[r.id(item)] = item
+		r.store(ctx, item)
+	}
+	return found, nil
+}
+
+// List returns the rows matching opts.Filters, ordered and paginated.
+func (r *EntRepository[T, ID, Q, P, O]) List(ctx context.Context, opts ListOptions) (*Page[T], error) {
+	filters := slices.Sorted(maps.Keys(opts.Filters))
+	for _, col := range filters {
+		if err := r.checkColumn(col); err != nil {
+			return nil, err
+		}
+	}
+	var orders []O
+	for _, col := range opts.OrderBy {
+		desc := strings.HasPrefix(col, "-")
+		col = strings.TrimPrefix(col, "-")
+		if err := r.checkColumn(col); err != nil {
+			return nil, err
+		}
+		orders = append(orders, func(s *sql.Selector) {
+			if desc {
+				s.OrderBy(sql.Desc(s.C(col)))
+			} else {
+				s.OrderBy(sql.Asc(s.C(col)))
+			}
+		})
+	}
+
+	preds := make([]P, 0, len(filters))
+	for _, col := range filters {
+		preds = append(preds, r.where(col, opts.Filters[col]))
+	}
+
+	total, err := r.query().Where(preds...).Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q := r.query().Where(preds...).Order(orders...)
+	page := &Page[T]{Total: total, Page: 1, PerPage: total, TotalPages: 1}
+	if p := opts.Pagination; p != nil {
+		q = q.Limit(p.Limit()).Offset(p.Offset())
+		page.Page, page.PerPage, page.TotalPages = p.Page, p.PerPage, p.TotalPages(total)
+	}
+	if page.Items, err = q.All(ctx); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Create stores entity through the WithCreate func and caches the result.
+func (r *EntRepository[T, ID, Q, P, O]) Create(ctx context.Context, entity T) (T, error) {
+	if r.create == nil {
+		var zero T
+		return zero, fmt.Errorf("%w: create %s", ErrNotSupported, r.name)
+	}
+	created, err := r.create(ctx, entity)
+	if err != nil {
+		return created, err
+	}
+	r.store(ctx, created)
+	return created, nil
+}
+
+// Update applies entity to the row with id through the WithUpdate func.
+func (r *EntRepository[T, ID, Q, P, O]) Update(ctx context.Context, id ID, entity T) (T, error) {
+	if r.update == nil {
+		var zero T
+		return zero, fmt.Errorf("%w: update %s", ErrNotSupported, r.name)
+	}
+	r.evict(ctx, id)
+	updated, err := r.update(ctx, id, entity)
+	if err != nil {
+		return updated, err
+	}
+	r.store(ctx, updated)
+	return updated, nil
+}
+
+// Delete removes the row with id through the WithDelete func.
+func (r *EntRepository[T, ID, Q, P, O]) Delete(ctx context.Context, id ID) error {
+	if r.remove == nil {
+		return fmt.Errorf("%w: delete %s", ErrNotSupported, r.name)
+	}
+	r.evict(ctx, id)
+	return r.remove(ctx, id)
+}
+
+// where builds an equality, IN or IS NULL predicate on column.
+func (r *EntRepository[T, ID, Q, P, O]) where(column string, value any) P {
+	return func(s *sql.Selector) {
+		switch values, isSlice := sliceValues(value); {
+		case value == nil:
+			s.Where(sql.IsNull(s.C(column)))
+		case isSlice:
+			s.Where(sql.In(s.C(column), values...))
+		default:
+			s.Where(sql.EQ(s.C(column), value))
+		}
+	}
+}
+
+func (r *EntRepository[T, ID, Q, P, O]) checkColumn(column string) error {
+	if !columnPattern.MatchString(column) || (r.columns != nil && !r.columns[column] && column != r.idColumn) {
+		return fmt.Errorf("%w: %q", ErrInvalidColumn, column)
+	}
+	return nil
+}
+
+// cached returns the cached entity for id. Entries of another type, such as
+// values decoded from Redis as maps, count as misses.
+func (r *EntRepository[T, ID, Q, P, O]) cached(ctx context.Context, id ID) (T, bool) {
+	var zero T
+	if r.cache == nil {
+		return zero, false
+	}
+	v, err := r.cache.Get(ctx, r.CacheKey(ctx, id))
+	if err != nil {
+		return zero, false
+	}
+	entity, ok := v.(T)
+	return entity, ok
+}
+
+func (r *EntRepository[T, ID, Q, P, O]) store(ctx context.Context, entity T) {
+	if r.cache != nil {
+		id := r.id(entity)
+		r.cache.Set(ctx, r.CacheKey(ctx, id), entity, cache.WithTags(r.CacheTag(), r.entityTag(id)))
+	}
+}
+
+// evict drops the cached entity for id in every scope.
+func (r *EntRepository[T, ID, Q, P, O]) evict(ctx context.Context, id ID) {
+	if r.cache != nil {
+		r.cache.InvalidateTag(ctx, r.entityTag(id))
+	}
+}
+
+// entityTag tags the entries of one entity across scopes.
+func (r *EntRepository[T, ID, Q, P, O]) entityTag(id ID) string {
+	return fmt.Sprintf("repo:%s:%v", r.name, id)
+}
+
+// sliceValues flattens slice values other than []byte for IN predicates.
+func sliceValues(v any) ([]any, bool) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	values := make([]any, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values, true
+}
@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/cache"
+	"github.com/leeforge/framework/ent"
+	"github.com/leeforge/framework/ent/media"
+	"github.com/leeforge/framework/ent/predicate"
+	"github.com/leeforge/framework/entities"
+
+	_ "modernc.org/sqlite"
+)
+
+type mediaRepo = EntRepository[*ent.Media, uuid.UUID, *ent.MediaQuery, predicate.Media, media.OrderOption]
+
+var _ Repository[*ent.Media, uuid.UUID] = (*mediaRepo)(nil)
+
+func newMediaRepo(t *testing.T) (*mediaRepo, *ent.Client) {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:repo-"+uuid.NewString()+"?mode=memory&cache=shared&_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ent.NewClient(ent.Driver(entsql.OpenDB(dialect.SQLite, db)))
+	t.Cleanup(func() { client.Close() })
+	if err := client.Schema.Create(context.Background()); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+
+	r := NewEnt("media", client.Media.Query, func(m *ent.Media) uuid.UUID { return m.ID }).
+		WithColumns("name", "mime", "width").
+		WithCreate(func(ctx context.Context, m *ent.Media) (*ent.Media, error) {
+			return client.Media.Create().SetName(m.Name).SetURL(m.URL).SetNillableMime(m.Mime).SetNillableWidth(m.Width).Save(ctx)
+		}).
+		WithUpdate(func(ctx context.Context, id uuid.UUID, m *ent.Media) (*ent.Media, error) {
+			return client.Media.UpdateOneID(id).SetName(m.Name).Save(ctx)
+		}).
+		WithDelete(func(ctx context.Context, id uuid.UUID) error {
+			return client.Media.DeleteOneID(id).Exec(ctx)
+		})
+	return r, client
+}
+
+func TestEntRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newMediaRepo(t)
+
+	created, err := r.Create(ctx, &ent.Media{Name: "a.png", URL: "/a.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Get(ctx, created.ID)
+	if err != nil || got.Name != "a.png" {
+		t.Fatalf("Get = %v, %v", got, err)
+	}
+
+	if _, err := r.Update(ctx, created.ID, &ent.Media{Name: "b.png"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := r.Get(ctx, created.ID); got.Name != "b.png" {
+		t.Errorf("name after update = %q", got.Name)
+	}
+
+	if err := r.Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestEntRepositoryList(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newMediaRepo(t)
+	for i, name := range []string{"c.png", "a.png", "b.pdf", "d.png"} {
+		mime := "image/png"
+		if name == "b.pdf" {
+			mime = "application/pdf"
+		}
+		if _, err := r.Create(ctx, &ent.Media{Name: name, URL: "/" + name, Mime: &mime, Width: &i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := r.List(ctx, ListOptions{
+		Pagination: ent.NewPagination(1, 2),
+		Filters:    map[string]any{"mime": "image/png"},
+		OrderBy:    []string{"name"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || page.TotalPages != 2 || len(page.Items) != 2 || page.Items[0].Name != "a.png" || page.Items[1].Name != "c.png" {
+		t.Errorf("page = total %d, pages %d, items %v", page.Total, page.TotalPages, page.Items)
+	}
+
+	page, err = r.List(ctx, ListOptions{Filters: map[string]any{"name": []string{"a.png", "b.pdf"}}, OrderBy: []string{"-width"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Items[0].Name != "b.pdf" {
+		t.Errorf("IN filter ordered by -width = %v", page.Items)
+	}
+
+	if _, err := r.List(ctx, ListOptions{Filters: map[string]any{"url": "/a.png"}}); !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("filter outside allowlist: err = %v, want ErrInvalidColumn", err)
+	}
+	if _, err := r.List(ctx, ListOptions{OrderBy: []string{"name; DROP TABLE media"}}); !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("injected order column: err = %v, want ErrInvalidColumn", err)
+	}
+}
+
+func TestEntRepositoryCache(t *testing.T) {
+	ctx := context.Background()
+	r, client := newMediaRepo(t)
+	mlc := cache.NewMultiLevelCache(nil, nil)
+	defer mlc.Close()
+	r.WithCache(mlc)
+
+	a, _ := r.Create(ctx, &ent.Media{Name: "a.png", URL: "/a.png"})
+	b := client.Media.Create().SetName("b.png").SetURL("/b.png").SaveX(ctx)
+
+	// a bypassing write is not seen until the cache is invalidated
+	client.Media.UpdateOneID(a.ID).SetName("renamed").ExecX(ctx)
+	if got, _ := r.Get(ctx, a.ID); got.Name != "a.png" {
+		t.Errorf("Get = %q, want cached a.png", got.Name)
+	}
+
+	found, err := r.BatchGet(ctx, []uuid.UUID{a.ID, b.ID, a.ID, uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[a.ID].Name != "a.png" || found[b.ID].Name != "b.png" {
+		t.Errorf("BatchGet = %v", found)
+	}
+	if _, err := mlc.Get(ctx, r.CacheKey(ctx, b.ID)); err != nil {
+		t.Errorf("BatchGet did not cache b: %v", err)
+	}
+
+	if err := r.InvalidateCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := r.Get(ctx, a.ID); got.Name != "renamed" {
+		t.Errorf("Get after invalidation = %q, want renamed", got.Name)
+	}
+}
+
+func TestEntRepositoryCacheIsScopedToTenant(t *testing.T) {
+	r, client := newMediaRepo(t)
+	client.Media.Use(entities.TenantScopeMixin{}.Hooks()...)
+	client.Media.Intercept(entities.TenantScopeMixin{}.Interceptors()...)
+	mlc := cache.NewMultiLevelCache(nil, nil)
+	defer mlc.Close()
+	r.WithCache(mlc)
+	ctxA := entities.WithTenant(context.Background(), "tenant-a")
+	ctxB := entities.WithTenant(context.Background(), "tenant-b")
+
+	a, err := r.Create(ctxA, &ent.Media{Name: "a.png", URL: "/a.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.Get(ctxA, a.ID); err != nil || got.Name != "a.png" {
+		t.Fatalf("Get as tenant-a = %v, %v", got, err)
+	}
+
+	if _, err := r.Get(ctxB, a.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get as tenant-b: err = %v, want ErrNotFound", err)
+	}
+	if found, err := r.BatchGet(ctxB, []uuid.UUID{a.ID}); err != nil || len(found) != 0 {
+		t.Errorf("BatchGet as tenant-b = %v, %v, want nothing", found, err)
+	}
+
+	// a delete evicts the entity in every scope
+	system := entities.AsSystem(context.Background())
+	if _, err := r.Get(system, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Delete(ctxA, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(system, a.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get as system after delete: err = %v, want ErrNotFound", err)
+	}
+}
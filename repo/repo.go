@@ -0,0 +1,53 @@
+// Package repo provides a generic repository interface and an ent-backed base
+// implementation, so plugins get Get/List/Create/Update/Delete/BatchGet with
+// pagination, column filters and caching without writing the plumbing per entity.
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/leeforge/framework/ent"
+)
+
+var (
+	// ErrNotFound is returned by Get when no row has the ID.
+	ErrNotFound = errors.New("repo: not found")
+	// ErrNotSupported is returned by writes the repository was not configured for.
+	ErrNotSupported = errors.New("repo: operation not supported")
+	// ErrInvalidColumn is returned for filter or order columns that are not
+	// identifiers or not in the repository's column allowlist.
+	ErrInvalidColumn = errors.New("repo: invalid column")
+)
+
+// Repository is the CRUD surface shared by entity repositories.
+type Repository[T any, ID comparable] interface {
+	Get(ctx context.Context, id ID) (T, error)
+	List(ctx context.Context, opts ListOptions) (*Page[T], error)
+	Create(ctx context.Context, entity T) (T, error)
+	Update(ctx context.Context, id ID, entity T) (T, error)
+	Delete(ctx context.Context, id ID) error
+	// BatchGet returns the entities found for ids, keyed by ID; missing IDs
+	// are absent from the map.
+	BatchGet(ctx context.Context, ids []ID) (map[ID]T, error)
+}
+
+// ListOptions filters, orders and paginates List.
+type ListOptions struct {
+	// Pagination limits the page; nil returns every matching row.
+	Pagination *ent.Pagination
+	// Filters restricts rows by column: a value matches by equality, a slice
+	// by IN and nil by IS NULL.
+	Filters map[string]any
+	// OrderBy lists columns to sort by; a "-" prefix sorts descending.
+	OrderBy []string
+}
+
+// Page is one page of List results.
+type Page[T any] struct {
+	Items      []T `json:"items"`
+	Total      int `json:"total"`
+	Page       int `json:"page"`
+	PerPage    int `json:"perPage"`
+	TotalPages int `json:"totalPages"`
+}
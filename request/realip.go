@@ -0,0 +1,196 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+)
+
+// PrivateNetworks lists loopback, private and link-local ranges, the usual
+// addresses of reverse proxies and load balancers inside a deployment.
+var PrivateNetworks = []string{
+	"127.0.0.0/8",
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"169.254.0.0/16",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+}
+
+// RealIP resolves the client IP of a request. Forwarding headers are only
+// honoured when the direct peer is a trusted proxy, and X-Forwarded-For is
+// walked from the right so entries prepended by the client are ignored.
+type RealIP struct {
+	trusted []netip.Prefix
+}
+
+// NewRealIP creates a resolver trusting the given proxies, each a CIDR
+// ("10.0.0.0/8") or a single address ("192.0.2.10"). Without proxies the
+// peer address is always the client IP.
+func NewRealIP(trustedProxies ...string) (*RealIP, error) {
+	ri := &RealIP{}
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("request: invalid trusted proxy %q: %w", proxy, err)
+			}
+			ri.trusted = append(ri.trusted, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("request: invalid trusted proxy %q: %w", proxy, err)
+		}
+		addr = addr.Unmap()
+		ri.trusted = append(ri.trusted, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return ri, nil
+}
+
+// Trusted reports whether ip belongs to a trusted proxy.
+func (ri *RealIP) Trusted(ip string) bool {
+	addr, ok := parseIP(ip)
+	return ok && ri.trustedAddr(addr)
+}
+
+func (ri *RealIP) trustedAddr(addr netip.Addr) bool {
+	for _, prefix := range ri.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client IP of r without port. When the peer is not a
+// trusted proxy it is the client; otherwise the rightmost untrusted
+// X-Forwarded-For entry is, falling back to X-Real-IP.
+func (ri *RealIP) ClientIP(r *http.Request) string {
+	peer := remoteIP(r.RemoteAddr)
+	addr, ok := parseIP(peer)
+	if !ok {
+		return peer
+	}
+	if !ri.trustedAddr(addr) {
+		return addr.String()
+	}
+	peer = addr.String()
+
+	if hops := forwardedFor(r.Header); len(hops) > 0 {
+		client := peer
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, ok := parseIP(hops[i])
+			if !ok {
+				// a malformed hop cannot be trusted further; keep the last good one
+				break
+			}
+			client = hop.String()
+			if !ri.trustedAddr(hop) {
+				break
+			}
+		}
+		return client
+	}
+
+	if xrip, ok := parseIP(r.Header.Get("X-Real-IP")); ok {
+		return xrip.String()
+	}
+	return peer
+}
+
+// Middleware resolves the client IP once and stores it in the request
+// context, where GetClientIP picks it up.
+func (ri *RealIP) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey{}, ri.ClientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+var defaultRealIP atomic.Pointer[RealIP]
+
+func init() {
+	defaultRealIP.Store(&RealIP{})
+}
+
+// SetRealIP replaces the resolver used by GetClientIP and FromHTTPRequest.
+// The default trusts no proxy. A nil ri restores the default.
+func SetRealIP(ri *RealIP) {
+	if ri == nil {
+		ri = &RealIP{}
+	}
+	defaultRealIP.Store(ri)
+}
+
+// DefaultRealIP returns the resolver set with SetRealIP.
+func DefaultRealIP() *RealIP {
+	return defaultRealIP.Load()
+}
+
+// GetClientIP returns the client IP resolved by RealIP.Middleware, or by the
+// default resolver when the middleware is not mounted. IP filters, rate
+// limiters and audit logs use it so they agree on who the client is.
+func GetClientIP(r *http.Request) string {
+	if ip, ok := ClientIPFromContext(r.Context()); ok {
+		return ip
+	}
+	return DefaultRealIP().ClientIP(r)
+}
+
+// ClientIPFromContext returns the IP stored by RealIP.Middleware.
+func ClientIPFromContext(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(clientIPKey{}).(string)
+	return ip, ok && ip != ""
+}
+
+type clientIPKey struct{}
+
+// forwardedFor returns the X-Forwarded-For hops of all header lines in
+// order, client first.
+func forwardedFor(h http.Header) []string {
+	var hops []string
+	for _, line := range h.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(line, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	return hops
+}
+
+// remoteIP strips the port from a RemoteAddr.
+func remoteIP(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// parseIP parses an address as found in headers: bracketed or with a port
+// is accepted, IPv4-mapped IPv6 is unmapped and zones are dropped.
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return netip.Addr{}, false
+	}
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap().WithZone(""), true
+	}
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
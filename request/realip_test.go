@@ -0,0 +1,67 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newReq(remoteAddr string, header ...string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = remoteAddr
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Add(header[i], header[i+1])
+	}
+	return r
+}
+
+func TestRealIPClientIP(t *testing.T) {
+	ri, err := NewRealIP("10.0.0.0/8", "192.0.2.10")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name string
+		req  *http.Request
+		want string
+	}{
+		{"untrusted peer ignores headers", newReq("203.0.113.7:5000", "X-Forwarded-For", "1.1.1.1", "X-Real-IP", "2.2.2.2"), "203.0.113.7"},
+		{"rightmost untrusted hop", newReq("10.0.0.2:80", "X-Forwarded-For", "6.6.6.6, 198.51.100.4, 10.0.0.9"), "198.51.100.4"},
+		{"multiple header lines", newReq("192.0.2.10:80", "X-Forwarded-For", "6.6.6.6", "X-Forwarded-For", "198.51.100.4"), "198.51.100.4"},
+		{"all hops trusted", newReq("10.0.0.2:80", "X-Forwarded-For", "10.1.1.1, 10.0.0.9"), "10.1.1.1"},
+		{"malformed hop stops the walk", newReq("10.0.0.2:80", "X-Forwarded-For", "198.51.100.4, bogus, 10.0.0.9"), "10.0.0.9"},
+		{"x-real-ip from trusted peer", newReq("10.0.0.2:80", "X-Real-IP", "198.51.100.4"), "198.51.100.4"},
+		{"ipv6 peer", newReq("[2001:db8::1]:443"), "2001:db8::1"},
+		{"mapped ipv4 peer", newReq("[::ffff:10.0.0.2]:80", "X-Forwarded-For", "198.51.100.4:1234"), "198.51.100.4"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, ri.ClientIP(tc.req))
+		})
+	}
+
+	_, err = NewRealIP("10.0.0.0/33")
+	require.Error(t, err)
+}
+
+func TestGetClientIP(t *testing.T) {
+	t.Cleanup(func() { SetRealIP(nil) })
+
+	// 默认不信任任何代理
+	r := newReq("10.0.0.2:80", "X-Forwarded-For", "198.51.100.4")
+	require.Equal(t, "10.0.0.2", GetClientIP(r))
+	require.Equal(t, "10.0.0.2", FromHTTPRequest(r).IPAddress)
+
+	ri, err := NewRealIP(PrivateNetworks...)
+	require.NoError(t, err)
+	SetRealIP(ri)
+	require.Equal(t, "198.51.100.4", GetClientIP(r))
+
+	var got string
+	SetRealIP(nil)
+	ri.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = GetClientIP(r)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	require.Equal(t, "198.51.100.4", got)
+}
@@ -114,22 +114,11 @@ func NewRequestContext() *RequestContext {
 
 // FromHTTPRequest extracts request context from HTTP request
 func FromHTTPRequest(r *http.Request) *RequestContext {
-	ctx := &RequestContext{
-		Method:    r.Method,
-		Path:      r.URL.Path,
-		IPAddress: getClientIP(r),
-		UserAgent: r.UserAgent(),
-		Timestamp: time.Now(),
-		Metadata:  make(map[string]string),
-	}
-
-	// Extract headers
-	ctx.RequestID = getHeader(r, "X-Request-ID", "X-Request-Id")
-	ctx.TraceID = getHeader(r, "X-Trace-ID", "X-Trace-Id")
-	ctx.SpanID = getHeader(r, "X-Span-ID", "X-Span-Id")
-	ctx.CorrelationID = getHeader(r, "X-Correlation-ID", "X-Correlation-Id")
-	ctx.UserID = getHeader(r, "X-User-ID", "X-User-Id")
-	ctx.TenantID = getHeader(r, "X-Tenant-ID", "X-Tenant-Id")
+	ctx := FromHeaders(r.Header)
+	ctx.Method = r.Method
+	ctx.Path = r.URL.Path
+	ctx.IPAddress = GetClientIP(r)
+	ctx.UserAgent = r.UserAgent()
 
 	// Generate missing IDs
 	if ctx.RequestID == "" {
@@ -145,8 +134,25 @@ func FromHTTPRequest(r *http.Request) *RequestContext {
 		ctx.CorrelationID = GenerateCorrelationID()
 	}
 
+	return ctx
+}
+
+// FromHeaders extracts request context from headers written by ToHeaders.
+// Unlike FromHTTPRequest it does not generate missing IDs.
+func FromHeaders(h http.Header) *RequestContext {
+	ctx := &RequestContext{
+		RequestID:     getHeaderValue(h, "X-Request-ID", "X-Request-Id"),
+		TraceID:       getHeaderValue(h, "X-Trace-ID", "X-Trace-Id"),
+		SpanID:        getHeaderValue(h, "X-Span-ID", "X-Span-Id"),
+		CorrelationID: getHeaderValue(h, "X-Correlation-ID", "X-Correlation-Id"),
+		UserID:        getHeaderValue(h, "X-User-ID", "X-User-Id"),
+		TenantID:      getHeaderValue(h, "X-Tenant-ID", "X-Tenant-Id"),
+		Timestamp:     time.Now(),
+		Metadata:      make(map[string]string),
+	}
+
 	// Extract custom metadata headers
-	for k, v := range r.Header {
+	for k, v := range h {
 		if strings.HasPrefix(k, "X-Meta-") {
 			key := strings.TrimPrefix(k, "X-Meta-")
 			if len(v) > 0 {
@@ -160,7 +166,11 @@ func FromHTTPRequest(r *http.Request) *RequestContext {
 
 // ToContext converts request context to context.Context
 func (rc *RequestContext) ToContext() context.Context {
-	ctx := context.Background()
+	return rc.WithContext(context.Background())
+}
+
+// WithContext returns a copy of ctx carrying the request context
+func (rc *RequestContext) WithContext(ctx context.Context) context.Context {
 	ctx = context.WithValue(ctx, requestIDKey{}, rc.RequestID)
 	ctx = context.WithValue(ctx, traceIDKey{}, rc.TraceID)
 	ctx = context.WithValue(ctx, spanIDKey{}, rc.SpanID)
@@ -283,7 +293,7 @@ func (m *RequestIDMiddleware) Middleware(next http.Handler) http.Handler {
 			CorrelationID: correlationID,
 			UserID:        r.Header.Get("X-User-ID"),
 			TenantID:      r.Header.Get("X-Tenant-ID"),
-			IPAddress:     getClientIP(r),
+			IPAddress:     GetClientIP(r),
 			UserAgent:     r.UserAgent(),
 			Method:        r.Method,
 			Path:          r.URL.Path,
@@ -339,6 +349,7 @@ func (l *RequestLogger) Middleware(next http.Handler) http.Handler {
 			"method", r.Method,
 			"path", r.URL.Path,
 			"remote", r.RemoteAddr,
+			"ip", GetClientIP(r),
 			"user_agent", r.UserAgent(),
 			"request_id", rc.RequestID,
 			"trace_id", rc.TraceID,
@@ -436,6 +447,9 @@ func (v *RequestValidator) Validate(body interface{}) error {
 }
 
 // RequestThrottler throttles requests
+//
+// Deprecated: RequestThrottler keeps every timestamp in memory; use
+// ratelimit.Limiter, which bounds state per key and supports Redis.
 type RequestThrottler struct {
 	limit    int
 	window   time.Duration
@@ -584,27 +598,13 @@ func (f *RequestIDGeneratorFactory) GetGenerator(prefix string) *RequestIDGenera
 
 // Helper functions
 
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		parts := strings.Split(xff, ",")
-		if len(parts) > 0 {
-			return strings.TrimSpace(parts[0])
-		}
-	}
-
-	// Check X-Real-IP
-	if xrip := r.Header.Get("X-Real-IP"); xrip != "" {
-		return xrip
-	}
-
-	// Use RemoteAddr
-	return r.RemoteAddr
+func getHeader(r *http.Request, keys ...string) string {
+	return getHeaderValue(r.Header, keys...)
 }
 
-func getHeader(r *http.Request, keys ...string) string {
+func getHeaderValue(h http.Header, keys ...string) string {
 	for _, key := range keys {
-		if value := r.Header.Get(key); value != "" {
+		if value := h.Get(key); value != "" {
 			return value
 		}
 	}
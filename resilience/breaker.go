@@ -0,0 +1,217 @@
+package resilience
+
+import (
+	"context"
+	stderrors "errors"
+	"sync"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+)
+
+// MetricBreakerState is a gauge labelled by "breaker": 0 closed, 1 open,
+// 2 half-open.
+const MetricBreakerState = "circuit_breaker_state"
+
+// ErrCircuitOpen is returned while a breaker rejects calls.
+var ErrCircuitOpen = stderrors.New("resilience: circuit open")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every call through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects calls until the cooldown elapses.
+	BreakerOpen
+	// BreakerHalfOpen lets a limited number of trial calls through.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerConfig configures a Breaker.
+type BreakerConfig struct {
+	// Name labels metrics. Defaults to "default".
+	Name string
+	// FailureThreshold is the number of consecutive failures that opens the
+	// breaker. Defaults to 5.
+	FailureThreshold int
+	// Cooldown is how long the breaker stays open before allowing trial
+	// calls. Defaults to 30s.
+	Cooldown time.Duration
+	// HalfOpenMax bounds concurrent trial calls while half-open. Defaults to 1.
+	HalfOpenMax int
+	// IsFailure decides whether an error counts against the breaker. Defaults
+	// to any non-nil error except context cancellation.
+	IsFailure func(err error) bool
+	// OnStateChange is called after every transition, outside the lock.
+	OnStateChange func(name string, from, to BreakerState)
+	// Metrics receives the state gauge when set.
+	Metrics *metrics.Collector
+}
+
+// Breaker is a consecutive-failure circuit breaker. A closed breaker opens
+// after FailureThreshold failures in a row; after Cooldown it lets trial
+// calls through, closing on the first success and reopening on a failure.
+type Breaker struct {
+	config BreakerConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trials   int
+}
+
+// NewBreaker creates a closed breaker.
+func NewBreaker(config BreakerConfig) *Breaker {
+	if config.Name == "" {
+		config.Name = "default"
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	if config.HalfOpenMax <= 0 {
+		config.HalfOpenMax = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = func(err error) bool {
+			return err != nil && !stderrors.Is(err, context.Canceled)
+		}
+	}
+	return &Breaker{config: config, now: time.Now}
+}
+
+// Name returns the breaker name.
+func (b *Breaker) Name() string {
+	return b.config.Name
+}
+
+// State returns the current state, moving an open breaker whose cooldown
+// has elapsed to half-open.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	from, to := b.advance()
+	state := b.state
+	b.mu.Unlock()
+	b.changed(from, to)
+	return state
+}
+
+// Allow reports whether a call may proceed. On success the caller must pass
+// the call's error to done exactly once.
+func (b *Breaker) Allow() (done func(err error), err error) {
+	b.mu.Lock()
+	from, to := b.advance()
+	switch b.state {
+	case BreakerOpen:
+		b.mu.Unlock()
+		b.changed(from, to)
+		return nil, ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.trials >= b.config.HalfOpenMax {
+			b.mu.Unlock()
+			b.changed(from, to)
+			return nil, ErrCircuitOpen
+		}
+		b.trials++
+	}
+	b.mu.Unlock()
+	b.changed(from, to)
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { b.record(err) })
+	}, nil
+}
+
+// Do runs fn unless the breaker is open and records its outcome.
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn(ctx)
+	done(err)
+	return err
+}
+
+// Reset closes the breaker and clears its failure count.
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	from := b.state
+	b.state, b.failures, b.trials = BreakerClosed, 0, 0
+	b.mu.Unlock()
+	b.changed(from, BreakerClosed)
+}
+
+func (b *Breaker) record(err error) {
+	failed := b.config.IsFailure(err)
+
+	b.mu.Lock()
+	from := b.state
+	if from == BreakerHalfOpen {
+		b.trials--
+	}
+	switch {
+	case !failed:
+		b.failures = 0
+		if from == BreakerHalfOpen {
+			b.state, b.trials = BreakerClosed, 0
+		}
+	case from == BreakerHalfOpen:
+		b.trip()
+	default:
+		b.failures++
+		if from == BreakerClosed && b.failures >= b.config.FailureThreshold {
+			b.trip()
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.changed(from, to)
+}
+
+// trip opens the breaker. Callers hold b.mu.
+func (b *Breaker) trip() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+	b.failures, b.trials = 0, 0
+}
+
+// advance moves an open breaker to half-open once the cooldown elapsed and
+// returns the transition. Callers hold b.mu.
+func (b *Breaker) advance() (from, to BreakerState) {
+	from = b.state
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.config.Cooldown {
+		b.state = BreakerHalfOpen
+		b.trials = 0
+	}
+	return from, b.state
+}
+
+func (b *Breaker) changed(from, to BreakerState) {
+	if from == to {
+		return
+	}
+	if c := b.config.Metrics; c != nil {
+		c.SetGauge(MetricBreakerState, float64(to), map[string]string{"breaker": b.config.Name})
+	}
+	if b.config.OnStateChange != nil {
+		b.config.OnStateChange(b.config.Name, from, to)
+	}
+}
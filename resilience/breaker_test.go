@@ -0,0 +1,90 @@
+package resilience
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+)
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	collector := metrics.NewCollector()
+	var transitions []string
+	b := NewBreaker(BreakerConfig{
+		Name:             "billing",
+		FailureThreshold: 2,
+		Cooldown:         time.Minute,
+		Metrics:          collector,
+		OnStateChange: func(_ string, from, to BreakerState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	boom := stderrors.New("boom")
+	fail := func(context.Context) error { return boom }
+	for i := 0; i < 2; i++ {
+		if err := b.Do(context.Background(), fail); err != boom {
+			t.Fatalf("call %d: err = %v", i, err)
+		}
+	}
+	if err := b.Do(context.Background(), fail); !stderrors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if m := collector.GetMetric(MetricBreakerState, map[string]string{"breaker": "billing"}); m == nil || m.Value != float64(BreakerOpen) {
+		t.Errorf("state gauge = %+v, want open", m)
+	}
+
+	now = now.Add(time.Minute)
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("half-open trial rejected: %v", err)
+	}
+	if _, err := b.Allow(); !stderrors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second trial err = %v, want ErrCircuitOpen", err)
+	}
+	done(nil)
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("state = %v, want closed", s)
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("transitions = %v, want %v", transitions, want)
+		}
+	}
+}
+
+func TestBreakerHalfOpenFailureReopens(t *testing.T) {
+	b := NewBreaker(BreakerConfig{FailureThreshold: 1, Cooldown: time.Second})
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	_ = b.Do(context.Background(), func(context.Context) error { return stderrors.New("x") })
+	now = now.Add(time.Second)
+	if s := b.State(); s != BreakerHalfOpen {
+		t.Fatalf("state = %v, want half-open", s)
+	}
+	_ = b.Do(context.Background(), func(context.Context) error { return stderrors.New("x") })
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("state = %v, want open", s)
+	}
+}
+
+func TestBreakerIgnoresCancellationAndResetsOnSuccess(t *testing.T) {
+	b := NewBreaker(BreakerConfig{FailureThreshold: 2})
+	_ = b.Do(context.Background(), func(context.Context) error { return context.Canceled })
+	_ = b.Do(context.Background(), func(context.Context) error { return stderrors.New("x") })
+	_ = b.Do(context.Background(), func(context.Context) error { return nil })
+	_ = b.Do(context.Background(), func(context.Context) error { return stderrors.New("x") })
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("state = %v, want closed", s)
+	}
+}
@@ -0,0 +1,257 @@
+// Package resilience provides components that keep one slow dependency or
+// route from exhausting a service's capacity.
+package resilience
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/response"
+	"github.com/leeforge/framework/metrics"
+)
+
+// Metric names exported to the metrics collector, labelled by "bulkhead".
+const (
+	MetricBulkheadActive     = "bulkhead_active"
+	MetricBulkheadWaiting    = "bulkhead_waiting"
+	MetricBulkheadSaturation = "bulkhead_saturation"
+	MetricBulkheadRejected   = "bulkhead_rejected_total"
+)
+
+var (
+	// ErrBulkheadFull is returned when every slot is busy and the queue is full.
+	ErrBulkheadFull = stderrors.New("resilience: bulkhead full")
+	// ErrBulkheadTimeout is returned when no slot frees up within QueueTimeout.
+	ErrBulkheadTimeout = stderrors.New("resilience: bulkhead queue timeout")
+)
+
+// BulkheadConfig configures a Bulkhead.
+type BulkheadConfig struct {
+	// Name labels metrics. Defaults to "default".
+	Name string
+	// MaxConcurrent bounds concurrent executions. Defaults to 10.
+	MaxConcurrent int
+	// MaxQueue bounds callers waiting for a slot; further callers are
+	// rejected immediately. Zero disables queueing.
+	MaxQueue int
+	// QueueTimeout bounds the wait for a slot. Zero waits until the context
+	// is done.
+	QueueTimeout time.Duration
+	// Metrics receives active/waiting/saturation gauges and a rejection
+	// counter when set.
+	Metrics *metrics.Collector
+}
+
+// BulkheadStats is a snapshot of a bulkhead's state.
+type BulkheadStats struct {
+	Active   int   `json:"active"`
+	Waiting  int   `json:"waiting"`
+	Rejected int64 `json:"rejected"`
+}
+
+// Bulkhead caps concurrent executions with a bounded wait queue.
+type Bulkhead struct {
+	config   BulkheadConfig
+	slots    chan struct{}
+	waiting  atomic.Int64
+	rejected atomic.Int64
+}
+
+// NewBulkhead creates a bulkhead.
+func NewBulkhead(config BulkheadConfig) *Bulkhead {
+	if config.Name == "" {
+		config.Name = "default"
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = 10
+	}
+	if config.MaxQueue < 0 {
+		config.MaxQueue = 0
+	}
+	return &Bulkhead{config: config, slots: make(chan struct{}, config.MaxConcurrent)}
+}
+
+// Name returns the bulkhead name.
+func (b *Bulkhead) Name() string {
+	return b.config.Name
+}
+
+// Acquire takes a slot, waiting in the queue if allowed. The returned
+// release must be called once the work is done; extra calls are no-ops.
+func (b *Bulkhead) Acquire(ctx context.Context) (release func(), err error) {
+	select {
+	case b.slots <- struct{}{}:
+		return b.acquired(), nil
+	default:
+	}
+
+	if b.waiting.Add(1) > int64(b.config.MaxQueue) {
+		b.waiting.Add(-1)
+		return nil, b.reject(ErrBulkheadFull)
+	}
+	b.report()
+	defer func() {
+		b.waiting.Add(-1)
+		b.report()
+	}()
+
+	var timeout <-chan time.Time
+	if b.config.QueueTimeout > 0 {
+		timer := time.NewTimer(b.config.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+		return b.acquired(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timeout:
+		return nil, b.reject(ErrBulkheadTimeout)
+	}
+}
+
+func (b *Bulkhead) acquired() func() {
+	b.report()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-b.slots
+			b.report()
+		})
+	}
+}
+
+func (b *Bulkhead) reject(err error) error {
+	b.rejected.Add(1)
+	if b.config.Metrics != nil {
+		b.config.Metrics.IncCounter(MetricBulkheadRejected, b.labels())
+	}
+	return err
+}
+
+func (b *Bulkhead) report() {
+	c := b.config.Metrics
+	if c == nil {
+		return
+	}
+	active := len(b.slots)
+	labels := b.labels()
+	c.SetGauge(MetricBulkheadActive, float64(active), labels)
+	c.SetGauge(MetricBulkheadWaiting, float64(b.waiting.Load()), labels)
+	c.SetGauge(MetricBulkheadSaturation, float64(active)/float64(b.config.MaxConcurrent), labels)
+}
+
+func (b *Bulkhead) labels() map[string]string {
+	return map[string]string{"bulkhead": b.config.Name}
+}
+
+// Stats returns the current state.
+func (b *Bulkhead) Stats() BulkheadStats {
+	return BulkheadStats{
+		Active:   len(b.slots),
+		Waiting:  int(b.waiting.Load()),
+		Rejected: b.rejected.Load(),
+	}
+}
+
+// Do runs fn in a slot. It returns ErrBulkheadFull, ErrBulkheadTimeout or the
+// context error without running fn when no slot is obtained.
+func (b *Bulkhead) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(ctx)
+}
+
+// Execute runs fn in a slot of b and returns its result.
+func Execute[T any](ctx context.Context, b *Bulkhead, fn func(ctx context.Context) (T, error)) (T, error) {
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer release()
+	return fn(ctx)
+}
+
+// Middleware caps concurrent requests through next. Rejected requests get
+// 503 with Retry-After: 1 in the standard error envelope.
+func (b *Bulkhead) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release, err := b.Acquire(r.Context())
+		if err != nil {
+			if r.Context().Err() != nil {
+				// The client is gone; nobody reads the response.
+				return
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(1))
+			response.WriteError(w, r, errors.WrapWithType(err, errors.ErrorTypeRateLimit, "server is busy, retry later").
+				WithCode("BULKHEAD_FULL").
+				WithHTTPStatus(http.StatusServiceUnavailable))
+			return
+		}
+		defer release()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Bulkheads holds named bulkheads, one per route or dependency.
+type Bulkheads struct {
+	defaults BulkheadConfig
+	configs  map[string]BulkheadConfig
+
+	mu        sync.Mutex
+	bulkheads map[string]*Bulkhead
+}
+
+// NewBulkheads creates a registry. Bulkheads without an entry in configs use
+// defaults; Name is set from the key and Metrics from defaults when unset.
+func NewBulkheads(defaults BulkheadConfig, configs map[string]BulkheadConfig) *Bulkheads {
+	return &Bulkheads{defaults: defaults, configs: configs, bulkheads: make(map[string]*Bulkhead)}
+}
+
+// Get returns the bulkhead for name, creating it on first use.
+func (r *Bulkheads) Get(name string) *Bulkhead {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b, ok := r.bulkheads[name]; ok {
+		return b
+	}
+	config, ok := r.configs[name]
+	if !ok {
+		config = r.defaults
+	}
+	config.Name = name
+	if config.Metrics == nil {
+		config.Metrics = r.defaults.Metrics
+	}
+	b := NewBulkhead(config)
+	r.bulkheads[name] = b
+	return b
+}
+
+// Middleware returns the middleware of the bulkhead for name.
+func (r *Bulkheads) Middleware(name string) func(http.Handler) http.Handler {
+	return r.Get(name).Middleware
+}
+
+// Stats returns a snapshot of every bulkhead created so far.
+func (r *Bulkheads) Stats() map[string]BulkheadStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make(map[string]BulkheadStats, len(r.bulkheads))
+	for name, b := range r.bulkheads {
+		out[name] = b.Stats()
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package resilience
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/metrics"
+)
+
+func TestBulkheadCapsConcurrency(t *testing.T) {
+	b := NewBulkhead(BulkheadConfig{MaxConcurrent: 2, MaxQueue: 10})
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := b.Do(context.Background(), func(context.Context) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Do: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak.Load() != 2 {
+		t.Fatalf("peak concurrency = %d, want 2", peak.Load())
+	}
+}
+
+func TestBulkheadRejectsWhenQueueFull(t *testing.T) {
+	collector := metrics.NewCollector()
+	b := NewBulkhead(BulkheadConfig{Name: "payments", MaxConcurrent: 1, Metrics: collector})
+
+	release, err := b.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Do(context.Background(), func(context.Context) error { return nil }); !stderrors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("err = %v, want ErrBulkheadFull", err)
+	}
+
+	labels := map[string]string{"bulkhead": "payments"}
+	if m := collector.GetMetric(MetricBulkheadSaturation, labels); m == nil || m.Value != 1 {
+		t.Errorf("saturation = %+v, want 1", m)
+	}
+	if m := collector.GetMetric(MetricBulkheadRejected, labels); m == nil || m.Value != 1 {
+		t.Errorf("rejected = %+v, want 1", m)
+	}
+
+	release()
+	release() // idempotent
+	if s := b.Stats(); s.Active != 0 || s.Rejected != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestBulkheadQueueTimeoutAndCancel(t *testing.T) {
+	b := NewBulkhead(BulkheadConfig{MaxConcurrent: 1, MaxQueue: 1, QueueTimeout: 10 * time.Millisecond})
+	release, _ := b.Acquire(context.Background())
+	defer release()
+
+	if _, err := b.Acquire(context.Background()); !stderrors.Is(err, ErrBulkheadTimeout) {
+		t.Fatalf("err = %v, want ErrBulkheadTimeout", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Execute(ctx, b, func(context.Context) (int, error) { return 1, nil }); !stderrors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if s := b.Stats(); s.Waiting != 0 {
+		t.Errorf("waiting = %d after timeouts", s.Waiting)
+	}
+}
+
+func TestBulkheadMiddleware(t *testing.T) {
+	registry := NewBulkheads(BulkheadConfig{MaxConcurrent: 1}, nil)
+	block := make(chan struct{})
+	handler := registry.Middleware("reports")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		close(done)
+	}()
+	for registry.Get("reports").Stats().Active == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("status = %d, Retry-After = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	close(block)
+	<-done
+	if _, ok := registry.Stats()["reports"]; !ok {
+		t.Error("Stats should include the reports bulkhead")
+	}
+}
@@ -0,0 +1,228 @@
+// Package retry runs operations with context-aware exponential backoff.
+package retry
+
+import (
+	"context"
+	stderrors "errors"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/leeforge/framework/errors"
+)
+
+// Policy configures retries. Zero fields take the defaults documented on
+// each field; Jitter has no default so a zero value disables it.
+type Policy struct {
+	// MaxAttempts bounds the number of calls, including the first. Default 3.
+	MaxAttempts int
+	// InitialInterval is the wait after the first failure. Default 100ms.
+	InitialInterval time.Duration
+	// MaxInterval caps a single wait. Default 10s.
+	MaxInterval time.Duration
+	// Multiplier grows the interval after each failure. Default 2.
+	Multiplier float64
+	// Jitter randomizes each wait by ±Jitter of its value (0 to 1).
+	Jitter float64
+	// MaxElapsed stops retrying once the next wait would end later than
+	// MaxElapsed after the first call. Zero means no limit.
+	MaxElapsed time.Duration
+	// Classify reports whether err is worth retrying. Default Retryable.
+	Classify func(err error) bool
+	// OnRetry is called before each wait with the attempt that failed.
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// DefaultPolicy returns a policy with 3 attempts, 100ms initial interval
+// doubling up to 10s, and 20% jitter.
+func DefaultPolicy() Policy {
+	return Policy{Jitter: 0.2}
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.InitialInterval <= 0 {
+		p.InitialInterval = 100 * time.Millisecond
+	}
+	if p.MaxInterval <= 0 {
+		p.MaxInterval = 10 * time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	if p.Jitter < 0 {
+		p.Jitter = 0
+	}
+	if p.Jitter > 1 {
+		p.Jitter = 1
+	}
+	if p.Classify == nil {
+		p.Classify = Retryable
+	}
+	return p
+}
+
+// Backoff returns the wait after the given failed attempt (1-based),
+// including jitter.
+func (p Policy) Backoff(attempt int) time.Duration {
+	p = p.withDefaults()
+	wait := float64(p.InitialInterval) * math.Pow(p.Multiplier, float64(attempt-1))
+	if wait > float64(p.MaxInterval) {
+		wait = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		wait *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(wait)
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, or the policy
+// is exhausted, and returns the last error. If ctx ends while waiting, the
+// result wraps both ctx.Err() and the last error.
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	p = p.withDefaults()
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if stderrors.As(err, &permanent) {
+			return permanent.err
+		}
+		if attempt >= p.MaxAttempts || !p.Classify(err) || ctx.Err() != nil {
+			return err
+		}
+
+		wait := p.Backoff(attempt)
+		if hint, ok := afterHint(err); ok && hint > wait {
+			wait = hint
+		}
+		if p.MaxElapsed > 0 && time.Since(start)+wait > p.MaxElapsed {
+			return err
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, wait)
+		}
+		if werr := sleep(ctx, wait); werr != nil {
+			return stderrors.Join(werr, err)
+		}
+	}
+}
+
+// Do runs fn with policy.
+func Do(ctx context.Context, policy Policy, fn func(ctx context.Context) error) error {
+	return policy.Do(ctx, fn)
+}
+
+// DoValue runs fn with policy and returns its result from the successful
+// call.
+func DoValue[T any](ctx context.Context, policy Policy, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := policy.Do(ctx, func(ctx context.Context) error {
+		v, err := fn(ctx)
+		if err == nil {
+			result = v
+		}
+		return err
+	})
+	return result, err
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Retryable is the default classifier. Context cancellation is never
+// retried. AppErrors are retried by type: internal, external, database,
+// timeout and rate-limit errors are transient; validation, auth, not-found,
+// conflict and business errors are not. Other errors are retried.
+func Retryable(err error) bool {
+	if stderrors.Is(err, context.Canceled) || stderrors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var appErr *errors.AppError
+	if !stderrors.As(err, &appErr) {
+		return true
+	}
+	return retryableTypes[appErr.Type]
+}
+
+var retryableTypes = map[errors.ErrorType]bool{
+	errors.ErrorTypeInternal:  true,
+	errors.ErrorTypeExternal:  true,
+	errors.ErrorTypeDatabase:  true,
+	errors.ErrorTypeTimeout:   true,
+	errors.ErrorTypeRateLimit: true,
+}
+
+// OnTypes returns a classifier retrying AppErrors of the given types only.
+// Errors that are not AppErrors are not retried.
+func OnTypes(types ...errors.ErrorType) func(error) bool {
+	set := make(map[errors.ErrorType]bool, len(types))
+	for _, t := range types {
+		set[t] = true
+	}
+	return func(err error) bool {
+		var appErr *errors.AppError
+		return stderrors.As(err, &appErr) && set[appErr.Type]
+	}
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not retryable regardless of the classifier. Do
+// returns err unwrapped.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err is or wraps an error marked by Permanent,
+// for callers that classify errors themselves, such as job handlers.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return stderrors.As(err, &permanent)
+}
+
+type afterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *afterError) Error() string { return e.err.Error() }
+func (e *afterError) Unwrap() error { return e.err }
+
+// After annotates err with a minimum wait before the next attempt, e.g. from
+// a Retry-After header. The classifier still decides whether to retry.
+func After(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &afterError{err: err, after: d}
+}
+
+func afterHint(err error) (time.Duration, bool) {
+	var after *afterError
+	if stderrors.As(err, &after) {
+		return after.after, true
+	}
+	return 0, false
+}
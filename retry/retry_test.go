@@ -0,0 +1,147 @@
+package retry
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/errors"
+)
+
+func fastPolicy() Policy {
+	return Policy{MaxAttempts: 4, InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond}
+}
+
+func TestDoRetriesUntilSuccess(t *testing.T) {
+	var waits []time.Duration
+	p := fastPolicy()
+	p.OnRetry = func(attempt int, err error, wait time.Duration) { waits = append(waits, wait) }
+
+	calls := 0
+	got, err := DoValue(context.Background(), p, func(context.Context) (string, error) {
+		calls++
+		if calls < 3 {
+			return "", errors.NewExternal("upstream down")
+		}
+		return "ok", nil
+	})
+	if err != nil || got != "ok" || calls != 3 {
+		t.Fatalf("got %q, %v after %d calls", got, err, calls)
+	}
+	if len(waits) != 2 || waits[0] != time.Millisecond || waits[1] != 2*time.Millisecond {
+		t.Fatalf("waits = %v, want [1ms 2ms]", waits)
+	}
+}
+
+func TestDoStopsOnNonRetryable(t *testing.T) {
+	calls := 0
+	want := errors.NewInvalid("email", "x", "bad format")
+	err := Do(context.Background(), fastPolicy(), func(context.Context) error {
+		calls++
+		return want
+	})
+	if err != want || calls != 1 {
+		t.Fatalf("err = %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = Do(context.Background(), fastPolicy(), func(context.Context) error {
+		calls++
+		return Permanent(stderrors.New("gone"))
+	})
+	if err == nil || err.Error() != "gone" || calls != 1 {
+		t.Fatalf("permanent: err = %v after %d calls", err, calls)
+	}
+
+	if !IsPermanent(fmt.Errorf("wrapped: %w", Permanent(want))) || IsPermanent(want) || IsPermanent(nil) {
+		t.Error("IsPermanent misclassifies")
+	}
+}
+
+func TestDoExhaustsAttempts(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), fastPolicy(), func(context.Context) error {
+		calls++
+		return errors.NewTimeout("slow")
+	})
+	if err == nil || calls != 4 {
+		t.Fatalf("err = %v after %d calls", err, calls)
+	}
+}
+
+func TestDoCancelledWhileWaiting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Policy{MaxAttempts: 5, InitialInterval: time.Hour}
+	p.OnRetry = func(int, error, time.Duration) { cancel() }
+
+	last := errors.NewExternal("down")
+	start := time.Now()
+	err := p.Do(ctx, func(context.Context) error { return last })
+	if !stderrors.Is(err, context.Canceled) || !stderrors.Is(err, last) {
+		t.Fatalf("err = %v, want canceled and last error", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("cancellation did not interrupt the wait")
+	}
+}
+
+func TestDoMaxElapsed(t *testing.T) {
+	p := Policy{MaxAttempts: 100, InitialInterval: 20 * time.Millisecond, Multiplier: 1, MaxElapsed: 50 * time.Millisecond}
+	calls := 0
+	err := p.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errors.NewInternal("boom")
+	})
+	if err == nil || calls < 2 || calls > 3 {
+		t.Fatalf("err = %v after %d calls, want 2-3", err, calls)
+	}
+}
+
+func TestAfterHintExtendsWait(t *testing.T) {
+	var wait time.Duration
+	p := fastPolicy()
+	p.MaxAttempts = 2
+	p.OnRetry = func(_ int, _ error, w time.Duration) { wait = w }
+	_ = p.Do(context.Background(), func(context.Context) error {
+		return After(errors.NewRateLimit("slow down"), 15*time.Millisecond)
+	})
+	if wait != 15*time.Millisecond {
+		t.Fatalf("wait = %v, want 15ms", wait)
+	}
+}
+
+func TestBackoffJitterAndCap(t *testing.T) {
+	p := Policy{InitialInterval: 100 * time.Millisecond, MaxInterval: time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if d := p.Backoff(1); d < 50*time.Millisecond || d > 150*time.Millisecond {
+			t.Fatalf("Backoff(1) = %v outside ±50%%", d)
+		}
+		if d := p.Backoff(10); d > 1500*time.Millisecond {
+			t.Fatalf("Backoff(10) = %v exceeds cap with jitter", d)
+		}
+	}
+}
+
+func TestClassifiers(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{errors.NewTimeout("t"), true},
+		{errors.NewNotFound("user", 1), false},
+		{stderrors.New("connection reset"), true},
+		{context.Canceled, false},
+	}
+	for _, c := range cases {
+		if got := Retryable(c.err); got != c.want {
+			t.Errorf("Retryable(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+
+	onlyDB := OnTypes(errors.ErrorTypeDatabase)
+	if !onlyDB(errors.NewDatabase("deadlock")) || onlyDB(errors.NewTimeout("t")) || onlyDB(stderrors.New("x")) {
+		t.Error("OnTypes classified incorrectly")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/leeforge/framework/plugin"
+	"github.com/leeforge/framework/tracing"
 	"go.uber.org/zap"
 )
 
@@ -18,13 +19,16 @@ type eventBus struct {
 	wg          sync.WaitGroup
 	closed      atomic.Bool
 	logger      *zap.Logger
+	tracer      *tracing.Tracer
 	nextID      atomic.Uint64
 	done        chan struct{} // signals dispatcher goroutine to stop
+	stopped     chan struct{} // closed when the dispatcher goroutine returns
 }
 
 type eventEnvelope struct {
-	ctx   context.Context
-	event plugin.Event
+	ctx      context.Context
+	event    plugin.Event
+	producer tracing.Link
 }
 
 type subscriberEntry struct {
@@ -59,6 +63,7 @@ func NewEventBus(bufferSize int, logger *zap.Logger) *eventBus {
 		ch:          make(chan eventEnvelope, bufferSize),
 		logger:      logger,
 		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
 	}
 
 	go bus.dispatch()
@@ -66,6 +71,7 @@ func NewEventBus(bufferSize int, logger *zap.Logger) *eventBus {
 }
 
 func (b *eventBus) dispatch() {
+	defer close(b.stopped)
 	for {
 		select {
 		case env, ok := <-b.ch:
@@ -99,7 +105,20 @@ func (b *eventBus) fanOut(env eventEnvelope) {
 		b.wg.Add(1)
 		go func(h plugin.EventHandler) {
 			defer b.wg.Done()
-			if err := h(env.ctx, env.event); err != nil {
+			ctx := env.ctx
+			var span *tracing.Span
+			if b.tracer != nil {
+				ctx, span = b.tracer.StartConsumer(ctx, "event "+env.event.Name, env.producer,
+					tracing.WithAttributes(map[string]interface{}{
+						"event.name":   env.event.Name,
+						"event.source": env.event.Source,
+					}))
+			}
+			err := h(ctx, env.event)
+			if span != nil {
+				b.tracer.End(span, err)
+			}
+			if err != nil {
 				b.logger.Warn("event handler error",
 					zap.String("event", env.event.Name),
 					zap.Error(err))
@@ -119,6 +138,7 @@ func (b *eventBus) Publish(ctx context.Context, event plugin.Event) error {
 	}
 
 	env := eventEnvelope{ctx: ctx, event: event}
+	env.producer, _ = tracing.LinkFromContext(ctx)
 
 	select {
 	case b.ch <- env:
@@ -155,6 +175,7 @@ func (b *eventBus) Close() error {
 	}
 
 	close(b.done) // signal dispatcher to drain and stop
+	<-b.stopped   // no handlers start after this
 	b.wg.Wait()   // wait for in-flight handlers
 	return nil
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/leeforge/framework/plugin"
+	"github.com/leeforge/framework/tracing"
 	"go.uber.org/zap"
 )
 
@@ -143,3 +144,37 @@ func TestEventBus_ContextCancellation(t *testing.T) {
 		t.Fatalf("expected nil or ErrPublishTimeout, got %v", err)
 	}
 }
+
+type recordingProcessor struct {
+	spans chan *tracing.Span
+}
+
+func (p *recordingProcessor) OnEnd(span *tracing.Span)       { p.spans <- span }
+func (p *recordingProcessor) Shutdown(context.Context) error { return nil }
+
+func TestEventBus_ConsumerSpanLinksPublisher(t *testing.T) {
+	rec := &recordingProcessor{spans: make(chan *tracing.Span, 1)}
+	tracer, _ := tracing.NewTracer(tracing.TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: rec})
+	bus := NewEventBus(1024, zap.NewNop())
+	bus.tracer = tracer
+	defer bus.Close()
+
+	bus.Subscribe("evt", func(ctx context.Context, e plugin.Event) error { return nil })
+
+	ctx, producer := tracer.Start(context.Background(), "http.request")
+	if err := bus.Publish(ctx, plugin.Event{Name: "evt"}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	select {
+	case span := <-rec.spans:
+		if span.Kind != tracing.SpanKindConsumer || span.Name != "event evt" {
+			t.Fatalf("unexpected consumer span %q kind %v", span.Name, span.Kind)
+		}
+		if len(span.Links) != 1 || span.Links[0].SpanID != producer.SpanID {
+			t.Fatalf("links = %+v, want link to %s", span.Links, producer.SpanID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("consumer span not recorded")
+	}
+}
@@ -0,0 +1,177 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"entgo.io/ent/dialect"
+)
+
+// DefaultLockTimeout bounds how long a run waits for a concurrent run to finish.
+const DefaultLockTimeout = time.Minute
+
+// lockTable holds SQLite migration locks; other dialects use advisory locks.
+const lockTable = "schema_migration_locks"
+
+const lockPollInterval = 200 * time.Millisecond
+
+// lockLease is how long a SQLite lock row stays valid without renewal. The
+// holder renews it every lockLease/3, so only rows of crashed runs expire.
+var lockLease = time.Minute
+
+// ErrLocked is returned when another process holds the migration lock for
+// longer than the lock timeout.
+var ErrLocked = errors.New("migration: lock held by another run")
+
+// acquireLock takes a cross-process lock named name, polling until timeout.
+// PostgreSQL uses pg_try_advisory_lock and MySQL GET_LOCK on a dedicated
+// connection, so the lock is dropped if the process dies. SQLite has no
+// advisory locks and inserts a row into schema_migration_locks instead,
+// renewing it while held; a row older than lockLease was left by a crashed
+// run and is replaced.
+func acquireLock(ctx context.Context, db *sql.DB, driver, name string, timeout time.Duration) (release func(), err error) {
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	var (
+		try    func(context.Context) (bool, error)
+		unlock func(context.Context) error
+		renew  func(context.Context) error
+		conn   *sql.Conn
+	)
+	switch driver {
+	case dialect.Postgres:
+		h := fnv.New64a()
+		h.Write([]byte(name))
+		key := int64(h.Sum64())
+		if conn, err = db.Conn(ctx); err != nil {
+			return nil, err
+		}
+		try = func(ctx context.Context) (ok bool, err error) {
+			err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&ok)
+			return ok, err
+		}
+		unlock = func(ctx context.Context) error {
+			_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", key)
+			return err
+		}
+	case dialect.MySQL:
+		if conn, err = db.Conn(ctx); err != nil {
+			return nil, err
+		}
+		try = func(ctx context.Context) (bool, error) {
+			var got sql.NullInt64
+			err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&got)
+			return got.Valid && got.Int64 == 1, err
+		}
+		unlock = func(ctx context.Context) error {
+			_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", name)
+			return err
+		}
+	default:
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	name VARCHAR(191) NOT NULL PRIMARY KEY,
+	locked_at TIMESTAMP NOT NULL
+)`, lockTable)); err != nil {
+			return nil, fmt.Errorf("create migration lock table: %w", err)
+		}
+		try = func(ctx context.Context) (bool, error) {
+			now := time.Now().UTC()
+			if _, err := db.ExecContext(ctx,
+				fmt.Sprintf("DELETE FROM %s WHERE name = ? AND locked_at < ?", lockTable),
+				name, now.Add(-lockLease)); err != nil {
+				return false, err
+			}
+			res, err := db.ExecContext(ctx,
+				fmt.Sprintf("INSERT INTO %s (name, locked_at) SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM %s WHERE name = ?)", lockTable, lockTable),
+				name, now, name)
+			if err != nil {
+				return false, err
+			}
+			n, err := res.RowsAffected()
+			return n == 1, err
+		}
+		unlock = func(ctx context.Context) error {
+			return forceUnlock(ctx, db, name)
+		}
+		renew = func(ctx context.Context) error {
+			_, err := db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET locked_at = ? WHERE name = ?", lockTable), time.Now().UTC(), name)
+			return err
+		}
+	}
+
+	for {
+		ok, err := try(ctx)
+		if err != nil {
+			if conn != nil {
+				conn.Close()
+			}
+			return nil, fmt.Errorf("acquire migration lock: %w", err)
+		}
+		if ok {
+			stopRenewing := func() {}
+			if renew != nil {
+				stopRenewing = renewLock(context.WithoutCancel(ctx), renew)
+			}
+			return func() {
+				stopRenewing()
+				// release even if the run's context was cancelled
+				unlock(context.WithoutCancel(ctx))
+				if conn != nil {
+					conn.Close()
+				}
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			if conn != nil {
+				conn.Close()
+			}
+			return nil, fmt.Errorf("%w: %s", ErrLocked, name)
+		}
+		select {
+		case <-ctx.Done():
+			if conn != nil {
+				conn.Close()
+			}
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// renewLock calls renew every lockLease/3 until the returned func is called.
+func renewLock(ctx context.Context, renew func(context.Context) error) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(lockLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				// a failed renewal is retried on the next tick; the row only
+				// expires after a full lease
+				_ = renew(ctx)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// forceUnlock removes the SQLite lock row of name.
+func forceUnlock(ctx context.Context, db *sql.DB, name string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE name = ?", lockTable), name)
+	return err
+}
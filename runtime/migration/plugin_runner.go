@@ -0,0 +1,84 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+
+	"github.com/leeforge/framework/plugin"
+)
+
+// DefaultPluginTable stores the applied migration versions of every plugin.
+const DefaultPluginTable = "plugin_schema_migrations"
+
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// PluginRunner applies plugin migrations and tracks applied versions per plugin.
+type PluginRunner struct {
+	db      *sql.DB
+	dialect string
+	table   string
+}
+
+// PluginRunnerOption configures a PluginRunner.
+type PluginRunnerOption func(*PluginRunner)
+
+// WithTable overrides the version tracking table name.
+func WithTable(table string) PluginRunnerOption {
+	return func(r *PluginRunner) {
+		r.table = table
+	}
+}
+
+// NewPluginRunner creates a runner for the given database and ent dialect
+// (dialect.Postgres, dialect.MySQL or dialect.SQLite).
+func NewPluginRunner(db *sql.DB, driver string, opts ...PluginRunnerOption) (*PluginRunner, error) {
+	r := &PluginRunner{db: db, dialect: driver, table: DefaultPluginTable}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if err := validateRunner(db, driver, r.table); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Apply runs every migration of pluginName that has not been applied yet,
+// in version order. Each migration and its version record commit together
+// unless the migration sets DisableTx. Concurrent Apply calls, also from other
+// processes, are serialized by the migration lock.
+func (r *PluginRunner) Apply(ctx context.Context, pluginName string, migrations []plugin.Migration) error {
+	if len(migrations) == 0 {
+		return nil
+	}
+	_, err := r.Runner(pluginName).Up(ctx, migrations)
+	return err
+}
+
+// Runner returns a Runner scoped to pluginName's versions, for reverting
+// (Down), inspecting (Status) or dry-running a plugin's migrations.
+func (r *PluginRunner) Runner(pluginName string, opts ...RunnerOption) *Runner {
+	runner := &Runner{db: r.db, dialect: r.dialect, lockTimeout: DefaultLockTimeout}
+	for _, opt := range opts {
+		opt(runner)
+	}
+	runner.table, runner.plugin = r.table, pluginName
+	return runner
+}
+
+// AppliedVersions returns the versions already applied for a plugin, in order.
+func (r *PluginRunner) AppliedVersions(ctx context.Context, pluginName string) ([]string, error) {
+	runner := r.Runner(pluginName)
+	if err := runner.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := runner.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, len(applied))
+	for i, v := range applied {
+		versions[i] = v.version
+	}
+	return versions, nil
+}
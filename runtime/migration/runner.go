@@ -0,0 +1,496 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"github.com/leeforge/framework/plugin"
+)
+
+// DefaultVersionTable stores the applied versions of application migrations.
+const DefaultVersionTable = "schema_version"
+
+// ErrIrreversible is returned by Down when a migration to revert has no Down func.
+var ErrIrreversible = errors.New("migration: migration has no Down")
+
+// Runner applies, reverts and reports versioned migrations (SQL scripts or Go
+// funcs, see plugin.Migration) against a version table. Up and Down hold a
+// database lock for the whole run, so several instances starting at once
+// apply each migration exactly once.
+type Runner struct {
+	db          *sql.DB
+	dialect     string
+	table       string
+	plugin      string
+	lockTimeout time.Duration
+	dryRun      io.Writer
+}
+
+// RunnerOption configures a Runner.
+type RunnerOption func(*Runner)
+
+// WithVersionTable overrides the version table name.
+func WithVersionTable(table string) RunnerOption {
+	return func(r *Runner) {
+		r.table = table
+	}
+}
+
+// WithLockTimeout sets how long Up and Down wait for a concurrent run to
+// release the lock before failing with ErrLocked.
+func WithLockTimeout(d time.Duration) RunnerOption {
+	return func(r *Runner) {
+		r.lockTimeout = d
+	}
+}
+
+// WithDryRun makes Up and Down write the SQL they would run to w instead of
+// executing it. Go migrations cannot be rendered and are listed as comments.
+func WithDryRun(w io.Writer) RunnerOption {
+	return func(r *Runner) {
+		r.dryRun = w
+	}
+}
+
+// Status describes one migration version.
+type Status struct {
+	Version     string
+	Description string
+	Applied     bool
+	AppliedAt   time.Time
+	// Orphaned marks a version recorded as applied that has no migration in
+	// the given set, e.g. after a migration file was removed.
+	Orphaned bool
+}
+
+// NewRunner creates a runner for the given database and ent dialect
+// (dialect.Postgres, dialect.MySQL or dialect.SQLite).
+func NewRunner(db *sql.DB, driver string, opts ...RunnerOption) (*Runner, error) {
+	r := &Runner{db: db, dialect: driver, table: DefaultVersionTable, lockTimeout: DefaultLockTimeout}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if err := validateRunner(db, driver, r.table); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func validateRunner(db *sql.DB, driver, table string) error {
+	if db == nil {
+		return fmt.Errorf("migration runner requires a database")
+	}
+	switch driver {
+	case dialect.Postgres, dialect.MySQL, dialect.SQLite:
+	default:
+		return fmt.Errorf("unsupported dialect %q", driver)
+	}
+	if !tableNamePattern.MatchString(table) {
+		return fmt.Errorf("invalid migration table name %q", table)
+	}
+	return nil
+}
+
+// Up applies every pending migration in version order and returns the
+// versions applied (or, in dry-run mode, that would be applied). Each
+// migration and its version record commit together unless it sets DisableTx.
+func (r *Runner) Up(ctx context.Context, migrations []plugin.Migration) ([]string, error) {
+	sorted, err := r.sortMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+	release, err := r.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	applied, err := r.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[string]bool, len(applied))
+	for _, v := range applied {
+		done[v.version] = true
+	}
+
+	var versions []string
+	for _, m := range sorted {
+		if done[m.Version] {
+			continue
+		}
+		if r.dryRun != nil {
+			r.printStep("up", m, m.UpSQL, m.Up != nil)
+			r.printSQL(r.recordSQL(m))
+		} else if err := r.run(ctx, m, m.Up, r.record); err != nil {
+			return versions, fmt.Errorf("%smigration %q: %w", r.prefix(), m.Version, err)
+		}
+		versions = append(versions, m.Version)
+	}
+	return versions, nil
+}
+
+// Down reverts the last steps applied migrations (at least one), newest
+// first, and returns the reverted versions. It fails with ErrIrreversible
+// before touching the database if one of them has no Down func.
+func (r *Runner) Down(ctx context.Context, migrations []plugin.Migration, steps int) ([]string, error) {
+	sorted, err := r.sortMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[string]plugin.Migration, len(sorted))
+	for _, m := range sorted {
+		byVersion[m.Version] = m
+	}
+	release, err := r.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	applied, err := r.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	steps = min(max(steps, 1), len(applied))
+	targets := make([]plugin.Migration, 0, steps)
+	for i := len(applied) - 1; i >= len(applied)-steps; i-- {
+		m, ok := byVersion[applied[i].version]
+		if !ok {
+			return nil, fmt.Errorf("%sapplied version %q has no migration", r.prefix(), applied[i].version)
+		}
+		if m.Down == nil {
+			return nil, fmt.Errorf("%s%w: %s", r.prefix(), ErrIrreversible, m.Version)
+		}
+		targets = append(targets, m)
+	}
+
+	var versions []string
+	for _, m := range targets {
+		if r.dryRun != nil {
+			r.printStep("down", m, m.DownSQL, true)
+			r.printSQL(r.deleteSQL(m.Version))
+		} else if err := r.run(ctx, m, m.Down, r.unrecord); err != nil {
+			return versions, fmt.Errorf("%srevert migration %q: %w", r.prefix(), m.Version, err)
+		}
+		versions = append(versions, m.Version)
+	}
+	return versions, nil
+}
+
+// Status reports every migration and every applied version, in version order.
+func (r *Runner) Status(ctx context.Context, migrations []plugin.Migration) ([]Status, error) {
+	sorted, err := r.sortMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := r.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(sorted))
+	index := make(map[string]int, len(sorted))
+	for _, m := range sorted {
+		index[m.Version] = len(statuses)
+		statuses = append(statuses, Status{Version: m.Version, Description: m.Description})
+	}
+	for _, v := range applied {
+		i, ok := index[v.version]
+		if !ok {
+			statuses = append(statuses, Status{Version: v.version, Orphaned: true})
+			i = len(statuses) - 1
+		}
+		statuses[i].Applied = true
+		statuses[i].AppliedAt = v.appliedAt
+	}
+	sort.SliceStable(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Command runs a migrate CLI command and writes its report to out:
+//
+//	up          apply all pending migrations
+//	down [N]    revert the last N applied migrations (default 1)
+//	status      list versions and whether they are applied
+func (r *Runner) Command(ctx context.Context, out io.Writer, migrations []plugin.Migration, args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migration command required: up, down [N] or status")
+	}
+	switch args[0] {
+	case "up":
+		versions, err := r.Up(ctx, migrations)
+		r.report(out, "applied", versions)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid down steps %q", args[1])
+			}
+			steps = n
+		}
+		versions, err := r.Down(ctx, migrations, steps)
+		r.report(out, "reverted", versions)
+		return err
+	case "status":
+		statuses, err := r.Status(ctx, migrations)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tDESCRIPTION")
+		for _, s := range statuses {
+			state, at := "pending", ""
+			if s.Applied {
+				state, at = "applied", s.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			if s.Orphaned {
+				state = "orphaned"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Version, state, at, s.Description)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migration command %q", args[0])
+	}
+}
+
+func (r *Runner) report(out io.Writer, verb string, versions []string) {
+	if r.dryRun != nil {
+		return
+	}
+	if len(versions) == 0 {
+		fmt.Fprintln(out, "no migrations to run")
+	}
+	for _, v := range versions {
+		fmt.Fprintf(out, "%s %s\n", verb, v)
+	}
+}
+
+// begin takes the migration lock and makes sure the version table exists.
+// Dry runs neither lock nor create anything.
+func (r *Runner) begin(ctx context.Context) (func(), error) {
+	if r.dryRun != nil {
+		return func() {}, nil
+	}
+	release, err := acquireLock(ctx, r.db, r.dialect, "migrate:"+r.table, r.lockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ensureTable(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+func (r *Runner) sortMigrations(migrations []plugin.Migration) ([]plugin.Migration, error) {
+	sorted := append([]plugin.Migration{}, migrations...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Version == "" || m.Up == nil {
+			return nil, fmt.Errorf("%smigration #%d has no version or Up func", r.prefix(), i)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("%sduplicate migration version %q", r.prefix(), m.Version)
+		}
+	}
+	return sorted, nil
+}
+
+type appliedVersion struct {
+	version   string
+	appliedAt time.Time
+}
+
+// appliedVersions loads the applied versions in order. A dry run against a
+// database without the version table treats every migration as pending.
+func (r *Runner) appliedVersions(ctx context.Context) ([]appliedVersion, error) {
+	query := fmt.Sprintf("SELECT version, applied_at FROM %s", r.table)
+	var args []any
+	if r.plugin != "" {
+		query += " WHERE plugin = " + r.placeholder(1)
+		args = append(args, r.plugin)
+	}
+	rows, err := r.db.QueryContext(ctx, query+" ORDER BY version", args...)
+	if err != nil {
+		if r.dryRun != nil {
+			fmt.Fprintf(r.dryRun, "-- version table %s not readable, assuming no applied migrations: %v\n", r.table, err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("load applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var applied []appliedVersion
+	for rows.Next() {
+		var (
+			v  string
+			at sql.NullTime
+		)
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		applied = append(applied, appliedVersion{version: v, appliedAt: at.Time})
+	}
+	return applied, rows.Err()
+}
+
+// run executes step and then track (recording or removing the version),
+// in one transaction unless the migration sets DisableTx.
+func (r *Runner) run(ctx context.Context, m plugin.Migration,
+	step func(context.Context, plugin.MigrationExecutor) error,
+	track func(context.Context, plugin.MigrationExecutor, plugin.Migration) error,
+) error {
+	if m.DisableTx {
+		if err := step(ctx, r.db); err != nil {
+			return err
+		}
+		return track(ctx, r.db, m)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := step(ctx, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := track(ctx, tx, m); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Runner) record(ctx context.Context, exec plugin.MigrationExecutor, m plugin.Migration) error {
+	columns := []string{"version", "description", "applied_at"}
+	args := []any{m.Version, m.Description, time.Now().UTC()}
+	if r.plugin != "" {
+		columns = append([]string{"plugin"}, columns...)
+		args = append([]any{r.plugin}, args...)
+	}
+	placeholders := make([]string, len(columns))
+	for i := range placeholders {
+		placeholders[i] = r.placeholder(i + 1)
+	}
+	_, err := exec.ExecContext(ctx,
+		fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", r.table, strings.Join(columns, ", "), strings.Join(placeholders, ", ")),
+		args...)
+	if err != nil {
+		return fmt.Errorf("record migration version: %w", err)
+	}
+	return nil
+}
+
+func (r *Runner) unrecord(ctx context.Context, exec plugin.MigrationExecutor, m plugin.Migration) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE version = %s", r.table, r.placeholder(1))
+	args := []any{m.Version}
+	if r.plugin != "" {
+		query += " AND plugin = " + r.placeholder(2)
+		args = append(args, r.plugin)
+	}
+	if _, err := exec.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("remove migration version: %w", err)
+	}
+	return nil
+}
+
+func (r *Runner) ensureTable(ctx context.Context) error {
+	var ddl string
+	if r.plugin != "" {
+		ddl = `CREATE TABLE IF NOT EXISTS %s (
+	plugin VARCHAR(191) NOT NULL,
+	version VARCHAR(191) NOT NULL,
+	description TEXT,
+	applied_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (plugin, version)
+)`
+	} else {
+		ddl = `CREATE TABLE IF NOT EXISTS %s (
+	version VARCHAR(191) NOT NULL PRIMARY KEY,
+	description TEXT,
+	applied_at TIMESTAMP NOT NULL
+)`
+	}
+	if _, err := r.db.ExecContext(ctx, fmt.Sprintf(ddl, r.table)); err != nil {
+		return fmt.Errorf("create migration table: %w", err)
+	}
+	return nil
+}
+
+// printStep writes the header and script of a dry-run step.
+func (r *Runner) printStep(direction string, m plugin.Migration, script string, runnable bool) {
+	fmt.Fprintf(r.dryRun, "-- migrate %s %s", direction, m.Version)
+	if m.Description != "" {
+		fmt.Fprintf(r.dryRun, ": %s", m.Description)
+	}
+	fmt.Fprintln(r.dryRun)
+	if m.DisableTx {
+		fmt.Fprintln(r.dryRun, "-- runs outside a transaction")
+	}
+	switch script = strings.TrimSpace(script); {
+	case script != "":
+		r.printSQL(script)
+	case runnable:
+		fmt.Fprintln(r.dryRun, "-- Go migration, not rendered in dry run")
+	}
+}
+
+func (r *Runner) printSQL(stmt string) {
+	if !strings.HasSuffix(stmt, ";") {
+		stmt += ";"
+	}
+	fmt.Fprintln(r.dryRun, stmt)
+}
+
+// recordSQL renders the version record with literals for dry-run output.
+func (r *Runner) recordSQL(m plugin.Migration) string {
+	if r.plugin != "" {
+		return fmt.Sprintf("INSERT INTO %s (plugin, version, description, applied_at) VALUES (%s, %s, %s, CURRENT_TIMESTAMP)",
+			r.table, sqlLiteral(r.plugin), sqlLiteral(m.Version), sqlLiteral(m.Description))
+	}
+	return fmt.Sprintf("INSERT INTO %s (version, description, applied_at) VALUES (%s, %s, CURRENT_TIMESTAMP)",
+		r.table, sqlLiteral(m.Version), sqlLiteral(m.Description))
+}
+
+func (r *Runner) deleteSQL(version string) string {
+	stmt := fmt.Sprintf("DELETE FROM %s WHERE version = %s", r.table, sqlLiteral(version))
+	if r.plugin != "" {
+		stmt += " AND plugin = " + sqlLiteral(r.plugin)
+	}
+	return stmt
+}
+
+func sqlLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func (r *Runner) prefix() string {
+	if r.plugin == "" {
+		return ""
+	}
+	return fmt.Sprintf("plugin %q: ", r.plugin)
+}
+
+func (r *Runner) placeholder(n int) string {
+	if r.dialect == dialect.Postgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
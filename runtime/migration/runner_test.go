@@ -0,0 +1,213 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/plugin"
+	"github.com/stretchr/testify/require"
+
+	_ "modernc.org/sqlite"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:migration-"+uuid.NewString()+"?mode=memory&cache=shared&_pragma=foreign_keys(1)")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func testMigrations(t *testing.T) []plugin.Migration {
+	t.Helper()
+	ms, err := plugin.MigrationsFromFS(fstest.MapFS{
+		"m/0001_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")},
+		"m/0001_users.down.sql": {Data: []byte("DROP TABLE users")},
+		"m/0002_email.up.sql":   {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT")},
+		"m/0002_email.down.sql": {Data: []byte("ALTER TABLE users DROP COLUMN email")},
+	}, "m")
+	require.NoError(t, err)
+	seed := plugin.Migration{
+		Version: "0003_seed",
+		Up: func(ctx context.Context, exec plugin.MigrationExecutor) error {
+			_, err := exec.ExecContext(ctx, "INSERT INTO users (name, email) VALUES ('admin', 'admin@example.com')")
+			return err
+		},
+		Down: func(ctx context.Context, exec plugin.MigrationExecutor) error {
+			_, err := exec.ExecContext(ctx, "DELETE FROM users WHERE name = 'admin'")
+			return err
+		},
+	}
+	return append(ms, seed)
+}
+
+func TestRunner_UpStatusDown(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	ms := testMigrations(t)
+	r, err := NewRunner(db, dialect.SQLite)
+	require.NoError(t, err)
+
+	applied, err := r.Up(ctx, ms[:2])
+	require.NoError(t, err)
+	require.Equal(t, []string{"0001_users", "0002_email"}, applied)
+
+	statuses, err := r.Status(ctx, ms)
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	require.True(t, statuses[0].Applied)
+	require.False(t, statuses[0].AppliedAt.IsZero())
+	require.False(t, statuses[2].Applied)
+
+	applied, err = r.Up(ctx, ms)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0003_seed"}, applied)
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM users").Scan(&n))
+	require.Equal(t, 1, n)
+
+	reverted, err := r.Down(ctx, ms, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0003_seed", "0002_email"}, reverted)
+
+	var out bytes.Buffer
+	require.NoError(t, r.Command(ctx, &out, ms, "status"))
+	require.Contains(t, out.String(), "0001_users")
+	require.Regexp(t, `0002_email\s+pending`, out.String())
+
+	// an applied version without a migration is reported, and blocks Down
+	statuses, err = r.Status(ctx, ms[1:])
+	require.NoError(t, err)
+	require.True(t, statuses[0].Orphaned)
+	_, err = r.Down(ctx, ms[1:], 1)
+	require.ErrorContains(t, err, "has no migration")
+}
+
+func TestRunner_DownIrreversible(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewRunner(openTestDB(t), dialect.SQLite)
+	require.NoError(t, err)
+	ms := []plugin.Migration{plugin.SQLMigration("0001", "CREATE TABLE t (id INTEGER)")}
+
+	_, err = r.Up(ctx, ms)
+	require.NoError(t, err)
+	_, err = r.Down(ctx, ms, 1)
+	require.ErrorIs(t, err, ErrIrreversible)
+}
+
+func TestRunner_DryRun(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	ms := testMigrations(t)
+	var out bytes.Buffer
+	r, err := NewRunner(db, dialect.SQLite, WithDryRun(&out))
+	require.NoError(t, err)
+
+	versions, err := r.Up(ctx, ms)
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	script := out.String()
+	require.Contains(t, script, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);")
+	require.Contains(t, script, "INSERT INTO schema_version (version, description, applied_at) VALUES ('0002_email', '0002_email.up.sql', CURRENT_TIMESTAMP);")
+	require.Contains(t, script, "-- Go migration, not rendered in dry run")
+
+	var tables int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name IN ('users', 'schema_version')").Scan(&tables))
+	require.Zero(t, tables, "dry run must not touch the database")
+
+	live, err := NewRunner(db, dialect.SQLite)
+	require.NoError(t, err)
+	_, err = live.Up(ctx, ms[:1])
+	require.NoError(t, err)
+	out.Reset()
+	versions, err = r.Down(ctx, ms, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0001_users"}, versions)
+	require.Contains(t, out.String(), "DROP TABLE users;\nDELETE FROM schema_version WHERE version = '0001_users';")
+}
+
+func TestRunner_LockBlocksConcurrentRun(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	release, err := acquireLock(ctx, db, dialect.SQLite, "migrate:"+DefaultVersionTable, time.Second)
+	require.NoError(t, err)
+
+	r, err := NewRunner(db, dialect.SQLite, WithLockTimeout(300*time.Millisecond))
+	require.NoError(t, err)
+	_, err = r.Up(ctx, testMigrations(t))
+	require.ErrorIs(t, err, ErrLocked)
+
+	release()
+	applied, err := r.Up(ctx, testMigrations(t))
+	require.NoError(t, err)
+	require.Len(t, applied, 3)
+}
+
+func TestRunner_ReplacesStaleSQLiteLock(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	// a run that crashed two hours ago left its lock row behind
+	release, err := acquireLock(ctx, db, dialect.SQLite, "migrate:"+DefaultVersionTable, time.Second)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "UPDATE "+lockTable+" SET locked_at = ?", time.Now().UTC().Add(-2*time.Hour))
+	require.NoError(t, err)
+
+	r, err := NewRunner(db, dialect.SQLite, WithLockTimeout(300*time.Millisecond))
+	require.NoError(t, err)
+	applied, err := r.Up(ctx, testMigrations(t))
+	require.NoError(t, err)
+	require.Len(t, applied, 3)
+	release()
+}
+
+func TestRunner_RenewsSQLiteLockWhileHeld(t *testing.T) {
+	lease := lockLease
+	lockLease = 90 * time.Millisecond
+	t.Cleanup(func() { lockLease = lease })
+
+	ctx := context.Background()
+	db := openTestDB(t)
+	release, err := acquireLock(ctx, db, dialect.SQLite, "migrate:"+DefaultVersionTable, time.Second)
+	require.NoError(t, err)
+	defer release()
+
+	// held for several leases, the renewed row never looks stale
+	r, err := NewRunner(db, dialect.SQLite, WithLockTimeout(400*time.Millisecond))
+	require.NoError(t, err)
+	_, err = r.Up(ctx, testMigrations(t))
+	require.ErrorIs(t, err, ErrLocked)
+}
+
+func TestPluginRunner_ScopedRunner(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	pr, err := NewPluginRunner(db, dialect.SQLite)
+	require.NoError(t, err)
+
+	billing := []plugin.Migration{plugin.SQLMigrationWithDown("0001", "CREATE TABLE invoices (id INTEGER)", "DROP TABLE invoices")}
+	users := []plugin.Migration{plugin.SQLMigration("0001", "CREATE TABLE accounts (id INTEGER)")}
+	require.NoError(t, pr.Apply(ctx, "billing", billing))
+	require.NoError(t, pr.Apply(ctx, "users", users))
+	require.NoError(t, pr.Apply(ctx, "billing", billing), "re-applying is a no-op")
+
+	reverted, err := pr.Runner("billing").Down(ctx, billing, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0001"}, reverted)
+
+	versions, err := pr.AppliedVersions(ctx, "billing")
+	require.NoError(t, err)
+	require.Empty(t, versions)
+	versions, err = pr.AppliedVersions(ctx, "users")
+	require.NoError(t, err)
+	require.Equal(t, []string{"0001"}, versions)
+
+	err = pr.Apply(ctx, "users", []plugin.Migration{{Version: "0002"}})
+	require.True(t, strings.HasPrefix(err.Error(), `plugin "users": `), err.Error())
+}
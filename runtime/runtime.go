@@ -4,13 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-redis/redis/v8"
+	"github.com/leeforge/framework/bus"
+	"github.com/leeforge/framework/config"
 	"github.com/leeforge/framework/plugin"
+	"github.com/leeforge/framework/tracing"
 	"go.uber.org/zap"
 )
 
@@ -21,6 +26,31 @@ type Config struct {
 	Redis       *redis.Client
 	Logger      *zap.Logger
 	EventBuffer int // default 1024
+	// Tracer, when set, starts a consumer span per event delivery linked to
+	// the publisher's span.
+	Tracer *tracing.Tracer
+	// Migrations applies MigrationPlugin migrations; nil skips that phase.
+	Migrations MigrationRunner
+	// Settings provides config sections for ConfigPlugin plugins (e.g. *config.Config).
+	// When nil, plugin configs are built from their defaults only.
+	Settings SectionBinder
+	// Bus receives the command and query handlers of bus.HandlerProvider
+	// plugins after Enable; nil skips that phase.
+	Bus *bus.Bus
+}
+
+// SectionBinder binds a named config section into a struct, applying defaults
+// and validation. *config.Config implements it.
+type SectionBinder interface {
+	BindSection(key string, target any) error
+}
+
+var _ SectionBinder = (*config.Config)(nil)
+
+// MigrationRunner applies a plugin's migrations and tracks applied versions.
+// migration.PluginRunner is the default SQL implementation.
+type MigrationRunner interface {
+	Apply(ctx context.Context, pluginName string, migrations []plugin.Migration) error
 }
 
 // Runtime manages plugin lifecycle with correct dependency ordering.
@@ -30,6 +60,9 @@ type Runtime struct {
 	redis  *redis.Client
 	logger *zap.Logger
 
+	migrations MigrationRunner
+	settings   SectionBinder
+
 	plugins      map[string]plugin.Plugin
 	pluginState  map[string]plugin.PluginState
 	pluginErrors map[string]error
@@ -39,11 +72,13 @@ type Runtime struct {
 	bootOrder  []string
 	appContext *plugin.AppContext
 	eventBus   *eventBus
+	commandBus *bus.Bus
 
 	shutdownCtx context.Context
 	shutdownFn  context.CancelFunc
 
 	healthChecks map[string]func(context.Context) error
+	httpMounts   map[string]string // plugin name -> base path
 }
 
 // NewRuntime creates a new runtime instance.
@@ -56,21 +91,26 @@ func NewRuntime(cfg Config) *Runtime {
 	}
 
 	shutdownCtx, shutdownFn := context.WithCancel(context.Background())
-	bus := NewEventBus(cfg.EventBuffer, cfg.Logger)
+	events := NewEventBus(cfg.EventBuffer, cfg.Logger)
+	events.tracer = cfg.Tracer
 
 	rt := &Runtime{
 		router:       cfg.Router,
 		db:           cfg.DB,
 		redis:        cfg.Redis,
 		logger:       cfg.Logger,
+		migrations:   cfg.Migrations,
+		settings:     cfg.Settings,
 		plugins:      make(map[string]plugin.Plugin),
 		pluginState:  make(map[string]plugin.PluginState),
 		pluginErrors: make(map[string]error),
 		pluginModels: make(map[string][]any),
-		eventBus:     bus,
+		eventBus:     events,
+		commandBus:   cfg.Bus,
 		shutdownCtx:  shutdownCtx,
 		shutdownFn:   shutdownFn,
 		healthChecks: make(map[string]func(context.Context) error),
+		httpMounts:   make(map[string]string),
 	}
 
 	rt.appContext = &plugin.AppContext{
@@ -80,7 +120,7 @@ func NewRuntime(cfg Config) *Runtime {
 		Logger:   cfg.Logger,
 		Services: plugin.NewServiceRegistry(),
 		Config:   plugin.EmptyConfig(),
-		Events:   bus,
+		Events:   events,
 	}
 
 	return rt
@@ -92,6 +132,12 @@ func (r *Runtime) Services() *plugin.ServiceRegistry {
 	return r.appContext.Services
 }
 
+// AppContext returns the context passed to plugin lifecycle methods, giving
+// hosts and test harnesses the same view of dependencies the plugins have.
+func (r *Runtime) AppContext() *plugin.AppContext {
+	return r.appContext
+}
+
 // Register adds a plugin. Must be called before Bootstrap.
 func (r *Runtime) Register(p plugin.Plugin) error {
 	r.mu.Lock()
@@ -123,7 +169,50 @@ func (r *Runtime) Bootstrap(ctx context.Context) error {
 	r.bootOrder = order
 	r.logger.Info("dependency resolution completed", zap.Strings("order", order))
 
-	// Phase 2: Install (only Installable plugins)
+	// Phase 2: Bind & validate plugin configuration (only ConfigPlugin plugins)
+	for _, name := range order {
+		p, ok := r.plugins[name].(plugin.ConfigPlugin)
+		if !ok {
+			continue
+		}
+		if err := r.bindPluginConfig(name, p); err != nil {
+			if abortErr := r.handlePluginError(name, fmt.Errorf("invalid configuration: %w", err)); abortErr != nil {
+				return abortErr
+			}
+		}
+	}
+
+	// Phase 3: Migrate (only MigrationPlugin plugins, requires a runner)
+	for _, name := range order {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("bootstrap canceled: %w", err)
+		}
+		if r.pluginState[name] == plugin.StateFailed {
+			continue
+		}
+		p, ok := r.plugins[name].(plugin.MigrationPlugin)
+		if !ok {
+			continue
+		}
+		if r.migrations == nil {
+			r.logger.Warn("plugin provides migrations but no migration runner is configured",
+				zap.String("plugin", name))
+			continue
+		}
+		if depErr := r.checkDependenciesHealthy(name); depErr != nil {
+			if abortErr := r.handlePluginError(name, depErr); abortErr != nil {
+				return abortErr
+			}
+			continue
+		}
+		if err := r.migrations.Apply(ctx, name, p.Migrations()); err != nil {
+			if abortErr := r.handlePluginError(name, fmt.Errorf("migration failed: %w", err)); abortErr != nil {
+				return abortErr
+			}
+		}
+	}
+
+	// Phase 4: Install (only Installable plugins)
 	for _, name := range order {
 		if err := ctx.Err(); err != nil {
 			return fmt.Errorf("bootstrap canceled: %w", err)
@@ -142,7 +231,7 @@ func (r *Runtime) Bootstrap(ctx context.Context) error {
 		r.pluginState[name] = plugin.StateInstalled
 	}
 
-	// Phase 3: Collect models (only ModelProvider plugins)
+	// Phase 5: Collect models (only ModelProvider plugins)
 	for _, name := range order {
 		if r.pluginState[name] == plugin.StateFailed {
 			continue
@@ -152,7 +241,7 @@ func (r *Runtime) Bootstrap(ctx context.Context) error {
 		}
 	}
 
-	// Phase 4: Enable (in dependency order)
+	// Phase 6: Enable (in dependency order)
 	for _, name := range order {
 		if err := ctx.Err(); err != nil {
 			return fmt.Errorf("bootstrap canceled: %w", err)
@@ -178,7 +267,7 @@ func (r *Runtime) Bootstrap(ctx context.Context) error {
 		r.pluginState[name] = plugin.StateEnabled
 	}
 
-	// Phase 5: Register routes & middleware
+	// Phase 7: Register routes & middleware
 	for _, name := range order {
 		if r.pluginState[name] != plugin.StateEnabled {
 			continue
@@ -189,9 +278,37 @@ func (r *Runtime) Bootstrap(ctx context.Context) error {
 		if p, ok := r.plugins[name].(plugin.MiddlewareProvider); ok {
 			p.RegisterMiddlewares(r.router)
 		}
+		if p, ok := r.plugins[name].(plugin.HTTPPlugin); ok {
+			if err := r.mountHTTPPlugin(name, p); err != nil {
+				if abortErr := r.handlePluginError(name, fmt.Errorf("mount routes failed: %w", err)); abortErr != nil {
+					return abortErr
+				}
+			}
+		}
 	}
 
-	// Phase 6: Subscribe events
+	// Phase 8: Register command/query handlers (only bus.HandlerProvider plugins, requires a bus)
+	for _, name := range order {
+		if r.pluginState[name] != plugin.StateEnabled {
+			continue
+		}
+		p, ok := r.plugins[name].(bus.HandlerProvider)
+		if !ok {
+			continue
+		}
+		if r.commandBus == nil {
+			r.logger.Warn("plugin provides bus handlers but no bus is configured",
+				zap.String("plugin", name))
+			continue
+		}
+		if err := p.RegisterHandlers(r.commandBus); err != nil {
+			if abortErr := r.handlePluginError(name, fmt.Errorf("register bus handlers failed: %w", err)); abortErr != nil {
+				return abortErr
+			}
+		}
+	}
+
+	// Phase 9: Subscribe events
 	for _, name := range order {
 		if r.pluginState[name] != plugin.StateEnabled {
 			continue
@@ -201,13 +318,15 @@ func (r *Runtime) Bootstrap(ctx context.Context) error {
 		}
 	}
 
-	// Phase 7: Register health checks
+	// Phase 10: Register health checks
 	for _, name := range order {
 		if r.pluginState[name] != plugin.StateEnabled {
 			continue
 		}
 		if p, ok := r.plugins[name].(plugin.HealthReporter); ok {
+			r.mu.Lock()
 			r.healthChecks[name] = p.HealthCheck
+			r.mu.Unlock()
 		}
 	}
 
@@ -260,6 +379,22 @@ func (r *Runtime) Publish(ctx context.Context, event plugin.Event) error {
 	return r.eventBus.Publish(ctx, event)
 }
 
+// Plugins returns the registered plugins in the order Bootstrap enables
+// them. It fails when a dependency is missing or circular.
+func (r *Runtime) Plugins() ([]plugin.Plugin, error) {
+	order, err := r.resolveDependencies()
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	plugins := make([]plugin.Plugin, 0, len(order))
+	for _, name := range order {
+		plugins = append(plugins, r.plugins[name])
+	}
+	return plugins, nil
+}
+
 // GetPluginState returns the state of a plugin by name.
 func (r *Runtime) GetPluginState(name string) (plugin.PluginState, bool) {
 	r.mu.RLock()
@@ -295,8 +430,92 @@ func (r *Runtime) GetPluginModels() map[string][]any {
 	return result
 }
 
+// HTTPMounts returns the base path each HTTPPlugin was mounted under.
+func (r *Runtime) HTTPMounts() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make(map[string]string, len(r.httpMounts))
+	for k, v := range r.httpMounts {
+		result[k] = v
+	}
+	return result
+}
+
+// HealthChecks returns the HealthCheck of every enabled HealthReporter
+// plugin, keyed by plugin name. It is populated by Bootstrap.
+func (r *Runtime) HealthChecks() map[string]func(context.Context) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make(map[string]func(context.Context) error, len(r.healthChecks))
+	for k, v := range r.healthChecks {
+		result[k] = v
+	}
+	return result
+}
+
 // --- Internal ---
 
+func (r *Runtime) bindPluginConfig(name string, p plugin.ConfigPlugin) error {
+	section := p.ConfigSection()
+	if section == "" {
+		section = "plugins." + name
+	}
+	target := p.ConfigTarget()
+	if r.settings == nil {
+		return config.DecodeSection(section, nil, target)
+	}
+	return r.settings.BindSection(section, target)
+}
+
+func (r *Runtime) mountHTTPPlugin(name string, p plugin.HTTPPlugin) (err error) {
+	if r.router == nil {
+		return errors.New("runtime has no router")
+	}
+
+	base := "/" + name
+	if bp, ok := p.(plugin.BasePathProvider); ok {
+		base = bp.BasePath()
+	}
+	base = "/" + strings.Trim(base, "/")
+
+	r.mu.RLock()
+	for other, mounted := range r.httpMounts {
+		if mounted == base {
+			r.mu.RUnlock()
+			return fmt.Errorf("base path %q already mounted by plugin %q", base, other)
+		}
+	}
+	r.mu.RUnlock()
+
+	var middlewares []func(http.Handler) http.Handler
+	if mp, ok := p.(plugin.RouteMiddlewareProvider); ok {
+		middlewares = mp.RouteMiddlewares()
+	}
+
+	// chi panics on conflicting mounts; surface that as a plugin error instead.
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+
+	register := func(sub chi.Router) {
+		sub.Use(middlewares...)
+		p.Routes(sub)
+	}
+	if base == "/" {
+		r.router.Group(register)
+	} else {
+		r.router.Route(base, register)
+	}
+
+	r.mu.Lock()
+	r.httpMounts[name] = base
+	r.mu.Unlock()
+	r.logger.Info("plugin routes mounted", zap.String("plugin", name), zap.String("base", base))
+	return nil
+}
+
 func (r *Runtime) resolveDependencies() ([]string, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -3,11 +3,16 @@ package runtime
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/leeforge/framework/bus"
+	"github.com/leeforge/framework/config"
 	"github.com/leeforge/framework/plugin"
 	"go.uber.org/zap"
 )
@@ -137,6 +142,30 @@ func TestRuntime_DependencyOrder(t *testing.T) {
 	}
 }
 
+func TestRuntime_PluginsInBootOrder(t *testing.T) {
+	rt := newTestRuntime()
+	rt.Register(&testPlugin{name: "b", deps: []string{"a"}})
+	rt.Register(&testPlugin{name: "c"})
+	rt.Register(&testPlugin{name: "a"})
+
+	plugins, err := rt.Plugins()
+	if err != nil {
+		t.Fatalf("Plugins failed: %v", err)
+	}
+	var names []string
+	for _, p := range plugins {
+		names = append(names, p.Name())
+	}
+	if strings.Join(names, ",") != "a,b,c" {
+		t.Errorf("plugins = %v, want [a b c]", names)
+	}
+
+	rt.Register(&testPlugin{name: "d", deps: []string{"missing"}})
+	if _, err := rt.Plugins(); err == nil {
+		t.Fatal("should report missing dependency")
+	}
+}
+
 func TestRuntime_CircularDependencyDetected(t *testing.T) {
 	rt := newTestRuntime()
 	rt.Register(&testPlugin{name: "x", deps: []string{"y"}})
@@ -328,3 +357,255 @@ func TestRuntime_EventsIntegration(t *testing.T) {
 		t.Error("event handler should have been called")
 	}
 }
+
+type testHTTPPlugin struct {
+	testPlugin
+	base        string
+	middlewares []func(http.Handler) http.Handler
+}
+
+func (p *testHTTPPlugin) Routes(r chi.Router) {
+	r.Get("/ping", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(p.name))
+	})
+}
+
+func (p *testHTTPPlugin) BasePath() string { return p.base }
+
+func (p *testHTTPPlugin) RouteMiddlewares() []func(http.Handler) http.Handler {
+	return p.middlewares
+}
+
+func TestRuntime_HTTPPluginMountedUnderBasePath(t *testing.T) {
+	router := chi.NewRouter()
+	rt := NewRuntime(Config{Router: router, Logger: zap.NewNop()})
+	defer rt.Shutdown(context.Background())
+
+	tagged := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("X-Plugin", "billing")
+			next.ServeHTTP(w, req)
+		})
+	}
+	rt.Register(&testHTTPPlugin{testPlugin: testPlugin{name: "billing"}, base: "/api/billing/", middlewares: []func(http.Handler) http.Handler{tagged}})
+	rt.Register(&testHTTPPlugin{testPlugin: testPlugin{name: "other"}, base: "/api/other"})
+
+	if err := rt.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/billing/ping", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "billing" {
+		t.Fatalf("billing ping = %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("X-Plugin") != "billing" {
+		t.Error("plugin middleware should wrap its own routes")
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/other/ping", nil))
+	if rec.Header().Get("X-Plugin") != "" {
+		t.Error("plugin middleware must not leak into other plugins")
+	}
+
+	if got := rt.HTTPMounts()["billing"]; got != "/api/billing" {
+		t.Errorf("mount = %q, want /api/billing", got)
+	}
+}
+
+func TestRuntime_HTTPPluginBasePathConflict(t *testing.T) {
+	rt := newTestRuntime()
+	defer rt.Shutdown(context.Background())
+
+	rt.Register(&testHTTPPlugin{testPlugin: testPlugin{name: "a"}, base: "/shared"})
+	rt.Register(&testHTTPPlugin{testPlugin: testPlugin{name: "b"}, base: "/shared"})
+
+	if err := rt.Bootstrap(context.Background()); err == nil {
+		t.Fatal("conflicting base paths should abort bootstrap")
+	}
+	if state, _ := rt.GetPluginState("b"); state != plugin.StateFailed {
+		t.Errorf("state = %v, want Failed", state)
+	}
+}
+
+func TestRuntime_HTTPMountsReadDuringBootstrap(t *testing.T) {
+	rt := newTestRuntime()
+	defer rt.Shutdown(context.Background())
+	for i := range 20 {
+		name := fmt.Sprintf("p%d", i)
+		rt.Register(&testHTTPPlugin{testPlugin: testPlugin{name: name}, base: "/" + name})
+	}
+
+	// Admin and health endpoints may read the mounts while plugins boot.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-t.Context().Done():
+				return
+			default:
+			}
+			if len(rt.HTTPMounts()) == 20 {
+				return
+			}
+		}
+	}()
+	if err := rt.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+	<-done
+}
+
+type testMigrationPlugin struct {
+	testPlugin
+	migrations []plugin.Migration
+}
+
+func (p *testMigrationPlugin) Migrations() []plugin.Migration { return p.migrations }
+
+type fakeMigrationRunner struct {
+	applied []string
+	failFor string
+}
+
+func (f *fakeMigrationRunner) Apply(_ context.Context, name string, migrations []plugin.Migration) error {
+	if name == f.failFor {
+		return fmt.Errorf("boom")
+	}
+	for _, m := range migrations {
+		f.applied = append(f.applied, name+":"+m.Version)
+	}
+	return nil
+}
+
+func TestRuntime_MigrationsAppliedInDependencyOrder(t *testing.T) {
+	runner := &fakeMigrationRunner{}
+	rt := NewRuntime(Config{Router: chi.NewRouter(), Logger: zap.NewNop(), Migrations: runner})
+	defer rt.Shutdown(context.Background())
+
+	var enabledAfterMigrate bool
+	billing := &testMigrationPlugin{
+		testPlugin: testPlugin{name: "billing", deps: []string{"users"}},
+		migrations: []plugin.Migration{{Version: "0001"}},
+	}
+	users := &testMigrationPlugin{
+		testPlugin: testPlugin{name: "users", enableFn: func(context.Context, *plugin.AppContext) error {
+			enabledAfterMigrate = len(runner.applied) == 3
+			return nil
+		}},
+		migrations: []plugin.Migration{{Version: "0001"}, {Version: "0002"}},
+	}
+	rt.Register(billing)
+	rt.Register(users)
+
+	if err := rt.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+	want := []string{"users:0001", "users:0002", "billing:0001"}
+	if fmt.Sprint(runner.applied) != fmt.Sprint(want) {
+		t.Errorf("applied = %v, want %v", runner.applied, want)
+	}
+	if !enabledAfterMigrate {
+		t.Error("migrations of all plugins should run before Enable")
+	}
+}
+
+func TestRuntime_MigrationFailureAbortsRequiredPlugin(t *testing.T) {
+	runner := &fakeMigrationRunner{failFor: "billing"}
+	rt := NewRuntime(Config{Router: chi.NewRouter(), Logger: zap.NewNop(), Migrations: runner})
+	defer rt.Shutdown(context.Background())
+
+	rt.Register(&testMigrationPlugin{testPlugin: testPlugin{name: "billing"}})
+
+	if err := rt.Bootstrap(context.Background()); err == nil {
+		t.Fatal("migration failure of a required plugin should abort bootstrap")
+	}
+	if state, _ := rt.GetPluginState("billing"); state != plugin.StateFailed {
+		t.Errorf("state = %v, want Failed", state)
+	}
+}
+
+type testConfigPlugin struct {
+	testPlugin
+	cfg struct {
+		Endpoint string `mapstructure:"endpoint" validate:"required,url"`
+		Workers  int    `mapstructure:"workers" default:"4"`
+	}
+}
+
+func (p *testConfigPlugin) ConfigSection() string { return "" }
+func (p *testConfigPlugin) ConfigTarget() any     { return &p.cfg }
+
+type mapSettings map[string]any
+
+func (m mapSettings) BindSection(key string, target any) error {
+	return config.DecodeSection(key, m[key], target)
+}
+
+func TestRuntime_ConfigPluginBoundBeforeEnable(t *testing.T) {
+	settings := mapSettings{"plugins.webhook": map[string]any{"endpoint": "https://example.com/hook"}}
+	rt := NewRuntime(Config{Router: chi.NewRouter(), Logger: zap.NewNop(), Settings: settings})
+	defer rt.Shutdown(context.Background())
+
+	p := &testConfigPlugin{}
+	var seen string
+	p.testPlugin = testPlugin{name: "webhook", enableFn: func(context.Context, *plugin.AppContext) error {
+		seen = p.cfg.Endpoint
+		return nil
+	}}
+	rt.Register(p)
+
+	if err := rt.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+	if seen != "https://example.com/hook" || p.cfg.Workers != 4 {
+		t.Errorf("config = %+v, want endpoint bound and default workers", p.cfg)
+	}
+}
+
+func TestRuntime_ConfigPluginValidationErrorNamesPluginAndField(t *testing.T) {
+	rt := newTestRuntime()
+	defer rt.Shutdown(context.Background())
+
+	rt.Register(&testConfigPlugin{testPlugin: testPlugin{name: "webhook"}})
+
+	err := rt.Bootstrap(context.Background())
+	if err == nil {
+		t.Fatal("missing required config should abort bootstrap")
+	}
+	for _, want := range []string{`"webhook"`, "endpoint is required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should mention %s", err, want)
+		}
+	}
+}
+
+type pingQuery struct{}
+
+type testHandlerPlugin struct {
+	testPlugin
+}
+
+func (p *testHandlerPlugin) RegisterHandlers(b *bus.Bus) error {
+	return bus.RegisterQuery(b, func(context.Context, pingQuery) (string, error) {
+		return p.name, nil
+	})
+}
+
+func TestRuntime_HandlerProviderRegistersOnBus(t *testing.T) {
+	b := bus.New()
+	rt := NewRuntime(Config{Router: chi.NewRouter(), Logger: zap.NewNop(), Bus: b})
+	defer rt.Shutdown(context.Background())
+
+	rt.Register(&testHandlerPlugin{testPlugin: testPlugin{name: "ping"}})
+	if err := rt.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+
+	got, err := bus.Ask[string](context.Background(), b, pingQuery{})
+	if err != nil || got != "ping" {
+		t.Errorf("Ask = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,63 @@
+package sanitize
+
+import (
+	"context"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// builtins are the modifiers available without Register:
+//
+//	trim         remove leading and trailing white space
+//	ltrim, rtrim remove leading or trailing white space
+//	lower, upper change case
+//	strip_ctrl   remove control characters except tab, newline and carriage return
+//	collapse     replace runs of white space with one space and trim
+//	escape       HTML-escape <, >, &, ' and "
+//	truncate=N   keep at most N characters (runes)
+//
+// Put truncate before escape, or it may cut an entity in half.
+var builtins = map[string]Func{
+	"trim":       simple(strings.TrimSpace),
+	"ltrim":      simple(func(s string) string { return strings.TrimLeftFunc(s, unicode.IsSpace) }),
+	"rtrim":      simple(func(s string) string { return strings.TrimRightFunc(s, unicode.IsSpace) }),
+	"lower":      simple(strings.ToLower),
+	"upper":      simple(strings.ToUpper),
+	"strip_ctrl": simple(stripControl),
+	"collapse":   simple(func(s string) string { return strings.Join(strings.Fields(s), " ") }),
+	"escape":     simple(html.EscapeString),
+	"truncate":   truncate,
+}
+
+func simple(fn func(string) string) Func {
+	return func(_ context.Context, s, _ string) string {
+		return fn(s)
+	}
+}
+
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// truncate keeps the first param runes of s. An invalid param panics, as
+// it is a mistake in the tag.
+func truncate(_ context.Context, s, param string) string {
+	n, err := strconv.Atoi(param)
+	if err != nil || n < 0 {
+		panic("sanitize: truncate needs a non-negative length, got " + strconv.Quote(param))
+	}
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
@@ -0,0 +1,209 @@
+// Package sanitize normalizes request structs according to their `mod`
+// tags, so handlers stop trimming and lower-casing input by hand. http/binding
+// applies it after binding and before validation, so validate rules see the
+// normalized values:
+//
+//	type SignupRequest struct {
+//		Email   string   `json:"email" mod:"trim,lower" validate:"required,email"`
+//		Name    string   `json:"name" mod:"strip_ctrl,collapse" validate:"required,max=50"`
+//		Bio     string   `json:"bio" mod:"trim,truncate=500,escape"`
+//		Tags    []string `json:"tags" mod:"trim,lower"`
+//		Address Address  `json:"address"` // nested structs are sanitized too
+//	}
+//
+// Modifiers run left to right on string fields, pointers to strings and
+// string slices. Custom modifiers are added with Register.
+package sanitize
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// TagName is the struct tag listing a field's modifiers.
+const TagName = "mod"
+
+// Func modifies s. param is the text after "=" in the tag, e.g. "500" for
+// truncate=500, or "".
+type Func func(ctx context.Context, s, param string) string
+
+var (
+	mu        sync.RWMutex
+	modifiers = map[string]Func{}
+	plans     sync.Map // reflect.Type -> *typePlan
+)
+
+func init() {
+	for name, fn := range builtins {
+		modifiers[name] = fn
+	}
+}
+
+// Register adds the modifier name, replacing a built-in one of the same
+// name. Register at startup, before the first struct using name is
+// sanitized.
+//
+//	sanitize.Register("digits", func(_ context.Context, s, _ string) string {
+//		return strings.Map(func(r rune) rune {
+//			if unicode.IsDigit(r) {
+//				return r
+//			}
+//			return -1
+//		}, s)
+//	})
+func Register(name string, fn Func) {
+	mu.Lock()
+	modifiers[name] = fn
+	mu.Unlock()
+}
+
+// Struct applies the `mod` tags of v, a pointer to a struct, in place.
+// Nested structs, pointers to them and slices of them are walked as well;
+// other values are left untouched. An unknown modifier is a programming
+// error and panics, like an unknown validate tag.
+func Struct(ctx context.Context, v any) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return
+	}
+	walk(ctx, rv)
+}
+
+// String applies the modifiers of a tag value, e.g. "trim,lower", to s.
+func String(ctx context.Context, s, tag string) string {
+	for _, m := range parse(tag, "") {
+		s = m.fn(ctx, s, m.param)
+	}
+	return s
+}
+
+// modifier is a resolved entry of a `mod` tag.
+type modifier struct {
+	fn    Func
+	param string
+}
+
+// fieldPlan lists what to do with one struct field.
+type fieldPlan struct {
+	index int
+	mods  []modifier
+	// walk is set when the field may hold structs to descend into.
+	walk bool
+}
+
+type typePlan struct {
+	fields []fieldPlan
+}
+
+func walk(ctx context.Context, rv reflect.Value) {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		for _, f := range planFor(rv.Type()).fields {
+			field := rv.Field(f.index)
+			if len(f.mods) > 0 {
+				apply(ctx, field, f.mods)
+			}
+			if f.walk {
+				walk(ctx, field)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			walk(ctx, rv.Index(i))
+		}
+	}
+}
+
+// apply runs mods on a string, a pointer to one, or each element of a
+// string slice.
+func apply(ctx context.Context, v reflect.Value, mods []modifier) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		if !v.CanSet() {
+			return
+		}
+		s := v.String()
+		for _, m := range mods {
+			s = m.fn(ctx, s, m.param)
+		}
+		v.SetString(s)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			apply(ctx, v.Index(i), mods)
+		}
+	}
+}
+
+// planFor returns the cached plan of the struct type t.
+func planFor(t reflect.Type) *typePlan {
+	if p, ok := plans.Load(t); ok {
+		return p.(*typePlan)
+	}
+	plan := &typePlan{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+		f := fieldPlan{index: i, walk: holdsStructs(sf.Type)}
+		if tag := sf.Tag.Get(TagName); tag != "" && tag != "-" {
+			f.mods = parse(tag, t.String()+"."+sf.Name)
+		}
+		if len(f.mods) > 0 || f.walk {
+			plan.fields = append(plan.fields, f)
+		}
+	}
+	p, _ := plans.LoadOrStore(t, plan)
+	return p.(*typePlan)
+}
+
+// holdsStructs reports whether values of t can contain structs to walk.
+func holdsStructs(t reflect.Type) bool {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		case reflect.Struct:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// parse resolves a tag value; where names the field in panics.
+func parse(tag, where string) []modifier {
+	mu.RLock()
+	defer mu.RUnlock()
+	var mods []modifier
+	for _, entry := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		if name == "" {
+			continue
+		}
+		fn, ok := modifiers[name]
+		if !ok {
+			if where == "" {
+				where = "tag " + tag
+			}
+			panic(fmt.Sprintf("sanitize: unknown modifier %q on %s", name, where))
+		}
+		mods = append(mods, modifier{fn: fn, param: param})
+	}
+	return mods
+}
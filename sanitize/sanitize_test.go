@@ -0,0 +1,99 @@
+package sanitize
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	City string `mod:"trim,collapse"`
+	Zip  string `mod:"upper"`
+}
+
+type signup struct {
+	Email    string   `mod:"trim,lower"`
+	Name     string   `mod:"strip_ctrl,collapse"`
+	Bio      string   `mod:"trim,truncate=12,escape"`
+	Nickname *string  `mod:"rtrim"`
+	Tags     []string `mod:"trim,lower"`
+	Raw      string
+	Skipped  string `mod:"-"`
+	Address  address
+	Previous *address
+	Others   []address
+	private  string `mod:"trim"`
+}
+
+func TestStruct(t *testing.T) {
+	nick := "  ann  "
+	in := signup{
+		Email:    "  Ann@Example.COM ",
+		Name:     "Ann\x00 \t Lee\n",
+		Bio:      "  <b>hi</b> there ",
+		Nickname: &nick,
+		Tags:     []string{" Go ", "RUST"},
+		Raw:      "  as is ",
+		Skipped:  " kept ",
+		Address:  address{City: "  New   York ", Zip: "ab1"},
+		Previous: &address{Zip: "cd2"},
+		Others:   []address{{City: " Paris "}},
+		private:  "  x ",
+	}
+	Struct(context.Background(), &in)
+
+	require.Equal(t, "ann@example.com", in.Email)
+	require.Equal(t, "Ann Lee", in.Name)
+	require.Equal(t, "&lt;b&gt;hi&lt;/b&gt; th", in.Bio)
+	require.Equal(t, "  ann", nick)
+	require.Equal(t, []string{"go", "rust"}, in.Tags)
+	require.Equal(t, "  as is ", in.Raw)
+	require.Equal(t, " kept ", in.Skipped)
+	require.Equal(t, address{City: "New York", Zip: "AB1"}, in.Address)
+	require.Equal(t, "CD2", in.Previous.Zip)
+	require.Equal(t, "Paris", in.Others[0].City)
+	require.Equal(t, "  x ", in.private)
+
+	// nil 指针与非结构体指针不处理
+	var empty *signup
+	Struct(context.Background(), empty)
+	s := " x "
+	Struct(context.Background(), &s)
+	require.Equal(t, " x ", s)
+}
+
+func TestRegisterAndString(t *testing.T) {
+	type tenantKey struct{}
+	Register("tenant_prefix", func(ctx context.Context, s, param string) string {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return tenant + param + s
+	})
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+
+	var in struct {
+		Key string `mod:"trim,tenant_prefix=:"`
+	}
+	in.Key = " order "
+	Struct(ctx, &in)
+	require.Equal(t, "acme:order", in.Key)
+
+	require.Equal(t, "héllo", String(ctx, "  HÉLLO WORLD ", "trim,lower,truncate=5"))
+}
+
+func TestUnknownModifierPanics(t *testing.T) {
+	var in struct {
+		Name string `mod:"trim,shout"`
+	}
+	require.PanicsWithValue(t, `sanitize: unknown modifier "shout" on struct { Name string "mod:\"trim,shout\"" }.Name`, func() {
+		Struct(context.Background(), &in)
+	})
+	require.Panics(t, func() { String(context.Background(), "x", "truncate=abc") })
+}
+
+func TestStripControl(t *testing.T) {
+	in := "a\x1b[31mb\u0085c\td\r\ne"
+	require.Equal(t, "a[31mbc\td\r\ne", String(context.Background(), in, "strip_ctrl"))
+	require.False(t, strings.ContainsRune(String(context.Background(), "x\x7fy", "strip_ctrl"), 0x7f))
+}
@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule computes activation times.
+type Schedule interface {
+	// Next returns the first activation strictly after t, or the zero time
+	// when the schedule is exhausted.
+	Next(t time.Time) time.Time
+}
+
+var cronParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// Cron parses a cron expression. Both the five-field form and a leading
+// seconds field are accepted, as are descriptors such as "@hourly" and
+// "@every 5m" and a "CRON_TZ=Asia/Shanghai " prefix. Without CRON_TZ the
+// expression is evaluated in the scheduler's Location.
+func Cron(expr string) (Schedule, error) {
+	s, err := cronParser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("scheduler: parse cron %q: %w", expr, err)
+	}
+	return s, nil
+}
+
+// MustCron is like Cron but panics on an invalid expression.
+func MustCron(expr string) Schedule {
+	s, err := Cron(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Every returns a fixed-interval schedule. Activations are aligned to
+// multiples of d since the zero time, so every instance computes the same
+// ticks, which distributed jobs rely on to agree on a run.
+func Every(d time.Duration) Schedule {
+	if d <= 0 {
+		panic("scheduler: non-positive interval")
+	}
+	return every(d)
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	d := time.Duration(e)
+	return t.Truncate(d).Add(d)
+}
@@ -0,0 +1,581 @@
+// Package scheduler runs recurring jobs on cron expressions or fixed
+// intervals, with per-job timeouts, overlap policies and, through the lock
+// package, single-run semantics across instances.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leeforge/framework/lock"
+	"github.com/leeforge/framework/metrics"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrDuplicateJob is returned by Add when a job with the same name exists.
+	ErrDuplicateJob = errors.New("scheduler: duplicate job")
+	// ErrUnknownJob is returned for names that were never added.
+	ErrUnknownJob = errors.New("scheduler: unknown job")
+	// ErrStopped is returned once Stop has been called.
+	ErrStopped = errors.New("scheduler: stopped")
+)
+
+// OverlapPolicy decides what happens when a job is due while its previous
+// run is still in progress.
+type OverlapPolicy string
+
+const (
+	// OverlapSkip records the new activation as skipped (default). For
+	// distributed jobs this also holds across instances.
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapAllow starts the new run concurrently.
+	OverlapAllow OverlapPolicy = "allow"
+	// OverlapQueue runs the new activation once the current run finishes.
+	// Activations that arrive while one is already queued are coalesced.
+	OverlapQueue OverlapPolicy = "queue"
+)
+
+// Status is the outcome of a run.
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusTimedOut  Status = "timed_out"
+	StatusSkipped   Status = "skipped"
+)
+
+// Trigger tells what started a run.
+type Trigger string
+
+const (
+	TriggerSchedule Trigger = "schedule"
+	TriggerManual   Trigger = "manual"
+)
+
+// Job is a recurring unit of work.
+type Job struct {
+	// Name identifies the job in history, metrics and lock keys.
+	Name     string
+	Schedule Schedule
+	// Run performs the work. It must return when ctx is done.
+	Run func(ctx context.Context) error
+	// Timeout bounds a single run; zero means no limit.
+	Timeout time.Duration
+	// Overlap defaults to OverlapSkip.
+	Overlap OverlapPolicy
+	// Distributed makes each activation run on only one instance. It
+	// requires Options.Locker, and all instances must share the job's name
+	// and schedule.
+	Distributed bool
+}
+
+// Run records one activation of a job.
+type Run struct {
+	ID          string        `json:"id"`
+	Job         string        `json:"job"`
+	Instance    string        `json:"instance"`
+	Trigger     Trigger       `json:"trigger"`
+	Status      Status        `json:"status"`
+	Reason      string        `json:"reason,omitempty"`
+	ScheduledAt time.Time     `json:"scheduled_at"`
+	StartedAt   time.Time     `json:"started_at,omitempty"`
+	FinishedAt  time.Time     `json:"finished_at,omitempty"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// JobStatus is a snapshot of a job's state.
+type JobStatus struct {
+	Name    string    `json:"name"`
+	Next    time.Time `json:"next,omitempty"`
+	Running int       `json:"running"`
+	LastRun *Run      `json:"last_run,omitempty"`
+}
+
+// Options configures a Scheduler.
+type Options struct {
+	// Locker coordinates distributed jobs across instances.
+	Locker lock.Locker
+	// KeyPrefix is prepended to lock keys (default "scheduler:").
+	KeyPrefix string
+	// LockTTL is the lease of the lock that keeps an OverlapSkip distributed
+	// job from running on two instances at once. It is refreshed while the
+	// run is in progress (default 30s).
+	LockTTL time.Duration
+	// Instance identifies this process in run history (default host-pid).
+	Instance string
+	// Location is used for schedules without an explicit time zone
+	// (default time.Local).
+	Location *time.Location
+	// HistorySize is the number of runs kept per job (default 20).
+	HistorySize int
+	// Metrics, when set, receives run counts, durations and running gauges.
+	Metrics *metrics.Collector
+	// Logger receives scheduler diagnostics (default no-op).
+	Logger *zap.Logger
+	// AuditLogger, when set, receives one entry per finished or skipped run.
+	AuditLogger *zap.Logger
+}
+
+// Scheduler triggers jobs according to their schedules.
+type Scheduler struct {
+	opts   Options
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	jobs    map[string]*entry
+	order   []string
+	started bool
+	stopped bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+type entry struct {
+	job Job
+
+	mu       sync.Mutex
+	next     time.Time
+	running  int
+	queued   bool
+	queuedAt time.Time
+	history  []Run
+}
+
+// New creates a scheduler. Call Start to begin triggering jobs.
+func New(opts Options) *Scheduler {
+	if opts.KeyPrefix == "" {
+		opts.KeyPrefix = "scheduler:"
+	}
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = 30 * time.Second
+	}
+	if opts.Instance == "" {
+		host, _ := os.Hostname()
+		opts.Instance = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	if opts.HistorySize <= 0 {
+		opts.HistorySize = 20
+	}
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		opts:   opts,
+		logger: opts.Logger,
+		jobs:   make(map[string]*entry),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Add registers a job. Jobs added after Start are scheduled immediately.
+func (s *Scheduler) Add(job Job) error {
+	switch {
+	case job.Name == "":
+		return errors.New("scheduler: job name is required")
+	case job.Schedule == nil:
+		return fmt.Errorf("scheduler: job %s has no schedule", job.Name)
+	case job.Run == nil:
+		return fmt.Errorf("scheduler: job %s has no run function", job.Name)
+	case job.Distributed && s.opts.Locker == nil:
+		return fmt.Errorf("scheduler: distributed job %s requires a Locker", job.Name)
+	}
+	switch job.Overlap {
+	case "":
+		job.Overlap = OverlapSkip
+	case OverlapSkip, OverlapAllow, OverlapQueue:
+	default:
+		return fmt.Errorf("scheduler: job %s: unknown overlap policy %q", job.Name, job.Overlap)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return ErrStopped
+	}
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateJob, job.Name)
+	}
+	e := &entry{job: job}
+	s.jobs[job.Name] = e
+	s.order = append(s.order, job.Name)
+	if s.started {
+		s.wg.Add(1)
+		go s.loop(e)
+	}
+	return nil
+}
+
+// Start begins triggering jobs. It is a no-op when already started.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || s.stopped {
+		return
+	}
+	s.started = true
+	for _, name := range s.order {
+		s.wg.Add(1)
+		go s.loop(s.jobs[name])
+	}
+}
+
+// Stop stops triggering jobs, cancels the context of in-flight runs and
+// waits for them to return or for ctx to be done.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunNow runs a job immediately and waits for the result. It honours the
+// job's overlap policy (OverlapQueue behaves like OverlapAllow) and, for
+// distributed OverlapSkip jobs, the cross-instance running lock.
+func (s *Scheduler) RunNow(ctx context.Context, name string) (Run, error) {
+	e, err := s.entry(name)
+	if err != nil {
+		return Run{}, err
+	}
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return Run{}, ErrStopped
+	}
+	s.wg.Add(1)
+	s.mu.Unlock()
+	defer s.wg.Done()
+
+	now := time.Now()
+	e.mu.Lock()
+	if e.running > 0 && e.job.Overlap == OverlapSkip {
+		e.mu.Unlock()
+		return s.skip(e, now, TriggerManual, "previous run still in progress"), nil
+	}
+	e.running++
+	e.mu.Unlock()
+
+	run := s.execute(ctx, e, now, TriggerManual)
+	e.mu.Lock()
+	e.running--
+	e.mu.Unlock()
+	s.reportRunning(e)
+	return run, nil
+}
+
+// History returns the most recent runs of a job, newest first.
+func (s *Scheduler) History(name string) ([]Run, error) {
+	e, err := s.entry(name)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make([]Run, len(e.history))
+	for i, run := range e.history {
+		out[len(out)-1-i] = run
+	}
+	return out, nil
+}
+
+// Jobs returns the status of every job in the order they were added.
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	entries := make([]*entry, 0, len(s.order))
+	for _, name := range s.order {
+		entries = append(entries, s.jobs[name])
+	}
+	s.mu.Unlock()
+
+	out := make([]JobStatus, 0, len(entries))
+	for _, e := range entries {
+		e.mu.Lock()
+		st := JobStatus{Name: e.job.Name, Next: e.next, Running: e.running}
+		if n := len(e.history); n > 0 {
+			last := e.history[n-1]
+			st.LastRun = &last
+		}
+		e.mu.Unlock()
+		out = append(out, st)
+	}
+	return out
+}
+
+func (s *Scheduler) entry(name string) (*entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJob, name)
+	}
+	return e, nil
+}
+
+// loop waits for each activation of e and dispatches it.
+func (s *Scheduler) loop(e *entry) {
+	defer s.wg.Done()
+	for {
+		now := time.Now().In(s.opts.Location)
+		next := e.job.Schedule.Next(now)
+		e.mu.Lock()
+		e.next = next
+		e.mu.Unlock()
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.dispatch(e, next)
+	}
+}
+
+// dispatch applies the overlap policy to a scheduled activation.
+func (s *Scheduler) dispatch(e *entry, scheduledAt time.Time) {
+	e.mu.Lock()
+	if e.running > 0 {
+		switch e.job.Overlap {
+		case OverlapSkip:
+			e.mu.Unlock()
+			s.skip(e, scheduledAt, TriggerSchedule, "previous run still in progress")
+			return
+		case OverlapQueue:
+			e.queued = true
+			e.queuedAt = scheduledAt
+			e.mu.Unlock()
+			return
+		}
+	}
+	e.running++
+	e.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		at := scheduledAt
+		for {
+			s.execute(s.ctx, e, at, TriggerSchedule)
+
+			e.mu.Lock()
+			if !e.queued || s.ctx.Err() != nil {
+				e.queued = false
+				e.running--
+				e.mu.Unlock()
+				s.reportRunning(e)
+				return
+			}
+			e.queued = false
+			at = e.queuedAt
+			e.mu.Unlock()
+		}
+	}()
+}
+
+// execute performs one run, including distributed coordination, and
+// records it. The caller accounts for e.running.
+func (s *Scheduler) execute(ctx context.Context, e *entry, scheduledAt time.Time, trigger Trigger) Run {
+	job := e.job
+	if job.Distributed {
+		if trigger == TriggerSchedule {
+			// The tick lock is never released: it expires around the next
+			// activation so that instances with skewed clocks cannot rerun it.
+			ttl := job.Schedule.Next(scheduledAt).Sub(scheduledAt)
+			if ttl < time.Second {
+				ttl = time.Second
+			}
+			key := fmt.Sprintf("%s%s:%d", s.opts.KeyPrefix, job.Name, scheduledAt.Unix())
+			if _, err := s.opts.Locker.Obtain(ctx, key, ttl); err != nil {
+				return s.lockFailed(e, scheduledAt, trigger, err, "activation handled by another instance")
+			}
+		}
+		if job.Overlap == OverlapSkip {
+			l, err := s.opts.Locker.Obtain(ctx, s.opts.KeyPrefix+job.Name+":running", s.opts.LockTTL)
+			if err != nil {
+				return s.lockFailed(e, scheduledAt, trigger, err, "running on another instance")
+			}
+			stop := s.keepAlive(l)
+			defer func() {
+				stop()
+				if err := l.Release(context.Background()); err != nil && !errors.Is(err, lock.ErrNotHeld) {
+					s.logger.Warn("scheduler: release lock failed", zap.String("job", job.Name), zap.Error(err))
+				}
+			}()
+		}
+	}
+
+	s.reportRunning(e)
+	run := Run{
+		ID:          uuid.NewString(),
+		Job:         job.Name,
+		Instance:    s.opts.Instance,
+		Trigger:     trigger,
+		ScheduledAt: scheduledAt,
+		StartedAt:   time.Now(),
+	}
+
+	runCtx := ctx
+	cancel := func() {}
+	if job.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, job.Timeout)
+	}
+	err := safeRun(runCtx, job.Run)
+	timedOut := errors.Is(runCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+	cancel()
+
+	run.FinishedAt = time.Now()
+	run.Duration = run.FinishedAt.Sub(run.StartedAt)
+	switch {
+	case timedOut:
+		run.Status = StatusTimedOut
+		run.Error = fmt.Sprintf("timed out after %s", job.Timeout)
+	case err != nil:
+		run.Status = StatusFailed
+		run.Error = err.Error()
+	default:
+		run.Status = StatusSucceeded
+	}
+	s.record(e, run)
+	return run
+}
+
+// lockFailed records a run that did not start because of the locker. A
+// held lock means another instance owns the run; any other error is a
+// failure, since running anyway could break single-run semantics.
+func (s *Scheduler) lockFailed(e *entry, scheduledAt time.Time, trigger Trigger, err error, reason string) Run {
+	if errors.Is(err, lock.ErrNotObtained) {
+		return s.skip(e, scheduledAt, trigger, reason)
+	}
+	run := Run{
+		ID:          uuid.NewString(),
+		Job:         e.job.Name,
+		Instance:    s.opts.Instance,
+		Trigger:     trigger,
+		Status:      StatusFailed,
+		ScheduledAt: scheduledAt,
+		Error:       err.Error(),
+	}
+	s.record(e, run)
+	return run
+}
+
+// keepAlive refreshes l until the returned function is called.
+func (s *Scheduler) keepAlive(l lock.Lock) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(s.opts.LockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := l.Refresh(context.Background(), s.opts.LockTTL); err != nil {
+					s.logger.Warn("scheduler: refresh lock failed", zap.String("key", l.Key()), zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func safeRun(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+func (s *Scheduler) skip(e *entry, scheduledAt time.Time, trigger Trigger, reason string) Run {
+	run := Run{
+		ID:          uuid.NewString(),
+		Job:         e.job.Name,
+		Instance:    s.opts.Instance,
+		Trigger:     trigger,
+		Status:      StatusSkipped,
+		Reason:      reason,
+		ScheduledAt: scheduledAt,
+	}
+	s.record(e, run)
+	return run
+}
+
+// record appends run to the job's history and exports it to metrics and
+// the audit log.
+func (s *Scheduler) record(e *entry, run Run) {
+	e.mu.Lock()
+	e.history = append(e.history, run)
+	if over := len(e.history) - s.opts.HistorySize; over > 0 {
+		e.history = append(e.history[:0], e.history[over:]...)
+	}
+	e.mu.Unlock()
+
+	if c := s.opts.Metrics; c != nil {
+		c.IncCounter("scheduler_runs_total", map[string]string{"job": run.Job, "status": string(run.Status)})
+		if !run.StartedAt.IsZero() {
+			c.ObserveHistogram("scheduler_run_duration_seconds", run.Duration.Seconds(), map[string]string{"job": run.Job})
+		}
+		if run.Status == StatusSucceeded {
+			c.SetGauge("scheduler_last_success_timestamp_seconds", float64(run.FinishedAt.Unix()), map[string]string{"job": run.Job})
+		}
+	}
+
+	if run.Status == StatusFailed || run.Status == StatusTimedOut {
+		s.logger.Warn("scheduler: job run failed", zap.String("job", run.Job), zap.String("status", string(run.Status)), zap.String("error", run.Error))
+	}
+	if a := s.opts.AuditLogger; a != nil {
+		a.Info("scheduled job run",
+			zap.String("run_id", run.ID),
+			zap.String("job", run.Job),
+			zap.String("instance", run.Instance),
+			zap.String("trigger", string(run.Trigger)),
+			zap.String("status", string(run.Status)),
+			zap.String("reason", run.Reason),
+			zap.Time("scheduled_at", run.ScheduledAt),
+			zap.Duration("duration", run.Duration),
+			zap.String("error", run.Error),
+		)
+	}
+}
+
+func (s *Scheduler) reportRunning(e *entry) {
+	if s.opts.Metrics == nil {
+		return
+	}
+	e.mu.Lock()
+	n := e.running
+	e.mu.Unlock()
+	s.opts.Metrics.SetGauge("scheduler_running", float64(n), map[string]string{"job": e.job.Name})
+}
@@ -0,0 +1,211 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/lock"
+	"github.com/leeforge/framework/metrics"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestCron(t *testing.T) {
+	base := time.Date(2026, 3, 1, 10, 2, 30, 0, time.UTC)
+
+	if got := MustCron("*/5 * * * *").Next(base); !got.Equal(time.Date(2026, 3, 1, 10, 5, 0, 0, time.UTC)) {
+		t.Errorf("five-field next = %v", got)
+	}
+	if got := MustCron("15 * * * * *").Next(base); !got.Equal(time.Date(2026, 3, 1, 10, 3, 15, 0, time.UTC)) {
+		t.Errorf("six-field next = %v", got)
+	}
+	if got := MustCron("CRON_TZ=Asia/Shanghai 0 9 * * *").Next(base); !got.Equal(time.Date(2026, 3, 2, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("CRON_TZ next = %v", got)
+	}
+	if _, err := Cron("61 * * * *"); err == nil {
+		t.Error("invalid expression must fail")
+	}
+	if got := Every(time.Minute).Next(base); !got.Equal(time.Date(2026, 3, 1, 10, 3, 0, 0, time.UTC)) {
+		t.Errorf("Every next = %v, want aligned to the minute", got)
+	}
+}
+
+func TestSchedulerRecordsRuns(t *testing.T) {
+	collector := metrics.NewCollector()
+	core, audit := observer.New(zap.InfoLevel)
+	s := New(Options{Metrics: collector, AuditLogger: zap.New(core), HistorySize: 3, Instance: "a"})
+	defer s.Stop(context.Background())
+
+	var calls atomic.Int32
+	if err := s.Add(Job{Name: "tick", Schedule: Every(10 * time.Millisecond), Run: func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Job{Name: "tick", Schedule: Every(time.Second), Run: func(context.Context) error { return nil }}); !errors.Is(err, ErrDuplicateJob) {
+		t.Fatalf("duplicate Add err = %v", err)
+	}
+	s.Start()
+	waitFor(t, func() bool { return calls.Load() >= 4 })
+
+	history, _ := s.History("tick")
+	if len(history) != 3 {
+		t.Fatalf("history len = %d, want capped at 3", len(history))
+	}
+	if history[0].Status != StatusSucceeded || history[0].Instance != "a" || !history[0].ScheduledAt.After(history[1].ScheduledAt) {
+		t.Errorf("history[0] = %+v, want newest succeeded run", history[0])
+	}
+	if st := s.Jobs(); len(st) != 1 || st[0].LastRun == nil || st[0].Next.IsZero() {
+		t.Errorf("Jobs() = %+v", st)
+	}
+	if m := collector.GetMetric("scheduler_last_success_timestamp_seconds", map[string]string{"job": "tick"}); m == nil || m.Value == 0 {
+		t.Error("last success gauge not exported")
+	}
+	if audit.FilterMessage("scheduled job run").Len() < 4 {
+		t.Errorf("audit entries = %d", audit.Len())
+	}
+	if _, err := s.History("missing"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("History(missing) err = %v", err)
+	}
+}
+
+func TestRunNowOutcomes(t *testing.T) {
+	s := New(Options{})
+	defer s.Stop(context.Background())
+	ctx := context.Background()
+	never := MustCron("0 0 1 1 *")
+
+	s.Add(Job{Name: "slow", Schedule: never, Timeout: 20 * time.Millisecond, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	s.Add(Job{Name: "boom", Schedule: never, Run: func(context.Context) error { panic("boom") }})
+	s.Add(Job{Name: "err", Schedule: never, Run: func(context.Context) error { return errors.New("bad") }})
+
+	for name, want := range map[string]Status{"slow": StatusTimedOut, "boom": StatusFailed, "err": StatusFailed} {
+		run, err := s.RunNow(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.Status != want || run.Trigger != TriggerManual {
+			t.Errorf("%s: run = %+v, want %s", name, run, want)
+		}
+	}
+}
+
+func TestOverlapPolicies(t *testing.T) {
+	t.Run("skip", func(t *testing.T) {
+		s := New(Options{})
+		defer s.Stop(context.Background())
+		release := make(chan struct{})
+		s.Add(Job{Name: "job", Schedule: Every(5 * time.Millisecond), Run: func(ctx context.Context) error {
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return nil
+		}})
+		s.Start()
+		waitFor(t, func() bool {
+			h, _ := s.History("job")
+			return len(h) > 0 && h[0].Status == StatusSkipped
+		})
+		run, _ := s.RunNow(context.Background(), "job")
+		if run.Status != StatusSkipped {
+			t.Errorf("RunNow during a run = %s, want skipped", run.Status)
+		}
+		close(release)
+	})
+
+	t.Run("queue", func(t *testing.T) {
+		s := New(Options{})
+		defer s.Stop(context.Background())
+		var running, maxRunning, runs atomic.Int32
+		s.Add(Job{Name: "job", Overlap: OverlapQueue, Schedule: Every(5 * time.Millisecond), Run: func(context.Context) error {
+			n := running.Add(1)
+			if n > maxRunning.Load() {
+				maxRunning.Store(n)
+			}
+			time.Sleep(20 * time.Millisecond)
+			running.Add(-1)
+			runs.Add(1)
+			return nil
+		}})
+		s.Start()
+		waitFor(t, func() bool { return runs.Load() >= 3 })
+		if maxRunning.Load() != 1 {
+			t.Errorf("max concurrent runs = %d, want 1", maxRunning.Load())
+		}
+		h, _ := s.History("job")
+		for _, run := range h {
+			if run.Status == StatusSkipped {
+				t.Fatal("queued activations must not be skipped")
+			}
+		}
+	})
+}
+
+func TestDistributedRunsOncePerActivation(t *testing.T) {
+	locker := lock.NewMemoryLocker()
+
+	var schedulers []*Scheduler
+	for _, instance := range []string{"a", "b", "c"} {
+		s := New(Options{Locker: locker, Instance: instance})
+		err := s.Add(Job{Name: "report", Distributed: true, Schedule: Every(20 * time.Millisecond), Run: func(context.Context) error {
+			return nil
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		schedulers = append(schedulers, s)
+	}
+	for _, s := range schedulers {
+		s.Start()
+	}
+	time.Sleep(150 * time.Millisecond)
+	for _, s := range schedulers {
+		s.Stop(context.Background())
+	}
+
+	var succeeded, skipped int
+	ticks := make(map[time.Time]int)
+	for _, s := range schedulers {
+		h, _ := s.History("report")
+		for _, run := range h {
+			switch run.Status {
+			case StatusSucceeded:
+				succeeded++
+				ticks[run.ScheduledAt]++
+			case StatusSkipped:
+				skipped++
+			}
+		}
+	}
+	if succeeded == 0 || skipped == 0 {
+		t.Fatalf("succeeded = %d, skipped = %d", succeeded, skipped)
+	}
+	for at, n := range ticks {
+		if n != 1 {
+			t.Errorf("activation %v ran %d times", at, n)
+		}
+	}
+
+	if err := New(Options{}).Add(Job{Name: "x", Distributed: true, Schedule: Every(time.Second), Run: func(context.Context) error { return nil }}); err == nil {
+		t.Error("distributed job without Locker must be rejected")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,376 @@
+package security
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidAPIKey API Key 格式错误或不存在
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrAPIKeyExpired API Key 已过期
+	ErrAPIKeyExpired = errors.New("api key expired")
+	// ErrAPIKeyRevoked API Key 已吊销
+	ErrAPIKeyRevoked = errors.New("api key revoked")
+	// ErrTooManyAttempts 同一查找前缀验证失败次数过多，暂时锁定
+	ErrTooManyAttempts = errors.New("too many failed api key attempts")
+)
+
+const (
+	apiKeyLookupBytes = 8
+	apiKeySecretBytes = 32
+)
+
+// 查找前缀使用小写无填充 base32，只含 [a-z2-7]，不会与分隔符 "_" 冲突
+var lookupEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// StoredAPIKey 落库的 API Key 记录，只保存哈希，不保存明文
+type StoredAPIKey struct {
+	ID        string            `json:"id"`
+	Lookup    string            `json:"lookup"` // 明文中的查找前缀，可建唯一索引
+	Hash      string            `json:"-"`      // HMAC-SHA256(pepper, 明文) 的十六进制
+	Name      string            `json:"name"`
+	OwnerID   string            `json:"owner_id"`
+	OrgID     string            `json:"org_id,omitempty"`
+	Scopes    []string          `json:"scopes,omitempty"` // 授权范围，如 "article:read"
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at,omitempty"` // 零值表示永不过期
+	RevokedAt *time.Time        `json:"revoked_at,omitempty"`
+}
+
+// Active 判断记录在给定时刻是否可用（未吊销且未过期）
+func (k *StoredAPIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt)
+}
+
+// Hint 返回可安全展示的 Key 标识（前缀 + 查找段）
+func (k *StoredAPIKey) Hint(prefix string) string {
+	return prefix + "_" + k.Lookup + "_…"
+}
+
+// APIKeyRepository API Key 存储接口
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *StoredAPIKey) error
+	// FindByLookup 按查找前缀获取记录，不存在时返回 (nil, nil)
+	FindByLookup(ctx context.Context, lookup string) (*StoredAPIKey, error)
+}
+
+// APIKeyFilter 列表过滤条件，空字段不参与过滤
+type APIKeyFilter struct {
+	OwnerID string
+	OrgID   string
+}
+
+// APIKeyAdminRepository 支持自助管理（列出、吊销、调整过期时间）的存储接口
+type APIKeyAdminRepository interface {
+	APIKeyRepository
+	List(ctx context.Context, filter APIKeyFilter) ([]*StoredAPIKey, error)
+	Revoke(ctx context.Context, lookup string) error
+	// SetExpiry 修改过期时间，用于轮换时让旧 Key 在重叠期后失效
+	SetExpiry(ctx context.Context, lookup string, expiresAt time.Time) error
+}
+
+// APIKeyIssueOptions 签发选项
+type APIKeyIssueOptions struct {
+	Name     string
+	OwnerID  string
+	OrgID    string
+	Scopes   []string
+	TTL      time.Duration // 0 表示永不过期
+	Metadata map[string]string
+}
+
+// APIKeyManagerConfig API Key 管理器配置
+type APIKeyManagerConfig struct {
+	Prefix        string        // 明文前缀，如 "lf_live"
+	Pepper        string        // 服务端密钥，参与哈希，泄露数据库也无法离线校验
+	MaxFailures   int           // 窗口内同一查找前缀允许的失败次数，默认 10
+	FailureWindow time.Duration // 失败计数窗口，默认 15 分钟
+}
+
+// APIKeyManager 签发与校验 API Key
+// 明文格式为 <prefix>_<lookup>_<secret>：lookup 用于定位记录，secret 只以哈希形式落库
+type APIKeyManager struct {
+	config   APIKeyManagerConfig
+	repo     APIKeyRepository
+	failures *failureLimiter
+	// dummyHash 用于查找前缀不存在时仍执行一次比较，保持耗时一致
+	dummyHash []byte
+}
+
+// NewAPIKeyManager 创建 API Key 管理器
+func NewAPIKeyManager(config APIKeyManagerConfig, repo APIKeyRepository) (*APIKeyManager, error) {
+	if config.Pepper == "" {
+		return nil, fmt.Errorf("api key pepper is required")
+	}
+	if config.Prefix == "" {
+		config.Prefix = "key"
+	}
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = 10
+	}
+	if config.FailureWindow <= 0 {
+		config.FailureWindow = 15 * time.Minute
+	}
+
+	m := &APIKeyManager{
+		config:   config,
+		repo:     repo,
+		failures: newFailureLimiter(config.MaxFailures, config.FailureWindow),
+	}
+	m.dummyHash = m.hash("dummy")
+	return m, nil
+}
+
+// Issue 签发新的 API Key，明文只在此处返回一次
+func (m *APIKeyManager) Issue(ctx context.Context, opts APIKeyIssueOptions) (string, *StoredAPIKey, error) {
+	lookupRaw := make([]byte, apiKeyLookupBytes)
+	secretRaw := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(lookupRaw); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(secretRaw); err != nil {
+		return "", nil, err
+	}
+
+	lookup := lookupEncoding.EncodeToString(lookupRaw)
+	plaintext := m.config.Prefix + "_" + lookup + "_" + hex.EncodeToString(secretRaw)
+
+	now := time.Now()
+	key := &StoredAPIKey{
+		ID:        lookup,
+		Lookup:    lookup,
+		Hash:      hex.EncodeToString(m.hash(plaintext)),
+		Name:      opts.Name,
+		OwnerID:   opts.OwnerID,
+		OrgID:     opts.OrgID,
+		Scopes:    opts.Scopes,
+		Metadata:  opts.Metadata,
+		CreatedAt: now,
+	}
+	if opts.TTL > 0 {
+		key.ExpiresAt = now.Add(opts.TTL)
+	}
+
+	if err := m.repo.Create(ctx, key); err != nil {
+		return "", nil, err
+	}
+	return plaintext, key, nil
+}
+
+// Verify 校验明文 API Key
+// 先按查找前缀定位记录，再常数时间比较哈希；失败次数超限的前缀会被暂时锁定
+func (m *APIKeyManager) Verify(ctx context.Context, plaintext string) (*StoredAPIKey, error) {
+	lookup, ok := m.parseLookup(plaintext)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	if m.failures.blocked(lookup) {
+		return nil, ErrTooManyAttempts
+	}
+
+	key, err := m.repo.FindByLookup(ctx, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := m.dummyHash
+	if key != nil {
+		if expected, err = hex.DecodeString(key.Hash); err != nil {
+			return nil, fmt.Errorf("corrupt api key hash for %s: %w", lookup, err)
+		}
+	}
+	match := subtle.ConstantTimeCompare(m.hash(plaintext), expected) == 1
+
+	if key == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if !match {
+		// 只为真实存在的前缀计数，随机前缀不会撑大计数表
+		m.failures.fail(lookup)
+		return nil, ErrInvalidAPIKey
+	}
+	m.failures.reset(lookup)
+
+	if key.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+	if !key.ExpiresAt.IsZero() && time.Now().After(key.ExpiresAt) {
+		return nil, ErrAPIKeyExpired
+	}
+	return key, nil
+}
+
+// parseLookup 解析 <prefix>_<lookup>_<secret>，prefix 本身可以包含 "_"
+func (m *APIKeyManager) parseLookup(plaintext string) (string, bool) {
+	rest, ok := strings.CutPrefix(plaintext, m.config.Prefix+"_")
+	if !ok {
+		return "", false
+	}
+	lookup, secret, ok := strings.Cut(rest, "_")
+	if !ok || lookup == "" || len(secret) != apiKeySecretBytes*2 {
+		return "", false
+	}
+	return lookup, true
+}
+
+func (m *APIKeyManager) hash(plaintext string) []byte {
+	h := hmac.New(sha256.New, []byte(m.config.Pepper))
+	h.Write([]byte(plaintext))
+	return h.Sum(nil)
+}
+
+// failureLimiter 按查找前缀统计固定窗口内的失败次数
+type failureLimiter struct {
+	max     int
+	window  time.Duration
+	mu      sync.Mutex
+	entries map[string]*failureEntry
+}
+
+type failureEntry struct {
+	count int
+	start time.Time
+}
+
+func newFailureLimiter(max int, window time.Duration) *failureLimiter {
+	return &failureLimiter{
+		max:     max,
+		window:  window,
+		entries: make(map[string]*failureEntry),
+	}
+}
+
+func (l *failureLimiter) blocked(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok {
+		return false
+	}
+	if time.Since(entry.start) > l.window {
+		delete(l.entries, key)
+		return false
+	}
+	return entry.count >= l.max
+}
+
+func (l *failureLimiter) fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok || time.Since(entry.start) > l.window {
+		l.entries[key] = &failureEntry{count: 1, start: time.Now()}
+		return
+	}
+	entry.count++
+}
+
+func (l *failureLimiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+// MemoryAPIKeyRepository 内存 API Key 存储（测试/单机）
+type MemoryAPIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[string]*StoredAPIKey
+}
+
+// NewMemoryAPIKeyRepository 创建内存 API Key 存储
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{
+		keys: make(map[string]*StoredAPIKey),
+	}
+}
+
+// Create 保存记录
+func (r *MemoryAPIKeyRepository) Create(_ context.Context, key *StoredAPIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.keys[key.Lookup]; exists {
+		return fmt.Errorf("api key lookup %s already exists", key.Lookup)
+	}
+	copied := *key
+	r.keys[key.Lookup] = &copied
+	return nil
+}
+
+// FindByLookup 按查找前缀获取记录
+func (r *MemoryAPIKeyRepository) FindByLookup(_ context.Context, lookup string) (*StoredAPIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[lookup]
+	if !ok {
+		return nil, nil
+	}
+	copied := *key
+	return &copied, nil
+}
+
+// List 按条件列出记录，按创建时间排序
+func (r *MemoryAPIKeyRepository) List(_ context.Context, filter APIKeyFilter) ([]*StoredAPIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []*StoredAPIKey
+	for _, key := range r.keys {
+		if filter.OwnerID != "" && key.OwnerID != filter.OwnerID {
+			continue
+		}
+		if filter.OrgID != "" && key.OrgID != filter.OrgID {
+			continue
+		}
+		copied := *key
+		out = append(out, &copied)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// SetExpiry 修改过期时间
+func (r *MemoryAPIKeyRepository) SetExpiry(_ context.Context, lookup string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[lookup]
+	if !ok {
+		return ErrInvalidAPIKey
+	}
+	key.ExpiresAt = expiresAt
+	return nil
+}
+
+// Revoke 吊销记录
+func (r *MemoryAPIKeyRepository) Revoke(_ context.Context, lookup string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[lookup]
+	if !ok {
+		return ErrInvalidAPIKey
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	return nil
+}
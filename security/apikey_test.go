@@ -0,0 +1,90 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAPIKeyManager(t *testing.T) (*APIKeyManager, *MemoryAPIKeyRepository) {
+	t.Helper()
+	repo := NewMemoryAPIKeyRepository()
+	m, err := NewAPIKeyManager(APIKeyManagerConfig{Prefix: "lf_test", Pepper: "pepper", MaxFailures: 3}, repo)
+	if err != nil {
+		t.Fatalf("NewAPIKeyManager: %v", err)
+	}
+	return m, repo
+}
+
+func TestAPIKeyManager_IssueAndVerify(t *testing.T) {
+	m, repo := newTestAPIKeyManager(t)
+	ctx := context.Background()
+
+	plaintext, stored, err := m.Issue(ctx, APIKeyIssueOptions{Name: "ci", OwnerID: "u1"})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if !strings.HasPrefix(plaintext, "lf_test_"+stored.Lookup+"_") {
+		t.Fatalf("unexpected key format %q", plaintext)
+	}
+	if strings.Contains(stored.Hash, plaintext) || stored.Hash == "" {
+		t.Fatal("stored record must only contain the hash")
+	}
+
+	got, err := m.Verify(ctx, plaintext)
+	if err != nil || got.OwnerID != "u1" {
+		t.Fatalf("Verify = %+v, %v", got, err)
+	}
+
+	tampered := []byte(plaintext)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := m.Verify(ctx, string(tampered)); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("tampered key: err = %v", err)
+	}
+	if _, err := m.Verify(ctx, "lf_test_unknown_"+strings.Repeat("0", 64)); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("unknown lookup: err = %v", err)
+	}
+
+	if err := repo.Revoke(ctx, stored.Lookup); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(ctx, plaintext); !errors.Is(err, ErrAPIKeyRevoked) {
+		t.Fatalf("revoked key: err = %v", err)
+	}
+}
+
+func TestAPIKeyManager_Expiry(t *testing.T) {
+	m, _ := newTestAPIKeyManager(t)
+	ctx := context.Background()
+
+	plaintext, _, err := m.Issue(ctx, APIKeyIssueOptions{TTL: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := m.Verify(ctx, plaintext); !errors.Is(err, ErrAPIKeyExpired) {
+		t.Fatalf("err = %v, want ErrAPIKeyExpired", err)
+	}
+}
+
+func TestAPIKeyManager_LocksLookupAfterFailures(t *testing.T) {
+	m, _ := newTestAPIKeyManager(t)
+	ctx := context.Background()
+
+	plaintext, stored, err := m.Issue(ctx, APIKeyIssueOptions{})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	wrong := "lf_test_" + stored.Lookup + "_" + strings.Repeat("f", 64)
+
+	for i := 0; i < 3; i++ {
+		if _, err := m.Verify(ctx, wrong); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Fatalf("attempt %d: err = %v", i, err)
+		}
+	}
+	if _, err := m.Verify(ctx, plaintext); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("err = %v, want ErrTooManyAttempts", err)
+	}
+}
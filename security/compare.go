@@ -0,0 +1,31 @@
+package security
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// ConstantTimeEqual 常数时间比较两个字符串（API Key、签名、Token 等凭证）
+// 先对两侧做 SHA-256 再比较，耗时与内容和长度均无关，不会泄露匹配的前缀长度
+func ConstantTimeEqual(a, b string) bool {
+	return ConstantTimeEqualBytes([]byte(a), []byte(b))
+}
+
+// ConstantTimeEqualBytes 常数时间比较两个字节切片
+func ConstantTimeEqualBytes(a, b []byte) bool {
+	ha := sha256.Sum256(a)
+	hb := sha256.Sum256(b)
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// ConstantTimeIn 判断 v 是否在 candidates 中
+// 总会比较全部候选项，耗时不暴露命中的位置
+func ConstantTimeIn(v string, candidates []string) bool {
+	found := 0
+	for _, c := range candidates {
+		if ConstantTimeEqual(v, c) {
+			found = 1
+		}
+	}
+	return found == 1
+}
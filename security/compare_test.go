@@ -0,0 +1,25 @@
+package security
+
+import "testing"
+
+func TestConstantTimeEqual(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{"secret", "secret", true},
+		{"secret", "secreT", false},
+		{"secret", "secret-longer", false},
+		{"", "", true},
+		{"", "x", false},
+	}
+	for _, c := range cases {
+		if got := ConstantTimeEqual(c.a, c.b); got != c.want {
+			t.Errorf("ConstantTimeEqual(%q, %q) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+
+	if !ConstantTimeIn("b", []string{"a", "b", "c"}) || ConstantTimeIn("d", []string{"a", "b", "c"}) {
+		t.Error("ConstantTimeIn returned wrong membership")
+	}
+}
@@ -25,6 +25,8 @@ func NewCryptoTool(secretKey string) *CryptoTool {
 }
 
 // HashPassword 使用 Bcrypt 模拟（简化版）
+//
+// Deprecated: HMAC 不适合存储密码，请使用 auth/password
 func (c *CryptoTool) HashPassword(password string) (string, error) {
 	// 使用 HMAC-SHA256 作为简化实现
 	// 实际应使用 golang.org/x/crypto/bcrypt
@@ -39,7 +41,7 @@ func (c *CryptoTool) VerifyPassword(password, hash string) bool {
 	if err != nil {
 		return false
 	}
-	return expectedHash == hash
+	return ConstantTimeEqual(expectedHash, hash)
 }
 
 // EncryptAES AES 加密
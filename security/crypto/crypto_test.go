@@ -0,0 +1,104 @@
+package crypto
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testKeyring(t *testing.T, primary string, ids ...string) *Keyring {
+	t.Helper()
+	keys := make(map[string][]byte)
+	for _, id := range ids {
+		keys[id] = []byte(strings.Repeat(id[:1], keySize))
+	}
+	kr, err := NewKeyring(primary, keys)
+	require.NoError(t, err)
+	return kr
+}
+
+func TestEnvelopeAndRotation(t *testing.T) {
+	old := testKeyring(t, "k1", "k1")
+	ct, err := old.Encrypt([]byte("110101199001011234"), []byte("users.national_id:42"))
+	require.NoError(t, err)
+
+	pt, err := old.Decrypt(ct, []byte("users.national_id:42"))
+	require.NoError(t, err)
+	require.Equal(t, "110101199001011234", string(pt))
+
+	_, err = old.Decrypt(ct, []byte("users.national_id:43"))
+	require.Error(t, err, "aad binds the ciphertext to its row")
+
+	// 新主密钥：旧值仍可解密，Rotate 只重新包装数据密钥
+	rotated := testKeyring(t, "k2", "k1", "k2")
+	require.True(t, rotated.NeedsRotation(ct))
+	ct2, err := rotated.Rotate(ct)
+	require.NoError(t, err)
+	id, err := rotated.KeyID(ct2)
+	require.NoError(t, err)
+	require.Equal(t, "k2", id)
+	pt, err = rotated.Decrypt(ct2, []byte("users.national_id:42"))
+	require.NoError(t, err)
+	require.Equal(t, "110101199001011234", string(pt))
+
+	_, err = old.Decrypt(ct2, []byte("users.national_id:42"))
+	require.ErrorIs(t, err, ErrUnknownKey)
+
+	_, err = NewKeyring("k1", map[string][]byte{"k1": []byte("short")})
+	require.Error(t, err)
+	_, err = NewKeyring("missing", map[string][]byte{"k1": make([]byte, keySize)})
+	require.Error(t, err)
+}
+
+func TestStringCodec(t *testing.T) {
+	kr := testKeyring(t, "k1", "k1")
+	codec := StringCodec(kr)
+
+	v, err := codec.Value("13800000000")
+	require.NoError(t, err)
+	stored := v.(string)
+	require.True(t, IsEncrypted(stored))
+	require.NotContains(t, stored, "13800000000")
+
+	got, err := codec.FromValue(&sql.NullString{String: stored, Valid: true})
+	require.NoError(t, err)
+	require.Equal(t, "13800000000", got)
+
+	// 迁移期间的明文值原样返回
+	got, err = codec.FromValue(&sql.NullString{String: "legacy", Valid: true})
+	require.NoError(t, err)
+	require.Equal(t, "legacy", got)
+
+	// 未设置默认密钥环
+	SetDefault(nil)
+	_, err = StringCodec(nil).Value("x")
+	require.ErrorIs(t, err, ErrNoKeyring)
+}
+
+func TestJSONEncryptsTaggedFields(t *testing.T) {
+	type customer struct {
+		Name  string `json:"name"`
+		Phone string `json:"phone" encrypt:"true"`
+		Email string `json:"email,omitempty" encrypt:"true"`
+	}
+	SetDefault(testKeyring(t, "k1", "k1"))
+	t.Cleanup(func() { SetDefault(nil) })
+	api := JSON(nil)
+
+	out, err := api.Marshal(customer{Name: "Lee", Phone: "13800000000"})
+	require.NoError(t, err)
+	require.Contains(t, string(out), `"name":"Lee"`)
+	require.Contains(t, string(out), `"phone":"enc:`)
+	require.NotContains(t, string(out), "13800000000")
+	require.NotContains(t, string(out), "email")
+
+	var back customer
+	require.NoError(t, api.Unmarshal(out, &back))
+	require.Equal(t, customer{Name: "Lee", Phone: "13800000000"}, back)
+
+	require.NoError(t, api.Unmarshal([]byte(`{"phone":"plain"}`), &back))
+	require.Equal(t, "plain", back.Phone)
+	require.Error(t, api.Unmarshal([]byte(`{"phone":"enc:AAAA"}`), &back))
+}
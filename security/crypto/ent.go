@@ -0,0 +1,43 @@
+package crypto
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"entgo.io/ent/schema/field"
+)
+
+// StringCodec returns an ent ValueScanner that stores a string field
+// encrypted and decrypts it when scanned:
+//
+//	field.String("national_id").
+//		Sensitive().
+//		ValueScanner(crypto.StringCodec(nil))
+//
+// A nil keyring resolves Default at query time, so the schema can be
+// declared before keys are loaded. Legacy plaintext values (without Prefix)
+// are returned as is, letting a column be encrypted in place; run Rotate or
+// re-save rows to finish a migration. Encrypted columns cannot be filtered
+// or indexed by value, and need room for the envelope (about 4/3 of the
+// plaintext plus 130 bytes).
+func StringCodec(kr *Keyring) field.ValueScannerFunc[string, *sql.NullString] {
+	return field.ValueScannerFunc[string, *sql.NullString]{
+		V: func(s string) (driver.Value, error) {
+			k, err := resolve(kr)
+			if err != nil {
+				return nil, err
+			}
+			return k.EncryptString(s, nil)
+		},
+		S: func(ns *sql.NullString) (string, error) {
+			if !ns.Valid || !IsEncrypted(ns.String) {
+				return ns.String, nil
+			}
+			k, err := resolve(kr)
+			if err != nil {
+				return "", err
+			}
+			return k.DecryptString(ns.String, nil)
+		},
+	}
+}
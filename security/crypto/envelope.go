@@ -0,0 +1,329 @@
+// Package crypto encrypts sensitive fields at rest with AES-256-GCM
+// envelope encryption. Every value gets its own data key, wrapped by a key
+// encryption key from a Keyring and identified by key ID, so keys rotate by
+// adding a new primary key: old values stay readable and Rotate re-wraps
+// their data key without touching the ciphertext.
+//
+// StringCodec plugs the Keyring into ent fields and JSON encrypts struct
+// fields tagged `encrypt:"true"`.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Prefix marks strings produced by EncryptString, so encrypted and legacy
+// plaintext values can coexist while a column is migrated.
+const Prefix = "enc:"
+
+const (
+	version   = 1
+	keySize   = 32
+	nonceSize = 12
+	tagSize   = 16
+)
+
+var (
+	// ErrUnknownKey is returned when a value was encrypted with a key ID the
+	// keyring does not hold.
+	ErrUnknownKey = errors.New("crypto: unknown key id")
+	// ErrMalformed is returned for values that are not envelopes.
+	ErrMalformed = errors.New("crypto: malformed ciphertext")
+	// ErrNoKeyring is returned by the default-keyring helpers before
+	// SetDefault is called.
+	ErrNoKeyring = errors.New("crypto: default keyring not set")
+)
+
+// Keyring holds the key encryption keys by ID. New values are wrapped with
+// the primary key; any key in the ring decrypts.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring creates a keyring. Every key must be 32 bytes (AES-256) and
+// primary must be one of them.
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	kr := &Keyring{primary: primary, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if id == "" || len(id) > 255 {
+			return nil, fmt.Errorf("crypto: invalid key id %q", id)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("crypto: key %q must be %d bytes, got %d", id, keySize, len(key))
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		kr.keys[id] = aead
+	}
+	if _, ok := kr.keys[primary]; !ok {
+		return nil, fmt.Errorf("crypto: primary key %q not in keyring", primary)
+	}
+	return kr, nil
+}
+
+// NewKeyringBase64 is NewKeyring with standard base64 encoded keys, as
+// they are usually stored in configuration or a secret manager.
+func NewKeyringBase64(primary string, keys map[string]string) (*Keyring, error) {
+	raw := make(map[string][]byte, len(keys))
+	for id, encoded := range keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("crypto: key %q: %w", id, err)
+		}
+		raw[id] = key
+	}
+	return NewKeyring(primary, raw)
+}
+
+// GenerateKey returns a random 32-byte key.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Primary returns the ID of the key that wraps new values.
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// Encrypt seals plaintext in an envelope. aad, when not nil, binds the
+// ciphertext to its context (e.g. table, column and row ID) and must be
+// passed again to Decrypt.
+func (k *Keyring) Encrypt(plaintext, aad []byte) ([]byte, error) {
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, err
+	}
+	data, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+	header := envelopeHeader(k.primary)
+	wrapped, err := seal(k.keys[k.primary], dek, header)
+	if err != nil {
+		return nil, err
+	}
+	body, err := seal(data, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(header)+len(wrapped)+len(body))
+	out = append(out, header...)
+	out = append(out, wrapped...)
+	return append(out, body...), nil
+}
+
+// Decrypt opens an envelope produced by Encrypt with the same aad.
+func (k *Keyring) Decrypt(ciphertext, aad []byte) ([]byte, error) {
+	env, err := parseEnvelope(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := k.unwrap(env)
+	if err != nil {
+		return nil, err
+	}
+	data, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+	return open(data, env.body, aad)
+}
+
+// KeyID returns the ID of the key that wrapped ciphertext.
+func (k *Keyring) KeyID(ciphertext []byte) (string, error) {
+	env, err := parseEnvelope(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return env.keyID, nil
+}
+
+// NeedsRotation reports whether ciphertext is wrapped by a key other than
+// the primary one.
+func (k *Keyring) NeedsRotation(ciphertext []byte) bool {
+	id, err := k.KeyID(ciphertext)
+	return err == nil && id != k.primary
+}
+
+// Rotate re-wraps the data key of ciphertext with the primary key. The
+// encrypted data itself is unchanged, so no aad is needed.
+func (k *Keyring) Rotate(ciphertext []byte) ([]byte, error) {
+	env, err := parseEnvelope(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if env.keyID == k.primary {
+		return ciphertext, nil
+	}
+	dek, err := k.unwrap(env)
+	if err != nil {
+		return nil, err
+	}
+	header := envelopeHeader(k.primary)
+	wrapped, err := seal(k.keys[k.primary], dek, header)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(header)+len(wrapped)+len(env.body))
+	out = append(out, header...)
+	out = append(out, wrapped...)
+	return append(out, env.body...), nil
+}
+
+// EncryptString encrypts s and returns Prefix followed by the base64url
+// envelope, suitable for text columns and JSON.
+func (k *Keyring) EncryptString(s string, aad []byte) (string, error) {
+	ct, err := k.Encrypt([]byte(s), aad)
+	if err != nil {
+		return "", err
+	}
+	return Prefix + base64.RawURLEncoding.EncodeToString(ct), nil
+}
+
+// DecryptString reverses EncryptString.
+func (k *Keyring) DecryptString(s string, aad []byte) (string, error) {
+	ct, err := decodeString(s)
+	if err != nil {
+		return "", err
+	}
+	pt, err := k.Decrypt(ct, aad)
+	if err != nil {
+		return "", err
+	}
+	return string(pt), nil
+}
+
+// RotateString is Rotate for values produced by EncryptString.
+func (k *Keyring) RotateString(s string) (string, error) {
+	ct, err := decodeString(s)
+	if err != nil {
+		return "", err
+	}
+	rotated, err := k.Rotate(ct)
+	if err != nil {
+		return "", err
+	}
+	return Prefix + base64.RawURLEncoding.EncodeToString(rotated), nil
+}
+
+// IsEncrypted reports whether s looks like an EncryptString result.
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, Prefix)
+}
+
+var defaultKeyring atomic.Pointer[Keyring]
+
+// SetDefault sets the keyring used by StringCodec(nil) and JSON(nil).
+func SetDefault(kr *Keyring) {
+	defaultKeyring.Store(kr)
+}
+
+// Default returns the keyring set with SetDefault, or nil.
+func Default() *Keyring {
+	return defaultKeyring.Load()
+}
+
+func resolve(kr *Keyring) (*Keyring, error) {
+	if kr != nil {
+		return kr, nil
+	}
+	if kr = Default(); kr == nil {
+		return nil, ErrNoKeyring
+	}
+	return kr, nil
+}
+
+type envelope struct {
+	header  []byte
+	keyID   string
+	wrapped []byte
+	body    []byte
+}
+
+func envelopeHeader(keyID string) []byte {
+	return append([]byte{version, byte(len(keyID))}, keyID...)
+}
+
+func parseEnvelope(b []byte) (envelope, error) {
+	if len(b) < 2 || b[0] != version {
+		return envelope{}, ErrMalformed
+	}
+	n := int(b[1])
+	headerLen := 2 + n
+	wrappedLen := nonceSize + keySize + tagSize
+	if len(b) < headerLen+wrappedLen+nonceSize+tagSize {
+		return envelope{}, ErrMalformed
+	}
+	return envelope{
+		header:  b[:headerLen],
+		keyID:   string(b[2:headerLen]),
+		wrapped: b[headerLen : headerLen+wrappedLen],
+		body:    b[headerLen+wrappedLen:],
+	}, nil
+}
+
+func (k *Keyring) unwrap(env envelope) ([]byte, error) {
+	kek, ok := k.keys[env.keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, env.keyID)
+	}
+	return open(kek, env.wrapped, env.header)
+}
+
+func decodeString(s string) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(s, Prefix)
+	if !ok {
+		return nil, ErrMalformed
+	}
+	ct, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return ct, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns nonce || ciphertext || tag.
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, nonceSize, nonceSize+len(plaintext)+tagSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < nonceSize+tagSize {
+		return nil, ErrMalformed
+	}
+	pt, err := aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], aad)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: decrypt: %w", err)
+	}
+	if pt == nil {
+		pt = []byte{}
+	}
+	return pt, nil
+}
@@ -0,0 +1,91 @@
+package crypto
+
+import (
+	"reflect"
+	"unsafe"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// TagName is the struct tag marking string fields JSON encrypts:
+//
+//	type Customer struct {
+//		Name  string `json:"name"`
+//		Phone string `json:"phone" encrypt:"true"`
+//	}
+const TagName = "encrypt"
+
+// JSON returns a json-iterator API, compatible with encoding/json, that
+// encrypts string fields tagged `encrypt:"true"` on Marshal and decrypts
+// them on Unmarshal. Use it where documents are persisted (JSON columns,
+// outbox payloads, caches), not for API responses. Values without Prefix
+// are decoded as plaintext. A nil keyring resolves Default at call time.
+func JSON(kr *Keyring) jsoniter.API {
+	api := jsoniter.Config{
+		EscapeHTML:             true,
+		SortMapKeys:            true,
+		ValidateJsonRawMessage: true,
+	}.Froze()
+	api.RegisterExtension(&encryptExtension{kr: kr})
+	return api
+}
+
+type encryptExtension struct {
+	jsoniter.DummyExtension
+	kr *Keyring
+}
+
+func (e *encryptExtension) UpdateStructDescriptor(sd *jsoniter.StructDescriptor) {
+	for _, binding := range sd.Fields {
+		if binding.Field.Tag().Get(TagName) != "true" || binding.Field.Type().Kind() != reflect.String {
+			continue
+		}
+		binding.Encoder = &encryptCodec{kr: e.kr}
+		binding.Decoder = &encryptCodec{kr: e.kr}
+	}
+}
+
+type encryptCodec struct {
+	kr *Keyring
+}
+
+func (c *encryptCodec) IsEmpty(ptr unsafe.Pointer) bool {
+	return *(*string)(ptr) == ""
+}
+
+func (c *encryptCodec) Encode(ptr unsafe.Pointer, stream *jsoniter.Stream) {
+	kr, err := resolve(c.kr)
+	if err != nil {
+		stream.Error = err
+		return
+	}
+	ct, err := kr.EncryptString(*(*string)(ptr), nil)
+	if err != nil {
+		stream.Error = err
+		return
+	}
+	stream.WriteString(ct)
+}
+
+func (c *encryptCodec) Decode(ptr unsafe.Pointer, iter *jsoniter.Iterator) {
+	if iter.ReadNil() {
+		*(*string)(ptr) = ""
+		return
+	}
+	s := iter.ReadString()
+	if !IsEncrypted(s) {
+		*(*string)(ptr) = s
+		return
+	}
+	kr, err := resolve(c.kr)
+	if err != nil {
+		iter.ReportError("decrypt", err.Error())
+		return
+	}
+	pt, err := kr.DecryptString(s, nil)
+	if err != nil {
+		iter.ReportError("decrypt", err.Error())
+		return
+	}
+	*(*string)(ptr) = pt
+}
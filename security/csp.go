@@ -0,0 +1,263 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// CSP 源关键字
+const (
+	CSPSelf           = "'self'"
+	CSPNone           = "'none'"
+	CSPUnsafeInline   = "'unsafe-inline'"
+	CSPUnsafeEval     = "'unsafe-eval'"
+	CSPStrictDynamic  = "'strict-dynamic'"
+	CSPReportSample   = "'report-sample'"
+	CSPWasmUnsafeEval = "'wasm-unsafe-eval'"
+)
+
+// CSPNoncePlaceholder 在指令中占位，生成响应头时替换为本次请求的
+// 'nonce-...'。使用 CSP.Nonce 添加到 script-src 与 style-src。
+const CSPNoncePlaceholder = "'nonce'"
+
+// CSP Content-Security-Policy 构建器
+//
+//	csp := security.NewCSP().
+//		DefaultSrc(security.CSPSelf).
+//		ScriptSrc(security.CSPSelf, security.CSPStrictDynamic).
+//		ImgSrc(security.CSPSelf, "data:").
+//		Nonce()
+//
+// 方法返回接收者以便链式调用；派生路由策略前先 Clone。
+type CSP struct {
+	order      []string
+	directives map[string][]string
+}
+
+// NewCSP 创建空的 CSP 构建器
+func NewCSP() *CSP {
+	return &CSP{directives: make(map[string][]string)}
+}
+
+// DefaultCSP 返回默认策略：所有资源仅限同源，禁止插件与被嵌入，
+// 并升级不安全请求
+func DefaultCSP() *CSP {
+	return NewCSP().
+		DefaultSrc(CSPSelf).
+		BaseURI(CSPSelf).
+		ObjectSrc(CSPNone).
+		FrameAncestors(CSPNone).
+		FormAction(CSPSelf).
+		UpgradeInsecureRequests()
+}
+
+// Directive 追加指令的源；没有源的指令（如 upgrade-insecure-requests）
+// 只写指令名
+func (c *CSP) Directive(name string, sources ...string) *CSP {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if _, ok := c.directives[name]; !ok {
+		c.order = append(c.order, name)
+		c.directives[name] = nil
+	}
+	for _, src := range sources {
+		if !containsString(c.directives[name], src) {
+			c.directives[name] = append(c.directives[name], src)
+		}
+	}
+	return c
+}
+
+// Set 替换指令的全部源
+func (c *CSP) Set(name string, sources ...string) *CSP {
+	c.Remove(name)
+	return c.Directive(name, sources...)
+}
+
+// Remove 删除指令
+func (c *CSP) Remove(name string) *CSP {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if _, ok := c.directives[name]; !ok {
+		return c
+	}
+	delete(c.directives, name)
+	for i, n := range c.order {
+		if n == name {
+			c.order = append(c.order[:i:i], c.order[i+1:]...)
+			break
+		}
+	}
+	return c
+}
+
+// 常用 fetch / document / navigation 指令
+
+func (c *CSP) DefaultSrc(sources ...string) *CSP { return c.Directive("default-src", sources...) }
+func (c *CSP) ScriptSrc(sources ...string) *CSP  { return c.Directive("script-src", sources...) }
+func (c *CSP) StyleSrc(sources ...string) *CSP   { return c.Directive("style-src", sources...) }
+func (c *CSP) ImgSrc(sources ...string) *CSP     { return c.Directive("img-src", sources...) }
+func (c *CSP) ConnectSrc(sources ...string) *CSP { return c.Directive("connect-src", sources...) }
+func (c *CSP) FontSrc(sources ...string) *CSP    { return c.Directive("font-src", sources...) }
+func (c *CSP) MediaSrc(sources ...string) *CSP   { return c.Directive("media-src", sources...) }
+func (c *CSP) FrameSrc(sources ...string) *CSP   { return c.Directive("frame-src", sources...) }
+func (c *CSP) WorkerSrc(sources ...string) *CSP  { return c.Directive("worker-src", sources...) }
+func (c *CSP) ObjectSrc(sources ...string) *CSP  { return c.Directive("object-src", sources...) }
+func (c *CSP) BaseURI(sources ...string) *CSP    { return c.Directive("base-uri", sources...) }
+func (c *CSP) FormAction(sources ...string) *CSP { return c.Directive("form-action", sources...) }
+
+// FrameAncestors 控制哪些页面可以嵌入本页，取代 X-Frame-Options
+func (c *CSP) FrameAncestors(sources ...string) *CSP {
+	return c.Directive("frame-ancestors", sources...)
+}
+
+// UpgradeInsecureRequests 让浏览器将 http 子资源升级为 https
+func (c *CSP) UpgradeInsecureRequests() *CSP {
+	return c.Directive("upgrade-insecure-requests")
+}
+
+// ReportTo 设置 report-to 分组名（需配合 Reporting-Endpoints 头）
+func (c *CSP) ReportTo(group string) *CSP { return c.Set("report-to", group) }
+
+// ReportURI 设置违规报告地址（已废弃但兼容性更好）
+func (c *CSP) ReportURI(uri string) *CSP { return c.Set("report-uri", uri) }
+
+// Nonce 为 script-src 与 style-src 启用每请求 nonce。未声明的指令会以
+// default-src 的源为基础创建，避免收窄策略之外的意外放宽。
+func (c *CSP) Nonce() *CSP {
+	for _, name := range []string{"script-src", "style-src"} {
+		if _, ok := c.directives[name]; !ok {
+			c.Directive(name, c.directives["default-src"]...)
+		}
+		c.Directive(name, CSPNoncePlaceholder)
+	}
+	return c
+}
+
+// UsesNonce 报告策略是否包含 nonce 占位
+func (c *CSP) UsesNonce() bool {
+	for _, sources := range c.directives {
+		if containsString(sources, CSPNoncePlaceholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// Clone 深拷贝策略，用于派生路由级策略
+func (c *CSP) Clone() *CSP {
+	clone := &CSP{
+		order:      append([]string(nil), c.order...),
+		directives: make(map[string][]string, len(c.directives)),
+	}
+	for name, sources := range c.directives {
+		clone.directives[name] = append([]string(nil), sources...)
+	}
+	return clone
+}
+
+// Build 生成响应头的值，nonce 替换占位；nonce 为空时占位被移除
+func (c *CSP) Build(nonce string) string {
+	var b strings.Builder
+	for _, name := range c.order {
+		if b.Len() > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(name)
+		for _, src := range c.directives[name] {
+			if src == CSPNoncePlaceholder {
+				if nonce == "" {
+					continue
+				}
+				src = "'nonce-" + nonce + "'"
+			}
+			b.WriteByte(' ')
+			b.WriteString(src)
+		}
+	}
+	return b.String()
+}
+
+// String 返回不含 nonce 的策略
+func (c *CSP) String() string {
+	return c.Build("")
+}
+
+type cspNonceKey struct{}
+
+// GenerateNonce 生成 128 位随机 nonce（base64）
+func GenerateNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// CSPNonce 返回本次请求的 nonce，供模板写入 <script nonce="...">；
+// 未启用 nonce 时返回空字符串
+func CSPNonce(ctx context.Context) string {
+	nonce, _ := ctx.Value(cspNonceKey{}).(string)
+	return nonce
+}
+
+// CSPNonceAttr 返回可直接写入 html/template 的 nonce="..." 属性
+func CSPNonceAttr(ctx context.Context) template.HTMLAttr {
+	nonce := CSPNonce(ctx)
+	if nonce == "" {
+		return ""
+	}
+	return template.HTMLAttr(`nonce="` + nonce + `"`)
+}
+
+// WithCSP 路由级 CSP 覆盖中间件：替换 Helmet 写入的策略，复用同一请求的
+// nonce。reportOnly 为 true 时写入 Content-Security-Policy-Report-Only。
+func WithCSP(csp *CSP, reportOnly bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, ok := applyCSP(w, r, csp, reportOnly)
+			if !ok {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// applyCSP 写入 CSP 头，需要时生成 nonce 并存入请求 context
+func applyCSP(w http.ResponseWriter, r *http.Request, csp *CSP, reportOnly bool) (*http.Request, bool) {
+	h := w.Header()
+	h.Del("Content-Security-Policy")
+	h.Del("Content-Security-Policy-Report-Only")
+	if csp == nil {
+		return r, true
+	}
+
+	nonce := CSPNonce(r.Context())
+	if nonce == "" && csp.UsesNonce() {
+		var err error
+		if nonce, err = GenerateNonce(); err != nil {
+			return r, false
+		}
+		r = r.WithContext(context.WithValue(r.Context(), cspNonceKey{}, nonce))
+	}
+
+	name := "Content-Security-Policy"
+	if reportOnly {
+		name = "Content-Security-Policy-Report-Only"
+	}
+	h.Set(name, csp.Build(nonce))
+	return r, true
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,315 @@
+package security
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/leeforge/framework/http/response"
+)
+
+// CSRF 默认参数
+const (
+	DefaultCSRFCookieName = "csrf_token"
+	DefaultCSRFHeaderName = "X-CSRF-Token"
+	DefaultCSRFFieldName  = "csrf_token"
+
+	csrfTokenLength = 32
+)
+
+// CSRFConfig CSRF 防护配置
+type CSRFConfig struct {
+	// Secret 对 Cookie 中的令牌签名，只能证明 Cookie 由本服务签发。
+	// 多实例部署必须配置相同的值；为空时每个进程随机生成。
+	Secret []byte
+	// SessionID 返回请求所属会话或用户的标识，非空时一并签入 Cookie。
+	// 未配置时，能写入父域 Cookie 的子域可以把自己从本服务取得的有效
+	// Cookie 注入受害者浏览器并提交对应令牌；配置后该 Cookie 与攻击者的
+	// 会话绑定，对受害者无效。会话变化（如登录）时令牌随之更换。
+	SessionID func(r *http.Request) string
+
+	CookieName string // 默认 DefaultCSRFCookieName
+	HeaderName string // 默认 DefaultCSRFHeaderName
+	FieldName  string // 表单字段名，默认 DefaultCSRFFieldName
+
+	CookiePath   string // 默认 "/"
+	CookieDomain string
+	MaxAge       int // Cookie 有效期（秒），0 为会话 Cookie
+	Secure       bool
+	// SameSite 默认 Lax；设为 None 时 Secure 强制为 true
+	SameSite http.SameSite
+
+	// ExemptPaths 免检路径，支持 path.Match 通配符，"/webhooks/*" 匹配整个前缀
+	ExemptPaths []string
+	// TrustedOrigins 除同源外允许提交的来源，如 "https://admin.example.com"
+	TrustedOrigins []string
+}
+
+// CSRF 基于签名双提交 Cookie 的 CSRF 防护
+//
+// 令牌保存在 HttpOnly Cookie 中，页面通过 CSRFToken / CSRFField 或 SPA
+// 令牌端点取得掩码后的令牌，以请求头或表单字段提交。每次取得的掩码不同，
+// 避免压缩侧信道（BREACH）推断令牌。
+type CSRF struct {
+	config CSRFConfig
+}
+
+type csrfContextKey struct{}
+
+type csrfContext struct {
+	token     []byte
+	fieldName string
+}
+
+// NewCSRF 创建 CSRF 防护
+func NewCSRF(config CSRFConfig) (*CSRF, error) {
+	if len(config.Secret) == 0 {
+		config.Secret = make([]byte, 32)
+		if _, err := rand.Read(config.Secret); err != nil {
+			return nil, fmt.Errorf("csrf: generate secret: %w", err)
+		}
+	} else if len(config.Secret) < 16 {
+		return nil, fmt.Errorf("csrf: secret must be at least 16 bytes")
+	}
+	if config.CookieName == "" {
+		config.CookieName = DefaultCSRFCookieName
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = DefaultCSRFHeaderName
+	}
+	if config.FieldName == "" {
+		config.FieldName = DefaultCSRFFieldName
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = "/"
+	}
+	if config.SameSite == http.SameSiteDefaultMode || config.SameSite == 0 {
+		config.SameSite = http.SameSiteLaxMode
+	}
+	if config.SameSite == http.SameSiteNoneMode {
+		config.Secure = true
+	}
+	return &CSRF{config: config}, nil
+}
+
+// Middleware CSRF 校验中间件：安全方法只下发令牌，POST/PUT/PATCH/DELETE
+// 需校验来源并提交与 Cookie 一致的令牌
+func (c *CSRF) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Cookie")
+
+		token, ok := c.cookieToken(r)
+		if !ok {
+			var err error
+			if token, err = generateCSRFToken(); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			c.setCookie(w, r, token)
+		}
+		r = r.WithContext(context.WithValue(r.Context(), csrfContextKey{}, &csrfContext{
+			token:     token,
+			fieldName: c.config.FieldName,
+		}))
+
+		if isSafeMethod(r.Method) || c.exempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if reason := c.checkOrigin(r); reason != "" {
+			http.Error(w, "CSRF validation failed: "+reason, http.StatusForbidden)
+			return
+		}
+		if !ok {
+			http.Error(w, "CSRF validation failed: missing token cookie", http.StatusForbidden)
+			return
+		}
+		submitted := r.Header.Get(c.config.HeaderName)
+		if submitted == "" {
+			submitted = r.PostFormValue(c.config.FieldName)
+		}
+		if submitted == "" {
+			http.Error(w, "CSRF validation failed: missing token", http.StatusForbidden)
+			return
+		}
+		if !hmac.Equal(unmaskCSRFToken(submitted), token) {
+			http.Error(w, "CSRF validation failed: invalid token", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TokenHandler SPA 令牌端点：返回 {"token": "..."}，前端在后续写请求中以
+// HeaderName 请求头提交。需挂在 Middleware 之后。
+func (c *CSRF) TokenHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		response.Write(w, r, http.StatusOK, map[string]string{
+			"token":  CSRFToken(r),
+			"header": c.config.HeaderName,
+		})
+	}
+}
+
+// CSRFToken 返回本次请求的掩码令牌，每次调用结果不同但都有效；
+// 请求未经过 CSRF 中间件时返回空字符串
+func CSRFToken(r *http.Request) string {
+	cc, ok := r.Context().Value(csrfContextKey{}).(*csrfContext)
+	if !ok {
+		return ""
+	}
+	return maskCSRFToken(cc.token)
+}
+
+// CSRFField 返回可直接写入 html/template 表单的隐藏字段
+func CSRFField(r *http.Request) template.HTML {
+	cc, ok := r.Context().Value(csrfContextKey{}).(*csrfContext)
+	if !ok {
+		return ""
+	}
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+		template.HTMLEscapeString(cc.fieldName), maskCSRFToken(cc.token)))
+}
+
+// cookieToken 读取并验证 Cookie 中的签名令牌
+func (c *CSRF) cookieToken(r *http.Request) ([]byte, bool) {
+	cookie, err := r.Cookie(c.config.CookieName)
+	if err != nil {
+		return nil, false
+	}
+	encoded, sig, found := strings.Cut(cookie.Value, ".")
+	if !found {
+		return nil, false
+	}
+	token, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(token) != csrfTokenLength {
+		return nil, false
+	}
+	expected, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(expected, c.sign(r, token)) {
+		return nil, false
+	}
+	return token, true
+}
+
+func (c *CSRF) setCookie(w http.ResponseWriter, r *http.Request, token []byte) {
+	value := base64.RawURLEncoding.EncodeToString(token) + "." +
+		base64.RawURLEncoding.EncodeToString(c.sign(r, token))
+	http.SetCookie(w, &http.Cookie{
+		Name:     c.config.CookieName,
+		Value:    value,
+		Path:     c.config.CookiePath,
+		Domain:   c.config.CookieDomain,
+		MaxAge:   c.config.MaxAge,
+		Secure:   c.config.Secure,
+		HttpOnly: true,
+		SameSite: c.config.SameSite,
+	})
+}
+
+// sign 签名令牌；配置 SessionID 时签名覆盖会话标识
+func (c *CSRF) sign(r *http.Request, token []byte) []byte {
+	mac := hmac.New(sha256.New, c.config.Secret)
+	if c.config.SessionID != nil {
+		if id := c.config.SessionID(r); id != "" {
+			mac.Write([]byte(id))
+			mac.Write([]byte{0})
+		}
+	}
+	mac.Write(token)
+	return mac.Sum(nil)
+}
+
+func (c *CSRF) exempt(p string) bool {
+	for _, pattern := range c.config.ExemptPaths {
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if p == prefix || strings.HasPrefix(p, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if matched, _ := path.Match(pattern, p); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrigin 校验 Origin（缺失时校验 HTTPS 请求的 Referer），返回失败原因
+func (c *CSRF) checkOrigin(r *http.Request) string {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		if r.TLS == nil {
+			return ""
+		}
+		if source = r.Header.Get("Referer"); source == "" {
+			return "missing referer"
+		}
+	}
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return "invalid origin"
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return ""
+	}
+	origin := u.Scheme + "://" + u.Host
+	for _, trusted := range c.config.TrustedOrigins {
+		if trusted == "*" || strings.EqualFold(strings.TrimSuffix(trusted, "/"), origin) {
+			return ""
+		}
+	}
+	return "untrusted origin"
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func generateCSRFToken() ([]byte, error) {
+	token := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// maskCSRFToken 返回 base64(pad || pad XOR token)
+func maskCSRFToken(token []byte) string {
+	masked := make([]byte, 2*len(token))
+	pad := masked[:len(token)]
+	if _, err := rand.Read(pad); err != nil {
+		return ""
+	}
+	for i := range token {
+		masked[len(token)+i] = pad[i] ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+func unmaskCSRFToken(masked string) []byte {
+	raw, err := base64.RawURLEncoding.DecodeString(masked)
+	if err != nil || len(raw) != 2*csrfTokenLength {
+		return nil
+	}
+	token := make([]byte, csrfTokenLength)
+	for i := range token {
+		token[i] = raw[i] ^ raw[csrfTokenLength+i]
+	}
+	return token
+}
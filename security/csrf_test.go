@@ -0,0 +1,166 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/leeforge/framework/json"
+	"github.com/stretchr/testify/require"
+)
+
+func newCSRFHandler(t *testing.T, config CSRFConfig) http.Handler {
+	t.Helper()
+	csrf, err := NewCSRF(config)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /csrf", csrf.TokenHandler())
+	mux.HandleFunc("GET /form", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(CSRFField(r)))
+	})
+	mux.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return csrf.Middleware(mux)
+}
+
+func csrfCookie(t *testing.T, rec *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == DefaultCSRFCookieName {
+			return c
+		}
+	}
+	t.Fatal("csrf cookie not set")
+	return nil
+}
+
+func TestCSRFDoubleSubmit(t *testing.T) {
+	h := newCSRFHandler(t, CSRFConfig{Secret: []byte("0123456789abcdef0123456789abcdef"), ExemptPaths: []string{"/webhooks/*"}})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/csrf", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	cookie := csrfCookie(t, rec)
+	require.True(t, cookie.HttpOnly)
+	require.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+	var body struct {
+		Data struct{ Token, Header string } `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, DefaultCSRFHeaderName, body.Data.Header)
+
+	post := func(token string, withCookie bool, header ...string) int {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if withCookie {
+			r.AddCookie(cookie)
+		}
+		if token != "" {
+			r.Header.Set(DefaultCSRFHeaderName, token)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusNoContent, post(body.Data.Token, true))
+	require.Equal(t, http.StatusNoContent, post(body.Data.Token, true, "Origin", "http://example.com"))
+	require.Equal(t, http.StatusForbidden, post(body.Data.Token, true, "Origin", "https://evil.example"))
+	require.Equal(t, http.StatusForbidden, post("", true))
+	require.Equal(t, http.StatusForbidden, post(body.Data.Token, false))
+	require.Equal(t, http.StatusForbidden, post("bogus", true))
+
+	// 伪造的未签名 Cookie 不被接受
+	forged := &http.Cookie{Name: DefaultCSRFCookieName, Value: strings.Split(cookie.Value, ".")[0] + ".AAAA"}
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.AddCookie(forged)
+	r.Header.Set(DefaultCSRFHeaderName, body.Data.Token)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	// 免检路径
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks/github", nil))
+	require.NotEqual(t, http.StatusForbidden, rec.Code)
+}
+
+func TestCSRFFormField(t *testing.T) {
+	h := newCSRFHandler(t, CSRFConfig{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/form", nil))
+	cookie := csrfCookie(t, rec)
+	field := rec.Body.String()
+	require.Contains(t, field, `name="csrf_token"`)
+	token := field[strings.Index(field, `value="`)+7 : strings.LastIndex(field, `"`)]
+
+	// 每次掩码不同，但都有效
+	require.NotEqual(t, token, maskCSRFToken(unmaskCSRFToken(token)))
+
+	form := url.Values{DefaultCSRFFieldName: {token}}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestNewCSRFConfig(t *testing.T) {
+	_, err := NewCSRF(CSRFConfig{Secret: []byte("short")})
+	require.Error(t, err)
+
+	csrf, err := NewCSRF(CSRFConfig{SameSite: http.SameSiteNoneMode})
+	require.NoError(t, err)
+	require.True(t, csrf.config.Secure)
+}
+
+func TestCSRFCookieBoundToSession(t *testing.T) {
+	h := newCSRFHandler(t, CSRFConfig{SessionID: func(r *http.Request) string {
+		return r.Header.Get("X-Session")
+	}})
+
+	// 攻击者以自己的会话取得有效 Cookie 与令牌
+	r := httptest.NewRequest(http.MethodGet, "/csrf", nil)
+	r.Header.Set("X-Session", "attacker")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	cookie := csrfCookie(t, rec)
+	var body struct {
+		Data struct{ Token string } `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+
+	post := func(session string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("X-Session", session)
+		r.Header.Set(DefaultCSRFHeaderName, body.Data.Token)
+		r.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	require.Equal(t, http.StatusNoContent, post("attacker").Code)
+
+	// 注入到受害者浏览器的同一 Cookie 不被接受，并重新下发
+	rec = post("victim")
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.NotEqual(t, cookie.Value, csrfCookie(t, rec).Value)
+}
+
+func TestSecurityMiddlewareCSRFIgnoresWildcardOrigins(t *testing.T) {
+	csrf := NewSecurityMiddleware(SecurityConfig{
+		EnableCSRF: true,
+		CORS:       CORSConfig{AllowedOrigins: []string{"*", "https://*.example.com", "https://admin.example.com"}},
+	}).CSRF()
+	require.NotNil(t, csrf)
+	require.Equal(t, []string{"https://admin.example.com"}, csrf.config.TrustedOrigins)
+}
@@ -0,0 +1,100 @@
+package security
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// HelmetConfig 安全响应头配置，零值字段不写对应的头
+type HelmetConfig struct {
+	// HSTSMaxAge Strict-Transport-Security 的 max-age（秒），0 不写
+	HSTSMaxAge            int
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+
+	// ContentTypeNosniff 写入 X-Content-Type-Options: nosniff
+	ContentTypeNosniff bool
+	// FrameOptions X-Frame-Options，如 DENY、SAMEORIGIN
+	FrameOptions string
+	// ReferrerPolicy Referrer-Policy
+	ReferrerPolicy string
+	// PermissionsPolicy Permissions-Policy，如 "camera=(), geolocation=()"
+	PermissionsPolicy string
+
+	// CrossOriginOpenerPolicy COOP，如 same-origin
+	CrossOriginOpenerPolicy string
+	// CrossOriginEmbedderPolicy COEP，如 require-corp、credentialless
+	CrossOriginEmbedderPolicy string
+	// CrossOriginResourcePolicy CORP，如 same-origin、same-site、cross-origin
+	CrossOriginResourcePolicy string
+
+	// CSP 默认的 Content-Security-Policy，路由可用 WithCSP 覆盖
+	CSP *CSP
+	// CSPReportOnly 以 Content-Security-Policy-Report-Only 下发，用于灰度新策略
+	CSPReportOnly bool
+}
+
+// DefaultHelmetConfig 返回默认安全头配置
+//
+// 旧版 X-XSS-Protection 过滤器本身可被利用泄露信息，现代浏览器已移除，
+// Helmet 统一写入 "0" 关闭它，由 CSP 提供 XSS 防护。COEP 会阻止未声明
+// CORP 的跨域资源，默认不开启。
+func DefaultHelmetConfig() HelmetConfig {
+	return HelmetConfig{
+		HSTSMaxAge:                31536000,
+		HSTSIncludeSubdomains:     true,
+		ContentTypeNosniff:        true,
+		FrameOptions:              "DENY",
+		ReferrerPolicy:            "strict-origin-when-cross-origin",
+		PermissionsPolicy:         "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+		CrossOriginOpenerPolicy:   "same-origin",
+		CrossOriginResourcePolicy: "same-origin",
+		CSP:                       DefaultCSP(),
+	}
+}
+
+// SetHeaders 写入除 CSP 以外的安全头
+func (c HelmetConfig) SetHeaders(h http.Header) {
+	if c.HSTSMaxAge > 0 {
+		value := "max-age=" + strconv.Itoa(c.HSTSMaxAge)
+		if c.HSTSIncludeSubdomains {
+			value += "; includeSubDomains"
+		}
+		if c.HSTSPreload {
+			value += "; preload"
+		}
+		h.Set("Strict-Transport-Security", value)
+	}
+	if c.ContentTypeNosniff {
+		h.Set("X-Content-Type-Options", "nosniff")
+	}
+	setHeader(h, "X-Frame-Options", c.FrameOptions)
+	setHeader(h, "Referrer-Policy", c.ReferrerPolicy)
+	setHeader(h, "Permissions-Policy", c.PermissionsPolicy)
+	setHeader(h, "Cross-Origin-Opener-Policy", c.CrossOriginOpenerPolicy)
+	setHeader(h, "Cross-Origin-Embedder-Policy", c.CrossOriginEmbedderPolicy)
+	setHeader(h, "Cross-Origin-Resource-Policy", c.CrossOriginResourcePolicy)
+	h.Set("X-XSS-Protection", "0")
+}
+
+// Helmet 安全响应头中间件。CSP 启用 nonce 时每个请求生成新的 nonce，
+// 处理函数与模板通过 CSPNonce / CSPNonceAttr 取得。
+func Helmet(config HelmetConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			config.SetHeaders(w.Header())
+			r, ok := applyCSP(w, r, config.CSP, config.CSPReportOnly)
+			if !ok {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func setHeader(h http.Header, key, value string) {
+	if value != "" {
+		h.Set(key, value)
+	}
+}
@@ -0,0 +1,72 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSPBuilder(t *testing.T) {
+	csp := NewCSP().
+		DefaultSrc(CSPSelf).
+		ImgSrc(CSPSelf, "data:", CSPSelf).
+		UpgradeInsecureRequests().
+		Nonce()
+
+	require.Equal(t, "default-src 'self'; img-src 'self' data:; upgrade-insecure-requests; script-src 'self'; style-src 'self'", csp.String())
+	require.Equal(t, "default-src 'self'; img-src 'self' data:; upgrade-insecure-requests; script-src 'self' 'nonce-abc'; style-src 'self' 'nonce-abc'", csp.Build("abc"))
+
+	derived := csp.Clone().Set("img-src", "*").Remove("upgrade-insecure-requests")
+	require.Equal(t, "default-src 'self'; script-src 'self'; style-src 'self'; img-src *", derived.String())
+	require.Contains(t, csp.String(), "img-src 'self' data:")
+}
+
+func TestHelmet(t *testing.T) {
+	config := DefaultHelmetConfig()
+	config.HSTSPreload = true
+	config.CSP = DefaultCSP().ScriptSrc(CSPSelf).Nonce()
+
+	var nonce string
+	h := Helmet(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce = CSPNonce(r.Context())
+		require.Equal(t, `nonce="`+nonce+`"`, string(CSPNonceAttr(r.Context())))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	header := rec.Header()
+	require.Equal(t, "max-age=31536000; includeSubDomains; preload", header.Get("Strict-Transport-Security"))
+	require.Equal(t, "0", header.Get("X-XSS-Protection"))
+	require.Equal(t, "same-origin", header.Get("Cross-Origin-Opener-Policy"))
+	require.Equal(t, "same-origin", header.Get("Cross-Origin-Resource-Policy"))
+	require.Empty(t, header.Get("Cross-Origin-Embedder-Policy"))
+	require.NotEmpty(t, header.Get("Permissions-Policy"))
+	require.NotEmpty(t, nonce)
+	require.Contains(t, header.Get("Content-Security-Policy"), "script-src 'self' 'nonce-"+nonce+"'")
+
+	// 每个请求生成新的 nonce
+	first := nonce
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	require.NotEqual(t, first, nonce)
+}
+
+func TestWithCSPOverride(t *testing.T) {
+	config := DefaultHelmetConfig()
+	config.CSP = DefaultCSP().Nonce()
+	route := config.CSP.Clone().Set("frame-ancestors", "https://partner.example")
+
+	var nonce string
+	h := Helmet(config)(WithCSP(route, true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce = CSPNonce(r.Context())
+	})))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/embed", nil))
+	require.Empty(t, rec.Header().Get("Content-Security-Policy"))
+	policy := rec.Header().Get("Content-Security-Policy-Report-Only")
+	require.Contains(t, policy, "frame-ancestors https://partner.example")
+	require.Equal(t, 2, strings.Count(policy, "'nonce-"+nonce+"'"))
+}
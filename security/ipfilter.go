@@ -0,0 +1,191 @@
+package security
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// IPFilter 基于白名单/黑名单的 IP 过滤器
+//
+// 名单条目支持：
+//   - 单个地址："192.0.2.1"、"2001:db8::1"
+//   - CIDR："10.0.0.0/8"、"2001:db8::/32"
+//   - IPv4 通配符："192.168.*.*"、"10.*"（省略的字节视为 *）
+//   - "*"：匹配所有地址
+//
+// 黑名单优先于白名单；白名单为空时放行所有未被拉黑的地址。
+// 名单可通过 Update 在运行时原子替换，并发安全。
+type IPFilter struct {
+	lists atomic.Pointer[ipLists]
+}
+
+type ipLists struct {
+	whitelist []ipRule
+	blacklist []ipRule
+}
+
+// ipRule 单条名单规则
+type ipRule struct {
+	any    bool
+	prefix netip.Prefix
+	octets [4]int // 通配符规则，-1 表示任意
+	wild   bool
+}
+
+// NewIPFilter 创建 IP 过滤器，名单中存在无法解析的条目时返回错误
+func NewIPFilter(whitelist, blacklist []string) (*IPFilter, error) {
+	f := &IPFilter{}
+	if err := f.Update(whitelist, blacklist); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Update 替换白名单与黑名单。任一条目无效时返回错误并保留原名单。
+func (f *IPFilter) Update(whitelist, blacklist []string) error {
+	white, err := parseIPRules(whitelist)
+	if err != nil {
+		return err
+	}
+	black, err := parseIPRules(blacklist)
+	if err != nil {
+		return err
+	}
+	f.lists.Store(&ipLists{whitelist: white, blacklist: black})
+	return nil
+}
+
+// Allow 检查 IP 是否放行。ip 可以带端口（"192.0.2.1:5000"、"[::1]:80"），
+// 无法解析的地址仅在没有任何名单时放行。
+func (f *IPFilter) Allow(ip string) bool {
+	lists := f.lists.Load()
+	if lists == nil || (len(lists.whitelist) == 0 && len(lists.blacklist) == 0) {
+		return true
+	}
+
+	addr, ok := parseHostIP(ip)
+	if !ok {
+		return false
+	}
+	if matchIPRules(lists.blacklist, addr) {
+		return false
+	}
+	if len(lists.whitelist) > 0 {
+		return matchIPRules(lists.whitelist, addr)
+	}
+	return true
+}
+
+// Blocked 检查 IP 是否命中黑名单
+func (f *IPFilter) Blocked(ip string) bool {
+	lists := f.lists.Load()
+	if lists == nil || len(lists.blacklist) == 0 {
+		return false
+	}
+	addr, ok := parseHostIP(ip)
+	return ok && matchIPRules(lists.blacklist, addr)
+}
+
+func parseIPRules(entries []string) ([]ipRule, error) {
+	rules := make([]ipRule, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rule, err := parseIPRule(entry)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseIPRule(entry string) (ipRule, error) {
+	switch {
+	case entry == "*":
+		return ipRule{any: true}, nil
+	case strings.Contains(entry, "/"):
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return ipRule{}, fmt.Errorf("invalid IP rule %q: %w", entry, err)
+		}
+		return ipRule{prefix: prefix.Masked()}, nil
+	case strings.Contains(entry, "*"):
+		return parseWildcard(entry)
+	}
+
+	addr, ok := parseHostIP(entry)
+	if !ok {
+		return ipRule{}, fmt.Errorf("invalid IP rule %q", entry)
+	}
+	return ipRule{prefix: netip.PrefixFrom(addr, addr.BitLen())}, nil
+}
+
+// parseWildcard 解析 IPv4 通配符，如 "192.168.*.*" 或 "10.*"
+func parseWildcard(entry string) (ipRule, error) {
+	parts := strings.Split(entry, ".")
+	if len(parts) > 4 {
+		return ipRule{}, fmt.Errorf("invalid IP rule %q", entry)
+	}
+	rule := ipRule{wild: true, octets: [4]int{-1, -1, -1, -1}}
+	for i, part := range parts {
+		if part == "*" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || n > 255 {
+			return ipRule{}, fmt.Errorf("invalid IP rule %q", entry)
+		}
+		rule.octets[i] = n
+	}
+	if len(parts) < 4 && parts[len(parts)-1] != "*" {
+		return ipRule{}, fmt.Errorf("invalid IP rule %q", entry)
+	}
+	return rule, nil
+}
+
+func (r ipRule) match(addr netip.Addr) bool {
+	switch {
+	case r.any:
+		return true
+	case r.wild:
+		if !addr.Is4() {
+			return false
+		}
+		b := addr.As4()
+		for i, octet := range r.octets {
+			if octet >= 0 && int(b[i]) != octet {
+				return false
+			}
+		}
+		return true
+	}
+	return r.prefix.Contains(addr)
+}
+
+func matchIPRules(rules []ipRule, addr netip.Addr) bool {
+	for _, rule := range rules {
+		if rule.match(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHostIP 解析可能带端口的地址，IPv4 映射的 IPv6 地址按 IPv4 处理
+func parseHostIP(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
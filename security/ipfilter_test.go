@@ -0,0 +1,61 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPFilter(t *testing.T) {
+	f, err := NewIPFilter(
+		[]string{"10.0.0.0/8", "192.168.*.*", "2001:db8::/32", "203.0.113.5"},
+		[]string{"10.0.13.7", "192.168.9.*"},
+	)
+	require.NoError(t, err)
+
+	require.True(t, f.Allow("10.1.2.3"))
+	require.True(t, f.Allow("10.1.2.3:5000"))
+	require.True(t, f.Allow("192.168.1.1"))
+	require.True(t, f.Allow("[2001:db8::1]:443"))
+	require.True(t, f.Allow("::ffff:203.0.113.5"))
+	require.False(t, f.Allow("10.0.13.7"))
+	require.True(t, f.Blocked("10.0.13.7:80"))
+	require.False(t, f.Allow("192.168.9.20"))
+	require.False(t, f.Allow("8.8.8.8"))
+	require.False(t, f.Allow("not-an-ip"))
+
+	// 只有黑名单时放行其余地址
+	require.NoError(t, f.Update(nil, []string{"10.*"}))
+	require.False(t, f.Allow("10.9.9.9"))
+	require.True(t, f.Allow("8.8.8.8"))
+
+	// 无效条目保留原名单
+	for _, bad := range []string{"10.0.0.0/33", "300.*", "10.*.1", "1.2.3.4.*", "host"} {
+		require.Error(t, f.Update([]string{bad}, nil), bad)
+	}
+	require.False(t, f.Allow("10.9.9.9"))
+}
+
+func TestIPFilterMiddlewareReload(t *testing.T) {
+	sm := NewSecurityMiddleware(SecurityConfig{IPBlacklist: []string{"192.0.2.0/24"}})
+	h := sm.Chain()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(remoteAddr string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusForbidden, do("192.0.2.10:41000"))
+	require.Equal(t, http.StatusOK, do("198.51.100.1:41000"))
+
+	require.NoError(t, sm.SetIPLists([]string{"192.0.2.*"}, nil))
+	require.Equal(t, http.StatusOK, do("192.0.2.10:41000"))
+	require.Equal(t, http.StatusForbidden, do("198.51.100.1:41000"))
+
+	require.Panics(t, func() { NewSecurityMiddleware(SecurityConfig{IPWhitelist: []string{"bogus"}}) })
+}
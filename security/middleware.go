@@ -7,21 +7,27 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+
+	"github.com/leeforge/framework/request"
 )
 
 // SecurityMiddleware 安全中间件
 type SecurityMiddleware struct {
-	config SecurityConfig
+	config   SecurityConfig
+	ipFilter *IPFilter
+	csrf     *CSRF
 }
 
 // SecurityConfig 安全配置
 type SecurityConfig struct {
 	CORS            CORSConfig
 	Helmet          bool
-	IPWhitelist     []string
+	HelmetConfig    *HelmetConfig // nil 时使用 DefaultHelmetConfig
+	IPWhitelist     []string      // 支持单个地址、CIDR 与 IPv4 通配符，见 IPFilter
 	IPBlacklist     []string
 	RequestSize     int64
 	EnableCSRF      bool
+	CSRF            CSRFConfig // TrustedOrigins 为空时使用 CORS.AllowedOrigins 中不含通配符的来源
 	EnableRateLimit bool
 }
 
@@ -35,11 +41,48 @@ type CORSConfig struct {
 	MaxAge           int
 }
 
-// NewSecurityMiddleware 创建安全中间件
+// NewSecurityMiddleware 创建安全中间件。IP 名单与 CSRF 配置属于启动配置，
+// 无效时 panic；运行时加载的名单请使用 SetIPLists 并处理返回的错误。
 func NewSecurityMiddleware(config SecurityConfig) *SecurityMiddleware {
-	return &SecurityMiddleware{
-		config: config,
+	filter, err := NewIPFilter(config.IPWhitelist, config.IPBlacklist)
+	if err != nil {
+		panic("security: " + err.Error())
+	}
+	s := &SecurityMiddleware{
+		config:   config,
+		ipFilter: filter,
+	}
+	if config.EnableCSRF {
+		csrfConfig := config.CSRF
+		if len(csrfConfig.TrustedOrigins) == 0 {
+			// 通配来源只适合 CORS 读取，不能用来放行写请求
+			for _, origin := range config.CORS.AllowedOrigins {
+				if !strings.Contains(origin, "*") {
+					csrfConfig.TrustedOrigins = append(csrfConfig.TrustedOrigins, origin)
+				}
+			}
+		}
+		if s.csrf, err = NewCSRF(csrfConfig); err != nil {
+			panic("security: " + err.Error())
+		}
 	}
+	return s
+}
+
+// SetIPLists 在运行时替换 IP 白名单与黑名单，对后续请求立即生效。
+// 任一条目无效时返回错误并保留原名单。
+func (s *SecurityMiddleware) SetIPLists(whitelist, blacklist []string) error {
+	return s.ipFilter.Update(whitelist, blacklist)
+}
+
+// CSRF 返回 CSRF 防护（EnableCSRF 为 false 时为 nil），用于挂载令牌端点
+func (s *SecurityMiddleware) CSRF() *CSRF {
+	return s.csrf
+}
+
+// IPFilter 返回中间件使用的 IP 过滤器
+func (s *SecurityMiddleware) IPFilter() *IPFilter {
+	return s.ipFilter
 }
 
 // Chain 安全中间件链
@@ -75,29 +118,18 @@ func (s *SecurityMiddleware) Chain() func(next http.Handler) http.Handler {
 // ipFilterMiddleware IP 过滤中间件
 func (s *SecurityMiddleware) ipFilterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := r.RemoteAddr
+		ip := request.GetClientIP(r)
 
 		// 黑名单检查
-		for _, black := range s.config.IPBlacklist {
-			if ip == black {
-				http.Error(w, "IP blocked", http.StatusForbidden)
-				return
-			}
+		if s.ipFilter.Blocked(ip) {
+			http.Error(w, "IP blocked", http.StatusForbidden)
+			return
 		}
 
 		// 白名单检查
-		if len(s.config.IPWhitelist) > 0 {
-			allowed := false
-			for _, white := range s.config.IPWhitelist {
-				if ip == white {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				http.Error(w, "IP not allowed", http.StatusForbidden)
-				return
-			}
+		if !s.ipFilter.Allow(ip) {
+			http.Error(w, "IP not allowed", http.StatusForbidden)
+			return
 		}
 
 		next.ServeHTTP(w, r)
@@ -124,7 +156,7 @@ func (s *SecurityMiddleware) corsMiddleware(next http.Handler) http.Handler {
 		if len(s.config.CORS.AllowedOrigins) > 0 {
 			allowed := false
 			for _, o := range s.config.CORS.AllowedOrigins {
-				if o == "*" || o == origin {
+				if o == "*" || ConstantTimeEqual(o, origin) {
 					w.Header().Set("Access-Control-Allow-Origin", o)
 					allowed = true
 					break
@@ -158,65 +190,16 @@ func (s *SecurityMiddleware) corsMiddleware(next http.Handler) http.Handler {
 
 // helmetMiddleware Security Headers 中间件
 func (s *SecurityMiddleware) helmetMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// 防止 MIME 类型嗅探
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-
-		// 防止点击劫持
-		w.Header().Set("X-Frame-Options", "DENY")
-
-		// XSS 保护
-		w.Header().Set("X-XSS-Protection", "1; mode=block")
-
-		// HSTS (HTTPS)
-		w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
-
-		// Referrer 策略
-		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
-
-		// 内容安全策略 (简化)
-		w.Header().Set("Content-Security-Policy", "default-src 'self'")
-
-		next.ServeHTTP(w, r)
-	})
+	config := DefaultHelmetConfig()
+	if s.config.HelmetConfig != nil {
+		config = *s.config.HelmetConfig
+	}
+	return Helmet(config)(next)
 }
 
 // csrfMiddleware CSRF 防护中间件
 func (s *SecurityMiddleware) csrfMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// 只检查 POST, PUT, DELETE, PATCH
-		method := r.Method
-		if method != "POST" && method != "PUT" && method != "DELETE" && method != "PATCH" {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// 检查 Origin 和 Referer
-		origin := r.Header.Get("Origin")
-		referer := r.Header.Get("Referer")
-
-		if origin == "" && referer == "" {
-			http.Error(w, "CSRF validation failed: missing origin/referer", http.StatusForbidden)
-			return
-		}
-
-		// 如果有 Origin，验证它
-		if origin != "" {
-			allowed := false
-			for _, o := range s.config.CORS.AllowedOrigins {
-				if o == "*" || strings.Contains(origin, o) {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				http.Error(w, "CSRF validation failed: invalid origin", http.StatusForbidden)
-				return
-			}
-		}
-
-		next.ServeHTTP(w, r)
-	})
+	return s.csrf.Middleware(next)
 }
 
 // Crypto 加密工具
@@ -232,6 +215,8 @@ func NewCrypto(secretKey string) *Crypto {
 }
 
 // HashPassword 哈希密码
+//
+// Deprecated: HMAC 不适合存储密码，请使用 auth/password
 func (c *Crypto) HashPassword(password string) (string, error) {
 	// 使用 HMAC-SHA256
 	h := hmac.New(sha256.New, c.secretKey)
@@ -245,7 +230,7 @@ func (c *Crypto) VerifyPassword(password, hash string) bool {
 	if err != nil {
 		return false
 	}
-	return expectedHash == hash
+	return ConstantTimeEqual(expectedHash, hash)
 }
 
 // Encrypt 数据加密（简化版）
@@ -321,6 +306,8 @@ type RateLimitConfig struct {
 }
 
 // RateLimiter 限流器
+//
+// Deprecated: 使用 ratelimit.Limiter
 type RateLimiter struct {
 	config  RateLimitConfig
 	storage map[string]int
@@ -419,15 +406,15 @@ func (s *SecurityHeaders) Apply(w http.ResponseWriter) {
 	}
 }
 
-// GetDefaultHeaders 获取默认安全头
+// GetDefaultHeaders 获取默认安全头（不含 CSP）
 func GetDefaultHeaders() map[string]string {
-	return map[string]string{
-		"X-Content-Type-Options":    "nosniff",
-		"X-Frame-Options":           "DENY",
-		"X-XSS-Protection":          "1; mode=block",
-		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
-		"Referrer-Policy":           "strict-origin-when-cross-origin",
+	h := make(http.Header)
+	DefaultHelmetConfig().SetHeaders(h)
+	headers := make(map[string]string, len(h))
+	for key := range h {
+		headers[key] = h.Get(key)
 	}
+	return headers
 }
 
 // SecurityMonitor 安全监控
@@ -0,0 +1,362 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leeforge/framework/errors"
+	"github.com/leeforge/framework/http/response"
+)
+
+// 请求签名头
+//
+//	X-Content-SHA256: <hex(sha256(body))>
+//	X-Signature: keyId="partner-1",ts=1700000000,nonce="...",sig="<base64>"
+//
+// sig 为 HMAC-SHA256(secret, StringToSign)，StringToSign 按行拼接：
+// 方法、转义后的路径、排序后的查询串、ts、nonce、正文哈希。
+const (
+	SignatureHeader   = "X-Signature"
+	ContentHashHeader = "X-Content-SHA256"
+)
+
+// 签名默认参数
+const (
+	DefaultSignatureMaxSkew = 5 * time.Minute
+	DefaultSignatureMaxBody = 10 << 20
+)
+
+// ErrUnknownSigningKey 密钥不存在
+var ErrUnknownSigningKey = errors.New(errors.ErrorTypeUnauthorized, "unknown signing key").
+	WithCode("SIGNATURE_INVALID")
+
+// SigningKeyStore 按 key ID 查找共享密钥
+type SigningKeyStore interface {
+	SigningKey(ctx context.Context, keyID string) ([]byte, error)
+}
+
+// StaticSigningKeys 固定的 key ID → 密钥表
+type StaticSigningKeys map[string][]byte
+
+// SigningKey 实现 SigningKeyStore
+func (k StaticSigningKeys) SigningKey(_ context.Context, keyID string) ([]byte, error) {
+	if secret, ok := k[keyID]; ok {
+		return secret, nil
+	}
+	return nil, ErrUnknownSigningKey
+}
+
+// RequestSigner 客户端请求签名器，可作为 httpclient.Options.Signer
+type RequestSigner struct {
+	KeyID  string
+	Secret []byte
+	// Now 默认 time.Now
+	Now func() time.Time
+}
+
+// Sign 读取请求体计算哈希并写入签名头。请求体可重放（GetBody）时读取副本，
+// 否则读取后替换为内存副本并设置 GetBody。
+func (s *RequestSigner) Sign(req *http.Request) error {
+	body, err := snapshotBody(req)
+	if err != nil {
+		return fmt.Errorf("security: sign request: %w", err)
+	}
+	nonce, err := signatureNonce()
+	if err != nil {
+		return fmt.Errorf("security: sign request: %w", err)
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+
+	hash := sha256.Sum256(body)
+	params := signatureParams{
+		keyID:       s.KeyID,
+		timestamp:   now().Unix(),
+		nonce:       nonce,
+		contentHash: hex.EncodeToString(hash[:]),
+	}
+	params.signature = computeSignature(s.Secret, req, params)
+
+	req.Header.Set(ContentHashHeader, params.contentHash)
+	req.Header.Set(SignatureHeader, params.String())
+	return nil
+}
+
+// SignatureOptions 签名校验配置
+type SignatureOptions struct {
+	Keys SigningKeyStore
+	// MaxSkew 允许的时钟偏差，默认 DefaultSignatureMaxSkew
+	MaxSkew time.Duration
+	// Nonces 防重放存储，默认进程内存储；多实例部署请使用共享存储
+	Nonces NonceStore
+	// MaxBody 参与哈希的最大请求体，默认 DefaultSignatureMaxBody
+	MaxBody int64
+	// Now 默认 time.Now
+	Now func() time.Time
+}
+
+type signatureKeyIDKey struct{}
+
+// SignatureKeyID 返回通过校验的调用方 key ID
+func SignatureKeyID(ctx context.Context) string {
+	keyID, _ := ctx.Value(signatureKeyIDKey{}).(string)
+	return keyID
+}
+
+// SignatureMiddleware 请求签名校验中间件：校验时间窗口、正文哈希与 HMAC，
+// 并拒绝窗口内重复的 nonce。失败返回 401。
+func SignatureMiddleware(opts SignatureOptions) func(http.Handler) http.Handler {
+	if opts.MaxSkew <= 0 {
+		opts.MaxSkew = DefaultSignatureMaxSkew
+	}
+	if opts.MaxBody <= 0 {
+		opts.MaxBody = DefaultSignatureMaxBody
+	}
+	if opts.Nonces == nil {
+		opts.Nonces = NewMemoryNonceStore()
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keyID, err := verifyRequest(r, &opts)
+			if err != nil {
+				response.WriteError(w, r, err)
+				return
+			}
+			ctx := context.WithValue(r.Context(), signatureKeyIDKey{}, keyID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func verifyRequest(r *http.Request, opts *SignatureOptions) (string, error) {
+	params, err := parseSignatureHeader(r.Header.Get(SignatureHeader))
+	if err != nil {
+		return "", signatureError(err.Error())
+	}
+
+	ts := time.Unix(params.timestamp, 0)
+	if skew := opts.Now().Sub(ts); skew > opts.MaxSkew || skew < -opts.MaxSkew {
+		return "", signatureError("timestamp outside allowed window")
+	}
+
+	secret, err := opts.Keys.SigningKey(r.Context(), params.keyID)
+	if err != nil {
+		return "", signatureError("unknown signing key")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, opts.MaxBody+1))
+	if err != nil {
+		return "", signatureError("unreadable body")
+	}
+	if int64(len(body)) > opts.MaxBody {
+		return "", errors.New(errors.ErrorTypeInvalid, "request body too large").
+			WithCode("PAYLOAD_TOO_LARGE").
+			WithHTTPStatus(http.StatusRequestEntityTooLarge)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	hash := sha256.Sum256(body)
+	params.contentHash = hex.EncodeToString(hash[:])
+	if !hmac.Equal([]byte(params.contentHash), []byte(strings.ToLower(r.Header.Get(ContentHashHeader)))) {
+		return "", signatureError("content hash mismatch")
+	}
+	expected := computeSignature(secret, r, params)
+	if !hmac.Equal([]byte(expected), []byte(params.signature)) {
+		return "", signatureError("signature mismatch")
+	}
+
+	// 签名通过后才记录 nonce，伪造请求无法占用合法 nonce
+	fresh, err := opts.Nonces.CheckAndStore(r.Context(), params.keyID+":"+params.nonce, 2*opts.MaxSkew)
+	if err != nil {
+		return "", errors.WrapWithType(err, errors.ErrorTypeInternal, "nonce store unavailable")
+	}
+	if !fresh {
+		return "", signatureError("replayed nonce")
+	}
+	return params.keyID, nil
+}
+
+func signatureError(reason string) error {
+	return errors.New(errors.ErrorTypeUnauthorized, "invalid request signature: "+reason).
+		WithCode("SIGNATURE_INVALID").
+		WithHTTPStatus(http.StatusUnauthorized)
+}
+
+type signatureParams struct {
+	keyID       string
+	timestamp   int64
+	nonce       string
+	signature   string
+	contentHash string
+}
+
+func (p signatureParams) String() string {
+	return fmt.Sprintf(`keyId=%q,ts=%d,nonce=%q,sig=%q`, p.keyID, p.timestamp, p.nonce, p.signature)
+}
+
+func parseSignatureHeader(header string) (signatureParams, error) {
+	var p signatureParams
+	if header == "" {
+		return p, fmt.Errorf("missing %s header", SignatureHeader)
+	}
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return p, fmt.Errorf("malformed %s header", SignatureHeader)
+		}
+		value = strings.Trim(value, `"`)
+		switch key {
+		case "keyId":
+			p.keyID = value
+		case "ts":
+			ts, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return p, fmt.Errorf("malformed timestamp")
+			}
+			p.timestamp = ts
+		case "nonce":
+			p.nonce = value
+		case "sig":
+			p.signature = value
+		}
+	}
+	if p.keyID == "" || p.timestamp == 0 || p.nonce == "" || p.signature == "" {
+		return p, fmt.Errorf("incomplete %s header", SignatureHeader)
+	}
+	return p, nil
+}
+
+// StringToSign 返回参与签名的规范字符串，便于其他语言的客户端对照实现
+func StringToSign(req *http.Request, timestamp int64, nonce, contentHash string) string {
+	return strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		strconv.FormatInt(timestamp, 10),
+		nonce,
+		contentHash,
+	}, "\n")
+}
+
+func computeSignature(secret []byte, req *http.Request, p signatureParams) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(StringToSign(req, p.timestamp, p.nonce, p.contentHash)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signatureNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func snapshotBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+
+// NonceStore 防重放存储
+type NonceStore interface {
+	// CheckAndStore 记录 nonce，首次出现返回 true，窗口内重复返回 false
+	CheckAndStore(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// MemoryNonceStore 进程内 nonce 存储
+type MemoryNonceStore struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+	lastGC  time.Time
+}
+
+// NewMemoryNonceStore 创建进程内 nonce 存储
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{entries: make(map[string]time.Time)}
+}
+
+// CheckAndStore 实现 NonceStore
+func (s *MemoryNonceStore) CheckAndStore(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastGC) > ttl {
+		for key, expiry := range s.entries {
+			if now.After(expiry) {
+				delete(s.entries, key)
+			}
+		}
+		s.lastGC = now
+	}
+	if expiry, ok := s.entries[nonce]; ok && now.Before(expiry) {
+		return false, nil
+	}
+	s.entries[nonce] = now.Add(ttl)
+	return true, nil
+}
+
+// nonceCache 是 cache.CacheAdapter 中 nonce 存储用到的方法
+type nonceCache interface {
+	Exists(key string) bool
+	Set(key string, value interface{}, ttl time.Duration) error
+}
+
+type cacheNonceStore struct {
+	cache nonceCache
+}
+
+// NewCacheNonceStore 基于 cache.CacheAdapter（如 RedisAdapter）的共享 nonce 存储。
+// Exists 与 Set 非原子，并发重放同一 nonce 存在极小窗口；要求严格时请实现基于
+// SET NX 的 NonceStore。
+func NewCacheNonceStore(c nonceCache) NonceStore {
+	return &cacheNonceStore{cache: c}
+}
+
+func (s *cacheNonceStore) CheckAndStore(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	key := "signature:nonce:" + nonce
+	if s.cache.Exists(key) {
+		return false, nil
+	}
+	if err := s.cache.Set(key, 1, ttl); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,86 @@
+package security_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leeforge/framework/httpclient"
+	"github.com/leeforge/framework/retry"
+	"github.com/leeforge/framework/security"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureRoundTrip(t *testing.T) {
+	secret := []byte("shared-secret")
+	var gotBody, gotKey string
+	var calls int
+	srv := httptest.NewServer(security.SignatureMiddleware(security.SignatureOptions{
+		Keys: security.StaticSigningKeys{"partner-1": secret},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		b, _ := io.ReadAll(r.Body)
+		gotBody, gotKey = string(b), security.SignatureKeyID(r.Context())
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	defer srv.Close()
+
+	// 重试会重新签名，不会被当作重放
+	policy := retry.Policy{MaxAttempts: 2, InitialInterval: time.Millisecond}
+	client := httpclient.New(httpclient.Options{
+		Signer: &security.RequestSigner{KeyID: "partner-1", Secret: secret},
+		Retry:  &policy,
+	})
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/orders/7?b=2&a=1", strings.NewReader(`{"qty":3}`))
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, `{"qty":3}`, gotBody)
+	require.Equal(t, "partner-1", gotKey)
+}
+
+func TestSignatureRejections(t *testing.T) {
+	secret := []byte("shared-secret")
+	now := time.Unix(1_700_000_000, 0)
+	h := security.SignatureMiddleware(security.SignatureOptions{
+		Keys: security.StaticSigningKeys{"partner-1": secret},
+		Now:  func() time.Time { return now },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	signed := func(body string, at time.Time, key []byte) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(body))
+		signer := &security.RequestSigner{KeyID: "partner-1", Secret: key, Now: func() time.Time { return at }}
+		require.NoError(t, signer.Sign(r))
+		return r
+	}
+	do := func(r *http.Request) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	r := signed(`{"a":1}`, now.Add(-time.Minute), secret)
+	replay := r.Clone(r.Context())
+	replay.Body = io.NopCloser(strings.NewReader(`{"a":1}`))
+	require.Equal(t, http.StatusNoContent, do(r))
+	require.Equal(t, http.StatusUnauthorized, do(replay))
+
+	require.Equal(t, http.StatusUnauthorized, do(signed(`{}`, now.Add(-10*time.Minute), secret)))
+	require.Equal(t, http.StatusUnauthorized, do(signed(`{}`, now, []byte("wrong"))))
+	require.Equal(t, http.StatusUnauthorized, do(httptest.NewRequest(http.MethodPost, "/hooks", nil)))
+
+	tampered := signed(`{"a":1}`, now, secret)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"a":2}`))
+	require.Equal(t, http.StatusUnauthorized, do(tampered))
+}
@@ -0,0 +1,308 @@
+package testing
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// DistributionCompareConfig controls how two latency distributions are compared.
+type DistributionCompareConfig struct {
+	// Alpha is the significance level; a difference is significant when p < Alpha.
+	Alpha float64
+	// MinSamples is the minimum size of each sample before a verdict is given.
+	MinSamples int
+	// MinEffect is the smallest relative slowdown (median, or p99 for tail
+	// regressions) that counts as a regression, so statistically significant
+	// but negligible shifts on very large samples are ignored.
+	MinEffect float64
+}
+
+// DefaultDistributionCompareConfig returns alpha 0.01, 30 samples and a 5% effect.
+func DefaultDistributionCompareConfig() DistributionCompareConfig {
+	return DistributionCompareConfig{
+		Alpha:      0.01,
+		MinSamples: 30,
+		MinEffect:  0.05,
+	}
+}
+
+// DistributionComparison is the outcome of comparing a current latency sample
+// against a baseline.
+type DistributionComparison struct {
+	BaselineN int
+	CurrentN  int
+
+	BaselineMedian time.Duration
+	CurrentMedian  time.Duration
+	BaselineP99    time.Duration
+	CurrentP99     time.Duration
+	MedianChange   float64 // relative, positive means slower
+	P99Change      float64 // relative, positive means slower
+
+	// Mann-Whitney U test of "current is slower than baseline" (one-sided).
+	MannWhitneyU float64
+	MannWhitneyZ float64
+	MannWhitneyP float64
+	// EffectSize is P(current > baseline) + P(tie)/2; 0.5 means no shift.
+	EffectSize float64
+
+	// Two-sample Kolmogorov-Smirnov test for any change in shape.
+	KSStatistic float64
+	KSP         float64
+
+	// Confidence that current is slower than baseline, 1 - MannWhitneyP.
+	Confidence float64
+
+	SufficientSamples bool
+	Regression        bool
+	Improvement       bool
+	Reason            string
+}
+
+// String returns a one-line summary suitable for CI logs.
+func (dc *DistributionComparison) String() string {
+	return fmt.Sprintf(
+		"median %v -> %v (%+.1f%%), p99 %v -> %v (%+.1f%%), MW p=%.4f, KS D=%.3f p=%.4f, confidence %.1f%%: %s",
+		dc.BaselineMedian, dc.CurrentMedian, dc.MedianChange*100,
+		dc.BaselineP99, dc.CurrentP99, dc.P99Change*100,
+		dc.MannWhitneyP, dc.KSStatistic, dc.KSP, dc.Confidence*100, dc.Reason,
+	)
+}
+
+// CompareLatencyDistributions compares two latency samples with rank-based
+// tests instead of raw means, which are dominated by outliers and flag noise.
+//
+// A regression is reported only with enough samples on both sides and when
+// either the Mann-Whitney test shows current is slower with a median slowdown
+// of at least MinEffect, or the KS test shows a different distribution with
+// a p99 slowdown of at least MinEffect (a tail-only regression).
+func CompareLatencyDistributions(baseline, current []time.Duration, config DistributionCompareConfig) *DistributionComparison {
+	defaults := DefaultDistributionCompareConfig()
+	if config.Alpha <= 0 || config.Alpha >= 1 {
+		config.Alpha = defaults.Alpha
+	}
+	if config.MinSamples <= 0 {
+		config.MinSamples = defaults.MinSamples
+	}
+	if config.MinEffect < 0 {
+		config.MinEffect = defaults.MinEffect
+	}
+
+	base := durationsToFloats(baseline)
+	cur := durationsToFloats(current)
+	slices.Sort(base)
+	slices.Sort(cur)
+
+	dc := &DistributionComparison{
+		BaselineN: len(base),
+		CurrentN:  len(cur),
+	}
+	if len(base) == 0 || len(cur) == 0 {
+		dc.Reason = "no samples"
+		return dc
+	}
+
+	dc.BaselineMedian = time.Duration(quantileSorted(base, 0.5))
+	dc.CurrentMedian = time.Duration(quantileSorted(cur, 0.5))
+	dc.BaselineP99 = time.Duration(quantileSorted(base, 0.99))
+	dc.CurrentP99 = time.Duration(quantileSorted(cur, 0.99))
+	dc.MedianChange = relativeChange(float64(dc.BaselineMedian), float64(dc.CurrentMedian))
+	dc.P99Change = relativeChange(float64(dc.BaselineP99), float64(dc.CurrentP99))
+
+	dc.MannWhitneyU, dc.MannWhitneyZ, dc.MannWhitneyP = MannWhitneyU(cur, base)
+	dc.EffectSize = dc.MannWhitneyU / (float64(len(cur)) * float64(len(base)))
+	dc.KSStatistic, dc.KSP = KolmogorovSmirnov(cur, base)
+	dc.Confidence = 1 - dc.MannWhitneyP
+
+	dc.SufficientSamples = len(base) >= config.MinSamples && len(cur) >= config.MinSamples
+	if !dc.SufficientSamples {
+		dc.Reason = fmt.Sprintf("insufficient samples: need %d per side, have %d baseline / %d current",
+			config.MinSamples, len(base), len(cur))
+		return dc
+	}
+
+	slower := dc.MannWhitneyP < config.Alpha && dc.MedianChange >= config.MinEffect
+	tail := dc.KSP < config.Alpha && dc.P99Change >= config.MinEffect
+	// Mirror of the one-sided test: current is faster than baseline.
+	faster := 1-dc.MannWhitneyP < config.Alpha && -dc.MedianChange >= config.MinEffect
+
+	switch {
+	case slower:
+		dc.Regression = true
+		dc.Reason = "regression: latency distribution shifted up"
+	case tail:
+		dc.Regression = true
+		dc.Reason = "regression: tail latency increased"
+	case faster:
+		dc.Improvement = true
+		dc.Reason = "improvement: latency distribution shifted down"
+	default:
+		dc.Reason = "no significant change"
+	}
+	return dc
+}
+
+// MannWhitneyU runs a one-sided Mann-Whitney U test of whether values in a
+// tend to be larger than values in b. It returns U for a, the tie-corrected
+// z score with continuity correction, and the one-sided p-value from the
+// normal approximation, which is accurate for samples of about 20 or more.
+func MannWhitneyU(a, b []float64) (u, z, p float64) {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 0, 0, 1
+	}
+
+	type obs struct {
+		v     float64
+		fromA bool
+	}
+	all := make([]obs, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, obs{v, true})
+	}
+	for _, v := range b {
+		all = append(all, obs{v, false})
+	}
+	slices.SortFunc(all, func(x, y obs) int {
+		switch {
+		case x.v < y.v:
+			return -1
+		case x.v > y.v:
+			return 1
+		}
+		return 0
+	})
+
+	// Assign average ranks to ties and accumulate the tie correction term.
+	var rankSumA, tieTerm float64
+	for i := 0; i < len(all); {
+		j := i + 1
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // ranks are 1-based: (i+1 + j) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+		t := float64(j - i)
+		tieTerm += t*t*t - t
+		i = j
+	}
+
+	u = rankSumA - n1*(n1+1)/2
+	n := n1 + n2
+	mean := n1 * n2 / 2
+	variance := n1 * n2 / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		// Every value is identical: no evidence either way.
+		return u, 0, 0.5
+	}
+
+	diff := u - mean
+	switch {
+	case diff > 0:
+		diff -= 0.5
+	case diff < 0:
+		diff += 0.5
+	}
+	z = diff / math.Sqrt(variance)
+	p = 0.5 * math.Erfc(z/math.Sqrt2)
+	return u, z, p
+}
+
+// KolmogorovSmirnov runs a two-sample Kolmogorov-Smirnov test and returns the
+// statistic D (largest gap between the empirical CDFs) and the two-sided
+// asymptotic p-value.
+func KolmogorovSmirnov(a, b []float64) (d, p float64) {
+	if len(a) == 0 || len(b) == 0 {
+		return 0, 1
+	}
+	x := slices.Clone(a)
+	y := slices.Clone(b)
+	slices.Sort(x)
+	slices.Sort(y)
+
+	n1, n2 := float64(len(x)), float64(len(y))
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		v := min(x[i], y[j])
+		for i < len(x) && x[i] == v {
+			i++
+		}
+		for j < len(y) && y[j] == v {
+			j++
+		}
+		d = max(d, math.Abs(float64(i)/n1-float64(j)/n2))
+	}
+
+	en := math.Sqrt(n1 * n2 / (n1 + n2))
+	return d, kolmogorovQ((en + 0.12 + 0.11/en) * d)
+}
+
+// kolmogorovQ is the complementary CDF of the Kolmogorov distribution.
+func kolmogorovQ(lambda float64) float64 {
+	if lambda < 1e-3 {
+		return 1
+	}
+	var sum, prev float64
+	sign := 1.0
+	for k := 1; k <= 100; k++ {
+		term := sign * 2 * math.Exp(-2*float64(k*k)*lambda*lambda)
+		sum += term
+		if math.Abs(term) <= 1e-10*math.Abs(prev) || math.Abs(term) <= 1e-16*sum {
+			return math.Max(0, math.Min(1, sum))
+		}
+		sign = -sign
+		prev = term
+	}
+	// Series failed to converge (lambda close to zero): distributions agree.
+	return 1
+}
+
+// IsLatencyRegression compares the per-operation samples recorded by
+// LatencyTest. Unlike IsRegression it will not flag noise: it requires enough
+// samples and a statistically significant, non-negligible slowdown.
+func (h *PerformanceTestHelper) IsLatencyRegression(current, baseline *BenchmarkResult, config DistributionCompareConfig) *DistributionComparison {
+	var cur, base []time.Duration
+	if current != nil {
+		cur = current.Samples
+	}
+	if baseline != nil {
+		base = baseline.Samples
+	}
+	return CompareLatencyDistributions(base, cur, config)
+}
+
+func durationsToFloats(ds []time.Duration) []float64 {
+	out := make([]float64, len(ds))
+	for i, d := range ds {
+		out[i] = float64(d)
+	}
+	return out
+}
+
+// quantileSorted returns the q-quantile of sorted values using linear
+// interpolation between closest ranks.
+func quantileSorted(sorted []float64, q float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+func relativeChange(base, cur float64) float64 {
+	if base == 0 {
+		if cur == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (cur - base) / base
+}
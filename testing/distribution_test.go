@@ -0,0 +1,123 @@
+package testing
+
+import (
+	"math"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lognormalLatencies draws n latencies around median with the given spread.
+func lognormalLatencies(rng *rand.Rand, n int, median time.Duration, sigma float64) []time.Duration {
+	out := make([]time.Duration, n)
+	for i := range out {
+		out[i] = time.Duration(float64(median) * math.Exp(sigma*rng.NormFloat64()))
+	}
+	return out
+}
+
+func TestMannWhitneyU_SeparatedSamples(t *testing.T) {
+	low := []float64{1, 2, 3, 4, 5}
+	high := []float64{6, 7, 8, 9, 10}
+
+	u, z, p := MannWhitneyU(high, low)
+	if u != 25 {
+		t.Errorf("U = %v, want 25", u)
+	}
+	// (25 - 12.5 - 0.5) / sqrt(25*11/12)
+	if math.Abs(z-2.5067) > 1e-3 {
+		t.Errorf("z = %v, want 2.5067", z)
+	}
+	if math.Abs(p-0.00609) > 1e-4 {
+		t.Errorf("p = %v, want 0.00609", p)
+	}
+
+	if _, _, p := MannWhitneyU(low, high); p < 0.99 {
+		t.Errorf("reverse p = %v, want close to 1", p)
+	}
+}
+
+func TestKolmogorovSmirnov(t *testing.T) {
+	d, p := KolmogorovSmirnov([]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10})
+	if d != 1 || p > 0.01 {
+		t.Errorf("separated: D = %v, p = %v", d, p)
+	}
+
+	same := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	if d, p := KolmogorovSmirnov(same, same); d != 0 || p != 1 {
+		t.Errorf("identical: D = %v, p = %v", d, p)
+	}
+}
+
+func TestCompareLatencyDistributions_IgnoresNoiseThatFoolsMeans(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	baseline := lognormalLatencies(rng, 500, 10*time.Millisecond, 0.3)
+	current := lognormalLatencies(rng, 500, 10*time.Millisecond, 0.3)
+	// A single GC pause or noisy neighbour in the current run.
+	current[0] = 2 * time.Second
+
+	helper := NewPerformanceTestHelper()
+	base := &BenchmarkResult{Operations: int64(len(baseline)), Samples: baseline}
+	cur := &BenchmarkResult{Operations: int64(len(current)), Samples: current}
+	for _, d := range baseline {
+		base.Duration += d
+	}
+	for _, d := range current {
+		cur.Duration += d
+	}
+
+	if !helper.IsRegression(cur, base, 0.1) {
+		t.Fatal("expected the mean-based check to be fooled by the outlier")
+	}
+	dc := helper.IsLatencyRegression(cur, base, DefaultDistributionCompareConfig())
+	if dc.Regression || dc.Improvement {
+		t.Errorf("same distribution reported as changed: %s", dc)
+	}
+}
+
+func TestCompareLatencyDistributions_DetectsShift(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	baseline := lognormalLatencies(rng, 300, 10*time.Millisecond, 0.3)
+	current := lognormalLatencies(rng, 300, 12*time.Millisecond, 0.3)
+
+	dc := CompareLatencyDistributions(baseline, current, DefaultDistributionCompareConfig())
+	if !dc.Regression || dc.Confidence < 0.99 {
+		t.Errorf("20%% slowdown not detected: %s", dc)
+	}
+	if dc.EffectSize <= 0.5 {
+		t.Errorf("EffectSize = %v, want > 0.5 for a slower current", dc.EffectSize)
+	}
+
+	reverse := CompareLatencyDistributions(current, baseline, DefaultDistributionCompareConfig())
+	if reverse.Regression || !reverse.Improvement {
+		t.Errorf("speed-up should be an improvement: %s", reverse)
+	}
+}
+
+func TestCompareLatencyDistributions_DetectsTailRegression(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+	baseline := lognormalLatencies(rng, 1000, 10*time.Millisecond, 0.2)
+	current := lognormalLatencies(rng, 1000, 10*time.Millisecond, 0.2)
+	for i := 0; i < len(current); i += 10 {
+		current[i] *= 5
+	}
+
+	dc := CompareLatencyDistributions(baseline, current, DefaultDistributionCompareConfig())
+	if !dc.Regression || !strings.Contains(dc.Reason, "tail") {
+		t.Errorf("tail regression not detected: %s", dc)
+	}
+}
+
+func TestCompareLatencyDistributions_RequiresMinimumSamples(t *testing.T) {
+	baseline := []time.Duration{10, 11, 12, 10, 11}
+	current := []time.Duration{50, 51, 52, 50, 51}
+
+	dc := CompareLatencyDistributions(baseline, current, DefaultDistributionCompareConfig())
+	if dc.SufficientSamples || dc.Regression {
+		t.Errorf("verdict given on 5 samples: %s", dc)
+	}
+	if !strings.Contains(dc.Reason, "insufficient samples") {
+		t.Errorf("Reason = %q", dc.Reason)
+	}
+}
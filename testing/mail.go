@@ -0,0 +1,85 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/leeforge/framework/mail"
+)
+
+// MockMailSender is a mail.Sender that records messages instead of sending
+// them. Queue errors with FailNext to exercise retries and rejections.
+type MockMailSender struct {
+	mu       sync.Mutex
+	messages []mail.Message
+	failures []error
+	seq      int
+}
+
+// NewMockMailSender creates an empty mock sender.
+func NewMockMailSender() *MockMailSender {
+	return &MockMailSender{}
+}
+
+// Send implements mail.Sender.
+func (m *MockMailSender) Send(_ context.Context, msg *mail.Message) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.failures) > 0 {
+		err := m.failures[0]
+		m.failures = m.failures[1:]
+		return "", err
+	}
+	m.seq++
+	m.messages = append(m.messages, *msg)
+	return fmt.Sprintf("mock-%d", m.seq), nil
+}
+
+// FailNext makes the next len(errs) sends fail with errs in order; wrap an
+// error with retry.Permanent to simulate a rejection.
+func (m *MockMailSender) FailNext(errs ...error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures = append(m.failures, errs...)
+}
+
+// Messages returns the messages sent so far.
+func (m *MockMailSender) Messages() []mail.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]mail.Message(nil), m.messages...)
+}
+
+// SentTo returns the messages with email among their recipients.
+func (m *MockMailSender) SentTo(email string) []mail.Message {
+	var out []mail.Message
+	for _, msg := range m.Messages() {
+		for _, a := range msg.Recipients() {
+			if strings.EqualFold(a.Email, email) {
+				out = append(out, msg)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Last returns the most recent message, or nil.
+func (m *MockMailSender) Last() *mail.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.messages) == 0 {
+		return nil
+	}
+	msg := m.messages[len(m.messages)-1]
+	return &msg
+}
+
+// Reset forgets sent messages and queued failures.
+func (m *MockMailSender) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages, m.failures = nil, nil
+}
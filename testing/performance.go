@@ -103,6 +103,9 @@ type BenchmarkResult struct {
 	Allocations int64
 	Memory      uint64
 	Extra       map[string]interface{}
+	// Samples holds per-operation latencies when the runner records them
+	// (LatencyTest does), for CompareLatencyDistributions.
+	Samples []time.Duration
 }
 
 // String returns a string representation
@@ -788,6 +791,7 @@ func (lt *LatencyTest) Run() *BenchmarkResult {
 		Name:       "Latency",
 		Duration:   totalLatency,
 		Operations: int64(len(latencies)),
+		Samples:    latencies,
 		Extra: map[string]interface{}{
 			"avg_latency": avgLatency,
 			"min_latency": minLatency,
@@ -1173,7 +1177,9 @@ func (h *PerformanceTestHelper) CompareResults(a, b *BenchmarkResult) string {
 	return report
 }
 
-// IsRegression checks if there's a performance regression
+// IsRegression checks if there's a performance regression.
+// It compares means only and is easily tripped by noise; prefer
+// IsLatencyRegression when per-operation samples are available.
 func (h *PerformanceTestHelper) IsRegression(current, baseline *BenchmarkResult, threshold float64) bool {
 	if current == nil || baseline == nil {
 		return false
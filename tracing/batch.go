@@ -0,0 +1,215 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Batch processor defaults, matching the OpenTelemetry SDK.
+const (
+	DefaultMaxQueueSize       = 2048
+	DefaultMaxExportBatchSize = 512
+	DefaultScheduleDelay      = 5 * time.Second
+	DefaultExportTimeout      = 30 * time.Second
+)
+
+// BatchSpanExporter is implemented by exporters that can send many spans in
+// one request. BatchSpanProcessor prefers it over per-span Export. The spans
+// slice is reused after ExportBatch returns and must not be retained.
+type BatchSpanExporter interface {
+	SpanExporter
+	ExportBatch(ctx context.Context, spans []*Span) error
+}
+
+// BatchSpanProcessorOptions configures a BatchSpanProcessor. Zero fields use
+// the defaults above.
+type BatchSpanProcessorOptions struct {
+	// MaxQueueSize bounds the spans waiting for export; spans ended while the
+	// queue is full are dropped and counted.
+	MaxQueueSize int
+	// MaxExportBatchSize exports as soon as this many spans are queued.
+	MaxExportBatchSize int
+	// ScheduleDelay exports whatever is queued at this interval.
+	ScheduleDelay time.Duration
+	// ExportTimeout bounds a single ExportBatch call.
+	ExportTimeout time.Duration
+}
+
+// BatchSpanProcessor queues ended spans and exports them from a background
+// worker, so End never waits on the exporter. Export happens when a batch
+// fills, every ScheduleDelay, on ForceFlush and on Shutdown.
+type BatchSpanProcessor struct {
+	exporter SpanExporter
+	opts     BatchSpanProcessorOptions
+
+	queue   chan *Span
+	flushes chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	dropped  atomic.Uint64
+	closed   atomic.Bool
+	stopOnce sync.Once
+}
+
+// NewBatchSpanProcessor creates a batch span processor exporting batchSize
+// spans at a time, or whatever is queued every timeout.
+func NewBatchSpanProcessor(exporter SpanExporter, batchSize int, timeout time.Duration) *BatchSpanProcessor {
+	return NewBatchSpanProcessorWithOptions(exporter, BatchSpanProcessorOptions{
+		MaxExportBatchSize: batchSize,
+		ScheduleDelay:      timeout,
+	})
+}
+
+// NewBatchSpanProcessorWithOptions creates a batch span processor and starts
+// its worker. Call Shutdown to stop it.
+func NewBatchSpanProcessorWithOptions(exporter SpanExporter, opts BatchSpanProcessorOptions) *BatchSpanProcessor {
+	if opts.MaxQueueSize <= 0 {
+		opts.MaxQueueSize = DefaultMaxQueueSize
+	}
+	if opts.MaxExportBatchSize <= 0 {
+		opts.MaxExportBatchSize = DefaultMaxExportBatchSize
+	}
+	opts.MaxExportBatchSize = min(opts.MaxExportBatchSize, opts.MaxQueueSize)
+	if opts.ScheduleDelay <= 0 {
+		opts.ScheduleDelay = DefaultScheduleDelay
+	}
+	if opts.ExportTimeout <= 0 {
+		opts.ExportTimeout = DefaultExportTimeout
+	}
+
+	b := &BatchSpanProcessor{
+		exporter: exporter,
+		opts:     opts,
+		queue:    make(chan *Span, opts.MaxQueueSize),
+		flushes:  make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// OnEnd queues the span for export without blocking. The span is dropped
+// when the queue is full or the processor has shut down.
+func (b *BatchSpanProcessor) OnEnd(span *Span) {
+	if b.closed.Load() {
+		b.dropped.Add(1)
+		return
+	}
+	select {
+	case b.queue <- span:
+	default:
+		b.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of spans dropped because the queue was full or
+// the processor had shut down.
+func (b *BatchSpanProcessor) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+// ForceFlush exports every span queued before the call and waits for the
+// export to finish or ctx to expire.
+func (b *BatchSpanProcessor) ForceFlush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case b.flushes <- done:
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush exports every queued span and waits for the export.
+func (b *BatchSpanProcessor) Flush() {
+	b.ForceFlush(context.Background())
+}
+
+// Shutdown stops accepting spans, exports everything queued and shuts the
+// exporter down. It returns ctx.Err() if draining outlives ctx; the worker
+// keeps draining in the background in that case.
+func (b *BatchSpanProcessor) Shutdown(ctx context.Context) error {
+	b.stopOnce.Do(func() {
+		b.closed.Store(true)
+		close(b.stop)
+	})
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if b.exporter != nil {
+		return b.exporter.Shutdown(ctx)
+	}
+	return nil
+}
+
+func (b *BatchSpanProcessor) run() {
+	defer close(b.done)
+
+	batch := make([]*Span, 0, b.opts.MaxExportBatchSize)
+	ticker := time.NewTicker(b.opts.ScheduleDelay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case span := <-b.queue:
+			batch = append(batch, span)
+			if len(batch) >= b.opts.MaxExportBatchSize {
+				batch = b.export(batch)
+			}
+		case <-ticker.C:
+			batch = b.export(batch)
+		case done := <-b.flushes:
+			batch = b.export(b.drain(batch))
+			close(done)
+		case <-b.stop:
+			b.export(b.drain(batch))
+			return
+		}
+	}
+}
+
+// drain moves queued spans into batch, exporting each time it fills.
+func (b *BatchSpanProcessor) drain(batch []*Span) []*Span {
+	for {
+		select {
+		case span := <-b.queue:
+			batch = append(batch, span)
+			if len(batch) >= b.opts.MaxExportBatchSize {
+				batch = b.export(batch)
+			}
+		default:
+			return batch
+		}
+	}
+}
+
+// export sends batch and returns it emptied for reuse.
+func (b *BatchSpanProcessor) export(batch []*Span) []*Span {
+	if len(batch) == 0 || b.exporter == nil {
+		return batch[:0]
+	}
+	if be, ok := b.exporter.(BatchSpanExporter); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), b.opts.ExportTimeout)
+		be.ExportBatch(ctx, batch)
+		cancel()
+	} else {
+		for _, span := range batch {
+			b.exporter.Export(span)
+		}
+	}
+	clear(batch)
+	return batch[:0]
+}
@@ -0,0 +1,184 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collectingExporter records exported spans; with gate set, Export blocks
+// until the gate is closed.
+type collectingExporter struct {
+	mu       sync.Mutex
+	spans    []string
+	batches  int
+	shutdown bool
+	gate     chan struct{}
+}
+
+func (e *collectingExporter) Export(span *Span) error {
+	if e.gate != nil {
+		<-e.gate
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span.Name)
+	return nil
+}
+
+func (e *collectingExporter) Shutdown(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdown = true
+	return nil
+}
+
+func (e *collectingExporter) count() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.spans)
+}
+
+type batchingExporter struct {
+	collectingExporter
+}
+
+func (e *batchingExporter) ExportBatch(_ context.Context, spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.batches++
+	for _, s := range spans {
+		e.spans = append(e.spans, s.Name)
+	}
+	return nil
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBatchSpanProcessorExportsFullBatches(t *testing.T) {
+	exp := &batchingExporter{}
+	p := NewBatchSpanProcessorWithOptions(exp, BatchSpanProcessorOptions{MaxExportBatchSize: 3, ScheduleDelay: time.Hour})
+	defer p.Shutdown(context.Background())
+
+	for i := 0; i < 3; i++ {
+		p.OnEnd(&Span{Name: "op"})
+	}
+	waitFor(t, func() bool { return exp.count() == 3 })
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+	if exp.batches != 1 {
+		t.Fatalf("batches = %d, want one ExportBatch call", exp.batches)
+	}
+}
+
+func TestBatchSpanProcessorExportsOnSchedule(t *testing.T) {
+	exp := &collectingExporter{}
+	p := NewBatchSpanProcessorWithOptions(exp, BatchSpanProcessorOptions{MaxExportBatchSize: 100, ScheduleDelay: 10 * time.Millisecond})
+	defer p.Shutdown(context.Background())
+
+	p.OnEnd(&Span{Name: "op"})
+	waitFor(t, func() bool { return exp.count() == 1 })
+}
+
+func TestBatchSpanProcessorDropsOnOverflow(t *testing.T) {
+	exp := &collectingExporter{gate: make(chan struct{})}
+	p := NewBatchSpanProcessorWithOptions(exp, BatchSpanProcessorOptions{MaxQueueSize: 4, MaxExportBatchSize: 1, ScheduleDelay: time.Hour})
+
+	// The worker blocks exporting the first span; the queue then fills.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 20; i++ {
+			p.OnEnd(&Span{Name: "op"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("OnEnd blocked on a full queue")
+	}
+	if p.Dropped() == 0 {
+		t.Fatal("expected dropped spans with a blocked exporter and a full queue")
+	}
+
+	close(exp.gate)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := uint64(exp.count()) + p.Dropped(); got != 20 {
+		t.Fatalf("exported + dropped = %d, want 20", got)
+	}
+}
+
+func TestBatchSpanProcessorForceFlush(t *testing.T) {
+	exp := &collectingExporter{}
+	p := NewBatchSpanProcessorWithOptions(exp, BatchSpanProcessorOptions{MaxExportBatchSize: 100, ScheduleDelay: time.Hour})
+	defer p.Shutdown(context.Background())
+
+	for i := 0; i < 10; i++ {
+		p.OnEnd(&Span{Name: "op"})
+	}
+	if err := p.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if exp.count() != 10 {
+		t.Fatalf("exported %d spans after ForceFlush, want 10", exp.count())
+	}
+
+	blocked := &collectingExporter{gate: make(chan struct{})}
+	slow := NewBatchSpanProcessorWithOptions(blocked, BatchSpanProcessorOptions{ScheduleDelay: time.Hour})
+	slow.OnEnd(&Span{Name: "op"})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := slow.ForceFlush(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("ForceFlush with a blocked exporter = %v, want deadline exceeded", err)
+	}
+	close(blocked.gate)
+	slow.Shutdown(context.Background())
+}
+
+func TestBatchSpanProcessorShutdownDrains(t *testing.T) {
+	exp := &collectingExporter{}
+	p := NewBatchSpanProcessorWithOptions(exp, BatchSpanProcessorOptions{MaxExportBatchSize: 100, ScheduleDelay: time.Hour})
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				p.OnEnd(&Span{Name: "op"})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if exp.count() != 200 || !exp.shutdown {
+		t.Fatalf("exported %d spans, exporter shut down = %v; want 200 and true", exp.count(), exp.shutdown)
+	}
+
+	p.OnEnd(&Span{Name: "late"})
+	if p.Dropped() != 1 {
+		t.Fatalf("dropped = %d, want the span ended after Shutdown", p.Dropped())
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("second Shutdown = %v", err)
+	}
+	if err := p.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush after Shutdown = %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Exporter types selectable through ExporterConfig.Type.
+const (
+	ExporterConsole = "console"
+	ExporterJaeger  = "jaeger"
+	ExporterZipkin  = "zipkin"
+)
+
+// Collector defaults for the HTTP exporters.
+const (
+	DefaultJaegerEndpoint    = "http://localhost:14268/api/traces"
+	DefaultZipkinEndpoint    = "http://localhost:9411/api/v2/spans"
+	DefaultHTTPExportTimeout = 10 * time.Second
+)
+
+// ExporterConfig selects and configures the span exporter.
+type ExporterConfig struct {
+	// Type is ExporterConsole (default), ExporterJaeger or ExporterZipkin.
+	Type string
+	// Endpoint is the full collector URL; defaults depend on Type.
+	Endpoint string
+	// Headers are sent with every export request, e.g. Authorization.
+	Headers map[string]string
+	// Timeout bounds a single export request.
+	Timeout time.Duration
+	// Batch configures the batch processor used when batching is enabled.
+	// A zero ScheduleDelay falls back to DistributedTracingConfig.BatchTimeout.
+	Batch BatchSpanProcessorOptions
+}
+
+// NewExporter creates the exporter selected by config.Type for serviceName.
+func NewExporter(serviceName string, config ExporterConfig) (SpanExporter, error) {
+	switch config.Type {
+	case "", ExporterConsole:
+		return NewConsoleExporter(), nil
+	case ExporterJaeger:
+		return NewJaegerExporter(serviceName, config), nil
+	case ExporterZipkin:
+		return NewZipkinExporter(serviceName, config), nil
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", config.Type)
+	}
+}
+
+// httpSender posts encoded span batches to a collector.
+type httpSender struct {
+	name     string
+	endpoint string
+	headers  map[string]string
+	timeout  time.Duration
+	client   *http.Client
+}
+
+func newHTTPSender(name, defaultEndpoint string, config ExporterConfig) *httpSender {
+	if config.Endpoint == "" {
+		config.Endpoint = defaultEndpoint
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultHTTPExportTimeout
+	}
+	return &httpSender{
+		name:     name,
+		endpoint: config.Endpoint,
+		headers:  config.Headers,
+		timeout:  config.Timeout,
+		client:   &http.Client{Timeout: config.Timeout},
+	}
+}
+
+func (s *httpSender) post(ctx context.Context, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("tracing: %s request: %w", s.name, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("tracing: %s export: %w", s.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("tracing: %s export: status %d: %s", s.name, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// exportOne sends a single span through ExportBatch with the sender timeout.
+func (s *httpSender) exportOne(exporter BatchSpanExporter, span *Span) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	return exporter.ExportBatch(ctx, []*Span{span})
+}
+
+func (s *httpSender) shutdown() {
+	s.client.CloseIdleConnections()
+}
+
+// sortedKeys returns the attribute keys in order so encoded spans are stable.
+func sortedKeys(attrs map[string]interface{}) []string {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// attributeString formats an attribute value for string-only tag formats.
+func attributeString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// spanDuration returns the span duration in microseconds, at least 1 as
+// collectors reject zero durations.
+func spanDuration(span *Span) int64 {
+	return max(span.EndTime.Sub(span.StartTime).Microseconds(), 1)
+}
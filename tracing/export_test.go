@@ -0,0 +1,335 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type capturedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func collectorServer(t *testing.T, status int) (*httptest.Server, func() []capturedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var reqs []capturedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		reqs = append(reqs, capturedRequest{header: r.Header.Clone(), body: body})
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []capturedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]capturedRequest(nil), reqs...)
+	}
+}
+
+func exportTestSpan() *Span {
+	start := time.Unix(1700000000, 0)
+	return &Span{
+		TraceID:    "0af7651916cd43dd8448eb211c80319c",
+		SpanID:     "b7ad6b7169203331",
+		ParentID:   "00f067aa0ba902b7",
+		Name:       "GET /users",
+		StartTime:  start,
+		EndTime:    start.Add(1500 * time.Microsecond),
+		Kind:       SpanKindServer,
+		Attributes: map[string]interface{}{"http.status_code": 502, "http.method": "GET", "cache.hit": false},
+		Events:     []SpanEvent{{Time: start.Add(time.Millisecond), Name: "exception", Attributes: map[string]interface{}{"exception.message": "boom"}}},
+		Links:      []Link{{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b8"}},
+		Status:     SpanStatus{Code: StatusCodeError, Message: "Bad Gateway"},
+	}
+}
+
+func TestZipkinExporterEncodesV2JSON(t *testing.T) {
+	srv, requests := collectorServer(t, http.StatusAccepted)
+	exporter := NewZipkinExporter("user-service", ExporterConfig{
+		Endpoint: srv.URL,
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+	})
+
+	if err := exporter.Export(exportTestSpan()); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1", len(reqs))
+	}
+	if got := reqs[0].header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := reqs[0].header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	var spans []map[string]interface{}
+	if err := json.Unmarshal(reqs[0].body, &spans); err != nil {
+		t.Fatalf("decode: %v\n%s", err, reqs[0].body)
+	}
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	checks := map[string]interface{}{
+		"traceId":   "0af7651916cd43dd8448eb211c80319c",
+		"id":        "b7ad6b7169203331",
+		"parentId":  "00f067aa0ba902b7",
+		"name":      "GET /users",
+		"kind":      "SERVER",
+		"timestamp": float64(1700000000000000),
+		"duration":  float64(1500),
+	}
+	for k, want := range checks {
+		if span[k] != want {
+			t.Errorf("%s = %v, want %v", k, span[k], want)
+		}
+	}
+	if ep, _ := span["localEndpoint"].(map[string]interface{}); ep["serviceName"] != "user-service" {
+		t.Errorf("localEndpoint = %v", span["localEndpoint"])
+	}
+	tags, _ := span["tags"].(map[string]interface{})
+	if tags["error"] != "Bad Gateway" || tags["http.status_code"] != "502" || tags["cache.hit"] != "false" {
+		t.Errorf("tags = %v", tags)
+	}
+	annotations, _ := span["annotations"].([]interface{})
+	if len(annotations) != 1 {
+		t.Fatalf("annotations = %v", span["annotations"])
+	}
+	if a := annotations[0].(map[string]interface{}); a["value"] != `exception {"exception.message":"boom"}` || a["timestamp"] != float64(1700000000001000) {
+		t.Errorf("annotation = %v", a)
+	}
+}
+
+func TestHTTPExporterReportsCollectorErrors(t *testing.T) {
+	srv, _ := collectorServer(t, http.StatusUnauthorized)
+	for _, exporter := range []BatchSpanExporter{
+		NewZipkinExporter("svc", ExporterConfig{Endpoint: srv.URL}),
+		NewJaegerExporter("svc", ExporterConfig{Endpoint: srv.URL}),
+	} {
+		if err := exporter.ExportBatch(context.Background(), []*Span{exportTestSpan()}); err == nil {
+			t.Errorf("%T: expected error for 401 response", exporter)
+		}
+		if err := exporter.ExportBatch(context.Background(), nil); err != nil {
+			t.Errorf("%T: empty batch should not be sent: %v", exporter, err)
+		}
+	}
+}
+
+func TestJaegerExporterEncodesThriftBatch(t *testing.T) {
+	srv, requests := collectorServer(t, http.StatusAccepted)
+	exporter := NewJaegerExporter("user-service", ExporterConfig{Endpoint: srv.URL})
+
+	if err := exporter.ExportBatch(context.Background(), []*Span{exportTestSpan()}); err != nil {
+		t.Fatalf("ExportBatch: %v", err)
+	}
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1", len(reqs))
+	}
+	if got := reqs[0].header.Get("Content-Type"); got != "application/x-thrift" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	r := &thriftReader{buf: bytes.NewReader(reqs[0].body)}
+	batch := r.readStruct()
+	if r.err != nil || r.buf.Len() != 0 {
+		t.Fatalf("malformed batch: err=%v, %d trailing bytes", r.err, r.buf.Len())
+	}
+	process := batch[1].(map[int16]interface{})
+	if process[1] != "user-service" {
+		t.Errorf("process.serviceName = %v", process[1])
+	}
+	spans := batch[2].([]interface{})
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0].(map[int16]interface{})
+	checks := map[int16]interface{}{
+		1: int64(-0x7bb714dee37fce64), // low 64 bits of the trace ID
+		2: int64(0x0af7651916cd43dd),
+		3: int64(-0x4852948e96dfcccf),
+		4: int64(0x00f067aa0ba902b7),
+		5: "GET /users",
+		7: int32(1),
+		8: int64(1700000000000000),
+		9: int64(1500),
+	}
+	for id, want := range checks {
+		if span[id] != want {
+			t.Errorf("span field %d = %#v, want %#v", id, span[id], want)
+		}
+	}
+
+	refs := span[6].([]interface{})
+	if ref := refs[0].(map[int16]interface{}); ref[1] != int32(1) || ref[4] != int64(0x00f067aa0ba902b8) {
+		t.Errorf("reference = %v, want FOLLOWS_FROM the link", ref)
+	}
+
+	tags := map[string]map[int16]interface{}{}
+	for _, tag := range span[10].([]interface{}) {
+		tag := tag.(map[int16]interface{})
+		tags[tag[1].(string)] = tag
+	}
+	if tag := tags["http.status_code"]; tag[2] != int32(3) || tag[6] != int64(502) {
+		t.Errorf("http.status_code tag = %v, want long 502", tag)
+	}
+	if tag := tags["cache.hit"]; tag[2] != int32(2) || tag[5] != false {
+		t.Errorf("cache.hit tag = %v, want bool false", tag)
+	}
+	if tag := tags["error"]; tag[5] != true {
+		t.Errorf("error tag = %v, want true", tag)
+	}
+	if tag := tags["span.kind"]; tag[3] != "server" {
+		t.Errorf("span.kind tag = %v", tag)
+	}
+
+	logs := span[11].([]interface{})
+	log := logs[0].(map[int16]interface{})
+	fields := log[2].([]interface{})
+	if log[1] != int64(1700000000001000) || fields[0].(map[int16]interface{})[3] != "exception" {
+		t.Errorf("log = %v", log)
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+	for typ, want := range map[string]interface{}{
+		"":              &ConsoleExporter{},
+		ExporterConsole: &ConsoleExporter{},
+		ExporterJaeger:  &JaegerExporter{},
+		ExporterZipkin:  &ZipkinExporter{},
+	} {
+		exporter, err := NewExporter("svc", ExporterConfig{Type: typ})
+		if err != nil {
+			t.Errorf("NewExporter(%q): %v", typ, err)
+			continue
+		}
+		if got, want := fmt.Sprintf("%T", exporter), fmt.Sprintf("%T", want); got != want {
+			t.Errorf("NewExporter(%q) = %s, want %s", typ, got, want)
+		}
+	}
+	if _, err := NewExporter("svc", ExporterConfig{Type: "otlp-grpc"}); err == nil {
+		t.Error("expected error for unknown exporter")
+	}
+	if e := NewZipkinExporter("svc", ExporterConfig{}); e.sender.endpoint != DefaultZipkinEndpoint {
+		t.Errorf("zipkin endpoint = %q", e.sender.endpoint)
+	}
+	if e := NewJaegerExporter("svc", ExporterConfig{}); e.sender.endpoint != DefaultJaegerEndpoint {
+		t.Errorf("jaeger endpoint = %q", e.sender.endpoint)
+	}
+}
+
+func TestDistributedTracerUsesConfiguredExporter(t *testing.T) {
+	srv, requests := collectorServer(t, http.StatusAccepted)
+	dt, err := NewDistributedTracer(DistributedTracingConfig{
+		Enable:         true,
+		ServiceName:    "order-service",
+		SamplingRate:   1,
+		EnableBatching: true,
+		BatchTimeout:   time.Hour,
+		Exporter: ExporterConfig{
+			Type:     ExporterZipkin,
+			Endpoint: srv.URL,
+			Headers:  map[string]string{"Authorization": "Basic abc"},
+			Batch:    BatchSpanProcessorOptions{MaxExportBatchSize: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewDistributedTracer: %v", err)
+	}
+
+	tracer := dt.GetTracer()
+	for range 3 {
+		_, span := tracer.Start(context.Background(), "order.create")
+		tracer.End(span, nil)
+	}
+	if err := dt.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want the 3 spans in one batch", len(reqs))
+	}
+	var spans []zipkinSpan
+	if err := json.Unmarshal(reqs[0].body, &spans); err != nil || len(spans) != 3 {
+		t.Fatalf("spans = %d, err = %v", len(spans), err)
+	}
+	if reqs[0].header.Get("Authorization") != "Basic abc" {
+		t.Errorf("missing auth header")
+	}
+
+	if _, err := NewDistributedTracer(DistributedTracingConfig{Enable: true, Exporter: ExporterConfig{Type: "x"}}); err == nil {
+		t.Error("expected error for unknown exporter type")
+	}
+}
+
+// thriftReader decodes Thrift binary structs into field ID maps for tests.
+type thriftReader struct {
+	buf *bytes.Reader
+	err error
+}
+
+func (r *thriftReader) read(n int) []byte {
+	b := make([]byte, n)
+	if r.err == nil {
+		_, r.err = io.ReadFull(r.buf, b)
+	}
+	return b
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	fields := map[int16]interface{}{}
+	for r.err == nil {
+		typ := r.read(1)[0]
+		if typ == thriftStop {
+			break
+		}
+		id := int16(binary.BigEndian.Uint16(r.read(2)))
+		fields[id] = r.readValue(typ)
+	}
+	return fields
+}
+
+func (r *thriftReader) readValue(typ byte) interface{} {
+	switch typ {
+	case thriftBool:
+		return r.read(1)[0] == 1
+	case thriftDouble:
+		return math.Float64frombits(binary.BigEndian.Uint64(r.read(8)))
+	case thriftI32:
+		return int32(binary.BigEndian.Uint32(r.read(4)))
+	case thriftI64:
+		return int64(binary.BigEndian.Uint64(r.read(8)))
+	case thriftString:
+		n := int32(binary.BigEndian.Uint32(r.read(4)))
+		return string(r.read(int(n)))
+	case thriftStruct:
+		return r.readStruct()
+	case thriftList:
+		elem := r.read(1)[0]
+		n := int32(binary.BigEndian.Uint32(r.read(4)))
+		list := make([]interface{}, 0, n)
+		for i := int32(0); i < n && r.err == nil; i++ {
+			list = append(list, r.readValue(elem))
+		}
+		return list
+	default:
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+}
@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/leeforge/framework/request"
+)
+
+// IDGenerator generates trace and span IDs. Implementations must be safe
+// for concurrent use.
+type IDGenerator interface {
+	// NewTraceID returns a 128-bit trace ID as 32 lowercase hex characters.
+	NewTraceID() string
+	// NewSpanID returns a 64-bit span ID as 16 lowercase hex characters.
+	NewSpanID() string
+}
+
+// randomIDGenerator draws IDs from crypto/rand, the same source request
+// uses for incoming requests, so IDs are unique across goroutines and hosts.
+type randomIDGenerator struct{}
+
+func (randomIDGenerator) NewTraceID() string { return request.GenerateTraceID() }
+func (randomIDGenerator) NewSpanID() string  { return request.GenerateSpanID() }
+
+// SequentialIDGenerator hands out increasing IDs (trace 000…001, span
+// 000…001, …) for deterministic tests.
+type SequentialIDGenerator struct {
+	traces atomic.Uint64
+	spans  atomic.Uint64
+}
+
+// NewSequentialIDGenerator creates a SequentialIDGenerator starting at 1.
+func NewSequentialIDGenerator() *SequentialIDGenerator {
+	return &SequentialIDGenerator{}
+}
+
+// NewTraceID implements IDGenerator.
+func (g *SequentialIDGenerator) NewTraceID() string {
+	return fmt.Sprintf("%032x", g.traces.Add(1))
+}
+
+// NewSpanID implements IDGenerator.
+func (g *SequentialIDGenerator) NewSpanID() string {
+	return fmt.Sprintf("%016x", g.spans.Add(1))
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentSpansHaveUniqueIDs(t *testing.T) {
+	tracer, _ := NewTracer(TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: &noopProcessor{}})
+
+	const workers, perWorker = 8, 500
+	var (
+		mu     sync.Mutex
+		traces = map[string]bool{}
+		spans  = map[string]bool{}
+		wg     sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				_, span := tracer.Start(context.Background(), "op")
+				mu.Lock()
+				traces[span.TraceID] = true
+				spans[span.SpanID] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(traces) != workers*perWorker || len(spans) != workers*perWorker {
+		t.Fatalf("unique trace IDs = %d, span IDs = %d, want %d each", len(traces), len(spans), workers*perWorker)
+	}
+	for id := range traces {
+		if b, err := hex.DecodeString(id); err != nil || len(b) != 16 {
+			t.Fatalf("trace ID %q is not 128-bit hex", id)
+		}
+		break
+	}
+	for id := range spans {
+		if b, err := hex.DecodeString(id); err != nil || len(b) != 8 {
+			t.Fatalf("span ID %q is not 64-bit hex", id)
+		}
+		break
+	}
+}
+
+func TestSequentialIDGenerator(t *testing.T) {
+	tracer, _ := NewTracer(TracerConfig{
+		ServiceName: "test", SamplingRate: 1, Processor: &noopProcessor{},
+		IDGenerator: NewSequentialIDGenerator(),
+	})
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	_, other := tracer.Start(ctx, "other", WithNewRoot())
+
+	want := []struct{ trace, span, parent string }{
+		{"00000000000000000000000000000001", "0000000000000001", ""},
+		{"00000000000000000000000000000001", "0000000000000002", "0000000000000001"},
+		{"00000000000000000000000000000002", "0000000000000003", ""},
+	}
+	for i, span := range []*Span{root, child, other} {
+		if span.TraceID != want[i].trace || span.SpanID != want[i].span || span.ParentID != want[i].parent {
+			t.Errorf("span %d = %s/%s parent %q, want %+v", i, span.TraceID, span.SpanID, span.ParentID, want[i])
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+// JaegerExporter sends spans to a Jaeger collector's /api/traces endpoint as
+// a Thrift binary encoded jaeger.Batch.
+type JaegerExporter struct {
+	service string
+	sender  *httpSender
+}
+
+// NewJaegerExporter creates a Jaeger exporter reporting spans as serviceName.
+func NewJaegerExporter(serviceName string, config ExporterConfig) *JaegerExporter {
+	return &JaegerExporter{
+		service: serviceName,
+		sender:  newHTTPSender(ExporterJaeger, DefaultJaegerEndpoint, config),
+	}
+}
+
+// Export sends a single span.
+func (j *JaegerExporter) Export(span *Span) error {
+	return j.sender.exportOne(j, span)
+}
+
+// ExportBatch sends spans in one request.
+func (j *JaegerExporter) ExportBatch(ctx context.Context, spans []*Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	return j.sender.post(ctx, "application/x-thrift", j.encode(spans))
+}
+
+// Shutdown releases idle connections.
+func (j *JaegerExporter) Shutdown(ctx context.Context) error {
+	j.sender.shutdown()
+	return nil
+}
+
+// Jaeger tag value types and span reference types from jaeger.thrift.
+const (
+	jaegerTagString = 0
+	jaegerTagDouble = 1
+	jaegerTagBool   = 2
+	jaegerTagLong   = 3
+
+	jaegerRefFollowsFrom = 1
+
+	jaegerFlagSampled = 1
+)
+
+var jaegerKinds = map[SpanKind]string{
+	SpanKindServer:   "server",
+	SpanKindClient:   "client",
+	SpanKindProducer: "producer",
+	SpanKindConsumer: "consumer",
+}
+
+type jaegerTag struct {
+	key   string
+	value interface{}
+}
+
+// encode writes Batch{1: Process process, 2: list<Span> spans}.
+func (j *JaegerExporter) encode(spans []*Span) []byte {
+	var w thriftWriter
+
+	w.field(thriftStruct, 1)
+	w.field(thriftString, 1)
+	w.string(j.service)
+	w.stop()
+
+	w.field(thriftList, 2)
+	w.list(thriftStruct, len(spans))
+	for _, span := range spans {
+		j.encodeSpan(&w, span)
+	}
+	w.stop()
+	return w.Bytes()
+}
+
+func (j *JaegerExporter) encodeSpan(w *thriftWriter, span *Span) {
+	high, low := jaegerTraceID(span.TraceID)
+
+	w.field(thriftI64, 1)
+	w.i64(low)
+	w.field(thriftI64, 2)
+	w.i64(high)
+	w.field(thriftI64, 3)
+	w.i64(jaegerSpanID(span.SpanID))
+	w.field(thriftI64, 4)
+	w.i64(jaegerSpanID(span.ParentID))
+	w.field(thriftString, 5)
+	w.string(span.Name)
+
+	if len(span.Links) > 0 {
+		w.field(thriftList, 6)
+		w.list(thriftStruct, len(span.Links))
+		for _, link := range span.Links {
+			linkHigh, linkLow := jaegerTraceID(link.TraceID)
+			w.field(thriftI32, 1)
+			w.i32(jaegerRefFollowsFrom)
+			w.field(thriftI64, 2)
+			w.i64(linkLow)
+			w.field(thriftI64, 3)
+			w.i64(linkHigh)
+			w.field(thriftI64, 4)
+			w.i64(jaegerSpanID(link.SpanID))
+			w.stop()
+		}
+	}
+
+	w.field(thriftI32, 7)
+	w.i32(jaegerFlagSampled)
+	w.field(thriftI64, 8)
+	w.i64(span.StartTime.UnixMicro())
+	w.field(thriftI64, 9)
+	w.i64(spanDuration(span))
+
+	tags := make([]jaegerTag, 0, len(span.Attributes)+3)
+	for _, k := range sortedKeys(span.Attributes) {
+		tags = append(tags, jaegerTag{k, span.Attributes[k]})
+	}
+	if kind, ok := jaegerKinds[span.Kind]; ok {
+		tags = append(tags, jaegerTag{"span.kind", kind})
+	}
+	if span.Status.Code == StatusCodeError {
+		tags = append(tags, jaegerTag{"error", true})
+		if span.Status.Message != "" {
+			tags = append(tags, jaegerTag{"otel.status_description", span.Status.Message})
+		}
+	}
+	w.field(thriftList, 10)
+	writeJaegerTags(w, tags)
+
+	if len(span.Events) > 0 {
+		w.field(thriftList, 11)
+		w.list(thriftStruct, len(span.Events))
+		for _, event := range span.Events {
+			fields := []jaegerTag{{"event", event.Name}}
+			for _, k := range sortedKeys(event.Attributes) {
+				fields = append(fields, jaegerTag{k, event.Attributes[k]})
+			}
+			w.field(thriftI64, 1)
+			w.i64(event.Time.UnixMicro())
+			w.field(thriftList, 2)
+			writeJaegerTags(w, fields)
+			w.stop()
+		}
+	}
+	w.stop()
+}
+
+// writeJaegerTags writes list<Tag> where Tag is {1: key, 2: vType, 3: vStr,
+// 4: vDouble, 5: vBool, 6: vLong}.
+func writeJaegerTags(w *thriftWriter, tags []jaegerTag) {
+	w.list(thriftStruct, len(tags))
+	for _, tag := range tags {
+		w.field(thriftString, 1)
+		w.string(tag.key)
+		switch v := tag.value.(type) {
+		case bool:
+			w.field(thriftI32, 2)
+			w.i32(jaegerTagBool)
+			w.field(thriftBool, 5)
+			w.bool(v)
+		case int:
+			writeJaegerLong(w, int64(v))
+		case int32:
+			writeJaegerLong(w, int64(v))
+		case int64:
+			writeJaegerLong(w, v)
+		case float32:
+			writeJaegerDouble(w, float64(v))
+		case float64:
+			writeJaegerDouble(w, v)
+		default:
+			w.field(thriftI32, 2)
+			w.i32(jaegerTagString)
+			w.field(thriftString, 3)
+			w.string(attributeString(v))
+		}
+		w.stop()
+	}
+}
+
+func writeJaegerLong(w *thriftWriter, v int64) {
+	w.field(thriftI32, 2)
+	w.i32(jaegerTagLong)
+	w.field(thriftI64, 6)
+	w.i64(v)
+}
+
+func writeJaegerDouble(w *thriftWriter, v float64) {
+	w.field(thriftI32, 2)
+	w.i32(jaegerTagDouble)
+	w.field(thriftDouble, 4)
+	w.double(v)
+}
+
+// jaegerTraceID splits a hex trace ID into its high and low 64 bits.
+func jaegerTraceID(id string) (high, low int64) {
+	if len(id) > 16 {
+		high = jaegerSpanID(id[:len(id)-16])
+		id = id[len(id)-16:]
+	}
+	return high, jaegerSpanID(id)
+}
+
+// jaegerSpanID parses a hex span ID; malformed or empty IDs yield 0.
+func jaegerSpanID(id string) int64 {
+	v, err := strconv.ParseUint(id, 16, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(v)
+}
+
+// Thrift binary protocol field types.
+const (
+	thriftStop   = 0
+	thriftBool   = 2
+	thriftDouble = 4
+	thriftI32    = 8
+	thriftI64    = 10
+	thriftString = 11
+	thriftStruct = 12
+	thriftList   = 15
+)
+
+// thriftWriter encodes the subset of the Thrift binary protocol used by
+// jaeger.thrift.
+type thriftWriter struct {
+	bytes.Buffer
+}
+
+func (w *thriftWriter) field(typ byte, id int16) {
+	w.WriteByte(typ)
+	w.i16(id)
+}
+
+func (w *thriftWriter) stop() {
+	w.WriteByte(thriftStop)
+}
+
+func (w *thriftWriter) list(elem byte, n int) {
+	w.WriteByte(elem)
+	w.i32(int32(n))
+}
+
+func (w *thriftWriter) bool(v bool) {
+	if v {
+		w.WriteByte(1)
+	} else {
+		w.WriteByte(0)
+	}
+}
+
+func (w *thriftWriter) i16(v int16) {
+	w.Write(binary.BigEndian.AppendUint16(nil, uint16(v)))
+}
+
+func (w *thriftWriter) i32(v int32) {
+	w.Write(binary.BigEndian.AppendUint32(nil, uint32(v)))
+}
+
+func (w *thriftWriter) i64(v int64) {
+	w.Write(binary.BigEndian.AppendUint64(nil, uint64(v)))
+}
+
+func (w *thriftWriter) double(v float64) {
+	w.i64(int64(math.Float64bits(v)))
+}
+
+func (w *thriftWriter) string(v string) {
+	w.i32(int32(len(v)))
+	w.WriteString(v)
+}
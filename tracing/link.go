@@ -0,0 +1,71 @@
+package tracing
+
+import "context"
+
+// Carrier keys used to propagate a producer span through message metadata
+// (job metadata, event headers, ...).
+const (
+	LinkTraceIDKey = "trace.trace_id"
+	LinkSpanIDKey  = "trace.span_id"
+)
+
+// Link connects a span to a span in another trace that is causally related
+// but not its parent, e.g. the request that enqueued a job.
+type Link struct {
+	TraceID    string
+	SpanID     string
+	Attributes map[string]interface{}
+}
+
+// Valid reports whether the link references a span.
+func (l Link) Valid() bool {
+	return l.TraceID != "" && l.SpanID != ""
+}
+
+// WithLinks adds links to the span being started. Invalid links are ignored.
+func WithLinks(links ...Link) SpanStartOption {
+	return func(s *Span) {
+		for _, link := range links {
+			if link.Valid() {
+				s.Links = append(s.Links, link)
+			}
+		}
+	}
+}
+
+// LinkFromContext returns a link to the span stored in ctx, if any.
+func LinkFromContext(ctx context.Context) (Link, bool) {
+	span := getSpanFromContext(ctx)
+	if span == nil {
+		return Link{}, false
+	}
+	link := Link{TraceID: span.TraceID, SpanID: span.SpanID}
+	return link, link.Valid()
+}
+
+// InjectLink writes the span stored in ctx into carrier so a consumer in
+// another trace can link back to it. It returns false when ctx has no span.
+func InjectLink(ctx context.Context, carrier map[string]string) bool {
+	link, ok := LinkFromContext(ctx)
+	if !ok || carrier == nil {
+		return false
+	}
+	carrier[LinkTraceIDKey] = link.TraceID
+	carrier[LinkSpanIDKey] = link.SpanID
+	return true
+}
+
+// ExtractLink reads a link previously written by InjectLink.
+func ExtractLink(carrier map[string]string) (Link, bool) {
+	link := Link{TraceID: carrier[LinkTraceIDKey], SpanID: carrier[LinkSpanIDKey]}
+	return link, link.Valid()
+}
+
+// StartConsumer starts a consumer span for work produced under another trace.
+// The span starts a new trace (it is not a child of any span in ctx) and links
+// back to the producer when producer is valid.
+func (t *Tracer) StartConsumer(ctx context.Context, name string, producer Link, opts ...SpanStartOption) (context.Context, *Span) {
+	opts = append([]SpanStartOption{WithSpanKind(SpanKindConsumer), WithLinks(producer)}, opts...)
+	// The span in ctx, if any, is the producer: never become its child.
+	return t.start(ctx, nil, name, opts)
+}
@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStartConsumerLinksProducer(t *testing.T) {
+	tracer, _ := NewTracer(TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: &noopProcessor{}})
+
+	ctx, producer := tracer.Start(context.Background(), "http.request")
+	carrier := map[string]string{}
+	if !InjectLink(ctx, carrier) {
+		t.Fatal("InjectLink should succeed when ctx carries a span")
+	}
+
+	link, ok := ExtractLink(carrier)
+	if !ok {
+		t.Fatal("ExtractLink should find the injected link")
+	}
+
+	consumerCtx, consumer := tracer.StartConsumer(ctx, "job send", link)
+	if consumer.Kind != SpanKindConsumer || consumer.ParentID != "" {
+		t.Fatalf("consumer span kind = %v, parent = %q", consumer.Kind, consumer.ParentID)
+	}
+	if len(consumer.Links) != 1 || consumer.Links[0].TraceID != producer.TraceID || consumer.Links[0].SpanID != producer.SpanID {
+		t.Fatalf("links = %+v, want producer %s/%s", consumer.Links, producer.TraceID, producer.SpanID)
+	}
+	if GetSpanID(consumerCtx) != consumer.SpanID {
+		t.Fatal("consumer context should carry the consumer span")
+	}
+}
+
+func TestWithLinksSkipsInvalid(t *testing.T) {
+	tracer, _ := NewTracer(TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: &noopProcessor{}})
+
+	_, span := tracer.StartConsumer(context.Background(), "event", Link{})
+	if len(span.Links) != 0 {
+		t.Fatalf("links = %+v, want none", span.Links)
+	}
+	if _, ok := ExtractLink(nil); ok {
+		t.Fatal("ExtractLink on nil carrier should report no link")
+	}
+}
+
+type noopProcessor struct{}
+
+func (noopProcessor) OnEnd(*Span)                    {}
+func (noopProcessor) Shutdown(context.Context) error { return nil }
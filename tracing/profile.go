@@ -0,0 +1,251 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/leeforge/framework/media/storage"
+)
+
+// SlowSpanEventName is the event added to spans that exceeded the slow-span
+// threshold.
+const SlowSpanEventName = "profile.slow_span"
+
+// ArtifactStore keeps full profile dumps that are too large for span
+// attributes. It returns a reference recorded on the span.
+type ArtifactStore interface {
+	Put(ctx context.Context, name string, data []byte) (ref string, err error)
+}
+
+// SlowSpanConfig configures profile capture for spans that run longer than
+// Threshold.
+//
+// When a span is still running at Threshold, the tracer snapshots the stack
+// of the goroutine that started it and, with CaptureContention, the runtime
+// block and mutex profile totals. At End the span gets a SlowSpanEventName
+// event with the stack and the contention deltas between the snapshot and
+// the end of the span. Contention deltas are only non-zero when the
+// application enables runtime.SetBlockProfileRate or
+// runtime.SetMutexProfileFraction.
+type SlowSpanConfig struct {
+	Threshold time.Duration
+	// CaptureContention records block/mutex profile deltas.
+	CaptureContention bool
+	// MaxStackBytes truncates the stack stored on the event. Default 8KiB.
+	MaxStackBytes int
+	// MinInterval limits snapshots process-wide; a goroutine dump briefly
+	// stops the world. Default 1s.
+	MinInterval time.Duration
+	// Artifacts, when set, receives a dump of all goroutines for each
+	// snapshot; its reference is added to the event.
+	Artifacts ArtifactStore
+}
+
+type slowSpanProfiler struct {
+	config SlowSpanConfig
+
+	mu       sync.Mutex
+	lastShot time.Time
+}
+
+// slowWatch tracks one span against the threshold.
+type slowWatch struct {
+	timer       *time.Timer
+	goroutineID string
+
+	mu       sync.Mutex
+	captured bool
+	stack    string
+	block    contention
+	mutex    contention
+	artifact string
+	err      error
+}
+
+type contention struct {
+	events int64
+	cycles int64
+}
+
+func newSlowSpanProfiler(config SlowSpanConfig) *slowSpanProfiler {
+	if config.MaxStackBytes <= 0 {
+		config.MaxStackBytes = 8 << 10
+	}
+	if config.MinInterval <= 0 {
+		config.MinInterval = time.Second
+	}
+	return &slowSpanProfiler{config: config}
+}
+
+// watch arms the threshold timer for span. It must be called on the
+// goroutine handling the span.
+func (p *slowSpanProfiler) watch(span *Span) *slowWatch {
+	w := &slowWatch{goroutineID: currentGoroutineID()}
+	w.timer = time.AfterFunc(p.config.Threshold, func() { p.capture(span, w) })
+	return w
+}
+
+func (p *slowSpanProfiler) allow() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if now.Sub(p.lastShot) < p.config.MinInterval {
+		return false
+	}
+	p.lastShot = now
+	return true
+}
+
+func (p *slowSpanProfiler) capture(span *Span, w *slowWatch) {
+	if !p.allow() {
+		return
+	}
+	dump := allGoroutines()
+	stack := goroutineStack(dump, w.goroutineID)
+	if len(stack) > p.config.MaxStackBytes {
+		stack = stack[:p.config.MaxStackBytes]
+	}
+
+	var block, mutex contention
+	if p.config.CaptureContention {
+		block, mutex = blockTotals(), mutexTotals()
+	}
+
+	var artifact string
+	var err error
+	if p.config.Artifacts != nil {
+		name := fmt.Sprintf("%s-%s-goroutines.txt", span.TraceID, span.SpanID)
+		artifact, err = p.config.Artifacts.Put(context.Background(), name, dump)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.captured = true
+	w.stack = string(stack)
+	w.block, w.mutex = block, mutex
+	w.artifact, w.err = artifact, err
+}
+
+// finish stops the timer and records the snapshot on span, if one was taken.
+func (p *slowSpanProfiler) finish(span *Span, w *slowWatch) {
+	w.timer.Stop()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.captured {
+		return
+	}
+
+	attrs := map[string]interface{}{
+		"profile.threshold_ms": p.config.Threshold.Milliseconds(),
+		"profile.goroutine":    w.stack,
+	}
+	if p.config.CaptureContention {
+		block, mutex := blockTotals(), mutexTotals()
+		attrs["profile.block.events"] = block.events - w.block.events
+		attrs["profile.block.cycles"] = block.cycles - w.block.cycles
+		attrs["profile.mutex.events"] = mutex.events - w.mutex.events
+		attrs["profile.mutex.cycles"] = mutex.cycles - w.mutex.cycles
+	}
+	if w.artifact != "" {
+		attrs["profile.artifact"] = w.artifact
+	}
+	if w.err != nil {
+		attrs["profile.artifact_error"] = w.err.Error()
+	}
+	span.Events = append(span.Events, SpanEvent{Time: time.Now(), Name: SlowSpanEventName, Attributes: attrs})
+}
+
+// currentGoroutineID parses the ID from the "goroutine N [" stack header.
+func currentGoroutineID() string {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	fields := bytes.Fields(buf[:n])
+	if len(fields) < 2 {
+		return ""
+	}
+	return string(fields[1])
+}
+
+func allGoroutines() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// goroutineStack extracts one goroutine's block from a full dump.
+func goroutineStack(dump []byte, id string) []byte {
+	if id == "" {
+		return nil
+	}
+	header := []byte("goroutine " + id + " [")
+	for _, block := range bytes.Split(dump, []byte("\n\n")) {
+		if bytes.HasPrefix(block, header) {
+			return block
+		}
+	}
+	return nil
+}
+
+func blockTotals() contention {
+	return sumProfile(runtime.BlockProfile)
+}
+
+func mutexTotals() contention {
+	return sumProfile(runtime.MutexProfile)
+}
+
+func sumProfile(read func([]runtime.BlockProfileRecord) (int, bool)) contention {
+	n, _ := read(nil)
+	for {
+		records := make([]runtime.BlockProfileRecord, n+16)
+		var ok bool
+		n, ok = read(records)
+		if !ok {
+			continue
+		}
+		var total contention
+		for _, r := range records[:n] {
+			total.events += r.Count
+			total.cycles += r.Cycles
+		}
+		return total
+	}
+}
+
+// StorageArtifactStore stores profile dumps through a media storage provider.
+type StorageArtifactStore struct {
+	provider storage.StorageProvider
+	folder   string
+}
+
+// NewStorageArtifactStore creates an ArtifactStore writing private files to
+// folder of provider.
+func NewStorageArtifactStore(provider storage.StorageProvider, folder string) *StorageArtifactStore {
+	return &StorageArtifactStore{provider: provider, folder: folder}
+}
+
+// Put implements ArtifactStore. The reference is the stored URL.
+func (s *StorageArtifactStore) Put(ctx context.Context, name string, data []byte) (string, error) {
+	out, err := s.provider.Upload(ctx, storage.UploadInput{
+		File:      bytes.NewReader(data),
+		Filename:  name,
+		Folder:    s.folder,
+		IsPrivate: true,
+		Size:      int64(len(data)),
+		Metadata:  map[string]interface{}{"content_type": "text/plain"},
+	})
+	if err != nil {
+		return "", err
+	}
+	return out.URL, nil
+}
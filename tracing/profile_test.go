@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryArtifacts struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (m *memoryArtifacts) Put(_ context.Context, name string, data []byte) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.files == nil {
+		m.files = make(map[string][]byte)
+	}
+	m.files[name] = data
+	return "mem://" + name, nil
+}
+
+func slowHandler(d time.Duration) {
+	time.Sleep(d)
+}
+
+func TestSlowSpanCapturesProfile(t *testing.T) {
+	runtime.SetMutexProfileFraction(1)
+	defer runtime.SetMutexProfileFraction(0)
+
+	artifacts := &memoryArtifacts{}
+	tracer, _ := NewTracer(TracerConfig{
+		ServiceName:  "test",
+		SamplingRate: 1,
+		Processor:    &noopProcessor{},
+		SlowSpans: &SlowSpanConfig{
+			Threshold:         10 * time.Millisecond,
+			CaptureContention: true,
+			MinInterval:       time.Nanosecond,
+			Artifacts:         artifacts,
+		},
+	})
+
+	_, span := tracer.Start(context.Background(), "slow")
+	slowHandler(50 * time.Millisecond)
+	tracer.End(span, nil)
+
+	if len(span.Events) != 1 || span.Events[0].Name != SlowSpanEventName {
+		t.Fatalf("events = %+v", span.Events)
+	}
+	attrs := span.Events[0].Attributes
+	if stack, _ := attrs["profile.goroutine"].(string); !strings.Contains(stack, "slowHandler") {
+		t.Errorf("goroutine stack does not show the handler:\n%s", stack)
+	}
+	if _, ok := attrs["profile.mutex.events"].(int64); !ok {
+		t.Errorf("missing contention deltas: %+v", attrs)
+	}
+	ref, _ := attrs["profile.artifact"].(string)
+	name := strings.TrimPrefix(ref, "mem://")
+	artifacts.mu.Lock()
+	dump := artifacts.files[name]
+	artifacts.mu.Unlock()
+	if !strings.HasPrefix(name, span.TraceID) || !strings.Contains(string(dump), "goroutine ") {
+		t.Errorf("artifact %q not stored", ref)
+	}
+}
+
+func TestFastSpanIsNotProfiled(t *testing.T) {
+	tracer, _ := NewTracer(TracerConfig{
+		ServiceName:  "test",
+		SamplingRate: 1,
+		Processor:    &noopProcessor{},
+		SlowSpans:    &SlowSpanConfig{Threshold: time.Second},
+	})
+
+	_, span := tracer.Start(context.Background(), "fast")
+	tracer.End(span, nil)
+
+	if len(span.Events) != 0 {
+		t.Fatalf("events = %+v", span.Events)
+	}
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	framerrors "github.com/leeforge/framework/errors"
+)
+
+// Exception event name and attributes, following the OpenTelemetry
+// semantic conventions.
+const (
+	ExceptionEventName      = "exception"
+	AttrExceptionType       = "exception.type"
+	AttrExceptionMessage    = "exception.message"
+	AttrExceptionStacktrace = "exception.stacktrace"
+	AttrErrorCode           = "error.code"
+)
+
+// RecordError adds an exception event describing err to span and marks the
+// span as failed. The stack trace is taken from a wrapped *errors.AppError
+// when it captured one, otherwise from the caller of RecordError. attrs are
+// merged into the event. A nil err is ignored.
+func (t *Tracer) RecordError(span *Span, err error, attrs map[string]interface{}) {
+	if span == nil || err == nil {
+		return
+	}
+
+	eventAttrs := make(map[string]interface{}, len(attrs)+4)
+	for k, v := range attrs {
+		eventAttrs[k] = v
+	}
+	eventAttrs[AttrExceptionType] = fmt.Sprintf("%T", err)
+	eventAttrs[AttrExceptionMessage] = err.Error()
+
+	stack := framerrors.CaptureStack(1)
+	var appErr *framerrors.AppError
+	if errors.As(err, &appErr) {
+		if appErr.Code != "" {
+			eventAttrs[AttrErrorCode] = appErr.Code
+		}
+		if len(appErr.Stack) > 0 {
+			stack = appErr.Stack
+		}
+	}
+	eventAttrs[AttrExceptionStacktrace] = strings.Join(stack, "\n")
+
+	t.AddEvent(span, ExceptionEventName, eventAttrs)
+	t.SetError(span, err.Error())
+}
+
+// SetError marks span as failed with message.
+func (t *Tracer) SetError(span *Span, message string) {
+	t.SetStatus(span, StatusCodeError, message)
+}
+
+// SetOK marks span as successful. It does not override an error status.
+func (t *Tracer) SetOK(span *Span) {
+	if span == nil || span.Status.Code == StatusCodeError {
+		return
+	}
+	t.SetStatus(span, StatusCodeOK, "")
+}
+
+// AddLink links span to another, causally related span after it started.
+// Invalid links are ignored.
+func (t *Tracer) AddLink(span *Span, link Link) {
+	if span == nil || !link.Valid() {
+		return
+	}
+	span.Links = append(span.Links, link)
+}
+
+// LinkToSpan returns a link to span with the given attributes.
+func LinkToSpan(span *Span, attrs map[string]interface{}) Link {
+	if span == nil {
+		return Link{}
+	}
+	return Link{TraceID: span.TraceID, SpanID: span.SpanID, Attributes: attrs}
+}
+
+// HTTPStatusCode maps an HTTP response status to a span status. Server spans
+// only treat 5xx as errors since 4xx are the client's fault; client spans
+// treat both 4xx and 5xx as errors.
+func HTTPStatusCode(status int, kind SpanKind) SpanStatusCode {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return StatusCodeError
+	case status >= http.StatusBadRequest && kind != SpanKindServer:
+		return StatusCodeError
+	case status < 100:
+		return StatusCodeError
+	default:
+		return StatusCodeUnset
+	}
+}
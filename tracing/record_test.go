@@ -0,0 +1,161 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	framerrors "github.com/leeforge/framework/errors"
+)
+
+func TestRecordErrorAddsExceptionEvent(t *testing.T) {
+	tracer, _ := NewTracer(TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: &noopProcessor{}})
+	_, span := tracer.Start(context.Background(), "op")
+
+	tracer.RecordError(span, fmt.Errorf("load user: %w", errors.New("boom")), map[string]interface{}{"retry": 2})
+
+	if len(span.Events) != 1 || span.Events[0].Name != ExceptionEventName {
+		t.Fatalf("events = %+v, want one exception event", span.Events)
+	}
+	attrs := span.Events[0].Attributes
+	if attrs[AttrExceptionMessage] != "load user: boom" {
+		t.Errorf("message = %v", attrs[AttrExceptionMessage])
+	}
+	if attrs[AttrExceptionType] != "*fmt.wrapError" {
+		t.Errorf("type = %v", attrs[AttrExceptionType])
+	}
+	if attrs["retry"] != 2 {
+		t.Errorf("custom attribute lost: %v", attrs)
+	}
+	stack, _ := attrs[AttrExceptionStacktrace].(string)
+	if !strings.Contains(stack, "TestRecordErrorAddsExceptionEvent") {
+		t.Errorf("stacktrace should start at the caller, got:\n%s", stack)
+	}
+	if span.Status.Code != StatusCodeError || span.Status.Message != "load user: boom" {
+		t.Errorf("status = %+v, want error", span.Status)
+	}
+
+	tracer.RecordError(span, nil, nil)
+	if len(span.Events) != 1 {
+		t.Error("nil error should be ignored")
+	}
+}
+
+func TestRecordErrorUsesAppErrorStack(t *testing.T) {
+	tracer, _ := NewTracer(TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: &noopProcessor{}})
+	_, span := tracer.Start(context.Background(), "op")
+
+	appErr := framerrors.New(framerrors.ErrorTypeNotFound, "user not found")
+	appErr.Stack = []string{"repo.go:10 repo.Find"}
+	tracer.RecordError(span, fmt.Errorf("handler: %w", appErr), nil)
+
+	attrs := span.Events[0].Attributes
+	if attrs[AttrExceptionStacktrace] != "repo.go:10 repo.Find" {
+		t.Errorf("stacktrace = %v, want the AppError stack", attrs[AttrExceptionStacktrace])
+	}
+	if attrs[AttrErrorCode] != appErr.Code {
+		t.Errorf("error.code = %v, want %q", attrs[AttrErrorCode], appErr.Code)
+	}
+}
+
+func TestEndKeepsErrorStatus(t *testing.T) {
+	proc := &recordingProcessor{}
+	tracer, _ := NewTracer(TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: proc})
+
+	_, failed := tracer.Start(context.Background(), "failed")
+	tracer.SetError(failed, "bad")
+	tracer.SetOK(failed)
+	tracer.End(failed, nil)
+	if failed.Status.Code != StatusCodeError || failed.Status.Message != "bad" {
+		t.Errorf("status = %+v, want the earlier error kept", failed.Status)
+	}
+
+	_, ok := tracer.Start(context.Background(), "ok")
+	tracer.End(ok, nil)
+	if ok.Status.Code != StatusCodeOK {
+		t.Errorf("status = %+v, want OK", ok.Status)
+	}
+}
+
+func TestAddLink(t *testing.T) {
+	tracer, _ := NewTracer(TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: &noopProcessor{}})
+	_, producer := tracer.Start(context.Background(), "publish")
+	_, consumer := tracer.Start(context.Background(), "consume")
+
+	tracer.AddLink(consumer, LinkToSpan(producer, map[string]interface{}{"messaging.batch": true}))
+	tracer.AddLink(consumer, Link{})
+	tracer.AddLink(consumer, LinkToSpan(nil, nil))
+
+	if len(consumer.Links) != 1 {
+		t.Fatalf("links = %+v, want one valid link", consumer.Links)
+	}
+	link := consumer.Links[0]
+	if link.TraceID != producer.TraceID || link.SpanID != producer.SpanID || link.Attributes["messaging.batch"] != true {
+		t.Errorf("link = %+v, want producer span", link)
+	}
+}
+
+func TestHTTPStatusCode(t *testing.T) {
+	tests := []struct {
+		status int
+		kind   SpanKind
+		want   SpanStatusCode
+	}{
+		{200, SpanKindServer, StatusCodeUnset},
+		{404, SpanKindServer, StatusCodeUnset},
+		{404, SpanKindClient, StatusCodeError},
+		{503, SpanKindServer, StatusCodeError},
+		{500, SpanKindClient, StatusCodeError},
+		{0, SpanKindServer, StatusCodeError},
+	}
+	for _, tt := range tests {
+		if got := HTTPStatusCode(tt.status, tt.kind); got != tt.want {
+			t.Errorf("HTTPStatusCode(%d, %v) = %v, want %v", tt.status, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestTracerMiddlewareMarksServerErrors(t *testing.T) {
+	proc := &recordingProcessor{}
+	tracer, _ := NewTracer(TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: proc})
+	mw := NewTracerMiddleware(tracer)
+
+	for _, status := range []int{http.StatusOK, http.StatusNotFound, http.StatusBadGateway} {
+		h := mw.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	if len(proc.spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(proc.spans))
+	}
+	want := []SpanStatusCode{StatusCodeOK, StatusCodeOK, StatusCodeError}
+	for i, span := range proc.spans {
+		if span.Status.Code != want[i] {
+			t.Errorf("span %d (status %v) code = %v, want %v", i, span.Attributes["http.status_code"], span.Status.Code, want[i])
+		}
+	}
+	if proc.spans[2].Status.Message != "Bad Gateway" {
+		t.Errorf("message = %q, want status text", proc.spans[2].Status.Message)
+	}
+}
+
+func TestTracedOperationRecordsError(t *testing.T) {
+	proc := &recordingProcessor{}
+	tracer, _ := NewTracer(TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: proc})
+
+	want := errors.New("failed")
+	err := NewTracedOperation(tracer, "job").Execute(context.Background(), func(context.Context) error { return want })
+	if err != want {
+		t.Fatalf("err = %v, want %v", err, want)
+	}
+	span := proc.spans[0]
+	if span.Status.Code != StatusCodeError || len(span.Events) != 1 || span.Events[0].Name != ExceptionEventName {
+		t.Errorf("span = %+v, want error status with exception event", span)
+	}
+}
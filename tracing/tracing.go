@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 )
@@ -18,8 +19,11 @@ type Span struct {
 	EndTime    time.Time
 	Attributes map[string]interface{}
 	Events     []SpanEvent
+	Links      []Link
 	Status     SpanStatus
 	Kind       SpanKind
+
+	slow *slowWatch
 }
 
 // SpanEvent represents an event within a span
@@ -61,6 +65,8 @@ type Tracer struct {
 	version   string
 	processor SpanProcessor
 	sampler   Sampler
+	ids       IDGenerator
+	profiler  *slowSpanProfiler
 	mu        sync.RWMutex
 }
 
@@ -70,6 +76,11 @@ type TracerConfig struct {
 	ServiceVersion string
 	SamplingRate   float64
 	Processor      SpanProcessor
+	// SlowSpans enables profile capture for spans exceeding a threshold
+	SlowSpans *SlowSpanConfig
+	// IDGenerator overrides the crypto/rand trace and span IDs, e.g. with
+	// NewSequentialIDGenerator in tests
+	IDGenerator IDGenerator
 }
 
 // DefaultTracerConfig creates a default tracer configuration
@@ -89,20 +100,44 @@ func NewTracer(config TracerConfig) (*Tracer, error) {
 	}
 
 	sampler := NewTraceIDRatioBased(config.SamplingRate)
+	if config.IDGenerator == nil {
+		config.IDGenerator = randomIDGenerator{}
+	}
 
-	return &Tracer{
+	tracer := &Tracer{
 		name:      config.ServiceName,
 		version:   config.ServiceVersion,
 		processor: config.Processor,
 		sampler:   sampler,
-	}, nil
+		ids:       config.IDGenerator,
+	}
+	if config.SlowSpans != nil && config.SlowSpans.Threshold > 0 {
+		tracer.profiler = newSlowSpanProfiler(*config.SlowSpans)
+	}
+	return tracer, nil
 }
 
-// Start starts a new span
+// Start starts a new span. When ctx carries a span the new span joins its
+// trace as a child; otherwise it starts a new trace. The returned context
+// carries the new span, so spans started from it become its children.
 func (t *Tracer) Start(ctx context.Context, name string, opts ...SpanStartOption) (context.Context, *Span) {
+	return t.start(ctx, getSpanFromContext(ctx), name, opts)
+}
+
+// StartChild starts a span as a child of parent rather than of the span in
+// ctx, for work that outlives or runs beside the request it belongs to
+// (goroutines fanned out from a handler, callbacks holding a span). A nil
+// parent behaves like Start.
+func (t *Tracer) StartChild(ctx context.Context, parent *Span, name string, opts ...SpanStartOption) (context.Context, *Span) {
+	if parent == nil {
+		parent = getSpanFromContext(ctx)
+	}
+	return t.start(ctx, parent, name, opts)
+}
+
+func (t *Tracer) start(ctx context.Context, parent *Span, name string, opts []SpanStartOption) (context.Context, *Span) {
 	span := &Span{
-		TraceID:    generateTraceID(),
-		SpanID:     generateSpanID(),
+		SpanID:     t.ids.NewSpanID(),
 		Name:       name,
 		StartTime:  time.Now(),
 		Attributes: make(map[string]interface{}),
@@ -110,17 +145,28 @@ func (t *Tracer) Start(ctx context.Context, name string, opts ...SpanStartOption
 		Status:     SpanStatus{Code: StatusCodeUnset},
 		Kind:       SpanKindInternal,
 	}
+	if parent != nil {
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+	}
 
 	// Apply options
 	for _, opt := range opts {
 		opt(span)
 	}
+	if span.TraceID == "" {
+		span.TraceID = t.ids.NewTraceID()
+	}
 
 	// Check if should sample
 	if !t.sampler.ShouldSample(span.TraceID) {
 		span.Status.Code = StatusCodeUnset
 	}
 
+	if t.profiler != nil {
+		span.slow = t.profiler.watch(span)
+	}
+
 	// Store span in context
 	ctx = context.WithValue(ctx, spanKey{}, span)
 
@@ -135,12 +181,18 @@ func (t *Tracer) End(span *Span, err error) {
 
 	span.EndTime = time.Now()
 
+	if span.slow != nil {
+		t.profiler.finish(span, span.slow)
+		span.slow = nil
+	}
+
 	if err != nil {
 		span.Status.Code = StatusCodeError
 		span.Status.Message = err.Error()
 		span.Attributes["error"] = true
 		span.Attributes["error.message"] = err.Error()
-	} else {
+	} else if span.Status.Code == StatusCodeUnset {
+		// Keep an error status set earlier through SetStatus or RecordError
 		span.Status.Code = StatusCodeOK
 	}
 
@@ -217,6 +269,14 @@ func WithParentID(parentID string) SpanStartOption {
 	}
 }
 
+// WithNewRoot starts a new trace even when ctx carries a span.
+func WithNewRoot() SpanStartOption {
+	return func(s *Span) {
+		s.TraceID = ""
+		s.ParentID = ""
+	}
+}
+
 // SpanProcessor processes spans
 type SpanProcessor interface {
 	OnEnd(span *Span)
@@ -235,6 +295,14 @@ func NewSimpleSpanProcessor() *SimpleSpanProcessor {
 	}
 }
 
+// NewSimpleSpanProcessorWithExporter creates a simple span processor that
+// exports each span synchronously through exporter
+func NewSimpleSpanProcessorWithExporter(exporter SpanExporter) *SimpleSpanProcessor {
+	return &SimpleSpanProcessor{
+		exporter: exporter,
+	}
+}
+
 // OnEnd processes a span when it ends
 func (s *SimpleSpanProcessor) OnEnd(span *Span) {
 	if s.exporter != nil {
@@ -250,90 +318,6 @@ func (s *SimpleSpanProcessor) Shutdown(ctx context.Context) error {
 	return nil
 }
 
-// BatchSpanProcessor processes spans in batches
-type BatchSpanProcessor struct {
-	exporter  SpanExporter
-	batchSize int
-	batch     []*Span
-	mu        sync.Mutex
-	timer     *time.Timer
-	timeout   time.Duration
-}
-
-// NewBatchSpanProcessor creates a new batch span processor
-func NewBatchSpanProcessor(exporter SpanExporter, batchSize int, timeout time.Duration) *BatchSpanProcessor {
-	processor := &BatchSpanProcessor{
-		exporter:  exporter,
-		batchSize: batchSize,
-		batch:     make([]*Span, 0, batchSize),
-		timeout:   timeout,
-	}
-
-	// Start a timer to flush periodically
-	if timeout > 0 {
-		processor.timer = time.AfterFunc(timeout, func() {
-			processor.Flush()
-		})
-	}
-
-	return processor
-}
-
-// OnEnd processes a span when it ends
-func (b *BatchSpanProcessor) OnEnd(span *Span) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	b.batch = append(b.batch, span)
-
-	if len(b.batch) >= b.batchSize {
-		b.flushLocked()
-	}
-}
-
-// Flush flushes the current batch
-func (b *BatchSpanProcessor) Flush() {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	b.flushLocked()
-}
-
-func (b *BatchSpanProcessor) flushLocked() {
-	if len(b.batch) == 0 {
-		return
-	}
-
-	// Export all spans in the batch
-	for _, span := range b.batch {
-		b.exporter.Export(span)
-	}
-
-	// Clear the batch
-	b.batch = b.batch[:0]
-
-	// Reset the timer
-	if b.timer != nil {
-		b.timer.Reset(b.timeout)
-	}
-}
-
-// Shutdown shuts down the processor
-func (b *BatchSpanProcessor) Shutdown(ctx context.Context) error {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	b.flushLocked()
-
-	if b.timer != nil {
-		b.timer.Stop()
-	}
-
-	if b.exporter != nil {
-		return b.exporter.Shutdown(ctx)
-	}
-	return nil
-}
-
 // SpanExporter exports spans
 type SpanExporter interface {
 	Export(span *Span) error
@@ -350,8 +334,17 @@ func NewConsoleExporter() *ConsoleExporter {
 
 // Export exports a span to the console
 func (c *ConsoleExporter) Export(span *Span) error {
-	fmt.Printf("[TRACE] %s | TraceID: %s | SpanID: %s | Duration: %v | Status: %d\n",
-		span.Name, span.TraceID, span.SpanID, span.EndTime.Sub(span.StartTime), span.Status.Code)
+	var links strings.Builder
+	for i, link := range span.Links {
+		if i == 0 {
+			links.WriteString(" | Links: ")
+		} else {
+			links.WriteString(", ")
+		}
+		links.WriteString(link.TraceID + "/" + link.SpanID)
+	}
+	fmt.Printf("[TRACE] %s | TraceID: %s | SpanID: %s | Duration: %v | Status: %d%s\n",
+		span.Name, span.TraceID, span.SpanID, span.EndTime.Sub(span.StartTime), span.Status.Code, links.String())
 	return nil
 }
 
@@ -438,6 +431,9 @@ func (m *TracerMiddleware) Middleware(next http.Handler) http.Handler {
 		m.tracer.SetAttributes(span, map[string]interface{}{
 			"http.status_code": ww.statusCode,
 		})
+		if HTTPStatusCode(ww.statusCode, SpanKindServer) == StatusCodeError {
+			m.tracer.SetError(span, http.StatusText(ww.statusCode))
+		}
 	})
 }
 
@@ -467,11 +463,14 @@ func NewTracedOperation(tracer *Tracer, name string) *TracedOperation {
 }
 
 // Execute executes a function with tracing
-func (t *TracedOperation) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+func (t *TracedOperation) Execute(ctx context.Context, fn func(ctx context.Context) error) (err error) {
 	ctx, span := t.tracer.Start(ctx, t.name)
-	defer t.tracer.End(span, nil)
+	defer func() { t.tracer.End(span, err) }()
 
-	return fn(ctx)
+	if err = fn(ctx); err != nil {
+		t.tracer.RecordError(span, err, nil)
+	}
+	return err
 }
 
 // ExecuteWithResult executes a function with tracing and returns a result
@@ -587,6 +586,8 @@ type DistributedTracingConfig struct {
 	EnableDBTracing    bool
 	EnableCacheTracing bool
 	EnableHTTPTracing  bool
+	// Exporter selects where spans are sent; the console by default
+	Exporter ExporterConfig
 }
 
 // DefaultDistributedTracingConfig creates a default distributed tracing configuration
@@ -626,9 +627,18 @@ func NewDistributedTracer(config DistributedTracingConfig) (*DistributedTracer,
 		SamplingRate:   config.SamplingRate,
 	}
 
+	exporter, err := NewExporter(config.ServiceName, config.Exporter)
+	if err != nil {
+		return nil, err
+	}
 	if config.EnableBatching {
-		exporter := NewConsoleExporter()
-		tracerConfig.Processor = NewBatchSpanProcessor(exporter, 100, config.BatchTimeout)
+		opts := config.Exporter.Batch
+		if opts.ScheduleDelay <= 0 {
+			opts.ScheduleDelay = config.BatchTimeout
+		}
+		tracerConfig.Processor = NewBatchSpanProcessorWithOptions(exporter, opts)
+	} else {
+		tracerConfig.Processor = NewSimpleSpanProcessorWithExporter(exporter)
 	}
 
 	tracer, err := NewTracer(tracerConfig)
@@ -737,6 +747,17 @@ func (d *DistributedTracer) TraceBusinessOperation(ctx context.Context, name str
 	})
 }
 
+// SpanFromContext returns the span stored in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	return getSpanFromContext(ctx)
+}
+
+// ContextWithSpan returns a copy of ctx carrying span, so spans started
+// from it become children of span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
 // GetTraceID gets the trace ID from context
 func GetTraceID(ctx context.Context) string {
 	span := getSpanFromContext(ctx)
@@ -773,14 +794,6 @@ func getSpanFromContext(ctx context.Context) *Span {
 	return span
 }
 
-func generateTraceID() string {
-	return fmt.Sprintf("%016x", time.Now().UnixNano())
-}
-
-func generateSpanID() string {
-	return fmt.Sprintf("%016x", time.Now().UnixNano())
-}
-
 func hash(s string) int {
 	h := 0
 	for i := 0; i < len(s); i++ {
@@ -0,0 +1,133 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type recordingProcessor struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (p *recordingProcessor) OnEnd(span *Span) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.spans = append(p.spans, span)
+}
+
+func (p *recordingProcessor) Shutdown(context.Context) error { return nil }
+
+func TestStartInheritsParentFromContext(t *testing.T) {
+	tracer, _ := NewTracer(TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: &noopProcessor{}})
+
+	ctx, root := tracer.Start(context.Background(), "http.request")
+	if root.ParentID != "" || root.TraceID == "" {
+		t.Fatalf("root span = %+v, want a new trace without parent", root)
+	}
+	if SpanFromContext(ctx) != root {
+		t.Fatal("Start should return a context carrying the new span")
+	}
+
+	childCtx, child := tracer.Start(ctx, "db.query")
+	_, grandchild := tracer.Start(childCtx, "db.row")
+	if child.TraceID != root.TraceID || child.ParentID != root.SpanID {
+		t.Fatalf("child = %s/%s, want trace %s parent %s", child.TraceID, child.ParentID, root.TraceID, root.SpanID)
+	}
+	if grandchild.TraceID != root.TraceID || grandchild.ParentID != child.SpanID {
+		t.Fatalf("grandchild = %s/%s, want trace %s parent %s", grandchild.TraceID, grandchild.ParentID, root.TraceID, child.SpanID)
+	}
+
+	// 兄弟 Span 共享父级
+	_, sibling := tracer.Start(ctx, "cache.get")
+	if sibling.ParentID != root.SpanID {
+		t.Fatalf("sibling parent = %q, want %q", sibling.ParentID, root.SpanID)
+	}
+}
+
+func TestStartChildUsesExplicitParent(t *testing.T) {
+	tracer, _ := NewTracer(TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: &noopProcessor{}})
+
+	_, request := tracer.Start(context.Background(), "http.request")
+	ctx, other := tracer.Start(context.Background(), "unrelated")
+
+	childCtx, child := tracer.StartChild(ctx, request, "fanout")
+	if child.TraceID != request.TraceID || child.ParentID != request.SpanID {
+		t.Fatalf("child = %s/%s, want trace %s parent %s", child.TraceID, child.ParentID, request.TraceID, request.SpanID)
+	}
+	if GetSpanID(childCtx) != child.SpanID {
+		t.Fatal("StartChild should return a context carrying the child span")
+	}
+
+	_, fallback := tracer.StartChild(ctx, nil, "fallback")
+	if fallback.ParentID != other.SpanID {
+		t.Fatalf("nil parent should fall back to the span in ctx, got parent %q", fallback.ParentID)
+	}
+}
+
+func TestWithNewRootIgnoresContextSpan(t *testing.T) {
+	tracer, _ := NewTracer(TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: &noopProcessor{}})
+
+	ctx, parent := tracer.Start(context.Background(), "http.request")
+	_, root := tracer.Start(ctx, "background.sync", WithNewRoot())
+	if root.ParentID != "" {
+		t.Fatalf("root parent = %q, want none", root.ParentID)
+	}
+	if root.TraceID == "" || root.TraceID == parent.TraceID {
+		t.Fatalf("root = %+v, want a span in a new trace", root)
+	}
+}
+
+func TestNestedOperationsFormOneTrace(t *testing.T) {
+	proc := &recordingProcessor{}
+	dt, err := NewDistributedTracer(DistributedTracingConfig{
+		Enable: true, ServiceName: "test", SamplingRate: 1,
+		EnableDBTracing: true, EnableCacheTracing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dt.tracer.processor = proc
+
+	boom := errors.New("boom")
+	err = dt.TraceBusinessOperation(context.Background(), "order.create", nil, func(ctx context.Context) error {
+		if err := dt.TraceCacheOperation(ctx, "get", "order:1", func(context.Context) error { return nil }); err != nil {
+			return err
+		}
+		return dt.TraceDBQuery(ctx, "INSERT INTO orders", func(context.Context) error { return boom })
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+
+	if len(proc.spans) != 3 {
+		t.Fatalf("ended %d spans, want 3", len(proc.spans))
+	}
+	byName := map[string]*Span{}
+	for _, s := range proc.spans {
+		byName[s.Name] = s
+	}
+	root := byName["order.create"]
+	for _, name := range []string{"cache.get", "db.query"} {
+		s := byName[name]
+		if s == nil || s.TraceID != root.TraceID || s.ParentID != root.SpanID {
+			t.Errorf("%s = %+v, want child of %s/%s", name, s, root.TraceID, root.SpanID)
+		}
+	}
+}
+
+func TestContextWithSpan(t *testing.T) {
+	tracer, _ := NewTracer(TracerConfig{ServiceName: "test", SamplingRate: 1, Processor: &noopProcessor{}})
+
+	_, span := tracer.Start(context.Background(), "job")
+	ctx := ContextWithSpan(context.Background(), span)
+	if GetTraceID(ctx) != span.TraceID {
+		t.Fatal("ContextWithSpan should make the span current")
+	}
+	_, child := tracer.Start(ctx, "step")
+	if child.ParentID != span.SpanID {
+		t.Fatalf("child parent = %q, want %q", child.ParentID, span.SpanID)
+	}
+}
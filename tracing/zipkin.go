@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ZipkinExporter sends spans to a Zipkin collector as v2 JSON.
+type ZipkinExporter struct {
+	service string
+	sender  *httpSender
+}
+
+// NewZipkinExporter creates a Zipkin exporter reporting spans as serviceName.
+func NewZipkinExporter(serviceName string, config ExporterConfig) *ZipkinExporter {
+	return &ZipkinExporter{
+		service: serviceName,
+		sender:  newHTTPSender(ExporterZipkin, DefaultZipkinEndpoint, config),
+	}
+}
+
+// Export sends a single span.
+func (z *ZipkinExporter) Export(span *Span) error {
+	return z.sender.exportOne(z, span)
+}
+
+// ExportBatch sends spans in one request.
+func (z *ZipkinExporter) ExportBatch(ctx context.Context, spans []*Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	out := make([]zipkinSpan, len(spans))
+	for i, span := range spans {
+		out[i] = z.encode(span)
+	}
+	body, err := json.Marshal(out)
+	if err != nil {
+		return fmt.Errorf("tracing: zipkin encode: %w", err)
+	}
+	return z.sender.post(ctx, "application/json", body)
+}
+
+// Shutdown releases idle connections.
+func (z *ZipkinExporter) Shutdown(ctx context.Context) error {
+	z.sender.shutdown()
+	return nil
+}
+
+// Zipkin v2 span model, see zipkin-api's zipkin2-api.yaml.
+type (
+	zipkinSpan struct {
+		TraceID       string             `json:"traceId"`
+		ID            string             `json:"id"`
+		ParentID      string             `json:"parentId,omitempty"`
+		Name          string             `json:"name"`
+		Kind          string             `json:"kind,omitempty"`
+		Timestamp     int64              `json:"timestamp"`
+		Duration      int64              `json:"duration"`
+		LocalEndpoint zipkinEndpoint     `json:"localEndpoint"`
+		Annotations   []zipkinAnnotation `json:"annotations,omitempty"`
+		Tags          map[string]string  `json:"tags,omitempty"`
+	}
+	zipkinEndpoint struct {
+		ServiceName string `json:"serviceName"`
+	}
+	zipkinAnnotation struct {
+		Timestamp int64  `json:"timestamp"`
+		Value     string `json:"value"`
+	}
+)
+
+var zipkinKinds = map[SpanKind]string{
+	SpanKindServer:   "SERVER",
+	SpanKindClient:   "CLIENT",
+	SpanKindProducer: "PRODUCER",
+	SpanKindConsumer: "CONSUMER",
+}
+
+func (z *ZipkinExporter) encode(span *Span) zipkinSpan {
+	tags := make(map[string]string, len(span.Attributes)+2)
+	for k, v := range span.Attributes {
+		tags[k] = attributeString(v)
+	}
+	switch span.Status.Code {
+	case StatusCodeError:
+		tags["otel.status_code"] = "ERROR"
+		// Zipkin marks failed spans by the presence of the error tag
+		tags["error"] = span.Status.Message
+		if tags["error"] == "" {
+			tags["error"] = "true"
+		}
+	case StatusCodeOK:
+		tags["otel.status_code"] = "OK"
+	}
+
+	var annotations []zipkinAnnotation
+	for _, event := range span.Events {
+		value := event.Name
+		if len(event.Attributes) > 0 {
+			if attrs, err := json.Marshal(event.Attributes); err == nil {
+				value += " " + string(attrs)
+			}
+		}
+		annotations = append(annotations, zipkinAnnotation{Timestamp: event.Time.UnixMicro(), Value: value})
+	}
+
+	return zipkinSpan{
+		TraceID:       span.TraceID,
+		ID:            span.SpanID,
+		ParentID:      span.ParentID,
+		Name:          span.Name,
+		Kind:          zipkinKinds[span.Kind],
+		Timestamp:     span.StartTime.UnixMicro(),
+		Duration:      spanDuration(span),
+		LocalEndpoint: zipkinEndpoint{ServiceName: z.service},
+		Annotations:   annotations,
+		Tags:          tags,
+	}
+}
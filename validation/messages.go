@@ -0,0 +1,130 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	validatorV10 "github.com/go-playground/validator/v10"
+)
+
+// Translator returns the message of a failed rule in the language of ctx,
+// or false to keep the default English one. param is the rule's parameter,
+// e.g. "2" for min=2.
+type Translator func(ctx context.Context, rule, field, param string) (string, bool)
+
+var translator atomic.Pointer[Translator]
+
+// SetTranslator localizes the messages of every validator built on this
+// package, e.g. with i18n.Bundle.TranslateRule. It takes precedence over
+// the default and registered messages; nil removes it. The config loader
+// translates with a background context, i.e. into the default language.
+func SetTranslator(fn Translator) {
+	if fn == nil {
+		translator.Store(nil)
+		return
+	}
+	translator.Store(&fn)
+}
+
+// messages holds the messages of the framework rules and SetMessage.
+var messages sync.Map
+
+func init() {
+	for _, rule := range Rules() {
+		messages.Store(rule.Tag, rule.Message)
+	}
+}
+
+// SetMessage sets the English message reported when the rule tag fails,
+// for rules registered outside this package.
+func SetMessage(tag, message string) {
+	messages.Store(tag, message)
+}
+
+// Message returns the message for fe: the translation when a Translator
+// is set and has one, else the message set for the rule, else a default
+// describing the built-in rule. Messages do not include the field name.
+func Message(ctx context.Context, fe validatorV10.FieldError) string {
+	if translate := translator.Load(); translate != nil {
+		if message, ok := (*translate)(ctx, fe.Tag(), fe.Field(), fe.Param()); ok {
+			return message
+		}
+	}
+	if message, ok := messages.Load(fe.Tag()); ok {
+		return message.(string)
+	}
+	return defaultMessage(fe.Tag(), fe.Param(), fe.Kind())
+}
+
+// defaultMessage describes the built-in rules. min, max and len read as
+// lengths for strings, item counts for collections and values otherwise.
+func defaultMessage(tag, param string, kind reflect.Kind) string {
+	switch tag {
+	case "required", "required_if", "required_unless", "required_with", "required_with_all",
+		"required_without", "required_without_all":
+		return "is required"
+	case "required_one_of":
+		return fmt.Sprintf("at least one of %s is required", param)
+	case "excluded_with":
+		return fmt.Sprintf("must not be set together with %s", param)
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return sizeMessage("at least", param, kind)
+	case "max":
+		return sizeMessage("at most", param, kind)
+	case "len":
+		return sizeMessage("exactly", param, kind)
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", param)
+	case "lte":
+		return fmt.Sprintf("must be less than or equal to %s", param)
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", param)
+	case "lt":
+		return fmt.Sprintf("must be less than %s", param)
+	case "eqfield":
+		return fmt.Sprintf("must be equal to %s", param)
+	case "nefield":
+		return fmt.Sprintf("must not be equal to %s", param)
+	case "gtfield":
+		return fmt.Sprintf("must be greater than %s", param)
+	case "gtefield":
+		return fmt.Sprintf("must be greater than or equal to %s", param)
+	case "ltfield":
+		return fmt.Sprintf("must be less than %s", param)
+	case "ltefield":
+		return fmt.Sprintf("must be less than or equal to %s", param)
+	case "alphanum":
+		return "must contain only alphanumeric characters"
+	case "alpha":
+		return "must contain only alphabetic characters"
+	case "numeric":
+		return "must be a valid number"
+	case "url":
+		return "must be a valid URL"
+	case "uri":
+		return "must be a valid URI"
+	case "uuid":
+		return "must be a valid UUID"
+	case "e164":
+		return "must be a valid phone number in E.164 format"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", param)
+	default:
+		return fmt.Sprintf("failed validation for tag '%s'", tag)
+	}
+}
+
+func sizeMessage(bound, param string, kind reflect.Kind) string {
+	switch kind {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("must contain %s %s items", bound, param)
+	case reflect.String:
+		return fmt.Sprintf("must be %s %s characters long", bound, param)
+	}
+	return fmt.Sprintf("must be %s %s", bound, param)
+}
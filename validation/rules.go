@@ -0,0 +1,156 @@
+package validation
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	validatorV10 "github.com/go-playground/validator/v10"
+	"golang.org/x/net/html"
+)
+
+var (
+	uuid4Pattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-4[0-9a-fA-F]{3}-[89abAB][0-9a-fA-F]{3}-[0-9a-fA-F]{12}$`)
+	ulidPattern  = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}$`)
+	slugPattern  = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+)
+
+// IsUUID4 reports whether s is a hyphenated UUID version 4 of the RFC 4122
+// variant.
+func IsUUID4(s string) bool {
+	return uuid4Pattern.MatchString(s)
+}
+
+// IsULID reports whether s is a ULID: 26 Crockford base32 characters, case
+// insensitive, whose first character keeps the timestamp within 48 bits.
+func IsULID(s string) bool {
+	return ulidPattern.MatchString(s)
+}
+
+// IsSlug reports whether s is a URL slug such as "release-2024".
+func IsSlug(s string) bool {
+	return slugPattern.MatchString(s)
+}
+
+// IsPhone reports whether s is an E.164 phone number: "+", a country code
+// and up to 15 digits in total, without separators.
+func IsPhone(s string) bool {
+	return phonePattern.MatchString(s)
+}
+
+// IsTimeZone reports whether s names an IANA time zone. "UTC" is accepted;
+// "" and "Local" are not, as they depend on the host.
+func IsTimeZone(s string) bool {
+	if s == "" || s == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(s)
+	return err == nil
+}
+
+// safeTags are the elements IsSafeHTML allows, with their allowed
+// attributes.
+var safeTags = map[string][]string{
+	"a": {"href", "title"}, "b": nil, "strong": nil, "i": nil, "em": nil, "u": nil, "s": nil,
+	"p": nil, "br": nil, "hr": nil, "blockquote": nil, "code": nil, "pre": nil,
+	"ul": nil, "ol": nil, "li": nil, "span": nil, "sub": nil, "sup": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+}
+
+// IsSafeHTML reports whether s uses only basic formatting elements, such
+// as p, strong, em, lists, headings and links, with no attributes other
+// than a link's title and an http, https, mailto or relative href. Scripts,
+// styles, event handlers, comments and other markup are rejected, so the
+// value can be rendered unescaped. Plain text is safe.
+func IsSafeHTML(s string) bool {
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return errors.Is(z.Err(), io.EOF)
+		case html.TextToken:
+			continue
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			allowed, ok := safeTags[string(name)]
+			if !ok {
+				return false
+			}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				if !contains(allowed, string(key)) {
+					return false
+				}
+				if string(key) == "href" && !safeURL(string(val)) {
+					return false
+				}
+			}
+		default:
+			// Comments and doctypes can hide markup from later filters.
+			return false
+		}
+	}
+}
+
+// safeURL reports whether an href cannot run script: a relative URL or one
+// with an http, https or mailto scheme.
+func safeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldString returns the field's string value, dereferencing pointers.
+// Other types are formatted with encoding.TextMarshaler or fmt.Stringer, as
+// the built-in uuid4 rule does, so uuid.UUID fields keep validating.
+func fieldString(fl validatorV10.FieldLevel) (string, bool) {
+	field := fl.Field()
+	for field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return "", false
+		}
+		field = field.Elem()
+	}
+	if field.Kind() == reflect.String {
+		return field.String(), true
+	}
+	if !field.CanInterface() {
+		return "", false
+	}
+	v := field.Interface()
+	if field.CanAddr() {
+		// the pointer's method set includes pointer-receiver methods too
+		v = field.Addr().Interface()
+	}
+	switch v := v.(type) {
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		return string(text), err == nil
+	case fmt.Stringer:
+		return v.String(), true
+	}
+	return "", false
+}
@@ -0,0 +1,143 @@
+package validation
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	validatorV10 "github.com/go-playground/validator/v10"
+)
+
+// StructRule is a struct-level check reporting failures with
+// sl.ReportError. Helpers cover the common cross-field cases; fields are
+// named by their Go names and reported under them.
+type StructRule func(ctx context.Context, sl validatorV10.StructLevel)
+
+// RegisterStruct runs rules whenever a value of the type of example is
+// validated by v, after its field tags. Register before the first
+// validation of the type; v caches the type's rules then.
+//
+//	validation.RegisterStruct(v, Booking{},
+//		validation.RequireOneOf("Email", "Phone"),
+//		validation.Ordered("StartsAt", "EndsAt"),
+//	)
+func RegisterStruct(v *validatorV10.Validate, example any, rules ...StructRule) {
+	v.RegisterStructValidationCtx(func(ctx context.Context, sl validatorV10.StructLevel) {
+		for _, rule := range rules {
+			rule(ctx, sl)
+		}
+	}, example)
+}
+
+// RequireOneOf requires at least one of fields to be non-zero. A failure
+// is reported on the first field with the "required_one_of" tag.
+func RequireOneOf(fields ...string) StructRule {
+	return func(_ context.Context, sl validatorV10.StructLevel) {
+		for _, name := range fields {
+			if f, ok := structField(sl, name); ok && !isZero(f) {
+				return
+			}
+		}
+		if len(fields) > 0 {
+			f, _ := structField(sl, fields[0])
+			sl.ReportError(valueOf(f), fields[0], fields[0], "required_one_of", strings.Join(fields, " "))
+		}
+	}
+}
+
+// Exclusive allows at most one of fields to be non-zero. Each further
+// non-zero field is reported with the "excluded_with" tag naming the first.
+func Exclusive(fields ...string) StructRule {
+	return func(_ context.Context, sl validatorV10.StructLevel) {
+		first := ""
+		for _, name := range fields {
+			f, ok := structField(sl, name)
+			if !ok || isZero(f) {
+				continue
+			}
+			if first == "" {
+				first = name
+				continue
+			}
+			sl.ReportError(valueOf(f), name, name, "excluded_with", first)
+		}
+	}
+}
+
+// Ordered requires the lower field not to exceed the upper one, e.g. a
+// start before an end. Numbers, strings, time.Time and pointers to them
+// compare; the rule is skipped while either field is zero. A failure is
+// reported on upper with the "gtefield" tag.
+func Ordered(lower, upper string) StructRule {
+	return func(_ context.Context, sl validatorV10.StructLevel) {
+		lo, ok1 := structField(sl, lower)
+		hi, ok2 := structField(sl, upper)
+		if !ok1 || !ok2 || isZero(lo) || isZero(hi) {
+			return
+		}
+		if order, ok := compare(deref(lo), deref(hi)); ok && order > 0 {
+			sl.ReportError(valueOf(hi), upper, upper, "gtefield", lower)
+		}
+	}
+}
+
+// structField returns the named field of the struct being validated. An
+// unknown name is a programming error and panics.
+func structField(sl validatorV10.StructLevel, name string) (reflect.Value, bool) {
+	current := deref(sl.Current())
+	if current.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	f := current.FieldByName(name)
+	if !f.IsValid() {
+		panic(fmt.Sprintf("validation: %s has no field %s", current.Type(), name))
+	}
+	return f, true
+}
+
+func isZero(v reflect.Value) bool {
+	return !v.IsValid() || v.IsZero()
+}
+
+func valueOf(v reflect.Value) any {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func deref(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// compare orders a and b when they are comparable kinds of the same type.
+func compare(a, b reflect.Value) (int, bool) {
+	if !a.IsValid() || !b.IsValid() || a.Type() != b.Type() {
+		return 0, false
+	}
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time)), true
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float()), true
+	case reflect.String:
+		return strings.Compare(a.String(), b.String()), true
+	}
+	return 0, false
+}
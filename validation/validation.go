@@ -0,0 +1,119 @@
+// Package validation holds the validate rules and messages shared by the
+// framework's validators, so services stop re-registering the same custom
+// rules. http/binding and the config loader both build their validator
+// with New and report failures with Message:
+//
+//	type CreateTenantUser struct {
+//		TenantID string `json:"tenant_id" validate:"required,uuid4,tenant_exists"`
+//		Handle   string `json:"handle" validate:"required,slug,max=40"`
+//		Phone    string `json:"phone" validate:"omitempty,phone"`
+//		TimeZone string `json:"time_zone" validate:"omitempty,timezone"`
+//		Bio      string `json:"bio" validate:"safe_html"`
+//	}
+//
+// Cross-field checks that struct tags cannot express are written as
+// StructRules and registered per type; messages are translated through
+// SetTranslator, e.g. with i18n.Bundle.TranslateRule.
+package validation
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	validatorV10 "github.com/go-playground/validator/v10"
+)
+
+// Rule is a validate tag and its check.
+type Rule struct {
+	Tag  string
+	Func validatorV10.FuncCtx
+	// Message is reported when the rule fails, unless translated.
+	Message string
+}
+
+// Rules returns the framework rules:
+//
+//	uuid4          canonical, hyphenated UUID version 4 (any case)
+//	ulid           26-character Crockford base32 ULID
+//	slug           lowercase letters and digits separated by single hyphens
+//	phone          E.164 phone number, e.g. +8613800138000
+//	timezone       IANA time zone name, e.g. Asia/Shanghai; not "Local"
+//	safe_html      HTML using only basic formatting tags and http(s)/mailto links
+//	tenant_exists  tenant ID accepted by the checker set with SetTenantChecker
+//
+// All apply to strings and to types implementing encoding.TextMarshaler or
+// fmt.Stringer, such as uuid.UUID; uuid4, ulid, slug, phone and timezone
+// fail on empty strings, so combine them with omitempty for optional fields.
+func Rules() []Rule {
+	return []Rule{
+		{Tag: "uuid4", Func: stringRule(IsUUID4), Message: "must be a valid UUID v4"},
+		{Tag: "ulid", Func: stringRule(IsULID), Message: "must be a valid ULID"},
+		{Tag: "slug", Func: stringRule(IsSlug), Message: "must contain only lowercase letters, digits and single hyphens"},
+		{Tag: "phone", Func: stringRule(IsPhone), Message: "must be a valid phone number in E.164 format"},
+		{Tag: "timezone", Func: stringRule(IsTimeZone), Message: "must be a valid IANA time zone"},
+		{Tag: "safe_html", Func: stringRule(IsSafeHTML), Message: "contains disallowed HTML"},
+		{Tag: "tenant_exists", Func: tenantExists, Message: "does not refer to an existing tenant"},
+	}
+}
+
+// New returns a validator with the framework rules registered.
+func New() *validatorV10.Validate {
+	v := validatorV10.New()
+	if err := Register(v); err != nil {
+		// The framework rules are static; failing to register them is a bug.
+		panic(err)
+	}
+	return v
+}
+
+// Register adds the framework rules to v, replacing built-in rules of the
+// same name.
+func Register(v *validatorV10.Validate) error {
+	for _, rule := range Rules() {
+		if err := v.RegisterValidationCtx(rule.Tag, rule.Func); err != nil {
+			return fmt.Errorf("validation: register %s: %w", rule.Tag, err)
+		}
+	}
+	return nil
+}
+
+// stringRule adapts a string predicate; fields fieldString cannot format fail.
+func stringRule(ok func(string) bool) validatorV10.FuncCtx {
+	return func(_ context.Context, fl validatorV10.FieldLevel) bool {
+		s, isString := fieldString(fl)
+		return isString && ok(s)
+	}
+}
+
+// TenantChecker reports whether the tenant with id exists.
+type TenantChecker func(ctx context.Context, id string) (bool, error)
+
+var tenantChecker atomic.Pointer[TenantChecker]
+
+// SetTenantChecker sets the lookup behind the tenant_exists rule, usually a
+// query against the tenant store. Without one, or when it returns an error,
+// the rule fails. Empty values are left to required/omitempty.
+func SetTenantChecker(fn TenantChecker) {
+	if fn == nil {
+		tenantChecker.Store(nil)
+		return
+	}
+	tenantChecker.Store(&fn)
+}
+
+func tenantExists(ctx context.Context, fl validatorV10.FieldLevel) bool {
+	id, ok := fieldString(fl)
+	if !ok {
+		return false
+	}
+	if id == "" {
+		return true
+	}
+	check := tenantChecker.Load()
+	if check == nil {
+		return false
+	}
+	exists, err := (*check)(ctx, id)
+	return err == nil && exists
+}
@@ -0,0 +1,207 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	validatorV10 "github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringRules(t *testing.T) {
+	cases := []struct {
+		name  string
+		check func(string) bool
+		ok    []string
+		bad   []string
+	}{
+		{"uuid4", IsUUID4,
+			[]string{"9b2f8c1e-3d4a-4f6b-8a7c-1e2d3c4b5a69", "9B2F8C1E-3D4A-4F6B-BA7C-1E2D3C4B5A69"},
+			[]string{"", "9b2f8c1e-3d4a-1f6b-8a7c-1e2d3c4b5a69", "9b2f8c1e-3d4a-4f6b-ca7c-1e2d3c4b5a69", "9b2f8c1e3d4a4f6b8a7c1e2d3c4b5a69"}},
+		{"ulid", IsULID,
+			[]string{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "7zzzzzzzzzzzzzzzzzzzzzzzzz"},
+			[]string{"", "81ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEKTSV4RRFFQ69G5FAI", "01ARZ3NDEKTSV4RRFFQ69G5FA"}},
+		{"slug", IsSlug,
+			[]string{"release", "release-2024", "a-b-c"},
+			[]string{"", "Release", "release--2024", "-release", "release-", "re lease", "发布"}},
+		{"phone", IsPhone,
+			[]string{"+8613800138000", "+14155550100"},
+			[]string{"", "13800138000", "+86 138 0013 8000", "+0123456789", "+1234567890123456"}},
+		{"timezone", IsTimeZone,
+			[]string{"UTC", "Asia/Shanghai", "America/New_York"},
+			[]string{"", "Local", "Mars/Olympus", "asia/shanghai"}},
+		{"safe_html", IsSafeHTML,
+			[]string{"", "plain text & more", `<p>Hi <strong>there</strong><br/></p>`, `<a href="https://example.com" title="x">link</a>`, `<a href="/docs">docs</a>`},
+			[]string{`<script>alert(1)</script>`, `<p onclick="x()">hi</p>`, `<a href="javascript:alert(1)">x</a>`,
+				`<a href="jav&#x61;script:alert(1)">x</a>`, `<img src=x onerror=alert(1)>`, `<!-- hidden -->`, `<p style="color:red">x</p>`}},
+	}
+	for _, c := range cases {
+		for _, s := range c.ok {
+			require.True(t, c.check(s), "%s should accept %q", c.name, s)
+		}
+		for _, s := range c.bad {
+			require.False(t, c.check(s), "%s should reject %q", c.name, s)
+		}
+	}
+}
+
+type account struct {
+	ID       string  `validate:"uuid4"`
+	Handle   string  `validate:"required,slug,max=10"`
+	Phone    *string `validate:"omitempty,phone"`
+	TimeZone string  `validate:"omitempty,timezone"`
+	Tenant   string  `validate:"tenant_exists"`
+	Tags     []string
+}
+
+func TestRulesAndMessages(t *testing.T) {
+	v := New()
+	phone := "138"
+	err := v.StructCtx(context.Background(), &account{ID: "nope", Handle: "Not A Slug", Phone: &phone, TimeZone: "Mars/Olympus", Tenant: "t1"})
+
+	var verrs validatorV10.ValidationErrors
+	require.True(t, errors.As(err, &verrs))
+	got := map[string]string{}
+	for _, fe := range verrs {
+		got[fe.Field()] = Message(context.Background(), fe)
+	}
+	require.Equal(t, map[string]string{
+		"ID":       "must be a valid UUID v4",
+		"Handle":   "must contain only lowercase letters, digits and single hyphens",
+		"Phone":    "must be a valid phone number in E.164 format",
+		"TimeZone": "must be a valid IANA time zone",
+		"Tenant":   "does not refer to an existing tenant",
+	}, got)
+}
+
+func TestUUID4AcceptsUUIDFields(t *testing.T) {
+	v := New()
+	type request struct {
+		ID    uuid.UUID  `validate:"uuid4"`
+		Owner *uuid.UUID `validate:"omitempty,uuid4"`
+	}
+	owner := uuid.New()
+	require.NoError(t, v.StructCtx(context.Background(), &request{ID: uuid.New(), Owner: &owner}))
+	require.NoError(t, v.StructCtx(context.Background(), request{ID: uuid.New()}))
+
+	v7 := uuid.Must(uuid.NewV7())
+	require.Error(t, v.StructCtx(context.Background(), &request{ID: v7}))
+	require.Error(t, v.StructCtx(context.Background(), &request{ID: uuid.New(), Owner: &v7}))
+}
+
+func TestTenantExists(t *testing.T) {
+	v := New()
+	type request struct {
+		Tenant string `validate:"omitempty,tenant_exists"`
+	}
+	type ctxKey struct{}
+	SetTenantChecker(func(ctx context.Context, id string) (bool, error) {
+		if ctx.Value(ctxKey{}) == nil {
+			return false, errors.New("no context")
+		}
+		return id == "acme", nil
+	})
+	t.Cleanup(func() { SetTenantChecker(nil) })
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, true)
+	require.NoError(t, v.StructCtx(ctx, &request{Tenant: "acme"}))
+	require.NoError(t, v.StructCtx(ctx, &request{}))
+	require.Error(t, v.StructCtx(ctx, &request{Tenant: "globex"}))
+	// 检查函数出错时规则失败
+	require.Error(t, v.StructCtx(context.Background(), &request{Tenant: "acme"}))
+
+	SetTenantChecker(nil)
+	require.Error(t, v.StructCtx(ctx, &request{Tenant: "acme"}))
+}
+
+type booking struct {
+	Email    string
+	Phone    string
+	Coupon   string
+	GiftCard string
+	StartsAt time.Time
+	EndsAt   *time.Time
+	MinSeats int
+	MaxSeats int
+}
+
+func TestStructRules(t *testing.T) {
+	v := New()
+	RegisterStruct(v, booking{},
+		RequireOneOf("Email", "Phone"),
+		Exclusive("Coupon", "GiftCard"),
+		Ordered("StartsAt", "EndsAt"),
+		Ordered("MinSeats", "MaxSeats"),
+	)
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+
+	failures := func(b booking) map[string]string {
+		err := v.Struct(&b)
+		if err == nil {
+			return nil
+		}
+		var verrs validatorV10.ValidationErrors
+		require.True(t, errors.As(err, &verrs), "%v", err)
+		got := map[string]string{}
+		for _, fe := range verrs {
+			got[fe.Field()] = fe.Tag() + ": " + Message(context.Background(), fe)
+		}
+		return got
+	}
+
+	require.Nil(t, failures(booking{Phone: "+14155550100", Coupon: "X", StartsAt: earlier, EndsAt: &now, MinSeats: 1, MaxSeats: 1}))
+	// 零值字段不参与比较
+	require.Nil(t, failures(booking{Email: "a@example.com", MinSeats: 3}))
+	require.Equal(t, map[string]string{
+		"Email":    "required_one_of: at least one of Email Phone is required",
+		"GiftCard": "excluded_with: must not be set together with Coupon",
+		"EndsAt":   "gtefield: must be greater than or equal to StartsAt",
+		"MaxSeats": "gtefield: must be greater than or equal to MinSeats",
+	}, failures(booking{Coupon: "X", GiftCard: "Y", StartsAt: now, EndsAt: &earlier, MinSeats: 4, MaxSeats: 2}))
+
+	// 字段名写错属于编程错误
+	typo := New()
+	RegisterStruct(typo, booking{}, RequireOneOf("Fax"))
+	require.Panics(t, func() { _ = typo.Struct(&booking{}) })
+}
+
+func TestMessageTranslation(t *testing.T) {
+	v := New()
+	type form struct {
+		Name  string   `validate:"min=3"`
+		Age   int      `validate:"max=10"`
+		Tags  []string `validate:"min=2"`
+		Token string   `validate:"custom"`
+	}
+	require.NoError(t, v.RegisterValidationCtx("custom", func(context.Context, validatorV10.FieldLevel) bool { return false }))
+	SetMessage("custom", "is not a valid token")
+
+	messages := func() []string {
+		var verrs validatorV10.ValidationErrors
+		require.True(t, errors.As(v.Struct(&form{Name: "al", Age: 11, Tags: []string{"a"}}), &verrs))
+		var out []string
+		for _, fe := range verrs {
+			out = append(out, Message(context.Background(), fe))
+		}
+		return out
+	}
+	require.Equal(t, []string{
+		"must be at least 3 characters long",
+		"must be at most 10",
+		"must contain at least 2 items",
+		"is not a valid token",
+	}, messages())
+
+	SetTranslator(func(ctx context.Context, rule, field, param string) (string, bool) {
+		if rule == "min" {
+			return field + " 至少为 " + param, true
+		}
+		return "", false
+	})
+	t.Cleanup(func() { SetTranslator(nil) })
+	require.Equal(t, []string{"Name 至少为 3", "must be at most 10", "Tags 至少为 2", "is not a valid token"}, messages())
+}